@@ -0,0 +1,530 @@
+// Package eventbridge provides an AWS EventBridge broker
+package eventbridge
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/google/uuid"
+	"go-micro.dev/v4/broker"
+	"go-micro.dev/v4/logger"
+	"go-micro.dev/v4/util/cmd"
+)
+
+const (
+	// HeaderSource overrides the source of a published event.
+	HeaderSource = "Source"
+	// HeaderDetailType overrides the detail type of a published event, which defaults to the topic.
+	HeaderDetailType = "Detail-Type"
+
+	defaultEventBus          = "default"
+	defaultSource            = "go.micro"
+	defaultMaxMessages       = 10
+	defaultVisibilityTimeout = 30
+	defaultWaitSeconds       = 20
+
+	targetID = "micro"
+	// maxNameLength is the maximum length of an EventBridge rule name
+	maxNameLength = 64
+)
+
+var invalidName = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+type ebBroker struct {
+	sync.RWMutex
+	options broker.Options
+	eb      *eventbridge.EventBridge
+	sqs     *sqs.SQS
+}
+
+// subscriber polls the SQS queue targeted by its rule.
+type subscriber struct {
+	b       *ebBroker
+	options broker.SubscribeOptions
+	topic   string
+	name    string
+	URL     string
+	// whether the rule and queue are removed on unsubscribe
+	ephemeral bool
+	exit      chan bool
+	done      chan struct{}
+}
+
+// event is an EventBridge event as delivered to an SQS target.
+type event struct {
+	ID         string          `json:"id"`
+	DetailType string          `json:"detail-type"`
+	Source     string          `json:"source"`
+	Time       time.Time       `json:"time"`
+	Detail     json.RawMessage `json:"detail"`
+}
+
+type publication struct {
+	sm    *sqs.Message
+	s     *subscriber
+	m     *broker.Message
+	topic string
+	err   error
+}
+
+func init() {
+	cmd.DefaultBrokers["eventbridge"] = NewBroker
+}
+
+func (p *publication) Topic() string {
+	return p.topic
+}
+
+func (p *publication) Message() *broker.Message {
+	return p.m
+}
+
+func (p *publication) Ack() error {
+	_, err := p.s.b.sqs.DeleteMessage(&sqs.DeleteMessageInput{
+		QueueUrl:      &p.s.URL,
+		ReceiptHandle: p.sm.ReceiptHandle,
+	})
+	return err
+}
+
+func (p *publication) Error() error {
+	return p.err
+}
+
+func (s *subscriber) Options() broker.SubscribeOptions {
+	return s.options
+}
+
+func (s *subscriber) Topic() string {
+	return s.topic
+}
+
+// Unsubscribe stops polling, the rule and queue of subscribers without a
+// queue name are deleted.
+func (s *subscriber) Unsubscribe() error {
+	select {
+	case <-s.exit:
+		return nil
+	default:
+		close(s.exit)
+	}
+
+	<-s.done
+
+	if !s.ephemeral {
+		return nil
+	}
+
+	return s.b.teardown(s.name, s.URL)
+}
+
+func (s *subscriber) run(h broker.Handler) {
+	defer close(s.done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		<-s.exit
+		cancel()
+	}()
+
+	for {
+		select {
+		case <-s.exit:
+			return
+		default:
+		}
+
+		result, err := s.b.sqs.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            &s.URL,
+			MaxNumberOfMessages: aws.Int64(defaultMaxMessages),
+			VisibilityTimeout:   aws.Int64(getInt64(s.options.Context, visibilityTimeoutKey{}, defaultVisibilityTimeout)),
+			WaitTimeSeconds:     aws.Int64(getInt64(s.options.Context, waitTimeSecondsKey{}, defaultWaitSeconds)),
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Errorf("[eventbridge] error receiving from %s: %v", s.name, err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		for _, sm := range result.Messages {
+			s.handle(sm, h)
+		}
+	}
+}
+
+func (s *subscriber) handle(sm *sqs.Message, h broker.Handler) {
+	p := &publication{
+		sm:    sm,
+		s:     s,
+		topic: s.topic,
+	}
+
+	m, err := fromEvent([]byte(aws.StringValue(sm.Body)))
+	if err != nil {
+		// not an event, nothing we can do with it
+		logger.Errorf("[eventbridge] failed to decode event: %v", err)
+		p.Ack()
+		return
+	}
+	p.m = m
+
+	p.err = h(p)
+	if p.err == nil && s.options.AutoAck {
+		if err := p.Ack(); err != nil {
+			logger.Errorf("[eventbridge] failed to ack message: %v", err)
+		}
+	}
+}
+
+// fromEvent converts an EventBridge event to a broker message, the detail is the body.
+func fromEvent(b []byte) (*broker.Message, error) {
+	var e event
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, err
+	}
+
+	if len(e.DetailType) == 0 {
+		return nil, errors.New("missing detail type")
+	}
+
+	return &broker.Message{
+		Header: map[string]string{
+			"Content-Type":   "application/json",
+			"Micro-Id":       e.ID,
+			"Micro-Topic":    e.DetailType,
+			HeaderSource:     e.Source,
+			HeaderDetailType: e.DetailType,
+		},
+		Body: e.Detail,
+	}, nil
+}
+
+// toEntry converts a broker message to an event, the body must be a JSON object.
+func (b *ebBroker) toEntry(topic string, msg *broker.Message) (*eventbridge.PutEventsRequestEntry, error) {
+	body := bytes.TrimSpace(msg.Body)
+	if len(body) == 0 || body[0] != '{' || !json.Valid(body) {
+		return nil, errors.New("eventbridge: message body must be a JSON object")
+	}
+
+	source := getString(b.options.Context, sourceKey{}, defaultSource)
+	if s, ok := msg.Header[HeaderSource]; ok && len(s) > 0 {
+		source = s
+	}
+
+	detailType := topic
+	if dt, ok := msg.Header[HeaderDetailType]; ok && len(dt) > 0 {
+		detailType = dt
+	}
+
+	return &eventbridge.PutEventsRequestEntry{
+		EventBusName: aws.String(b.eventBus()),
+		Source:       aws.String(source),
+		DetailType:   aws.String(detailType),
+		Detail:       aws.String(string(body)),
+		Time:         aws.Time(time.Now()),
+	}, nil
+}
+
+func (b *ebBroker) eventBus() string {
+	return getString(b.options.Context, eventBusKey{}, defaultEventBus)
+}
+
+// ruleName returns the name used for the rule and queue of a subscription.
+// Queue subscriptions of different topics need rules of their own, so the
+// name ends with a hash of the topic.
+func ruleName(topic, queue string) (string, bool) {
+	if len(queue) > 0 {
+		sum := sha256.Sum256([]byte(topic))
+		hash := hex.EncodeToString(sum[:4])
+
+		name := invalidName.ReplaceAllString(queue, "-")
+		if max := maxNameLength - len(hash) - 1; len(name) > max {
+			name = name[:max]
+		}
+		return name + "-" + hash, false
+	}
+
+	id := uuid.New().String()
+	name := invalidName.ReplaceAllString(topic, "-")
+	if max := maxNameLength - len(id) - len("micro--"); len(name) > max {
+		name = name[:max]
+	}
+
+	return "micro-" + name + "-" + id, true
+}
+
+func (b *ebBroker) Options() broker.Options {
+	return b.options
+}
+
+func (b *ebBroker) Address() string {
+	return b.eventBus()
+}
+
+func (b *ebBroker) Connect() error {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.eb != nil {
+		return nil
+	}
+
+	sess, ok := b.options.Context.Value(sessionKey{}).(*session.Session)
+	if !ok || sess == nil {
+		s, err := session.NewSessionWithOptions(session.Options{
+			SharedConfigState: session.SharedConfigEnable,
+		})
+		if err != nil {
+			return err
+		}
+		sess = s
+	}
+
+	var cfgs []*aws.Config
+	if cfg, ok := b.options.Context.Value(configKey{}).(*aws.Config); ok && cfg != nil {
+		cfgs = append(cfgs, cfg)
+	}
+
+	b.eb = eventbridge.New(sess, cfgs...)
+	b.sqs = sqs.New(sess, cfgs...)
+
+	return nil
+}
+
+// Disconnect does nothing as there's no live connection to terminate.
+func (b *ebBroker) Disconnect() error {
+	return nil
+}
+
+func (b *ebBroker) Init(opts ...broker.Option) error {
+	for _, o := range opts {
+		o(&b.options)
+	}
+	return nil
+}
+
+// Publish puts an event on the event bus with the topic as detail type.
+func (b *ebBroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
+	b.RLock()
+	svc := b.eb
+	b.RUnlock()
+
+	if svc == nil {
+		return errors.New("eventbridge: not connected")
+	}
+
+	entry, err := b.toEntry(topic, msg)
+	if err != nil {
+		return err
+	}
+
+	result, err := svc.PutEvents(&eventbridge.PutEventsInput{
+		Entries: []*eventbridge.PutEventsRequestEntry{entry},
+	})
+	if err != nil {
+		return err
+	}
+
+	if aws.Int64Value(result.FailedEntryCount) > 0 && len(result.Entries) > 0 {
+		e := result.Entries[0]
+		return fmt.Errorf("eventbridge: failed to put event: %s %s", aws.StringValue(e.ErrorCode), aws.StringValue(e.ErrorMessage))
+	}
+
+	return nil
+}
+
+// Subscribe creates a rule matching the topic which targets an SQS queue, and
+// polls the queue. Subscribers of the topic with the same queue name share
+// the rule and queue, which are kept on unsubscribe.
+func (b *ebBroker) Subscribe(topic string, h broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	options := broker.SubscribeOptions{
+		AutoAck: true,
+		Context: context.Background(),
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	b.RLock()
+	connected := b.eb != nil
+	b.RUnlock()
+
+	if !connected {
+		return nil, errors.New("eventbridge: not connected")
+	}
+
+	name, ephemeral := ruleName(topic, options.Queue)
+
+	pattern := getString(options.Context, eventPatternKey{}, "")
+	if len(pattern) == 0 {
+		p, err := json.Marshal(map[string][]string{"detail-type": {topic}})
+		if err != nil {
+			return nil, err
+		}
+		pattern = string(p)
+	}
+
+	url, err := b.setup(name, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &subscriber{
+		b:         b,
+		options:   options,
+		topic:     topic,
+		name:      name,
+		URL:       url,
+		ephemeral: ephemeral,
+		exit:      make(chan bool),
+		done:      make(chan struct{}),
+	}
+
+	go s.run(h)
+
+	return s, nil
+}
+
+// setup creates the rule, the target queue allowing the rule to send to it
+// and the target, returning the queue url.
+func (b *ebBroker) setup(name, pattern string) (string, error) {
+	rule, err := b.eb.PutRule(&eventbridge.PutRuleInput{
+		Name:         aws.String(name),
+		EventBusName: aws.String(b.eventBus()),
+		EventPattern: aws.String(pattern),
+		State:        aws.String(eventbridge.RuleStateEnabled),
+	})
+	if err != nil {
+		return "", fmt.Errorf("eventbridge: failed to create rule %s: %v", name, err)
+	}
+
+	queue, err := b.sqs.CreateQueue(&sqs.CreateQueueInput{
+		QueueName: aws.String(name),
+	})
+	if err != nil {
+		return "", fmt.Errorf("eventbridge: failed to create queue %s: %v", name, err)
+	}
+
+	attrs, err := b.sqs.GetQueueAttributes(&sqs.GetQueueAttributesInput{
+		QueueUrl:       queue.QueueUrl,
+		AttributeNames: aws.StringSlice([]string{sqs.QueueAttributeNameQueueArn}),
+	})
+	if err != nil {
+		return "", err
+	}
+	queueArn := aws.StringValue(attrs.Attributes[sqs.QueueAttributeNameQueueArn])
+
+	policy, err := json.Marshal(map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{{
+			"Effect":    "Allow",
+			"Principal": map[string]string{"Service": "events.amazonaws.com"},
+			"Action":    "sqs:SendMessage",
+			"Resource":  queueArn,
+			"Condition": map[string]interface{}{
+				"ArnEquals": map[string]string{"aws:SourceArn": aws.StringValue(rule.RuleArn)},
+			},
+		}},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := b.sqs.SetQueueAttributes(&sqs.SetQueueAttributesInput{
+		QueueUrl:   queue.QueueUrl,
+		Attributes: map[string]*string{sqs.QueueAttributeNamePolicy: aws.String(string(policy))},
+	}); err != nil {
+		return "", fmt.Errorf("eventbridge: failed to set policy of queue %s: %v", name, err)
+	}
+
+	if _, err := b.eb.PutTargets(&eventbridge.PutTargetsInput{
+		Rule:         aws.String(name),
+		EventBusName: aws.String(b.eventBus()),
+		Targets: []*eventbridge.Target{{
+			Id:  aws.String(targetID),
+			Arn: aws.String(queueArn),
+		}},
+	}); err != nil {
+		return "", fmt.Errorf("eventbridge: failed to add target to rule %s: %v", name, err)
+	}
+
+	return aws.StringValue(queue.QueueUrl), nil
+}
+
+// teardown deletes the rule and its target queue.
+func (b *ebBroker) teardown(name, url string) error {
+	if _, err := b.eb.RemoveTargets(&eventbridge.RemoveTargetsInput{
+		Rule:         aws.String(name),
+		EventBusName: aws.String(b.eventBus()),
+		Ids:          aws.StringSlice([]string{targetID}),
+	}); err != nil {
+		return err
+	}
+
+	if _, err := b.eb.DeleteRule(&eventbridge.DeleteRuleInput{
+		Name:         aws.String(name),
+		EventBusName: aws.String(b.eventBus()),
+	}); err != nil {
+		return err
+	}
+
+	_, err := b.sqs.DeleteQueue(&sqs.DeleteQueueInput{QueueUrl: aws.String(url)})
+	return err
+}
+
+// String returns the name of the broker plugin.
+func (b *ebBroker) String() string {
+	return "eventbridge"
+}
+
+func getString(ctx context.Context, k interface{}, def string) string {
+	if ctx != nil {
+		if v, ok := ctx.Value(k).(string); ok && len(strings.TrimSpace(v)) > 0 {
+			return v
+		}
+	}
+	return def
+}
+
+func getInt64(ctx context.Context, k interface{}, def int64) int64 {
+	if ctx != nil {
+		if v, ok := ctx.Value(k).(int64); ok {
+			return v
+		}
+	}
+	return def
+}
+
+// NewBroker creates a new EventBridge broker.
+func NewBroker(opts ...broker.Option) broker.Broker {
+	options := broker.Options{
+		Context: context.Background(),
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &ebBroker{
+		options: options,
+	}
+}
@@ -0,0 +1,78 @@
+package eventbridge
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"go-micro.dev/v4/broker"
+)
+
+func TestToEntry(t *testing.T) {
+	b := NewBroker(Source("orders"), EventBus("shop")).(*ebBroker)
+
+	entry, err := b.toEntry("order.created", &broker.Message{Body: []byte(`{"id":"1"}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if aws.StringValue(entry.DetailType) != "order.created" || aws.StringValue(entry.Source) != "orders" || aws.StringValue(entry.EventBusName) != "shop" {
+		t.Fatalf("Unexpected entry %v", entry)
+	}
+
+	entry, err = b.toEntry("order.created", &broker.Message{
+		Header: map[string]string{HeaderSource: "billing", HeaderDetailType: "Order Created"},
+		Body:   []byte(`{"id":"1"}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if aws.StringValue(entry.DetailType) != "Order Created" || aws.StringValue(entry.Source) != "billing" {
+		t.Fatalf("Expected headers to override source and detail type, got %v", entry)
+	}
+
+	if _, err := b.toEntry("order.created", &broker.Message{Body: []byte(`"text"`)}); err == nil {
+		t.Fatal("Expected error for body which isn't a JSON object")
+	}
+}
+
+func TestFromEvent(t *testing.T) {
+	m, err := fromEvent([]byte(`{"version":"0","id":"abc","detail-type":"order.created","source":"orders","time":"2022-01-01T00:00:00Z","detail":{"id":"1"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(m.Body) != `{"id":"1"}` {
+		t.Fatalf("Expected detail as body, got %s", m.Body)
+	}
+	if m.Header["Micro-Id"] != "abc" || m.Header[HeaderSource] != "orders" || m.Header[HeaderDetailType] != "order.created" {
+		t.Fatalf("Unexpected headers %v", m.Header)
+	}
+}
+
+func TestRuleName(t *testing.T) {
+	name, ephemeral := ruleName("order.created", "")
+	if !ephemeral || !strings.HasPrefix(name, "micro-order-created-") || len(name) > maxNameLength {
+		t.Fatalf("Unexpected ephemeral name %s", name)
+	}
+
+	name, ephemeral = ruleName("order.created", "billing.orders")
+	if ephemeral || !strings.HasPrefix(name, "billing-orders-") {
+		t.Fatalf("Expected queue name billing-orders, got %s", name)
+	}
+	if again, _ := ruleName("order.created", "billing.orders"); again != name {
+		t.Fatalf("Expected the subscribers of the queue to share %s, got %s", name, again)
+	}
+
+	// queue subscriptions of other topics don't replace the rule
+	other, _ := ruleName("order.cancelled", "billing.orders")
+	if other == name || !strings.HasPrefix(other, "billing-orders-") {
+		t.Fatalf("Expected a rule of its own for the other topic, got %s", other)
+	}
+
+	name, _ = ruleName("order.created", strings.Repeat("a", 100))
+	if len(name) > maxNameLength {
+		t.Fatalf("Expected the name to be truncated, got %s", name)
+	}
+}
@@ -0,0 +1,83 @@
+package eventbridge
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"go-micro.dev/v4/broker"
+)
+
+type sessionKey struct{}
+
+// Session sets the AWS session, by default a session is created from the
+// shared config and environment.
+func Session(s *session.Session) broker.Option {
+	return setBrokerOption(sessionKey{}, s)
+}
+
+type configKey struct{}
+
+// Config adds AWS config options to the eventbridge, sqs and sts clients.
+func Config(c *aws.Config) broker.Option {
+	return setBrokerOption(configKey{}, c)
+}
+
+type eventBusKey struct{}
+
+// EventBus sets the name or ARN of the event bus. Defaults to "default".
+func EventBus(name string) broker.Option {
+	return setBrokerOption(eventBusKey{}, name)
+}
+
+type sourceKey struct{}
+
+// Source sets the source of published events. It can be overridden per
+// message with the Source header.
+func Source(s string) broker.Option {
+	return setBrokerOption(sourceKey{}, s)
+}
+
+type eventPatternKey struct{}
+
+// EventPattern sets the event pattern of the rule created for the subscriber.
+// By default events with the topic as detail type are matched.
+func EventPattern(pattern string) broker.SubscribeOption {
+	return setSubscribeOption(eventPatternKey{}, pattern)
+}
+
+type waitTimeSecondsKey struct{}
+
+// WaitTimeSeconds controls the length of long polling of the target queue.
+// Defaults to 20 seconds.
+func WaitTimeSeconds(seconds int64) broker.SubscribeOption {
+	return setSubscribeOption(waitTimeSecondsKey{}, seconds)
+}
+
+type visibilityTimeoutKey struct{}
+
+// VisibilityTimeout controls how long a received message is hidden from other
+// consumers of the target queue. Defaults to 30 seconds.
+func VisibilityTimeout(seconds int64) broker.SubscribeOption {
+	return setSubscribeOption(visibilityTimeoutKey{}, seconds)
+}
+
+// setBrokerOption returns a function to setup a context with given value.
+func setBrokerOption(k, v interface{}) broker.Option {
+	return func(o *broker.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+// setSubscribeOption returns a function to setup a context with given value.
+func setSubscribeOption(k, v interface{}) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
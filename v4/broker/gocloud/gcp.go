@@ -0,0 +1,64 @@
+//go:build !gocloud_minimal && !gocloud_nogcp
+// +build !gocloud_minimal,!gocloud_nogcp
+
+package gocloud
+
+import (
+	"context"
+
+	"go-micro.dev/v4/broker"
+	"gocloud.dev/gcp"
+	"gocloud.dev/pubsub"
+	"gocloud.dev/pubsub/gcppubsub"
+)
+
+type (
+	gcpTokenSourceKey struct{}
+	gcpProjectIDKey   struct{}
+)
+
+func init() {
+	drivers = append(drivers, func(options broker.Options) (topicOpener, subOpener, bool, error) {
+		projID, ok := options.Context.Value(gcpProjectIDKey{}).(gcp.ProjectID)
+		if !ok {
+			return nil, nil, false, nil
+		}
+		ts := options.Context.Value(gcpTokenSourceKey{}).(gcp.TokenSource)
+		openTopic, openSub, err := setupGCP(options.Context, projID, ts)
+		return openTopic, openSub, true, err
+	})
+}
+
+// GCPTokenSource is a broker Option that provides a TokenSource
+// for Go Cloud's Google Pub/Sub implementation.
+func GCPTokenSource(ts gcp.TokenSource) broker.Option {
+	return optfunc(gcpTokenSourceKey{}, ts)
+}
+
+// GCPProjectID is a broker Option that provides a project ID
+// for Go Cloud's Google Pub/Sub implementation.
+func GCPProjectID(projID gcp.ProjectID) broker.Option {
+	return optfunc(gcpProjectIDKey{}, projID)
+}
+
+func setupGCP(ctx context.Context, projectID gcp.ProjectID, ts gcp.TokenSource) (topicOpener, subOpener, error) {
+	conn, _, err := gcppubsub.Dial(ctx, ts) // ignore closer; no way to close
+	if err != nil {
+		return nil, nil, err
+	}
+	pc, err := gcppubsub.PublisherClient(ctx, conn)
+	if err != nil {
+		return nil, nil, err
+	}
+	sc, err := gcppubsub.SubscriberClient(ctx, conn)
+	if err != nil {
+		return nil, nil, err
+	}
+	return func(name string) (*pubsub.Topic, error) {
+			return gcppubsub.OpenTopic(pc, projectID, name, nil), nil
+		},
+		func(t *pubsub.Topic, name string) (*pubsub.Subscription, error) {
+			return gcppubsub.OpenSubscription(sc, projectID, name, nil), nil
+		},
+		nil
+}
@@ -7,17 +7,14 @@ import (
 	"context"
 	"errors"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/streadway/amqp"
 	"go-micro.dev/v4/broker"
 	"go-micro.dev/v4/util/cmd"
-	"gocloud.dev/gcp"
 	"gocloud.dev/pubsub"
-	"gocloud.dev/pubsub/gcppubsub"
 	"gocloud.dev/pubsub/mempubsub"
-	"gocloud.dev/pubsub/rabbitpubsub"
 )
 
 func init() {
@@ -25,10 +22,17 @@ func init() {
 }
 
 type (
-	topicOpener func(string) *pubsub.Topic
-	subOpener   func(*pubsub.Topic, string) *pubsub.Subscription
+	topicOpener func(string) (*pubsub.Topic, error)
+	subOpener   func(*pubsub.Topic, string) (*pubsub.Subscription, error)
 )
 
+// driver sets up the openers of a Go Cloud implementation, if the options
+// for it are set. Drivers register themselves in files which can be left out
+// with build tags.
+type driver func(options broker.Options) (topicOpener, subOpener, bool, error)
+
+var drivers []driver
+
 type pubsubBroker struct {
 	options   broker.Options
 	openTopic topicOpener
@@ -41,9 +45,14 @@ type pubsubBroker struct {
 }
 
 // NewBroker creates a new gocloud pubsubBroker.
+// If the TopicURL option is set, Go Cloud opens topics and subscriptions by URL.
 // If the GCPProjectID option is set, Go Cloud uses its Google Cloud PubSub implementation.
 // If the RabbitURL option is set, Go Cloud uses its RabbitMQ implementation.
 // Otherwise, Go Cloud uses its in-memory implementation.
+//
+// The Google Cloud PubSub and RabbitMQ implementations are left out when
+// building with the gocloud_minimal tag, or individually with the gocloud_nogcp
+// and gocloud_norabbit tags.
 func NewBroker(opts ...broker.Option) broker.Broker {
 	options := broker.Options{
 		Context: context.Background(),
@@ -52,17 +61,8 @@ func NewBroker(opts ...broker.Option) broker.Broker {
 	for _, o := range opts {
 		o(&options)
 	}
-	var openTopic topicOpener
-	var openSub subOpener
-	var err error
-	if projID, ok := options.Context.Value(gcpProjectIDKey{}).(gcp.ProjectID); ok {
-		ts := options.Context.Value(gcpTokenSourceKey{}).(gcp.TokenSource)
-		openTopic, openSub, err = setupGCP(options.Context, projID, ts)
-	} else if rurl, ok := options.Context.Value(rabbitURLKey{}).(string); ok {
-		openTopic, openSub, err = setupRabbit(options.Context, rurl)
-	} else {
-		openTopic, openSub, err = setupMem()
-	}
+
+	openTopic, openSub, err := setup(options)
 	if err != nil {
 		return &pubsubBroker{err: err}
 	}
@@ -75,44 +75,55 @@ func NewBroker(opts ...broker.Option) broker.Broker {
 	}
 }
 
-func setupGCP(ctx context.Context, projectID gcp.ProjectID, ts gcp.TokenSource) (topicOpener, subOpener, error) {
-	conn, _, err := gcppubsub.Dial(ctx, ts) // ignore closer; no way to close
-	if err != nil {
-		return nil, nil, err
-	}
-	pc, err := gcppubsub.PublisherClient(ctx, conn)
-	if err != nil {
-		return nil, nil, err
+// NewURLBroker creates a gocloud pubsubBroker which only opens topics and
+// subscriptions by URL. Only the Go Cloud implementations imported by the
+// binary are linked, e.g.
+//
+//	import _ "gocloud.dev/pubsub/awssnssqs"
+//
+//	b := gocloud.NewURLBroker(
+//		"awssns:///arn:aws:sns:us-east-2:123456789012:{topic}?region=us-east-2",
+//		"awssqs://sqs.us-east-2.amazonaws.com/123456789012/{queue}?region=us-east-2",
+//	)
+func NewURLBroker(topicURL, subscriptionURL string, opts ...broker.Option) broker.Broker {
+	return NewBroker(append(opts, TopicURL(topicURL), SubscriptionURL(subscriptionURL))...)
+}
+
+func setup(options broker.Options) (topicOpener, subOpener, error) {
+	if topicURL, ok := options.Context.Value(topicURLKey{}).(string); ok {
+		subURL, _ := options.Context.Value(subscriptionURLKey{}).(string)
+		return setupURL(options.Context, topicURL, subURL)
 	}
-	sc, err := gcppubsub.SubscriberClient(ctx, conn)
-	if err != nil {
-		return nil, nil, err
+
+	for _, d := range drivers {
+		openTopic, openSub, ok, err := d(options)
+		if ok || err != nil {
+			return openTopic, openSub, err
+		}
 	}
-	return func(name string) *pubsub.Topic {
-			return gcppubsub.OpenTopic(pc, projectID, name, nil)
-		},
-		func(t *pubsub.Topic, name string) *pubsub.Subscription {
-			return gcppubsub.OpenSubscription(sc, projectID, name, nil)
-		},
-		nil
+
+	return setupMem()
 }
 
-func setupRabbit(ctx context.Context, url string) (topicOpener, subOpener, error) {
-	conn, err := amqp.Dial(url)
-	if err != nil {
-		return nil, nil, err
+// setupURL opens topics and subscriptions by URL, replacing {topic} and
+// {queue} with the topic and queue names.
+func setupURL(ctx context.Context, topicURL, subURL string) (topicOpener, subOpener, error) {
+	if len(subURL) == 0 {
+		return nil, nil, errors.New("gocloud: need SubscriptionURL option")
 	}
-	return func(name string) *pubsub.Topic { return rabbitpubsub.OpenTopic(conn, name, nil) },
-		func(_ *pubsub.Topic, name string) *pubsub.Subscription {
-			return rabbitpubsub.OpenSubscription(conn, name, nil)
+	return func(name string) (*pubsub.Topic, error) {
+			return pubsub.OpenTopic(ctx, strings.ReplaceAll(topicURL, "{topic}", name))
+		},
+		func(t *pubsub.Topic, name string) (*pubsub.Subscription, error) {
+			return pubsub.OpenSubscription(ctx, strings.ReplaceAll(subURL, "{queue}", name))
 		},
 		nil
 }
 
 func setupMem() (topicOpener, subOpener, error) {
-	return func(string) *pubsub.Topic { return mempubsub.NewTopic() },
-		func(t *pubsub.Topic, _ string) *pubsub.Subscription {
-			return mempubsub.NewSubscription(t, 10*time.Second)
+	return func(string) (*pubsub.Topic, error) { return mempubsub.NewTopic(), nil },
+		func(t *pubsub.Topic, _ string) (*pubsub.Subscription, error) {
+			return mempubsub.NewSubscription(t, 10*time.Second), nil
 		},
 		nil
 }
@@ -132,7 +143,10 @@ func (b *pubsubBroker) Publish(topic string, msg *broker.Message, opts ...broker
 	if b.err != nil {
 		return b.err
 	}
-	t := b.topic(topic)
+	t, err := b.topic(topic)
+	if err != nil {
+		return err
+	}
 	return t.Send(context.Background(), &pubsub.Message{Metadata: msg.Header, Body: msg.Body})
 }
 
@@ -155,13 +169,19 @@ func (b *pubsubBroker) Subscribe(topic string, h broker.Handler, opts ...broker.
 	if options.Queue == "" {
 		return nil, errors.New("gocloud.Subscribe: need Queue option")
 	}
-	t := b.topic(topic)
+	t, err := b.topic(topic)
+	if err != nil {
+		return nil, err
+	}
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	s := b.subs[options.Queue]
 	if s == nil {
 		// TODO(jba): how to configure ack deadline?
-		s = b.openSub(t, options.Queue)
+		s, err = b.openSub(t, options.Queue)
+		if err != nil {
+			return nil, err
+		}
 		b.subs[options.Queue] = s
 	}
 	// TODO(jba): how can we verify that an existing subscription's topic matches?
@@ -178,15 +198,19 @@ func (b *pubsubBroker) Subscribe(topic string, h broker.Handler, opts ...broker.
 
 // topic returns the topic with the given name if this broker has
 // seen it before. Otherwise it opens a new topic.
-func (b *pubsubBroker) topic(name string) *pubsub.Topic {
+func (b *pubsubBroker) topic(name string) (*pubsub.Topic, error) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	t := b.topics[name]
 	if t == nil {
-		t = b.openTopic(name)
+		var err error
+		t, err = b.openTopic(name)
+		if err != nil {
+			return nil, err
+		}
 		b.topics[name] = t
 	}
-	return t
+	return t, nil
 }
 
 // A pubsub subscriber that manages handling of messages.
@@ -50,3 +50,24 @@ func receiveN(b broker.Broker, topic string, n int, donec chan struct{}) (broker
 	}
 	return b.Subscribe(topic, handler, broker.Queue("S"))
 }
+
+func TestURLBroker(t *testing.T) {
+	const nMessages = 10
+	// mem subscription URLs name the topic instead of the queue
+	b := NewURLBroker("mem://url-{topic}", "mem://url-T")
+	donec := make(chan struct{})
+	sub, err := receiveN(b, "T", nMessages, donec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := publishN(b, "T", nMessages); err != nil {
+		t.Fatal(err)
+	}
+	<-donec
+	sub.Unsubscribe()
+
+	b = NewBroker(TopicURL("mem://{topic}"))
+	if err := b.Publish("T", &broker.Message{}); err == nil {
+		t.Fatal("Expected error without subscription URL")
+	}
+}
@@ -4,31 +4,24 @@ import (
 	"context"
 
 	"go-micro.dev/v4/broker"
-	"gocloud.dev/gcp"
 )
 
 type (
-	rabbitURLKey      struct{}
-	gcpTokenSourceKey struct{}
-	gcpProjectIDKey   struct{}
+	topicURLKey        struct{}
+	subscriptionURLKey struct{}
 )
 
-// RabbitURL is a broker Option that provides a URL for
-// Go Cloud's RabbitMQ implementation.
-func RabbitURL(url string) broker.Option {
-	return optfunc(rabbitURLKey{}, url)
+// TopicURL is a broker Option that opens topics by URL, {topic} is replaced
+// with the topic name. The Go Cloud implementation of the URL scheme must be
+// imported. It requires the SubscriptionURL option.
+func TopicURL(url string) broker.Option {
+	return optfunc(topicURLKey{}, url)
 }
 
-// GCPTokenSource is a broker Option that provides a TokenSource
-// for Go Cloud's Google Pub/Sub implementation.
-func GCPTokenSource(ts gcp.TokenSource) broker.Option {
-	return optfunc(gcpTokenSourceKey{}, ts)
-}
-
-// GCPProjectID is a broker Option that provides a project ID
-// for Go Cloud's Google Pub/Sub implementation.
-func GCPProjectID(projID gcp.ProjectID) broker.Option {
-	return optfunc(gcpProjectIDKey{}, projID)
+// SubscriptionURL is a broker Option that opens subscriptions by URL, {queue}
+// is replaced with the queue name.
+func SubscriptionURL(url string) broker.Option {
+	return optfunc(subscriptionURLKey{}, url)
 }
 
 func optfunc(key, val interface{}) func(*broker.Options) {
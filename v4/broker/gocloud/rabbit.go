@@ -0,0 +1,42 @@
+//go:build !gocloud_minimal && !gocloud_norabbit
+// +build !gocloud_minimal,!gocloud_norabbit
+
+package gocloud
+
+import (
+	"github.com/streadway/amqp"
+	"go-micro.dev/v4/broker"
+	"gocloud.dev/pubsub"
+	"gocloud.dev/pubsub/rabbitpubsub"
+)
+
+type rabbitURLKey struct{}
+
+func init() {
+	drivers = append(drivers, func(options broker.Options) (topicOpener, subOpener, bool, error) {
+		rurl, ok := options.Context.Value(rabbitURLKey{}).(string)
+		if !ok {
+			return nil, nil, false, nil
+		}
+		openTopic, openSub, err := setupRabbit(rurl)
+		return openTopic, openSub, true, err
+	})
+}
+
+// RabbitURL is a broker Option that provides a URL for
+// Go Cloud's RabbitMQ implementation.
+func RabbitURL(url string) broker.Option {
+	return optfunc(rabbitURLKey{}, url)
+}
+
+func setupRabbit(url string) (topicOpener, subOpener, error) {
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return nil, nil, err
+	}
+	return func(name string) (*pubsub.Topic, error) { return rabbitpubsub.OpenTopic(conn, name, nil), nil },
+		func(_ *pubsub.Topic, name string) (*pubsub.Subscription, error) {
+			return rabbitpubsub.OpenSubscription(conn, name, nil), nil
+		},
+		nil
+}
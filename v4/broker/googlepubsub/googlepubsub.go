@@ -3,7 +3,9 @@ package googlepubsub
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"cloud.google.com/go/pubsub"
@@ -19,6 +21,10 @@ import (
 type pubsubBroker struct {
 	client  *pubsub.Client
 	options broker.Options
+
+	sync.Mutex
+	// topics are kept so publishes with an ordering key share a scheduler
+	topics map[string]*pubsub.Topic
 }
 
 // A pubsub subscriber that manages handling of messages.
@@ -27,14 +33,18 @@ type subscriber struct {
 	topic   string
 	exit    chan bool
 	sub     *pubsub.Subscription
+	// whether acks wait for the server to confirm them
+	exactlyOnce bool
 }
 
 // A single publication received by a handler.
 type publication struct {
-	pm    *pubsub.Message
-	m     *broker.Message
-	topic string
-	err   error
+	pm          *pubsub.Message
+	m           *broker.Message
+	topic       string
+	err         error
+	exactlyOnce bool
+	acked       bool
 }
 
 func init() {
@@ -49,6 +59,12 @@ func (s *subscriber) run(hdlr broker.Handler) {
 		if max, ok := s.options.Context.Value(maxExtensionKey{}).(time.Duration); ok {
 			s.sub.ReceiveSettings.MaxExtension = max
 		}
+		if min, ok := s.options.Context.Value(minExtensionPeriodKey{}).(time.Duration); ok {
+			s.sub.ReceiveSettings.MinExtensionPeriod = min
+		}
+		if max, ok := s.options.Context.Value(maxExtensionPeriodKey{}).(time.Duration); ok {
+			s.sub.ReceiveSettings.MaxExtensionPeriod = max
+		}
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -68,9 +84,10 @@ func (s *subscriber) run(hdlr broker.Handler) {
 
 				// create publication
 				p := &publication{
-					pm:    pm,
-					m:     m,
-					topic: s.topic,
+					pm:          pm,
+					m:           m,
+					topic:       s.topic,
+					exactlyOnce: s.exactlyOnce,
 				}
 
 				// If the error is nil lets check if we should auto ack
@@ -78,8 +95,13 @@ func (s *subscriber) run(hdlr broker.Handler) {
 				if p.err == nil {
 					// auto ack?
 					if s.options.AutoAck {
-						p.Ack()
+						if err := p.Ack(); err != nil {
+							log.Errorf("Failed to ack message %s: %v", pm.ID, err)
+						}
 					}
+				} else if !p.acked {
+					// redeliver failed messages right away instead of waiting for the deadline
+					p.Nack()
 				}
 			}); err != nil {
 				time.Sleep(time.Second)
@@ -110,11 +132,32 @@ func (s *subscriber) Unsubscribe() error {
 	}
 }
 
+// Ack acknowledges the message. With exactly once delivery it blocks until
+// the server confirmed the ack, an error means the message will be redelivered.
 func (p *publication) Ack() error {
-	p.pm.Ack()
+	p.acked = true
+
+	if !p.exactlyOnce {
+		p.pm.Ack()
+		return nil
+	}
+
+	status, err := p.pm.AckWithResult().Get(context.Background())
+	if err != nil {
+		return err
+	}
+	if status != pubsub.AcknowledgeStatusSuccess {
+		return fmt.Errorf("ack of message %s failed with status %d", p.pm.ID, status)
+	}
 	return nil
 }
 
+// Nack tells the server to redeliver the message.
+func (p *publication) Nack() {
+	p.acked = true
+	p.pm.Nack()
+}
+
 func (p *publication) Error() error {
 	return p.err
 }
@@ -136,9 +179,33 @@ func (b *pubsubBroker) Connect() error {
 }
 
 func (b *pubsubBroker) Disconnect() error {
+	b.Lock()
+	for name, t := range b.topics {
+		t.Stop()
+		delete(b.topics, name)
+	}
+	b.Unlock()
+
 	return b.client.Close()
 }
 
+// topic returns the topic used to publish, message ordering is enabled so
+// messages with an ordering key can be published.
+func (b *pubsubBroker) topic(name string) *pubsub.Topic {
+	b.Lock()
+	defer b.Unlock()
+
+	if t, ok := b.topics[name]; ok {
+		return t
+	}
+
+	t := b.client.Topic(name)
+	t.EnableMessageOrdering = true
+	b.topics[name] = t
+
+	return t
+}
+
 // Init not currently implemented.
 func (b *pubsubBroker) Init(opts ...broker.Option) error {
 	return nil
@@ -150,7 +217,15 @@ func (b *pubsubBroker) Options() broker.Options {
 
 // Publish checks if the topic exists and then publishes via google pubsub.
 func (b *pubsubBroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) (err error) {
-	t := b.client.Topic(topic)
+	options := broker.PublishOptions{
+		Context: context.Background(),
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	t := b.topic(topic)
 	ctx := context.Background()
 
 	m := &pubsub.Message{
@@ -159,12 +234,22 @@ func (b *pubsubBroker) Publish(topic string, msg *broker.Message, opts ...broker
 		Attributes: msg.Header,
 	}
 
+	if key, ok := options.Context.Value(orderingKey{}).(string); ok {
+		m.OrderingKey = key
+	}
+
 	pr := t.Publish(ctx, m)
 	if _, err = pr.Get(ctx); err != nil {
+		// publishing with the key is paused after an error, resume so the
+		// caller can retry
+		if len(m.OrderingKey) > 0 {
+			t.ResumePublish(m.OrderingKey)
+		}
+
 		// create Topic if not exists
 		if status.Code(err) == codes.NotFound {
 			log.Infof("Topic not exists. creating Topic: %s", topic)
-			if t, err = b.client.CreateTopic(ctx, topic); err == nil {
+			if _, err = b.client.CreateTopic(ctx, topic); err == nil {
 				_, err = t.Publish(ctx, m).Get(ctx)
 			}
 		}
@@ -195,9 +280,14 @@ func (b *pubsubBroker) Subscribe(topic string, h broker.Handler, opts ...broker.
 
 		if !exists {
 			tt := b.client.Topic(topic)
+			ordering, _ := options.Context.Value(messageOrderingKey{}).(bool)
+			exactlyOnce, _ := options.Context.Value(exactlyOnceDeliveryKey{}).(bool)
+
 			subb, err := b.client.CreateSubscription(ctx, options.Queue, pubsub.SubscriptionConfig{
-				Topic:       tt,
-				AckDeadline: time.Duration(0),
+				Topic:                     tt,
+				AckDeadline:               time.Duration(0),
+				EnableMessageOrdering:     ordering,
+				EnableExactlyOnceDelivery: exactlyOnce,
 			})
 			if err != nil {
 				return nil, err
@@ -206,11 +296,14 @@ func (b *pubsubBroker) Subscribe(topic string, h broker.Handler, opts ...broker.
 		}
 	}
 
+	exactlyOnce, _ := options.Context.Value(exactlyOnceDeliveryKey{}).(bool)
+
 	subscriber := &subscriber{
-		options: options,
-		topic:   topic,
-		exit:    make(chan bool),
-		sub:     sub,
+		options:     options,
+		topic:       topic,
+		exit:        make(chan bool),
+		sub:         sub,
+		exactlyOnce: exactlyOnce,
 	}
 
 	go subscriber.run(h)
@@ -252,5 +345,6 @@ func NewBroker(opts ...broker.Option) broker.Broker {
 	return &pubsubBroker{
 		client:  c,
 		options: options,
+		topics:  make(map[string]*pubsub.Topic),
 	}
 }
@@ -20,6 +20,16 @@ type createSubscription struct{}
 
 type deleteSubscription struct{}
 
+type orderingKey struct{}
+
+type messageOrderingKey struct{}
+
+type exactlyOnceDeliveryKey struct{}
+
+type minExtensionPeriodKey struct{}
+
+type maxExtensionPeriodKey struct{}
+
 // ClientOption is a broker Option which allows google pubsub client options to be
 // set for the client.
 func ClientOption(c ...option.ClientOption) broker.Option {
@@ -86,3 +96,62 @@ func MaxExtension(d time.Duration) broker.SubscribeOption {
 		o.Context = context.WithValue(o.Context, maxExtensionKey{}, d)
 	}
 }
+
+// OrderingKey sets the ordering key of a published message. Messages with the
+// same ordering key are delivered in order to subscriptions with message
+// ordering enabled. If a publish fails, later messages with the same key are
+// rejected until a publish with the key is retried.
+func OrderingKey(k string) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+
+		o.Context = context.WithValue(o.Context, orderingKey{}, k)
+	}
+}
+
+// MessageOrdering enables message ordering on the subscription if it's created.
+func MessageOrdering() broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+
+		o.Context = context.WithValue(o.Context, messageOrderingKey{}, true)
+	}
+}
+
+// ExactlyOnceDelivery enables exactly once delivery on the subscription if it's
+// created. Acks then wait for the confirmation of the server and return its error.
+func ExactlyOnceDelivery() broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+
+		o.Context = context.WithValue(o.Context, exactlyOnceDeliveryKey{}, true)
+	}
+}
+
+// MinExtensionPeriod is the minimum duration of a single ack deadline extension.
+func MinExtensionPeriod(d time.Duration) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+
+		o.Context = context.WithValue(o.Context, minExtensionPeriodKey{}, d)
+	}
+}
+
+// MaxExtensionPeriod is the maximum duration of a single ack deadline extension.
+func MaxExtensionPeriod(d time.Duration) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+
+		o.Context = context.WithValue(o.Context, maxExtensionPeriodKey{}, d)
+	}
+}
@@ -0,0 +1,276 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"go-micro.dev/v4/broker"
+)
+
+// Offset is the committed offset of a consumer group on a partition, the
+// offset of the next message the group consumes.
+type Offset struct {
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+	Offset    int64  `json:"offset"`
+	Metadata  string `json:"metadata,omitempty"`
+	// Time is the timestamp of the last message consumed, if exported with
+	// ExportTimestamps.
+	Time time.Time `json:"time,omitempty"`
+}
+
+// Checkpoint is the position of a consumer group, see Export.
+type Checkpoint struct {
+	Group    string    `json:"group"`
+	Offsets  []Offset  `json:"offsets"`
+	Exported time.Time `json:"exported"`
+}
+
+// CheckpointOptions configure Export and Import.
+type CheckpointOptions struct {
+	// Topics only exports the offsets of the topics, by default the offsets
+	// of all topics the group committed are.
+	Topics []string
+	// Timestamps exports the timestamp of the last message consumed per
+	// partition, as needed by Shift.
+	Timestamps bool
+	// Group imports the checkpoint into another group.
+	Group string
+	// Shift moves the imported offsets by time, relative to the timestamps of
+	// the checkpoint.
+	Shift time.Duration
+	// At imports the offsets of the timestamp, instead of those of the
+	// checkpoint.
+	At time.Time
+}
+
+// CheckpointOption sets values in CheckpointOptions.
+type CheckpointOption func(o *CheckpointOptions)
+
+// ExportTopics only exports the offsets of the topics.
+func ExportTopics(topics ...string) CheckpointOption {
+	return func(o *CheckpointOptions) {
+		o.Topics = topics
+	}
+}
+
+// ExportTimestamps exports the timestamp of the last message consumed per
+// partition. It reads a message per partition.
+func ExportTimestamps() CheckpointOption {
+	return func(o *CheckpointOptions) {
+		o.Timestamps = true
+	}
+}
+
+// ImportGroup imports the checkpoint into the group, e.g. to clone a
+// consumer group into another environment.
+func ImportGroup(group string) CheckpointOption {
+	return func(o *CheckpointOptions) {
+		o.Group = group
+	}
+}
+
+// ImportShift moves each offset to the first message after the timestamp of
+// the checkpoint shifted by d, e.g. -time.Hour reprocesses the last hour
+// before the checkpoint. The checkpoint must have been exported with
+// ExportTimestamps.
+func ImportShift(d time.Duration) CheckpointOption {
+	return func(o *CheckpointOptions) {
+		o.Shift = d
+	}
+}
+
+// ImportAt moves each offset of the checkpoint to the first message at or
+// after t.
+func ImportAt(t time.Time) CheckpointOption {
+	return func(o *CheckpointOptions) {
+		o.At = t
+	}
+}
+
+// Export returns the committed offsets of the consumer group. The broker
+// must be connected.
+func Export(b broker.Broker, group string, opts ...CheckpointOption) (*Checkpoint, error) {
+	k, err := connected(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var options CheckpointOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	req := &sarama.OffsetFetchRequest{ConsumerGroup: group, Version: 2}
+	for _, t := range options.Topics {
+		partitions, err := k.c.Partitions(t)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range partitions {
+			req.AddPartition(t, p)
+		}
+	}
+
+	coordinator, err := k.c.Coordinator(group)
+	if err != nil {
+		return nil, err
+	}
+	rsp, err := coordinator.FetchOffset(req)
+	if err != nil {
+		return nil, err
+	}
+	if rsp.Err != sarama.ErrNoError {
+		return nil, rsp.Err
+	}
+
+	cp := &Checkpoint{Group: group, Exported: time.Now()}
+	for t, blocks := range rsp.Blocks {
+		for p, block := range blocks {
+			if block.Err != sarama.ErrNoError {
+				return nil, fmt.Errorf("fetching offset of %s/%d: %w", t, p, block.Err)
+			}
+			// partitions without a committed offset
+			if block.Offset < 0 {
+				continue
+			}
+			cp.Offsets = append(cp.Offsets, Offset{
+				Topic:     t,
+				Partition: p,
+				Offset:    block.Offset,
+				Metadata:  block.Metadata,
+			})
+		}
+	}
+
+	if options.Timestamps {
+		if err := k.timestamps(cp.Offsets); err != nil {
+			return nil, err
+		}
+	}
+
+	return cp, nil
+}
+
+// Import commits the offsets of the checkpoint for its group. Consumers of
+// the group must be stopped, Kafka rejects commits to a group with members
+// and the offsets of running consumers would overwrite them.
+func Import(b broker.Broker, cp *Checkpoint, opts ...CheckpointOption) error {
+	k, err := connected(b)
+	if err != nil {
+		return err
+	}
+
+	options := CheckpointOptions{Group: cp.Group}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	req := &sarama.OffsetCommitRequest{
+		Version:                 2,
+		ConsumerGroup:           options.Group,
+		ConsumerGroupGeneration: sarama.GroupGenerationUndefined,
+		RetentionTime:           -1,
+	}
+	for _, o := range cp.Offsets {
+		offset, err := k.importOffset(o, options)
+		if err != nil {
+			return err
+		}
+		req.AddBlock(o.Topic, o.Partition, offset, 0, o.Metadata)
+	}
+
+	coordinator, err := k.c.Coordinator(options.Group)
+	if err != nil {
+		return err
+	}
+	rsp, err := coordinator.CommitOffset(req)
+	if err != nil {
+		return err
+	}
+	for t, errs := range rsp.Errors {
+		for p, kerr := range errs {
+			if kerr != sarama.ErrNoError {
+				return fmt.Errorf("committing offset of %s/%d: %w", t, p, kerr)
+			}
+		}
+	}
+	return nil
+}
+
+func connected(b broker.Broker) (*kBroker, error) {
+	k, ok := b.(*kBroker)
+	if !ok {
+		return nil, errors.New(b.String() + " is not a kafka broker")
+	}
+	k.scMutex.Lock()
+	defer k.scMutex.Unlock()
+	if k.c == nil {
+		return nil, errors.New("kafka broker is not connected")
+	}
+	return k, nil
+}
+
+// importOffset returns the offset to commit for o.
+func (k *kBroker) importOffset(o Offset, options CheckpointOptions) (int64, error) {
+	var at time.Time
+	switch {
+	case !options.At.IsZero():
+		at = options.At
+	case options.Shift != 0:
+		if o.Time.IsZero() {
+			return 0, fmt.Errorf("no timestamp for %s/%d to shift, export with ExportTimestamps", o.Topic, o.Partition)
+		}
+		// the first message after the last one consumed
+		at = o.Time.Add(options.Shift + time.Millisecond)
+	default:
+		return o.Offset, nil
+	}
+
+	offset, err := k.c.GetOffset(o.Topic, o.Partition, at.UnixNano()/int64(time.Millisecond))
+	if err != nil {
+		return 0, err
+	}
+	// no message at or after the time
+	if offset < 0 {
+		return k.c.GetOffset(o.Topic, o.Partition, sarama.OffsetNewest)
+	}
+	return offset, nil
+}
+
+// timestamps sets the time of the offsets to the timestamp of the message
+// before them. Offsets whose message was deleted are left without one.
+func (k *kBroker) timestamps(offsets []Offset) error {
+	consumer, err := sarama.NewConsumerFromClient(k.c)
+	if err != nil {
+		return err
+	}
+	defer consumer.Close()
+
+	for i, o := range offsets {
+		if o.Offset == 0 {
+			continue
+		}
+		pc, err := consumer.ConsumePartition(o.Topic, o.Partition, o.Offset-1)
+		if errors.Is(err, sarama.ErrOffsetOutOfRange) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		select {
+		case m := <-pc.Messages():
+			offsets[i].Time = m.Timestamp
+		case err := <-pc.Errors():
+			pc.Close()
+			return err
+		case <-time.After(k.c.Config().Consumer.MaxWaitTime + k.c.Config().Net.ReadTimeout):
+			pc.Close()
+			return fmt.Errorf("reading timestamp of %s/%d: timeout", o.Topic, o.Partition)
+		}
+		pc.Close()
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"go-micro.dev/v4/broker"
+)
+
+const (
+	testGroup = "billing"
+	testTopic = "orders"
+)
+
+// newMockBroker returns a connected broker of a mock Kafka broker, which
+// leads both partitions of testTopic and coordinates the groups.
+func newMockBroker(t *testing.T, handlers map[string]sarama.MockResponse) (broker.Broker, *sarama.MockBroker) {
+	t.Helper()
+
+	mb := sarama.NewMockBroker(t, 1)
+	t.Cleanup(mb.Close)
+
+	coordinator := sarama.NewMockFindCoordinatorResponse(t)
+	for _, group := range []string{testGroup, "billing-staging"} {
+		coordinator.SetCoordinator(sarama.CoordinatorGroup, group, mb)
+	}
+
+	all := map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(mb.Addr(), mb.BrokerID()).
+			SetLeader(testTopic, 0, mb.BrokerID()).
+			SetLeader(testTopic, 1, mb.BrokerID()),
+		"FindCoordinatorRequest": coordinator,
+	}
+	for k, v := range handlers {
+		all[k] = v
+	}
+	mb.SetHandlerByMap(all)
+
+	config := sarama.NewConfig()
+	config.Version = sarama.V1_0_0_0
+	config.Metadata.Retry.Max = 0
+
+	b := NewBroker(broker.Addrs(mb.Addr()), BrokerConfig(config))
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Disconnect() })
+
+	return b, mb
+}
+
+// committed returns the offsets of the last commit to the mock broker.
+func committed(t *testing.T, mb *sarama.MockBroker) (string, map[int32]int64) {
+	t.Helper()
+
+	var req *sarama.OffsetCommitRequest
+	for _, rr := range mb.History() {
+		if r, ok := rr.Request.(*sarama.OffsetCommitRequest); ok {
+			req = r
+		}
+	}
+	if req == nil {
+		t.Fatal("Expected offsets to be committed")
+	}
+
+	offsets := make(map[int32]int64)
+	for _, p := range []int32{0, 1} {
+		if offset, _, err := req.Offset(testTopic, p); err == nil {
+			offsets[p] = offset
+		}
+	}
+	return req.ConsumerGroup, offsets
+}
+
+func TestExport(t *testing.T) {
+	b, _ := newMockBroker(t, map[string]sarama.MockResponse{
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset(testGroup, testTopic, 0, 42, "meta", sarama.ErrNoError).
+			// no committed offset
+			SetOffset(testGroup, testTopic, 1, -1, "", sarama.ErrNoError),
+	})
+
+	for name, opts := range map[string][]CheckpointOption{
+		"all":    nil,
+		"topics": {ExportTopics(testTopic)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cp, err := Export(b, testGroup, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cp.Group != testGroup || cp.Exported.IsZero() {
+				t.Fatalf("Unexpected checkpoint %+v", cp)
+			}
+			want := Offset{Topic: testTopic, Partition: 0, Offset: 42, Metadata: "meta"}
+			if len(cp.Offsets) != 1 || cp.Offsets[0] != want {
+				t.Fatalf("Expected the committed offset only, got %+v", cp.Offsets)
+			}
+		})
+	}
+}
+
+func TestExportError(t *testing.T) {
+	b, _ := newMockBroker(t, map[string]sarama.MockResponse{
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset(testGroup, testTopic, 0, 0, "", sarama.ErrUnknownTopicOrPartition),
+	})
+
+	if _, err := Export(b, testGroup); err == nil {
+		t.Fatal("Expected the error of the partition")
+	}
+
+	if _, err := Export(broker.NewMemoryBroker(), testGroup); err == nil {
+		t.Fatal("Expected an error for a broker which isn't kafka")
+	}
+	if _, err := Export(NewBroker(), testGroup); err == nil {
+		t.Fatal("Expected an error for a broker which isn't connected")
+	}
+}
+
+func TestImport(t *testing.T) {
+	b, mb := newMockBroker(t, map[string]sarama.MockResponse{
+		"OffsetCommitRequest": sarama.NewMockOffsetCommitResponse(t),
+	})
+
+	cp := &Checkpoint{Group: testGroup, Offsets: []Offset{
+		{Topic: testTopic, Partition: 0, Offset: 42},
+		{Topic: testTopic, Partition: 1, Offset: 7},
+	}}
+
+	if err := Import(b, cp); err != nil {
+		t.Fatal(err)
+	}
+	group, offsets := committed(t, mb)
+	if group != testGroup || offsets[0] != 42 || offsets[1] != 7 {
+		t.Fatalf("Expected the offsets of the checkpoint, got %s %v", group, offsets)
+	}
+
+	// into another group
+	if err := Import(b, cp, ImportGroup("billing-staging")); err != nil {
+		t.Fatal(err)
+	}
+	if group, _ := committed(t, mb); group != "billing-staging" {
+		t.Fatalf("Expected the offsets to be committed for billing-staging, got %s", group)
+	}
+}
+
+func TestImportCommitError(t *testing.T) {
+	b, _ := newMockBroker(t, map[string]sarama.MockResponse{
+		"OffsetCommitRequest": sarama.NewMockOffsetCommitResponse(t).
+			SetError(testGroup, testTopic, 0, sarama.ErrRebalanceInProgress),
+	})
+
+	cp := &Checkpoint{Group: testGroup, Offsets: []Offset{{Topic: testTopic, Partition: 0, Offset: 42}}}
+	if err := Import(b, cp); err == nil {
+		t.Fatal("Expected the commit to a group with members to fail")
+	}
+}
+
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func TestImportShift(t *testing.T) {
+	consumed := time.Unix(1600000000, 0)
+	shifted := consumed.Add(-time.Hour + time.Millisecond)
+
+	b, mb := newMockBroker(t, map[string]sarama.MockResponse{
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).SetVersion(1).
+			SetOffset(testTopic, 0, millis(shifted), 30),
+		"OffsetCommitRequest": sarama.NewMockOffsetCommitResponse(t),
+	})
+
+	cp := &Checkpoint{Group: testGroup, Offsets: []Offset{
+		{Topic: testTopic, Partition: 0, Offset: 42, Time: consumed},
+	}}
+	if err := Import(b, cp, ImportShift(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, offsets := committed(t, mb); offsets[0] != 30 {
+		t.Fatalf("Expected the offset an hour before the checkpoint, got %v", offsets)
+	}
+
+	// checkpoints exported without timestamps can't be shifted
+	cp.Offsets[0].Time = time.Time{}
+	if err := Import(b, cp, ImportShift(-time.Hour)); err == nil {
+		t.Fatal("Expected an error for an offset without a timestamp")
+	}
+}
+
+func TestImportAt(t *testing.T) {
+	at := time.Unix(1600000000, 0)
+
+	b, mb := newMockBroker(t, map[string]sarama.MockResponse{
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).SetVersion(1).
+			SetOffset(testTopic, 0, millis(at), 12).
+			// no message at or after the time on partition 1
+			SetOffset(testTopic, 1, millis(at), -1).
+			SetOffset(testTopic, 1, sarama.OffsetNewest, 99),
+		"OffsetCommitRequest": sarama.NewMockOffsetCommitResponse(t),
+	})
+
+	cp := &Checkpoint{Group: testGroup, Offsets: []Offset{
+		{Topic: testTopic, Partition: 0, Offset: 42},
+		{Topic: testTopic, Partition: 1, Offset: 7},
+	}}
+	if err := Import(b, cp, ImportAt(at)); err != nil {
+		t.Fatal(err)
+	}
+	if _, offsets := committed(t, mb); offsets[0] != 12 || offsets[1] != 99 {
+		t.Fatalf("Expected the offsets at the time, the newest without messages after it, got %v", offsets)
+	}
+}
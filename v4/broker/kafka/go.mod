@@ -4,6 +4,7 @@ go 1.17
 
 require (
 	github.com/Shopify/sarama v1.30.1
+	github.com/go-micro/plugins/v4/util/expiry v1.0.0
 	github.com/google/uuid v1.2.0
 	go-micro.dev/v4 v4.9.0
 )
@@ -59,3 +60,5 @@ require (
 	gopkg.in/warnings.v0 v0.1.2 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
+
+replace github.com/go-micro/plugins/v4/util/expiry => ../../util/expiry
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"time"
 
 	"github.com/Shopify/sarama"
 	"github.com/google/uuid"
@@ -12,6 +13,8 @@ import (
 	"go-micro.dev/v4/codec/json"
 	log "go-micro.dev/v4/logger"
 	"go-micro.dev/v4/util/cmd"
+
+	"github.com/go-micro/plugins/v4/util/expiry"
 )
 
 type kBroker struct {
@@ -218,15 +221,26 @@ func (k *kBroker) Options() broker.Options {
 }
 
 func (k *kBroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
-	b, err := k.opts.Codec.Marshal(msg)
+	var options broker.PublishOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	m := msg
+	if ttl, ok := publishTTL(options); ok {
+		m = expiry.Set(msg, ttl, time.Now())
+	}
+
+	b, err := k.opts.Codec.Marshal(m)
 	if err != nil {
 		return err
 	}
 
 	var produceMsg = &sarama.ProducerMessage{
-		Topic:    topic,
-		Value:    sarama.ByteEncoder(b),
-		Metadata: msg,
+		Topic:     topic,
+		Value:     sarama.ByteEncoder(b),
+		Metadata:  msg,
+		Timestamp: time.Now(),
 	}
 	if k.ap != nil {
 		k.ap.Input() <- produceMsg
@@ -2,10 +2,13 @@ package kafka
 
 import (
 	"context"
+	"time"
 
 	"github.com/Shopify/sarama"
 	"go-micro.dev/v4/broker"
 	log "go-micro.dev/v4/logger"
+
+	"github.com/go-micro/plugins/v4/util/expiry"
 )
 
 var (
@@ -52,6 +55,35 @@ func SubscribeConfig(c *sarama.Config) broker.SubscribeOption {
 	return setSubscribeOption(subscribeConfigKey{}, c)
 }
 
+// HeaderExpires is the time in unix milliseconds a message published with a
+// TTL expires at.
+const HeaderExpires = expiry.Header
+
+type ttlKey struct{}
+
+// TTL drops the message in subscribers receiving it later than d after it
+// was published. The clocks of publishers and subscribers must be in sync.
+func TTL(d time.Duration) broker.PublishOption {
+	return setPublishOption(ttlKey{}, d)
+}
+
+func publishTTL(o broker.PublishOptions) (time.Duration, bool) {
+	if o.Context == nil {
+		return 0, false
+	}
+	d, ok := o.Context.Value(ttlKey{}).(time.Duration)
+	return d, ok && d > 0
+}
+
+type maxAgeKey struct{}
+
+// MaxAge drops the messages whose record timestamp is older than d, e.g. the
+// backlog of a consumer group which was down, whether they were published
+// with a TTL or not.
+func MaxAge(d time.Duration) broker.SubscribeOption {
+	return setSubscribeOption(maxAgeKey{}, d)
+}
+
 // consumerGroupHandler is the implementation of sarama.ConsumerGroupHandler.
 type consumerGroupHandler struct {
 	handler broker.Handler
@@ -95,6 +127,12 @@ func (h *consumerGroupHandler) ConsumeClaim(sess sarama.ConsumerGroupSession, cl
 			m.Header["Content-Type"] = "application/json" // default to json codec
 		}
 
+		if h.expired(msg, &m, time.Now()) {
+			log.Debugf("[kafka]: dropping expired message at offset %d of %s", msg.Offset, msg.Topic)
+			sess.MarkMessage(msg, "")
+			continue
+		}
+
 		err := h.handler(p)
 		if err == nil && h.subopts.AutoAck {
 			sess.MarkMessage(msg, "")
@@ -109,3 +147,17 @@ func (h *consumerGroupHandler) ConsumeClaim(sess sarama.ConsumerGroupSession, cl
 	}
 	return nil
 }
+
+// expired reports whether the message passed its expiry or is older than the
+// max age of the subscriber. Records without a timestamp have no age.
+func (h *consumerGroupHandler) expired(msg *sarama.ConsumerMessage, m *broker.Message, now time.Time) bool {
+	if expiry.Expired(m, now) {
+		return true
+	}
+
+	if msg.Timestamp.IsZero() || h.subopts.Context == nil {
+		return false
+	}
+	d, ok := h.subopts.Context.Value(maxAgeKey{}).(time.Duration)
+	return ok && d > 0 && now.Sub(msg.Timestamp) > d
+}
@@ -0,0 +1,72 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"go-micro.dev/v4/broker"
+
+	"github.com/go-micro/plugins/v4/util/expiry"
+)
+
+func TestExpired(t *testing.T) {
+	now := time.Now()
+	expires := func(d time.Duration) map[string]string {
+		return expiry.Set(&broker.Message{}, d, now).Header
+	}
+
+	testcases := []struct {
+		name    string
+		age     time.Duration
+		header  map[string]string
+		maxAge  time.Duration
+		expired bool
+	}{
+		{"no ttl", time.Hour, map[string]string{}, 0, false},
+		{"within ttl", time.Second, expires(time.Minute), 0, false},
+		{"past ttl", 2 * time.Minute, expires(-time.Minute), 0, true},
+		{"invalid ttl", time.Hour, map[string]string{HeaderExpires: "soon"}, 0, false},
+		{"within max age", time.Second, map[string]string{}, time.Minute, false},
+		{"past max age", 2 * time.Minute, map[string]string{}, time.Minute, true},
+		{"past max age within ttl", 2 * time.Minute, expires(10 * time.Minute), time.Minute, true},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			h := &consumerGroupHandler{}
+			if test.maxAge > 0 {
+				h.subopts.Context = context.WithValue(context.Background(), maxAgeKey{}, test.maxAge)
+			}
+
+			msg := &sarama.ConsumerMessage{Timestamp: now.Add(-test.age)}
+			if have := h.expired(msg, &broker.Message{Header: test.header}, now); have != test.expired {
+				t.Errorf("Expected expired %t, have %t", test.expired, have)
+			}
+		})
+	}
+
+	// records without a timestamp have no age, but expire by their header
+	h := &consumerGroupHandler{subopts: broker.SubscribeOptions{
+		Context: context.WithValue(context.Background(), maxAgeKey{}, time.Minute),
+	}}
+	if h.expired(&sarama.ConsumerMessage{}, &broker.Message{Header: map[string]string{}}, now) {
+		t.Error("Expected a record without a timestamp to be kept")
+	}
+	if !h.expired(&sarama.ConsumerMessage{}, &broker.Message{Header: expires(-time.Second)}, now) {
+		t.Error("Expected a record without a timestamp past its expiry to be dropped")
+	}
+}
+
+func TestPublishTTL(t *testing.T) {
+	var o broker.PublishOptions
+	if _, ok := publishTTL(o); ok {
+		t.Fatal("Expected no TTL without options")
+	}
+
+	TTL(time.Minute)(&o)
+	if d, ok := publishTTL(o); !ok || d != time.Minute {
+		t.Fatalf("Expected a TTL of a minute, got %v %t", d, ok)
+	}
+}
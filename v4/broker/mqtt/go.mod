@@ -3,6 +3,7 @@ module github.com/go-micro/plugins/v4/broker/mqtt
 go 1.17
 
 require (
+	github.com/eclipse/paho.golang v0.10.0
 	github.com/eclipse/paho.mqtt.golang v1.3.5
 	go-micro.dev/v4 v4.9.0
 )
@@ -113,8 +113,8 @@ func setAddrs(addrs []string) []string {
 func newClient(addrs []string, opts broker.Options) mqtt.Client {
 	// create opts
 	cOpts := mqtt.NewClientOptions()
-	cOpts.SetClientID(fmt.Sprintf("%d%d", time.Now().UnixNano(), rand.Intn(10)))
-	cOpts.SetCleanSession(false)
+	cOpts.SetClientID(newClientID(opts))
+	cOpts.SetCleanSession(getCleanStart(opts))
 
 	// setup tls
 	if opts.TLSConfig != nil {
@@ -129,6 +129,14 @@ func newClient(addrs []string, opts broker.Options) mqtt.Client {
 	return mqtt.NewClient(cOpts)
 }
 
+// newClientID returns the configured client id or a random one.
+func newClientID(opts broker.Options) string {
+	if id := getClientID(opts); len(id) > 0 {
+		return id
+	}
+	return fmt.Sprintf("%d%d", time.Now().UnixNano(), rand.Intn(10))
+}
+
 func newBroker(opts ...broker.Option) broker.Broker {
 	options := broker.Options{
 		// Default codec
@@ -139,6 +147,10 @@ func newBroker(opts ...broker.Option) broker.Broker {
 		o(&options)
 	}
 
+	if getProtocolVersion(options) == 5 {
+		return newBroker5(options)
+	}
+
 	addrs := setAddrs(options.Addrs)
 	client := newClient(addrs, options)
 
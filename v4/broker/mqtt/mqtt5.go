@@ -0,0 +1,609 @@
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse/paho.golang/paho"
+	"go-micro.dev/v4/broker"
+	log "go-micro.dev/v4/logger"
+)
+
+var (
+	// DefaultKeepAlive is the keep alive interval in seconds of MQTT v5 connections.
+	DefaultKeepAlive uint16 = 30
+	// DefaultTimeout is how long MQTT v5 operations wait for the server.
+	DefaultTimeout = 10 * time.Second
+	// DefaultRedeliveryExpiry is how long the server keeps the session of a
+	// lost connection without SessionExpiry, so the messages which weren't
+	// acknowledged are redelivered once reconnected.
+	DefaultRedeliveryExpiry = time.Minute
+	// DefaultRetries is how often a message is handed again to the
+	// subscribers whose handler failed, before it is acknowledged anyway.
+	DefaultRetries = 3
+	// DefaultRetryDelay is the delay of the first retry, doubled for each
+	// following one.
+	DefaultRetryDelay = 100 * time.Millisecond
+)
+
+// mqtt5Broker is a broker using the MQTT v5 protocol. Message headers are
+// mapped to user properties and subscriptions with a queue are shared
+// subscriptions.
+type mqtt5Broker struct {
+	sync.RWMutex
+	addrs    []string
+	opts     broker.Options
+	clientID string
+
+	client    *paho.Client
+	connected bool
+	exit      chan struct{}
+	// subIDs is whether the server supports subscription identifiers
+	subIDs bool
+
+	// the subscriptions by filter and by subscription identifier
+	filters map[string]*subscription
+	ids     map[int]*subscription
+	lastID  int
+}
+
+// subscription is a filter subscribed to on the server and the subscribers
+// using it. The server delivers the messages of a shared subscription to
+// one client of the group, so they are delivered to one of the subscribers
+// in turn, and to every subscriber otherwise.
+type subscription struct {
+	id     int
+	filter string
+	subs   []*mqtt5Sub
+	next   int
+}
+
+// mqtt5Sub is a broker.Subscriber.
+type mqtt5Sub struct {
+	b      *mqtt5Broker
+	opts   broker.SubscribeOptions
+	topic  string
+	filter string
+	h      broker.Handler
+}
+
+func newBroker5(options broker.Options) broker.Broker {
+	return &mqtt5Broker{
+		opts:     options,
+		addrs:    setAddrs(options.Addrs),
+		clientID: newClientID(options),
+		filters:  make(map[string]*subscription),
+		ids:      make(map[int]*subscription),
+	}
+}
+
+// receivers returns the subscribers a message of the subscription is
+// delivered to.
+func (s *subscription) receivers() []*mqtt5Sub {
+	if len(s.subs) == 0 {
+		return nil
+	}
+
+	if !strings.HasPrefix(s.filter, "$share/") {
+		return append([]*mqtt5Sub(nil), s.subs...)
+	}
+
+	sub := s.subs[s.next%len(s.subs)]
+	s.next++
+	return []*mqtt5Sub{sub}
+}
+
+// sharedTopic returns the subscription filter for the topic, subscribers
+// with a queue use a shared subscription so messages are load balanced
+// across the group.
+func sharedTopic(topic, queue string) string {
+	if len(queue) == 0 {
+		return topic
+	}
+	return fmt.Sprintf("$share/%s/%s", queue, topic)
+}
+
+// matchTopic reports whether the topic matches the subscription filter.
+func matchTopic(filter, topic string) bool {
+	if strings.HasPrefix(filter, "$share/") {
+		parts := strings.SplitN(filter, "/", 3)
+		if len(parts) < 3 {
+			return false
+		}
+		filter = parts[2]
+	}
+
+	f := strings.Split(filter, "/")
+	t := strings.Split(topic, "/")
+
+	for i, part := range f {
+		switch {
+		case part == "#":
+			return true
+		case i >= len(t):
+			return false
+		case part == "+" || part == t[i]:
+			continue
+		default:
+			return false
+		}
+	}
+
+	return len(f) == len(t)
+}
+
+func (m *mqtt5Broker) dial() (net.Conn, error) {
+	var err error
+
+	for _, addr := range m.addrs {
+		var u *url.URL
+		u, err = url.Parse(addr)
+		if err != nil {
+			continue
+		}
+
+		var conn net.Conn
+		switch u.Scheme {
+		case "ssl":
+			config := m.opts.TLSConfig
+			if config == nil {
+				config = &tls.Config{}
+			}
+			conn, err = tls.DialWithDialer(&net.Dialer{Timeout: DefaultTimeout}, "tcp", u.Host, config)
+		case "tcp":
+			conn, err = net.DialTimeout("tcp", u.Host, DefaultTimeout)
+		default:
+			err = fmt.Errorf("scheme %s is not supported with MQTT v5", u.Scheme)
+		}
+
+		if err == nil {
+			return conn, nil
+		}
+	}
+
+	return nil, err
+}
+
+// connect establishes the connection and restores the subscriptions. The
+// session is only started clean on the first connect, reconnects resume it.
+func (m *mqtt5Broker) connect(clean bool) error {
+	conn, err := m.dial()
+	if err != nil {
+		return err
+	}
+
+	var c *paho.Client
+	c = paho.NewClient(paho.ClientConfig{
+		ClientID: m.clientID,
+		Conn:     conn,
+		Router: paho.NewSingleHandlerRouter(func(p *paho.Publish) {
+			m.route(c, p)
+		}),
+		// messages are acknowledged once handled without errors
+		EnableManualAcknowledgment: true,
+		OnClientError: func(err error) {
+			m.reconnect(c, err)
+		},
+		OnServerDisconnect: func(d *paho.Disconnect) {
+			m.reconnect(c, fmt.Errorf("server disconnected with reason code %d", d.ReasonCode))
+		},
+	})
+
+	expiry := uint32(getSessionExpiry(m.opts) / time.Second)
+	if expiry == 0 {
+		// keep the session of a lost connection to redeliver its messages,
+		// it is ended on Disconnect
+		expiry = uint32(DefaultRedeliveryExpiry / time.Second)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+
+	ca, err := c.Connect(ctx, &paho.Connect{
+		ClientID:   m.clientID,
+		KeepAlive:  DefaultKeepAlive,
+		CleanStart: clean,
+		Properties: &paho.ConnectProperties{
+			SessionExpiryInterval: &expiry,
+		},
+	})
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	if ca.ReasonCode != 0 {
+		conn.Close()
+		return fmt.Errorf("connect failed with reason code %d", ca.ReasonCode)
+	}
+
+	m.Lock()
+	m.client = c
+	m.connected = true
+	m.subIDs = ca.Properties == nil || ca.Properties.SubIDAvailable
+	subs := make([]*subscription, 0, len(m.filters))
+	for _, s := range m.filters {
+		subs = append(subs, s)
+	}
+	m.Unlock()
+
+	// the server only keeps the subscriptions if the session was resumed
+	if ca.SessionPresent {
+		return nil
+	}
+
+	for _, s := range subs {
+		if err := m.subscribe(s); err != nil {
+			log.Errorf("[mqtt] failed to restore subscription %s: %v", s.filter, err)
+		}
+	}
+
+	return nil
+}
+
+// reconnect re-establishes a lost connection until the broker is disconnected.
+func (m *mqtt5Broker) reconnect(c *paho.Client, err error) {
+	m.Lock()
+	if m.client != c || !m.connected {
+		m.Unlock()
+		return
+	}
+	m.connected = false
+	exit := m.exit
+	m.Unlock()
+
+	log.Errorf("[mqtt] connection lost: %v", err)
+
+	go func() {
+		delay := 100 * time.Millisecond
+		for {
+			select {
+			case <-exit:
+				return
+			case <-time.After(delay):
+			}
+
+			if err := m.connect(false); err == nil {
+				return
+			}
+
+			if delay *= 2; delay > 30*time.Second {
+				delay = 30 * time.Second
+			}
+		}
+	}()
+}
+
+// route delivers the message and acknowledges it. The subscribers whose
+// handler failed get it again, up to DefaultRetries times, MQTT has no way
+// to reject a single message.
+func (m *mqtt5Broker) route(c *paho.Client, p *paho.Publish) {
+	m.RLock()
+	exit := m.exit
+	m.RUnlock()
+
+	msg := newMessage(p)
+	subs := m.receivers(p)
+	delay := DefaultRetryDelay
+
+	for i := 0; ; i++ {
+		if subs = deliver(p.Topic, msg, subs); len(subs) == 0 {
+			break
+		}
+
+		if i >= DefaultRetries {
+			log.Errorf("[mqtt] handlers of %s failed %d times, dropping message", p.Topic, i+1)
+			break
+		}
+
+		select {
+		case <-exit:
+			// the session redelivers it, if kept
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+
+	if err := c.Ack(p); err != nil {
+		log.Errorf("[mqtt] failed to acknowledge message of %s: %v", p.Topic, err)
+	}
+}
+
+func newMessage(p *paho.Publish) *broker.Message {
+	header := make(map[string]string)
+	if p.Properties != nil {
+		for _, prop := range p.Properties.User {
+			header[prop.Key] = prop.Value
+		}
+		if len(p.Properties.ContentType) > 0 {
+			header["Content-Type"] = p.Properties.ContentType
+		}
+	}
+
+	return &broker.Message{
+		Header: header,
+		Body:   p.Payload,
+	}
+}
+
+// receivers returns the subscribers of the subscription the message was
+// sent for, or of every subscription matching the topic if the server
+// doesn't send subscription identifiers. Messages queued in a resumed
+// session can have the identifiers of a previous process.
+func (m *mqtt5Broker) receivers(p *paho.Publish) []*mqtt5Sub {
+	m.Lock()
+	defer m.Unlock()
+
+	var s *subscription
+	if p.Properties != nil && p.Properties.SubscriptionIdentifier != nil {
+		s = m.ids[*p.Properties.SubscriptionIdentifier]
+	}
+	if s != nil {
+		return s.receivers()
+	}
+
+	var subs []*mqtt5Sub
+	for _, s := range m.filters {
+		if matchTopic(s.filter, p.Topic) {
+			subs = append(subs, s.receivers()...)
+		}
+	}
+	return subs
+}
+
+// deliver calls the handlers of the subscribers and returns those which
+// failed.
+func deliver(topic string, msg *broker.Message, subs []*mqtt5Sub) []*mqtt5Sub {
+	var failed []*mqtt5Sub
+	for _, sub := range subs {
+		pub := &mqttPub{topic: topic, msg: msg}
+		if pub.err = sub.h(pub); pub.err != nil {
+			failed = append(failed, sub)
+			log.Error(pub.err)
+		}
+	}
+	return failed
+}
+
+func (m *mqtt5Broker) subscribe(s *subscription) error {
+	m.RLock()
+	c, subIDs := m.client, m.subIDs
+	m.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+
+	sp := &paho.Subscribe{
+		Subscriptions: map[string]paho.SubscribeOptions{
+			s.filter: {QoS: 1},
+		},
+	}
+	if subIDs {
+		id := s.id
+		sp.Properties = &paho.SubscribeProperties{SubscriptionIdentifier: &id}
+	}
+
+	sa, err := c.Subscribe(ctx, sp)
+	if err != nil {
+		return err
+	}
+
+	for _, code := range sa.Reasons {
+		if code >= 0x80 {
+			return fmt.Errorf("subscribe failed with reason code %d", code)
+		}
+	}
+
+	return nil
+}
+
+// remove removes the subscriber, and its subscription once no other
+// subscriber uses it, which it reports.
+func (m *mqtt5Broker) remove(sub *mqtt5Sub) bool {
+	m.Lock()
+	defer m.Unlock()
+
+	s, ok := m.filters[sub.filter]
+	if !ok {
+		return false
+	}
+
+	for i, v := range s.subs {
+		if v == sub {
+			s.subs = append(s.subs[:i:i], s.subs[i+1:]...)
+			break
+		}
+	}
+	if len(s.subs) > 0 {
+		return false
+	}
+
+	delete(m.filters, s.filter)
+	delete(m.ids, s.id)
+	return true
+}
+
+func (m *mqtt5Broker) Options() broker.Options {
+	return m.opts
+}
+
+func (m *mqtt5Broker) Address() string {
+	return strings.Join(m.addrs, ",")
+}
+
+func (m *mqtt5Broker) Connect() error {
+	m.Lock()
+	if m.connected {
+		m.Unlock()
+		return nil
+	}
+	m.exit = make(chan struct{})
+	m.Unlock()
+
+	return m.connect(getCleanStart(m.opts))
+}
+
+func (m *mqtt5Broker) Disconnect() error {
+	m.Lock()
+	if m.exit != nil {
+		select {
+		case <-m.exit:
+		default:
+			close(m.exit)
+		}
+	}
+
+	if !m.connected {
+		m.Unlock()
+		return nil
+	}
+
+	m.connected = false
+	c := m.client
+	// disconnecting waits for the router, which takes the lock to deliver
+	m.Unlock()
+
+	d := &paho.Disconnect{ReasonCode: 0}
+	if getSessionExpiry(m.opts) == 0 {
+		// end the session kept for redelivery
+		var expiry uint32
+		d.Properties = &paho.DisconnectProperties{SessionExpiryInterval: &expiry}
+	}
+
+	return c.Disconnect(d)
+}
+
+func (m *mqtt5Broker) Init(opts ...broker.Option) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.connected {
+		return errors.New("cannot init while connected")
+	}
+
+	for _, o := range opts {
+		o(&m.opts)
+	}
+
+	m.addrs = setAddrs(m.opts.Addrs)
+	m.clientID = newClientID(m.opts)
+
+	return nil
+}
+
+func (m *mqtt5Broker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
+	m.RLock()
+	c, connected := m.client, m.connected
+	m.RUnlock()
+
+	if !connected {
+		return errors.New("not connected")
+	}
+
+	props := &paho.PublishProperties{}
+	for k, v := range msg.Header {
+		props.User.Add(k, v)
+	}
+	if ct, ok := msg.Header["Content-Type"]; ok {
+		props.ContentType = ct
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+
+	_, err := c.Publish(ctx, &paho.Publish{
+		Topic:      topic,
+		QoS:        1,
+		Payload:    msg.Body,
+		Properties: props,
+	})
+
+	return err
+}
+
+func (m *mqtt5Broker) Subscribe(topic string, h broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	m.RLock()
+	connected := m.connected
+	m.RUnlock()
+
+	if !connected {
+		return nil, errors.New("not connected")
+	}
+
+	var options broker.SubscribeOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	sub := &mqtt5Sub{
+		b:      m,
+		opts:   options,
+		topic:  topic,
+		filter: sharedTopic(topic, options.Queue),
+		h:      h,
+	}
+
+	m.Lock()
+	s, ok := m.filters[sub.filter]
+	if !ok {
+		m.lastID++
+		s = &subscription{id: m.lastID, filter: sub.filter}
+		m.filters[s.filter] = s
+		m.ids[s.id] = s
+	}
+	s.subs = append(s.subs, sub)
+	m.Unlock()
+
+	if ok {
+		return sub, nil
+	}
+
+	if err := m.subscribe(s); err != nil {
+		m.remove(sub)
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+func (m *mqtt5Broker) String() string {
+	return "mqtt"
+}
+
+func (s *mqtt5Sub) Options() broker.SubscribeOptions {
+	return s.opts
+}
+
+func (s *mqtt5Sub) Topic() string {
+	return s.topic
+}
+
+// Unsubscribe removes the subscriber, the subscription is only removed from
+// the server once no other subscriber uses the same filter.
+func (s *mqtt5Sub) Unsubscribe() error {
+	if !s.b.remove(s) {
+		return nil
+	}
+
+	s.b.RLock()
+	c, connected := s.b.client, s.b.connected
+	s.b.RUnlock()
+
+	if !connected {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+
+	_, err := c.Unsubscribe(ctx, &paho.Unsubscribe{Topics: []string{s.filter}})
+	return err
+}
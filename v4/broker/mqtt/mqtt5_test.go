@@ -0,0 +1,409 @@
+package mqtt
+
+import (
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.golang/packets"
+	"go-micro.dev/v4/broker"
+)
+
+// fakeServer is an MQTT v5 server keeping a single session.
+type fakeServer struct {
+	l     net.Listener
+	conns chan *fakeConn
+
+	sync.Mutex
+	session bool
+}
+
+// fakeConn is a connection of a client to the fake server.
+type fakeConn struct {
+	mu sync.Mutex
+	net.Conn
+	connect     *packets.Connect
+	subs        chan *packets.Subscribe
+	acks        chan *packets.Puback
+	disconnects chan *packets.Disconnect
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	s := &fakeServer{l: l, conns: make(chan *fakeConn, 4)}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	cp, err := packets.ReadPacket(conn)
+	if err != nil || cp.Type != packets.CONNECT {
+		return
+	}
+
+	c := &fakeConn{
+		Conn:        conn,
+		connect:     cp.Content.(*packets.Connect),
+		subs:        make(chan *packets.Subscribe, 16),
+		acks:        make(chan *packets.Puback, 16),
+		disconnects: make(chan *packets.Disconnect, 1),
+	}
+
+	s.Lock()
+	present := s.session && !c.connect.CleanStart
+	s.session = true
+	s.Unlock()
+
+	c.write(&packets.Connack{Properties: &packets.Properties{}, SessionPresent: present})
+	s.conns <- c
+
+	for {
+		cp, err := packets.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+
+		switch p := cp.Content.(type) {
+		case *packets.Subscribe:
+			c.write(&packets.Suback{
+				Properties: &packets.Properties{},
+				PacketID:   p.PacketID,
+				Reasons:    make([]byte, len(p.Subscriptions)),
+			})
+			c.subs <- p
+		case *packets.Unsubscribe:
+			c.write(&packets.Unsuback{
+				Properties: &packets.Properties{},
+				PacketID:   p.PacketID,
+				Reasons:    make([]byte, len(p.Topics)),
+			})
+		case *packets.Pingreq:
+			c.write(&packets.Pingresp{})
+		case *packets.Puback:
+			c.acks <- p
+		case *packets.Disconnect:
+			c.disconnects <- p
+			return
+		}
+	}
+}
+
+func (s *fakeServer) addr() string {
+	return "tcp://" + s.l.Addr().String()
+}
+
+func (s *fakeServer) accept(t *testing.T) *fakeConn {
+	t.Helper()
+	select {
+	case c := <-s.conns:
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a connection")
+		return nil
+	}
+}
+
+func (c *fakeConn) write(p io.WriterTo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p.WriteTo(c.Conn)
+}
+
+// subscribed returns the filter and subscription identifier of the next
+// subscribe.
+func (c *fakeConn) subscribed(t *testing.T) (string, int) {
+	t.Helper()
+	select {
+	case p := <-c.subs:
+		if p.Properties == nil || p.Properties.SubscriptionIdentifier == nil {
+			t.Fatal("Expected a subscription identifier")
+		}
+		for filter := range p.Subscriptions {
+			return filter, *p.Properties.SubscriptionIdentifier
+		}
+		t.Fatal("Expected a subscription")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a subscribe")
+	}
+	return "", 0
+}
+
+func (c *fakeConn) publish(id uint16, body string, subID int, dup bool) {
+	c.write(&packets.Publish{
+		PacketID:   id,
+		QoS:        1,
+		Topic:      "orders",
+		Payload:    []byte(body),
+		Duplicate:  dup,
+		Properties: &packets.Properties{SubscriptionIdentifier: &subID},
+	})
+}
+
+func (c *fakeConn) acked(t *testing.T, id uint16) {
+	t.Helper()
+	select {
+	case p := <-c.acks:
+		if p.PacketID != id {
+			t.Fatalf("Expected the ack of %d, got %d", id, p.PacketID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the ack of %d", id)
+	}
+}
+
+func (c *fakeConn) disconnected(t *testing.T) *packets.Disconnect {
+	t.Helper()
+	select {
+	case p := <-c.disconnects:
+		return p
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a disconnect")
+		return nil
+	}
+}
+
+func received(t *testing.T, ch chan string) string {
+	t.Helper()
+	select {
+	case body := <-ch:
+		return body
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a message")
+		return ""
+	}
+}
+
+func newTestBroker5(t *testing.T, s *fakeServer) broker.Broker {
+	t.Helper()
+	b := NewBroker(ProtocolVersion(5), ClientID("mock"), broker.Addrs(s.addr()))
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestMQTT5Ack(t *testing.T) {
+	s := newFakeServer(t)
+	b := newTestBroker5(t, s)
+	c := s.accept(t)
+
+	if expiry := c.connect.Properties.SessionExpiryInterval; expiry == nil || *expiry != uint32(DefaultRedeliveryExpiry/time.Second) {
+		t.Fatalf("Expected the session to be kept for redelivery, got %v", expiry)
+	}
+
+	ch := make(chan string, 1)
+	if _, err := b.Subscribe("orders", func(e broker.Event) error {
+		ch <- string(e.Message().Body)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	_, id := c.subscribed(t)
+
+	c.publish(1, "one", id, false)
+	if body := received(t, ch); body != "one" {
+		t.Fatalf("Expected one, got %s", body)
+	}
+	c.acked(t, 1)
+
+	if err := b.Disconnect(); err != nil {
+		t.Fatal(err)
+	}
+	d := c.disconnected(t)
+	if d.Properties == nil || d.Properties.SessionExpiryInterval == nil || *d.Properties.SessionExpiryInterval != 0 {
+		t.Fatal("Expected the session to be ended on disconnect")
+	}
+}
+
+func TestMQTT5Retry(t *testing.T) {
+	s := newFakeServer(t)
+	b := newTestBroker5(t, s)
+	defer b.Disconnect()
+	c := s.accept(t)
+
+	failing := make(chan string, 2)
+	var once sync.Once
+	if _, err := b.Subscribe("orders", func(e broker.Event) error {
+		failing <- string(e.Message().Body)
+		var err error
+		once.Do(func() {
+			err = io.ErrUnexpectedEOF
+		})
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	_, id := c.subscribed(t)
+
+	other := make(chan string, 2)
+	if _, err := b.Subscribe("orders", func(e broker.Event) error {
+		other <- string(e.Message().Body)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	c.publish(1, "one", id, false)
+	for i := 0; i < 2; i++ {
+		if body := received(t, failing); body != "one" {
+			t.Fatalf("Expected one, got %s", body)
+		}
+	}
+	c.acked(t, 1)
+
+	// only the failed handler gets the message again
+	if body := received(t, other); body != "one" {
+		t.Fatalf("Expected one, got %s", body)
+	}
+	select {
+	case body := <-other:
+		t.Fatalf("Expected no other message, got %s", body)
+	default:
+	}
+}
+
+func TestMQTT5RetryLimit(t *testing.T) {
+	s := newFakeServer(t)
+	b := newTestBroker5(t, s)
+	defer b.Disconnect()
+	c := s.accept(t)
+
+	ch := make(chan string, 2*(DefaultRetries+1))
+	if _, err := b.Subscribe("orders", func(e broker.Event) error {
+		ch <- string(e.Message().Body)
+		return io.ErrUnexpectedEOF
+	}); err != nil {
+		t.Fatal(err)
+	}
+	_, id := c.subscribed(t)
+
+	// the message is dropped once retried, without losing the connection
+	c.publish(1, "one", id, false)
+	c.acked(t, 1)
+	if len(ch) != DefaultRetries+1 {
+		t.Fatalf("Expected %d deliveries, got %d", DefaultRetries+1, len(ch))
+	}
+
+	c.publish(2, "two", id, false)
+	c.acked(t, 2)
+	select {
+	case <-c.disconnects:
+		t.Fatal("Expected the connection to be kept")
+	default:
+	}
+}
+
+func TestMQTT5DisconnectInFlight(t *testing.T) {
+	delay := DefaultRetryDelay
+	DefaultRetryDelay = time.Minute
+	defer func() { DefaultRetryDelay = delay }()
+
+	s := newFakeServer(t)
+	b := newTestBroker5(t, s)
+	c := s.accept(t)
+
+	ch := make(chan string, 1)
+	if _, err := b.Subscribe("orders", func(e broker.Event) error {
+		ch <- string(e.Message().Body)
+		return io.ErrUnexpectedEOF
+	}); err != nil {
+		t.Fatal(err)
+	}
+	_, id := c.subscribed(t)
+
+	c.publish(1, "one", id, false)
+	received(t, ch)
+
+	// the message waiting for its retry doesn't block the disconnect
+	done := make(chan error, 1)
+	go func() { done <- b.Disconnect() }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the disconnect to return")
+	}
+
+	c.disconnected(t)
+	select {
+	case p := <-c.acks:
+		t.Fatalf("Expected no ack, got the ack of %d", p.PacketID)
+	default:
+	}
+}
+
+func TestMQTT5SharedSubscriptions(t *testing.T) {
+	s := newFakeServer(t)
+	b := newTestBroker5(t, s)
+	defer b.Disconnect()
+	c := s.accept(t)
+
+	subscribe := func(opts ...broker.SubscribeOption) chan string {
+		ch := make(chan string, 4)
+		if _, err := b.Subscribe("orders", func(e broker.Event) error {
+			ch <- string(e.Message().Body)
+			return nil
+		}, opts...); err != nil {
+			t.Fatal(err)
+		}
+		return ch
+	}
+
+	first := subscribe(broker.Queue("workers"))
+	filter, shared := c.subscribed(t)
+	if filter != "$share/workers/orders" {
+		t.Fatalf("Expected the shared subscription, got %s", filter)
+	}
+
+	// the second subscriber of the group uses the same subscription
+	second := subscribe(broker.Queue("workers"))
+	all := subscribe()
+	filter, plain := c.subscribed(t)
+	if filter != "orders" || plain == shared {
+		t.Fatalf("Expected a subscription of its own, got %s %d", filter, plain)
+	}
+
+	for i, body := range []string{"one", "two", "three", "four"} {
+		c.publish(uint16(i+1), body, shared, false)
+		c.acked(t, uint16(i+1))
+	}
+	c.publish(5, "five", plain, false)
+	c.acked(t, 5)
+
+	for _, test := range []struct {
+		ch     chan string
+		bodies []string
+	}{
+		{first, []string{"one", "three"}},
+		{second, []string{"two", "four"}},
+		{all, []string{"five"}},
+	} {
+		for _, body := range test.bodies {
+			if have := received(t, test.ch); have != body {
+				t.Fatalf("Expected %s, got %s", body, have)
+			}
+		}
+		select {
+		case body := <-test.ch:
+			t.Fatalf("Expected no other message, got %s", body)
+		default:
+		}
+	}
+}
@@ -3,6 +3,7 @@ package mqtt
 import (
 	"testing"
 
+	"github.com/eclipse/paho.golang/paho"
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"go-micro.dev/v4/broker"
 )
@@ -87,3 +88,93 @@ func TestMQTT(t *testing.T) {
 
 	b.(*mqttBroker).client.Disconnect(0)
 }
+
+func TestMQTT5Topics(t *testing.T) {
+	testcases := []struct {
+		filter string
+		topic  string
+		match  bool
+	}{
+		{"mock", "mock", true},
+		{"mock", "other", false},
+		{"mock/+", "mock/one", true},
+		{"mock/+", "mock/one/two", false},
+		{"mock/#", "mock/one/two", true},
+		{"$share/group/mock/+", "mock/one", true},
+		{"$share/group/mock", "group/mock", false},
+	}
+
+	for _, test := range testcases {
+		if have := matchTopic(test.filter, test.topic); have != test.match {
+			t.Errorf("%s %s: expected match %t, have %t", test.filter, test.topic, test.match, have)
+		}
+	}
+
+	if have := sharedTopic("mock", "group"); have != "$share/group/mock" {
+		t.Errorf("Expected shared subscription, have %s", have)
+	}
+
+	if have := sharedTopic("mock", ""); have != "mock" {
+		t.Errorf("Expected plain subscription, have %s", have)
+	}
+}
+
+func TestMQTT5Route(t *testing.T) {
+	b, ok := NewBroker(ProtocolVersion(5), ClientID("mock")).(*mqtt5Broker)
+	if !ok {
+		t.Fatal("Expected MQTT v5 broker")
+	}
+
+	if b.clientID != "mock" {
+		t.Fatalf("Expected client id mock got %s", b.clientID)
+	}
+
+	done := make(chan broker.Event, 1)
+	sub := &mqtt5Sub{
+		b:      b,
+		topic:  "mock",
+		filter: sharedTopic("mock", "group"),
+		h: func(e broker.Event) error {
+			done <- e
+			return nil
+		},
+	}
+	s := &subscription{id: 1, filter: sub.filter, subs: []*mqtt5Sub{sub}}
+	b.filters[s.filter] = s
+	b.ids[s.id] = s
+
+	props := &paho.PublishProperties{ContentType: "application/json"}
+	props.User.Add("Micro-Id", "1")
+
+	p := &paho.Publish{
+		Topic:      "mock",
+		Payload:    []byte(`hello`),
+		Properties: props,
+	}
+	if failed := deliver(p.Topic, newMessage(p), b.receivers(p)); len(failed) != 0 {
+		t.Fatal("Expected the handler to succeed")
+	}
+
+	e := <-done
+
+	if string(e.Message().Body) != "hello" {
+		t.Fatalf("Expected `hello` message got %s", string(e.Message().Body))
+	}
+
+	if id := e.Message().Header["Micro-Id"]; id != "1" {
+		t.Fatalf("Expected header Micro-Id 1 got %s", id)
+	}
+
+	if ct := e.Message().Header["Content-Type"]; ct != "application/json" {
+		t.Fatalf("Expected header Content-Type application/json got %s", ct)
+	}
+
+	// unsubscribing while not connected only removes the subscriber
+	if err := sub.Unsubscribe(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(b.filters) != 0 || len(b.ids) != 0 {
+		t.Fatal("Expected subscriber to be removed")
+	}
+}
@@ -0,0 +1,85 @@
+package mqtt
+
+import (
+	"context"
+	"time"
+
+	"go-micro.dev/v4/broker"
+)
+
+type protocolVersionKey struct{}
+type clientIDKey struct{}
+type cleanStartKey struct{}
+type sessionExpiryKey struct{}
+
+// ProtocolVersion sets the MQTT protocol version, either 4 (3.1.1, the default) or 5.
+// With version 5 message headers are sent as user properties and the payload
+// is the message body, instead of encoding the whole message with the codec.
+func ProtocolVersion(v int) broker.Option {
+	return setBrokerOption(protocolVersionKey{}, v)
+}
+
+// ClientID sets a fixed client id. A stable id is required to resume the
+// session after a restart, by default a random id is used.
+func ClientID(id string) broker.Option {
+	return setBrokerOption(clientIDKey{}, id)
+}
+
+// CleanStart sets whether the server discards any existing session on connect.
+// With protocol version 5 reconnects resume the session. Defaults to false.
+func CleanStart(b bool) broker.Option {
+	return setBrokerOption(cleanStartKey{}, b)
+}
+
+// SessionExpiry sets how long the server keeps the session, including its
+// subscriptions and queued messages, after the client disconnects. Only
+// supported with protocol version 5.
+func SessionExpiry(d time.Duration) broker.Option {
+	return setBrokerOption(sessionExpiryKey{}, d)
+}
+
+// setBrokerOption returns a function to setup a context with given value.
+func setBrokerOption(k, v interface{}) broker.Option {
+	return func(o *broker.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+func getProtocolVersion(opts broker.Options) int {
+	if opts.Context != nil {
+		if v, ok := opts.Context.Value(protocolVersionKey{}).(int); ok {
+			return v
+		}
+	}
+	return 4
+}
+
+func getClientID(opts broker.Options) string {
+	if opts.Context != nil {
+		if id, ok := opts.Context.Value(clientIDKey{}).(string); ok && len(id) > 0 {
+			return id
+		}
+	}
+	return ""
+}
+
+func getCleanStart(opts broker.Options) bool {
+	if opts.Context != nil {
+		if b, ok := opts.Context.Value(cleanStartKey{}).(bool); ok {
+			return b
+		}
+	}
+	return false
+}
+
+func getSessionExpiry(opts broker.Options) time.Duration {
+	if opts.Context != nil {
+		if d, ok := opts.Context.Value(sessionExpiryKey{}).(time.Duration); ok {
+			return d
+		}
+	}
+	return 0
+}
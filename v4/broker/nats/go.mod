@@ -3,6 +3,7 @@ module github.com/go-micro/plugins/v4/broker/nats
 go 1.17
 
 require (
+	github.com/go-micro/plugins/v4/util/expiry v1.0.0
 	github.com/nats-io/nats.go v1.16.0
 	go-micro.dev/v4 v4.9.0
 )
@@ -52,3 +53,5 @@ require (
 	gopkg.in/tomb.v1 v1.0.0-20141024135613-dd632973f1e7 // indirect
 	gopkg.in/warnings.v0 v0.1.2 // indirect
 )
+
+replace github.com/go-micro/plugins/v4/util/expiry => ../../util/expiry
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"strings"
 	"sync"
+	"time"
 
 	nats "github.com/nats-io/nats.go"
 	"go-micro.dev/v4/broker"
@@ -13,6 +14,8 @@ import (
 	"go-micro.dev/v4/logger"
 	"go-micro.dev/v4/registry"
 	"go-micro.dev/v4/util/cmd"
+
+	"github.com/go-micro/plugins/v4/util/expiry"
 )
 
 func init() {
@@ -180,7 +183,12 @@ func (n *natsBroker) Publish(topic string, msg *broker.Message, opts ...broker.P
 		return errors.New("not connected")
 	}
 
-	b, err := n.opts.Codec.Marshal(msg)
+	var options broker.PublishOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	b, err := n.opts.Codec.Marshal(withExpiry(msg, options))
 	if err != nil {
 		return err
 	}
@@ -219,6 +227,10 @@ func (n *natsBroker) Subscribe(topic string, handler broker.Handler, opts ...bro
 			}
 			return
 		}
+		if expiry.Expired(&m, time.Now()) {
+			n.opts.Logger.Logf(logger.DebugLevel, "dropping expired message on %s", msg.Subject)
+			return
+		}
 		if err := handler(pub); err != nil {
 			pub.err = err
 			n.opts.Logger.Log(logger.ErrorLevel, err)
@@ -3,9 +3,12 @@ package nats
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	nats "github.com/nats-io/nats.go"
 	"go-micro.dev/v4/broker"
+
+	"github.com/go-micro/plugins/v4/util/expiry"
 )
 
 var addrTestCases = []struct {
@@ -94,3 +97,28 @@ func TestInitAddrs(t *testing.T) {
 		})
 	}
 }
+
+func TestExpiry(t *testing.T) {
+	msg := &broker.Message{Header: map[string]string{"Foo": "bar"}}
+
+	if m := withExpiry(msg, broker.PublishOptions{}); m != msg || expiry.Expired(m, time.Now()) {
+		t.Fatal("Expected message without ttl to be published as is")
+	}
+
+	var opts broker.PublishOptions
+	TTL(time.Minute)(&opts)
+
+	m := withExpiry(msg, opts)
+	if _, ok := msg.Header[HeaderExpires]; ok {
+		t.Fatal("Expected published message to be left alone")
+	}
+	if m.Header["Foo"] != "bar" {
+		t.Fatal("Expected headers to be kept")
+	}
+	if expiry.Expired(m, time.Now()) {
+		t.Fatal("Expected message not to be expired yet")
+	}
+	if !expiry.Expired(m, time.Now().Add(2*time.Minute)) {
+		t.Fatal("Expected message to be expired")
+	}
+}
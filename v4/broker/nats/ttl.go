@@ -0,0 +1,36 @@
+package nats
+
+import (
+	"time"
+
+	"go-micro.dev/v4/broker"
+
+	"github.com/go-micro/plugins/v4/util/expiry"
+)
+
+// HeaderExpires is the time in unix milliseconds a message published with a
+// TTL expires at.
+const HeaderExpires = expiry.Header
+
+type ttlKey struct{}
+
+// TTL drops the message in subscribers receiving it later than d after it
+// was published, e.g. by a subscriber which was busy or reconnecting. The
+// clocks of publishers and subscribers must be in sync.
+func TTL(d time.Duration) broker.PublishOption {
+	return setPublishOption(ttlKey{}, d)
+}
+
+// withExpiry returns a copy of the message with the expiry header set if the
+// options have a TTL.
+func withExpiry(msg *broker.Message, opts broker.PublishOptions) *broker.Message {
+	if opts.Context == nil {
+		return msg
+	}
+	ttl, ok := opts.Context.Value(ttlKey{}).(time.Duration)
+	if !ok || ttl <= 0 {
+		return msg
+	}
+
+	return expiry.Set(msg, ttl, time.Now())
+}
@@ -88,6 +88,27 @@ func (n *nsqBroker) configure(ctx context.Context) {
 			cfgFlag.Set(opt)
 		}
 	}
+
+	if v, ok := ctx.Value(authSecretKey{}).(string); ok {
+		n.config.AuthSecret = v
+	}
+
+	if n.opts.Secure || n.opts.TLSConfig != nil {
+		n.config.TlsV1 = true
+		n.config.TlsConfig = n.opts.TLSConfig
+	}
+}
+
+// backoff returns the delay of the given delivery attempt.
+func (d requeueDelay) backoff(attempts uint16) time.Duration {
+	delay := d.base
+	for i := uint16(1); i < attempts && delay < d.max; i++ {
+		delay *= 2
+	}
+	if delay > d.max {
+		delay = d.max
+	}
+	return delay
 }
 
 func (n *nsqBroker) Options() broker.Options {
@@ -235,6 +256,8 @@ func (n *nsqBroker) Subscribe(topic string, handler broker.Handler, opts ...brok
 	}
 
 	concurrency, maxInFlight := DefaultConcurrentHandlers, DefaultConcurrentHandlers
+	maxAttempts := n.config.MaxAttempts
+	delay := requeueDelay{base: n.config.DefaultRequeueDelay, max: n.config.MaxRequeueDelay}
 	if options.Context != nil {
 		if v, ok := options.Context.Value(concurrentHandlerKey{}).(int); ok {
 			maxInFlight, concurrency = v, v
@@ -242,6 +265,12 @@ func (n *nsqBroker) Subscribe(topic string, handler broker.Handler, opts ...brok
 		if v, ok := options.Context.Value(maxInFlightKey{}).(int); ok {
 			maxInFlight = v
 		}
+		if v, ok := options.Context.Value(maxAttemptsKey{}).(uint16); ok {
+			maxAttempts = v
+		}
+		if v, ok := options.Context.Value(requeueDelayKey{}).(requeueDelay); ok {
+			delay = v
+		}
 	}
 	channel := options.Queue
 	if len(channel) == 0 {
@@ -249,6 +278,7 @@ func (n *nsqBroker) Subscribe(topic string, handler broker.Handler, opts ...brok
 	}
 	config := *n.config
 	config.MaxInFlight = maxInFlight
+	config.MaxAttempts = maxAttempts
 
 	c, err := nsq.NewConsumer(topic, channel, &config)
 	if err != nil {
@@ -268,6 +298,17 @@ func (n *nsqBroker) Subscribe(topic string, handler broker.Handler, opts ...brok
 
 		p := &publication{topic: topic, nm: nm, m: &m}
 		p.err = handler(p)
+
+		// requeue unhandled messages backing off the consumer, the message
+		// is dropped after the last attempt
+		if p.err != nil && !nm.HasResponded() {
+			if maxAttempts > 0 && nm.Attempts >= maxAttempts {
+				nm.Finish()
+			} else {
+				nm.Requeue(delay.backoff(nm.Attempts))
+			}
+		}
+
 		return p.err
 	})
 
@@ -14,6 +14,15 @@ type asyncPublishKey struct{}
 type deferredPublishKey struct{}
 type lookupdAddrsKey struct{}
 type consumerOptsKey struct{}
+type authSecretKey struct{}
+type maxAttemptsKey struct{}
+type requeueDelayKey struct{}
+
+// requeueDelay is the base and maximum delay of a requeued message.
+type requeueDelay struct {
+	base time.Duration
+	max  time.Duration
+}
 
 func WithConcurrentHandlers(n int) broker.SubscribeOption {
 	return func(o *broker.SubscribeOptions) {
@@ -62,3 +71,35 @@ func WithConsumerOpts(consumerOpts []string) broker.Option {
 		o.Context = context.WithValue(o.Context, consumerOptsKey{}, consumerOpts)
 	}
 }
+
+// WithAuthSecret sets the secret sent to nsqd if it requires authentication.
+func WithAuthSecret(secret string) broker.Option {
+	return func(o *broker.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, authSecretKey{}, secret)
+	}
+}
+
+// WithMaxAttempts sets how often a message is delivered before it's finished
+// without being handled, 0 retries forever.
+func WithMaxAttempts(n uint16) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, maxAttemptsKey{}, n)
+	}
+}
+
+// WithRequeueDelay sets the delay of a message requeued after a handler error.
+// The delay doubles with every attempt up to max.
+func WithRequeueDelay(base, max time.Duration) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, requeueDelayKey{}, requeueDelay{base: base, max: max})
+	}
+}
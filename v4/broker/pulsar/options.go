@@ -0,0 +1,116 @@
+package pulsar
+
+import (
+	"context"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"go-micro.dev/v4/broker"
+)
+
+type authenticationKey struct{}
+
+// Authentication sets the authentication of the client, e.g.
+// pulsar.NewAuthenticationToken.
+func Authentication(auth pulsar.Authentication) broker.Option {
+	return setBrokerOption(authenticationKey{}, auth)
+}
+
+type tlsTrustCertsFileKey struct{}
+
+// TLSTrustCertsFile sets the PEM file of the CA certificates the broker
+// certificate is verified with, the pulsar client doesn't take the RootCAs of
+// a TLS config.
+func TLSTrustCertsFile(path string) broker.Option {
+	return setBrokerOption(tlsTrustCertsFileKey{}, path)
+}
+
+type tenantKey struct{}
+
+// Tenant sets the tenant of topics which aren't fully qualified. Defaults to
+// "public".
+func Tenant(tenant string) broker.Option {
+	return setBrokerOption(tenantKey{}, tenant)
+}
+
+type namespaceKey struct{}
+
+// Namespace sets the namespace of topics which aren't fully qualified.
+// Defaults to "default".
+func Namespace(namespace string) broker.Option {
+	return setBrokerOption(namespaceKey{}, namespace)
+}
+
+type operationTimeoutKey struct{}
+
+// OperationTimeout sets the timeout of producer and consumer operations.
+func OperationTimeout(d time.Duration) broker.Option {
+	return setBrokerOption(operationTimeoutKey{}, d)
+}
+
+type keyKey struct{}
+
+// Key sets the key of the published message. Messages with the same key are
+// delivered in order to the same consumer of a key shared subscription.
+func Key(key string) broker.PublishOption {
+	return setPublishOption(keyKey{}, key)
+}
+
+type subscriptionTypeKey struct{}
+
+// SubscriptionType sets the type of the subscription, Shared, Failover,
+// KeyShared or Exclusive. Defaults to Shared for queues.
+func SubscriptionType(t pulsar.SubscriptionType) broker.SubscribeOption {
+	return setSubscribeOption(subscriptionTypeKey{}, t)
+}
+
+type nackRedeliveryDelayKey struct{}
+
+// NackRedeliveryDelay sets the delay after which a message which wasn't
+// handled is redelivered. Defaults to one minute.
+func NackRedeliveryDelay(d time.Duration) broker.SubscribeOption {
+	return setSubscribeOption(nackRedeliveryDelayKey{}, d)
+}
+
+type deadLetterPolicyKey struct{}
+
+// DeadLetterPolicy moves messages to the dead letter topic after they were
+// delivered maxDeliveries times. An empty topic defaults to
+// <topic>-<subscription>-DLQ. It's only supported by shared and key shared
+// subscriptions.
+func DeadLetterPolicy(maxDeliveries uint32, topic string) broker.SubscribeOption {
+	return setSubscribeOption(deadLetterPolicyKey{}, &pulsar.DLQPolicy{
+		MaxDeliveries:   maxDeliveries,
+		DeadLetterTopic: topic,
+	})
+}
+
+// setBrokerOption returns a function to setup a context with given value.
+func setBrokerOption(k, v interface{}) broker.Option {
+	return func(o *broker.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+// setPublishOption returns a function to setup a context with given value.
+func setPublishOption(k, v interface{}) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+// setSubscribeOption returns a function to setup a context with given value.
+func setSubscribeOption(k, v interface{}) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
@@ -0,0 +1,436 @@
+// Package pulsar provides an Apache Pulsar broker
+package pulsar
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/google/uuid"
+	"go-micro.dev/v4/broker"
+	"go-micro.dev/v4/logger"
+	"go-micro.dev/v4/util/cmd"
+)
+
+var (
+	// DefaultAddress is the address of the pulsar service.
+	DefaultAddress = "pulsar://127.0.0.1:6650"
+	// DefaultTenant is the tenant of topics which aren't fully qualified.
+	DefaultTenant = "public"
+	// DefaultNamespace is the namespace of topics which aren't fully qualified.
+	DefaultNamespace = "default"
+
+	// ErrNotConnected is returned when publishing or subscribing before Connect.
+	ErrNotConnected = errors.New("pulsar: not connected")
+)
+
+type pulsarBroker struct {
+	opts broker.Options
+
+	sync.RWMutex
+	client    pulsar.Client
+	producers map[string]pulsar.Producer
+}
+
+type subscriber struct {
+	topic     string
+	opts      broker.SubscribeOptions
+	consumer  pulsar.Consumer
+	ephemeral bool
+
+	once sync.Once
+	exit chan struct{}
+}
+
+type publication struct {
+	topic    string
+	m        *broker.Message
+	msg      pulsar.Message
+	consumer pulsar.Consumer
+	acked    bool
+	err      error
+}
+
+func init() {
+	cmd.DefaultBrokers["pulsar"] = NewBroker
+}
+
+func (p *publication) Topic() string {
+	return p.topic
+}
+
+func (p *publication) Message() *broker.Message {
+	return p.m
+}
+
+func (p *publication) Ack() error {
+	p.acked = true
+	p.consumer.Ack(p.msg)
+	return nil
+}
+
+func (p *publication) Error() error {
+	return p.err
+}
+
+func (s *subscriber) Options() broker.SubscribeOptions {
+	return s.opts
+}
+
+func (s *subscriber) Topic() string {
+	return s.topic
+}
+
+func (s *subscriber) Unsubscribe() error {
+	var err error
+	s.once.Do(func() {
+		close(s.exit)
+		// ephemeral subscriptions are deleted, others keep their backlog
+		if s.ephemeral {
+			err = s.consumer.Unsubscribe()
+		}
+		s.consumer.Close()
+	})
+	return err
+}
+
+func (s *subscriber) run(h broker.Handler) {
+	for {
+		select {
+		case <-s.exit:
+			return
+		case cm, ok := <-s.consumer.Chan():
+			if !ok {
+				return
+			}
+			s.handle(cm.Message, h)
+		}
+	}
+}
+
+func (s *subscriber) handle(msg pulsar.Message, h broker.Handler) {
+	p := &publication{
+		topic:    s.topic,
+		m:        fromMessage(msg),
+		msg:      msg,
+		consumer: s.consumer,
+	}
+
+	p.err = h(p)
+
+	switch {
+	case p.err != nil && !p.acked:
+		// redelivered after the nack delay or moved to the dead letter topic
+		logger.Logf(logger.ErrorLevel, "[pulsar] handler for %s failed: %v", s.topic, p.err)
+		s.consumer.Nack(msg)
+	case p.err == nil && s.opts.AutoAck && !p.acked:
+		p.Ack()
+	}
+}
+
+// toMessage converts a broker message, headers are sent as properties.
+func toMessage(msg *broker.Message, key string) *pulsar.ProducerMessage {
+	return &pulsar.ProducerMessage{
+		Payload:    msg.Body,
+		Key:        key,
+		Properties: msg.Header,
+	}
+}
+
+func fromMessage(msg pulsar.Message) *broker.Message {
+	header := make(map[string]string, len(msg.Properties()))
+	for k, v := range msg.Properties() {
+		header[k] = v
+	}
+	return &broker.Message{
+		Header: header,
+		Body:   msg.Payload(),
+	}
+}
+
+// topic returns the fully qualified name of the topic.
+func (b *pulsarBroker) topic(name string) string {
+	if strings.Contains(name, "://") {
+		return name
+	}
+	tenant := getString(b.opts.Context, tenantKey{}, DefaultTenant)
+	namespace := getString(b.opts.Context, namespaceKey{}, DefaultNamespace)
+	return "persistent://" + tenant + "/" + namespace + "/" + name
+}
+
+func (b *pulsarBroker) Options() broker.Options {
+	return b.opts
+}
+
+func (b *pulsarBroker) Address() string {
+	if len(b.opts.Addrs) > 0 {
+		return strings.Join(b.opts.Addrs, ",")
+	}
+	return DefaultAddress
+}
+
+// url returns the service url of the configured addresses.
+func (b *pulsarBroker) url() string {
+	addrs := make([]string, 0, len(b.opts.Addrs))
+	for _, addr := range b.opts.Addrs {
+		if len(addr) == 0 {
+			continue
+		}
+		addrs = append(addrs, strings.TrimPrefix(strings.TrimPrefix(addr, "pulsar://"), "pulsar+ssl://"))
+	}
+
+	if len(addrs) == 0 {
+		addrs = []string{strings.TrimPrefix(DefaultAddress, "pulsar://")}
+	}
+
+	scheme := "pulsar://"
+	if _, ok := b.opts.Context.Value(tlsTrustCertsFileKey{}).(string); ok || b.opts.Secure || b.opts.TLSConfig != nil {
+		scheme = "pulsar+ssl://"
+	}
+
+	return scheme + strings.Join(addrs, ",")
+}
+
+// clientOptions returns the options of the client. The TLS config is mapped
+// to the TLS options of the client, settings it has none for are rejected.
+func (b *pulsarBroker) clientOptions() (pulsar.ClientOptions, error) {
+	options := pulsar.ClientOptions{
+		URL: b.url(),
+	}
+
+	if v, ok := b.opts.Context.Value(authenticationKey{}).(pulsar.Authentication); ok {
+		options.Authentication = v
+	}
+	if v, ok := b.opts.Context.Value(operationTimeoutKey{}).(time.Duration); ok {
+		options.OperationTimeout = v
+	}
+	if v, ok := b.opts.Context.Value(tlsTrustCertsFileKey{}).(string); ok {
+		options.TLSTrustCertsFilePath = v
+		options.TLSValidateHostname = true
+	}
+
+	c := b.opts.TLSConfig
+	if c == nil {
+		return options, nil
+	}
+
+	// the client only reads the CA from a file
+	if c.RootCAs != nil {
+		return options, errors.New("pulsar: the RootCAs of the TLS config aren't supported, use TLSTrustCertsFile")
+	}
+
+	options.TLSAllowInsecureConnection = c.InsecureSkipVerify
+	options.TLSValidateHostname = !c.InsecureSkipVerify
+
+	// the client certificate is the TLS authentication of the client
+	if len(c.Certificates) > 0 || c.GetClientCertificate != nil {
+		if options.Authentication != nil {
+			return options, errors.New("pulsar: the client certificate of the TLS config can't be used with another authentication")
+		}
+		options.Authentication = pulsar.NewAuthenticationFromTLSCertSupplier(func() (*tls.Certificate, error) {
+			if c.GetClientCertificate != nil {
+				return c.GetClientCertificate(&tls.CertificateRequestInfo{})
+			}
+			return &c.Certificates[0], nil
+		})
+	}
+
+	return options, nil
+}
+
+func (b *pulsarBroker) Connect() error {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.client != nil {
+		return nil
+	}
+
+	options, err := b.clientOptions()
+	if err != nil {
+		return err
+	}
+
+	client, err := pulsar.NewClient(options)
+	if err != nil {
+		return err
+	}
+
+	b.client = client
+	b.producers = make(map[string]pulsar.Producer)
+	return nil
+}
+
+func (b *pulsarBroker) Disconnect() error {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.client == nil {
+		return nil
+	}
+
+	for _, p := range b.producers {
+		p.Close()
+	}
+	b.client.Close()
+
+	b.client = nil
+	b.producers = nil
+	return nil
+}
+
+func (b *pulsarBroker) Init(opts ...broker.Option) error {
+	for _, o := range opts {
+		o(&b.opts)
+	}
+	return nil
+}
+
+// producer returns the cached producer of the topic.
+func (b *pulsarBroker) producer(topic string) (pulsar.Producer, error) {
+	b.RLock()
+	p, ok := b.producers[topic]
+	client := b.client
+	b.RUnlock()
+
+	if client == nil {
+		return nil, ErrNotConnected
+	}
+	if ok {
+		return p, nil
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	if p, ok := b.producers[topic]; ok {
+		return p, nil
+	}
+
+	p, err := client.CreateProducer(pulsar.ProducerOptions{Topic: topic})
+	if err != nil {
+		return nil, err
+	}
+	b.producers[topic] = p
+	return p, nil
+}
+
+func (b *pulsarBroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
+	options := broker.PublishOptions{
+		Context: context.Background(),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	p, err := b.producer(b.topic(topic))
+	if err != nil {
+		return err
+	}
+
+	_, err = p.Send(options.Context, toMessage(msg, getString(options.Context, keyKey{}, "")))
+	return err
+}
+
+// consumerOptions returns the consumer options of the subscription and whether
+// it's ephemeral.
+func (b *pulsarBroker) consumerOptions(topic string, options broker.SubscribeOptions) (pulsar.ConsumerOptions, bool) {
+	co := pulsar.ConsumerOptions{
+		Topic:            b.topic(topic),
+		SubscriptionName: options.Queue,
+		Type:             pulsar.Shared,
+	}
+
+	ephemeral := len(options.Queue) == 0
+	if ephemeral {
+		co.SubscriptionName = "micro-" + uuid.New().String()
+		co.Type = pulsar.Exclusive
+	}
+
+	if options.Context == nil {
+		return co, ephemeral
+	}
+
+	if v, ok := options.Context.Value(subscriptionTypeKey{}).(pulsar.SubscriptionType); ok {
+		co.Type = v
+	}
+	if v, ok := options.Context.Value(nackRedeliveryDelayKey{}).(time.Duration); ok {
+		co.NackRedeliveryDelay = v
+	}
+	if v, ok := options.Context.Value(deadLetterPolicyKey{}).(*pulsar.DLQPolicy); ok {
+		policy := *v
+		co.DLQ = &policy
+	}
+
+	return co, ephemeral
+}
+
+func (b *pulsarBroker) Subscribe(topic string, h broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	options := broker.SubscribeOptions{
+		AutoAck: true,
+		Context: context.Background(),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	b.RLock()
+	client := b.client
+	b.RUnlock()
+
+	if client == nil {
+		return nil, ErrNotConnected
+	}
+
+	co, ephemeral := b.consumerOptions(topic, options)
+
+	consumer, err := client.Subscribe(co)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &subscriber{
+		topic:     topic,
+		opts:      options,
+		consumer:  consumer,
+		ephemeral: ephemeral,
+		exit:      make(chan struct{}),
+	}
+
+	go s.run(h)
+
+	return s, nil
+}
+
+func (b *pulsarBroker) String() string {
+	return "pulsar"
+}
+
+func getString(ctx context.Context, k interface{}, def string) string {
+	if ctx == nil {
+		return def
+	}
+	if v, ok := ctx.Value(k).(string); ok && len(v) > 0 {
+		return v
+	}
+	return def
+}
+
+// NewBroker returns a new pulsar broker.
+func NewBroker(opts ...broker.Option) broker.Broker {
+	options := broker.Options{
+		Context: context.Background(),
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &pulsarBroker{
+		opts: options,
+	}
+}
@@ -0,0 +1,154 @@
+package pulsar
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"go-micro.dev/v4/broker"
+)
+
+func TestTopic(t *testing.T) {
+	b := NewBroker().(*pulsarBroker)
+
+	if got := b.topic("orders"); got != "persistent://public/default/orders" {
+		t.Fatalf("Unexpected topic %s", got)
+	}
+
+	b = NewBroker(Tenant("shop"), Namespace("billing")).(*pulsarBroker)
+
+	if got := b.topic("orders"); got != "persistent://shop/billing/orders" {
+		t.Fatalf("Unexpected topic %s", got)
+	}
+	if got := b.topic("non-persistent://shop/events/orders"); got != "non-persistent://shop/events/orders" {
+		t.Fatalf("Expected qualified topic to be kept, got %s", got)
+	}
+}
+
+func TestURL(t *testing.T) {
+	testData := []struct {
+		opts []broker.Option
+		url  string
+	}{
+		{nil, "pulsar://127.0.0.1:6650"},
+		{[]broker.Option{broker.Addrs("pulsar://a:6650", "b:6650")}, "pulsar://a:6650,b:6650"},
+		{[]broker.Option{broker.Addrs("a:6651"), broker.Secure(true)}, "pulsar+ssl://a:6651"},
+	}
+
+	for _, d := range testData {
+		b := NewBroker(d.opts...).(*pulsarBroker)
+		if got := b.url(); got != d.url {
+			t.Fatalf("Expected url %s, got %s", d.url, got)
+		}
+	}
+}
+
+func TestTLS(t *testing.T) {
+	// the broker certificate is verified by default
+	o, err := NewBroker(broker.TLSConfig(&tls.Config{})).(*pulsarBroker).clientOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(o.URL, "pulsar+ssl://") || o.TLSAllowInsecureConnection || !o.TLSValidateHostname || o.Authentication != nil {
+		t.Fatalf("Unexpected options %+v", o)
+	}
+
+	o, err = NewBroker(broker.TLSConfig(&tls.Config{InsecureSkipVerify: true})).(*pulsarBroker).clientOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !o.TLSAllowInsecureConnection || o.TLSValidateHostname {
+		t.Fatalf("Expected an insecure connection, got %+v", o)
+	}
+
+	o, err = NewBroker(TLSTrustCertsFile("ca.pem")).(*pulsarBroker).clientOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(o.URL, "pulsar+ssl://") || o.TLSTrustCertsFilePath != "ca.pem" {
+		t.Fatalf("Expected the CA file, got %+v", o)
+	}
+
+	// the client certificate authenticates the client
+	cert := tls.Certificate{Certificate: [][]byte{[]byte("cert")}}
+	o, err = NewBroker(broker.TLSConfig(&tls.Config{Certificates: []tls.Certificate{cert}})).(*pulsarBroker).clientOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Authentication == nil {
+		t.Fatal("Expected the client certificate authentication")
+	}
+
+	// settings the client can't take are rejected
+	rejected := [][]broker.Option{
+		{broker.TLSConfig(&tls.Config{RootCAs: x509.NewCertPool()})},
+		{broker.TLSConfig(&tls.Config{Certificates: []tls.Certificate{cert}}), Authentication(pulsar.NewAuthenticationToken("token"))},
+	}
+	for _, opts := range rejected {
+		if _, err := NewBroker(opts...).(*pulsarBroker).clientOptions(); err == nil {
+			t.Fatal("Expected the TLS config to be rejected")
+		}
+	}
+}
+
+func TestConsumerOptions(t *testing.T) {
+	b := NewBroker().(*pulsarBroker)
+
+	co, ephemeral := b.consumerOptions("orders", broker.SubscribeOptions{})
+	if !ephemeral || co.Type != pulsar.Exclusive || len(co.SubscriptionName) == 0 {
+		t.Fatalf("Unexpected ephemeral subscription %+v", co)
+	}
+
+	options := broker.SubscribeOptions{}
+	for _, o := range []broker.SubscribeOption{
+		broker.Queue("billing"),
+		SubscriptionType(pulsar.KeyShared),
+		NackRedeliveryDelay(time.Second),
+		DeadLetterPolicy(3, "orders-dlq"),
+	} {
+		o(&options)
+	}
+
+	co, ephemeral = b.consumerOptions("orders", options)
+	if ephemeral || co.SubscriptionName != "billing" || co.Type != pulsar.KeyShared {
+		t.Fatalf("Unexpected subscription %+v", co)
+	}
+	if co.NackRedeliveryDelay != time.Second {
+		t.Fatalf("Unexpected nack delay %v", co.NackRedeliveryDelay)
+	}
+	if co.DLQ == nil || co.DLQ.MaxDeliveries != 3 || co.DLQ.DeadLetterTopic != "orders-dlq" {
+		t.Fatalf("Unexpected dead letter policy %+v", co.DLQ)
+	}
+
+	options = broker.SubscribeOptions{}
+	broker.Queue("billing")(&options)
+
+	if co, _ = b.consumerOptions("orders", options); co.Type != pulsar.Shared {
+		t.Fatalf("Expected shared subscription for queue, got %v", co.Type)
+	}
+}
+
+func TestToMessage(t *testing.T) {
+	msg := toMessage(&broker.Message{
+		Header: map[string]string{"Micro-Topic": "orders"},
+		Body:   []byte("hello"),
+	}, "user-1")
+
+	if msg.Key != "user-1" || string(msg.Payload) != "hello" || msg.Properties["Micro-Topic"] != "orders" {
+		t.Fatalf("Unexpected message %+v", msg)
+	}
+}
+
+func TestNotConnected(t *testing.T) {
+	b := NewBroker()
+
+	if err := b.Publish("orders", &broker.Message{}); err != ErrNotConnected {
+		t.Fatalf("Expected ErrNotConnected, got %v", err)
+	}
+	if _, err := b.Subscribe("orders", func(broker.Event) error { return nil }); err != ErrNotConnected {
+		t.Fatalf("Expected ErrNotConnected, got %v", err)
+	}
+}
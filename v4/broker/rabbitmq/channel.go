@@ -155,15 +155,21 @@ func (r *rabbitMQChannel) DeclareReplyQueue(queue string) error {
 	return err
 }
 
-func (r *rabbitMQChannel) ConsumeQueue(queue string, autoAck bool) (<-chan amqp.Delivery, error) {
+// ConsumeQueue starts consuming the queue, the channel id is used as the
+// consumer tag if none is given.
+func (r *rabbitMQChannel) ConsumeQueue(queue, consumer string, autoAck bool) (<-chan amqp.Delivery, error) {
+	if len(consumer) == 0 {
+		consumer = r.uuid
+	}
+
 	return r.channel.Consume(
-		queue,   // queue
-		r.uuid,  // consumer
-		autoAck, // autoAck
-		false,   // exclusive
-		false,   // nolocal
-		false,   // nowait
-		nil,     // args
+		queue,    // queue
+		consumer, // consumer
+		autoAck,  // autoAck
+		false,    // exclusive
+		false,    // nolocal
+		false,    // nowait
+		nil,      // args
 	)
 }
 
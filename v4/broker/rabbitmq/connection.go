@@ -68,7 +68,8 @@ type rabbitMQConn struct {
 
 	waitConnection chan struct{}
 
-	logger logger.Logger
+	logger  logger.Logger
+	onEvent func(Event)
 }
 
 // Exchange is the rabbitmq exchange.
@@ -81,7 +82,29 @@ type Exchange struct {
 	Durable bool
 }
 
-func newRabbitMQConn(ex Exchange, urls []string, prefetchCount int, prefetchGlobal bool, confirmPublish bool, withoutExchange bool, logger logger.Logger) *rabbitMQConn {
+// EventType is the type of a connection event.
+type EventType int
+
+const (
+	// EventDisconnected is emitted when the connection or channel to the broker is lost.
+	EventDisconnected EventType = iota
+	// EventReconnected is emitted when the connection and exchange have been re-established.
+	EventReconnected
+	// EventResubscribed is emitted when a subscriber re-established its consumer.
+	EventResubscribed
+)
+
+// Event describes a change of the broker connection state.
+type Event struct {
+	Type EventType
+	// Topic and Queue are set for EventResubscribed
+	Topic string
+	Queue string
+	// Err is set for EventDisconnected
+	Err error
+}
+
+func newRabbitMQConn(ex Exchange, urls []string, prefetchCount int, prefetchGlobal bool, confirmPublish bool, withoutExchange bool, logger logger.Logger, onEvent func(Event)) *rabbitMQConn {
 	var url string
 
 	if len(urls) > 0 && regexp.MustCompile("^amqp(s)?://.*").MatchString(urls[0]) {
@@ -100,6 +123,7 @@ func newRabbitMQConn(ex Exchange, urls []string, prefetchCount int, prefetchGlob
 		close:           make(chan bool),
 		waitConnection:  make(chan struct{}),
 		logger:          logger,
+		onEvent:         onEvent,
 	}
 	// its bad case of nil == waitConnection, so close it at start
 	close(ret.waitConnection)
@@ -130,6 +154,7 @@ func (r *rabbitMQConn) reconnect(secure bool, config *amqp.Config) {
 		if connect {
 			// try reconnect
 			if err := r.tryConnect(secure, config); err != nil {
+				r.logger.Logf(logger.ErrorLevel, "[rabbitmq] reconnect failed: %v", err)
 				time.Sleep(1 * time.Second)
 				continue
 			}
@@ -141,6 +166,7 @@ func (r *rabbitMQConn) reconnect(secure bool, config *amqp.Config) {
 			// unblock resubscribe cycle - close channel
 			//at this point channel is created and unclosed - close it without any additional checks
 			close(r.waitConnection)
+			r.emit(Event{Type: EventReconnected})
 		}
 
 		connect = true
@@ -166,6 +192,7 @@ func (r *rabbitMQConn) reconnect(secure bool, config *amqp.Config) {
 				r.connected = false
 				r.waitConnection = make(chan struct{})
 				r.Unlock()
+				r.emit(Event{Type: EventDisconnected, Err: err})
 				chanNotifyClose = nil
 			case err := <-notifyClose:
 				r.logger.Log(logger.ErrorLevel, err)
@@ -175,6 +202,7 @@ func (r *rabbitMQConn) reconnect(secure bool, config *amqp.Config) {
 				r.connected = false
 				r.waitConnection = make(chan struct{})
 				r.Unlock()
+				r.emit(Event{Type: EventDisconnected, Err: err})
 				notifyClose = nil
 			case <-r.close:
 				return
@@ -251,17 +279,30 @@ func (r *rabbitMQConn) tryConnect(secure bool, config *amqp.Config) error {
 	}
 
 	if !r.withoutExchange {
+		// the exchange is re-declared on every (re)connect since non durable
+		// exchanges are gone after a broker restart
 		if r.exchange.Durable {
-			r.Channel.DeclareDurableExchange(r.exchange.Name)
+			err = r.Channel.DeclareDurableExchange(r.exchange.Name)
 		} else {
-			r.Channel.DeclareExchange(r.exchange)
+			err = r.Channel.DeclareExchange(r.exchange)
+		}
+		if err != nil {
+			r.Connection.Close()
+			return err
 		}
 		r.ExchangeChannel, err = newRabbitChannel(r.Connection, r.prefetchCount, r.prefetchGlobal, r.confirmPublish)
 	}
 	return err
 }
 
-func (r *rabbitMQConn) Consume(queue, key string, headers amqp.Table, qArgs amqp.Table, autoAck, durableQueue bool) (*rabbitMQChannel, <-chan amqp.Delivery, error) {
+// emit sends the event to the connection event callback, if any.
+func (r *rabbitMQConn) emit(e Event) {
+	if r.onEvent != nil {
+		r.onEvent(e)
+	}
+}
+
+func (r *rabbitMQConn) Consume(queue, key, consumerTag string, headers amqp.Table, qArgs amqp.Table, autoAck, durableQueue bool) (*rabbitMQChannel, <-chan amqp.Delivery, error) {
 	consumerChannel, err := newRabbitChannel(r.Connection, r.prefetchCount, r.prefetchGlobal, r.confirmPublish)
 	if err != nil {
 		return nil, nil, err
@@ -277,7 +318,7 @@ func (r *rabbitMQConn) Consume(queue, key string, headers amqp.Table, qArgs amqp
 		return nil, nil, err
 	}
 
-	deliveries, err := consumerChannel.ConsumeQueue(queue, autoAck)
+	deliveries, err := consumerChannel.ConsumeQueue(queue, consumerTag, autoAck)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -23,7 +23,7 @@ func TestNewRabbitMQConnURL(t *testing.T) {
 	}
 
 	for _, test := range testcases {
-		conn := newRabbitMQConn(Exchange{Name: "exchange"}, test.urls, 0, false, false, false, logger.DefaultLogger)
+		conn := newRabbitMQConn(Exchange{Name: "exchange"}, test.urls, 0, false, false, false, logger.DefaultLogger, nil)
 
 		if have, want := conn.url, test.want; have != want {
 			t.Errorf("%s: invalid url, want %q, have %q", test.title, want, have)
@@ -64,7 +64,7 @@ func TestTryToConnectTLS(t *testing.T) {
 	for _, test := range testcases {
 		dialCount, dialTLSCount = 0, 0
 
-		conn := newRabbitMQConn(Exchange{Name: "exchange"}, []string{test.url}, 0, false, false, false, logger.DefaultLogger)
+		conn := newRabbitMQConn(Exchange{Name: "exchange"}, []string{test.url}, 0, false, false, false, logger.DefaultLogger, nil)
 		conn.tryConnect(test.secure, test.amqpConfig)
 
 		have := dialCount
@@ -94,7 +94,7 @@ func TestNewRabbitMQPrefetchConfirmPublish(t *testing.T) {
 	}
 
 	for _, test := range testcases {
-		conn := newRabbitMQConn(Exchange{Name: "exchange"}, test.urls, test.prefetchCount, test.prefetchGlobal, test.confirmPublish, false, logger.DefaultLogger)
+		conn := newRabbitMQConn(Exchange{Name: "exchange"}, test.urls, test.prefetchCount, test.prefetchGlobal, test.confirmPublish, false, logger.DefaultLogger, nil)
 
 		if have, want := conn.prefetchCount, test.prefetchCount; have != want {
 			t.Errorf("%s: invalid prefetch count, want %d, have %d", test.title, want, have)
@@ -109,3 +109,26 @@ func TestNewRabbitMQPrefetchConfirmPublish(t *testing.T) {
 		}
 	}
 }
+
+func TestConnectionEvents(t *testing.T) {
+	var events []Event
+
+	b := NewBroker(ConnectionEvents(func(e Event) {
+		events = append(events, e)
+	})).(*rbroker)
+
+	conn := newRabbitMQConn(Exchange{Name: "exchange"}, nil, 0, false, false, false, logger.DefaultLogger, b.getConnectionEvents())
+	conn.emit(Event{Type: EventDisconnected, Err: errors.New("connection reset")})
+	conn.emit(Event{Type: EventReconnected})
+
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, have %d", len(events))
+	}
+
+	if have, want := events[1].Type, EventReconnected; have != want {
+		t.Errorf("invalid event type, want %d, have %d", want, have)
+	}
+
+	// no callback set
+	newRabbitMQConn(Exchange{Name: "exchange"}, nil, 0, false, false, false, logger.DefaultLogger, nil).emit(Event{})
+}
@@ -2,6 +2,7 @@ package rabbitmq
 
 import (
 	"context"
+	"strconv"
 	"time"
 
 	"go-micro.dev/v4/broker"
@@ -31,6 +32,7 @@ type userID struct{}
 type appID struct{}
 type externalAuth struct{}
 type durableExchange struct{}
+type connectionEventsKey struct{}
 
 // DurableQueue creates a durable queue when subscribing.
 func DurableQueue() broker.SubscribeOption {
@@ -122,6 +124,12 @@ func Expiration(value string) broker.PublishOption {
 	return setPublishOption(expiration{}, value)
 }
 
+// TTL sets the message expiration for publishing, RabbitMQ drops the message
+// from the queues once it's waited for longer than d.
+func TTL(d time.Duration) broker.PublishOption {
+	return setPublishOption(expiration{}, strconv.FormatInt(int64(d/time.Millisecond), 10))
+}
+
 // MessageId sets a property message identifier for publishing.
 func MessageId(value string) broker.PublishOption {
 	return setPublishOption(messageID{}, value)
@@ -147,6 +155,12 @@ func AppID(value string) broker.PublishOption {
 	return setPublishOption(appID{}, value)
 }
 
+// ConnectionEvents sets a callback which is notified when the connection is
+// lost, re-established and when subscribers recovered their consumers.
+func ConnectionEvents(fn func(Event)) broker.Option {
+	return setBrokerOption(connectionEventsKey{}, fn)
+}
+
 func ExternalAuth() broker.Option {
 	return setBrokerOption(externalAuth{}, ExternalAuthentication{})
 }
@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/streadway/amqp"
 	"go-micro.dev/v4/broker"
 	"go-micro.dev/v4/logger"
@@ -30,6 +31,7 @@ type subscriber struct {
 	unsub        chan bool
 	opts         broker.SubscribeOptions
 	topic        string
+	consumerTag  string
 	ch           *rabbitMQChannel
 	durableQueue bool
 	queueArgs    map[string]interface{}
@@ -100,6 +102,8 @@ func (s *subscriber) resubscribe() {
 	maxResubscribeDelay := 30 * time.Second
 	expFactor := time.Duration(2)
 	reSubscribeDelay := minResubscribeDelay
+	// whether the consumer was established before
+	var subscribed bool
 	// loop until unsubscribe
 	for {
 		select {
@@ -125,9 +129,12 @@ func (s *subscriber) resubscribe() {
 			continue
 		}
 
+		// the queue and binding are re-declared with the original options
+		// and the consumer keeps its tag across reconnects
 		ch, sub, err := s.r.conn.Consume(
 			s.opts.Queue,
 			s.topic,
+			s.consumerTag,
 			s.headers,
 			s.queueArgs,
 			s.opts.AutoAck,
@@ -141,7 +148,13 @@ func (s *subscriber) resubscribe() {
 			s.mtx.Lock()
 			s.ch = ch
 			s.mtx.Unlock()
+
+			if subscribed {
+				s.r.conn.emit(Event{Type: EventResubscribed, Topic: s.topic, Queue: s.opts.Queue})
+			}
+			subscribed = true
 		default:
+			s.r.opts.Logger.Logf(logger.ErrorLevel, "[rabbitmq] failed to subscribe to %s: %v", s.topic, err)
 			if reSubscribeDelay > maxResubscribeDelay {
 				reSubscribeDelay = maxResubscribeDelay
 			}
@@ -315,9 +328,14 @@ func (r *rbroker) Subscribe(topic string, handler broker.Handler, opts ...broker
 		}
 	}
 
+	consumerTag, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+
 	sret := &subscriber{topic: topic, opts: opt, unsub: make(chan bool), r: r,
 		durableQueue: durableQueue, fn: fn, headers: headers, queueArgs: qArgs,
-		wg: sync.WaitGroup{}}
+		consumerTag: consumerTag.String(), wg: sync.WaitGroup{}}
 
 	go sret.resubscribe()
 
@@ -362,6 +380,7 @@ func (r *rbroker) Connect() error {
 			r.getConfirmPublish(),
 			r.getWithoutExchange(),
 			r.opts.Logger,
+			r.getConnectionEvents(),
 		)
 	}
 
@@ -446,3 +465,10 @@ func (r *rbroker) getWithoutExchange() bool {
 	}
 	return DefaultWithoutExchange
 }
+
+func (r *rbroker) getConnectionEvents() func(Event) {
+	if e, ok := r.opts.Context.Value(connectionEventsKey{}).(func(Event)); ok {
+		return e
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// slots is the number of hash slots of a Redis cluster.
+const slots = 16384
+
+// slotRange is a range of hash slots served by a node.
+type slotRange struct {
+	start int
+	end   int
+	addr  string
+}
+
+// cluster routes channels to the nodes of a Redis cluster by their hash
+// slot. Nodes are discovered from the seed addresses with CLUSTER SLOTS.
+type cluster struct {
+	seeds   []string
+	newPool func(addr string) *redis.Pool
+
+	sync.RWMutex
+	ranges []slotRange
+	pools  map[string]*redis.Pool
+}
+
+func newCluster(seeds []string, newPool func(addr string) *redis.Pool) *cluster {
+	return &cluster{
+		seeds:   seeds,
+		newPool: newPool,
+		pools:   make(map[string]*redis.Pool),
+	}
+}
+
+// slot returns the hash slot of the channel, the CRC16 of its hash tag if it
+// has one, e.g. {orders}.created and {orders}.paid share a slot.
+func slot(channel string) int {
+	if i := strings.IndexByte(channel, '{'); i >= 0 {
+		if j := strings.IndexByte(channel[i+1:], '}'); j > 0 {
+			channel = channel[i+1 : i+1+j]
+		}
+	}
+	return int(crc16(channel) % slots)
+}
+
+// crc16 is the CRC16-CCITT (XMODEM) checksum used by Redis Cluster.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// refresh reloads the slots from the first node answering, known nodes
+// first so nodes added since the start are found.
+func (c *cluster) refresh() error {
+	c.RLock()
+	addrs := make([]string, 0, len(c.ranges)+len(c.seeds))
+	for _, r := range c.ranges {
+		addrs = append(addrs, r.addr)
+	}
+	c.RUnlock()
+	addrs = append(addrs, c.seeds...)
+
+	var err error
+	for _, addr := range addrs {
+		var ranges []slotRange
+		if ranges, err = c.slots(addr); err == nil {
+			c.Lock()
+			c.ranges = ranges
+			c.Unlock()
+			return nil
+		}
+	}
+	return fmt.Errorf("redis: cluster slots unavailable: %v", err)
+}
+
+func (c *cluster) slots(addr string) ([]slotRange, error) {
+	conn := c.pool(addr).Get()
+	defer conn.Close()
+
+	reply, err := redis.Values(conn.Do("CLUSTER", "SLOTS"))
+	if err != nil {
+		return nil, err
+	}
+	return parseSlots(addr, reply)
+}
+
+// parseSlots parses the reply of CLUSTER SLOTS sent by the node, the master
+// of every range is the first node. Nodes without an ip are the node asked.
+func parseSlots(addr string, reply []interface{}) ([]slotRange, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	host, _, _ := net.SplitHostPort(u.Host)
+
+	ranges := make([]slotRange, 0, len(reply))
+	for _, r := range reply {
+		v, err := redis.Values(r, nil)
+		if err != nil || len(v) < 3 {
+			return nil, errors.New("redis: invalid cluster slots reply")
+		}
+		start, err1 := redis.Int(v[0], nil)
+		end, err2 := redis.Int(v[1], nil)
+		node, err3 := redis.Values(v[2], nil)
+		if err1 != nil || err2 != nil || err3 != nil || len(node) < 2 {
+			return nil, errors.New("redis: invalid cluster slots reply")
+		}
+		ip, _ := redis.String(node[0], nil)
+		port, _ := redis.Int(node[1], nil)
+		if len(ip) == 0 {
+			ip = host
+		}
+
+		n := *u
+		n.Host = net.JoinHostPort(ip, strconv.Itoa(port))
+		ranges = append(ranges, slotRange{start: start, end: end, addr: n.String()})
+	}
+
+	return ranges, nil
+}
+
+// node returns the address of the node serving the slot of the channel.
+func (c *cluster) node(channel string) (string, error) {
+	s := slot(channel)
+
+	c.RLock()
+	defer c.RUnlock()
+
+	for _, r := range c.ranges {
+		if s >= r.start && s <= r.end {
+			return r.addr, nil
+		}
+	}
+	return "", fmt.Errorf("redis: no cluster node serves slot %d of %s", s, channel)
+}
+
+// pool returns the pool of the node, creating it on first use.
+func (c *cluster) pool(addr string) *redis.Pool {
+	c.Lock()
+	defer c.Unlock()
+
+	p, ok := c.pools[addr]
+	if !ok {
+		p = c.newPool(addr)
+		c.pools[addr] = p
+	}
+	return p
+}
+
+// get returns a connection to the node serving the channel.
+func (c *cluster) get(channel string) (redis.Conn, error) {
+	addr, err := c.node(channel)
+	if err != nil {
+		return nil, err
+	}
+	return c.pool(addr).Get(), nil
+}
+
+// dial returns a connection of its own to the node serving the channel.
+func (c *cluster) dial(channel string) (redis.Conn, error) {
+	addr, err := c.node(channel)
+	if err != nil {
+		return nil, err
+	}
+	return c.pool(addr).Dial()
+}
+
+func (c *cluster) close() error {
+	c.Lock()
+	defer c.Unlock()
+
+	var err error
+	for addr, p := range c.pools {
+		if cerr := p.Close(); cerr != nil {
+			err = cerr
+		}
+		delete(c.pools, addr)
+	}
+	return err
+}
+
+// moved reports whether the error redirects to another node, after the
+// slots of the cluster changed.
+func moved(err error) bool {
+	var rerr redis.Error
+	return errors.As(err, &rerr) && strings.HasPrefix(string(rerr), "MOVED ")
+}
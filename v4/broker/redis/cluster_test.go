@@ -0,0 +1,288 @@
+package redis
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"go-micro.dev/v4/broker"
+)
+
+func TestSlot(t *testing.T) {
+	testData := map[string]int{
+		"":                     0,
+		"foo":                  12182,
+		"bar":                  5061,
+		"{user1000}.following": slot("user1000"),
+		"{user1000}.followers": slot("user1000"),
+		"foo{}{bar}":           slot("foo{}{bar}"),
+		"foo{{bar}}zap":        slot("{bar"),
+	}
+
+	for channel, expected := range testData {
+		if s := slot(channel); s != expected {
+			t.Errorf("Expected slot %d of %q, got %d", expected, channel, s)
+		}
+	}
+}
+
+func TestParseSlots(t *testing.T) {
+	reply := []interface{}{
+		[]interface{}{int64(0), int64(5460), []interface{}{[]byte("10.0.0.1"), int64(6379), []byte("a")}, []interface{}{[]byte("10.0.0.4"), int64(6379), []byte("d")}},
+		[]interface{}{int64(5461), int64(16383), []interface{}{[]byte(""), int64(6380), []byte("b")}},
+	}
+
+	ranges, err := parseSlots("redis://:secret@10.0.0.9:6379/0", reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []slotRange{
+		{0, 5460, "redis://:secret@10.0.0.1:6379/0"},
+		{5461, 16383, "redis://:secret@10.0.0.9:6380/0"},
+	}
+	if len(ranges) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, ranges)
+	}
+	for i := range expected {
+		if ranges[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected[i], ranges[i])
+		}
+	}
+
+	if _, err := parseSlots("redis://10.0.0.9:6379", []interface{}{[]interface{}{int64(0)}}); err == nil {
+		t.Error("Expected an invalid reply to fail")
+	}
+}
+
+// fakeCluster is a cluster of nodes speaking enough RESP for sharded pub/sub,
+// the owner serves every slot.
+type fakeCluster struct {
+	sync.Mutex
+	nodes []*fakeNode
+	owner *fakeNode
+}
+
+type fakeNode struct {
+	c    *fakeCluster
+	l    net.Listener
+	subs map[*fakeConn]string
+}
+
+type fakeConn struct {
+	sync.Mutex
+	w io.Writer
+}
+
+func (f *fakeConn) write(format string, args ...interface{}) {
+	f.Lock()
+	fmt.Fprintf(f.w, format, args...)
+	f.Unlock()
+}
+
+func newFakeCluster(t *testing.T, n int) *fakeCluster {
+	c := &fakeCluster{}
+	for i := 0; i < n; i++ {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		node := &fakeNode{c: c, l: l, subs: map[*fakeConn]string{}}
+		c.nodes = append(c.nodes, node)
+		go node.serve()
+	}
+	c.owner = c.nodes[0]
+	return c
+}
+
+func (c *fakeCluster) close() {
+	for _, n := range c.nodes {
+		n.l.Close()
+	}
+}
+
+// migrate moves every slot to the node, the subscribers of the old owner
+// are unsubscribed.
+func (c *fakeCluster) migrate(to *fakeNode) {
+	c.Lock()
+	defer c.Unlock()
+
+	for conn, channel := range c.owner.subs {
+		conn.write("*3\r\n$12\r\nsunsubscribe\r\n$%d\r\n%s\r\n:0\r\n", len(channel), channel)
+		delete(c.owner.subs, conn)
+	}
+	c.owner = to
+}
+
+func (c *fakeCluster) subscribers(n *fakeNode) int {
+	c.Lock()
+	defer c.Unlock()
+	return len(n.subs)
+}
+
+func (n *fakeNode) addr() string {
+	return n.l.Addr().String()
+}
+
+func (n *fakeNode) serve() {
+	for {
+		conn, err := n.l.Accept()
+		if err != nil {
+			return
+		}
+		go n.handle(conn)
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+
+	args := make([]string, count)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		b := make([]byte, size+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		args[i] = string(b[:size])
+	}
+	return args, nil
+}
+
+func (n *fakeNode) handle(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	fc := &fakeConn{w: conn}
+	c := n.c
+
+	defer func() {
+		c.Lock()
+		delete(n.subs, fc)
+		c.Unlock()
+	}()
+
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+
+		c.Lock()
+		owner := c.owner
+		moved := fmt.Sprintf("-MOVED %d %s\r\n", 0, owner.addr())
+
+		switch strings.ToUpper(args[0]) {
+		case "PING":
+			fc.write("+PONG\r\n")
+		case "CLUSTER":
+			host, port, _ := net.SplitHostPort(owner.addr())
+			fc.write("*1\r\n*3\r\n:0\r\n:16383\r\n*2\r\n$%d\r\n%s\r\n:%s\r\n", len(host), host, port)
+		case "SPUBLISH":
+			if owner != n {
+				fc.write(moved)
+				break
+			}
+			count := 0
+			for sub, channel := range n.subs {
+				if channel == args[1] {
+					sub.write("*3\r\n$8\r\nsmessage\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(args[1]), args[1], len(args[2]), args[2])
+					count++
+				}
+			}
+			fc.write(":%d\r\n", count)
+		case "SSUBSCRIBE":
+			if owner != n {
+				fc.write(moved)
+				break
+			}
+			n.subs[fc] = args[1]
+			fc.write("*3\r\n$10\r\nssubscribe\r\n$%d\r\n%s\r\n:1\r\n", len(args[1]), args[1])
+		case "SUNSUBSCRIBE":
+			channel := n.subs[fc]
+			delete(n.subs, fc)
+			fc.write("*3\r\n$12\r\nsunsubscribe\r\n$%d\r\n%s\r\n:0\r\n", len(channel), channel)
+		default:
+			fc.write("-ERR unknown command\r\n")
+		}
+		c.Unlock()
+	}
+}
+
+func TestShardedCluster(t *testing.T) {
+	c := newFakeCluster(t, 2)
+	defer c.close()
+
+	b := NewBroker(broker.Addrs(c.nodes[0].addr()), Cluster(), Sharded())
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	msgs := make(chan string, 10)
+	s, err := b.Subscribe("orders", func(e broker.Event) error {
+		msgs <- string(e.Message().Body)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receive := func(expected string) {
+		select {
+		case m := <-msgs:
+			if m != expected {
+				t.Fatalf("Expected %q, got %q", expected, m)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected %q to be received", expected)
+		}
+	}
+
+	publish(t, b, "orders", &broker.Message{Body: []byte("first")})
+	receive("first")
+
+	// the slot moves, the subscriber follows it
+	c.migrate(c.nodes[1])
+	for i := 0; c.subscribers(c.nodes[1]) == 0; i++ {
+		if i == 100 {
+			t.Fatal("Expected the subscriber to subscribe on the new owner")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// the publisher is redirected
+	publish(t, b, "orders", &broker.Message{Body: []byte("second")})
+	receive("second")
+
+	unsubscribe(t, s)
+	for i := 0; c.subscribers(c.nodes[1]) > 0; i++ {
+		if i == 100 {
+			t.Fatal("Expected the subscriber to unsubscribe")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestMoved(t *testing.T) {
+	if !moved(redis.Error("MOVED 3999 127.0.0.1:6381")) {
+		t.Error("Expected MOVED to redirect")
+	}
+	if moved(redis.Error("ERR unknown command")) || moved(nil) {
+		t.Error("Expected other errors not to redirect")
+	}
+}
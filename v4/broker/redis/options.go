@@ -25,6 +25,8 @@ type brokerOptions struct {
 	connectTimeout time.Duration
 	readTimeout    time.Duration
 	writeTimeout   time.Duration
+	cluster        bool
+	sharded        bool
 }
 
 type optionsKeyType struct{}
@@ -70,3 +72,24 @@ func IdleTimeout(d time.Duration) broker.Option {
 		bo.idleTimeout = d
 	}
 }
+
+// Cluster connects to a Redis Cluster, the addresses are seed nodes the
+// other nodes are discovered from. Topics are published and subscribed to on
+// the node serving their hash slot, and subscribers follow their slot when
+// it moves to another node.
+func Cluster() broker.Option {
+	return func(o *broker.Options) {
+		bo := o.Context.Value(optionsKey).(*brokerOptions)
+		bo.cluster = true
+	}
+}
+
+// Sharded uses the sharded pub/sub of Redis 7, SPUBLISH and SSUBSCRIBE. In a
+// cluster messages are then only propagated to the shard of the topic rather
+// than to every node, so subscriptions scale with the number of shards.
+func Sharded() broker.Option {
+	return func(o *broker.Options) {
+		bo := o.Context.Value(optionsKey).(*brokerOptions)
+		bo.sharded = true
+	}
+}
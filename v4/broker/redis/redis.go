@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gomodule/redigo/redis"
@@ -48,31 +49,116 @@ func (p *publication) Error() error {
 // subscriber proxies and handles Redis messages as broker publications.
 type subscriber struct {
 	codec  codec.Marshaler
-	conn   *redis.PubSubConn
 	topic  string
 	handle broker.Handler
 	opts   broker.SubscribeOptions
+	// sharded subscribes with SSUBSCRIBE
+	sharded bool
+	// dial returns a connection to the node serving the topic for
+	// resubscribing, nil outside of a cluster
+	dial func() (redis.Conn, error)
+
+	sync.Mutex
+	conn redis.Conn
+	done chan struct{}
+}
+
+// commands returns the subscribe and unsubscribe commands of the subscriber.
+func (s *subscriber) commands() (string, string) {
+	if s.sharded {
+		return "SSUBSCRIBE", "SUNSUBSCRIBE"
+	}
+	return "SUBSCRIBE", "UNSUBSCRIBE"
+}
+
+// subscribe subscribes the connection to the topic.
+func (s *subscriber) subscribe(conn redis.Conn) error {
+	cmd, _ := s.commands()
+	if err := conn.Send(cmd, s.topic); err != nil {
+		return err
+	}
+	return conn.Flush()
 }
 
 // recv loops to receive new messages from Redis and handle them
-// as publications.
+// as publications. In a cluster the subscriber subscribes again on the node
+// serving the topic when its connection fails or the slot of the topic
+// moved to another node.
 func (s *subscriber) recv() {
-	// Close the connection once the subscriber stops receiving.
-	defer s.conn.Close()
+	for backoff := time.Duration(0); ; {
+		s.Lock()
+		conn := s.conn
+		s.Unlock()
+
+		received := s.receive(conn)
+		// Close the connection once the subscriber stops receiving.
+		conn.Close()
+
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+		if s.dial == nil {
+			return
+		}
+
+		// unsubscribed by the node, or disconnected
+		for {
+			if received {
+				backoff = 0
+			}
+			backoff = nextBackoff(backoff)
+			select {
+			case <-s.done:
+				return
+			case <-time.After(backoff):
+			}
+
+			c, err := s.dial()
+			if err == nil {
+				if err = s.subscribe(c); err == nil {
+					s.Lock()
+					s.conn = c
+					s.Unlock()
+					break
+				}
+				c.Close()
+			}
+			received = false
+		}
+	}
+}
+
+// receive handles the messages of the connection until it's unsubscribed
+// or fails, and returns whether it received any.
+func (s *subscriber) receive(conn redis.Conn) bool {
+	received := false
 
 	for {
-		switch x := s.conn.Receive().(type) {
-		case redis.Message:
+		// no read timeout, the subscriber waits for messages indefinitely
+		reply, err := redis.Values(redis.ReceiveWithTimeout(conn, 0))
+		if err != nil || len(reply) < 3 {
+			return received
+		}
+		kind, _ := redis.String(reply[0], nil)
+
+		switch kind {
+		case "message", "smessage":
+			received = true
+			channel, _ := redis.String(reply[1], nil)
+			data, _ := redis.Bytes(reply[2], nil)
+
 			var m broker.Message
 
 			// Handle error? Only a log would be necessary since this type
 			// of issue cannot be fixed.
-			if err := s.codec.Unmarshal(x.Data, &m); err != nil {
+			if err := s.codec.Unmarshal(data, &m); err != nil {
 				break
 			}
 
 			p := publication{
-				topic:   x.Channel,
+				topic:   channel,
 				message: &m,
 			}
 
@@ -88,17 +174,25 @@ func (s *subscriber) recv() {
 				}
 			}
 
-		case redis.Subscription:
-			if x.Count == 0 {
-				return
+		case "unsubscribe", "sunsubscribe":
+			if count, _ := redis.Int(reply[2], nil); count == 0 {
+				return true
 			}
-
-		case error:
-			return
 		}
 	}
 }
 
+// nextBackoff doubles the backoff between resubscriptions up to 5 seconds.
+func nextBackoff(d time.Duration) time.Duration {
+	if d == 0 {
+		return 100 * time.Millisecond
+	}
+	if d *= 2; d > 5*time.Second {
+		return 5 * time.Second
+	}
+	return d
+}
+
 // Options returns the subscriber options.
 func (s *subscriber) Options() broker.SubscribeOptions {
 	return s.opts
@@ -111,15 +205,30 @@ func (s *subscriber) Topic() string {
 
 // Unsubscribe unsubscribes the subscriber and frees the connection.
 func (s *subscriber) Unsubscribe() error {
-	return s.conn.Unsubscribe()
+	s.Lock()
+	defer s.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	default:
+		close(s.done)
+	}
+
+	_, cmd := s.commands()
+	if err := s.conn.Send(cmd); err != nil {
+		return err
+	}
+	return s.conn.Flush()
 }
 
 // broker implementation for Redis.
 type redisBroker struct {
-	addr  string
-	pool  *redis.Pool
-	opts  broker.Options
-	bopts *brokerOptions
+	addr    string
+	pool    *redis.Pool
+	cluster *cluster
+	opts    broker.Options
+	bopts   *brokerOptions
 }
 
 // String returns the name of the broker implementation.
@@ -152,33 +261,50 @@ func (b *redisBroker) Init(opts ...broker.Option) error {
 }
 
 // Connect establishes a connection to Redis which provides the
-// pub/sub implementation.
+// pub/sub implementation. In a cluster the nodes are discovered from the
+// addresses.
 func (b *redisBroker) Connect() error {
 	if b.pool != nil {
 		return nil
 	}
 
-	var addr string
+	addrs := []string{"redis://127.0.0.1:6379"}
+	if len(b.opts.Addrs) > 0 && b.opts.Addrs[0] != "" {
+		addrs = addrs[:0]
+		for _, addr := range b.opts.Addrs {
+			if !strings.HasPrefix(addr, "redis://") && !strings.HasPrefix(addr, "rediss://") {
+				addr = "redis://" + addr
+			}
+			addrs = append(addrs, addr)
+		}
+	}
 
-	if len(b.opts.Addrs) == 0 || b.opts.Addrs[0] == "" {
-		addr = "redis://127.0.0.1:6379"
-	} else {
-		addr = b.opts.Addrs[0]
+	b.addr = addrs[0]
+	b.pool = b.newPool(b.addr)
 
-		if !strings.HasPrefix("redis://", addr) {
-			addr = "redis://" + addr
+	if b.bopts.cluster {
+		b.cluster = newCluster(addrs, b.newPool)
+		if err := b.cluster.refresh(); err != nil {
+			b.cluster.close()
+			b.pool.Close()
+			b.cluster = nil
+			b.pool = nil
+			return err
 		}
 	}
 
-	b.addr = addr
+	return nil
+}
 
-	b.pool = &redis.Pool{
+// newPool returns a connection pool of the node.
+func (b *redisBroker) newPool(addr string) *redis.Pool {
+	return &redis.Pool{
 		MaxIdle:     b.bopts.maxIdle,
 		MaxActive:   b.bopts.maxActive,
 		IdleTimeout: b.bopts.idleTimeout,
 		Dial: func() (redis.Conn, error) {
 			return redis.DialURL(
-				b.addr,
+				addr,
 				redis.DialConnectTimeout(b.bopts.connectTimeout),
 				redis.DialReadTimeout(b.bopts.readTimeout),
 				redis.DialWriteTimeout(b.bopts.writeTimeout),
@@ -189,55 +315,114 @@ func (b *redisBroker) Connect() error {
 			return err
 		},
 	}
+}
 
-	return nil
+// conn returns a connection for the topic, to the node serving its slot in
+// a cluster.
+func (b *redisBroker) conn(topic string) (redis.Conn, error) {
+	if b.cluster == nil {
+		return b.pool.Get(), nil
+	}
+	return b.cluster.get(topic)
+}
+
+// dial returns a connection of its own for subscribing to the topic. Pooled
+// connections can't be used, the pool doesn't end sharded subscriptions
+// when they're returned.
+func (b *redisBroker) dial(topic string) (redis.Conn, error) {
+	if b.cluster == nil {
+		return b.pool.Dial()
+	}
+	return b.cluster.dial(topic)
 }
 
 // Disconnect closes the connection pool.
 func (b *redisBroker) Disconnect() error {
-	err := b.pool.Close()
+	var err error
+	if b.cluster != nil {
+		err = b.cluster.close()
+		b.cluster = nil
+	}
+	if perr := b.pool.Close(); perr != nil {
+		err = perr
+	}
 	b.pool = nil
 	b.addr = ""
 	return err
 }
 
-// Publish publishes a message.
+// Publish publishes a message, with SPUBLISH if the broker is sharded.
 func (b *redisBroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
 	v, err := b.opts.Codec.Marshal(msg)
 	if err != nil {
 		return err
 	}
 
-	conn := b.pool.Get()
-	_, err = redis.Int(conn.Do("PUBLISH", topic, v))
-	conn.Close()
+	cmd := "PUBLISH"
+	if b.bopts.sharded {
+		cmd = "SPUBLISH"
+	}
 
-	return err
+	for retry := true; ; retry = false {
+		conn, err := b.conn(topic)
+		if err != nil {
+			return err
+		}
+		_, err = redis.Int(conn.Do(cmd, topic, v))
+		conn.Close()
+
+		// the slot of the topic moved to another node
+		if retry && b.cluster != nil && moved(err) {
+			if err := b.cluster.refresh(); err != nil {
+				return err
+			}
+			continue
+		}
+		return err
+	}
 }
 
-// Subscribe returns a subscriber for the topic and handler.
+// Subscribe returns a subscriber for the topic and handler, subscribed with
+// SSUBSCRIBE if the broker is sharded.
 func (b *redisBroker) Subscribe(topic string, handler broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
 	var options broker.SubscribeOptions
 	for _, o := range opts {
 		o(&options)
 	}
 
-	s := subscriber{
-		codec:  b.opts.Codec,
-		conn:   &redis.PubSubConn{Conn: b.pool.Get()},
-		topic:  topic,
-		handle: handler,
-		opts:   options,
+	conn, err := b.dial(topic)
+	if err != nil {
+		return nil, err
 	}
 
-	// Run the receiver routine.
-	go s.recv()
+	s := &subscriber{
+		codec:   b.opts.Codec,
+		conn:    conn,
+		topic:   topic,
+		handle:  handler,
+		opts:    options,
+		sharded: b.bopts.sharded,
+		done:    make(chan struct{}),
+	}
 
-	if err := s.conn.Subscribe(s.topic); err != nil {
+	if c := b.cluster; c != nil {
+		s.dial = func() (redis.Conn, error) {
+			if err := c.refresh(); err != nil {
+				return nil, err
+			}
+			return c.dial(topic)
+		}
+	}
+
+	if err := s.subscribe(conn); err != nil {
+		conn.Close()
 		return nil, err
 	}
 
-	return &s, nil
+	// Run the receiver routine.
+	go s.recv()
+
+	return s, nil
 }
 
 // NewBroker returns a new broker implemented using the Redis pub/sub
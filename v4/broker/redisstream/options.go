@@ -0,0 +1,56 @@
+package redisstream
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go-micro.dev/v4/broker"
+)
+
+var (
+	// DefaultBlockTime is how long XREADGROUP blocks waiting for new messages.
+	DefaultBlockTime = 5 * time.Second
+	// DefaultClaimMinIdle is how long a message stays pending before it is
+	// claimed from a crashed consumer.
+	DefaultClaimMinIdle = time.Minute
+	// DefaultMaxLen is the approximate max length of a stream, 0 is unlimited.
+	DefaultMaxLen int64
+)
+
+type blockTimeKey struct{}
+type claimMinIdleKey struct{}
+type maxLenKey struct{}
+type redisOptionsKey struct{}
+
+// BlockTime sets how long a subscriber blocks waiting for new messages.
+func BlockTime(d time.Duration) broker.Option {
+	return setBrokerOption(blockTimeKey{}, d)
+}
+
+// ClaimMinIdle sets how long a message must be pending before another
+// consumer of the group claims it with XAUTOCLAIM.
+func ClaimMinIdle(d time.Duration) broker.Option {
+	return setBrokerOption(claimMinIdleKey{}, d)
+}
+
+// MaxLen caps the length of the streams on publish, trimming is approximate.
+func MaxLen(n int64) broker.Option {
+	return setBrokerOption(maxLenKey{}, n)
+}
+
+// RedisOptions sets advanced options for the redis client. Addrs and TLS
+// config fall back to the broker options when unset.
+func RedisOptions(opts *redis.UniversalOptions) broker.Option {
+	return setBrokerOption(redisOptionsKey{}, opts)
+}
+
+// setBrokerOption returns a function to setup a context with given value.
+func setBrokerOption(k, v interface{}) broker.Option {
+	return func(o *broker.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
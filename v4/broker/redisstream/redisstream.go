@@ -0,0 +1,443 @@
+// Package redisstream provides a Redis Streams broker with consumer groups
+package redisstream
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"go-micro.dev/v4/broker"
+	"go-micro.dev/v4/codec/json"
+	"go-micro.dev/v4/logger"
+	"go-micro.dev/v4/util/cmd"
+)
+
+const (
+	messageField = "message"
+	claimCount   = 50
+)
+
+func init() {
+	cmd.DefaultBrokers["redisstream"] = NewBroker
+}
+
+type streamBroker struct {
+	sync.RWMutex
+	opts        broker.Options
+	client      redis.UniversalClient
+	subscribers map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	b *streamBroker
+	// client the subscriber was created with, it stays valid until the
+	// subscriber has been stopped
+	client   redis.UniversalClient
+	topic    string
+	group    string
+	consumer string
+	// whether the group was created for this subscriber only
+	ephemeral bool
+	opts      broker.SubscribeOptions
+	handler   broker.Handler
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+type publication struct {
+	s     *subscriber
+	id    string
+	topic string
+	m     *broker.Message
+	err   error
+}
+
+func (p *publication) Topic() string {
+	return p.topic
+}
+
+func (p *publication) Message() *broker.Message {
+	return p.m
+}
+
+// Ack removes the message from the pending entries list of the group.
+func (p *publication) Ack() error {
+	return p.s.client.XAck(context.Background(), p.s.topic, p.s.group, p.id).Err()
+}
+
+func (p *publication) Error() error {
+	return p.err
+}
+
+func (s *subscriber) Options() broker.SubscribeOptions {
+	return s.opts
+}
+
+func (s *subscriber) Topic() string {
+	return s.topic
+}
+
+func (s *subscriber) Unsubscribe() error {
+	s.stop()
+
+	s.b.Lock()
+	_, ok := s.b.subscribers[s]
+	delete(s.b.subscribers, s)
+	s.b.Unlock()
+
+	// already cleaned up by Disconnect
+	if !ok {
+		return nil
+	}
+
+	return s.cleanup()
+}
+
+// stop cancels the read loop and waits for it to return.
+func (s *subscriber) stop() {
+	s.cancel()
+	<-s.done
+}
+
+// cleanup removes the group or consumer of a stopped subscriber.
+func (s *subscriber) cleanup() error {
+	ctx := context.Background()
+	if s.ephemeral {
+		return s.client.XGroupDestroy(ctx, s.topic, s.group).Err()
+	}
+
+	// deleting the consumer drops its pending messages, keep it around so
+	// they can still be claimed by the rest of the group
+	pending, err := s.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream:   s.topic,
+		Group:    s.group,
+		Start:    "-",
+		End:      "+",
+		Count:    1,
+		Consumer: s.consumer,
+	}).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return nil
+	}
+
+	return s.client.XGroupDelConsumer(ctx, s.topic, s.group, s.consumer).Err()
+}
+
+func (s *subscriber) handle(msgs []redis.XMessage) {
+	for _, msg := range msgs {
+		p := &publication{
+			s:     s,
+			id:    msg.ID,
+			topic: s.topic,
+		}
+
+		v, ok := msg.Values[messageField].(string)
+		if !ok {
+			// nothing we can do with it, don't let it be redelivered
+			p.Ack()
+			continue
+		}
+
+		var m broker.Message
+		if err := s.b.opts.Codec.Unmarshal([]byte(v), &m); err != nil {
+			s.b.opts.Logger.Logf(logger.ErrorLevel, "[redisstream] failed to unmarshal message %s: %v", msg.ID, err)
+			p.Ack()
+			continue
+		}
+		p.m = &m
+
+		p.err = s.handler(p)
+		// failed messages stay pending and are claimed again once idle
+		if p.err == nil && s.opts.AutoAck {
+			if err := p.Ack(); err != nil {
+				s.b.opts.Logger.Logf(logger.ErrorLevel, "[redisstream] failed to ack message %s: %v", msg.ID, err)
+			}
+		}
+	}
+}
+
+// claim takes over messages which have been pending for longer than the
+// min idle time, e.g. because the consumer crashed before acking them.
+func (s *subscriber) claim(ctx context.Context, minIdle time.Duration) error {
+	start := "0-0"
+	for {
+		msgs, next, err := s.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   s.topic,
+			Group:    s.group,
+			Consumer: s.consumer,
+			MinIdle:  minIdle,
+			Start:    start,
+			Count:    claimCount,
+		}).Result()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+
+		s.handle(msgs)
+
+		if next == "0-0" || len(next) == 0 {
+			return nil
+		}
+		start = next
+	}
+}
+
+func (s *subscriber) run(ctx context.Context) {
+	defer close(s.done)
+
+	blockTime := s.b.getBlockTime()
+	minIdle := s.b.getClaimMinIdle()
+
+	var lastClaim time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		if time.Since(lastClaim) >= minIdle {
+			if err := s.claim(ctx, minIdle); err != nil && ctx.Err() == nil {
+				s.b.opts.Logger.Logf(logger.ErrorLevel, "[redisstream] failed to claim pending messages: %v", err)
+			}
+			lastClaim = time.Now()
+		}
+
+		streams, err := s.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    s.group,
+			Consumer: s.consumer,
+			Streams:  []string{s.topic, ">"},
+			Block:    blockTime,
+			Count:    claimCount,
+		}).Result()
+		if err != nil {
+			if err == redis.Nil || ctx.Err() != nil {
+				continue
+			}
+			s.b.opts.Logger.Logf(logger.ErrorLevel, "[redisstream] failed to read from %s: %v", s.topic, err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		for _, stream := range streams {
+			s.handle(stream.Messages)
+		}
+	}
+}
+
+func (b *streamBroker) Init(opts ...broker.Option) error {
+	b.RLock()
+	connected := b.client != nil
+	b.RUnlock()
+
+	if connected {
+		return errors.New("redisstream: cannot init while connected")
+	}
+
+	for _, o := range opts {
+		o(&b.opts)
+	}
+
+	return nil
+}
+
+func (b *streamBroker) Options() broker.Options {
+	return b.opts
+}
+
+func (b *streamBroker) Address() string {
+	if len(b.opts.Addrs) > 0 {
+		return b.opts.Addrs[0]
+	}
+	return "127.0.0.1:6379"
+}
+
+func (b *streamBroker) Connect() error {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.client != nil {
+		return nil
+	}
+
+	opts, ok := b.opts.Context.Value(redisOptionsKey{}).(*redis.UniversalOptions)
+	if !ok || opts == nil {
+		opts = &redis.UniversalOptions{}
+	}
+
+	if len(opts.Addrs) == 0 {
+		for _, addr := range b.opts.Addrs {
+			opts.Addrs = append(opts.Addrs, strings.TrimPrefix(addr, "redis://"))
+		}
+	}
+
+	if len(opts.Addrs) == 0 {
+		opts.Addrs = []string{"127.0.0.1:6379"}
+	}
+
+	if opts.TLSConfig == nil {
+		opts.TLSConfig = b.opts.TLSConfig
+	}
+
+	client := redis.NewUniversalClient(opts)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return err
+	}
+
+	b.client = client
+
+	return nil
+}
+
+func (b *streamBroker) Disconnect() error {
+	b.Lock()
+	client := b.client
+	subs := b.subscribers
+	b.client = nil
+	b.subscribers = nil
+	b.Unlock()
+
+	if client == nil {
+		return nil
+	}
+
+	// handlers may still call into the broker, so the subscribers are
+	// stopped without holding the lock
+	for s := range subs {
+		s.stop()
+		if err := s.cleanup(); err != nil {
+			b.opts.Logger.Logf(logger.ErrorLevel, "[redisstream] failed to clean up subscriber of %s: %v", s.topic, err)
+		}
+	}
+
+	return client.Close()
+}
+
+func (b *streamBroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
+	v, err := b.opts.Codec.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	b.RLock()
+	client := b.client
+	b.RUnlock()
+
+	if client == nil {
+		return errors.New("redisstream: not connected")
+	}
+
+	return client.XAdd(context.Background(), &redis.XAddArgs{
+		Stream: topic,
+		MaxLen: b.getMaxLen(),
+		Approx: true,
+		Values: map[string]interface{}{messageField: string(v)},
+	}).Err()
+}
+
+// Subscribe creates a consumer in the group named after the queue. Subscribers
+// without a queue each get their own group so that every subscriber receives
+// all messages.
+func (b *streamBroker) Subscribe(topic string, handler broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	options := broker.NewSubscribeOptions(opts...)
+
+	b.RLock()
+	client := b.client
+	b.RUnlock()
+
+	if client == nil {
+		return nil, errors.New("redisstream: not connected")
+	}
+
+	s := &subscriber{
+		b:        b,
+		client:   client,
+		topic:    topic,
+		group:    options.Queue,
+		consumer: uuid.New().String(),
+		opts:     options,
+		handler:  handler,
+		done:     make(chan struct{}),
+	}
+
+	if len(s.group) == 0 {
+		s.group = uuid.New().String()
+		s.ephemeral = true
+	}
+
+	err := client.XGroupCreateMkStream(context.Background(), topic, s.group, "$").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	b.Lock()
+	if b.client != client {
+		b.Unlock()
+		cancel()
+		return nil, errors.New("redisstream: not connected")
+	}
+	if b.subscribers == nil {
+		b.subscribers = make(map[*subscriber]struct{})
+	}
+	b.subscribers[s] = struct{}{}
+	b.Unlock()
+
+	go s.run(ctx)
+
+	return s, nil
+}
+
+func (b *streamBroker) String() string {
+	return "redisstream"
+}
+
+func (b *streamBroker) getBlockTime() time.Duration {
+	if d, ok := b.opts.Context.Value(blockTimeKey{}).(time.Duration); ok && d > 0 {
+		return d
+	}
+	return DefaultBlockTime
+}
+
+func (b *streamBroker) getClaimMinIdle() time.Duration {
+	if d, ok := b.opts.Context.Value(claimMinIdleKey{}).(time.Duration); ok && d > 0 {
+		return d
+	}
+	return DefaultClaimMinIdle
+}
+
+func (b *streamBroker) getMaxLen() int64 {
+	if n, ok := b.opts.Context.Value(maxLenKey{}).(int64); ok {
+		return n
+	}
+	return DefaultMaxLen
+}
+
+// NewBroker returns a new Redis Streams broker.
+func NewBroker(opts ...broker.Option) broker.Broker {
+	options := broker.Options{
+		Codec:   json.Marshaler{},
+		Context: context.Background(),
+		Logger:  logger.DefaultLogger,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &streamBroker{
+		opts: options,
+	}
+}
@@ -0,0 +1,155 @@
+package redisstream
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"go-micro.dev/v4/broker"
+)
+
+func newTestBroker(t *testing.T, opts ...broker.Option) broker.Broker {
+	url := os.Getenv("REDIS_URL")
+	if url == "" {
+		t.Skip("REDIS_URL not defined")
+	}
+
+	b := NewBroker(append(opts, broker.Addrs(url), BlockTime(100*time.Millisecond))...)
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Disconnect() })
+
+	return b
+}
+
+func TestBroker(t *testing.T) {
+	b := newTestBroker(t, MaxLen(100))
+
+	msgs := make(chan string, 10)
+
+	s1, err := b.Subscribe("test-fanout", func(e broker.Event) error {
+		msgs <- "s1:" + string(e.Message().Body)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s1.Unsubscribe()
+
+	s2, err := b.Subscribe("test-fanout", func(e broker.Event) error {
+		msgs <- "s2:" + string(e.Message().Body)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s2.Unsubscribe()
+
+	if err := b.Publish("test-fanout", &broker.Message{Body: []byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		select {
+		case m := <-msgs:
+			received[m] = true
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for message")
+		}
+	}
+
+	if !received["s1:hello"] || !received["s2:hello"] {
+		t.Fatalf("expected both subscribers to receive the message, got %v", received)
+	}
+}
+
+func TestClaimPending(t *testing.T) {
+	b := newTestBroker(t, ClaimMinIdle(200*time.Millisecond))
+
+	// the first consumer fails so the message stays pending
+	s1, err := b.Subscribe("test-claim", func(e broker.Event) error {
+		return errors.New("failed")
+	}, broker.Queue("workers"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Publish("test-claim", &broker.Message{Body: []byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	s1.Unsubscribe()
+
+	done := make(chan struct{}, 1)
+
+	s2, err := b.Subscribe("test-claim", func(e broker.Event) error {
+		done <- struct{}{}
+		return nil
+	}, broker.Queue("workers"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s2.Unsubscribe()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for pending message to be claimed")
+	}
+}
+
+func TestDisconnectStopsSubscribers(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	b := NewBroker(broker.Addrs(mr.Addr()), BlockTime(10*time.Millisecond))
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := make(chan string, 10)
+
+	var subs []broker.Subscriber
+	for i := 0; i < 3; i++ {
+		s, err := b.Subscribe("test-disconnect", func(e broker.Event) error {
+			msgs <- string(e.Message().Body)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		subs = append(subs, s)
+	}
+
+	if err := b.Publish("test-disconnect", &broker.Message{Body: []byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-msgs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+
+	if err := b.Disconnect(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range subs {
+		if err := s.Unsubscribe(); err != nil {
+			t.Fatalf("unexpected error unsubscribing after disconnect: %v", err)
+		}
+	}
+
+	if _, err := b.Subscribe("test-disconnect", func(broker.Event) error { return nil }); err == nil {
+		t.Fatal("expected subscribe to fail once disconnected")
+	}
+}
@@ -13,6 +13,8 @@ type groupIdFunctionKey struct{}
 type maxMessagesKey struct{}
 type visiblityTimeoutKey struct{}
 type waitTimeSecondsKey struct{}
+type messageGroupIDKey struct{}
+type messageDeduplicationIDKey struct{}
 
 type StringFromMessageFunc func(m *broker.Message) string
 
@@ -39,7 +41,7 @@ func GroupIDFunction(groupfunc StringFromMessageFunc) broker.Option {
 }
 
 // MaxReceiveMessages indicates how many messages a receive operation should pull
-// during any single call.
+// during any single call. Defaults to 10, the maximum allowed by SQS.
 func MaxReceiveMessages(max int64) broker.SubscribeOption {
 	return func(o *broker.SubscribeOptions) {
 		if o.Context == nil {
@@ -51,7 +53,8 @@ func MaxReceiveMessages(max int64) broker.SubscribeOption {
 
 // VisibilityTimeout controls how long a message is hidden from other queue consumers
 // before being put back. If a consumer does not delete the message, it will be put back
-// even if it was "processed".
+// even if it was "processed". The timeout of messages which are still being handled is
+// extended automatically. Defaults to 30 seconds.
 func VisibilityTimeout(seconds int64) broker.SubscribeOption {
 	return func(o *broker.SubscribeOptions) {
 		if o.Context == nil {
@@ -62,6 +65,7 @@ func VisibilityTimeout(seconds int64) broker.SubscribeOption {
 }
 
 // WaitTimeSeconds controls the length of long polling for available messages.
+// Defaults to 20 seconds, the maximum allowed by SQS.
 func WaitTimeSeconds(seconds int64) broker.SubscribeOption {
 	return func(o *broker.SubscribeOptions) {
 		if o.Context == nil {
@@ -71,6 +75,29 @@ func WaitTimeSeconds(seconds int64) broker.SubscribeOption {
 	}
 }
 
+// MessageGroupID sets the message group of a message published to a FIFO queue,
+// messages of the same group are delivered in order. Takes precedence over the
+// GroupIDFunction.
+func MessageGroupID(id string) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, messageGroupIDKey{}, id)
+	}
+}
+
+// MessageDeduplicationID sets the deduplication id of a message published to a
+// FIFO queue. It can be omitted if the queue has content based deduplication
+// enabled. Takes precedence over the DeduplicationFunction.
+func MessageDeduplicationID(id string) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, messageDeduplicationIDKey{}, id)
+	}
+}
 
 func Client(c *sqs.SQS) broker.Option {
 	return func(o *broker.Options) {
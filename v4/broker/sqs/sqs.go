@@ -5,6 +5,8 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -17,15 +19,28 @@ import (
 )
 
 const (
-	defaultMaxMessages       = 1
-	defaultVisibilityTimeout = 3
-	defaultWaitSeconds       = 10
+	defaultMaxMessages       = 10
+	defaultVisibilityTimeout = 30
+	defaultWaitSeconds       = 20
+
+	// maxBatchSize is the maximum number of entries of a batch request
+	maxBatchSize = 10
 )
 
 // Amazon SQS Broker.
 type sqsBroker struct {
 	svc     *sqs.SQS
 	options broker.Options
+
+	sync.RWMutex
+	// attributes of the known queues by url
+	queues map[string]*queueInfo
+}
+
+// queueInfo holds the attributes of a queue relevant for publishing.
+type queueInfo struct {
+	fifo         bool
+	contentDedup bool
 }
 
 // A subscriber (poller) to an SQS queue.
@@ -34,6 +49,7 @@ type subscriber struct {
 	queueName string
 	svc       *sqs.SQS
 	URL       string
+	fifo      bool
 	exit      chan bool
 }
 
@@ -45,28 +61,40 @@ type publication struct {
 	URL       string
 	queueName string
 	err       error
+	acked     bool
 }
 
 func init() {
 	cmd.DefaultBrokers["sqs"] = NewBroker
 }
 
-// run is designed to run as a goroutine and poll SQS for new messages. Note that it's possible to receive
-// more than one message from a single poll depending on the options configured for the plugin.
+// run is designed to run as a goroutine and poll SQS for new messages. Messages are received in batches using
+// long polling, the visibility of messages still being handled is extended periodically and acknowledged
+// messages are deleted in a single batch once the whole batch was handled.
 func (s *subscriber) run(hdlr broker.Handler) {
 	log.Infof("SQS subscription started. Queue:%s, URL: %s", s.queueName, s.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		<-s.exit
+		cancel()
+	}()
+
 	for {
 		select {
 		case <-s.exit:
 			return
 		default:
-			result, err := s.svc.ReceiveMessage(&sqs.ReceiveMessageInput{
+			result, err := s.svc.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
 				QueueUrl:            &s.URL,
 				MaxNumberOfMessages: s.getMaxMessages(),
 				VisibilityTimeout:   s.getVisibilityTimeout(),
 				WaitTimeSeconds:     s.getWaitSeconds(),
 				AttributeNames: aws.StringSlice([]string{
 					"SentTimestamp", // TODO: not currently exposing this to plugin users
+					sqs.MessageSystemAttributeNameMessageGroupId,
 				}),
 				MessageAttributeNames: aws.StringSlice([]string{
 					"All",
@@ -74,23 +102,152 @@ func (s *subscriber) run(hdlr broker.Handler) {
 			})
 
 			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
 				time.Sleep(time.Second)
 				log.Errorf("Error receiving SQS message: %s", err.Error())
 				continue
 			}
 
 			if len(result.Messages) == 0 {
-				time.Sleep(time.Second)
 				continue
 			}
 
-			for _, sm := range result.Messages {
-				s.handleMessage(sm, hdlr)
+			b := newBatch(result.Messages)
+
+			stop := make(chan struct{})
+			go s.heartbeat(b, stop)
+
+			if s.fifo {
+				s.handleGroups(result.Messages, hdlr, b)
+			} else {
+				for _, sm := range result.Messages {
+					s.handleMessage(sm, hdlr, b, true)
+				}
 			}
+
+			close(stop)
+			s.deleteMessages(b.acked)
 		}
 	}
 }
 
+// batch tracks the messages of a single receive.
+type batch struct {
+	sync.Mutex
+	// messages still being handled
+	pending map[string]*sqs.Message
+	// messages to delete once the batch is done
+	acked []*sqs.Message
+}
+
+func newBatch(msgs []*sqs.Message) *batch {
+	b := &batch{
+		pending: make(map[string]*sqs.Message, len(msgs)),
+	}
+	for _, sm := range msgs {
+		b.pending[aws.StringValue(sm.MessageId)] = sm
+	}
+	return b
+}
+
+func (b *batch) done(msg *sqs.Message, ack bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	delete(b.pending, aws.StringValue(msg.MessageId))
+	if ack {
+		b.acked = append(b.acked, msg)
+	}
+}
+
+// heartbeat extends the visibility timeout of the messages still pending in the batch
+// until stopped, so slow handlers don't cause messages to be redelivered.
+func (s *subscriber) heartbeat(b *batch, stop chan struct{}) {
+	timeout := s.getVisibilityTimeout()
+
+	interval := time.Duration(*timeout) * time.Second / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+
+		b.Lock()
+		entries := make([]*sqs.ChangeMessageVisibilityBatchRequestEntry, 0, len(b.pending))
+		for id, sm := range b.pending {
+			entries = append(entries, &sqs.ChangeMessageVisibilityBatchRequestEntry{
+				Id:                aws.String(id),
+				ReceiptHandle:     sm.ReceiptHandle,
+				VisibilityTimeout: timeout,
+			})
+		}
+		b.Unlock()
+
+		for len(entries) > 0 {
+			n := len(entries)
+			if n > maxBatchSize {
+				n = maxBatchSize
+			}
+
+			result, err := s.svc.ChangeMessageVisibilityBatch(&sqs.ChangeMessageVisibilityBatchInput{
+				QueueUrl: &s.URL,
+				Entries:  entries[:n],
+			})
+			if err != nil {
+				log.Errorf("Failed to extend visibility of SQS messages: %s", err.Error())
+			} else {
+				for _, f := range result.Failed {
+					log.Errorf("Failed to extend visibility of SQS message %s: %s", aws.StringValue(f.Id), aws.StringValue(f.Message))
+				}
+			}
+
+			entries = entries[n:]
+		}
+	}
+}
+
+// deleteMessages deletes the messages in batches of up to ten.
+func (s *subscriber) deleteMessages(msgs []*sqs.Message) {
+	for len(msgs) > 0 {
+		n := len(msgs)
+		if n > maxBatchSize {
+			n = maxBatchSize
+		}
+
+		entries := make([]*sqs.DeleteMessageBatchRequestEntry, 0, n)
+		for _, sm := range msgs[:n] {
+			entries = append(entries, &sqs.DeleteMessageBatchRequestEntry{
+				Id:            sm.MessageId,
+				ReceiptHandle: sm.ReceiptHandle,
+			})
+		}
+
+		result, err := s.svc.DeleteMessageBatch(&sqs.DeleteMessageBatchInput{
+			QueueUrl: &s.URL,
+			Entries:  entries,
+		})
+		if err != nil {
+			log.Errorf("Failed auto-acknowledge of messages: %s", err.Error())
+		} else {
+			for _, f := range result.Failed {
+				log.Errorf("Failed auto-acknowledge of message %s: %s", aws.StringValue(f.Id), aws.StringValue(f.Message))
+			}
+		}
+
+		msgs = msgs[n:]
+	}
+}
+
 func (s *subscriber) getMaxMessages() *int64 {
 	if v := s.options.Context.Value(maxMessagesKey{}); v != nil {
 		v2 := v.(int64)
@@ -115,35 +272,72 @@ func (s *subscriber) getWaitSeconds() *int64 {
 	return aws.Int64(defaultWaitSeconds)
 }
 
-func (s *subscriber) handleMessage(msg *sqs.Message, hdlr broker.Handler) {
+// handleGroups dispatches the messages of a FIFO queue. Messages of the same
+// group are handled one after the other in the order they were received,
+// different groups are handled concurrently. A group stops at the first
+// message which fails, it and the rest of the group aren't acknowledged, so
+// they are received again in order.
+func (s *subscriber) handleGroups(msgs []*sqs.Message, hdlr broker.Handler, b *batch) {
+	var order []string
+	groups := make(map[string][]*sqs.Message)
+
+	for _, sm := range msgs {
+		group := aws.StringValue(sm.Attributes[sqs.MessageSystemAttributeNameMessageGroupId])
+		if _, ok := groups[group]; !ok {
+			order = append(order, group)
+		}
+		groups[group] = append(groups[group], sm)
+	}
+
+	var wg sync.WaitGroup
+	for _, group := range order {
+		wg.Add(1)
+		go func(msgs []*sqs.Message) {
+			defer wg.Done()
+			for i, sm := range msgs {
+				if !s.handleMessage(sm, hdlr, b, false) {
+					for _, rest := range msgs[i+1:] {
+						b.done(rest, false)
+					}
+					return
+				}
+			}
+		}(groups[group])
+	}
+	wg.Wait()
+}
+
+// handleMessage calls the handler and reports whether it succeeded. Messages
+// whose handler failed are only auto acknowledged with ackFailed.
+func (s *subscriber) handleMessage(msg *sqs.Message, hdlr broker.Handler, b *batch, ackFailed bool) bool {
 	log.Infof("Received SQS message: %d bytes", len(*msg.Body))
 
-	if decodeBody, err := base64.StdEncoding.DecodeString(*msg.Body); err != nil {
+	decodeBody, err := base64.StdEncoding.DecodeString(*msg.Body)
+	if err != nil {
 		log.Errorf("Failed to decode message body : %s", err.Error())
-	} else {
-		m := &broker.Message{
-			Header: buildMessageHeader(msg.MessageAttributes),
-			Body:   decodeBody,
-		}
+		b.done(msg, false)
+		return false
+	}
 
-		p := &publication{
-			sMessage:  msg,
-			m:         m,
-			URL:       s.URL,
-			queueName: s.queueName,
-			svc:       s.svc,
-		}
+	m := &broker.Message{
+		Header: buildMessageHeader(msg.MessageAttributes),
+		Body:   decodeBody,
+	}
 
-		if p.err = hdlr(p); p.err != nil {
-			fmt.Println(p.err)
-		}
-		if s.options.AutoAck {
-			err := p.Ack()
-			if err != nil {
-				log.Errorf("Failed auto-acknowledge of message: %s", err.Error())
-			}
-		}
+	p := &publication{
+		sMessage:  msg,
+		m:         m,
+		URL:       s.URL,
+		queueName: s.queueName,
+		svc:       s.svc,
 	}
+
+	if p.err = hdlr(p); p.err != nil {
+		fmt.Println(p.err)
+	}
+	// acknowledged messages are deleted together once the batch is done
+	b.done(msg, s.options.AutoAck && !p.acked && (p.err == nil || ackFailed))
+	return p.err == nil
 }
 
 func (s *subscriber) Options() broker.SubscribeOptions {
@@ -173,6 +367,9 @@ func (p *publication) Ack() error {
 		QueueUrl:      &p.URL,
 		ReceiptHandle: p.sMessage.ReceiptHandle,
 	})
+	if err == nil {
+		p.acked = true
+	}
 	return err
 }
 
@@ -236,8 +433,40 @@ func (b *sqsBroker) Publish(queueName string, msg *broker.Message, opts ...broke
 		QueueUrl:    &queueURL,
 	}
 	input.MessageAttributes = copyMessageHeader(msg)
-	input.MessageDeduplicationId = b.generateDedupID(msg)
-	input.MessageGroupId = b.generateGroupID(msg)
+
+	info, err := b.queueInfo(queueURL)
+	if err != nil {
+		return err
+	}
+
+	// group and deduplication ids are only valid for FIFO queues
+	if info.fifo {
+		options := broker.PublishOptions{
+			Context: context.Background(),
+		}
+		for _, o := range opts {
+			o(&options)
+		}
+
+		input.MessageGroupId = b.generateGroupID(msg)
+		if id, ok := options.Context.Value(messageGroupIDKey{}).(string); ok {
+			input.MessageGroupId = &id
+		}
+		if len(aws.StringValue(input.MessageGroupId)) == 0 {
+			return fmt.Errorf("a message group id is required to publish to FIFO queue %s", queueName)
+		}
+
+		input.MessageDeduplicationId = b.generateDedupID(msg)
+		if id, ok := options.Context.Value(messageDeduplicationIDKey{}).(string); ok {
+			input.MessageDeduplicationId = &id
+		}
+		if len(aws.StringValue(input.MessageDeduplicationId)) == 0 {
+			if !info.contentDedup {
+				return fmt.Errorf("a deduplication id is required to publish to FIFO queue %s without content based deduplication", queueName)
+			}
+			input.MessageDeduplicationId = nil
+		}
+	}
 
 	log.Infof("Publishing SQS message, %d bytes", len(msg.Body))
 	_, err = b.svc.SendMessage(input)
@@ -267,11 +496,17 @@ func (b *sqsBroker) Subscribe(queueName string, h broker.Handler, opts ...broker
 		o(&options)
 	}
 
+	info, err := b.queueInfo(queueURL)
+	if err != nil {
+		return nil, err
+	}
+
 	subscriber := &subscriber{
 		options:   options,
 		URL:       queueURL,
 		queueName: queueName,
 		svc:       b.svc,
+		fifo:      info.fifo,
 		exit:      make(chan bool),
 	}
 	go subscriber.run(h)
@@ -292,6 +527,41 @@ func (b *sqsBroker) urlFromQueueName(queueName string) (string, error) {
 	return *resultURL.QueueUrl, nil
 }
 
+// queueInfo returns the attributes of the queue, they are looked up once and cached.
+func (b *sqsBroker) queueInfo(queueURL string) (*queueInfo, error) {
+	b.RLock()
+	info, ok := b.queues[queueURL]
+	b.RUnlock()
+	if ok {
+		return info, nil
+	}
+
+	info = &queueInfo{}
+
+	// only FIFO queue names end in .fifo, standard queues need no lookup
+	if strings.HasSuffix(queueURL, ".fifo") {
+		result, err := b.svc.GetQueueAttributes(&sqs.GetQueueAttributesInput{
+			QueueUrl: &queueURL,
+			AttributeNames: aws.StringSlice([]string{
+				sqs.QueueAttributeNameFifoQueue,
+				sqs.QueueAttributeNameContentBasedDeduplication,
+			}),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Unable to get attributes of queue %s: %s", queueURL, err.Error())
+		}
+
+		info.fifo = aws.StringValue(result.Attributes[sqs.QueueAttributeNameFifoQueue]) == "true"
+		info.contentDedup = aws.StringValue(result.Attributes[sqs.QueueAttributeNameContentBasedDeduplication]) == "true"
+	}
+
+	b.Lock()
+	b.queues[queueURL] = info
+	b.Unlock()
+
+	return info, nil
+}
+
 // String returns the name of the broker plugin.
 func (b *sqsBroker) String() string {
 	return "sqs"
@@ -356,5 +626,6 @@ func NewBroker(opts ...broker.Option) broker.Broker {
 
 	return &sqsBroker{
 		options: options,
+		queues:  make(map[string]*queueInfo),
 	}
 }
@@ -15,6 +15,10 @@ type receiptKey struct{}
 type subscribeHeaderKey struct{}
 type suppressContentLengthKey struct{}
 type vHostKey struct{}
+type clientIDKey struct{}
+type subscriptionNameKey struct{}
+type ackModeKey struct{}
+type transactionKey struct{}
 
 type authRecord struct {
 	username string
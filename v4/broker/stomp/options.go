@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/go-stomp/stomp/v3"
 	"go-micro.dev/v4/broker"
 )
 
@@ -31,6 +32,26 @@ func Durable() broker.SubscribeOption {
 	return setSubscribeOption(durableQueueKey{}, true)
 }
 
+// SubscriptionName sets the name of an ActiveMQ durable topic subscription,
+// it requires the ClientID to be set. Durable subscriptions with a queue are
+// named after the queue.
+func SubscriptionName(name string) broker.SubscribeOption {
+	return setSubscribeOption(subscriptionNameKey{}, name)
+}
+
+// AckMode sets the acknowledgement mode, stomp.AckClient acknowledges all
+// messages up to the acked one, stomp.AckClientIndividual only the acked one.
+// Messages are acked once handled without error unless auto ack is disabled.
+func AckMode(mode stomp.AckMode) broker.SubscribeOption {
+	return setSubscribeOption(ackModeKey{}, mode)
+}
+
+// Transaction publishes the message as part of the transaction, it's sent
+// once the transaction is committed.
+func Transaction(tx *stomp.Transaction) broker.PublishOption {
+	return setPublishOption(transactionKey{}, tx)
+}
+
 // Receipt requests a receipt for the delivery should be received.
 func Receipt(ct time.Duration) broker.PublishOption {
 	return setPublishOption(receiptKey{}, true)
@@ -59,6 +80,12 @@ func ConnectHeaders(h map[string]string) broker.Option {
 	return setBrokerOption(connectHeaderKey{}, h)
 }
 
+// ClientID sets the client id of the connection, which identifies the owner
+// of durable subscriptions.
+func ClientID(id string) broker.Option {
+	return setBrokerOption(clientIDKey{}, id)
+}
+
 // VirtualHost adds host header to define the vhost.
 func VirtualHost(h string) broker.Option {
 	return setBrokerOption(vHostKey{}, h)
@@ -91,6 +91,9 @@ func (r *rbroker) Connect() error {
 			stompOpts = append(stompOpts, stomp.ConnOpt.Header(k, v))
 		}
 	}
+	if id, ok := r.Options().Context.Value(clientIDKey{}).(string); ok && id != "" {
+		stompOpts = append(stompOpts, stomp.ConnOpt.Header("client-id", id))
+	}
 	if host, ok := r.Options().Context.Value(vHostKey{}).(string); ok && host != "" {
 		log.Printf("Adding host: %s", host)
 		stompOpts = append(stompOpts, stomp.ConnOpt.Host(host))
@@ -130,17 +133,24 @@ func (r *rbroker) Publish(topic string, msg *broker.Message, opts ...broker.Publ
 		stompOpt = append(stompOpt, stomp.SendOpt.Header(k, v))
 	}
 
-	bOpt := broker.PublishOptions{}
+	bOpt := broker.PublishOptions{
+		Context: context.Background(),
+	}
 	for _, o := range opts {
 		o(&bOpt)
 	}
-	if withReceipt, ok := r.Options().Context.Value(receiptKey{}).(bool); ok && withReceipt {
+	if withReceipt, ok := bOpt.Context.Value(receiptKey{}).(bool); ok && withReceipt {
 		stompOpt = append(stompOpt, stomp.SendOpt.Receipt)
 	}
-	if withoutContentLength, ok := r.Options().Context.Value(suppressContentLengthKey{}).(bool); ok && withoutContentLength {
+	if withoutContentLength, ok := bOpt.Context.Value(suppressContentLengthKey{}).(bool); ok && withoutContentLength {
 		stompOpt = append(stompOpt, stomp.SendOpt.NoContentLength)
 	}
 
+	// Send within the transaction
+	if tx, ok := bOpt.Context.Value(transactionKey{}).(*stomp.Transaction); ok && tx != nil {
+		return tx.Send(topic, "", msg.Body, stompOpt...)
+	}
+
 	// Send
 	if err := r.stompConn.Send(
 		topic,
@@ -178,10 +188,19 @@ func (r *rbroker) Subscribe(topic string, handler broker.Handler, opts ...broker
 		ctx = subscribeContext
 	}
 
-	if durableQueue, ok := ctx.Value(durableQueueKey{}).(bool); ok && durableQueue {
+	durable, _ := ctx.Value(durableQueueKey{}).(bool)
+	if durable {
 		stompOpt = append(stompOpt, stomp.SubscribeOpt.Header("persistent", "true"))
 	}
 
+	name, _ := ctx.Value(subscriptionNameKey{}).(string)
+	if name == "" && durable {
+		name = bOpt.Queue
+	}
+	if name != "" {
+		stompOpt = append(stompOpt, stomp.SubscribeOpt.Header("activemq.subscriptionName", name))
+	}
+
 	if headers, ok := ctx.Value(subscribeHeaderKey{}).(map[string]string); ok && len(headers) > 0 {
 		for k, v := range headers {
 			stompOpt = append(stompOpt, stomp.SubscribeOpt.Header(k, v))
@@ -200,6 +219,15 @@ func (r *rbroker) Subscribe(topic string, handler broker.Handler, opts ...broker
 		ackMode = stomp.AckClientIndividual
 	}
 
+	// ack handled messages unless acking is left to the handler
+	if mode, ok := ctx.Value(ackModeKey{}).(stomp.AckMode); ok {
+		ackMode = mode
+		if bOpt.AutoAck && mode.ShouldAck() {
+			bOpt.AutoAck = false
+			ackSuccess = true
+		}
+	}
+
 	// Subscribe now
 	sub, err := r.stompConn.Subscribe(topic, ackMode, stompOpt...)
 	if err != nil {
@@ -229,6 +257,20 @@ func (r *rbroker) Subscribe(topic string, handler broker.Handler, opts ...broker
 	return &subscriber{sub: sub, topic: topic, opts: bOpt}, nil
 }
 
+// Begin starts a transaction on the connection of the broker. Messages
+// published with the Transaction option are sent once it's committed, and
+// discarded if it's aborted.
+func Begin(b broker.Broker) (*stomp.Transaction, error) {
+	r, ok := b.(*rbroker)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a stomp broker", b.String())
+	}
+	if r.stompConn == nil {
+		return nil, errors.New("not connected")
+	}
+	return r.stompConn.Begin(), nil
+}
+
 func (r *rbroker) String() string {
 	return "stomp"
 }
@@ -0,0 +1,109 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go-micro.dev/v4/cache"
+)
+
+var (
+	// DefaultLockTTL is how long a load holds the lock of its key, other
+	// loads of the key wait for it as long at most.
+	DefaultLockTTL = 10 * time.Second
+	// DefaultLockPoll is how often waiting loads look for the value.
+	DefaultLockPoll = 50 * time.Millisecond
+)
+
+// LoadFunc loads the value of a key on a miss, e.g. from a database.
+type LoadFunc func(ctx context.Context) (interface{}, error)
+
+// unlock deletes the lock if it's still held by the token.
+var unlock = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// Load returns the value of the key, loading and putting it for d on a
+// miss. Loads are protected from stampedes, so an expired hot key doesn't
+// send every caller to the origin: loads of a key within the process share
+// one call of fn, and across processes a lock in redis has one load call fn
+// while the others wait for its value. Tags of the context are applied to
+// the value put.
+func Load(ctx context.Context, c cache.Cache, key string, d time.Duration, fn LoadFunc) (interface{}, error) {
+	rc, err := redisOf(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if v, _, err := rc.Get(ctx, key); err == nil {
+		return v, nil
+	} else if err != cache.ErrKeyNotFound && err != cache.ErrItemExpired {
+		return nil, err
+	}
+
+	v, err, _ := rc.sg.Do(key, func() (interface{}, error) {
+		return rc.load(ctx, key, d, fn)
+	})
+	return v, err
+}
+
+func (c *redisCache) load(ctx context.Context, key string, d time.Duration, fn LoadFunc) (interface{}, error) {
+	lock := key + ":lock"
+	token := newToken()
+	deadline := time.Now().Add(DefaultLockTTL)
+
+	for {
+		ok, err := c.client.SetNX(ctx, lock, token, DefaultLockTTL).Result()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			break
+		}
+
+		// another process loads the key
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(DefaultLockPoll):
+		}
+
+		if v, _, err := c.Get(ctx, key); err == nil {
+			return v, nil
+		}
+		if time.Now().After(deadline) {
+			// the other load is stuck, load without the lock
+			token = ""
+			break
+		}
+	}
+
+	if len(token) > 0 {
+		defer unlock.Run(context.Background(), c.client, []string{lock}, token)
+
+		// the value may have been put before the lock was taken
+		if v, _, err := c.Get(ctx, key); err == nil {
+			return v, nil
+		}
+	}
+
+	v, err := fn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Put(ctx, key, v, d); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func newToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/go-redis/redis/v8"
 	"go-micro.dev/v4/cache"
 	"go-micro.dev/v4/util/cmd"
+	"golang.org/x/sync/singleflight"
 )
 
 func init() {
@@ -25,6 +26,7 @@ func NewCache(opts ...cache.Option) cache.Cache {
 type redisCache struct {
 	opts   cache.Options
 	client redis.UniversalClient
+	sg     singleflight.Group
 }
 
 func (c *redisCache) Get(ctx context.Context, key string) (interface{}, time.Time, error) {
@@ -50,7 +52,19 @@ func (c *redisCache) Get(ctx context.Context, key string) (interface{}, time.Tim
 }
 
 func (c *redisCache) Put(ctx context.Context, key string, val interface{}, dur time.Duration) error {
-	return c.client.Set(ctx, key, val, dur).Err()
+	tags := tagsFrom(ctx)
+	if len(tags) == 0 {
+		return c.client.Set(ctx, key, val, dur).Err()
+	}
+
+	_, err := c.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		p.Set(ctx, key, val, dur)
+		for _, tag := range tags {
+			p.SAdd(ctx, DefaultTagPrefix+tag, key)
+		}
+		return nil
+	})
+	return err
 }
 
 func (c *redisCache) Delete(ctx context.Context, key string) error {
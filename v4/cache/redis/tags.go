@@ -0,0 +1,68 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+	"go-micro.dev/v4/cache"
+)
+
+// DefaultTagPrefix prefixes the keys of the sets holding the keys of tags.
+var DefaultTagPrefix = "micro:cache:tag:"
+
+type tagsKey struct{}
+
+// WithTags tags the keys put with the context, to invalidate them together
+// with InvalidateTag:
+//
+//	c.Put(redis.WithTags(ctx, "user:1"), "profile:1", profile, time.Hour)
+func WithTags(ctx context.Context, tags ...string) context.Context {
+	if t, ok := ctx.Value(tagsKey{}).([]string); ok {
+		tags = append(append([]string{}, t...), tags...)
+	}
+	return context.WithValue(ctx, tagsKey{}, tags)
+}
+
+func tagsFrom(ctx context.Context) []string {
+	tags, _ := ctx.Value(tagsKey{}).([]string)
+	return tags
+}
+
+func redisOf(c cache.Cache) (*redisCache, error) {
+	rc, ok := c.(*redisCache)
+	if !ok {
+		return nil, errors.Errorf("%s is not a redis cache", c.String())
+	}
+	return rc, nil
+}
+
+// InvalidateTag deletes the keys tagged with the tag.
+func InvalidateTag(ctx context.Context, c cache.Cache, tag string) error {
+	rc, err := redisOf(c)
+	if err != nil {
+		return err
+	}
+
+	set := DefaultTagPrefix + tag
+	keys, err := rc.client.SMembers(ctx, set).Result()
+	if err != nil || len(keys) == 0 {
+		return err
+	}
+
+	// keys are deleted one by one, they may be on different cluster slots,
+	// and removed from the set instead of deleting it, as keys tagged in
+	// the meantime stay tagged
+	_, err = rc.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, k := range keys {
+			p.Del(ctx, k)
+		}
+		members := make([]interface{}, len(keys))
+		for i, k := range keys {
+			members[i] = k
+		}
+		p.SRem(ctx, set, members...)
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,92 @@
+package redis
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/cache"
+)
+
+func TestInvalidateTag(t *testing.T) {
+	if len(os.Getenv("LOCAL")) == 0 {
+		t.Skip()
+	}
+
+	c := NewCache(addr)
+	tagged := WithTags(ctx, "user:1")
+
+	if err := c.Put(WithTags(tagged, "profiles"), "profile:1", "one", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Put(tagged, "orders:1", "two", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Put(ctx, "other", "three", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := InvalidateTag(ctx, c, "user:1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"profile:1", "orders:1"} {
+		if _, _, err := c.Get(ctx, k); err != cache.ErrKeyNotFound {
+			t.Errorf("Expected %s to be invalidated, got %v", k, err)
+		}
+	}
+	if _, _, err := c.Get(ctx, "other"); err != nil {
+		t.Errorf("Expected untagged key to stay, got %v", err)
+	}
+
+	if err := InvalidateTag(ctx, c, "missing"); err != nil {
+		t.Fatal(err)
+	}
+	if err := InvalidateTag(ctx, cache.NewCache(), "user:1"); err == nil {
+		t.Fatal("Expected an error for another cache")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	if len(os.Getenv("LOCAL")) == 0 {
+		t.Skip()
+	}
+
+	c := NewCache(addr)
+	other := NewCache(addr)
+	c.Delete(ctx, "hot")
+
+	var loads int64
+	fn := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt64(&loads, 1)
+		time.Sleep(100 * time.Millisecond)
+		return "value", nil
+	}
+
+	// concurrent loads of two processes
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(c cache.Cache) {
+			defer wg.Done()
+			v, err := Load(ctx, c, "hot", time.Minute, fn)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if b, ok := v.([]byte); ok {
+				v = string(b)
+			}
+			if v != "value" {
+				t.Errorf("Unexpected value %v", v)
+			}
+		}([]cache.Cache{c, other}[i%2])
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt64(&loads); n != 1 {
+		t.Fatalf("Expected 1 load, got %d", n)
+	}
+}
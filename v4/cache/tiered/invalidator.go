@@ -0,0 +1,102 @@
+package tiered
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/go-redis/redis/v8"
+	"go-micro.dev/v4/broker"
+	"go-micro.dev/v4/logger"
+)
+
+// Invalidator broadcasts the keys changed by an instance to the others, so
+// they drop them from their local caches.
+type Invalidator interface {
+	// Publish tells the other instances the key changed.
+	Publish(ctx context.Context, msg *Message) error
+	// Subscribe calls fn with the messages of all instances.
+	Subscribe(fn func(*Message)) error
+}
+
+// Message is the invalidation of a key.
+type Message struct {
+	// Id is the instance which changed the key
+	Id  string `json:"id"`
+	Key string `json:"key"`
+}
+
+// RedisPubSub broadcasts invalidations on the redis channel.
+func RedisPubSub(client redis.UniversalClient, channel string) Invalidator {
+	return &redisInvalidator{client: client, channel: channel}
+}
+
+type redisInvalidator struct {
+	client  redis.UniversalClient
+	channel string
+}
+
+func (r *redisInvalidator) Publish(ctx context.Context, msg *Message) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return r.client.Publish(ctx, r.channel, b).Err()
+}
+
+func (r *redisInvalidator) Subscribe(fn func(*Message)) error {
+	ctx := context.Background()
+	sub := r.client.Subscribe(ctx, r.channel)
+	// wait for the subscription, invalidations published before it's
+	// confirmed would be missed
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return err
+	}
+
+	go func() {
+		for m := range sub.Channel() {
+			var msg Message
+			if err := json.Unmarshal([]byte(m.Payload), &msg); err != nil {
+				logger.Logf(logger.ErrorLevel, "tiered cache: invalid message: %v", err)
+				continue
+			}
+			fn(&msg)
+		}
+	}()
+	return nil
+}
+
+// Broker broadcasts invalidations on the topic of the broker. Every instance
+// subscribes without a queue, so each receives all of them.
+func Broker(b broker.Broker, topic string) Invalidator {
+	return &brokerInvalidator{broker: b, topic: topic}
+}
+
+type brokerInvalidator struct {
+	broker broker.Broker
+	topic  string
+}
+
+func (b *brokerInvalidator) Publish(ctx context.Context, msg *Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return b.broker.Publish(b.topic, &broker.Message{Body: body})
+}
+
+func (b *brokerInvalidator) Subscribe(fn func(*Message)) error {
+	if err := b.broker.Connect(); err != nil {
+		return err
+	}
+
+	_, err := b.broker.Subscribe(b.topic, func(e broker.Event) error {
+		var msg Message
+		if err := json.Unmarshal(e.Message().Body, &msg); err != nil {
+			return err
+		}
+		fn(&msg)
+		return nil
+	})
+	return err
+}
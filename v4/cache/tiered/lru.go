@@ -0,0 +1,85 @@
+package tiered
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type item struct {
+	key     string
+	value   interface{}
+	expires time.Time
+	// remote is when the key expires in the remote cache
+	remote time.Time
+}
+
+// lru is the local cache, it evicts the least recently used keys beyond its
+// size.
+type lru struct {
+	size int
+
+	sync.Mutex
+	items map[string]*list.Element
+	order *list.List
+}
+
+func newLRU(size int) *lru {
+	return &lru{
+		size:  size,
+		items: make(map[string]*list.Element),
+		order: list.New(),
+	}
+}
+
+func (l *lru) get(key string, now time.Time) (*item, bool) {
+	l.Lock()
+	defer l.Unlock()
+
+	e, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	it := e.Value.(*item)
+	if !now.Before(it.expires) {
+		l.order.Remove(e)
+		delete(l.items, key)
+		return nil, false
+	}
+	l.order.MoveToFront(e)
+	return it, true
+}
+
+func (l *lru) set(it *item) {
+	l.Lock()
+	defer l.Unlock()
+
+	if e, ok := l.items[it.key]; ok {
+		e.Value = it
+		l.order.MoveToFront(e)
+		return
+	}
+
+	l.items[it.key] = l.order.PushFront(it)
+	for l.order.Len() > l.size {
+		last := l.order.Back()
+		l.order.Remove(last)
+		delete(l.items, last.Value.(*item).key)
+	}
+}
+
+func (l *lru) delete(key string) {
+	l.Lock()
+	defer l.Unlock()
+
+	if e, ok := l.items[key]; ok {
+		l.order.Remove(e)
+		delete(l.items, key)
+	}
+}
+
+func (l *lru) len() int {
+	l.Lock()
+	defer l.Unlock()
+	return l.order.Len()
+}
@@ -0,0 +1,54 @@
+package tiered
+
+import (
+	"context"
+	"time"
+
+	"go-micro.dev/v4/cache"
+)
+
+var (
+	// DefaultLocalSize is the number of keys the local cache holds.
+	DefaultLocalSize = 10000
+	// DefaultLocalTTL is how long keys are cached locally at most. It bounds
+	// how long a missed invalidation serves stale values.
+	DefaultLocalTTL = 5 * time.Second
+	// DefaultChannel is the channel or topic invalidations are sent on.
+	DefaultChannel = "micro.cache.invalidate"
+)
+
+type remoteKey struct{}
+type localSizeKey struct{}
+type localTTLKey struct{}
+type invalidatorKey struct{}
+
+func setCacheOption(k, v interface{}) cache.Option {
+	return func(o *cache.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+// Remote sets the shared cache, a redis cache of the address by default.
+func Remote(c cache.Cache) cache.Option {
+	return setCacheOption(remoteKey{}, c)
+}
+
+// LocalSize sets the number of keys cached locally, the least recently used
+// keys are evicted beyond it.
+func LocalSize(n int) cache.Option {
+	return setCacheOption(localSizeKey{}, n)
+}
+
+// LocalTTL sets how long keys are cached locally at most.
+func LocalTTL(d time.Duration) cache.Option {
+	return setCacheOption(localTTLKey{}, d)
+}
+
+// WithInvalidator sets how instances tell each other about changed keys,
+// redis pub/sub on the address by default.
+func WithInvalidator(i Invalidator) cache.Option {
+	return setCacheOption(invalidatorKey{}, i)
+}
@@ -0,0 +1,158 @@
+// Package tiered provides a two-tier cache: a local LRU cache with a short
+// TTL in front of a shared cache, redis by default. Instances broadcast the
+// keys they change, so the others drop them from their local caches.
+package tiered
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	rcache "github.com/go-micro/plugins/v4/cache/redis"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"go-micro.dev/v4/cache"
+	"go-micro.dev/v4/logger"
+	"go-micro.dev/v4/util/cmd"
+)
+
+func init() {
+	cmd.DefaultCaches["tiered"] = NewCache
+}
+
+type tieredCache struct {
+	opts        cache.Options
+	id          string
+	ttl         time.Duration
+	local       *lru
+	remote      cache.Cache
+	invalidator Invalidator
+
+	// gen counts the invalidations, values read from the remote cache
+	// aren't cached locally if a key was invalidated during the read
+	gen uint64
+}
+
+// NewCache returns a two-tier cache. Without options it caches locally in
+// front of redis at the address, and invalidates over redis pub/sub.
+func NewCache(opts ...cache.Option) cache.Cache {
+	options := cache.NewOptions(opts...)
+	if options.Context == nil {
+		options.Context = context.Background()
+	}
+
+	c := &tieredCache{
+		opts: options,
+		id:   uuid.New().String(),
+		ttl:  DefaultLocalTTL,
+	}
+
+	size := DefaultLocalSize
+	if n, ok := options.Context.Value(localSizeKey{}).(int); ok && n > 0 {
+		size = n
+	}
+	c.local = newLRU(size)
+
+	if d, ok := options.Context.Value(localTTLKey{}).(time.Duration); ok && d > 0 {
+		c.ttl = d
+	}
+
+	if r, ok := options.Context.Value(remoteKey{}).(cache.Cache); ok {
+		c.remote = r
+	} else {
+		c.remote = rcache.NewCache(opts...)
+	}
+
+	if i, ok := options.Context.Value(invalidatorKey{}).(Invalidator); ok {
+		c.invalidator = i
+	} else {
+		c.invalidator = RedisPubSub(newClient(options.Address), DefaultChannel)
+	}
+
+	// without invalidations local keys are stale for the local TTL at most
+	if err := c.invalidator.Subscribe(c.invalidated); err != nil {
+		c.log().Logf(logger.ErrorLevel, "tiered cache: subscribing to invalidations: %v", err)
+	}
+
+	return c
+}
+
+func newClient(addr string) redis.UniversalClient {
+	if len(addr) == 0 {
+		addr = "redis://127.0.0.1:6379"
+	}
+	opts, err := redis.ParseURL(addr)
+	if err != nil {
+		opts = &redis.Options{Addr: addr}
+	}
+	return redis.NewClient(opts)
+}
+
+func (c *tieredCache) log() logger.Logger {
+	if c.opts.Logger != nil {
+		return c.opts.Logger
+	}
+	return logger.DefaultLogger
+}
+
+func (c *tieredCache) invalidated(msg *Message) {
+	if msg.Id == c.id {
+		return
+	}
+	atomic.AddUint64(&c.gen, 1)
+	c.local.delete(msg.Key)
+}
+
+func (c *tieredCache) Get(ctx context.Context, key string) (interface{}, time.Time, error) {
+	now := time.Now()
+	if it, ok := c.local.get(key, now); ok {
+		return it.value, it.remote, nil
+	}
+
+	gen := atomic.LoadUint64(&c.gen)
+	val, expires, err := c.remote.Get(ctx, key)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	it := &item{key: key, value: val, expires: now.Add(c.ttl), remote: expires}
+	// caches return a zero or past expiry for keys which don't expire
+	if expires.After(now) && expires.Before(it.expires) {
+		it.expires = expires
+	}
+	if atomic.LoadUint64(&c.gen) == gen {
+		c.local.set(it)
+	}
+
+	return val, expires, nil
+}
+
+// Put writes the value to the remote cache, it's cached locally once read.
+func (c *tieredCache) Put(ctx context.Context, key string, val interface{}, d time.Duration) error {
+	if err := c.remote.Put(ctx, key, val, d); err != nil {
+		return err
+	}
+	c.invalidate(ctx, key)
+	return nil
+}
+
+func (c *tieredCache) Delete(ctx context.Context, key string) error {
+	if err := c.remote.Delete(ctx, key); err != nil {
+		return err
+	}
+	c.invalidate(ctx, key)
+	return nil
+}
+
+func (c *tieredCache) invalidate(ctx context.Context, key string) {
+	atomic.AddUint64(&c.gen, 1)
+	c.local.delete(key)
+
+	if err := c.invalidator.Publish(ctx, &Message{Id: c.id, Key: key}); err != nil {
+		c.log().Logf(logger.ErrorLevel, "tiered cache: publishing invalidation of %s: %v", key, err)
+	}
+}
+
+func (c *tieredCache) String() string {
+	return "tiered"
+}
@@ -0,0 +1,126 @@
+package tiered
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/broker"
+	"go-micro.dev/v4/cache"
+)
+
+// counting counts the reads of the remote cache.
+type counting struct {
+	cache.Cache
+	gets int64
+}
+
+func (c *counting) Get(ctx context.Context, key string) (interface{}, time.Time, error) {
+	atomic.AddInt64(&c.gets, 1)
+	return c.Cache.Get(ctx, key)
+}
+
+func newCaches(t *testing.T, opts ...cache.Option) (*counting, cache.Cache, cache.Cache) {
+	remote := &counting{Cache: cache.NewCache()}
+	b := broker.NewMemoryBroker()
+
+	opts = append([]cache.Option{
+		Remote(remote),
+		WithInvalidator(Broker(b, DefaultChannel)),
+	}, opts...)
+	return remote, NewCache(opts...), NewCache(opts...)
+}
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+	remote, a, _ := newCaches(t)
+
+	if _, _, err := a.Get(ctx, "foo"); err != cache.ErrKeyNotFound {
+		t.Fatalf("Expected key not found, got %v", err)
+	}
+
+	if err := a.Put(ctx, "foo", "bar", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		v, expires, err := a.Get(ctx, "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != "bar" || time.Until(expires) <= 0 {
+			t.Fatalf("Unexpected value %v expiring %v", v, expires)
+		}
+	}
+	if n := atomic.LoadInt64(&remote.gets); n != 2 {
+		t.Fatalf("Expected 2 remote reads, got %d", n)
+	}
+}
+
+func TestInvalidation(t *testing.T) {
+	ctx := context.Background()
+	_, a, b := newCaches(t)
+
+	a.Put(ctx, "foo", "bar", 0)
+	if v, _, _ := b.Get(ctx, "foo"); v != "bar" {
+		t.Fatalf("Expected bar, got %v", v)
+	}
+
+	// the put of a drops the key from the local cache of b
+	a.Put(ctx, "foo", "baz", 0)
+	if v, _, _ := b.Get(ctx, "foo"); v != "baz" {
+		t.Fatalf("Expected baz after invalidation, got %v", v)
+	}
+
+	a.Delete(ctx, "foo")
+	if _, _, err := b.Get(ctx, "foo"); err != cache.ErrKeyNotFound {
+		t.Fatalf("Expected key not found after delete, got %v", err)
+	}
+}
+
+func TestLocalTTL(t *testing.T) {
+	ctx := context.Background()
+	remote, a, _ := newCaches(t, LocalTTL(20*time.Millisecond))
+
+	// changed without invalidation
+	a.Put(ctx, "foo", "bar", 0)
+	a.Get(ctx, "foo")
+	remote.Put(ctx, "foo", "baz", 0)
+
+	if v, _, _ := a.Get(ctx, "foo"); v != "bar" {
+		t.Fatalf("Expected the local value, got %v", v)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if v, _, _ := a.Get(ctx, "foo"); v != "baz" {
+		t.Fatalf("Expected the remote value after the local ttl, got %v", v)
+	}
+
+	// keys expiring remotely expire locally
+	a.Put(ctx, "short", "lived", 10*time.Millisecond)
+	a.Get(ctx, "short")
+	time.Sleep(15 * time.Millisecond)
+	if _, _, err := a.Get(ctx, "short"); err == nil {
+		t.Fatal("Expected the key to expire locally")
+	}
+}
+
+func TestLRU(t *testing.T) {
+	l := newLRU(2)
+	now := time.Now()
+	later := now.Add(time.Minute)
+
+	l.set(&item{key: "a", expires: later})
+	l.set(&item{key: "b", expires: later})
+	l.get("a", now)
+	l.set(&item{key: "c", expires: later})
+
+	if _, ok := l.get("b", now); ok {
+		t.Fatal("Expected the least recently used key to be evicted")
+	}
+	if _, ok := l.get("a", now); !ok {
+		t.Fatal("Expected a recently used key to stay")
+	}
+	if l.len() != 2 {
+		t.Fatalf("Expected 2 keys, got %d", l.len())
+	}
+}
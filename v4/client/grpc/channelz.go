@@ -0,0 +1,228 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"go-micro.dev/v4/client"
+	"google.golang.org/grpc"
+	channelzpb "google.golang.org/grpc/channelz/grpc_channelz_v1"
+	"google.golang.org/grpc/channelz/service"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// TargetStats are the pooled connections to a target by connectivity state.
+type TargetStats struct {
+	Target string `json:"target"`
+	// Conns is the number of pooled connections
+	Conns int `json:"conns"`
+	// Streams is the number of streams in use
+	Streams int `json:"streams"`
+	// States counts the connections by state, e.g. READY or
+	// TRANSIENT_FAILURE
+	States map[string]int `json:"states"`
+	// Dials is the number of connections dialed since the client started,
+	// a fast growing count hints at a connection storm
+	Dials int64 `json:"dials"`
+	// FailedDials is the number of dials which failed
+	FailedDials int64 `json:"failed_dials"`
+	// Idle is the number of pooled connections without streams
+	Idle int `json:"idle"`
+	// Reaped is the number of idle connections closed by the reaper as they
+	// were unused for longer than PoolMaxIdleTime, or their peer was gone
+	Reaped int64 `json:"reaped"`
+	// Trimmed is the number of idle connections closed by the reaper as the
+	// heap was above PoolMemoryLimit
+	Trimmed int64 `json:"trimmed"`
+}
+
+func stateName(s connectivity.State) string {
+	switch s {
+	case connectivity.Idle:
+		return "IDLE"
+	case connectivity.Connecting:
+		return "CONNECTING"
+	case connectivity.Ready:
+		return "READY"
+	case connectivity.TransientFailure:
+		return "TRANSIENT_FAILURE"
+	case connectivity.Shutdown:
+		return "SHUTDOWN"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+func (p *pool) stats() []TargetStats {
+	p.Lock()
+	defer p.Unlock()
+
+	targets := make(map[string]*TargetStats)
+	target := func(addr string) *TargetStats {
+		ts, ok := targets[addr]
+		if !ok {
+			ts = &TargetStats{Target: addr, States: make(map[string]int)}
+			targets[addr] = ts
+		}
+		return ts
+	}
+
+	for addr, sp := range p.conns {
+		ts := target(addr)
+		for _, head := range []*poolConn{sp.head, sp.busy} {
+			for conn := head.next; conn != nil; conn = conn.next {
+				ts.Conns++
+				ts.Streams += conn.streams
+				if conn.streams == 0 {
+					ts.Idle++
+				}
+				ts.States[stateName(conn.GetState())]++
+			}
+		}
+	}
+
+	for addr, ds := range p.dials {
+		ts := target(addr)
+		ts.Dials = ds.dials
+		ts.FailedDials = ds.failed
+		ts.Reaped = ds.reaped
+		ts.Trimmed = ds.trimmed
+	}
+
+	stats := make([]TargetStats, 0, len(targets))
+	for _, ts := range targets {
+		stats = append(stats, *ts)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Target < stats[j].Target
+	})
+
+	return stats
+}
+
+// Stats returns the connectivity state of the pooled connections of a grpc
+// client by target, false if c isn't a grpc client. Clients wrapped by a
+// client wrapper aren't grpc clients, keep a reference to the client before
+// wrapping it.
+func Stats(c client.Client) ([]TargetStats, bool) {
+	g, ok := c.(*grpcClient)
+	if !ok {
+		return nil, false
+	}
+	return g.pool.stats(), true
+}
+
+// registrar captures the channelz service implementation, to query it
+// without serving it.
+type registrar struct {
+	impl channelzpb.ChannelzServer
+}
+
+func (r *registrar) RegisterService(desc *grpc.ServiceDesc, impl interface{}) {
+	r.impl, _ = impl.(channelzpb.ChannelzServer)
+}
+
+// channelz returns the channelz service, importing it turns channelz on.
+func channelz() channelzpb.ChannelzServer {
+	r := &registrar{}
+	service.RegisterChannelzServiceToServer(r)
+	return r.impl
+}
+
+// Handler returns an admin handler serving the stats of the client, its
+// payloads and the channelz data of the process as JSON. GET / returns the stats and top
+// channels, GET /?channel=<id> returns a channel and its subchannels. It
+// should only be exposed internally.
+func Handler(c client.Client) http.Handler {
+	cz := channelz()
+	marshaler := protojson.MarshalOptions{}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		ctx := r.Context()
+		rsp := make(map[string]interface{})
+
+		if id := r.URL.Query().Get("channel"); len(id) > 0 {
+			cid, err := strconv.ParseInt(id, 10, 64)
+			if err != nil {
+				http.Error(w, "invalid channel id", http.StatusBadRequest)
+				return
+			}
+
+			ch, err := channel(ctx, cz, cid, marshaler)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			rsp["channel"] = ch
+		} else {
+			if stats, ok := Stats(c); ok {
+				rsp["targets"] = stats
+			}
+			if payloads, ok := Payloads(c); ok {
+				rsp["payloads"] = payloads
+			}
+
+			top, err := cz.GetTopChannels(ctx, &channelzpb.GetTopChannelsRequest{})
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			b, err := marshaler.Marshal(top)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			rsp["channelz"] = json.RawMessage(b)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		json.NewEncoder(w).Encode(rsp)
+	})
+}
+
+// channel returns a channel with its subchannels.
+func channel(ctx context.Context, cz channelzpb.ChannelzServer, id int64, m protojson.MarshalOptions) (map[string]json.RawMessage, error) {
+	ch, err := cz.GetChannel(ctx, &channelzpb.GetChannelRequest{ChannelId: id})
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := m.Marshal(ch.GetChannel())
+	if err != nil {
+		return nil, err
+	}
+
+	rsp := map[string]json.RawMessage{"channel": b}
+
+	var subchannels []json.RawMessage
+	for _, ref := range ch.GetChannel().GetSubchannelRef() {
+		sc, err := cz.GetSubchannel(ctx, &channelzpb.GetSubchannelRequest{SubchannelId: ref.GetSubchannelId()})
+		if err != nil {
+			continue
+		}
+		b, err := m.Marshal(sc.GetSubchannel())
+		if err != nil {
+			continue
+		}
+		subchannels = append(subchannels, b)
+	}
+
+	b, err = json.Marshal(subchannels)
+	if err != nil {
+		return nil, err
+	}
+	rsp["subchannels"] = b
+
+	return rsp, nil
+}
@@ -0,0 +1,86 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/selector"
+	pgrpc "google.golang.org/grpc"
+	pb "google.golang.org/grpc/examples/helloworld/helloworld"
+)
+
+func TestStats(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer l.Close()
+
+	s := pgrpc.NewServer()
+	pb.RegisterGreeterServer(s, &greeterServer{})
+
+	go s.Serve(l)
+	defer s.Stop()
+
+	r := registry.NewMemoryRegistry()
+	r.Register(&registry.Service{
+		Name: "helloworld",
+		Nodes: []*registry.Node{{
+			Id:       "test-1",
+			Address:  l.Addr().String(),
+			Metadata: map[string]string{"protocol": "grpc"},
+		}},
+	})
+
+	c := NewClient(
+		client.Registry(r),
+		client.Selector(selector.NewSelector(selector.Registry(r))),
+	)
+
+	req := c.NewRequest("helloworld", "Greeter.SayHello", &pb.HelloRequest{Name: "John"})
+	if err := c.Call(context.Background(), req, new(pb.HelloReply)); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, ok := Stats(c)
+	if !ok {
+		t.Fatal("Expected stats of a grpc client")
+	}
+	if len(stats) != 1 {
+		t.Fatalf("Expected stats of 1 target, got %+v", stats)
+	}
+
+	ts := stats[0]
+	if ts.Target != l.Addr().String() || ts.Conns != 1 || ts.Dials != 1 || ts.States["READY"] != 1 || ts.Streams != 0 {
+		t.Fatalf("Unexpected stats %+v", ts)
+	}
+
+	if _, ok := Stats(client.NewClient()); ok {
+		t.Fatal("Expected no stats of another client")
+	}
+
+	w := httptest.NewRecorder()
+	Handler(c).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var rsp struct {
+		Targets  []TargetStats `json:"targets"`
+		Channelz struct {
+			Channel []json.RawMessage `json:"channel"`
+		} `json:"channelz"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &rsp); err != nil {
+		t.Fatal(err)
+	}
+	if len(rsp.Targets) != 1 || len(rsp.Channelz.Channel) == 0 {
+		t.Fatalf("Expected the targets and channels, got %s", w.Body.String())
+	}
+}
@@ -0,0 +1,114 @@
+package grpc
+
+import (
+	"context"
+	"io"
+
+	raw "go-micro.dev/v4/codec/bytes"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	gmetadata "google.golang.org/grpc/metadata"
+)
+
+const (
+	// chunkedHeader marks a request or response sent in chunks
+	chunkedHeader = "x-micro-chunked"
+	// acceptChunkedHeader tells the server the client reassembles chunked
+	// responses
+	acceptChunkedHeader = "x-micro-accept-chunked"
+)
+
+var chunkedDesc = &grpc.StreamDesc{
+	ClientStreams: true,
+	ServerStreams: true,
+}
+
+func (g *grpcClient) chunkSizeValue() int {
+	if g.opts.Context == nil {
+		return 0
+	}
+	v, ok := g.opts.Context.Value(chunkSizeKey{}).(int)
+	if !ok {
+		return 0
+	}
+	return v
+}
+
+// split splits b into chunks of at most size bytes.
+func split(b []byte, size int) [][]byte {
+	chunks := make([][]byte, 0, len(b)/size+1)
+	for len(b) > size {
+		chunks = append(chunks, b[:size])
+		b = b[size:]
+	}
+	return append(chunks, b)
+}
+
+// invokeChunked calls the method over a stream, sending the request in
+// chunks if it's larger than size and reassembling chunked responses.
+func invokeChunked(ctx context.Context, cc grpc.ClientConnInterface, cf encoding.Codec, method string, req, rsp interface{}, size int, opts ...grpc.CallOption) error {
+	b, err := cf.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	chunks := [][]byte{b}
+	if len(b) > size {
+		chunks = split(b, size)
+		ctx = gmetadata.AppendToOutgoingContext(ctx, chunkedHeader, "true")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	st, err := cc.NewStream(ctx, chunkedDesc, method, opts...)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range chunks {
+		if err := st.SendMsg(&raw.Frame{Data: c}); err != nil {
+			// the actual error is returned by RecvMsg
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+	}
+	if err := st.CloseSend(); err != nil {
+		return err
+	}
+
+	f := &raw.Frame{}
+	if err := st.RecvMsg(f); err != nil {
+		return err
+	}
+
+	md, err := st.Header()
+	if err != nil {
+		return err
+	}
+
+	body := f.Data
+	if len(md.Get(chunkedHeader)) > 0 {
+		body = append([]byte(nil), body...)
+		for {
+			f := &raw.Frame{}
+			err := st.RecvMsg(f)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+			body = append(body, f.Data...)
+		}
+	} else if err := st.RecvMsg(&raw.Frame{}); err != io.EOF {
+		// a unary response is followed by the status
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	return cf.Unmarshal(body, rsp)
+}
@@ -0,0 +1,21 @@
+package grpc
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	b := bytes.Repeat([]byte("a"), 2500)
+
+	chunks := split(b, 1000)
+	if len(chunks) != 3 {
+		t.Fatalf("expected 3 chunks, got %d", len(chunks))
+	}
+	if len(chunks[2]) != 500 {
+		t.Fatalf("expected last chunk of 500 bytes, got %d", len(chunks[2]))
+	}
+	if !bytes.Equal(bytes.Join(chunks, nil), b) {
+		t.Fatal("chunks don't add up to the message")
+	}
+}
@@ -0,0 +1,197 @@
+package grpc
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"go-micro.dev/v4/client"
+	raw "go-micro.dev/v4/codec/bytes"
+	// registers the gzip compressor
+	_ "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/runtime/protoiface"
+	"google.golang.org/protobuf/runtime/protoimpl"
+)
+
+type compressorKey struct{}
+type minCompressSizeKey struct{}
+
+// Compressor compresses the messages of the call with the named compressor,
+// e.g. gzip. Compressors are registered with encoding.RegisterCompressor,
+// the server must have it registered too.
+func Compressor(name string) client.CallOption {
+	return func(o *client.CallOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, compressorKey{}, name)
+	}
+}
+
+// MinCompressSize only compresses requests of calls of at least n bytes,
+// as compressing small messages costs more than it saves. The size of
+// messages other than protobuf and bytes isn't known before marshaling,
+// they're always compressed. Streams don't apply it.
+func MinCompressSize(n int) client.CallOption {
+	return func(o *client.CallOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, minCompressSizeKey{}, n)
+	}
+}
+
+// compressor returns the compressor of the call, empty if the call isn't
+// compressed.
+func compressor(opts client.CallOptions, body interface{}) string {
+	if opts.Context == nil {
+		return ""
+	}
+	name, _ := opts.Context.Value(compressorKey{}).(string)
+	if len(name) == 0 {
+		return ""
+	}
+	if min, ok := opts.Context.Value(minCompressSizeKey{}).(int); ok && body != nil {
+		if n, ok := size(body); ok && n < min {
+			return ""
+		}
+	}
+	return name
+}
+
+func size(v interface{}) (int, bool) {
+	switch m := v.(type) {
+	case *raw.Frame:
+		return len(m.Data), true
+	case []byte:
+		return len(m), true
+	case proto.Message:
+		return proto.Size(m), true
+	case protoiface.MessageV1:
+		return proto.Size(protoimpl.X.ProtoMessageV2Of(m)), true
+	}
+	return 0, false
+}
+
+// PayloadStats are the bytes of messages of an endpoint before and after
+// compression. Compressed bytes include the 5 byte message header of gRPC.
+type PayloadStats struct {
+	// Endpoint is the gRPC method, e.g. /helloworld.Greeter/SayHello
+	Endpoint string `json:"endpoint"`
+	// Compressor is the compressor of the calls, empty if uncompressed
+	Compressor string `json:"compressor,omitempty"`
+	// Calls is the number of calls
+	Calls int64 `json:"calls"`
+	// SentBytes is the size of the sent messages
+	SentBytes int64 `json:"sent_bytes"`
+	// SentWireBytes is the size of the sent messages on the wire
+	SentWireBytes int64 `json:"sent_wire_bytes"`
+	// ReceivedBytes is the size of the received messages
+	ReceivedBytes int64 `json:"received_bytes"`
+	// ReceivedWireBytes is the size of the received messages on the wire
+	ReceivedWireBytes int64 `json:"received_wire_bytes"`
+}
+
+// payloadKey is the endpoint and compressor of a call.
+type payloadKey struct {
+	endpoint, compressor string
+}
+
+// payloads counts the bytes of messages, it's the stats handler of the
+// connections of the pool.
+type payloads struct {
+	sync.RWMutex
+	stats map[payloadKey]*PayloadStats
+}
+
+func newPayloads() *payloads {
+	return &payloads{stats: make(map[payloadKey]*PayloadStats)}
+}
+
+func (p *payloads) get(k payloadKey) *PayloadStats {
+	p.RLock()
+	ps, ok := p.stats[k]
+	p.RUnlock()
+	if ok {
+		return ps
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	if ps, ok = p.stats[k]; !ok {
+		ps = &PayloadStats{Endpoint: k.endpoint, Compressor: k.compressor}
+		p.stats[k] = ps
+	}
+	return ps
+}
+
+type endpointKey struct{}
+
+func (p *payloads) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	return context.WithValue(ctx, endpointKey{}, info.FullMethodName)
+}
+
+func (p *payloads) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	if !s.IsClient() {
+		return
+	}
+	endpoint, _ := ctx.Value(endpointKey{}).(string)
+	compressor, _ := ctx.Value(compressorKey{}).(string)
+	ps := p.get(payloadKey{endpoint, compressor})
+
+	switch s := s.(type) {
+	case *stats.Begin:
+		atomic.AddInt64(&ps.Calls, 1)
+	case *stats.OutPayload:
+		atomic.AddInt64(&ps.SentBytes, int64(s.Length))
+		atomic.AddInt64(&ps.SentWireBytes, int64(s.WireLength))
+	case *stats.InPayload:
+		atomic.AddInt64(&ps.ReceivedBytes, int64(s.Length))
+		atomic.AddInt64(&ps.ReceivedWireBytes, int64(s.WireLength))
+	}
+}
+
+func (p *payloads) TagConn(ctx context.Context, info *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (p *payloads) HandleConn(ctx context.Context, s stats.ConnStats) {}
+
+func (p *payloads) list() []PayloadStats {
+	p.RLock()
+	defer p.RUnlock()
+
+	list := make([]PayloadStats, 0, len(p.stats))
+	for _, ps := range p.stats {
+		list = append(list, PayloadStats{
+			Endpoint:          ps.Endpoint,
+			Compressor:        ps.Compressor,
+			Calls:             atomic.LoadInt64(&ps.Calls),
+			SentBytes:         atomic.LoadInt64(&ps.SentBytes),
+			SentWireBytes:     atomic.LoadInt64(&ps.SentWireBytes),
+			ReceivedBytes:     atomic.LoadInt64(&ps.ReceivedBytes),
+			ReceivedWireBytes: atomic.LoadInt64(&ps.ReceivedWireBytes),
+		})
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Endpoint != list[j].Endpoint {
+			return list[i].Endpoint < list[j].Endpoint
+		}
+		return list[i].Compressor < list[j].Compressor
+	})
+	return list
+}
+
+// Payloads returns the bytes of messages of a grpc client by endpoint and
+// compressor, false if c isn't a grpc client.
+func Payloads(c client.Client) ([]PayloadStats, bool) {
+	g, ok := c.(*grpcClient)
+	if !ok {
+		return nil, false
+	}
+	return g.payloads.list(), true
+}
@@ -0,0 +1,84 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/selector"
+	pgrpc "google.golang.org/grpc"
+	pb "google.golang.org/grpc/examples/helloworld/helloworld"
+)
+
+func TestCompressor(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer l.Close()
+
+	s := pgrpc.NewServer()
+	pb.RegisterGreeterServer(s, &greeterServer{})
+
+	go s.Serve(l)
+	defer s.Stop()
+
+	r := registry.NewMemoryRegistry()
+	r.Register(&registry.Service{
+		Name: "helloworld",
+		Nodes: []*registry.Node{{
+			Id:       "test-1",
+			Address:  l.Addr().String(),
+			Metadata: map[string]string{"protocol": "grpc"},
+		}},
+	})
+
+	c := NewClient(
+		client.Registry(r),
+		client.Selector(selector.NewSelector(selector.Registry(r))),
+	)
+
+	name := strings.Repeat("John", 1024)
+	req := c.NewRequest("helloworld", "Greeter.SayHello", &pb.HelloRequest{Name: name})
+
+	// compressed, uncompressed and below the min size
+	calls := [][]client.CallOption{
+		{Compressor("gzip")},
+		nil,
+		{Compressor("gzip"), MinCompressSize(len(name) * 2)},
+	}
+	for _, opts := range calls {
+		rsp := new(pb.HelloReply)
+		if err := c.Call(context.Background(), req, rsp, opts...); err != nil {
+			t.Fatal(err)
+		}
+		if rsp.Message != "Hello "+name {
+			t.Fatalf("Unexpected response %.32s", rsp.Message)
+		}
+	}
+
+	payloads, ok := Payloads(c)
+	if !ok {
+		t.Fatal("Expected payloads of a grpc client")
+	}
+	if len(payloads) != 2 {
+		t.Fatalf("Expected payloads of 2 compressors, got %+v", payloads)
+	}
+
+	plain, gzip := payloads[0], payloads[1]
+	if plain.Endpoint != "/helloworld.Greeter/SayHello" || plain.Compressor != "" || plain.Calls != 2 {
+		t.Fatalf("Unexpected uncompressed payloads %+v", plain)
+	}
+	if plain.SentWireBytes != plain.SentBytes+2*5 {
+		t.Fatalf("Expected uncompressed wire bytes with headers, got %+v", plain)
+	}
+	if gzip.Compressor != "gzip" || gzip.Calls != 1 || gzip.SentBytes != plain.SentBytes/2 {
+		t.Fatalf("Unexpected gzip payloads %+v", gzip)
+	}
+	if gzip.SentWireBytes*10 > gzip.SentBytes {
+		t.Fatalf("Expected compressed wire bytes, got %+v", gzip)
+	}
+}
@@ -3,6 +3,7 @@ module github.com/go-micro/plugins/v4/client/grpc
 go 1.17
 
 require (
+	github.com/go-micro/plugins/v4/util/socket v1.0.0
 	go-micro.dev/v4 v4.9.0
 	google.golang.org/grpc v1.42.0
 	google.golang.org/grpc/examples v0.0.0-20211102180624-670c133e568e
@@ -47,3 +48,5 @@ require (
 	gopkg.in/warnings.v0 v0.1.2 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
+
+replace github.com/go-micro/plugins/v4/util/socket => ../../util/socket
@@ -27,9 +27,10 @@ import (
 )
 
 type grpcClient struct {
-	opts client.Options
-	pool *pool
-	once atomic.Value
+	opts     client.Options
+	pool     *pool
+	payloads *payloads
+	once     atomic.Value
 }
 
 func init() {
@@ -117,6 +118,11 @@ func (g *grpcClient) call(ctx context.Context, node *registry.Node, req client.R
 	// set the content type for the request
 	header["x-content-type"] = req.ContentType()
 
+	chunkSize := g.chunkSizeValue()
+	if chunkSize > 0 {
+		header[acceptChunkedHeader] = "true"
+	}
+
 	md := gmetadata.New(header)
 	ctx = gmetadata.NewOutgoingContext(ctx, md)
 
@@ -145,6 +151,7 @@ func (g *grpcClient) call(ctx context.Context, node *registry.Node, req client.R
 			grpc.MaxCallRecvMsgSize(maxRecvMsgSize),
 			grpc.MaxCallSendMsgSize(maxSendMsgSize),
 		),
+		grpc.WithStatsHandler(g.payloads),
 	}
 
 	if opts := g.getGrpcDialOptions(); opts != nil {
@@ -160,16 +167,31 @@ func (g *grpcClient) call(ctx context.Context, node *registry.Node, req client.R
 		g.pool.release(address, cc, grr)
 	}()
 
+	// the stats of payloads are by compressor
+	comp := compressor(opts, req.Body())
+	if len(comp) > 0 {
+		ctx = context.WithValue(ctx, compressorKey{}, comp)
+	}
+
 	ch := make(chan error, 1)
 
 	go func() {
 		grpcCallOptions := []grpc.CallOption{
 			grpc.ForceCodec(cf),
 			grpc.CallContentSubtype(cf.Name())}
+		if len(comp) > 0 {
+			grpcCallOptions = append(grpcCallOptions, grpc.UseCompressor(comp))
+		}
 		if opts := callOpts(opts); opts != nil {
 			grpcCallOptions = append(grpcCallOptions, opts...)
 		}
-		err := cc.Invoke(ctx, methodToGRPC(req.Service(), req.Endpoint()), req.Body(), rsp, grpcCallOptions...)
+		method := methodToGRPC(req.Service(), req.Endpoint())
+		var err error
+		if chunkSize > 0 {
+			err = invokeChunked(ctx, cc, cf, method, req.Body(), rsp, chunkSize, grpcCallOptions...)
+		} else {
+			err = cc.Invoke(ctx, method, req.Body(), rsp, grpcCallOptions...)
+		}
 		ch <- microError(err)
 	}()
 
@@ -225,6 +247,7 @@ func (g *grpcClient) stream(ctx context.Context, node *registry.Node, req client
 
 	grpcDialOptions := []grpc.DialOption{
 		g.secure(address),
+		grpc.WithStatsHandler(g.payloads),
 	}
 
 	if opts := g.getGrpcDialOptions(); opts != nil {
@@ -246,6 +269,10 @@ func (g *grpcClient) stream(ctx context.Context, node *registry.Node, req client
 		grpc.ForceCodec(wc),
 		grpc.CallContentSubtype(cf.Name()),
 	}
+	if comp := compressor(opts, nil); len(comp) > 0 {
+		grpcCallOptions = append(grpcCallOptions, grpc.UseCompressor(comp))
+		ctx = context.WithValue(ctx, compressorKey{}, comp)
+	}
 	if opts := callOpts(opts); opts != nil {
 		grpcCallOptions = append(grpcCallOptions, opts...)
 	}
@@ -319,6 +346,28 @@ func (g *grpcClient) poolMaxIdle() int {
 	return v.(int)
 }
 
+func (g *grpcClient) poolMaxIdleTime() time.Duration {
+	if g.opts.Context == nil {
+		return 0
+	}
+	v, ok := g.opts.Context.Value(poolMaxIdleTime{}).(time.Duration)
+	if !ok {
+		return 0
+	}
+	return v
+}
+
+func (g *grpcClient) poolMemoryLimit() uint64 {
+	if g.opts.Context == nil {
+		return 0
+	}
+	v, ok := g.opts.Context.Value(poolMemoryLimit{}).(uint64)
+	if !ok {
+		return 0
+	}
+	return v
+}
+
 func (g *grpcClient) maxRecvMsgSizeValue() int {
 	if g.opts.Context == nil {
 		return DefaultMaxRecvMsgSize
@@ -373,6 +422,8 @@ func (g *grpcClient) Init(opts ...client.Option) error {
 		g.pool.Unlock()
 	}
 
+	g.pool.startReaper(g.poolMaxIdleTime(), g.poolMemoryLimit())
+
 	return nil
 }
 
@@ -683,11 +734,13 @@ func newClient(opts ...client.Option) client.Client {
 	}
 
 	rc := &grpcClient{
-		opts: options,
+		opts:     options,
+		payloads: newPayloads(),
 	}
 	rc.once.Store(false)
 
 	rc.pool = newPool(options.PoolSize, options.PoolTTL, rc.poolMaxIdle(), rc.poolMaxStreams())
+	rc.pool.startReaper(rc.poolMaxIdleTime(), rc.poolMemoryLimit())
 
 	c := client.Client(rc)
 
@@ -2,9 +2,11 @@ package grpc
 
 import (
 	"context"
+	"net"
 	"sync"
 	"time"
 
+	"github.com/go-micro/plugins/v4/util/socket"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/connectivity"
 )
@@ -18,8 +20,28 @@ type pool struct {
 	//  max idle conns
 	maxIdle int
 
+	//  close idle conns unused for longer
+	idleTime time.Duration
+	//  close all idle conns while the heap is larger
+	memLimit uint64
+	//  the reaper was started
+	reaping bool
+
 	sync.Mutex
 	conns map[string]*streamsPool
+	//  dials, failed dials and reaped conns by target
+	dials map[string]*dialStats
+	//  last time targets without conns were pruned
+	pruned time.Time
+}
+
+type dialStats struct {
+	dials   int64
+	failed  int64
+	reaped  int64
+	trimmed int64
+	//  last time a conn of the target was dialed
+	used time.Time
 }
 
 type streamsPool struct {
@@ -31,6 +53,8 @@ type streamsPool struct {
 	count int
 	//  idle conn
 	idle int
+	//  conns being dialed
+	dialing int
 }
 
 type poolConn struct {
@@ -49,6 +73,9 @@ type poolConn struct {
 	pre  *poolConn
 	next *poolConn
 	in   bool
+
+	//  last time a stream was released
+	used time.Time
 }
 
 func newPool(size int, ttl time.Duration, idle int, ms int) *pool {
@@ -64,6 +91,7 @@ func newPool(size int, ttl time.Duration, idle int, ms int) *pool {
 		maxStreams: ms,
 		maxIdle:    idle,
 		conns:      make(map[string]*streamsPool),
+		dials:      make(map[string]*dialStats),
 	}
 }
 
@@ -133,17 +161,22 @@ func (p *pool) getConn(dialCtx context.Context, addr string, opts ...grpc.DialOp
 		p.Unlock()
 		return conn, nil
 	}
+	sp.dialing++
 	p.Unlock()
 
 	//  create new conn
-	cc, err := grpc.DialContext(dialCtx, addr, opts...)
+	cc, err := grpc.DialContext(dialCtx, target(addr), dialOptions(addr, opts)...)
+
+	p.Lock()
+	sp.dialing--
+	p.dialed(addr, err)
 	if err != nil {
+		p.Unlock()
 		return nil, err
 	}
-	conn = &poolConn{cc, nil, addr, p, sp, 1, time.Now().Unix(), nil, nil, false}
+	conn = &poolConn{cc, nil, addr, p, sp, 1, time.Now().Unix(), nil, nil, false, time.Now()}
 
 	//  add conn to streams pool
-	p.Lock()
 	if sp.count < p.size {
 		addConnAfter(conn, sp.head)
 	}
@@ -152,9 +185,64 @@ func (p *pool) getConn(dialCtx context.Context, addr string, opts ...grpc.DialOp
 	return conn, nil
 }
 
+// dialed counts the dial of the target, the pool must be locked.
+func (p *pool) dialed(addr string, err error) {
+	now := time.Now()
+
+	ds := p.dialStats(addr)
+	ds.dials++
+	if err != nil {
+		ds.failed++
+	}
+	ds.used = now
+
+	if now.Sub(p.pruned) > DefaultPoolStatsRetention {
+		p.prune(now)
+	}
+}
+
+// prune removes the targets without conns which weren't used within
+// DefaultPoolStatsRetention, along with their stats, so targets which went
+// away aren't kept forever. The pool must be locked.
+func (p *pool) prune(now time.Time) {
+	p.pruned = now
+
+	for addr, sp := range p.conns {
+		if sp.head.next != nil || sp.busy.next != nil || sp.dialing > 0 {
+			continue
+		}
+		if ds, ok := p.dials[addr]; ok && now.Sub(ds.used) <= DefaultPoolStatsRetention {
+			continue
+		}
+		delete(p.conns, addr)
+	}
+
+	for addr, ds := range p.dials {
+		if _, ok := p.conns[addr]; !ok && now.Sub(ds.used) > DefaultPoolStatsRetention {
+			delete(p.dials, addr)
+		}
+	}
+}
+
+// dialStats returns the stats of the target, the pool must be locked.
+func (p *pool) dialStats(addr string) *dialStats {
+	ds, ok := p.dials[addr]
+	if !ok {
+		ds = &dialStats{}
+		p.dials[addr] = ds
+	}
+	return ds
+}
+
 func (p *pool) release(addr string, conn *poolConn, err error) {
 	p.Lock()
 	p, sp, created := conn.pool, conn.sp, conn.created
+	//  the target was pruned
+	if p.conns[addr] != sp {
+		p.Unlock()
+		conn.ClientConn.Close()
+		return
+	}
 	//  try to add conn
 	if !conn.in && sp.count < p.size {
 		addConnAfter(conn, sp.head)
@@ -183,6 +271,7 @@ func (p *pool) release(addr string, conn *poolConn, err error) {
 			return
 		}
 		sp.idle++
+		conn.used = time.Now()
 	}
 	p.Unlock()
 }
@@ -216,3 +305,24 @@ func addConnAfter(conn *poolConn, after *poolConn) {
 	conn.sp.count++
 	return
 }
+
+// target returns the target to dial the address. grpc doesn't dial named
+// pipes, so local sockets are dialed by dialOptions.
+func target(addr string) string {
+	if socket.Local(addr) {
+		return "passthrough:///" + addr
+	}
+	return addr
+}
+
+func dialOptions(addr string, opts []grpc.DialOption) []grpc.DialOption {
+	if !socket.Local(addr) {
+		return opts
+	}
+	return append(opts,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return socket.Dial(ctx, addr)
+		}),
+		grpc.WithAuthority("localhost"),
+	)
+}
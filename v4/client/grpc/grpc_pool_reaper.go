@@ -0,0 +1,106 @@
+package grpc
+
+import (
+	"runtime"
+	"time"
+
+	"google.golang.org/grpc/connectivity"
+)
+
+// DefaultPoolReapInterval is the longest interval at which the reaper checks
+// the pool for idle conns (10 seconds).
+var DefaultPoolReapInterval = time.Second * 10
+
+// DefaultPoolStatsRetention is how long the stats of a target without conns
+// are kept after it was last used (10 minutes).
+var DefaultPoolStatsRetention = time.Minute * 10
+
+// startReaper configures the reaper and starts it once either limit is set.
+func (p *pool) startReaper(idleTime time.Duration, memLimit uint64) {
+	p.Lock()
+	p.idleTime = idleTime
+	p.memLimit = memLimit
+	start := !p.reaping && (idleTime > 0 || memLimit > 0)
+	if start {
+		p.reaping = true
+	}
+	p.Unlock()
+
+	if start {
+		go p.reaper()
+	}
+}
+
+func (p *pool) reaper() {
+	for {
+		p.Lock()
+		interval := DefaultPoolReapInterval
+		if d := p.idleTime / 2; d > 0 && d < interval {
+			interval = d
+		}
+		p.Unlock()
+
+		time.Sleep(interval)
+		p.reap(time.Now(), p.pressure())
+	}
+}
+
+// pressure reports whether the heap is larger than the memory limit.
+func (p *pool) pressure() bool {
+	p.Lock()
+	limit := p.memLimit
+	p.Unlock()
+
+	if limit == 0 {
+		return false
+	}
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms.HeapInuse > limit
+}
+
+// reap closes the idle conns which are unused for longer than the idle time,
+// or whose keepalive found the peer gone. Under memory pressure all idle
+// conns are closed.
+func (p *pool) reap(now time.Time, pressure bool) {
+	var closed []*poolConn
+
+	p.Lock()
+	for addr, sp := range p.conns {
+		for conn := sp.head.next; conn != nil; {
+			next := conn.next
+			if conn.streams > 0 {
+				conn = next
+				continue
+			}
+
+			var reaped bool
+			switch conn.GetState() {
+			case connectivity.Shutdown, connectivity.TransientFailure:
+				reaped = true
+			default:
+				reaped = p.idleTime > 0 && now.Sub(conn.used) > p.idleTime
+			}
+
+			if reaped || pressure {
+				removeConn(conn)
+				sp.idle--
+				closed = append(closed, conn)
+
+				if ds := p.dialStats(addr); reaped {
+					ds.reaped++
+				} else {
+					ds.trimmed++
+				}
+			}
+			conn = next
+		}
+	}
+	p.prune(now)
+	p.Unlock()
+
+	for _, conn := range closed {
+		conn.ClientConn.Close()
+	}
+}
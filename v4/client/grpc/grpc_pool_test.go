@@ -2,6 +2,7 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"net"
 	"testing"
 	"time"
@@ -61,3 +62,97 @@ func TestGRPCPool(t *testing.T) {
 	testPool(t, 0, time.Minute, 10, 2)
 	testPool(t, 2, time.Minute, 10, 1)
 }
+
+func TestGRPCPoolReap(t *testing.T) {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer l.Close()
+
+	s := grpc.NewServer()
+	pb.RegisterGreeterServer(s, &greeterServer{})
+
+	go s.Serve(l)
+	defer s.Stop()
+
+	addr := l.Addr().String()
+	p := newPool(10, time.Minute, 10, 1)
+	p.idleTime = time.Minute
+
+	// two conns, one of them busy
+	busy, err := p.getConn(context.TODO(), addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	idle, err := p.getConn(context.TODO(), addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.release(addr, idle, nil)
+
+	p.reap(time.Now(), false)
+	if n := p.conns[addr].count; n != 2 {
+		t.Fatalf("Expected recently used conn to be kept, got %d conns", n)
+	}
+
+	p.reap(time.Now().Add(2*time.Minute), false)
+	if n := p.conns[addr].count; n != 1 {
+		t.Fatalf("Expected idle conn to be reaped, got %d conns", n)
+	}
+
+	p.release(addr, busy, nil)
+	p.reap(time.Now(), true)
+
+	stats := p.stats()
+	if len(stats) != 1 || stats[0].Conns != 0 || stats[0].Reaped != 1 || stats[0].Trimmed != 1 {
+		t.Fatalf("Expected a reaped and a trimmed conn, got %+v", stats)
+	}
+}
+
+func TestGRPCPoolPrune(t *testing.T) {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer l.Close()
+
+	s := grpc.NewServer()
+	pb.RegisterGreeterServer(s, &greeterServer{})
+
+	go s.Serve(l)
+	defer s.Stop()
+
+	addr := l.Addr().String()
+	p := newPool(10, time.Minute, 10, 1)
+	p.idleTime = time.Minute
+
+	conn, err := p.getConn(context.TODO(), addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	busy, err := p.getConn(context.TODO(), addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.release(addr, conn, nil)
+
+	// targets with conns are kept
+	p.reap(time.Now().Add(2*DefaultPoolStatsRetention), false)
+	if len(p.stats()) != 1 {
+		t.Fatalf("Expected the target with a busy conn to be kept, got %+v", p.stats())
+	}
+
+	// the target went away, its last conn is closed
+	p.release(addr, busy, errors.New("gone"))
+
+	p.reap(time.Now(), false)
+	if len(p.stats()) != 1 {
+		t.Fatal("Expected the stats of a recently used target to be kept")
+	}
+
+	p.reap(time.Now().Add(2*DefaultPoolStatsRetention), false)
+	if stats := p.stats(); len(stats) != 0 || len(p.conns) != 0 || len(p.dials) != 0 {
+		t.Fatalf("Expected the target to be pruned, got %+v", stats)
+	}
+}
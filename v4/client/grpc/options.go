@@ -4,6 +4,7 @@ package grpc
 import (
 	"context"
 	"crypto/tls"
+	"time"
 
 	"go-micro.dev/v4/client"
 	"google.golang.org/grpc"
@@ -30,12 +31,15 @@ var (
 
 type poolMaxStreams struct{}
 type poolMaxIdle struct{}
+type poolMaxIdleTime struct{}
+type poolMemoryLimit struct{}
 type codecsKey struct{}
 type tlsAuth struct{}
 type maxRecvMsgSizeKey struct{}
 type maxSendMsgSizeKey struct{}
 type grpcDialOptions struct{}
 type grpcCallOptions struct{}
+type chunkSizeKey struct{}
 
 // maximum streams on a connectioin.
 func PoolMaxStreams(n int) client.Option {
@@ -57,6 +61,28 @@ func PoolMaxIdle(d int) client.Option {
 	}
 }
 
+// PoolMaxIdleTime closes pooled conns which had no streams for longer than d.
+// Conns whose keepalive found the peer gone are closed as well.
+func PoolMaxIdleTime(d time.Duration) client.Option {
+	return func(o *client.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, poolMaxIdleTime{}, d)
+	}
+}
+
+// PoolMemoryLimit closes all idle pooled conns while the heap in use is
+// larger than n bytes.
+func PoolMemoryLimit(n uint64) client.Option {
+	return func(o *client.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, poolMemoryLimit{}, n)
+	}
+}
+
 // gRPC Codec to be used to encode/decode requests for a given content type.
 func Codec(contentType string, c encoding.Codec) client.Option {
 	return func(o *client.Options) {
@@ -102,6 +128,18 @@ func MaxSendMsgSize(s int) client.Option {
 	}
 }
 
+// ChunkSize splits requests larger than n bytes into chunks of n bytes, which
+// the server reassembles, and accepts chunked responses of servers. Calls are
+// sent over a stream then. The server must support chunking too.
+func ChunkSize(n int) client.Option {
+	return func(o *client.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, chunkSizeKey{}, n)
+	}
+}
+
 // DialOptions to be used to configure gRPC dial options.
 func DialOptions(opts ...grpc.DialOption) client.CallOption {
 	return func(o *client.CallOptions) {
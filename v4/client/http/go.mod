@@ -3,6 +3,7 @@ module github.com/go-micro/plugins/v4/client/http
 go 1.17
 
 require (
+	github.com/go-micro/plugins/v4/util/socket v1.0.0
 	github.com/golang/protobuf v1.5.2
 	go-micro.dev/v4 v4.9.0
 )
@@ -45,3 +46,5 @@ require (
 	gopkg.in/warnings.v0 v0.1.2 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
+
+replace github.com/go-micro/plugins/v4/util/socket => ../../util/socket
@@ -15,6 +15,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/go-micro/plugins/v4/util/socket"
 	"go-micro.dev/v4/broker"
 	"go-micro.dev/v4/client"
 	"go-micro.dev/v4/codec"
@@ -32,6 +33,33 @@ type httpClient struct {
 	opts client.Options
 }
 
+// localClients are the http clients of local socket addresses, keyed by
+// address. They dial the socket whatever the host of the request.
+var localClients sync.Map
+
+// host returns the host of requests to the address, local sockets have none.
+func host(addr string) string {
+	if socket.Local(addr) {
+		return "localhost"
+	}
+	return addr
+}
+
+func clientFor(addr string) *http.Client {
+	if !socket.Local(addr) {
+		return http.DefaultClient
+	}
+	if c, ok := localClients.Load(addr); ok {
+		return c.(*http.Client)
+	}
+	c, _ := localClients.LoadOrStore(addr, &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return socket.Dial(ctx, addr)
+		},
+	}})
+	return c.(*http.Client)
+}
+
 func init() {
 	cmd.DefaultClients["http"] = NewClient
 }
@@ -112,7 +140,7 @@ func (h *httpClient) call(ctx context.Context, node *registry.Node, req client.R
 	if !strings.HasPrefix(endpoint, "/") {
 		endpoint = "/" + endpoint
 	}
-	rawurl := "http://" + address + endpoint
+	rawurl := "http://" + host(address) + endpoint
 
 	// parse rawurl
 	URL, err := url.Parse(rawurl)
@@ -126,11 +154,11 @@ func (h *httpClient) call(ctx context.Context, node *registry.Node, req client.R
 		Header:        header,
 		Body:          buf,
 		ContentLength: int64(len(b)),
-		Host:          address,
+		Host:          host(address),
 	}
 
 	// make the request
-	hrsp, err := http.DefaultClient.Do(hreq.WithContext(ctx))
+	hrsp, err := clientFor(address).Do(hreq.WithContext(ctx))
 	if err != nil {
 		return errors.InternalServerError("go.micro.client", err.Error())
 	}
@@ -171,13 +199,13 @@ func (h *httpClient) stream(ctx context.Context, node *registry.Node, req client
 		return nil, errors.InternalServerError("go.micro.client", err.Error())
 	}
 
-	cc, err := net.Dial("tcp", address)
+	cc, err := socket.Dial(ctx, address)
 	if err != nil {
 		return nil, errors.InternalServerError("go.micro.client", fmt.Sprintf("Error dialing: %v", err))
 	}
 
 	return &httpStream{
-		address: address,
+		address: host(address),
 		context: ctx,
 		closed:  make(chan bool),
 		conn:    cc,
@@ -8,6 +8,8 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/go-micro/plugins/v4/client/http/test"
@@ -104,6 +106,46 @@ func TestHTTPClient(t *testing.T) {
 	}
 }
 
+func TestHTTPClientUnix(t *testing.T) {
+	dir, err := os.MkdirTemp("", "http")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "svc.sock")
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/foo/bar", func(w http.ResponseWriter, r *http.Request) {
+		codec := defaultHTTPCodecs[r.Header.Get("Content-Type")]
+		b, _ := io.ReadAll(r.Body)
+		msg := new(test.Message)
+		if err := codec.Unmarshal(b, msg); err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		b, _ = codec.Marshal(msg)
+		w.Write(b)
+	})
+	go http.Serve(l, mux)
+
+	c := NewClient()
+	msg := &test.Message{Seq: 1, Data: "message 1"}
+	req := c.NewRequest("test.service", "/foo/bar", msg)
+	rsp := new(test.Message)
+	if err := c.Call(context.TODO(), req, rsp, client.WithAddress("unix://"+path)); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Seq != msg.Seq {
+		t.Fatalf("invalid seq %d for %d", rsp.Seq, msg.Seq)
+	}
+}
+
 func TestHTTPClientStream(t *testing.T) {
 	r := registry.NewMemoryRegistry()
 	s := selector.NewSelector(selector.Registry(r))
@@ -0,0 +1,309 @@
+// Package avro provides a binary Avro codec for RPC and a marshaler for
+// broker payloads, with writer and reader schema resolution and optionally
+// a Confluent compatible schema registry, for interop with Kafka-centric
+// data platforms.
+package avro
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/go-micro/plugins/v4/schemaregistry"
+	"github.com/go-micro/plugins/v4/schemaregistry/confluent"
+	"github.com/linkedin/goavro/v2"
+)
+
+var (
+	// ErrNoSchema is returned for values without an Avro schema, see
+	// Schemer and TypeSchema.
+	ErrNoSchema = errors.New("avro: no schema for value")
+	// ErrUnknownSchema is returned for data written with a schema which
+	// isn't known, see Schemas and Registry.
+	ErrUnknownSchema = errors.New("avro: unknown writer schema")
+)
+
+// Schemer is implemented by types with an Avro schema, such as the types
+// generated by gogen-avro. Values are converted to and from Avro through
+// encoding/json, so the JSON names of the fields are the names of the
+// fields of the schema.
+type Schemer interface {
+	Schema() string
+}
+
+// Options configure the marshaler.
+type Options struct {
+	// Types are the schemas of types which don't implement Schemer
+	Types map[reflect.Type]string
+	// Schemas are writer schemas of data written by others, to resolve
+	// data in single object encoding by fingerprint
+	Schemas []string
+	// Registry is the schema registry. Data is written in its wire format
+	// with the ID of the schema, registered under the full name of the
+	// schema
+	Registry schemaregistry.Registry
+}
+
+// Option sets an option.
+type Option func(*Options)
+
+// TypeSchema sets the schema of the type of v, for types which don't
+// implement Schemer.
+func TypeSchema(v interface{}, schema string) Option {
+	return func(o *Options) {
+		if o.Types == nil {
+			o.Types = make(map[reflect.Type]string)
+		}
+		o.Types[indirect(reflect.TypeOf(v))] = schema
+	}
+}
+
+// Schemas adds writer schemas of data written by others. Data written with
+// them is resolved to the schema of the value it's read into.
+func Schemas(schemas ...string) Option {
+	return func(o *Options) {
+		o.Schemas = append(o.Schemas, schemas...)
+	}
+}
+
+// Registry looks up and registers schemas in the Confluent schema registry
+// at the URL, e.g. http://localhost:8081.
+func Registry(url string, opts ...schemaregistry.Option) Option {
+	return SchemaRegistry(confluent.NewRegistry(append([]schemaregistry.Option{schemaregistry.URL(url)}, opts...)...))
+}
+
+// SchemaRegistry looks up and registers schemas in the registry, whose ids
+// must be numeric as they're written in the Confluent wire format.
+func SchemaRegistry(r schemaregistry.Registry) Option {
+	return func(o *Options) {
+		o.Registry = r
+	}
+}
+
+// compiled is a schema compiled by goavro and parsed for resolution.
+type compiled struct {
+	spec  string
+	codec *goavro.Codec
+	root  *schema
+}
+
+// Marshaler encodes values in binary Avro with their schema. Data is
+// written in single object encoding, with the fingerprint of the writer
+// schema, or in the wire format of the registry, with the ID of the writer
+// schema. It's read in either, resolved to the schema of the value it's
+// read into.
+type Marshaler struct {
+	opts Options
+
+	sync.RWMutex
+	specs        map[string]*compiled
+	fingerprints map[uint64]*compiled
+	ids          map[string]*compiled
+	registered   map[string]string
+}
+
+// NewMarshaler returns a marshaler, which is a codec.Marshaler.
+func NewMarshaler(opts ...Option) *Marshaler {
+	var options Options
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &Marshaler{
+		opts:         options,
+		specs:        make(map[string]*compiled),
+		fingerprints: make(map[uint64]*compiled),
+		ids:          make(map[string]*compiled),
+		registered:   make(map[string]string),
+	}
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// schemaOf returns the schema of the value, or false if it has none.
+func (m *Marshaler) schemaOf(v interface{}) (string, bool) {
+	if s, ok := v.(Schemer); ok {
+		return s.Schema(), true
+	}
+	// the value pointed to, for reads into pointers of Schemer values
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		if s, ok := rv.Elem().Interface().(Schemer); ok {
+			return s.Schema(), true
+		}
+	}
+	spec, ok := m.opts.Types[indirect(reflect.TypeOf(v))]
+	return spec, ok
+}
+
+func (m *Marshaler) compile(spec string) (*compiled, error) {
+	m.RLock()
+	c, ok := m.specs[spec]
+	m.RUnlock()
+	if ok {
+		return c, nil
+	}
+
+	codec, err := goavro.NewCodec(spec)
+	if err != nil {
+		return nil, err
+	}
+	root, err := parseSchema(spec)
+	if err != nil {
+		return nil, err
+	}
+	c = &compiled{spec: spec, codec: codec, root: root}
+
+	m.Lock()
+	m.specs[spec] = c
+	m.fingerprints[codec.Rabin] = c
+	m.Unlock()
+	return c, nil
+}
+
+// writer returns the writer schema of the data and its body, from the
+// fingerprint of single object encoding or the ID of the registry format.
+func (m *Marshaler) writer(d []byte) (*compiled, []byte, error) {
+	switch {
+	case len(d) >= 10 && d[0] == 0xC3 && d[1] == 0x01:
+		fp := binary.LittleEndian.Uint64(d[2:10])
+		m.RLock()
+		c, ok := m.fingerprints[fp]
+		m.RUnlock()
+		if !ok {
+			for _, spec := range m.opts.Schemas {
+				// compiling registers the fingerprint
+				if _, err := m.compile(spec); err != nil {
+					return nil, nil, err
+				}
+			}
+			m.RLock()
+			c, ok = m.fingerprints[fp]
+			m.RUnlock()
+		}
+		if !ok {
+			return nil, nil, ErrUnknownSchema
+		}
+		return c, d[10:], nil
+	case len(d) >= 5 && d[0] == 0x00:
+		id, body, err := schemaregistry.Decode(d)
+		if err != nil {
+			return nil, nil, err
+		}
+		c, err := m.registrySchema(id)
+		if err != nil {
+			return nil, nil, err
+		}
+		return c, body, nil
+	}
+	return nil, nil, errors.New("avro: data is neither in single object encoding nor the registry format")
+}
+
+// Marshal encodes the value with its schema. Values without a schema whose
+// type is an empty struct, as errors are replied with, are encoded empty.
+func (m *Marshaler) Marshal(v interface{}) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	spec, ok := m.schemaOf(v)
+	if !ok {
+		if t := indirect(reflect.TypeOf(v)); t.Kind() == reflect.Struct && t.NumField() == 0 {
+			return []byte{}, nil
+		}
+		return nil, ErrNoSchema
+	}
+
+	c, err := m.compile(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var generic interface{}
+	if err := d.Decode(&generic); err != nil {
+		return nil, err
+	}
+	n, err := native(c.root, generic)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.opts.Registry == nil {
+		return c.codec.SingleFromNative(nil, n)
+	}
+
+	id, err := m.registryID(c)
+	if err != nil {
+		return nil, err
+	}
+	header, err := schemaregistry.Encode(id, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.codec.BinaryFromNative(header, n)
+}
+
+// Unmarshal decodes the data into the value, resolving the writer schema of
+// the data to the schema of the value. Values without a schema, such as
+// maps, are decoded with the writer schema, *interface{} values get the
+// decoded value as is.
+func (m *Marshaler) Unmarshal(d []byte, v interface{}) error {
+	if len(d) == 0 || v == nil {
+		return nil
+	}
+
+	// the reader schema is compiled first, so data written with it is
+	// resolved by its fingerprint
+	r, hasReader := (*compiled)(nil), false
+	if spec, ok := m.schemaOf(v); ok {
+		var err error
+		if r, err = m.compile(spec); err != nil {
+			return err
+		}
+		hasReader = true
+	}
+
+	w, body, err := m.writer(d)
+	if err != nil {
+		return err
+	}
+	if !hasReader {
+		r = w
+	}
+
+	n, _, err := w.codec.NativeFromBinary(body)
+	if err != nil {
+		return err
+	}
+	plain, err := resolve(w.root, r.root, n)
+	if err != nil {
+		return err
+	}
+
+	if iv, ok := v.(*interface{}); ok {
+		*iv = plain
+		return nil
+	}
+	b, err := json.Marshal(plain)
+	if err != nil {
+		return fmt.Errorf("avro: %v", err)
+	}
+	return json.Unmarshal(b, v)
+}
+
+func (m *Marshaler) String() string {
+	return "avro"
+}
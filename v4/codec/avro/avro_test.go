@@ -0,0 +1,209 @@
+package avro
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/codec"
+)
+
+const userV1 = `{
+	"type": "record", "name": "User", "namespace": "com.example",
+	"fields": [
+		{"name": "name", "type": "string"},
+		{"name": "age", "type": "int"},
+		{"name": "nick", "type": ["null", "string"], "default": null},
+		{"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["ACTIVE", "BANNED"]}},
+		{"name": "created", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+		{"name": "avatar", "type": "bytes"}
+	]
+}`
+
+const userV2 = `{
+	"type": "record", "name": "User", "namespace": "com.example",
+	"fields": [
+		{"name": "fullName", "type": "string", "aliases": ["name"]},
+		{"name": "age", "type": "long"},
+		{"name": "nick", "type": ["null", "string"], "default": null},
+		{"name": "email", "type": "string", "default": "unknown"},
+		{"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["ACTIVE"], "default": "ACTIVE"}},
+		{"name": "created", "type": {"type": "long", "logicalType": "timestamp-millis"}}
+	]
+}`
+
+type UserV1 struct {
+	Name    string    `json:"name"`
+	Age     int32     `json:"age"`
+	Nick    *string   `json:"nick"`
+	Status  string    `json:"status"`
+	Created time.Time `json:"created"`
+	Avatar  []byte    `json:"avatar"`
+}
+
+func (UserV1) Schema() string { return userV1 }
+
+type UserV2 struct {
+	FullName string    `json:"fullName"`
+	Age      int64     `json:"age"`
+	Nick     *string   `json:"nick"`
+	Email    string    `json:"email"`
+	Status   string    `json:"status"`
+	Created  time.Time `json:"created"`
+}
+
+func (UserV2) Schema() string { return userV2 }
+
+func TestMarshaler(t *testing.T) {
+	nick := "al"
+	created := time.Date(2024, 1, 2, 3, 4, 5, 6000000, time.UTC)
+	in := UserV1{Name: "alice", Age: 30, Nick: &nick, Status: "ACTIVE", Created: created, Avatar: []byte{1, 2}}
+
+	m := NewMarshaler()
+	b, err := m.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b[0] != 0xC3 || b[1] != 0x01 {
+		t.Fatalf("not single object encoded: %v", b[:2])
+	}
+
+	var out UserV1
+	if err := m.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Name != "alice" || out.Age != 30 || *out.Nick != "al" || !out.Created.Equal(created) || !bytes.Equal(out.Avatar, in.Avatar) {
+		t.Fatalf("unexpected value %+v", out)
+	}
+
+	var generic map[string]interface{}
+	if err := m.Unmarshal(b, &generic); err != nil {
+		t.Fatal(err)
+	}
+	if generic["name"] != "alice" || generic["nick"] != "al" {
+		t.Fatalf("unexpected map %v", generic)
+	}
+}
+
+func TestResolution(t *testing.T) {
+	in := UserV1{Name: "bob", Age: 40, Status: "BANNED", Created: time.Unix(1700000000, 0).UTC()}
+	b, err := NewMarshaler().Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a reader which doesn't know the writer schema fails
+	var out UserV2
+	if err := NewMarshaler().Unmarshal(b, &out); err != ErrUnknownSchema {
+		t.Fatalf("expected unknown schema, got %v", err)
+	}
+
+	if err := NewMarshaler(Schemas(userV1)).Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.FullName != "bob" || out.Age != 40 || out.Nick != nil || out.Email != "unknown" || out.Status != "ACTIVE" || !out.Created.Equal(in.Created) {
+		t.Fatalf("unexpected resolved value %+v", out)
+	}
+
+	// a type without the field can't read it
+	const strict = `{"type": "record", "name": "User", "namespace": "com.example", "fields": [{"name": "id", "type": "string"}]}`
+	var v map[string]interface{}
+	err = NewMarshaler(Schemas(userV1), TypeSchema(v, strict)).Unmarshal(b, &v)
+	if err == nil || !strings.Contains(err.Error(), "no default") {
+		t.Fatalf("expected a missing field error, got %v", err)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		schemas []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/subjects/com.example.User/versions":
+			var req struct{ Schema string }
+			b, _ := ioutil.ReadAll(r.Body)
+			json.Unmarshal(b, &req)
+			schemas = append(schemas, req.Schema)
+			json.NewEncoder(w).Encode(map[string]int{"id": len(schemas)})
+		case r.Method == http.MethodPost && r.URL.Path == "/subjects/com.example.User":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"id": len(schemas), "subject": "com.example.User", "version": len(schemas), "schema": schemas[len(schemas)-1],
+			})
+		case r.Method == http.MethodGet && r.URL.Path == "/schemas/ids/1":
+			json.NewEncoder(w).Encode(map[string]string{"schema": schemas[0]})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error_code": 40403, "message": "Schema not found"}`))
+		}
+	}))
+	defer srv.Close()
+
+	b, err := NewMarshaler(Registry(srv.URL)).Marshal(UserV1{Name: "carol", Status: "ACTIVE"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b[0] != 0 || b[4] != 1 {
+		t.Fatalf("not in registry format: %v", b[:5])
+	}
+
+	// another service resolves the writer schema through the registry
+	var out UserV2
+	if err := NewMarshaler(Registry(srv.URL)).Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.FullName != "carol" {
+		t.Fatalf("unexpected value %+v", out)
+	}
+
+	b[4] = 2
+	if err := NewMarshaler(Registry(srv.URL)).Unmarshal(b, &out); err != ErrUnknownSchema {
+		t.Fatalf("expected unknown schema, got %v", err)
+	}
+}
+
+type rwc struct {
+	*bytes.Buffer
+}
+
+func (rwc) Close() error { return nil }
+
+func TestCodec(t *testing.T) {
+	buf := rwc{new(bytes.Buffer)}
+	c := NewCodec(buf)
+
+	if err := c.Write(&codec.Message{Type: codec.Request}, UserV1{Name: "dave", Status: "ACTIVE"}); err != nil {
+		t.Fatal(err)
+	}
+	var out UserV1
+	if err := c.ReadHeader(&codec.Message{}, codec.Request); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ReadBody(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Name != "dave" {
+		t.Fatalf("unexpected value %+v", out)
+	}
+
+	// error replies have an empty body
+	if err := c.Write(&codec.Message{Type: codec.Response, Error: "failed"}, struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("unexpected body %v", buf.Bytes())
+	}
+
+	if err := c.Write(&codec.Message{Type: codec.Request}, map[string]string{}); err != ErrNoSchema {
+		t.Fatalf("expected no schema, got %v", err)
+	}
+}
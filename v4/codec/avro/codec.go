@@ -0,0 +1,55 @@
+package avro
+
+import (
+	"io"
+	"io/ioutil"
+
+	"go-micro.dev/v4/codec"
+)
+
+var defaultMarshaler = NewMarshaler()
+
+type avroCodec struct {
+	rwc io.ReadWriteCloser
+	m   *Marshaler
+}
+
+// NewCodec returns an RPC codec encoding the bodies with a marshaler
+// without options. Use the NewCodec of a Marshaler for options.
+func NewCodec(rwc io.ReadWriteCloser) codec.Codec {
+	return defaultMarshaler.NewCodec(rwc)
+}
+
+// NewCodec returns an RPC codec encoding the bodies with the marshaler.
+func (m *Marshaler) NewCodec(rwc io.ReadWriteCloser) codec.Codec {
+	return &avroCodec{rwc: rwc, m: m}
+}
+
+func (c *avroCodec) ReadHeader(m *codec.Message, mt codec.MessageType) error {
+	return nil
+}
+
+func (c *avroCodec) ReadBody(v interface{}) error {
+	b, err := ioutil.ReadAll(c.rwc)
+	if err != nil {
+		return err
+	}
+	return c.m.Unmarshal(b, v)
+}
+
+func (c *avroCodec) Write(m *codec.Message, v interface{}) error {
+	b, err := c.m.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = c.rwc.Write(b)
+	return err
+}
+
+func (c *avroCodec) Close() error {
+	return c.rwc.Close()
+}
+
+func (c *avroCodec) String() string {
+	return "avro"
+}
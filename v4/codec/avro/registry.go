@@ -0,0 +1,70 @@
+package avro
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-micro/plugins/v4/schemaregistry"
+)
+
+// registryID returns the ID of the schema in the registry, registering it
+// under its full name, as the record name strategy of the registry does.
+func (m *Marshaler) registryID(c *compiled) (string, error) {
+	m.RLock()
+	id, ok := m.registered[c.spec]
+	m.RUnlock()
+	if ok {
+		return id, nil
+	}
+
+	subject := c.root.name
+	if len(subject) == 0 {
+		subject = c.root.typ
+	}
+
+	s, err := m.opts.Registry.Register(context.Background(), &schemaregistry.Schema{
+		Subject:    subject,
+		Type:       schemaregistry.Avro,
+		Definition: c.spec,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	m.Lock()
+	m.registered[c.spec] = s.ID
+	m.ids[s.ID] = c
+	m.Unlock()
+	return s.ID, nil
+}
+
+// registrySchema returns the schema of the ID in the registry.
+func (m *Marshaler) registrySchema(id string) (*compiled, error) {
+	m.RLock()
+	c, ok := m.ids[id]
+	m.RUnlock()
+	if ok {
+		return c, nil
+	}
+
+	if m.opts.Registry == nil {
+		return nil, ErrUnknownSchema
+	}
+
+	s, err := m.opts.Registry.GetByID(context.Background(), id)
+	if errors.Is(err, schemaregistry.ErrNotFound) {
+		return nil, ErrUnknownSchema
+	} else if err != nil {
+		return nil, err
+	}
+
+	c, err = m.compile(s.Definition)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Lock()
+	m.ids[id] = c
+	m.Unlock()
+	return c, nil
+}
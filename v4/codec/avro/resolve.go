@@ -0,0 +1,429 @@
+package avro
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// resolve converts the native of goavro decoded with the writer schema to a
+// plain value of the reader schema, following the schema resolution rules of
+// the Avro specification: fields are matched by name or alias, fields the
+// writer lacks take their default, numbers are promoted and unions resolve
+// to the first matching branch. Plain values have no union wrappers, so
+// they're marshaled to JSON as the reader's types expect.
+func resolve(w, r *schema, v interface{}) (interface{}, error) {
+	if w.typ == "union" {
+		if v == nil {
+			for _, b := range w.branches {
+				if b.typ == "null" {
+					return resolve(b, r, nil)
+				}
+			}
+			return nil, fmt.Errorf("avro: null for union without null")
+		}
+		m, ok := v.(map[string]interface{})
+		if !ok || len(m) != 1 {
+			return nil, fmt.Errorf("avro: invalid union %v", v)
+		}
+		for name, bv := range m {
+			for _, b := range w.branches {
+				if b.branchName() == name {
+					return resolve(b, r, bv)
+				}
+			}
+			return nil, fmt.Errorf("avro: unknown union branch %s", name)
+		}
+	}
+
+	if r.typ == "union" {
+		for _, b := range r.branches {
+			if matches(w, b) {
+				return resolve(w, b, v)
+			}
+		}
+		return nil, fmt.Errorf("avro: no branch of the reader union matches %s", w.typ)
+	}
+
+	if !matches(w, r) {
+		return nil, fmt.Errorf("avro: %s can't be read as %s", describe(w), describe(r))
+	}
+
+	switch r.typ {
+	case "long":
+		if i, ok := v.(int32); ok {
+			return int64(i), nil
+		}
+	case "float":
+		switch n := v.(type) {
+		case int32:
+			return float32(n), nil
+		case int64:
+			return float32(n), nil
+		}
+	case "double":
+		switch n := v.(type) {
+		case int32:
+			return float64(n), nil
+		case int64:
+			return float64(n), nil
+		case float32:
+			return float64(n), nil
+		}
+	case "string":
+		if b, ok := v.([]byte); ok {
+			return string(b), nil
+		}
+	case "bytes":
+		if s, ok := v.(string); ok {
+			return []byte(s), nil
+		}
+	case "enum":
+		s, _ := v.(string)
+		for _, sym := range r.symbols {
+			if sym == s {
+				return s, nil
+			}
+		}
+		if r.def != nil {
+			return *r.def, nil
+		}
+		return nil, fmt.Errorf("avro: symbol %s not in enum %s", s, r.name)
+	case "fixed":
+		if w.size != r.size {
+			return nil, fmt.Errorf("avro: fixed %s of size %d can't be read with size %d", r.name, w.size, r.size)
+		}
+	case "array":
+		items, _ := v.([]interface{})
+		out := make([]interface{}, len(items))
+		for i, item := range items {
+			var err error
+			if out[i], err = resolve(w.items, r.items, item); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case "map":
+		values, _ := v.(map[string]interface{})
+		out := make(map[string]interface{}, len(values))
+		for k, value := range values {
+			var err error
+			if out[k], err = resolve(w.values, r.values, value); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case "record":
+		values, _ := v.(map[string]interface{})
+		out := make(map[string]interface{}, len(r.fields))
+		for _, f := range r.fields {
+			wf := writerField(w, f)
+			var err error
+			switch {
+			case wf != nil:
+				out[f.name], err = resolve(wf.typ, f.typ, values[wf.name])
+			case f.hasDef:
+				out[f.name], err = defaultValue(f.typ, f.def)
+			default:
+				err = fmt.Errorf("avro: field %s of %s has no value and no default", f.name, r.name)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+
+	return v, nil
+}
+
+// writerField returns the field of the writer record the reader field reads.
+func writerField(w *schema, f *field) *field {
+	for _, wf := range w.fields {
+		if wf.name == f.name {
+			return wf
+		}
+	}
+	for _, wf := range w.fields {
+		for _, a := range f.aliases {
+			if wf.name == a {
+				return wf
+			}
+		}
+	}
+	return nil
+}
+
+func describe(s *schema) string {
+	if len(s.name) > 0 {
+		return s.typ + " " + s.name
+	}
+	return s.typ
+}
+
+// defaultValue returns the plain value of the JSON default of a field. The
+// default of a union is of its first branch, those of bytes and fixed are
+// strings of code points 0-255.
+func defaultValue(s *schema, d interface{}) (interface{}, error) {
+	switch s.typ {
+	case "union":
+		return defaultValue(s.branches[0], d)
+	case "null":
+		return nil, nil
+	case "int", "long":
+		n, ok := d.(json.Number)
+		if !ok {
+			return nil, fmt.Errorf("avro: invalid default %v of %s", d, s.typ)
+		}
+		return n.Int64()
+	case "float", "double":
+		n, ok := d.(json.Number)
+		if !ok {
+			return nil, fmt.Errorf("avro: invalid default %v of %s", d, s.typ)
+		}
+		return n.Float64()
+	case "bytes", "fixed":
+		str, _ := d.(string)
+		b := make([]byte, 0, len(str))
+		for _, c := range str {
+			b = append(b, byte(c))
+		}
+		return b, nil
+	case "array":
+		items, _ := d.([]interface{})
+		out := make([]interface{}, len(items))
+		for i, item := range items {
+			var err error
+			if out[i], err = defaultValue(s.items, item); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case "map":
+		values, _ := d.(map[string]interface{})
+		out := make(map[string]interface{}, len(values))
+		for k, value := range values {
+			var err error
+			if out[k], err = defaultValue(s.values, value); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case "record":
+		values, _ := d.(map[string]interface{})
+		out := make(map[string]interface{}, len(s.fields))
+		for _, f := range s.fields {
+			v, ok := values[f.name]
+			if !ok {
+				if !f.hasDef {
+					return nil, fmt.Errorf("avro: default of %s lacks field %s", s.name, f.name)
+				}
+				v = f.def
+			}
+			var err error
+			if out[f.name], err = defaultValue(f.typ, v); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	return d, nil
+}
+
+// native converts a value decoded from JSON, with numbers as json.Number,
+// to the native goavro encodes with the schema. Bytes are base64 strings,
+// as encoding/json marshals []byte, times are RFC 3339 strings and
+// durations nanoseconds.
+func native(s *schema, v interface{}) (interface{}, error) {
+	switch s.typ {
+	case "union":
+		if v == nil {
+			for _, b := range s.branches {
+				if b.typ == "null" {
+					return nil, nil
+				}
+			}
+			return nil, fmt.Errorf("avro: null for union without null")
+		}
+		for _, b := range s.branches {
+			if b.typ == "null" {
+				continue
+			}
+			if n, err := native(b, v); err == nil {
+				return goavro.Union(b.branchName(), n), nil
+			}
+		}
+		return nil, fmt.Errorf("avro: no branch of the union matches %v", v)
+	case "null":
+		if v != nil {
+			return nil, fmt.Errorf("avro: %v is not null", v)
+		}
+		return nil, nil
+	case "boolean":
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case "int", "long":
+		switch s.logical {
+		case "timestamp-millis", "timestamp-micros", "date":
+			return nativeTime(s, v)
+		case "time-millis", "time-micros":
+			if n, ok := v.(json.Number); ok {
+				d, err := n.Int64()
+				return time.Duration(d), err
+			}
+			return nil, fmt.Errorf("avro: %v is not a duration", v)
+		}
+		if n, ok := v.(json.Number); ok {
+			return n.Int64()
+		}
+	case "float", "double":
+		if n, ok := v.(json.Number); ok {
+			return n.Float64()
+		}
+	case "string", "enum":
+		if str, ok := v.(string); ok {
+			return str, nil
+		}
+	case "bytes", "fixed":
+		if s.logical == "decimal" {
+			r, ok := new(big.Rat).SetString(fmt.Sprint(v))
+			if !ok {
+				return nil, fmt.Errorf("avro: %v is not a decimal", v)
+			}
+			return r, nil
+		}
+		if str, ok := v.(string); ok {
+			return base64.StdEncoding.DecodeString(str)
+		}
+		// nil slices marshal to null
+		if v == nil {
+			return []byte{}, nil
+		}
+	case "array":
+		items, ok := v.([]interface{})
+		if !ok && v != nil {
+			break
+		}
+		out := make([]interface{}, len(items))
+		for i, item := range items {
+			var err error
+			if out[i], err = native(s.items, item); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case "map":
+		values, ok := v.(map[string]interface{})
+		if !ok && v != nil {
+			break
+		}
+		out := make(map[string]interface{}, len(values))
+		for k, value := range values {
+			var err error
+			if out[k], err = native(s.values, value); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case "record":
+		values, ok := v.(map[string]interface{})
+		if !ok {
+			break
+		}
+		out := make(map[string]interface{}, len(s.fields))
+		for _, f := range s.fields {
+			fv, ok := values[f.name]
+			if !ok && f.hasDef {
+				d, err := defaultValue(f.typ, f.def)
+				if err != nil {
+					return nil, err
+				}
+				out[f.name], err = plainNative(f.typ, d)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			var err error
+			if out[f.name], err = native(f.typ, fv); err != nil {
+				return nil, fmt.Errorf("avro: field %s of %s: %v", f.name, s.name, err)
+			}
+		}
+		return out, nil
+	}
+
+	return nil, fmt.Errorf("avro: %v (%T) is not %s", v, v, describe(s))
+}
+
+func nativeTime(s *schema, v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case string:
+		return time.Parse(time.RFC3339Nano, t)
+	case json.Number:
+		n, err := t.Int64()
+		if err != nil {
+			return nil, err
+		}
+		switch s.logical {
+		case "timestamp-millis":
+			return time.Unix(0, n*int64(time.Millisecond)).UTC(), nil
+		case "timestamp-micros":
+			return time.Unix(0, n*int64(time.Microsecond)).UTC(), nil
+		default:
+			return time.Unix(n*24*60*60, 0).UTC(), nil
+		}
+	}
+	return nil, fmt.Errorf("avro: %v is not a time", v)
+}
+
+// plainNative converts a plain default value to the native goavro encodes,
+// wrapping the first branch of unions.
+func plainNative(s *schema, v interface{}) (interface{}, error) {
+	switch s.typ {
+	case "union":
+		if s.branches[0].typ == "null" {
+			return nil, nil
+		}
+		n, err := plainNative(s.branches[0], v)
+		if err != nil {
+			return nil, err
+		}
+		return goavro.Union(s.branches[0].branchName(), n), nil
+	case "array":
+		items, _ := v.([]interface{})
+		out := make([]interface{}, len(items))
+		for i, item := range items {
+			var err error
+			if out[i], err = plainNative(s.items, item); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case "map":
+		values, _ := v.(map[string]interface{})
+		out := make(map[string]interface{}, len(values))
+		for k, value := range values {
+			var err error
+			if out[k], err = plainNative(s.values, value); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case "record":
+		values, _ := v.(map[string]interface{})
+		out := make(map[string]interface{}, len(s.fields))
+		for _, f := range s.fields {
+			var err error
+			if out[f.name], err = plainNative(f.typ, values[f.name]); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	return v, nil
+}
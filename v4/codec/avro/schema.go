@@ -0,0 +1,258 @@
+package avro
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// schema is the parsed tree of an Avro schema, for resolution and for
+// converting values to and from the natives of goavro.
+type schema struct {
+	typ     string
+	name    string
+	aliases []string
+	logical string
+	size    int
+
+	fields   []*field
+	symbols  []string
+	def      *string
+	items    *schema
+	values   *schema
+	branches []*schema
+}
+
+type field struct {
+	name    string
+	aliases []string
+	typ     *schema
+	def     interface{}
+	hasDef  bool
+}
+
+var primitives = map[string]bool{
+	"null":    true,
+	"boolean": true,
+	"int":     true,
+	"long":    true,
+	"float":   true,
+	"double":  true,
+	"bytes":   true,
+	"string":  true,
+}
+
+// logicalNames are the logical types goavro names union branches after,
+// e.g. long.timestamp-millis.
+var logicalNames = map[string]bool{
+	"long.timestamp-millis": true,
+	"long.timestamp-micros": true,
+	"int.time-millis":       true,
+	"long.time-micros":      true,
+	"int.date":              true,
+	"bytes.decimal":         true,
+}
+
+func parseSchema(spec string) (*schema, error) {
+	d := json.NewDecoder(bytes.NewReader([]byte(spec)))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	p := &parser{named: make(map[string]*schema)}
+	return p.parse(v, "")
+}
+
+type parser struct {
+	named map[string]*schema
+}
+
+func fullName(name, namespace string) string {
+	if strings.Contains(name, ".") || len(namespace) == 0 {
+		return name
+	}
+	return namespace + "." + name
+}
+
+func (p *parser) parse(v interface{}, namespace string) (*schema, error) {
+	switch t := v.(type) {
+	case string:
+		if primitives[t] {
+			return &schema{typ: t}, nil
+		}
+		if s, ok := p.named[fullName(t, namespace)]; ok {
+			return s, nil
+		}
+		if s, ok := p.named[t]; ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("avro: unknown type %q", t)
+	case []interface{}:
+		s := &schema{typ: "union"}
+		for _, b := range t {
+			bs, err := p.parse(b, namespace)
+			if err != nil {
+				return nil, err
+			}
+			s.branches = append(s.branches, bs)
+		}
+		return s, nil
+	case map[string]interface{}:
+		return p.parseMap(t, namespace)
+	default:
+		return nil, fmt.Errorf("avro: invalid schema %v", v)
+	}
+}
+
+func (p *parser) parseMap(m map[string]interface{}, namespace string) (*schema, error) {
+	typ, _ := m["type"].(string)
+	logical, _ := m["logicalType"].(string)
+
+	switch typ {
+	case "record", "error", "enum", "fixed":
+	case "array":
+		items, err := p.parse(m["items"], namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &schema{typ: "array", items: items}, nil
+	case "map":
+		values, err := p.parse(m["values"], namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &schema{typ: "map", values: values}, nil
+	default:
+		// a primitive or a reference, possibly with a logical type
+		s, err := p.parse(m["type"], namespace)
+		if err != nil {
+			return nil, err
+		}
+		if len(logical) > 0 && primitives[s.typ] {
+			c := *s
+			c.logical = logical
+			return &c, nil
+		}
+		return s, nil
+	}
+
+	name, _ := m["name"].(string)
+	if ns, ok := m["namespace"].(string); ok && !strings.Contains(name, ".") {
+		namespace = ns
+	}
+	s := &schema{typ: typ, name: fullName(name, namespace), logical: logical}
+	if i := strings.LastIndex(s.name, "."); i >= 0 {
+		namespace = s.name[:i]
+	}
+	if aliases, ok := m["aliases"].([]interface{}); ok {
+		for _, a := range aliases {
+			if as, ok := a.(string); ok {
+				s.aliases = append(s.aliases, fullName(as, namespace))
+			}
+		}
+	}
+	// registered before the fields, so records can refer to themselves
+	p.named[s.name] = s
+
+	switch typ {
+	case "error":
+		s.typ = "record"
+		fallthrough
+	case "record":
+		fields, _ := m["fields"].([]interface{})
+		for _, fv := range fields {
+			fm, ok := fv.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("avro: invalid field of %s", s.name)
+			}
+			f := &field{}
+			f.name, _ = fm["name"].(string)
+			if aliases, ok := fm["aliases"].([]interface{}); ok {
+				for _, a := range aliases {
+					if as, ok := a.(string); ok {
+						f.aliases = append(f.aliases, as)
+					}
+				}
+			}
+			var err error
+			if f.typ, err = p.parse(fm["type"], namespace); err != nil {
+				return nil, err
+			}
+			f.def, f.hasDef = fm["default"]
+			s.fields = append(s.fields, f)
+		}
+	case "enum":
+		symbols, _ := m["symbols"].([]interface{})
+		for _, sym := range symbols {
+			if ss, ok := sym.(string); ok {
+				s.symbols = append(s.symbols, ss)
+			}
+		}
+		if d, ok := m["default"].(string); ok {
+			s.def = &d
+		}
+	case "fixed":
+		if n, ok := m["size"].(json.Number); ok {
+			size, err := n.Int64()
+			if err != nil {
+				return nil, err
+			}
+			s.size = int(size)
+		}
+	}
+
+	return s, nil
+}
+
+// branchName is the name goavro gives the union branch of the schema.
+func (s *schema) branchName() string {
+	if len(s.name) > 0 {
+		return s.name
+	}
+	if n := s.typ + "." + s.logical; logicalNames[n] {
+		return n
+	}
+	return s.typ
+}
+
+// shortName returns the name without its namespace.
+func shortName(name string) string {
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// matches returns whether data written with w can be read with r, for
+// picking the union branch of the reader.
+func matches(w, r *schema) bool {
+	if w.typ == r.typ {
+		switch w.typ {
+		case "record", "enum", "fixed":
+			if shortName(w.name) == shortName(r.name) {
+				return true
+			}
+			for _, a := range r.aliases {
+				if shortName(a) == shortName(w.name) {
+					return true
+				}
+			}
+			return false
+		default:
+			return true
+		}
+	}
+
+	switch w.typ {
+	case "int":
+		return r.typ == "long" || r.typ == "float" || r.typ == "double"
+	case "long":
+		return r.typ == "float" || r.typ == "double"
+	case "float":
+		return r.typ == "double"
+	case "string":
+		return r.typ == "bytes"
+	case "bytes":
+		return r.typ == "string"
+	}
+	return false
+}
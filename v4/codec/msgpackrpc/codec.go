@@ -9,8 +9,14 @@ import (
 	"go-micro.dev/v4/codec"
 )
 
+// msgpackCodec reads and writes the messages of the connection in place,
+// through a reader kept across messages so data buffered past a header isn't
+// lost, and a writer flushed as it fills and after every message.
 type msgpackCodec struct {
 	rwc  io.ReadWriteCloser
+	r    *msgp.Reader
+	w    *msgp.Writer
+	enc  *encoder
 	mt   codec.MessageType
 	body bool
 }
@@ -29,9 +35,9 @@ func (c *msgpackCodec) ReadHeader(m *codec.Message, mt codec.MessageType) error
 
 	switch mt {
 	case codec.Request:
-		var h Request
+		h := Request{enc: c.enc}
 
-		if err := msgp.Decode(c.rwc, &h); err != nil {
+		if err := h.DecodeMsg(c.r); err != nil {
 			return err
 		}
 
@@ -40,9 +46,9 @@ func (c *msgpackCodec) ReadHeader(m *codec.Message, mt codec.MessageType) error
 		m.Endpoint = h.Method
 
 	case codec.Response:
-		var h Response
+		h := Response{enc: c.enc}
 
-		if err := msgp.Decode(c.rwc, &h); err != nil {
+		if err := h.DecodeMsg(c.r); err != nil {
 			return err
 		}
 
@@ -51,9 +57,9 @@ func (c *msgpackCodec) ReadHeader(m *codec.Message, mt codec.MessageType) error
 		m.Error = h.Error
 
 	case codec.Event:
-		var h Notification
+		h := Notification{enc: c.enc}
 
-		if err := msgp.Decode(c.rwc, &h); err != nil {
+		if err := h.DecodeMsg(c.r); err != nil {
 			return err
 		}
 
@@ -67,30 +73,30 @@ func (c *msgpackCodec) ReadHeader(m *codec.Message, mt codec.MessageType) error
 	return nil
 }
 
-// ReadBody reads the body of the message. It is assumed the value being
-// decoded into is a satisfies the msgp.Decodable interface.
+// ReadBody reads the body of the message into a msgp.Decodable, a
+// *bytes.Frame, or a pointer to any other value decoded by reflection.
 func (c *msgpackCodec) ReadBody(v interface{}) error {
 	if !c.body {
 		return nil
 	}
-
-	r := msgp.NewReader(c.rwc)
+	c.body = false
 
 	// Body is present, but no value to decode into.
 	if v == nil {
-		return r.Skip()
+		return c.r.Skip()
 	}
 
 	switch c.mt {
 	case codec.Request, codec.Response, codec.Event:
-		return decodeBody(r, v)
+		return c.enc.decode(c.r, v)
 	default:
 		return fmt.Errorf("Unrecognized message type: %v", c.mt)
 	}
 }
 
 // Write writes a message to the wire which contains the header followed by the body.
-// The body is assumed to satisfy the msgp.Encodable interface.
+// The body is a msgp.Encodable, a *bytes.Frame of msgpack, or any other value
+// encoded by reflection.
 func (c *msgpackCodec) Write(m *codec.Message, b interface{}) error {
 	switch m.Type {
 	case codec.Request:
@@ -98,35 +104,65 @@ func (c *msgpackCodec) Write(m *codec.Message, b interface{}) error {
 			ID:     m.Id,
 			Method: m.Endpoint,
 			Body:   b,
+			enc:    c.enc,
 		}
 
-		return msgp.Encode(c.rwc, &h)
+		return c.flush(h.EncodeMsg(c.w))
 
 	case codec.Response:
 		h := Response{
 			ID:   m.Id,
 			Body: b,
+			enc:  c.enc,
 		}
 
 		h.Error = m.Error
 
-		return msgp.Encode(c.rwc, &h)
+		return c.flush(h.EncodeMsg(c.w))
 
 	case codec.Event:
 		h := Notification{
 			Method: m.Endpoint,
 			Body:   b,
+			enc:    c.enc,
 		}
 
-		return msgp.Encode(c.rwc, &h)
+		return c.flush(h.EncodeMsg(c.w))
 
 	default:
 		return fmt.Errorf("Unrecognized message type: %v", m.Type)
 	}
 }
 
+// flush flushes the message written, unless writing it failed.
+func (c *msgpackCodec) flush(err error) error {
+	if err != nil {
+		return err
+	}
+	return c.w.Flush()
+}
+
 func NewCodec(rwc io.ReadWriteCloser) codec.Codec {
+	return newCodec(rwc, defaultEncoder)
+}
+
+// WithOptions returns a codec constructor encoding the bodies which aren't
+// msgp generated types with the options, e.g. to read the json tags of the
+// types of a json API:
+//
+//	server.Codec("application/msgpack", msgpackrpc.WithOptions(msgpackrpc.JSONTags()))
+func WithOptions(opts ...Option) codec.NewCodec {
+	enc := newEncoder(opts...)
+	return func(rwc io.ReadWriteCloser) codec.Codec {
+		return newCodec(rwc, enc)
+	}
+}
+
+func newCodec(rwc io.ReadWriteCloser, enc *encoder) *msgpackCodec {
 	return &msgpackCodec{
 		rwc: rwc,
+		r:   msgp.NewReader(rwc),
+		w:   msgp.NewWriter(rwc),
+		enc: enc,
 	}
 }
@@ -0,0 +1,176 @@
+package msgpackrpc
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tinylib/msgp/msgp"
+	"go-micro.dev/v4/codec"
+)
+
+type rwc struct {
+	*bytes.Buffer
+}
+
+func (rwc) Close() error { return nil }
+
+type address struct {
+	City string `json:"city"`
+}
+
+type user struct {
+	UserID    int64             `json:"user_id"`
+	Name      string            `msgpack:"name"`
+	Email     string            `json:"email,omitempty"`
+	Tags      []string          `json:"tags"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Avatar    []byte            `json:"avatar"`
+	CreatedAt time.Time         `json:"created_at"`
+	Address   *address          `json:"address"`
+	Secret    string            `json:"-"`
+}
+
+func TestCodecStream(t *testing.T) {
+	buf := rwc{new(bytes.Buffer)}
+	c := WithOptions(JSONTags())(buf)
+
+	u := user{
+		UserID:    42,
+		Name:      "alice",
+		Tags:      []string{"admin"},
+		Avatar:    []byte{1, 2, 3},
+		CreatedAt: time.Unix(1700000000, 500).UTC(),
+		Address:   &address{City: "Berlin"},
+		Secret:    "hidden",
+	}
+
+	// several messages on the wire are read back in order
+	for _, id := range []string{"1", "2"} {
+		m := &codec.Message{Type: codec.Request, Id: id, Endpoint: "Users.Create"}
+		if err := c.Write(m, &u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, id := range []string{"1", "2"} {
+		var m codec.Message
+		if err := c.ReadHeader(&m, codec.Request); err != nil {
+			t.Fatal(err)
+		}
+		if m.Id != id || m.Endpoint != "Users.Create" {
+			t.Fatalf("unexpected header %+v", m)
+		}
+
+		var got user
+		if err := c.ReadBody(&got); err != nil {
+			t.Fatal(err)
+		}
+		want := u
+		want.Secret = ""
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %+v, want %+v", got, want)
+		}
+	}
+}
+
+func TestForeignPayload(t *testing.T) {
+	// a map as written by a library predating the str type, with bin keys
+	// and strings, an unknown field and a spec timestamp
+	var buf bytes.Buffer
+	w := msgp.NewWriter(&buf)
+	w.WriteMapHeader(5)
+	w.WriteBytes([]byte("user_id"))
+	w.WriteUint8(7)
+	w.WriteBytes([]byte("NAME"))
+	w.WriteBytes([]byte("bob"))
+	w.WriteString("unknown")
+	w.WriteArrayHeader(1)
+	w.WriteNil()
+	w.WriteString("created_at")
+	w.WriteExtension(&timestamp{t: time.Unix(1700000000, 0)})
+	w.WriteString("tags")
+	w.WriteNil()
+	w.Flush()
+
+	var got user
+	enc := newEncoder(JSONTags())
+	if err := enc.decode(msgp.NewReader(&buf), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.UserID != 7 || got.Name != "bob" || !got.CreatedAt.Equal(time.Unix(1700000000, 0)) {
+		t.Fatalf("unexpected user %+v", got)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	type point struct {
+		PosX  int
+		PosY  int
+		Label string
+	}
+
+	enc := func(v interface{}, opts ...Option) map[string]interface{} {
+		var buf bytes.Buffer
+		w := msgp.NewWriter(&buf)
+		if err := newEncoder(opts...).encode(w, v); err != nil {
+			t.Fatal(err)
+		}
+		w.Flush()
+		i, err := msgp.NewReader(&buf).ReadIntf()
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, _ := i.(map[string]interface{})
+		return m
+	}
+
+	m := enc(point{PosX: 1}, FieldName(SnakeCase), OmitEmpty())
+	if len(m) != 1 || m["pos_x"] != int64(1) {
+		t.Errorf("unexpected map %v", m)
+	}
+
+	// arrays are decoded into structs in field order
+	var buf bytes.Buffer
+	w := msgp.NewWriter(&buf)
+	if err := newEncoder(StructAsArray()).encode(w, point{1, 2, "a"}); err != nil {
+		t.Fatal(err)
+	}
+	w.Flush()
+	var p point
+	if err := defaultEncoder.decode(msgp.NewReader(&buf), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p != (point{1, 2, "a"}) {
+		t.Errorf("unexpected point %+v", p)
+	}
+
+	for name, want := range map[string]string{"UserID": "user_id", "HTTPServer": "http_server", "Name": "name"} {
+		if got := SnakeCase(name); got != want {
+			t.Errorf("SnakeCase(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestTimestamp(t *testing.T) {
+	for _, tm := range []time.Time{
+		time.Unix(1700000000, 0),
+		time.Unix(1700000000, 999),
+		time.Unix(1<<35, 1),
+		time.Unix(-1, 0),
+	} {
+		ts := timestamp{t: tm}
+		b := make([]byte, ts.Len())
+		if err := ts.MarshalBinaryTo(b); err != nil {
+			t.Fatal(err)
+		}
+		var got timestamp
+		if err := got.UnmarshalBinary(b); err != nil {
+			t.Fatal(err)
+		}
+		if !got.t.Equal(tm) {
+			t.Errorf("timestamp %v round-tripped to %v", tm, got.t)
+		}
+	}
+}
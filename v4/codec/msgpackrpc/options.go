@@ -0,0 +1,94 @@
+package msgpackrpc
+
+import (
+	"strings"
+	"unicode"
+)
+
+// DefaultTags are the struct tags field names are read from, the tag of
+// msgp generated types followed by the one of vmihailenco/msgpack.
+var DefaultTags = []string{"msg", "msgpack"}
+
+// Options control how bodies which aren't msgp generated types are encoded.
+type Options struct {
+	// Tags are the struct tags the name of a field is read from, the first
+	// one set wins. A "-" name skips the field.
+	Tags []string
+	// FieldName names the fields without a tag. Defaults to the Go name.
+	FieldName func(string) string
+	// OmitEmpty omits the empty fields of every struct, not just those
+	// tagged omitempty.
+	OmitEmpty bool
+	// StructAsArray encodes structs as arrays of their fields in order, as
+	// done by the array mode of other msgpack libraries.
+	StructAsArray bool
+}
+
+type Option func(*Options)
+
+// Tags sets the struct tags field names are read from, in order.
+// Defaults to DefaultTags.
+func Tags(tags ...string) Option {
+	return func(o *Options) {
+		o.Tags = tags
+	}
+}
+
+// JSONTags falls back to the json tag of fields without a msgpack tag, so
+// types shared with a json API round-trip under the same names.
+func JSONTags() Option {
+	return func(o *Options) {
+		o.Tags = append(append([]string{}, o.Tags...), "json")
+	}
+}
+
+// FieldName names the fields without a tag, e.g. SnakeCase to match the
+// field names of python or ruby producers.
+func FieldName(f func(string) string) Option {
+	return func(o *Options) {
+		o.FieldName = f
+	}
+}
+
+// OmitEmpty omits the false, zero, empty and nil fields of every struct.
+func OmitEmpty() Option {
+	return func(o *Options) {
+		o.OmitEmpty = true
+	}
+}
+
+// StructAsArray encodes structs as arrays of their fields in order. Arrays
+// are decoded into structs with or without it.
+func StructAsArray() Option {
+	return func(o *Options) {
+		o.StructAsArray = true
+	}
+}
+
+// SnakeCase returns the name in snake case, e.g. user_id for UserID.
+func SnakeCase(name string) string {
+	var b strings.Builder
+	r := []rune(name)
+	for i, c := range r {
+		if unicode.IsUpper(c) {
+			// start a word at a lower to upper change, or at the last upper
+			// of an acronym followed by a lower
+			if i > 0 && (unicode.IsLower(r[i-1]) || i+1 < len(r) && unicode.IsLower(r[i+1])) {
+				b.WriteByte('_')
+			}
+			c = unicode.ToLower(c)
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func newOptions(opts ...Option) Options {
+	o := Options{
+		Tags: DefaultTags,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
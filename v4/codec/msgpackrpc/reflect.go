@@ -0,0 +1,693 @@
+package msgpackrpc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tinylib/msgp/msgp"
+	raw "go-micro.dev/v4/codec/bytes"
+)
+
+// TimestampExtension is the extension type of the timestamps of the
+// msgpack spec, which time.Time values are encoded as.
+const TimestampExtension = -1
+
+var (
+	encodableType = reflect.TypeOf((*msgp.Encodable)(nil)).Elem()
+	decodableType = reflect.TypeOf((*msgp.Decodable)(nil)).Elem()
+	timeType      = reflect.TypeOf(time.Time{})
+
+	// defaultEncoder encodes the bodies of the codecs of NewCodec.
+	defaultEncoder = newEncoder()
+)
+
+// encoder encodes and decodes bodies which aren't msgp generated types by
+// reflection, caching the fields of every struct type.
+type encoder struct {
+	opts   Options
+	fields sync.Map
+}
+
+type field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+func newEncoder(opts ...Option) *encoder {
+	return &encoder{opts: newOptions(opts...)}
+}
+
+// encode writes the body, a msgp.Encodable, a *raw.Frame of msgpack or any
+// other value by reflection.
+func (e *encoder) encode(w *msgp.Writer, v interface{}) error {
+	switch b := v.(type) {
+	case msgp.Encodable:
+		return b.EncodeMsg(w)
+	case *raw.Frame:
+		_, err := w.Write(b.Data)
+		return err
+	}
+	return e.encodeValue(w, reflect.ValueOf(v))
+}
+
+// decode reads the body into v, a msgp.Decodable, a *raw.Frame or a
+// pointer to any other value by reflection.
+func (e *encoder) decode(r *msgp.Reader, v interface{}) error {
+	switch b := v.(type) {
+	case msgp.Decodable:
+		return b.DecodeMsg(r)
+	case *raw.Frame:
+		var buf bytes.Buffer
+		if _, err := r.CopyNext(&buf); err != nil {
+			return err
+		}
+		b.Data = buf.Bytes()
+		return nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return ErrNotDecodable
+	}
+	return e.decodeValue(r, rv.Elem())
+}
+
+func (e *encoder) encodeValue(w *msgp.Writer, v reflect.Value) error {
+	if !v.IsValid() {
+		return w.WriteNil()
+	}
+	if v.Type().Implements(encodableType) {
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return w.WriteNil()
+		}
+		return v.Interface().(msgp.Encodable).EncodeMsg(w)
+	}
+	if v.Kind() != reflect.Ptr && reflect.PtrTo(v.Type()).Implements(encodableType) {
+		if !v.CanAddr() {
+			p := reflect.New(v.Type())
+			p.Elem().Set(v)
+			v = p.Elem()
+		}
+		return v.Addr().Interface().(msgp.Encodable).EncodeMsg(w)
+	}
+	if v.Type() == timeType {
+		return w.WriteExtension(&timestamp{t: v.Interface().(time.Time)})
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return w.WriteNil()
+		}
+		return e.encodeValue(w, v.Elem())
+	case reflect.Bool:
+		return w.WriteBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return w.WriteInt64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return w.WriteUint64(v.Uint())
+	case reflect.Float32:
+		return w.WriteFloat32(float32(v.Float()))
+	case reflect.Float64:
+		return w.WriteFloat64(v.Float())
+	case reflect.String:
+		return w.WriteString(v.String())
+	case reflect.Slice:
+		if v.IsNil() {
+			return w.WriteNil()
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return w.WriteBytes(v.Bytes())
+		}
+		return e.encodeArray(w, v)
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return w.WriteBytes(b)
+		}
+		return e.encodeArray(w, v)
+	case reflect.Map:
+		if v.IsNil() {
+			return w.WriteNil()
+		}
+		if err := w.WriteMapHeader(uint32(v.Len())); err != nil {
+			return err
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := e.encodeValue(w, iter.Key()); err != nil {
+				return err
+			}
+			if err := e.encodeValue(w, iter.Value()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Struct:
+		return e.encodeStruct(w, v)
+	}
+
+	return fmt.Errorf("%w: %s", ErrNotEncodable, v.Type())
+}
+
+func (e *encoder) encodeArray(w *msgp.Writer, v reflect.Value) error {
+	if err := w.WriteArrayHeader(uint32(v.Len())); err != nil {
+		return err
+	}
+	for i := 0; i < v.Len(); i++ {
+		if err := e.encodeValue(w, v.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *encoder) encodeStruct(w *msgp.Writer, v reflect.Value) error {
+	fields := e.structFields(v.Type())
+
+	if e.opts.StructAsArray {
+		if err := w.WriteArrayHeader(uint32(len(fields))); err != nil {
+			return err
+		}
+		for _, f := range fields {
+			if err := e.encodeValue(w, fieldByIndex(v, f.index)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	values := make([]reflect.Value, len(fields))
+	n := 0
+	for i, f := range fields {
+		values[i] = fieldByIndex(v, f.index)
+		if (f.omitEmpty || e.opts.OmitEmpty) && isEmpty(values[i]) {
+			values[i] = reflect.Value{}
+			continue
+		}
+		n++
+	}
+
+	if err := w.WriteMapHeader(uint32(n)); err != nil {
+		return err
+	}
+	for i, f := range fields {
+		if !values[i].IsValid() {
+			continue
+		}
+		if err := w.WriteString(f.name); err != nil {
+			return err
+		}
+		if err := e.encodeValue(w, values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *encoder) decodeValue(r *msgp.Reader, v reflect.Value) error {
+	typ, err := r.NextType()
+	if err != nil {
+		return err
+	}
+
+	if typ == msgp.NilType {
+		v.Set(reflect.Zero(v.Type()))
+		return r.ReadNil()
+	}
+	if v.Kind() != reflect.Ptr && v.CanAddr() && v.Addr().Type().Implements(decodableType) {
+		return v.Addr().Interface().(msgp.Decodable).DecodeMsg(r)
+	}
+	if v.Type() == timeType {
+		t, err := readTime(r, typ)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return e.decodeValue(r, v.Elem())
+	case reflect.Interface:
+		if v.NumMethod() > 0 {
+			return fmt.Errorf("%w: %s", ErrNotDecodable, v.Type())
+		}
+		i, err := readIntf(r)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(&i).Elem())
+		return nil
+	case reflect.Bool:
+		b, err := r.ReadBool()
+		v.SetBool(b)
+		return err
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := r.ReadInt64()
+		if err != nil {
+			return err
+		}
+		if v.OverflowInt(i) {
+			return msgp.IntOverflow{Value: i, FailedBitsize: v.Type().Bits()}
+		}
+		v.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := r.ReadUint64()
+		if err != nil {
+			return err
+		}
+		if v.OverflowUint(u) {
+			return msgp.UintOverflow{Value: u, FailedBitsize: v.Type().Bits()}
+		}
+		v.SetUint(u)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := readFloat(r, typ)
+		v.SetFloat(f)
+		return err
+	case reflect.String:
+		s, err := readString(r, typ)
+		v.SetString(s)
+		return err
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 && typ != msgp.ArrayType {
+			b, err := readBytes(r, typ)
+			if err != nil {
+				return err
+			}
+			v.SetBytes(b)
+			return nil
+		}
+		n, err := r.ReadArrayHeader()
+		if err != nil {
+			return err
+		}
+		s := reflect.MakeSlice(v.Type(), int(n), int(n))
+		for i := 0; i < int(n); i++ {
+			if err := e.decodeValue(r, s.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 && typ != msgp.ArrayType {
+			b, err := readBytes(r, typ)
+			if err != nil {
+				return err
+			}
+			reflect.Copy(v, reflect.ValueOf(b))
+			return nil
+		}
+		n, err := r.ReadArrayHeader()
+		if err != nil {
+			return err
+		}
+		for i := 0; i < int(n); i++ {
+			if i >= v.Len() {
+				if err := r.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := e.decodeValue(r, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		n, err := r.ReadMapHeader()
+		if err != nil {
+			return err
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(v.Type(), int(n)))
+		}
+		for i := 0; i < int(n); i++ {
+			k := reflect.New(v.Type().Key()).Elem()
+			if err := e.decodeValue(r, k); err != nil {
+				return err
+			}
+			el := reflect.New(v.Type().Elem()).Elem()
+			if err := e.decodeValue(r, el); err != nil {
+				return err
+			}
+			v.SetMapIndex(k, el)
+		}
+		return nil
+	case reflect.Struct:
+		return e.decodeStruct(r, v, typ)
+	}
+
+	return fmt.Errorf("%w: %s", ErrNotDecodable, v.Type())
+}
+
+// decodeStruct decodes a map of field names, or an array of the fields in
+// order. Names match exactly or else case insensitively, unknown ones are
+// skipped.
+func (e *encoder) decodeStruct(r *msgp.Reader, v reflect.Value, typ msgp.Type) error {
+	fields := e.structFields(v.Type())
+
+	if typ == msgp.ArrayType {
+		n, err := r.ReadArrayHeader()
+		if err != nil {
+			return err
+		}
+		for i := 0; i < int(n); i++ {
+			if i >= len(fields) {
+				if err := r.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := e.decodeValue(r, allocField(v, fields[i].index)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	n, err := r.ReadMapHeader()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < int(n); i++ {
+		kt, err := r.NextType()
+		if err != nil {
+			return err
+		}
+		if kt != msgp.StrType && kt != msgp.BinType {
+			if err := r.Skip(); err != nil {
+				return err
+			}
+			if err := r.Skip(); err != nil {
+				return err
+			}
+			continue
+		}
+		name, err := readString(r, kt)
+		if err != nil {
+			return err
+		}
+
+		f := lookup(fields, name)
+		if f == nil {
+			if err := r.Skip(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := e.decodeValue(r, allocField(v, f.index)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// structFields returns the encoded fields of the struct type in order, with
+// the fields of untagged embedded structs inlined.
+func (e *encoder) structFields(t reflect.Type) []field {
+	if f, ok := e.fields.Load(t); ok {
+		return f.([]field)
+	}
+
+	var fields []field
+	seen := map[string]bool{}
+
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			name, opts, tagged := e.tag(sf)
+			if name == "-" {
+				continue
+			}
+
+			idx := append(append([]int{}, index...), i)
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if sf.Anonymous && !tagged && ft.Kind() == reflect.Struct {
+				walk(ft, idx)
+				continue
+			}
+			if len(sf.PkgPath) > 0 {
+				continue
+			}
+
+			if len(name) == 0 {
+				name = sf.Name
+				if e.opts.FieldName != nil {
+					name = e.opts.FieldName(name)
+				}
+			}
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			fields = append(fields, field{
+				name:      name,
+				index:     idx,
+				omitEmpty: strings.Contains(","+opts+",", ",omitempty,"),
+			})
+		}
+	}
+	walk(t, nil)
+
+	f, _ := e.fields.LoadOrStore(t, fields)
+	return f.([]field)
+}
+
+// tag returns the name and options of the first tag of the field set.
+func (e *encoder) tag(sf reflect.StructField) (string, string, bool) {
+	for _, t := range e.opts.Tags {
+		tag, ok := sf.Tag.Lookup(t)
+		if !ok {
+			continue
+		}
+		name, opts := tag, ""
+		if i := strings.Index(tag, ","); i >= 0 {
+			name, opts = tag[:i], tag[i+1:]
+		}
+		return name, opts, true
+	}
+	return "", "", false
+}
+
+func lookup(fields []field, name string) *field {
+	for i := range fields {
+		if fields[i].name == name {
+			return &fields[i]
+		}
+	}
+	for i := range fields {
+		if strings.EqualFold(fields[i].name, name) {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+// fieldByIndex returns the field, or an invalid value if it's in a nil
+// embedded struct.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// allocField returns the field, allocating nil embedded structs on the way.
+func allocField(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// isEmpty reports whether the value is false, zero, empty or nil, as with
+// the omitempty of encoding/json, or the zero time.
+func isEmpty(v reflect.Value) bool {
+	if !v.IsValid() {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return v.Interface().(time.Time).IsZero()
+		}
+	}
+	return false
+}
+
+// readString reads a str, or a bin as written by libraries predating the
+// str type.
+func readString(r *msgp.Reader, typ msgp.Type) (string, error) {
+	if typ == msgp.BinType {
+		b, err := r.ReadBytes(nil)
+		return string(b), err
+	}
+	return r.ReadString()
+}
+
+// readBytes reads a bin, or a str as written by libraries predating the bin
+// type.
+func readBytes(r *msgp.Reader, typ msgp.Type) ([]byte, error) {
+	if typ == msgp.StrType {
+		return r.ReadStringAsBytes(nil)
+	}
+	return r.ReadBytes(nil)
+}
+
+// readFloat reads a float, or an int as written for whole floats by some
+// libraries.
+func readFloat(r *msgp.Reader, typ msgp.Type) (float64, error) {
+	switch typ {
+	case msgp.IntType:
+		i, err := r.ReadInt64()
+		return float64(i), err
+	case msgp.UintType:
+		u, err := r.ReadUint64()
+		return float64(u), err
+	}
+	return r.ReadFloat64()
+}
+
+// readTime reads a timestamp extension, or a time of msgp.
+func readTime(r *msgp.Reader, typ msgp.Type) (time.Time, error) {
+	if typ == msgp.TimeType {
+		return r.ReadTime()
+	}
+	var ts timestamp
+	err := r.ReadExtension(&ts)
+	return ts.t, err
+}
+
+// readIntf reads any value, with timestamp extensions as time.Time.
+func readIntf(r *msgp.Reader) (interface{}, error) {
+	i, err := r.ReadIntf()
+	if err != nil {
+		return nil, err
+	}
+	return fromRaw(i)
+}
+
+func fromRaw(i interface{}) (interface{}, error) {
+	switch v := i.(type) {
+	case *msgp.RawExtension:
+		if v.Type != TimestampExtension {
+			return v, nil
+		}
+		var ts timestamp
+		if err := ts.UnmarshalBinary(v.Data); err != nil {
+			return nil, err
+		}
+		return ts.t, nil
+	case map[string]interface{}:
+		for k, e := range v {
+			e, err := fromRaw(e)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = e
+		}
+	case []interface{}:
+		for k, e := range v {
+			e, err := fromRaw(e)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = e
+		}
+	}
+	return i, nil
+}
+
+// timestamp is the timestamp extension of the msgpack spec, in the 32, 64
+// or 96 bit format.
+type timestamp struct {
+	t time.Time
+}
+
+func (ts *timestamp) ExtensionType() int8 {
+	return TimestampExtension
+}
+
+func (ts *timestamp) Len() int {
+	sec, nsec := ts.t.Unix(), ts.t.Nanosecond()
+	switch {
+	case sec>>34 != 0:
+		return 12
+	case nsec != 0 || sec>>32 != 0:
+		return 8
+	}
+	return 4
+}
+
+func (ts *timestamp) MarshalBinaryTo(b []byte) error {
+	sec, nsec := ts.t.Unix(), ts.t.Nanosecond()
+	switch ts.Len() {
+	case 4:
+		binary.BigEndian.PutUint32(b, uint32(sec))
+	case 8:
+		binary.BigEndian.PutUint64(b, uint64(nsec)<<34|uint64(sec))
+	default:
+		binary.BigEndian.PutUint32(b, uint32(nsec))
+		binary.BigEndian.PutUint64(b[4:], uint64(sec))
+	}
+	return nil
+}
+
+func (ts *timestamp) UnmarshalBinary(b []byte) error {
+	switch len(b) {
+	case 4:
+		ts.t = time.Unix(int64(binary.BigEndian.Uint32(b)), 0).UTC()
+	case 8:
+		v := binary.BigEndian.Uint64(b)
+		ts.t = time.Unix(int64(v&(1<<34-1)), int64(v>>34)).UTC()
+	case 12:
+		ts.t = time.Unix(int64(binary.BigEndian.Uint64(b[4:])), int64(binary.BigEndian.Uint32(b))).UTC()
+	default:
+		return fmt.Errorf("bad timestamp length %d", len(b))
+	}
+	return nil
+}
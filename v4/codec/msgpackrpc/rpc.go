@@ -27,14 +27,12 @@ var (
 	ErrNotDecodable     = errors.New("Not decodable")
 )
 
-// decodeBody decodes the body of the message.
-func decodeBody(r *msgp.Reader, v interface{}) error {
-	b, ok := v.(msgp.Decodable)
-	if !ok {
-		return ErrNotDecodable
+// encoderOf returns the encoder of a message, the default one if unset.
+func encoderOf(e *encoder) *encoder {
+	if e == nil {
+		return defaultEncoder
 	}
-
-	return msgp.Decode(r, b)
+	return e
 }
 
 // Request is what the client can construct to be sent to the server.
@@ -45,20 +43,13 @@ type Request struct {
 	Body   interface{}
 
 	hasBody bool
+	enc     *encoder
 }
 
-// EncodeMsg encodes the request to writer. The body is expected to
-// be an encodable type.
+// EncodeMsg encodes the request to writer. The body is a msgp.Encodable,
+// or any other value encoded by reflection.
 func (r *Request) EncodeMsg(w *msgp.Writer) error {
-	var bm msgp.Encodable
-
-	if r.Body != nil {
-		var ok bool
-		bm, ok = r.Body.(msgp.Encodable)
-		if !ok {
-			return ErrNotEncodable
-		}
-	}
+	bm := r.Body
 
 	var err error
 
@@ -88,19 +79,11 @@ func (r *Request) EncodeMsg(w *msgp.Writer) error {
 		return err
 	}
 
-	return msgp.Encode(w, bm)
+	return encoderOf(r.enc).encode(w, bm)
 }
 
 func (r *Request) DecodeMsg(mr *msgp.Reader) error {
-	var bm msgp.Decodable
-
-	if r.Body != nil {
-		var ok bool
-		bm, ok = r.Body.(msgp.Decodable)
-		if !ok {
-			return ErrNotDecodable
-		}
-	}
+	bm := r.Body
 
 	if size, err := mr.ReadArrayHeader(); err != nil {
 		return err
@@ -145,7 +128,7 @@ func (r *Request) DecodeMsg(mr *msgp.Reader) error {
 	// Skip decoding the body if no value is present to decode into.
 	// The caller is expected to decode the body or skip it.
 	if bm != nil {
-		return decodeBody(mr, bm)
+		return encoderOf(r.enc).decode(mr, bm)
 	}
 
 	return nil
@@ -157,18 +140,11 @@ type Response struct {
 	Body  interface{}
 
 	hasBody bool
+	enc     *encoder
 }
 
 func (r *Response) EncodeMsg(w *msgp.Writer) error {
-	var bm msgp.Encodable
-
-	if r.Body != nil {
-		var ok bool
-		bm, ok = r.Body.(msgp.Encodable)
-		if !ok {
-			return ErrNotEncodable
-		}
-	}
+	bm := r.Body
 
 	var err error
 
@@ -191,7 +167,7 @@ func (r *Response) EncodeMsg(w *msgp.Writer) error {
 		}
 
 		if bm != nil {
-			return msgp.Encode(w, bm)
+			return encoderOf(r.enc).encode(w, bm)
 		}
 	} else {
 		if err = w.WriteString(r.Error); err != nil {
@@ -204,15 +180,7 @@ func (r *Response) EncodeMsg(w *msgp.Writer) error {
 }
 
 func (r *Response) DecodeMsg(mr *msgp.Reader) error {
-	var bm msgp.Decodable
-
-	if r.Body != nil {
-		var ok bool
-		bm, ok = r.Body.(msgp.Decodable)
-		if !ok {
-			return ErrNotDecodable
-		}
-	}
+	bm := r.Body
 
 	if size, err := mr.ReadArrayHeader(); err != nil {
 		return err
@@ -273,7 +241,7 @@ func (r *Response) DecodeMsg(mr *msgp.Reader) error {
 	// Skip decoding the body if no value is present to decode into.
 	// The caller is expected to read the body or skip it.
 	if bm != nil {
-		return decodeBody(mr, bm)
+		return encoderOf(r.enc).decode(mr, bm)
 	}
 
 	return nil
@@ -284,20 +252,13 @@ type Notification struct {
 	Body   interface{}
 
 	hasBody bool
+	enc     *encoder
 }
 
-// EncodeMsg encodes the notification to writer. The body is expected to
-// be an encodable type.
+// EncodeMsg encodes the notification to writer. The body is a
+// msgp.Encodable, or any other value encoded by reflection.
 func (n *Notification) EncodeMsg(w *msgp.Writer) error {
-	var bm msgp.Encodable
-
-	if n.Body != nil {
-		var ok bool
-		bm, ok = n.Body.(msgp.Encodable)
-		if !ok {
-			return ErrNotEncodable
-		}
-	}
+	bm := n.Body
 
 	var err error
 
@@ -323,19 +284,11 @@ func (n *Notification) EncodeMsg(w *msgp.Writer) error {
 		return err
 	}
 
-	return msgp.Encode(w, bm)
+	return encoderOf(n.enc).encode(w, bm)
 }
 
 func (n *Notification) DecodeMsg(mr *msgp.Reader) error {
-	var bm msgp.Decodable
-
-	if n.Body != nil {
-		var ok bool
-		bm, ok = n.Body.(msgp.Decodable)
-		if !ok {
-			return ErrNotDecodable
-		}
-	}
+	bm := n.Body
 
 	if size, err := mr.ReadArrayHeader(); err != nil {
 		return err
@@ -373,7 +326,7 @@ func (n *Notification) DecodeMsg(mr *msgp.Reader) error {
 	// Skip decoding the body if no value is present to decode into.
 	// The caller is expected to decode the body or skip it.
 	if bm != nil {
-		return decodeBody(mr, bm)
+		return encoderOf(n.enc).decode(mr, bm)
 	}
 
 	return nil
@@ -0,0 +1,384 @@
+// Package bind binds the merged config into annotated structs, with defaults,
+// required keys and env overrides, and documents the keys of a struct.
+//
+// The key of a field is the name of its json tag, or the field name, as used
+// by Scan. Nested structs are keys of their own, embedded structs share the
+// keys of their parent:
+//
+//	type Config struct {
+//		Server struct {
+//			Address string        `json:"address" default:":8080" desc:"address to listen on"`
+//			Timeout time.Duration `json:"timeout" default:"30s"`
+//		} `json:"server"`
+//		Database string    `json:"database" required:"true" env:"DATABASE_URL"`
+//		MaxBody  bind.Size `json:"max_body" default:"4MiB"`
+//	}
+package bind
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-micro.dev/v4/config"
+)
+
+// Bind binds the merged config of c into the struct pointed to by v.
+func Bind(c config.Config, v interface{}, opts ...Option) error {
+	return Values(c.Map(), v, opts...)
+}
+
+// Values binds the config values into the struct pointed to by v. A key is
+// set from its env variable, else from the values, else from its default,
+// else it keeps the value of the field. Keys are matched case insensitively.
+// All the invalid and missing required keys are returned as Errors.
+func Values(values map[string]interface{}, v interface{}, opts ...Option) error {
+	options := newOptions(opts...)
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind: expected a pointer to a struct, got %T", v)
+	}
+
+	var errs Errors
+	for _, f := range fields(rv.Elem(), nil) {
+		if err := f.bind(values, options); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// FieldError is the error of a key.
+type FieldError struct {
+	// Key is the path of the key, e.g. server.port
+	Key string
+	// Field is the Go path of the field, e.g. Config.Server.Port
+	Field string
+	// Source is where the value came from, env, config or default
+	Source string
+	// Env is the variable overriding the key, if any
+	Env string
+	Err error
+}
+
+func (e *FieldError) Error() string {
+	if len(e.Source) == 0 {
+		return fmt.Sprintf("%s (%s): %v", e.Key, e.Field, e.Err)
+	}
+	return fmt.Sprintf("%s (%s) from %s: %v", e.Key, e.Field, e.Source, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Errors are the errors of the keys of a struct.
+type Errors []*FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("bind: %d invalid config keys: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// field is a leaf of a struct bound to a key.
+type field struct {
+	path     []string
+	name     string
+	value    reflect.Value
+	def      string
+	hasDef   bool
+	env      string
+	required bool
+	desc     string
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	textType     = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	jsonType     = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// leaf reports whether a value of the type is bound as a whole.
+func leaf(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return true
+	}
+	p := reflect.PtrTo(t)
+	return p.Implements(textType) || p.Implements(jsonType)
+}
+
+// fields returns the leaves of the struct, allocating nil struct pointers.
+func fields(v reflect.Value, path []string) []*field {
+	var fs []*field
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if len(sf.PkgPath) > 0 && !sf.Anonymous {
+			continue
+		}
+
+		name := sf.Name
+		if tag, ok := sf.Tag.Lookup("json"); ok {
+			n := strings.Split(tag, ",")[0]
+			if n == "-" {
+				continue
+			}
+			if len(n) > 0 {
+				name = n
+			}
+		}
+
+		fv := v.Field(i)
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr && ft.Elem().Kind() == reflect.Struct && !leaf(ft.Elem()) {
+			if fv.IsNil() {
+				fv.Set(reflect.New(ft.Elem()))
+			}
+			fv = fv.Elem()
+			ft = ft.Elem()
+		}
+
+		if !leaf(ft) {
+			p := append(path[:len(path):len(path)], name)
+			if _, tagged := sf.Tag.Lookup("json"); sf.Anonymous && !tagged {
+				p = path
+			}
+			fs = append(fs, fields(fv, p)...)
+			continue
+		}
+		if len(sf.PkgPath) > 0 {
+			continue
+		}
+
+		f := &field{
+			path:  append(path[:len(path):len(path)], name),
+			name:  sf.Name,
+			value: fv,
+			env:   sf.Tag.Get("env"),
+			desc:  sf.Tag.Get("desc"),
+		}
+		f.def, f.hasDef = sf.Tag.Lookup("default")
+		f.required, _ = strconv.ParseBool(sf.Tag.Get("required"))
+		fs = append(fs, f)
+	}
+
+	return fs
+}
+
+func (f *field) key() string {
+	return strings.Join(f.path, ".")
+}
+
+// envName returns the variable overriding the key, if any.
+func (f *field) envName(o Options) string {
+	if len(f.env) > 0 || len(o.EnvPrefix) == 0 {
+		return f.env
+	}
+	name := strings.ToUpper(o.EnvPrefix + "_" + strings.Join(f.path, "_"))
+	return strings.NewReplacer("-", "_", ".", "_").Replace(name)
+}
+
+func (f *field) bind(values map[string]interface{}, o Options) *FieldError {
+	env := f.envName(o)
+	fail := func(source string, err error) *FieldError {
+		return &FieldError{Key: f.key(), Field: f.name, Source: source, Env: env, Err: err}
+	}
+
+	if len(env) > 0 {
+		if s, ok := o.LookupEnv(env); ok {
+			if err := setString(f.value, s); err != nil {
+				return fail("env "+env, err)
+			}
+			return nil
+		}
+	}
+
+	if x, ok := lookup(values, f.path); ok && x != nil {
+		if err := setValue(f.value, x); err != nil {
+			return fail("config", err)
+		}
+		return nil
+	}
+
+	if f.hasDef {
+		if err := setString(f.value, f.def); err != nil {
+			return fail("default", err)
+		}
+		return nil
+	}
+
+	if f.required {
+		msg := "required, set it in the config"
+		if len(env) > 0 {
+			msg += " or with " + env
+		}
+		return fail("", fmt.Errorf("%s", msg))
+	}
+
+	return nil
+}
+
+// lookup returns the value at the path, matching keys case insensitively.
+func lookup(values map[string]interface{}, path []string) (interface{}, bool) {
+	var cur interface{} = values
+	for _, p := range path {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		v, ok := m[p]
+		if !ok {
+			for k, kv := range m {
+				if strings.EqualFold(k, p) {
+					v, ok = kv, true
+					break
+				}
+			}
+		}
+		if !ok {
+			return nil, false
+		}
+		cur = v
+	}
+	return cur, true
+}
+
+// setString sets the value from a string of the env or a default.
+func setString(v reflect.Value, s string) error {
+	if v.CanAddr() && v.Addr().Type().Implements(textType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q, expected a number with a unit such as 300ms or 1h30m", s)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid bool %q, expected true or false", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 0, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid %s %q", v.Type(), s)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 0, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid %s %q", v.Type(), s)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid %s %q", v.Type(), s)
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		if s = strings.TrimSpace(s); strings.HasPrefix(s, "[") {
+			return setJSON(v, s)
+		}
+		parts := strings.Split(s, ",")
+		sl := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, p := range parts {
+			if err := setString(sl.Index(i), strings.TrimSpace(p)); err != nil {
+				return fmt.Errorf("element %d: %v", i, err)
+			}
+		}
+		v.Set(sl)
+	default:
+		return setJSON(v, s)
+	}
+
+	return nil
+}
+
+// setValue sets the value from a decoded config value.
+func setValue(v reflect.Value, x interface{}) error {
+	if s, ok := x.(string); ok {
+		return setString(v, s)
+	}
+	// sources decoding with UseNumber keep numbers as json.Number
+	if jn, ok := x.(json.Number); ok {
+		f, err := jn.Float64()
+		if err != nil {
+			return fmt.Errorf("invalid number %s", jn)
+		}
+		x = f
+	}
+
+	n, isNumber := x.(float64)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !isNumber {
+			break
+		}
+		if n != math.Trunc(n) || v.OverflowInt(int64(n)) {
+			return fmt.Errorf("invalid %s %v", v.Type(), n)
+		}
+		v.SetInt(int64(n))
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if !isNumber {
+			break
+		}
+		if n != math.Trunc(n) || n < 0 || v.OverflowUint(uint64(n)) {
+			return fmt.Errorf("invalid %s %v", v.Type(), n)
+		}
+		v.SetUint(uint64(n))
+		return nil
+	case reflect.Slice:
+		xs, ok := x.([]interface{})
+		if !ok {
+			break
+		}
+		sl := reflect.MakeSlice(v.Type(), len(xs), len(xs))
+		for i, e := range xs {
+			if err := setValue(sl.Index(i), e); err != nil {
+				return fmt.Errorf("element %d: %v", i, err)
+			}
+		}
+		v.Set(sl)
+		return nil
+	}
+
+	b, err := json.Marshal(x)
+	if err != nil {
+		return err
+	}
+	return setJSON(v, string(b))
+}
+
+func setJSON(v reflect.Value, s string) error {
+	p := reflect.New(v.Type())
+	if err := json.Unmarshal([]byte(s), p.Interface()); err != nil {
+		return fmt.Errorf("invalid %s %s", v.Type(), s)
+	}
+	v.Set(p.Elem())
+	return nil
+}
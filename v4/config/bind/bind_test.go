@@ -0,0 +1,171 @@
+package bind
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/config"
+	"go-micro.dev/v4/config/source/memory"
+)
+
+type Server struct {
+	Address string        `json:"address" default:":8080" desc:"address to listen on"`
+	Timeout time.Duration `json:"timeout" default:"30s"`
+}
+
+type Limits struct {
+	MaxBody Size `json:"max_body" default:"4MiB"`
+}
+
+type Config struct {
+	Server   Server   `json:"server"`
+	Database string   `json:"database" required:"true" env:"DATABASE_URL"`
+	Replicas int      `json:"replicas" default:"1"`
+	Tags     []string `json:"tags"`
+	Debug    bool     `json:"debug"`
+	Cache    *Server  `json:"cache"`
+	Limits
+	ignored string
+}
+
+func env(vars map[string]string) Option {
+	return LookupEnv(func(k string) (string, bool) {
+		v, ok := vars[k]
+		return v, ok
+	})
+}
+
+func TestBind(t *testing.T) {
+	c, err := config.NewConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte(`{"Server": {"timeout": "5s"}, "database": "postgres://db", "tags": ["a", "b"], "cache": {"address": ":6379"}, "max_body": 1024}`)
+	if err := c.Load(memory.NewSource(memory.WithJSON(data))); err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg Config
+	cfg.Debug = true
+	if err := Bind(c, &cfg, EnvPrefix("test"), env(map[string]string{"TEST_REPLICAS": "3"})); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Server.Address != ":8080" || cfg.Server.Timeout != 5*time.Second {
+		t.Errorf("Expected the default address and configured timeout, got %+v", cfg.Server)
+	}
+	if cfg.Database != "postgres://db" || cfg.Replicas != 3 || cfg.MaxBody != 1024 || !cfg.Debug {
+		t.Errorf("Unexpected config %+v", cfg)
+	}
+	if strings.Join(cfg.Tags, ",") != "a,b" || cfg.Cache == nil || cfg.Cache.Address != ":6379" || cfg.Cache.Timeout != 30*time.Second {
+		t.Errorf("Unexpected tags %v or cache %+v", cfg.Tags, cfg.Cache)
+	}
+}
+
+func TestEnv(t *testing.T) {
+	var cfg Config
+	err := Values(map[string]interface{}{"database": "file"}, &cfg, EnvPrefix("test"), env(map[string]string{
+		"DATABASE_URL":        "postgres://env",
+		"TEST_TAGS":           "a, b",
+		"TEST_MAX_BODY":       "1.5MB",
+		"TEST_SERVER_TIMEOUT": "1m",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Database != "postgres://env" || strings.Join(cfg.Tags, ",") != "a,b" || cfg.MaxBody != 1500000 || cfg.Server.Timeout != time.Minute {
+		t.Errorf("Expected the env to override the config, got %+v", cfg)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	var cfg Config
+	err := Values(map[string]interface{}{
+		"server":   map[string]interface{}{"timeout": "5 seconds"},
+		"replicas": 1.5,
+	}, &cfg, EnvPrefix("test"), env(map[string]string{"TEST_DEBUG": "yes"}))
+
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected errors, got %v", err)
+	}
+
+	keys := map[string]string{}
+	for _, e := range errs {
+		keys[e.Key] = e.Error()
+	}
+	if len(keys) != 4 {
+		t.Fatalf("Expected four invalid keys, got %v", err)
+	}
+	if msg := keys["database"]; !strings.Contains(msg, "required") || !strings.Contains(msg, "DATABASE_URL") {
+		t.Errorf("Expected database to be required, got %q", msg)
+	}
+	if msg := keys["server.timeout"]; !strings.Contains(msg, "from config") || !strings.Contains(msg, "invalid duration") {
+		t.Errorf("Unexpected timeout error %q", msg)
+	}
+	if msg := keys["debug"]; !strings.Contains(msg, "from env TEST_DEBUG") {
+		t.Errorf("Unexpected debug error %q", msg)
+	}
+	if _, ok := keys["replicas"]; !ok {
+		t.Errorf("Expected a fractional replica count to be rejected, got %v", err)
+	}
+}
+
+func TestSize(t *testing.T) {
+	testData := map[string]Size{
+		"1024":   1024,
+		"512B":   512,
+		"4 KiB":  4096,
+		"64MB":   64e6,
+		"1.5GiB": 3 << 29,
+		"2kB":    2000,
+	}
+
+	for s, expected := range testData {
+		size, err := ParseSize(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size != expected {
+			t.Errorf("Expected %q to be %d, got %d", s, expected, size)
+		}
+	}
+
+	for _, s := range []string{"", "MB", "-1KB", "10 parsecs"} {
+		if _, err := ParseSize(s); err == nil {
+			t.Errorf("Expected %q to be invalid", s)
+		}
+	}
+
+	if s := Size(4 << 20).String(); s != "4MiB" {
+		t.Errorf("Expected 4MiB, got %s", s)
+	}
+}
+
+func TestDocument(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := Document(buf, Config{}, EnvPrefix("test")); err != nil {
+		t.Fatal(err)
+	}
+	t.Log(buf.String())
+
+	for _, line := range []string{
+		"| `server.address` | string | `:8080` | `TEST_SERVER_ADDRESS` | address to listen on |",
+		"| `server.timeout` | duration | `30s` | `TEST_SERVER_TIMEOUT` |  |",
+		"| `database` | string | required | `DATABASE_URL` |  |",
+		"| `tags` | []string |  | `TEST_TAGS` |  |",
+		"| `cache.address` | string | `:8080` | `TEST_CACHE_ADDRESS` | address to listen on |",
+		"| `max_body` | size | `4MiB` | `TEST_MAX_BODY` |  |",
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("Expected line %q", line)
+		}
+	}
+	if strings.Contains(buf.String(), "ignored") {
+		t.Error("Expected unexported fields to be skipped")
+	}
+}
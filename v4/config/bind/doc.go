@@ -0,0 +1,92 @@
+package bind
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Key documents a key of a struct.
+type Key struct {
+	// Key is the path of the key, e.g. server.port
+	Key string
+	// Type is the type of the value, e.g. duration or []string
+	Type     string
+	Default  string
+	Required bool
+	// Env is the variable overriding the key, if any
+	Env         string
+	Description string
+}
+
+// Keys returns the keys of the struct pointed to by v, or of a struct value,
+// in the order of the fields.
+func Keys(v interface{}, opts ...Option) ([]Key, error) {
+	options := newOptions(opts...)
+
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("bind: expected a struct, got %T", v)
+	}
+
+	var keys []Key
+	for _, f := range fields(reflect.New(t).Elem(), nil) {
+		keys = append(keys, Key{
+			Key:         f.key(),
+			Type:        typeName(f.value.Type()),
+			Default:     f.def,
+			Required:    f.required,
+			Env:         f.envName(options),
+			Description: f.desc,
+		})
+	}
+	return keys, nil
+}
+
+// Document writes the keys of the struct as a markdown table, e.g. for the
+// README of a service or a --help-config flag.
+func Document(w io.Writer, v interface{}, opts ...Option) error {
+	keys, err := Keys(v, opts...)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintln(w, "| Key | Type | Default | Env | Description |\n|-----|------|---------|-----|-------------|"); err != nil {
+		return err
+	}
+	for _, k := range keys {
+		def := code(k.Default)
+		if k.Required {
+			def = "required"
+		}
+		desc := strings.ReplaceAll(k.Description, "|", "\\|")
+		if _, err := fmt.Fprintf(w, "| `%s` | %s | %s | %s | %s |\n", k.Key, k.Type, def, code(k.Env), desc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func code(s string) string {
+	if len(s) == 0 {
+		return ""
+	}
+	return "`" + s + "`"
+}
+
+func typeName(t reflect.Type) string {
+	switch t {
+	case durationType:
+		return "duration"
+	case reflect.TypeOf(Size(0)):
+		return "size"
+	}
+	if t.Kind() == reflect.Slice {
+		return "[]" + typeName(t.Elem())
+	}
+	return t.String()
+}
@@ -0,0 +1,45 @@
+package bind
+
+import (
+	"os"
+)
+
+// Options configure the binding of a struct.
+type Options struct {
+	// EnvPrefix enables the env override of every key, the variable is the
+	// prefix and the key path in upper case joined by underscores, e.g.
+	// MYAPP_SERVER_PORT for server.port. Without a prefix only the keys with
+	// an env tag can be overridden
+	EnvPrefix string
+	// LookupEnv looks up env variables. Defaults to os.LookupEnv
+	LookupEnv func(key string) (string, bool)
+}
+
+// Option sets an option.
+type Option func(*Options)
+
+// EnvPrefix sets the prefix of the env variables overriding the keys.
+func EnvPrefix(p string) Option {
+	return func(o *Options) {
+		o.EnvPrefix = p
+	}
+}
+
+// LookupEnv sets the lookup of env variables, e.g. for tests.
+func LookupEnv(fn func(key string) (string, bool)) Option {
+	return func(o *Options) {
+		o.LookupEnv = fn
+	}
+}
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		LookupEnv: os.LookupEnv,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return options
+}
@@ -0,0 +1,69 @@
+package bind
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Size is a size in bytes, bound from a number of bytes or a string with a
+// unit such as "512KiB" or "64MB". The units of powers of 1000 are kB, MB,
+// GB and TB, the ones of powers of 1024 KiB, MiB, GiB and TiB.
+type Size int64
+
+var units = []struct {
+	suffix string
+	size   Size
+}{
+	// longest suffixes first
+	{"KiB", 1 << 10},
+	{"MiB", 1 << 20},
+	{"GiB", 1 << 30},
+	{"TiB", 1 << 40},
+	{"kB", 1e3},
+	{"KB", 1e3},
+	{"MB", 1e6},
+	{"GB", 1e9},
+	{"TB", 1e12},
+	{"B", 1},
+}
+
+// ParseSize parses a size such as "64MB".
+func ParseSize(s string) (Size, error) {
+	t := strings.TrimSpace(s)
+
+	unit := Size(1)
+	for _, u := range units {
+		if strings.HasSuffix(t, u.suffix) {
+			t = strings.TrimSpace(strings.TrimSuffix(t, u.suffix))
+			unit = u.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(t, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q, expected bytes or a number with a unit such as 64MB or 512KiB", s)
+	}
+	return Size(n * float64(unit)), nil
+}
+
+// String returns the size with the largest binary unit it's a multiple of.
+func (s Size) String() string {
+	for i := 3; i >= 0; i-- {
+		if u := units[i]; s != 0 && s%u.size == 0 {
+			return fmt.Sprintf("%d%s", s/u.size, u.suffix)
+		}
+	}
+	return fmt.Sprintf("%dB", int64(s))
+}
+
+// UnmarshalText parses the size, so sizes can be decoded from any encoding.
+func (s *Size) UnmarshalText(b []byte) error {
+	v, err := ParseSize(string(b))
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
@@ -0,0 +1,73 @@
+package safe
+
+import (
+	"reflect"
+
+	"github.com/go-micro/plugins/v4/config/bind"
+	"go-micro.dev/v4/config/reader"
+	"go-micro.dev/v4/config/reader/json"
+)
+
+// Options configure the verification of a source.
+type Options struct {
+	// Verifiers check the values of every change set, in order
+	Verifiers []Verifier
+	// Reader decodes the change sets, it needs the encoders of their
+	// formats. Defaults to the json reader
+	Reader reader.Reader
+	// OnReject is called with every change set rejected
+	OnReject func(Rejection)
+}
+
+// Option sets an option.
+type Option func(*Options)
+
+// Verify adds verifiers of the change sets.
+func Verify(fns ...Verifier) Option {
+	return func(o *Options) {
+		o.Verifiers = append(o.Verifiers, fns...)
+	}
+}
+
+// Reader sets the reader decoding the change sets, e.g. one with the yaml
+// encoder for a source of yaml files.
+func Reader(r reader.Reader) Option {
+	return func(o *Options) {
+		o.Reader = r
+	}
+}
+
+// OnReject sets the hook called with every change set rejected.
+func OnReject(fn func(Rejection)) Option {
+	return func(o *Options) {
+		o.OnReject = fn
+	}
+}
+
+// Schema returns a verifier binding the values into a new struct of the type
+// of v, see bind.Values, so missing required keys and values of the wrong
+// type are rejected.
+func Schema(v interface{}, opts ...bind.Option) Verifier {
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return func(vals reader.Values) error {
+		return bind.Values(vals.Map(), reflect.New(t).Interface(), opts...)
+	}
+}
+
+func newOptions(opts ...Option) Options {
+	var options Options
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if options.Reader == nil {
+		options.Reader = json.NewReader()
+	}
+
+	return options
+}
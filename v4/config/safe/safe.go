@@ -0,0 +1,174 @@
+// Package safe guards the config against bad updates of a source. Change sets
+// of the source which don't decode, or fail a verifier such as the schema of
+// a struct, are rejected and never reach the config, which keeps serving the
+// last accepted values of the source. The rejected change set is kept for
+// inspection and reported to a hook, e.g. to count it in an alert metric.
+package safe
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go-micro.dev/v4/config/reader"
+	"go-micro.dev/v4/config/source"
+	"go-micro.dev/v4/logger"
+)
+
+// Verifier checks the values of a change set, returning why they're invalid.
+type Verifier func(reader.Values) error
+
+// Rejection is a change set which was rejected.
+type Rejection struct {
+	// ChangeSet is the change set rejected, its checksum identifies the
+	// version
+	ChangeSet *source.ChangeSet
+	// Err is why it was rejected
+	Err error
+	// Time is when it was rejected
+	Time time.Time
+}
+
+// Source is a source verifying the change sets of the source it wraps.
+type Source struct {
+	source source.Source
+	opts   Options
+
+	sync.RWMutex
+	accepted  *source.ChangeSet
+	rejection *Rejection
+	rejected  uint64
+}
+
+// NewSource wraps the source, verifying its change sets before the config loads
+// them. The verifiers see the values of this source only, not the config
+// merged from all sources.
+func NewSource(s source.Source, opts ...Option) *Source {
+	return &Source{
+		source: s,
+		opts:   newOptions(opts...),
+	}
+}
+
+// Read reads the source. A rejected change set is replaced by the last one
+// accepted, if any, so a sync of the config keeps its values.
+func (s *Source) Read() (*source.ChangeSet, error) {
+	cs, err := s.source.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.verify(cs); err != nil {
+		s.RLock()
+		accepted := s.accepted
+		s.RUnlock()
+
+		if accepted == nil {
+			return nil, err
+		}
+		return accepted, nil
+	}
+
+	return cs, nil
+}
+
+// Write writes to the source.
+func (s *Source) Write(cs *source.ChangeSet) error {
+	return s.source.Write(cs)
+}
+
+// Watch watches the source, skipping the change sets rejected.
+func (s *Source) Watch() (source.Watcher, error) {
+	w, err := s.source.Watch()
+	if err != nil {
+		return nil, err
+	}
+	return &watcher{s: s, w: w}, nil
+}
+
+func (s *Source) String() string {
+	return s.source.String()
+}
+
+// Rejected returns the rejection of the latest change set, nil if it was
+// accepted.
+func (s *Source) Rejected() *Rejection {
+	s.RLock()
+	defer s.RUnlock()
+	return s.rejection
+}
+
+// Rejections returns the number of change sets rejected.
+func (s *Source) Rejections() uint64 {
+	s.RLock()
+	defer s.RUnlock()
+	return s.rejected
+}
+
+// verify checks the change set, recording it as accepted or rejected.
+func (s *Source) verify(cs *source.ChangeSet) error {
+	err := s.check(cs)
+
+	s.Lock()
+	if err == nil {
+		s.accepted = cs
+		s.rejection = nil
+		s.Unlock()
+		return nil
+	}
+
+	r := &Rejection{ChangeSet: cs, Err: err, Time: time.Now()}
+	s.rejection = r
+	s.rejected++
+	s.Unlock()
+
+	logger.Warnf("Rejected config %s of source %s, keeping the previous values: %v", cs.Checksum, s.String(), err)
+	if s.opts.OnReject != nil {
+		s.opts.OnReject(*r)
+	}
+
+	return err
+}
+
+func (s *Source) check(cs *source.ChangeSet) error {
+	// a change set which doesn't merge would stop the config from loading
+	// any further change of the source
+	set, err := s.opts.Reader.Merge(cs)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %v", cs.Format, err)
+	}
+
+	vals, err := s.opts.Reader.Values(set)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %v", cs.Format, err)
+	}
+
+	for _, v := range s.opts.Verifiers {
+		if err := v(vals); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type watcher struct {
+	s *Source
+	w source.Watcher
+}
+
+func (w *watcher) Next() (*source.ChangeSet, error) {
+	for {
+		cs, err := w.w.Next()
+		if err != nil {
+			return nil, err
+		}
+		if err := w.s.verify(cs); err != nil {
+			continue
+		}
+		return cs, nil
+	}
+}
+
+func (w *watcher) Stop() error {
+	return w.w.Stop()
+}
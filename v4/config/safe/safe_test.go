@@ -0,0 +1,129 @@
+package safe
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/config"
+	"go-micro.dev/v4/config/reader"
+	"go-micro.dev/v4/config/source"
+)
+
+// testSource sends the change sets written to it to its watcher.
+type testSource struct {
+	sync.Mutex
+	data []byte
+	ch   chan *source.ChangeSet
+}
+
+func newTestSource(data string) *testSource {
+	return &testSource{data: []byte(data), ch: make(chan *source.ChangeSet)}
+}
+
+func (t *testSource) changeSet(data []byte) *source.ChangeSet {
+	cs := &source.ChangeSet{Data: data, Format: "json", Source: "test", Timestamp: time.Now()}
+	cs.Checksum = cs.Sum()
+	return cs
+}
+
+func (t *testSource) Read() (*source.ChangeSet, error) {
+	t.Lock()
+	defer t.Unlock()
+	return t.changeSet(t.data), nil
+}
+
+func (t *testSource) Write(*source.ChangeSet) error  { return nil }
+func (t *testSource) String() string                 { return "test" }
+func (t *testSource) Watch() (source.Watcher, error) { return t, nil }
+func (t *testSource) Stop() error                    { return nil }
+
+func (t *testSource) Next() (*source.ChangeSet, error) {
+	return <-t.ch, nil
+}
+
+func (t *testSource) update(data string) {
+	t.Lock()
+	t.data = []byte(data)
+	t.Unlock()
+	t.ch <- t.changeSet([]byte(data))
+}
+
+type Config struct {
+	Address string `json:"address" required:"true"`
+	Port    int    `json:"port"`
+}
+
+func TestRollback(t *testing.T) {
+	ts := newTestSource(`{"address": "a", "port": 1}`)
+
+	var mu sync.Mutex
+	var rejections []Rejection
+	s := NewSource(ts,
+		Verify(Schema(Config{}), func(v reader.Values) error {
+			if v.Get("port").Int(0) > 65535 {
+				return errors.New("port out of range")
+			}
+			return nil
+		}),
+		OnReject(func(r Rejection) {
+			mu.Lock()
+			rejections = append(rejections, r)
+			mu.Unlock()
+		}),
+	)
+
+	c, _ := config.NewConfig()
+	if err := c.Load(s); err != nil {
+		t.Fatal(err)
+	}
+	w, err := c.Watch("port")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, bad := range []string{`{"port": 2}`, `{"address": "b", "port": 70000}`, `{"address": `} {
+		ts.update(bad)
+	}
+	mu.Lock()
+	n := len(rejections)
+	mu.Unlock()
+	if s.Rejections() != 3 || n != 3 {
+		t.Fatalf("Expected 3 rejections, got %d", s.Rejections())
+	}
+	if r := s.Rejected(); r == nil || string(r.ChangeSet.Data) != `{"address": ` {
+		t.Fatalf("Expected the last change set rejected, got %+v", r)
+	}
+	if p := c.Get("port").Int(0); p != 1 {
+		t.Errorf("Expected to keep the previous port 1, got %d", p)
+	}
+
+	// a sync reads the bad change set again, the config keeps its values
+	if err := c.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if a := c.Get("address").String(""); a != "a" {
+		t.Errorf("Expected to keep the previous address a, got %s", a)
+	}
+
+	go ts.update(`{"address": "b", "port": 2}`)
+	v, err := w.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Int(0) != 2 {
+		t.Errorf("Expected the accepted port 2, got %d", v.Int(0))
+	}
+	if s.Rejected() != nil {
+		t.Errorf("Expected no rejection after an accepted change, got %+v", s.Rejected())
+	}
+}
+
+func TestRejectFirstRead(t *testing.T) {
+	s := NewSource(newTestSource(`{"port": 1}`), Verify(Schema(&Config{})))
+
+	if _, err := s.Read(); err == nil {
+		t.Fatal("Expected the missing address to fail the read")
+	}
+}
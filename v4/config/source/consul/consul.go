@@ -13,7 +13,6 @@ import (
 type consul struct {
 	prefix      string
 	stripPrefix string
-	addr        string
 	opts        source.Options
 	client      *api.Client
 }
@@ -30,16 +29,17 @@ func (c *consul) Read() (*source.ChangeSet, error) {
 		return nil, err
 	}
 
-	if kv == nil || len(kv) == 0 {
+	if len(kv) == 0 {
 		return nil, fmt.Errorf("source not found: %s", c.prefix)
 	}
 
-	data, err := makeMap(c.opts.Encoder, kv, c.stripPrefix)
-	if err != nil {
-		return nil, fmt.Errorf("error reading data: %v", err)
-	}
+	return c.changeSet(kv)
+}
 
-	b, err := c.opts.Encoder.Encode(data)
+// changeSet merges the keys of the prefix, listed at one index, into the
+// tree of a change set.
+func (c *consul) changeSet(kv api.KVPairs) (*source.ChangeSet, error) {
+	b, err := c.opts.Encoder.Encode(makeMap(c.opts.Encoder, kv, c.stripPrefix))
 	if err != nil {
 		return nil, fmt.Errorf("error reading source: %v", err)
 	}
@@ -64,12 +64,10 @@ func (c *consul) String() string {
 	return "consul"
 }
 
+// Watch watches the prefix with blocking queries, a change of any key under
+// it results in a change set of the whole tree.
 func (c *consul) Watch() (source.Watcher, error) {
-	w, err := newWatcher(c.prefix, c.addr, c.String(), c.stripPrefix, c.opts.Encoder)
-	if err != nil {
-		return nil, err
-	}
-	return w, nil
+	return newWatcher(c), nil
 }
 
 // NewSource creates a new consul source.
@@ -124,7 +122,6 @@ func NewSource(opts ...source.Option) source.Source {
 	return &consul{
 		prefix:      prefix,
 		stripPrefix: sp,
-		addr:        config.Address,
 		opts:        options,
 		client:      client,
 	}
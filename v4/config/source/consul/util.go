@@ -1,36 +1,17 @@
 package consul
 
 import (
-	"fmt"
 	"strings"
 
 	"github.com/hashicorp/consul/api"
 	"go-micro.dev/v4/config/encoder"
 )
 
-type configValue interface {
-	Value() interface{}
-	Decode(encoder.Encoder, []byte) error
-}
-type configArrayValue struct {
-	v []interface{}
-}
-
-func (a *configArrayValue) Value() interface{} { return a.v }
-func (a *configArrayValue) Decode(e encoder.Encoder, b []byte) error {
-	return e.Decode(b, &a.v)
-}
-
-type configMapValue struct {
-	v map[string]interface{}
-}
-
-func (m *configMapValue) Value() interface{} { return m.v }
-func (m *configMapValue) Decode(e encoder.Encoder, b []byte) error {
-	return e.Decode(b, &m.v)
-}
-
-func makeMap(e encoder.Encoder, kv api.KVPairs, stripPrefix string) (map[string]interface{}, error) {
+// makeMap merges the keys of the prefix into a tree, split on "/". Values
+// which the encoder decodes are set as such, others as strings, so nested
+// keys may hold maps, arrays and scalars alike. A map value is merged with
+// the keys nested under it.
+func makeMap(e encoder.Encoder, kv api.KVPairs, stripPrefix string) map[string]interface{} {
 	data := make(map[string]interface{})
 
 	// consul guarantees lexicographic order, so no need to sort
@@ -39,50 +20,68 @@ func makeMap(e encoder.Encoder, kv api.KVPairs, stripPrefix string) (map[string]
 		if pathString == "" {
 			continue
 		}
-		var val configValue
-		var err error
 
-		// ensure a valid value is stored at this location
-		if len(v.Value) > 0 {
-			// try to decode into map value or array value
-			arrayV := &configArrayValue{v: []interface{}{}}
-			mapV := &configMapValue{v: map[string]interface{}{}}
-			switch {
-			case arrayV.Decode(e, v.Value) == nil:
-				val = arrayV
-			case mapV.Decode(e, v.Value) == nil:
-				val = mapV
-			default:
-				return nil, fmt.Errorf("faild decode value. path: %s, error: %s", pathString, err)
-			}
-		}
+		// folders, keys ending with a /, only create their node
+		folder := strings.HasSuffix(pathString, "/")
+		path := strings.Split(strings.TrimSuffix(pathString, "/"), "/")
 
 		// set target at the root
 		target := data
-		path := strings.Split(pathString, "/")
-		// find (or create) the leaf node we want to put this value at
+		// find (or create) the node the leaf is put at, replacing values
+		// which aren't a map since the nested keys take precedence
 		for _, dir := range path[:len(path)-1] {
-			if _, ok := target[dir]; !ok {
-				target[dir] = make(map[string]interface{})
+			m, ok := target[dir].(map[string]interface{})
+			if !ok {
+				m = make(map[string]interface{})
+				target[dir] = m
 			}
-			target = target[dir].(map[string]interface{})
+			target = m
 		}
 
 		leafDir := path[len(path)-1]
 
-		// copy over the keys from the value
-		switch val.(type) {
-		case *configArrayValue:
-			target[leafDir] = val.Value()
-		case *configMapValue:
-			target[leafDir] = make(map[string]interface{})
-			target = target[leafDir].(map[string]interface{})
-			mapv := val.Value().(map[string]interface{})
-			for k := range mapv {
-				target[k] = mapv[k]
+		if folder {
+			if _, ok := target[leafDir].(map[string]interface{}); !ok {
+				target[leafDir] = make(map[string]interface{})
 			}
+			continue
 		}
+
+		// ensure a valid value is stored at this location
+		if len(v.Value) == 0 {
+			continue
+		}
+
+		val := decodeValue(e, v.Value)
+		if m, ok := val.(map[string]interface{}); ok {
+			if existing, ok := target[leafDir].(map[string]interface{}); ok {
+				mergeMap(existing, m)
+				continue
+			}
+		}
+		target[leafDir] = val
 	}
 
-	return data, nil
+	return data
+}
+
+func decodeValue(e encoder.Encoder, b []byte) interface{} {
+	var v interface{}
+	if err := e.Decode(b, &v); err == nil {
+		return v
+	}
+	return string(b)
+}
+
+// mergeMap merges src into dst, keeping the keys of dst.
+func mergeMap(dst, src map[string]interface{}) {
+	for k, v := range src {
+		dm, dok := dst[k].(map[string]interface{})
+		sm, sok := v.(map[string]interface{})
+		if dok && sok {
+			mergeMap(dm, sm)
+		} else if _, ok := dst[k]; !ok {
+			dst[k] = v
+		}
+	}
 }
@@ -1,77 +1,108 @@
 package consul
 
 import (
+	"context"
 	"time"
 
 	"github.com/hashicorp/consul/api"
-	"github.com/hashicorp/consul/api/watch"
-	"go-micro.dev/v4/config/encoder"
 	"go-micro.dev/v4/config/source"
+	"go-micro.dev/v4/logger"
 )
 
-type watcher struct {
-	e           encoder.Encoder
-	name        string
-	stripPrefix string
-
-	wp   *watch.Plan
-	ch   chan *source.ChangeSet
-	exit chan bool
-}
-
-func newWatcher(key, addr, name, stripPrefix string, e encoder.Encoder) (source.Watcher, error) {
-	w := &watcher{
-		e:           e,
-		name:        name,
-		stripPrefix: stripPrefix,
-		ch:          make(chan *source.ChangeSet),
-		exit:        make(chan bool),
-	}
-
-	wp, err := watch.Parse(map[string]interface{}{"type": "keyprefix", "prefix": key})
-	if err != nil {
-		return nil, err
-	}
-
-	wp.Handler = w.handle
+var (
+	// DefaultWaitTime is the time a blocking query of the watcher waits for
+	// a change of the prefix.
+	DefaultWaitTime = 5 * time.Minute
 
-	// wp.Run is a blocking call and will prevent newWatcher from returning
-	go wp.Run(addr)
+	// maxBackoff bounds the time between queries which failed.
+	maxBackoff = 30 * time.Second
+)
 
-	w.wp = wp
+type watcher struct {
+	c *consul
 
-	return w, nil
+	ch     chan *source.ChangeSet
+	exit   chan bool
+	cancel context.CancelFunc
 }
 
-func (w *watcher) handle(idx uint64, data interface{}) {
-	if data == nil {
-		return
-	}
+func newWatcher(c *consul) *watcher {
+	ctx, cancel := context.WithCancel(context.Background())
 
-	kvs, ok := data.(api.KVPairs)
-	if !ok {
-		return
+	w := &watcher{
+		c:      c,
+		ch:     make(chan *source.ChangeSet),
+		exit:   make(chan bool),
+		cancel: cancel,
 	}
 
-	d, err := makeMap(w.e, kvs, w.stripPrefix)
-	if err != nil {
-		return
-	}
+	go w.run(ctx)
 
-	b, err := w.e.Encode(d)
-	if err != nil {
-		return
-	}
+	return w
+}
 
-	cs := &source.ChangeSet{
-		Timestamp: time.Now(),
-		Format:    w.e.String(),
-		Source:    w.name,
-		Data:      b,
+// run lists the prefix with blocking queries, so every change set is a
+// snapshot of all keys at the index of the change, and sends the change sets
+// which differ from the last one.
+func (w *watcher) run(ctx context.Context) {
+	var index uint64
+	var sum string
+	backoff := time.Second
+
+	for {
+		opts := &api.QueryOptions{WaitIndex: index, WaitTime: DefaultWaitTime}
+		kv, meta, err := w.c.client.KV().List(w.c.prefix, opts.WithContext(ctx))
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Errorf("consul watch of %s: %v", w.c.prefix, err)
+			select {
+			case <-time.After(backoff):
+			case <-w.exit:
+				return
+			}
+			if backoff *= 2; backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+			continue
+		}
+		backoff = time.Second
+
+		// an index of zero wouldn't block
+		last := meta.LastIndex
+		if last == 0 {
+			last = 1
+		}
+
+		switch {
+		case last < index:
+			// the index went backwards, e.g. after a snapshot was restored
+			index = 0
+			continue
+		case last == index:
+			// the query timed out without a change
+			continue
+		}
+		index = last
+
+		cs, err := w.c.changeSet(kv)
+		if err != nil {
+			logger.Errorf("consul watch of %s: %v", w.c.prefix, err)
+			continue
+		}
+		// the index also changes with keys deleted outside the prefix
+		if cs.Checksum == sum {
+			continue
+		}
+		sum = cs.Checksum
+
+		select {
+		case w.ch <- cs:
+		case <-w.exit:
+			return
+		}
 	}
-	cs.Checksum = cs.Sum()
-
-	w.ch <- cs
 }
 
 func (w *watcher) Next() (*source.ChangeSet, error) {
@@ -88,7 +119,7 @@ func (w *watcher) Stop() error {
 	case <-w.exit:
 		return nil
 	default:
-		w.wp.Stop()
+		w.cancel()
 		close(w.exit)
 	}
 	return nil
@@ -0,0 +1,171 @@
+// Package kubernetes is a config source loading a ConfigMap and/or a Secret,
+// read from the API or from the files of their mounted volumes, and watching
+// them so edits reload the config without restarting pods.
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-micro.dev/v4/config/source"
+	api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+)
+
+// Predefined variables.
+var (
+	DefaultNamespace = "default"
+)
+
+type kubernetes struct {
+	opts   source.Options
+	client k8s.Interface
+	cerr   error
+
+	namespace      string
+	configMap      string
+	secret         string
+	configMapMount string
+	secretMount    string
+}
+
+func (k *kubernetes) Read() (*source.ChangeSet, error) {
+	data, err := k.read(k.getConfigMap, k.getSecret)
+	if err != nil {
+		return nil, err
+	}
+	return k.changeSet(data)
+}
+
+// Write is unsupported.
+func (k *kubernetes) Write(cs *source.ChangeSet) error {
+	return nil
+}
+
+func (k *kubernetes) String() string {
+	return "kubernetes"
+}
+
+// Watch watches the ConfigMap and the Secret, with informers or fsnotify for
+// mounted ones, an edit of either results in a change set of both merged.
+func (k *kubernetes) Watch() (source.Watcher, error) {
+	if k.cerr != nil {
+		return nil, k.cerr
+	}
+	return newWatcher(k)
+}
+
+// read returns the data of the ConfigMap and the Secret, in the order they're
+// merged, reading the mounted ones from their files and the others with the
+// getters.
+func (k *kubernetes) read(configMap func() (*api.ConfigMap, error), secret func() (*api.Secret, error)) ([]map[string][]byte, error) {
+	var data []map[string][]byte
+
+	switch {
+	case len(k.configMapMount) > 0:
+		d, err := readDir(k.configMapMount)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, d)
+	case len(k.configMap) > 0:
+		cm, err := configMap()
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, configMapData(cm))
+	}
+
+	switch {
+	case len(k.secretMount) > 0:
+		d, err := readDir(k.secretMount)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, d)
+	case len(k.secret) > 0:
+		s, err := secret()
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, s.Data)
+	}
+
+	if len(data) == 0 {
+		return nil, errors.New("no configmap or secret to read")
+	}
+	return data, nil
+}
+
+func (k *kubernetes) getConfigMap() (*api.ConfigMap, error) {
+	if k.cerr != nil {
+		return nil, k.cerr
+	}
+	return k.client.CoreV1().ConfigMaps(k.namespace).Get(context.TODO(), k.configMap, metav1.GetOptions{})
+}
+
+func (k *kubernetes) getSecret() (*api.Secret, error) {
+	if k.cerr != nil {
+		return nil, k.cerr
+	}
+	return k.client.CoreV1().Secrets(k.namespace).Get(context.TODO(), k.secret, metav1.GetOptions{})
+}
+
+func (k *kubernetes) changeSet(data []map[string][]byte) (*source.ChangeSet, error) {
+	b, err := k.opts.Encoder.Encode(makeMap(k.opts.Encoder, data...))
+	if err != nil {
+		return nil, fmt.Errorf("error reading source: %v", err)
+	}
+
+	cs := &source.ChangeSet{
+		Format:    k.opts.Encoder.String(),
+		Source:    k.String(),
+		Data:      b,
+		Timestamp: time.Now(),
+	}
+	cs.Checksum = cs.Sum()
+
+	return cs, nil
+}
+
+// NewSource returns a source loading the ConfigMap and the Secret set, the
+// keys of the Secret override those of the ConfigMap.
+func NewSource(opts ...source.Option) source.Source {
+	options := source.NewOptions(opts...)
+
+	k := &kubernetes{
+		opts:      options,
+		namespace: DefaultNamespace,
+	}
+
+	if ns, ok := options.Context.Value(namespaceKey{}).(string); ok {
+		k.namespace = ns
+	}
+	if name, ok := options.Context.Value(configMapKey{}).(string); ok {
+		k.configMap = name
+	}
+	if name, ok := options.Context.Value(secretKey{}).(string); ok {
+		k.secret = name
+	}
+	if dir, ok := options.Context.Value(configMapMountKey{}).(string); ok {
+		k.configMapMount = dir
+	}
+	if dir, ok := options.Context.Value(secretMountKey{}).(string); ok {
+		k.secretMount = dir
+	}
+
+	// the client is only needed to read from the API
+	if (len(k.configMap) > 0 && len(k.configMapMount) == 0) || (len(k.secret) > 0 && len(k.secretMount) == 0) {
+		if c, ok := options.Context.Value(clientKey{}).(k8s.Interface); ok {
+			k.client = c
+		} else {
+			path, _ := options.Context.Value(configPathKey{}).(string)
+			k.client, k.cerr = getClient(path)
+		}
+	}
+
+	return k
+}
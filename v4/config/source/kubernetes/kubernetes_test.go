@@ -0,0 +1,115 @@
+package kubernetes
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/config"
+	api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMakeMap(t *testing.T) {
+	k := NewSource(WithConfigMapMount(t.TempDir())).(*kubernetes)
+
+	m := makeMap(k.opts.Encoder,
+		map[string][]byte{"database.json": []byte(`{"host": "db", "port": 5432}`), "level": []byte("info")},
+		map[string][]byte{"level": []byte("debug"), "password": []byte("007")},
+	)
+
+	if db, ok := m["database"].(map[string]interface{}); !ok || db["host"] != "db" {
+		t.Errorf("Expected the database map, got %v", m)
+	}
+	if m["level"] != "debug" || m["password"] != "007" {
+		t.Errorf("Expected the secret to override the configmap as strings, got %v", m)
+	}
+}
+
+func TestAPI(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&api.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test"},
+			Data:       map[string]string{"server.json": `{"port": 8080}`, "level": "info"},
+		},
+		&api.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test"},
+			Data:       map[string][]byte{"password": []byte("secret")},
+		},
+	)
+
+	s := NewSource(
+		WithClient(client),
+		WithNamespace("test"),
+		WithConfigMap("app"),
+		WithSecret("app"),
+	)
+
+	c, _ := config.NewConfig()
+	if err := c.Load(s); err != nil {
+		t.Fatal(err)
+	}
+	if p := c.Get("server", "port").Int(0); p != 8080 {
+		t.Errorf("Expected port 8080, got %d", p)
+	}
+	if p := c.Get("password").String(""); p != "secret" {
+		t.Errorf("Expected the password of the secret, got %s", p)
+	}
+
+	// wait for the informers to sync before editing
+	time.Sleep(200 * time.Millisecond)
+
+	_, err := client.CoreV1().Secrets("test").Update(context.TODO(), &api.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test"},
+		Data:       map[string][]byte{"password": []byte("rotated")},
+	}, metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, func() bool { return c.Get("password").String("") == "rotated" })
+}
+
+func TestMount(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("level", "info")
+	if err := os.Mkdir(filepath.Join(dir, "..data"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	c, _ := config.NewConfig()
+	if err := c.Load(NewSource(WithConfigMapMount(dir))); err != nil {
+		t.Fatal(err)
+	}
+	if l := c.Get("level").String(""); l != "info" {
+		t.Errorf("Expected level info, got %s", l)
+	}
+
+	write("level", "debug")
+	waitFor(t, func() bool { return c.Get("level").String("") == "debug" })
+}
+
+func TestNothingToRead(t *testing.T) {
+	if _, err := NewSource().Read(); err == nil {
+		t.Fatal("Expected an error without a configmap or secret")
+	}
+}
+
+func waitFor(t *testing.T, fn func() bool) {
+	t.Helper()
+
+	for i := 0; !fn(); i++ {
+		if i == 100 {
+			t.Fatal("Expected the config to reload")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,89 @@
+package kubernetes
+
+import (
+	"context"
+
+	"go-micro.dev/v4/config/source"
+	k8s "k8s.io/client-go/kubernetes"
+)
+
+type namespaceKey struct{}
+type configMapKey struct{}
+type secretKey struct{}
+type configMapMountKey struct{}
+type secretMountKey struct{}
+type configPathKey struct{}
+type clientKey struct{}
+
+// WithNamespace sets the namespace of the ConfigMap and the Secret.
+func WithNamespace(s string) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, namespaceKey{}, s)
+	}
+}
+
+// WithConfigMap sets the name of the ConfigMap to load.
+func WithConfigMap(name string) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, configMapKey{}, name)
+	}
+}
+
+// WithSecret sets the name of the Secret to load.
+func WithSecret(name string) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, secretKey{}, name)
+	}
+}
+
+// WithConfigMapMount reads the ConfigMap from the directory of its mounted
+// volume instead of the API, so no RBAC rules are needed.
+func WithConfigMapMount(dir string) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, configMapMountKey{}, dir)
+	}
+}
+
+// WithSecretMount reads the Secret from the directory of its mounted volume
+// instead of the API.
+func WithSecretMount(dir string) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, secretMountKey{}, dir)
+	}
+}
+
+// WithConfigPath sets the path to a kubeconfig, mostly used outside of a
+// cluster. Defaults to the in cluster config.
+func WithConfigPath(s string) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, configPathKey{}, s)
+	}
+}
+
+// WithClient sets the kubernetes client, instead of one from the config path.
+func WithClient(c k8s.Interface) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, clientKey{}, c)
+	}
+}
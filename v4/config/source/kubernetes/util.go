@@ -0,0 +1,84 @@
+package kubernetes
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go-micro.dev/v4/config/encoder"
+	api "k8s.io/api/core/v1"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func getClient(configPath string) (*k8s.Clientset, error) {
+	var config *rest.Config
+	var err error
+
+	if configPath == "" {
+		config, err = rest.InClusterConfig()
+	} else {
+		config, err = clientcmd.BuildConfigFromFlags("", configPath)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return k8s.NewForConfig(config)
+}
+
+func configMapData(cm *api.ConfigMap) map[string][]byte {
+	data := make(map[string][]byte, len(cm.Data)+len(cm.BinaryData))
+	for k, v := range cm.BinaryData {
+		data[k] = v
+	}
+	for k, v := range cm.Data {
+		data[k] = []byte(v)
+	}
+	return data
+}
+
+// readDir reads the keys of a mounted ConfigMap or Secret, its files. The
+// hidden files and directories kubernetes swaps on updates are skipped.
+func readDir(dir string) (map[string][]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string][]byte, len(entries))
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") || e.IsDir() {
+			continue
+		}
+		// the keys are symlinks into the current ..data directory
+		b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		data[e.Name()] = b
+	}
+	return data, nil
+}
+
+// makeMap merges the keys into a map. Values which the encoder decodes into
+// a map are set as such, under their key without the extension of the
+// format, e.g. "database" for "database.json". Other values are strings.
+func makeMap(e encoder.Encoder, data ...map[string][]byte) map[string]interface{} {
+	out := make(map[string]interface{})
+
+	for _, d := range data {
+		for k, v := range d {
+			var m map[string]interface{}
+			if err := e.Decode(v, &m); err == nil && m != nil {
+				out[strings.TrimSuffix(k, "."+e.String())] = m
+				continue
+			}
+			out[k] = string(v)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,228 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go-micro.dev/v4/config/source"
+	"go-micro.dev/v4/logger"
+	api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+type watcher struct {
+	k *kubernetes
+
+	configMaps cache.Store
+	secrets    cache.Store
+	synced     []cache.InformerSynced
+	fsw        *fsnotify.Watcher
+
+	changed chan struct{}
+	ch      chan *source.ChangeSet
+	exit    chan bool
+	stop    chan struct{}
+}
+
+func newWatcher(k *kubernetes) (*watcher, error) {
+	w := &watcher{
+		k:       k,
+		changed: make(chan struct{}, 1),
+		ch:      make(chan *source.ChangeSet),
+		exit:    make(chan bool),
+		stop:    make(chan struct{}),
+	}
+
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { w.notify() },
+		UpdateFunc: func(interface{}, interface{}) { w.notify() },
+		DeleteFunc: func(interface{}) { w.notify() },
+	}
+
+	if len(k.configMap) > 0 && len(k.configMapMount) == 0 {
+		lw := w.listWatch(k.configMap,
+			func(o metav1.ListOptions) (runtime.Object, error) {
+				return k.client.CoreV1().ConfigMaps(k.namespace).List(context.TODO(), o)
+			},
+			func(o metav1.ListOptions) (watch.Interface, error) {
+				return k.client.CoreV1().ConfigMaps(k.namespace).Watch(context.TODO(), o)
+			},
+		)
+		st, ct := cache.NewInformer(lw, &api.ConfigMap{}, time.Second*30, handler)
+		go ct.Run(w.stop)
+		w.configMaps = st
+		w.synced = append(w.synced, ct.HasSynced)
+	}
+
+	if len(k.secret) > 0 && len(k.secretMount) == 0 {
+		lw := w.listWatch(k.secret,
+			func(o metav1.ListOptions) (runtime.Object, error) {
+				return k.client.CoreV1().Secrets(k.namespace).List(context.TODO(), o)
+			},
+			func(o metav1.ListOptions) (watch.Interface, error) {
+				return k.client.CoreV1().Secrets(k.namespace).Watch(context.TODO(), o)
+			},
+		)
+		st, ct := cache.NewInformer(lw, &api.Secret{}, time.Second*30, handler)
+		go ct.Run(w.stop)
+		w.secrets = st
+		w.synced = append(w.synced, ct.HasSynced)
+	}
+
+	if len(k.configMapMount) > 0 || len(k.secretMount) > 0 {
+		fsw, err := fsnotify.NewWatcher()
+		if err != nil {
+			close(w.stop)
+			return nil, err
+		}
+		// kubernetes updates a mounted volume by swapping the ..data symlink
+		// in its directory, the files themselves never change
+		for _, dir := range []string{k.configMapMount, k.secretMount} {
+			if len(dir) == 0 {
+				continue
+			}
+			if err := fsw.Add(dir); err != nil {
+				fsw.Close()
+				close(w.stop)
+				return nil, err
+			}
+		}
+		w.fsw = fsw
+		go w.watchFiles()
+		w.notify()
+	}
+
+	go w.run()
+
+	return w, nil
+}
+
+// listWatch lists and watches the object with the name only.
+func (w *watcher) listWatch(name string, list cache.ListFunc, watchFunc cache.WatchFunc) *cache.ListWatch {
+	selector := fields.OneTermEqualSelector("metadata.name", name).String()
+
+	return &cache.ListWatch{
+		ListFunc: func(o metav1.ListOptions) (runtime.Object, error) {
+			o.FieldSelector = selector
+			return list(o)
+		},
+		WatchFunc: func(o metav1.ListOptions) (watch.Interface, error) {
+			o.FieldSelector = selector
+			return watchFunc(o)
+		},
+	}
+}
+
+func (w *watcher) notify() {
+	select {
+	case w.changed <- struct{}{}:
+	default:
+	}
+}
+
+func (w *watcher) watchFiles() {
+	for {
+		select {
+		case _, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			w.notify()
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			logger.Errorf("kubernetes config watch: %v", err)
+		}
+	}
+}
+
+// run reads the ConfigMap and the Secret on every change, and sends the
+// change sets which differ from the last one. While either is missing, e.g.
+// deleted, the config keeps its values.
+func (w *watcher) run() {
+	if !cache.WaitForCacheSync(w.stop, w.synced...) {
+		return
+	}
+
+	var sum string
+	for {
+		select {
+		case <-w.changed:
+		case <-w.exit:
+			return
+		}
+
+		data, err := w.k.read(w.configMap, w.secret)
+		if err != nil {
+			logger.Errorf("kubernetes config watch: %v", err)
+			continue
+		}
+		cs, err := w.k.changeSet(data)
+		if err != nil {
+			logger.Errorf("kubernetes config watch: %v", err)
+			continue
+		}
+		if cs.Checksum == sum {
+			continue
+		}
+		sum = cs.Checksum
+
+		select {
+		case w.ch <- cs:
+		case <-w.exit:
+			return
+		}
+	}
+}
+
+func (w *watcher) configMap() (*api.ConfigMap, error) {
+	obj, ok, err := w.configMaps.GetByKey(w.k.namespace + "/" + w.k.configMap)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("configmap %s/%s not found", w.k.namespace, w.k.configMap)
+	}
+	return obj.(*api.ConfigMap), nil
+}
+
+func (w *watcher) secret() (*api.Secret, error) {
+	obj, ok, err := w.secrets.GetByKey(w.k.namespace + "/" + w.k.secret)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("secret %s/%s not found", w.k.namespace, w.k.secret)
+	}
+	return obj.(*api.Secret), nil
+}
+
+func (w *watcher) Next() (*source.ChangeSet, error) {
+	select {
+	case cs := <-w.ch:
+		return cs, nil
+	case <-w.exit:
+		return nil, source.ErrWatcherStopped
+	}
+}
+
+func (w *watcher) Stop() error {
+	select {
+	case <-w.exit:
+		return nil
+	default:
+		close(w.exit)
+		close(w.stop)
+		if w.fsw != nil {
+			w.fsw.Close()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package ssm
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"go-micro.dev/v4/config/source"
+)
+
+type pathKey struct{}
+type secretIDKey struct{}
+type decryptionKey struct{}
+type intervalKey struct{}
+type refreshKey struct{}
+type sessionKey struct{}
+type configKey struct{}
+
+// WithPath sets the parameter path hierarchy to read, e.g. /my/service. The
+// parameters below it are read recursively, their names below the path split
+// on "/" into the keys of the config.
+func WithPath(p string) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, pathKey{}, p)
+	}
+}
+
+// WithSecretID sets the name or ARN of the Secrets Manager secret to read, a
+// json object whose keys are merged into the root of the config.
+func WithSecretID(id string) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, secretIDKey{}, id)
+	}
+}
+
+// WithDecryption sets whether SecureString parameters are decrypted, which
+// they are by default.
+func WithDecryption(d bool) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, decryptionKey{}, d)
+	}
+}
+
+// WithInterval sets the interval the watcher polls in, zero disables polling
+// for a source refreshed by events only. Defaults to DefaultInterval.
+func WithInterval(d time.Duration) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, intervalKey{}, d)
+	}
+}
+
+// WithRefresh makes the watcher read the source on every signal of the
+// channel, e.g. sent on the EventBridge events of parameter or secret
+// changes delivered to a queue.
+func WithRefresh(ch <-chan struct{}) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, refreshKey{}, ch)
+	}
+}
+
+// WithSession sets the AWS session of the clients.
+func WithSession(s *session.Session) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, sessionKey{}, s)
+	}
+}
+
+// WithConfig sets the AWS config of the session created, e.g. its region.
+// Defaults to the config of the environment and shared config files.
+func WithConfig(c *aws.Config) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, configKey{}, c)
+	}
+}
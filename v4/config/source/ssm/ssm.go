@@ -0,0 +1,193 @@
+// Package ssm is a config source reading a parameter path hierarchy from the
+// AWS SSM Parameter Store and/or a secret of the AWS Secrets Manager.
+package ssm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"go-micro.dev/v4/config/source"
+)
+
+// Predefined variables.
+var (
+	// DefaultInterval is the interval the watcher polls for changes in.
+	DefaultInterval = time.Minute
+)
+
+type ssmSource struct {
+	opts source.Options
+	cerr error
+
+	ssm            *ssm.SSM
+	secretsManager *secretsmanager.SecretsManager
+
+	path       string
+	secretID   string
+	decryption bool
+	interval   time.Duration
+	refresh    <-chan struct{}
+}
+
+func (s *ssmSource) Read() (*source.ChangeSet, error) {
+	if s.cerr != nil {
+		return nil, s.cerr
+	}
+
+	data := make(map[string]interface{})
+
+	if len(s.path) > 0 {
+		if err := s.readParameters(data); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(s.secretID) > 0 {
+		if err := s.readSecret(data); err != nil {
+			return nil, err
+		}
+	}
+
+	b, err := s.opts.Encoder.Encode(data)
+	if err != nil {
+		return nil, fmt.Errorf("error reading source: %v", err)
+	}
+
+	cs := &source.ChangeSet{
+		Format:    s.opts.Encoder.String(),
+		Source:    s.String(),
+		Data:      b,
+		Timestamp: time.Now(),
+	}
+	cs.Checksum = cs.Sum()
+
+	return cs, nil
+}
+
+// readParameters reads the parameters under the path into the tree of data,
+// split on "/" below the path.
+func (s *ssmSource) readParameters(data map[string]interface{}) error {
+	input := &ssm.GetParametersByPathInput{
+		Path:           aws.String(s.path),
+		Recursive:      aws.Bool(true),
+		WithDecryption: aws.Bool(s.decryption),
+	}
+
+	var found bool
+	err := s.ssm.GetParametersByPathPages(input, func(out *ssm.GetParametersByPathOutput, last bool) bool {
+		for _, p := range out.Parameters {
+			found = true
+			setParameter(s.opts.Encoder, data, strings.TrimPrefix(aws.StringValue(p.Name), s.path), p)
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("error reading parameters of %s: %v", s.path, err)
+	}
+	if !found {
+		return fmt.Errorf("source not found: %s", s.path)
+	}
+	return nil
+}
+
+// readSecret reads the secret, a json object, into the root of data.
+func (s *ssmSource) readSecret(data map[string]interface{}) error {
+	out, err := s.secretsManager.GetSecretValue(&secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(s.secretID),
+	})
+	if err != nil {
+		return fmt.Errorf("error reading secret %s: %v", s.secretID, err)
+	}
+
+	b := out.SecretBinary
+	if out.SecretString != nil {
+		b = []byte(aws.StringValue(out.SecretString))
+	}
+
+	var m map[string]interface{}
+	if err := s.opts.Encoder.Decode(b, &m); err != nil {
+		return fmt.Errorf("error decoding secret %s: %v", s.secretID, err)
+	}
+	for k, v := range m {
+		data[k] = v
+	}
+	return nil
+}
+
+// Write is unsupported.
+func (s *ssmSource) Write(cs *source.ChangeSet) error {
+	return nil
+}
+
+func (s *ssmSource) String() string {
+	return "ssm"
+}
+
+// Watch polls the parameters and the secret in the interval, and on every
+// refresh signal, sending a change set when they changed.
+func (s *ssmSource) Watch() (source.Watcher, error) {
+	if s.cerr != nil {
+		return nil, s.cerr
+	}
+	return newWatcher(s), nil
+}
+
+// NewSource returns a source reading the parameters of the path set, and the
+// secret set, whose keys override the parameters.
+func NewSource(opts ...source.Option) source.Source {
+	options := source.NewOptions(opts...)
+
+	s := &ssmSource{
+		opts:       options,
+		decryption: true,
+		interval:   DefaultInterval,
+	}
+
+	if p, ok := options.Context.Value(pathKey{}).(string); ok {
+		// without a trailing / the path would match the prefix of names
+		s.path = strings.TrimSuffix(p, "/") + "/"
+	}
+	if id, ok := options.Context.Value(secretIDKey{}).(string); ok {
+		s.secretID = id
+	}
+	if d, ok := options.Context.Value(decryptionKey{}).(bool); ok {
+		s.decryption = d
+	}
+	if i, ok := options.Context.Value(intervalKey{}).(time.Duration); ok {
+		s.interval = i
+	}
+	if r, ok := options.Context.Value(refreshKey{}).(<-chan struct{}); ok {
+		s.refresh = r
+	}
+
+	if len(s.path) == 0 && len(s.secretID) == 0 {
+		s.cerr = errors.New("no parameter path or secret to read")
+		return s
+	}
+
+	sess, ok := options.Context.Value(sessionKey{}).(*session.Session)
+	if !ok {
+		cfg, _ := options.Context.Value(configKey{}).(*aws.Config)
+		if cfg == nil {
+			cfg = aws.NewConfig()
+		}
+		sess, s.cerr = session.NewSessionWithOptions(session.Options{
+			Config:            *cfg,
+			SharedConfigState: session.SharedConfigEnable,
+		})
+		if s.cerr != nil {
+			return s
+		}
+	}
+
+	s.ssm = ssm.New(sess)
+	s.secretsManager = secretsmanager.New(sess)
+
+	return s
+}
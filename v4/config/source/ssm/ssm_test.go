@@ -0,0 +1,126 @@
+package ssm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"go-micro.dev/v4/config"
+)
+
+// fakeAWS serves GetParametersByPath in pages of two, and GetSecretValue.
+type fakeAWS struct {
+	sync.Mutex
+	params    []map[string]string
+	secret    string
+	decrypted []bool
+}
+
+func (f *fakeAWS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	var in map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&in)
+
+	switch r.Header.Get("X-Amz-Target") {
+	case "AmazonSSM.GetParametersByPath":
+		f.decrypted = append(f.decrypted, in["WithDecryption"] == true)
+		start := 0
+		if t, ok := in["NextToken"].(string); ok {
+			start, _ = strconv.Atoi(t)
+		}
+		end := start + 2
+		out := map[string]interface{}{}
+		if end < len(f.params) {
+			out["NextToken"] = strconv.Itoa(end)
+		} else {
+			end = len(f.params)
+		}
+		out["Parameters"] = f.params[start:end]
+		json.NewEncoder(w).Encode(out)
+	case "secretsmanager.GetSecretValue":
+		json.NewEncoder(w).Encode(map[string]interface{}{"SecretString": f.secret})
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func newFake(t *testing.T) (*fakeAWS, *aws.Config) {
+	f := &fakeAWS{
+		params: []map[string]string{
+			{"Name": "/app/server/port", "Value": "8080", "Type": "String"},
+			{"Name": "/app/server/hosts", "Value": "a,b", "Type": "StringList"},
+			{"Name": "/app/db", "Value": `{"host": "db"}`, "Type": "String"},
+			{"Name": "/app/db/password", "Value": "secret", "Type": "SecureString"},
+		},
+		secret: `{"token": "t1"}`,
+	}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	return f, &aws.Config{
+		Endpoint:    aws.String(srv.URL),
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	}
+}
+
+func TestRead(t *testing.T) {
+	f, cfg := newFake(t)
+
+	c, _ := config.NewConfig()
+	if err := c.Load(NewSource(WithConfig(cfg), WithPath("/app"), WithSecretID("app"), WithInterval(0))); err != nil {
+		t.Fatal(err)
+	}
+
+	if p := c.Get("server", "port").Int(0); p != 8080 {
+		t.Errorf("Expected port 8080, got %d", p)
+	}
+	if h := c.Get("server", "hosts").StringSlice(nil); len(h) != 2 || h[1] != "b" {
+		t.Errorf("Expected the hosts list, got %v", h)
+	}
+	if h, p := c.Get("db", "host").String(""), c.Get("db", "password").String(""); h != "db" || p != "secret" {
+		t.Errorf("Expected the db map merged with its nested parameter, got %s %s", h, p)
+	}
+	if tk := c.Get("token").String(""); tk != "t1" {
+		t.Errorf("Expected the token of the secret, got %s", tk)
+	}
+	if len(f.decrypted) != 2 || !f.decrypted[0] {
+		t.Errorf("Expected two decrypted pages, got %v", f.decrypted)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	f, cfg := newFake(t)
+
+	refresh := make(chan struct{})
+	c, _ := config.NewConfig()
+	if err := c.Load(NewSource(WithConfig(cfg), WithSecretID("app"), WithInterval(0), WithRefresh(refresh))); err != nil {
+		t.Fatal(err)
+	}
+
+	f.Lock()
+	f.secret = `{"token": "t2"}`
+	f.Unlock()
+	refresh <- struct{}{}
+
+	for i := 0; c.Get("token").String("") != "t2"; i++ {
+		if i == 100 {
+			t.Fatal("Expected the rotated token after the refresh")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestNothingToRead(t *testing.T) {
+	if _, err := NewSource().Read(); err == nil {
+		t.Fatal("Expected an error without a path or secret")
+	}
+}
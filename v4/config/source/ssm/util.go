@@ -0,0 +1,60 @@
+package ssm
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"go-micro.dev/v4/config/encoder"
+)
+
+// setParameter sets the parameter at its name below the path in the tree.
+// StringList parameters are set as lists, values which the encoder decodes
+// into a map as such, others as strings.
+func setParameter(e encoder.Encoder, data map[string]interface{}, name string, p *ssm.Parameter) {
+	path := strings.Split(strings.Trim(name, "/"), "/")
+
+	// find (or create) the node the leaf is put at
+	target := data
+	for _, dir := range path[:len(path)-1] {
+		m, ok := target[dir].(map[string]interface{})
+		if !ok {
+			m = make(map[string]interface{})
+			target[dir] = m
+		}
+		target = m
+	}
+
+	leaf := path[len(path)-1]
+	value := aws.StringValue(p.Value)
+
+	if aws.StringValue(p.Type) == ssm.ParameterTypeStringList {
+		var list []interface{}
+		for _, v := range strings.Split(value, ",") {
+			list = append(list, v)
+		}
+		target[leaf] = list
+		return
+	}
+
+	var m map[string]interface{}
+	if err := e.Decode([]byte(value), &m); err == nil && m != nil {
+		// nested parameters set before take precedence
+		if existing, ok := target[leaf].(map[string]interface{}); ok {
+			for k, v := range m {
+				if _, ok := existing[k]; !ok {
+					existing[k] = v
+				}
+			}
+			return
+		}
+		target[leaf] = m
+		return
+	}
+
+	// a value of a parameter with nested ones is shadowed by them
+	if _, ok := target[leaf].(map[string]interface{}); ok {
+		return
+	}
+	target[leaf] = value
+}
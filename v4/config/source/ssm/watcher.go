@@ -0,0 +1,91 @@
+package ssm
+
+import (
+	"time"
+
+	"go-micro.dev/v4/config/source"
+	"go-micro.dev/v4/logger"
+)
+
+type watcher struct {
+	s *ssmSource
+
+	ch   chan *source.ChangeSet
+	exit chan bool
+}
+
+func newWatcher(s *ssmSource) *watcher {
+	w := &watcher{
+		s:    s,
+		ch:   make(chan *source.ChangeSet),
+		exit: make(chan bool),
+	}
+
+	go w.run()
+
+	return w
+}
+
+// run reads the source on every tick and refresh, and sends the change sets
+// which differ from the last one. Reads which fail keep the config at its
+// values until the next one.
+func (w *watcher) run() {
+	var tick <-chan time.Time
+	if w.s.interval > 0 {
+		t := time.NewTicker(w.s.interval)
+		defer t.Stop()
+		tick = t.C
+	}
+
+	refresh := w.s.refresh
+
+	var sum string
+	for {
+		select {
+		case <-tick:
+		case _, ok := <-refresh:
+			if !ok {
+				// a closed channel would signal forever
+				refresh = nil
+				continue
+			}
+		case <-w.exit:
+			return
+		}
+
+		cs, err := w.s.Read()
+		if err != nil {
+			logger.Errorf("ssm config watch: %v", err)
+			continue
+		}
+		if cs.Checksum == sum {
+			continue
+		}
+		sum = cs.Checksum
+
+		select {
+		case w.ch <- cs:
+		case <-w.exit:
+			return
+		}
+	}
+}
+
+func (w *watcher) Next() (*source.ChangeSet, error) {
+	select {
+	case cs := <-w.ch:
+		return cs, nil
+	case <-w.exit:
+		return nil, source.ErrWatcherStopped
+	}
+}
+
+func (w *watcher) Stop() error {
+	select {
+	case <-w.exit:
+		return nil
+	default:
+		close(w.exit)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// DefaultServiceAccountToken is the path of the service account token of a
+// pod, the jwt of the kubernetes auth method.
+var DefaultServiceAccountToken = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// authMethod logs in to vault, returning the secret of the token.
+type authMethod interface {
+	login(c *api.Client) (*api.Secret, error)
+	// setMount sets the path the auth method is mounted at
+	setMount(path string)
+	String() string
+}
+
+type kubernetesAuth struct {
+	mount   string
+	role    string
+	jwtPath string
+}
+
+func (k *kubernetesAuth) login(c *api.Client) (*api.Secret, error) {
+	jwt, err := os.ReadFile(k.jwtPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading service account token: %v", err)
+	}
+
+	return c.Logical().Write("auth/"+k.mount+"/login", map[string]interface{}{
+		"role": k.role,
+		"jwt":  string(jwt),
+	})
+}
+
+func (k *kubernetesAuth) setMount(path string) {
+	k.mount = path
+}
+
+func (k *kubernetesAuth) String() string {
+	return "kubernetes"
+}
+
+type appRoleAuth struct {
+	mount    string
+	roleID   string
+	secretID string
+}
+
+func (a *appRoleAuth) login(c *api.Client) (*api.Secret, error) {
+	return c.Logical().Write("auth/"+a.mount+"/login", map[string]interface{}{
+		"role_id":   a.roleID,
+		"secret_id": a.secretID,
+	})
+}
+
+func (a *appRoleAuth) setMount(path string) {
+	a.mount = path
+}
+
+func (a *appRoleAuth) String() string {
+	return "approle"
+}
+
+// login logs in with the auth method, on a clone of the client so reads
+// in flight keep their token, and sets the new token.
+func (v *vault) login() error {
+	c, err := v.client.Clone()
+	if err != nil {
+		return err
+	}
+	// the clone has the configured address and no headers, nor a token
+	// since an expired one fails the login
+	if err := c.SetAddress(v.client.Address()); err != nil {
+		return err
+	}
+	c.SetHeaders(v.client.Headers())
+	c.ClearToken()
+
+	s, err := v.auth.login(c)
+	if err != nil {
+		return fmt.Errorf("vault %s login: %v", v.auth, err)
+	}
+	if s == nil || s.Auth == nil || len(s.Auth.ClientToken) == 0 {
+		return fmt.Errorf("vault %s login: %v", v.auth, errors.New("no token returned"))
+	}
+
+	v.client.SetToken(s.Auth.ClientToken)
+	v.token = newLease(s.Auth.Accessor, s.Auth.LeaseDuration, s.Auth.Renewable)
+	if v.token != nil {
+		v.token.self = true
+	}
+	v.authed = true
+	return nil
+}
+
+// authenticate logs in if the source has an auth method and no valid token,
+// e.g. since the token expired without a watcher renewing it.
+func (v *vault) authenticate() error {
+	v.Lock()
+	defer v.Unlock()
+
+	if v.auth == nil || (v.authed && (v.token == nil || time.Now().Before(v.token.expires))) {
+		return nil
+	}
+	return v.login()
+}
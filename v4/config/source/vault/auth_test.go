@@ -0,0 +1,147 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAuth issues tokens with a ttl of a second, renewable once.
+type fakeAuth struct {
+	sync.Mutex
+	mount   string
+	logins  []map[string]interface{}
+	renewed int
+	token   string
+}
+
+func (f *fakeAuth) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	switch r.URL.Path {
+	case "/v1/auth/" + f.mount + "/login":
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		f.logins = append(f.logins, body)
+		f.token = fmt.Sprintf("token-%d", len(f.logins))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"auth": map[string]interface{}{
+				"client_token":   f.token,
+				"accessor":       "accessor-" + f.token,
+				"lease_duration": 1,
+				"renewable":      true,
+			},
+		})
+	case "/v1/auth/token/renew-self":
+		if f.renewed > 0 || r.Header.Get("X-Vault-Token") != f.token {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{"errors": []string{"permission denied"}})
+			return
+		}
+		f.renewed++
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"auth": map[string]interface{}{
+				"client_token":   f.token,
+				"lease_duration": 1,
+				"renewable":      true,
+			},
+		})
+	case "/v1/secret/app":
+		if r.Header.Get("X-Vault-Token") != f.token {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{"errors": []string{"permission denied"}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"password": "secret"},
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeAuth) login(i int) map[string]interface{} {
+	f.Lock()
+	defer f.Unlock()
+	if i >= len(f.logins) {
+		return nil
+	}
+	return f.logins[i]
+}
+
+func TestAppRoleAuth(t *testing.T) {
+	f := &fakeAuth{mount: "approle"}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	s := NewSource(
+		WithAddress(srv.URL),
+		WithResourcePath("secret/app"),
+		WithSecretName("app"),
+		WithAppRoleAuth("role", "secret-id"),
+	)
+
+	if _, err := s.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if l := f.login(0); l["role_id"] != "role" || l["secret_id"] != "secret-id" {
+		t.Fatalf("Unexpected login %v", l)
+	}
+
+	w, err := s.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	// renewed once, then logged in again as the renewal fails
+	for i := 0; f.login(1) == nil; i++ {
+		if i == 100 {
+			t.Fatal("Expected to log in again")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	f.Lock()
+	renewed := f.renewed
+	f.Unlock()
+	if renewed != 1 {
+		t.Errorf("Expected the token to be renewed once, got %d", renewed)
+	}
+
+	if _, err := s.Read(); err != nil {
+		t.Fatalf("Expected to read with the new token: %v", err)
+	}
+}
+
+func TestKubernetesAuth(t *testing.T) {
+	f := &fakeAuth{mount: "k8s"}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("jwt"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewSource(
+		WithAddress(srv.URL),
+		WithResourcePath("secret/app"),
+		WithKubernetesAuth("app", path),
+		WithAuthMount("k8s"),
+	)
+
+	if _, err := s.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if l := f.login(0); l["role"] != "app" || l["jwt"] != "jwt" {
+		t.Fatalf("Unexpected login %v", l)
+	}
+}
@@ -0,0 +1,73 @@
+//go:build !vault_noaws
+// +build !vault_noaws
+
+package vault
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/hashicorp/vault/api"
+	"go-micro.dev/v4/config/source"
+)
+
+// WithAWSAuth logs in with the iam type of the aws auth method mounted at
+// aws, as the role, with the credentials of the default AWS chain. The server
+// id is the value of the X-Vault-AWS-IAM-Server-ID header if the auth method
+// requires one.
+func WithAWSAuth(role, serverID string) source.Option {
+	return withAuth(&awsAuth{mount: "aws", role: role, serverID: serverID})
+}
+
+type awsAuth struct {
+	mount    string
+	role     string
+	serverID string
+}
+
+// login signs a sts:GetCallerIdentity request with the credentials of the
+// default chain, e.g. of the instance profile or the IRSA role of a pod, and
+// hands it to vault, which sends it to verify the identity.
+func (a *awsAuth) login(c *api.Client) (*api.Secret, error) {
+	sess, err := session.NewSession()
+	if err != nil {
+		return nil, err
+	}
+
+	req, _ := sts.New(sess).GetCallerIdentityRequest(nil)
+	if len(a.serverID) > 0 {
+		req.HTTPRequest.Header.Add("X-Vault-AWS-IAM-Server-ID", a.serverID)
+	}
+	if err := req.Sign(); err != nil {
+		return nil, fmt.Errorf("signing sts request: %v", err)
+	}
+
+	body, err := io.ReadAll(req.HTTPRequest.Body)
+	if err != nil {
+		return nil, err
+	}
+	headers, err := json.Marshal(req.HTTPRequest.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Logical().Write("auth/"+a.mount+"/login", map[string]interface{}{
+		"role":                    a.role,
+		"iam_http_request_method": req.HTTPRequest.Method,
+		"iam_request_url":         base64.StdEncoding.EncodeToString([]byte(req.HTTPRequest.URL.String())),
+		"iam_request_body":        base64.StdEncoding.EncodeToString(body),
+		"iam_request_headers":     base64.StdEncoding.EncodeToString(headers),
+	})
+}
+
+func (a *awsAuth) setMount(path string) {
+	a.mount = path
+}
+
+func (a *awsAuth) String() string {
+	return "aws"
+}
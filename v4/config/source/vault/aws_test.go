@@ -0,0 +1,53 @@
+//go:build !vault_noaws
+// +build !vault_noaws
+
+package vault
+
+import (
+	"encoding/base64"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAWSAuth(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_REGION", "us-east-1")
+
+	f := &fakeAuth{mount: "aws"}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	s := NewSource(
+		WithAddress(srv.URL),
+		WithResourcePath("secret/app"),
+		WithAWSAuth("app", "vault.example.com"),
+	)
+
+	if _, err := s.Read(); err != nil {
+		t.Fatal(err)
+	}
+
+	l := f.login(0)
+	decode := func(k string) string {
+		b, err := base64.StdEncoding.DecodeString(l[k].(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	if l["role"] != "app" || l["iam_http_request_method"] != "POST" {
+		t.Fatalf("Unexpected login %v", l)
+	}
+	if u := decode("iam_request_url"); !strings.Contains(u, "sts.amazonaws.com") {
+		t.Errorf("Expected the sts url, got %s", u)
+	}
+	if b := decode("iam_request_body"); !strings.Contains(b, "Action=GetCallerIdentity") {
+		t.Errorf("Expected the GetCallerIdentity action, got %s", b)
+	}
+	if h := decode("iam_request_headers"); !strings.Contains(h, "AWS4-HMAC-SHA256") || !strings.Contains(h, "vault.example.com") {
+		t.Errorf("Expected signed headers with the server id, got %s", h)
+	}
+}
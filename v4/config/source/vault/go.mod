@@ -3,6 +3,7 @@ module github.com/go-micro/plugins/v4/config/source/vault
 go 1.17
 
 require (
+	github.com/aws/aws-sdk-go v1.38.69
 	github.com/hashicorp/vault/api v1.0.4
 	go-micro.dev/v4 v4.9.0
 )
@@ -23,6 +24,7 @@ require (
 	github.com/hashicorp/hcl v1.0.0 // indirect
 	github.com/hashicorp/vault/sdk v0.1.13 // indirect
 	github.com/imdario/mergo v0.3.12 // indirect
+	github.com/jmespath/go-jmespath v0.4.0 // indirect
 	github.com/kr/pretty v0.2.1 // indirect
 	github.com/kr/text v0.2.0 // indirect
 	github.com/miekg/dns v1.1.43 // indirect
@@ -0,0 +1,180 @@
+package vault
+
+import (
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"go-micro.dev/v4/logger"
+)
+
+const (
+	// renewAt is the fraction of the ttl of a lease after which it's renewed,
+	// or the secret read again.
+	renewAt = 2.0 / 3.0
+	// retryInterval is the interval failed reads of expiring secrets are
+	// retried in.
+	retryInterval = 5 * time.Second
+)
+
+// lease is the lease of a dynamic secret, such as database credentials, or
+// of the token of an auth method.
+type lease struct {
+	id        string
+	renewable bool
+	// self is set for the token of the client, it's renewed with
+	// renew-self rather than by its lease id
+	self bool
+	// increment is the ttl asked for on renewal, the ttl of the secret
+	increment time.Duration
+	expires   time.Time
+	// next is when the lease is renewed or the secret read again
+	next time.Time
+	// rotate is set once the lease can't be extended anymore, so the secret
+	// is read again when it's due
+	rotate bool
+}
+
+// newLease returns the lease of a ttl in seconds, nil without a ttl, e.g.
+// for kv secrets or root tokens.
+func newLease(id string, seconds int, renewable bool) *lease {
+	if seconds <= 0 {
+		return nil
+	}
+
+	ttl := time.Duration(seconds) * time.Second
+	now := time.Now()
+
+	return &lease{
+		id:        id,
+		renewable: renewable && len(id) > 0,
+		increment: ttl,
+		expires:   now.Add(ttl),
+		next:      now.Add(time.Duration(float64(ttl) * renewAt)),
+		rotate:    !renewable || len(id) == 0,
+	}
+}
+
+// renew extends the lease. It's rotated instead once vault stops granting
+// the full increment, typically when the max ttl of the lease is reached.
+func (l *lease) renew(c *api.Client) error {
+	var (
+		s   *api.Secret
+		err error
+	)
+	if l.self {
+		s, err = c.Auth().Token().RenewSelf(int(l.increment / time.Second))
+	} else {
+		s, err = c.Sys().Renew(l.id, int(l.increment/time.Second))
+	}
+	if err != nil {
+		return err
+	}
+
+	seconds, renewable := s.LeaseDuration, s.Renewable
+	if l.self && s.Auth != nil {
+		seconds, renewable = s.Auth.LeaseDuration, s.Auth.Renewable
+	}
+
+	ttl := time.Duration(seconds) * time.Second
+	now := time.Now()
+
+	l.expires = now.Add(ttl)
+	l.next = now.Add(time.Duration(float64(ttl) * renewAt))
+	l.rotate = !renewable || ttl < l.increment
+	return nil
+}
+
+// due reports whether the lease is due.
+func (l *lease) due(now time.Time) bool {
+	return l != nil && !now.Before(l.next)
+}
+
+// refresh renews the token and the lease if they're due and returns whether
+// the secret has to be read again.
+func (v *vault) refresh() bool {
+	v.Lock()
+	defer v.Unlock()
+
+	now := time.Now()
+	again := v.refreshToken(now)
+
+	l := v.lease
+	if !l.due(now) {
+		return again
+	}
+	if l.rotate {
+		return true
+	}
+
+	if err := l.renew(v.client); err != nil {
+		logger.Warnf("Vault lease %s of %s can't be renewed, reading the secret again: %v", l.id, v.secretPath, err)
+		return true
+	}
+	return again
+}
+
+// refreshToken renews the token if it's due, or logs in again once it can't
+// be renewed. Leases are revoked with the token they were created by, so it
+// returns whether the secret has to be read again after logging in.
+func (v *vault) refreshToken(now time.Time) bool {
+	t := v.token
+	if !t.due(now) {
+		return false
+	}
+
+	if !t.rotate {
+		err := t.renew(v.client)
+		if err == nil {
+			return false
+		}
+		logger.Warnf("Vault %s token can't be renewed, logging in again: %v", v.auth, err)
+	}
+
+	if err := v.login(); err != nil {
+		logger.Errorf("Vault %s token expires at %s: %v", v.auth, t.expires.Format(time.RFC3339), err)
+		t.next = now.Add(retryInterval)
+		return false
+	}
+	return v.lease != nil
+}
+
+// retry postpones reading an expiring secret again after a failure.
+func (v *vault) retry(err error) {
+	v.Lock()
+	defer v.Unlock()
+
+	if v.lease == nil {
+		return
+	}
+
+	logger.Errorf("Vault secret %s can't be read, the lease expires at %s: %v", v.secretPath, v.lease.expires.Format(time.RFC3339), err)
+	v.lease.next = time.Now().Add(retryInterval)
+}
+
+// due returns when the token or the lease is renewed or the secret read
+// again, the zero time without either.
+func (v *vault) due() time.Time {
+	v.Lock()
+	defer v.Unlock()
+
+	var next time.Time
+	for _, l := range []*lease{v.token, v.lease} {
+		if l != nil && (next.IsZero() || l.next.Before(next)) {
+			next = l.next
+		}
+	}
+	return next
+}
+
+// setLease replaces the lease by the one of a new secret and wakes the
+// watcher up to schedule it.
+func (v *vault) setLease(s *api.Secret) {
+	v.Lock()
+	v.lease = newLease(s.LeaseID, s.LeaseDuration, s.Renewable)
+	v.Unlock()
+
+	select {
+	case v.leased <- struct{}{}:
+	default:
+	}
+}
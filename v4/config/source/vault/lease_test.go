@@ -0,0 +1,136 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// fakeVault issues database credentials with a lease of a second.
+type fakeVault struct {
+	sync.Mutex
+	renewable bool
+	// renewals are the renewals granted, further ones fail
+	renewals int
+	reads    int
+	renewed  int
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	switch r.URL.Path {
+	case "/v1/database/creds/app":
+		f.reads++
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"lease_id":       fmt.Sprintf("database/creds/app/%d", f.reads),
+			"lease_duration": 1,
+			"renewable":      f.renewable,
+			"data": map[string]interface{}{
+				"username": fmt.Sprintf("user-%d", f.reads),
+				"password": "secret",
+			},
+		})
+	case "/v1/sys/leases/renew":
+		if f.renewed == f.renewals {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"errors": []string{"lease not found"}})
+			return
+		}
+		f.renewed++
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"lease_id":       fmt.Sprintf("database/creds/app/%d", f.reads),
+			"lease_duration": 1,
+			"renewable":      true,
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func testRotation(t *testing.T, f *fakeVault) {
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	s := NewSource(
+		WithAddress(srv.URL),
+		WithResourcePath("database/creds/app"),
+		WithSecretName("db"),
+		WithToken("test"),
+	)
+
+	cs, err := s.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(cs.Data) != `{"db":{"password":"secret","username":"user-1"}}` {
+		t.Fatalf("Unexpected data %s", cs.Data)
+	}
+
+	w, err := s.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		cs, err = w.Next()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the credentials to be rotated")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(cs.Data) != `{"db":{"password":"secret","username":"user-2"}}` {
+		t.Fatalf("Expected the new credentials, got %s", cs.Data)
+	}
+
+	// rotated before the lease of the last renewal expired
+	if d := time.Since(start); d > time.Duration(f.renewals+1)*time.Second {
+		t.Errorf("Expected the rotation before the lease expired, took %s", d)
+	}
+
+	f.Lock()
+	defer f.Unlock()
+	if f.renewed != f.renewals {
+		t.Errorf("Expected %d renewals, got %d", f.renewals, f.renewed)
+	}
+}
+
+func TestLeaseRotation(t *testing.T) {
+	testRotation(t, &fakeVault{})
+}
+
+func TestLeaseRenewal(t *testing.T) {
+	testRotation(t, &fakeVault{renewable: true, renewals: 1})
+}
+
+func TestStaticSecret(t *testing.T) {
+	s := &vault{}
+	s.leased = make(chan struct{}, 1)
+	s.setLease(&api.Secret{Data: map[string]interface{}{"password": "secret"}})
+
+	if !s.due().IsZero() {
+		t.Fatal("Expected no renewal of a secret without lease")
+	}
+
+	w := newWatcher(s)
+	w.Stop()
+	if _, err := w.Next(); err == nil {
+		t.Fatal("Expected the stopped watcher to return an error")
+	}
+}
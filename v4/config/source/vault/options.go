@@ -11,6 +11,7 @@ type resourcePath struct{}
 type nameSpace struct{}
 type tokenKey struct{}
 type secretName struct{}
+type authKey struct{}
 
 // WithAddress sets the server address.
 func WithAddress(a string) source.Option {
@@ -61,3 +62,37 @@ func WithSecretName(t string) source.Option {
 		o.Context = context.WithValue(o.Context, secretName{}, t)
 	}
 }
+
+// WithKubernetesAuth logs in with the kubernetes auth method mounted at
+// kubernetes, as the role, with the service account token of the pod. The
+// token path defaults to DefaultServiceAccountToken.
+func WithKubernetesAuth(role, tokenPath string) source.Option {
+	if tokenPath == "" {
+		tokenPath = DefaultServiceAccountToken
+	}
+	return withAuth(&kubernetesAuth{mount: "kubernetes", role: role, jwtPath: tokenPath})
+}
+
+// WithAppRoleAuth logs in with the approle auth method mounted at approle.
+func WithAppRoleAuth(roleID, secretID string) source.Option {
+	return withAuth(&appRoleAuth{mount: "approle", roleID: roleID, secretID: secretID})
+}
+
+// WithAuthMount sets the path the auth method is mounted at, if it's not its
+// default. It's set after the auth method.
+func WithAuthMount(path string) source.Option {
+	return func(o *source.Options) {
+		if a := getAuth(*o); a != nil {
+			a.setMount(path)
+		}
+	}
+}
+
+func withAuth(a authMethod) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, authKey{}, a)
+	}
+}
@@ -93,3 +93,11 @@ func getSecretName(options source.Options) string {
 	}
 	return ""
 }
+
+func getAuth(options source.Options) authMethod {
+	if options.Context == nil {
+		return nil
+	}
+	a, _ := options.Context.Value(authKey{}).(authMethod)
+	return a
+}
@@ -2,6 +2,7 @@ package vault
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/vault/api"
@@ -14,9 +15,25 @@ type vault struct {
 	secretName string
 	opts       source.Options
 	client     *api.Client
+
+	sync.Mutex
+	// lease is the lease of the last secret read, if it's dynamic
+	lease *lease
+	// leased signals a new lease to the watcher
+	leased chan struct{}
+
+	// auth logs in, in place of a static token
+	auth authMethod
+	// token is the lease of the token of the auth method
+	token  *lease
+	authed bool
 }
 
 func (c *vault) Read() (*source.ChangeSet, error) {
+	if err := c.authenticate(); err != nil {
+		return nil, err
+	}
+
 	secret, err := c.client.Logical().Read(c.secretPath)
 	if err != nil {
 		return nil, err
@@ -30,6 +47,8 @@ func (c *vault) Read() (*source.ChangeSet, error) {
 		return nil, fmt.Errorf("source: %s errors: %v", c.secretPath, secret.Warnings)
 	}
 
+	c.setLease(secret)
+
 	data, err := makeMap(secret.Data, c.secretName)
 	if err != nil {
 		return nil, fmt.Errorf("error reading data: %v", err)
@@ -60,8 +79,12 @@ func (c *vault) String() string {
 	return "vault"
 }
 
+// Watch returns a watcher renewing the lease of a dynamic secret and the
+// token of the auth method in the background. Once the lease can't be
+// renewed anymore the secret is read again before it expires, and the new
+// credentials are returned by Next.
 func (c *vault) Watch() (source.Watcher, error) {
-	w := newWatcher(c.client)
+	w := newWatcher(c)
 
 	return w, nil
 }
@@ -97,5 +120,7 @@ func NewSource(opts ...source.Option) source.Source {
 		client:     client,
 		secretPath: path,
 		secretName: name,
+		leased:     make(chan struct{}, 1),
+		auth:       getAuth(options),
 	}
 }
@@ -2,32 +2,89 @@ package vault
 
 import (
 	"errors"
+	"time"
 
-	"github.com/hashicorp/vault/api"
 	"go-micro.dev/v4/config/source"
 )
 
 type watcher struct {
-	c    *api.Client
-	exit chan bool
+	v       *vault
+	changes chan *source.ChangeSet
+	exit    chan bool
 }
 
-func newWatcher(c *api.Client) *watcher {
-	return &watcher{
-		c:    c,
-		exit: make(chan bool),
+func newWatcher(v *vault) *watcher {
+	w := &watcher{
+		v:       v,
+		changes: make(chan *source.ChangeSet),
+		exit:    make(chan bool),
+	}
+
+	go w.run()
+
+	return w
+}
+
+// run renews the lease when it's due, and reads the secret again once it
+// can't be renewed anymore.
+func (w *watcher) run() {
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		var wait <-chan time.Time
+		if due := w.v.due(); !due.IsZero() {
+			timer.Reset(time.Until(due))
+			wait = timer.C
+		}
+
+		select {
+		case <-w.exit:
+			return
+		case <-w.v.leased:
+			if !timer.Stop() && wait != nil {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			continue
+		case <-wait:
+		}
+
+		if !w.v.refresh() {
+			continue
+		}
+
+		cs, err := w.v.Read()
+		if err != nil {
+			w.v.retry(err)
+			continue
+		}
+
+		select {
+		case w.changes <- cs:
+		case <-w.exit:
+			return
+		}
 	}
 }
 
 func (w *watcher) Next() (*source.ChangeSet, error) {
-	<-w.exit
-	return nil, errors.New("url watcher stopped")
+	select {
+	case cs := <-w.changes:
+		return cs, nil
+	case <-w.exit:
+		return nil, errors.New("vault watcher stopped")
+	}
 }
 
 func (w *watcher) Stop() error {
 	select {
 	case <-w.exit:
 	default:
+		close(w.exit)
 	}
 	return nil
 }
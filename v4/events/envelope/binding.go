@@ -0,0 +1,206 @@
+package envelope
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-micro.dev/v4/broker"
+)
+
+// Mode is a CloudEvents content mode.
+type Mode int
+
+const (
+	// Structured mode encodes the whole envelope in the payload.
+	Structured Mode = iota
+	// Binary mode sends the data as payload and the attributes as headers.
+	Binary
+)
+
+// HeaderPrefix is the prefix of the attribute headers in binary mode.
+var HeaderPrefix = "ce-"
+
+// headers returns the attributes of the envelope as binary mode headers.
+func (e *Envelope) headers() map[string]string {
+	hdr := map[string]string{
+		HeaderPrefix + "specversion": SpecVersion,
+		HeaderPrefix + "id":          e.ID,
+		HeaderPrefix + "type":        e.Type,
+		HeaderPrefix + "source":      e.Source,
+	}
+	if !e.Time.IsZero() {
+		hdr[HeaderPrefix+"time"] = e.Time.UTC().Format(time.RFC3339Nano)
+	}
+	if len(e.SchemaVersion) > 0 {
+		hdr[HeaderPrefix+"schemaversion"] = e.SchemaVersion
+	}
+	if len(e.DataSchema) > 0 {
+		hdr[HeaderPrefix+"dataschema"] = e.DataSchema
+	}
+	if len(e.DataContentType) > 0 {
+		hdr["Content-Type"] = e.DataContentType
+	}
+	return hdr
+}
+
+// fromHeaders decodes a binary mode envelope, get looks up a header case insensitively.
+func fromHeaders(get func(string) string, data []byte) (*Envelope, error) {
+	e := &Envelope{
+		ID:              get(HeaderPrefix + "id"),
+		Type:            get(HeaderPrefix + "type"),
+		Source:          get(HeaderPrefix + "source"),
+		SchemaVersion:   get(HeaderPrefix + "schemaversion"),
+		DataSchema:      get(HeaderPrefix + "dataschema"),
+		DataContentType: get("Content-Type"),
+		Data:            data,
+	}
+
+	if len(e.Type) == 0 {
+		return nil, ErrMissingType
+	}
+
+	if t := get(HeaderPrefix + "time"); len(t) > 0 {
+		ts, err := time.Parse(time.RFC3339Nano, t)
+		if err != nil {
+			return nil, err
+		}
+		e.Time = ts
+	}
+
+	return e, nil
+}
+
+func isStructured(ct string) bool {
+	return strings.HasPrefix(ct, ContentTypeEnvelope)
+}
+
+// Message returns a broker message of the envelope in structured mode.
+func (e *Envelope) Message() (*broker.Message, error) {
+	return e.ToMessage(Structured)
+}
+
+// ToMessage returns a broker message of the envelope in the given mode.
+func (e *Envelope) ToMessage(mode Mode) (*broker.Message, error) {
+	if mode == Binary {
+		hdr := e.headers()
+		hdr["Micro-Id"] = e.ID
+		return &broker.Message{Header: hdr, Body: e.Data}, nil
+	}
+
+	b, err := Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	return &broker.Message{
+		Header: map[string]string{
+			"Content-Type": ContentTypeEnvelope,
+			"Micro-Id":     e.ID,
+		},
+		Body: b,
+	}, nil
+}
+
+// FromMessage decodes the envelope of a broker message in either mode.
+func FromMessage(m *broker.Message) (*Envelope, error) {
+	get := func(k string) string {
+		for hk, v := range m.Header {
+			if strings.EqualFold(hk, k) {
+				return v
+			}
+		}
+		return ""
+	}
+
+	if len(get(HeaderPrefix+"specversion")) > 0 && !isStructured(get("Content-Type")) {
+		return fromHeaders(get, m.Body)
+	}
+
+	return Unmarshal(m.Body)
+}
+
+// WriteRequest sets the body and headers of the request to the envelope in the given mode.
+func (e *Envelope) WriteRequest(r *http.Request, mode Mode) error {
+	var body []byte
+
+	if mode == Binary {
+		for k, v := range e.headers() {
+			r.Header.Set(k, v)
+		}
+		body = e.Data
+	} else {
+		b, err := Marshal(e)
+		if err != nil {
+			return err
+		}
+		r.Header.Set("Content-Type", ContentTypeEnvelope)
+		body = b
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+
+	return nil
+}
+
+// NewRequest returns a POST request to the url delivering the envelope in the given mode.
+func (e *Envelope) NewRequest(ctx context.Context, url string, mode Mode) (*http.Request, error) {
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.WriteRequest(r, mode); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ReadRequest decodes the envelope of an http request in either mode.
+func ReadRequest(r *http.Request) (*Envelope, error) {
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(r.Header.Get(HeaderPrefix+"specversion")) > 0 && !isStructured(r.Header.Get("Content-Type")) {
+		return fromHeaders(r.Header.Get, b)
+	}
+
+	return Unmarshal(b)
+}
+
+// HTTPHandler returns a handler dispatching the envelopes delivered by http,
+// e.g. by a Knative trigger or an EventBridge api destination. It can be
+// served by the http server plugin.
+func (r *Router) HTTPHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		e, err := ReadRequest(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := r.Dispatch(req.Context(), e); err != nil {
+			if _, ok := err.(*ErrNoHandler); ok {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
@@ -0,0 +1,81 @@
+package envelope
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBinaryMessage(t *testing.T) {
+	e, err := New("order.created", &order{ID: "1"}, SchemaVersion("2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := e.ToMessage(Binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.Header["ce-type"] != "order.created" || msg.Header["Content-Type"] != "application/json" {
+		t.Fatalf("Unexpected headers %v", msg.Header)
+	}
+	if string(msg.Body) != `{"id":"1","total":0}` {
+		t.Fatalf("Expected the data as body, got %s", msg.Body)
+	}
+
+	d, err := FromMessage(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.ID != e.ID || d.SchemaVersion != "2" || !d.Time.Equal(e.Time) {
+		t.Fatalf("Unexpected envelope %+v", d)
+	}
+}
+
+func TestHTTPHandler(t *testing.T) {
+	r := NewRouter()
+
+	var got order
+	r.Handle("order.created", "", func(ctx context.Context, e *Envelope) error {
+		return e.Decode(&got)
+	})
+
+	srv := httptest.NewServer(r.HTTPHandler())
+	defer srv.Close()
+
+	for _, mode := range []Mode{Structured, Binary} {
+		got = order{}
+
+		e, _ := New("order.created", &order{ID: "1", Total: 5})
+		req, err := e.NewRequest(context.Background(), srv.URL, mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rsp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rsp.Body.Close()
+
+		if rsp.StatusCode != http.StatusAccepted {
+			t.Fatalf("Expected status %d, got %d", http.StatusAccepted, rsp.StatusCode)
+		}
+		if got.Total != 5 {
+			t.Fatalf("Expected event to be handled in mode %d", mode)
+		}
+	}
+
+	e, _ := New("order.deleted", &order{})
+	req, _ := e.NewRequest(context.Background(), srv.URL, Binary)
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp.Body.Close()
+	if rsp.StatusCode != http.StatusNotFound {
+		t.Fatalf("Expected status %d, got %d", http.StatusNotFound, rsp.StatusCode)
+	}
+}
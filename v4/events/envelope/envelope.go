@@ -0,0 +1,216 @@
+// Package envelope provides a versioned, CloudEvents compatible event envelope.
+package envelope
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go-micro.dev/v4/codec"
+	jsoncodec "go-micro.dev/v4/codec/json"
+	protocodec "go-micro.dev/v4/codec/proto"
+	"go-micro.dev/v4/events"
+)
+
+const (
+	// SpecVersion is the CloudEvents version the envelope is compatible with.
+	SpecVersion = "1.0"
+	// ContentTypeEnvelope is the content type of an encoded envelope.
+	ContentTypeEnvelope = "application/cloudevents+json"
+)
+
+var (
+	// DefaultSource is used if no source is set.
+	DefaultSource = "go-micro"
+
+	// Codecs are the codecs by content type used to encode and decode data.
+	Codecs = map[string]codec.Marshaler{
+		"application/json":     jsoncodec.Marshaler{},
+		"application/protobuf": protocodec.Marshaler{},
+	}
+
+	// ErrMissingType is returned for envelopes without a type.
+	ErrMissingType = errors.New("envelope: missing event type")
+	// ErrUnknownContentType is returned if no codec is known for the data.
+	ErrUnknownContentType = errors.New("envelope: unknown content type")
+)
+
+// Envelope wraps the data of an event with the attributes needed to route and
+// decode it.
+type Envelope struct {
+	ID              string
+	Type            string
+	Source          string
+	Time            time.Time
+	SchemaVersion   string
+	DataSchema      string
+	DataContentType string
+	// Data is the encoded event data
+	Data []byte
+}
+
+// wire is the CloudEvents JSON format of the envelope.
+type wire struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Type            string          `json:"type"`
+	Source          string          `json:"source"`
+	Time            *time.Time      `json:"time,omitempty"`
+	SchemaVersion   string          `json:"schemaversion,omitempty"`
+	DataSchema      string          `json:"dataschema,omitempty"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	DataBase64      []byte          `json:"data_base64,omitempty"`
+}
+
+// New returns an envelope of the given type with the encoded data.
+func New(typ string, data interface{}, opts ...Option) (*Envelope, error) {
+	if len(typ) == 0 {
+		return nil, ErrMissingType
+	}
+
+	options := Options{
+		ID:     uuid.New().String(),
+		Source: DefaultSource,
+		Time:   time.Now(),
+		Codec:  jsoncodec.Marshaler{},
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if len(options.ContentType) == 0 {
+		options.ContentType = contentType(options.Codec)
+	}
+
+	b, err := options.Codec.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Envelope{
+		ID:              options.ID,
+		Type:            typ,
+		Source:          options.Source,
+		Time:            options.Time,
+		SchemaVersion:   options.SchemaVersion,
+		DataSchema:      options.DataSchema,
+		DataContentType: options.ContentType,
+		Data:            b,
+	}, nil
+}
+
+// contentType returns the content type of the codec.
+func contentType(c codec.Marshaler) string {
+	for ct, m := range Codecs {
+		if m.String() == c.String() {
+			return ct
+		}
+	}
+	return "application/" + c.String()
+}
+
+// Decode decodes the data into v using the codec of the data content type.
+func (e *Envelope) Decode(v interface{}) error {
+	ct := e.DataContentType
+	if len(ct) == 0 {
+		ct = "application/json"
+	}
+
+	// strip parameters such as the charset
+	if i := strings.Index(ct, ";"); i > 0 {
+		ct = strings.TrimSpace(ct[:i])
+	}
+
+	c, ok := Codecs[ct]
+	if !ok {
+		return ErrUnknownContentType
+	}
+
+	return c.Unmarshal(e.Data, v)
+}
+
+func isJSON(ct string) bool {
+	return len(ct) == 0 || strings.HasPrefix(ct, "application/json") || strings.HasSuffix(strings.SplitN(ct, ";", 2)[0], "+json")
+}
+
+// MarshalJSON encodes the envelope in the CloudEvents JSON format. JSON data
+// is embedded as is, any other data is base64 encoded.
+func (e *Envelope) MarshalJSON() ([]byte, error) {
+	w := wire{
+		SpecVersion:     SpecVersion,
+		ID:              e.ID,
+		Type:            e.Type,
+		Source:          e.Source,
+		SchemaVersion:   e.SchemaVersion,
+		DataSchema:      e.DataSchema,
+		DataContentType: e.DataContentType,
+	}
+
+	if !e.Time.IsZero() {
+		t := e.Time.UTC()
+		w.Time = &t
+	}
+
+	if isJSON(e.DataContentType) && json.Valid(e.Data) {
+		w.Data = e.Data
+	} else if len(e.Data) > 0 {
+		w.DataBase64 = e.Data
+	}
+
+	return json.Marshal(w)
+}
+
+// UnmarshalJSON decodes an envelope in the CloudEvents JSON format.
+func (e *Envelope) UnmarshalJSON(b []byte) error {
+	var w wire
+	if err := json.Unmarshal(b, &w); err != nil {
+		return err
+	}
+
+	if len(w.Type) == 0 {
+		return ErrMissingType
+	}
+
+	*e = Envelope{
+		ID:              w.ID,
+		Type:            w.Type,
+		Source:          w.Source,
+		SchemaVersion:   w.SchemaVersion,
+		DataSchema:      w.DataSchema,
+		DataContentType: w.DataContentType,
+		Data:            w.Data,
+	}
+
+	if w.Time != nil {
+		e.Time = *w.Time
+	}
+
+	if len(w.DataBase64) > 0 {
+		e.Data = w.DataBase64
+	}
+
+	return nil
+}
+
+// Marshal encodes the envelope.
+func Marshal(e *Envelope) ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// Unmarshal decodes an envelope.
+func Unmarshal(b []byte) (*Envelope, error) {
+	e := new(Envelope)
+	if err := json.Unmarshal(b, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// FromEvent decodes the envelope of a stream event.
+func FromEvent(ev *events.Event) (*Envelope, error) {
+	return Unmarshal(ev.Payload)
+}
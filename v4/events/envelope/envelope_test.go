@@ -0,0 +1,132 @@
+package envelope
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/codec/bytes"
+)
+
+type order struct {
+	ID    string `json:"id"`
+	Total int    `json:"total"`
+}
+
+func TestEnvelope(t *testing.T) {
+	now := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	e, err := New("order.created", &order{ID: "1", Total: 10},
+		Source("orders"),
+		SchemaVersion("2"),
+		Time(now),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := e.Message()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// JSON data is embedded in the CloudEvents document
+	var doc map[string]interface{}
+	if err := json.Unmarshal(msg.Body, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["specversion"] != SpecVersion {
+		t.Fatalf("Expected spec version %s, got %v", SpecVersion, doc["specversion"])
+	}
+	if data, ok := doc["data"].(map[string]interface{}); !ok || data["id"] != "1" {
+		t.Fatalf("Expected embedded json data, got %v", doc["data"])
+	}
+
+	d, err := FromMessage(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d.ID != e.ID || d.Type != "order.created" || d.Source != "orders" || d.SchemaVersion != "2" {
+		t.Fatalf("Unexpected envelope %+v", d)
+	}
+	if !d.Time.Equal(now) {
+		t.Fatalf("Expected time %v, got %v", now, d.Time)
+	}
+
+	var o order
+	if err := d.Decode(&o); err != nil {
+		t.Fatal(err)
+	}
+	if o.Total != 10 {
+		t.Fatalf("Expected total 10, got %d", o.Total)
+	}
+}
+
+func TestBinaryData(t *testing.T) {
+	e, err := New("blob", []byte{0xff, 0x00}, Codec(bytes.Marshaler{}), ContentType("application/octet-stream"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := Unmarshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(d.Data) != string([]byte{0xff, 0x00}) {
+		t.Fatalf("Expected data to round trip, got %v", d.Data)
+	}
+
+	if err := d.Decode(&order{}); err != ErrUnknownContentType {
+		t.Fatalf("Expected %v, got %v", ErrUnknownContentType, err)
+	}
+}
+
+func TestRouter(t *testing.T) {
+	r := NewRouter()
+
+	var got string
+	handler := func(name string) HandlerFunc {
+		return func(ctx context.Context, e *Envelope) error {
+			got = name
+			return nil
+		}
+	}
+
+	r.Handle("order.created", "", handler("any"))
+	r.Handle("order.created", "1", handler("v1"))
+	r.Handle("order.created", "2.1.0", handler("v2.1.0"))
+
+	testData := []struct {
+		version string
+		handler string
+	}{
+		{"1", "v1"},
+		{"1.3.0", "v1"},
+		{"2.1.0", "v2.1.0"},
+		{"2.2.0", "any"},
+		{"", "any"},
+	}
+
+	for _, d := range testData {
+		e, _ := New("order.created", &order{}, SchemaVersion(d.version))
+		if err := r.Dispatch(context.Background(), e); err != nil {
+			t.Fatal(err)
+		}
+		if got != d.handler {
+			t.Fatalf("Expected version %s to be handled by %s, got %s", d.version, d.handler, got)
+		}
+	}
+
+	e, _ := New("order.deleted", &order{})
+	if _, ok := r.Dispatch(context.Background(), e).(*ErrNoHandler); !ok {
+		t.Fatal("Expected no handler error")
+	}
+}
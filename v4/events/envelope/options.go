@@ -0,0 +1,77 @@
+package envelope
+
+import (
+	"time"
+
+	"go-micro.dev/v4/codec"
+)
+
+// Options are the attributes of a new envelope.
+type Options struct {
+	// ID of the event, defaults to a random uuid
+	ID string
+	// Source identifies the producer of the event, e.g. the service name
+	Source string
+	// Time the event occurred, defaults to now
+	Time time.Time
+	// SchemaVersion is the version of the data schema
+	SchemaVersion string
+	// DataSchema is an optional uri of the data schema
+	DataSchema string
+	// Codec encodes the data, defaults to json
+	Codec codec.Marshaler
+	// ContentType of the encoded data, derived from the codec if not set
+	ContentType string
+}
+
+// Option sets an option.
+type Option func(*Options)
+
+// ID sets the event id.
+func ID(id string) Option {
+	return func(o *Options) {
+		o.ID = id
+	}
+}
+
+// Source sets the producer of the event.
+func Source(s string) Option {
+	return func(o *Options) {
+		o.Source = s
+	}
+}
+
+// Time sets when the event occurred.
+func Time(t time.Time) Option {
+	return func(o *Options) {
+		o.Time = t
+	}
+}
+
+// SchemaVersion sets the version of the data schema, e.g. "2" or "1.3.0".
+func SchemaVersion(v string) Option {
+	return func(o *Options) {
+		o.SchemaVersion = v
+	}
+}
+
+// DataSchema sets the uri of the data schema.
+func DataSchema(uri string) Option {
+	return func(o *Options) {
+		o.DataSchema = uri
+	}
+}
+
+// Codec sets the codec used to encode the data.
+func Codec(c codec.Marshaler) Option {
+	return func(o *Options) {
+		o.Codec = c
+	}
+}
+
+// ContentType sets the content type of the encoded data.
+func ContentType(ct string) Option {
+	return func(o *Options) {
+		o.ContentType = ct
+	}
+}
@@ -0,0 +1,125 @@
+package envelope
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"go-micro.dev/v4/broker"
+	"go-micro.dev/v4/events"
+)
+
+// HandlerFunc handles an event envelope.
+type HandlerFunc func(ctx context.Context, e *Envelope) error
+
+// ErrNoHandler is returned if no handler is registered for an envelope.
+type ErrNoHandler struct {
+	Type          string
+	SchemaVersion string
+}
+
+func (e *ErrNoHandler) Error() string {
+	return fmt.Sprintf("envelope: no handler for %s version %s", e.Type, e.SchemaVersion)
+}
+
+// Router dispatches envelopes to handlers by type and schema version.
+type Router struct {
+	sync.RWMutex
+	handlers map[string]map[string]HandlerFunc
+}
+
+// NewRouter returns an empty router.
+func NewRouter() *Router {
+	return &Router{
+		handlers: make(map[string]map[string]HandlerFunc),
+	}
+}
+
+// Handle registers the handler for envelopes of the type and schema version.
+// An envelope is dispatched to the handler of its exact version, otherwise to
+// the handler of its major version, e.g. "1.4.0" falls back to "1", and
+// finally to the handler registered with an empty version.
+func (r *Router) Handle(typ, version string, h HandlerFunc) {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.handlers[typ]; !ok {
+		r.handlers[typ] = make(map[string]HandlerFunc)
+	}
+	r.handlers[typ][version] = h
+}
+
+func (r *Router) handler(e *Envelope) (HandlerFunc, bool) {
+	r.RLock()
+	defer r.RUnlock()
+
+	versions, ok := r.handlers[e.Type]
+	if !ok {
+		return nil, false
+	}
+
+	if h, ok := versions[e.SchemaVersion]; ok {
+		return h, true
+	}
+
+	major := strings.TrimPrefix(e.SchemaVersion, "v")
+	if i := strings.Index(major, "."); i > 0 {
+		major = major[:i]
+	}
+	for _, v := range []string{major, "v" + major} {
+		if h, ok := versions[v]; ok {
+			return h, true
+		}
+	}
+
+	h, ok := versions[""]
+	return h, ok
+}
+
+// Dispatch calls the handler of the envelope.
+func (r *Router) Dispatch(ctx context.Context, e *Envelope) error {
+	h, ok := r.handler(e)
+	if !ok {
+		return &ErrNoHandler{Type: e.Type, SchemaVersion: e.SchemaVersion}
+	}
+	return h(ctx, e)
+}
+
+// Process decodes the envelope and dispatches it.
+func (r *Router) Process(ctx context.Context, b []byte) error {
+	e, err := Unmarshal(b)
+	if err != nil {
+		return err
+	}
+	return r.Dispatch(ctx, e)
+}
+
+// BrokerHandler returns a broker handler dispatching the envelopes of the
+// received messages.
+func (r *Router) BrokerHandler() broker.Handler {
+	return func(ev broker.Event) error {
+		e, err := FromMessage(ev.Message())
+		if err != nil {
+			return err
+		}
+		return r.Dispatch(context.Background(), e)
+	}
+}
+
+// Consume dispatches the envelopes of the events received on the channel
+// until it's closed. Events are acked or nacked if the stream was consumed
+// with manual acks.
+func (r *Router) Consume(ch <-chan events.Event, manualAck bool) {
+	for ev := range ch {
+		err := r.Process(context.Background(), ev.Payload)
+		if !manualAck {
+			continue
+		}
+		if err != nil {
+			ev.Nack()
+		} else {
+			ev.Ack()
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package natsjs
+
+import (
+	"errors"
+	"time"
+
+	nats "github.com/nats-io/nats.go"
+	"go-micro.dev/v4/events"
+)
+
+// Cursor is the position of a consumer group on the stream of a topic.
+type Cursor struct {
+	Topic string `json:"topic"`
+	// Sequence is the stream sequence of the last message the group
+	// acknowledged, including all messages before it.
+	Sequence uint64 `json:"sequence"`
+	// Time is when the message of the sequence was published, if it's still
+	// in the stream.
+	Time time.Time `json:"time,omitempty"`
+}
+
+// Checkpoint is the position of a consumer group, see Export.
+type Checkpoint struct {
+	Group    string    `json:"group"`
+	Cursors  []Cursor  `json:"cursors"`
+	Exported time.Time `json:"exported"`
+}
+
+// CheckpointOptions configure Export and Import.
+type CheckpointOptions struct {
+	// Topics only exports the cursors of the topics, by default the cursors
+	// on all streams with a consumer of the group are.
+	Topics []string
+	// Group imports the checkpoint into another group.
+	Group string
+	// Shift moves the imported cursors by time, relative to the times of the
+	// checkpoint.
+	Shift time.Duration
+	// At imports the cursors of the time, instead of those of the
+	// checkpoint.
+	At time.Time
+}
+
+// CheckpointOption sets values in CheckpointOptions.
+type CheckpointOption func(o *CheckpointOptions)
+
+// ExportTopics only exports the cursors of the topics.
+func ExportTopics(topics ...string) CheckpointOption {
+	return func(o *CheckpointOptions) {
+		o.Topics = topics
+	}
+}
+
+// ImportGroup imports the checkpoint into the group.
+func ImportGroup(group string) CheckpointOption {
+	return func(o *CheckpointOptions) {
+		o.Group = group
+	}
+}
+
+// ImportShift moves each cursor to the first message published after the
+// time of the checkpoint shifted by d, e.g. -time.Hour redelivers the last
+// hour before the checkpoint.
+func ImportShift(d time.Duration) CheckpointOption {
+	return func(o *CheckpointOptions) {
+		o.Shift = d
+	}
+}
+
+// ImportAt moves each cursor of the checkpoint to the first message
+// published at or after t.
+func ImportAt(t time.Time) CheckpointOption {
+	return func(o *CheckpointOptions) {
+		o.At = t
+	}
+}
+
+// Export returns the cursors of the consumer group, the durable consumers
+// named after the group.
+func Export(s events.Stream, group string, opts ...CheckpointOption) (*Checkpoint, error) {
+	js, err := jetStream(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var options CheckpointOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	topics := options.Topics
+	if len(topics) == 0 {
+		for name := range js.StreamNames() {
+			topics = append(topics, name)
+		}
+	}
+
+	cp := &Checkpoint{Group: group, Exported: time.Now()}
+	for _, topic := range topics {
+		info, err := js.ConsumerInfo(topic, group)
+		if errors.Is(err, nats.ErrConsumerNotFound) && len(options.Topics) == 0 {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		c := Cursor{Topic: topic, Sequence: info.AckFloor.Stream}
+		if c.Sequence > 0 {
+			msg, err := js.GetMsg(topic, c.Sequence)
+			if err == nil {
+				c.Time = msg.Time
+			} else if !errors.Is(err, nats.ErrMsgNotFound) {
+				return nil, err
+			}
+		}
+		cp.Cursors = append(cp.Cursors, c)
+	}
+
+	return cp, nil
+}
+
+// Import moves the consumers of the group to the cursors of the checkpoint.
+// The consumers are recreated, so the group must not be consuming. Consumers
+// which don't exist yet are created with explicit acks, as Consume creates
+// them.
+func Import(s events.Stream, cp *Checkpoint, opts ...CheckpointOption) error {
+	js, err := jetStream(s)
+	if err != nil {
+		return err
+	}
+
+	options := CheckpointOptions{Group: cp.Group}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	for _, c := range cp.Cursors {
+		cfg := nats.ConsumerConfig{
+			Durable:      options.Group,
+			DeliverGroup: options.Group,
+			AckPolicy:    nats.AckExplicitPolicy,
+		}
+
+		info, err := js.ConsumerInfo(c.Topic, options.Group)
+		if err == nil {
+			cfg = info.Config
+			if err := js.DeleteConsumer(c.Topic, options.Group); err != nil {
+				return err
+			}
+		} else if !errors.Is(err, nats.ErrConsumerNotFound) {
+			return err
+		}
+
+		// a new deliver subject, so subscriptions to the old consumer don't
+		// receive its messages
+		cfg.DeliverSubject = nats.NewInbox()
+		cfg.OptStartSeq = 0
+		cfg.OptStartTime = nil
+		switch {
+		case !options.At.IsZero():
+			cfg.DeliverPolicy = nats.DeliverByStartTimePolicy
+			cfg.OptStartTime = &options.At
+		case options.Shift != 0:
+			if c.Time.IsZero() {
+				return errors.New("no time of the cursor on " + c.Topic + " to shift")
+			}
+			// the first message after the last one acknowledged
+			at := c.Time.Add(options.Shift + time.Nanosecond)
+			cfg.DeliverPolicy = nats.DeliverByStartTimePolicy
+			cfg.OptStartTime = &at
+		case c.Sequence == 0:
+			cfg.DeliverPolicy = nats.DeliverAllPolicy
+		default:
+			cfg.DeliverPolicy = nats.DeliverByStartSequencePolicy
+			cfg.OptStartSeq = c.Sequence + 1
+		}
+
+		if _, err := js.AddConsumer(c.Topic, &cfg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func jetStream(s events.Stream) (nats.JetStreamContext, error) {
+	st, ok := s.(*stream)
+	if !ok {
+		return nil, errors.New("stream is not a nats jetstream stream")
+	}
+	return st.natsJetStreamCtx, nil
+}
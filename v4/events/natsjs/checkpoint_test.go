@@ -0,0 +1,92 @@
+package natsjs_test
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-micro/plugins/v4/events/natsjs"
+	nserver "github.com/nats-io/nats-server/v2/server"
+	"github.com/stretchr/testify/assert"
+	"go-micro.dev/v4/events"
+)
+
+func TestCheckpoint(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	topic := "orders"
+	natsAddr := getFreeLocalhostAddress()
+	natsPort, _ := strconv.Atoi(strings.Split(natsAddr, ":")[1])
+
+	go natsServer(ctx,
+		t,
+		&nserver.Options{
+			Host: strings.Split(natsAddr, ":")[0],
+			Port: natsPort,
+		},
+	)
+
+	time.Sleep(1 * time.Second)
+
+	newStream := func() events.Stream {
+		s, err := natsjs.NewStream(natsjs.Address(natsAddr))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	// receive returns the ids of the next n events
+	receive := func(c <-chan events.Event, n int) []string {
+		var ids []string
+		for i := 0; i < n; i++ {
+			select {
+			case e := <-c:
+				ids = append(ids, string(e.Payload))
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timeout waiting for event %d", i+1)
+			}
+		}
+		return ids
+	}
+
+	s := newStream()
+	c, err := s.Consume(topic, events.WithGroup("billing"))
+	assert.NoError(t, err)
+
+	start := time.Now()
+	for i := 1; i <= 3; i++ {
+		assert.NoError(t, s.Publish(topic, []byte(strconv.Itoa(i))))
+	}
+	assert.Equal(t, []string{"1", "2", "3"}, receive(c, 3))
+
+	var cp *natsjs.Checkpoint
+	assert.Eventually(t, func() bool {
+		cp, err = natsjs.Export(s, "billing")
+		return err == nil && len(cp.Cursors) == 1 && cp.Cursors[0].Sequence == 3
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.Equal(t, "billing", cp.Group)
+	assert.Equal(t, topic, cp.Cursors[0].Topic)
+	assert.False(t, cp.Cursors[0].Time.IsZero())
+
+	// clone the group one message before the checkpoint
+	cp.Cursors[0].Sequence = 2
+	assert.NoError(t, natsjs.Import(s, cp, natsjs.ImportGroup("audit")))
+
+	c, err = newStream().Consume(topic, events.WithGroup("audit"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"3"}, receive(c, 1))
+
+	// move the group back to before the first message
+	assert.NoError(t, natsjs.Import(s, cp, natsjs.ImportAt(start)))
+
+	c, err = newStream().Consume(topic, events.WithGroup("billing"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "2", "3"}, receive(c, 3))
+
+	_, err = natsjs.Export(s, "billing", natsjs.ExportTopics("missing"))
+	assert.Error(t, err)
+}
@@ -200,10 +200,14 @@ func (s *stream) Consume(topic string, opts ...events.ConsumeOption) (<-chan eve
 		subOpts = append(subOpts, nats.AckExplicit())
 	}
 
-	if !options.Offset.IsZero() {
-		subOpts = append(subOpts, nats.StartTime(options.Offset))
-	} else {
-		subOpts = append(subOpts, nats.DeliverNew())
+	// an existing consumer of the group resumes where it is, which may have
+	// been moved by Import
+	if _, err := s.natsJetStreamCtx.ConsumerInfo(topic, options.Group); err != nil {
+		if !options.Offset.IsZero() {
+			subOpts = append(subOpts, nats.StartTime(options.Offset))
+		} else {
+			subOpts = append(subOpts, nats.DeliverNew())
+		}
 	}
 
 	if options.AckWait > 0 {
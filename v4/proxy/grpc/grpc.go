@@ -0,0 +1,257 @@
+// Package grpc provides a proxy which transparently forwards unary and
+// streaming requests to a backend service.
+package grpc
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	gclient "github.com/go-micro/plugins/v4/client/grpc"
+	"go-micro.dev/v4"
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/codec/bytes"
+	"go-micro.dev/v4/errors"
+	"go-micro.dev/v4/metadata"
+	"go-micro.dev/v4/server"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// DefaultBufferSize is the number of messages buffered per stream direction.
+	DefaultBufferSize = 16
+
+	// hopHeaders are set per connection and not forwarded to the backend.
+	hopHeaders = map[string]bool{
+		"remote":       true,
+		"content-type": true,
+		"user-agent":   true,
+		"te":           true,
+		"timeout":      true,
+	}
+)
+
+// Router proxies the requests it serves to a backend service. It is a
+// server.Router.
+type Router struct {
+	opts Options
+}
+
+// NewRouter returns a router proxying requests to the backend.
+func NewRouter(opts ...Option) *Router {
+	options := Options{
+		BufferSize: DefaultBufferSize,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if options.Client == nil {
+		options.Client = gclient.NewClient()
+	}
+	if options.BufferSize <= 0 {
+		options.BufferSize = DefaultBufferSize
+	}
+
+	return &Router{opts: options}
+}
+
+// NewService returns a new proxy service forwarding all requests to the
+// backend set with the router.
+//
+//	service := grpc.NewService(
+//		micro.Name("greeter.proxy"),
+//		grpc.WithRouter(grpc.NewRouter(grpc.Service("greeter"))),
+//	)
+func NewService(opts ...micro.Option) micro.Service {
+	opts = append([]micro.Option{
+		WithRouter(NewRouter()),
+	}, opts...)
+
+	return micro.NewService(opts...)
+}
+
+// Options returns the options of the router.
+func (r *Router) Options() Options {
+	return r.opts
+}
+
+func (r *Router) service(req server.Request) string {
+	if len(r.opts.Service) > 0 {
+		return r.opts.Service
+	}
+	return req.Service()
+}
+
+func (r *Router) callOptions() []client.CallOption {
+	if len(r.opts.Address) == 0 {
+		return nil
+	}
+	return []client.CallOption{client.WithAddress(r.opts.Address)}
+}
+
+// ProcessMessage publishes the message to the backend's broker.
+func (r *Router) ProcessMessage(ctx context.Context, msg server.Message) error {
+	m := r.opts.Client.NewMessage(msg.Topic(), &bytes.Frame{Data: msg.Body()}, client.WithMessageContentType(msg.ContentType()))
+	return r.opts.Client.Publish(forward(ctx), m)
+}
+
+// ServeRequest forwards the request to the backend. Streams are proxied in
+// both directions until the backend closes the stream or either side fails.
+func (r *Router) ServeRequest(ctx context.Context, req server.Request, rsp server.Response) error {
+	ctx = forward(ctx)
+
+	if !req.Stream() {
+		return r.call(ctx, req, rsp)
+	}
+
+	return r.stream(ctx, req, rsp)
+}
+
+func (r *Router) call(ctx context.Context, req server.Request, rsp server.Response) error {
+	body, err := req.Read()
+	if err != nil {
+		return errors.BadRequest(req.Service(), err.Error())
+	}
+
+	creq := r.opts.Client.NewRequest(r.service(req), req.Endpoint(), &bytes.Frame{Data: body}, client.WithContentType(req.ContentType()))
+
+	f := &bytes.Frame{}
+	if err := r.opts.Client.Call(ctx, creq, f, r.callOptions()...); err != nil {
+		return proxyError(err)
+	}
+
+	return rsp.Write(f.Data)
+}
+
+func (r *Router) stream(ctx context.Context, req server.Request, rsp server.Response) error {
+	// cancelling the context aborts the backend stream
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	creq := r.opts.Client.NewRequest(r.service(req), req.Endpoint(), &bytes.Frame{}, client.WithContentType(req.ContentType()), client.StreamingRequest())
+
+	stream, err := r.opts.Client.Stream(ctx, creq, r.callOptions()...)
+	if err != nil {
+		return proxyError(err)
+	}
+	defer stream.Close()
+
+	up := make(chan error, 1)
+	down := make(chan error, 1)
+
+	// client to backend, the backend is half closed once the client is done
+	go func() {
+		err := pipe(r.opts.BufferSize, req.Read, func(b []byte) error {
+			return stream.Send(&bytes.Frame{Data: b})
+		})
+		if err == nil {
+			err = stream.CloseSend()
+		}
+		up <- err
+	}()
+
+	// backend to client, headers are written before the first message
+	go func() {
+		first := true
+		down <- pipe(r.opts.BufferSize, func() ([]byte, error) {
+			f := &bytes.Frame{}
+			if err := stream.Recv(f); err != nil {
+				return nil, err
+			}
+			return f.Data, nil
+		}, func(b []byte) error {
+			if first {
+				first = false
+				if hdr := stream.Response().Header(); len(hdr) > 0 {
+					rsp.WriteHeader(hdr)
+				}
+			}
+			return rsp.Write(b)
+		})
+	}()
+
+	for {
+		select {
+		case err := <-up:
+			if err != nil {
+				return proxyError(err)
+			}
+			up = nil
+		case err := <-down:
+			return proxyError(err)
+		}
+	}
+}
+
+// pipe copies messages from read to write until read returns io.EOF. Up to
+// size messages are buffered, so a slow writer only holds up its own
+// direction of the stream once the buffer is full.
+func pipe(size int, read func() ([]byte, error), write func([]byte) error) error {
+	buf := make(chan []byte, size)
+	errc := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		defer close(buf)
+		for {
+			b, err := read()
+			if err != nil {
+				errc <- err
+				return
+			}
+			select {
+			case buf <- b:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for b := range buf {
+		if err := write(b); err != nil {
+			return err
+		}
+	}
+
+	if err := <-errc; err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// forward returns the context with the metadata which is passed through to
+// the backend, headers of the incoming connection are dropped.
+func forward(ctx context.Context) context.Context {
+	md, ok := metadata.FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	out := make(metadata.Metadata, len(md))
+	for k, v := range md {
+		lk := strings.ToLower(k)
+		if strings.HasPrefix(lk, ":") || strings.HasPrefix(lk, "grpc-") || hopHeaders[lk] {
+			continue
+		}
+		out[k] = v
+	}
+
+	return metadata.NewContext(ctx, out)
+}
+
+// proxyError returns the micro error encoded in a grpc status, or the
+// error as is so the status code is kept.
+func proxyError(err error) error {
+	if err == nil || err == io.EOF {
+		return nil
+	}
+	if s, ok := status.FromError(err); ok {
+		if e := errors.Parse(s.Message()); e.Code > 0 {
+			return e
+		}
+	}
+	return err
+}
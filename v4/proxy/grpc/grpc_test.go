@@ -0,0 +1,197 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/codec"
+	"go-micro.dev/v4/codec/bytes"
+	"go-micro.dev/v4/errors"
+	"go-micro.dev/v4/metadata"
+	"go-micro.dev/v4/server"
+)
+
+type testRequest struct {
+	server.Request
+	stream bool
+	msgs   [][]byte
+}
+
+func (r *testRequest) Service() string     { return "greeter" }
+func (r *testRequest) Endpoint() string    { return "Greeter.Chat" }
+func (r *testRequest) ContentType() string { return "application/grpc+proto" }
+func (r *testRequest) Stream() bool        { return r.stream }
+
+func (r *testRequest) Read() ([]byte, error) {
+	if len(r.msgs) == 0 {
+		return nil, io.EOF
+	}
+	b := r.msgs[0]
+	r.msgs = r.msgs[1:]
+	return b, nil
+}
+
+type testResponse struct {
+	server.Response
+	header map[string]string
+	msgs   []string
+}
+
+func (r *testResponse) WriteHeader(hdr map[string]string) { r.header = hdr }
+
+func (r *testResponse) Write(b []byte) error {
+	r.msgs = append(r.msgs, string(b))
+	return nil
+}
+
+// testClient echoes the messages it's sent upper cased, failing with err
+// after the given number of messages.
+type testClient struct {
+	client.Client
+	failAfter int
+	err       error
+
+	mu  sync.Mutex
+	md  metadata.Metadata
+	req client.Request
+}
+
+func (c *testClient) NewRequest(service, endpoint string, req interface{}, opts ...client.RequestOption) client.Request {
+	return client.NewRequest(service, endpoint, req, opts...)
+}
+
+func (c *testClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	c.record(ctx, req)
+	in := req.Body().(*bytes.Frame)
+	rsp.(*bytes.Frame).Data = []byte(strings.ToUpper(string(in.Data)))
+	return c.err
+}
+
+func (c *testClient) Stream(ctx context.Context, req client.Request, opts ...client.CallOption) (client.Stream, error) {
+	c.record(ctx, req)
+	return &testStream{ctx: ctx, c: c, msgs: make(chan []byte, 16)}, nil
+}
+
+func (c *testClient) record(ctx context.Context, req client.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.md, _ = metadata.FromContext(ctx)
+	c.req = req
+}
+
+type testStream struct {
+	client.Stream
+	ctx  context.Context
+	c    *testClient
+	msgs chan []byte
+	n    int
+}
+
+func (s *testStream) Send(msg interface{}) error {
+	s.msgs <- msg.(*bytes.Frame).Data
+	return nil
+}
+
+func (s *testStream) Recv(msg interface{}) error {
+	if s.c.err != nil && s.n == s.c.failAfter {
+		return s.c.err
+	}
+	select {
+	case b, ok := <-s.msgs:
+		if !ok {
+			return io.EOF
+		}
+		s.n++
+		msg.(*bytes.Frame).Data = []byte(strings.ToUpper(string(b)))
+		return nil
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+func (s *testStream) CloseSend() error {
+	close(s.msgs)
+	return nil
+}
+
+func (s *testStream) Response() client.Response { return &testClientResponse{} }
+func (s *testStream) Close() error              { return nil }
+
+type testClientResponse struct {
+	client.Response
+}
+
+func (r *testClientResponse) Codec() codec.Reader        { return nil }
+func (r *testClientResponse) Header() map[string]string { return map[string]string{"Backend": "1"} }
+
+func TestStream(t *testing.T) {
+	c := &testClient{}
+	r := NewRouter(Client(c), BufferSize(1))
+
+	req := &testRequest{stream: true, msgs: [][]byte{[]byte("a"), []byte("b"), []byte("c")}}
+	rsp := &testResponse{}
+
+	if err := r.ServeRequest(context.Background(), req, rsp); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(rsp.msgs, ",") != "A,B,C" {
+		t.Fatalf("Unexpected messages %v", rsp.msgs)
+	}
+	if rsp.header["Backend"] != "1" {
+		t.Fatalf("Expected backend headers, got %v", rsp.header)
+	}
+	if !c.req.Stream() || c.req.Service() != "greeter" || c.req.Endpoint() != "Greeter.Chat" {
+		t.Fatalf("Unexpected backend request %v", c.req)
+	}
+}
+
+func TestStreamError(t *testing.T) {
+	c := &testClient{failAfter: 1, err: errors.NotFound("greeter", "gone")}
+	r := NewRouter(Client(c))
+
+	req := &testRequest{stream: true, msgs: [][]byte{[]byte("a"), []byte("b")}}
+	rsp := &testResponse{}
+
+	err := r.ServeRequest(context.Background(), req, rsp)
+	if e := errors.FromError(err); e.Code != 404 {
+		t.Fatalf("Expected backend error, got %v", err)
+	}
+	if len(rsp.msgs) != 1 {
+		t.Fatalf("Expected one message before the error, got %v", rsp.msgs)
+	}
+}
+
+func TestCall(t *testing.T) {
+	c := &testClient{}
+	r := NewRouter(Client(c), Service("greeter.backend"))
+
+	ctx := metadata.NewContext(context.Background(), metadata.Metadata{
+		"Authorization": "Bearer token",
+		":authority":    "proxy:8080",
+		"Remote":        "10.0.0.1:1234",
+		"grpc-timeout":  "1S",
+	})
+
+	rsp := &testResponse{}
+	if err := r.ServeRequest(ctx, &testRequest{msgs: [][]byte{[]byte("hi")}}, rsp); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rsp.msgs) != 1 || rsp.msgs[0] != "HI" {
+		t.Fatalf("Unexpected response %v", rsp.msgs)
+	}
+	if c.req.Service() != "greeter.backend" {
+		t.Fatalf("Expected backend service, got %s", c.req.Service())
+	}
+	if v, _ := c.md.Get("Authorization"); v != "Bearer token" {
+		t.Fatalf("Expected metadata to be passed through, got %v", c.md)
+	}
+	if len(c.md) != 1 {
+		t.Fatalf("Expected connection headers to be dropped, got %v", c.md)
+	}
+}
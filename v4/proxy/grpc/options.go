@@ -0,0 +1,59 @@
+package grpc
+
+import (
+	"go-micro.dev/v4"
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/server"
+)
+
+// Options configure the proxy router.
+type Options struct {
+	// Client calls the backend. Defaults to the grpc client.
+	Client client.Client
+	// Service is the backend service name. Defaults to the service of the
+	// request.
+	Service string
+	// Address is the backend address, bypassing service discovery.
+	Address string
+	// BufferSize is the number of messages buffered per stream direction
+	// before the sender is held up. Defaults to 16.
+	BufferSize int
+}
+
+// Option sets an option.
+type Option func(*Options)
+
+// Client sets the client used to call the backend.
+func Client(c client.Client) Option {
+	return func(o *Options) {
+		o.Client = c
+	}
+}
+
+// Service sets the name of the backend service.
+func Service(name string) Option {
+	return func(o *Options) {
+		o.Service = name
+	}
+}
+
+// Address sets the address of the backend.
+func Address(addr string) Option {
+	return func(o *Options) {
+		o.Address = addr
+	}
+}
+
+// BufferSize sets the number of messages buffered per stream direction.
+func BufferSize(n int) Option {
+	return func(o *Options) {
+		o.BufferSize = n
+	}
+}
+
+// WithRouter provides an option to set the proxy router.
+func WithRouter(r server.Router) micro.Option {
+	return func(o *micro.Options) {
+		o.Server.Init(server.WithRouter(r))
+	}
+}
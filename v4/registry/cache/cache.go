@@ -1,12 +1,235 @@
-// Package cache provides a registry cache
+// Package cache provides a registry cache, which keeps services resolvable
+// while the registry is down.
 package cache
 
 import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"go-micro.dev/v4/logger"
 	"go-micro.dev/v4/registry"
 	"go-micro.dev/v4/registry/cache"
+	util "go-micro.dev/v4/util/registry"
 )
 
-// New returns a new cache.
+type entry struct {
+	name   string
+	domain string
+	// opts are the options of the lookup, to refresh it with
+	opts []registry.GetOption
+
+	services []*registry.Service
+	// fetched is when the services were read from the registry
+	fetched time.Time
+	// refresh is when the entry is refreshed in the background
+	refresh time.Time
+	// read is when the entry was last returned
+	read time.Time
+	// invalid is set by watch events, the entry is only served stale
+	invalid bool
+}
+
+type registryCache struct {
+	registry.Registry
+	opts Options
+
+	sync.RWMutex
+	// entries are keyed by the domain and name of the service
+	entries map[string]*entry
+	// dirty is set when the entries changed since the last snapshot
+	dirty bool
+
+	sg    singleflight.Group
+	watch sync.Once
+	once  sync.Once
+	exit  chan struct{}
+}
+
+// New returns a cache of the registry configured with the options of the
+// go-micro cache. Use NewCache for the options of this package.
 func New(r registry.Registry, opts ...cache.Option) cache.Cache {
-	return cache.New(r, opts...)
+	options := cache.Options{TTL: DefaultTTL, Logger: logger.DefaultLogger}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return NewCache(r, TTL(options.TTL), WithLogger(options.Logger))
+}
+
+// NewCache returns a cache of the registry. Lookups are cached for the TTL
+// and refreshed in the background while they're read, changes seen by a
+// watch of the registry invalidate them, and expired entries are served
+// while the registry fails.
+func NewCache(r registry.Registry, opts ...Option) cache.Cache {
+	c := &registryCache{
+		Registry: r,
+		opts:     newOptions(opts...),
+		entries:  make(map[string]*entry),
+		exit:     make(chan struct{}),
+	}
+
+	if c.opts.Persister != nil {
+		c.load()
+	}
+
+	go c.refresher()
+
+	return c
+}
+
+func (c *registryCache) GetService(name string, opts ...registry.GetOption) ([]*registry.Service, error) {
+	c.watch.Do(func() {
+		go c.watcher()
+	})
+
+	now := time.Now()
+	domain := getDomain(opts...)
+
+	c.Lock()
+	e, ok := c.entries[key(domain, name)]
+	if ok {
+		e.read = now
+	}
+	c.Unlock()
+
+	if ok && c.fresh(e, now) {
+		return c.copy(e), nil
+	}
+
+	services, err := c.fetch(name, domain, opts)
+	if err == nil {
+		return services, nil
+	}
+	if errors.Is(err, registry.ErrNotFound) || !ok || !c.servable(e, now) {
+		return nil, err
+	}
+
+	c.opts.Logger.Logf(logger.DebugLevel, "registry cache: serving stale %s: %v", name, err)
+	return c.copy(e), nil
+}
+
+func (c *registryCache) fresh(e *entry, now time.Time) bool {
+	c.RLock()
+	defer c.RUnlock()
+	return !e.invalid && now.Before(e.fetched.Add(c.opts.TTL))
+}
+
+// servable returns whether the entry can be served while the registry
+// fails.
+func (c *registryCache) servable(e *entry, now time.Time) bool {
+	if c.opts.MaxStale <= 0 {
+		return true
+	}
+	c.RLock()
+	defer c.RUnlock()
+	return now.Before(e.fetched.Add(c.opts.TTL + c.opts.MaxStale))
+}
+
+func (c *registryCache) copy(e *entry) []*registry.Service {
+	c.RLock()
+	defer c.RUnlock()
+	return util.Copy(e.services)
+}
+
+// key returns the key of the entry of the service in the domain.
+func key(domain, name string) string {
+	if len(domain) == 0 {
+		return name
+	}
+	return domain + "/" + name
+}
+
+// fetch reads the service from the registry and caches it. Concurrent
+// fetches of a service share the request.
+func (c *registryCache) fetch(name, domain string, opts []registry.GetOption) ([]*registry.Service, error) {
+	k := key(domain, name)
+	v, err, _ := c.sg.Do(k, func() (interface{}, error) {
+		services, err := c.Registry.GetService(name, opts...)
+		if errors.Is(err, registry.ErrNotFound) || (err == nil && len(services) == 0) {
+			c.Lock()
+			if _, ok := c.entries[k]; ok {
+				delete(c.entries, k)
+				c.dirty = true
+			}
+			c.Unlock()
+			return nil, registry.ErrNotFound
+		} else if err != nil {
+			return nil, err
+		}
+
+		c.set(name, domain, opts, services)
+		return services, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return util.Copy(v.([]*registry.Service)), nil
+}
+
+func (c *registryCache) set(name, domain string, opts []registry.GetOption, services []*registry.Service) {
+	now := time.Now()
+
+	// refresh within the last jitter fraction of the ttl
+	ttl := float64(c.opts.TTL)
+	refresh := now.Add(time.Duration(ttl * (1 - c.opts.Jitter*rand.Float64())))
+
+	c.Lock()
+	defer c.Unlock()
+
+	k := key(domain, name)
+	e, ok := c.entries[k]
+	if !ok {
+		e = &entry{name: name, domain: domain, read: now}
+		c.entries[k] = e
+	}
+	e.opts = opts
+	e.services = util.Copy(services)
+	e.fetched = now
+	e.refresh = refresh
+	e.invalid = false
+	c.dirty = true
+}
+
+// invalidate marks the entries of the service to be fetched again, they're
+// kept to be served if the registry fails. Watch events don't carry the
+// domain, so the entries of every domain are invalidated.
+func (c *registryCache) invalidate(name string) {
+	c.Lock()
+	defer c.Unlock()
+
+	now := time.Now()
+	for _, e := range c.entries {
+		if e.name == name {
+			e.invalid = true
+			e.refresh = now
+		}
+	}
+}
+
+func (c *registryCache) invalidateAll() {
+	c.Lock()
+	defer c.Unlock()
+
+	now := time.Now()
+	for _, e := range c.entries {
+		e.invalid = true
+		e.refresh = now
+	}
+}
+
+func (c *registryCache) Stop() {
+	c.once.Do(func() {
+		close(c.exit)
+		if c.opts.Persister != nil {
+			c.save()
+		}
+	})
+}
+
+func (c *registryCache) String() string {
+	return "cache"
 }
@@ -0,0 +1,206 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/logger"
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/registry/cache"
+)
+
+var errDown = errors.New("registry down")
+
+// flaky counts lookups and fails them while down.
+type flaky struct {
+	registry.Registry
+	lookups int64
+
+	sync.Mutex
+	down bool
+}
+
+func (f *flaky) setDown(down bool) {
+	f.Lock()
+	f.down = down
+	f.Unlock()
+}
+
+func (f *flaky) GetService(name string, opts ...registry.GetOption) ([]*registry.Service, error) {
+	atomic.AddInt64(&f.lookups, 1)
+	f.Lock()
+	down := f.down
+	f.Unlock()
+	if down {
+		return nil, errDown
+	}
+	return f.Registry.GetService(name, opts...)
+}
+
+func newFlaky(t *testing.T) *flaky {
+	r := registry.NewMemoryRegistry()
+	err := r.Register(&registry.Service{
+		Name:    "foo",
+		Version: "1",
+		Nodes:   []*registry.Node{{Id: "foo-1", Address: "10.0.0.1:8080"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &flaky{Registry: r}
+}
+
+func TestCache(t *testing.T) {
+	f := newFlaky(t)
+	c := NewCache(f, TTL(time.Hour))
+	defer c.Stop()
+
+	for i := 0; i < 3; i++ {
+		services, err := c.GetService("foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(services) != 1 || services[0].Nodes[0].Id != "foo-1" {
+			t.Fatalf("Unexpected services %+v", services)
+		}
+	}
+	if n := atomic.LoadInt64(&f.lookups); n != 1 {
+		t.Errorf("Expected 1 lookup, got %d", n)
+	}
+
+	if _, err := c.GetService("bar"); err != registry.ErrNotFound {
+		t.Errorf("Expected not found, got %v", err)
+	}
+}
+
+func TestStaleOnError(t *testing.T) {
+	f := newFlaky(t)
+	c := NewCache(f, TTL(50*time.Millisecond), WithMaxStale(200*time.Millisecond))
+	defer c.Stop()
+
+	if _, err := c.GetService("foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	f.setDown(true)
+	time.Sleep(100 * time.Millisecond)
+
+	services, err := c.GetService("foo")
+	if err != nil {
+		t.Fatalf("Expected the stale entry, got %v", err)
+	}
+	if len(services) != 1 {
+		t.Errorf("Expected 1 service, got %d", len(services))
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if _, err := c.GetService("foo"); err != errDown {
+		t.Errorf("Expected the registry error past max stale, got %v", err)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	f := newFlaky(t)
+	c := NewCache(f, TTL(50*time.Millisecond))
+	defer c.Stop()
+
+	if _, err := c.GetService("foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	// keep reading, the entry is refreshed before it expires
+	for i := 0; i < 10; i++ {
+		time.Sleep(20 * time.Millisecond)
+		if _, err := c.GetService("foo"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := atomic.LoadInt64(&f.lookups); n < 3 {
+		t.Errorf("Expected background refreshes, got %d lookups", n)
+	}
+}
+
+func TestWatchInvalidates(t *testing.T) {
+	f := newFlaky(t)
+	c := NewCache(f, TTL(time.Hour))
+	defer c.Stop()
+
+	if _, err := c.GetService("foo"); err != nil {
+		t.Fatal(err)
+	}
+	// let the watcher start
+	time.Sleep(50 * time.Millisecond)
+
+	err := f.Register(&registry.Service{
+		Name:    "foo",
+		Version: "1",
+		Nodes:   []*registry.Node{{Id: "foo-2", Address: "10.0.0.2:8080"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		services, err := c.GetService("foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(services[0].Nodes) == 2 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("Expected the watch event to invalidate the entry")
+}
+
+// domains serves a node of foo in each domain.
+type domains struct {
+	registry.Registry
+}
+
+func (d *domains) GetService(name string, opts ...registry.GetOption) ([]*registry.Service, error) {
+	domain := getDomain(opts...)
+	if len(domain) == 0 {
+		domain = "default"
+	}
+	return []*registry.Service{{
+		Name:  name,
+		Nodes: []*registry.Node{{Id: name + "-" + domain}},
+	}}, nil
+}
+
+func TestDomains(t *testing.T) {
+	r := &domains{Registry: registry.NewMemoryRegistry()}
+	c := NewCache(r, TTL(time.Hour))
+	defer c.Stop()
+
+	for i := 0; i < 2; i++ {
+		for domain, id := range map[string]string{"": "foo-default", "eu": "foo-eu", "us": "foo-us"} {
+			services, err := c.GetService("foo", GetDomain(domain))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have := services[0].Nodes[0].Id; have != id {
+				t.Errorf("Expected %s in domain %q, got %s", id, domain, have)
+			}
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	// New takes the options of the go-micro cache
+	c := New(newFlaky(t), WithTTL(time.Hour), cache.WithLogger(logger.DefaultLogger))
+	defer c.Stop()
+
+	if have := c.(*registryCache).opts.TTL; have != time.Hour {
+		t.Errorf("Expected the TTL of the options, got %v", have)
+	}
+	if _, err := c.GetService("foo"); err != nil {
+		t.Fatal(err)
+	}
+}
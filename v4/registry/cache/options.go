@@ -1,12 +1,127 @@
 package cache
 
 import (
+	"context"
 	"time"
 
+	"go-micro.dev/v4/logger"
+	"go-micro.dev/v4/registry"
 	"go-micro.dev/v4/registry/cache"
 )
 
-// WithTTL sets the cache TTL.
+var (
+	// DefaultTTL is how long services are cached.
+	DefaultTTL = time.Minute
+	// DefaultJitter is the fraction of the TTL refreshes are spread over.
+	DefaultJitter = 0.1
+)
+
+// Options configure the cache created by NewCache.
+type Options struct {
+	// TTL is how long a lookup is served from the cache. Entries read
+	// within the TTL are refreshed in the background before they expire.
+	TTL time.Duration
+	// Jitter spreads the refreshes over the last fraction of the TTL, so
+	// the entries cached at once aren't refreshed at once.
+	Jitter float64
+	// MaxStale is how long past the TTL expired entries are served while
+	// the registry fails. Zero serves them until the registry recovers.
+	MaxStale time.Duration
+	// Persister persists the last known good lookups, to serve them while
+	// the registry is unreachable at startup.
+	Persister Persister
+
+	Logger logger.Logger
+}
+
+// Option sets an option.
+type Option func(o *Options)
+
+// WithTTL sets the cache TTL of New. It's the TTL option of the go-micro
+// cache.
 func WithTTL(t time.Duration) cache.Option {
 	return cache.WithTTL(t)
 }
+
+// TTL sets the cache TTL of NewCache.
+func TTL(t time.Duration) Option {
+	return func(o *Options) {
+		o.TTL = t
+	}
+}
+
+// WithJitter sets the fraction of the TTL refreshes are spread over.
+func WithJitter(j float64) Option {
+	return func(o *Options) {
+		o.Jitter = j
+	}
+}
+
+// WithMaxStale sets how long expired entries are served while the registry
+// fails.
+func WithMaxStale(d time.Duration) Option {
+	return func(o *Options) {
+		o.MaxStale = d
+	}
+}
+
+// WithPersister persists the last known good lookups with the persister,
+// e.g. File or Store. They're loaded when the cache is created.
+func WithPersister(p Persister) Option {
+	return func(o *Options) {
+		o.Persister = p
+	}
+}
+
+// WithLogger sets the logger.
+func WithLogger(l logger.Logger) Option {
+	return func(o *Options) {
+		o.Logger = l
+	}
+}
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		TTL:    DefaultTTL,
+		Jitter: DefaultJitter,
+		Logger: logger.DefaultLogger,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if options.Jitter < 0 {
+		options.Jitter = 0
+	} else if options.Jitter > 1 {
+		options.Jitter = 1
+	}
+
+	return options
+}
+
+type domainKey struct{}
+
+// GetDomain looks the service up in the domain, for registries serving
+// several of them. The options are passed to the registry, the cache keeps
+// the lookups of each domain apart.
+func GetDomain(d string) registry.GetOption {
+	return func(o *registry.GetOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, domainKey{}, d)
+	}
+}
+
+func getDomain(opts ...registry.GetOption) string {
+	var options registry.GetOptions
+	for _, o := range opts {
+		o(&options)
+	}
+	if options.Context == nil {
+		return ""
+	}
+	d, _ := options.Context.Value(domainKey{}).(string)
+	return d
+}
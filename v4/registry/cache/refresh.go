@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"time"
+
+	"go-micro.dev/v4/logger"
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/util/backoff"
+)
+
+// minTick bounds how often the refresher looks for entries to refresh.
+const minTick = 10 * time.Millisecond
+
+// refresher refreshes the entries due, as long as they were read within
+// the TTL. Entries nobody reads are left to expire. It saves snapshots of
+// changed entries too.
+func (c *registryCache) refresher() {
+	tick := c.opts.TTL / 10
+	if tick < minTick {
+		tick = minTick
+	}
+
+	t := time.NewTicker(tick)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-c.exit:
+			return
+		case now := <-t.C:
+			if c.opts.Persister != nil {
+				c.save()
+			}
+			for _, e := range c.due(now) {
+				go func(e entry) {
+					if _, err := c.fetch(e.name, e.domain, e.opts); err != nil {
+						c.opts.Logger.Logf(logger.DebugLevel, "registry cache: refreshing %s: %v", key(e.domain, e.name), err)
+					}
+				}(e)
+			}
+		}
+	}
+}
+
+// due returns the lookups of the entries to refresh.
+func (c *registryCache) due(now time.Time) []entry {
+	c.Lock()
+	defer c.Unlock()
+
+	var due []entry
+	for _, e := range c.entries {
+		if now.Before(e.refresh) || now.Sub(e.read) > c.opts.TTL {
+			continue
+		}
+		due = append(due, entry{name: e.name, domain: e.domain, opts: e.opts})
+		// retry a failing refresh on the next tick at the earliest
+		e.refresh = now.Add(c.opts.TTL / 10)
+	}
+	return due
+}
+
+func (c *registryCache) quit() bool {
+	select {
+	case <-c.exit:
+		return true
+	default:
+		return false
+	}
+}
+
+// watcher invalidates the entries of the services the registry reports
+// changes of. Events may be missed while the watch is down, so every entry
+// is invalidated after it recovers.
+func (c *registryCache) watcher() {
+	var attempts int
+	var failed bool
+
+	for !c.quit() {
+		if attempts > 0 {
+			time.Sleep(backoff.Do(attempts))
+		}
+
+		w, err := c.Registry.Watch()
+		if err != nil {
+			c.opts.Logger.Logf(logger.DebugLevel, "registry cache: watching: %v", err)
+			failed = true
+			attempts++
+			continue
+		}
+
+		if failed {
+			c.invalidateAll()
+			failed = false
+		}
+		attempts = 0
+
+		err = c.watchEvents(w)
+		if c.quit() {
+			return
+		}
+		c.opts.Logger.Logf(logger.DebugLevel, "registry cache: watch: %v", err)
+		failed = true
+		attempts++
+	}
+}
+
+func (c *registryCache) watchEvents(w registry.Watcher) error {
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go func() {
+		select {
+		case <-c.exit:
+		case <-stop:
+		}
+		w.Stop()
+	}()
+
+	for {
+		res, err := w.Next()
+		if err != nil {
+			return err
+		}
+		if res == nil || res.Service == nil {
+			continue
+		}
+		c.invalidate(res.Service.Name)
+	}
+}
@@ -0,0 +1,211 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go-micro.dev/v4/logger"
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/store"
+	util "go-micro.dev/v4/util/registry"
+)
+
+// SnapshotEntry is the last known good lookup of a service.
+type SnapshotEntry struct {
+	// Name and Domain are of the lookup, snapshots without them are of
+	// the service named by the key in the default domain
+	Name     string              `json:"name,omitempty"`
+	Domain   string              `json:"domain,omitempty"`
+	Services []*registry.Service `json:"services"`
+	// Fetched is when the services were read from the registry
+	Fetched time.Time `json:"fetched"`
+}
+
+// Snapshot is the last known good lookups of the cache by service name,
+// prefixed with the domain and a slash for domains set by GetDomain.
+type Snapshot map[string]SnapshotEntry
+
+// Persister persists snapshots of the cache, which are loaded when the cache
+// is created, so services are resolvable while the registry is unreachable
+// at startup.
+type Persister interface {
+	// Load returns the saved snapshot, an empty one if there's none.
+	Load() (Snapshot, error)
+	Save(Snapshot) error
+}
+
+// File persists snapshots as JSON in the file. Writes go to a temporary file
+// renamed over it, so a crash doesn't leave a partial snapshot.
+func File(path string) Persister {
+	return &filePersister{path: path}
+}
+
+// Sealer encrypts snapshots at rest, e.g. the Sealer of the encrypt store
+// wrapper.
+type Sealer interface {
+	Seal(plaintext []byte) ([]byte, error)
+	Open(sealed []byte) ([]byte, error)
+}
+
+// EncryptedFile persists snapshots in the file like File, encrypted by the
+// sealer, as they reveal the addresses and metadata of the services.
+func EncryptedFile(path string, s Sealer) Persister {
+	return &filePersister{path: path, sealer: s}
+}
+
+type filePersister struct {
+	path   string
+	sealer Sealer
+}
+
+func (f *filePersister) Load() (Snapshot, error) {
+	b, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return Snapshot{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if f.sealer != nil {
+		if b, err = f.sealer.Open(b); err != nil {
+			return nil, err
+		}
+	}
+
+	var s Snapshot
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (f *filePersister) Save(s Snapshot) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if f.sealer != nil {
+		if b, err = f.sealer.Seal(b); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path), filepath.Base(f.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
+
+// Store persists snapshots as JSON in a record of the store, e.g. to share
+// them between the instances of a service.
+func Store(s store.Store, key string) Persister {
+	return &storePersister{store: s, key: key}
+}
+
+type storePersister struct {
+	store store.Store
+	key   string
+}
+
+func (s *storePersister) Load() (Snapshot, error) {
+	records, err := s.store.Read(s.key)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && len(records) == 0) {
+		return Snapshot{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var snap Snapshot
+	if err := json.Unmarshal(records[0].Value, &snap); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+func (s *storePersister) Save(snap Snapshot) error {
+	b, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	return s.store.Write(&store.Record{Key: s.key, Value: b})
+}
+
+// load fills the cache with the saved snapshot. The entries are invalid, so
+// they're only served while the registry fails.
+func (c *registryCache) load() {
+	snap, err := c.opts.Persister.Load()
+	if err != nil {
+		c.opts.Logger.Logf(logger.WarnLevel, "registry cache: loading snapshot: %v", err)
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	for k, se := range snap {
+		if len(se.Services) == 0 {
+			continue
+		}
+		name := se.Name
+		if len(name) == 0 {
+			name = k
+		}
+		var opts []registry.GetOption
+		if len(se.Domain) > 0 {
+			opts = append(opts, GetDomain(se.Domain))
+		}
+		c.entries[key(se.Domain, name)] = &entry{
+			name:     name,
+			domain:   se.Domain,
+			opts:     opts,
+			services: se.Services,
+			fetched:  se.Fetched,
+			invalid:  true,
+		}
+	}
+}
+
+// save persists a snapshot of the entries if they changed since the last
+// one.
+func (c *registryCache) save() {
+	c.Lock()
+	if !c.dirty {
+		c.Unlock()
+		return
+	}
+	c.dirty = false
+
+	snap := make(Snapshot, len(c.entries))
+	for k, e := range c.entries {
+		snap[k] = SnapshotEntry{
+			Name:     e.name,
+			Domain:   e.domain,
+			Services: util.Copy(e.services),
+			Fetched:  e.fetched,
+		}
+	}
+	c.Unlock()
+
+	if err := c.opts.Persister.Save(snap); err != nil {
+		c.opts.Logger.Logf(logger.WarnLevel, "registry cache: saving snapshot: %v", err)
+		c.Lock()
+		c.dirty = true
+		c.Unlock()
+	}
+}
@@ -0,0 +1,140 @@
+package cache
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/store"
+)
+
+func TestSnapshot(t *testing.T) {
+	persisters := map[string]Persister{
+		"file":      File(filepath.Join(t.TempDir(), "registry", "snapshot.json")),
+		"store":     Store(store.NewMemoryStore(), "registry-snapshot"),
+		"encrypted": EncryptedFile(filepath.Join(t.TempDir(), "snapshot.enc"), xorSealer(0x5a)),
+	}
+
+	for name, p := range persisters {
+		t.Run(name, func(t *testing.T) {
+			snap, err := p.Load()
+			if err != nil || len(snap) != 0 {
+				t.Fatalf("Expected an empty snapshot, got %v %v", snap, err)
+			}
+
+			f := newFlaky(t)
+			c := NewCache(f, WithPersister(p))
+			if _, err := c.GetService("foo"); err != nil {
+				t.Fatal(err)
+			}
+			c.Stop()
+
+			// the registry is down at startup
+			f.setDown(true)
+			c = NewCache(f, WithPersister(p))
+			defer c.Stop()
+
+			services, err := c.GetService("foo")
+			if err != nil {
+				t.Fatalf("Expected the snapshot, got %v", err)
+			}
+			if len(services) != 1 || services[0].Nodes[0].Address != "10.0.0.1:8080" {
+				t.Fatalf("Unexpected services %+v", services)
+			}
+			if n := atomic.LoadInt64(&f.lookups); n != 2 {
+				t.Errorf("Expected the registry to be tried first, got %d lookups", n)
+			}
+
+			// snapshot entries are replaced once the registry is back
+			f.setDown(false)
+			if _, err := c.GetService("foo"); err != nil {
+				t.Fatal(err)
+			}
+			if n := atomic.LoadInt64(&f.lookups); n != 3 {
+				t.Errorf("Expected a lookup after recovery, got %d", n)
+			}
+		})
+	}
+}
+
+// xorSealer stands in for a real sealer.
+type xorSealer byte
+
+func (x xorSealer) Seal(b []byte) ([]byte, error) {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[i] = b[i] ^ byte(x)
+	}
+	return out, nil
+}
+
+func (x xorSealer) Open(b []byte) ([]byte, error) {
+	return x.Seal(b)
+}
+
+func TestEncryptedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.enc")
+	p := EncryptedFile(path, xorSealer(0x5a))
+
+	if err := p.Save(Snapshot{"foo": {Fetched: time.Now()}}); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(b, []byte("foo")) {
+		t.Error("Expected the snapshot to be sealed")
+	}
+
+	snap, err := p.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := snap["foo"]; !ok {
+		t.Errorf("Expected the snapshot to open, got %v", snap)
+	}
+}
+
+func TestSnapshotMaxStale(t *testing.T) {
+	services, err := newFlaky(t).GetService("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := Store(store.NewMemoryStore(), "registry-snapshot")
+	p.Save(Snapshot{"foo": {Fetched: time.Now().Add(-time.Hour), Services: services}})
+
+	f := newFlaky(t)
+	f.setDown(true)
+	c := NewCache(f, WithPersister(p), WithMaxStale(time.Minute))
+	defer c.Stop()
+
+	if _, err := c.GetService("foo"); err != errDown {
+		t.Fatalf("Expected the registry error for an old snapshot, got %v", err)
+	}
+}
+
+func TestSnapshotSaved(t *testing.T) {
+	p := Store(store.NewMemoryStore(), "registry-snapshot")
+	c := NewCache(newFlaky(t), TTL(50*time.Millisecond), WithPersister(p))
+	defer c.Stop()
+
+	if _, err := c.GetService("foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	// saved by the refresher without stopping
+	time.Sleep(50 * time.Millisecond)
+	snap, err := p.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snap["foo"].Services) != 1 || snap["foo"].Fetched.IsZero() {
+		t.Fatalf("Unexpected snapshot %+v", snap)
+	}
+}
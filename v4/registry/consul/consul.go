@@ -101,6 +101,20 @@ func configure(c *consulRegistry, opts ...registry.Option) {
 		if as, ok := c.opts.Context.Value("consul_allow_stale").(bool); ok {
 			c.queryOptions.AllowStale = as
 		}
+
+		// the defaults of every request, queries may override them
+		if ns, ok := c.opts.Context.Value("consul_namespace").(string); ok {
+			config.Namespace = ns
+		}
+		if p, ok := c.opts.Context.Value("consul_partition").(string); ok {
+			config.Partition = p
+		}
+		if t, ok := c.opts.Context.Value("consul_register_token").(string); ok {
+			config.Token = t
+		}
+		if t, ok := c.opts.Context.Value("consul_query_token").(string); ok {
+			c.queryOptions.Token = t
+		}
 	}
 
 	// check if there are any addrs
@@ -186,6 +200,13 @@ func (c *consulRegistry) Register(s *registry.Service, opts ...registry.Register
 		o(&options)
 	}
 
+	// the ttl of the check passed on every registration
+	ttl := options.TTL
+	co := getCheckOptions(options.Context)
+	if co.ttl > 0 {
+		ttl = co.ttl
+	}
+
 	if c.opts.Context != nil {
 		if tcpCheckInterval, ok := c.opts.Context.Value("consul_tcp_check").(time.Duration); ok {
 			regTCPCheck = true
@@ -214,7 +235,7 @@ func (c *consulRegistry) Register(s *registry.Service, opts ...registry.Register
 
 	// if it's already registered and matches then just pass the check
 	if ok && v == h {
-		if options.TTL == time.Duration(0) {
+		if ttl == time.Duration(0) || regTCPCheck || regHTTPCheck {
 			// ensure that our service hasn't been deregistered by Consul
 			if time.Since(lastChecked) <= getDeregisterTTL(regInterval) {
 				return nil
@@ -267,11 +288,11 @@ func (c *consulRegistry) Register(s *registry.Service, opts ...registry.Register
 		}
 
 		// if the TTL is greater than 0 create an associated check
-	} else if options.TTL > time.Duration(0) {
-		deregTTL := getDeregisterTTL(options.TTL)
+	} else if ttl > time.Duration(0) {
+		deregTTL := getDeregisterTTL(ttl)
 
 		check = &consul.AgentServiceCheck{
-			TTL:                            fmt.Sprintf("%v", options.TTL),
+			TTL:                            fmt.Sprintf("%v", ttl),
 			DeregisterCriticalServiceAfter: fmt.Sprintf("%v", deregTTL),
 		}
 	}
@@ -282,6 +303,32 @@ func (c *consulRegistry) Register(s *registry.Service, opts ...registry.Register
 	}
 	port, _ := strconv.Atoi(pt)
 
+	// the checks defined by the register options
+	var checks consul.AgentServiceChecks
+	for _, rc := range co.checks {
+		rc := rc
+		rc.HTTP = expandCheckAddress(rc.HTTP, host, node.Address)
+		rc.TCP = expandCheckAddress(rc.TCP, host, node.Address)
+		rc.GRPC = expandCheckAddress(rc.GRPC, host, node.Address)
+		if len(rc.DeregisterCriticalServiceAfter) == 0 && len(rc.Interval) > 0 {
+			interval, _ := time.ParseDuration(rc.Interval)
+			rc.DeregisterCriticalServiceAfter = fmt.Sprintf("%v", getDeregisterTTL(interval))
+		}
+		checks = append(checks, &rc)
+	}
+
+	for _, ch := range append(consul.AgentServiceChecks{check}, checks...) {
+		if ch == nil {
+			continue
+		}
+		if co.deregisterAfter > 0 {
+			ch.DeregisterCriticalServiceAfter = fmt.Sprintf("%v", co.deregisterAfter)
+		}
+		ch.SuccessBeforePassing = co.successBeforePassing
+		ch.FailuresBeforeWarning = co.failuresBeforeWarning
+		ch.FailuresBeforeCritical = co.failuresBeforeCritical
+	}
+
 	// register the service
 	asr := &consul.AgentServiceRegistration{
 		ID:      node.Id,
@@ -291,6 +338,7 @@ func (c *consulRegistry) Register(s *registry.Service, opts ...registry.Register
 		Address: host,
 		Meta:    node.Metadata,
 		Check:   check,
+		Checks:  checks,
 	}
 
 	// Specify consul connect
@@ -310,8 +358,8 @@ func (c *consulRegistry) Register(s *registry.Service, opts ...registry.Register
 	c.lastChecked[s.Name] = time.Now()
 	c.Unlock()
 
-	// if the TTL is 0 we don't mess with the checks
-	if options.TTL == time.Duration(0) {
+	// only a TTL check needs to be passed
+	if ttl == time.Duration(0) || regTCPCheck || regHTTPCheck {
 		return nil
 	}
 
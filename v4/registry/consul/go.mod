@@ -3,7 +3,7 @@ module github.com/go-micro/plugins/v4/registry/consul
 go 1.17
 
 require (
-	github.com/hashicorp/consul/api v1.9.0
+	github.com/hashicorp/consul/api v1.12.0
 	github.com/mitchellh/hashstructure v1.1.0
 	go-micro.dev/v4 v4.9.0
 )
@@ -31,7 +31,7 @@ require (
 	github.com/hashicorp/go-rootcerts v1.0.2 // indirect
 	github.com/hashicorp/go-uuid v1.0.2 // indirect
 	github.com/hashicorp/golang-lru v0.5.1 // indirect
-	github.com/hashicorp/serf v0.9.5 // indirect
+	github.com/hashicorp/serf v0.9.6 // indirect
 	github.com/imdario/mergo v0.3.12 // indirect
 	github.com/jbenet/go-context v0.0.0-20150711004518-d14ea06fba99 // indirect
 	github.com/kevinburke/ssh_config v0.0.0-20201106050909-4977a11b4351 // indirect
@@ -3,6 +3,7 @@ package consul
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	consul "github.com/hashicorp/consul/api"
@@ -99,3 +100,177 @@ func HTTPCheck(protocol, port, httpEndpoint string, interval, timeout time.Durat
 		o.Context = context.WithValue(o.Context, "consul_http_check_config", check)
 	}
 }
+
+// WatchWaitTime sets how long the blocking queries of watchers wait for
+// changes before they're reissued. Defaults to 5 minutes.
+func WatchWaitTime(t time.Duration) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, "consul_watch_wait_time", t)
+	}
+}
+
+// WatchCoalesce sets the minimum interval between changes handled by
+// watchers, rapid changes within it are coalesced into one update.
+// Defaults to 100ms.
+func WatchCoalesce(t time.Duration) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, "consul_watch_coalesce", t)
+	}
+}
+
+// Namespace sets the Consul Enterprise namespace services are registered in
+// and queried from.
+func Namespace(ns string) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, "consul_namespace", ns)
+	}
+}
+
+// Partition sets the Consul Enterprise admin partition services are
+// registered in and queried from.
+func Partition(p string) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, "consul_partition", p)
+	}
+}
+
+// RegisterToken sets the ACL token used to register and deregister services
+// and to pass their health checks. It only needs the service:write policy.
+func RegisterToken(t string) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, "consul_register_token", t)
+	}
+}
+
+// QueryToken sets the ACL token used to look up and watch services. It only
+// needs the service:read and node:read policies.
+func QueryToken(t string) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, "consul_query_token", t)
+	}
+}
+
+// checkOptions are the check settings of a registration.
+type checkOptions struct {
+	ttl                    time.Duration
+	checks                 []consul.AgentServiceCheck
+	deregisterAfter        time.Duration
+	successBeforePassing   int
+	failuresBeforeWarning  int
+	failuresBeforeCritical int
+}
+
+func getCheckOptions(ctx context.Context) checkOptions {
+	if ctx == nil {
+		return checkOptions{}
+	}
+	co, _ := ctx.Value("consul_check_options").(checkOptions)
+	return co
+}
+
+func setCheckOption(fn func(*checkOptions)) registry.RegisterOption {
+	return func(o *registry.RegisterOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		co := getCheckOptions(o.Context)
+		// copy the checks so registrations don't share them
+		co.checks = append([]consul.AgentServiceCheck(nil), co.checks...)
+		fn(&co)
+		o.Context = context.WithValue(o.Context, "consul_check_options", co)
+	}
+}
+
+// expandCheckAddress replaces the {host} and {address} placeholders of a
+// check target with the host and the address of the registered node.
+func expandCheckAddress(target, host, address string) string {
+	target = strings.Replace(target, "{host}", host, -1)
+	return strings.Replace(target, "{address}", address, -1)
+}
+
+// CheckTTL sets the TTL of the check passed on every registration, instead
+// of the registration TTL. Register the service at an interval shorter than
+// the TTL to keep it passing.
+func CheckTTL(t time.Duration) registry.RegisterOption {
+	return setCheckOption(func(co *checkOptions) {
+		co.ttl = t
+	})
+}
+
+// RegisterCheck adds check definitions to the registration. The {host} and
+// {address} placeholders of the HTTP, TCP and GRPC targets are replaced with
+// the host and the address of the node.
+func RegisterCheck(checks ...consul.AgentServiceCheck) registry.RegisterOption {
+	return setCheckOption(func(co *checkOptions) {
+		co.checks = append(co.checks, checks...)
+	})
+}
+
+// RegisterHTTPCheck adds a check requesting the url at an interval. The
+// check passes on 2xx responses.
+func RegisterHTTPCheck(url string, interval, timeout time.Duration) registry.RegisterOption {
+	return RegisterCheck(consul.AgentServiceCheck{
+		HTTP:     url,
+		Interval: fmt.Sprintf("%v", interval),
+		Timeout:  fmt.Sprintf("%v", timeout),
+	})
+}
+
+// RegisterTCPCheck adds a check connecting to the node's address at an
+// interval.
+func RegisterTCPCheck(interval, timeout time.Duration) registry.RegisterOption {
+	return RegisterCheck(consul.AgentServiceCheck{
+		TCP:      "{address}",
+		Interval: fmt.Sprintf("%v", interval),
+		Timeout:  fmt.Sprintf("%v", timeout),
+	})
+}
+
+// RegisterGRPCCheck adds a check calling the standard gRPC health service of
+// the node at an interval.
+func RegisterGRPCCheck(interval, timeout time.Duration, useTLS bool) registry.RegisterOption {
+	return RegisterCheck(consul.AgentServiceCheck{
+		GRPC:       "{address}",
+		GRPCUseTLS: useTLS,
+		Interval:   fmt.Sprintf("%v", interval),
+		Timeout:    fmt.Sprintf("%v", timeout),
+	})
+}
+
+// DeregisterCriticalServiceAfter sets how long the checks of the service may
+// be critical before Consul deregisters it. Defaults to the check interval
+// plus a splay, at least a minute.
+func DeregisterCriticalServiceAfter(t time.Duration) registry.RegisterOption {
+	return setCheckOption(func(co *checkOptions) {
+		co.deregisterAfter = t
+	})
+}
+
+// CheckThresholds sets the number of consecutive successes before a check
+// is passing, and the failures before it's warning and critical. Zero keeps
+// Consul's default of one.
+func CheckThresholds(successBeforePassing, failuresBeforeWarning, failuresBeforeCritical int) registry.RegisterOption {
+	return setCheckOption(func(co *checkOptions) {
+		co.successBeforePassing = successBeforePassing
+		co.failuresBeforeWarning = failuresBeforeWarning
+		co.failuresBeforeCritical = failuresBeforeCritical
+	})
+}
@@ -6,6 +6,9 @@ import (
 	"errors"
 	"net"
 	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -206,3 +209,122 @@ func TestConsul_GetService_WithUnhealthyServiceNodes(t *testing.T) {
 		t.Fatalf("Expected len of nodes to be `%d`, got `%d`.", exp, act)
 	}
 }
+
+func TestConsul_Tenancy(t *testing.T) {
+	type request struct {
+		ns, partition, token string
+	}
+	requests := make(map[string]request)
+	var mu sync.Mutex
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path] = request{
+			ns:        r.URL.Query().Get("ns"),
+			partition: r.URL.Query().Get("partition"),
+			token:     r.Header.Get("X-Consul-Token"),
+		}
+		mu.Unlock()
+
+		if strings.HasPrefix(r.URL.Path, "/v1/health/") {
+			w.Write([]byte("[]"))
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer srv.Close()
+
+	r := NewRegistry(
+		registry.Addrs(strings.TrimPrefix(srv.URL, "http://")),
+		Namespace("team-a"),
+		Partition("billing"),
+		RegisterToken("write-token"),
+		QueryToken("read-token"),
+	)
+
+	err := r.Register(&registry.Service{
+		Name:  "test",
+		Nodes: []*registry.Node{{Id: "test-1", Address: "10.0.0.1:8080"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.GetService("test"); err != nil {
+		t.Fatal(err)
+	}
+
+	testData := map[string]request{
+		"/v1/agent/service/register": {"team-a", "billing", "write-token"},
+		"/v1/health/service/test":    {"team-a", "billing", "read-token"},
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	for path, want := range testData {
+		if got := requests[path]; got != want {
+			t.Errorf("Expected %s to be requested with %+v, got %+v", path, want, got)
+		}
+	}
+}
+
+func TestConsul_RegisterChecks(t *testing.T) {
+	var asr consul.AgentServiceRegistration
+	var passed bool
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/agent/service/register":
+			if err := json.NewDecoder(r.Body).Decode(&asr); err != nil {
+				t.Error(err)
+			}
+		case strings.HasPrefix(r.URL.Path, "/v1/agent/check/"):
+			passed = true
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer srv.Close()
+
+	r := NewRegistry(registry.Addrs(strings.TrimPrefix(srv.URL, "http://")))
+
+	err := r.Register(&registry.Service{
+		Name:  "test",
+		Nodes: []*registry.Node{{Id: "test-1", Address: "10.0.0.1:8080"}},
+	},
+		CheckTTL(30*time.Second),
+		RegisterHTTPCheck("http://{host}:9090/health", 10*time.Second, time.Second),
+		RegisterGRPCCheck(5*time.Second, time.Second, false),
+		DeregisterCriticalServiceAfter(2*time.Minute),
+		CheckThresholds(2, 0, 3),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if asr.Check == nil || asr.Check.TTL != "30s" {
+		t.Fatalf("Expected a 30s TTL check, got %+v", asr.Check)
+	}
+	if !passed {
+		t.Error("Expected the TTL check to be passed")
+	}
+
+	if len(asr.Checks) != 2 {
+		t.Fatalf("Expected 2 additional checks, got %d", len(asr.Checks))
+	}
+	if asr.Checks[0].HTTP != "http://10.0.0.1:9090/health" || asr.Checks[0].Interval != "10s" {
+		t.Errorf("Unexpected HTTP check %+v", asr.Checks[0])
+	}
+	if asr.Checks[1].GRPC != "10.0.0.1:8080" {
+		t.Errorf("Unexpected gRPC check %+v", asr.Checks[1])
+	}
+
+	for _, ch := range append(consul.AgentServiceChecks{asr.Check}, asr.Checks...) {
+		if ch.DeregisterCriticalServiceAfter != "2m0s" {
+			t.Errorf("Expected deregistration after 2m, got %q", ch.DeregisterCriticalServiceAfter)
+		}
+		if ch.SuccessBeforePassing != 2 || ch.FailuresBeforeCritical != 3 {
+			t.Errorf("Expected the check thresholds to be set, got %+v", ch)
+		}
+	}
+}
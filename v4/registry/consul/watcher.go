@@ -1,21 +1,37 @@
 package consul
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/consul/api"
-	"github.com/hashicorp/consul/api/watch"
+	"go-micro.dev/v4/logger"
 	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/util/backoff"
 	regutil "go-micro.dev/v4/util/registry"
 )
 
+var (
+	// DefaultWatchWaitTime is how long a blocking query waits for changes.
+	DefaultWatchWaitTime = 5 * time.Minute
+	// DefaultWatchCoalesce is the minimum interval between handling changes,
+	// changes within it are handled together.
+	DefaultWatchCoalesce = 100 * time.Millisecond
+)
+
 type consulWatcher struct {
 	r        *consulRegistry
 	wo       registry.WatchOptions
-	wp       *watch.Plan
-	watchers map[string]*watch.Plan
+	watchers map[string]context.CancelFunc
+
+	waitTime time.Duration
+	coalesce time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
 
 	next chan *registry.Result
 	exit chan bool
@@ -30,30 +46,124 @@ func newConsulWatcher(cr *consulRegistry, opts ...registry.WatchOption) (registr
 		o(&wo)
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+
 	cw := &consulWatcher{
 		r:        cr,
 		wo:       wo,
+		waitTime: DefaultWatchWaitTime,
+		coalesce: DefaultWatchCoalesce,
+		ctx:      ctx,
+		cancel:   cancel,
 		exit:     make(chan bool),
 		next:     make(chan *registry.Result, 10),
-		watchers: make(map[string]*watch.Plan),
+		watchers: make(map[string]context.CancelFunc),
 		services: make(map[string][]*registry.Service),
 	}
 
-	wp, err := watch.Parse(map[string]interface{}{
-		"service": wo.Service,
-		"type":    "service",
-	})
-	if err != nil {
-		return nil, err
+	if cr.opts.Context != nil {
+		if d, ok := cr.opts.Context.Value("consul_watch_wait_time").(time.Duration); ok && d > 0 {
+			cw.waitTime = d
+		}
+		if d, ok := cr.opts.Context.Value("consul_watch_coalesce").(time.Duration); ok {
+			cw.coalesce = d
+		}
 	}
 
-	wp.Handler = cw.handle
-	go wp.RunWithClientAndHclog(cr.Client(), wp.Logger)
-	cw.wp = wp
+	if len(wo.Service) > 0 {
+		go cw.watchService(ctx, wo.Service)
+	} else {
+		go cw.watch(ctx, func(q *api.QueryOptions) (interface{}, *api.QueryMeta, error) {
+			return cr.Client().Catalog().Services(q)
+		}, cw.handle)
+	}
 
 	return cw, nil
 }
 
+func (cw *consulWatcher) watchService(ctx context.Context, service string) {
+	cw.watch(ctx, func(q *api.QueryOptions) (interface{}, *api.QueryMeta, error) {
+		return cw.r.Client().Health().Service(service, "", false, q)
+	}, cw.serviceHandler)
+}
+
+// watch runs blocking queries until the context is cancelled, passing every
+// change to the handler. The index of the last response is tracked so the
+// query only returns on changes, and kept when resuming after errors.
+func (cw *consulWatcher) watch(ctx context.Context, query func(*api.QueryOptions) (interface{}, *api.QueryMeta, error), handler func(uint64, interface{})) {
+	var (
+		index    uint64
+		attempts int
+		handled  time.Time
+	)
+
+	for {
+		q := &api.QueryOptions{}
+		if cw.r.queryOptions != nil {
+			*q = *cw.r.queryOptions
+		}
+		q.WaitIndex = index
+		q.WaitTime = cw.waitTime
+
+		data, meta, err := query(q.WithContext(ctx))
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			attempts++
+			logger.Logf(logger.WarnLevel, "consul watch failed, retrying: %v", err)
+			if !sleep(ctx, backoff.Do(attempts)) {
+				return
+			}
+			continue
+		}
+		attempts = 0
+
+		// timed out without changes
+		if meta.LastIndex == index && index > 0 {
+			continue
+		}
+
+		// the index went backwards, e.g. after a restore, start over
+		if meta.LastIndex < index {
+			index = 0
+		} else {
+			index = meta.LastIndex
+		}
+
+		handler(index, data)
+		handled = time.Now()
+
+		// changes until the next query are handled together
+		if !sleep(ctx, cw.coalesce-time.Since(handled)) {
+			return
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+func (cw *consulWatcher) send(r *registry.Result) {
+	select {
+	case cw.next <- r:
+	case <-cw.exit:
+	}
+}
+
 func (cw *consulWatcher) serviceHandler(idx uint64, data interface{}) {
 	entries, ok := data.([]*api.ServiceEntry)
 	if !ok {
@@ -130,7 +240,7 @@ func (cw *consulWatcher) serviceHandler(idx uint64, data interface{}) {
 		oldServices, ok := rservices[serviceName]
 		if !ok {
 			// does not exist? then we're creating brand new entries
-			cw.next <- &registry.Result{Action: "create", Service: newService}
+			cw.send(&registry.Result{Action: "create", Service: newService})
 			continue
 		}
 
@@ -168,11 +278,11 @@ func (cw *consulWatcher) serviceHandler(idx uint64, data interface{}) {
 			if len(nodes) > 0 {
 				delService := regutil.CopyService(oldService)
 				delService.Nodes = nodes
-				cw.next <- &registry.Result{Action: "delete", Service: delService}
+				cw.send(&registry.Result{Action: "delete", Service: delService})
 			}
 		}
 
-		cw.next <- &registry.Result{Action: action, Service: newService}
+		cw.send(&registry.Result{Action: action, Service: newService})
 	}
 
 	// Now check old versions that may not be in new services map
@@ -180,7 +290,7 @@ func (cw *consulWatcher) serviceHandler(idx uint64, data interface{}) {
 		// old version does not exist in new version map
 		// kill it with fire!
 		if _, ok := serviceMap[old.Version]; !ok {
-			cw.next <- &registry.Result{Action: "delete", Service: old}
+			cw.send(&registry.Result{Action: "delete", Service: old})
 		}
 	}
 
@@ -206,16 +316,11 @@ func (cw *consulWatcher) handle(idx uint64, data interface{}) {
 		if _, ok := cw.watchers[service]; ok {
 			continue
 		}
-		wp, err := watch.Parse(map[string]interface{}{
-			"type":    "service",
-			"service": service,
-		})
-		if err == nil {
-			wp.Handler = cw.serviceHandler
-			go wp.RunWithClientAndHclog(cw.r.Client(), wp.Logger)
-			cw.watchers[service] = wp
-			cw.next <- &registry.Result{Action: "create", Service: &registry.Service{Name: service}}
-		}
+
+		ctx, cancel := context.WithCancel(cw.ctx)
+		cw.watchers[service] = cancel
+		go cw.watchService(ctx, service)
+		cw.send(&registry.Result{Action: "create", Service: &registry.Service{Name: service}})
 	}
 
 	cw.RLock()
@@ -241,16 +346,16 @@ func (cw *consulWatcher) handle(idx uint64, data interface{}) {
 	}
 
 	// remove unknown services from watchers
-	for service, w := range cw.watchers {
+	for service, cancel := range cw.watchers {
 		if _, ok := services[service]; !ok {
-			w.Stop()
+			cancel()
 			delete(cw.watchers, service)
 			for _, oldService := range deleted[service] {
 				// send a delete for the service nodes that we're removing
-				cw.next <- &registry.Result{Action: "delete", Service: oldService}
+				cw.send(&registry.Result{Action: "delete", Service: oldService})
 			}
 			// sent the empty list as the last resort to indicate to delete the entire service
-			cw.next <- &registry.Result{Action: "delete", Service: &registry.Service{Name: service}}
+			cw.send(&registry.Result{Action: "delete", Service: &registry.Service{Name: service}})
 		}
 	}
 }
@@ -273,10 +378,10 @@ func (cw *consulWatcher) Stop() {
 		return
 	default:
 		close(cw.exit)
-		if cw.wp == nil {
+		if cw.cancel == nil {
 			return
 		}
-		cw.wp.Stop()
+		cw.cancel()
 
 		// drain results
 		for {
@@ -1,6 +1,9 @@
 package consul
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/hashicorp/consul/api"
@@ -84,3 +87,51 @@ func newServiceEntry(node, address, name, version string, checks []*api.HealthCh
 		Checks: checks,
 	}
 }
+
+func TestWatchIndex(t *testing.T) {
+	watcher := newWatcher()
+	watcher.r = &consulRegistry{queryOptions: &api.QueryOptions{AllowStale: true}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	responses := []struct {
+		index uint64
+		err   error
+	}{
+		{10, nil},
+		{10, nil}, // timed out without changes
+		{0, errors.New("unavailable")},
+		{12, nil},
+		{3, nil}, // index went backwards
+	}
+
+	var (
+		waitIndexes []uint64
+		handled     []uint64
+	)
+
+	query := func(q *api.QueryOptions) (interface{}, *api.QueryMeta, error) {
+		if !q.AllowStale {
+			t.Error("Expected registry query options to be used")
+		}
+		waitIndexes = append(waitIndexes, q.WaitIndex)
+		if len(waitIndexes) > len(responses) {
+			cancel()
+			return nil, nil, context.Canceled
+		}
+		r := responses[len(waitIndexes)-1]
+		return nil, &api.QueryMeta{LastIndex: r.index}, r.err
+	}
+
+	watcher.watch(ctx, query, func(idx uint64, _ interface{}) {
+		handled = append(handled, idx)
+	})
+
+	if fmt.Sprint(handled) != "[10 12 0]" {
+		t.Errorf("Expected changes to be handled once, got %v", handled)
+	}
+	if fmt.Sprint(waitIndexes) != "[0 10 10 10 12 0]" {
+		t.Errorf("Expected the index to be tracked across errors, got %v", waitIndexes)
+	}
+}
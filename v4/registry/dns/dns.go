@@ -0,0 +1,393 @@
+// Package dns provides a DNS SRV based service registry
+package dns
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/util/cmd"
+)
+
+var (
+	// DefaultDomain is the domain services are looked up in.
+	DefaultDomain = "local"
+	// DefaultResolvConf is used to find the nameservers when no addresses are set.
+	DefaultResolvConf = "/etc/resolv.conf"
+	// DefaultNameFormat is the name of the SRV records of a service.
+	DefaultNameFormat = "_%s._tcp"
+	// DefaultPollInterval is how often watchers look services up.
+	DefaultPollInterval = 30 * time.Second
+
+	defaultNameserver = "127.0.0.1:53"
+)
+
+type dnsRegistry struct {
+	options registry.Options
+	domain  string
+	format  string
+	port    int
+	minTTL  time.Duration
+	maxTTL  time.Duration
+	poll    time.Duration
+	servers []string
+	client  *dns.Client
+
+	sync.RWMutex
+	cache map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	services []*registry.Service
+	expires  time.Time
+}
+
+func init() {
+	cmd.DefaultRegistries["dns"] = NewRegistry
+}
+
+func configure(d *dnsRegistry, opts ...registry.Option) error {
+	for _, o := range opts {
+		o(&d.options)
+	}
+
+	if d.options.Timeout == 0 {
+		d.options.Timeout = 5 * time.Second
+	}
+
+	d.domain = DefaultDomain
+	d.format = DefaultNameFormat
+	d.port = 0
+	d.minTTL, d.maxTTL = 0, 0
+	d.poll = DefaultPollInterval
+	if ctx := d.options.Context; ctx != nil {
+		if domain, ok := ctx.Value(domainKey{}).(string); ok && len(domain) > 0 {
+			d.domain = domain
+		}
+		if f, ok := ctx.Value(nameFormatKey{}).(string); ok && len(f) > 0 {
+			d.format = f
+		}
+		if p, ok := ctx.Value(portKey{}).(int); ok {
+			d.port = p
+		}
+		if t, ok := ctx.Value(minTTLKey{}).(time.Duration); ok {
+			d.minTTL = t
+		}
+		if t, ok := ctx.Value(maxTTLKey{}).(time.Duration); ok {
+			d.maxTTL = t
+		}
+		if t, ok := ctx.Value(pollIntervalKey{}).(time.Duration); ok && t > 0 {
+			d.poll = t
+		}
+	}
+
+	var servers []string
+	for _, addr := range d.options.Addrs {
+		if len(addr) == 0 {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, "53")
+		}
+		servers = append(servers, addr)
+	}
+
+	// fall back to the system resolvers
+	if len(servers) == 0 {
+		if conf, err := dns.ClientConfigFromFile(DefaultResolvConf); err == nil {
+			for _, server := range conf.Servers {
+				servers = append(servers, net.JoinHostPort(server, conf.Port))
+			}
+		}
+	}
+
+	if len(servers) == 0 {
+		servers = []string{defaultNameserver}
+	}
+
+	d.servers = servers
+	d.client = &dns.Client{Timeout: d.options.Timeout}
+
+	d.Lock()
+	d.cache = make(map[string]*cacheEntry)
+	d.Unlock()
+
+	return nil
+}
+
+// query sends the question to each nameserver in turn until one answers.
+func (d *dnsRegistry) query(name string, qtype uint16) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), qtype)
+
+	var err error
+	for _, server := range d.servers {
+		var r *dns.Msg
+		r, _, err = d.client.Exchange(m, server)
+		if err != nil {
+			continue
+		}
+
+		switch r.Rcode {
+		case dns.RcodeSuccess:
+			return r, nil
+		case dns.RcodeNameError:
+			return nil, registry.ErrNotFound
+		default:
+			err = fmt.Errorf("dns query for %s failed: %s", name, dns.RcodeToString[r.Rcode])
+		}
+	}
+
+	return nil, err
+}
+
+// txt returns the key=value pairs published in TXT records for name.
+func (d *dnsRegistry) txt(name string) map[string]string {
+	md := make(map[string]string)
+
+	r, err := d.query(name, dns.TypeTXT)
+	if err != nil {
+		return md
+	}
+
+	for _, rr := range r.Answer {
+		txt, ok := rr.(*dns.TXT)
+		if !ok {
+			continue
+		}
+		for _, s := range txt.Txt {
+			parts := strings.SplitN(s, "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			md[parts[0]] = parts[1]
+		}
+	}
+
+	return md
+}
+
+// lookup resolves the service from its SRV and TXT records and returns
+// the service along with the ttl of the records.
+func (d *dnsRegistry) lookup(service string) ([]*registry.Service, time.Duration, error) {
+	name := fmt.Sprintf(d.format, service) + "." + d.domain
+
+	r, err := d.query(name, dns.TypeSRV)
+	if err == registry.ErrNotFound && d.port > 0 {
+		return d.lookupHost(service)
+	} else if err != nil {
+		return nil, 0, err
+	}
+
+	// addresses of the targets supplied in the additional section
+	addrs := make(map[string]string)
+	for _, rr := range r.Extra {
+		switch a := rr.(type) {
+		case *dns.A:
+			addrs[a.Hdr.Name] = a.A.String()
+		case *dns.AAAA:
+			addrs[a.Hdr.Name] = a.AAAA.String()
+		}
+	}
+
+	var srvs []*dns.SRV
+	ttl := uint32(math.MaxUint32)
+	for _, rr := range r.Answer {
+		srv, ok := rr.(*dns.SRV)
+		if !ok {
+			continue
+		}
+		srvs = append(srvs, srv)
+		if srv.Hdr.Ttl < ttl {
+			ttl = srv.Hdr.Ttl
+		}
+	}
+
+	if len(srvs) == 0 {
+		if d.port > 0 {
+			return d.lookupHost(service)
+		}
+		return nil, 0, registry.ErrNotFound
+	}
+
+	// order by priority then weight as per RFC 2782
+	sort.SliceStable(srvs, func(i, j int) bool {
+		if srvs[i].Priority != srvs[j].Priority {
+			return srvs[i].Priority < srvs[j].Priority
+		}
+		return srvs[i].Weight > srvs[j].Weight
+	})
+
+	nodes := make([]*registry.Node, 0, len(srvs))
+	for _, srv := range srvs {
+		host := strings.TrimSuffix(srv.Target, ".")
+		if addr, ok := addrs[srv.Target]; ok {
+			host = addr
+		}
+		port := strconv.Itoa(int(srv.Port))
+
+		nodes = append(nodes, &registry.Node{
+			Id:      net.JoinHostPort(strings.TrimSuffix(srv.Target, "."), port),
+			Address: net.JoinHostPort(host, port),
+			Metadata: map[string]string{
+				"priority": strconv.Itoa(int(srv.Priority)),
+				"weight":   strconv.Itoa(int(srv.Weight)),
+			},
+		})
+	}
+
+	md := d.txt(name)
+
+	return []*registry.Service{
+		{
+			Name:     service,
+			Version:  md["version"],
+			Metadata: md,
+			Nodes:    nodes,
+		},
+	}, time.Duration(ttl) * time.Second, nil
+}
+
+// lookupHost resolves the nodes of a service without SRV records from the
+// A and AAAA records of its name, on the configured port.
+func (d *dnsRegistry) lookupHost(service string) ([]*registry.Service, time.Duration, error) {
+	name := service + "." + d.domain
+	port := strconv.Itoa(d.port)
+
+	var nodes []*registry.Node
+	ttl := uint32(math.MaxUint32)
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		r, err := d.query(name, qtype)
+		if err == registry.ErrNotFound {
+			continue
+		} else if err != nil {
+			return nil, 0, err
+		}
+
+		for _, rr := range r.Answer {
+			var ip net.IP
+			switch a := rr.(type) {
+			case *dns.A:
+				ip = a.A
+			case *dns.AAAA:
+				ip = a.AAAA
+			default:
+				continue
+			}
+			if rr.Header().Ttl < ttl {
+				ttl = rr.Header().Ttl
+			}
+
+			addr := net.JoinHostPort(ip.String(), port)
+			nodes = append(nodes, &registry.Node{
+				Id:       addr,
+				Address:  addr,
+				Metadata: map[string]string{},
+			})
+		}
+	}
+
+	if len(nodes) == 0 {
+		return nil, 0, registry.ErrNotFound
+	}
+
+	return []*registry.Service{{Name: service, Nodes: nodes}}, time.Duration(ttl) * time.Second, nil
+}
+
+// cacheTTL bounds the ttl of the records.
+func (d *dnsRegistry) cacheTTL(ttl time.Duration) time.Duration {
+	if d.minTTL > 0 && ttl < d.minTTL {
+		ttl = d.minTTL
+	}
+	if d.maxTTL > 0 && ttl > d.maxTTL {
+		ttl = d.maxTTL
+	}
+	return ttl
+}
+
+// refresh looks the service up and caches it.
+func (d *dnsRegistry) refresh(name string) ([]*registry.Service, error) {
+	services, ttl, err := d.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	d.Lock()
+	d.cache[name] = &cacheEntry{
+		services: services,
+		expires:  time.Now().Add(d.cacheTTL(ttl)),
+	}
+	d.Unlock()
+
+	return services, nil
+}
+
+func (d *dnsRegistry) Init(opts ...registry.Option) error {
+	return configure(d, opts...)
+}
+
+func (d *dnsRegistry) Options() registry.Options {
+	return d.options
+}
+
+// Register is a no-op, records are managed by the DNS provider.
+func (d *dnsRegistry) Register(s *registry.Service, opts ...registry.RegisterOption) error {
+	return nil
+}
+
+// Deregister is a no-op, records are managed by the DNS provider.
+func (d *dnsRegistry) Deregister(s *registry.Service, opts ...registry.DeregisterOption) error {
+	return nil
+}
+
+func (d *dnsRegistry) GetService(name string, opts ...registry.GetOption) ([]*registry.Service, error) {
+	d.RLock()
+	entry, ok := d.cache[name]
+	d.RUnlock()
+
+	if ok && time.Now().Before(entry.expires) {
+		return entry.services, nil
+	}
+
+	return d.refresh(name)
+}
+
+// ListServices returns the services which have been looked up so far, DNS
+// provides no way to enumerate them.
+func (d *dnsRegistry) ListServices(opts ...registry.ListOption) ([]*registry.Service, error) {
+	d.RLock()
+	defer d.RUnlock()
+
+	services := make([]*registry.Service, 0, len(d.cache))
+	for name := range d.cache {
+		services = append(services, &registry.Service{Name: name})
+	}
+
+	return services, nil
+}
+
+// Watch polls the services, DNS has no way to notify of changes. Without a
+// service the services looked up so far are watched.
+func (d *dnsRegistry) Watch(opts ...registry.WatchOption) (registry.Watcher, error) {
+	return newWatcher(d, opts...), nil
+}
+
+func (d *dnsRegistry) String() string {
+	return "dns"
+}
+
+// NewRegistry returns a new dns registry.
+func NewRegistry(opts ...registry.Option) registry.Registry {
+	d := &dnsRegistry{
+		options: registry.Options{},
+	}
+	configure(d, opts...)
+	return d
+}
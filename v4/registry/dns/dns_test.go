@@ -0,0 +1,279 @@
+package dns
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"go-micro.dev/v4/registry"
+)
+
+func testServer(t *testing.T) string {
+	t.Helper()
+
+	mux := dns.NewServeMux()
+	mux.HandleFunc("_foo._tcp.micro.test.", func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+
+		switch r.Question[0].Qtype {
+		case dns.TypeSRV:
+			for _, s := range []string{
+				"_foo._tcp.micro.test. 30 IN SRV 20 10 8080 backup.micro.test.",
+				"_foo._tcp.micro.test. 60 IN SRV 10 50 8080 node1.micro.test.",
+				"_foo._tcp.micro.test. 60 IN SRV 10 10 8081 node2.micro.test.",
+			} {
+				rr, _ := dns.NewRR(s)
+				m.Answer = append(m.Answer, rr)
+			}
+			rr, _ := dns.NewRR("node1.micro.test. 60 IN A 10.0.0.1")
+			m.Extra = append(m.Extra, rr)
+		case dns.TypeTXT:
+			rr, _ := dns.NewRR(`_foo._tcp.micro.test. 60 IN TXT "version=1.0.0" "region=eu"`)
+			m.Answer = append(m.Answer, rr)
+		}
+
+		w.WriteMsg(m)
+	})
+
+	mux.HandleFunc("micro.test.", func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeNameError)
+		w.WriteMsg(m)
+	})
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &dns.Server{PacketConn: pc, Handler: mux}
+	go server.ActivateAndServe()
+	t.Cleanup(func() { server.Shutdown() })
+
+	return pc.LocalAddr().String()
+}
+
+func TestGetService(t *testing.T) {
+	r := NewRegistry(
+		registry.Addrs(testServer(t)),
+		Domain("micro.test"),
+	)
+
+	services, err := r.GetService("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(services) != 1 {
+		t.Fatalf("Expected 1 service, got %d", len(services))
+	}
+
+	service := services[0]
+	if service.Version != "1.0.0" {
+		t.Fatalf("Expected version 1.0.0, got %s", service.Version)
+	}
+	if service.Metadata["region"] != "eu" {
+		t.Fatalf("Expected region metadata eu, got %s", service.Metadata["region"])
+	}
+
+	if len(service.Nodes) != 3 {
+		t.Fatalf("Expected 3 nodes, got %d", len(service.Nodes))
+	}
+
+	expected := []string{"10.0.0.1:8080", "node2.micro.test:8081", "backup.micro.test:8080"}
+	for i, node := range service.Nodes {
+		if node.Address != expected[i] {
+			t.Fatalf("Expected node %d address %s, got %s", i, expected[i], node.Address)
+		}
+	}
+
+	// the lowest ttl of the records is used for the cache
+	entry := r.(*dnsRegistry).cache["foo"]
+	if entry == nil {
+		t.Fatal("Expected service to be cached")
+	}
+	if ttl := time.Until(entry.expires); ttl > 30*time.Second {
+		t.Fatalf("Expected cache ttl of at most 30s, got %v", ttl)
+	}
+
+	if _, err := r.GetService("bar"); err != registry.ErrNotFound {
+		t.Fatalf("Expected %v, got %v", registry.ErrNotFound, err)
+	}
+}
+
+func TestWeighted(t *testing.T) {
+	services := []*registry.Service{
+		{
+			Name: "foo",
+			Nodes: []*registry.Node{
+				{Id: "1", Metadata: map[string]string{"priority": "10", "weight": "100"}},
+				{Id: "2", Metadata: map[string]string{"priority": "10", "weight": "0"}},
+				{Id: "3", Metadata: map[string]string{"priority": "20", "weight": "100"}},
+			},
+		},
+	}
+
+	next := Weighted(services)
+	counts := make(map[string]int)
+
+	for i := 0; i < 1000; i++ {
+		node, err := next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[node.Id]++
+	}
+
+	if counts["3"] != 0 {
+		t.Fatalf("Expected higher priority node to be skipped, got %d selections", counts["3"])
+	}
+
+	if counts["1"] <= counts["2"] {
+		t.Fatalf("Expected weighted node to be preferred, got %v", counts)
+	}
+}
+
+func TestWeightedDistribution(t *testing.T) {
+	weighted := func(weights ...string) map[string]int {
+		var nodes []*registry.Node
+		for i, w := range weights {
+			nodes = append(nodes, &registry.Node{Id: strconv.Itoa(i), Metadata: map[string]string{"priority": "10", "weight": w}})
+		}
+
+		next := Weighted([]*registry.Service{{Name: "foo", Nodes: nodes}})
+		counts := make(map[string]int)
+		for i := 0; i < 10000; i++ {
+			node, err := next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			counts[node.Id]++
+		}
+		return counts
+	}
+
+	// the picks are in proportion to the weights, zero weights don't skew
+	// them
+	counts := weighted("1", "3", "0")
+	if share := float64(counts["1"]) / 10000; share < 0.72 || share > 0.78 {
+		t.Fatalf("Expected the node of weight 3 to be picked 75%% of the time, got %v", counts)
+	}
+	if counts["2"] > 50 {
+		t.Fatalf("Expected the zero weight node to be picked rarely, got %v", counts)
+	}
+
+	// without weights the nodes are picked evenly
+	counts = weighted("0", "0")
+	if share := float64(counts["0"]) / 10000; share < 0.45 || share > 0.55 {
+		t.Fatalf("Expected the nodes to be picked evenly, got %v", counts)
+	}
+}
+
+func hostServer(t *testing.T, addrs func() []string) string {
+	t.Helper()
+
+	mux := dns.NewServeMux()
+	mux.HandleFunc("bar.ns.svc.test.", func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+
+		if r.Question[0].Qtype == dns.TypeA {
+			for _, a := range addrs() {
+				rr, _ := dns.NewRR("bar.ns.svc.test. 5 IN A " + a)
+				m.Answer = append(m.Answer, rr)
+			}
+		}
+
+		w.WriteMsg(m)
+	})
+
+	mux.HandleFunc("ns.svc.test.", func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeNameError)
+		w.WriteMsg(m)
+	})
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &dns.Server{PacketConn: pc, Handler: mux}
+	go server.ActivateAndServe()
+	t.Cleanup(func() { server.Shutdown() })
+
+	return pc.LocalAddr().String()
+}
+
+func TestLookupHost(t *testing.T) {
+	r := NewRegistry(
+		registry.Addrs(hostServer(t, func() []string { return []string{"10.0.0.1", "10.0.0.2"} })),
+		Domain("ns.svc.test"),
+		Port(9090),
+		MinTTL(time.Minute),
+	)
+
+	services, err := r.GetService("bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 || len(services[0].Nodes) != 2 {
+		t.Fatalf("Expected 1 service with 2 nodes, got %+v", services)
+	}
+	if addr := services[0].Nodes[0].Address; addr != "10.0.0.1:9090" {
+		t.Fatalf("Expected 10.0.0.1:9090, got %s", addr)
+	}
+
+	// the ttl of the records is raised to the minimum
+	if ttl := time.Until(r.(*dnsRegistry).cache["bar"].expires); ttl < 50*time.Second {
+		t.Fatalf("Expected cache ttl of about a minute, got %v", ttl)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	var mu sync.Mutex
+	addrs := []string{"10.0.0.1", "10.0.0.2"}
+
+	r := NewRegistry(
+		registry.Addrs(hostServer(t, func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return addrs
+		})),
+		Domain("ns.svc.test"),
+		Port(9090),
+		PollInterval(10*time.Millisecond),
+	)
+
+	if _, err := r.GetService("bar"); err != nil {
+		t.Fatal(err)
+	}
+
+	// changes are reported from the cached nodes on
+	w, err := r.Watch(registry.WatchService("bar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	mu.Lock()
+	addrs = []string{"10.0.0.2", "10.0.0.3"}
+	mu.Unlock()
+
+	got := make(map[string]string)
+	for len(got) < 2 {
+		res, err := w.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[res.Service.Nodes[0].Address] = res.Action
+	}
+
+	if got["10.0.0.3:9090"] != "create" || got["10.0.0.1:9090"] != "delete" {
+		t.Fatalf("Expected 10.0.0.3 created and 10.0.0.1 deleted, got %v", got)
+	}
+}
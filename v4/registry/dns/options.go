@@ -0,0 +1,62 @@
+package dns
+
+import (
+	"context"
+	"time"
+
+	"go-micro.dev/v4/registry"
+)
+
+type domainKey struct{}
+type nameFormatKey struct{}
+type portKey struct{}
+type minTTLKey struct{}
+type maxTTLKey struct{}
+type pollIntervalKey struct{}
+
+func setRegistryOption(k, v interface{}) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+// Domain sets the domain services are looked up in, e.g. "service.consul".
+// SRV records are queried as _<service>._tcp.<domain>. Defaults to "local".
+func Domain(d string) registry.Option {
+	return setRegistryOption(domainKey{}, d)
+}
+
+// NameFormat sets the name of the SRV records of a service within the
+// domain, the service name replaces %s. Defaults to "_%s._tcp", use "%s"
+// for Consul DNS.
+func NameFormat(f string) registry.Option {
+	return setRegistryOption(nameFormatKey{}, f)
+}
+
+// Port sets the port of the nodes of services without SRV records, whose
+// nodes are then resolved from the A and AAAA records of <service>.<domain>,
+// e.g. for headless Kubernetes services. Without it such services aren't
+// found.
+func Port(p int) registry.Option {
+	return setRegistryOption(portKey{}, p)
+}
+
+// MinTTL sets the minimum time lookups are cached, regardless of the TTL
+// of the records.
+func MinTTL(d time.Duration) registry.Option {
+	return setRegistryOption(minTTLKey{}, d)
+}
+
+// MaxTTL sets the maximum time lookups are cached.
+func MaxTTL(d time.Duration) registry.Option {
+	return setRegistryOption(maxTTLKey{}, d)
+}
+
+// PollInterval sets how often watchers look the services up again.
+// Defaults to DefaultPollInterval.
+func PollInterval(d time.Duration) registry.Option {
+	return setRegistryOption(pollIntervalKey{}, d)
+}
@@ -0,0 +1,52 @@
+package dns
+
+import (
+	"strconv"
+
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/selector"
+
+	"github.com/go-micro/plugins/v4/util/weighted"
+)
+
+// Weighted is a selector strategy which honours the SRV priority and weight of
+// the nodes. Only nodes with the lowest priority are selected and they are
+// picked at random in proportion to their weight.
+func Weighted(services []*registry.Service) selector.Next {
+	var (
+		nodes    []*registry.Node
+		priority = -1
+	)
+
+	for _, service := range services {
+		for _, node := range service.Nodes {
+			p, _ := strconv.Atoi(node.Metadata["priority"])
+
+			// nodes with a lower priority take precedence
+			if priority < 0 || p < priority {
+				priority = p
+				nodes = nil
+			}
+			if p != priority {
+				continue
+			}
+
+			nodes = append(nodes, node)
+		}
+	}
+
+	return weighted.Next(nodes, nodeWeight)
+}
+
+// zeroWeight is the weight of the nodes with an SRV weight of zero. As per
+// RFC 2782 they get a very small chance of selection next to weighted nodes,
+// and are picked evenly if no node is weighted.
+const zeroWeight = 0.01
+
+func nodeWeight(node *registry.Node) float64 {
+	w, _ := strconv.Atoi(node.Metadata["weight"])
+	if w <= 0 {
+		return zeroWeight
+	}
+	return float64(w)
+}
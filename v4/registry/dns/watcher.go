@@ -0,0 +1,145 @@
+package dns
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"go-micro.dev/v4/registry"
+)
+
+type dnsWatcher struct {
+	d       *dnsRegistry
+	service string
+
+	once sync.Once
+	exit chan struct{}
+
+	// last nodes seen of every service by id
+	seen    map[string]map[string]*registry.Node
+	pending []*registry.Result
+}
+
+func newWatcher(d *dnsRegistry, opts ...registry.WatchOption) *dnsWatcher {
+	var wo registry.WatchOptions
+	for _, o := range opts {
+		o(&wo)
+	}
+
+	w := &dnsWatcher{
+		d:       d,
+		service: wo.Service,
+		exit:    make(chan struct{}),
+		seen:    make(map[string]map[string]*registry.Node),
+	}
+
+	// changes are reported from the cached services on
+	d.RLock()
+	for name, entry := range d.cache {
+		if len(w.service) == 0 || name == w.service {
+			w.seen[name] = nodes(entry.services)
+		}
+	}
+	d.RUnlock()
+
+	return w
+}
+
+func nodes(services []*registry.Service) map[string]*registry.Node {
+	m := make(map[string]*registry.Node)
+	for _, s := range services {
+		for _, n := range s.Nodes {
+			m[n.Id] = n
+		}
+	}
+	return m
+}
+
+func (w *dnsWatcher) Next() (*registry.Result, error) {
+	t := time.NewTicker(w.d.poll)
+	defer t.Stop()
+
+	for len(w.pending) == 0 {
+		select {
+		case <-w.exit:
+			return nil, registry.ErrWatcherStopped
+		case <-t.C:
+			w.poll()
+		}
+	}
+
+	r := w.pending[0]
+	w.pending = w.pending[1:]
+	return r, nil
+}
+
+func (w *dnsWatcher) names() []string {
+	if len(w.service) > 0 {
+		return []string{w.service}
+	}
+
+	w.d.RLock()
+	defer w.d.RUnlock()
+
+	names := make([]string, 0, len(w.d.cache))
+	for name := range w.d.cache {
+		names = append(names, name)
+	}
+	return names
+}
+
+// poll looks the services up and queues the nodes created, updated and
+// deleted since the last poll. Services seen for the first time don't
+// report their nodes.
+func (w *dnsWatcher) poll() {
+	for _, name := range w.names() {
+		services, err := w.d.refresh(name)
+		if err != nil && err != registry.ErrNotFound {
+			continue
+		}
+
+		cur := nodes(services)
+		prev, ok := w.seen[name]
+		w.seen[name] = cur
+		if !ok {
+			continue
+		}
+
+		var version string
+		if len(services) > 0 {
+			version = services[0].Version
+		}
+
+		for id, n := range cur {
+			p, ok := prev[id]
+			switch {
+			case !ok:
+				w.queue("create", name, version, n)
+			case p.Address != n.Address || !reflect.DeepEqual(p.Metadata, n.Metadata):
+				w.queue("update", name, version, n)
+			}
+		}
+		for id, n := range prev {
+			if _, ok := cur[id]; !ok {
+				w.queue("delete", name, version, n)
+			}
+		}
+	}
+}
+
+func (w *dnsWatcher) queue(action, name, version string, n *registry.Node) {
+	w.pending = append(w.pending, &registry.Result{
+		Action: action,
+		Service: &registry.Service{
+			Name:    name,
+			Version: version,
+			Nodes:   []*registry.Node{n},
+		},
+	})
+}
+
+func (w *dnsWatcher) Stop() {
+	w.once.Do(func() {
+		close(w.exit)
+	})
+}
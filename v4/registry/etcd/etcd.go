@@ -19,21 +19,25 @@ import (
 	"go-micro.dev/v4/registry"
 	"go-micro.dev/v4/util/cmd"
 	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"go.etcd.io/etcd/client/pkg/v3/transport"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.uber.org/zap"
 )
 
 var (
-	prefix = "/micro/registry/"
+	// DefaultPrefix is the key prefix of the registry.
+	DefaultPrefix = "/micro/registry/"
 )
 
 type etcdRegistry struct {
 	client  *clientv3.Client
 	options registry.Options
+	prefix  string
 
 	sync.RWMutex
-	register map[string]uint64
-	leases   map[string]clientv3.LeaseID
+	register   map[string]uint64
+	leases     map[string]clientv3.LeaseID
+	keepalives map[string]*keepalive
 }
 
 func init() {
@@ -42,9 +46,10 @@ func init() {
 
 func NewRegistry(opts ...registry.Option) registry.Registry {
 	e := &etcdRegistry{
-		options:  registry.Options{},
-		register: make(map[string]uint64),
-		leases:   make(map[string]clientv3.LeaseID),
+		options:    registry.Options{},
+		register:   make(map[string]uint64),
+		leases:     make(map[string]clientv3.LeaseID),
+		keepalives: make(map[string]*keepalive),
 	}
 	username, password := os.Getenv("ETCD_USERNAME"), os.Getenv("ETCD_PASSWORD")
 	if len(username) > 0 && len(password) > 0 {
@@ -59,14 +64,30 @@ func NewRegistry(opts ...registry.Option) registry.Registry {
 }
 
 func configure(e *etcdRegistry, opts ...registry.Option) error {
-	config := clientv3.Config{
-		Endpoints: []string{"127.0.0.1:2379"},
-	}
-
 	for _, o := range opts {
 		o(&e.options)
 	}
 
+	config, err := e.config()
+	if err != nil {
+		return err
+	}
+
+	cli, err := clientv3.New(config)
+	if err != nil {
+		return err
+	}
+	e.client = cli
+	return nil
+}
+
+// config applies the options to the registry and returns the config of its
+// etcd client.
+func (e *etcdRegistry) config() (clientv3.Config, error) {
+	config := clientv3.Config{
+		Endpoints: []string{"127.0.0.1:2379"},
+	}
+
 	if e.options.Timeout == 0 {
 		e.options.Timeout = 5 * time.Second
 	}
@@ -88,6 +109,8 @@ func configure(e *etcdRegistry, opts ...registry.Option) error {
 		config.TLS = tlsConfig
 	}
 
+	e.prefix = DefaultPrefix
+
 	if e.options.Context != nil {
 		u, ok := e.options.Context.Value(authKey{}).(*authCreds)
 		if ok {
@@ -98,6 +121,16 @@ func configure(e *etcdRegistry, opts ...registry.Option) error {
 		if ok && cfg != nil {
 			config.LogConfig = cfg
 		}
+		if files, ok := e.options.Context.Value(tlsFilesKey{}).(*transport.TLSInfo); ok {
+			tlsConfig, err := files.ClientConfig()
+			if err != nil {
+				return config, err
+			}
+			config.TLS = tlsConfig
+		}
+		if p, ok := e.options.Context.Value(prefixKey{}).(string); ok && len(p) > 0 {
+			e.prefix = strings.TrimSuffix(p, "/") + "/"
+		}
 	}
 
 	var cAddrs []string
@@ -121,12 +154,7 @@ func configure(e *etcdRegistry, opts ...registry.Option) error {
 		config.Endpoints = cAddrs
 	}
 
-	cli, err := clientv3.New(config)
-	if err != nil {
-		return err
-	}
-	e.client = cli
-	return nil
+	return config, nil
 }
 
 func encode(s *registry.Service) string {
@@ -140,14 +168,14 @@ func decode(ds []byte) *registry.Service {
 	return s
 }
 
-func nodePath(s, id string) string {
+func (e *etcdRegistry) nodePath(s, id string) string {
 	service := strings.Replace(s, "/", "-", -1)
 	node := strings.Replace(id, "/", "-", -1)
-	return path.Join(prefix, service, node)
+	return path.Join(e.prefix, service, node)
 }
 
-func servicePath(s string) string {
-	return path.Join(prefix, strings.Replace(s, "/", "-", -1))
+func (e *etcdRegistry) servicePath(s string) string {
+	return path.Join(e.prefix, strings.Replace(s, "/", "-", -1))
 }
 
 func (e *etcdRegistry) Init(opts ...registry.Option) error {
@@ -158,7 +186,10 @@ func (e *etcdRegistry) Options() registry.Options {
 	return e.options
 }
 
-func (e *etcdRegistry) registerNode(s *registry.Service, node *registry.Node, opts ...registry.RegisterOption) error {
+// registerNode registers the node. Nodes registered again after their lease
+// was lost pass the keepalive ka, they're only registered while it's
+// current.
+func (e *etcdRegistry) registerNode(s *registry.Service, node *registry.Node, ka *keepalive, opts ...registry.RegisterOption) error {
 	if len(s.Nodes) == 0 {
 		return errors.New("Require at least one node")
 	}
@@ -176,7 +207,7 @@ func (e *etcdRegistry) registerNode(s *registry.Service, node *registry.Node, op
 		defer cancel()
 
 		// look for the existing key
-		rsp, err := e.client.Get(ctx, nodePath(s.Name, node.Id), clientv3.WithSerializable())
+		rsp, err := e.client.Get(ctx, e.nodePath(s.Name, node.Id), clientv3.WithSerializable())
 		if err != nil {
 			return err
 		}
@@ -239,6 +270,9 @@ func (e *etcdRegistry) registerNode(s *registry.Service, node *registry.Node, op
 	// the service is unchanged, skip registering
 	if ok && v == h && !leaseNotFound {
 		log.Logf(logger.TraceLevel, "Service %s node %s unchanged skipping registration", s.Name, node.Id)
+		if leaseID > 0 {
+			e.keepAlive(s, node, leaseID, ka, opts)
+		}
 		return nil
 	}
 
@@ -267,26 +301,58 @@ func (e *etcdRegistry) registerNode(s *registry.Service, node *registry.Node, op
 		}
 	}
 
-	log.Logf(logger.TraceLevel, "Registering %s id %s with lease %v and leaseID %v and ttl %v", service.Name, node.Id, lgr, lgr.ID, options.TTL)
+	log.Logf(logger.TraceLevel, "Registering %s id %s with lease %v and ttl %v", service.Name, node.Id, lgr, options.TTL)
 	// create an entry for the node
+	if err := e.put(ctx, service, h, lgr, ka); err != nil {
+		return err
+	}
+
 	if lgr != nil {
-		_, err = e.client.Put(ctx, nodePath(service.Name, node.Id), encode(service), clientv3.WithLease(lgr.ID))
-	} else {
-		_, err = e.client.Put(ctx, nodePath(service.Name, node.Id), encode(service))
+		e.keepAlive(s, node, lgr.ID, ka, opts)
 	}
-	if err != nil {
+
+	return nil
+}
+
+// errDeregistered is returned when registering a node again whose
+// keepalive was cancelled by deregistering it.
+var errDeregistered = errors.New("deregistered")
+
+// put writes the entry of the service node and saves its hash and lease.
+// Nodes registered again by their keepalive ka are put holding the lock,
+// while ka is current, so deregistering the node meanwhile either cancels
+// registering it or deletes the entry afterwards.
+func (e *etcdRegistry) put(ctx context.Context, service *registry.Service, h uint64, lgr *clientv3.LeaseGrantResponse, ka *keepalive) error {
+	node := service.Nodes[0]
+	key := service.Name + node.Id
+
+	var opts []clientv3.OpOption
+	if lgr != nil {
+		opts = append(opts, clientv3.WithLease(lgr.ID))
+	}
+
+	if ka != nil {
+		e.Lock()
+		defer e.Unlock()
+		if e.keepalives[key] != ka {
+			return errDeregistered
+		}
+	}
+
+	if _, err := e.client.Put(ctx, e.nodePath(service.Name, node.Id), encode(service), opts...); err != nil {
 		return err
 	}
 
-	e.Lock()
+	if ka == nil {
+		e.Lock()
+		defer e.Unlock()
+	}
 	// save our hash of the service
-	e.register[s.Name+node.Id] = h
+	e.register[key] = h
 	// save our leaseID of the service
 	if lgr != nil {
-		e.leases[s.Name+node.Id] = lgr.ID
+		e.leases[key] = lgr.ID
 	}
-	e.Unlock()
-
 	return nil
 }
 
@@ -301,13 +367,18 @@ func (e *etcdRegistry) Deregister(s *registry.Service, opts ...registry.Deregist
 		delete(e.register, s.Name+node.Id)
 		// delete our lease of the service
 		delete(e.leases, s.Name+node.Id)
+		// stop keeping the lease alive
+		if ka, ok := e.keepalives[s.Name+node.Id]; ok {
+			ka.cancel()
+			delete(e.keepalives, s.Name+node.Id)
+		}
 		e.Unlock()
 
 		ctx, cancel := context.WithTimeout(context.Background(), e.options.Timeout)
 		defer cancel()
 
 		e.options.Logger.Logf(logger.TraceLevel, "Deregistering %s id %s", s.Name, node.Id)
-		_, err := e.client.Delete(ctx, nodePath(s.Name, node.Id))
+		_, err := e.client.Delete(ctx, e.nodePath(s.Name, node.Id))
 		if err != nil {
 			return err
 		}
@@ -325,7 +396,7 @@ func (e *etcdRegistry) Register(s *registry.Service, opts ...registry.RegisterOp
 
 	// register each node individually
 	for _, node := range s.Nodes {
-		err := e.registerNode(s, node, opts...)
+		err := e.registerNode(s, node, nil, opts...)
 		if err != nil {
 			gerr = err
 		}
@@ -338,7 +409,7 @@ func (e *etcdRegistry) GetService(name string, opts ...registry.GetOption) ([]*r
 	ctx, cancel := context.WithTimeout(context.Background(), e.options.Timeout)
 	defer cancel()
 
-	rsp, err := e.client.Get(ctx, servicePath(name)+"/", clientv3.WithPrefix(), clientv3.WithSerializable())
+	rsp, err := e.client.Get(ctx, e.servicePath(name)+"/", clientv3.WithPrefix(), clientv3.WithSerializable())
 	if err != nil {
 		return nil, err
 	}
@@ -380,7 +451,7 @@ func (e *etcdRegistry) ListServices(opts ...registry.ListOption) ([]*registry.Se
 	ctx, cancel := context.WithTimeout(context.Background(), e.options.Timeout)
 	defer cancel()
 
-	rsp, err := e.client.Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithSerializable())
+	rsp, err := e.client.Get(ctx, e.prefix, clientv3.WithPrefix(), clientv3.WithSerializable())
 	if err != nil {
 		return nil, err
 	}
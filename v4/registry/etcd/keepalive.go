@@ -0,0 +1,112 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-micro.dev/v4/logger"
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/util/backoff"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// keepalive keeps the lease of a registered node alive.
+type keepalive struct {
+	lease  clientv3.LeaseID
+	cancel context.CancelFunc
+}
+
+// keepAlive starts keeping the lease of the node alive, unless it already is.
+// A lease which expires or is revoked is detected and the node registered
+// again with a new lease. Nodes registered again pass the keepalive prev of
+// the lost lease, the new one is only started while prev is current.
+func (e *etcdRegistry) keepAlive(s *registry.Service, node *registry.Node, leaseID clientv3.LeaseID, prev *keepalive, opts []registry.RegisterOption) {
+	key := s.Name + node.Id
+
+	e.Lock()
+	if prev != nil && e.keepalives[key] != prev {
+		// deregistered meanwhile
+		e.Unlock()
+		return
+	}
+	if ka, ok := e.keepalives[key]; ok {
+		if ka.lease == leaseID {
+			e.Unlock()
+			return
+		}
+		ka.cancel()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ka := &keepalive{lease: leaseID, cancel: cancel}
+	e.keepalives[key] = ka
+	e.Unlock()
+
+	ch, err := e.client.KeepAlive(ctx, leaseID)
+	if err != nil {
+		e.options.Logger.Logf(logger.WarnLevel, "Failed to keep lease %d of %s %s alive: %v", leaseID, s.Name, node.Id, err)
+		e.Lock()
+		// the node may have been deregistered or registered again meanwhile
+		if ka, ok := e.keepalives[key]; ok && ka.lease == leaseID {
+			delete(e.keepalives, key)
+		}
+		e.Unlock()
+		cancel()
+		return
+	}
+
+	go func() {
+		// the channel is closed once the lease is lost or the context cancelled
+		for range ch {
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		e.options.Logger.Logf(logger.WarnLevel, "Lease %d of %s %s lost, registering again", leaseID, s.Name, node.Id)
+		e.forget(key, leaseID)
+		e.reregister(ctx, s, node, ka, opts)
+	}()
+}
+
+// forget removes the lease and hash of the node, if the lease is current, so
+// the next registration creates a new lease. The keepalive stays, so
+// deregistering the node cancels registering it again.
+func (e *etcdRegistry) forget(key string, leaseID clientv3.LeaseID) {
+	e.Lock()
+	defer e.Unlock()
+
+	if e.leases[key] == leaseID {
+		delete(e.leases, key)
+		delete(e.register, key)
+	}
+}
+
+// reregister registers the node until it succeeds or the keepalive ka is
+// cancelled, calling the OnReregister callback with the outcome.
+func (e *etcdRegistry) reregister(ctx context.Context, s *registry.Service, node *registry.Node, ka *keepalive, opts []registry.RegisterOption) {
+	fn := getReregisterFunc(e.options)
+
+	for i := 0; ctx.Err() == nil; i++ {
+		err := e.registerNode(s, node, ka, opts...)
+		if errors.Is(err, errDeregistered) {
+			return
+		}
+		if fn != nil {
+			fn(s, node, err)
+		}
+		if err == nil {
+			return
+		}
+
+		e.options.Logger.Logf(logger.WarnLevel, "Failed to register %s %s again: %v", s.Name, node.Id, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff.Do(i + 1)):
+		}
+	}
+}
@@ -0,0 +1,435 @@
+package etcd
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/registry"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+type fakeEntry struct {
+	value string
+	lease clientv3.LeaseID
+}
+
+// fakeEtcd is the KV and the leases of an etcd in memory. Leases are kept
+// alive until expired.
+type fakeEtcd struct {
+	clientv3.KV
+	clientv3.Lease
+
+	sync.Mutex
+	kvs    map[string]fakeEntry
+	leases map[clientv3.LeaseID]chan *clientv3.LeaseKeepAliveResponse
+	nextID clientv3.LeaseID
+
+	// hooks are called before the operation, e.g. "grant" or "put", to
+	// block it
+	hooks map[string]func()
+}
+
+func newFakeEtcd() *fakeEtcd {
+	return &fakeEtcd{
+		kvs:    make(map[string]fakeEntry),
+		leases: make(map[clientv3.LeaseID]chan *clientv3.LeaseKeepAliveResponse),
+		hooks:  make(map[string]func()),
+	}
+}
+
+func (f *fakeEtcd) hook(op string) {
+	f.Lock()
+	fn := f.hooks[op]
+	f.Unlock()
+	if fn != nil {
+		fn()
+	}
+}
+
+// hold blocks the operation until released, started is closed once it's
+// held up.
+func (f *fakeEtcd) hold(op string) (started, release chan struct{}) {
+	started, release = make(chan struct{}), make(chan struct{})
+	var once sync.Once
+
+	f.Lock()
+	f.hooks[op] = func() {
+		once.Do(func() {
+			close(started)
+			<-release
+		})
+	}
+	f.Unlock()
+	return started, release
+}
+
+func (f *fakeEtcd) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	op := clientv3.OpGet(key, opts...)
+	end := string(op.RangeBytes())
+
+	f.Lock()
+	defer f.Unlock()
+
+	rsp := &clientv3.GetResponse{}
+	for k, e := range f.kvs {
+		if k == key || (len(end) > 0 && k >= key && k < end) {
+			rsp.Kvs = append(rsp.Kvs, &mvccpb.KeyValue{Key: []byte(k), Value: []byte(e.value), Lease: int64(e.lease)})
+		}
+	}
+	return rsp, nil
+}
+
+func (f *fakeEtcd) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	f.hook("put")
+
+	// the lease of an op isn't exported
+	op := clientv3.OpPut(key, val, opts...)
+	lease := clientv3.LeaseID(reflect.ValueOf(op).FieldByName("leaseID").Int())
+
+	f.Lock()
+	defer f.Unlock()
+	f.kvs[key] = fakeEntry{value: val, lease: lease}
+	return &clientv3.PutResponse{}, nil
+}
+
+func (f *fakeEtcd) Delete(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+	f.Lock()
+	defer f.Unlock()
+	delete(f.kvs, key)
+	return &clientv3.DeleteResponse{}, nil
+}
+
+func (f *fakeEtcd) Grant(ctx context.Context, ttl int64) (*clientv3.LeaseGrantResponse, error) {
+	f.hook("grant")
+
+	f.Lock()
+	defer f.Unlock()
+	f.nextID++
+	f.leases[f.nextID] = nil
+	return &clientv3.LeaseGrantResponse{ID: f.nextID, TTL: ttl}, nil
+}
+
+func (f *fakeEtcd) KeepAlive(ctx context.Context, id clientv3.LeaseID) (<-chan *clientv3.LeaseKeepAliveResponse, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	if _, ok := f.leases[id]; !ok {
+		return nil, rpctypes.ErrLeaseNotFound
+	}
+	ch := make(chan *clientv3.LeaseKeepAliveResponse)
+	f.leases[id] = ch
+
+	go func() {
+		<-ctx.Done()
+		f.Lock()
+		defer f.Unlock()
+		if f.leases[id] == ch {
+			f.leases[id] = nil
+			close(ch)
+		}
+	}()
+	return ch, nil
+}
+
+func (f *fakeEtcd) KeepAliveOnce(ctx context.Context, id clientv3.LeaseID) (*clientv3.LeaseKeepAliveResponse, error) {
+	f.Lock()
+	defer f.Unlock()
+	if _, ok := f.leases[id]; !ok {
+		return nil, rpctypes.ErrLeaseNotFound
+	}
+	return &clientv3.LeaseKeepAliveResponse{ID: id}, nil
+}
+
+// expire expires the lease, deleting its keys.
+func (f *fakeEtcd) expire(id clientv3.LeaseID) {
+	f.Lock()
+	defer f.Unlock()
+
+	if ch := f.leases[id]; ch != nil {
+		close(ch)
+	}
+	delete(f.leases, id)
+	for k, e := range f.kvs {
+		if e.lease == id {
+			delete(f.kvs, k)
+		}
+	}
+}
+
+func (f *fakeEtcd) entry(key string) (fakeEntry, bool) {
+	f.Lock()
+	defer f.Unlock()
+	e, ok := f.kvs[key]
+	return e, ok
+}
+
+func newTestRegistry(t *testing.T, f *fakeEtcd, opts ...registry.Option) *etcdRegistry {
+	t.Helper()
+
+	e := &etcdRegistry{
+		register:   make(map[string]uint64),
+		leases:     make(map[string]clientv3.LeaseID),
+		keepalives: make(map[string]*keepalive),
+	}
+	for _, o := range opts {
+		o(&e.options)
+	}
+	if _, err := e.config(); err != nil {
+		t.Fatal(err)
+	}
+	e.client = &clientv3.Client{KV: f, Lease: f}
+	return e
+}
+
+func testService() *registry.Service {
+	return &registry.Service{
+		Name:    "billing",
+		Version: "1.0.0",
+		Nodes:   []*registry.Node{{Id: "billing-1", Address: "10.0.0.1:8080"}},
+	}
+}
+
+// waitFor polls until the condition holds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestReregister(t *testing.T) {
+	f := newFakeEtcd()
+
+	type outcome struct {
+		node string
+		err  error
+	}
+	reregistered := make(chan outcome, 1)
+	e := newTestRegistry(t, f, OnReregister(func(s *registry.Service, node *registry.Node, err error) {
+		reregistered <- outcome{node.Id, err}
+	}))
+
+	s := testService()
+	if err := e.Register(s, registry.RegisterTTL(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	key := e.nodePath(s.Name, s.Nodes[0].Id)
+	first, ok := f.entry(key)
+	if !ok || first.lease == 0 {
+		t.Fatalf("Expected the node to be registered with a lease, got %+v", first)
+	}
+
+	// the lease is lost, the node is registered again with a new one
+	f.expire(first.lease)
+
+	select {
+	case o := <-reregistered:
+		if o.node != "billing-1" || o.err != nil {
+			t.Fatalf("Expected billing-1 to be registered again, got %+v", o)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the node to be registered again")
+	}
+
+	second, ok := f.entry(key)
+	if !ok || second.lease == 0 || second.lease == first.lease {
+		t.Fatalf("Expected the node with a new lease, got %+v", second)
+	}
+
+	e.RLock()
+	lease, ka := e.leases[s.Name+s.Nodes[0].Id], e.keepalives[s.Name+s.Nodes[0].Id]
+	e.RUnlock()
+	if lease != second.lease || ka == nil || ka.lease != second.lease {
+		t.Fatalf("Expected the new lease to be kept alive, got %d %+v", lease, ka)
+	}
+
+	if err := e.Deregister(s); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.entry(key); ok {
+		t.Fatal("Expected the node to be deregistered")
+	}
+}
+
+func TestForget(t *testing.T) {
+	e := newTestRegistry(t, newFakeEtcd())
+	e.leases["billingbilling-1"] = 2
+	e.register["billingbilling-1"] = 1
+
+	// an old lease leaves a newer registration alone
+	e.forget("billingbilling-1", 1)
+	if _, ok := e.leases["billingbilling-1"]; !ok {
+		t.Fatal("Expected the current lease to be kept")
+	}
+
+	e.forget("billingbilling-1", 2)
+	if len(e.leases) != 0 || len(e.register) != 0 {
+		t.Fatalf("Expected the lease and hash to be forgotten, got %v %v", e.leases, e.register)
+	}
+}
+
+func TestDeregisterDuringReregister(t *testing.T) {
+	// the registration again is held up before it checks the node is still
+	// registered, or while putting it
+	for _, op := range []string{"grant", "put"} {
+		t.Run(op, func(t *testing.T) {
+			f := newFakeEtcd()
+			e := newTestRegistry(t, f, OnReregister(func(s *registry.Service, node *registry.Node, err error) {
+				if op == "grant" {
+					t.Errorf("Expected the deregistered node not to be registered again, got %v", err)
+				}
+			}))
+
+			s := testService()
+			if err := e.Register(s, registry.RegisterTTL(time.Minute)); err != nil {
+				t.Fatal(err)
+			}
+			key := e.nodePath(s.Name, s.Nodes[0].Id)
+			lost, _ := f.entry(key)
+
+			started, release := f.hold(op)
+			f.expire(lost.lease)
+			<-started
+
+			deregistered := make(chan error)
+			go func() { deregistered <- e.Deregister(s) }()
+
+			if op == "put" {
+				// the deregistration waits for the put, and deletes the
+				// node after it
+				select {
+				case <-deregistered:
+					t.Fatal("Expected the deregistration to wait for the put")
+				case <-time.After(50 * time.Millisecond):
+				}
+				close(release)
+				if err := <-deregistered; err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				if err := <-deregistered; err != nil {
+					t.Fatal(err)
+				}
+				close(release)
+			}
+
+			// let the registration again finish
+			time.Sleep(50 * time.Millisecond)
+			if entry, ok := f.entry(key); ok {
+				t.Fatalf("Expected the node to stay deregistered, got %+v", entry)
+			}
+			e.RLock()
+			defer e.RUnlock()
+			if len(e.keepalives) != 0 || len(e.leases) != 0 {
+				t.Fatalf("Expected no keepalives, got %v %v", e.keepalives, e.leases)
+			}
+		})
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	f := newFakeEtcd()
+	e := newTestRegistry(t, f, Prefix("/staging/registry"))
+
+	if err := e.Register(testService()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.entry("/staging/registry/billing/billing-1"); !ok {
+		t.Fatalf("Expected the node under the prefix, got %v", f.kvs)
+	}
+
+	services, err := e.GetService("billing")
+	if err != nil || len(services) != 1 || len(services[0].Nodes) != 1 {
+		t.Fatalf("Expected the service under the prefix, got %+v %v", services, err)
+	}
+
+	// other prefixes don't see it
+	other := newTestRegistry(t, f)
+	if _, err := other.GetService("billing"); err != registry.ErrNotFound {
+		t.Fatalf("Expected the service not to be found under the default prefix, got %v", err)
+	}
+	list, err := other.ListServices()
+	if err != nil || len(list) != 0 {
+		t.Fatalf("Expected no services under the default prefix, got %+v %v", list, err)
+	}
+}
+
+func TestTLSFiles(t *testing.T) {
+	e := newTestRegistry(t, newFakeEtcd())
+	TLSFiles("missing.crt", "missing.key", "missing-ca.crt")(&e.options)
+	if _, err := e.config(); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Fatalf("Expected an error for the missing files, got %v", err)
+	}
+
+	certFile, keyFile := writeCert(t)
+	e = newTestRegistry(t, newFakeEtcd(), TLSFiles(certFile, keyFile, certFile))
+	config, err := e.config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.TLS == nil || config.TLS.GetClientCertificate == nil || config.TLS.RootCAs == nil {
+		t.Fatalf("Expected the client certificate and CA, got %+v", config.TLS)
+	}
+	if cert, err := config.TLS.GetClientCertificate(nil); err != nil || len(cert.Certificate) != 1 {
+		t.Fatalf("Expected the client certificate, got %v", err)
+	}
+}
+
+// writeCert writes a self-signed certificate and its key.
+func writeCert(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "etcd"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"go-micro.dev/v4/registry"
+	"go.etcd.io/etcd/client/pkg/v3/transport"
 	"go.uber.org/zap"
 )
 
@@ -35,3 +36,58 @@ func LogConfig(config *zap.Config) registry.Option {
 		o.Context = context.WithValue(o.Context, logConfigKey{}, config)
 	}
 }
+
+type reregisterKey struct{}
+
+// ReregisterFunc is called when a node whose lease was lost has been
+// registered again, or failed to.
+type ReregisterFunc func(s *registry.Service, node *registry.Node, err error)
+
+// OnReregister sets the func called when a node is registered again after
+// its lease expired or was revoked.
+func OnReregister(fn ReregisterFunc) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, reregisterKey{}, fn)
+	}
+}
+
+func getReregisterFunc(o registry.Options) ReregisterFunc {
+	if o.Context == nil {
+		return nil
+	}
+	fn, _ := o.Context.Value(reregisterKey{}).(ReregisterFunc)
+	return fn
+}
+
+type tlsFilesKey struct{}
+
+type prefixKey struct{}
+
+// TLSFiles sets the client certificate, key and trusted CA files used to
+// connect to etcd, e.g. for client certificate auth.
+func TLSFiles(certFile, keyFile, caFile string) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, tlsFilesKey{}, &transport.TLSInfo{
+			CertFile:      certFile,
+			KeyFile:       keyFile,
+			TrustedCAFile: caFile,
+		})
+	}
+}
+
+// Prefix sets the key prefix of the registry, so several environments can
+// share an etcd cluster. Defaults to /micro/registry/.
+func Prefix(p string) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, prefixKey{}, p)
+	}
+}
@@ -0,0 +1,161 @@
+package etcd
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"go-micro.dev/v4/logger"
+	"go-micro.dev/v4/registry"
+	"google.golang.org/grpc/resolver"
+)
+
+// ResolverScheme is the scheme of the gRPC targets the resolver builder
+// resolves, e.g. micro:///helloworld.
+const ResolverScheme = "micro"
+
+// resolverRetry is how long the resolver waits to watch again after the
+// watch failed.
+var resolverRetry = 5 * time.Second
+
+type resolverBuilder struct {
+	registry registry.Registry
+}
+
+// NewResolverBuilder returns a gRPC resolver builder resolving the services
+// registered in etcd, so plain grpc-go clients can call go-micro services:
+//
+//	conn, err := grpc.Dial("micro:///helloworld", grpc.WithResolvers(etcd.NewResolverBuilder()))
+//
+// Only nodes of services served with the grpc server are resolved. The
+// options are those of NewRegistry.
+func NewResolverBuilder(opts ...registry.Option) resolver.Builder {
+	return &resolverBuilder{registry: NewRegistry(opts...)}
+}
+
+func (b *resolverBuilder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	r := &grpcResolver{
+		registry: b.registry,
+		service:  strings.TrimPrefix(target.Endpoint, "/"),
+		cc:       cc,
+		ctx:      ctx,
+		cancel:   cancel,
+		refresh:  make(chan struct{}, 1),
+	}
+
+	if err := r.resolve(); err != nil {
+		cc.ReportError(err)
+	}
+
+	r.wg.Add(2)
+	go r.watch()
+	go r.run()
+
+	return r, nil
+}
+
+func (b *resolverBuilder) Scheme() string {
+	return ResolverScheme
+}
+
+type grpcResolver struct {
+	registry registry.Registry
+	service  string
+	cc       resolver.ClientConn
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	refresh chan struct{}
+	wg      sync.WaitGroup
+}
+
+func (r *grpcResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.refresh <- struct{}{}:
+	default:
+	}
+}
+
+func (r *grpcResolver) Close() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+func (r *grpcResolver) run() {
+	defer r.wg.Done()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-r.refresh:
+			if err := r.resolve(); err != nil {
+				r.cc.ReportError(err)
+			}
+		}
+	}
+}
+
+// watch resolves the service again on every change of it.
+func (r *grpcResolver) watch() {
+	defer r.wg.Done()
+
+	for {
+		w, err := r.registry.Watch(registry.WatchService(r.service))
+		if err == nil {
+			stop := make(chan struct{})
+			go func() {
+				select {
+				case <-r.ctx.Done():
+				case <-stop:
+				}
+				w.Stop()
+			}()
+
+			for {
+				if _, err = w.Next(); err != nil {
+					break
+				}
+				r.ResolveNow(resolver.ResolveNowOptions{})
+			}
+			close(stop)
+		}
+
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-time.After(resolverRetry):
+		}
+
+		logger.Logf(logger.WarnLevel, "etcd resolver: watching %s: %v", r.service, err)
+		// changes may have been missed
+		r.ResolveNow(resolver.ResolveNowOptions{})
+	}
+}
+
+func (r *grpcResolver) resolve() error {
+	services, err := r.registry.GetService(r.service)
+	if err != nil {
+		return err
+	}
+
+	var addrs []resolver.Address
+	seen := make(map[string]bool)
+	for _, s := range services {
+		for _, n := range s.Nodes {
+			if p, ok := n.Metadata["protocol"]; ok && p != "grpc" {
+				continue
+			}
+			if seen[n.Address] {
+				continue
+			}
+			seen[n.Address] = true
+			addrs = append(addrs, resolver.Address{Addr: n.Address, ServerName: r.service})
+		}
+	}
+
+	return r.cc.UpdateState(resolver.State{Addresses: addrs})
+}
@@ -0,0 +1,148 @@
+package etcd
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/registry"
+	"google.golang.org/grpc/resolver"
+)
+
+type fakeClientConn struct {
+	resolver.ClientConn
+
+	states chan resolver.State
+	errs   chan error
+}
+
+func newFakeClientConn() *fakeClientConn {
+	return &fakeClientConn{
+		states: make(chan resolver.State, 16),
+		errs:   make(chan error, 16),
+	}
+}
+
+func (c *fakeClientConn) UpdateState(s resolver.State) error {
+	c.states <- s
+	return nil
+}
+
+func (c *fakeClientConn) ReportError(err error) {
+	c.errs <- err
+}
+
+func (c *fakeClientConn) next(t *testing.T) []string {
+	t.Helper()
+
+	select {
+	case s := <-c.states:
+		var addrs []string
+		for _, a := range s.Addresses {
+			addrs = append(addrs, a.Addr)
+		}
+		sort.Strings(addrs)
+		return addrs
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a state update")
+		return nil
+	}
+}
+
+// watchedRegistry tells once the resolver watches it.
+type watchedRegistry struct {
+	registry.Registry
+
+	watching chan struct{}
+}
+
+func (r *watchedRegistry) Watch(opts ...registry.WatchOption) (registry.Watcher, error) {
+	w, err := r.Registry.Watch(opts...)
+	close(r.watching)
+	return w, err
+}
+
+func TestResolver(t *testing.T) {
+	r := &watchedRegistry{Registry: registry.NewMemoryRegistry(), watching: make(chan struct{})}
+	b := &resolverBuilder{registry: r}
+	if b.Scheme() != ResolverScheme {
+		t.Fatalf("Expected scheme %s, got %s", ResolverScheme, b.Scheme())
+	}
+
+	register := func(version string, nodes ...*registry.Node) {
+		t.Helper()
+		if err := r.Register(&registry.Service{Name: "billing", Version: version, Nodes: nodes}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	grpcNode := func(id, addr string) *registry.Node {
+		return &registry.Node{Id: id, Address: addr, Metadata: map[string]string{"protocol": "grpc"}}
+	}
+
+	register("1.0.0",
+		grpcNode("billing-1", "10.0.0.1:8080"),
+		&registry.Node{Id: "billing-2", Address: "10.0.0.2:8080", Metadata: map[string]string{"protocol": "http"}},
+		&registry.Node{Id: "billing-3", Address: "10.0.0.3:8080"},
+	)
+	// the same address in another version is resolved once
+	register("1.1.0", grpcNode("billing-4", "10.0.0.1:8080"))
+
+	cc := newFakeClientConn()
+	res, err := b.Build(resolver.Target{Endpoint: "/billing"}, cc, resolver.BuildOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if addrs := cc.next(t); len(addrs) != 2 || addrs[0] != "10.0.0.1:8080" || addrs[1] != "10.0.0.3:8080" {
+		t.Fatalf("Expected the grpc nodes once, got %v", addrs)
+	}
+
+	// a change of the service is resolved again
+	<-r.watching
+	register("1.0.0", grpcNode("billing-5", "10.0.0.5:8080"))
+	deadline := time.After(5 * time.Second)
+	for found := false; !found; {
+		select {
+		case <-deadline:
+			t.Fatal("Expected the new node to be resolved")
+		default:
+		}
+		for _, addr := range cc.next(t) {
+			found = found || addr == "10.0.0.5:8080"
+		}
+	}
+
+	res.ResolveNow(resolver.ResolveNowOptions{})
+	cc.next(t)
+
+	done := make(chan struct{})
+	go func() {
+		res.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the resolver to close")
+	}
+}
+
+func TestResolverNotFound(t *testing.T) {
+	b := &resolverBuilder{registry: registry.NewMemoryRegistry()}
+
+	cc := newFakeClientConn()
+	res, err := b.Build(resolver.Target{Endpoint: "/billing"}, cc, resolver.BuildOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+
+	select {
+	case err := <-cc.errs:
+		if err != registry.ErrNotFound {
+			t.Fatalf("Expected the service not to be found, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the error to be reported")
+	}
+}
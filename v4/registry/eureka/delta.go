@@ -0,0 +1,415 @@
+package eureka
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hudl/fargo"
+	log "go-micro.dev/v4/logger"
+	"go-micro.dev/v4/registry"
+)
+
+// Delta action types of instances.
+const (
+	actionAdded    = "ADDED"
+	actionModified = "MODIFIED"
+	actionDeleted  = "DELETED"
+)
+
+// instanceChange is an instance added, modified or deleted since the last
+// delta.
+type instanceChange struct {
+	action   string
+	instance *fargo.Instance
+}
+
+// appsDelta are the recent changes to the registry and the hash code of the
+// registry after them.
+type appsDelta struct {
+	hashcode string
+	changes  map[string][]instanceChange
+}
+
+type deltaSource interface {
+	GetAppsDelta() (*appsDelta, error)
+}
+
+// httpDelta fetches deltas from the apps/delta endpoint, which fargo doesn't
+// support.
+type httpDelta struct {
+	addrs []string
+}
+
+func (h *httpDelta) GetAppsDelta() (*appsDelta, error) {
+	err := errors.New("no eureka servers")
+	for _, addr := range h.addrs {
+		var d *appsDelta
+		if d, err = h.get(strings.TrimSuffix(addr, "/") + "/apps/delta"); err == nil {
+			return d, nil
+		}
+	}
+	return nil, err
+}
+
+func (h *httpDelta) get(url string) (*appsDelta, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/xml")
+
+	rsp, err := fargo.HttpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+
+	b, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if rsp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching delta from %s: %s", url, rsp.Status)
+	}
+
+	return parseDelta(b)
+}
+
+// parseDelta parses the XML delta. The action types of the instances are
+// read in a second pass, as fargo's instances don't decode them.
+func parseDelta(b []byte) (*appsDelta, error) {
+	var r fargo.GetAppsResponse
+	if err := xml.Unmarshal(b, &r); err != nil {
+		return nil, err
+	}
+
+	var actions struct {
+		Applications []struct {
+			Instances []struct {
+				ActionType string `xml:"actionType"`
+			} `xml:"instance"`
+		} `xml:"application"`
+	}
+	if err := xml.NewDecoder(bytes.NewReader(b)).Decode(&actions); err != nil {
+		return nil, err
+	}
+
+	d := &appsDelta{
+		hashcode: r.AppsHashcode,
+		changes:  make(map[string][]instanceChange),
+	}
+
+	for i, app := range r.Applications {
+		app.ParseAllMetadata()
+		for j, instance := range app.Instances {
+			action := actionModified
+			if i < len(actions.Applications) && j < len(actions.Applications[i].Instances) {
+				action = actions.Applications[i].Instances[j].ActionType
+			}
+			name := strings.ToUpper(app.Name)
+			d.changes[name] = append(d.changes[name], instanceChange{action: action, instance: instance})
+		}
+	}
+
+	return d, nil
+}
+
+// hashcode is the reconcile hash code of the applications, the count of
+// instances by status ordered by status, e.g. "DOWN_1_UP_3_".
+func hashcode(apps map[string]*fargo.Application) string {
+	counts := make(map[string]int)
+	for _, app := range apps {
+		for _, instance := range app.Instances {
+			counts[string(instance.Status)]++
+		}
+	}
+
+	statuses := make([]string, 0, len(counts))
+	for status := range counts {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+
+	var b strings.Builder
+	for _, status := range statuses {
+		fmt.Fprintf(&b, "%s_%d_", status, counts[status])
+	}
+	return b.String()
+}
+
+// appsCache is the local copy of the registry kept up to date with deltas.
+type appsCache struct {
+	conn     fargoConnection
+	delta    deltaSource
+	interval time.Duration
+	exit     chan bool
+
+	sync.RWMutex
+	apps     map[string]*fargo.Application
+	watchers map[*cacheWatcher]bool
+}
+
+func newAppsCache(conn fargoConnection, delta deltaSource, interval time.Duration) *appsCache {
+	return &appsCache{
+		conn:     conn,
+		delta:    delta,
+		interval: interval,
+		exit:     make(chan bool),
+		apps:     make(map[string]*fargo.Application),
+		watchers: make(map[*cacheWatcher]bool),
+	}
+}
+
+func (c *appsCache) run() {
+	if err := c.refresh(); err != nil {
+		log.Errorf("Eureka registry fetch failed: %v", err)
+	}
+
+	t := time.NewTicker(c.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-c.exit:
+			return
+		case <-t.C:
+			if err := c.update(); err != nil {
+				log.Errorf("Eureka registry fetch failed: %v", err)
+			}
+		}
+	}
+}
+
+func (c *appsCache) stop() {
+	close(c.exit)
+}
+
+// refresh fetches every application and notifies watchers of the
+// differences to the local copy.
+func (c *appsCache) refresh() error {
+	fetched, err := c.conn.GetApps()
+	if err != nil {
+		return err
+	}
+
+	apps := make(map[string]*fargo.Application, len(fetched))
+	for name, app := range fetched {
+		apps[strings.ToUpper(name)] = app
+	}
+
+	c.Lock()
+	old := c.apps
+	c.apps = apps
+	c.Unlock()
+
+	for name, app := range apps {
+		var changes []instanceChange
+		known := instances(old[name])
+		for _, instance := range app.Instances {
+			if prev, ok := known[instance.Id()]; ok && sameInstance(prev, instance) {
+				continue
+			}
+			changes = append(changes, instanceChange{action: actionModified, instance: instance})
+		}
+		c.notify(name, changes)
+	}
+
+	for name, app := range old {
+		current := instances(apps[name])
+		var changes []instanceChange
+		for _, instance := range app.Instances {
+			if _, ok := current[instance.Id()]; !ok {
+				changes = append(changes, instanceChange{action: actionDeleted, instance: instance})
+			}
+		}
+		c.notify(name, changes)
+	}
+
+	return nil
+}
+
+// update applies the delta to the local copy, falling back to a full fetch
+// if the hash codes differ afterwards.
+func (c *appsCache) update() error {
+	d, err := c.delta.GetAppsDelta()
+	if err != nil {
+		return err
+	}
+
+	c.Lock()
+	for name, changes := range d.changes {
+		app, ok := c.apps[name]
+		if !ok {
+			app = &fargo.Application{Name: name}
+		}
+
+		// copy the instances, the old application may still be read
+		updated := &fargo.Application{Name: app.Name}
+		index := make(map[string]*fargo.Instance)
+		for _, instance := range app.Instances {
+			index[instance.Id()] = instance
+		}
+		for _, change := range changes {
+			if change.action == actionDeleted {
+				delete(index, change.instance.Id())
+			} else {
+				index[change.instance.Id()] = change.instance
+			}
+		}
+		for _, instance := range index {
+			updated.Instances = append(updated.Instances, instance)
+		}
+
+		if len(updated.Instances) == 0 {
+			delete(c.apps, name)
+		} else {
+			c.apps[name] = updated
+		}
+	}
+	hash := hashcode(c.apps)
+	c.Unlock()
+
+	if hash != d.hashcode {
+		log.Debugf("Eureka registry hash code %s differs from %s, fetching full registry", hash, d.hashcode)
+		return c.refresh()
+	}
+
+	for name, changes := range d.changes {
+		c.notify(name, changes)
+	}
+
+	return nil
+}
+
+func instances(app *fargo.Application) map[string]*fargo.Instance {
+	m := make(map[string]*fargo.Instance)
+	if app == nil {
+		return m
+	}
+	for _, instance := range app.Instances {
+		m[instance.Id()] = instance
+	}
+	return m
+}
+
+func sameInstance(a, b *fargo.Instance) bool {
+	return a.Status == b.Status &&
+		a.IPAddr == b.IPAddr &&
+		a.Port == b.Port &&
+		bytes.Equal(a.Metadata.Raw, b.Metadata.Raw)
+}
+
+func (c *appsCache) getApp(name string) (*fargo.Application, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	app, ok := c.apps[strings.ToUpper(name)]
+	return app, ok
+}
+
+func (c *appsCache) listApps() []*fargo.Application {
+	c.RLock()
+	defer c.RUnlock()
+	apps := make([]*fargo.Application, 0, len(c.apps))
+	for _, app := range c.apps {
+		apps = append(apps, app)
+	}
+	return apps
+}
+
+func (c *appsCache) notify(name string, changes []instanceChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	c.RLock()
+	watchers := make([]*cacheWatcher, 0, len(c.watchers))
+	for w := range c.watchers {
+		watchers = append(watchers, w)
+	}
+	c.RUnlock()
+
+	for _, change := range changes {
+		action := "update"
+		switch {
+		case change.action == actionDeleted:
+			action = "delete"
+		case change.instance.Status != fargo.UP:
+			action = "delete"
+		case change.action == actionAdded:
+			action = "create"
+		}
+
+		service := appToService(&fargo.Application{
+			Name:      name,
+			Instances: []*fargo.Instance{change.instance},
+		})
+		if len(service) == 0 {
+			continue
+		}
+
+		for _, w := range watchers {
+			if len(w.service) > 0 && w.service != service[0].Name {
+				continue
+			}
+			select {
+			case w.results <- &registry.Result{Action: action, Service: service[0]}:
+			case <-w.exit:
+			}
+		}
+	}
+}
+
+func (c *appsCache) watch(opts ...registry.WatchOption) *cacheWatcher {
+	var wo registry.WatchOptions
+	for _, o := range opts {
+		o(&wo)
+	}
+
+	w := &cacheWatcher{
+		cache:   c,
+		service: strings.ToLower(wo.Service),
+		exit:    make(chan bool),
+		results: make(chan *registry.Result, 64),
+	}
+
+	c.Lock()
+	c.watchers[w] = true
+	c.Unlock()
+
+	return w
+}
+
+// cacheWatcher watches the changes to the local copy of the registry.
+type cacheWatcher struct {
+	cache   *appsCache
+	service string
+	exit    chan bool
+	results chan *registry.Result
+	once    sync.Once
+}
+
+func (w *cacheWatcher) Next() (*registry.Result, error) {
+	select {
+	case <-w.exit:
+		return nil, errors.New("watcher stopped")
+	case r := <-w.results:
+		return r, nil
+	}
+}
+
+func (w *cacheWatcher) Stop() {
+	w.once.Do(func() {
+		w.cache.Lock()
+		delete(w.cache.watchers, w)
+		w.cache.Unlock()
+		close(w.exit)
+	})
+}
@@ -0,0 +1,189 @@
+package eureka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-micro/plugins/v4/registry/eureka/mock"
+	"github.com/hudl/fargo"
+	"go-micro.dev/v4/registry"
+)
+
+const testDelta = `<applications>
+  <versions__delta>2</versions__delta>
+  <apps__hashcode>UP_1_</apps__hashcode>
+  <application>
+    <name>FOO</name>
+    <instance>
+      <instanceId>foo-2</instanceId>
+      <hostName>10.0.0.2</hostName>
+      <app>FOO</app>
+      <ipAddr>10.0.0.2</ipAddr>
+      <status>UP</status>
+      <port enabled="true">8080</port>
+      <metadata>
+        <version>1.0.0</version>
+        <zone>eu-west-1a</zone>
+      </metadata>
+      <actionType>ADDED</actionType>
+    </instance>
+    <instance>
+      <instanceId>foo-1</instanceId>
+      <hostName>10.0.0.1</hostName>
+      <app>FOO</app>
+      <ipAddr>10.0.0.1</ipAddr>
+      <status>UP</status>
+      <port enabled="true">8080</port>
+      <metadata>
+        <version>1.0.0</version>
+      </metadata>
+      <actionType>DELETED</actionType>
+    </instance>
+  </application>
+</applications>`
+
+type testDeltaSource struct {
+	delta *appsDelta
+}
+
+func (t *testDeltaSource) GetAppsDelta() (*appsDelta, error) {
+	return t.delta, nil
+}
+
+func testApps() map[string]*fargo.Application {
+	instance := &fargo.Instance{InstanceId: "foo-1", App: "FOO", IPAddr: "10.0.0.1", Port: 8080, Status: fargo.UP}
+	instance.SetMetadataString("version", "1.0.0")
+	return map[string]*fargo.Application{
+		"FOO": {Name: "FOO", Instances: []*fargo.Instance{instance}},
+	}
+}
+
+func TestParseDelta(t *testing.T) {
+	d, err := parseDelta([]byte(testDelta))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d.hashcode != "UP_1_" {
+		t.Errorf("Expected hash code UP_1_, got %s", d.hashcode)
+	}
+
+	changes := d.changes["FOO"]
+	if len(changes) != 2 {
+		t.Fatalf("Expected 2 changes, got %d", len(changes))
+	}
+	if changes[0].action != actionAdded || changes[0].instance.Id() != "foo-2" {
+		t.Errorf("Unexpected first change %s %s", changes[0].action, changes[0].instance.Id())
+	}
+	if changes[1].action != actionDeleted || changes[1].instance.Id() != "foo-1" {
+		t.Errorf("Unexpected second change %s %s", changes[1].action, changes[1].instance.Id())
+	}
+	if z := instanceZone(changes[0].instance); z != "eu-west-1a" {
+		t.Errorf("Expected zone eu-west-1a, got %q", z)
+	}
+}
+
+func TestHashcode(t *testing.T) {
+	apps := testApps()
+	apps["BAR"] = &fargo.Application{Name: "BAR", Instances: []*fargo.Instance{
+		{Status: fargo.UP}, {Status: fargo.DOWN},
+	}}
+
+	if h := hashcode(apps); h != "DOWN_1_UP_2_" {
+		t.Errorf("Expected hash code DOWN_1_UP_2_, got %s", h)
+	}
+}
+
+func TestCacheDelta(t *testing.T) {
+	d, err := parseDelta([]byte(testDelta))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn := new(mock.FargoConnection)
+	conn.GetAppsReturns(testApps(), nil)
+
+	c := newAppsCache(conn, &testDeltaSource{delta: d}, time.Minute)
+	if err := c.refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	w := c.watch(registry.WatchService("foo"))
+	defer w.Stop()
+
+	if err := c.update(); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := conn.GetAppsCallCount(); n != 1 {
+		t.Errorf("Expected no full fetch after a matching delta, got %d fetches", n)
+	}
+
+	app, ok := c.getApp("foo")
+	if !ok || len(app.Instances) != 1 || app.Instances[0].Id() != "foo-2" {
+		t.Fatalf("Expected foo-2 to replace foo-1, got %+v", app)
+	}
+
+	for _, action := range []string{"create", "delete"} {
+		r, err := w.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Action != action {
+			t.Errorf("Expected action %s, got %s", action, r.Action)
+		}
+	}
+}
+
+func TestCacheHashMismatch(t *testing.T) {
+	d, err := parseDelta([]byte(testDelta))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.hashcode = "UP_2_"
+
+	conn := new(mock.FargoConnection)
+	conn.GetAppsReturns(testApps(), nil)
+
+	c := newAppsCache(conn, &testDeltaSource{delta: d}, time.Minute)
+	if err := c.refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.update(); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := conn.GetAppsCallCount(); n != 2 {
+		t.Errorf("Expected a full fetch after a mismatching delta, got %d fetches", n)
+	}
+
+	app, ok := c.getApp("foo")
+	if !ok || len(app.Instances) != 1 || app.Instances[0].Id() != "foo-1" {
+		t.Fatalf("Expected the full registry to be restored, got %+v", app)
+	}
+}
+
+func TestPreferZone(t *testing.T) {
+	services := []*registry.Service{{
+		Name: "foo",
+		Nodes: []*registry.Node{
+			{Id: "a", Metadata: map[string]string{MetadataZone: "a"}},
+			{Id: "b", Metadata: map[string]string{MetadataZone: "b"}},
+		},
+	}}
+
+	next := PreferZone("b")(services)
+	for i := 0; i < 10; i++ {
+		node, err := next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if node.Id != "b" {
+			t.Fatalf("Expected node of zone b, got %s", node.Id)
+		}
+	}
+
+	if _, err := PreferZone("c")(services)(); err != nil {
+		t.Errorf("Expected fallback to other zones, got %v", err)
+	}
+}
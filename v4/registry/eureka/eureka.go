@@ -8,6 +8,7 @@ package eureka
 import (
 	"context"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/hudl/fargo"
@@ -27,8 +28,13 @@ type fargoConnection interface {
 }
 
 type eurekaRegistry struct {
-	conn fargoConnection
-	opts registry.Options
+	conn  fargoConnection
+	opts  registry.Options
+	cache *appsCache
+
+	sync.Mutex
+	// heartbeats of the registered instances
+	heartbeats map[string]chan bool
 }
 
 func init() {
@@ -60,6 +66,16 @@ func configure(e *eurekaRegistry, opts ...registry.Option) error {
 	conn := fargo.NewConn(cAddrs...)
 	conn.PollInterval = time.Second * 5
 	e.conn = &conn
+
+	if e.cache != nil {
+		e.cache.stop()
+		e.cache = nil
+	}
+	if interval := deltaInterval(e.opts); interval > 0 {
+		e.cache = newAppsCache(e.conn, &httpDelta{addrs: cAddrs}, interval)
+		go e.cache.run()
+	}
+
 	return nil
 }
 
@@ -68,6 +84,7 @@ func newRegistry(opts ...registry.Option) registry.Registry {
 		opts: registry.Options{
 			Context: context.Background(),
 		},
+		heartbeats: make(map[string]chan bool),
 	}
 	configure(e, opts...)
 	return e
@@ -82,16 +99,32 @@ func (e *eurekaRegistry) Options() registry.Options {
 }
 
 func (e *eurekaRegistry) Register(s *registry.Service, opts ...registry.RegisterOption) error {
+	var options registry.RegisterOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
 	instance, err := serviceToInstance(s)
 	if err != nil {
 		return err
 	}
 
+	if z := zone(e.opts); len(z) > 0 {
+		instance.SetMetadataString(MetadataZone, z)
+	}
+	lease(instance, heartbeatInterval(e.opts), options.TTL)
+
 	if e.instanceRegistered(instance) {
-		return e.conn.HeartBeatInstance(instance)
+		err = e.conn.HeartBeatInstance(instance)
+	} else {
+		err = e.conn.RegisterInstance(instance)
+	}
+	if err != nil {
+		return err
 	}
 
-	return e.conn.RegisterInstance(instance)
+	e.startHeartbeat(instance)
+	return nil
 }
 
 func (e *eurekaRegistry) Deregister(s *registry.Service, opts ...registry.DeregisterOption) error {
@@ -99,10 +132,19 @@ func (e *eurekaRegistry) Deregister(s *registry.Service, opts ...registry.Deregi
 	if err != nil {
 		return err
 	}
+	e.stopHeartbeat(instance)
 	return e.conn.DeregisterInstance(instance)
 }
 
 func (e *eurekaRegistry) GetService(name string, opts ...registry.GetOption) ([]*registry.Service, error) {
+	if e.cache != nil {
+		app, ok := e.cache.getApp(name)
+		if !ok {
+			return nil, registry.ErrNotFound
+		}
+		return appToService(app), nil
+	}
+
 	app, err := e.conn.GetApp(name)
 	if err != nil {
 		return nil, err
@@ -113,6 +155,13 @@ func (e *eurekaRegistry) GetService(name string, opts ...registry.GetOption) ([]
 func (e *eurekaRegistry) ListServices(opts ...registry.ListOption) ([]*registry.Service, error) {
 	var services []*registry.Service
 
+	if e.cache != nil {
+		for _, app := range e.cache.listApps() {
+			services = append(services, appToService(app)...)
+		}
+		return services, nil
+	}
+
 	apps, err := e.conn.GetApps()
 	if err != nil {
 		return nil, err
@@ -126,6 +175,9 @@ func (e *eurekaRegistry) ListServices(opts ...registry.ListOption) ([]*registry.
 }
 
 func (e *eurekaRegistry) Watch(opts ...registry.WatchOption) (registry.Watcher, error) {
+	if e.cache != nil {
+		return e.cache.watch(opts...), nil
+	}
 	return newWatcher(e.conn, opts...), nil
 }
 
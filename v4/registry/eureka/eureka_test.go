@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/go-micro/plugins/v4/registry/eureka/mock"
 	"github.com/hudl/fargo"
@@ -72,3 +73,40 @@ func TestSwitchHttpClient(t *testing.T) {
 		t.Errorf("Unexpected fargo.HttpClient: got %v, want %v", fargo.HttpClient, expected)
 	}
 }
+
+func TestHeartbeat(t *testing.T) {
+	eureka := NewRegistry(HeartbeatInterval(10 * time.Millisecond)).(*eurekaRegistry)
+
+	mockConn := new(mock.FargoConnection)
+	mockConn.GetInstanceReturns(nil, errors.New("Instance not existing"))
+	eureka.conn = mockConn
+
+	service := &registry.Service{
+		Name:  "foo",
+		Nodes: []*registry.Node{{Id: "1", Address: "10.0.0.1:8080"}},
+	}
+
+	if err := eureka.Register(service, registry.RegisterTTL(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	lease := mockConn.RegisterInstanceArgsForCall(0).LeaseInfo
+	if lease.DurationInSecs != 60 {
+		t.Errorf("Expected a lease of 60s, got %ds", lease.DurationInSecs)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if mockConn.HeartBeatInstanceCallCount() == 0 {
+		t.Error("Expected the lease to be renewed")
+	}
+
+	if err := eureka.Deregister(service); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	n := mockConn.HeartBeatInstanceCallCount()
+	time.Sleep(50 * time.Millisecond)
+	if mockConn.HeartBeatInstanceCallCount() != n {
+		t.Error("Expected heartbeats to stop after deregistering")
+	}
+}
@@ -0,0 +1,89 @@
+package eureka
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hudl/fargo"
+	log "go-micro.dev/v4/logger"
+)
+
+// lease sets the renewal interval and the lease duration of the instance,
+// so Eureka evicts it once heartbeats stop arriving.
+func lease(instance *fargo.Instance, interval, ttl time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	if ttl <= 0 {
+		ttl = 3 * interval
+	}
+	instance.LeaseInfo = fargo.LeaseInfo{
+		RenewalIntervalInSecs: int32(interval / time.Second),
+		DurationInSecs:        int32(ttl / time.Second),
+	}
+}
+
+func instanceKey(instance *fargo.Instance) string {
+	return instance.App + ":" + instance.Id()
+}
+
+// startHeartbeat renews the lease of the instance every interval until it's
+// deregistered. Instances evicted in the meantime are registered again.
+func (e *eurekaRegistry) startHeartbeat(instance *fargo.Instance) {
+	interval := heartbeatInterval(e.opts)
+	if interval <= 0 {
+		return
+	}
+
+	key := instanceKey(instance)
+
+	e.Lock()
+	defer e.Unlock()
+
+	if _, ok := e.heartbeats[key]; ok {
+		return
+	}
+
+	exit := make(chan bool)
+	e.heartbeats[key] = exit
+
+	go heartbeat(e.conn, instance, interval, exit)
+}
+
+func (e *eurekaRegistry) stopHeartbeat(instance *fargo.Instance) {
+	key := instanceKey(instance)
+
+	e.Lock()
+	defer e.Unlock()
+
+	if exit, ok := e.heartbeats[key]; ok {
+		close(exit)
+		delete(e.heartbeats, key)
+	}
+}
+
+func heartbeat(conn fargoConnection, instance *fargo.Instance, interval time.Duration, exit chan bool) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-exit:
+			return
+		case <-t.C:
+		}
+
+		err := conn.HeartBeatInstance(instance)
+		if err == nil {
+			continue
+		}
+
+		// the lease expired, the instance has to register again
+		if code, ok := fargo.HTTPResponseStatusCode(err); ok && code == http.StatusNotFound {
+			err = conn.RegisterInstance(instance)
+		}
+		if err != nil {
+			log.Errorf("Eureka heartbeat of %s failed: %v", instanceKey(instance), err)
+		}
+	}
+}
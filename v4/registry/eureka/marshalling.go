@@ -37,6 +37,16 @@ func appToService(app *fargo.Application) []*registry.Service {
 			json.Unmarshal([]byte(k), &metadata)
 		}
 
+		// add the zone
+		if z := instanceZone(instance); len(z) > 0 {
+			if metadata == nil {
+				metadata = make(map[string]string)
+			}
+			if _, ok := metadata[MetadataZone]; !ok {
+				metadata[MetadataZone] = z
+			}
+		}
+
 		// get existing service
 		service, ok := serviceMap[version]
 		if !ok {
@@ -111,3 +121,15 @@ func serviceToInstance(service *registry.Service) (*fargo.Instance, error) {
 
 	return instance, nil
 }
+
+// instanceZone returns the availability zone of the instance, from its
+// metadata or the Amazon data center info.
+func instanceZone(instance *fargo.Instance) string {
+	if z, err := instance.Metadata.GetString(MetadataZone); err == nil && len(z) > 0 {
+		return z
+	}
+	if instance.DataCenterInfo.Name == fargo.Amazon {
+		return instance.DataCenterInfo.Metadata.AvailabilityZone
+	}
+	return ""
+}
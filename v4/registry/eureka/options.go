@@ -3,6 +3,7 @@ package eureka
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"go-micro.dev/v4/registry"
 	"golang.org/x/oauth2"
@@ -10,6 +11,13 @@ import (
 )
 
 type contextHttpClient struct{}
+type contextHeartbeatInterval struct{}
+type contextDeltaInterval struct{}
+type contextZone struct{}
+
+// DefaultHeartbeatInterval is the default lease renewal interval, the one of
+// Eureka clients.
+var DefaultHeartbeatInterval = 30 * time.Second
 
 var newOAuthClient = func(c clientcredentials.Config) *http.Client {
 	return c.Client(oauth2.NoContext)
@@ -27,3 +35,47 @@ func OAuth2ClientCredentials(clientID, clientSecret, tokenURL string) registry.O
 		o.Context = context.WithValue(o.Context, contextHttpClient{}, newOAuthClient(c))
 	}
 }
+
+// HeartbeatInterval sets how often the leases of registered instances are
+// renewed. A negative interval disables heartbeats, leaving renewal to the
+// re-registration of the service. Defaults to DefaultHeartbeatInterval.
+func HeartbeatInterval(d time.Duration) registry.Option {
+	return func(o *registry.Options) {
+		o.Context = context.WithValue(o.Context, contextHeartbeatInterval{}, d)
+	}
+}
+
+// DeltaFetch keeps a local copy of the registry, refreshed every interval
+// from the delta of recent changes instead of fetching every application.
+// The copy is checked against the hash code of the server and fetched in
+// full if they differ. Lookups and watches are served from the copy.
+func DeltaFetch(interval time.Duration) registry.Option {
+	return func(o *registry.Options) {
+		o.Context = context.WithValue(o.Context, contextDeltaInterval{}, interval)
+	}
+}
+
+// Zone sets the availability zone registered instances are in. It's
+// registered as the "zone" metadata of the instance, see PreferZone.
+func Zone(zone string) registry.Option {
+	return func(o *registry.Options) {
+		o.Context = context.WithValue(o.Context, contextZone{}, zone)
+	}
+}
+
+func heartbeatInterval(o registry.Options) time.Duration {
+	if d, ok := o.Context.Value(contextHeartbeatInterval{}).(time.Duration); ok {
+		return d
+	}
+	return DefaultHeartbeatInterval
+}
+
+func deltaInterval(o registry.Options) time.Duration {
+	d, _ := o.Context.Value(contextDeltaInterval{}).(time.Duration)
+	return d
+}
+
+func zone(o registry.Options) string {
+	z, _ := o.Context.Value(contextZone{}).(string)
+	return z
+}
@@ -0,0 +1,40 @@
+package eureka
+
+import (
+	"math/rand"
+
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/selector"
+)
+
+// MetadataZone is the instance and node metadata key of the availability
+// zone.
+const MetadataZone = "zone"
+
+// PreferZone is a selector strategy picking nodes of the zone at random,
+// falling back to the nodes of other zones if the zone has none.
+func PreferZone(zone string) selector.Strategy {
+	return func(services []*registry.Service) selector.Next {
+		var local, all []*registry.Node
+		for _, service := range services {
+			for _, node := range service.Nodes {
+				all = append(all, node)
+				if node.Metadata[MetadataZone] == zone {
+					local = append(local, node)
+				}
+			}
+		}
+
+		nodes := local
+		if len(nodes) == 0 {
+			nodes = all
+		}
+
+		return func() (*registry.Node, error) {
+			if len(nodes) == 0 {
+				return nil, selector.ErrNoneAvailable
+			}
+			return nodes[rand.Intn(len(nodes))], nil
+		}
+	}
+}
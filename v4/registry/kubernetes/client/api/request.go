@@ -20,6 +20,7 @@ type Request struct {
 	method    string
 	host      string
 	namespace string
+	prefix    string
 
 	resource     string
 	resourceName *string
@@ -31,8 +32,9 @@ type Request struct {
 // Params is the object to pass in to set parameters
 // on a request.
 type Params struct {
-	LabelSelector map[string]string
-	Watch         bool
+	LabelSelector   map[string]string
+	ResourceVersion string
+	Watch           bool
 }
 
 // Options ...
@@ -51,6 +53,7 @@ func NewRequest(opts *Options) *Request {
 		client:    opts.Client,
 		namespace: opts.Namespace,
 		host:      opts.Host,
+		prefix:    "api/v1",
 	}
 
 	if opts.BearerToken != nil {
@@ -98,6 +101,13 @@ func (r *Request) Namespace(s string) *Request {
 	return r
 }
 
+// Group sets the api group and version of the resource, e.g.
+// "discovery.k8s.io/v1". Defaults to the core api.
+func (r *Request) Group(s string) *Request {
+	r.prefix = "apis/" + s
+	return r
+}
+
 // Resource is the type of resource the operation is
 // for, such as "services", "endpoints" or "pods".
 func (r *Request) Resource(s string) *Request {
@@ -138,6 +148,10 @@ func (r *Request) Params(p *Params) *Request {
 		r.params.Set("labelSelector", value)
 	}
 
+	if len(p.ResourceVersion) > 0 {
+		r.params.Set("resourceVersion", p.ResourceVersion)
+	}
+
 	return r
 }
 
@@ -150,7 +164,7 @@ func (r *Request) SetHeader(key, value string) *Request {
 
 // request builds the http.Request from the options.
 func (r *Request) request() (*http.Request, error) {
-	url := fmt.Sprintf("%s/api/v1/namespaces/%s/%s/", r.host, r.namespace, r.resource)
+	url := fmt.Sprintf("%s/%s/namespaces/%s/%s/", r.host, r.prefix, r.namespace, r.resource)
 
 	// append resourceName if it is present
 	if r.resourceName != nil {
@@ -17,6 +17,9 @@ import (
 var (
 	serviceAccountPath = "/var/run/secrets/kubernetes.io/serviceaccount"
 
+	// discoveryGroup is the api group of endpoint slices.
+	discoveryGroup = "discovery.k8s.io/v1"
+
 	// ErrReadNamespace error when failed to read namespace.
 	ErrReadNamespace = errors.New("could not read namespace from service account secret")
 )
@@ -122,6 +125,22 @@ func (c *client) WatchPods(labels map[string]string) (watch.Watch, error) {
 	return api.NewRequest(c.opts).Get().Resource("pods").Params(&api.Params{LabelSelector: labels}).Watch()
 }
 
+// ListEndpointSlices ...
+func (c *client) ListEndpointSlices(labels map[string]string) (*EndpointSliceList, error) {
+	var slices EndpointSliceList
+	err := api.NewRequest(c.opts).Get().Group(discoveryGroup).Resource("endpointslices").Params(&api.Params{LabelSelector: labels}).Do().Decode(&slices)
+
+	return &slices, err
+}
+
+// WatchEndpointSlices ...
+func (c *client) WatchEndpointSlices(labels map[string]string, resourceVersion string) (watch.Watch, error) {
+	return api.NewRequest(c.opts).Get().Group(discoveryGroup).Resource("endpointslices").Params(&api.Params{
+		LabelSelector:   labels,
+		ResourceVersion: resourceVersion,
+	}).Watch()
+}
+
 func detectNamespace() (string, error) {
 	nsPath := path.Join(serviceAccountPath, "namespace")
 
@@ -7,6 +7,8 @@ type Kubernetes interface {
 	ListPods(labels map[string]string) (*PodList, error)
 	UpdatePod(podName string, pod *Pod) (*Pod, error)
 	WatchPods(labels map[string]string) (watch.Watch, error)
+	ListEndpointSlices(labels map[string]string) (*EndpointSliceList, error)
+	WatchEndpointSlices(labels map[string]string, resourceVersion string) (watch.Watch, error)
 }
 
 // PodList ...
@@ -23,6 +25,7 @@ type Pod struct {
 // Meta ...
 type Meta struct {
 	Name              string             `json:"name,omitempty"`
+	ResourceVersion   string             `json:"resourceVersion,omitempty"`
 	Labels            map[string]*string `json:"labels,omitempty"`
 	Annotations       map[string]*string `json:"annotations,omitempty"`
 	DeletionTimestamp string             `json:"deletionTimestamp,omitempty"`
@@ -33,3 +36,52 @@ type Status struct {
 	PodIP string `json:"podIP"`
 	Phase string `json:"phase"`
 }
+
+// ListMeta ...
+type ListMeta struct {
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+// EndpointSliceList ...
+type EndpointSliceList struct {
+	Metadata *ListMeta       `json:"metadata"`
+	Items    []EndpointSlice `json:"items"`
+}
+
+// EndpointSlice is a set of endpoints of a service.
+type EndpointSlice struct {
+	Metadata    *Meta          `json:"metadata"`
+	AddressType string         `json:"addressType"`
+	Endpoints   []Endpoint     `json:"endpoints"`
+	Ports       []EndpointPort `json:"ports"`
+}
+
+// Endpoint ...
+type Endpoint struct {
+	Addresses  []string           `json:"addresses"`
+	Conditions EndpointConditions `json:"conditions"`
+	Hostname   string             `json:"hostname,omitempty"`
+	NodeName   string             `json:"nodeName,omitempty"`
+	Zone       string             `json:"zone,omitempty"`
+	TargetRef  *ObjectReference   `json:"targetRef,omitempty"`
+}
+
+// EndpointConditions ...
+type EndpointConditions struct {
+	Ready       *bool `json:"ready,omitempty"`
+	Terminating *bool `json:"terminating,omitempty"`
+}
+
+// EndpointPort ...
+type EndpointPort struct {
+	Name     string `json:"name,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// ObjectReference ...
+type ObjectReference struct {
+	Kind      string `json:"kind,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+}
@@ -15,16 +15,19 @@ import (
 // Client ...
 type Client struct {
 	sync.RWMutex
-	Pods     map[string]*client.Pod
-	events   chan watch.Event
-	watchers []*mockWatcher
+	Pods           map[string]*client.Pod
+	EndpointSlices map[string]*client.EndpointSlice
+	events         chan watch.Event
+	watchers       []*mockWatcher
+	sliceWatchers  []*mockWatcher
 }
 
 // NewClient ...
 func NewClient() *Client {
 	c := &Client{
-		Pods:   make(map[string]*client.Pod),
-		events: make(chan watch.Event),
+		Pods:           make(map[string]*client.Pod),
+		EndpointSlices: make(map[string]*client.EndpointSlice),
+		events:         make(chan watch.Event),
 	}
 
 	// broadcast events to watchers
@@ -112,6 +115,96 @@ func (c *Client) WatchPods(labels map[string]string) (watch.Watch, error) {
 	return w, nil
 }
 
+// ListEndpointSlices ...
+func (c *Client) ListEndpointSlices(labels map[string]string) (*client.EndpointSliceList, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	slices := client.EndpointSliceList{
+		Metadata: &client.ListMeta{ResourceVersion: "1"},
+	}
+
+	for _, v := range c.EndpointSlices {
+		if labelFilterMatch(v.Metadata.Labels, labels) {
+			slices.Items = append(slices.Items, *v)
+		}
+	}
+
+	return &slices, nil
+}
+
+// WatchEndpointSlices ...
+func (c *Client) WatchEndpointSlices(labels map[string]string, resourceVersion string) (watch.Watch, error) {
+	w := &mockWatcher{
+		results: make(chan watch.Event),
+		stop:    make(chan bool),
+	}
+
+	c.Lock()
+	c.sliceWatchers = append(c.sliceWatchers, w)
+	c.Unlock()
+
+	return w, nil
+}
+
+// EndpointSliceWatchesStopped reports whether every endpoint slice watch
+// was stopped.
+func (c *Client) EndpointSliceWatchesStopped() bool {
+	c.RLock()
+	defer c.RUnlock()
+
+	for _, w := range c.sliceWatchers {
+		select {
+		case <-w.stop:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// UpdateEndpointSlice adds or replaces the endpoint slice and notifies the
+// watchers.
+func (c *Client) UpdateEndpointSlice(s *client.EndpointSlice) {
+	c.Lock()
+	_, ok := c.EndpointSlices[s.Metadata.Name]
+	c.EndpointSlices[s.Metadata.Name] = s
+	c.Unlock()
+
+	if ok {
+		c.sendSliceEvent(watch.Modified, s)
+	} else {
+		c.sendSliceEvent(watch.Added, s)
+	}
+}
+
+// DeleteEndpointSlice removes the endpoint slice and notifies the watchers.
+func (c *Client) DeleteEndpointSlice(name string) {
+	c.Lock()
+	s, ok := c.EndpointSlices[name]
+	delete(c.EndpointSlices, name)
+	c.Unlock()
+
+	if ok {
+		c.sendSliceEvent(watch.Deleted, s)
+	}
+}
+
+func (c *Client) sendSliceEvent(t watch.EventType, s *client.EndpointSlice) {
+	//nolint:errcheck
+	b, _ := json.Marshal(s)
+
+	c.RLock()
+	defer c.RUnlock()
+
+	for _, w := range c.sliceWatchers {
+		select {
+		case <-w.stop:
+		case w.results <- watch.Event{Type: t, Object: json.RawMessage(b)}:
+		}
+	}
+}
+
 // Teardown ...
 func Teardown(c *Client) {
 	for _, p := range c.Pods {
@@ -0,0 +1,477 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-micro.dev/v4/logger"
+	"go-micro.dev/v4/registry"
+
+	"github.com/go-micro/plugins/v4/registry/kubernetes/client"
+	"github.com/go-micro/plugins/v4/registry/kubernetes/client/watch"
+)
+
+var (
+	// label set on endpoint slices to the name of their service.
+	labelServiceName = "kubernetes.io/service-name"
+	// label used as the version of a service.
+	labelVersion = "app.kubernetes.io/version"
+
+	// how long to wait before listing again when the watch fails.
+	relistInterval = time.Second * 5
+
+	// how many results a watcher queues before dropping the oldest.
+	maxQueuedResults = 1024
+)
+
+// informer caches the endpoint slices matching a selector, watching them
+// for changes once started and until stopped.
+type informer struct {
+	client   client.Kubernetes
+	selector map[string]string
+	port     string
+	exit     chan struct{}
+
+	startMu sync.Mutex
+	started bool
+
+	sync.RWMutex
+	slices   map[string]*client.EndpointSlice
+	watchers map[*sliceWatcher]bool
+}
+
+func newInformer(c client.Kubernetes, selector map[string]string, port string) *informer {
+	return &informer{
+		client:   c,
+		selector: selector,
+		port:     port,
+		exit:     make(chan struct{}),
+		slices:   make(map[string]*client.EndpointSlice),
+		watchers: make(map[*sliceWatcher]bool),
+	}
+}
+
+// start lists the endpoint slices and keeps watching them in the
+// background, a failed first list is retried on the next call.
+func (i *informer) start() error {
+	i.startMu.Lock()
+	defer i.startMu.Unlock()
+
+	if i.started {
+		return nil
+	}
+
+	rv, err := i.list()
+	if err != nil {
+		return err
+	}
+
+	w, err := i.client.WatchEndpointSlices(i.selector, rv)
+	if err != nil {
+		return err
+	}
+
+	i.started = true
+	go i.run(w)
+
+	return nil
+}
+
+// stop ends the watch of the endpoint slices and stops the watchers.
+func (i *informer) stop() {
+	select {
+	case <-i.exit:
+		return
+	default:
+		close(i.exit)
+	}
+
+	i.RLock()
+	watchers := make([]*sliceWatcher, 0, len(i.watchers))
+	for w := range i.watchers {
+		watchers = append(watchers, w)
+	}
+	i.RUnlock()
+
+	for _, w := range watchers {
+		w.Stop()
+	}
+}
+
+// list replaces the cache with the current endpoint slices, returning the
+// resource version to watch from.
+func (i *informer) list() (string, error) {
+	list, err := i.client.ListEndpointSlices(i.selector)
+	if err != nil {
+		return "", err
+	}
+
+	slices := make(map[string]*client.EndpointSlice, len(list.Items))
+	for n := range list.Items {
+		s := list.Items[n]
+		slices[s.Metadata.Name] = &s
+	}
+
+	i.Lock()
+	names := make(map[string]bool)
+	for _, s := range i.slices {
+		names[sliceService(s)] = true
+	}
+	for _, s := range slices {
+		names[sliceService(s)] = true
+	}
+	results := i.apply(names, func() {
+		i.slices = slices
+	})
+	i.Unlock()
+
+	i.notify(results)
+
+	var rv string
+	if list.Metadata != nil {
+		rv = list.Metadata.ResourceVersion
+	}
+
+	return rv, nil
+}
+
+// run handles the events of the watch, listing and watching again once it
+// ends, e.g. when the resource version expired, until stopped.
+func (i *informer) run(w watch.Watch) {
+	for {
+		if !i.handle(w) {
+			return
+		}
+
+		for {
+			rv, err := i.list()
+			if err == nil {
+				if w, err = i.client.WatchEndpointSlices(i.selector, rv); err == nil {
+					break
+				}
+			}
+
+			logger.Errorf("K8s EndpointSlice informer: %v, retrying in %v", err, relistInterval)
+			select {
+			case <-i.exit:
+				return
+			case <-time.After(relistInterval):
+			}
+		}
+	}
+}
+
+// handle handles the events of the watch until it ends, reporting false
+// once the informer is stopped.
+func (i *informer) handle(w watch.Watch) bool {
+	for {
+		select {
+		case <-i.exit:
+			w.Stop()
+			return false
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return true
+			}
+			if event.Type == watch.Error {
+				w.Stop()
+				return true
+			}
+			i.handleEvent(event)
+		}
+	}
+}
+
+func (i *informer) handleEvent(event watch.Event) {
+	var s client.EndpointSlice
+	if err := json.Unmarshal(event.Object, &s); err != nil || s.Metadata == nil {
+		logger.Error("K8s EndpointSlice informer: Couldnt unmarshal event object")
+		return
+	}
+
+	names := map[string]bool{sliceService(&s): true}
+
+	var results []*registry.Result
+
+	i.Lock()
+	//nolint:exhaustive
+	switch event.Type {
+	case watch.Added, watch.Modified:
+		results = i.apply(names, func() {
+			i.slices[s.Metadata.Name] = &s
+		})
+	case watch.Deleted:
+		results = i.apply(names, func() {
+			delete(i.slices, s.Metadata.Name)
+		})
+	}
+	i.Unlock()
+
+	i.notify(results)
+}
+
+// apply changes the cache and returns the changes to the named services.
+// It must be called with the lock held.
+func (i *informer) apply(names map[string]bool, fn func()) []*registry.Result {
+	before := make(map[string]*registry.Service, len(names))
+	for name := range names {
+		before[name] = i.service(name)
+	}
+
+	fn()
+
+	var results []*registry.Result
+
+	for name := range names {
+		old, svc := before[name], i.service(name)
+
+		var r *registry.Result
+
+		switch {
+		case svc == nil && old != nil:
+			r = &registry.Result{Action: deleteAction, Service: old}
+		case svc != nil && old == nil:
+			r = &registry.Result{Action: "create", Service: svc}
+		case svc != nil && !sameNodes(old, svc):
+			r = &registry.Result{Action: "update", Service: svc}
+		default:
+			continue
+		}
+
+		results = append(results, r)
+	}
+
+	return results
+}
+
+// notify sends the changes to the watchers. The changes only come from the
+// goroutine of the informer, so they are sent in order.
+func (i *informer) notify(results []*registry.Result) {
+	if len(results) == 0 {
+		return
+	}
+
+	i.RLock()
+	watchers := make([]*sliceWatcher, 0, len(i.watchers))
+	for w := range i.watchers {
+		watchers = append(watchers, w)
+	}
+	i.RUnlock()
+
+	for _, w := range watchers {
+		for _, r := range results {
+			w.send(r)
+		}
+	}
+}
+
+// service builds the service of the name from the cached slices, nil if
+// it has no ready endpoints.
+func (i *informer) service(name string) *registry.Service {
+	svc := &registry.Service{Name: name}
+
+	for _, s := range i.slices {
+		if sliceService(s) != name {
+			continue
+		}
+
+		if v, ok := s.Metadata.Labels[labelVersion]; ok && v != nil {
+			svc.Version = *v
+		}
+
+		port, ok := slicePort(s, i.port)
+		if !ok {
+			continue
+		}
+
+		for _, e := range s.Endpoints {
+			// endpoints without a condition are ready
+			if e.Conditions.Ready != nil && !*e.Conditions.Ready {
+				continue
+			}
+
+			for _, addr := range e.Addresses {
+				svc.Nodes = append(svc.Nodes, endpointNode(name, addr, port, e))
+			}
+		}
+	}
+
+	if len(svc.Nodes) == 0 {
+		return nil
+	}
+
+	return svc
+}
+
+func (i *informer) services() []*registry.Service {
+	i.RLock()
+	defer i.RUnlock()
+
+	names := make(map[string]bool)
+	for _, s := range i.slices {
+		names[sliceService(s)] = true
+	}
+
+	list := make([]*registry.Service, 0, len(names))
+	for name := range names {
+		if svc := i.service(name); svc != nil {
+			list = append(list, svc)
+		}
+	}
+
+	return list
+}
+
+func (i *informer) getService(name string) *registry.Service {
+	i.RLock()
+	defer i.RUnlock()
+
+	return i.service(name)
+}
+
+func (i *informer) watch(service string) *sliceWatcher {
+	w := &sliceWatcher{
+		informer: i,
+		service:  service,
+		ready:    make(chan struct{}, 1),
+		exit:     make(chan bool),
+	}
+
+	i.Lock()
+	i.watchers[w] = true
+	i.Unlock()
+
+	return w
+}
+
+func sliceService(s *client.EndpointSlice) string {
+	if s.Metadata == nil {
+		return ""
+	}
+	if name, ok := s.Metadata.Labels[labelServiceName]; ok && name != nil {
+		return *name
+	}
+	return s.Metadata.Name
+}
+
+func slicePort(s *client.EndpointSlice, name string) (int, bool) {
+	for _, p := range s.Ports {
+		if len(name) == 0 || p.Name == name {
+			return p.Port, p.Port > 0
+		}
+	}
+	return 0, false
+}
+
+func endpointNode(service, addr string, port int, e client.Endpoint) *registry.Node {
+	id := addr
+	if e.TargetRef != nil && len(e.TargetRef.Name) > 0 {
+		id = e.TargetRef.Name
+	}
+
+	md := map[string]string{}
+	if len(e.Zone) > 0 {
+		md["zone"] = e.Zone
+	}
+	if len(e.NodeName) > 0 {
+		md["node"] = e.NodeName
+	}
+
+	return &registry.Node{
+		Id:       service + "-" + id,
+		Address:  net.JoinHostPort(addr, strconv.Itoa(port)),
+		Metadata: md,
+	}
+}
+
+func sameNodes(a, b *registry.Service) bool {
+	if a.Version != b.Version || len(a.Nodes) != len(b.Nodes) {
+		return false
+	}
+
+	addrs := make(map[string]string, len(a.Nodes))
+	for _, n := range a.Nodes {
+		addrs[n.Id] = n.Address
+	}
+
+	for _, n := range b.Nodes {
+		if addr, ok := addrs[n.Id]; !ok || addr != n.Address {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sliceWatcher receives the changes of the informer. They are queued so
+// slow watchers don't hold up the informer.
+type sliceWatcher struct {
+	informer *informer
+	service  string
+	exit     chan bool
+
+	sync.Mutex
+	queue []*registry.Result
+	ready chan struct{}
+}
+
+func (w *sliceWatcher) send(r *registry.Result) {
+	if len(w.service) > 0 && w.service != r.Service.Name {
+		return
+	}
+
+	w.Lock()
+	if len(w.queue) >= maxQueuedResults {
+		logger.Errorf("K8s EndpointSlice watcher: %d results queued, dropping the oldest", len(w.queue))
+		w.queue = w.queue[1:]
+	}
+	w.queue = append(w.queue, r)
+	w.Unlock()
+
+	select {
+	case w.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (w *sliceWatcher) Next() (*registry.Result, error) {
+	for {
+		select {
+		case <-w.exit:
+			return nil, registry.ErrWatcherStopped
+		default:
+		}
+
+		w.Lock()
+		if len(w.queue) > 0 {
+			r := w.queue[0]
+			w.queue[0] = nil
+			w.queue = w.queue[1:]
+			w.Unlock()
+			return r, nil
+		}
+		w.Unlock()
+
+		select {
+		case <-w.exit:
+			return nil, registry.ErrWatcherStopped
+		case <-w.ready:
+		}
+	}
+}
+
+func (w *sliceWatcher) Stop() {
+	select {
+	case <-w.exit:
+		return
+	default:
+		close(w.exit)
+	}
+
+	w.informer.Lock()
+	delete(w.informer.watchers, w)
+	w.informer.Unlock()
+}
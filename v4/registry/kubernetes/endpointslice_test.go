@@ -0,0 +1,218 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/registry"
+
+	"github.com/go-micro/plugins/v4/registry/kubernetes/client"
+	"github.com/go-micro/plugins/v4/registry/kubernetes/client/mock"
+)
+
+func newSlice(name, service string, ready bool, addrs ...string) *client.EndpointSlice {
+	team := "payments"
+	version := "1.2.0"
+
+	s := &client.EndpointSlice{
+		Metadata: &client.Meta{
+			Name: name,
+			Labels: map[string]*string{
+				labelServiceName: &service,
+				labelVersion:     &version,
+				"team":           &team,
+			},
+		},
+		AddressType: "IPv4",
+		Ports: []client.EndpointPort{
+			{Name: "metrics", Port: 9090},
+			{Name: "grpc", Port: 8080},
+		},
+	}
+
+	for _, addr := range addrs {
+		r := ready
+		s.Endpoints = append(s.Endpoints, client.Endpoint{
+			Addresses:  []string{addr},
+			Conditions: client.EndpointConditions{Ready: &r},
+			Zone:       "eu-west-1a",
+			TargetRef:  &client.ObjectReference{Kind: "Pod", Name: "pod-" + addr},
+		})
+	}
+
+	return s
+}
+
+func setupSliceRegistry(c *mock.Client) registry.Registry {
+	k := &kregistry{client: c, timeout: time.Second}
+	k.slices = newInformer(c, map[string]string{"team": "payments"}, "grpc")
+	return k
+}
+
+func TestEndpointSlices(t *testing.T) {
+	c := mock.NewClient()
+	c.EndpointSlices["billing-abc"] = newSlice("billing-abc", "billing", true, "10.0.0.1", "10.0.0.2")
+	c.EndpointSlices["billing-def"] = newSlice("billing-def", "billing", false, "10.0.0.3")
+	c.EndpointSlices["other-abc"] = newSlice("other-abc", "other", true, "10.0.1.1")
+	delete(c.EndpointSlices["other-abc"].Metadata.Labels, "team")
+
+	r := setupSliceRegistry(c)
+
+	// endpoint slices are managed by kubernetes
+	if err := r.Register(&registry.Service{Name: "billing", Nodes: []*registry.Node{{Id: "1"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	services, err := r.GetService("billing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 || len(services[0].Nodes) != 2 {
+		t.Fatalf("Expected 2 ready nodes, got %+v", services)
+	}
+
+	svc := services[0]
+	if svc.Version != "1.2.0" {
+		t.Fatalf("Expected version 1.2.0, got %s", svc.Version)
+	}
+	for _, n := range svc.Nodes {
+		if n.Address != "10.0.0.1:8080" && n.Address != "10.0.0.2:8080" {
+			t.Fatalf("Unexpected node address %s", n.Address)
+		}
+		if n.Metadata["zone"] != "eu-west-1a" {
+			t.Fatalf("Expected the zone in the node metadata, got %v", n.Metadata)
+		}
+	}
+
+	if _, err := r.GetService("other"); err != registry.ErrNotFound {
+		t.Fatalf("Expected services without the selected labels to be ignored, got %v", err)
+	}
+
+	list, err := r.ListServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Name != "billing" {
+		t.Fatalf("Expected only billing to be listed, got %+v", list)
+	}
+}
+
+func TestEndpointSlicesWatch(t *testing.T) {
+	c := mock.NewClient()
+	r := setupSliceRegistry(c)
+
+	w, err := r.Watch(registry.WatchService("billing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	next := func(action string, nodes int) {
+		t.Helper()
+
+		res, err := w.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Action != action || res.Service.Name != "billing" || len(res.Service.Nodes) != nodes {
+			t.Fatalf("Expected %s of billing with %d nodes, got %s of %+v", action, nodes, res.Action, res.Service)
+		}
+	}
+
+	c.UpdateEndpointSlice(newSlice("other-abc", "other", true, "10.0.1.1"))
+	c.UpdateEndpointSlice(newSlice("billing-abc", "billing", true, "10.0.0.1"))
+	next("create", 1)
+
+	c.UpdateEndpointSlice(newSlice("billing-abc", "billing", true, "10.0.0.1", "10.0.0.2"))
+	next("update", 2)
+
+	// an endpoint turning unready removes its node
+	c.UpdateEndpointSlice(newSlice("billing-abc", "billing", false, "10.0.0.1", "10.0.0.2"))
+	next("delete", 2)
+
+	c.UpdateEndpointSlice(newSlice("billing-abc", "billing", true, "10.0.0.1"))
+	next("create", 1)
+
+	c.DeleteEndpointSlice("billing-abc")
+	next("delete", 1)
+
+	if services, _ := r.GetService("billing"); len(services) != 0 {
+		t.Fatalf("Expected the deleted service to be gone, got %+v", services)
+	}
+}
+
+func TestEndpointSlicesSlowWatcher(t *testing.T) {
+	c := mock.NewClient()
+	r := setupSliceRegistry(c)
+
+	// a watcher which is never read doesn't hold up the informer
+	w, err := r.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for n := 0; n < 64; n++ {
+			if n%2 == 0 {
+				c.UpdateEndpointSlice(newSlice("billing-abc", "billing", true, "10.0.0.1"))
+			} else {
+				c.DeleteEndpointSlice("billing-abc")
+			}
+		}
+		c.UpdateEndpointSlice(newSlice("billing-abc", "billing", true, "10.0.0.1", "10.0.0.2"))
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the informer to keep handling events")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		services, _ := r.GetService("billing")
+		if len(services) == 1 && len(services[0].Nodes) == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the latest slice to be cached, got %+v", services)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	res, err := w.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Action != "create" || len(res.Service.Nodes) != 1 {
+		t.Fatalf("Expected the queued create of billing, got %s of %+v", res.Action, res.Service)
+	}
+}
+
+func TestEndpointSlicesStop(t *testing.T) {
+	c := mock.NewClient()
+	k := setupSliceRegistry(c).(*kregistry)
+
+	w, err := k.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k.slices.stop()
+
+	if _, err := w.Next(); err != registry.ErrWatcherStopped {
+		t.Fatalf("Expected the watcher to be stopped, got %v", err)
+	}
+
+	// the informer stops its watch of the endpoint slices
+	deadline := time.Now().Add(5 * time.Second)
+	for !c.EndpointSliceWatchesStopped() {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the watch to be stopped")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
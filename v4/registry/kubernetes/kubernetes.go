@@ -21,6 +21,9 @@ type kregistry struct {
 	client  client.Kubernetes
 	timeout time.Duration
 	options registry.Options
+
+	// slices is set in EndpointSlice mode
+	slices *informer
 }
 
 var (
@@ -84,6 +87,18 @@ func configure(k *kregistry, opts ...registry.Option) error {
 	k.client = c
 	k.timeout = k.options.Timeout
 
+	if k.slices != nil {
+		k.slices.stop()
+		k.slices = nil
+	}
+
+	if k.options.Context != nil {
+		if selector, ok := k.options.Context.Value(endpointSlicesKey{}).(map[string]string); ok {
+			port, _ := k.options.Context.Value(endpointSlicePortKey{}).(string)
+			k.slices = newInformer(c, selector, port)
+		}
+	}
+
 	return nil
 }
 
@@ -120,6 +135,11 @@ func (c *kregistry) Register(s *registry.Service, opts ...registry.RegisterOptio
 		return ErrNoNodesFound
 	}
 
+	// endpoint slices are managed by kubernetes
+	if c.slices != nil {
+		return nil
+	}
+
 	svcName := s.Name
 
 	// TODO: grab podname from somewhere better than this.
@@ -161,6 +181,10 @@ func (c *kregistry) Deregister(s *registry.Service, opts ...registry.DeregisterO
 		return ErrNoNodesFound
 	}
 
+	if c.slices != nil {
+		return nil
+	}
+
 	svcName := s.Name
 
 	// TODO: grab podname from somewhere better than env var.
@@ -190,6 +214,19 @@ func (c *kregistry) Deregister(s *registry.Service, opts ...registry.DeregisterO
 // GetService will get all the pods with the given service selector,
 // and build services from the annotations.
 func (c *kregistry) GetService(name string, opts ...registry.GetOption) ([]*registry.Service, error) {
+	if c.slices != nil {
+		if err := c.slices.start(); err != nil {
+			return nil, err
+		}
+
+		svc := c.slices.getService(name)
+		if svc == nil {
+			return nil, registry.ErrNotFound
+		}
+
+		return []*registry.Service{svc}, nil
+	}
+
 	pods, err := c.client.ListPods(map[string]string{
 		svcSelectorPrefix + serviceName(name): svcSelectorValue,
 	})
@@ -243,6 +280,14 @@ func (c *kregistry) GetService(name string, opts ...registry.GetOption) ([]*regi
 
 // ListServices will list all the service names.
 func (c *kregistry) ListServices(opts ...registry.ListOption) ([]*registry.Service, error) {
+	if c.slices != nil {
+		if err := c.slices.start(); err != nil {
+			return nil, err
+		}
+
+		return c.slices.services(), nil
+	}
+
 	pods, err := c.client.ListPods(podSelector)
 	if err != nil {
 		return nil, err
@@ -292,6 +337,19 @@ func (c *kregistry) ListServices(opts ...registry.ListOption) ([]*registry.Servi
 
 // Watch returns a kubernetes watcher.
 func (c *kregistry) Watch(opts ...registry.WatchOption) (registry.Watcher, error) {
+	if c.slices != nil {
+		if err := c.slices.start(); err != nil {
+			return nil, err
+		}
+
+		var wo registry.WatchOptions
+		for _, o := range opts {
+			o(&wo)
+		}
+
+		return c.slices.watch(wo.Service), nil
+	}
+
 	return newWatcher(c, opts...)
 }
 
@@ -0,0 +1,39 @@
+package kubernetes
+
+import (
+	"context"
+
+	"go-micro.dev/v4/registry"
+)
+
+type endpointSlicesKey struct{}
+
+type endpointSlicePortKey struct{}
+
+// EndpointSlices discovers services from the Kubernetes EndpointSlices
+// matching the label selector instead of pod annotations, so plain
+// Kubernetes workloads which don't register themselves can be called.
+// EndpointSlices carry the labels of their Service, so the selector selects
+// Services. Register and Deregister are no-ops in this mode.
+func EndpointSlices(selector map[string]string) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		if selector == nil {
+			selector = map[string]string{}
+		}
+		o.Context = context.WithValue(o.Context, endpointSlicesKey{}, selector)
+	}
+}
+
+// EndpointSlicePort sets the name of the port the nodes are addressed on,
+// defaults to the first port of an EndpointSlice.
+func EndpointSlicePort(name string) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, endpointSlicePortKey{}, name)
+	}
+}
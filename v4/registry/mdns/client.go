@@ -0,0 +1,180 @@
+package mdns
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// serviceEntry is an instance found by a query or announcement.
+type serviceEntry struct {
+	name   string
+	target string
+	port   int
+	txt    []string
+	ttl    uint32
+	// from is the sender, the address used without address records
+	from net.IP
+
+	hasSRV, hasTXT bool
+}
+
+// collector gathers the records of the responses to a query, which may be
+// spread over several messages.
+type collector struct {
+	entries map[string]*serviceEntry
+	hosts   map[string][]net.IPAddr
+}
+
+func newCollector() *collector {
+	return &collector{
+		entries: make(map[string]*serviceEntry),
+		hosts:   make(map[string][]net.IPAddr),
+	}
+}
+
+func (c *collector) entry(name string) *serviceEntry {
+	e, ok := c.entries[name]
+	if !ok {
+		e = &serviceEntry{name: name}
+		c.entries[name] = e
+	}
+	return e
+}
+
+func (c *collector) addHost(name string, addr net.IPAddr) {
+	for _, a := range c.hosts[name] {
+		if a.IP.Equal(addr.IP) {
+			return
+		}
+	}
+	c.hosts[name] = append(c.hosts[name], addr)
+}
+
+func (c *collector) add(p *packet) {
+	records := append(append(append([]dns.RR{}, p.msg.Answer...), p.msg.Ns...), p.msg.Extra...)
+
+	for _, rr := range records {
+		switch rr := rr.(type) {
+		case *dns.SRV:
+			e := c.entry(rr.Hdr.Name)
+			e.target = rr.Target
+			e.port = int(rr.Port)
+			e.ttl = rr.Hdr.Ttl
+			e.from = p.from.IP
+			e.hasSRV = true
+		case *dns.TXT:
+			e := c.entry(rr.Hdr.Name)
+			e.txt = rr.Txt
+			e.hasTXT = true
+		case *dns.A:
+			c.addHost(rr.Hdr.Name, net.IPAddr{IP: rr.A})
+		case *dns.AAAA:
+			addr := net.IPAddr{IP: rr.AAAA}
+			// link local addresses are only reachable through the interface
+			if rr.AAAA.IsLinkLocalUnicast() && p.iface != nil {
+				addr.Zone = p.iface.Name
+			}
+			c.addHost(rr.Hdr.Name, addr)
+		}
+	}
+}
+
+// complete returns the entries with a SRV and TXT record.
+func (c *collector) complete() []*serviceEntry {
+	var entries []*serviceEntry
+	for _, e := range c.entries {
+		if e.hasSRV && e.hasTXT {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// address returns the address of the entry, preferring IPv4, then global
+// IPv6 and then link local IPv6 addresses. Entries advertising the
+// unspecified address are reached at the address of the sender.
+func (c *collector) address(e *serviceEntry) string {
+	var best *net.IPAddr
+	rank := func(ip net.IP) int {
+		switch {
+		case ip.IsUnspecified():
+			return 0
+		case ip.To4() != nil:
+			return 3
+		case !ip.IsLinkLocalUnicast():
+			return 2
+		}
+		return 1
+	}
+
+	for _, a := range c.hosts[e.target] {
+		a := a
+		if best == nil || rank(a.IP) > rank(best.IP) {
+			best = &a
+		}
+	}
+
+	port := strconv.Itoa(e.port)
+	if best == nil || best.IP.IsUnspecified() {
+		if e.from == nil {
+			return ""
+		}
+		return net.JoinHostPort(e.from.String(), port)
+	}
+	if len(best.Zone) > 0 {
+		return net.JoinHostPort(best.IP.String()+"%"+best.Zone, port)
+	}
+	return net.JoinHostPort(best.IP.String(), port)
+}
+
+// query asks every interface for the PTR records of the name and collects
+// the responses until the timeout.
+func (m *mdnsRegistry) query(name string, timeout time.Duration) (*collector, error) {
+	ifaces, err := m.interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := newConn(ifaces, m.network, false)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	ch := make(chan *packet, 32)
+	go c.recv(ch, done)
+
+	q := new(dns.Msg)
+	q.SetQuestion(name, dns.TypePTR)
+	// ask for unicast responses to the port
+	q.Question[0].Qclass |= 1 << 15
+	q.RecursionDesired = false
+
+	if err := c.multicast(q, nil); err != nil {
+		return nil, err
+	}
+
+	col := newCollector()
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+
+	for {
+		select {
+		case p, ok := <-ch:
+			if !ok {
+				return col, nil
+			}
+			if p.msg.Response {
+				col.add(p)
+			}
+		case <-t.C:
+			return col, nil
+		}
+	}
+}
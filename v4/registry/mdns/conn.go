@@ -0,0 +1,294 @@
+package mdns
+
+import (
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const mdnsPort = 5353
+
+var (
+	groupIPv4 = &net.UDPAddr{IP: net.ParseIP("224.0.0.251"), Port: mdnsPort}
+	groupIPv6 = &net.UDPAddr{IP: net.ParseIP("ff02::fb"), Port: mdnsPort}
+)
+
+// packet is a message received on one of the interfaces.
+type packet struct {
+	msg   *dns.Msg
+	from  *net.UDPAddr
+	iface *netInterface
+}
+
+// conn sends and receives mdns messages on a set of interfaces, over IPv4
+// and IPv6. Multicast conns listen on the mdns port and join the groups on
+// every interface, unicast conns listen on a random port for the responses
+// to their queries.
+type conn struct {
+	ifaces []*netInterface
+	p4     *ipv4.PacketConn
+	p6     *ipv6.PacketConn
+	// filter drops the messages received on other interfaces
+	filter bool
+
+	// guards the multicast interface set before every write
+	mu sync.Mutex
+}
+
+func newConn(ifaces []*netInterface, network Network, multicast bool) (*conn, error) {
+	c := &conn{ifaces: ifaces, filter: multicast}
+
+	var v4, v6 bool
+	for _, iface := range ifaces {
+		v4 = v4 || len(iface.v4) > 0
+		v6 = v6 || len(iface.v6) > 0
+	}
+
+	var errs []error
+	if v4 && network != NetworkIPv6 {
+		addr := "0.0.0.0:0"
+		if multicast {
+			// binds the wildcard address with address reuse
+			addr = groupIPv4.String()
+		}
+		if err := c.listen4(addr, multicast); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if v6 && network != NetworkIPv4 {
+		addr := "[::]:0"
+		if multicast {
+			addr = groupIPv6.String()
+		}
+		if err := c.listen6(addr, multicast); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.p4 == nil && c.p6 == nil {
+		if len(errs) > 0 {
+			return nil, errs[0]
+		}
+		return nil, errors.New("mdns: no interface with an address of the network")
+	}
+
+	return c, nil
+}
+
+func (c *conn) listen4(addr string, multicast bool) error {
+	l, err := net.ListenPacket("udp4", addr)
+	if err != nil {
+		return err
+	}
+
+	p := ipv4.NewPacketConn(l)
+	_ = p.SetControlMessage(ipv4.FlagInterface, true)
+	_ = p.SetMulticastLoopback(true)
+	_ = p.SetMulticastTTL(255)
+
+	if multicast {
+		var joined int
+		for _, iface := range c.ifaces {
+			if len(iface.v4) == 0 {
+				continue
+			}
+			if err = p.JoinGroup(iface.Interface, groupIPv4); err == nil {
+				joined++
+			}
+		}
+		if joined == 0 {
+			l.Close()
+			return err
+		}
+	}
+
+	c.p4 = p
+	return nil
+}
+
+func (c *conn) listen6(addr string, multicast bool) error {
+	l, err := net.ListenPacket("udp6", addr)
+	if err != nil {
+		return err
+	}
+
+	p := ipv6.NewPacketConn(l)
+	_ = p.SetControlMessage(ipv6.FlagInterface, true)
+	_ = p.SetMulticastLoopback(true)
+	_ = p.SetMulticastHopLimit(255)
+
+	if multicast {
+		var joined int
+		for _, iface := range c.ifaces {
+			if len(iface.v6) == 0 {
+				continue
+			}
+			if err = p.JoinGroup(iface.Interface, groupIPv6); err == nil {
+				joined++
+			}
+		}
+		if joined == 0 {
+			l.Close()
+			return err
+		}
+	}
+
+	c.p6 = p
+	return nil
+}
+
+// multicast sends the message to the groups on the interface, or on every
+// interface if it's nil.
+func (c *conn) multicast(msg *dns.Msg, iface *netInterface) error {
+	buf, err := msg.Pack()
+	if err != nil {
+		return err
+	}
+
+	ifaces := c.ifaces
+	if iface != nil {
+		ifaces = []*netInterface{iface}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var sent int
+	for _, i := range ifaces {
+		if c.p4 != nil && len(i.v4) > 0 {
+			if err = c.p4.SetMulticastInterface(i.Interface); err == nil {
+				if _, err = c.p4.WriteTo(buf, nil, groupIPv4); err == nil {
+					sent++
+				}
+			}
+		}
+		if c.p6 != nil && len(i.v6) > 0 {
+			if err = c.p6.SetMulticastInterface(i.Interface); err == nil {
+				if _, err = c.p6.WriteTo(buf, nil, groupIPv6); err == nil {
+					sent++
+				}
+			}
+		}
+	}
+
+	if sent == 0 && err != nil {
+		return err
+	}
+	return nil
+}
+
+// unicast sends the message to the address.
+func (c *conn) unicast(msg *dns.Msg, to *net.UDPAddr) error {
+	buf, err := msg.Pack()
+	if err != nil {
+		return err
+	}
+
+	if to.IP.To4() != nil {
+		if c.p4 == nil {
+			return errors.New("mdns: no IPv4 conn")
+		}
+		_, err = c.p4.WriteTo(buf, nil, to)
+		return err
+	}
+
+	if c.p6 == nil {
+		return errors.New("mdns: no IPv6 conn")
+	}
+	_, err = c.p6.WriteTo(buf, nil, to)
+	return err
+}
+
+func (c *conn) iface(index int) (*netInterface, bool) {
+	if index == 0 {
+		// the platform doesn't report the interface
+		return nil, true
+	}
+	for _, i := range c.ifaces {
+		if i.Index == index {
+			return i, true
+		}
+	}
+	// responses to queries may arrive on any interface
+	return nil, !c.filter
+}
+
+// recv sends the messages received on the selected interfaces to the
+// channel until the conn is closed or done is.
+func (c *conn) recv(ch chan<- *packet, done <-chan struct{}) {
+	var wg sync.WaitGroup
+
+	read := func(readFrom func([]byte) (int, int, net.Addr, error)) {
+		defer wg.Done()
+
+		buf := make([]byte, 65536)
+		for {
+			n, index, from, err := readFrom(buf)
+			if err != nil {
+				var nerr net.Error
+				if errors.As(err, &nerr) && nerr.Timeout() {
+					continue
+				}
+				return
+			}
+
+			iface, ok := c.iface(index)
+			if !ok {
+				continue
+			}
+			udp, ok := from.(*net.UDPAddr)
+			if !ok {
+				continue
+			}
+
+			msg := new(dns.Msg)
+			if err := msg.Unpack(buf[:n]); err != nil {
+				continue
+			}
+
+			select {
+			case ch <- &packet{msg: msg, from: udp, iface: iface}:
+			case <-done:
+				return
+			}
+		}
+	}
+
+	if c.p4 != nil {
+		wg.Add(1)
+		go read(func(b []byte) (int, int, net.Addr, error) {
+			n, cm, from, err := c.p4.ReadFrom(b)
+			if cm != nil {
+				return n, cm.IfIndex, from, err
+			}
+			return n, 0, from, err
+		})
+	}
+	if c.p6 != nil {
+		wg.Add(1)
+		go read(func(b []byte) (int, int, net.Addr, error) {
+			n, cm, from, err := c.p6.ReadFrom(b)
+			if cm != nil {
+				return n, cm.IfIndex, from, err
+			}
+			return n, 0, from, err
+		})
+	}
+
+	wg.Wait()
+	close(ch)
+}
+
+func (c *conn) Close() error {
+	if c.p4 != nil {
+		c.p4.Close()
+	}
+	if c.p6 != nil {
+		c.p6.Close()
+	}
+	return nil
+}
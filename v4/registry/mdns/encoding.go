@@ -0,0 +1,70 @@
+package mdns
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"go-micro.dev/v4/registry"
+)
+
+// mdnsTxt is the TXT record of a node, compatible with the mdns registry of
+// go-micro.
+type mdnsTxt struct {
+	Service   string
+	Version   string
+	Endpoints []*registry.Endpoint
+	Metadata  map[string]string
+}
+
+func encode(txt *mdnsTxt) ([]string, error) {
+	b, err := json.Marshal(txt)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	w.Close()
+
+	encoded := hex.EncodeToString(buf.Bytes())
+
+	// individual txt limit
+	var record []string
+	for len(encoded) > 255 {
+		record = append(record, encoded[:255])
+		encoded = encoded[255:]
+	}
+
+	return append(record, encoded), nil
+}
+
+func decode(record []string) (*mdnsTxt, error) {
+	hr, err := hex.DecodeString(strings.Join(record, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(hr))
+	if err != nil {
+		return nil, err
+	}
+
+	rbuf, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	var txt *mdnsTxt
+	if err := json.Unmarshal(rbuf, &txt); err != nil {
+		return nil, err
+	}
+
+	return txt, nil
+}
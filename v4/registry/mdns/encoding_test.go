@@ -0,0 +1,90 @@
+package mdns
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"go-micro.dev/v4/registry"
+)
+
+func TestEncoding(t *testing.T) {
+	// random metadata doesn't compress into a single TXT string
+	r := rand.New(rand.NewSource(1))
+	blob := make([]byte, 512)
+	r.Read(blob)
+
+	testData := []*mdnsTxt{
+		{
+			Service: "test",
+			Version: "1.0.0",
+			Metadata: map[string]string{
+				"foo": "bar",
+			},
+			Endpoints: []*registry.Endpoint{
+				{
+					Name: "endpoint1",
+					Request: &registry.Value{
+						Name: "request",
+						Type: "request",
+					},
+					Response: &registry.Value{
+						Name: "response",
+						Type: "response",
+					},
+					Metadata: map[string]string{
+						"foo1": "bar1",
+					},
+				},
+			},
+		},
+		{
+			Service: "large",
+			Version: "1.0.0",
+			Metadata: map[string]string{
+				"blob": fmt.Sprintf("%x", blob),
+			},
+		},
+	}
+
+	for _, d := range testData {
+		encoded, err := encode(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if d.Service == "large" && len(encoded) < 2 {
+			t.Fatalf("expected the record to be split, got %d strings", len(encoded))
+		}
+
+		for _, txt := range encoded {
+			if len(txt) > 255 {
+				t.Fatalf("one of the TXT strings exceeds 255 characters: %d", len(txt))
+			}
+		}
+
+		decoded, err := decode(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(d, decoded) {
+			t.Fatalf("expected %+v, got %+v", d, decoded)
+		}
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	testData := [][]string{
+		{"not hex"},
+		// valid hex but not zlib
+		{"deadbeef"},
+	}
+
+	for _, d := range testData {
+		if _, err := decode(d); err == nil {
+			t.Fatalf("expected error decoding %v", d)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package mdns
+
+import (
+	"errors"
+	"net"
+	"path"
+)
+
+// netInterface is an interface mdns runs on, with its addresses.
+type netInterface struct {
+	*net.Interface
+	v4 []net.IP
+	v6 []net.IP
+}
+
+func (i *netInterface) ips(network Network) []net.IP {
+	switch network {
+	case NetworkIPv4:
+		return i.v4
+	case NetworkIPv6:
+		return i.v6
+	}
+	return append(append([]net.IP{}, i.v4...), i.v6...)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// interfaces returns the usable interfaces: up, multicast capable, with an
+// address of the network and not excluded. Loopback interfaces are only
+// used if listed or if there's no other.
+func interfaces(allow, deny []string, network Network) ([]*netInterface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var usable, loopback []*netInterface
+	for i := range ifaces {
+		iface := &ifaces[i]
+
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagMulticast == 0 && iface.Flags&net.FlagLoopback == 0 {
+			continue
+		}
+		if len(allow) > 0 && !matchAny(allow, iface.Name) {
+			continue
+		}
+		if matchAny(deny, iface.Name) {
+			continue
+		}
+
+		ni, err := addresses(iface)
+		if err != nil || len(ni.ips(network)) == 0 {
+			continue
+		}
+
+		if iface.Flags&net.FlagLoopback != 0 && len(allow) == 0 {
+			loopback = append(loopback, ni)
+			continue
+		}
+		usable = append(usable, ni)
+	}
+
+	if len(usable) == 0 {
+		usable = loopback
+	}
+	if len(usable) == 0 {
+		return nil, errors.New("mdns: no usable multicast interface")
+	}
+
+	return usable, nil
+}
+
+func addresses(iface *net.Interface) (*netInterface, error) {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+
+	ni := &netInterface{Interface: iface}
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ip4 := ipnet.IP.To4(); ip4 != nil {
+			ni.v4 = append(ni.v4, ip4)
+		} else if ipnet.IP.To16() != nil {
+			ni.v6 = append(ni.v6, ipnet.IP)
+		}
+	}
+
+	return ni, nil
+}
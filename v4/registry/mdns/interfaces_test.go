@@ -0,0 +1,137 @@
+package mdns
+
+import (
+	"net"
+	"testing"
+)
+
+func TestMatchAny(t *testing.T) {
+	testData := []struct {
+		patterns []string
+		name     string
+		match    bool
+	}{
+		{nil, "eth0", false},
+		{[]string{"eth0"}, "eth0", true},
+		{[]string{"eth*"}, "eth1", true},
+		{[]string{"docker*", "veth*"}, "veth12ab", true},
+		{[]string{"eth*"}, "wlan0", false},
+		// malformed patterns never match
+		{[]string{"[eth"}, "eth0", false},
+	}
+
+	for _, d := range testData {
+		if m := matchAny(d.patterns, d.name); m != d.match {
+			t.Errorf("matchAny(%v, %s) = %v, expected %v", d.patterns, d.name, m, d.match)
+		}
+	}
+}
+
+func TestNetInterfaceIPs(t *testing.T) {
+	ni := &netInterface{
+		v4: []net.IP{net.ParseIP("192.0.2.1").To4()},
+		v6: []net.IP{net.ParseIP("2001:db8::1")},
+	}
+
+	if ips := ni.ips(NetworkIPv4); len(ips) != 1 || ips[0].To4() == nil {
+		t.Fatalf("expected the IPv4 address, got %v", ips)
+	}
+	if ips := ni.ips(NetworkIPv6); len(ips) != 1 || ips[0].To4() != nil {
+		t.Fatalf("expected the IPv6 address, got %v", ips)
+	}
+	if ips := ni.ips(NetworkAny); len(ips) != 2 {
+		t.Fatalf("expected both addresses, got %v", ips)
+	}
+}
+
+// loopback returns the name of an up loopback interface with an address of
+// the network.
+func loopback(t *testing.T, network Network) string {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range ifaces {
+		iface := &ifaces[i]
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback == 0 {
+			continue
+		}
+		if ni, err := addresses(iface); err == nil && len(ni.ips(network)) > 0 {
+			return iface.Name
+		}
+	}
+	t.Skip("no loopback interface")
+	return ""
+}
+
+func TestInterfacesFilter(t *testing.T) {
+	lo := loopback(t, NetworkIPv4)
+
+	// loopback interfaces are used when listed
+	ifaces, err := interfaces([]string{lo}, nil, NetworkIPv4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ifaces) != 1 || ifaces[0].Name != lo {
+		t.Fatalf("expected only %s, got %v", lo, names(ifaces))
+	}
+	if len(ifaces[0].v4) == 0 {
+		t.Fatalf("expected IPv4 addresses on %s", lo)
+	}
+
+	// denying overrides allowing
+	if _, err := interfaces([]string{lo}, []string{lo}, NetworkAny); err == nil {
+		t.Fatal("expected no usable interface")
+	}
+
+	if _, err := interfaces([]string{"does-not-exist*"}, nil, NetworkAny); err == nil {
+		t.Fatal("expected no usable interface")
+	}
+
+	// without other interfaces loopback ones are the fallback
+	all, err := interfaces(nil, nil, NetworkIPv4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var deny []string
+	for _, i := range all {
+		if i.Flags&net.FlagLoopback == 0 {
+			deny = append(deny, i.Name)
+		}
+	}
+	ifaces, err = interfaces(nil, deny, NetworkIPv4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range ifaces {
+		if i.Flags&net.FlagLoopback == 0 {
+			t.Fatalf("expected only loopback interfaces, got %v", names(ifaces))
+		}
+		for _, d := range deny {
+			if i.Name == d {
+				t.Fatalf("denied interface %s returned", d)
+			}
+		}
+	}
+
+	// every interface returned has an address of the network
+	for _, network := range []Network{NetworkIPv4, NetworkIPv6} {
+		ifaces, err := interfaces(nil, nil, network)
+		if err != nil {
+			continue
+		}
+		for _, i := range ifaces {
+			if len(i.ips(network)) == 0 {
+				t.Fatalf("interface %s has no %s address", i.Name, network)
+			}
+		}
+	}
+}
+
+func names(ifaces []*netInterface) []string {
+	var n []string
+	for _, i := range ifaces {
+		n = append(n, i.Name)
+	}
+	return n
+}
@@ -2,15 +2,316 @@
 package mdns
 
 import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "go-micro.dev/v4/logger"
 	"go-micro.dev/v4/registry"
 	"go-micro.dev/v4/util/cmd"
 )
 
+const (
+	// use a .micro domain rather than .local
+	defaultDomain = "micro"
+
+	// wildcard service of the entries used for list queries
+	wildcard     = "_services"
+	wildcardPort = 9999
+)
+
 func init() {
 	cmd.DefaultRegistries["mdns"] = NewRegistry
 }
 
-// NewRegistry returns a new mdns registry.
+type mdnsRegistry struct {
+	opts    *registry.Options
+	domain  string
+	network Network
+	allow   []string
+	deny    []string
+
+	sync.Mutex
+	// registered node ids by service
+	services  map[string][]string
+	responder *responder
+}
+
+// NewRegistry returns a new mdns registry. It advertises and browses on
+// every usable interface, over IPv4 and IPv6, see Interfaces,
+// ExcludeInterfaces and WithNetwork to restrict them.
 func NewRegistry(opts ...registry.Option) registry.Registry {
-	return registry.NewRegistry(opts...)
+	m := &mdnsRegistry{
+		services: make(map[string][]string),
+	}
+	m.configure(append([]registry.Option{registry.Timeout(time.Millisecond * 100)}, opts...)...)
+	return m
+}
+
+func (m *mdnsRegistry) configure(opts ...registry.Option) {
+	if m.opts == nil {
+		m.opts = registry.NewOptions(opts...)
+	} else {
+		for _, o := range opts {
+			o(m.opts)
+		}
+	}
+
+	m.domain = defaultDomain
+	if d, ok := m.opts.Context.Value(domainKey{}).(string); ok && len(d) > 0 {
+		m.domain = d
+	}
+	m.network, _ = m.opts.Context.Value(networkKey{}).(Network)
+	m.allow = getStrings(m.opts, interfacesKey{})
+	m.deny = getStrings(m.opts, excludeInterfacesKey{})
+}
+
+func (m *mdnsRegistry) Init(opts ...registry.Option) error {
+	m.Lock()
+	defer m.Unlock()
+	m.configure(opts...)
+	return nil
+}
+
+func (m *mdnsRegistry) Options() registry.Options {
+	return *m.opts
+}
+
+func (m *mdnsRegistry) interfaces() ([]*netInterface, error) {
+	return interfaces(m.allow, m.deny, m.network)
+}
+
+// getResponder starts the responder on the first registration.
+func (m *mdnsRegistry) getResponder() (*responder, error) {
+	if m.responder != nil {
+		return m.responder, nil
+	}
+
+	ifaces, err := m.interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := newResponder(ifaces, m.network, m.domain)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(ifaces))
+	for _, i := range ifaces {
+		names = append(names, i.Name)
+	}
+	m.opts.Logger.Logf(log.DebugLevel, "[mdns] advertising on %s", strings.Join(names, ", "))
+
+	m.responder = r
+	return r, nil
+}
+
+func (m *mdnsRegistry) Register(service *registry.Service, opts ...registry.RegisterOption) error {
+	m.Lock()
+	defer m.Unlock()
+
+	r, err := m.getResponder()
+	if err != nil {
+		return err
+	}
+
+	ids, ok := m.services[service.Name]
+	// first entry, create wildcard used for list queries
+	if !ok {
+		err := r.add(&zoneNode{
+			instance: service.Name,
+			service:  wildcard,
+			port:     wildcardPort,
+			ip:       net.IPv4zero,
+			dnssd:    true,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var gerr error
+
+	for _, node := range service.Nodes {
+		var seen bool
+		for _, id := range ids {
+			if id == node.Id {
+				seen = true
+				break
+			}
+		}
+		// already registered
+		if seen {
+			continue
+		}
+
+		txt, err := encode(&mdnsTxt{
+			Service:   service.Name,
+			Version:   service.Version,
+			Endpoints: service.Endpoints,
+			Metadata:  node.Metadata,
+		})
+		if err != nil {
+			gerr = err
+			continue
+		}
+
+		host, pt, err := net.SplitHostPort(node.Address)
+		if err != nil {
+			gerr = err
+			continue
+		}
+		port, _ := strconv.Atoi(pt)
+
+		// nodes listening on every address advertise those of the interfaces
+		ip := net.ParseIP(host)
+		if ip != nil && ip.IsUnspecified() {
+			ip = nil
+		}
+
+		err = r.add(&zoneNode{
+			instance: node.Id,
+			service:  service.Name,
+			port:     port,
+			ip:       ip,
+			txt:      txt,
+		})
+		if err != nil {
+			gerr = err
+			continue
+		}
+
+		ids = append(ids, node.Id)
+	}
+
+	m.services[service.Name] = ids
+
+	return gerr
+}
+
+func (m *mdnsRegistry) Deregister(service *registry.Service, opts ...registry.DeregisterOption) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.responder == nil {
+		return nil
+	}
+
+	var ids []string
+	for _, id := range m.services[service.Name] {
+		var remove bool
+		for _, node := range service.Nodes {
+			if node.Id == id {
+				remove = true
+				break
+			}
+		}
+
+		if remove {
+			m.responder.remove(id, service.Name)
+		} else {
+			ids = append(ids, id)
+		}
+	}
+
+	// remove the wildcard with the last node
+	if len(ids) == 0 {
+		m.responder.remove(service.Name, wildcard)
+		delete(m.services, service.Name)
+	} else {
+		m.services[service.Name] = ids
+	}
+
+	if m.responder.empty() {
+		m.responder.Close()
+		m.responder = nil
+	}
+
+	return nil
+}
+
+func (m *mdnsRegistry) GetService(service string, opts ...registry.GetOption) ([]*registry.Service, error) {
+	col, err := m.query(fmt.Sprintf("%s.%s.", service, m.domain), m.opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	suffix := fmt.Sprintf(".%s.%s.", service, m.domain)
+	serviceMap := make(map[string]*registry.Service)
+
+	for _, e := range col.complete() {
+		if e.ttl == 0 || !strings.HasSuffix(e.name, suffix) {
+			continue
+		}
+
+		txt, err := decode(e.txt)
+		if err != nil || txt.Service != service {
+			continue
+		}
+
+		addr := col.address(e)
+		if len(addr) == 0 {
+			m.opts.Logger.Logf(log.InfoLevel, "[mdns]: invalid endpoint received: %s", e.name)
+			continue
+		}
+
+		s, ok := serviceMap[txt.Version]
+		if !ok {
+			s = &registry.Service{
+				Name:      txt.Service,
+				Version:   txt.Version,
+				Endpoints: txt.Endpoints,
+			}
+			serviceMap[txt.Version] = s
+		}
+
+		s.Nodes = append(s.Nodes, &registry.Node{
+			Id:       strings.TrimSuffix(e.name, suffix),
+			Address:  addr,
+			Metadata: txt.Metadata,
+		})
+	}
+
+	services := make([]*registry.Service, 0, len(serviceMap))
+	for _, s := range serviceMap {
+		services = append(services, s)
+	}
+
+	return services, nil
+}
+
+func (m *mdnsRegistry) ListServices(opts ...registry.ListOption) ([]*registry.Service, error) {
+	col, err := m.query(fmt.Sprintf("%s.%s.", wildcard, m.domain), m.opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	suffix := fmt.Sprintf(".%s.%s.", wildcard, m.domain)
+	seen := make(map[string]bool)
+
+	var services []*registry.Service
+	for _, e := range col.entries {
+		if !e.hasSRV || e.ttl == 0 || !strings.HasSuffix(e.name, suffix) {
+			continue
+		}
+		name := strings.TrimSuffix(e.name, suffix)
+		if !seen[name] {
+			seen[name] = true
+			services = append(services, &registry.Service{Name: name})
+		}
+	}
+
+	return services, nil
+}
+
+func (m *mdnsRegistry) Watch(opts ...registry.WatchOption) (registry.Watcher, error) {
+	return newWatcher(m, opts...)
+}
+
+func (m *mdnsRegistry) String() string {
+	return "mdns"
 }
@@ -0,0 +1,372 @@
+package mdns
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/util/mdns"
+)
+
+var testServices = []*registry.Service{
+	{
+		Name:    "test1",
+		Version: "1.0.1",
+		Nodes: []*registry.Node{
+			{
+				Id:      "test1-1",
+				Address: "10.0.0.1:10001",
+				Metadata: map[string]string{
+					"foo": "bar",
+				},
+			},
+		},
+	},
+	{
+		Name:    "test2",
+		Version: "1.0.2",
+		Nodes: []*registry.Node{
+			{
+				Id:      "test2-1",
+				Address: "10.0.0.2:10002",
+				Metadata: map[string]string{
+					"foo2": "bar2",
+				},
+			},
+		},
+	},
+}
+
+func newTestRegistry(t *testing.T, opts ...registry.Option) *mdnsRegistry {
+	m := NewRegistry(append([]registry.Option{registry.Timeout(time.Millisecond * 500)}, opts...)...).(*mdnsRegistry)
+	if _, err := m.interfaces(); err != nil {
+		t.Skip(err)
+	}
+	return m
+}
+
+func TestRegisterZones(t *testing.T) {
+	m := newTestRegistry(t, Domain("zones"))
+
+	service := &registry.Service{
+		Name:    "test",
+		Version: "1.0.0",
+		Nodes: []*registry.Node{
+			{Id: "test-1", Address: "10.0.0.1:10001"},
+			{Id: "test-2", Address: "10.0.0.2:10002"},
+		},
+	}
+
+	if err := m.Register(service); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Deregister(service)
+
+	// registering again doesn't duplicate the nodes
+	if err := m.Register(service); err != nil {
+		t.Fatal(err)
+	}
+	if ids := m.services["test"]; len(ids) != 2 {
+		t.Fatalf("expected 2 node ids, got %v", ids)
+	}
+
+	m.responder.RLock()
+	nodes := len(m.responder.nodes)
+	_, wildcard := m.responder.nodes["test._services.zones."]
+	n, ok := m.responder.nodes["test-1.test.zones."]
+	m.responder.RUnlock()
+
+	if nodes != 3 || !wildcard {
+		t.Fatalf("expected the two nodes and the wildcard entry, got %d entries", nodes)
+	}
+	if !ok {
+		t.Fatal("expected an entry for test-1")
+	}
+
+	z, err := n.zone("zones", nil, m.responder)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var srv *dns.SRV
+	var a *dns.A
+	var txt *dns.TXT
+	for _, rr := range z.Records(dns.Question{Name: "test.zones.", Qtype: dns.TypePTR, Qclass: dns.ClassINET}) {
+		switch rr := rr.(type) {
+		case *dns.SRV:
+			srv = rr
+		case *dns.A:
+			a = rr
+		case *dns.TXT:
+			txt = rr
+		}
+	}
+	if srv == nil || srv.Port != 10001 || srv.Target != "test-1.test.zones." {
+		t.Fatalf("unexpected SRV record %v", srv)
+	}
+	if a == nil || !a.A.Equal(net.ParseIP("10.0.0.1")) {
+		t.Fatalf("unexpected A record %v", a)
+	}
+	if txt == nil {
+		t.Fatal("expected a TXT record")
+	}
+	if d, err := decode(txt.Txt); err != nil || d.Service != "test" || d.Version != "1.0.0" {
+		t.Fatalf("unexpected TXT record %v: %v", d, err)
+	}
+
+	// removing one node keeps the wildcard
+	if err := m.Deregister(&registry.Service{Name: "test", Nodes: service.Nodes[:1]}); err != nil {
+		t.Fatal(err)
+	}
+	if ids := m.services["test"]; len(ids) != 1 || ids[0] != "test-2" {
+		t.Fatalf("expected test-2 to be left, got %v", ids)
+	}
+	m.responder.RLock()
+	_, wildcard = m.responder.nodes["test._services.zones."]
+	m.responder.RUnlock()
+	if !wildcard {
+		t.Fatal("expected the wildcard entry to be kept")
+	}
+
+	// removing the last one stops the responder
+	if err := m.Deregister(service); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.services["test"]; ok {
+		t.Fatal("expected the service to be removed")
+	}
+	if m.responder != nil {
+		t.Fatal("expected the responder to be stopped")
+	}
+}
+
+func TestRegisterInvalidAddress(t *testing.T) {
+	m := newTestRegistry(t)
+
+	service := &registry.Service{
+		Name:  "test",
+		Nodes: []*registry.Node{{Id: "test-1", Address: "no port"}},
+	}
+	defer m.Deregister(service)
+
+	if err := m.Register(service); err == nil {
+		t.Fatal("expected error registering a node without port")
+	}
+	if ids := m.services["test"]; len(ids) != 0 {
+		t.Fatalf("expected no node ids, got %v", ids)
+	}
+}
+
+func TestMDNS(t *testing.T) {
+	m := newTestRegistry(t)
+
+	for _, service := range testServices {
+		if err := m.Register(service); err != nil {
+			t.Fatal(err)
+		}
+		defer m.Deregister(service)
+	}
+
+	for _, service := range testServices {
+		s, err := m.GetService(service.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(s) != 1 {
+			t.Fatalf("expected one result for %s, got %d", service.Name, len(s))
+		}
+		if s[0].Name != service.Name || s[0].Version != service.Version {
+			t.Fatalf("expected %s %s, got %s %s", service.Name, service.Version, s[0].Name, s[0].Version)
+		}
+		if len(s[0].Nodes) != 1 {
+			t.Fatalf("expected one node, got %d", len(s[0].Nodes))
+		}
+
+		node := s[0].Nodes[0]
+		if node.Id != service.Nodes[0].Id || node.Address != service.Nodes[0].Address {
+			t.Fatalf("expected %s at %s, got %s at %s", service.Nodes[0].Id, service.Nodes[0].Address, node.Id, node.Address)
+		}
+	}
+
+	services, err := m.ListServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	listed := make(map[string]bool)
+	for _, s := range services {
+		listed[s.Name] = true
+	}
+	for _, service := range testServices {
+		if !listed[service.Name] {
+			t.Fatalf("expected %s to be listed, got %v", service.Name, services)
+		}
+	}
+
+	for _, service := range testServices {
+		if err := m.Deregister(service); err != nil {
+			t.Fatal(err)
+		}
+		s, err := m.GetService(service.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(s) != 0 {
+			t.Fatalf("expected %s to be deregistered, got %d results", service.Name, len(s))
+		}
+	}
+}
+
+// announcement returns the response multicast for the node, with the TTL if
+// it isn't negative.
+func announcement(t *testing.T, service *registry.Service, ttl int) *packet {
+	txt, err := encode(&mdnsTxt{
+		Service:  service.Name,
+		Version:  service.Version,
+		Metadata: service.Nodes[0].Metadata,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	host, pt, _ := net.SplitHostPort(service.Nodes[0].Address)
+	n := &zoneNode{
+		instance: service.Nodes[0].Id,
+		service:  service.Name,
+		ip:       net.ParseIP(host),
+		txt:      txt,
+		zones:    make(map[int]*mdns.MDNSService),
+	}
+	n.port, _ = strconv.Atoi(pt)
+
+	r := &responder{conn: &conn{}, domain: defaultDomain}
+	z, err := n.zone(defaultDomain, nil, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recs := z.Records(dns.Question{Name: n.name(defaultDomain), Qtype: dns.TypeANY, Qclass: dns.ClassINET})
+	if ttl >= 0 {
+		for _, rr := range recs {
+			rr.Header().Ttl = uint32(ttl)
+		}
+	}
+
+	return &packet{
+		msg:  &dns.Msg{MsgHdr: dns.MsgHdr{Response: true}, Answer: recs},
+		from: &net.UDPAddr{IP: net.ParseIP(host), Port: mdnsPort},
+	}
+}
+
+func TestWatcherResults(t *testing.T) {
+	w := &mdnsWatcher{
+		domain: defaultDomain,
+		conn:   &conn{},
+		ch:     make(chan *packet, 4),
+		exit:   make(chan struct{}),
+	}
+
+	w.ch <- announcement(t, testServices[0], -1)
+	w.ch <- announcement(t, testServices[0], 0)
+
+	for _, action := range []string{"create", "delete"} {
+		r, err := w.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Action != action {
+			t.Fatalf("expected %s, got %s", action, r.Action)
+		}
+		if r.Service.Name != "test1" || r.Service.Version != "1.0.1" {
+			t.Fatalf("unexpected service %s %s", r.Service.Name, r.Service.Version)
+		}
+		node := r.Service.Nodes[0]
+		if node.Id != "test1-1" || node.Address != "10.0.0.1:10001" || node.Metadata["foo"] != "bar" {
+			t.Fatalf("unexpected node %+v", node)
+		}
+	}
+
+	w.Stop()
+	if _, err := w.Next(); err != registry.ErrWatcherStopped {
+		t.Fatalf("expected %v, got %v", registry.ErrWatcherStopped, err)
+	}
+	// stopping twice is fine
+	w.Stop()
+}
+
+func TestWatcherFilter(t *testing.T) {
+	w := &mdnsWatcher{
+		wo:     registry.WatchOptions{Service: "test2"},
+		domain: defaultDomain,
+	}
+
+	if r := w.results(announcement(t, testServices[0], -1)); len(r) != 0 {
+		t.Fatalf("expected test1 to be filtered, got %d results", len(r))
+	}
+	if r := w.results(announcement(t, testServices[1], -1)); len(r) != 1 {
+		t.Fatalf("expected a result for test2, got %d", len(r))
+	}
+
+	// other domains are ignored
+	w = &mdnsWatcher{domain: "other"}
+	if r := w.results(announcement(t, testServices[1], -1)); len(r) != 0 {
+		t.Fatalf("expected results of other domains to be ignored, got %d", len(r))
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	m := newTestRegistry(t)
+
+	w, err := m.Watch()
+	if err != nil {
+		t.Skip(err)
+	}
+	defer w.Stop()
+
+	service := testServices[1]
+	if err := m.Register(service); err != nil {
+		t.Fatal(err)
+	}
+
+	results := make(chan *registry.Result, 16)
+	go func() {
+		for {
+			r, err := w.Next()
+			if err != nil {
+				close(results)
+				return
+			}
+			results <- r
+		}
+	}()
+
+	wait := func(action string) {
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case r, ok := <-results:
+				if !ok {
+					t.Fatal("watcher stopped")
+				}
+				if r.Action == action && r.Service.Name == service.Name {
+					return
+				}
+			case <-timeout:
+				t.Fatalf("timed out waiting for %s", action)
+			}
+		}
+	}
+
+	wait("create")
+
+	if err := m.Deregister(service); err != nil {
+		t.Fatal(err)
+	}
+	wait("delete")
+
+	w.Stop()
+	for range results {
+	}
+}
@@ -0,0 +1,64 @@
+package mdns
+
+import (
+	"context"
+
+	"go-micro.dev/v4/registry"
+)
+
+type domainKey struct{}
+type interfacesKey struct{}
+type excludeInterfacesKey struct{}
+type networkKey struct{}
+
+// Network restricts discovery to one IP version.
+type Network string
+
+const (
+	// NetworkAny advertises and browses on IPv4 and IPv6.
+	NetworkAny Network = ""
+	// NetworkIPv4 only uses 224.0.0.251.
+	NetworkIPv4 Network = "ip4"
+	// NetworkIPv6 only uses ff02::fb.
+	NetworkIPv6 Network = "ip6"
+)
+
+func setRegistryOption(k, v interface{}) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+// Domain sets the mdns domain. Defaults to micro.
+func Domain(d string) registry.Option {
+	return setRegistryOption(domainKey{}, d)
+}
+
+// Interfaces restricts discovery to the interfaces matching the names,
+// which may be patterns as in path.Match, e.g. "eth*". Loopback interfaces
+// are only used if no other interface is usable, or they're listed here.
+func Interfaces(names ...string) registry.Option {
+	return setRegistryOption(interfacesKey{}, names)
+}
+
+// ExcludeInterfaces excludes the interfaces matching the names from
+// discovery, e.g. "docker*" or "veth*".
+func ExcludeInterfaces(names ...string) registry.Option {
+	return setRegistryOption(excludeInterfacesKey{}, names)
+}
+
+// WithNetwork restricts discovery to IPv4 or IPv6.
+func WithNetwork(n Network) registry.Option {
+	return setRegistryOption(networkKey{}, n)
+}
+
+func getStrings(o *registry.Options, k interface{}) []string {
+	if o.Context == nil {
+		return nil
+	}
+	v, _ := o.Context.Value(k).([]string)
+	return v
+}
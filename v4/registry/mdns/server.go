@@ -0,0 +1,248 @@
+package mdns
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"go-micro.dev/v4/util/mdns"
+)
+
+// zoneNode is an advertised instance. Nodes without an IP advertise the
+// addresses of the interface they answer on.
+type zoneNode struct {
+	instance string
+	service  string
+	port     int
+	ip       net.IP
+	txt      []string
+	// dnssd answers service enumeration queries too
+	dnssd bool
+
+	sync.Mutex
+	zones map[int]*mdns.MDNSService
+}
+
+func (n *zoneNode) name(domain string) string {
+	return fmt.Sprintf("%s.%s.%s.", n.instance, n.service, domain)
+}
+
+// zone returns the records of the node on the interface. The instance name
+// is the target of the SRV record, so instances on one host don't share
+// address records.
+func (n *zoneNode) zone(domain string, iface *netInterface, r *responder) (mdns.Zone, error) {
+	index := 0
+	if iface != nil {
+		index = iface.Index
+	}
+
+	n.Lock()
+	defer n.Unlock()
+
+	z, ok := n.zones[index]
+	if !ok {
+		var ips []net.IP
+		switch {
+		case n.ip != nil:
+			ips = []net.IP{n.ip}
+		case iface != nil:
+			ips = iface.ips(r.network)
+		default:
+			for _, i := range r.conn.ifaces {
+				ips = append(ips, i.ips(r.network)...)
+			}
+		}
+
+		var err error
+		z, err = mdns.NewMDNSService(n.instance, n.service, domain+".", n.name(domain), n.port, ips, n.txt)
+		if err != nil {
+			return nil, err
+		}
+		n.zones[index] = z
+	}
+
+	if n.dnssd {
+		return &mdns.DNSSDService{MDNSService: z}, nil
+	}
+	return z, nil
+}
+
+// responder answers the queries for the registered nodes on every interface.
+type responder struct {
+	conn    *conn
+	domain  string
+	network Network
+
+	sync.RWMutex
+	nodes map[string]*zoneNode
+
+	done chan struct{}
+}
+
+func newResponder(ifaces []*netInterface, network Network, domain string) (*responder, error) {
+	c, err := newConn(ifaces, network, true)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &responder{
+		conn:    c,
+		domain:  domain,
+		network: network,
+		nodes:   make(map[string]*zoneNode),
+		done:    make(chan struct{}),
+	}
+
+	ch := make(chan *packet, 32)
+	go c.recv(ch, r.done)
+	go func() {
+		for p := range ch {
+			r.handle(p)
+		}
+	}()
+
+	return r, nil
+}
+
+func (r *responder) add(n *zoneNode) error {
+	n.zones = make(map[int]*mdns.MDNSService)
+
+	// fail on invalid nodes now rather than on the first query
+	if _, err := n.zone(r.domain, nil, r); err != nil {
+		return err
+	}
+
+	r.Lock()
+	r.nodes[n.name(r.domain)] = n
+	r.Unlock()
+
+	go r.announce(n)
+	return nil
+}
+
+func (r *responder) remove(instance, service string) {
+	name := fmt.Sprintf("%s.%s.%s.", instance, service, r.domain)
+
+	r.Lock()
+	n, ok := r.nodes[name]
+	delete(r.nodes, name)
+	r.Unlock()
+
+	if ok {
+		r.send(n, 0)
+	}
+}
+
+func (r *responder) empty() bool {
+	r.RLock()
+	defer r.RUnlock()
+	return len(r.nodes) == 0
+}
+
+func (r *responder) Close() error {
+	close(r.done)
+	return r.conn.Close()
+}
+
+// announce sends two unsolicited responses one second apart, as RFC 6762
+// requires.
+func (r *responder) announce(n *zoneNode) {
+	for i := 0; i < 2; i++ {
+		if i > 0 {
+			select {
+			case <-r.done:
+				return
+			case <-time.After(time.Second):
+			}
+		}
+
+		r.RLock()
+		_, ok := r.nodes[n.name(r.domain)]
+		r.RUnlock()
+		if !ok {
+			return
+		}
+
+		r.send(n, -1)
+	}
+}
+
+// send multicasts the records of the node on every interface, with the TTL
+// if it isn't negative.
+func (r *responder) send(n *zoneNode, ttl int) {
+	for _, iface := range r.conn.ifaces {
+		z, err := n.zone(r.domain, iface, r)
+		if err != nil {
+			continue
+		}
+
+		recs := z.Records(dns.Question{Name: n.name(r.domain), Qtype: dns.TypeANY, Qclass: dns.ClassINET})
+		if ttl >= 0 {
+			for _, rr := range recs {
+				rr.Header().Ttl = uint32(ttl)
+			}
+		}
+
+		msg := &dns.Msg{
+			MsgHdr:   dns.MsgHdr{Response: true, Authoritative: true},
+			Compress: true,
+			Answer:   recs,
+		}
+		_ = r.conn.multicast(msg, iface)
+	}
+}
+
+func (r *responder) handle(p *packet) {
+	query := p.msg
+	if query.Response || query.Opcode != dns.OpcodeQuery || query.Rcode != 0 {
+		return
+	}
+
+	r.RLock()
+	nodes := make([]*zoneNode, 0, len(r.nodes))
+	for _, n := range r.nodes {
+		nodes = append(nodes, n)
+	}
+	r.RUnlock()
+
+	// queries from other ports than the mdns one expect unicast responses,
+	// see section 6.7 of RFC 6762
+	unicast := p.from.Port != mdnsPort
+
+	var answer []dns.RR
+	for _, q := range query.Question {
+		if q.Qclass&(1<<15) != 0 {
+			unicast = true
+		}
+		for _, n := range nodes {
+			z, err := n.zone(r.domain, p.iface, r)
+			if err != nil {
+				continue
+			}
+			answer = append(answer, z.Records(q)...)
+		}
+	}
+	if len(answer) == 0 {
+		return
+	}
+
+	resp := &dns.Msg{
+		MsgHdr: dns.MsgHdr{
+			Response:      true,
+			Opcode:        dns.OpcodeQuery,
+			Authoritative: true,
+		},
+		Compress: true,
+		Question: query.Question,
+		Answer:   answer,
+	}
+
+	if unicast {
+		resp.Id = query.Id
+		_ = r.conn.unicast(resp, p.from)
+		return
+	}
+	_ = r.conn.multicast(resp, p.iface)
+}
@@ -0,0 +1,132 @@
+package mdns
+
+import (
+	"fmt"
+	"strings"
+
+	"go-micro.dev/v4/registry"
+)
+
+type mdnsWatcher struct {
+	wo     registry.WatchOptions
+	domain string
+	conn   *conn
+
+	ch   chan *packet
+	exit chan struct{}
+
+	// results of the last message not returned yet
+	pending []*registry.Result
+}
+
+// newWatcher listens to the announcements and responses multicast on the
+// interfaces.
+func newWatcher(m *mdnsRegistry, opts ...registry.WatchOption) (registry.Watcher, error) {
+	var wo registry.WatchOptions
+	for _, o := range opts {
+		o(&wo)
+	}
+
+	ifaces, err := m.interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := newConn(ifaces, m.network, true)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &mdnsWatcher{
+		wo:     wo,
+		domain: m.domain,
+		conn:   c,
+		ch:     make(chan *packet, 32),
+		exit:   make(chan struct{}),
+	}
+	go c.recv(w.ch, w.exit)
+
+	return w, nil
+}
+
+func (m *mdnsWatcher) Next() (*registry.Result, error) {
+	for {
+		if len(m.pending) > 0 {
+			r := m.pending[0]
+			m.pending = m.pending[1:]
+			return r, nil
+		}
+
+		select {
+		case p, ok := <-m.ch:
+			if !ok {
+				return nil, registry.ErrWatcherStopped
+			}
+			if p.msg.Response {
+				m.pending = m.results(p)
+			}
+		case <-m.exit:
+			return nil, registry.ErrWatcherStopped
+		}
+	}
+}
+
+func (m *mdnsWatcher) results(p *packet) []*registry.Result {
+	col := newCollector()
+	col.add(p)
+
+	var results []*registry.Result
+	for _, e := range col.complete() {
+		txt, err := decode(e.txt)
+		if err != nil {
+			continue
+		}
+
+		if len(txt.Service) == 0 || len(txt.Version) == 0 {
+			continue
+		}
+
+		// Filter watch options
+		// wo.Service: Only keep services we care about
+		if len(m.wo.Service) > 0 && txt.Service != m.wo.Service {
+			continue
+		}
+
+		// skip anything without the domain we care about
+		suffix := fmt.Sprintf(".%s.%s.", txt.Service, m.domain)
+		if !strings.HasSuffix(e.name, suffix) {
+			continue
+		}
+
+		action := "create"
+		if e.ttl == 0 {
+			action = "delete"
+		}
+
+		results = append(results, &registry.Result{
+			Action: action,
+			Service: &registry.Service{
+				Name:      txt.Service,
+				Version:   txt.Version,
+				Endpoints: txt.Endpoints,
+				Nodes: []*registry.Node{{
+					Id:       strings.TrimSuffix(e.name, suffix),
+					Address:  col.address(e),
+					Metadata: txt.Metadata,
+				}},
+			},
+		})
+	}
+
+	return results
+}
+
+func (m *mdnsWatcher) Stop() {
+	select {
+	case <-m.exit:
+		return
+	default:
+		close(m.exit)
+		m.conn.Close()
+	}
+}
@@ -3,6 +3,7 @@ module github.com/go-micro/plugins/v4/registry/memory
 go 1.17
 
 require (
+	github.com/go-micro/plugins/v4/util/clock v1.0.0
 	github.com/google/uuid v1.2.0
 	go-micro.dev/v4 v4.9.0
 )
@@ -12,3 +13,5 @@ require (
 	golang.org/x/net v0.0.0-20210510120150-4163338589ed // indirect
 	golang.org/x/sys v0.0.0-20210502180810-71e4cd670f79 // indirect
 )
+
+replace github.com/go-micro/plugins/v4/util/clock => ../../util/clock
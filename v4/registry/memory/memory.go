@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/go-micro/plugins/v4/util/clock"
 	"github.com/google/uuid"
 	"go-micro.dev/v4/logger"
 	"go-micro.dev/v4/registry"
@@ -32,6 +33,7 @@ type record struct {
 
 type Registry struct {
 	options registry.Options
+	clock   clock.Clock
 
 	sync.RWMutex
 	records  map[string]map[string]*record
@@ -55,6 +57,7 @@ func NewRegistry(opts ...registry.Option) registry.Registry {
 
 	reg := &Registry{
 		options:  options,
+		clock:    getClock(options.Context),
 		records:  records,
 		watchers: make(map[string]*Watcher),
 	}
@@ -65,17 +68,17 @@ func NewRegistry(opts ...registry.Option) registry.Registry {
 }
 
 func (m *Registry) ttlPrune() {
-	prune := time.NewTicker(ttlPruneTime)
+	prune := m.clock.NewTicker(ttlPruneTime)
 	defer prune.Stop()
 
 	for {
 		select {
-		case <-prune.C:
+		case <-prune.C():
 			m.Lock()
 			for name, records := range m.records {
 				for version, record := range records {
 					for id, n := range record.Nodes {
-						if n.TTL != 0 && time.Since(n.LastSeen) > n.TTL {
+						if n.TTL != 0 && m.clock.Since(n.LastSeen) > n.TTL {
 							m.options.Logger.Logf(logger.DebugLevel, "Registry TTL expired for node %s of service %s", n.Id, name)
 							delete(m.records[name][version].Nodes, id)
 						}
@@ -152,7 +155,7 @@ func (m *Registry) Register(s *registry.Service, opts ...registry.RegisterOption
 		o(&options)
 	}
 
-	r := serviceToRecord(s, options.TTL)
+	r := serviceToRecord(s, options.TTL, m.clock.Now())
 
 	if _, ok := m.records[s.Name]; !ok {
 		m.records[s.Name] = make(map[string]*record)
@@ -179,7 +182,7 @@ func (m *Registry) Register(s *registry.Service, opts ...registry.RegisterOption
 						Metadata: metadata,
 					},
 					TTL:      options.TTL,
-					LastSeen: time.Now(),
+					LastSeen: m.clock.Now(),
 				}
 			}
 		}
@@ -195,7 +198,7 @@ func (m *Registry) Register(s *registry.Service, opts ...registry.RegisterOption
 	for _, n := range s.Nodes {
 		log.Logf(logger.DebugLevel, "Updated registration for service: %s, version: %s", s.Name, s.Version)
 		m.records[s.Name][s.Version].Nodes[n.Id].TTL = options.TTL
-		m.records[s.Name][s.Version].Nodes[n.Id].LastSeen = time.Now()
+		m.records[s.Name][s.Version].Nodes[n.Id].LastSeen = m.clock.Now()
 	}
 
 	return nil
@@ -3,9 +3,11 @@ package memory
 import (
 	"fmt"
 	"os"
+	"runtime"
 	"testing"
 	"time"
 
+	"github.com/go-micro/plugins/v4/util/clock"
 	"go-micro.dev/v4/registry"
 )
 
@@ -193,6 +195,49 @@ func TestMemoryRegistryTTL(t *testing.T) {
 	}
 }
 
+func TestMemoryRegistryTTLClock(t *testing.T) {
+	c := clock.NewMock(time.Now())
+	m := NewRegistry(Clock(c))
+
+	service := &registry.Service{
+		Name:    "foo",
+		Version: "1.0.0",
+		Nodes:   []*registry.Node{{Id: "foo-1", Address: "10.0.0.1:8080"}},
+	}
+	if err := m.Register(service, registry.RegisterTTL(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	// not expired before the TTL passed
+	c.BlockUntil(1)
+	c.Add(30 * time.Second)
+	if err := m.Register(service, registry.RegisterTTL(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	c.Add(45 * time.Second)
+
+	svcs, err := m.GetService("foo")
+	if err != nil || len(svcs[0].Nodes) != 1 {
+		t.Fatalf("Expected the refreshed node to be registered, got %v %v", svcs, err)
+	}
+
+	c.Add(time.Minute)
+
+	// the pruner runs on its own goroutine
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		svcs, err := m.GetService("foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(svcs[0].Nodes) == 0 {
+			return
+		}
+		runtime.Gosched()
+	}
+	t.Fatal("Expected the node to expire")
+}
+
 func TestMemoryRegistryTTLConcurrent(t *testing.T) {
 	concurrency := 1000
 	waitTime := ttlPruneTime * 2
@@ -2,11 +2,14 @@ package memory
 
 import (
 	"context"
+	"time"
 
+	"github.com/go-micro/plugins/v4/util/clock"
 	"go-micro.dev/v4/registry"
 )
 
 type servicesKey struct{}
+type clockKey struct{}
 
 func getServiceRecords(ctx context.Context) map[string]map[string]*record {
 	memServices, ok := ctx.Value(servicesKey{}).(map[string][]*registry.Service)
@@ -22,7 +25,7 @@ func getServiceRecords(ctx context.Context) map[string]map[string]*record {
 		}
 		// go through every version of the service
 		for _, s := range svc {
-			services[s.Name][s.Version] = serviceToRecord(s, 0)
+			services[s.Name][s.Version] = serviceToRecord(s, 0, time.Time{})
 		}
 	}
 
@@ -38,3 +41,21 @@ func Services(s map[string][]*registry.Service) registry.Option {
 		o.Context = context.WithValue(o.Context, servicesKey{}, s)
 	}
 }
+
+// Clock sets the clock the TTLs of the nodes are checked against, e.g. a
+// mock clock in tests. Defaults to the system clock.
+func Clock(c clock.Clock) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, clockKey{}, c)
+	}
+}
+
+func getClock(ctx context.Context) clock.Clock {
+	if c, ok := ctx.Value(clockKey{}).(clock.Clock); ok && c != nil {
+		return c
+	}
+	return clock.DefaultClock
+}
@@ -6,7 +6,7 @@ import (
 	"go-micro.dev/v4/registry"
 )
 
-func serviceToRecord(s *registry.Service, ttl time.Duration) *record {
+func serviceToRecord(s *registry.Service, ttl time.Duration, now time.Time) *record {
 	metadata := make(map[string]string, len(s.Metadata))
 	for k, v := range s.Metadata {
 		metadata[k] = v
@@ -17,7 +17,7 @@ func serviceToRecord(s *registry.Service, ttl time.Duration) *record {
 		nodes[n.Id] = &node{
 			Node:     n,
 			TTL:      ttl,
-			LastSeen: time.Now(),
+			LastSeen: now,
 		}
 	}
 
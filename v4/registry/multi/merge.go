@@ -0,0 +1,45 @@
+package multi
+
+import (
+	"go-micro.dev/v4/registry"
+)
+
+// merge combines the services read from several registries. Services of the
+// same name and version are merged into one, and a node found in several
+// registries is kept once, as found in the first registry.
+func merge(results ...[]*registry.Service) []*registry.Service {
+	var services []*registry.Service
+	index := make(map[string]*registry.Service)
+	nodes := make(map[string]bool)
+
+	for _, svcs := range results {
+		for _, s := range svcs {
+			key := s.Name + ":" + s.Version
+
+			merged, ok := index[key]
+			if !ok {
+				merged = &registry.Service{
+					Name:      s.Name,
+					Version:   s.Version,
+					Metadata:  s.Metadata,
+					Endpoints: s.Endpoints,
+				}
+				index[key] = merged
+				services = append(services, merged)
+			}
+			if len(merged.Endpoints) == 0 {
+				merged.Endpoints = s.Endpoints
+			}
+
+			for _, n := range s.Nodes {
+				if nodes[key+":"+n.Id] {
+					continue
+				}
+				nodes[key+":"+n.Id] = true
+				merged.Nodes = append(merged.Nodes, n)
+			}
+		}
+	}
+
+	return services
+}
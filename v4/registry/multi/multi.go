@@ -1,7 +1,11 @@
+// Package multi composes several registries, e.g. to migrate between
+// discovery backends. Services are registered to every write registry and
+// looked up in every registry, with the results merged.
 package multi
 
 import (
 	"context"
+	"reflect"
 	"sync"
 
 	log "go-micro.dev/v4/logger"
@@ -9,9 +13,11 @@ import (
 )
 
 type multiRegistry struct {
-	r    []registry.Registry
-	w    []registry.Registry
-	opts registry.Options
+	r        []registry.Registry
+	w        []registry.Registry
+	// the policies of the write registries, by index
+	policies []WritePolicy
+	opts     registry.Options
 }
 
 func (m *multiRegistry) Init(opts ...registry.Option) error {
@@ -22,194 +28,100 @@ func (m *multiRegistry) Options() registry.Options {
 	return m.opts
 }
 
-func (m *multiRegistry) Register(s *registry.Service, opts ...registry.RegisterOption) error {
+// write calls fn on every write registry in parallel and returns the first
+// error of a registry whose policy requires the write.
+func (m *multiRegistry) write(op string, fn func(registry.Registry) error) error {
 	var wg sync.WaitGroup
-	var errs []error
-
-	done := make(chan bool)
-	cerr := make(chan error)
-
-	wg.Add(len(m.w))
-
-	go func() {
-		for {
-			select {
-			case <-done:
-				return
-			case err := <-cerr:
-				errs = append(errs, err)
-				wg.Done()
-			}
-		}
-	}()
-
-	for _, mw := range m.w {
-		go func(w registry.Registry) {
-			if err := w.Register(s, opts...); err != nil {
-				cerr <- err
-			} else {
-				wg.Done()
-			}
-		}(mw)
+	errs := make([]error, len(m.w))
+
+	for i, w := range m.w {
+		wg.Add(1)
+		go func(i int, w registry.Registry) {
+			defer wg.Done()
+			errs[i] = fn(w)
+		}(i, w)
 	}
 
 	wg.Wait()
-	defer close(done)
 
-	if len(errs) > 0 {
-		return errs[0]
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if m.policies[i] == WriteBestEffort {
+			log.Warnf("[multi] %s in %s failed: %v", op, m.w[i].String(), err)
+			continue
+		}
+		return err
 	}
 
 	return nil
 }
 
-func (m *multiRegistry) Deregister(s *registry.Service, opts ...registry.DeregisterOption) error {
-	var wg sync.WaitGroup
-	var errs []error
-
-	done := make(chan bool)
-	cerr := make(chan error)
+func (m *multiRegistry) Register(s *registry.Service, opts ...registry.RegisterOption) error {
+	return m.write("Register", func(w registry.Registry) error {
+		return w.Register(s, opts...)
+	})
+}
 
-	wg.Add(len(m.w))
+func (m *multiRegistry) Deregister(s *registry.Service, opts ...registry.DeregisterOption) error {
+	return m.write("Deregister", func(w registry.Registry) error {
+		return w.Deregister(s, opts...)
+	})
+}
 
-	go func() {
-		for {
-			select {
-			case <-done:
-				return
-			case err := <-cerr:
-				errs = append(errs, err)
-				wg.Done()
-			}
-		}
-	}()
-
-	for _, mw := range m.w {
-		go func(w registry.Registry) {
-			if err := w.Deregister(s); err != nil {
-				cerr <- err
-			} else {
-				wg.Done()
-			}
-		}(mw)
+// read calls fn on every read registry in parallel and merges the services.
+// It only fails if every registry failed.
+func (m *multiRegistry) read(fn func(registry.Registry) ([]*registry.Service, error)) ([]*registry.Service, error) {
+	var wg sync.WaitGroup
+	results := make([][]*registry.Service, len(m.r))
+	errs := make([]error, len(m.r))
+
+	for i, r := range m.r {
+		wg.Add(1)
+		go func(i int, r registry.Registry) {
+			defer wg.Done()
+			results[i], errs[i] = fn(r)
+		}(i, r)
 	}
 
 	wg.Wait()
-	defer close(done)
 
-	if len(errs) > 0 {
-		return errs[0]
+	var err error
+	var ok bool
+	for i, e := range errs {
+		switch e {
+		case nil, registry.ErrNotFound:
+			ok = true
+		default:
+			log.Debugf("[multi] Reading %s failed: %v", m.r[i].String(), e)
+			err = e
+		}
+	}
+	if !ok && err != nil {
+		return nil, err
 	}
 
-	return nil
+	return merge(results...), nil
 }
 
 func (m *multiRegistry) GetService(n string, opts ...registry.GetOption) ([]*registry.Service, error) {
-	var wg sync.WaitGroup
-	var errs []error
-	var svcs []*registry.Service
-	var mu sync.Mutex
-
-	done := make(chan bool)
-	cerr := make(chan error)
-	csvc := make(chan []*registry.Service)
-
-	wg.Add(len(m.r))
-
-	go func() {
-		for {
-			select {
-			case <-done:
-				return
-			case err := <-cerr:
-				errs = append(errs, err)
-				wg.Done()
-			case svc := <-csvc:
-				mu.Lock()
-				svcs = append(svcs, svc...)
-				mu.Unlock()
-				wg.Done()
-			}
-		}
-	}()
-
-	for _, mr := range m.r {
-		go func(r registry.Registry) {
-			svc, err := r.GetService(n)
-			if err != nil && err != registry.ErrNotFound {
-				cerr <- err
-			} else {
-				csvc <- svc
-			}
-		}(mr)
+	svcs, err := m.read(func(r registry.Registry) ([]*registry.Service, error) {
+		return r.GetService(n, opts...)
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	wg.Wait()
-	defer close(done)
-
-	if len(errs) > 0 {
-		return nil, errs[0]
-	}
-
-	mu.Lock()
 	if len(svcs) == 0 {
 		return nil, registry.ErrNotFound
 	}
-	mu.Unlock()
-
 	return svcs, nil
 }
 
 func (m *multiRegistry) ListServices(opts ...registry.ListOption) ([]*registry.Service, error) {
-	var wg sync.WaitGroup
-	var errs []error
-	var svcs []*registry.Service
-	var mu sync.Mutex
-
-	done := make(chan bool)
-	cerr := make(chan error)
-	csvc := make(chan []*registry.Service)
-
-	wg.Add(len(m.r))
-
-	go func() {
-		for {
-			select {
-			case <-done:
-				return
-			case err := <-cerr:
-				errs = append(errs, err)
-				wg.Done()
-			case svc := <-csvc:
-				mu.Lock()
-				svcs = append(svcs, svc...)
-				mu.Unlock()
-				wg.Done()
-			}
-		}
-	}()
-
-	for _, mr := range m.r {
-		go func(r registry.Registry) {
-			if svc, err := r.ListServices(); err != nil {
-				cerr <- err
-			} else {
-				csvc <- svc
-			}
-		}(mr)
-	}
-
-	wg.Wait()
-	defer close(done)
-
-	if len(errs) > 0 {
-		return nil, errs[0]
-	}
-
-	mu.Lock()
-	ret := svcs
-	mu.Unlock()
-	return ret, nil
+	return m.read(func(r registry.Registry) ([]*registry.Service, error) {
+		return r.ListServices(opts...)
+	})
 }
 
 func (m *multiRegistry) Watch(opts ...registry.WatchOption) (registry.Watcher, error) {
@@ -241,13 +153,47 @@ func configure(m *multiRegistry, opts ...registry.Option) error {
 	}
 
 	if w, ok := m.opts.Context.Value(writeKey{}).([]registry.Registry); ok && w != nil {
-		m.w = w
+		m.w = make([]registry.Registry, len(w))
+		m.policies = make([]WritePolicy, len(w))
+		for i, r := range w {
+			m.w[i], m.policies[i] = unwrap(r)
+		}
 	}
 
 	m.r = m.w
 
 	if r, ok := m.opts.Context.Value(readKey{}).([]registry.Registry); ok && r != nil {
-		m.r = append(m.r, r...)
+		m.r = dedupe(append(append([]registry.Registry{}, m.r...), r...))
 	}
+
 	return nil
 }
+
+// same returns whether the registries are the same. Registries which aren't
+// comparable are never the same, comparing them would panic.
+func same(a, b registry.Registry) bool {
+	t := reflect.TypeOf(a)
+	if t != reflect.TypeOf(b) || !t.Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// dedupe drops registries added as both write and read registry.
+func dedupe(rs []registry.Registry) []registry.Registry {
+	var out []registry.Registry
+	for _, r := range rs {
+		r, _ = unwrap(r)
+		dup := false
+		for _, o := range out {
+			if same(o, r) {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			out = append(out, r)
+		}
+	}
+	return out
+}
@@ -0,0 +1,204 @@
+package multi
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"go-micro.dev/v4/registry"
+)
+
+var errDown = errors.New("registry down")
+
+// flaky fails its calls while down and counts the registrations.
+type flaky struct {
+	registry.Registry
+	name string
+
+	sync.Mutex
+	down          bool
+	registrations int
+}
+
+func newFlaky(name string, services ...*registry.Service) *flaky {
+	r := registry.NewMemoryRegistry()
+	for _, s := range services {
+		r.Register(s)
+	}
+	return &flaky{Registry: r, name: name}
+}
+
+func (f *flaky) setDown(down bool) {
+	f.Lock()
+	f.down = down
+	f.Unlock()
+}
+
+func (f *flaky) isDown() bool {
+	f.Lock()
+	defer f.Unlock()
+	return f.down
+}
+
+func (f *flaky) Register(s *registry.Service, opts ...registry.RegisterOption) error {
+	f.Lock()
+	f.registrations++
+	f.Unlock()
+	if f.isDown() {
+		return errDown
+	}
+	return f.Registry.Register(s, opts...)
+}
+
+func (f *flaky) GetService(name string, opts ...registry.GetOption) ([]*registry.Service, error) {
+	if f.isDown() {
+		return nil, errDown
+	}
+	return f.Registry.GetService(name, opts...)
+}
+
+func (f *flaky) ListServices(opts ...registry.ListOption) ([]*registry.Service, error) {
+	if f.isDown() {
+		return nil, errDown
+	}
+	return f.Registry.ListServices(opts...)
+}
+
+func (f *flaky) String() string {
+	return f.name
+}
+
+// tagged isn't comparable, comparing it panics.
+type tagged struct {
+	registry.Registry
+	tags []string
+}
+
+func service(version string, nodes ...*registry.Node) *registry.Service {
+	return &registry.Service{Name: "foo", Version: version, Nodes: nodes}
+}
+
+func TestMerge(t *testing.T) {
+	primary := service("1", &registry.Node{Id: "foo-1", Address: "10.0.0.1:8080"})
+	migrated := service("1",
+		&registry.Node{Id: "foo-1", Address: "10.0.1.1:8080"},
+		&registry.Node{Id: "foo-2", Address: "10.0.1.2:8080"},
+	)
+	migrated.Endpoints = []*registry.Endpoint{{Name: "Foo.Call"}}
+	other := service("2", &registry.Node{Id: "foo-3", Address: "10.0.1.3:8080"})
+
+	services := merge([]*registry.Service{primary}, []*registry.Service{migrated, other})
+	if len(services) != 2 {
+		t.Fatalf("Expected a service per version, got %d", len(services))
+	}
+
+	s := services[0]
+	if s.Version != "1" || len(s.Nodes) != 2 {
+		t.Fatalf("Expected foo-1 and foo-2 in version 1, got %+v", s.Nodes)
+	}
+	// the node of the first registry takes precedence
+	if s.Nodes[0].Id != "foo-1" || s.Nodes[0].Address != "10.0.0.1:8080" {
+		t.Errorf("Expected foo-1 of the first registry, got %+v", s.Nodes[0])
+	}
+	// endpoints missing in the first registry are taken from the next
+	if len(s.Endpoints) != 1 || s.Endpoints[0].Name != "Foo.Call" {
+		t.Errorf("Expected the endpoints of the second registry, got %+v", s.Endpoints)
+	}
+	if len(primary.Nodes) != 1 {
+		t.Error("Expected the services read not to be modified")
+	}
+}
+
+func TestRead(t *testing.T) {
+	consul := newFlaky("consul", service("1", &registry.Node{Id: "foo-1", Address: "10.0.0.1:8080"}))
+	etcd := newFlaky("etcd", service("1", &registry.Node{Id: "foo-2", Address: "10.0.0.2:8080"}))
+	m := NewRegistry(WriteRegistry(consul), ReadRegistry(etcd))
+
+	services, err := m.GetService("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 || len(services[0].Nodes) != 2 {
+		t.Fatalf("Expected the nodes of both registries, got %+v", services)
+	}
+
+	// reads succeed while a registry is up
+	etcd.setDown(true)
+	services, err = m.GetService("foo")
+	if err != nil {
+		t.Fatalf("Expected a registry being down to be skipped, got %v", err)
+	}
+	if len(services[0].Nodes) != 1 || services[0].Nodes[0].Id != "foo-1" {
+		t.Errorf("Expected the node of consul, got %+v", services[0].Nodes)
+	}
+
+	consul.setDown(true)
+	if _, err := m.ListServices(); err != errDown {
+		t.Errorf("Expected the error of the registries, got %v", err)
+	}
+
+	consul.setDown(false)
+	if _, err := m.GetService("bar"); err != registry.ErrNotFound {
+		t.Errorf("Expected registry.ErrNotFound, got %v", err)
+	}
+}
+
+func TestWritePolicies(t *testing.T) {
+	consul := newFlaky("consul")
+	etcd := newFlaky("etcd")
+	m := NewRegistry(WriteRegistry(consul, Policy(etcd, WriteBestEffort)))
+
+	svc := service("1", &registry.Node{Id: "foo-1", Address: "10.0.0.1:8080"})
+
+	// best effort registries may fail
+	etcd.setDown(true)
+	if err := m.Register(svc); err != nil {
+		t.Fatalf("Expected the failure of the best effort registry to be logged, got %v", err)
+	}
+	if _, err := consul.GetService("foo"); err != nil {
+		t.Errorf("Expected the service in consul, got %v", err)
+	}
+
+	// required ones may not
+	etcd.setDown(false)
+	consul.setDown(true)
+	if err := m.Register(svc); err != errDown {
+		t.Fatalf("Expected the failure of the required registry, got %v", err)
+	}
+	// every registry is written to anyway
+	if _, err := etcd.GetService("foo"); err != nil {
+		t.Errorf("Expected the service in etcd, got %v", err)
+	}
+	if consul.registrations != 2 || etcd.registrations != 2 {
+		t.Errorf("Expected both registrations in both registries, got %d and %d", consul.registrations, etcd.registrations)
+	}
+
+	// wrapped registries are read from as is
+	if len(m.(*multiRegistry).r) != 2 || m.(*multiRegistry).r[1] != etcd {
+		t.Errorf("Expected consul and etcd as read registries, got %v", m.(*multiRegistry).r)
+	}
+}
+
+func TestIncomparableRegistries(t *testing.T) {
+	a := tagged{Registry: newFlaky("a"), tags: []string{"a"}}
+	b := tagged{Registry: newFlaky("b"), tags: []string{"b"}}
+	c := newFlaky("c")
+
+	m := NewRegistry(
+		WriteRegistry(a, Policy(b, WriteBestEffort), c),
+		ReadRegistry(a, c),
+	).(*multiRegistry)
+
+	// registries which can't be compared are kept, comparable ones deduped
+	if len(m.r) != 4 {
+		t.Errorf("Expected a, b, c and a again as read registries, got %d", len(m.r))
+	}
+	if m.policies[0] != WriteRequired || m.policies[1] != WriteBestEffort || m.policies[2] != WriteRequired {
+		t.Errorf("Expected the policies by index, got %v", m.policies)
+	}
+
+	b.Registry.(*flaky).setDown(true)
+	if err := m.Register(service("1", &registry.Node{Id: "foo-1"})); err != nil {
+		t.Errorf("Expected the best effort registry to fail silently, got %v", err)
+	}
+}
@@ -9,6 +9,17 @@ import (
 type writeKey struct{}
 type readKey struct{}
 
+// WritePolicy is how failed writes to a registry are handled.
+type WritePolicy int
+
+const (
+	// WriteRequired fails the registration if the registry fails it.
+	WriteRequired WritePolicy = iota
+	// WriteBestEffort logs the failures of the registry without failing the
+	// registration, e.g. for the registry being migrated to.
+	WriteBestEffort
+)
+
 // helper for setting registry options.
 func setRegistryOption(k, v interface{}) registry.Option {
 	return func(o *registry.Options) {
@@ -28,3 +39,26 @@ func WriteRegistry(w ...registry.Registry) registry.Option {
 func ReadRegistry(r ...registry.Registry) registry.Option {
 	return setRegistryOption(readKey{}, r)
 }
+
+// Policy returns the write registry with the write policy, to pass to
+// WriteRegistry. Write registries are WriteRequired by default:
+//
+//	multi.WriteRegistry(consul, multi.Policy(etcd, multi.WriteBestEffort))
+func Policy(r registry.Registry, p WritePolicy) registry.Registry {
+	return &policyRegistry{Registry: r, policy: p}
+}
+
+// policyRegistry wraps a write registry with its policy, registries can't be
+// map keys as they may not be comparable.
+type policyRegistry struct {
+	registry.Registry
+	policy WritePolicy
+}
+
+// unwrap returns the registry and write policy of a write registry.
+func unwrap(r registry.Registry) (registry.Registry, WritePolicy) {
+	if p, ok := r.(*policyRegistry); ok {
+		return p.Registry, p.policy
+	}
+	return r, WriteRequired
+}
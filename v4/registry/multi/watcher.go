@@ -6,11 +6,25 @@ import (
 	"go-micro.dev/v4/registry"
 )
 
+// result is a result of the watcher of a registry.
+type result struct {
+	source int
+	res    *registry.Result
+	err    error
+}
+
+// multiWatcher fans in the results of the watchers of every registry. A node
+// in several registries is only created once and deleted once it's deleted
+// from all of them.
 type multiWatcher struct {
 	wo   registry.WatchOptions
 	w    []registry.Watcher
-	next chan *registry.Result
+	next chan result
 	stop chan bool
+	once sync.Once
+
+	// the registries every node is in
+	owners map[string]map[int]bool
 }
 
 func newMultiWatcher(r []registry.Registry, opts ...registry.WatchOption) (registry.Watcher, error) {
@@ -20,68 +34,129 @@ func newMultiWatcher(r []registry.Registry, opts ...registry.WatchOption) (regis
 	}
 
 	mw := &multiWatcher{
-		wo:   wo,
-		next: make(chan *registry.Result),
-		stop: make(chan bool),
+		wo:     wo,
+		next:   make(chan result),
+		stop:   make(chan bool),
+		owners: make(map[string]map[int]bool),
 	}
 
 	for _, wr := range r {
 		w, err := wr.Watch(opts...)
 		if err != nil {
+			mw.Stop()
 			return nil, err
 		}
 		mw.w = append(mw.w, w)
 	}
 
+	for i, w := range mw.w {
+		go mw.watch(i, w)
+	}
+
 	return mw, nil
 }
 
-func (mw *multiWatcher) Next() (*registry.Result, error) {
-	cerr := make(chan error)
+func (mw *multiWatcher) watch(i int, w registry.Watcher) {
+	for {
+		r, err := w.Next()
+		if err == registry.ErrNotFound {
+			continue
+		}
 
-	for _, wt := range mw.w {
-		go func(w registry.Watcher) {
-			r, err := w.Next()
-			if err != nil && err != registry.ErrNotFound {
-				cerr <- err
-			}
-			mw.next <- r
-		}(wt)
+		select {
+		case mw.next <- result{source: i, res: r, err: err}:
+		case <-mw.stop:
+			return
+		}
+
+		if err != nil {
+			return
+		}
 	}
+}
 
+func (mw *multiWatcher) Next() (*registry.Result, error) {
 	for {
 		select {
-		case err := <-cerr:
-			return nil, err
-		case r, ok := <-mw.next:
-			if !ok {
-				return nil, registry.ErrWatcherStopped
+		case r := <-mw.next:
+			if r.err != nil {
+				return nil, r.err
+			}
+			if res := mw.filter(r.source, r.res); res != nil {
+				return res, nil
 			}
-			nr := &registry.Result{}
-			*nr = *r
-			return nr, nil
 		case <-mw.stop:
 			return nil, registry.ErrWatcherStopped
 		}
 	}
 }
 
-func (mw *multiWatcher) Stop() {
-	var wg sync.WaitGroup
-	wg.Add(len(mw.w))
-
-	for _, w := range mw.w {
-		go func(w registry.Watcher) {
-			w.Stop()
-			wg.Done()
-		}(w)
+// filter updates the registries the nodes of the result are in and drops the
+// changes of nodes other registries already reported.
+func (mw *multiWatcher) filter(source int, r *registry.Result) *registry.Result {
+	if r == nil || r.Service == nil {
+		return nil
+	}
+	if len(r.Service.Nodes) == 0 {
+		return r
 	}
 
-	wg.Wait()
-	select {
-	case <-mw.stop:
-		return
-	default:
-		close(mw.stop)
+	var nodes []*registry.Node
+	for _, n := range r.Service.Nodes {
+		key := r.Service.Name + ":" + r.Service.Version + ":" + n.Id
+		owners := mw.owners[key]
+
+		switch r.Action {
+		case "delete":
+			delete(owners, source)
+			if len(owners) > 0 {
+				continue
+			}
+			delete(mw.owners, key)
+		case "create":
+			if owners == nil {
+				owners = make(map[int]bool)
+				mw.owners[key] = owners
+			}
+			known := len(owners) > 0
+			owners[source] = true
+			if known {
+				continue
+			}
+		default:
+			if owners == nil {
+				owners = make(map[int]bool)
+				mw.owners[key] = owners
+			}
+			owners[source] = true
+		}
+
+		nodes = append(nodes, n)
+	}
+
+	if len(nodes) == 0 {
+		return nil
 	}
+
+	svc := *r.Service
+	svc.Nodes = nodes
+	return &registry.Result{Action: r.Action, Service: &svc}
+}
+
+func (mw *multiWatcher) Stop() {
+	mw.once.Do(func() {
+		close(mw.stop)
+
+		var wg sync.WaitGroup
+		wg.Add(len(mw.w))
+
+		for _, w := range mw.w {
+			go func(w registry.Watcher) {
+				w.Stop()
+				wg.Done()
+			}(w)
+		}
+
+		wg.Wait()
+	})
 }
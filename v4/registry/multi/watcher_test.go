@@ -0,0 +1,158 @@
+package multi
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/registry"
+)
+
+// testWatcher returns the results sent to it until stopped, buffering a few.
+type testWatcher struct {
+	results chan *registry.Result
+	exit    chan bool
+	once    sync.Once
+}
+
+func newTestWatcher() *testWatcher {
+	return &testWatcher{
+		results: make(chan *registry.Result, 4),
+		exit:    make(chan bool),
+	}
+}
+
+func (w *testWatcher) Next() (*registry.Result, error) {
+	select {
+	case r := <-w.results:
+		return r, nil
+	case <-w.exit:
+		return nil, registry.ErrWatcherStopped
+	}
+}
+
+func (w *testWatcher) Stop() {
+	w.once.Do(func() {
+		close(w.exit)
+	})
+}
+
+func (w *testWatcher) stopped() bool {
+	select {
+	case <-w.exit:
+		return true
+	default:
+		return false
+	}
+}
+
+// send queues the result, failing the test if the buffer is full.
+func (w *testWatcher) send(t *testing.T, action, id string) {
+	t.Helper()
+	r := &registry.Result{Action: action, Service: service("1", &registry.Node{Id: id})}
+	select {
+	case w.results <- r:
+	default:
+		t.Fatalf("Expected the %s of %s to be queued", action, id)
+	}
+}
+
+type watchRegistry struct {
+	registry.Registry
+	w *testWatcher
+}
+
+func (r *watchRegistry) Watch(opts ...registry.WatchOption) (registry.Watcher, error) {
+	return r.w, nil
+}
+
+func newWatch(t *testing.T) (*testWatcher, *testWatcher, registry.Watcher) {
+	a, b := newTestWatcher(), newTestWatcher()
+	m := NewRegistry(WriteRegistry(
+		&watchRegistry{Registry: registry.NewMemoryRegistry(), w: a},
+		&watchRegistry{Registry: registry.NewMemoryRegistry(), w: b},
+	))
+
+	w, err := m.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(w.Stop)
+	return a, b, w
+}
+
+func next(t *testing.T, w registry.Watcher) *registry.Result {
+	t.Helper()
+	r, err := w.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestWatcherFanIn(t *testing.T) {
+	a, b, w := newWatch(t)
+
+	// a node in both registries is created once
+	a.send(t, "create", "foo-1")
+	if r := next(t, w); r.Action != "create" || r.Service.Nodes[0].Id != "foo-1" {
+		t.Fatalf("Expected the create of foo-1, got %s %+v", r.Action, r.Service.Nodes)
+	}
+	b.send(t, "create", "foo-1")
+
+	b.send(t, "create", "foo-2")
+	if r := next(t, w); r.Service.Nodes[0].Id != "foo-2" {
+		t.Fatalf("Expected the create of foo-2 to follow, got %+v", r.Service.Nodes)
+	}
+
+	// and deleted once it's deleted from both
+	a.send(t, "delete", "foo-1")
+	b.send(t, "delete", "foo-1")
+	if r := next(t, w); r.Action != "delete" || r.Service.Nodes[0].Id != "foo-1" {
+		t.Fatalf("Expected the delete of foo-1, got %s %+v", r.Action, r.Service.Nodes)
+	}
+}
+
+func TestWatcherStop(t *testing.T) {
+	a, b, w := newWatch(t)
+
+	done := make(chan error)
+	go func() {
+		_, err := w.Next()
+		done <- err
+	}()
+
+	w.Stop()
+	select {
+	case err := <-done:
+		if err != registry.ErrWatcherStopped {
+			t.Errorf("Expected registry.ErrWatcherStopped, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the blocked Next to return")
+	}
+
+	if !a.stopped() || !b.stopped() {
+		t.Error("Expected the watchers of every registry to be stopped")
+	}
+	if _, err := w.Next(); err != registry.ErrWatcherStopped {
+		t.Errorf("Expected registry.ErrWatcherStopped after stopping, got %v", err)
+	}
+	// stopping again is a no-op
+	w.Stop()
+}
+
+func TestWatcherError(t *testing.T) {
+	a, b, w := newWatch(t)
+
+	// a registry whose watcher stops ends the watch
+	b.Stop()
+	if _, err := w.Next(); err != registry.ErrWatcherStopped {
+		t.Fatalf("Expected the error of the watcher, got %v", err)
+	}
+
+	w.Stop()
+	if !a.stopped() {
+		t.Error("Expected the other watchers to be stopped")
+	}
+}
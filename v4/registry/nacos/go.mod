@@ -3,6 +3,7 @@ module github.com/go-micro/plugins/v4/registry/nacos
 go 1.17
 
 require (
+	github.com/go-micro/plugins/v4/util/weighted v1.0.0
 	github.com/nacos-group/nacos-sdk-go/v2 v2.0.0-Beta.1
 	go-micro.dev/v4 v4.9.0
 )
@@ -67,3 +68,5 @@ require (
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 	honnef.co/go/tools v0.0.1-2020.1.3 // indirect
 )
+
+replace github.com/go-micro/plugins/v4/util/weighted => ../../util/weighted
@@ -6,19 +6,36 @@ import (
 	"fmt"
 	"net"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/nacos-group/nacos-sdk-go/v2/clients"
 	"github.com/nacos-group/nacos-sdk-go/v2/clients/naming_client"
 	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
+	"github.com/nacos-group/nacos-sdk-go/v2/model"
 	"github.com/nacos-group/nacos-sdk-go/v2/vo"
 	"go-micro.dev/v4/registry"
 	"go-micro.dev/v4/util/cmd"
 )
 
+// Metadata keys of the nacos instance attributes set on every node.
+const (
+	MetadataWeight    = "weight"
+	MetadataCluster   = "cluster"
+	MetadataGroup     = "group"
+	MetadataEphemeral = "ephemeral"
+)
+
 type nacosRegistry struct {
-	client naming_client.INamingClient
-	opts   registry.Options
+	client   naming_client.INamingClient
+	opts     registry.Options
+	group    string
+	clusters []string
+
+	sync.Mutex
+	// registered are the register params by instance, to deregister from
+	// the same group and cluster
+	registered map[string]vo.RegisterInstanceParam
 }
 
 func init() {
@@ -31,6 +48,7 @@ func NewRegistry(opts ...registry.Option) registry.Registry {
 		opts: registry.Options{
 			Context: context.Background(),
 		},
+		registered: make(map[string]vo.RegisterInstanceParam),
 	}
 	if err := configure(n, opts...); err != nil {
 		panic(err)
@@ -52,6 +70,12 @@ func configure(n *nacosRegistry, opts ...registry.Option) error {
 	if ok {
 		clientConfig = cfg
 	}
+	if ns, ok := n.opts.Context.Value(namespaceKey{}).(string); ok {
+		clientConfig.NamespaceId = ns
+	}
+	n.group, _ = n.opts.Context.Value(groupKey{}).(string)
+	n.clusters, _ = n.opts.Context.Value(clustersKey{}).([]string)
+
 	addrs, ok := n.opts.Context.Value(addressKey{}).([]string)
 	if !ok {
 		addrs = n.opts.Addrs
@@ -139,18 +163,48 @@ func (n *nacosRegistry) Register(s *registry.Service, opts ...registry.RegisterO
 		if err != nil {
 			return err
 		}
+		if s.Nodes[0].Metadata == nil {
+			s.Nodes[0].Metadata = make(map[string]string)
+		}
 		s.Nodes[0].Metadata["version"] = s.Version
 		param.Ip = host
 		param.Port = uint64(port)
 		param.Metadata = s.Nodes[0].Metadata
 		param.ServiceName = s.Name
+		param.GroupName = n.group
 		param.Enable = true
 		param.Healthy = true
 		param.Weight = 1.0
 		param.Ephemeral = true
+
+		if options.Context != nil {
+			if g, ok := options.Context.Value(groupKey{}).(string); ok {
+				param.GroupName = g
+			}
+			if c, ok := options.Context.Value(clusterKey{}).(string); ok {
+				param.ClusterName = c
+			}
+			if w, ok := options.Context.Value(weightKey{}).(float64); ok {
+				param.Weight = w
+			}
+			if e, ok := options.Context.Value(ephemeralKey{}).(bool); ok {
+				param.Ephemeral = e
+			}
+		}
 	}
-	_, err := n.client.RegisterInstance(param)
-	return err
+	if _, err := n.client.RegisterInstance(param); err != nil {
+		return err
+	}
+
+	n.Lock()
+	n.registered[instanceKey(param.ServiceName, param.Ip, param.Port)] = param
+	n.Unlock()
+
+	return nil
+}
+
+func instanceKey(service, ip string, port uint64) string {
+	return service + "/" + net.JoinHostPort(ip, strconv.FormatUint(port, 10))
 }
 
 func (n *nacosRegistry) Deregister(s *registry.Service, opts ...registry.DeregisterOption) error {
@@ -174,10 +228,27 @@ func (n *nacosRegistry) Deregister(s *registry.Service, opts ...registry.Deregis
 		param.Ip = host
 		param.Port = uint64(port)
 		param.ServiceName = s.Name
+		param.GroupName = n.group
+		param.Ephemeral = true
+
+		n.Lock()
+		if rp, ok := n.registered[instanceKey(s.Name, host, uint64(port))]; ok {
+			param.GroupName = rp.GroupName
+			param.Cluster = rp.ClusterName
+			param.Ephemeral = rp.Ephemeral
+		}
+		n.Unlock()
+	}
+
+	if _, err := n.client.DeregisterInstance(param); err != nil {
+		return err
 	}
 
-	_, err := n.client.DeregisterInstance(param)
-	return err
+	n.Lock()
+	delete(n.registered, instanceKey(param.ServiceName, param.Ip, param.Port))
+	n.Unlock()
+
+	return nil
 }
 
 func (n *nacosRegistry) GetService(name string, opts ...registry.GetOption) ([]*registry.Service, error) {
@@ -195,6 +266,8 @@ func (n *nacosRegistry) GetService(name string, opts ...registry.GetOption) ([]*
 	}
 	if !withContext {
 		param.ServiceName = name
+		param.GroupName = n.group
+		param.Clusters = n.clusters
 	}
 	service, err := n.client.GetService(param)
 	if err != nil {
@@ -210,10 +283,10 @@ func (n *nacosRegistry) GetService(name string, opts ...registry.GetOption) ([]*
 		nodes = append(nodes, &registry.Node{
 			Id:       v.InstanceId,
 			Address:  net.JoinHostPort(v.Ip, fmt.Sprintf("%d", v.Port)),
-			Metadata: v.Metadata,
+			Metadata: nodeMetadata(&v, param.GroupName),
 		})
 		s := registry.Service{
-			Name:     v.ServiceName,
+			Name:     name,
 			Version:  v.Metadata["version"],
 			Metadata: v.Metadata,
 			Nodes:    nodes,
@@ -238,6 +311,8 @@ func (n *nacosRegistry) ListServices(opts ...registry.ListOption) ([]*registry.S
 		}
 	}
 	if !withContext {
+		param.NameSpace = n.namespace()
+		param.GroupName = n.group
 		services, err := n.client.GetAllServicesInfo(param)
 		if err != nil {
 			return nil, err
@@ -256,6 +331,31 @@ func (n *nacosRegistry) ListServices(opts ...registry.ListOption) ([]*registry.S
 	return registryServices, nil
 }
 
+func (n *nacosRegistry) namespace() string {
+	ns, _ := n.opts.Context.Value(namespaceKey{}).(string)
+	return ns
+}
+
+// nodeMetadata returns the metadata of the instance with its group,
+// cluster, weight and ephemeral flag.
+func nodeMetadata(v *model.Instance, group string) map[string]string {
+	md := make(map[string]string, len(v.Metadata)+4)
+	for k, val := range v.Metadata {
+		md[k] = val
+	}
+
+	if len(group) == 0 {
+		group = constant.DEFAULT_GROUP
+	}
+
+	md[MetadataWeight] = strconv.FormatFloat(v.Weight, 'f', -1, 64)
+	md[MetadataCluster] = v.ClusterName
+	md[MetadataGroup] = group
+	md[MetadataEphemeral] = strconv.FormatBool(v.Ephemeral)
+
+	return md
+}
+
 func (n *nacosRegistry) Watch(opts ...registry.WatchOption) (registry.Watcher, error) {
 	return newWatcher(n, opts...)
 }
@@ -29,3 +29,82 @@ func WithClientConfig(cc constant.ClientConfig) registry.Option {
 		o.Context = context.WithValue(o.Context, configKey{}, cc)
 	}
 }
+
+type namespaceKey struct{}
+type groupKey struct{}
+type clustersKey struct{}
+type clusterKey struct{}
+type weightKey struct{}
+type ephemeralKey struct{}
+
+// WithNamespace sets the nacos namespace id, defaults to the public namespace.
+func WithNamespace(ns string) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, namespaceKey{}, ns)
+	}
+}
+
+// WithGroup sets the group services are registered in and looked up from,
+// defaults to DEFAULT_GROUP.
+func WithGroup(group string) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, groupKey{}, group)
+	}
+}
+
+// WithClusters limits GetService and Watch to instances of the clusters.
+func WithClusters(clusters ...string) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, clustersKey{}, clusters)
+	}
+}
+
+// Group registers the instance in the group instead of the registry's group.
+func Group(group string) registry.RegisterOption {
+	return func(o *registry.RegisterOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, groupKey{}, group)
+	}
+}
+
+// Cluster registers the instance in the cluster, defaults to DEFAULT.
+func Cluster(cluster string) registry.RegisterOption {
+	return func(o *registry.RegisterOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, clusterKey{}, cluster)
+	}
+}
+
+// Weight sets the weight of the instance, defaults to 1.
+func Weight(w float64) registry.RegisterOption {
+	return func(o *registry.RegisterOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, weightKey{}, w)
+	}
+}
+
+// Ephemeral sets whether the instance is ephemeral, removed by nacos once
+// its heartbeats stop, or persistent until deregistered. Defaults to true.
+func Ephemeral(e bool) registry.RegisterOption {
+	return func(o *registry.RegisterOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, ephemeralKey{}, e)
+	}
+}
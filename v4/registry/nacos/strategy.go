@@ -0,0 +1,30 @@
+package nacos
+
+import (
+	"strconv"
+
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/selector"
+
+	"github.com/go-micro/plugins/v4/util/weighted"
+)
+
+// Weighted is a selector strategy which picks nodes at random in proportion
+// to their nacos instance weight. Nodes without a weight have the default
+// weight of 1.
+func Weighted(services []*registry.Service) selector.Next {
+	var nodes []*registry.Node
+	for _, service := range services {
+		nodes = append(nodes, service.Nodes...)
+	}
+
+	return weighted.Next(nodes, nodeWeight)
+}
+
+func nodeWeight(node *registry.Node) float64 {
+	w, err := strconv.ParseFloat(node.Metadata[MetadataWeight], 64)
+	if err != nil {
+		return 1
+	}
+	return w
+}
@@ -0,0 +1,76 @@
+package nacos
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
+	"github.com/nacos-group/nacos-sdk-go/v2/model"
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/selector"
+)
+
+func TestNodeMetadata(t *testing.T) {
+	v := &model.Instance{
+		Weight:      2.5,
+		ClusterName: "eu",
+		Ephemeral:   true,
+		Metadata:    map[string]string{"team": "payments"},
+	}
+
+	md := nodeMetadata(v, "billing")
+	want := map[string]string{
+		"team":            "payments",
+		MetadataWeight:    "2.5",
+		MetadataCluster:   "eu",
+		MetadataGroup:     "billing",
+		MetadataEphemeral: "true",
+	}
+	if len(md) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, md)
+	}
+	for k, v := range want {
+		if md[k] != v {
+			t.Fatalf("Expected %s to be %s, got %v", k, v, md)
+		}
+	}
+
+	if md := nodeMetadata(&model.Instance{}, ""); md[MetadataGroup] != constant.DEFAULT_GROUP {
+		t.Fatalf("Expected the default group, got %v", md)
+	}
+}
+
+func TestWeighted(t *testing.T) {
+	services := []*registry.Service{
+		{Name: "billing", Version: "1.0.0", Nodes: []*registry.Node{
+			{Id: "heavy", Metadata: map[string]string{MetadataWeight: "3"}},
+			{Id: "disabled", Metadata: map[string]string{MetadataWeight: "0"}},
+		}},
+		{Name: "billing", Version: "1.1.0", Nodes: []*registry.Node{
+			// nodes without a weight have the default weight of 1
+			{Id: "default"},
+		}},
+	}
+
+	next := Weighted(services)
+	picks := make(map[string]int)
+	const n = 20000
+	for i := 0; i < n; i++ {
+		node, err := next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		picks[node.Id]++
+	}
+
+	if picks["disabled"] != 0 {
+		t.Fatalf("Expected the node with a zero weight not to be picked, got %v", picks)
+	}
+	if share := float64(picks["heavy"]) / n; math.Abs(share-0.75) > 0.02 {
+		t.Fatalf("Expected the heavy node to be picked 75%% of the time, got %.2f", share)
+	}
+
+	if _, err := Weighted(nil)(); err != selector.ErrNoneAvailable {
+		t.Fatalf("Expected no node to be available, got %v", err)
+	}
+}
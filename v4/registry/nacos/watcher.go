@@ -52,7 +52,10 @@ func newWatcher(nr *nacosRegistry, opts ...registry.WatchOption) (registry.Watch
 		}
 	}
 	if !withContext {
-		param := vo.GetAllServiceInfoParam{}
+		param := vo.GetAllServiceInfoParam{
+			NameSpace: nr.namespace(),
+			GroupName: nr.group,
+		}
 		services, err := nr.client.GetAllServicesInfo(param)
 		if err != nil {
 			return nil, err
@@ -67,6 +70,8 @@ func newWatcher(nr *nacosRegistry, opts ...registry.WatchOption) (registry.Watch
 		for _, v := range nw.Doms {
 			param := &vo.SubscribeParam{
 				ServiceName:       v,
+				GroupName:         nr.group,
+				Clusters:          nr.clusters,
 				SubscribeCallback: nw.callBackHandle,
 			}
 			go nr.client.Subscribe(param)
@@ -89,7 +94,7 @@ func (nw *watcher) callBackHandle(services []model.Instance, err error) {
 		nw.Unlock()
 
 		for _, v := range services {
-			nw.next <- &registry.Result{Action: "create", Service: buildRegistryService(&v)}
+			nw.next <- &registry.Result{Action: "create", Service: nw.buildRegistryService(&v)}
 			return
 		}
 	} else {
@@ -99,7 +104,7 @@ func (nw *watcher) callBackHandle(services []model.Instance, err error) {
 				if subscribeService.InstanceId == cacheService.InstanceId {
 					if !reflect.DeepEqual(subscribeService, cacheService) {
 						// update instance
-						nw.next <- &registry.Result{Action: "update", Service: buildRegistryService(&subscribeService)}
+						nw.next <- &registry.Result{Action: "update", Service: nw.buildRegistryService(&subscribeService)}
 						return
 					}
 					create = false
@@ -109,7 +114,7 @@ func (nw *watcher) callBackHandle(services []model.Instance, err error) {
 			if create {
 				log.Println("create", subscribeService.ServiceName, subscribeService.Port)
 
-				nw.next <- &registry.Result{Action: "create", Service: buildRegistryService(&subscribeService)}
+				nw.next <- &registry.Result{Action: "create", Service: nw.buildRegistryService(&subscribeService)}
 
 				nw.Lock()
 				nw.cacheServices[serviceName] = append(nw.cacheServices[serviceName], subscribeService)
@@ -127,7 +132,7 @@ func (nw *watcher) callBackHandle(services []model.Instance, err error) {
 			}
 			if del {
 				log.Println("del", cacheService.ServiceName, cacheService.Port)
-				nw.next <- &registry.Result{Action: "delete", Service: buildRegistryService(&cacheService)}
+				nw.next <- &registry.Result{Action: "delete", Service: nw.buildRegistryService(&cacheService)}
 
 				nw.Lock()
 				nw.cacheServices[serviceName][index] = model.Instance{}
@@ -139,12 +144,17 @@ func (nw *watcher) callBackHandle(services []model.Instance, err error) {
 	}
 }
 
-func buildRegistryService(v *model.Instance) (s *registry.Service) {
+func (nw *watcher) buildRegistryService(v *model.Instance) (s *registry.Service) {
+	group := nw.n.group
+	if len(nw.param.GroupName) > 0 {
+		group = nw.param.GroupName
+	}
+
 	nodes := make([]*registry.Node, 0)
 	nodes = append(nodes, &registry.Node{
 		Id:       v.InstanceId,
 		Address:  net.JoinHostPort(v.Ip, fmt.Sprintf("%d", v.Port)),
-		Metadata: v.Metadata,
+		Metadata: nodeMetadata(v, group),
 	})
 	s = &registry.Service{
 		Name:     v.ServiceName,
@@ -177,6 +187,8 @@ func (nw *watcher) Stop() {
 			for _, v := range nw.Doms {
 				param := &vo.SubscribeParam{
 					ServiceName:       v,
+					GroupName:         nw.n.group,
+					Clusters:          nw.n.clusters,
 					SubscribeCallback: nw.callBackHandle,
 				}
 				_ = nw.n.client.Unsubscribe(param)
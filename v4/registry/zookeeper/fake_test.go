@@ -0,0 +1,198 @@
+package zookeeper
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-zookeeper/zk"
+)
+
+type znode struct {
+	data      []byte
+	ephemeral bool
+	version   int32
+}
+
+// fakeConn is an in memory znode tree with one-shot watches, like the
+// watches of a zookeeper session.
+type fakeConn struct {
+	sync.Mutex
+	nodes map[string]*znode
+	seq   int
+
+	children map[string][]chan zk.Event
+	data     map[string][]chan zk.Event
+	exists   map[string][]chan zk.Event
+}
+
+func newFakeConn() *fakeConn {
+	return &fakeConn{
+		nodes:    map[string]*znode{"/": {}},
+		children: make(map[string][]chan zk.Event),
+		data:     make(map[string][]chan zk.Event),
+		exists:   make(map[string][]chan zk.Event),
+	}
+}
+
+func (f *fakeConn) watch(watches map[string][]chan zk.Event, p string) <-chan zk.Event {
+	ch := make(chan zk.Event, 1)
+	watches[p] = append(watches[p], ch)
+	return ch
+}
+
+// fire triggers and removes the watches of the path.
+func (f *fakeConn) fire(watches map[string][]chan zk.Event, p string, t zk.EventType) {
+	for _, ch := range watches[p] {
+		ch <- zk.Event{Type: t, Path: p}
+	}
+	delete(watches, p)
+}
+
+func (f *fakeConn) list(p string) ([]string, *zk.Stat, error) {
+	if _, ok := f.nodes[p]; !ok {
+		return nil, nil, zk.ErrNoNode
+	}
+
+	var children []string
+	for c := range f.nodes {
+		if c != "/" && c != p && path.Dir(c) == p {
+			children = append(children, path.Base(c))
+		}
+	}
+	sort.Strings(children)
+	return children, &zk.Stat{NumChildren: int32(len(children))}, nil
+}
+
+func (f *fakeConn) Children(p string) ([]string, *zk.Stat, error) {
+	f.Lock()
+	defer f.Unlock()
+	return f.list(p)
+}
+
+func (f *fakeConn) ChildrenW(p string) ([]string, *zk.Stat, <-chan zk.Event, error) {
+	f.Lock()
+	defer f.Unlock()
+	children, stat, err := f.list(p)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return children, stat, f.watch(f.children, p), nil
+}
+
+func (f *fakeConn) Get(p string) ([]byte, *zk.Stat, error) {
+	f.Lock()
+	defer f.Unlock()
+	n, ok := f.nodes[p]
+	if !ok {
+		return nil, nil, zk.ErrNoNode
+	}
+	return n.data, &zk.Stat{Version: n.version}, nil
+}
+
+func (f *fakeConn) GetW(p string) ([]byte, *zk.Stat, <-chan zk.Event, error) {
+	f.Lock()
+	defer f.Unlock()
+	n, ok := f.nodes[p]
+	if !ok {
+		return nil, nil, nil, zk.ErrNoNode
+	}
+	return n.data, &zk.Stat{Version: n.version}, f.watch(f.data, p), nil
+}
+
+func (f *fakeConn) Set(p string, data []byte, version int32) (*zk.Stat, error) {
+	f.Lock()
+	defer f.Unlock()
+	n, ok := f.nodes[p]
+	if !ok {
+		return nil, zk.ErrNoNode
+	}
+	n.data = data
+	n.version++
+	f.fire(f.data, p, zk.EventNodeDataChanged)
+	return &zk.Stat{Version: n.version}, nil
+}
+
+func (f *fakeConn) Create(p string, data []byte, flags int32, acl []zk.ACL) (string, error) {
+	f.Lock()
+	defer f.Unlock()
+	if _, ok := f.nodes[path.Dir(p)]; !ok {
+		return "", zk.ErrNoNode
+	}
+	if flags&zk.FlagSequence != 0 {
+		p = fmt.Sprintf("%s%010d", p, f.seq)
+		f.seq++
+	}
+	if _, ok := f.nodes[p]; ok {
+		return "", zk.ErrNodeExists
+	}
+
+	f.nodes[p] = &znode{data: data, ephemeral: flags&zk.FlagEphemeral != 0}
+	f.fire(f.exists, p, zk.EventNodeCreated)
+	f.fire(f.children, path.Dir(p), zk.EventNodeChildrenChanged)
+	return p, nil
+}
+
+func (f *fakeConn) delete(p string) error {
+	if _, ok := f.nodes[p]; !ok {
+		return zk.ErrNoNode
+	}
+	if children, _, _ := f.list(p); len(children) > 0 {
+		return zk.ErrNotEmpty
+	}
+
+	delete(f.nodes, p)
+	f.fire(f.exists, p, zk.EventNodeDeleted)
+	f.fire(f.data, p, zk.EventNodeDeleted)
+	f.fire(f.children, p, zk.EventNodeDeleted)
+	f.fire(f.children, path.Dir(p), zk.EventNodeChildrenChanged)
+	return nil
+}
+
+func (f *fakeConn) Delete(p string, version int32) error {
+	f.Lock()
+	defer f.Unlock()
+	return f.delete(p)
+}
+
+func (f *fakeConn) Exists(p string) (bool, *zk.Stat, error) {
+	f.Lock()
+	defer f.Unlock()
+	_, ok := f.nodes[p]
+	return ok, &zk.Stat{}, nil
+}
+
+func (f *fakeConn) ExistsW(p string) (bool, *zk.Stat, <-chan zk.Event, error) {
+	f.Lock()
+	defer f.Unlock()
+	_, ok := f.nodes[p]
+	return ok, &zk.Stat{}, f.watch(f.exists, p), nil
+}
+
+// expire removes the ephemeral znodes, like zookeeper does once a session
+// expired.
+func (f *fakeConn) expire() {
+	f.Lock()
+	defer f.Unlock()
+	for p, n := range f.nodes {
+		if n.ephemeral {
+			f.delete(p)
+		}
+	}
+}
+
+// paths returns the paths under the prefix.
+func (f *fakeConn) paths(prefix string) []string {
+	f.Lock()
+	defer f.Unlock()
+	var paths []string
+	for p := range f.nodes {
+		if strings.HasPrefix(p, prefix) {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -25,6 +25,24 @@ func nodePath(s, id string) string {
 	return path.Join(prefix, service, node)
 }
 
+func nodeKey(s, id string) string {
+	return s + "/" + id
+}
+
+// isSequential reports whether the child is a sequential znode of the name,
+// the name followed by a dash and the 10 digit sequence number.
+func isSequential(child, name string) bool {
+	if len(child) != len(name)+11 || !strings.HasPrefix(child, name+"-") {
+		return false
+	}
+	for _, c := range child[len(name)+1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 func childPath(parent, child string) string {
 	return path.Join(parent, strings.Replace(child, "/", "-", -1))
 }
@@ -33,7 +51,7 @@ func servicePath(s string) string {
 	return path.Join(prefix, strings.Replace(s, "/", "-", -1))
 }
 
-func createPath(path string, data []byte, client *zk.Conn) error {
+func createPath(path string, data []byte, client conn) error {
 	exists, _, err := client.Exists(path)
 	if err != nil {
 		return err
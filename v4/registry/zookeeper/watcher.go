@@ -2,24 +2,29 @@ package zookeeper
 
 import (
 	"errors"
-	"path"
+	"sync"
+	"time"
 
 	"github.com/go-zookeeper/zk"
 	"go-micro.dev/v4/registry"
 )
 
+// retryInterval is how long to wait before watching again after an error,
+// e.g. while the connection is lost.
+var retryInterval = time.Second
+
+// zookeeperWatcher watches the service znodes with zookeeper watches. The
+// prefix is watched for new services, every service for its nodes and every
+// node for changes of its data.
 type zookeeperWatcher struct {
-	wo       registry.WatchOptions
-	client   *zk.Conn
-	stop     chan bool
-	results  chan result
-	respChan chan watchResponse
-}
+	wo      registry.WatchOptions
+	client  conn
+	stop    chan bool
+	results chan result
 
-type watchResponse struct {
-	event   zk.Event
-	service *registry.Service
-	err     error
+	sync.Mutex
+	// services are the watched service znodes
+	services map[string]bool
 }
 
 type result struct {
@@ -38,59 +43,88 @@ func newZookeeperWatcher(r *zookeeperRegistry, opts ...registry.WatchOption) (re
 		client:   r.client,
 		stop:     make(chan bool),
 		results:  make(chan result),
-		respChan: make(chan watchResponse),
+		services: make(map[string]bool),
+	}
+
+	if len(wo.Service) > 0 {
+		zw.watchService(servicePath(wo.Service))
+	} else {
+		go zw.watchPrefix()
 	}
 
-	go zw.watch()
 	return zw, nil
 }
 
-func (zw *zookeeperWatcher) writeRespChan(resp *watchResponse) {
-	if resp == nil {
-		return
-	}
+func (zw *zookeeperWatcher) writeResult(r *result) {
 	select {
 	case <-zw.stop:
-	default:
-		zw.respChan <- *resp
+	case zw.results <- *r:
 	}
 }
 
-func (zw *zookeeperWatcher) writeResult(r *result) {
-	if r == nil {
-		return
+// wait waits before retrying, it returns false if the watcher stopped.
+func (zw *zookeeperWatcher) wait(err error) bool {
+	if err == zk.ErrClosing {
+		return false
 	}
+
 	select {
 	case <-zw.stop:
-	default:
-		zw.results <- *r
+		return false
+	case <-time.After(retryInterval):
+		return true
 	}
 }
 
-func (zw *zookeeperWatcher) watchDir(key string) {
-	// mark children changed
-	childrenChanged := true
-
+// waitCreated waits until the znode of the path is created, with an exists
+// watch as children and data watches can't be set on missing znodes. It
+// returns false if the watcher stopped.
+func (zw *zookeeperWatcher) waitCreated(p string) bool {
 	for {
-		// get current children for a key
-		newChildren, _, childEventCh, err := zw.client.ChildrenW(key)
+		exists, _, ch, err := zw.client.ExistsW(p)
 		if err != nil {
-			zw.writeRespChan(&watchResponse{zk.Event{}, nil, err})
-			return
+			if !zw.wait(err) {
+				return false
+			}
+			continue
 		}
-
-		if childrenChanged {
-			zw.overrideChildrenInfo(newChildren, key)
+		if exists {
+			// created since the children were read
+			return true
 		}
 
 		select {
-		case e := <-childEventCh:
-			if e.Type != zk.EventNodeChildrenChanged {
-				childrenChanged = false
-				continue
+		case <-ch:
+			return true
+		case <-zw.stop:
+			return false
+		}
+	}
+}
+
+// watchPrefix watches the services created under the prefix.
+func (zw *zookeeperWatcher) watchPrefix() {
+	for {
+		children, _, ch, err := zw.client.ChildrenW(prefix)
+		if err == zk.ErrNoNode {
+			if !zw.waitCreated(prefix) {
+				return
 			}
+			continue
+		}
+		if err != nil {
+			if !zw.wait(err) {
+				return
+			}
+			continue
+		}
+
+		for _, c := range children {
+			zw.watchService(childPath(prefix, c))
+		}
 
-			childrenChanged = true
+		select {
+		case <-ch:
 		case <-zw.stop:
 			// There is no way to stop GetW/ChildrenW so just quit
 			return
@@ -98,37 +132,95 @@ func (zw *zookeeperWatcher) watchDir(key string) {
 	}
 }
 
-func (zw *zookeeperWatcher) watchKey(key string) {
+// watchService starts watching the nodes of the service once.
+func (zw *zookeeperWatcher) watchService(p string) {
+	zw.Lock()
+	defer zw.Unlock()
+
+	if zw.services[p] {
+		return
+	}
+	zw.services[p] = true
+
+	go zw.watchNodes(p)
+}
+
+// watchNodes watches the children of the service znode, sending a create
+// for every new node and a delete for every removed node, e.g. when the
+// ephemeral znode of a dead instance disappears with its session. Services
+// which don't exist, yet or anymore, are watched until they're created.
+func (zw *zookeeperWatcher) watchNodes(p string) {
+	// nodes are the known nodes by znode name
+	nodes := make(map[string]*registry.Service)
+	// stops the data watches of removed nodes
+	stops := make(map[string]chan bool)
+
+	defer func() {
+		for _, s := range stops {
+			close(s)
+		}
+	}()
+
 	for {
-		s, _, keyEventCh, err := zw.client.GetW(key)
+		children, _, ch, err := zw.client.ChildrenW(p)
+		if err == zk.ErrNoNode {
+			for name, s := range nodes {
+				close(stops[name])
+				delete(stops, name)
+				delete(nodes, name)
+
+				zw.writeResult(&result{&registry.Result{Action: "delete", Service: s}, nil})
+			}
+
+			if !zw.waitCreated(p) {
+				return
+			}
+			continue
+		}
 		if err != nil {
-			zw.writeRespChan(&watchResponse{zk.Event{}, nil, err})
-			return
+			if !zw.wait(err) {
+				return
+			}
+			continue
 		}
 
-		select {
-		case e := <-keyEventCh:
-			switch e.Type {
-			case zk.EventNodeDataChanged, zk.EventNodeCreated, zk.EventNodeDeleted:
-				if e.Type != zk.EventNodeDeleted {
-					// get the updated service
-					s, _, err = zw.client.Get(e.Path)
-					if err != nil {
-						continue
-					}
-				}
-
-				srv, err := decode(s)
-				if err != nil {
-					continue
-				}
-
-				zw.writeRespChan(&watchResponse{e, srv, err})
+		current := make(map[string]bool, len(children))
+		for _, c := range children {
+			current[c] = true
+			if _, ok := nodes[c]; ok {
+				continue
 			}
-			if e.Type == zk.EventNodeDeleted {
-				// The Node was deleted - stop watching
-				return
+
+			b, stat, err := zw.client.Get(childPath(p, c))
+			if err != nil {
+				continue
+			}
+			s, err := decode(b)
+			if err != nil {
+				continue
+			}
+
+			nodes[c] = s
+			stops[c] = make(chan bool)
+			go zw.watchData(childPath(p, c), stat.Version, stops[c])
+
+			zw.writeResult(&result{&registry.Result{Action: "create", Service: s}, nil})
+		}
+
+		for name, s := range nodes {
+			if current[name] {
+				continue
 			}
+
+			close(stops[name])
+			delete(stops, name)
+			delete(nodes, name)
+
+			zw.writeResult(&result{&registry.Result{Action: "delete", Service: s}, nil})
+		}
+
+		select {
+		case <-ch:
 		case <-zw.stop:
 			// There is no way to stop GetW/ChildrenW so just quit
 			return
@@ -136,54 +228,37 @@ func (zw *zookeeperWatcher) watchKey(key string) {
 	}
 }
 
-func (zw *zookeeperWatcher) watch() {
-	services := func() []string {
-		if len(zw.wo.Service) > 0 {
-			return []string{zw.wo.Service}
+// watchData sends an update whenever the data of the node changes from the
+// version the create was sent with.
+func (zw *zookeeperWatcher) watchData(p string, version int32, stop chan bool) {
+	for {
+		b, stat, ch, err := zw.client.GetW(p)
+		if err == zk.ErrNoNode {
+			return
 		}
-		allServices, _, err := zw.client.Children(prefix)
 		if err != nil {
-			zw.writeResult(&result{nil, err})
+			if !zw.wait(err) {
+				return
+			}
+			continue
 		}
-		return allServices
-	}
 
-	// watch every service
-	for _, service := range services() {
-		sPath := childPath(prefix, service)
-		if sPath == prefix {
-			continue
+		// the data may have changed before the watch was set
+		if stat.Version != version {
+			version = stat.Version
+			if s, err := decode(b); err == nil {
+				zw.writeResult(&result{&registry.Result{Action: "update", Service: s}, nil})
+			}
 		}
-		go zw.watchDir(sPath)
-	}
 
-	var service *registry.Service
-	var action string
-	for {
+		// a lost watch is set again, the data may have changed meanwhile
 		select {
+		case <-ch:
+		case <-stop:
+			return
 		case <-zw.stop:
 			return
-		case rsp := <-zw.respChan:
-			if rsp.err != nil {
-				zw.writeResult(&result{nil, rsp.err})
-				continue
-			}
-			switch rsp.event.Type {
-			case zk.EventNodeDataChanged:
-				action = "update"
-				service = rsp.service
-			case zk.EventNodeDeleted:
-				action = "delete"
-				service = rsp.service
-			case zk.EventNodeCreated:
-				action = "create"
-				service = rsp.service
-			case zk.EventNodeChildrenChanged:
-				action = "override"
-				service = rsp.service
-			}
 		}
-		zw.writeResult(&result{&registry.Result{Action: action, Service: service}, nil})
 	}
 }
 
@@ -204,43 +279,3 @@ func (zw *zookeeperWatcher) Next() (*registry.Result, error) {
 		return r.res, r.err
 	}
 }
-
-func (zw *zookeeperWatcher) overrideChildrenInfo(newChildren []string, parentPath string) {
-	// nodes were removed
-	if len(newChildren) == 0 {
-		zw.writeRespChan(&watchResponse{zk.Event{
-			Type: zk.EventNodeCreated,
-		}, nil, nil})
-		return
-	}
-
-	// override resp
-	var overrideResp *watchResponse
-
-	for _, i := range newChildren {
-		newNode := path.Join(parentPath, i)
-
-		s, _, err := zw.client.Get(newNode)
-		if err != nil {
-			continue
-		}
-
-		srv, err := decode(s)
-		if err != nil {
-			continue
-		}
-
-		// if nil, then init, do it once
-		if overrideResp == nil {
-			overrideResp = &watchResponse{zk.Event{
-				Type: zk.EventNodeChildrenChanged,
-			}, srv, nil}
-			zw.writeRespChan(overrideResp)
-		}
-
-		// when node was added or updated
-		zw.writeRespChan(&watchResponse{zk.Event{
-			Type: zk.EventNodeCreated,
-		}, srv, nil})
-	}
-}
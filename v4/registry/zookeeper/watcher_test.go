@@ -0,0 +1,119 @@
+package zookeeper
+
+import (
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/registry"
+)
+
+func next(t *testing.T, w registry.Watcher) *registry.Result {
+	t.Helper()
+
+	type res struct {
+		r   *registry.Result
+		err error
+	}
+	ch := make(chan res, 1)
+	go func() {
+		r, err := w.Next()
+		ch <- res{r, err}
+	}()
+
+	select {
+	case r := <-ch:
+		if r.err != nil {
+			t.Fatal(r.err)
+		}
+		return r.r
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a result")
+		return nil
+	}
+}
+
+func expect(t *testing.T, w registry.Watcher, action, id string) {
+	t.Helper()
+	r := next(t, w)
+	if r.Action != action || r.Service.Nodes[0].Id != id {
+		t.Fatalf("Expected the %s of %s, got the %s of %s", action, id, r.Action, r.Service.Nodes[0].Id)
+	}
+}
+
+func TestWatchService(t *testing.T) {
+	z, f := newTestRegistry(t)
+
+	// the service doesn't exist yet
+	w, err := z.Watch(registry.WatchService("foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	if err := z.Register(testService("foo-1", nil)); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, w, "create", "foo-1")
+
+	if err := z.Register(testService("foo-1", map[string]string{"zone": "a"})); err != nil {
+		t.Fatal(err)
+	}
+	r := next(t, w)
+	if r.Action != "update" || r.Service.Nodes[0].Metadata["zone"] != "a" {
+		t.Fatalf("Expected the update of foo-1, got the %s of %+v", r.Action, r.Service.Nodes[0])
+	}
+
+	if err := z.Deregister(testService("foo-1", nil)); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, w, "delete", "foo-1")
+
+	// the service is watched again once its znode is removed
+	if err := f.Delete(servicePath("foo"), -1); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.Register(testService("foo-2", nil)); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, w, "create", "foo-2")
+}
+
+func TestWatchServiceRemoved(t *testing.T) {
+	z, f := newTestRegistry(t)
+
+	if err := z.Register(testService("foo-1", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := z.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	expect(t, w, "create", "foo-1")
+
+	// the session of the node expires and the service is removed
+	f.expire()
+	expect(t, w, "delete", "foo-1")
+	if err := f.Delete(servicePath("foo"), -1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := z.Register(&registry.Service{Name: "bar", Nodes: []*registry.Node{{Id: "bar-1"}}}); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, w, "create", "bar-1")
+
+	// and found again once created
+	z.register = make(map[string]uint64)
+	z.nodes = make(map[string]*registeredNode)
+	if err := z.Register(testService("foo-1", nil)); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, w, "create", "foo-1")
+
+	w.Stop()
+	if _, err := w.Next(); err == nil {
+		t.Error("Expected an error once stopped")
+	}
+}
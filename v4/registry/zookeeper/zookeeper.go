@@ -3,7 +3,9 @@ package zookeeper
 
 import (
 	"errors"
+	"path"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-zookeeper/zk"
@@ -17,11 +19,37 @@ var (
 	prefix = "/micro-registry"
 )
 
+// conn is the part of *zk.Conn the registry uses.
+type conn interface {
+	Children(path string) ([]string, *zk.Stat, error)
+	ChildrenW(path string) ([]string, *zk.Stat, <-chan zk.Event, error)
+	Get(path string) ([]byte, *zk.Stat, error)
+	GetW(path string) ([]byte, *zk.Stat, <-chan zk.Event, error)
+	Set(path string, data []byte, version int32) (*zk.Stat, error)
+	Create(path string, data []byte, flags int32, acl []zk.ACL) (string, error)
+	Delete(path string, version int32) error
+	Exists(path string) (bool, *zk.Stat, error)
+	ExistsW(path string) (bool, *zk.Stat, <-chan zk.Event, error)
+}
+
 type zookeeperRegistry struct {
-	client  *zk.Conn
+	client  conn
 	options registry.Options
 	sync.Mutex
 	register map[string]uint64
+	// nodes are the registered nodes by service and node id
+	nodes map[string]*registeredNode
+	// expired is set once the session expired, until the nodes are
+	// registered again
+	expired int32
+}
+
+// registeredNode is a node registered as an ephemeral sequential znode.
+type registeredNode struct {
+	service string
+	id      string
+	path    string
+	data    []byte
 }
 
 func init() {
@@ -44,8 +72,19 @@ func configure(z *zookeeperRegistry, opts ...registry.Option) error {
 		return nil
 	}
 
-	// reset
-	cAddrs = nil
+	c, err := z.connect()
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	z.client = c
+	return nil
+}
+
+// connect connects to zookeeper and creates the prefix path.
+func (z *zookeeperRegistry) connect() (*zk.Conn, error) {
+	var cAddrs []string
 
 	for _, addr := range z.options.Addrs {
 		if len(addr) == 0 {
@@ -58,21 +97,80 @@ func configure(z *zookeeperRegistry, opts ...registry.Option) error {
 		cAddrs = []string{"127.0.0.1:2181"}
 	}
 
-	// connect to zookeeper
-	c, _, err := zk.Connect(cAddrs, time.Second*z.options.Timeout)
+	// connect to zookeeper, the callback sees every session event, the
+	// event channel drops them when full
+	c, _, err := zk.Connect(cAddrs, time.Second*z.options.Timeout, zk.WithEventCallback(z.onEvent))
 	if err != nil {
-		log.Error(err.Error())
-		return err
+		return nil, err
 	}
 
 	// create our prefix path
 	if err := createPath(prefix, []byte{}, c); err != nil {
-		log.Error(err.Error())
-		return err
+		return nil, err
 	}
 
-	z.client = c
-	return nil
+	return c, nil
+}
+
+// onEvent registers the nodes again once a new session is established
+// after the previous one expired, as their ephemeral znodes were removed
+// with it.
+func (z *zookeeperRegistry) onEvent(e zk.Event) {
+	if e.Type != zk.EventSession {
+		return
+	}
+
+	switch e.State {
+	case zk.StateExpired:
+		atomic.StoreInt32(&z.expired, 1)
+	case zk.StateHasSession:
+		if atomic.CompareAndSwapInt32(&z.expired, 1, 0) {
+			// the callback must not block the connection
+			go z.reregister()
+		}
+	}
+}
+
+func (z *zookeeperRegistry) reregister() {
+	z.Lock()
+	nodes := make([]*registeredNode, 0, len(z.nodes))
+	for _, n := range z.nodes {
+		nodes = append(nodes, n)
+	}
+	z.Unlock()
+
+	log.Infof("Zookeeper session expired, registering %d nodes again", len(nodes))
+
+	for _, n := range nodes {
+		p, err := z.create(n.service, n.id, n.data)
+		if err != nil {
+			// the session expired again, the next one retries
+			log.Errorf("Zookeeper failed to register %s node %s again: %v", n.service, n.id, err)
+			continue
+		}
+
+		z.Lock()
+		// skip nodes deregistered in the meantime
+		if z.nodes[nodeKey(n.service, n.id)] != n {
+			z.Unlock()
+			//nolint:errcheck
+			z.client.Delete(p, -1)
+			continue
+		}
+		n.path = p
+		z.Unlock()
+	}
+}
+
+// create creates the ephemeral sequential znode of the node. A sequential
+// znode doesn't clash with the znode of the node in a previous session
+// which isn't removed yet.
+func (z *zookeeperRegistry) create(service, id string, data []byte) (string, error) {
+	if err := createPath(servicePath(service), []byte{}, z.client); err != nil && err != zk.ErrNodeExists {
+		return "", err
+	}
+
+	return z.client.Create(nodePath(service, id)+"-", data, zk.FlagEphemeral|zk.FlagSequence, zk.WorldACL(zk.PermAll))
 }
 
 func (z *zookeeperRegistry) Init(opts ...registry.Option) error {
@@ -94,15 +192,57 @@ func (z *zookeeperRegistry) Deregister(s *registry.Service, opts ...registry.Der
 	z.Unlock()
 
 	for _, node := range s.Nodes {
-		err := z.client.Delete(nodePath(s.Name, node.Id), -1)
-		if err != nil {
-			return err
+		key := nodeKey(s.Name, node.Id)
+
+		z.Lock()
+		n, ok := z.nodes[key]
+		delete(z.nodes, key)
+		z.Unlock()
+
+		var paths []string
+		if ok {
+			paths = []string{n.path}
+		} else {
+			// registered by another registry instance
+			found, err := z.find(s.Name, node.Id)
+			if err != nil {
+				return err
+			}
+			paths = found
+		}
+
+		for _, p := range paths {
+			if err := z.client.Delete(p, -1); err != nil && err != zk.ErrNoNode {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
 
+// find returns the paths of the znodes of the node.
+func (z *zookeeperRegistry) find(service, id string) ([]string, error) {
+	children, _, err := z.client.Children(servicePath(service))
+	if err == zk.ErrNoNode {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	name := path.Base(nodePath(service, id))
+
+	var paths []string
+	for _, c := range children {
+		if c == name || isSequential(c, name) {
+			paths = append(paths, childPath(servicePath(service), c))
+		}
+	}
+
+	return paths, nil
+}
+
 func (z *zookeeperRegistry) Register(s *registry.Service, opts ...registry.RegisterOption) error {
 	if len(s.Nodes) == 0 {
 		return errors.New("Require at least one node")
@@ -138,27 +278,43 @@ func (z *zookeeperRegistry) Register(s *registry.Service, opts ...registry.Regis
 
 	for _, node := range s.Nodes {
 		service.Nodes = []*registry.Node{node}
-		exists, _, err := z.client.Exists(nodePath(service.Name, node.Id))
-		if err != nil {
-			return err
-		}
-
 		srv, err := encode(service)
 		if err != nil {
 			return err
 		}
 
-		if exists {
-			_, err := z.client.Set(nodePath(service.Name, node.Id), srv, -1)
-			if err != nil {
-				return err
+		key := nodeKey(service.Name, node.Id)
+
+		z.Lock()
+		n, ok := z.nodes[key]
+		z.Unlock()
+
+		if ok {
+			_, err := z.client.Set(n.path, srv, -1)
+			if err == nil {
+				z.Lock()
+				n.data = srv
+				z.Unlock()
+				continue
 			}
-		} else {
-			err := createPath(nodePath(service.Name, node.Id), srv, z.client)
-			if err != nil {
+			if err != zk.ErrNoNode {
 				return err
 			}
 		}
+
+		p, err := z.create(service.Name, node.Id, srv)
+		if err != nil {
+			return err
+		}
+
+		z.Lock()
+		z.nodes[key] = &registeredNode{
+			service: service.Name,
+			id:      node.Id,
+			path:    p,
+			data:    srv,
+		}
+		z.Unlock()
 	}
 
 	// save our hash of the service
@@ -284,34 +440,18 @@ func NewRegistry(opts ...registry.Option) registry.Registry {
 		options.Timeout = 5
 	}
 
-	cAddrs := make([]string, 0, len(options.Addrs))
-	for _, addr := range options.Addrs {
-		if len(addr) == 0 {
-			continue
-		}
-		cAddrs = append(cAddrs, addr)
-	}
-
-	if len(cAddrs) == 0 {
-		cAddrs = []string{"127.0.0.1:2181"}
+	z := &zookeeperRegistry{
+		options:  options,
+		register: make(map[string]uint64),
+		nodes:    make(map[string]*registeredNode),
 	}
 
-	// connect to zookeeper
-	c, _, err := zk.Connect(cAddrs, time.Second*options.Timeout)
+	c, err := z.connect()
 	if err != nil {
 		log.Error(err.Error())
 		return nil
 	}
 
-	// create our prefix path
-	if err := createPath(prefix, []byte{}, c); err != nil {
-		log.Error(err.Error())
-		return nil
-	}
-
-	return &zookeeperRegistry{
-		client:   c,
-		options:  options,
-		register: make(map[string]uint64),
-	}
+	z.client = c
+	return z
 }
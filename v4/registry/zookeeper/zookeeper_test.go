@@ -0,0 +1,171 @@
+package zookeeper
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-zookeeper/zk"
+	"go-micro.dev/v4/registry"
+)
+
+func newTestRegistry(t *testing.T) (*zookeeperRegistry, *fakeConn) {
+	f := newFakeConn()
+	if err := createPath(prefix, []byte{}, f); err != nil {
+		t.Fatal(err)
+	}
+	z := &zookeeperRegistry{
+		client:   f,
+		options:  registry.Options{Timeout: 5},
+		register: make(map[string]uint64),
+		nodes:    make(map[string]*registeredNode),
+	}
+	return z, f
+}
+
+func testService(id string, metadata map[string]string) *registry.Service {
+	return &registry.Service{
+		Name:    "foo",
+		Version: "1",
+		Nodes:   []*registry.Node{{Id: id, Address: "10.0.0.1:8080", Metadata: metadata}},
+	}
+}
+
+func TestIsSequential(t *testing.T) {
+	tests := []struct {
+		child, name string
+		ok          bool
+	}{
+		{"foo-1-0000000001", "foo-1", true},
+		{"foo-1-0000000000", "foo-1", true},
+		{"foo-1", "foo-1", false},
+		// a node whose id starts with the id of another
+		{"foo-10-0000000001", "foo-1", false},
+		{"foo-1-000000001", "foo-1", false},
+		{"foo-1-00000000012", "foo-1", false},
+		{"foo-1-000000000a", "foo-1", false},
+		{"foo-1_0000000001", "foo-1", false},
+	}
+	for _, tt := range tests {
+		if ok := isSequential(tt.child, tt.name); ok != tt.ok {
+			t.Errorf("Expected isSequential(%s, %s) to be %v", tt.child, tt.name, tt.ok)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	z, f := newTestRegistry(t)
+
+	if err := z.Register(testService("foo-1", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.Register(testService("foo-10", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	paths := f.paths(servicePath("foo") + "/")
+	if len(paths) != 2 || !isSequential(paths[0][len(servicePath("foo"))+1:], "foo-1") {
+		t.Fatalf("Expected a sequential znode per node, got %v", paths)
+	}
+	f.Lock()
+	ephemeral := f.nodes[paths[0]].ephemeral
+	f.Unlock()
+	if !ephemeral {
+		t.Error("Expected the znode to be ephemeral")
+	}
+
+	services, err := z.GetService("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 || len(services[0].Nodes) != 2 {
+		t.Fatalf("Expected both nodes, got %+v", services)
+	}
+
+	// updates set the data of the znode
+	if err := z.Register(testService("foo-1", map[string]string{"zone": "a"})); err != nil {
+		t.Fatal(err)
+	}
+	if p := f.paths(servicePath("foo") + "/"); p[0] != paths[0] {
+		t.Errorf("Expected the znode to be updated in place, got %v", p)
+	}
+
+	// nodes registered by another instance are found by name
+	other, _ := newTestRegistry(t)
+	other.client = f
+	if err := other.Deregister(testService("foo-1", nil)); err != nil {
+		t.Fatal(err)
+	}
+	paths = f.paths(servicePath("foo") + "/")
+	if len(paths) != 1 || !strings.Contains(paths[0], "foo-10-") {
+		t.Errorf("Expected only the znode of foo-10 to be left, got %v", paths)
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	z, f := newTestRegistry(t)
+
+	if err := z.Register(testService("foo-1", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.Register(testService("foo-2", nil)); err != nil {
+		t.Fatal(err)
+	}
+	before := z.nodes[nodeKey("foo", "foo-1")].path
+
+	// a new session without an expired one registers nothing
+	z.onEvent(zk.Event{Type: zk.EventSession, State: zk.StateHasSession})
+
+	f.expire()
+	z.onEvent(zk.Event{Type: zk.EventSession, State: zk.StateDisconnected})
+	z.onEvent(zk.Event{Type: zk.EventSession, State: zk.StateExpired})
+	if p := f.paths(servicePath("foo") + "/"); len(p) != 0 {
+		t.Fatalf("Expected the ephemeral znodes to expire, got %v", p)
+	}
+
+	// other events are ignored
+	z.onEvent(zk.Event{Type: zk.EventNodeCreated, State: zk.StateHasSession})
+
+	// the nodes are registered again with the new session
+	z.onEvent(zk.Event{Type: zk.EventSession, State: zk.StateHasSession})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(f.paths(servicePath("foo")+"/")) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the nodes to be registered again")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// with new znodes
+	deadline = time.Now().Add(5 * time.Second)
+	for {
+		z.Lock()
+		after := z.nodes[nodeKey("foo", "foo-1")].path
+		z.Unlock()
+		if after != before {
+			if ok, _, _ := f.Exists(after); !ok {
+				t.Errorf("Expected the new znode %s to exist", after)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the path of the node to be updated")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// only once
+	time.Sleep(50 * time.Millisecond)
+	if p := f.paths(servicePath("foo") + "/"); len(p) != 2 {
+		t.Errorf("Expected a znode per node, got %v", p)
+	}
+
+	services, err := z.GetService("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 || len(services[0].Nodes) != 2 {
+		t.Errorf("Expected both nodes to be found again, got %+v", services)
+	}
+}
@@ -0,0 +1,225 @@
+// Package apicurio provides an Apicurio schema registry backend, using the
+// registry's v2 api. Subjects are artifact ids of a group, schema ids are
+// global ids.
+package apicurio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-micro/plugins/v4/schemaregistry"
+)
+
+type groupKey struct{}
+
+// Group sets the artifact group of the subjects. Defaults to "default".
+func Group(g string) schemaregistry.Option {
+	return func(o *schemaregistry.Options) {
+		o.Context = context.WithValue(o.Context, groupKey{}, g)
+	}
+}
+
+type apicurio struct {
+	opts  schemaregistry.Options
+	group string
+}
+
+// NewRegistry returns an Apicurio schema registry, by default at
+// http://localhost:8080.
+func NewRegistry(opts ...schemaregistry.Option) schemaregistry.Registry {
+	options := schemaregistry.NewOptions(opts...)
+	if len(options.URL) == 0 {
+		options.URL = "http://localhost:8080"
+	}
+	options.URL = strings.TrimSuffix(options.URL, "/") + "/apis/registry/v2"
+
+	group := "default"
+	if g, ok := options.Context.Value(groupKey{}).(string); ok && len(g) > 0 {
+		group = g
+	}
+
+	return &apicurio{opts: options, group: group}
+}
+
+type metaData struct {
+	ID       string `json:"id"`
+	Version  string `json:"version"`
+	Type     string `json:"type"`
+	GlobalID int64  `json:"globalId"`
+}
+
+func (m *metaData) toSchema(definition string) *schemaregistry.Schema {
+	version, _ := strconv.Atoi(m.Version)
+	return &schemaregistry.Schema{
+		ID:         strconv.FormatInt(m.GlobalID, 10),
+		Subject:    m.ID,
+		Version:    version,
+		Type:       schemaregistry.Type(m.Type),
+		Definition: definition,
+	}
+}
+
+func (a *apicurio) do(ctx context.Context, method, path string, body []byte, header map[string]string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, a.opts.URL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	if len(a.opts.Username) > 0 {
+		req.SetBasicAuth(a.opts.Username, a.opts.Password)
+	}
+
+	rsp, err := a.opts.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case rsp.StatusCode == http.StatusNotFound:
+		rsp.Body.Close()
+		return nil, schemaregistry.ErrNotFound
+	case rsp.StatusCode >= 300 && rsp.StatusCode != http.StatusConflict:
+		defer rsp.Body.Close()
+		var e struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(rsp.Body).Decode(&e)
+		return nil, fmt.Errorf("apicurio schema registry: %d %s", rsp.StatusCode, e.Message)
+	}
+
+	return rsp, nil
+}
+
+func (a *apicurio) artifactPath(subject string) string {
+	return "/groups/" + url.PathEscape(a.group) + "/artifacts/" + url.PathEscape(subject)
+}
+
+func (a *apicurio) meta(ctx context.Context, path string) (*metaData, error) {
+	rsp, err := a.do(ctx, http.MethodGet, path+"/meta", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+
+	var m metaData
+	if err := json.NewDecoder(rsp.Body).Decode(&m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (a *apicurio) content(ctx context.Context, path string) (string, error) {
+	rsp, err := a.do(ctx, http.MethodGet, path, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	defer rsp.Body.Close()
+
+	b, err := io.ReadAll(rsp.Body)
+	return string(b), err
+}
+
+func contentHeader(s *schemaregistry.Schema) map[string]string {
+	t := s.Type
+	if len(t) == 0 {
+		t = schemaregistry.Avro
+	}
+
+	ct := "application/json"
+	if t == schemaregistry.Protobuf {
+		ct = "application/x-protobuf"
+	}
+
+	return map[string]string{
+		"Content-Type":            ct,
+		"X-Registry-ArtifactId":   s.Subject,
+		"X-Registry-ArtifactType": string(t),
+	}
+}
+
+func (a *apicurio) Register(ctx context.Context, s *schemaregistry.Schema) (*schemaregistry.Schema, error) {
+	path := "/groups/" + url.PathEscape(a.group) + "/artifacts?ifExists=RETURN_OR_UPDATE"
+
+	rsp, err := a.do(ctx, http.MethodPost, path, []byte(s.Definition), contentHeader(s))
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode == http.StatusConflict {
+		return nil, fmt.Errorf("apicurio schema registry: %s schema violates the rules of the artifact", s.Subject)
+	}
+
+	var m metaData
+	if err := json.NewDecoder(rsp.Body).Decode(&m); err != nil {
+		return nil, err
+	}
+
+	return m.toSchema(s.Definition), nil
+}
+
+func (a *apicurio) GetByID(ctx context.Context, id string) (*schemaregistry.Schema, error) {
+	rsp, err := a.do(ctx, http.MethodGet, "/ids/globalIds/"+url.PathEscape(id), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+
+	b, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &schemaregistry.Schema{
+		ID:         id,
+		Type:       schemaregistry.Type(rsp.Header.Get("X-Registry-ArtifactType")),
+		Definition: string(b),
+	}, nil
+}
+
+func (a *apicurio) get(ctx context.Context, path string) (*schemaregistry.Schema, error) {
+	m, err := a.meta(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	// the content of the version of the metadata, which may be newer than
+	// the latest when it was requested
+	def, err := a.content(ctx, a.artifactPath(m.ID)+"/versions/"+url.PathEscape(m.Version))
+	if err != nil {
+		return nil, err
+	}
+
+	return m.toSchema(def), nil
+}
+
+func (a *apicurio) GetLatest(ctx context.Context, subject string) (*schemaregistry.Schema, error) {
+	return a.get(ctx, a.artifactPath(subject))
+}
+
+func (a *apicurio) GetVersion(ctx context.Context, subject string, version int) (*schemaregistry.Schema, error) {
+	return a.get(ctx, a.artifactPath(subject)+"/versions/"+strconv.Itoa(version))
+}
+
+func (a *apicurio) CheckCompatibility(ctx context.Context, s *schemaregistry.Schema) (bool, error) {
+	rsp, err := a.do(ctx, http.MethodPut, a.artifactPath(s.Subject)+"/test", []byte(s.Definition), contentHeader(s))
+	if err != nil {
+		return false, err
+	}
+	rsp.Body.Close()
+
+	return rsp.StatusCode != http.StatusConflict, nil
+}
+
+func (a *apicurio) String() string {
+	return "apicurio"
+}
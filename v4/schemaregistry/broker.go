@@ -0,0 +1,76 @@
+package schemaregistry
+
+import (
+	"context"
+	"fmt"
+
+	"go-micro.dev/v4/broker"
+)
+
+// HeaderSchemaID is the id of the schema of a message body.
+const HeaderSchemaID = "Micro-Schema-Id"
+
+// ErrMissingSchema is returned for messages without a schema id.
+var ErrMissingSchema = fmt.Errorf("message has no %s header", HeaderSchemaID)
+
+type validator struct {
+	broker.Broker
+	r        Registry
+	subjects map[string]string
+}
+
+// NewBroker wraps the broker to validate the schema of every published and
+// received message. A message must name a schema of the registry in the
+// Micro-Schema-Id header, and if subjects maps its topic to a subject, the
+// schema must belong to it. Use a cached registry to avoid a lookup per
+// message.
+func NewBroker(b broker.Broker, r Registry, subjects map[string]string) broker.Broker {
+	return &validator{
+		Broker:   b,
+		r:        r,
+		subjects: subjects,
+	}
+}
+
+func (v *validator) validate(topic string, m *broker.Message) error {
+	id, ok := m.Header[HeaderSchemaID]
+	if !ok {
+		return ErrMissingSchema
+	}
+
+	s, err := v.r.GetByID(context.Background(), id)
+	if err != nil {
+		return fmt.Errorf("schema %s: %w", id, err)
+	}
+
+	subject, ok := v.subjects[topic]
+	if !ok || len(s.Subject) == 0 {
+		return nil
+	}
+
+	if s.Subject != subject {
+		return fmt.Errorf("schema %s belongs to %s, not %s", id, s.Subject, subject)
+	}
+
+	return nil
+}
+
+func (v *validator) Publish(topic string, m *broker.Message, opts ...broker.PublishOption) error {
+	if err := v.validate(topic, m); err != nil {
+		return err
+	}
+	return v.Broker.Publish(topic, m, opts...)
+}
+
+func (v *validator) Subscribe(topic string, h broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	return v.Broker.Subscribe(topic, func(e broker.Event) error {
+		if err := v.validate(e.Topic(), e.Message()); err != nil {
+			return err
+		}
+		return h(e)
+	}, opts...)
+}
+
+func (v *validator) String() string {
+	return v.Broker.String()
+}
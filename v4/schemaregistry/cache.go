@@ -0,0 +1,137 @@
+package schemaregistry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type cached struct {
+	schema  *Schema
+	expires time.Time
+}
+
+// cache caches the schemas of a registry. Schema versions are immutable, so
+// they're cached until the process exits, only the latest versions expire.
+type cache struct {
+	Registry
+	ttl time.Duration
+
+	sync.RWMutex
+	ids        map[string]*Schema
+	versions   map[string]*Schema
+	latest     map[string]cached
+	registered map[string]*Schema
+}
+
+// NewCache returns the registry caching its schemas locally. The latest
+// version of a subject is looked up again after the ttl.
+func NewCache(r Registry, ttl time.Duration) Registry {
+	return &cache{
+		Registry:   r,
+		ttl:        ttl,
+		ids:        make(map[string]*Schema),
+		versions:   make(map[string]*Schema),
+		latest:     make(map[string]cached),
+		registered: make(map[string]*Schema),
+	}
+}
+
+func versionKey(subject string, version int) string {
+	return fmt.Sprintf("%s/%d", subject, version)
+}
+
+func (c *cache) add(s *Schema) {
+	c.ids[s.ID] = s
+	c.versions[versionKey(s.Subject, s.Version)] = s
+}
+
+func (c *cache) Register(ctx context.Context, s *Schema) (*Schema, error) {
+	key := s.Subject + "\xff" + string(s.Type) + "\xff" + s.Definition
+
+	c.RLock()
+	rs, ok := c.registered[key]
+	c.RUnlock()
+	if ok {
+		return rs, nil
+	}
+
+	rs, err := c.Registry.Register(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Lock()
+	c.registered[key] = rs
+	c.add(rs)
+	c.Unlock()
+
+	return rs, nil
+}
+
+func (c *cache) GetByID(ctx context.Context, id string) (*Schema, error) {
+	c.RLock()
+	s, ok := c.ids[id]
+	c.RUnlock()
+	if ok {
+		return s, nil
+	}
+
+	s, err := c.Registry.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Lock()
+	// backends may not know the subject of an id
+	c.ids[id] = s
+	c.Unlock()
+
+	return s, nil
+}
+
+func (c *cache) GetLatest(ctx context.Context, subject string) (*Schema, error) {
+	c.RLock()
+	l, ok := c.latest[subject]
+	c.RUnlock()
+	if ok && time.Now().Before(l.expires) {
+		return l.schema, nil
+	}
+
+	s, err := c.Registry.GetLatest(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Lock()
+	c.latest[subject] = cached{schema: s, expires: time.Now().Add(c.ttl)}
+	c.add(s)
+	c.Unlock()
+
+	return s, nil
+}
+
+func (c *cache) GetVersion(ctx context.Context, subject string, version int) (*Schema, error) {
+	c.RLock()
+	s, ok := c.versions[versionKey(subject, version)]
+	c.RUnlock()
+	if ok {
+		return s, nil
+	}
+
+	s, err := c.Registry.GetVersion(ctx, subject, version)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Lock()
+	c.add(s)
+	c.Unlock()
+
+	return s, nil
+}
+
+func (c *cache) String() string {
+	return c.Registry.String()
+}
@@ -0,0 +1,61 @@
+package schemaregistry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// IncompatibleError lists the subjects whose schemas aren't compatible.
+type IncompatibleError struct {
+	Subjects []string
+}
+
+func (e *IncompatibleError) Error() string {
+	return fmt.Sprintf("incompatible schemas for subjects %s", strings.Join(e.Subjects, ", "))
+}
+
+// Check checks the schemas a service uses against the registry at startup,
+// so an incompatible schema fails the deploy instead of its consumers.
+// Subjects which don't exist yet are compatible.
+func Check(ctx context.Context, r Registry, schemas ...*Schema) error {
+	var incompatible []string
+
+	for _, s := range schemas {
+		ok, err := r.CheckCompatibility(ctx, s)
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("checking %s: %w", s.Subject, err)
+		}
+		if !ok {
+			incompatible = append(incompatible, s.Subject)
+		}
+	}
+
+	if len(incompatible) > 0 {
+		return &IncompatibleError{Subjects: incompatible}
+	}
+
+	return nil
+}
+
+// RegisterAll checks and registers the schemas, returning the registered
+// versions in the same order.
+func RegisterAll(ctx context.Context, r Registry, schemas ...*Schema) ([]*Schema, error) {
+	if err := Check(ctx, r, schemas...); err != nil {
+		return nil, err
+	}
+
+	registered := make([]*Schema, 0, len(schemas))
+	for _, s := range schemas {
+		rs, err := r.Register(ctx, s)
+		if err != nil {
+			return nil, fmt.Errorf("registering %s: %w", s.Subject, err)
+		}
+		registered = append(registered, rs)
+	}
+
+	return registered, nil
+}
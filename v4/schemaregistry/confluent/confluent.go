@@ -0,0 +1,172 @@
+// Package confluent provides a Confluent schema registry backend.
+package confluent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-micro/plugins/v4/schemaregistry"
+)
+
+const contentType = "application/vnd.schemaregistry.v1+json"
+
+type confluent struct {
+	opts schemaregistry.Options
+}
+
+// NewRegistry returns a Confluent schema registry, by default at
+// http://localhost:8081.
+func NewRegistry(opts ...schemaregistry.Option) schemaregistry.Registry {
+	options := schemaregistry.NewOptions(opts...)
+	if len(options.URL) == 0 {
+		options.URL = "http://localhost:8081"
+	}
+	options.URL = strings.TrimSuffix(options.URL, "/")
+
+	return &confluent{opts: options}
+}
+
+type schemaResponse struct {
+	ID         int    `json:"id"`
+	Subject    string `json:"subject"`
+	Version    int    `json:"version"`
+	SchemaType string `json:"schemaType"`
+	Schema     string `json:"schema"`
+}
+
+func (r *schemaResponse) toSchema() *schemaregistry.Schema {
+	t := schemaregistry.Type(r.SchemaType)
+	if len(t) == 0 {
+		t = schemaregistry.Avro
+	}
+
+	return &schemaregistry.Schema{
+		ID:         strconv.Itoa(r.ID),
+		Subject:    r.Subject,
+		Version:    r.Version,
+		Type:       t,
+		Definition: r.Schema,
+	}
+}
+
+type schemaRequest struct {
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType,omitempty"`
+}
+
+func newSchemaRequest(s *schemaregistry.Schema) *schemaRequest {
+	req := &schemaRequest{Schema: s.Definition}
+	// avro is the default and not known to old registries
+	if s.Type != schemaregistry.Avro {
+		req.SchemaType = string(s.Type)
+	}
+	return req
+}
+
+type errorResponse struct {
+	ErrorCode int    `json:"error_code"`
+	Message   string `json:"message"`
+}
+
+func (c *confluent) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.opts.URL+path, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", contentType)
+	if in != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if len(c.opts.Username) > 0 {
+		req.SetBasicAuth(c.opts.Username, c.opts.Password)
+	}
+
+	rsp, err := c.opts.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode == http.StatusNotFound {
+		return schemaregistry.ErrNotFound
+	}
+
+	if rsp.StatusCode >= 300 {
+		var e errorResponse
+		json.NewDecoder(rsp.Body).Decode(&e)
+		return fmt.Errorf("confluent schema registry: %d %s", e.ErrorCode, e.Message)
+	}
+
+	return json.NewDecoder(rsp.Body).Decode(out)
+}
+
+func subjectPath(subject string) string {
+	return "/subjects/" + url.PathEscape(subject)
+}
+
+func (c *confluent) Register(ctx context.Context, s *schemaregistry.Schema) (*schemaregistry.Schema, error) {
+	var rsp schemaResponse
+	if err := c.do(ctx, http.MethodPost, subjectPath(s.Subject)+"/versions", newSchemaRequest(s), &rsp); err != nil {
+		return nil, err
+	}
+
+	// the response only has the id, look up the version
+	var version schemaResponse
+	if err := c.do(ctx, http.MethodPost, subjectPath(s.Subject), newSchemaRequest(s), &version); err != nil {
+		return nil, err
+	}
+
+	return version.toSchema(), nil
+}
+
+func (c *confluent) GetByID(ctx context.Context, id string) (*schemaregistry.Schema, error) {
+	var rsp schemaResponse
+	if err := c.do(ctx, http.MethodGet, "/schemas/ids/"+url.PathEscape(id), nil, &rsp); err != nil {
+		return nil, err
+	}
+	rsp.ID, _ = strconv.Atoi(id)
+	return rsp.toSchema(), nil
+}
+
+func (c *confluent) GetLatest(ctx context.Context, subject string) (*schemaregistry.Schema, error) {
+	var rsp schemaResponse
+	if err := c.do(ctx, http.MethodGet, subjectPath(subject)+"/versions/latest", nil, &rsp); err != nil {
+		return nil, err
+	}
+	return rsp.toSchema(), nil
+}
+
+func (c *confluent) GetVersion(ctx context.Context, subject string, version int) (*schemaregistry.Schema, error) {
+	var rsp schemaResponse
+	if err := c.do(ctx, http.MethodGet, subjectPath(subject)+"/versions/"+strconv.Itoa(version), nil, &rsp); err != nil {
+		return nil, err
+	}
+	return rsp.toSchema(), nil
+}
+
+func (c *confluent) CheckCompatibility(ctx context.Context, s *schemaregistry.Schema) (bool, error) {
+	var rsp struct {
+		IsCompatible bool `json:"is_compatible"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/compatibility"+subjectPath(s.Subject)+"/versions/latest", newSchemaRequest(s), &rsp); err != nil {
+		return false, err
+	}
+	return rsp.IsCompatible, nil
+}
+
+func (c *confluent) String() string {
+	return "confluent"
+}
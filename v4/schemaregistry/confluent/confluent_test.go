@@ -0,0 +1,84 @@
+package confluent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-micro/plugins/v4/schemaregistry"
+)
+
+func TestConfluent(t *testing.T) {
+	var auth string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/subjects/users/versions", func(w http.ResponseWriter, r *http.Request) {
+		auth, _, _ = r.BasicAuth()
+		w.Write([]byte(`{"id":7}`))
+	})
+	mux.HandleFunc("/subjects/users", func(w http.ResponseWriter, r *http.Request) {
+		var req schemaRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(schemaResponse{ID: 7, Subject: "users", Version: 3, SchemaType: req.SchemaType, Schema: req.Schema})
+	})
+	mux.HandleFunc("/schemas/ids/7", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"schema":"\"string\""}`))
+	})
+	mux.HandleFunc("/subjects/users/versions/latest", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":7,"subject":"users","version":3,"schema":"\"string\""}`))
+	})
+	mux.HandleFunc("/compatibility/subjects/users/versions/latest", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"is_compatible":false}`))
+	})
+	mux.HandleFunc("/compatibility/subjects/orders/versions/latest", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error_code":40401,"message":"Subject not found"}`))
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	ctx := context.Background()
+	r := NewRegistry(schemaregistry.URL(srv.URL), schemaregistry.BasicAuth("user", "pass"))
+
+	s, err := r.Register(ctx, &schemaregistry.Schema{Subject: "users", Type: schemaregistry.JSON, Definition: "{}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.ID != "7" || s.Version != 3 || s.Type != schemaregistry.JSON {
+		t.Fatalf("unexpected schema %+v", s)
+	}
+	if auth != "user" {
+		t.Fatalf("expected basic auth, got %q", auth)
+	}
+
+	s, err = r.GetByID(ctx, "7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.ID != "7" || s.Type != schemaregistry.Avro || s.Definition != `"string"` {
+		t.Fatalf("unexpected schema %+v", s)
+	}
+
+	s, err = r.GetLatest(ctx, "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Version != 3 {
+		t.Fatalf("expected version 3, got %d", s.Version)
+	}
+
+	if _, err := r.GetVersion(ctx, "users", 1); err != schemaregistry.ErrNotFound {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	err = schemaregistry.Check(ctx, r,
+		&schemaregistry.Schema{Subject: "orders", Definition: "{}"},
+		&schemaregistry.Schema{Subject: "users", Definition: "{}"},
+	)
+	if _, ok := err.(*schemaregistry.IncompatibleError); !ok {
+		t.Fatalf("expected incompatible error, got %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+// Package glue provides an AWS Glue schema registry backend. Subjects are
+// schema names of a registry, schema ids are schema version ids.
+package glue
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/glue"
+	"github.com/aws/aws-sdk-go/service/glue/glueiface"
+	"github.com/go-micro/plugins/v4/schemaregistry"
+)
+
+type clientKey struct{}
+
+type registryNameKey struct{}
+
+type compatibilityKey struct{}
+
+// Client sets the glue client, e.g. to configure custom credentials.
+// Defaults to a client of the default session.
+func Client(c glueiface.GlueAPI) schemaregistry.Option {
+	return func(o *schemaregistry.Options) {
+		o.Context = context.WithValue(o.Context, clientKey{}, c)
+	}
+}
+
+// RegistryName sets the registry the schemas are in. Defaults to
+// "default-registry".
+func RegistryName(name string) schemaregistry.Option {
+	return func(o *schemaregistry.Options) {
+		o.Context = context.WithValue(o.Context, registryNameKey{}, name)
+	}
+}
+
+// Compatibility sets the compatibility mode of the schemas created by
+// Register, e.g. "BACKWARD" or "FULL". Defaults to "BACKWARD".
+func Compatibility(c string) schemaregistry.Option {
+	return func(o *schemaregistry.Options) {
+		o.Context = context.WithValue(o.Context, compatibilityKey{}, c)
+	}
+}
+
+type glueRegistry struct {
+	client        glueiface.GlueAPI
+	registry      string
+	compatibility string
+}
+
+// NewRegistry returns a Glue schema registry.
+func NewRegistry(opts ...schemaregistry.Option) schemaregistry.Registry {
+	options := schemaregistry.NewOptions(opts...)
+
+	g := &glueRegistry{
+		registry:      "default-registry",
+		compatibility: glue.CompatibilityBackward,
+	}
+
+	if c, ok := options.Context.Value(clientKey{}).(glueiface.GlueAPI); ok {
+		g.client = c
+	} else {
+		g.client = glue.New(session.Must(session.NewSession()))
+	}
+	if n, ok := options.Context.Value(registryNameKey{}).(string); ok && len(n) > 0 {
+		g.registry = n
+	}
+	if c, ok := options.Context.Value(compatibilityKey{}).(string); ok && len(c) > 0 {
+		g.compatibility = c
+	}
+
+	return g
+}
+
+func (g *glueRegistry) schemaID(subject string) *glue.SchemaId {
+	return &glue.SchemaId{
+		RegistryName: aws.String(g.registry),
+		SchemaName:   aws.String(subject),
+	}
+}
+
+func dataFormat(t schemaregistry.Type) string {
+	if len(t) == 0 {
+		return string(schemaregistry.Avro)
+	}
+	return string(t)
+}
+
+func isNotFound(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == glue.ErrCodeEntityNotFoundException
+}
+
+func (g *glueRegistry) Register(ctx context.Context, s *schemaregistry.Schema) (*schemaregistry.Schema, error) {
+	rsp, err := g.client.RegisterSchemaVersionWithContext(ctx, &glue.RegisterSchemaVersionInput{
+		SchemaId:         g.schemaID(s.Subject),
+		SchemaDefinition: aws.String(s.Definition),
+	})
+	if err == nil {
+		if aws.StringValue(rsp.Status) == glue.SchemaVersionStatusFailure {
+			return nil, &schemaregistry.IncompatibleError{Subjects: []string{s.Subject}}
+		}
+
+		return &schemaregistry.Schema{
+			ID:         aws.StringValue(rsp.SchemaVersionId),
+			Subject:    s.Subject,
+			Version:    int(aws.Int64Value(rsp.VersionNumber)),
+			Type:       schemaregistry.Type(dataFormat(s.Type)),
+			Definition: s.Definition,
+		}, nil
+	}
+
+	if !isNotFound(err) {
+		return nil, err
+	}
+
+	// the first version creates the schema
+	crsp, err := g.client.CreateSchemaWithContext(ctx, &glue.CreateSchemaInput{
+		RegistryId:       &glue.RegistryId{RegistryName: aws.String(g.registry)},
+		SchemaName:       aws.String(s.Subject),
+		DataFormat:       aws.String(dataFormat(s.Type)),
+		Compatibility:    aws.String(g.compatibility),
+		SchemaDefinition: aws.String(s.Definition),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &schemaregistry.Schema{
+		ID:         aws.StringValue(crsp.SchemaVersionId),
+		Subject:    s.Subject,
+		Version:    int(aws.Int64Value(crsp.LatestSchemaVersion)),
+		Type:       schemaregistry.Type(aws.StringValue(crsp.DataFormat)),
+		Definition: s.Definition,
+	}, nil
+}
+
+func (g *glueRegistry) get(ctx context.Context, in *glue.GetSchemaVersionInput) (*schemaregistry.Schema, error) {
+	rsp, err := g.client.GetSchemaVersionWithContext(ctx, in)
+	if isNotFound(err) {
+		return nil, schemaregistry.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the schema name is the last segment of the arn
+	arn := aws.StringValue(rsp.SchemaArn)
+	subject := arn[strings.LastIndex(arn, "/")+1:]
+
+	return &schemaregistry.Schema{
+		ID:         aws.StringValue(rsp.SchemaVersionId),
+		Subject:    subject,
+		Version:    int(aws.Int64Value(rsp.VersionNumber)),
+		Type:       schemaregistry.Type(aws.StringValue(rsp.DataFormat)),
+		Definition: aws.StringValue(rsp.SchemaDefinition),
+	}, nil
+}
+
+func (g *glueRegistry) GetByID(ctx context.Context, id string) (*schemaregistry.Schema, error) {
+	return g.get(ctx, &glue.GetSchemaVersionInput{SchemaVersionId: aws.String(id)})
+}
+
+func (g *glueRegistry) GetLatest(ctx context.Context, subject string) (*schemaregistry.Schema, error) {
+	return g.get(ctx, &glue.GetSchemaVersionInput{
+		SchemaId:            g.schemaID(subject),
+		SchemaVersionNumber: &glue.SchemaVersionNumber{LatestVersion: aws.Bool(true)},
+	})
+}
+
+func (g *glueRegistry) GetVersion(ctx context.Context, subject string, version int) (*schemaregistry.Schema, error) {
+	return g.get(ctx, &glue.GetSchemaVersionInput{
+		SchemaId:            g.schemaID(subject),
+		SchemaVersionNumber: &glue.SchemaVersionNumber{VersionNumber: aws.Int64(int64(version))},
+	})
+}
+
+// CheckCompatibility only validates the definition, Glue checks the
+// compatibility when a version is registered.
+func (g *glueRegistry) CheckCompatibility(ctx context.Context, s *schemaregistry.Schema) (bool, error) {
+	rsp, err := g.client.CheckSchemaVersionValidityWithContext(ctx, &glue.CheckSchemaVersionValidityInput{
+		DataFormat:       aws.String(dataFormat(s.Type)),
+		SchemaDefinition: aws.String(s.Definition),
+	})
+	if err != nil {
+		return false, err
+	}
+	return aws.BoolValue(rsp.Valid), nil
+}
+
+func (g *glueRegistry) String() string {
+	return "glue"
+}
@@ -0,0 +1,90 @@
+package schemaregistry
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"time"
+)
+
+// Options configure a backend.
+type Options struct {
+	// URL of the registry
+	URL string
+	// Username and Password for basic auth
+	Username string
+	Password string
+	// TLSConfig of the connection to the registry
+	TLSConfig *tls.Config
+	// Timeout of requests. Defaults to 10 seconds
+	Timeout time.Duration
+	// HTTPClient overrides the client used by http backends
+	HTTPClient *http.Client
+
+	// Other options for implementations of the interface
+	// can be stored in a context
+	Context context.Context
+}
+
+// Option sets an option.
+type Option func(*Options)
+
+// URL sets the url of the registry.
+func URL(u string) Option {
+	return func(o *Options) {
+		o.URL = u
+	}
+}
+
+// BasicAuth sets the credentials of the registry.
+func BasicAuth(username, password string) Option {
+	return func(o *Options) {
+		o.Username = username
+		o.Password = password
+	}
+}
+
+// TLSConfig sets the tls config of the connection to the registry.
+func TLSConfig(c *tls.Config) Option {
+	return func(o *Options) {
+		o.TLSConfig = c
+	}
+}
+
+// Timeout sets the timeout of requests.
+func Timeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.Timeout = d
+	}
+}
+
+// HTTPClient sets the client used by http backends.
+func HTTPClient(c *http.Client) Option {
+	return func(o *Options) {
+		o.HTTPClient = c
+	}
+}
+
+// NewOptions returns the options with the defaults applied, for backends.
+func NewOptions(opts ...Option) Options {
+	options := Options{
+		Timeout: 10 * time.Second,
+		Context: context.Background(),
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if options.HTTPClient == nil {
+		options.HTTPClient = &http.Client{Timeout: options.Timeout}
+		if options.TLSConfig != nil {
+			options.HTTPClient.Transport = &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: options.TLSConfig,
+			}
+		}
+	}
+
+	return options
+}
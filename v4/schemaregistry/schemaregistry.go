@@ -0,0 +1,57 @@
+// Package schemaregistry provides a schema registry client abstraction shared
+// by codecs and brokers. Backends for the Confluent, Apicurio and AWS Glue
+// registries are in the subpackages.
+package schemaregistry
+
+import (
+	"context"
+	"errors"
+)
+
+// Type is the format of a schema.
+type Type string
+
+const (
+	Avro     Type = "AVRO"
+	Protobuf Type = "PROTOBUF"
+	JSON     Type = "JSON"
+)
+
+var (
+	// ErrNotFound is returned if the schema or subject doesn't exist.
+	ErrNotFound = errors.New("schema not found")
+	// ErrNotSupported is returned if the backend doesn't support the operation.
+	ErrNotSupported = errors.New("not supported by the schema registry")
+)
+
+// Schema is a version of the schema of a subject.
+type Schema struct {
+	// ID uniquely identifies the schema version in the registry
+	ID string
+	// Subject the schema is registered under, e.g. the topic
+	Subject string
+	// Version of the schema within the subject
+	Version int
+	// Type of the schema, defaults to Avro
+	Type Type
+	// Definition is the schema itself
+	Definition string
+}
+
+// Registry is a schema registry.
+type Registry interface {
+	// Register registers the schema under its subject, returning the
+	// registered version. Registering an existing schema returns it.
+	Register(ctx context.Context, s *Schema) (*Schema, error)
+	// GetByID returns the schema version with the id.
+	GetByID(ctx context.Context, id string) (*Schema, error)
+	// GetLatest returns the latest version of the subject.
+	GetLatest(ctx context.Context, subject string) (*Schema, error)
+	// GetVersion returns a version of the subject.
+	GetVersion(ctx context.Context, subject string, version int) (*Schema, error)
+	// CheckCompatibility reports whether the schema is compatible with the
+	// subject according to its compatibility level.
+	CheckCompatibility(ctx context.Context, s *Schema) (bool, error)
+	// String returns the name of the backend.
+	String() string
+}
@@ -0,0 +1,245 @@
+package schemaregistry
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/broker"
+)
+
+// memory is an in memory registry where every schema is compatible unless
+// its definition is "incompatible".
+type memory struct {
+	sync.Mutex
+	schemas []*Schema
+	lookups int
+}
+
+func (m *memory) Register(ctx context.Context, s *Schema) (*Schema, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	version := 1
+	for _, rs := range m.schemas {
+		if rs.Subject != s.Subject {
+			continue
+		}
+		if rs.Definition == s.Definition {
+			return rs, nil
+		}
+		version++
+	}
+
+	rs := *s
+	rs.ID = strconv.Itoa(len(m.schemas) + 1)
+	rs.Version = version
+	m.schemas = append(m.schemas, &rs)
+	return &rs, nil
+}
+
+func (m *memory) GetByID(ctx context.Context, id string) (*Schema, error) {
+	m.Lock()
+	defer m.Unlock()
+	m.lookups++
+
+	for _, s := range m.schemas {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *memory) GetLatest(ctx context.Context, subject string) (*Schema, error) {
+	m.Lock()
+	defer m.Unlock()
+	m.lookups++
+
+	var latest *Schema
+	for _, s := range m.schemas {
+		if s.Subject == subject {
+			latest = s
+		}
+	}
+	if latest == nil {
+		return nil, ErrNotFound
+	}
+	return latest, nil
+}
+
+func (m *memory) GetVersion(ctx context.Context, subject string, version int) (*Schema, error) {
+	m.Lock()
+	defer m.Unlock()
+	m.lookups++
+
+	for _, s := range m.schemas {
+		if s.Subject == subject && s.Version == version {
+			return s, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *memory) CheckCompatibility(ctx context.Context, s *Schema) (bool, error) {
+	if _, err := m.GetLatest(ctx, s.Subject); err != nil {
+		return false, err
+	}
+	return s.Definition != "incompatible", nil
+}
+
+func (m *memory) String() string {
+	return "memory"
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	m := new(memory)
+	c := NewCache(m, time.Hour)
+
+	s, err := c.Register(ctx, &Schema{Subject: "users", Type: Avro, Definition: `"string"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetByID(ctx, s.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.GetVersion(ctx, "users", 1); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.GetLatest(ctx, "users"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// only the first latest lookup hits the registry
+	if m.lookups != 1 {
+		t.Fatalf("expected 1 lookup, got %d", m.lookups)
+	}
+
+	if _, err := c.GetByID(ctx, "42"); err != ErrNotFound {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	ctx := context.Background()
+	m := new(memory)
+	c := NewCache(m, time.Millisecond)
+
+	c.Register(ctx, &Schema{Subject: "users", Definition: "v1"})
+	if s, _ := c.GetLatest(ctx, "users"); s.Version != 1 {
+		t.Fatalf("expected version 1, got %d", s.Version)
+	}
+
+	m.Register(ctx, &Schema{Subject: "users", Definition: "v2"})
+	time.Sleep(5 * time.Millisecond)
+
+	if s, _ := c.GetLatest(ctx, "users"); s.Version != 2 {
+		t.Fatalf("expected version 2, got %d", s.Version)
+	}
+}
+
+func TestRegisterAll(t *testing.T) {
+	ctx := context.Background()
+	m := new(memory)
+
+	schemas, err := RegisterAll(ctx, m,
+		&Schema{Subject: "users", Definition: "v1"},
+		&Schema{Subject: "orders", Definition: "v1"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schemas) != 2 || schemas[1].Subject != "orders" {
+		t.Fatalf("unexpected schemas %+v", schemas)
+	}
+
+	_, err = RegisterAll(ctx, m,
+		&Schema{Subject: "users", Definition: "v2"},
+		&Schema{Subject: "orders", Definition: "incompatible"},
+	)
+	var ie *IncompatibleError
+	if !errors.As(err, &ie) || len(ie.Subjects) != 1 || ie.Subjects[0] != "orders" {
+		t.Fatalf("expected orders to be incompatible, got %v", err)
+	}
+
+	// nothing is registered if a schema is incompatible
+	if s, _ := m.GetLatest(ctx, "users"); s.Version != 1 {
+		t.Fatalf("expected version 1, got %d", s.Version)
+	}
+}
+
+func TestWireFormat(t *testing.T) {
+	b, err := Encode("258", []byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != 9 || b[0] != 0 || b[3] != 1 || b[4] != 2 {
+		t.Fatalf("unexpected encoding %v", b)
+	}
+
+	id, data, err := Decode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "258" || string(data) != "data" {
+		t.Fatalf("unexpected decoding %s %s", id, data)
+	}
+
+	if _, _, err := Decode([]byte("data")); err != ErrInvalidWireFormat {
+		t.Fatalf("expected invalid wire format, got %v", err)
+	}
+	if _, err := Encode("abc", nil); err == nil {
+		t.Fatal("expected error for non numeric id")
+	}
+}
+
+func TestBroker(t *testing.T) {
+	ctx := context.Background()
+	m := new(memory)
+	users, _ := m.Register(ctx, &Schema{Subject: "users", Definition: "v1"})
+	orders, _ := m.Register(ctx, &Schema{Subject: "orders", Definition: "v1"})
+
+	b := NewBroker(broker.NewMemoryBroker(), m, map[string]string{"users": "users"})
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	received := make(chan string, 1)
+	if _, err := b.Subscribe("users", func(e broker.Event) error {
+		received <- e.Message().Header[HeaderSchemaID]
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Publish("users", &broker.Message{}); err != ErrMissingSchema {
+		t.Fatalf("expected missing schema, got %v", err)
+	}
+	if err := b.Publish("users", &broker.Message{Header: map[string]string{HeaderSchemaID: "42"}}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if err := b.Publish("users", &broker.Message{Header: map[string]string{HeaderSchemaID: orders.ID}}); err == nil {
+		t.Fatal("expected error for schema of another subject")
+	}
+
+	if err := b.Publish("users", &broker.Message{Header: map[string]string{HeaderSchemaID: users.ID}}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case id := <-received:
+		if id != users.ID {
+			t.Fatalf("expected schema %s, got %s", users.ID, id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message not received")
+	}
+}
@@ -0,0 +1,36 @@
+package schemaregistry
+
+import (
+	"encoding/binary"
+	"errors"
+	"strconv"
+)
+
+// ErrInvalidWireFormat is returned for data without the wire format header.
+var ErrInvalidWireFormat = errors.New("data is not in the schema registry wire format")
+
+const magicByte = 0
+
+// Encode prefixes the data with the Confluent wire format header, a zero
+// byte and the 4 byte big endian schema id. The id must be numeric.
+func Encode(id string, data []byte) ([]byte, error) {
+	n, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 5+len(data))
+	b[0] = magicByte
+	binary.BigEndian.PutUint32(b[1:5], uint32(n))
+	copy(b[5:], data)
+	return b, nil
+}
+
+// Decode splits data in the Confluent wire format into the schema id and the
+// encoded data.
+func Decode(b []byte) (string, []byte, error) {
+	if len(b) < 5 || b[0] != magicByte {
+		return "", nil, ErrInvalidWireFormat
+	}
+	return strconv.FormatUint(uint64(binary.BigEndian.Uint32(b[1:5])), 10), b[5:], nil
+}
@@ -0,0 +1,32 @@
+package subset
+
+import (
+	"context"
+
+	"go-micro.dev/v4/selector"
+)
+
+type clientIDKey struct{}
+type sizeKey struct{}
+
+// ClientID sets the identity used to pick this client's subset. Clients with
+// the same id always select the same subset, so it should be unique per
+// instance. Defaults to the hostname.
+func ClientID(id string) selector.Option {
+	return func(o *selector.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, clientIDKey{}, id)
+	}
+}
+
+// Size sets the number of nodes each client connects to. Defaults to 10.
+func Size(n int) selector.Option {
+	return func(o *selector.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, sizeKey{}, n)
+	}
+}
@@ -0,0 +1,258 @@
+// Package subset is a selector which limits each client to a stable subset of nodes.
+package subset
+
+import (
+	"context"
+	"hash/fnv"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/registry/cache"
+	"go-micro.dev/v4/selector"
+	"go-micro.dev/v4/util/cmd"
+)
+
+/*
+   A deterministic subsetting selector. When a service runs hundreds of instances
+   every client connecting to every node leads to unmanageable connection counts.
+   Instead each client picks a subset of N out of M nodes based on its client id,
+   such that the subsets are evenly spread across the clients. The subset is stable
+   for a given membership and recomputed whenever nodes join or leave.
+
+   See https://sre.google/sre-book/load-balancing-datacenter/#a-subset-selection-algorithm-deterministic-subsetting-eKsdcecQ
+*/
+
+const defaultSize = 10
+
+type subsetSelector struct {
+	so selector.Options
+	rc cache.Cache
+
+	sync.RWMutex
+	// subsets by service name
+	subsets map[string]*subset
+}
+
+type subset struct {
+	// fingerprint of the membership the subset was computed from
+	fingerprint string
+	nodes       map[string]bool
+}
+
+func init() {
+	cmd.DefaultSelectors["subset"] = NewSelector
+}
+
+func (s *subsetSelector) newCache() cache.Cache {
+	opts := make([]cache.Option, 0, 1)
+	if t, ok := s.so.Context.Value("selector_ttl").(time.Duration); ok {
+		opts = append(opts, cache.WithTTL(t))
+	}
+	return cache.New(s.so.Registry, opts...)
+}
+
+func (s *subsetSelector) clientID() uint64 {
+	id, ok := s.so.Context.Value(clientIDKey{}).(string)
+	if !ok || len(id) == 0 {
+		id, _ = os.Hostname()
+	}
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	return h.Sum64()
+}
+
+func (s *subsetSelector) size() int {
+	if n, ok := s.so.Context.Value(sizeKey{}).(int); ok && n > 0 {
+		return n
+	}
+	return defaultSize
+}
+
+// fingerprint returns a key identifying the membership of the node list.
+func fingerprint(nodes []*registry.Node) string {
+	ids := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		ids = append(ids, node.Id)
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, ",")
+}
+
+// deterministic returns the subset of nodes for the given client id. Nodes are
+// shuffled with a seed derived from the client's round so that every group of
+// clients in a round covers all nodes exactly once.
+func deterministic(nodes []*registry.Node, clientID uint64, size int) []*registry.Node {
+	if len(nodes) <= size {
+		return nodes
+	}
+
+	sorted := make([]*registry.Node, len(nodes))
+	copy(sorted, nodes)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Id < sorted[j].Id
+	})
+
+	count := uint64(len(sorted) / size)
+	round := clientID / count
+
+	r := rand.New(rand.NewSource(int64(round)))
+	r.Shuffle(len(sorted), func(i, j int) {
+		sorted[i], sorted[j] = sorted[j], sorted[i]
+	})
+
+	start := int(clientID%count) * size
+	return sorted[start : start+size]
+}
+
+// subset returns the ids of the nodes this client should use, recomputing
+// them if the membership changed since the last call.
+func (s *subsetSelector) subset(service string, nodes []*registry.Node) map[string]bool {
+	fp := fingerprint(nodes)
+
+	s.RLock()
+	sub, ok := s.subsets[service]
+	s.RUnlock()
+
+	if ok && sub.fingerprint == fp {
+		return sub.nodes
+	}
+
+	sub = &subset{
+		fingerprint: fp,
+		nodes:       make(map[string]bool),
+	}
+	for _, node := range deterministic(nodes, s.clientID(), s.size()) {
+		sub.nodes[node.Id] = true
+	}
+
+	s.Lock()
+	s.subsets[service] = sub
+	s.Unlock()
+
+	return sub.nodes
+}
+
+func (s *subsetSelector) Init(opts ...selector.Option) error {
+	for _, o := range opts {
+		o(&s.so)
+	}
+
+	s.rc.Stop()
+	s.rc = s.newCache()
+
+	s.Lock()
+	s.subsets = make(map[string]*subset)
+	s.Unlock()
+
+	return nil
+}
+
+func (s *subsetSelector) Options() selector.Options {
+	return s.so
+}
+
+func (s *subsetSelector) Select(service string, opts ...selector.SelectOption) (selector.Next, error) {
+	sopts := selector.SelectOptions{
+		Strategy: s.so.Strategy,
+	}
+
+	for _, opt := range opts {
+		opt(&sopts)
+	}
+
+	// get the service
+	services, err := s.rc.GetService(service)
+	if err != nil {
+		if err == registry.ErrNotFound {
+			return nil, selector.ErrNotFound
+		}
+		return nil, err
+	}
+
+	// apply the filters
+	for _, filter := range sopts.Filters {
+		services = filter(services)
+	}
+
+	// if there's nothing left, return
+	if len(services) == 0 {
+		return nil, selector.ErrNoneAvailable
+	}
+
+	var nodes []*registry.Node
+
+	// flatten node list
+	for _, service := range services {
+		nodes = append(nodes, service.Nodes...)
+	}
+
+	// any nodes left?
+	if len(nodes) == 0 {
+		return nil, selector.ErrNoneAvailable
+	}
+
+	ids := s.subset(service, nodes)
+
+	// only keep the nodes in our subset
+	subsetted := make([]*registry.Service, 0, len(services))
+	for _, service := range services {
+		var snodes []*registry.Node
+		for _, node := range service.Nodes {
+			if ids[node.Id] {
+				snodes = append(snodes, node)
+			}
+		}
+		if len(snodes) == 0 {
+			continue
+		}
+		svc := *service
+		svc.Nodes = snodes
+		subsetted = append(subsetted, &svc)
+	}
+
+	return sopts.Strategy(subsetted), nil
+}
+
+func (s *subsetSelector) Mark(service string, node *registry.Node, err error) {
+}
+
+func (s *subsetSelector) Reset(service string) {
+	s.Lock()
+	delete(s.subsets, service)
+	s.Unlock()
+}
+
+func (s *subsetSelector) Close() error {
+	s.rc.Stop()
+	return nil
+}
+
+func (s *subsetSelector) String() string {
+	return "subset"
+}
+
+// NewSelector returns a new subset selector.
+func NewSelector(opts ...selector.Option) selector.Selector {
+	sopts := selector.Options{
+		Context:  context.TODO(),
+		Registry: registry.DefaultRegistry,
+		Strategy: selector.Random,
+	}
+
+	for _, opt := range opts {
+		opt(&sopts)
+	}
+
+	s := &subsetSelector{
+		so:      sopts,
+		subsets: make(map[string]*subset),
+	}
+	s.rc = s.newCache()
+
+	return s
+}
@@ -0,0 +1,123 @@
+package subset
+
+import (
+	"fmt"
+	"testing"
+
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/selector"
+)
+
+func testNodes(n int) []*registry.Node {
+	nodes := make([]*registry.Node, 0, n)
+	for i := 0; i < n; i++ {
+		nodes = append(nodes, &registry.Node{
+			Id:      fmt.Sprintf("node-%d", i),
+			Address: fmt.Sprintf("10.0.0.%d:8080", i),
+		})
+	}
+	return nodes
+}
+
+func TestDeterministicSubset(t *testing.T) {
+	nodes := testNodes(100)
+
+	a := deterministic(nodes, 42, 10)
+	b := deterministic(nodes, 42, 10)
+
+	if len(a) != 10 {
+		t.Fatalf("Expected subset of 10 nodes, got %d", len(a))
+	}
+
+	for i := range a {
+		if a[i].Id != b[i].Id {
+			t.Fatalf("Expected stable subset, got %s and %s", a[i].Id, b[i].Id)
+		}
+	}
+
+	// less nodes than the subset size returns all of them
+	if sub := deterministic(nodes[:5], 42, 10); len(sub) != 5 {
+		t.Fatalf("Expected all 5 nodes, got %d", len(sub))
+	}
+}
+
+func TestDeterministicSubsetCoverage(t *testing.T) {
+	nodes := testNodes(100)
+	used := make(map[string]int)
+
+	// every round of clients covers all nodes exactly once
+	for id := uint64(0); id < 10; id++ {
+		for _, node := range deterministic(nodes, id, 10) {
+			used[node.Id]++
+		}
+	}
+
+	if len(used) != len(nodes) {
+		t.Fatalf("Expected all %d nodes to be used, got %d", len(nodes), len(used))
+	}
+
+	for id, count := range used {
+		if count != 1 {
+			t.Fatalf("Expected node %s to be used once, got %d", id, count)
+		}
+	}
+}
+
+func TestSubsetSelector(t *testing.T) {
+	r := registry.NewMemoryRegistry()
+
+	service := &registry.Service{
+		Name:    "foo",
+		Version: "latest",
+		Nodes:   testNodes(50),
+	}
+
+	if err := r.Register(service); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewSelector(
+		selector.Registry(r),
+		ClientID("client-1"),
+		Size(5),
+	)
+	defer s.Close()
+
+	next, err := s.Select("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		node, err := next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[node.Id] = true
+	}
+
+	if len(seen) > 5 {
+		t.Fatalf("Expected at most 5 nodes to be selected, got %d", len(seen))
+	}
+
+	// membership change triggers re-subsetting
+	ss := s.(*subsetSelector)
+	remaining := testNodes(50)[25:]
+
+	for id := range ss.subset("foo", remaining) {
+		var found bool
+		for _, node := range remaining {
+			if node.Id == id {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("Expected removed node %s not to be in the subset", id)
+		}
+	}
+
+	if fp := ss.subsets["foo"].fingerprint; fp != fingerprint(remaining) {
+		t.Fatalf("Expected subset to be recomputed for the new membership")
+	}
+}
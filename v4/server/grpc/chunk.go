@@ -0,0 +1,100 @@
+package grpc
+
+import (
+	"fmt"
+	"io"
+
+	"go-micro.dev/v4/codec/bytes"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// chunkedHeader marks a request or response sent in chunks
+	chunkedHeader = "x-micro-chunked"
+	// acceptChunkedHeader is set by clients reassembling chunked responses
+	acceptChunkedHeader = "x-micro-accept-chunked"
+)
+
+func (g *grpcServer) getChunkSize() int {
+	if g.opts.Context == nil {
+		return 0
+	}
+	s, ok := g.opts.Context.Value(chunkSizeKey{}).(int)
+	if !ok {
+		return 0
+	}
+	return s
+}
+
+func (g *grpcServer) getMaxChunkedMsgSize() int {
+	if g.opts.Context == nil {
+		return DefaultMaxChunkedMsgSize
+	}
+	s, ok := g.opts.Context.Value(maxChunkedMsgSizeKey{}).(int)
+	if !ok {
+		return DefaultMaxChunkedMsgSize
+	}
+	return s
+}
+
+// chunked reports whether the header is set on the request.
+func chunked(stream grpc.ServerStream, header string) bool {
+	md, ok := metadata.FromIncomingContext(stream.Context())
+	return ok && len(md.Get(header)) > 0
+}
+
+// recvChunked reassembles a chunked request and decodes it into v.
+func (g *grpcServer) recvChunked(stream grpc.ServerStream, cc encoding.Codec, v interface{}) error {
+	max := g.getMaxChunkedMsgSize()
+
+	var body []byte
+	for {
+		f := &bytes.Frame{}
+		err := stream.RecvMsg(f)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if len(body)+len(f.Data) > max {
+			return status.Errorf(codes.ResourceExhausted, "chunked message larger than max (%d)", max)
+		}
+		body = append(body, f.Data...)
+	}
+
+	if err := cc.Unmarshal(body, v); err != nil {
+		return status.New(codes.InvalidArgument, fmt.Sprintf("failed to decode chunked message: %v", err)).Err()
+	}
+	return nil
+}
+
+// sendChunked sends v in chunks of size bytes if it's larger than that.
+func (g *grpcServer) sendChunked(stream grpc.ServerStream, cc encoding.Codec, v interface{}, size int) error {
+	b, err := cc.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if len(b) <= size {
+		return stream.SendMsg(&bytes.Frame{Data: b})
+	}
+
+	if err := stream.SetHeader(metadata.Pairs(chunkedHeader, "true")); err != nil {
+		return err
+	}
+	for len(b) > 0 {
+		n := size
+		if n > len(b) {
+			n = len(b)
+		}
+		if err := stream.SendMsg(&bytes.Frame{Data: b[:n]}); err != nil {
+			return err
+		}
+		b = b[n:]
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package grpc_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/errors"
+	"go-micro.dev/v4/server"
+
+	gcli "github.com/go-micro/plugins/v4/client/grpc"
+	gsrv "github.com/go-micro/plugins/v4/server/grpc"
+	pb "github.com/go-micro/plugins/v4/server/grpc/proto"
+)
+
+func TestChunking(t *testing.T) {
+	r, b, tr := getTestHarness()
+	s := gsrv.NewServer(
+		server.Broker(b),
+		server.Name("foo"),
+		server.Registry(r),
+		server.Transport(tr),
+		gsrv.MaxMsgSize(4096),
+		gsrv.ChunkSize(1024),
+	)
+	pb.RegisterTestHandler(s, &testServer{})
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	defer s.Stop()
+
+	newClient := func(opts ...client.Option) pb.TestService {
+		opts = append(opts,
+			client.Registry(r),
+			client.Broker(b),
+			client.Transport(tr),
+			gcli.MaxRecvMsgSize(4096),
+			gcli.MaxSendMsgSize(4096),
+		)
+		return pb.NewTestService("foo", gcli.NewClient(opts...))
+	}
+
+	name := strings.Repeat("a", 64*1024)
+
+	if _, err := newClient().Call(context.TODO(), &pb.Request{Name: name}); err == nil {
+		t.Fatal("expected call without chunking to fail")
+	}
+
+	c := newClient(gcli.ChunkSize(1024))
+
+	for _, name := range []string{"John", name} {
+		rsp, err := c.Call(context.TODO(), &pb.Request{Name: name})
+		if err != nil {
+			t.Fatalf("error calling server: %v", err)
+		}
+		if rsp.Msg != "Hello "+name {
+			t.Fatalf("got unexpected response of %d bytes", len(rsp.Msg))
+		}
+	}
+
+	_, err := c.Call(context.TODO(), &pb.Request{Name: "Error"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if verr := errors.Parse(err.Error()); verr.Code != 99 || verr.Id != "1" {
+		t.Fatalf("invalid error received %v", err)
+	}
+}
@@ -5,6 +5,7 @@ go 1.17
 require (
 	github.com/go-micro/plugins/v4/client/grpc v1.1.0
 	github.com/go-micro/plugins/v4/transport/grpc v1.1.0
+	github.com/go-micro/plugins/v4/util/socket v1.0.0
 	github.com/golang/protobuf v1.5.3
 	go-micro.dev/v4 v4.9.0
 	golang.org/x/net v0.0.0-20211020060615-d418f374d309
@@ -57,4 +58,5 @@ require (
 replace (
 	github.com/go-micro/plugins/v4/client/grpc => ../../client/grpc
 	github.com/go-micro/plugins/v4/transport/grpc => ../../transport/grpc
+	github.com/go-micro/plugins/v4/util/socket => ../../util/socket
 )
@@ -6,6 +6,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net"
+	"net/http"
 	"reflect"
 	"runtime/debug"
 	"sort"
@@ -14,6 +15,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/go-micro/plugins/v4/util/socket"
 	"github.com/golang/protobuf/proto"
 	"go-micro.dev/v4/broker"
 	"go-micro.dev/v4/errors"
@@ -25,7 +27,6 @@ import (
 	"go-micro.dev/v4/util/backoff"
 	"go-micro.dev/v4/util/cmd"
 	mgrpc "go-micro.dev/v4/util/grpc"
-	mnet "go-micro.dev/v4/util/net"
 	"golang.org/x/net/netutil"
 
 	"google.golang.org/grpc"
@@ -45,6 +46,14 @@ var (
 	// DefaultMaxMsgSize define maximum message size that server can send
 	// or receive.  Default value is 4MB.
 	DefaultMaxMsgSize = 1024 * 1024 * 4
+
+	// DefaultMaxChunkedMsgSize define maximum size of a reassembled chunked
+	// request.  Default value is 64MB.
+	DefaultMaxChunkedMsgSize = 1024 * 1024 * 64
+
+	// DefaultGracefulTimeout define how long the server waits for the
+	// requests in flight on stop.  Default value is 1 second.
+	DefaultGracefulTimeout = time.Second
 )
 
 const (
@@ -68,6 +77,8 @@ type grpcServer struct {
 
 	// registry service instance
 	rsvc *registry.Service
+	// per client quotas, nil without them
+	quota *quota
 }
 
 func init() {
@@ -149,6 +160,11 @@ func (g *grpcServer) configure(opts ...server.Option) {
 		gopts = append(gopts, grpc.Creds(creds))
 	}
 
+	g.quota = newQuota(g.opts)
+	if g.quota != nil {
+		gopts = append(gopts, grpc.ChainStreamInterceptor(g.quota.interceptor))
+	}
+
 	if opts := g.getGrpcOptions(); opts != nil {
 		gopts = append(gopts, opts...)
 	}
@@ -167,6 +183,17 @@ func (g *grpcServer) getMaxMsgSize() int {
 	return s
 }
 
+func (g *grpcServer) getGracefulTimeout() time.Duration {
+	if g.opts.Context == nil {
+		return DefaultGracefulTimeout
+	}
+	d, ok := g.opts.Context.Value(gracefulTimeoutKey{}).(time.Duration)
+	if !ok {
+		return DefaultGracefulTimeout
+	}
+	return d
+}
+
 func (g *grpcServer) getCredentials() credentials.TransportCredentials {
 	if g.opts.Context != nil {
 		if v, ok := g.opts.Context.Value(tlsAuth{}).(*tls.Config); ok && v != nil {
@@ -256,6 +283,8 @@ func (g *grpcServer) handler(srv interface{}, stream grpc.ServerStream) error {
 
 	delete(md, "x-content-type")
 	delete(md, "timeout")
+	delete(md, chunkedHeader)
+	delete(md, acceptChunkedHeader)
 
 	// create new context
 	ctx := meta.NewContext(stream.Context(), md)
@@ -362,8 +391,18 @@ func (g *grpcServer) processRequest(stream grpc.ServerStream, service *service,
 			argIsValue = true
 		}
 
+		cc, err := g.newGRPCCodec(ct)
+		if err != nil {
+			return errors.InternalServerError("go.micro.server", err.Error())
+		}
+
 		// Unmarshal request
-		if err := stream.RecvMsg(argv.Interface()); err != nil {
+		if chunked(stream, chunkedHeader) {
+			err = g.recvChunked(stream, cc, argv.Interface())
+		} else {
+			err = stream.RecvMsg(argv.Interface())
+		}
+		if err != nil {
 			return err
 		}
 
@@ -377,10 +416,6 @@ func (g *grpcServer) processRequest(stream grpc.ServerStream, service *service,
 		function := mtype.method.Func
 		var returnValues []reflect.Value
 
-		cc, err := g.newGRPCCodec(ct)
-		if err != nil {
-			return errors.InternalServerError("go.micro.server", err.Error())
-		}
 		b, err := cc.Marshal(argv.Interface())
 		if err != nil {
 			return err
@@ -450,7 +485,12 @@ func (g *grpcServer) processRequest(stream grpc.ServerStream, service *service,
 			return errStatus.Err()
 		}
 
-		if err := stream.SendMsg(replyv.Interface()); err != nil {
+		if size := g.getChunkSize(); size > 0 && chunked(stream, acceptChunkedHeader) {
+			err = g.sendChunked(stream, cc, replyv.Interface(), size)
+		} else {
+			err = stream.SendMsg(replyv.Interface())
+		}
+		if err != nil {
 			return err
 		}
 		return status.New(statusCode, statusDesc).Err()
@@ -671,7 +711,7 @@ func (g *grpcServer) Register() error {
 	// register service
 	node := &registry.Node{
 		Id:       config.Name + "-" + config.Id,
-		Address:  mnet.HostPort(addr, port),
+		Address:  hostPort(advt, addr, port),
 		Metadata: md,
 	}
 
@@ -808,7 +848,7 @@ func (g *grpcServer) Deregister() error {
 
 	node := &registry.Node{
 		Id:      config.Name + "-" + config.Id,
-		Address: mnet.HostPort(addr, port),
+		Address: hostPort(advt, addr, port),
 	}
 
 	service := &registry.Service{
@@ -868,15 +908,23 @@ func (g *grpcServer) Start() error {
 		err error
 	)
 
+	handler := g.getHTTPHandler()
+
 	if l := g.getListener(); l != nil {
 		ts = l
 	} else {
 		// check the tls config for secure connect
 		if tc := config.TLSConfig; tc != nil {
-			ts, err = tls.Listen("tcp", config.Address, tc)
+			if handler != nil {
+				tc = withHTTP2(tc)
+			}
+			ts, err = socket.Listen(config.Address)
+			if err == nil {
+				ts = tls.NewListener(ts, tc)
+			}
 			// otherwise just plain tcp listener
 		} else {
-			ts, err = net.Listen("tcp", config.Address)
+			ts, err = socket.Listen(config.Address)
 		}
 		if err != nil {
 			return err
@@ -889,9 +937,20 @@ func (g *grpcServer) Start() error {
 		}
 	}
 
-	log.Logf(logger.InfoLevel, "Server [grpc] Listening on %s", ts.Addr().String())
+	g.RLock()
+	q := g.quota
+	g.RUnlock()
+	if q != nil {
+		if err := q.start(); err != nil {
+			ts.Close()
+			return err
+		}
+		ts = q.listener(ts)
+	}
+
+	log.Logf(logger.InfoLevel, "Server [grpc] Listening on %s", socket.Addr(ts.Addr()))
 	g.Lock()
-	g.opts.Address = ts.Addr().String()
+	g.opts.Address = socket.Addr(ts.Addr())
 	g.Unlock()
 
 	// only connect if we're subscribed
@@ -915,8 +974,20 @@ func (g *grpcServer) Start() error {
 		}
 	}
 
+	// share the listener with the http handler
+	var hs *httpServer
+	if handler != nil {
+		hs = newHTTPServer(g.srv, handler)
+	}
+
 	// micro: go ts.Accept(s.accept)
 	go func() {
+		if hs != nil {
+			if err := hs.Serve(ts); err != nil && err != http.ErrServerClosed {
+				log.Logf(logger.ErrorLevel, "gRPC Server start error: %v", err)
+			}
+			return
+		}
 		if err := g.srv.Serve(ts); err != nil {
 			log.Logf(logger.ErrorLevel, "gRPC Server start error: %v", err)
 		}
@@ -979,14 +1050,26 @@ func (g *grpcServer) Start() error {
 		exit := make(chan bool)
 
 		go func() {
-			g.srv.GracefulStop()
+			if hs != nil {
+				// GracefulStop can't drain the requests served over http
+				hs.gracefulStop()
+			} else {
+				g.srv.GracefulStop()
+			}
 			close(exit)
 		}()
 
 		select {
 		case <-exit:
-		case <-time.After(time.Second):
-			g.srv.Stop()
+		case <-time.After(g.getGracefulTimeout()):
+			if hs != nil {
+				hs.Close()
+			}
+		}
+		g.srv.Stop()
+
+		if q != nil {
+			q.stop()
 		}
 
 		log.Logf(logger.InfoLevel, "Broker [%s] Disconnected from %s", config.Broker.String(), config.Broker.Address())
@@ -3,6 +3,8 @@ package grpc_test
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"google.golang.org/grpc"
@@ -253,3 +255,49 @@ func TestGRPCServerInjectedServer(t *testing.T) {
 	)
 	testGRPCServer(t, s, c, r, false)
 }
+
+func TestGRPCServerUnix(t *testing.T) {
+	dir, err := os.MkdirTemp("", "grpc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	addr := "unix://" + filepath.Join(dir, "foo.sock")
+
+	r, b, tr := getTestHarness()
+	s := gsrv.NewServer(
+		server.Address(addr),
+		server.Broker(b),
+		server.Name("foo"),
+		server.Registry(r),
+		server.Transport(tr),
+	)
+	c := gcli.NewClient(
+		client.Registry(r),
+		client.Broker(b),
+		client.Transport(tr),
+	)
+
+	pb.RegisterTestHandler(s, &testServer{})
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	defer s.Stop()
+
+	services, err := r.GetService("foo")
+	if err != nil || len(services) == 0 {
+		t.Fatalf("failed to get service: %v # %d", err, len(services))
+	}
+	if got := services[0].Nodes[0].Address; got != addr {
+		t.Fatalf("Expected node address %s, got %s", addr, got)
+	}
+
+	rsp := pb.Response{}
+	req := c.NewRequest("foo", "Test.Call", &pb.Request{Name: "John"})
+	if err := c.Call(context.Background(), req, &rsp); err != nil {
+		t.Fatalf("error calling server: %v", err)
+	}
+	if rsp.Msg != "Hello John" {
+		t.Fatalf("Got unexpected response %v", rsp.Msg)
+	}
+}
@@ -0,0 +1,78 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+)
+
+func (g *grpcServer) getHTTPHandler() http.Handler {
+	if g.opts.Context == nil {
+		return nil
+	}
+
+	if h, ok := g.opts.Context.Value(httpHandlerKey{}).(http.Handler); ok && h != nil {
+		return h
+	}
+
+	return nil
+}
+
+// httpServer serves the handler and the grpc server on a shared listener.
+type httpServer struct {
+	*http.Server
+
+	// the gRPC requests in flight, the http server doesn't track the
+	// connections hijacked by h2c
+	wg sync.WaitGroup
+}
+
+// newHTTPServer returns the server sharing the listener between the grpc
+// server and the handler. gRPC requests are served by the grpc server's
+// ServeHTTP, others by the handler. Without TLS HTTP/2 is spoken in clear
+// text (h2c), which gRPC clients do by default.
+func newHTTPServer(srv *grpc.Server, h http.Handler) *httpServer {
+	hs := &httpServer{}
+	mux := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			hs.wg.Add(1)
+			defer hs.wg.Done()
+			srv.ServeHTTP(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+
+	h2 := &http2.Server{}
+	hs.Server = &http.Server{Handler: h2c.NewHandler(mux, h2)}
+	// negotiate HTTP/2 on TLS listeners
+	http2.ConfigureServer(hs.Server, h2)
+
+	return hs
+}
+
+// gracefulStop closes the listener and waits for the requests in flight.
+// The shutdown of the http2 server sends GOAWAY on the HTTP/2 connections.
+func (hs *httpServer) gracefulStop() {
+	hs.Shutdown(context.Background())
+	hs.wg.Wait()
+}
+
+// withHTTP2 advertises HTTP/2 and HTTP/1.1 on a TLS listener shared with the
+// handler.
+func withHTTP2(tc *tls.Config) *tls.Config {
+	tc = tc.Clone()
+	for _, p := range tc.NextProtos {
+		if p == http2.NextProtoTLS {
+			return tc
+		}
+	}
+	tc.NextProtos = append([]string{http2.NextProtoTLS, "http/1.1"}, tc.NextProtos...)
+	return tc
+}
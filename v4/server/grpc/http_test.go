@@ -0,0 +1,154 @@
+package grpc_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/server"
+
+	gcli "github.com/go-micro/plugins/v4/client/grpc"
+	gsrv "github.com/go-micro/plugins/v4/server/grpc"
+	pb "github.com/go-micro/plugins/v4/server/grpc/proto"
+)
+
+func TestHTTPHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	})
+
+	r, b, tr := getTestHarness()
+	s := gsrv.NewServer(
+		server.Broker(b),
+		server.Name("foo"),
+		server.Registry(r),
+		server.Transport(tr),
+		server.Address("127.0.0.1:0"),
+		gsrv.HTTPHandler(mux),
+	)
+	pb.RegisterTestHandler(s, &testServer{})
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	defer s.Stop()
+
+	c := pb.NewTestService("foo", gcli.NewClient(
+		client.Registry(r),
+		client.Broker(b),
+		client.Transport(tr),
+	))
+
+	rsp, err := c.Call(context.TODO(), &pb.Request{Name: "John"})
+	if err != nil {
+		t.Fatalf("error calling server: %v", err)
+	}
+	if rsp.Msg != "Hello John" {
+		t.Fatalf("got unexpected response %v", rsp.Msg)
+	}
+
+	hrsp, err := http.Get("http://" + s.Options().Address + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hrsp.Body.Close()
+
+	body, _ := io.ReadAll(hrsp.Body)
+	if hrsp.StatusCode != http.StatusOK || string(body) != "ok" {
+		t.Fatalf("got unexpected http response %d %s", hrsp.StatusCode, body)
+	}
+}
+
+// slowServer answers Call after a delay.
+type slowServer struct {
+	testServer
+	delay   time.Duration
+	started chan struct{}
+}
+
+func (s *slowServer) Call(ctx context.Context, req *pb.Request, rsp *pb.Response) error {
+	close(s.started)
+	time.Sleep(s.delay)
+	rsp.Msg = "Hello " + req.Name
+	return nil
+}
+
+func stopDuringCall(t *testing.T, delay time.Duration, opts ...server.Option) (time.Duration, error) {
+	t.Helper()
+
+	r, b, tr := getTestHarness()
+	s := gsrv.NewServer(append([]server.Option{
+		server.Broker(b),
+		server.Name("foo"),
+		server.Registry(r),
+		server.Transport(tr),
+		server.Address("127.0.0.1:0"),
+		gsrv.HTTPHandler(http.NotFoundHandler()),
+	}, opts...)...)
+	h := &slowServer{delay: delay, started: make(chan struct{})}
+	pb.RegisterTestHandler(s, h)
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+
+	c := pb.NewTestService("foo", gcli.NewClient(
+		client.Registry(r),
+		client.Broker(b),
+		client.Transport(tr),
+	))
+
+	errc := make(chan error, 1)
+	go func() {
+		rsp, err := c.Call(context.TODO(), &pb.Request{Name: "John"}, client.WithRequestTimeout(5*time.Second))
+		if err == nil && rsp.Msg != "Hello John" {
+			err = fmt.Errorf("got unexpected response %v", rsp.Msg)
+		}
+		errc <- err
+	}()
+
+	select {
+	case <-h.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the call wasn't received")
+	}
+
+	start := time.Now()
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	stopped := time.Since(start)
+
+	select {
+	case err := <-errc:
+		return stopped, err
+	case <-time.After(5 * time.Second):
+		t.Fatal("the call didn't return")
+		return 0, nil
+	}
+}
+
+func TestHTTPHandlerGracefulStop(t *testing.T) {
+	stopped, err := stopDuringCall(t, 200*time.Millisecond)
+	if err != nil {
+		t.Fatalf("the call in flight failed: %v", err)
+	}
+	if stopped < 100*time.Millisecond {
+		t.Fatalf("stopped after %v without waiting for the call", stopped)
+	}
+}
+
+func TestHTTPHandlerGracefulTimeout(t *testing.T) {
+	stopped, err := stopDuringCall(t, time.Second, gsrv.GracefulTimeout(100*time.Millisecond))
+	if err == nil {
+		t.Fatal("expected the call to fail once the grace timeout passed")
+	}
+	if stopped > 500*time.Millisecond {
+		t.Fatalf("stopped after %v, past the grace timeout", stopped)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"crypto/tls"
 	"net"
+	"net/http"
+	"time"
 
 	"go-micro.dev/v4/broker"
 	"go-micro.dev/v4/codec"
@@ -22,6 +24,10 @@ type maxMsgSizeKey struct{}
 type maxConnKey struct{}
 type tlsAuth struct{}
 type grpcServerKey struct{}
+type chunkSizeKey struct{}
+type maxChunkedMsgSizeKey struct{}
+type httpHandlerKey struct{}
+type gracefulTimeoutKey struct{}
 
 // gRPC Codec to be used to encode/decode requests for a given content type.
 func Codec(contentType string, c encoding.Codec) server.Option {
@@ -72,6 +78,34 @@ func MaxMsgSize(s int) server.Option {
 	return setServerOption(maxMsgSizeKey{}, s)
 }
 
+// ChunkSize splits responses larger than n bytes into chunks of n bytes, for
+// clients accepting chunked responses. Chunked requests are reassembled
+// regardless.
+func ChunkSize(n int) server.Option {
+	return setServerOption(chunkSizeKey{}, n)
+}
+
+// MaxChunkedMsgSize set the maximum size in bytes of a reassembled chunked
+// request. Default maximum size is 64 MB.
+func MaxChunkedMsgSize(s int) server.Option {
+	return setServerOption(maxChunkedMsgSizeKey{}, s)
+}
+
+// HTTPHandler serves HTTP requests other than gRPC on the listener of the
+// server with the handler, e.g. metrics, health checks or a small REST API,
+// so they share the port. gRPC requests are then served by the ServeHTTP
+// of the grpc server, which doesn't apply AuthTLS, use server.TLSConfig for
+// TLS instead.
+func HTTPHandler(h http.Handler) server.Option {
+	return setServerOption(httpHandlerKey{}, h)
+}
+
+// GracefulTimeout set how long the server waits for the requests in flight
+// on stop before closing their connections. Default is 1 second.
+func GracefulTimeout(d time.Duration) server.Option {
+	return setServerOption(gracefulTimeoutKey{}, d)
+}
+
 func newOptions(opt ...server.Option) server.Options {
 	opts := server.Options{
 		Codecs:        make(map[string]codec.NewCodec),
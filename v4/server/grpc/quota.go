@@ -0,0 +1,385 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"go-micro.dev/v4/config/source"
+	"go-micro.dev/v4/logger"
+	"go-micro.dev/v4/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type maxConnPerClientKey struct{}
+type maxStreamsPerClientKey struct{}
+type clientIdentityKey struct{}
+type banListKey struct{}
+
+// Reasons connections and streams are rejected for.
+const (
+	RejectBanned      = "banned"
+	RejectConnections = "connections"
+	RejectStreams     = "streams"
+)
+
+// MaxConnPerClient limits the connections of a client IP. Connections over
+// the limit are closed on accept.
+func MaxConnPerClient(n int) server.Option {
+	return setServerOption(maxConnPerClientKey{}, n)
+}
+
+// MaxStreamsPerClient limits the concurrent calls and streams of a client,
+// by ClientIdentity. Calls over the limit fail with ResourceExhausted.
+func MaxStreamsPerClient(n int) server.Option {
+	return setServerOption(maxStreamsPerClientKey{}, n)
+}
+
+// ClientIdentity identifies the client of a call for MaxStreamsPerClient
+// and ban lists, e.g. by a tenant header or the subject of its certificate.
+// Clients are identified by IP by default.
+func ClientIdentity(fn func(ctx context.Context) string) server.Option {
+	return setServerOption(clientIdentityKey{}, fn)
+}
+
+// BanList rejects the clients listed by the source, hot-loaded as it changes.
+// The data is a JSON list of IPs, CIDRs and client identities, or an object
+// with the list as "banned":
+//
+//	{"banned": ["203.0.113.7", "198.51.100.0/24", "tenant-x"]}
+func BanList(s source.Source) server.Option {
+	return setServerOption(banListKey{}, s)
+}
+
+// QuotaStats are the clients of the server and the rejections of quotas.
+type QuotaStats struct {
+	// Connections counts the connections by client IP
+	Connections map[string]int `json:"connections"`
+	// Streams counts the calls and streams by client
+	Streams map[string]int `json:"streams"`
+	// Rejected counts the rejections by reason
+	Rejected map[string]int64 `json:"rejected"`
+	// Banned is the number of entries of the ban list
+	Banned int `json:"banned"`
+}
+
+// Quotas returns the stats of the quotas of a grpc server, false if s isn't
+// a grpc server or has no quotas.
+func Quotas(s server.Server) (QuotaStats, bool) {
+	g, ok := s.(*grpcServer)
+	if !ok {
+		return QuotaStats{}, false
+	}
+	g.RLock()
+	q := g.quota
+	g.RUnlock()
+	if q == nil {
+		return QuotaStats{}, false
+	}
+	return q.stats(), true
+}
+
+// banList matches IPs, networks and identities.
+type banList struct {
+	entries []string
+	ids     map[string]bool
+	nets    []*net.IPNet
+}
+
+func parseBanList(data []byte) (*banList, error) {
+	var entries []string
+	if err := json.Unmarshal(data, &entries); err != nil {
+		var obj struct {
+			Banned []string `json:"banned"`
+		}
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return nil, err
+		}
+		entries = obj.Banned
+	}
+
+	b := &banList{entries: entries, ids: make(map[string]bool)}
+	for _, e := range entries {
+		if _, n, err := net.ParseCIDR(e); err == nil {
+			b.nets = append(b.nets, n)
+			continue
+		}
+		if ip := net.ParseIP(e); ip != nil {
+			// normalise the notation of IPs
+			e = ip.String()
+		}
+		b.ids[e] = true
+	}
+	return b, nil
+}
+
+// banned reports whether the client, an IP or identity, is banned.
+func (b *banList) banned(client string) bool {
+	if b == nil || len(client) == 0 {
+		return false
+	}
+	if b.ids[client] {
+		return true
+	}
+	if ip := net.ParseIP(client); ip != nil {
+		if b.ids[ip.String()] {
+			return true
+		}
+		for _, n := range b.nets {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type quota struct {
+	maxConns   int
+	maxStreams int
+	identity   func(ctx context.Context) string
+	source     source.Source
+	log        logger.Logger
+
+	bans atomic.Value
+
+	sync.Mutex
+	conns    map[string]int
+	streams  map[string]int
+	rejected map[string]int64
+	watcher  source.Watcher
+}
+
+// newQuota returns the quotas of the options, nil if there are none.
+func newQuota(opts server.Options) *quota {
+	if opts.Context == nil {
+		return nil
+	}
+
+	q := &quota{
+		log:      opts.Logger,
+		conns:    make(map[string]int),
+		streams:  make(map[string]int),
+		rejected: make(map[string]int64),
+	}
+	q.maxConns, _ = opts.Context.Value(maxConnPerClientKey{}).(int)
+	q.maxStreams, _ = opts.Context.Value(maxStreamsPerClientKey{}).(int)
+	q.identity, _ = opts.Context.Value(clientIdentityKey{}).(func(context.Context) string)
+	q.source, _ = opts.Context.Value(banListKey{}).(source.Source)
+
+	if q.maxConns <= 0 && q.maxStreams <= 0 && q.source == nil {
+		return nil
+	}
+	if q.identity == nil {
+		q.identity = peerIP
+	}
+	if q.log == nil {
+		q.log = logger.DefaultLogger
+	}
+	q.bans.Store((*banList)(nil))
+	return q
+}
+
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	return hostOf(p.Addr)
+}
+
+func hostOf(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+func (q *quota) banList() *banList {
+	return q.bans.Load().(*banList)
+}
+
+func (q *quota) reject(reason string) {
+	q.Lock()
+	q.rejected[reason]++
+	q.Unlock()
+}
+
+// start loads the ban list and watches it for changes.
+func (q *quota) start() error {
+	if q.source == nil {
+		return nil
+	}
+
+	cs, err := q.source.Read()
+	if err != nil {
+		return err
+	}
+	if err := q.load(cs); err != nil {
+		return err
+	}
+
+	w, err := q.source.Watch()
+	if err != nil {
+		return err
+	}
+	q.Lock()
+	q.watcher = w
+	q.Unlock()
+
+	go func() {
+		for {
+			cs, err := w.Next()
+			if err != nil {
+				if err != source.ErrWatcherStopped {
+					q.log.Logf(logger.ErrorLevel, "gRPC Server ban list watch error: %v", err)
+				}
+				return
+			}
+			if err := q.load(cs); err != nil {
+				q.log.Logf(logger.ErrorLevel, "gRPC Server ban list error: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+func (q *quota) load(cs *source.ChangeSet) error {
+	b, err := parseBanList(cs.Data)
+	if err != nil {
+		return err
+	}
+	q.bans.Store(b)
+	q.log.Logf(logger.InfoLevel, "gRPC Server loaded ban list of %d entries", len(b.entries))
+	return nil
+}
+
+func (q *quota) stop() {
+	q.Lock()
+	w := q.watcher
+	q.watcher = nil
+	q.Unlock()
+	if w != nil {
+		w.Stop()
+	}
+}
+
+// listener applies the connection quotas to the listener.
+func (q *quota) listener(l net.Listener) net.Listener {
+	return &quotaListener{Listener: l, quota: q}
+}
+
+type quotaListener struct {
+	net.Listener
+	quota *quota
+}
+
+func (l *quotaListener) Accept() (net.Conn, error) {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		q := l.quota
+		ip := hostOf(c.RemoteAddr())
+		if q.banList().banned(ip) {
+			q.reject(RejectBanned)
+			c.Close()
+			continue
+		}
+
+		q.Lock()
+		if q.maxConns > 0 && q.conns[ip] >= q.maxConns {
+			q.rejected[RejectConnections]++
+			q.Unlock()
+			c.Close()
+			continue
+		}
+		q.conns[ip]++
+		q.Unlock()
+
+		return &quotaConn{Conn: c, quota: q, ip: ip}, nil
+	}
+}
+
+type quotaConn struct {
+	net.Conn
+	quota *quota
+	ip    string
+	once  sync.Once
+}
+
+func (c *quotaConn) Close() error {
+	c.once.Do(func() {
+		q := c.quota
+		q.Lock()
+		if q.conns[c.ip]--; q.conns[c.ip] <= 0 {
+			delete(q.conns, c.ip)
+		}
+		q.Unlock()
+	})
+	return c.Conn.Close()
+}
+
+// interceptor applies the stream quotas and ban list to calls and streams.
+func (q *quota) interceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx := ss.Context()
+	id := q.identity(ctx)
+
+	if bans := q.banList(); bans.banned(id) || bans.banned(peerIP(ctx)) {
+		q.reject(RejectBanned)
+		return status.Error(codes.PermissionDenied, "client banned")
+	}
+
+	if q.maxStreams > 0 {
+		q.Lock()
+		if q.streams[id] >= q.maxStreams {
+			q.rejected[RejectStreams]++
+			q.Unlock()
+			return status.Errorf(codes.ResourceExhausted, "too many concurrent calls, at most %d", q.maxStreams)
+		}
+		q.streams[id]++
+		q.Unlock()
+
+		defer func() {
+			q.Lock()
+			if q.streams[id]--; q.streams[id] <= 0 {
+				delete(q.streams, id)
+			}
+			q.Unlock()
+		}()
+	}
+
+	return handler(srv, ss)
+}
+
+func (q *quota) stats() QuotaStats {
+	q.Lock()
+	defer q.Unlock()
+
+	s := QuotaStats{
+		Connections: make(map[string]int, len(q.conns)),
+		Streams:     make(map[string]int, len(q.streams)),
+		Rejected:    make(map[string]int64, len(q.rejected)),
+	}
+	for k, v := range q.conns {
+		s.Connections[k] = v
+	}
+	for k, v := range q.streams {
+		s.Streams[k] = v
+	}
+	for k, v := range q.rejected {
+		s.Rejected[k] = v
+	}
+	if b := q.banList(); b != nil {
+		s.Banned = len(b.entries)
+	}
+	return s
+}
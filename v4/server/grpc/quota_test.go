@@ -0,0 +1,154 @@
+package grpc_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/config/source"
+	"go-micro.dev/v4/config/source/memory"
+	"go-micro.dev/v4/server"
+
+	gcli "github.com/go-micro/plugins/v4/client/grpc"
+	gsrv "github.com/go-micro/plugins/v4/server/grpc"
+	pb "github.com/go-micro/plugins/v4/server/grpc/proto"
+)
+
+// blockingServer blocks calls of Block until released.
+type blockingServer struct {
+	testServer
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingServer) Call(ctx context.Context, req *pb.Request, rsp *pb.Response) error {
+	if req.Name == "Block" {
+		s.started <- struct{}{}
+		<-s.release
+	}
+	return s.testServer.Call(ctx, req, rsp)
+}
+
+func startQuotaServer(t *testing.T, h pb.TestHandler, opts ...server.Option) (server.Server, pb.TestService) {
+	r, b, tr := getTestHarness()
+	opts = append([]server.Option{
+		server.Broker(b),
+		server.Name("foo"),
+		server.Registry(r),
+		server.Transport(tr),
+		server.Address("127.0.0.1:0"),
+	}, opts...)
+
+	s := gsrv.NewServer(opts...)
+	pb.RegisterTestHandler(s, h)
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+
+	c := pb.NewTestService("foo", gcli.NewClient(
+		client.Registry(r),
+		client.Broker(b),
+		client.Transport(tr),
+		client.Retries(0),
+	))
+	return s, c
+}
+
+func TestMaxStreamsPerClient(t *testing.T) {
+	h := &blockingServer{started: make(chan struct{}), release: make(chan struct{})}
+	s, c := startQuotaServer(t, h, gsrv.MaxStreamsPerClient(1))
+	defer s.Stop()
+
+	done := make(chan error)
+	go func() {
+		_, err := c.Call(context.TODO(), &pb.Request{Name: "Block"})
+		done <- err
+	}()
+	<-h.started
+
+	if _, err := c.Call(context.TODO(), &pb.Request{Name: "John"}); err == nil {
+		t.Fatal("expected the call over the quota to fail")
+	}
+
+	stats, ok := gsrv.Quotas(s)
+	if !ok {
+		t.Fatal("expected quotas of the server")
+	}
+	if stats.Rejected[gsrv.RejectStreams] != 1 || stats.Streams["127.0.0.1"] != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	close(h.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Call(context.TODO(), &pb.Request{Name: "John"}); err != nil {
+		t.Fatalf("expected the call within the quota to succeed, got %v", err)
+	}
+}
+
+func TestMaxConnPerClient(t *testing.T) {
+	s, _ := startQuotaServer(t, &testServer{}, gsrv.MaxConnPerClient(1))
+	defer s.Stop()
+
+	c1, err := net.Dial("tcp", s.Options().Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c1.Close()
+
+	c2, err := net.Dial("tcp", s.Options().Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+
+	// the second connection is closed on accept
+	c2.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := c2.Read(make([]byte, 1)); err == nil {
+		t.Fatal("expected the connection over the quota to be closed")
+	}
+
+	stats, _ := gsrv.Quotas(s)
+	if stats.Rejected[gsrv.RejectConnections] != 1 || stats.Connections["127.0.0.1"] != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestBanList(t *testing.T) {
+	src := memory.NewSource(memory.WithJSON([]byte(`{"banned": ["127.0.0.0/8"]}`)))
+	s, c := startQuotaServer(t, &testServer{}, gsrv.BanList(src))
+	defer s.Stop()
+
+	if _, err := c.Call(context.TODO(), &pb.Request{Name: "John"}); err == nil {
+		t.Fatal("expected the call of a banned client to fail")
+	}
+	if stats, _ := gsrv.Quotas(s); stats.Banned != 1 || stats.Rejected[gsrv.RejectBanned] == 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	// hot-load a ban list without the client
+	src.Write(&source.ChangeSet{Data: []byte(`["203.0.113.7"]`)})
+	time.Sleep(100 * time.Millisecond)
+
+	if _, err := c.Call(context.TODO(), &pb.Request{Name: "John"}); err != nil {
+		t.Fatalf("expected the call after unbanning to succeed, got %v", err)
+	}
+}
+
+func TestClientIdentity(t *testing.T) {
+	src := memory.NewSource(memory.WithJSON([]byte(`["tenant-x"]`)))
+	s, c := startQuotaServer(t, &testServer{},
+		gsrv.BanList(src),
+		gsrv.ClientIdentity(func(ctx context.Context) string {
+			return "tenant-x"
+		}),
+	)
+	defer s.Stop()
+
+	if _, err := c.Call(context.TODO(), &pb.Request{Name: "John"}); err == nil {
+		t.Fatal("expected the call of a banned identity to fail")
+	}
+}
@@ -6,9 +6,20 @@ import (
 	"os"
 	"sync"
 
+	"github.com/go-micro/plugins/v4/util/socket"
+	mnet "go-micro.dev/v4/util/net"
 	"google.golang.org/grpc/codes"
 )
 
+// hostPort returns the address of the node to register. Local socket
+// addresses are registered as they are, with their scheme.
+func hostPort(advt, host, port string) string {
+	if socket.Local(advt) {
+		return advt
+	}
+	return mnet.HostPort(host, port)
+}
+
 // convertCode converts a standard Go error into its canonical code. Note that
 // this is only used to translate the error returned by the server applications.
 func convertCode(err error) codes.Code {
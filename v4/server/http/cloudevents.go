@@ -0,0 +1,65 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"go-micro.dev/v4/broker"
+)
+
+const cloudEventsContentType = "application/cloudevents+json"
+
+// isCloudEvent reports whether the message is a structured mode CloudEvent.
+func isCloudEvent(msg *broker.Message) bool {
+	return strings.HasPrefix(msg.Header["Content-Type"], cloudEventsContentType)
+}
+
+// unpackCloudEvent converts a structured mode CloudEvent to binary mode, the
+// data becomes the body and the attributes are set as ce- headers, so the
+// data can be decoded with the codec of its content type.
+func unpackCloudEvent(msg *broker.Message) (*broker.Message, error) {
+	var event map[string]json.RawMessage
+	if err := json.Unmarshal(msg.Body, &event); err != nil {
+		return nil, fmt.Errorf("invalid cloudevent: %v", err)
+	}
+
+	hdr := make(map[string]string, len(msg.Header)+len(event))
+	for k, v := range msg.Header {
+		hdr[k] = v
+	}
+	hdr["Content-Type"] = "application/json"
+
+	var body []byte
+
+	for k, v := range event {
+		switch k {
+		case "data":
+			body = v
+		case "data_base64":
+			var b []byte
+			if err := json.Unmarshal(v, &b); err != nil {
+				return nil, fmt.Errorf("invalid cloudevent data: %v", err)
+			}
+			body = b
+		case "datacontenttype":
+			var ct string
+			if err := json.Unmarshal(v, &ct); err != nil {
+				return nil, fmt.Errorf("invalid cloudevent content type: %v", err)
+			}
+			hdr["Content-Type"] = ct
+		default:
+			var s string
+			if err := json.Unmarshal(v, &s); err != nil {
+				// extension attributes may be numbers or booleans
+				s = string(v)
+			}
+			hdr["ce-"+k] = s
+		}
+	}
+
+	return &broker.Message{
+		Header: hdr,
+		Body:   body,
+	}, nil
+}
@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/go-micro/plugins/v4/util/socket"
 	"go-micro.dev/v4/registry"
 	"go-micro.dev/v4/server"
 	"go-micro.dev/v4/util/addr"
@@ -40,6 +41,10 @@ func serviceDef(opts server.Options) *registry.Service {
 		Address:  net.JoinHostPort(addr, fmt.Sprint(port)),
 		Metadata: opts.Metadata,
 	}
+	// local sockets are registered with their scheme
+	if socket.Local(advt) {
+		node.Address = advt
+	}
 
 	node.Metadata["server"] = "http"
 	node.Metadata["broker"] = opts.Broker.String()
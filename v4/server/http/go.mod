@@ -2,7 +2,10 @@ module github.com/go-micro/plugins/v4/server/http
 
 go 1.17
 
-require go-micro.dev/v4 v4.9.0
+require (
+	github.com/go-micro/plugins/v4/util/socket v1.0.0
+	go-micro.dev/v4 v4.9.0
+)
 
 require (
 	github.com/Microsoft/go-winio v0.5.0 // indirect
@@ -43,3 +46,5 @@ require (
 	gopkg.in/warnings.v0 v0.1.2 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
+
+replace github.com/go-micro/plugins/v4/util/socket => ../../util/socket
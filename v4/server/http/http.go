@@ -11,6 +11,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/go-micro/plugins/v4/util/socket"
 	"go-micro.dev/v4/broker"
 	"go-micro.dev/v4/codec"
 	"go-micro.dev/v4/codec/jsonrpc"
@@ -269,19 +270,22 @@ func (h *httpServer) Start() error {
 	if l := h.getListener(); l != nil {
 		ln = l
 	} else if opts.TLSConfig != nil {
-		ln, err = tls.Listen("tcp", opts.Address, opts.TLSConfig)
+		ln, err = socket.Listen(opts.Address)
+		if err == nil {
+			ln = tls.NewListener(ln, opts.TLSConfig)
+		}
 	} else {
-		ln, err = net.Listen("tcp", opts.Address)
+		ln, err = socket.Listen(opts.Address)
 	}
 
 	if err != nil {
 		return err
 	}
 
-	log.Infof("Listening on %s", ln.Addr().String())
+	log.Infof("Listening on %s", socket.Addr(ln.Addr()))
 
 	h.Lock()
-	h.opts.Address = ln.Addr().String()
+	h.opts.Address = socket.Addr(ln.Addr())
 	h.Unlock()
 
 	handler, ok := hd.Handler().(http.Handler)
@@ -1,12 +1,16 @@
 package http
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"go-micro.dev/v4/broker"
 	"go-micro.dev/v4/registry"
 	"go-micro.dev/v4/server"
 )
@@ -142,3 +146,81 @@ func TestCustomerListen(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestUnpackCloudEvent(t *testing.T) {
+	msg := &broker.Message{
+		Header: map[string]string{"Content-Type": "application/cloudevents+json"},
+		Body:   []byte(`{"specversion":"1.0","id":"1","type":"order.created","source":"orders","datacontenttype":"application/json","data":{"id":"1"}}`),
+	}
+
+	if !isCloudEvent(msg) {
+		t.Fatal("Expected message to be a cloudevent")
+	}
+
+	m, err := unpackCloudEvent(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(m.Body) != `{"id":"1"}` {
+		t.Fatalf("Expected the data as body, got %s", m.Body)
+	}
+	if m.Header["Content-Type"] != "application/json" {
+		t.Fatalf("Expected data content type application/json, got %s", m.Header["Content-Type"])
+	}
+	if m.Header["ce-type"] != "order.created" || m.Header["ce-id"] != "1" {
+		t.Fatalf("Expected attributes as headers, got %v", m.Header)
+	}
+}
+
+func TestHTTPServerUnix(t *testing.T) {
+	dir, err := os.MkdirTemp("", "http")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "svc.sock")
+
+	reg := registry.NewMemoryRegistry()
+	srv := NewServer(server.Registry(reg), server.Address("unix://"+path))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`hello world`))
+	})
+	if err := srv.Handle(srv.NewHandler(mux)); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	service, err := reg.GetService(server.DefaultName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr := service[0].Nodes[0].Address; addr != "unix://"+path {
+		t.Fatalf("Expected node address %s, got %s", "unix://"+path, addr)
+	}
+
+	c := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}
+	rsp, err := c.Get("http://localhost/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+
+	b, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b); s != "hello world" {
+		t.Fatalf("Expected response %s, got %s", "hello world", s)
+	}
+}
@@ -188,6 +188,15 @@ func validateSubscriber(sub server.Subscriber) error {
 func (s *httpServer) createSubHandler(sb *httpSubscriber, opts server.Options) broker.Handler {
 	return func(p broker.Event) error {
 		msg := p.Message()
+		// structured cloudevents are decoded by the content type of their data
+		if isCloudEvent(msg) {
+			m, err := unpackCloudEvent(msg)
+			if err != nil {
+				return err
+			}
+			msg = m
+		}
+
 		ct := msg.Header["Content-Type"]
 		cf, err := s.newCodec(ct)
 		if err != nil {
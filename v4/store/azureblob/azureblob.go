@@ -0,0 +1,366 @@
+// Package azureblob implements the store on Azure Blob Storage.
+package azureblob
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/go-micro/plugins/v4/util/records"
+	"github.com/pkg/errors"
+	log "go-micro.dev/v4/logger"
+	"go-micro.dev/v4/store"
+	"go-micro.dev/v4/util/cmd"
+)
+
+// DefaultDatabase is the container used if no database is provided.
+var DefaultDatabase = "micro"
+
+// blob metadata key of the record metadata
+const metadataKey = "micrometadata"
+
+// container names are lowercase letters, numbers and hyphens
+var containerRe = regexp.MustCompile("[^a-z0-9-]+")
+
+type blobStore struct {
+	ctx     context.Context
+	options store.Options
+	client  *azblob.Client
+
+	sync.RWMutex
+	// containers known to exist
+	containers map[string]bool
+}
+
+func init() {
+	cmd.DefaultStores["azureblob"] = NewStore
+}
+
+// NewStore returns a new store backed by Azure Blob Storage. The node is
+// the service URL of the storage account, e.g.
+// https://account.blob.core.windows.net/. Databases are containers and
+// tables prefix the blob names as "<table>/<key>".
+func NewStore(opts ...store.Option) store.Store {
+	options := store.Options{
+		Database: DefaultDatabase,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	s := &blobStore{
+		ctx:        context.Background(),
+		options:    options,
+		containers: make(map[string]bool),
+	}
+
+	if err := s.configure(); err != nil {
+		log.Fatal(err)
+	}
+
+	return s
+}
+
+func (s *blobStore) configure() error {
+	client, err := newClient(s.options)
+	if err != nil {
+		return errors.Wrap(err, "creating blob client")
+	}
+
+	s.Lock()
+	s.client = client
+	s.containers = make(map[string]bool)
+	s.Unlock()
+
+	return nil
+}
+
+func newClient(o store.Options) (*azblob.Client, error) {
+	if o.Context != nil {
+		if cs, ok := o.Context.Value(connectionStringKey{}).(string); ok && len(cs) > 0 {
+			return azblob.NewClientFromConnectionString(cs, nil)
+		}
+	}
+
+	url := ""
+	if len(o.Nodes) > 0 {
+		url = o.Nodes[0]
+	}
+
+	if o.Context != nil {
+		if k, ok := o.Context.Value(sharedKeyKey{}).(sharedKey); ok {
+			if len(url) == 0 {
+				url = "https://" + k.account + ".blob.core.windows.net/"
+			}
+			cred, err := azblob.NewSharedKeyCredential(k.account, k.key)
+			if err != nil {
+				return nil, err
+			}
+			return azblob.NewClientWithSharedKeyCredential(url, cred, nil)
+		}
+	}
+
+	if len(url) == 0 {
+		return nil, errors.New("no service url")
+	}
+
+	cred, err := tokenCredential(o)
+	if err != nil {
+		return nil, err
+	}
+	return azblob.NewClient(url, cred, nil)
+}
+
+func tokenCredential(o store.Options) (azcore.TokenCredential, error) {
+	if o.Context != nil {
+		if c, ok := o.Context.Value(credentialKey{}).(azcore.TokenCredential); ok {
+			return c, nil
+		}
+		if id, ok := o.Context.Value(managedIdentityKey{}).(string); ok {
+			var opts azidentity.ManagedIdentityCredentialOptions
+			if len(id) > 0 {
+				opts.ID = azidentity.ClientID(id)
+			}
+			return azidentity.NewManagedIdentityCredential(&opts)
+		}
+	}
+	return azidentity.NewDefaultAzureCredential(nil)
+}
+
+func (s *blobStore) Init(opts ...store.Option) error {
+	for _, o := range opts {
+		o(&s.options)
+	}
+	return s.configure()
+}
+
+func (s *blobStore) Options() store.Options {
+	return s.options
+}
+
+func (s *blobStore) Close() error {
+	return nil
+}
+
+func (s *blobStore) String() string {
+	return "azureblob"
+}
+
+func (s *blobStore) names(database, table string) (string, string) {
+	return records.ObjectNames(s.options, database, table, containerRe)
+}
+
+// createContainer creates the container on the first write to it.
+func (s *blobStore) createContainer(container string) error {
+	s.RLock()
+	ok := s.containers[container]
+	s.RUnlock()
+	if ok {
+		return nil
+	}
+
+	_, err := s.client.CreateContainer(s.ctx, container, nil)
+	if err != nil && !bloberror.HasCode(err, bloberror.ContainerAlreadyExists) {
+		return err
+	}
+
+	s.Lock()
+	s.containers[container] = true
+	s.Unlock()
+	return nil
+}
+
+func (s *blobStore) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	var options store.ReadOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	container, prefix := s.names(options.Database, options.Table)
+
+	if !options.Prefix && !options.Suffix {
+		r, err := s.read(container, prefix, key)
+		if err != nil {
+			return nil, err
+		}
+		return []*store.Record{r}, nil
+	}
+
+	listPrefix := ""
+	if options.Prefix {
+		listPrefix = key
+	}
+	keys, err := s.list(container, prefix, listPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*store.Record
+	for _, k := range keys {
+		if options.Suffix && !strings.HasSuffix(k, key) {
+			continue
+		}
+		r, err := s.read(container, prefix, k)
+		if err == store.ErrNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+
+	start, end := records.Bounds(len(results), options.Offset, options.Limit)
+	return results[start:end], nil
+}
+
+func (s *blobStore) read(container, prefix, key string) (*store.Record, error) {
+	rsp, err := s.client.DownloadStream(s.ctx, container, prefix+key, nil)
+	if bloberror.HasCode(err, bloberror.BlobNotFound, bloberror.ContainerNotFound) {
+		return nil, store.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+
+	now := time.Now()
+	d, ok := records.Expiry(rsp.Metadata, now)
+	if ok && d <= 0 {
+		// lazily delete the expired record
+		go s.client.DeleteBlob(s.ctx, container, prefix+key, nil)
+		return nil, store.ErrNotFound
+	}
+
+	value, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &store.Record{
+		Key:      key,
+		Value:    value,
+		Metadata: make(map[string]interface{}),
+		Expiry:   d,
+	}
+	if v, ok := records.Metadata(rsp.Metadata, metadataKey); ok {
+		if b, err := base64.StdEncoding.DecodeString(v); err == nil {
+			json.Unmarshal(b, &r.Metadata)
+		}
+	}
+
+	return r, nil
+}
+
+func (s *blobStore) Write(r *store.Record, opts ...store.WriteOption) error {
+	var options store.WriteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	container, prefix := s.names(options.Database, options.Table)
+	if err := s.createContainer(container); err != nil {
+		return err
+	}
+
+	metadata := make(map[string]*string)
+	if len(r.Metadata) > 0 {
+		b, err := json.Marshal(r.Metadata)
+		if err != nil {
+			return err
+		}
+		// metadata headers are ascii
+		v := base64.StdEncoding.EncodeToString(b)
+		metadata[metadataKey] = &v
+	}
+
+	records.SetExpiry(metadata, records.TTL(r.Expiry, options), time.Now())
+
+	_, err := s.client.UploadBuffer(s.ctx, container, prefix+r.Key, r.Value, &azblob.UploadBufferOptions{
+		Metadata: metadata,
+	})
+	return err
+}
+
+func (s *blobStore) Delete(key string, opts ...store.DeleteOption) error {
+	var options store.DeleteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	container, prefix := s.names(options.Database, options.Table)
+
+	_, err := s.client.DeleteBlob(s.ctx, container, prefix+key, nil)
+	if bloberror.HasCode(err, bloberror.BlobNotFound, bloberror.ContainerNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (s *blobStore) List(opts ...store.ListOption) ([]string, error) {
+	var options store.ListOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	container, prefix := s.names(options.Database, options.Table)
+
+	keys, err := s.list(container, prefix, options.Prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(options.Suffix) > 0 {
+		filtered := keys[:0]
+		for _, k := range keys {
+			if strings.HasSuffix(k, options.Suffix) {
+				filtered = append(filtered, k)
+			}
+		}
+		keys = filtered
+	}
+
+	start, end := records.Bounds(len(keys), options.Offset, options.Limit)
+	return keys[start:end], nil
+}
+
+// list returns the keys of the table with the prefix, listing the blobs
+// with the prefix server side. Expired records are skipped.
+func (s *blobStore) list(container, table, prefix string) ([]string, error) {
+	p := table + prefix
+	pager := s.client.NewListBlobsFlatPager(container, &azblob.ListBlobsFlatOptions{
+		Prefix:  &p,
+		Include: azblob.ListBlobsInclude{Metadata: true},
+	})
+
+	now := time.Now()
+	var keys []string
+
+	for pager.More() {
+		page, err := pager.NextPage(s.ctx)
+		if bloberror.HasCode(err, bloberror.ContainerNotFound) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		for _, b := range page.Segment.BlobItems {
+			if b.Name == nil {
+				continue
+			}
+			if d, ok := records.Expiry(b.Metadata, now); ok && d <= 0 {
+				continue
+			}
+			keys = append(keys, strings.TrimPrefix(*b.Name, table))
+		}
+	}
+
+	return keys, nil
+}
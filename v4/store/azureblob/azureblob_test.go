@@ -0,0 +1,362 @@
+package azureblob
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-micro/plugins/v4/util/records"
+	"go-micro.dev/v4/store"
+)
+
+type blob struct {
+	data     []byte
+	metadata map[string]string
+}
+
+// blobServer is the part of the Blob Storage REST API used by the store. It
+// returns at most pageSize blobs per list request.
+type blobServer struct {
+	pageSize int
+
+	sync.Mutex
+	containers map[string]map[string]*blob
+	// requests by operation
+	requests map[string]int
+}
+
+func newBlobServer(t *testing.T, pageSize int) (*blobServer, *httptest.Server) {
+	b := &blobServer{
+		pageSize:   pageSize,
+		containers: make(map[string]map[string]*blob),
+		requests:   make(map[string]int),
+	}
+	ts := httptest.NewServer(b)
+	t.Cleanup(ts.Close)
+	return b, ts
+}
+
+func (b *blobServer) count(op string) int {
+	b.Lock()
+	defer b.Unlock()
+	return b.requests[op]
+}
+
+func (b *blobServer) get(container, name string) (*blob, bool) {
+	b.Lock()
+	defer b.Unlock()
+	bl, ok := b.containers[container][name]
+	return bl, ok
+}
+
+func blobError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("x-ms-error-code", code)
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?><Error><Code>%s</Code></Error>`, code)
+}
+
+func (b *blobServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.Lock()
+	defer b.Unlock()
+
+	// the path is /<account>/<container>[/<blob>]
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 3)
+	container := parts[1]
+	name := ""
+	if len(parts) == 3 {
+		name = parts[2]
+	}
+	q := r.URL.Query()
+	blobs, exists := b.containers[container]
+
+	switch {
+	case name == "" && r.Method == http.MethodPut:
+		b.requests["create"]++
+		if exists {
+			blobError(w, http.StatusConflict, "ContainerAlreadyExists")
+			return
+		}
+		b.containers[container] = make(map[string]*blob)
+		w.WriteHeader(http.StatusCreated)
+	case name == "" && q.Get("comp") == "list":
+		b.requests["list"]++
+		if !exists {
+			blobError(w, http.StatusNotFound, "ContainerNotFound")
+			return
+		}
+		b.list(w, container, blobs, q.Get("prefix"), q.Get("marker"))
+	case !exists:
+		blobError(w, http.StatusNotFound, "ContainerNotFound")
+	case r.Method == http.MethodPut:
+		b.requests["upload"]++
+		data, _ := io.ReadAll(r.Body)
+		bl := &blob{data: data, metadata: make(map[string]string)}
+		for k, v := range r.Header {
+			if strings.HasPrefix(strings.ToLower(k), "x-ms-meta-") {
+				bl.metadata[strings.ToLower(strings.TrimPrefix(strings.ToLower(k), "x-ms-meta-"))] = v[0]
+			}
+		}
+		blobs[name] = bl
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodGet:
+		b.requests["download"]++
+		bl, ok := blobs[name]
+		if !ok {
+			blobError(w, http.StatusNotFound, "BlobNotFound")
+			return
+		}
+		for k, v := range bl.metadata {
+			w.Header().Set("x-ms-meta-"+k, v)
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(bl.data)))
+		w.Write(bl.data)
+	case r.Method == http.MethodDelete:
+		b.requests["delete"]++
+		if _, ok := blobs[name]; !ok {
+			blobError(w, http.StatusNotFound, "BlobNotFound")
+			return
+		}
+		delete(blobs, name)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+type xmlMetadata map[string]string
+
+func (m xmlMetadata) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for k, v := range m {
+		if err := e.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: k}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+func (b *blobServer) list(w http.ResponseWriter, container string, blobs map[string]*blob, prefix, marker string) {
+	var names []string
+	for name := range blobs {
+		if strings.HasPrefix(name, prefix) && name > marker {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	type item struct {
+		Name     string      `xml:"Name"`
+		Metadata xmlMetadata `xml:"Metadata"`
+	}
+	rsp := struct {
+		XMLName         xml.Name `xml:"EnumerationResults"`
+		ServiceEndpoint string   `xml:"ServiceEndpoint,attr"`
+		ContainerName   string   `xml:"ContainerName,attr"`
+		Prefix          string   `xml:"Prefix"`
+		Marker          string   `xml:"Marker"`
+		Blobs           []item   `xml:"Blobs>Blob"`
+		NextMarker      string   `xml:"NextMarker"`
+	}{
+		ServiceEndpoint: "http://localhost/",
+		ContainerName:   container,
+		Prefix:          prefix,
+		Marker:          marker,
+	}
+
+	if len(names) > b.pageSize {
+		names = names[:b.pageSize]
+		// continue after the last blob of the page
+		rsp.NextMarker = names[len(names)-1]
+	}
+	for _, name := range names {
+		rsp.Blobs = append(rsp.Blobs, item{Name: name, Metadata: blobs[name].metadata})
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(rsp)
+}
+
+func newTestStore(t *testing.T, pageSize int, opts ...store.Option) (*blobServer, *blobStore) {
+	b, ts := newBlobServer(t, pageSize)
+	cs := fmt.Sprintf("DefaultEndpointsProtocol=http;AccountName=test;AccountKey=a2V5;BlobEndpoint=%s/test;", ts.URL)
+	s := NewStore(append([]store.Option{ConnectionString(cs)}, opts...)...).(*blobStore)
+	return b, s
+}
+
+func TestContainerCreation(t *testing.T) {
+	b, s := newTestStore(t, 10, store.Database("Orders_DB"))
+
+	// reads of a container which doesn't exist yet find nothing
+	if _, err := s.Read("1"); err != store.ErrNotFound {
+		t.Fatalf("Expected store.ErrNotFound, got %v", err)
+	}
+	if keys, err := s.List(); err != nil || len(keys) != 0 {
+		t.Fatalf("Expected no keys, got %v %v", keys, err)
+	}
+	if err := s.Delete("1"); err != nil {
+		t.Fatalf("Expected deleting from a missing container to succeed, got %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := s.Write(&store.Record{Key: strconv.Itoa(i), Value: []byte("order")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := b.count("create"); n != 1 {
+		t.Errorf("Expected the container to be created once, got %d requests", n)
+	}
+	if _, ok := b.get("orders-db", "1"); !ok {
+		t.Error("Expected the blob in the orders-db container")
+	}
+
+	// containers created by others are used as is
+	s = NewStore(store.WithContext(s.options.Context), store.Database("Orders_DB")).(*blobStore)
+	if err := s.Write(&store.Record{Key: "3", Value: []byte("order")}); err != nil {
+		t.Fatalf("Expected writing to an existing container to succeed, got %v", err)
+	}
+}
+
+func TestContinuation(t *testing.T) {
+	b, s := newTestStore(t, 2, store.Table("users"))
+
+	for i := 0; i < 7; i++ {
+		if err := s.Write(&store.Record{Key: fmt.Sprintf("user/%d", i), Value: []byte("user")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Write(&store.Record{Key: "group/1", Value: []byte("group")}); err != nil {
+		t.Fatal(err)
+	}
+	// other tables share the container
+	if err := s.Write(&store.Record{Key: "user/0", Value: []byte("order")}, store.WriteTo("", "orders")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.get("micro", "users/user/0"); !ok {
+		t.Fatal("Expected the table to prefix the blob name")
+	}
+
+	keys, err := s.List(store.ListPrefix("user/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 7 {
+		t.Fatalf("Expected the 7 keys of every page, got %v", keys)
+	}
+	for _, k := range keys {
+		if !strings.HasPrefix(k, "user/") {
+			t.Errorf("Expected the table prefix to be trimmed, got %s", k)
+		}
+	}
+	if n := b.count("list"); n != 4 {
+		t.Errorf("Expected 4 pages of 2 blobs, got %d list requests", n)
+	}
+
+	// offsets and limits are applied after the pages are merged
+	keys, err = s.List(store.ListPrefix("user/"), store.ListOffset(3), store.ListLimit(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(keys, ",") != "user/3,user/4,user/5" {
+		t.Errorf("Unexpected page of keys %v", keys)
+	}
+
+	recs, err := s.Read("/1", store.ReadSuffix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 2 {
+		t.Errorf("Expected user/1 and group/1, got %d records", len(recs))
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	b, s := newTestStore(t, 10)
+
+	err := s.Write(&store.Record{
+		Key:      "user/1",
+		Value:    []byte("alice"),
+		Metadata: map[string]interface{}{"role": "admin", "name": "Älice"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// metadata headers only take ascii
+	bl, _ := b.get("micro", "user/1")
+	for _, c := range bl.metadata[metadataKey] {
+		if c > 127 {
+			t.Fatalf("Expected ascii metadata, got %q", bl.metadata[metadataKey])
+		}
+	}
+
+	recs, err := s.Read("user/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := recs[0]
+	if string(r.Value) != "alice" || r.Metadata["role"] != "admin" || r.Metadata["name"] != "Älice" {
+		t.Errorf("Unexpected record %s %v", r.Value, r.Metadata)
+	}
+	if r.Expiry != 0 {
+		t.Errorf("Expected no expiry, got %v", r.Expiry)
+	}
+}
+
+func TestExpiredBlob(t *testing.T) {
+	b, s := newTestStore(t, 10)
+
+	if err := s.Write(&store.Record{Key: "tmp", Value: []byte("tmp")}, store.WriteTTL(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(&store.Record{Key: "keep", Value: []byte("keep")}); err != nil {
+		t.Fatal(err)
+	}
+
+	recs, err := s.Read("tmp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recs[0].Expiry <= 0 || recs[0].Expiry > time.Hour {
+		t.Errorf("Expected the record to expire within an hour, got %v", recs[0].Expiry)
+	}
+
+	// expire the blob
+	bl, _ := b.get("micro", "tmp")
+	b.Lock()
+	bl.metadata[records.ExpiryMetadataKey] = strconv.FormatInt(time.Now().Add(-time.Second).Unix(), 10)
+	b.Unlock()
+
+	keys, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != "keep" {
+		t.Errorf("Expected the expired blob to be skipped, got %v", keys)
+	}
+
+	if _, err := s.Read("tmp"); err != store.ErrNotFound {
+		t.Fatalf("Expected store.ErrNotFound, got %v", err)
+	}
+
+	// and deleted once read
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok := b.get("micro", "tmp"); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the expired blob to be deleted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,51 @@
+package azureblob
+
+import (
+	"context"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"go-micro.dev/v4/store"
+)
+
+type connectionStringKey struct{}
+type sharedKeyKey struct{}
+type managedIdentityKey struct{}
+type credentialKey struct{}
+
+type sharedKey struct {
+	account, key string
+}
+
+// ConnectionString authenticates with the connection string of the storage
+// account instead of the service URL passed as node.
+func ConnectionString(s string) store.Option {
+	return setStoreOption(connectionStringKey{}, s)
+}
+
+// SharedKey authenticates with the access key of the storage account.
+func SharedKey(account, key string) store.Option {
+	return setStoreOption(sharedKeyKey{}, sharedKey{account: account, key: key})
+}
+
+// ManagedIdentity authenticates with the managed identity of the host. The
+// client ID selects a user-assigned identity, leave it empty for the
+// system-assigned one.
+func ManagedIdentity(clientID string) store.Option {
+	return setStoreOption(managedIdentityKey{}, clientID)
+}
+
+// Credential authenticates with the token credential. Without any of the
+// auth options the default Azure credential chain is used, which includes
+// managed identities.
+func Credential(c azcore.TokenCredential) store.Option {
+	return setStoreOption(credentialKey{}, c)
+}
+
+func setStoreOption(k, v interface{}) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
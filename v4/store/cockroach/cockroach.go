@@ -35,6 +35,17 @@ var (
 		"write":      "INSERT INTO %s.%s(key, value, metadata, expiry) VALUES ($1, $2::bytea, $3, $4) ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, metadata = EXCLUDED.metadata, expiry = EXCLUDED.expiry;",
 		"delete":     "DELETE FROM %s.%s WHERE key = $1;",
 	}
+
+	// tenantStatements are used when tenancy is enabled, every query is
+	// scoped by the tenant column passed as the first argument.
+	tenantStatements = map[string]string{
+		"list":       "SELECT key, value, metadata, expiry FROM %[1]s.%[2]s WHERE %[3]s = $1;",
+		"read":       "SELECT key, value, metadata, expiry FROM %[1]s.%[2]s WHERE %[3]s = $1 AND key = $2;",
+		"readMany":   "SELECT key, value, metadata, expiry FROM %[1]s.%[2]s WHERE %[3]s = $1 AND key LIKE $2;",
+		"readOffset": "SELECT key, value, metadata, expiry FROM %[1]s.%[2]s WHERE %[3]s = $1 AND key LIKE $2 ORDER BY key DESC LIMIT $3 OFFSET $4;",
+		"write":      "INSERT INTO %[1]s.%[2]s(%[3]s, key, value, metadata, expiry) VALUES ($1, $2, $3::bytea, $4, $5) ON CONFLICT (%[3]s, key) DO UPDATE SET value = EXCLUDED.value, metadata = EXCLUDED.metadata, expiry = EXCLUDED.expiry;",
+		"delete":     "DELETE FROM %[1]s.%[2]s WHERE %[3]s = $1 AND key = $2;",
+	}
 )
 
 type sqlStore struct {
@@ -44,6 +55,9 @@ type sqlStore struct {
 	sync.RWMutex
 	// known databases
 	databases map[string]bool
+
+	// stop the expiry sweeper
+	sweepExit, sweepDone chan bool
 }
 
 func init() {
@@ -109,7 +123,18 @@ func (s *sqlStore) initDB(database, table string) error {
 	}
 
 	// Create a table for the namespace's prefix
-	_, err = s.db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+	if column := getTenantColumn(s.options); len(column) > 0 {
+		_, err = s.db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+	(
+		%s text NOT NULL,
+		key text NOT NULL,
+		value bytea,
+		metadata JSONB,
+		expiry timestamp with time zone,
+		CONSTRAINT %s_pkey PRIMARY KEY (%s, key)
+	);`, table, column, table, column))
+	} else {
+		_, err = s.db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
 	(
 		key text NOT NULL,
 		value bytea,
@@ -117,9 +142,15 @@ func (s *sqlStore) initDB(database, table string) error {
 		expiry timestamp with time zone,
 		CONSTRAINT %s_pkey PRIMARY KEY (key)
 	);`, table, table))
+	}
 	if err != nil {
 		return errors.Wrap(err, "Couldn't create table")
 	}
+	if column := getTenantColumn(s.options); len(column) > 0 {
+		if err := s.migrateTenant(table, column); err != nil {
+			return err
+		}
+	}
 
 	// Create Index
 	_, err = s.db.Exec(fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s" ON %s.%s USING btree ("key");`, "key_index_"+table, database, table))
@@ -136,6 +167,28 @@ func (s *sqlStore) initDB(database, table string) error {
 	return nil
 }
 
+// migrateTenant adds the tenant column to tables created without tenancy,
+// as part of the primary key. Existing records get the empty tenant, which
+// no scope uses, until assigned to a tenant with an UPDATE. The primary key
+// is dropped and added in one statement, so the old one isn't kept as a
+// unique index on the key.
+func (s *sqlStore) migrateTenant(table, column string) error {
+	var n int
+	row := s.db.QueryRow("SELECT count(*) FROM information_schema.columns WHERE table_name = $1 AND column_name = $2;", table, column)
+	if err := row.Scan(&n); err != nil {
+		return errors.Wrap(err, "Couldn't check tenant column")
+	}
+	if n > 0 {
+		return nil
+	}
+
+	if _, err := s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s text NOT NULL DEFAULT '';", table, column)); err != nil {
+		return errors.Wrap(err, "Couldn't add tenant column")
+	}
+	_, err := s.db.Exec(fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s_pkey, ADD CONSTRAINT %s_pkey PRIMARY KEY (%s, key);", table, table, table, column))
+	return errors.Wrap(err, "Couldn't add tenant column to the primary key")
+}
+
 func (s *sqlStore) configure() error {
 	if len(s.options.Nodes) == 0 {
 		s.options.Nodes = []string{"postgresql://root@localhost:26257?sslmode=disable"}
@@ -161,6 +214,7 @@ func (s *sqlStore) configure() error {
 		return err
 	}
 
+	s.stopSweeper()
 	if s.db != nil {
 		s.db.Close()
 	}
@@ -172,11 +226,24 @@ func (s *sqlStore) configure() error {
 	database, table := s.getDB(s.options.Database, s.options.Table)
 
 	// initialize the database
-	return s.initDB(database, table)
+	if err := s.initDB(database, table); err != nil {
+		return err
+	}
+
+	s.startSweeper()
+	return nil
 }
 
-func (s *sqlStore) prepare(database, table, query string) (*sql.Stmt, error) {
-	st, ok := statements[query]
+func (s *sqlStore) prepare(sc scope, database, table, query string) (*sql.Stmt, error) {
+	column := getTenantColumn(s.options)
+
+	var st string
+	var ok bool
+	if len(column) > 0 {
+		st, ok = tenantStatements[query]
+	} else {
+		st, ok = statements[query]
+	}
 	if !ok {
 		return nil, errors.New("unsupported statement")
 	}
@@ -184,15 +251,50 @@ func (s *sqlStore) prepare(database, table, query string) (*sql.Stmt, error) {
 	// get DB
 	database, table = s.getDB(database, table)
 
-	q := fmt.Sprintf(st, database, table)
-	stmt, err := s.db.Prepare(q)
+	var q string
+	if len(column) > 0 {
+		q = fmt.Sprintf(st, database, table, column)
+	} else {
+		q = fmt.Sprintf(st, database, table)
+	}
+	q = s.followerRead(sc, database, table, query, q)
+
+	var stmt *sql.Stmt
+	var err error
+	if sc.tx != nil {
+		stmt, err = sc.tx.Prepare(q)
+	} else {
+		stmt, err = s.db.Prepare(q)
+	}
 	if err != nil {
 		return nil, err
 	}
 	return stmt, nil
 }
 
+// expire deletes the expired record in the background. Within a transaction
+// it's left to the sweeper or a later read, so the transaction doesn't
+// conflict with the delete.
+func (s *sqlStore) expire(sc scope, key string) {
+	if sc.tx != nil {
+		return
+	}
+	go s.deleteScope(sc, key)
+}
+
+// args prepends the tenant to the query arguments if tenancy is enabled.
+func (s *sqlStore) args(tenant string, args ...interface{}) ([]interface{}, error) {
+	if len(getTenantColumn(s.options)) == 0 {
+		return args, nil
+	}
+	if len(tenant) == 0 {
+		return nil, ErrNoTenant
+	}
+	return append([]interface{}{tenant}, args...), nil
+}
+
 func (s *sqlStore) Close() error {
+	s.stopSweeper()
 	if s.db != nil {
 		return s.db.Close()
 	}
@@ -209,6 +311,10 @@ func (s *sqlStore) Init(opts ...store.Option) error {
 
 // List all the known records.
 func (s *sqlStore) List(opts ...store.ListOption) ([]string, error) {
+	return s.listScope(scope{}, opts...)
+}
+
+func (s *sqlStore) listScope(sc scope, opts ...store.ListOption) ([]string, error) {
 	var options store.ListOptions
 	for _, o := range opts {
 		o(&options)
@@ -219,13 +325,18 @@ func (s *sqlStore) List(opts ...store.ListOption) ([]string, error) {
 		return nil, err
 	}
 
-	st, err := s.prepare(options.Database, options.Table, "list")
+	args, err := s.args(sc.tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	st, err := s.prepare(sc, options.Database, options.Table, "list")
 	if err != nil {
 		return nil, err
 	}
 	defer st.Close()
 
-	rows, err := st.Query()
+	rows, err := st.Query(args...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -251,7 +362,7 @@ func (s *sqlStore) List(opts ...store.ListOption) ([]string, error) {
 		if timehelper.Valid {
 			if timehelper.Time.Before(time.Now()) {
 				// record has expired
-				go s.Delete(record.Key)
+				s.expire(sc, record.Key)
 			} else {
 				record.Expiry = time.Until(timehelper.Time)
 				keys = append(keys, record.Key)
@@ -273,6 +384,10 @@ func (s *sqlStore) List(opts ...store.ListOption) ([]string, error) {
 
 // Read a single key.
 func (s *sqlStore) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	return s.readScope(scope{}, key, opts...)
+}
+
+func (s *sqlStore) readScope(sc scope, key string, opts ...store.ReadOption) ([]*store.Record, error) {
 	var options store.ReadOptions
 	for _, o := range opts {
 		o(&options)
@@ -284,19 +399,24 @@ func (s *sqlStore) Read(key string, opts ...store.ReadOption) ([]*store.Record,
 	}
 
 	if options.Prefix || options.Suffix {
-		return s.read(key, options)
+		return s.read(sc, key, options)
 	}
 
 	var records []*store.Record
 	var timehelper pq.NullTime
 
-	st, err := s.prepare(options.Database, options.Table, "read")
+	args, err := s.args(sc.tenant, key)
+	if err != nil {
+		return nil, err
+	}
+
+	st, err := s.prepare(sc, options.Database, options.Table, "read")
 	if err != nil {
 		return nil, err
 	}
 	defer st.Close()
 
-	row := st.QueryRow(key)
+	row := st.QueryRow(args...)
 	record := &store.Record{}
 	metadata := make(Metadata)
 
@@ -313,7 +433,7 @@ func (s *sqlStore) Read(key string, opts ...store.ReadOption) ([]*store.Record,
 	if timehelper.Valid {
 		if timehelper.Time.Before(time.Now()) {
 			// record has expired
-			go s.Delete(key)
+			s.expire(sc, key)
 			return records, store.ErrNotFound
 		}
 		record.Expiry = time.Until(timehelper.Time)
@@ -326,7 +446,7 @@ func (s *sqlStore) Read(key string, opts ...store.ReadOption) ([]*store.Record,
 }
 
 // Read Many records.
-func (s *sqlStore) read(key string, options store.ReadOptions) ([]*store.Record, error) {
+func (s *sqlStore) read(sc scope, key string, options store.ReadOptions) ([]*store.Record, error) {
 	pattern := "%"
 	if options.Prefix {
 		pattern = key + pattern
@@ -335,26 +455,24 @@ func (s *sqlStore) read(key string, options store.ReadOptions) ([]*store.Record,
 		pattern = pattern + key
 	}
 
-	var rows *sql.Rows
-	var err error
-
+	query, args := "readMany", []interface{}{pattern}
 	if options.Limit != 0 {
-		st, err := s.prepare(options.Database, options.Table, "readOffset")
-		if err != nil {
-			return nil, err
-		}
-		defer st.Close()
+		query = "readOffset"
+		args = append(args, options.Limit, options.Offset)
+	}
 
-		rows, err = st.Query(pattern, options.Limit, options.Offset)
-	} else {
-		st, err := s.prepare(options.Database, options.Table, "readMany")
-		if err != nil {
-			return nil, err
-		}
-		defer st.Close()
+	args, err := s.args(sc.tenant, args...)
+	if err != nil {
+		return nil, err
+	}
 
-		rows, err = st.Query(pattern)
+	st, err := s.prepare(sc, options.Database, options.Table, query)
+	if err != nil {
+		return nil, err
 	}
+	defer st.Close()
+
+	rows, err := st.Query(args...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return []*store.Record{}, nil
@@ -381,7 +499,7 @@ func (s *sqlStore) read(key string, options store.ReadOptions) ([]*store.Record,
 		if timehelper.Valid {
 			if timehelper.Time.Before(time.Now()) {
 				// record has expired
-				go s.Delete(record.Key)
+				s.expire(sc, record.Key)
 			} else {
 				record.Expiry = time.Until(timehelper.Time)
 				records = append(records, record)
@@ -404,6 +522,10 @@ func (s *sqlStore) read(key string, options store.ReadOptions) ([]*store.Record,
 
 // Write records.
 func (s *sqlStore) Write(r *store.Record, opts ...store.WriteOption) error {
+	return s.writeScope(scope{}, r, opts...)
+}
+
+func (s *sqlStore) writeScope(sc scope, r *store.Record, opts ...store.WriteOption) error {
 	var options store.WriteOptions
 	for _, o := range opts {
 		o(&options)
@@ -414,7 +536,7 @@ func (s *sqlStore) Write(r *store.Record, opts ...store.WriteOption) error {
 		return err
 	}
 
-	st, err := s.prepare(options.Database, options.Table, "write")
+	st, err := s.prepare(sc, options.Database, options.Table, "write")
 	if err != nil {
 		return err
 	}
@@ -425,21 +547,30 @@ func (s *sqlStore) Write(r *store.Record, opts ...store.WriteOption) error {
 		metadata[k] = v
 	}
 
+	var expiry interface{}
 	if r.Expiry != 0 {
-		_, err = st.Exec(r.Key, r.Value, metadata, time.Now().Add(r.Expiry))
-	} else {
-		_, err = st.Exec(r.Key, r.Value, metadata, nil)
+		expiry = s.expiry(r.Expiry)
 	}
 
+	args, err := s.args(sc.tenant, r.Key, r.Value, metadata, expiry)
 	if err != nil {
+		return err
+	}
+
+	if _, err := st.Exec(args...); err != nil {
 		return errors.Wrap(err, "Couldn't insert record "+r.Key)
 	}
 
+	s.written(sc, options.Database)
 	return nil
 }
 
 // Delete records with keys.
 func (s *sqlStore) Delete(key string, opts ...store.DeleteOption) error {
+	return s.deleteScope(scope{}, key, opts...)
+}
+
+func (s *sqlStore) deleteScope(sc scope, key string, opts ...store.DeleteOption) error {
 	var options store.DeleteOptions
 	for _, o := range opts {
 		o(&options)
@@ -450,13 +581,18 @@ func (s *sqlStore) Delete(key string, opts ...store.DeleteOption) error {
 		return err
 	}
 
-	st, err := s.prepare(options.Database, options.Table, "delete")
+	args, err := s.args(sc.tenant, key)
+	if err != nil {
+		return err
+	}
+
+	st, err := s.prepare(sc, options.Database, options.Table, "delete")
 	if err != nil {
 		return err
 	}
 	defer st.Close()
 
-	result, err := st.Exec(key)
+	result, err := st.Exec(args...)
 	if err != nil {
 		return err
 	}
@@ -466,6 +602,7 @@ func (s *sqlStore) Delete(key string, opts ...store.DeleteOption) error {
 		return err
 	}
 
+	s.written(sc, options.Database)
 	return nil
 }
 
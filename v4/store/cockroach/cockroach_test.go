@@ -1,6 +1,7 @@
 package cockroach
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
@@ -8,6 +9,9 @@ import (
 	"time"
 
 	"github.com/kr/pretty"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"go-micro.dev/v4/metadata"
 	"go-micro.dev/v4/store"
 )
 
@@ -128,3 +132,456 @@ func TestSQL(t *testing.T) {
 		t.Fatal("Results should have returned 0 records")
 	}
 }
+
+func TestTenantScope(t *testing.T) {
+	s := &sqlStore{options: store.Options{}}
+	if _, err := Scope(context.Background(), s); err != ErrNoTenancy {
+		t.Fatalf("Expected %v, got %v", ErrNoTenancy, err)
+	}
+
+	TenantColumn("tenant-id")(&s.options)
+	if _, err := Scope(context.Background(), s); err != ErrNoTenant {
+		t.Fatalf("Expected %v, got %v", ErrNoTenant, err)
+	}
+	if _, err := s.args(""); err != ErrNoTenant {
+		t.Fatalf("Expected unscoped queries to fail with %v, got %v", ErrNoTenant, err)
+	}
+
+	ctx := metadata.NewContext(context.Background(), map[string]string{DefaultTenantKey: "acme"})
+	ts, err := Scope(ctx, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tenant := ts.(*scopedStore).tenant; tenant != "acme" {
+		t.Fatalf("Expected tenant acme, got %s", tenant)
+	}
+
+	args, err := s.args("acme", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(args) != 2 || args[0] != "acme" {
+		t.Fatalf("Expected tenant to be the first argument, got %v", args)
+	}
+
+	if column := getTenantColumn(s.options); column != "tenant_id" {
+		t.Fatalf("Expected sanitized column tenant_id, got %s", column)
+	}
+}
+
+func TestTenantSQL(t *testing.T) {
+	if len(os.Getenv("IN_TRAVIS_CI")) != 0 {
+		t.Skip()
+	}
+
+	connection := fmt.Sprintf(
+		"host=%s port=%d user=%s sslmode=disable dbname=%s",
+		"localhost",
+		26257,
+		"root",
+		"test",
+	)
+	db, err := sql.Open("postgres", connection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Ping(); err != nil {
+		t.Skip("store/cockroach: can't connect to db")
+	}
+	db.Close()
+
+	sqlStore := NewStore(
+		store.Database("testsql"),
+		store.Table("tenants"),
+		store.Nodes(connection),
+		TenantColumn("tenant"),
+	)
+
+	if _, err := sqlStore.List(); err != ErrNoTenant {
+		t.Fatalf("Expected %v, got %v", ErrNoTenant, err)
+	}
+
+	acme, err := Scope(metadata.NewContext(context.Background(), map[string]string{DefaultTenantKey: "acme"}), sqlStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := Scope(metadata.NewContext(context.Background(), map[string]string{DefaultTenantKey: "other"}), sqlStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := acme.Write(&store.Record{Key: "foo", Value: []byte("acme")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Write(&store.Record{Key: "foo", Value: []byte("other")}); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := acme.Read("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(records[0].Value) != "acme" {
+		t.Fatalf("Expected acme, got %s", records[0].Value)
+	}
+
+	if err := other.Delete("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Read("foo"); err != store.ErrNotFound {
+		t.Fatalf("Expected %v, got %v", store.ErrNotFound, err)
+	}
+	if _, err := acme.Read("foo"); err != nil {
+		t.Fatalf("Expected record of other tenant to be kept, got %v", err)
+	}
+
+	acme.Delete("foo")
+}
+
+func TestTenantMigration(t *testing.T) {
+	if len(os.Getenv("IN_TRAVIS_CI")) != 0 {
+		t.Skip()
+	}
+
+	connection := fmt.Sprintf(
+		"host=%s port=%d user=%s sslmode=disable dbname=%s",
+		"localhost",
+		26257,
+		"root",
+		"test",
+	)
+	db, err := sql.Open("postgres", connection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Ping(); err != nil {
+		t.Skip("store/cockroach: can't connect to db")
+	}
+	defer db.Close()
+	if _, err := db.Exec("DROP TABLE IF EXISTS testsql.migrated;"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a table created without tenancy
+	plain := NewStore(
+		store.Database("testsql"),
+		store.Table("migrated"),
+		store.Nodes(connection),
+	)
+	if err := plain.Write(&store.Record{Key: "foo", Value: []byte("unassigned")}); err != nil {
+		t.Fatal(err)
+	}
+	plain.Close()
+
+	sqlStore := NewStore(
+		store.Database("testsql"),
+		store.Table("migrated"),
+		store.Nodes(connection),
+		TenantColumn("tenant"),
+	)
+	defer sqlStore.Close()
+
+	acme, err := Scope(metadata.NewContext(context.Background(), map[string]string{DefaultTenantKey: "acme"}), sqlStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the existing record has the empty tenant, the key is free for tenants
+	if _, err := acme.Read("foo"); err != store.ErrNotFound {
+		t.Fatalf("Expected the unassigned record not to be found, got %v", err)
+	}
+	if err := acme.Write(&store.Record{Key: "foo", Value: []byte("acme")}); err != nil {
+		t.Fatalf("Expected the key to be part of the tenant primary key, got %v", err)
+	}
+
+	var n int
+	if err := db.QueryRow("SELECT count(*) FROM testsql.migrated WHERE key = 'foo';").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("Expected the unassigned and the acme record, got %d", n)
+	}
+}
+
+func TestSweep(t *testing.T) {
+	if len(os.Getenv("IN_TRAVIS_CI")) != 0 {
+		t.Skip()
+	}
+
+	connection := fmt.Sprintf(
+		"host=%s port=%d user=%s sslmode=disable dbname=%s",
+		"localhost",
+		26257,
+		"root",
+		"test",
+	)
+	db, err := sql.Open("postgres", connection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Ping(); err != nil {
+		t.Skip("store/cockroach: can't connect to db")
+	}
+	db.Close()
+
+	sqlStore := NewStore(
+		store.Database("testsweep"),
+		store.Nodes(connection),
+		SweepBatchSize(2),
+		TTLJitter(time.Millisecond),
+	)
+	defer sqlStore.Close()
+
+	for _, key := range []string{"a", "b", "c"} {
+		if err := sqlStore.Write(&store.Record{Key: key, Value: []byte(key), Expiry: time.Millisecond}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sqlStore.Write(&store.Record{Key: "d", Value: []byte("d")}); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	n, err := Sweep(sqlStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Fatalf("expected 3 expired rows to be swept, got %d", n)
+	}
+
+	if _, err := sqlStore.Read("d"); err != nil {
+		t.Fatalf("expected the record without expiry to be kept: %v", err)
+	}
+	sqlStore.Delete("d")
+}
+
+func TestWriteManyStatement(t *testing.T) {
+	want := "INSERT INTO db.t(key, value, metadata, expiry) VALUES ($1, $2::bytea, $3, $4), ($5, $6::bytea, $7, $8) ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, metadata = EXCLUDED.metadata, expiry = EXCLUDED.expiry;"
+	if got := writeManyStatement("db", "t", "", 2); got != want {
+		t.Fatalf("Expected %s, got %s", want, got)
+	}
+
+	want = "INSERT INTO db.t(tenant, key, value, metadata, expiry) VALUES ($1, $2, $3::bytea, $4, $5), ($6, $7, $8::bytea, $9, $10) ON CONFLICT (tenant, key) DO UPDATE SET value = EXCLUDED.value, metadata = EXCLUDED.metadata, expiry = EXCLUDED.expiry;"
+	if got := writeManyStatement("db", "t", "tenant", 2); got != want {
+		t.Fatalf("Expected %s, got %s", want, got)
+	}
+
+	records := dedupe([]*store.Record{
+		{Key: "a", Value: []byte("1")},
+		{Key: "b", Value: []byte("2")},
+		{Key: "a", Value: []byte("3")},
+	})
+	if len(records) != 2 || records[0].Key != "b" || string(records[1].Value) != "3" {
+		t.Fatalf("Expected the last record of every key, got %v", records)
+	}
+
+	if !retryable(errors.Wrap(&pq.Error{Code: "40001"}, "commit")) {
+		t.Fatal("Expected serialization failures to be retryable")
+	}
+	if retryable(&pq.Error{Code: "23505"}) {
+		t.Fatal("Expected unique violations not to be retryable")
+	}
+}
+
+func TestSessionFollowerReads(t *testing.T) {
+	s := &sqlStore{options: store.Options{Database: "micro"}}
+	q := "SELECT key, value, metadata, expiry FROM micro.t WHERE key = $1;"
+	if got := s.followerRead(scope{}, "micro", "t", "read", q); got != q {
+		t.Fatalf("Expected no follower read without the option, got %s", got)
+	}
+
+	FollowerReads()(&s.options)
+	want := "SELECT key, value, metadata, expiry FROM micro.t AS OF SYSTEM TIME follower_read_timestamp() WHERE key = $1;"
+	if got := s.followerRead(scope{}, "micro", "t", "read", q); got != want {
+		t.Fatalf("Expected %s, got %s", want, got)
+	}
+
+	ss, err := Session(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := ss.(*scopedStore).scope
+	if got := s.followerRead(sc, "micro", "t", "read", q); got != want {
+		t.Fatalf("Expected a follower read before the session wrote, got %s", got)
+	}
+
+	s.written(sc, "")
+	if got := s.followerRead(sc, "micro", "t", "read", q); got != q {
+		t.Fatalf("Expected the session to read its write, got %s", got)
+	}
+	if got := s.followerRead(sc, "other", "t", "read", "SELECT key FROM other.t;"); got == "SELECT key FROM other.t;" {
+		t.Fatal("Expected follower reads of databases the session didn't write to")
+	}
+
+	// writes of a transaction are recorded once it commits
+	writes := new([]string)
+	s.written(scope{session: sc.session, tx: &sql.Tx{}, writes: writes}, "other")
+	if len(*writes) != 1 {
+		t.Fatalf("Expected the write to be pending, got %v", *writes)
+	}
+	if _, ok := sc.session.Position(s.sessionKey("other")); ok {
+		t.Fatal("Expected no position before the commit")
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	if len(os.Getenv("IN_TRAVIS_CI")) != 0 {
+		t.Skip()
+	}
+
+	connection := fmt.Sprintf(
+		"host=%s port=%d user=%s sslmode=disable dbname=%s",
+		"localhost",
+		26257,
+		"root",
+		"test",
+	)
+	db, err := sql.Open("postgres", connection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Ping(); err != nil {
+		t.Skip("store/cockroach: can't connect to db")
+	}
+	db.Close()
+
+	sqlStore := NewStore(
+		store.Database("testtx"),
+		store.Nodes(connection),
+	)
+	defer sqlStore.Close()
+
+	var records []*store.Record
+	for i := 0; i < DefaultWriteBatchSize+10; i++ {
+		records = append(records, &store.Record{Key: fmt.Sprintf("many%d", i), Value: []byte("v")})
+	}
+	if err := WriteMany(sqlStore, records); err != nil {
+		t.Fatal(err)
+	}
+	found, err := sqlStore.Read("many", store.ReadPrefix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != len(records) {
+		t.Fatalf("Expected %d records, got %d", len(records), len(found))
+	}
+
+	errAbort := errors.New("abort")
+	err = Transaction(context.Background(), sqlStore, func(ctx context.Context, tx store.Store) error {
+		if _, ok := TxFromContext(ctx); !ok {
+			t.Fatal("Expected the transaction in the context")
+		}
+		if err := tx.Delete("many0"); err != nil {
+			return err
+		}
+		if err := tx.Write(&store.Record{Key: "many1", Value: []byte("changed")}); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if err != errAbort {
+		t.Fatalf("Expected %v, got %v", errAbort, err)
+	}
+	if found, err := sqlStore.Read("many1"); err != nil || string(found[0].Value) != "v" {
+		t.Fatalf("Expected the write to be rolled back, got %v", err)
+	}
+	if _, err := sqlStore.Read("many0"); err != nil {
+		t.Fatalf("Expected the delete to be rolled back, got %v", err)
+	}
+
+	err = Transaction(context.Background(), sqlStore, func(ctx context.Context, tx store.Store) error {
+		for _, r := range records {
+			if err := tx.Delete(r.Key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found, _ := sqlStore.Read("many", store.ReadPrefix()); len(found) != 0 {
+		t.Fatalf("Expected the records to be deleted, got %d", len(found))
+	}
+}
+
+func TestFilterQuery(t *testing.T) {
+	n := 2
+	if got := placeholders("metadata->>'a?' = ? AND metadata ?? 'b' AND x IN (?, ?)", &n); got != "metadata->>'a?' = $3 AND metadata ? 'b' AND x IN ($4, $5)" {
+		t.Fatalf("Unexpected placeholders %s", got)
+	}
+	if n != 5 {
+		t.Fatalf("Expected 5 arguments, got %d", n)
+	}
+
+	now := time.Now()
+	query, args := filterQuery("db", "t", "tenant", store.ReadOptions{Limit: 10, Offset: 20}, []Filter{
+		Where("metadata->>'kind' = ?", "user"),
+		MetadataEquals("active", true),
+	}, []interface{}{"acme", "foo%", now})
+
+	want := "SELECT key, value, metadata, expiry FROM db.t WHERE tenant = $1 AND key LIKE $2 AND (expiry IS NULL OR expiry > $3) AND (metadata->>'kind' = $4) AND (metadata @> $5::jsonb) ORDER BY key LIMIT $6 OFFSET $7;"
+	if query != want {
+		t.Fatalf("Expected %s, got %s", want, query)
+	}
+	if len(args) != 7 || args[3] != "user" || args[4] != `{"active":true}` || args[5] != uint(10) {
+		t.Fatalf("Unexpected arguments %v", args)
+	}
+}
+
+func TestFilterSQL(t *testing.T) {
+	if len(os.Getenv("IN_TRAVIS_CI")) != 0 {
+		t.Skip()
+	}
+
+	connection := fmt.Sprintf(
+		"host=%s port=%d user=%s sslmode=disable dbname=%s",
+		"localhost",
+		26257,
+		"root",
+		"test",
+	)
+	db, err := sql.Open("postgres", connection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Ping(); err != nil {
+		t.Skip("store/cockroach: can't connect to db")
+	}
+	db.Close()
+
+	sqlStore := NewStore(
+		store.Database("testfilter"),
+		store.Nodes(connection),
+	)
+	defer sqlStore.Close()
+
+	for _, r := range []*store.Record{
+		{Key: "user1", Value: []byte("1"), Metadata: map[string]interface{}{"tenant": "acme", "active": true}},
+		{Key: "user2", Value: []byte("2"), Metadata: map[string]interface{}{"tenant": "acme", "active": false}},
+		{Key: "user3", Value: []byte("3"), Metadata: map[string]interface{}{"tenant": "other", "active": true}},
+	} {
+		if err := sqlStore.Write(r); err != nil {
+			t.Fatal(err)
+		}
+		defer sqlStore.Delete(r.Key)
+	}
+
+	keys, err := ListFiltered(sqlStore, []Filter{Where("metadata->>'tenant' = ?", "acme")}, store.ListPrefix("user"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0] != "user1" || keys[1] != "user2" {
+		t.Fatalf("Expected the records of acme, got %v", keys)
+	}
+
+	records, err := ReadFiltered(sqlStore, "user", []Filter{MetadataEquals("active", true)}, store.ReadPrefix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Key != "user1" || records[1].Key != "user3" {
+		t.Fatalf("Expected the active records, got %v", records)
+	}
+}
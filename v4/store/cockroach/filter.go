@@ -0,0 +1,184 @@
+package cockroach
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"go-micro.dev/v4/store"
+)
+
+// Filter is a condition on the rows of a table, evaluated by the database.
+type Filter struct {
+	expr string
+	args []interface{}
+}
+
+// Where returns a filter of the SQL expression, with ? as placeholders of
+// the arguments, e.g. Where("metadata->>'tenant' = ?", tenant). Use ?? for
+// a literal question mark, e.g. for the jsonb ? operator. The expression is
+// inlined into the query, only its arguments are escaped.
+func Where(expr string, args ...interface{}) Filter {
+	return Filter{expr: expr, args: args}
+}
+
+// MetadataEquals returns a filter of the records whose metadata has the
+// value for the key. It's a containment query, so it uses the metadata
+// index.
+func MetadataEquals(key string, value interface{}) Filter {
+	b, _ := json.Marshal(map[string]interface{}{key: value})
+	return Filter{expr: "metadata @> ?::jsonb", args: []interface{}{string(b)}}
+}
+
+// ListFiltered lists the keys of the records matching all filters.
+func ListFiltered(s store.Store, filters []Filter, opts ...store.ListOption) ([]string, error) {
+	var options store.ListOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	records, err := readFiltered(s, options.Prefix+"%"+options.Suffix, filters, store.ReadOptions{
+		Database: options.Database,
+		Table:    options.Table,
+		Limit:    options.Limit,
+		Offset:   options.Offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, len(records))
+	for i, r := range records {
+		keys[i] = r.Key
+	}
+	return keys, nil
+}
+
+// ReadFiltered reads the records matching the key and all filters, with the
+// key matched as set by the prefix and suffix options.
+func ReadFiltered(s store.Store, key string, filters []Filter, opts ...store.ReadOption) ([]*store.Record, error) {
+	var options store.ReadOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	pattern := key
+	if options.Prefix {
+		pattern = pattern + "%"
+	}
+	if options.Suffix {
+		pattern = "%" + pattern
+	}
+
+	return readFiltered(s, pattern, filters, options)
+}
+
+func readFiltered(s store.Store, pattern string, filters []Filter, options store.ReadOptions) ([]*store.Record, error) {
+	ss, sc, err := unscope(s)
+	if err != nil {
+		return nil, err
+	}
+
+	// create the db if not exists
+	if err := ss.createDB(options.Database, options.Table); err != nil {
+		return nil, err
+	}
+
+	args, err := ss.args(sc.tenant, pattern, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	database, table := ss.getDB(options.Database, options.Table)
+	query, args := filterQuery(database, table, getTenantColumn(ss.options), options, filters, args)
+
+	var rows *sql.Rows
+	if sc.tx != nil {
+		rows, err = sc.tx.Query(query, args...)
+	} else {
+		rows, err = ss.db.Query(query, args...)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "sqlStore.ReadFiltered failed")
+	}
+	defer rows.Close()
+
+	var records []*store.Record
+	var timehelper pq.NullTime
+
+	for rows.Next() {
+		record := &store.Record{}
+		metadata := make(Metadata)
+
+		if err := rows.Scan(&record.Key, &record.Value, &metadata, &timehelper); err != nil {
+			return records, err
+		}
+
+		record.Metadata = toMetadata(&metadata)
+		if timehelper.Valid {
+			record.Expiry = time.Until(timehelper.Time)
+		}
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+// filterQuery returns the query of the filtered read and its arguments. The
+// arguments passed in are those of the key pattern and the current time,
+// after the tenant if tenancy is enabled.
+func filterQuery(database, table, column string, options store.ReadOptions, filters []Filter, args []interface{}) (string, []interface{}) {
+	var where []string
+	if len(column) > 0 {
+		where = append(where, column+" = $1")
+	}
+
+	n := len(args)
+	where = append(where,
+		fmt.Sprintf("key LIKE $%d", n-1),
+		fmt.Sprintf("(expiry IS NULL OR expiry > $%d)", n),
+	)
+
+	for _, f := range filters {
+		where = append(where, "("+placeholders(f.expr, &n)+")")
+		args = append(args, f.args...)
+	}
+
+	query := fmt.Sprintf("SELECT key, value, metadata, expiry FROM %s.%s WHERE %s ORDER BY key",
+		database, table, strings.Join(where, " AND "))
+	if options.Limit != 0 {
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", n+1, n+2)
+		args = append(args, options.Limit, options.Offset)
+	}
+
+	return query + ";", args
+}
+
+// placeholders replaces the ? placeholders of the expression with numbered
+// ones following n, leaving quoted strings as they are.
+func placeholders(expr string, n *int) string {
+	var b strings.Builder
+	var quoted bool
+
+	for i := 0; i < len(expr); i++ {
+		ch := expr[i]
+		switch {
+		case ch == '\'':
+			quoted = !quoted
+		case quoted || ch != '?':
+		case i+1 < len(expr) && expr[i+1] == '?':
+			i++
+		default:
+			*n++
+			fmt.Fprintf(&b, "$%d", *n)
+			continue
+		}
+		b.WriteByte(ch)
+	}
+
+	return b.String()
+}
@@ -3,6 +3,7 @@ module github.com/go-micro/plugins/v4/store/cockroach
 go 1.17
 
 require (
+	github.com/go-micro/plugins/v4/util/session v1.0.0
 	github.com/kr/pretty v0.2.1
 	github.com/lib/pq v1.10.2
 	github.com/pkg/errors v0.9.1
@@ -48,3 +49,5 @@ require (
 	gopkg.in/warnings.v0 v0.1.2 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
+
+replace github.com/go-micro/plugins/v4/util/session => ../../util/session
@@ -0,0 +1,88 @@
+package cockroach
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-micro/plugins/v4/util/session"
+	"github.com/pkg/errors"
+	"go-micro.dev/v4/store"
+)
+
+// DefaultFollowerReadLag is how far behind follower reads are, about the
+// lag of follower_read_timestamp() plus the maximum clock offset. Within a
+// session databases written to less than the lag ago are read without
+// follower reads.
+var DefaultFollowerReadLag = 5 * time.Second
+
+// followerReads are the statements read as of follower_read_timestamp()
+// with FollowerReads.
+var followerReads = map[string]bool{
+	"list":       true,
+	"read":       true,
+	"readMany":   true,
+	"readOffset": true,
+}
+
+// Session returns a view of the store which reads the writes of the session
+// of the context, see the session package. With FollowerReads the databases
+// the session wrote to less than DefaultFollowerReadLag ago are read from
+// the leaseholder. It keeps the tenant of a scoped store, so Scope the store
+// first.
+func Session(ctx context.Context, s store.Store) (store.Store, error) {
+	ss, sc, err := unscope(s)
+	if err != nil {
+		return nil, err
+	}
+	if sc.tx != nil {
+		return nil, errors.New("can't start a session within a transaction")
+	}
+
+	_, sc.session = session.Context(ctx)
+	return &scopedStore{s: ss, scope: sc}, nil
+}
+
+// sessionKey returns the key of the database in sessions.
+func (s *sqlStore) sessionKey(database string) string {
+	if len(database) == 0 {
+		database = s.options.Database
+	}
+	if len(database) == 0 {
+		database = DefaultDatabase
+	}
+	return "cockroach/" + re.ReplaceAllString(database, "_")
+}
+
+// written records a write to the database in the session of the scope, or
+// in the writes of its transaction until it commits.
+func (s *sqlStore) written(sc scope, database string) {
+	switch {
+	case sc.session == nil:
+	case sc.tx != nil:
+		if sc.writes != nil {
+			*sc.writes = append(*sc.writes, database)
+		}
+	default:
+		sc.session.Advance(s.sessionKey(database), uint64(time.Now().UnixNano()))
+	}
+}
+
+// followerRead returns the query reading as of follower_read_timestamp(),
+// unless the session of the scope recently wrote to the database.
+func (s *sqlStore) followerRead(sc scope, database, table, query, q string) string {
+	if !followerReads[query] || !getFollowerReads(s.options) || sc.tx != nil {
+		return q
+	}
+
+	if sc.session != nil {
+		pos, ok := sc.session.Position(s.sessionKey(database))
+		if ok && time.Since(time.Unix(0, int64(pos))) < DefaultFollowerReadLag {
+			return q
+		}
+	}
+
+	from := fmt.Sprintf("FROM %s.%s", database, table)
+	return strings.Replace(q, from, from+" AS OF SYSTEM TIME follower_read_timestamp()", 1)
+}
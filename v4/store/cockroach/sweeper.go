@@ -0,0 +1,140 @@
+package cockroach
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go-micro.dev/v4/logger"
+	"go-micro.dev/v4/store"
+)
+
+// Sweep deletes the expired rows of every table the store knows of in
+// batches and returns the number of rows deleted. It's what the sweeper
+// started by SweepInterval runs, for callers scheduling it themselves.
+func Sweep(s store.Store) (int64, error) {
+	ss, ok := s.(*sqlStore)
+	if !ok {
+		return 0, errors.Errorf("%s is not a cockroach store", s.String())
+	}
+	return ss.sweep(nil)
+}
+
+func (s *sqlStore) startSweeper() {
+	interval := getSweepInterval(s.options)
+	if interval <= 0 {
+		return
+	}
+
+	s.sweepExit = make(chan bool)
+	s.sweepDone = make(chan bool)
+
+	go func(exit, done chan bool) {
+		defer close(done)
+
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-exit:
+				return
+			case <-t.C:
+				n, err := s.sweep(exit)
+				if err != nil {
+					s.options.Logger.Logf(logger.ErrorLevel, "Error sweeping expired rows: %v", err)
+				} else if n > 0 {
+					s.options.Logger.Logf(logger.DebugLevel, "Swept %d expired rows", n)
+				}
+			}
+		}
+	}(s.sweepExit, s.sweepDone)
+}
+
+func (s *sqlStore) stopSweeper() {
+	if s.sweepExit == nil {
+		return
+	}
+	close(s.sweepExit)
+	<-s.sweepDone
+	s.sweepExit, s.sweepDone = nil, nil
+}
+
+// tables returns the database and table pairs created so far, including the
+// default one.
+func (s *sqlStore) tables() [][2]string {
+	database, table := s.getDB(s.options.Database, s.options.Table)
+	tables := [][2]string{{database, table}}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	for k := range s.databases {
+		parts := strings.SplitN(k, ":", 2)
+		if len(parts) != 2 || (parts[0] == database && parts[1] == table) {
+			continue
+		}
+		tables = append(tables, [2]string{parts[0], parts[1]})
+	}
+
+	return tables
+}
+
+func (s *sqlStore) sweep(exit chan bool) (int64, error) {
+	var total int64
+	for _, t := range s.tables() {
+		n, err := s.sweepTable(t[0], t[1], exit)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// sweepTable deletes expired rows a batch at a time until a batch comes back
+// short, pausing between batches to keep to the sweep rate. The batch is
+// selected in a subquery as postgres has no DELETE ... LIMIT.
+func (s *sqlStore) sweepTable(database, table string, exit chan bool) (int64, error) {
+	batch := getSweepBatchSize(s.options)
+	pause := time.Second / time.Duration(getSweepRate(s.options))
+
+	query := fmt.Sprintf("DELETE FROM %[1]s.%[2]s WHERE key IN (SELECT key FROM %[1]s.%[2]s WHERE expiry < $1 LIMIT %[3]d);", database, table, batch)
+	if column := getTenantColumn(s.options); len(column) > 0 {
+		query = fmt.Sprintf("DELETE FROM %[1]s.%[2]s WHERE (%[3]s, key) IN (SELECT %[3]s, key FROM %[1]s.%[2]s WHERE expiry < $1 LIMIT %[4]d);", database, table, column, batch)
+	}
+
+	var total int64
+	for {
+		result, err := s.db.Exec(query, time.Now())
+		if err != nil {
+			return total, err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += n
+
+		if n < int64(batch) {
+			return total, nil
+		}
+
+		select {
+		case <-exit:
+			return total, nil
+		case <-time.After(pause):
+		}
+	}
+}
+
+// expiry returns the time a record written now with the ttl expires at,
+// with the configured jitter added.
+func (s *sqlStore) expiry(ttl time.Duration) time.Time {
+	if jitter := getTTLJitter(s.options); ttl > 0 && jitter > 0 {
+		ttl += time.Duration(rand.Int63n(int64(jitter)))
+	}
+	return time.Now().Add(ttl)
+}
@@ -0,0 +1,101 @@
+package cockroach
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/go-micro/plugins/v4/util/session"
+	"github.com/pkg/errors"
+	"go-micro.dev/v4/metadata"
+	"go-micro.dev/v4/store"
+)
+
+var (
+	// ErrNoTenant is returned when tenancy is enabled but no tenant is set.
+	ErrNoTenant = errors.New("no tenant set")
+	// ErrNoTenancy is returned by Scope if the store has no tenant column.
+	ErrNoTenancy = errors.New("tenancy is not enabled")
+)
+
+// scope restricts the queries of the store to the rows of a tenant, and to
+// a transaction within Transaction. Within a Session it records the writes
+// of the session, committed ones in session, those of a transaction in
+// writes until it commits.
+type scope struct {
+	tenant  string
+	tx      *sql.Tx
+	session *session.Session
+	writes  *[]string
+}
+
+// scopedStore is a view of the store bound to a single tenant or
+// transaction.
+type scopedStore struct {
+	s *sqlStore
+	scope
+}
+
+// unscope returns the store and scope of a cockroach store or a view of one.
+func unscope(s store.Store) (*sqlStore, scope, error) {
+	switch v := s.(type) {
+	case *sqlStore:
+		return v, scope{}, nil
+	case *scopedStore:
+		return v.s, v.scope, nil
+	}
+	return nil, scope{}, errors.Errorf("%s is not a cockroach store", s.String())
+}
+
+// Scope returns a view of the store which only reads and writes the rows of
+// the tenant found in the context metadata. The store must have been created
+// with the TenantColumn option.
+func Scope(ctx context.Context, s store.Store) (store.Store, error) {
+	ss, ok := s.(*sqlStore)
+	if !ok {
+		return nil, errors.Errorf("%s is not a cockroach store", s.String())
+	}
+
+	if len(getTenantColumn(ss.options)) == 0 {
+		return nil, ErrNoTenancy
+	}
+
+	tenant, ok := metadata.Get(ctx, getTenantKey(ss.options))
+	if !ok || len(tenant) == 0 {
+		return nil, ErrNoTenant
+	}
+
+	return &scopedStore{s: ss, scope: scope{tenant: tenant}}, nil
+}
+
+func (t *scopedStore) Init(opts ...store.Option) error {
+	return t.s.Init(opts...)
+}
+
+func (t *scopedStore) Options() store.Options {
+	return t.s.Options()
+}
+
+func (t *scopedStore) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	return t.s.readScope(t.scope, key, opts...)
+}
+
+func (t *scopedStore) Write(r *store.Record, opts ...store.WriteOption) error {
+	return t.s.writeScope(t.scope, r, opts...)
+}
+
+func (t *scopedStore) Delete(key string, opts ...store.DeleteOption) error {
+	return t.s.deleteScope(t.scope, key, opts...)
+}
+
+func (t *scopedStore) List(opts ...store.ListOption) ([]string, error) {
+	return t.s.listScope(t.scope, opts...)
+}
+
+// Close is a no-op, the connection is owned by the underlying store.
+func (t *scopedStore) Close() error {
+	return nil
+}
+
+func (t *scopedStore) String() string {
+	return t.s.String()
+}
@@ -0,0 +1,205 @@
+package cockroach
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"go-micro.dev/v4/store"
+)
+
+var (
+	// DefaultTxRetries is the number of times Transaction retries after a
+	// serialization failure.
+	DefaultTxRetries = 5
+	// DefaultWriteBatchSize is the number of rows WriteMany upserts per
+	// statement.
+	DefaultWriteBatchSize = 500
+)
+
+// serializationFailure is the SQLSTATE of transactions aborted because they
+// conflicted with another one, they can be retried.
+const serializationFailure = "40001"
+
+type txKey struct{}
+
+// TxFromContext returns the transaction of the context passed to the func of
+// Transaction, for queries of the caller's own in the same transaction.
+func TxFromContext(ctx context.Context) (*sql.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(*sql.Tx)
+	return tx, ok
+}
+
+// Transaction runs fn in a transaction and commits it if fn returns no
+// error. The store passed to fn reads and writes within the transaction, it
+// keeps the tenant of a scoped store. The transaction is retried from the
+// start if it fails to serialize, so fn may run more than once.
+// Transactions don't nest, a store of a transaction runs fn in its
+// transaction.
+func Transaction(ctx context.Context, s store.Store, fn func(ctx context.Context, tx store.Store) error) error {
+	ss, sc, err := unscope(s)
+	if err != nil {
+		return err
+	}
+
+	if sc.tx != nil {
+		return fn(context.WithValue(ctx, txKey{}, sc.tx), s)
+	}
+
+	if ss.db == nil {
+		return errors.New("Database connection not initialized")
+	}
+
+	for i := 0; ; i++ {
+		err = ss.transaction(ctx, sc, fn)
+		if err == nil || !retryable(err) || i >= DefaultTxRetries {
+			return err
+		}
+		time.Sleep(time.Duration(1<<uint(i)) * 10 * time.Millisecond)
+	}
+}
+
+func (s *sqlStore) transaction(ctx context.Context, sc scope, fn func(ctx context.Context, tx store.Store) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	sc.tx = tx
+	if sc.session != nil {
+		sc.writes = new([]string)
+	}
+	if err := fn(context.WithValue(ctx, txKey{}, tx), &scopedStore{s: s, scope: sc}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// the writes of the session are visible once committed
+	if sc.writes != nil {
+		sc.tx = nil
+		for _, database := range *sc.writes {
+			s.written(sc, database)
+		}
+	}
+	return nil
+}
+
+// retryable reports whether the transaction failed to serialize.
+func retryable(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == serializationFailure
+}
+
+// WriteMany upserts the records in one transaction, in batches of
+// DefaultWriteBatchSize rows per statement. Of records with the same key
+// the last one is written.
+func WriteMany(s store.Store, records []*store.Record, opts ...store.WriteOption) error {
+	return Transaction(context.Background(), s, func(ctx context.Context, tx store.Store) error {
+		ss, sc, err := unscope(tx)
+		if err != nil {
+			return err
+		}
+		return ss.writeMany(sc, records, opts...)
+	})
+}
+
+func (s *sqlStore) writeMany(sc scope, records []*store.Record, opts ...store.WriteOption) error {
+	var options store.WriteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	// create the db if not exists
+	if err := s.createDB(options.Database, options.Table); err != nil {
+		return err
+	}
+
+	records = dedupe(records)
+	database, table := s.getDB(options.Database, options.Table)
+	column := getTenantColumn(s.options)
+
+	for len(records) > 0 {
+		n := DefaultWriteBatchSize
+		if n > len(records) {
+			n = len(records)
+		}
+		batch := records[:n]
+		records = records[n:]
+
+		var args []interface{}
+		for _, r := range batch {
+			metadata := make(Metadata)
+			for k, v := range r.Metadata {
+				metadata[k] = v
+			}
+
+			var expiry interface{}
+			if r.Expiry != 0 {
+				expiry = s.expiry(r.Expiry)
+			}
+
+			rargs, err := s.args(sc.tenant, r.Key, r.Value, metadata, expiry)
+			if err != nil {
+				return err
+			}
+			args = append(args, rargs...)
+		}
+
+		if _, err := sc.tx.Exec(writeManyStatement(database, table, column, len(batch)), args...); err != nil {
+			return errors.Wrap(err, "Couldn't insert records")
+		}
+	}
+
+	s.written(sc, options.Database)
+	return nil
+}
+
+// dedupe keeps the last record of every key, as a statement can't upsert a
+// row twice.
+func dedupe(records []*store.Record) []*store.Record {
+	last := make(map[string]int, len(records))
+	for i, r := range records {
+		last[r.Key] = i
+	}
+	if len(last) == len(records) {
+		return records
+	}
+
+	deduped := make([]*store.Record, 0, len(last))
+	for i, r := range records {
+		if last[r.Key] == i {
+			deduped = append(deduped, r)
+		}
+	}
+	return deduped
+}
+
+// writeManyStatement returns the upsert of n rows, with the tenant column
+// if tenancy is enabled.
+func writeManyStatement(database, table, column string, n int) string {
+	columns, conflict, width := "key, value, metadata, expiry", "key", 4
+	if len(column) > 0 {
+		columns, conflict, width = column+", "+columns, column+", key", 5
+	}
+
+	values := make([]string, n)
+	for i := range values {
+		p := i * width
+		if width == 5 {
+			values[i] = fmt.Sprintf("($%d, $%d, $%d::bytea, $%d, $%d)", p+1, p+2, p+3, p+4, p+5)
+		} else {
+			values[i] = fmt.Sprintf("($%d, $%d::bytea, $%d, $%d)", p+1, p+2, p+3, p+4)
+		}
+	}
+
+	return fmt.Sprintf("INSERT INTO %s.%s(%s) VALUES %s ON CONFLICT (%s) DO UPDATE SET value = EXCLUDED.value, metadata = EXCLUDED.metadata, expiry = EXCLUDED.expiry;",
+		database, table, columns, strings.Join(values, ", "), conflict)
+}
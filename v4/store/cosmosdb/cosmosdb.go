@@ -0,0 +1,455 @@
+// Package cosmosdb implements the store on Azure Cosmos DB.
+package cosmosdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/go-micro/plugins/v4/util/records"
+	log "go-micro.dev/v4/logger"
+	"go-micro.dev/v4/store"
+	"go-micro.dev/v4/util/cmd"
+)
+
+// DefaultDatabase is the database used if none is provided.
+var DefaultDatabase = "micro"
+
+type cosmosStore struct {
+	ctx     context.Context
+	options store.Options
+	client  *azcosmos.Client
+
+	// container of every database and the document field of the table
+	container    string
+	partitionKey string
+
+	sync.RWMutex
+	// containers known to exist, by database
+	containers map[string]*azcosmos.ContainerClient
+}
+
+// document is a record as stored in Cosmos DB. The table is stored in the
+// partition key field, which isn't known until runtime.
+type document struct {
+	ID       string                 `json:"id"`
+	Key      string                 `json:"key"`
+	Value    []byte                 `json:"value"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	// TTL is the time to live in seconds after the last write
+	TTL *int64 `json:"ttl,omitempty"`
+	// TS is the time of the last write, set by Cosmos DB
+	TS int64 `json:"_ts,omitempty"`
+}
+
+func init() {
+	cmd.DefaultStores["cosmosdb"] = NewStore
+}
+
+// NewStore returns a new store backed by Cosmos DB. The node is the
+// endpoint of the account, e.g. https://account.documents.azure.com:443/.
+// Databases map to Cosmos DB databases, whose records are stored in one
+// container partitioned by table, so every table is a logical partition.
+func NewStore(opts ...store.Option) store.Store {
+	options := store.Options{
+		Database: DefaultDatabase,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	s := &cosmosStore{
+		ctx:        context.Background(),
+		options:    options,
+		containers: make(map[string]*azcosmos.ContainerClient),
+	}
+
+	if err := s.configure(); err != nil {
+		log.Fatal(err)
+	}
+
+	return s
+}
+
+func (s *cosmosStore) configure() error {
+	client, err := newClient(s.options)
+	if err != nil {
+		return fmt.Errorf("creating cosmos client: %w", err)
+	}
+
+	s.Lock()
+	s.client = client
+	s.container = getString(s.options, containerKey{}, DefaultContainer)
+	s.partitionKey = strings.TrimPrefix(getString(s.options, partitionKeyPathKey{}, DefaultPartitionKeyPath), "/")
+	s.containers = make(map[string]*azcosmos.ContainerClient)
+	s.Unlock()
+
+	return nil
+}
+
+func newClient(o store.Options) (*azcosmos.Client, error) {
+	if cs := getString(o, connectionStringKey{}, ""); len(cs) > 0 {
+		return azcosmos.NewClientFromConnectionString(cs, nil)
+	}
+
+	if len(o.Nodes) == 0 {
+		return nil, errors.New("no endpoint")
+	}
+	endpoint := o.Nodes[0]
+
+	if key := getString(o, keyKey{}, ""); len(key) > 0 {
+		cred, err := azcosmos.NewKeyCredential(key)
+		if err != nil {
+			return nil, err
+		}
+		return azcosmos.NewClientWithKey(endpoint, cred, nil)
+	}
+
+	cred, err := tokenCredential(o)
+	if err != nil {
+		return nil, err
+	}
+	return azcosmos.NewClient(endpoint, cred, nil)
+}
+
+func tokenCredential(o store.Options) (azcore.TokenCredential, error) {
+	if o.Context != nil {
+		if c, ok := o.Context.Value(credentialKey{}).(azcore.TokenCredential); ok {
+			return c, nil
+		}
+		if id, ok := o.Context.Value(managedIdentityKey{}).(string); ok {
+			var opts azidentity.ManagedIdentityCredentialOptions
+			if len(id) > 0 {
+				opts.ID = azidentity.ClientID(id)
+			}
+			return azidentity.NewManagedIdentityCredential(&opts)
+		}
+	}
+	return azidentity.NewDefaultAzureCredential(nil)
+}
+
+func (s *cosmosStore) Init(opts ...store.Option) error {
+	for _, o := range opts {
+		o(&s.options)
+	}
+	return s.configure()
+}
+
+func (s *cosmosStore) Options() store.Options {
+	return s.options
+}
+
+func (s *cosmosStore) Close() error {
+	return nil
+}
+
+func (s *cosmosStore) String() string {
+	return "cosmosdb"
+}
+
+func hasStatus(err error, code int) bool {
+	var rerr *azcore.ResponseError
+	return errors.As(err, &rerr) && rerr.StatusCode == code
+}
+
+// getContainer returns the container of the database, creating the
+// database and container on first use. Containers are created with per
+// item TTLs enabled.
+func (s *cosmosStore) getContainer(database string) (*azcosmos.ContainerClient, error) {
+	if len(database) == 0 {
+		database = s.options.Database
+	}
+
+	s.RLock()
+	c, ok := s.containers[database]
+	s.RUnlock()
+	if ok {
+		return c, nil
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if c, ok := s.containers[database]; ok {
+		return c, nil
+	}
+
+	_, err := s.client.CreateDatabase(s.ctx, azcosmos.DatabaseProperties{ID: database}, nil)
+	if err != nil && !hasStatus(err, http.StatusConflict) {
+		return nil, err
+	}
+
+	db, err := s.client.NewDatabase(database)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := int32(-1)
+	_, err = db.CreateContainer(s.ctx, azcosmos.ContainerProperties{
+		ID: s.container,
+		PartitionKeyDefinition: azcosmos.PartitionKeyDefinition{
+			Paths: []string{"/" + s.partitionKey},
+		},
+		DefaultTimeToLive: &ttl,
+	}, nil)
+	if err != nil && !hasStatus(err, http.StatusConflict) {
+		return nil, err
+	}
+
+	c, err = db.NewContainer(s.container)
+	if err != nil {
+		return nil, err
+	}
+
+	s.containers[database] = c
+	return c, nil
+}
+
+func (s *cosmosStore) table(table string) string {
+	if len(table) == 0 {
+		return s.options.Table
+	}
+	return table
+}
+
+// itemID encodes the key as an item ID, which can't contain slashes,
+// backslashes, question marks or hashes.
+func itemID(key string) string {
+	return url.PathEscape(key)
+}
+
+func (d *document) record(now time.Time) *store.Record {
+	r := &store.Record{
+		Key:      d.Key,
+		Value:    d.Value,
+		Metadata: d.Metadata,
+	}
+	if r.Metadata == nil {
+		r.Metadata = make(map[string]interface{})
+	}
+	if d.TTL != nil && *d.TTL > 0 {
+		r.Expiry = time.Unix(d.TS+*d.TTL, 0).Sub(now)
+	}
+	return r
+}
+
+func (s *cosmosStore) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	var options store.ReadOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	c, err := s.getContainer(options.Database)
+	if err != nil {
+		return nil, err
+	}
+	pk := azcosmos.NewPartitionKeyString(s.table(options.Table))
+
+	if !options.Prefix && !options.Suffix {
+		rsp, err := c.ReadItem(s.ctx, pk, itemID(key), nil)
+		if hasStatus(err, http.StatusNotFound) {
+			return nil, store.ErrNotFound
+		} else if err != nil {
+			return nil, err
+		}
+
+		var d document
+		if err := json.Unmarshal(rsp.Value, &d); err != nil {
+			return nil, err
+		}
+		return []*store.Record{d.record(time.Now())}, nil
+	}
+
+	items, err := s.query(c, pk, "*", key, options.Prefix, options.Suffix, options.Offset, options.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	results := make([]*store.Record, 0, len(items))
+	for _, item := range items {
+		var d document
+		if err := json.Unmarshal(item, &d); err != nil {
+			return nil, err
+		}
+		results = append(results, d.record(now))
+	}
+
+	return results, nil
+}
+
+// query selects the items of the partition whose key has the prefix and or
+// suffix, ordered by key.
+func (s *cosmosStore) query(c *azcosmos.ContainerClient, pk azcosmos.PartitionKey, sel, key string, prefix, suffix bool, offset, limit uint) ([][]byte, error) {
+	var where []string
+	var params []azcosmos.QueryParameter
+
+	if prefix {
+		where = append(where, "STARTSWITH(c.key, @prefix)")
+		params = append(params, azcosmos.QueryParameter{Name: "@prefix", Value: key})
+	}
+	if suffix {
+		where = append(where, "ENDSWITH(c.key, @suffix)")
+		params = append(params, azcosmos.QueryParameter{Name: "@suffix", Value: key})
+	}
+
+	q := "SELECT " + sel + " FROM c"
+	if len(where) > 0 {
+		q += " WHERE " + strings.Join(where, " AND ")
+	}
+	q += " ORDER BY c.key"
+	if limit > 0 {
+		q += " OFFSET @offset LIMIT @limit"
+		params = append(params,
+			azcosmos.QueryParameter{Name: "@offset", Value: offset},
+			azcosmos.QueryParameter{Name: "@limit", Value: limit},
+		)
+	}
+
+	pager := c.NewQueryItemsPager(q, pk, &azcosmos.QueryOptions{QueryParameters: params})
+
+	var items [][]byte
+	for pager.More() {
+		page, err := pager.NextPage(s.ctx)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page.Items...)
+	}
+
+	// without a limit the offset is applied here, OFFSET requires LIMIT
+	if limit == 0 {
+		start, end := records.Bounds(len(items), offset, 0)
+		items = items[start:end]
+	}
+
+	return items, nil
+}
+
+func (s *cosmosStore) Write(r *store.Record, opts ...store.WriteOption) error {
+	var options store.WriteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	c, err := s.getContainer(options.Database)
+	if err != nil {
+		return err
+	}
+	table := s.table(options.Table)
+
+	d := document{
+		ID:       itemID(r.Key),
+		Key:      r.Key,
+		Value:    r.Value,
+		Metadata: r.Metadata,
+	}
+
+	if expiry := records.TTL(r.Expiry, options); expiry > 0 {
+		ttl := int64((expiry + time.Second - 1) / time.Second)
+		d.TTL = &ttl
+	}
+
+	b, err := s.marshal(d, table)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.UpsertItem(s.ctx, azcosmos.NewPartitionKeyString(table), b, nil)
+	return err
+}
+
+// marshal encodes the document with the table in the partition key field.
+func (s *cosmosStore) marshal(d document, table string) ([]byte, error) {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	if m[s.partitionKey], err = json.Marshal(table); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(m)
+}
+
+func (s *cosmosStore) Delete(key string, opts ...store.DeleteOption) error {
+	var options store.DeleteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	c, err := s.getContainer(options.Database)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.DeleteItem(s.ctx, azcosmos.NewPartitionKeyString(s.table(options.Table)), itemID(key), nil)
+	if hasStatus(err, http.StatusNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (s *cosmosStore) List(opts ...store.ListOption) ([]string, error) {
+	var options store.ListOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	c, err := s.getContainer(options.Database)
+	if err != nil {
+		return nil, err
+	}
+	pk := azcosmos.NewPartitionKeyString(s.table(options.Table))
+
+	var items [][]byte
+	switch {
+	case len(options.Prefix) > 0 && len(options.Suffix) > 0:
+		// filter the suffix here, the query takes one key
+		items, err = s.query(c, pk, "c.key", options.Prefix, true, false, 0, 0)
+	case len(options.Suffix) > 0:
+		items, err = s.query(c, pk, "c.key", options.Suffix, false, true, options.Offset, options.Limit)
+	default:
+		items, err = s.query(c, pk, "c.key", options.Prefix, len(options.Prefix) > 0, false, options.Offset, options.Limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the client only decodes objects, so the keys aren't selected as values
+	keys := make([]string, 0, len(items))
+	for _, item := range items {
+		var d struct {
+			Key string `json:"key"`
+		}
+		if err := json.Unmarshal(item, &d); err != nil {
+			return nil, err
+		}
+		key := d.Key
+		if len(options.Prefix) > 0 && len(options.Suffix) > 0 && !strings.HasSuffix(key, options.Suffix) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	if len(options.Prefix) > 0 && len(options.Suffix) > 0 {
+		start, end := records.Bounds(len(keys), options.Offset, options.Limit)
+		keys = keys[start:end]
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,416 @@
+package cosmosdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/store"
+)
+
+func TestMarshal(t *testing.T) {
+	s := &cosmosStore{partitionKey: "namespace"}
+
+	b, err := s.marshal(document{ID: itemID("user/1"), Key: "user/1", Value: []byte("1")}, "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["namespace"] != "users" {
+		t.Errorf("Expected the table in the partition key, got %v", m["namespace"])
+	}
+	if m["id"] != "user%2F1" {
+		t.Errorf("Expected an escaped id, got %v", m["id"])
+	}
+	if _, ok := m["ttl"]; ok {
+		t.Error("Expected no ttl")
+	}
+}
+
+func TestRecord(t *testing.T) {
+	now := time.Now()
+	ttl := int64(60)
+
+	r := (&document{Key: "foo", TTL: &ttl, TS: now.Unix()}).record(now)
+	if r.Expiry <= 0 || r.Expiry > time.Minute {
+		t.Errorf("Expected an expiry within a minute, got %v", r.Expiry)
+	}
+	if r.Metadata == nil {
+		t.Error("Expected metadata")
+	}
+
+	if r := (&document{Key: "foo"}).record(now); r.Expiry != 0 {
+		t.Errorf("Expected no expiry, got %v", r.Expiry)
+	}
+}
+
+// cosmosServer is the part of the Cosmos DB REST API used by the store. It
+// evaluates the queries of the store and returns at most pageSize items per
+// page.
+type cosmosServer struct {
+	pageSize int
+
+	sync.Mutex
+	databases  map[string]bool
+	containers map[string]map[string]interface{}
+	// items by container, partition key and id
+	items   map[string]map[string]map[string]map[string]interface{}
+	queries []string
+	creates int
+}
+
+func newCosmosServer(t *testing.T, pageSize int) (*cosmosServer, *httptest.Server) {
+	c := &cosmosServer{
+		pageSize:   pageSize,
+		databases:  make(map[string]bool),
+		containers: make(map[string]map[string]interface{}),
+		items:      make(map[string]map[string]map[string]map[string]interface{}),
+	}
+	ts := httptest.NewServer(c)
+	t.Cleanup(ts.Close)
+	return c, ts
+}
+
+func cosmosError(w http.ResponseWriter, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"code":%q,"message":"%d"}`, http.StatusText(status), status)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (c *cosmosServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.Lock()
+	defer c.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	// /dbs
+	case len(parts) == 1 && r.Method == http.MethodPost:
+		var db map[string]interface{}
+		json.Unmarshal(body, &db)
+		id := db["id"].(string)
+		if c.databases[id] {
+			cosmosError(w, http.StatusConflict)
+			return
+		}
+		c.databases[id] = true
+		writeJSON(w, http.StatusCreated, db)
+	// /dbs/<db>/colls
+	case len(parts) == 3 && r.Method == http.MethodPost:
+		var props map[string]interface{}
+		json.Unmarshal(body, &props)
+		name := parts[1] + "/" + props["id"].(string)
+		c.creates++
+		if _, ok := c.containers[name]; ok {
+			cosmosError(w, http.StatusConflict)
+			return
+		}
+		c.containers[name] = props
+		c.items[name] = make(map[string]map[string]map[string]interface{})
+		writeJSON(w, http.StatusCreated, props)
+	// /dbs/<db>/colls/<coll>/docs[/<id>]
+	case len(parts) >= 5:
+		c.docs(w, r, parts[1]+"/"+parts[3], parts[5:], body)
+	default:
+		cosmosError(w, http.StatusNotImplemented)
+	}
+}
+
+func (c *cosmosServer) docs(w http.ResponseWriter, r *http.Request, container string, id []string, body []byte) {
+	partitions, ok := c.items[container]
+	if !ok {
+		cosmosError(w, http.StatusNotFound)
+		return
+	}
+
+	var pk []string
+	if err := json.Unmarshal([]byte(r.Header.Get("x-ms-documentdb-partitionkey")), &pk); err != nil || len(pk) != 1 {
+		cosmosError(w, http.StatusBadRequest)
+		return
+	}
+	items := partitions[pk[0]]
+	if items == nil {
+		items = make(map[string]map[string]interface{})
+		partitions[pk[0]] = items
+	}
+
+	switch {
+	case len(id) == 1 && r.Method == http.MethodGet:
+		item, ok := items[id[0]]
+		if !ok {
+			cosmosError(w, http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, item)
+	case len(id) == 1 && r.Method == http.MethodDelete:
+		if _, ok := items[id[0]]; !ok {
+			cosmosError(w, http.StatusNotFound)
+			return
+		}
+		delete(items, id[0])
+		w.WriteHeader(http.StatusNoContent)
+	case r.Header.Get("x-ms-documentdb-isquery") != "" || r.Header.Get("Content-Type") == "application/query+json":
+		c.query(w, r, items, body)
+	case r.Method == http.MethodPost:
+		var item map[string]interface{}
+		json.Unmarshal(body, &item)
+
+		// the partition key field has to match the partition written to
+		path := c.containers[container]["partitionKey"].(map[string]interface{})["paths"].([]interface{})[0].(string)
+		if item[strings.TrimPrefix(path, "/")] != pk[0] {
+			cosmosError(w, http.StatusBadRequest)
+			return
+		}
+
+		item["_ts"] = time.Now().Unix()
+		items[item["id"].(string)] = item
+		writeJSON(w, http.StatusCreated, item)
+	default:
+		cosmosError(w, http.StatusNotImplemented)
+	}
+}
+
+func (c *cosmosServer) query(w http.ResponseWriter, r *http.Request, items map[string]map[string]interface{}, body []byte) {
+	var q struct {
+		Query      string `json:"query"`
+		Parameters []struct {
+			Name  string          `json:"name"`
+			Value json.RawMessage `json:"value"`
+		} `json:"parameters"`
+	}
+	json.Unmarshal(body, &q)
+	c.queries = append(c.queries, q.Query)
+
+	params := make(map[string]string)
+	for _, p := range q.Parameters {
+		params[p.Name] = strings.Trim(string(p.Value), `"`)
+	}
+
+	var keys []string
+	for _, item := range items {
+		key := item["key"].(string)
+		if p, ok := params["@prefix"]; ok && !strings.HasPrefix(key, p) {
+			continue
+		}
+		if s, ok := params["@suffix"]; ok && !strings.HasSuffix(key, s) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if l, ok := params["@limit"]; ok {
+		offset, _ := strconv.Atoi(params["@offset"])
+		limit, _ := strconv.Atoi(l)
+		if offset > len(keys) {
+			offset = len(keys)
+		}
+		keys = keys[offset:]
+		if limit < len(keys) {
+			keys = keys[:limit]
+		}
+	}
+
+	// the continuation token is the index of the next result
+	start, _ := strconv.Atoi(r.Header.Get("x-ms-continuation"))
+	end := start + c.pageSize
+	if end < len(keys) {
+		w.Header().Set("x-ms-continuation", strconv.Itoa(end))
+	} else {
+		end = len(keys)
+	}
+
+	docs := []interface{}{}
+	for _, key := range keys[start:end] {
+		if strings.HasPrefix(q.Query, "SELECT c.key ") {
+			docs = append(docs, map[string]interface{}{"key": key})
+		} else {
+			docs = append(docs, items[itemID(key)])
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"Documents": docs, "_count": len(docs)})
+}
+
+func newTestStore(t *testing.T, pageSize int, opts ...store.Option) (*cosmosServer, *cosmosStore) {
+	c, ts := newCosmosServer(t, pageSize)
+	cs := fmt.Sprintf("AccountEndpoint=%s/;AccountKey=a2V5;", ts.URL)
+	return c, NewStore(append([]store.Option{ConnectionString(cs)}, opts...)...).(*cosmosStore)
+}
+
+func TestPartitionKey(t *testing.T) {
+	c, s := newTestStore(t, 10, store.Database("accounts"), store.Table("users"), PartitionKeyPath("/tenant"))
+
+	if err := s.Write(&store.Record{Key: "1", Value: []byte("alice")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(&store.Record{Key: "1", Value: []byte("order")}, store.WriteTo("", "orders")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the container is created once, partitioned by the path, with per
+	// item TTLs enabled
+	props := c.containers["accounts/micro"]
+	if props == nil {
+		t.Fatal("Expected the micro container in the accounts database")
+	}
+	paths := props["partitionKey"].(map[string]interface{})["paths"].([]interface{})
+	if len(paths) != 1 || paths[0] != "/tenant" {
+		t.Errorf("Expected the container to be partitioned by /tenant, got %v", paths)
+	}
+	if props["defaultTtl"] != float64(-1) {
+		t.Errorf("Expected per item TTLs to be enabled, got %v", props["defaultTtl"])
+	}
+	if c.creates != 1 {
+		t.Errorf("Expected the container to be created once, got %d", c.creates)
+	}
+
+	// every table is a partition
+	if c.items["accounts/micro"]["users"]["1"]["tenant"] != "users" {
+		t.Errorf("Expected the record in the users partition, got %v", c.items["accounts/micro"]["users"])
+	}
+	if c.items["accounts/micro"]["orders"]["1"]["tenant"] != "orders" {
+		t.Errorf("Expected the record in the orders partition, got %v", c.items["accounts/micro"]["orders"])
+	}
+
+	records, err := s.Read("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(records[0].Value) != "alice" {
+		t.Errorf("Expected the record of the users table, got %s", records[0].Value)
+	}
+
+	if err := s.Delete("1", store.DeleteFrom("", "orders")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Read("1", store.ReadFrom("", "orders")); err != store.ErrNotFound {
+		t.Errorf("Expected store.ErrNotFound, got %v", err)
+	}
+	if _, err := s.Read("1"); err != nil {
+		t.Errorf("Expected the users record to be kept, got %v", err)
+	}
+	if err := s.Delete("1", store.DeleteFrom("", "orders")); err != nil {
+		t.Errorf("Expected deleting a missing record to succeed, got %v", err)
+	}
+
+	// databases and containers created by others are used as is
+	cs := getString(s.options, connectionStringKey{}, "")
+	s = NewStore(ConnectionString(cs), store.Database("accounts"), store.Table("users"), PartitionKeyPath("/tenant")).(*cosmosStore)
+	if _, err := s.Read("1"); err != nil {
+		t.Errorf("Expected reading from an existing container to succeed, got %v", err)
+	}
+}
+
+func TestQueryContinuation(t *testing.T) {
+	c, s := newTestStore(t, 2, store.Table("users"))
+
+	for i := 0; i < 7; i++ {
+		if err := s.Write(&store.Record{Key: fmt.Sprintf("user/%d", i), Value: []byte(strconv.Itoa(i))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Write(&store.Record{Key: "group/1", Value: []byte("group")}); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := s.Read("user/", store.ReadPrefix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 7 {
+		t.Fatalf("Expected the records of every page, got %d", len(records))
+	}
+	for i, r := range records {
+		if r.Key != fmt.Sprintf("user/%d", i) {
+			t.Errorf("Expected the records ordered by key, got %s at %d", r.Key, i)
+		}
+	}
+
+	// limits are queried, OFFSET needs a LIMIT
+	keys, err := s.List(store.ListPrefix("user/"), store.ListOffset(2), store.ListLimit(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(keys, ",") != "user/2,user/3,user/4" {
+		t.Errorf("Unexpected keys %v", keys)
+	}
+	if q := c.queries[len(c.queries)-1]; !strings.Contains(q, "OFFSET @offset LIMIT @limit") {
+		t.Errorf("Expected the limit to be queried, got %s", q)
+	}
+
+	// offsets without a limit are applied to the pages
+	keys, err = s.List(store.ListPrefix("user/"), store.ListOffset(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(keys, ",") != "user/5,user/6" {
+		t.Errorf("Unexpected keys %v", keys)
+	}
+	if q := c.queries[len(c.queries)-1]; strings.Contains(q, "OFFSET") {
+		t.Errorf("Expected no OFFSET without a limit, got %s", q)
+	}
+
+	// prefixes and suffixes together filter the suffix client side
+	keys, err = s.List(store.ListPrefix("user/"), store.ListSuffix("1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(keys, ",") != "user/1" {
+		t.Errorf("Unexpected keys %v", keys)
+	}
+
+	keys, err = s.List(store.ListSuffix("/1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(keys, ",") != "group/1,user/1" {
+		t.Errorf("Unexpected keys %v", keys)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	c, s := newTestStore(t, 10)
+
+	if err := s.Write(&store.Record{Key: "tmp", Value: []byte("tmp")}, store.WriteTTL(1500*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(&store.Record{Key: "keep", Value: []byte("keep")}); err != nil {
+		t.Fatal(err)
+	}
+
+	items := c.items["micro/micro"][""]
+	// TTLs are whole seconds, rounded up
+	if items["tmp"]["ttl"] != float64(2) {
+		t.Errorf("Expected a ttl of 2 seconds, got %v", items["tmp"]["ttl"])
+	}
+	if _, ok := items["keep"]["ttl"]; ok {
+		t.Error("Expected no ttl")
+	}
+
+	records, err := s.Read("tmp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if records[0].Expiry <= 0 || records[0].Expiry > 2*time.Second {
+		t.Errorf("Expected the record to expire within 2 seconds, got %v", records[0].Expiry)
+	}
+}
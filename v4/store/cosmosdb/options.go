@@ -0,0 +1,77 @@
+package cosmosdb
+
+import (
+	"context"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"go-micro.dev/v4/store"
+)
+
+type connectionStringKey struct{}
+type keyKey struct{}
+type containerKey struct{}
+type partitionKeyPathKey struct{}
+type managedIdentityKey struct{}
+type credentialKey struct{}
+
+// DefaultContainer is the container the records are stored in.
+var DefaultContainer = "micro"
+
+// DefaultPartitionKeyPath is the document field holding the partition key.
+var DefaultPartitionKeyPath = "/namespace"
+
+// ConnectionString authenticates with the connection string of the account
+// instead of the endpoint passed as node.
+func ConnectionString(s string) store.Option {
+	return setStoreOption(connectionStringKey{}, s)
+}
+
+// Key authenticates with the primary or secondary key of the account.
+func Key(key string) store.Option {
+	return setStoreOption(keyKey{}, key)
+}
+
+// Container sets the container of every database the records are stored
+// in. Defaults to DefaultContainer.
+func Container(name string) store.Option {
+	return setStoreOption(containerKey{}, name)
+}
+
+// PartitionKeyPath sets the partition key path of the containers the store
+// creates, the table of a record is written to the field. Defaults to
+// DefaultPartitionKeyPath.
+func PartitionKeyPath(path string) store.Option {
+	return setStoreOption(partitionKeyPathKey{}, path)
+}
+
+// ManagedIdentity authenticates with the managed identity of the host. The
+// client ID selects a user-assigned identity, leave it empty for the
+// system-assigned one.
+func ManagedIdentity(clientID string) store.Option {
+	return setStoreOption(managedIdentityKey{}, clientID)
+}
+
+// Credential authenticates with the token credential. Without any of the
+// auth options the default Azure credential chain is used, which includes
+// managed identities.
+func Credential(c azcore.TokenCredential) store.Option {
+	return setStoreOption(credentialKey{}, c)
+}
+
+func setStoreOption(k, v interface{}) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+func getString(o store.Options, k interface{}, def string) string {
+	if o.Context != nil {
+		if v, ok := o.Context.Value(k).(string); ok && len(v) > 0 {
+			return v
+		}
+	}
+	return def
+}
@@ -0,0 +1,114 @@
+package dynamodb
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/pkg/errors"
+	"go-micro.dev/v4/store"
+	"go-micro.dev/v4/util/backoff"
+)
+
+// DefaultBatchRetries is the number of times unprocessed items of a batch
+// write are retried.
+var DefaultBatchRetries = 5
+
+// maxBatchSize is the most items BatchWriteItem takes.
+const maxBatchSize = 25
+
+// WriteMany writes the records with BatchWriteItem, in batches of 25.
+// Of records with the same key the last one is written. Batches aren't
+// atomic, if WriteMany fails some records may have been written.
+func WriteMany(s store.Store, records []*store.Record, opts ...store.WriteOption) error {
+	ds, ok := s.(*dynamoStore)
+	if !ok {
+		return errors.Errorf("%s is not a dynamodb store", s.String())
+	}
+
+	var options store.WriteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	name, table := ds.names(options.Database, options.Table)
+	if err := ds.createTable(name); err != nil {
+		return err
+	}
+
+	for _, batch := range chunk(dedupe(records), maxBatchSize) {
+		requests := make([]*dynamodb.WriteRequest, 0, len(batch))
+		for _, r := range batch {
+			av, err := dynamodbattribute.MarshalMap(newItem(table, r, options))
+			if err != nil {
+				return err
+			}
+			requests = append(requests, &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: av}})
+		}
+
+		if err := ds.batchWrite(name, requests); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// batchWrite writes the requests, retrying unprocessed items with backoff
+// as they're returned when the table is throttled.
+func (s *dynamoStore) batchWrite(name string, requests []*dynamodb.WriteRequest) error {
+	items := map[string][]*dynamodb.WriteRequest{name: requests}
+
+	for i := 0; ; i++ {
+		rsp, err := s.client.BatchWriteItemWithContext(s.ctx, &dynamodb.BatchWriteItemInput{RequestItems: items})
+		if err != nil {
+			return err
+		}
+
+		items = rsp.UnprocessedItems
+		if len(items[name]) == 0 {
+			return nil
+		}
+		if i >= DefaultBatchRetries {
+			return errors.Errorf("%d items unprocessed after %d retries", len(items[name]), i)
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return s.ctx.Err()
+		case <-time.After(backoff.Do(i + 1)):
+		}
+	}
+}
+
+// dedupe keeps the last of the records with the same key, as a batch can't
+// write an item twice.
+func dedupe(records []*store.Record) []*store.Record {
+	last := make(map[string]int, len(records))
+	for i, r := range records {
+		last[r.Key] = i
+	}
+	if len(last) == len(records) {
+		return records
+	}
+
+	deduped := make([]*store.Record, 0, len(last))
+	for i, r := range records {
+		if last[r.Key] == i {
+			deduped = append(deduped, r)
+		}
+	}
+	return deduped
+}
+
+func chunk(records []*store.Record, n int) [][]*store.Record {
+	var chunks [][]*store.Record
+	for len(records) > 0 {
+		if n > len(records) {
+			n = len(records)
+		}
+		chunks = append(chunks, records[:n])
+		records = records[n:]
+	}
+	return chunks
+}
@@ -0,0 +1,441 @@
+// Package dynamodb implements the store on Amazon DynamoDB.
+package dynamodb
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	awscreds "github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/go-micro/plugins/v4/util/records"
+	"github.com/pkg/errors"
+	log "go-micro.dev/v4/logger"
+	"go-micro.dev/v4/store"
+	"go-micro.dev/v4/util/cmd"
+)
+
+var (
+	// DefaultDatabase is the table used if no database is provided.
+	DefaultDatabase = "micro"
+	// DefaultTable is the partition used if no table is provided.
+	DefaultTable = "micro"
+)
+
+const (
+	// attributes of the items
+	tableAttribute  = "table"
+	keyAttribute    = "key"
+	expiryAttribute = "expireAt"
+)
+
+// table names are letters, numbers, underscores, hyphens and dots
+var tableRe = regexp.MustCompile("[^a-zA-Z0-9_.-]+")
+
+// item is a record as stored in DynamoDB. Items are partitioned by the table
+// of the record and sorted by key.
+type item struct {
+	Table    string                 `dynamodbav:"table"`
+	Key      string                 `dynamodbav:"key"`
+	Value    []byte                 `dynamodbav:"value"`
+	Metadata map[string]interface{} `dynamodbav:"metadata,omitempty"`
+	ExpireAt int64                  `dynamodbav:"expireAt,omitempty"`
+}
+
+type dynamoStore struct {
+	ctx     context.Context
+	options store.Options
+	client  *dynamodb.DynamoDB
+
+	sync.RWMutex
+	// tables known to exist
+	tables map[string]bool
+}
+
+func init() {
+	cmd.DefaultStores["dynamodb"] = NewStore
+}
+
+// NewStore returns a new store backed by DynamoDB. The node is the endpoint,
+// only needed for DynamoDB Local, e.g. http://localhost:8000. Databases are
+// tables, created on the first write, and tables partitions of them.
+func NewStore(opts ...store.Option) store.Store {
+	options := store.Options{
+		Database: DefaultDatabase,
+		Table:    DefaultTable,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	s := &dynamoStore{
+		ctx:     context.Background(),
+		options: options,
+		tables:  make(map[string]bool),
+	}
+
+	if err := s.configure(); err != nil {
+		log.Fatal(err)
+	}
+
+	return s
+}
+
+func (s *dynamoStore) configure() error {
+	sess, err := session.NewSession(newConfig(s.options))
+	if err != nil {
+		return errors.Wrap(err, "creating dynamodb session")
+	}
+
+	s.Lock()
+	s.client = dynamodb.New(sess)
+	s.tables = make(map[string]bool)
+	s.Unlock()
+
+	return nil
+}
+
+func newConfig(o store.Options) *aws.Config {
+	cfg := aws.NewConfig()
+	if len(o.Nodes) > 0 {
+		cfg = cfg.WithEndpoint(o.Nodes[0])
+	}
+	if o.Context == nil {
+		return cfg
+	}
+
+	if r, ok := o.Context.Value(regionKey{}).(string); ok {
+		cfg = cfg.WithRegion(r)
+	}
+	if c, ok := o.Context.Value(credentialsKey{}).(credentials); ok {
+		cfg = cfg.WithCredentials(awscreds.NewStaticCredentials(c.id, c.secret, c.token))
+	}
+	return cfg
+}
+
+func (s *dynamoStore) Init(opts ...store.Option) error {
+	for _, o := range opts {
+		o(&s.options)
+	}
+	return s.configure()
+}
+
+func (s *dynamoStore) Options() store.Options {
+	return s.options
+}
+
+func (s *dynamoStore) Close() error {
+	return nil
+}
+
+func (s *dynamoStore) String() string {
+	return "dynamodb"
+}
+
+func (s *dynamoStore) names(database, table string) (string, string) {
+	database, table = records.Names(s.options, database, table)
+	if len(table) == 0 {
+		// key attributes can't be empty
+		table = DefaultTable
+	}
+	return tableRe.ReplaceAllString(database, "_"), table
+}
+
+func hasCode(err error, codes ...string) bool {
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		return false
+	}
+	for _, c := range codes {
+		if aerr.Code() == c {
+			return true
+		}
+	}
+	return false
+}
+
+// createTable creates the table with TTL enabled on the first write to it.
+func (s *dynamoStore) createTable(name string) error {
+	s.RLock()
+	ok := s.tables[name]
+	s.RUnlock()
+	if ok {
+		return nil
+	}
+
+	_, err := s.client.DescribeTableWithContext(s.ctx, &dynamodb.DescribeTableInput{TableName: aws.String(name)})
+	if hasCode(err, dynamodb.ErrCodeResourceNotFoundException) {
+		if err := s.create(name); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	s.Lock()
+	s.tables[name] = true
+	s.Unlock()
+	return nil
+}
+
+func (s *dynamoStore) create(name string) error {
+	in := &dynamodb.CreateTableInput{
+		TableName: aws.String(name),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String(tableAttribute), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+			{AttributeName: aws.String(keyAttribute), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String(tableAttribute), KeyType: aws.String(dynamodb.KeyTypeHash)},
+			{AttributeName: aws.String(keyAttribute), KeyType: aws.String(dynamodb.KeyTypeRange)},
+		},
+		BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+	}
+	if p, ok := getProvisioned(s.options); ok {
+		in.BillingMode = aws.String(dynamodb.BillingModeProvisioned)
+		in.ProvisionedThroughput = &dynamodb.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(p.read),
+			WriteCapacityUnits: aws.Int64(p.write),
+		}
+	}
+
+	_, err := s.client.CreateTableWithContext(s.ctx, in)
+	if err != nil && !hasCode(err, dynamodb.ErrCodeResourceInUseException) {
+		return errors.Wrap(err, "creating table")
+	}
+
+	if err := s.client.WaitUntilTableExistsWithContext(s.ctx, &dynamodb.DescribeTableInput{TableName: aws.String(name)}); err != nil {
+		return errors.Wrap(err, "waiting for table")
+	}
+
+	_, err = s.client.UpdateTimeToLiveWithContext(s.ctx, &dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String(name),
+		TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
+			AttributeName: aws.String(expiryAttribute),
+			Enabled:       aws.Bool(true),
+		},
+	})
+	// enabling it again fails validation
+	if err != nil && !hasCode(err, "ValidationException") {
+		return errors.Wrap(err, "enabling ttl")
+	}
+	return nil
+}
+
+func (i *item) expired(now time.Time) bool {
+	return i.ExpireAt > 0 && i.ExpireAt <= now.Unix()
+}
+
+func (i *item) record(now time.Time) *store.Record {
+	r := &store.Record{
+		Key:      i.Key,
+		Value:    i.Value,
+		Metadata: i.Metadata,
+	}
+	if r.Metadata == nil {
+		r.Metadata = make(map[string]interface{})
+	}
+	if i.ExpireAt > 0 {
+		r.Expiry = time.Unix(i.ExpireAt, 0).Sub(now)
+	}
+	return r
+}
+
+// newItem returns the item of the record written with the options.
+func newItem(table string, r *store.Record, options store.WriteOptions) *item {
+	i := &item{
+		Table:    table,
+		Key:      r.Key,
+		Value:    r.Value,
+		Metadata: r.Metadata,
+	}
+
+	if expiry := records.TTL(r.Expiry, options); expiry > 0 {
+		i.ExpireAt = time.Now().Add(expiry).Unix()
+	}
+	return i
+}
+
+func (s *dynamoStore) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	var options store.ReadOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	name, table := s.names(options.Database, options.Table)
+	now := time.Now()
+
+	if !options.Prefix && !options.Suffix {
+		rsp, err := s.client.GetItemWithContext(s.ctx, &dynamodb.GetItemInput{
+			TableName:      aws.String(name),
+			Key:            itemKey(table, key),
+			ConsistentRead: aws.Bool(getConsistentRead(s.options)),
+		})
+		if hasCode(err, dynamodb.ErrCodeResourceNotFoundException) {
+			return nil, store.ErrNotFound
+		} else if err != nil {
+			return nil, err
+		}
+
+		var i item
+		if len(rsp.Item) == 0 {
+			return nil, store.ErrNotFound
+		}
+		if err := dynamodbattribute.UnmarshalMap(rsp.Item, &i); err != nil {
+			return nil, err
+		}
+		if i.expired(now) {
+			return nil, store.ErrNotFound
+		}
+		return []*store.Record{i.record(now)}, nil
+	}
+
+	prefix := ""
+	if options.Prefix {
+		prefix = key
+	}
+
+	items, err := s.query(name, table, prefix, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*store.Record
+	for _, i := range items {
+		if options.Suffix && !strings.HasSuffix(i.Key, key) {
+			continue
+		}
+		results = append(results, i.record(now))
+	}
+
+	start, end := records.Bounds(len(results), options.Offset, options.Limit)
+	return results[start:end], nil
+}
+
+func itemKey(table, key string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		tableAttribute: {S: aws.String(table)},
+		keyAttribute:   {S: aws.String(key)},
+	}
+}
+
+// query returns the unexpired items of the table whose key has the prefix,
+// in key order. Keys only returns the keys of the items.
+func (s *dynamoStore) query(name, table, prefix string, keys bool) ([]*item, error) {
+	cond := expression.Key(tableAttribute).Equal(expression.Value(table))
+	if len(prefix) > 0 {
+		cond = cond.And(expression.Key(keyAttribute).BeginsWith(prefix))
+	}
+	filter := expression.AttributeNotExists(expression.Name(expiryAttribute)).
+		Or(expression.Name(expiryAttribute).GreaterThan(expression.Value(time.Now().Unix())))
+
+	b := expression.NewBuilder().WithKeyCondition(cond).WithFilter(filter)
+	if keys {
+		b = b.WithProjection(expression.NamesList(expression.Name(keyAttribute)))
+	}
+	expr, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var items []*item
+	var uerr error
+	err = s.client.QueryPagesWithContext(s.ctx, &dynamodb.QueryInput{
+		TableName:                 aws.String(name),
+		KeyConditionExpression:    expr.KeyCondition(),
+		FilterExpression:          expr.Filter(),
+		ProjectionExpression:      expr.Projection(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ConsistentRead:            aws.Bool(getConsistentRead(s.options)),
+	}, func(page *dynamodb.QueryOutput, last bool) bool {
+		for _, av := range page.Items {
+			i := &item{}
+			if uerr = dynamodbattribute.UnmarshalMap(av, i); uerr != nil {
+				return false
+			}
+			items = append(items, i)
+		}
+		return true
+	})
+	if hasCode(err, dynamodb.ErrCodeResourceNotFoundException) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return items, uerr
+}
+
+func (s *dynamoStore) Write(r *store.Record, opts ...store.WriteOption) error {
+	var options store.WriteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	name, table := s.names(options.Database, options.Table)
+	if err := s.createTable(name); err != nil {
+		return err
+	}
+
+	av, err := dynamodbattribute.MarshalMap(newItem(table, r, options))
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.PutItemWithContext(s.ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(name),
+		Item:      av,
+	})
+	return err
+}
+
+func (s *dynamoStore) Delete(key string, opts ...store.DeleteOption) error {
+	var options store.DeleteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	name, table := s.names(options.Database, options.Table)
+
+	_, err := s.client.DeleteItemWithContext(s.ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(name),
+		Key:       itemKey(table, key),
+	})
+	if hasCode(err, dynamodb.ErrCodeResourceNotFoundException) {
+		return nil
+	}
+	return err
+}
+
+func (s *dynamoStore) List(opts ...store.ListOption) ([]string, error) {
+	var options store.ListOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	name, table := s.names(options.Database, options.Table)
+
+	items, err := s.query(name, table, options.Prefix, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, i := range items {
+		if len(options.Suffix) > 0 && !strings.HasSuffix(i.Key, options.Suffix) {
+			continue
+		}
+		keys = append(keys, i.Key)
+	}
+
+	start, end := records.Bounds(len(keys), options.Offset, options.Limit)
+	return keys[start:end], nil
+}
@@ -0,0 +1,603 @@
+package dynamodb
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"go-micro.dev/v4/store"
+)
+
+func TestNames(t *testing.T) {
+	s := &dynamoStore{options: store.Options{Database: "my service", Table: "users"}}
+
+	name, table := s.names("", "")
+	if name != "my_service" || table != "users" {
+		t.Errorf("Expected my_service and users, got %s and %s", name, table)
+	}
+
+	s.options.Table = ""
+	if _, table := s.names("db", ""); table != DefaultTable {
+		t.Errorf("Expected %s, got %s", DefaultTable, table)
+	}
+}
+
+func TestNewItem(t *testing.T) {
+	r := &store.Record{Key: "foo", Value: []byte("bar")}
+
+	if i := newItem("users", r, store.WriteOptions{}); i.ExpireAt != 0 {
+		t.Errorf("Expected no expiry, got %d", i.ExpireAt)
+	}
+
+	i := newItem("users", r, store.WriteOptions{TTL: time.Hour})
+	now := time.Now()
+	if i.ExpireAt < now.Add(59*time.Minute).Unix() || i.ExpireAt > now.Add(time.Hour).Unix() {
+		t.Errorf("Expected an expiry in an hour, got %d", i.ExpireAt)
+	}
+	if i.expired(now) || !i.expired(now.Add(2*time.Hour)) {
+		t.Error("Expected the item to expire after an hour")
+	}
+}
+
+func TestBatches(t *testing.T) {
+	var records []*store.Record
+	for i := 0; i < 60; i++ {
+		records = append(records, &store.Record{Key: strconv.Itoa(i % 55), Value: []byte{byte(i)}})
+	}
+
+	deduped := dedupe(records)
+	if len(deduped) != 55 {
+		t.Fatalf("Expected 55 records, got %d", len(deduped))
+	}
+	for _, r := range deduped {
+		if r.Key == "0" && r.Value[0] != 55 {
+			t.Errorf("Expected the last record of key 0, got %d", r.Value[0])
+		}
+	}
+
+	chunks := chunk(deduped, maxBatchSize)
+	if len(chunks) != 3 || len(chunks[0]) != 25 || len(chunks[2]) != 5 {
+		t.Errorf("Expected batches of 25, 25 and 5, got %d", len(chunks))
+	}
+}
+
+var (
+	equalRe      = regexp.MustCompile(`(#\d+) = (:\d+)`)
+	beginsWithRe = regexp.MustCompile(`begins_with \((#\d+), (:\d+)\)`)
+	greaterRe    = regexp.MustCompile(`(#\d+) > (:\d+)`)
+)
+
+// dynamoServer is the part of the DynamoDB API used by the store. Queries
+// read at most pageSize items per page before filtering, like DynamoDB
+// reads a page before applying the filter expression.
+type dynamoServer struct {
+	pageSize int
+	// unprocessed is the number of batch writes answering the last item as
+	// unprocessed.
+	unprocessed int
+
+	sync.Mutex
+	// items by table, partition and key
+	tables map[string]map[string]map[string]map[string]*dynamodb.AttributeValue
+	ttl    map[string]string
+	// requests by operation
+	requests map[string]int
+	// the query requests
+	queries []*dynamodb.QueryInput
+	// the consistency of the reads
+	consistent []bool
+}
+
+func newDynamoServer(t *testing.T, pageSize int) (*dynamoServer, *httptest.Server) {
+	d := &dynamoServer{
+		pageSize: pageSize,
+		tables:   make(map[string]map[string]map[string]map[string]*dynamodb.AttributeValue),
+		ttl:      make(map[string]string),
+		requests: make(map[string]int),
+	}
+	ts := httptest.NewServer(d)
+	t.Cleanup(ts.Close)
+	return d, ts
+}
+
+func (d *dynamoServer) count(op string) int {
+	d.Lock()
+	defer d.Unlock()
+	return d.requests[op]
+}
+
+func (d *dynamoServer) get(table, partition, key string) (map[string]*dynamodb.AttributeValue, bool) {
+	d.Lock()
+	defer d.Unlock()
+	i, ok := d.tables[table][partition][key]
+	return i, ok
+}
+
+func dynamoError(w http.ResponseWriter, code, msg string) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	w.WriteHeader(http.StatusBadRequest)
+	fmt.Fprintf(w, `{"__type":"com.amazonaws.dynamodb.v20120810#%s","message":%q}`, code, msg)
+}
+
+func (d *dynamoServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.Lock()
+	defer d.Unlock()
+
+	op := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "DynamoDB_20120810.")
+	d.requests[op]++
+
+	var out interface{}
+	var err error
+	switch op {
+	case "DescribeTable":
+		var in dynamodb.DescribeTableInput
+		if err = jsonutil.UnmarshalJSON(&in, r.Body); err == nil {
+			out, err = d.describe(&in)
+		}
+	case "CreateTable":
+		var in dynamodb.CreateTableInput
+		if err = jsonutil.UnmarshalJSON(&in, r.Body); err == nil {
+			out, err = d.create(&in)
+		}
+	case "UpdateTimeToLive":
+		var in dynamodb.UpdateTimeToLiveInput
+		if err = jsonutil.UnmarshalJSON(&in, r.Body); err == nil {
+			out, err = d.updateTTL(&in)
+		}
+	case "PutItem":
+		var in dynamodb.PutItemInput
+		if err = jsonutil.UnmarshalJSON(&in, r.Body); err == nil {
+			out, err = d.put(aws.StringValue(in.TableName), in.Item)
+		}
+	case "GetItem":
+		var in dynamodb.GetItemInput
+		if err = jsonutil.UnmarshalJSON(&in, r.Body); err == nil {
+			out, err = d.getItem(&in)
+		}
+	case "DeleteItem":
+		var in dynamodb.DeleteItemInput
+		if err = jsonutil.UnmarshalJSON(&in, r.Body); err == nil {
+			out, err = d.deleteItem(&in)
+		}
+	case "Query":
+		var in dynamodb.QueryInput
+		if err = jsonutil.UnmarshalJSON(&in, r.Body); err == nil {
+			out, err = d.query(&in)
+		}
+	case "BatchWriteItem":
+		var in dynamodb.BatchWriteItemInput
+		if err = jsonutil.UnmarshalJSON(&in, r.Body); err == nil {
+			out, err = d.batchWrite(&in)
+		}
+	default:
+		err = apiError{"UnknownOperationException", op}
+	}
+
+	if e, ok := err.(apiError); ok {
+		dynamoError(w, e.code, e.msg)
+		return
+	} else if err != nil {
+		dynamoError(w, "SerializationException", err.Error())
+		return
+	}
+
+	b, err := jsonutil.BuildJSON(out)
+	if err != nil {
+		dynamoError(w, "InternalServerError", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	w.Write(b)
+}
+
+type apiError struct {
+	code, msg string
+}
+
+func (e apiError) Error() string {
+	return e.code + ": " + e.msg
+}
+
+func notFound(table string) error {
+	return apiError{dynamodb.ErrCodeResourceNotFoundException, "Requested resource not found: Table: " + table + " not found"}
+}
+
+func (d *dynamoServer) describe(in *dynamodb.DescribeTableInput) (interface{}, error) {
+	name := aws.StringValue(in.TableName)
+	if _, ok := d.tables[name]; !ok {
+		return nil, notFound(name)
+	}
+	return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+		TableName:   in.TableName,
+		TableStatus: aws.String(dynamodb.TableStatusActive),
+	}}, nil
+}
+
+func (d *dynamoServer) create(in *dynamodb.CreateTableInput) (interface{}, error) {
+	name := aws.StringValue(in.TableName)
+	if _, ok := d.tables[name]; ok {
+		return nil, apiError{dynamodb.ErrCodeResourceInUseException, "Table already exists: " + name}
+	}
+
+	// the store needs the table as hash and the key as range key
+	var schema []string
+	for _, k := range in.KeySchema {
+		schema = append(schema, aws.StringValue(k.AttributeName)+"="+aws.StringValue(k.KeyType))
+	}
+	if strings.Join(schema, ",") != "table=HASH,key=RANGE" {
+		return nil, apiError{"ValidationException", "unexpected key schema " + strings.Join(schema, ",")}
+	}
+
+	d.tables[name] = make(map[string]map[string]map[string]*dynamodb.AttributeValue)
+	return &dynamodb.CreateTableOutput{TableDescription: &dynamodb.TableDescription{
+		TableName:   in.TableName,
+		TableStatus: aws.String(dynamodb.TableStatusCreating),
+	}}, nil
+}
+
+func (d *dynamoServer) updateTTL(in *dynamodb.UpdateTimeToLiveInput) (interface{}, error) {
+	name := aws.StringValue(in.TableName)
+	if _, ok := d.tables[name]; !ok {
+		return nil, notFound(name)
+	}
+	if _, ok := d.ttl[name]; ok {
+		return nil, apiError{"ValidationException", "TimeToLive is already enabled"}
+	}
+	d.ttl[name] = aws.StringValue(in.TimeToLiveSpecification.AttributeName)
+	return &dynamodb.UpdateTimeToLiveOutput{TimeToLiveSpecification: in.TimeToLiveSpecification}, nil
+}
+
+func (d *dynamoServer) put(name string, item map[string]*dynamodb.AttributeValue) (interface{}, error) {
+	partitions, ok := d.tables[name]
+	if !ok {
+		return nil, notFound(name)
+	}
+	partition := aws.StringValue(item[tableAttribute].S)
+	if partitions[partition] == nil {
+		partitions[partition] = make(map[string]map[string]*dynamodb.AttributeValue)
+	}
+	partitions[partition][aws.StringValue(item[keyAttribute].S)] = item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (d *dynamoServer) getItem(in *dynamodb.GetItemInput) (interface{}, error) {
+	name := aws.StringValue(in.TableName)
+	partitions, ok := d.tables[name]
+	if !ok {
+		return nil, notFound(name)
+	}
+	d.consistent = append(d.consistent, aws.BoolValue(in.ConsistentRead))
+	// expired items are returned until DynamoDB deletes them
+	item := partitions[aws.StringValue(in.Key[tableAttribute].S)][aws.StringValue(in.Key[keyAttribute].S)]
+	return &dynamodb.GetItemOutput{Item: item}, nil
+}
+
+func (d *dynamoServer) deleteItem(in *dynamodb.DeleteItemInput) (interface{}, error) {
+	name := aws.StringValue(in.TableName)
+	partitions, ok := d.tables[name]
+	if !ok {
+		return nil, notFound(name)
+	}
+	delete(partitions[aws.StringValue(in.Key[tableAttribute].S)], aws.StringValue(in.Key[keyAttribute].S))
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+// query evaluates the key condition and filter expressions the store
+// builds: the partition, a key prefix and the expiry.
+func (d *dynamoServer) query(in *dynamodb.QueryInput) (interface{}, error) {
+	name := aws.StringValue(in.TableName)
+	partitions, ok := d.tables[name]
+	if !ok {
+		return nil, notFound(name)
+	}
+	d.queries = append(d.queries, in)
+	d.consistent = append(d.consistent, aws.BoolValue(in.ConsistentRead))
+
+	attr := func(placeholder string) string {
+		return aws.StringValue(in.ExpressionAttributeNames[placeholder])
+	}
+	value := func(placeholder string) *dynamodb.AttributeValue {
+		return in.ExpressionAttributeValues[placeholder]
+	}
+
+	m := equalRe.FindStringSubmatch(aws.StringValue(in.KeyConditionExpression))
+	if m == nil || attr(m[1]) != tableAttribute {
+		return nil, apiError{"ValidationException", "Query condition missed key schema element: table"}
+	}
+	items := partitions[aws.StringValue(value(m[2]).S)]
+
+	prefix := ""
+	if m := beginsWithRe.FindStringSubmatch(aws.StringValue(in.KeyConditionExpression)); m != nil && attr(m[1]) == keyAttribute {
+		prefix = aws.StringValue(value(m[2]).S)
+	}
+	var now int64
+	if m := greaterRe.FindStringSubmatch(aws.StringValue(in.FilterExpression)); m != nil && attr(m[1]) == expiryAttribute {
+		now, _ = strconv.ParseInt(aws.StringValue(value(m[2]).N), 10, 64)
+	}
+
+	var keys []string
+	start := ""
+	if in.ExclusiveStartKey != nil {
+		start = aws.StringValue(in.ExclusiveStartKey[keyAttribute].S)
+	}
+	for k := range items {
+		if strings.HasPrefix(k, prefix) && k > start {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	out := &dynamodb.QueryOutput{}
+	if len(keys) > d.pageSize {
+		keys = keys[:d.pageSize]
+		out.LastEvaluatedKey = map[string]*dynamodb.AttributeValue{
+			tableAttribute: value(m[2]),
+			keyAttribute:   {S: aws.String(keys[len(keys)-1])},
+		}
+	}
+	for _, k := range keys {
+		item := items[k]
+		if e, ok := item[expiryAttribute]; ok {
+			if at, _ := strconv.ParseInt(aws.StringValue(e.N), 10, 64); at <= now {
+				continue
+			}
+		}
+		if p := aws.StringValue(in.ProjectionExpression); len(p) > 0 {
+			projected := make(map[string]*dynamodb.AttributeValue)
+			for _, n := range strings.Split(p, ", ") {
+				projected[attr(n)] = item[attr(n)]
+			}
+			item = projected
+		}
+		out.Items = append(out.Items, item)
+	}
+	out.Count = aws.Int64(int64(len(out.Items)))
+	return out, nil
+}
+
+func (d *dynamoServer) batchWrite(in *dynamodb.BatchWriteItemInput) (interface{}, error) {
+	out := &dynamodb.BatchWriteItemOutput{UnprocessedItems: make(map[string][]*dynamodb.WriteRequest)}
+	for name, requests := range in.RequestItems {
+		if len(requests) > maxBatchSize {
+			return nil, apiError{"ValidationException", "Too many items requested for the BatchWriteItem call"}
+		}
+		seen := make(map[string]bool)
+		for _, r := range requests {
+			k := aws.StringValue(r.PutRequest.Item[tableAttribute].S) + "/" + aws.StringValue(r.PutRequest.Item[keyAttribute].S)
+			if seen[k] {
+				return nil, apiError{"ValidationException", "Provided list of item keys contains duplicates"}
+			}
+			seen[k] = true
+		}
+
+		if d.unprocessed > 0 {
+			d.unprocessed--
+			out.UnprocessedItems[name] = requests[len(requests)-1:]
+			requests = requests[:len(requests)-1]
+		}
+		for _, r := range requests {
+			if _, err := d.put(name, r.PutRequest.Item); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return out, nil
+}
+
+func newTestStore(t *testing.T, pageSize int, opts ...store.Option) (*dynamoServer, store.Store) {
+	d, ts := newDynamoServer(t, pageSize)
+	opts = append([]store.Option{
+		store.Nodes(ts.URL),
+		Region("us-east-1"),
+		Credentials("id", "secret", ""),
+	}, opts...)
+	return d, NewStore(opts...)
+}
+
+func TestTableCreation(t *testing.T) {
+	d, s := newTestStore(t, 10, store.Database("my service"))
+
+	// reads of a table which doesn't exist yet find nothing
+	if _, err := s.Read("1"); err != store.ErrNotFound {
+		t.Fatalf("Expected store.ErrNotFound, got %v", err)
+	}
+	if keys, err := s.List(); err != nil || len(keys) != 0 {
+		t.Fatalf("Expected no keys, got %v %v", keys, err)
+	}
+	if err := s.Delete("1"); err != nil {
+		t.Fatalf("Expected deleting from a missing table to succeed, got %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := s.Write(&store.Record{Key: strconv.Itoa(i), Value: []byte("user")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := d.count("CreateTable"); n != 1 {
+		t.Errorf("Expected the table to be created once, got %d requests", n)
+	}
+	d.Lock()
+	a := d.ttl["my_service"]
+	d.Unlock()
+	if a != expiryAttribute {
+		t.Errorf("Expected ttl on %s, got %q", expiryAttribute, a)
+	}
+	if _, ok := d.get("my_service", DefaultTable, "1"); !ok {
+		t.Error("Expected the item in the default partition of my_service")
+	}
+
+	// tables created by others are used as is, even with ttl enabled
+	s = NewStore(store.Nodes(s.Options().Nodes...), store.WithContext(s.Options().Context), store.Database("my service"))
+	if err := s.Write(&store.Record{Key: "3", Value: []byte("user")}); err != nil {
+		t.Fatalf("Expected writing to an existing table to succeed, got %v", err)
+	}
+	if n := d.count("CreateTable"); n != 1 {
+		t.Errorf("Expected the existing table not to be created, got %d requests", n)
+	}
+}
+
+func TestPartitions(t *testing.T) {
+	d, s := newTestStore(t, 10, store.Table("users"), ConsistentRead())
+
+	if err := s.Write(&store.Record{Key: "1", Value: []byte("alice"), Metadata: map[string]interface{}{"role": "admin"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(&store.Record{Key: "1", Value: []byte("order")}, store.WriteTo("", "orders")); err != nil {
+		t.Fatal(err)
+	}
+
+	// tables are partitions of the same dynamodb table
+	if n := d.count("CreateTable"); n != 1 {
+		t.Errorf("Expected one table, got %d", n)
+	}
+	for _, p := range []string{"users", "orders"} {
+		if _, ok := d.get("micro", p, "1"); !ok {
+			t.Errorf("Expected the item in the %s partition", p)
+		}
+	}
+
+	results, err := s.Read("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(results[0].Value) != "alice" || results[0].Metadata["role"] != "admin" {
+		t.Errorf("Unexpected record %s %v", results[0].Value, results[0].Metadata)
+	}
+
+	keys, err := s.List(store.ListFrom("", "orders"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != "1" {
+		t.Errorf("Expected the key of the orders partition, got %v", keys)
+	}
+	d.Lock()
+	for i, c := range d.consistent {
+		if !c {
+			t.Errorf("Expected read %d to be consistent", i)
+		}
+	}
+	d.Unlock()
+
+	if err := s.Delete("1", store.DeleteFrom("", "orders")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := d.get("micro", "users", "1"); !ok {
+		t.Error("Expected the delete to be limited to its partition")
+	}
+}
+
+func TestQueryPages(t *testing.T) {
+	d, s := newTestStore(t, 2)
+
+	for i := 0; i < 7; i++ {
+		if err := s.Write(&store.Record{Key: fmt.Sprintf("user/%d", i), Value: []byte("user")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Write(&store.Record{Key: "group/1", Value: []byte("group")}); err != nil {
+		t.Fatal(err)
+	}
+	// expired items are filtered from pages, until DynamoDB deletes them
+	d.Lock()
+	d.tables["micro"][DefaultTable]["user/2"][expiryAttribute] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(time.Now().Add(-time.Second).Unix(), 10))}
+	d.Unlock()
+
+	keys, err := s.List(store.ListPrefix("user/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(keys, ",") != "user/0,user/1,user/3,user/4,user/5,user/6" {
+		t.Fatalf("Expected the unexpired keys of every page, got %v", keys)
+	}
+	if n := d.count("Query"); n != 4 {
+		t.Errorf("Expected 4 pages of 2 items, got %d queries", n)
+	}
+	d.Lock()
+	q := d.queries[0]
+	d.Unlock()
+	if p := aws.StringValue(q.ProjectionExpression); aws.StringValue(q.ExpressionAttributeNames[p]) != keyAttribute {
+		t.Errorf("Expected lists to only fetch the key, got %s", p)
+	}
+
+	// offsets and limits are applied after the pages are merged
+	results, err := s.Read("user/", store.ReadPrefix(), store.ReadOffset(3), store.ReadLimit(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Key != "user/4" || string(results[0].Value) != "user" {
+		t.Errorf("Unexpected page of records %v", results)
+	}
+
+	// suffixes are matched client side
+	keys, err = s.List(store.ListSuffix("/1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(keys, ",") != "group/1,user/1" {
+		t.Errorf("Expected group/1 and user/1, got %v", keys)
+	}
+
+	if _, err := s.Read("user/2"); err != store.ErrNotFound {
+		t.Errorf("Expected reads to skip the expired item, got %v", err)
+	}
+}
+
+func TestWriteMany(t *testing.T) {
+	d, s := newTestStore(t, 10)
+
+	var records []*store.Record
+	for i := 0; i < 60; i++ {
+		records = append(records, &store.Record{Key: strconv.Itoa(i % 55), Value: []byte{byte(i)}})
+	}
+
+	// the last item of the first batch is unprocessed and retried
+	d.unprocessed = 1
+	if err := WriteMany(s, records, store.WriteTTL(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if n := d.count("BatchWriteItem"); n != 4 {
+		t.Errorf("Expected 3 batches and a retry, got %d requests", n)
+	}
+	for i := 0; i < 55; i++ {
+		item, ok := d.get("micro", DefaultTable, strconv.Itoa(i))
+		if !ok {
+			t.Fatalf("Expected item %d to be written", i)
+		}
+		if item[expiryAttribute] == nil {
+			t.Errorf("Expected item %d to expire", i)
+		}
+	}
+	if item, _ := d.get("micro", DefaultTable, "0"); item["value"].B[0] != 55 {
+		t.Errorf("Expected the last record of key 0, got %d", item["value"].B[0])
+	}
+
+	// items still unprocessed after the retries fail the write
+	retries := DefaultBatchRetries
+	DefaultBatchRetries = 1
+	defer func() { DefaultBatchRetries = retries }()
+
+	d.Lock()
+	d.unprocessed = 2
+	d.Unlock()
+	if err := WriteMany(s, records[:2]); err == nil {
+		t.Error("Expected the unprocessed items to fail the write")
+	}
+	if _, ok := d.get("micro", DefaultTable, "1"); !ok {
+		t.Error("Expected the processed items to be written")
+	}
+
+	if err := WriteMany(store.NewMemoryStore(), records); err == nil {
+		t.Error("Expected an error writing many to another store")
+	}
+}
@@ -0,0 +1,74 @@
+package dynamodb
+
+import (
+	"context"
+
+	"go-micro.dev/v4/store"
+)
+
+type regionKey struct{}
+type credentialsKey struct{}
+type consistentReadKey struct{}
+type provisionedKey struct{}
+
+type credentials struct {
+	id, secret, token string
+}
+
+type provisioned struct {
+	read, write int64
+}
+
+// Region sets the region of the tables. Defaults to the region of the
+// environment.
+func Region(r string) store.Option {
+	return setStoreOption(regionKey{}, r)
+}
+
+// Credentials authenticates with static credentials. Without it the default
+// credential chain is used, which includes environment variables, shared
+// config and the role of the Lambda function or task.
+func Credentials(id, secret, token string) store.Option {
+	return setStoreOption(credentialsKey{}, credentials{id: id, secret: secret, token: token})
+}
+
+// ConsistentRead makes reads and lists strongly consistent, so they see all
+// the writes acknowledged before. They cost twice the read capacity of the
+// default eventually consistent reads.
+func ConsistentRead() store.Option {
+	return setStoreOption(consistentReadKey{}, true)
+}
+
+// ProvisionedThroughput creates tables with provisioned capacity of the read
+// and write units per second. Tables are created with on-demand capacity
+// otherwise.
+func ProvisionedThroughput(read, write int64) store.Option {
+	return setStoreOption(provisionedKey{}, provisioned{read: read, write: write})
+}
+
+func setStoreOption(k, v interface{}) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+func getConsistentRead(o store.Options) bool {
+	if o.Context != nil {
+		if ok, _ := o.Context.Value(consistentReadKey{}).(bool); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func getProvisioned(o store.Options) (provisioned, bool) {
+	if o.Context != nil {
+		if p, ok := o.Context.Value(provisionedKey{}).(provisioned); ok {
+			return p, true
+		}
+	}
+	return provisioned{}, false
+}
@@ -2,14 +2,14 @@
 package file
 
 import (
+	"bytes"
 	"encoding/json"
 	"os"
 	"path/filepath"
-	"sort"
-	"strings"
 	"sync"
 	"time"
 
+	"github.com/go-micro/plugins/v4/util/clock"
 	"go-micro.dev/v4/store"
 	"go-micro.dev/v4/util/cmd"
 	bolt "go.etcd.io/bbolt"
@@ -23,16 +23,15 @@ var (
 	DefaultTable = "micro"
 	// DefaultDir is the default directory for bbolt files.
 	DefaultDir = filepath.Join(os.TempDir(), "micro", "store")
-
-	// bucket used for data storage.
-	dataBucket = "data"
 )
 
 func init() {
 	cmd.DefaultStores["file"] = NewStore
 }
 
-// NewStore returns a memory store.
+// NewStore returns a store backed by bbolt files in the directory. Every
+// database is a file, with a bucket per table nested in the bucket of the
+// database.
 func NewStore(opts ...store.Option) store.Store {
 	s := &fileStore{
 		handles: make(map[string]*fileHandle),
@@ -44,6 +43,7 @@ func NewStore(opts ...store.Option) store.Store {
 type fileStore struct {
 	options store.Options
 	dir     string
+	clock   clock.Clock
 
 	// the database handle
 	sync.RWMutex
@@ -51,6 +51,7 @@ type fileStore struct {
 }
 
 type fileHandle struct {
+	// the database stored in the file
 	key string
 	db  *bolt.DB
 }
@@ -63,13 +64,31 @@ type record struct {
 	ExpiresAt time.Time
 }
 
-func key(database, table string) string {
-	return database + ":" + table
+func (r *record) expired(now time.Time) bool {
+	return !r.ExpiresAt.IsZero() && r.ExpiresAt.Before(now)
+}
+
+// bucket returns the bucket of the table, nested in the bucket of the
+// database, or nil if nothing was written to it yet.
+func bucket(tx *bolt.Tx, database, table string) *bolt.Bucket {
+	b := tx.Bucket([]byte(database))
+	if b == nil {
+		return nil
+	}
+	return b.Bucket([]byte(table))
 }
 
-func (m *fileStore) delete(fd *fileHandle, key string) error {
+func createBucket(tx *bolt.Tx, database, table string) (*bolt.Bucket, error) {
+	b, err := tx.CreateBucketIfNotExists([]byte(database))
+	if err != nil {
+		return nil, err
+	}
+	return b.CreateBucketIfNotExists([]byte(table))
+}
+
+func (m *fileStore) delete(fd *fileHandle, table, key string) error {
 	return fd.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(dataBucket))
+		b := bucket(tx, fd.key, table)
 		if b == nil {
 			return nil
 		}
@@ -95,6 +114,13 @@ func (m *fileStore) init(opts ...store.Option) error {
 		if dir, ok := m.options.Context.Value(dirOptionKey{}).(string); ok {
 			m.dir = dir
 		}
+		if c, ok := m.options.Context.Value(clockKey{}).(clock.Clock); ok {
+			m.clock = c
+		}
+	}
+
+	if m.clock == nil {
+		m.clock = clock.DefaultClock
 	}
 
 	// create default directory
@@ -110,17 +136,21 @@ func (m *fileStore) init(opts ...store.Option) error {
 	return nil
 }
 
-func (f *fileStore) getDB(database, table string) (*fileHandle, error) {
+func (f *fileStore) names(database, table string) (string, string) {
 	if len(database) == 0 {
 		database = f.options.Database
 	}
 	if len(table) == 0 {
 		table = f.options.Table
 	}
+	return database, table
+}
 
-	k := key(database, table)
+// getDB returns the handle of the file of the database, all its tables are
+// buckets in the same file.
+func (f *fileStore) getDB(database string) (*fileHandle, error) {
 	f.RLock()
-	fd, ok := f.handles[k]
+	fd, ok := f.handles[database]
 	f.RUnlock()
 
 	// return the file handle
@@ -131,101 +161,114 @@ func (f *fileStore) getDB(database, table string) (*fileHandle, error) {
 	// double check locking
 	f.Lock()
 	defer f.Unlock()
-	if fd, ok := f.handles[k]; ok {
+	if fd, ok := f.handles[database]; ok {
 		return fd, nil
 	}
 
-	// create directory
-	dir := filepath.Join(f.dir, database)
-	// create the database handle
-	fname := table + ".db"
-	// make the dir
-	os.MkdirAll(dir, 0700)
 	// database path
-	dbPath := filepath.Join(dir, fname)
+	dbPath := filepath.Join(f.dir, database+".db")
+	readOnly := getReadOnly(f.options)
 
 	// create new db handle
 	// Bolt DB only allows one process to open the file R/W so make sure we're doing this under a lock
-	db, err := bolt.Open(dbPath, 0700, &bolt.Options{Timeout: 5 * time.Second})
+	db, err := bolt.Open(dbPath, 0700, &bolt.Options{Timeout: 5 * time.Second, ReadOnly: readOnly})
 	if err != nil {
 		return nil, err
 	}
 	fd = &fileHandle{
-		key: k,
+		key: database,
 		db:  db,
 	}
-	f.handles[k] = fd
+
+	if !readOnly {
+		migrate(fd, filepath.Join(f.dir, database))
+	}
+
+	f.handles[database] = fd
 
 	return fd, nil
 }
 
-func (m *fileStore) list(fd *fileHandle, limit, offset uint) []string {
-	var allItems []string
+// scan calls fn with the unexpired records of the table whose key has the
+// prefix and suffix, in key order, skipping the first offset of them and
+// stopping after limit. The cursor seeks to the prefix, so only the keys
+// with the prefix are visited.
+func (m *fileStore) scan(fd *fileHandle, table, prefix, suffix string, limit, offset uint, fn func(r *record)) error {
+	now := m.clock.Now()
+	p, sfx := []byte(prefix), []byte(suffix)
 
-	fd.db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(dataBucket))
+	return fd.db.View(func(tx *bolt.Tx) error {
+		b := bucket(tx, fd.key, table)
 		// nothing to read
 		if b == nil {
 			return nil
 		}
 
-		// @todo very inefficient
-		if err := b.ForEach(func(k, v []byte) error {
-			storedRecord := &record{}
+		var n uint
+		c := b.Cursor()
+		for k, v := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, v = c.Next() {
+			if !bytes.HasSuffix(k, sfx) {
+				continue
+			}
 
+			storedRecord := &record{}
 			if err := json.Unmarshal(v, storedRecord); err != nil {
 				return err
 			}
+			if storedRecord.expired(now) {
+				continue
+			}
 
-			if !storedRecord.ExpiresAt.IsZero() {
-				if storedRecord.ExpiresAt.Before(time.Now()) {
-					return nil
-				}
+			if n++; n <= offset {
+				continue
 			}
 
-			allItems = append(allItems, string(k))
+			fn(storedRecord)
 
-			return nil
-		}); err != nil {
-			return err
+			if limit > 0 && n-offset >= limit {
+				return nil
+			}
 		}
 
 		return nil
 	})
+}
 
-	allKeys := make([]string, len(allItems))
+func newRecord(storedRecord *record, now time.Time) *store.Record {
+	newRecord := &store.Record{}
+	newRecord.Key = storedRecord.Key
+	newRecord.Value = storedRecord.Value
+	newRecord.Metadata = make(map[string]interface{})
 
-	for i, k := range allItems {
-		allKeys[i] = k
+	for k, v := range storedRecord.Metadata {
+		newRecord.Metadata[k] = v
 	}
 
-	if limit != 0 || offset != 0 {
-		sort.Slice(allKeys, func(i, j int) bool { return allKeys[i] < allKeys[j] })
-		min := func(i, j uint) uint {
-			if i < j {
-				return i
-			}
-			return j
-		}
-		return allKeys[offset:min(limit, uint(len(allKeys)))]
+	if !storedRecord.ExpiresAt.IsZero() {
+		newRecord.Expiry = storedRecord.ExpiresAt.Sub(now)
 	}
 
-	return allKeys
+	return newRecord
 }
 
-func (m *fileStore) get(fd *fileHandle, k string) (*store.Record, error) {
+func (m *fileStore) get(fd *fileHandle, table, k string) (*store.Record, error) {
 	var value []byte
 
-	fd.db.View(func(tx *bolt.Tx) error {
-		// @todo this is still very experimental...
-		b := tx.Bucket([]byte(dataBucket))
+	err := fd.db.View(func(tx *bolt.Tx) error {
+		b := bucket(tx, fd.key, table)
 		if b == nil {
 			return nil
 		}
 
-		value = b.Get([]byte(k))
+		// the value is only valid during the transaction
+		if v := b.Get([]byte(k)); v != nil {
+			value = append([]byte{}, v...)
+		}
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
 
 	if value == nil {
 		return nil, store.ErrNotFound
@@ -237,26 +280,15 @@ func (m *fileStore) get(fd *fileHandle, k string) (*store.Record, error) {
 		return nil, err
 	}
 
-	newRecord := &store.Record{}
-	newRecord.Key = storedRecord.Key
-	newRecord.Value = storedRecord.Value
-	newRecord.Metadata = make(map[string]interface{})
-
-	for k, v := range storedRecord.Metadata {
-		newRecord.Metadata[k] = v
-	}
-
-	if !storedRecord.ExpiresAt.IsZero() {
-		if storedRecord.ExpiresAt.Before(time.Now()) {
-			return nil, store.ErrNotFound
-		}
-		newRecord.Expiry = time.Until(storedRecord.ExpiresAt)
+	now := m.clock.Now()
+	if storedRecord.expired(now) {
+		return nil, store.ErrNotFound
 	}
 
-	return newRecord, nil
+	return newRecord(storedRecord, now), nil
 }
 
-func (m *fileStore) set(fd *fileHandle, r *store.Record) error {
+func (m *fileStore) set(fd *fileHandle, table string, r *store.Record) error {
 	// copy the incoming record and then
 	// convert the expiry in to a hard timestamp
 	item := &record{}
@@ -265,7 +297,7 @@ func (m *fileStore) set(fd *fileHandle, r *store.Record) error {
 	item.Metadata = make(map[string]interface{})
 
 	if r.Expiry != 0 {
-		item.ExpiresAt = time.Now().Add(r.Expiry)
+		item.ExpiresAt = m.clock.Now().Add(r.Expiry)
 	}
 
 	for k, v := range r.Metadata {
@@ -276,13 +308,9 @@ func (m *fileStore) set(fd *fileHandle, r *store.Record) error {
 	data, _ := json.Marshal(item)
 
 	return fd.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(dataBucket))
-		if b == nil {
-			var err error
-			b, err = tx.CreateBucketIfNotExists([]byte(dataBucket))
-			if err != nil {
-				return err
-			}
+		b, err := createBucket(tx, fd.key, table)
+		if err != nil {
+			return err
 		}
 		return b.Put([]byte(r.Key), data)
 	})
@@ -308,12 +336,13 @@ func (m *fileStore) Delete(key string, opts ...store.DeleteOption) error {
 		o(&deleteOptions)
 	}
 
-	fd, err := m.getDB(deleteOptions.Database, deleteOptions.Table)
+	database, table := m.names(deleteOptions.Database, deleteOptions.Table)
+	fd, err := m.getDB(database)
 	if err != nil {
 		return err
 	}
 
-	return m.delete(fd, key)
+	return m.delete(fd, table, key)
 }
 
 func (m *fileStore) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
@@ -322,44 +351,36 @@ func (m *fileStore) Read(key string, opts ...store.ReadOption) ([]*store.Record,
 		o(&readOpts)
 	}
 
-	fd, err := m.getDB(readOpts.Database, readOpts.Table)
+	database, table := m.names(readOpts.Database, readOpts.Table)
+	fd, err := m.getDB(database)
 	if err != nil {
 		return nil, err
 	}
 
-	var keys []string
-
-	// Handle Prefix / suffix
-	// TODO: do range scan here rather than listing all keys
-	if readOpts.Prefix || readOpts.Suffix {
-		// list the keys
-		k := m.list(fd, readOpts.Limit, readOpts.Offset)
-
-		// check for prefix and suffix
-		for _, v := range k {
-			if readOpts.Prefix && !strings.HasPrefix(v, key) {
-				continue
-			}
-			if readOpts.Suffix && !strings.HasSuffix(v, key) {
-				continue
-			}
-			keys = append(keys, v)
+	if !readOpts.Prefix && !readOpts.Suffix {
+		r, err := m.get(fd, table, key)
+		if err != nil {
+			return nil, err
 		}
-	} else {
-		keys = []string{key}
+		return []*store.Record{r}, nil
+	}
+
+	var prefix, suffix string
+	if readOpts.Prefix {
+		prefix = key
+	}
+	if readOpts.Suffix {
+		suffix = key
 	}
 
 	var results []*store.Record
+	now := m.clock.Now()
 
-	for _, k := range keys {
-		r, err := m.get(fd, k)
-		if err != nil {
-			return results, err
-		}
-		results = append(results, r)
-	}
+	err = m.scan(fd, table, prefix, suffix, readOpts.Limit, readOpts.Offset, func(r *record) {
+		results = append(results, newRecord(r, now))
+	})
 
-	return results, nil
+	return results, err
 }
 
 func (m *fileStore) Write(r *store.Record, opts ...store.WriteOption) error {
@@ -368,7 +389,8 @@ func (m *fileStore) Write(r *store.Record, opts ...store.WriteOption) error {
 		o(&writeOpts)
 	}
 
-	fd, err := m.getDB(writeOpts.Database, writeOpts.Table)
+	database, table := m.names(writeOpts.Database, writeOpts.Table)
+	fd, err := m.getDB(database)
 	if err != nil {
 		return err
 	}
@@ -382,7 +404,7 @@ func (m *fileStore) Write(r *store.Record, opts ...store.WriteOption) error {
 		newRecord.Expiry = r.Expiry
 
 		if !writeOpts.Expiry.IsZero() {
-			newRecord.Expiry = time.Until(writeOpts.Expiry)
+			newRecord.Expiry = m.clock.Until(writeOpts.Expiry)
 		}
 		if writeOpts.TTL != 0 {
 			newRecord.Expiry = writeOpts.TTL
@@ -392,10 +414,10 @@ func (m *fileStore) Write(r *store.Record, opts ...store.WriteOption) error {
 			newRecord.Metadata[k] = v
 		}
 
-		return m.set(fd, &newRecord)
+		return m.set(fd, table, &newRecord)
 	}
 
-	return m.set(fd, r)
+	return m.set(fd, table, r)
 }
 
 func (m *fileStore) Options() store.Options {
@@ -409,35 +431,19 @@ func (m *fileStore) List(opts ...store.ListOption) ([]string, error) {
 		o(&listOptions)
 	}
 
-	fd, err := m.getDB(listOptions.Database, listOptions.Table)
+	database, table := m.names(listOptions.Database, listOptions.Table)
+	fd, err := m.getDB(database)
 	if err != nil {
 		return nil, err
 	}
 
-	// TODO apply prefix/suffix in range query
-	allKeys := m.list(fd, listOptions.Limit, listOptions.Offset)
-
-	if len(listOptions.Prefix) > 0 {
-		var prefixKeys []string
-		for _, k := range allKeys {
-			if strings.HasPrefix(k, listOptions.Prefix) {
-				prefixKeys = append(prefixKeys, k)
-			}
-		}
-		allKeys = prefixKeys
-	}
+	var keys []string
 
-	if len(listOptions.Suffix) > 0 {
-		var suffixKeys []string
-		for _, k := range allKeys {
-			if strings.HasSuffix(k, listOptions.Suffix) {
-				suffixKeys = append(suffixKeys, k)
-			}
-		}
-		allKeys = suffixKeys
-	}
+	err = m.scan(fd, table, listOptions.Prefix, listOptions.Suffix, listOptions.Limit, listOptions.Offset, func(r *record) {
+		keys = append(keys, r.Key)
+	})
 
-	return allKeys, nil
+	return keys, err
 }
 
 func (m *fileStore) String() string {
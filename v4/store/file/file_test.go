@@ -9,14 +9,17 @@ import (
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/go-micro/plugins/v4/util/clock"
 	"github.com/kr/pretty"
 	"go-micro.dev/v4/store"
+	bolt "go.etcd.io/bbolt"
 )
 
 func cleanup(db string, s store.Store) {
 	s.Close()
 	dir := filepath.Join(DefaultDir, db+"/")
 	os.RemoveAll(dir)
+	os.Remove(filepath.Join(DefaultDir, db+".db"))
 }
 
 func TestFileStoreReInit(t *testing.T) {
@@ -52,6 +55,131 @@ func TestFileStoreDatabaseTable(t *testing.T) {
 	fileTest(s, t)
 }
 
+func TestFileStoreScan(t *testing.T) {
+	s := NewStore(store.Database("scandb"))
+	defer cleanup("scandb", s)
+
+	for i := 0; i < 100; i++ {
+		s.Write(&store.Record{Key: fmt.Sprintf("b%02d", i), Value: []byte{}})
+	}
+	s.Write(&store.Record{Key: "a", Value: []byte{}})
+	s.Write(&store.Record{Key: "b50", Value: []byte{}}, store.WriteTTL(time.Nanosecond))
+	s.Write(&store.Record{Key: "b", Value: []byte{}}, store.WriteTo("scandb", "other"))
+
+	keys, err := s.List(store.ListPrefix("b"), store.ListOffset(45), store.ListLimit(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// b50 expired and isn't counted
+	if len(keys) != 10 || keys[0] != "b45" || keys[9] != "b55" {
+		t.Errorf("Expected b45 to b55 without b50, got %v", keys)
+	}
+
+	keys, err = s.List(store.ListPrefix("b"), store.ListSuffix("9"), store.ListLimit(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 3 || keys[0] != "b09" || keys[2] != "b29" {
+		t.Errorf("Expected b09, b19 and b29, got %v", keys)
+	}
+
+	results, err := s.Read("b9", store.ReadPrefix(), store.ReadOffset(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Key != "b98" {
+		t.Errorf("Expected b98 and b99, got %v", results)
+	}
+
+	keys, err = s.List(store.ListFrom("scandb", "other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != "b" {
+		t.Errorf("Expected the table to be a bucket of its own, got %v", keys)
+	}
+}
+
+func TestFileStoreClock(t *testing.T) {
+	c := clock.NewMock(time.Now())
+	s := NewStore(store.Database("clockdb"), Clock(c))
+	defer cleanup("clockdb", s)
+
+	if err := s.Write(&store.Record{Key: "foo", Value: []byte("bar")}, store.WriteTTL(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Add(59 * time.Minute)
+	r, err := s.Read("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r[0].Expiry != time.Minute {
+		t.Errorf("Expected a minute left, got %v", r[0].Expiry)
+	}
+
+	c.Add(time.Minute + time.Second)
+	if _, err := s.Read("foo"); err != store.ErrNotFound {
+		t.Errorf("Expected %v, got %v", store.ErrNotFound, err)
+	}
+	if keys, _ := s.List(); len(keys) != 0 {
+		t.Errorf("Expected no keys, got %v", keys)
+	}
+}
+
+func TestFileStoreReadOnly(t *testing.T) {
+	s := NewStore(store.Database("rodb"))
+	defer cleanup("rodb", s)
+
+	if err := s.Write(&store.Record{Key: "foo", Value: []byte("bar")}); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	ro := NewStore(store.Database("rodb"), ReadOnly())
+	defer ro.Close()
+
+	if r, err := ro.Read("foo"); err != nil || string(r[0].Value) != "bar" {
+		t.Errorf("Expected bar, got %v %v", r, err)
+	}
+	if err := ro.Write(&store.Record{Key: "foo", Value: []byte("baz")}); err == nil {
+		t.Error("Expected writes to fail")
+	}
+}
+
+func TestFileStoreMigrate(t *testing.T) {
+	dir := filepath.Join(DefaultDir, "legacydb")
+	os.MkdirAll(dir, 0700)
+	defer os.Remove(filepath.Join(DefaultDir, "legacydb.db"))
+	defer os.RemoveAll(dir)
+
+	old, err := bolt.Open(filepath.Join(dir, "users.db"), 0700, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = old.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(legacyBucket))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte("foo"), []byte(`{"Key":"foo","Value":"YmFy"}`))
+	})
+	old.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewStore(store.Database("legacydb"), store.Table("users"))
+	defer s.Close()
+
+	if r, err := s.Read("foo"); err != nil || string(r[0].Value) != "bar" {
+		t.Errorf("Expected the legacy record, got %v %v", r, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "users.db.migrated")); err != nil {
+		t.Errorf("Expected the legacy file to be renamed: %v", err)
+	}
+}
+
 func fileTest(s store.Store, t *testing.T) {
 	if len(os.Getenv("IN_TRAVIS_CI")) == 0 {
 		t.Logf("Options %s %v\n", s.String(), s.Options())
@@ -4,6 +4,7 @@ go 1.17
 
 require (
 	github.com/davecgh/go-spew v1.1.1
+	github.com/go-micro/plugins/v4/util/clock v1.0.0
 	github.com/kr/pretty v0.2.1
 	go-micro.dev/v4 v4.9.0
 	go.etcd.io/bbolt v1.3.6
@@ -49,3 +50,5 @@ require (
 	gopkg.in/warnings.v0 v0.1.2 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
+
+replace github.com/go-micro/plugins/v4/util/clock => ../../util/clock
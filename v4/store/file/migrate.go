@@ -0,0 +1,63 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go-micro.dev/v4/logger"
+	bolt "go.etcd.io/bbolt"
+)
+
+// legacyBucket is the bucket of the records in the files of the previous
+// layout, which had a file per table.
+const legacyBucket = "data"
+
+// migrate imports the tables of the database from the files of the previous
+// layout, dir/database/table.db, into buckets of the database file. Imported
+// files are renamed to table.db.migrated, so they're imported once.
+func migrate(fd *fileHandle, dir string) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.db"))
+	if err != nil || len(files) == 0 {
+		return
+	}
+
+	for _, f := range files {
+		table := strings.TrimSuffix(filepath.Base(f), ".db")
+		if err := migrateTable(fd, f, table); err != nil {
+			logger.Logf(logger.WarnLevel, "[file] failed to migrate table %s of %s: %v", table, fd.key, err)
+			continue
+		}
+		os.Rename(f, f+".migrated")
+	}
+}
+
+func migrateTable(fd *fileHandle, path, table string) error {
+	old, err := bolt.Open(path, 0700, &bolt.Options{Timeout: time.Second, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer old.Close()
+
+	return old.View(func(otx *bolt.Tx) error {
+		ob := otx.Bucket([]byte(legacyBucket))
+		if ob == nil {
+			return nil
+		}
+
+		return fd.db.Update(func(tx *bolt.Tx) error {
+			b, err := createBucket(tx, fd.key, table)
+			if err != nil {
+				return err
+			}
+			return ob.ForEach(func(k, v []byte) error {
+				// keep the records written since
+				if b.Get(k) != nil {
+					return nil
+				}
+				return b.Put(k, v)
+			})
+		})
+	})
+}
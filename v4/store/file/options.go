@@ -3,10 +3,12 @@ package file
 import (
 	"context"
 
+	"github.com/go-micro/plugins/v4/util/clock"
 	"go-micro.dev/v4/store"
 )
 
 type dirOptionKey struct{}
+type clockKey struct{}
 
 // DirOption is a file store Option to set the directory for the file store.
 func DirOption(dir string) store.Option {
@@ -17,3 +19,35 @@ func DirOption(dir string) store.Option {
 		o.Context = context.WithValue(o.Context, dirOptionKey{}, dir)
 	}
 }
+
+type readOnlyKey struct{}
+
+// ReadOnly opens the files read only, so several processes can read them at
+// once, e.g. to serve a prebuilt dataset. Writes and deletes fail.
+func ReadOnly() store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, readOnlyKey{}, true)
+	}
+}
+
+func getReadOnly(o store.Options) bool {
+	if o.Context == nil {
+		return false
+	}
+	ok, _ := o.Context.Value(readOnlyKey{}).(bool)
+	return ok
+}
+
+// Clock sets the clock the expiry of the records is checked against, e.g. a
+// mock clock in tests. Defaults to the system clock.
+func Clock(c clock.Clock) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, clockKey{}, c)
+	}
+}
@@ -0,0 +1,313 @@
+// Package firestore implements the store on Google Cloud Firestore.
+package firestore
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/go-micro/plugins/v4/util/records"
+	"github.com/pkg/errors"
+	log "go-micro.dev/v4/logger"
+	"go-micro.dev/v4/store"
+	"go-micro.dev/v4/util/cmd"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// DefaultDatabase is the database used if none is provided.
+	DefaultDatabase = "micro"
+
+	// ExpiryField is the document field holding the expiry of a record.
+	// Configure a TTL policy on it to have Firestore delete expired
+	// records, until then they're skipped on reads.
+	ExpiryField = "expireAt"
+)
+
+const (
+	valueField    = "value"
+	metadataField = "metadata"
+
+	// rangeEnd sorts after the characters of encoded keys, prefix+rangeEnd
+	// is after every document ID with the prefix
+	rangeEnd = "\uf8ff"
+)
+
+type fsStore struct {
+	ctx     context.Context
+	options store.Options
+	client  *firestore.Client
+}
+
+func init() {
+	cmd.DefaultStores["firestore"] = NewStore
+}
+
+// NewStore returns a new store backed by Firestore. Records of a database
+// and table are the documents of the collection "<database>_<table>", or
+// "<database>" without a table.
+func NewStore(opts ...store.Option) store.Store {
+	options := store.Options{
+		Database: DefaultDatabase,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	s := &fsStore{
+		ctx:     context.Background(),
+		options: options,
+	}
+
+	if err := s.configure(); err != nil {
+		log.Fatal(err)
+	}
+
+	return s
+}
+
+func (s *fsStore) configure() error {
+	project := getProjectID(s.options)
+	if len(project) == 0 {
+		project = os.Getenv("GOOGLE_CLOUD_PROJECT")
+	}
+	if len(project) == 0 {
+		project = firestore.DetectProjectID
+	}
+
+	client, err := firestore.NewClient(s.ctx, project, getClientOptions(s.options)...)
+	if err != nil {
+		return errors.Wrap(err, "creating firestore client")
+	}
+
+	if s.client != nil {
+		s.client.Close()
+	}
+	s.client = client
+
+	return nil
+}
+
+func (s *fsStore) Init(opts ...store.Option) error {
+	for _, o := range opts {
+		o(&s.options)
+	}
+	return s.configure()
+}
+
+func (s *fsStore) Options() store.Options {
+	return s.options
+}
+
+func (s *fsStore) Close() error {
+	return s.client.Close()
+}
+
+func (s *fsStore) String() string {
+	return "firestore"
+}
+
+func (s *fsStore) collection(database, table string) *firestore.CollectionRef {
+	if len(database) == 0 {
+		database = s.options.Database
+	}
+	if len(table) == 0 {
+		table = s.options.Table
+	}
+
+	name := database
+	if len(table) > 0 {
+		name = database + "_" + table
+	}
+	return s.client.Collection(name)
+}
+
+// docID encodes the key as a document ID, which can't contain slashes. The
+// encoding keeps prefixes, so prefix queries work on the encoded IDs.
+func docID(key string) string {
+	return url.PathEscape(key)
+}
+
+func recordKey(id string) string {
+	key, err := url.PathUnescape(id)
+	if err != nil {
+		return id
+	}
+	return key
+}
+
+// prefixQuery selects the documents whose ID starts with the key.
+func prefixQuery(col *firestore.CollectionRef, prefix string) firestore.Query {
+	q := col.OrderBy(firestore.DocumentID, firestore.Asc)
+	if len(prefix) == 0 {
+		return q
+	}
+	id := docID(prefix)
+	return q.StartAt(id).EndBefore(id + rangeEnd)
+}
+
+func expired(doc *firestore.DocumentSnapshot, now time.Time) (time.Time, bool) {
+	return expiredData(doc.Data(), now)
+}
+
+func expiredData(data map[string]interface{}, now time.Time) (time.Time, bool) {
+	at, ok := data[ExpiryField].(time.Time)
+	if !ok {
+		return time.Time{}, false
+	}
+	return at, !at.After(now)
+}
+
+func toRecord(doc *firestore.DocumentSnapshot, now time.Time) *store.Record {
+	return fromData(doc.Ref.ID, doc.Data(), now)
+}
+
+// fromData returns the record of the fields of a document.
+func fromData(id string, data map[string]interface{}, now time.Time) *store.Record {
+	r := &store.Record{
+		Key:      recordKey(id),
+		Metadata: make(map[string]interface{}),
+	}
+	r.Value, _ = data[valueField].([]byte)
+	if md, ok := data[metadataField].(map[string]interface{}); ok {
+		r.Metadata = md
+	}
+	if at, ok := data[ExpiryField].(time.Time); ok {
+		r.Expiry = at.Sub(now)
+	}
+	return r
+}
+
+func (s *fsStore) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	var options store.ReadOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	col := s.collection(options.Database, options.Table)
+	now := time.Now()
+
+	if !options.Prefix && !options.Suffix {
+		doc, err := col.Doc(docID(key)).Get(s.ctx)
+		if status.Code(err) == codes.NotFound {
+			return nil, store.ErrNotFound
+		} else if err != nil {
+			return nil, err
+		}
+		if _, ok := expired(doc, now); ok {
+			return nil, store.ErrNotFound
+		}
+		return []*store.Record{toRecord(doc, now)}, nil
+	}
+
+	var q firestore.Query
+	if options.Prefix {
+		q = prefixQuery(col, key)
+	} else {
+		q = prefixQuery(col, "")
+	}
+
+	docs, err := q.Documents(s.ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*store.Record
+	for _, doc := range docs {
+		if _, ok := expired(doc, now); ok {
+			continue
+		}
+		r := toRecord(doc, now)
+		if options.Suffix && !strings.HasSuffix(r.Key, key) {
+			continue
+		}
+		results = append(results, r)
+	}
+
+	offset, limit := records.Bounds(len(results), options.Offset, options.Limit)
+	return results[offset:limit], nil
+}
+
+func (s *fsStore) Write(r *store.Record, opts ...store.WriteOption) error {
+	var options store.WriteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	data := toData(r, options, time.Now())
+	_, err := s.collection(options.Database, options.Table).Doc(docID(r.Key)).Set(s.ctx, data)
+	return err
+}
+
+// toData returns the fields of the document of the record. The TTL of the
+// options takes precedence over their expiry, which takes precedence over
+// the expiry of the record.
+func toData(r *store.Record, options store.WriteOptions, now time.Time) map[string]interface{} {
+	data := map[string]interface{}{
+		valueField:    r.Value,
+		metadataField: r.Metadata,
+	}
+
+	expiry := r.Expiry
+	if options.TTL > 0 {
+		expiry = options.TTL
+	} else if !options.Expiry.IsZero() {
+		expiry = options.Expiry.Sub(now)
+	}
+	if expiry > 0 {
+		data[ExpiryField] = now.Add(expiry)
+	}
+
+	return data
+}
+
+func (s *fsStore) Delete(key string, opts ...store.DeleteOption) error {
+	var options store.DeleteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	_, err := s.collection(options.Database, options.Table).Doc(docID(key)).Delete(s.ctx)
+	return err
+}
+
+func (s *fsStore) List(opts ...store.ListOption) ([]string, error) {
+	var options store.ListOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	col := s.collection(options.Database, options.Table)
+	q := prefixQuery(col, options.Prefix).Select(ExpiryField)
+
+	now := time.Now()
+	var keys []string
+
+	iter := q.Documents(s.ctx)
+	defer iter.Stop()
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if _, ok := expired(doc, now); ok {
+			continue
+		}
+		key := recordKey(doc.Ref.ID)
+		if len(options.Suffix) > 0 && !strings.HasSuffix(key, options.Suffix) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	offset, limit := records.Bounds(len(keys), options.Offset, options.Limit)
+	return keys[offset:limit], nil
+}
@@ -0,0 +1,280 @@
+package firestore
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/go-micro/plugins/v4/util/records"
+	"go-micro.dev/v4/store"
+	pb "google.golang.org/genproto/googleapis/firestore/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestDocID(t *testing.T) {
+	for _, key := range []string{"foo", "foo/bar", "a%2Fb", "with space"} {
+		id := docID(key)
+		if strings.Contains(id, "/") {
+			t.Errorf("docID(%q) = %q contains a slash", key, id)
+		}
+		if got := recordKey(id); got != key {
+			t.Errorf("recordKey(docID(%q)) = %q", key, got)
+		}
+	}
+
+	if !strings.HasPrefix(docID("users/1"), docID("users/")) {
+		t.Error("Expected the encoding to keep prefixes")
+	}
+}
+
+func TestToData(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	tests := []struct {
+		name    string
+		record  time.Duration
+		options store.WriteOptions
+		// zero without an expiry
+		expiry time.Duration
+	}{
+		{"none", 0, store.WriteOptions{}, 0},
+		{"record", time.Hour, store.WriteOptions{}, time.Hour},
+		{"expiry overrides record", time.Hour, store.WriteOptions{Expiry: now.Add(time.Minute)}, time.Minute},
+		{"ttl overrides expiry", time.Hour, store.WriteOptions{Expiry: now.Add(time.Minute), TTL: time.Second}, time.Second},
+	}
+
+	for _, tt := range tests {
+		r := &store.Record{
+			Key:      "key",
+			Value:    []byte("value"),
+			Metadata: map[string]interface{}{"foo": "bar"},
+			Expiry:   tt.record,
+		}
+
+		data := toData(r, tt.options, now)
+		if string(data[valueField].([]byte)) != "value" {
+			t.Errorf("%s: unexpected value %v", tt.name, data[valueField])
+		}
+
+		at, ok := data[ExpiryField].(time.Time)
+		if tt.expiry == 0 {
+			if ok {
+				t.Errorf("%s: expected no expiry field, got %v", tt.name, at)
+			}
+			continue
+		}
+		if !at.Equal(now.Add(tt.expiry)) {
+			t.Errorf("%s: expected the record to expire at %v, got %v", tt.name, now.Add(tt.expiry), at)
+		}
+	}
+}
+
+func TestFromData(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	data := map[string]interface{}{
+		valueField:    []byte("alice"),
+		metadataField: map[string]interface{}{"role": "admin"},
+		ExpiryField:   now.Add(time.Minute),
+	}
+
+	r := fromData(docID("user/1"), data, now)
+	if r.Key != "user/1" || string(r.Value) != "alice" {
+		t.Errorf("Unexpected record %s %s", r.Key, r.Value)
+	}
+	if r.Metadata["role"] != "admin" {
+		t.Errorf("Unexpected metadata %v", r.Metadata)
+	}
+	if r.Expiry != time.Minute {
+		t.Errorf("Expected the record to expire in a minute, got %v", r.Expiry)
+	}
+
+	// documents written by other clients may lack fields
+	r = fromData("other", map[string]interface{}{}, now)
+	if r.Value != nil || r.Metadata == nil || r.Expiry != 0 {
+		t.Errorf("Unexpected record of an empty document %+v", r)
+	}
+}
+
+func TestExpiredData(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	tests := []struct {
+		data    map[string]interface{}
+		expired bool
+	}{
+		{map[string]interface{}{}, false},
+		{map[string]interface{}{ExpiryField: "not a timestamp"}, false},
+		{map[string]interface{}{ExpiryField: now.Add(time.Second)}, false},
+		// until the TTL policy deletes them
+		{map[string]interface{}{ExpiryField: now}, true},
+		{map[string]interface{}{ExpiryField: now.Add(-time.Hour)}, true},
+	}
+
+	for _, tt := range tests {
+		if _, expired := expiredData(tt.data, now); expired != tt.expired {
+			t.Errorf("expiredData(%v) = %v, want %v", tt.data, expired, tt.expired)
+		}
+	}
+}
+
+// newUnitStore returns a store whose client never connects, to inspect the
+// references and queries it builds.
+func newUnitStore(t *testing.T, opts ...store.Option) *fsStore {
+	if len(os.Getenv("FIRESTORE_EMULATOR_HOST")) == 0 {
+		t.Setenv("FIRESTORE_EMULATOR_HOST", "127.0.0.1:1")
+	}
+
+	client, err := firestore.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	options := store.Options{Database: DefaultDatabase}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &fsStore{ctx: context.Background(), options: options, client: client}
+}
+
+func TestCollection(t *testing.T) {
+	s := newUnitStore(t, store.Table("users"))
+
+	tests := []struct {
+		database, table string
+		name            string
+	}{
+		{"", "", "micro_users"},
+		{"accounts", "", "accounts_users"},
+		{"", "orders", "micro_orders"},
+		{"accounts", "orders", "accounts_orders"},
+	}
+	for _, tt := range tests {
+		if id := s.collection(tt.database, tt.table).ID; id != tt.name {
+			t.Errorf("collection(%q, %q) = %s, want %s", tt.database, tt.table, id, tt.name)
+		}
+	}
+
+	// without a table the database is the collection
+	s = newUnitStore(t, store.Database("accounts"))
+	if id := s.collection("", "").ID; id != "accounts" {
+		t.Errorf("Expected the accounts collection, got %s", id)
+	}
+}
+
+func TestPrefixQuery(t *testing.T) {
+	s := newUnitStore(t)
+	col := s.collection("", "")
+
+	structured := func(q firestore.Query) *pb.StructuredQuery {
+		b, err := q.Serialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var req pb.RunQueryRequest
+		if err := proto.Unmarshal(b, &req); err != nil {
+			t.Fatal(err)
+		}
+		return req.GetStructuredQuery()
+	}
+
+	sq := structured(prefixQuery(col, ""))
+	if sq.StartAt != nil || sq.EndAt != nil {
+		t.Errorf("Expected no bounds without a prefix, got %v %v", sq.StartAt, sq.EndAt)
+	}
+
+	sq = structured(prefixQuery(col, "user/"))
+	if sq.StartAt == nil || sq.EndAt == nil {
+		t.Fatal("Expected the query to be bounded")
+	}
+
+	start := sq.StartAt.Values[0].GetReferenceValue()
+	end := sq.EndAt.Values[0].GetReferenceValue()
+	if !strings.HasSuffix(start, "/"+docID("user/")) {
+		t.Errorf("Expected the query to start at the encoded prefix, got %s", start)
+	}
+	if !strings.HasSuffix(end, "/"+docID("user/")+rangeEnd) || !sq.EndAt.Before {
+		t.Errorf("Expected the query to end before the end of the prefix range, got %s", end)
+	}
+}
+
+func TestEmulator(t *testing.T) {
+	if len(os.Getenv("FIRESTORE_EMULATOR_HOST")) == 0 {
+		t.Skip("FIRESTORE_EMULATOR_HOST not set")
+	}
+
+	s := NewStore(ProjectID("test"), store.Table("emulator")).(*fsStore)
+	defer s.Close()
+
+	w, err := Watch(s, records.WatchPrefix("user/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	// snapshots may merge changes, so each is awaited before the next
+	next := func(key string, typ records.EventType) {
+		t.Helper()
+		e, err := w.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Record.Key != key || e.Type != typ {
+			t.Fatalf("Expected %v of %s, got %v of %s", typ, key, e.Type, e.Record.Key)
+		}
+	}
+
+	if err := s.Write(&store.Record{Key: "user/1", Value: []byte("alice")}, store.WriteTTL(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete("user/1")
+	next("user/1", records.Create)
+
+	// keys are stored as document IDs without slashes, with the expiry
+	// field for the TTL policy
+	doc, err := s.client.Collection("micro_emulator").Doc("user%2F1").Get(s.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := doc.Data()[ExpiryField].(time.Time); !ok {
+		t.Errorf("Expected the %s field to be a timestamp, got %v", ExpiryField, doc.Data()[ExpiryField])
+	}
+
+	// expired documents are skipped until the TTL policy deletes them, and
+	// watched as deletes
+	_, err = s.client.Collection("micro_emulator").Doc(docID("user/2")).Set(s.ctx, map[string]interface{}{
+		valueField:  []byte("bob"),
+		ExpiryField: time.Now().Add(-time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete("user/2")
+	next("user/2", records.Delete)
+
+	if _, err := s.Read("user/2"); err != store.ErrNotFound {
+		t.Errorf("Expected store.ErrNotFound for the expired record, got %v", err)
+	}
+	keys, err := s.List(store.ListPrefix("user/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != "user/1" {
+		t.Errorf("Expected only user/1 to be listed, got %v", keys)
+	}
+
+	if err := s.Write(&store.Record{Key: "user/1", Value: []byte("alice2")}); err != nil {
+		t.Fatal(err)
+	}
+	next("user/1", records.Update)
+
+	if err := s.Delete("user/1"); err != nil {
+		t.Fatal(err)
+	}
+	next("user/1", records.Delete)
+}
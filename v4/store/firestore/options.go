@@ -0,0 +1,50 @@
+package firestore
+
+import (
+	"context"
+
+	"go-micro.dev/v4/store"
+	"google.golang.org/api/option"
+)
+
+type projectIDKey struct{}
+type clientOptionsKey struct{}
+
+// ProjectID sets the Google Cloud project of the Firestore database.
+// Defaults to the project detected from the credentials.
+func ProjectID(id string) store.Option {
+	return setStoreOption(projectIDKey{}, id)
+}
+
+// ClientOptions sets the options of the Firestore client, e.g. the
+// credentials.
+func ClientOptions(opts ...option.ClientOption) store.Option {
+	return setStoreOption(clientOptionsKey{}, opts)
+}
+
+func setStoreOption(k, v interface{}) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+func getProjectID(o store.Options) string {
+	if o.Context != nil {
+		if id, ok := o.Context.Value(projectIDKey{}).(string); ok && len(id) > 0 {
+			return id
+		}
+	}
+	return ""
+}
+
+func getClientOptions(o store.Options) []option.ClientOption {
+	if o.Context != nil {
+		if opts, ok := o.Context.Value(clientOptionsKey{}).([]option.ClientOption); ok {
+			return opts
+		}
+	}
+	return nil
+}
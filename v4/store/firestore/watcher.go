@@ -0,0 +1,77 @@
+package firestore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/go-micro/plugins/v4/util/records"
+	"go-micro.dev/v4/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Watch listens to the snapshots of the table and returns its changes. The
+// records existing when the watch starts are returned first, as creates.
+// Only the prefix of the options is supported.
+func Watch(s store.Store, opts ...records.WatchOption) (records.Watcher, error) {
+	fs, ok := s.(*fsStore)
+	if !ok {
+		return nil, errors.New(s.String() + " is not a firestore store")
+	}
+
+	options := records.NewWatchOptions(opts...)
+
+	ctx, cancel := context.WithCancel(fs.ctx)
+	col := fs.collection(options.Database, options.Table)
+
+	return &watcher{
+		iter:   prefixQuery(col, options.Prefix).Snapshots(ctx),
+		cancel: cancel,
+	}, nil
+}
+
+type watcher struct {
+	iter   *firestore.QuerySnapshotIterator
+	cancel context.CancelFunc
+	// changes of the last snapshot not returned yet
+	pending []*records.Event
+}
+
+func (w *watcher) Next() (*records.Event, error) {
+	for len(w.pending) == 0 {
+		snap, err := w.iter.Next()
+		if status.Code(err) == codes.Canceled {
+			return nil, records.ErrWatcherStopped
+		} else if err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		for _, c := range snap.Changes {
+			e := &records.Event{Record: toRecord(c.Doc, now)}
+			switch c.Kind {
+			case firestore.DocumentAdded:
+				e.Type = records.Create
+			case firestore.DocumentModified:
+				e.Type = records.Update
+			default:
+				e.Type = records.Delete
+			}
+			if _, ok := expired(c.Doc, now); ok {
+				e.Type = records.Delete
+			}
+			w.pending = append(w.pending, e)
+		}
+	}
+
+	e := w.pending[0]
+	w.pending = w.pending[1:]
+	return e, nil
+}
+
+func (w *watcher) Stop() {
+	w.cancel()
+	w.iter.Stop()
+}
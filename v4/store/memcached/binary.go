@@ -0,0 +1,243 @@
+package memcached
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	mc "github.com/bradfitz/gomemcache/memcache"
+)
+
+// opcodes and status codes of the binary protocol
+const (
+	magicRequest  = 0x80
+	magicResponse = 0x81
+
+	opGet      = 0x00
+	opSet      = 0x01
+	opDelete   = 0x04
+	opSASLAuth = 0x21
+
+	statusOK           = 0x0000
+	statusNotFound     = 0x0001
+	statusExists       = 0x0002
+	statusNotStored    = 0x0005
+	statusAuthError    = 0x0020
+	statusAuthContinue = 0x0021
+
+	headerLen = 24
+)
+
+// ErrAuth is returned when the server rejects the credentials.
+var ErrAuth = errors.New("memcached: authentication failed")
+
+// client are the commands of the store, implemented by gomemcache and by
+// binaryClient.
+type client interface {
+	Get(key string) (*mc.Item, error)
+	Set(item *mc.Item) error
+	Delete(key string) error
+}
+
+// binaryClient speaks the binary protocol, as servers with SASL enabled
+// only accept it. Connections authenticate with SASL PLAIN when they're
+// opened and are pooled per server.
+type binaryClient struct {
+	selector mc.ServerSelector
+	username string
+	password string
+	timeout  time.Duration
+
+	sync.Mutex
+	free map[string][]*conn
+}
+
+type conn struct {
+	nc   net.Conn
+	rw   *bufio.ReadWriter
+	addr net.Addr
+}
+
+func newBinaryClient(selector mc.ServerSelector, username, password string) *binaryClient {
+	return &binaryClient{
+		selector: selector,
+		username: username,
+		password: password,
+		timeout:  mc.DefaultTimeout,
+		free:     make(map[string][]*conn),
+	}
+}
+
+func (b *binaryClient) Get(key string) (*mc.Item, error) {
+	var item *mc.Item
+	err := b.do(key, opGet, nil, nil, func(extras, value []byte) {
+		item = &mc.Item{Key: key, Value: value}
+		if len(extras) >= 4 {
+			item.Flags = binary.BigEndian.Uint32(extras)
+		}
+	})
+	return item, err
+}
+
+func (b *binaryClient) Set(item *mc.Item) error {
+	extras := make([]byte, 8)
+	binary.BigEndian.PutUint32(extras, item.Flags)
+	binary.BigEndian.PutUint32(extras[4:], uint32(item.Expiration))
+	return b.do(item.Key, opSet, extras, item.Value, nil)
+}
+
+func (b *binaryClient) Delete(key string) error {
+	return b.do(key, opDelete, nil, nil, nil)
+}
+
+// do sends the request to the server of the key and calls fn with the
+// extras and value of a successful response.
+func (b *binaryClient) do(key string, op byte, extras, value []byte, fn func(extras, value []byte)) error {
+	if len(key) == 0 || len(key) > 250 {
+		return mc.ErrMalformedKey
+	}
+
+	addr, err := b.selector.PickServer(key)
+	if err != nil {
+		return err
+	}
+
+	c, err := b.conn(addr)
+	if err != nil {
+		return err
+	}
+
+	c.nc.SetDeadline(time.Now().Add(b.timeout))
+
+	status, rextras, rvalue, err := c.roundTrip(op, []byte(key), extras, value)
+	if err != nil {
+		// the connection is in an unknown state
+		c.nc.Close()
+		return err
+	}
+	b.put(c)
+
+	switch status {
+	case statusOK:
+		if fn != nil {
+			fn(rextras, rvalue)
+		}
+		return nil
+	case statusNotFound:
+		return mc.ErrCacheMiss
+	case statusExists:
+		return mc.ErrCASConflict
+	case statusNotStored:
+		return mc.ErrNotStored
+	default:
+		return fmt.Errorf("memcached: status %#x: %s", status, rvalue)
+	}
+}
+
+func (b *binaryClient) conn(addr net.Addr) (*conn, error) {
+	b.Lock()
+	if free := b.free[addr.String()]; len(free) > 0 {
+		c := free[len(free)-1]
+		b.free[addr.String()] = free[:len(free)-1]
+		b.Unlock()
+		return c, nil
+	}
+	b.Unlock()
+
+	nc, err := net.DialTimeout(addr.Network(), addr.String(), b.timeout)
+	if err != nil {
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			return nil, &mc.ConnectTimeoutError{Addr: addr}
+		}
+		return nil, err
+	}
+
+	c := &conn{
+		nc:   nc,
+		rw:   bufio.NewReadWriter(bufio.NewReader(nc), bufio.NewWriter(nc)),
+		addr: addr,
+	}
+
+	if err := c.auth(b.username, b.password, b.timeout); err != nil {
+		nc.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (b *binaryClient) put(c *conn) {
+	b.Lock()
+	defer b.Unlock()
+
+	free := b.free[c.addr.String()]
+	if len(free) >= mc.DefaultMaxIdleConns {
+		c.nc.Close()
+		return
+	}
+	b.free[c.addr.String()] = append(free, c)
+}
+
+// auth authenticates the connection with SASL PLAIN.
+func (c *conn) auth(username, password string, timeout time.Duration) error {
+	c.nc.SetDeadline(time.Now().Add(timeout))
+
+	creds := []byte("\x00" + username + "\x00" + password)
+	status, _, _, err := c.roundTrip(opSASLAuth, []byte("PLAIN"), nil, creds)
+	if err != nil {
+		return err
+	}
+	switch status {
+	case statusOK:
+		return nil
+	case statusAuthError, statusAuthContinue:
+		return ErrAuth
+	default:
+		return fmt.Errorf("memcached: sasl status %#x", status)
+	}
+}
+
+func (c *conn) roundTrip(op byte, key, extras, value []byte) (uint16, []byte, []byte, error) {
+	header := make([]byte, headerLen)
+	header[0] = magicRequest
+	header[1] = op
+	binary.BigEndian.PutUint16(header[2:], uint16(len(key)))
+	header[4] = byte(len(extras))
+	binary.BigEndian.PutUint32(header[8:], uint32(len(extras)+len(key)+len(value)))
+
+	for _, p := range [][]byte{header, extras, key, value} {
+		if _, err := c.rw.Write(p); err != nil {
+			return 0, nil, nil, err
+		}
+	}
+	if err := c.rw.Flush(); err != nil {
+		return 0, nil, nil, err
+	}
+
+	if _, err := io.ReadFull(c.rw, header); err != nil {
+		return 0, nil, nil, err
+	}
+	if header[0] != magicResponse {
+		return 0, nil, nil, fmt.Errorf("memcached: invalid response magic %#x", header[0])
+	}
+
+	keyLen := int(binary.BigEndian.Uint16(header[2:]))
+	extrasLen := int(header[4])
+	status := binary.BigEndian.Uint16(header[6:])
+	bodyLen := int(binary.BigEndian.Uint32(header[8:]))
+	if bodyLen < extrasLen+keyLen {
+		return 0, nil, nil, fmt.Errorf("memcached: invalid response length %d", bodyLen)
+	}
+
+	body := make([]byte, bodyLen)
+	if _, err := io.ReadFull(c.rw, body); err != nil {
+		return 0, nil, nil, err
+	}
+
+	return status, body[:extrasLen], body[extrasLen+keyLen:], nil
+}
@@ -0,0 +1,360 @@
+package memcached
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	mc "github.com/bradfitz/gomemcache/memcache"
+	"go-micro.dev/v4/store"
+)
+
+type request struct {
+	op     byte
+	key    []byte
+	extras []byte
+	value  []byte
+}
+
+func readRequest(r io.Reader) (*request, error) {
+	header := make([]byte, headerLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	keyLen := int(binary.BigEndian.Uint16(header[2:]))
+	extrasLen := int(header[4])
+	body := make([]byte, binary.BigEndian.Uint32(header[8:]))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+
+	return &request{
+		op:     header[1],
+		extras: body[:extrasLen],
+		key:    body[extrasLen : extrasLen+keyLen],
+		value:  body[extrasLen+keyLen:],
+	}, nil
+}
+
+func writeResponse(w io.Writer, op byte, status uint16, extras, value []byte) error {
+	header := make([]byte, headerLen)
+	header[0] = magicResponse
+	header[1] = op
+	header[4] = byte(len(extras))
+	binary.BigEndian.PutUint16(header[6:], status)
+	binary.BigEndian.PutUint32(header[8:], uint32(len(extras)+len(value)))
+
+	for _, p := range [][]byte{header, extras, value} {
+		if _, err := w.Write(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRoundTripFraming(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	c := &conn{
+		nc: client,
+		rw: bufio.NewReadWriter(bufio.NewReader(client), bufio.NewWriter(client)),
+	}
+
+	extras := []byte{0, 0, 0, 1, 0, 0, 0, 60}
+
+	errs := make(chan error, 1)
+	go func() {
+		header := make([]byte, headerLen)
+		if _, err := io.ReadFull(server, header); err != nil {
+			errs <- err
+			return
+		}
+
+		expected := make([]byte, headerLen)
+		expected[0] = magicRequest
+		expected[1] = opSet
+		// key length
+		expected[3] = 3
+		expected[4] = byte(len(extras))
+		// total body length
+		expected[11] = byte(len(extras) + 3 + 5)
+		if !bytes.Equal(header, expected) {
+			t.Errorf("expected header %x, got %x", expected, header)
+		}
+
+		body := make([]byte, len(extras)+3+5)
+		if _, err := io.ReadFull(server, body); err != nil {
+			errs <- err
+			return
+		}
+		if !bytes.Equal(body, append(append(append([]byte{}, extras...), "key"...), "value"...)) {
+			t.Errorf("unexpected body %q", body)
+		}
+
+		// responses may echo the key, it's skipped
+		resp := make([]byte, headerLen)
+		resp[0] = magicResponse
+		resp[1] = opSet
+		resp[3] = 3
+		resp[4] = 4
+		binary.BigEndian.PutUint16(resp[6:], statusExists)
+		binary.BigEndian.PutUint32(resp[8:], 4+3+2)
+		_, err := server.Write(append(resp, "flagkeyok"...))
+		errs <- err
+	}()
+
+	status, rextras, rvalue, err := c.roundTrip(opSet, []byte("key"), extras, []byte("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	if status != statusExists {
+		t.Fatalf("expected status %#x, got %#x", statusExists, status)
+	}
+	if string(rextras) != "flag" || string(rvalue) != "ok" {
+		t.Fatalf("unexpected extras %q and value %q", rextras, rvalue)
+	}
+}
+
+func TestRoundTripInvalidResponse(t *testing.T) {
+	testData := []struct {
+		name string
+		resp func() []byte
+	}{
+		{"magic", func() []byte {
+			resp := make([]byte, headerLen)
+			resp[0] = magicRequest
+			return resp
+		}},
+		{"length", func() []byte {
+			resp := make([]byte, headerLen)
+			resp[0] = magicResponse
+			resp[4] = 8
+			binary.BigEndian.PutUint32(resp[8:], 4)
+			return resp
+		}},
+	}
+
+	for _, d := range testData {
+		t.Run(d.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer client.Close()
+			defer server.Close()
+
+			c := &conn{
+				nc: client,
+				rw: bufio.NewReadWriter(bufio.NewReader(client), bufio.NewWriter(client)),
+			}
+
+			go func() {
+				if _, err := readRequest(server); err == nil {
+					server.Write(d.resp())
+				}
+			}()
+
+			if _, _, _, err := c.roundTrip(opGet, []byte("key"), nil, nil); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+// fakeServer is a memcached server speaking the binary protocol with SASL
+// PLAIN.
+type fakeServer struct {
+	l                  net.Listener
+	username, password string
+
+	sync.Mutex
+	items map[string][]byte
+	auths int
+}
+
+func newFakeServer(t *testing.T, username, password string) *fakeServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &fakeServer{
+		l:        l,
+		username: username,
+		password: password,
+		items:    make(map[string][]byte),
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(c)
+		}
+	}()
+
+	return s
+}
+
+func (s *fakeServer) serve(c net.Conn) {
+	defer c.Close()
+
+	var authed bool
+	for {
+		req, err := readRequest(c)
+		if err != nil {
+			return
+		}
+
+		s.Lock()
+		status, extras, value := uint16(statusOK), []byte(nil), []byte(nil)
+		switch {
+		case req.op == opSASLAuth:
+			s.auths++
+			if string(req.key) == "PLAIN" && string(req.value) == "\x00"+s.username+"\x00"+s.password {
+				authed = true
+			} else {
+				status = statusAuthError
+			}
+		case !authed:
+			status = statusAuthError
+		case req.op == opGet:
+			v, ok := s.items[string(req.key)]
+			if ok {
+				extras, value = []byte{0, 0, 0, 0}, v
+			} else {
+				status = statusNotFound
+			}
+		case req.op == opSet:
+			s.items[string(req.key)] = req.value
+		case req.op == opDelete:
+			if _, ok := s.items[string(req.key)]; ok {
+				delete(s.items, string(req.key))
+			} else {
+				status = statusNotFound
+			}
+		}
+		s.Unlock()
+
+		if err := writeResponse(c, req.op, status, extras, value); err != nil {
+			return
+		}
+	}
+}
+
+func TestBinaryClient(t *testing.T) {
+	s := newFakeServer(t, "user", "secret")
+
+	m := NewStore(store.Nodes(s.l.Addr().String()), Auth("user", "secret"))
+
+	if err := m.Write(&store.Record{Key: "foo", Value: []byte("bar"), Expiry: time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := m.Read("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || string(records[0].Value) != "bar" {
+		t.Fatalf("unexpected records %v", records)
+	}
+
+	if err := m.Delete("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Read("foo"); err != store.ErrNotFound {
+		t.Fatalf("expected %v, got %v", store.ErrNotFound, err)
+	}
+	if err := m.Delete("foo"); err != mc.ErrCacheMiss {
+		t.Fatalf("expected %v, got %v", mc.ErrCacheMiss, err)
+	}
+
+	if _, err := m.List(); err != ErrListAuth {
+		t.Fatalf("expected %v, got %v", ErrListAuth, err)
+	}
+
+	// connections are pooled and authenticated once
+	s.Lock()
+	auths := s.auths
+	s.Unlock()
+	if auths != 1 {
+		t.Fatalf("expected one authentication, got %d", auths)
+	}
+}
+
+func TestBinaryClientAuthError(t *testing.T) {
+	s := newFakeServer(t, "user", "secret")
+
+	m := NewStore(store.Nodes(s.l.Addr().String()), Auth("user", "wrong"))
+
+	if err := m.Write(&store.Record{Key: "foo", Value: []byte("bar")}); err != ErrAuth {
+		t.Fatalf("expected %v, got %v", ErrAuth, err)
+	}
+}
+
+func TestBinaryClientMalformedKey(t *testing.T) {
+	b := newBinaryClient(serverAddr{&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1}}, "user", "secret")
+
+	for _, key := range []string{"", string(make([]byte, 251))} {
+		if _, err := b.Get(key); err != mc.ErrMalformedKey {
+			t.Fatalf("expected %v, got %v", mc.ErrMalformedKey, err)
+		}
+	}
+}
+
+func TestEjection(t *testing.T) {
+	s := newFakeServer(t, "user", "secret")
+
+	// nothing listens on the second server
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := l.Addr().String()
+	l.Close()
+
+	m := NewStore(
+		store.Nodes(s.l.Addr().String(), down),
+		Auth("user", "secret"),
+		EjectAfter(1),
+		EjectTimeout(time.Minute),
+	).(*mkv)
+
+	// find a key of the server which is down
+	var key string
+	for i := 0; ; i++ {
+		key = fmt.Sprintf("key-%d", i)
+		if addr, _ := m.ring.PickServer(key); addr.String() == down {
+			break
+		}
+	}
+
+	if err := m.Write(&store.Record{Key: key, Value: []byte("bar")}); err == nil {
+		t.Fatal("expected error writing to the server which is down")
+	}
+
+	if _, ok := m.ring.ejected[down]; !ok {
+		t.Fatalf("expected %s to be ejected", down)
+	}
+
+	// the key moved to the other server
+	if err := m.Write(&store.Record{Key: key, Value: []byte("bar")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.ring.ejected[s.l.Addr().String()]; ok {
+		t.Fatal("expected the server which is up not to be ejected")
+	}
+}
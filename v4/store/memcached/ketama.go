@@ -0,0 +1,218 @@
+package memcached
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	mc "github.com/bradfitz/gomemcache/memcache"
+)
+
+// pointsPerServer is the number of points of every server on the ring, 40
+// hashes of 4 points, as libmemcached's ketama does.
+const pointsPerServer = 160
+
+type point struct {
+	hash uint32
+	addr net.Addr
+}
+
+// ketama is a consistent hashing server selector: every server owns many
+// points on a ring of hashes and a key goes to the server of the next point,
+// so adding or removing a server only moves the keys of its points. Servers
+// failing too often are ejected from the ring for a while.
+type ketama struct {
+	ejectAfter   int
+	ejectTimeout time.Duration
+	now          func() time.Time
+
+	sync.RWMutex
+	addrs    []net.Addr
+	points   []point
+	failures map[string]int
+	// ejected servers and when they're retried
+	ejected map[string]time.Time
+	// earliest retry of an ejected server
+	retry time.Time
+}
+
+func newKetama(servers []string, ejectAfter int, ejectTimeout time.Duration) (*ketama, error) {
+	k := &ketama{
+		ejectAfter:   ejectAfter,
+		ejectTimeout: ejectTimeout,
+		now:          time.Now,
+		failures:     make(map[string]int),
+		ejected:      make(map[string]time.Time),
+	}
+
+	for _, s := range servers {
+		addr, err := resolve(s)
+		if err != nil {
+			return nil, err
+		}
+		k.addrs = append(k.addrs, addr)
+	}
+
+	k.build()
+	return k, nil
+}
+
+func resolve(server string) (net.Addr, error) {
+	if strings.Contains(server, "/") {
+		return net.ResolveUnixAddr("unix", server)
+	}
+	return net.ResolveTCPAddr("tcp", server)
+}
+
+// build places the servers which aren't ejected on the ring.
+func (k *ketama) build() {
+	points := make([]point, 0, len(k.addrs)*pointsPerServer)
+	for _, addr := range k.addrs {
+		if _, ok := k.ejected[addr.String()]; ok {
+			continue
+		}
+		for i := 0; i < pointsPerServer/4; i++ {
+			sum := md5.Sum([]byte(fmt.Sprintf("%s-%d", addr.String(), i)))
+			for j := 0; j < 4; j++ {
+				points = append(points, point{hash: binary.LittleEndian.Uint32(sum[j*4:]), addr: addr})
+			}
+		}
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].hash < points[j].hash })
+	k.points = points
+}
+
+func hash(key string) uint32 {
+	sum := md5.Sum([]byte(key))
+	return binary.LittleEndian.Uint32(sum[:4])
+}
+
+// readmit puts the ejected servers whose timeout passed back on the ring.
+func (k *ketama) readmit() {
+	k.RLock()
+	due := len(k.ejected) > 0 && !k.now().Before(k.retry)
+	k.RUnlock()
+	if !due {
+		return
+	}
+
+	k.Lock()
+	defer k.Unlock()
+
+	now := k.now()
+	k.retry = time.Time{}
+	for addr, at := range k.ejected {
+		if !now.Before(at) {
+			delete(k.ejected, addr)
+			delete(k.failures, addr)
+			continue
+		}
+		if k.retry.IsZero() || at.Before(k.retry) {
+			k.retry = at
+		}
+	}
+	k.build()
+}
+
+func (k *ketama) PickServer(key string) (net.Addr, error) {
+	k.readmit()
+
+	k.RLock()
+	defer k.RUnlock()
+
+	if len(k.points) == 0 {
+		return nil, mc.ErrNoServers
+	}
+
+	h := hash(key)
+	i := sort.Search(len(k.points), func(i int) bool { return k.points[i].hash >= h })
+	if i == len(k.points) {
+		i = 0
+	}
+	return k.points[i].addr, nil
+}
+
+// Each calls f for every server on the ring.
+func (k *ketama) Each(f func(net.Addr) error) error {
+	k.readmit()
+
+	k.RLock()
+	addrs := make([]net.Addr, 0, len(k.addrs))
+	for _, addr := range k.addrs {
+		if _, ok := k.ejected[addr.String()]; !ok {
+			addrs = append(addrs, addr)
+		}
+	}
+	k.RUnlock()
+
+	for _, addr := range addrs {
+		if err := f(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// result records the outcome of a request to the server. The server is
+// ejected once it failed ejectAfter times in a row.
+func (k *ketama) result(addr net.Addr, err error) {
+	if k.ejectAfter <= 0 {
+		return
+	}
+
+	a := addr.String()
+
+	k.Lock()
+	defer k.Unlock()
+
+	if !failed(err) {
+		delete(k.failures, a)
+		return
+	}
+
+	k.failures[a]++
+	if k.failures[a] < k.ejectAfter {
+		return
+	}
+
+	// already ejected by a concurrent request
+	if _, ok := k.ejected[a]; ok {
+		return
+	}
+
+	at := k.now().Add(k.ejectTimeout)
+	k.ejected[a] = at
+	if k.retry.IsZero() || at.Before(k.retry) {
+		k.retry = at
+	}
+	k.build()
+}
+
+// failed reports whether the error is a failure of the server rather than a
+// response of it.
+func failed(err error) bool {
+	switch err {
+	case nil, mc.ErrCacheMiss, mc.ErrCASConflict, mc.ErrNotStored, mc.ErrMalformedKey, mc.ErrNoStats:
+		return false
+	}
+	return true
+}
+
+// serverAddr selects a single server, the one picked from the ring for the
+// request.
+type serverAddr struct {
+	net.Addr
+}
+
+func (s serverAddr) PickServer(string) (net.Addr, error) {
+	return s.Addr, nil
+}
+
+func (s serverAddr) Each(f func(net.Addr) error) error {
+	return f(s.Addr)
+}
@@ -0,0 +1,195 @@
+package memcached
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	mc "github.com/bradfitz/gomemcache/memcache"
+)
+
+var testServers = []string{"10.0.0.1:11211", "10.0.0.2:11211", "10.0.0.3:11211", "10.0.0.4:11211"}
+
+func pick(t *testing.T, k *ketama, key string) string {
+	addr, err := k.PickServer(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return addr.String()
+}
+
+func TestKetamaDistribution(t *testing.T) {
+	k, err := newKetama(testServers, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(k.points) != len(testServers)*pointsPerServer {
+		t.Fatalf("expected %d points, got %d", len(testServers)*pointsPerServer, len(k.points))
+	}
+
+	const keys = 10000
+
+	counts := make(map[string]int)
+	before := make(map[string]string)
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		addr := pick(t, k, key)
+		counts[addr]++
+		before[key] = addr
+	}
+
+	// every server owns a fair share of the keys
+	for _, s := range testServers {
+		share := float64(counts[s]) / keys
+		if share < 0.15 || share > 0.35 {
+			t.Errorf("server %s owns %.2f of the keys", s, share)
+		}
+	}
+
+	// the same key always goes to the same server
+	for key, addr := range before {
+		if a := pick(t, k, key); a != addr {
+			t.Fatalf("key %s moved from %s to %s", key, addr, a)
+		}
+	}
+
+	// removing a server only moves its own keys
+	k, err = newKetama(testServers[:3], 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, addr := range before {
+		a := pick(t, k, key)
+		if addr != testServers[3] && a != addr {
+			t.Fatalf("key %s moved from %s to %s", key, addr, a)
+		}
+		if a == testServers[3] {
+			t.Fatalf("key %s still on the removed server", key)
+		}
+	}
+}
+
+func TestKetamaEmpty(t *testing.T) {
+	k, err := newKetama(nil, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.PickServer("key"); err != mc.ErrNoServers {
+		t.Fatalf("expected %v, got %v", mc.ErrNoServers, err)
+	}
+}
+
+func TestKetamaEjection(t *testing.T) {
+	now := time.Unix(0, 0)
+
+	k, err := newKetama(testServers, 2, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.now = func() time.Time { return now }
+
+	addr, err := k.PickServer("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fail := errors.New("connection refused")
+
+	testData := []struct {
+		err     error
+		ejected bool
+	}{
+		{fail, false},
+		// responses of the server reset the failures
+		{mc.ErrCacheMiss, false},
+		{fail, false},
+		{fail, true},
+	}
+
+	for i, d := range testData {
+		k.result(addr, d.err)
+		_, ejected := k.ejected[addr.String()]
+		if ejected != d.ejected {
+			t.Fatalf("result %d: expected ejected %v, got %v", i, d.ejected, ejected)
+		}
+	}
+
+	if len(k.points) != (len(testServers)-1)*pointsPerServer {
+		t.Fatalf("expected the points of the ejected server to be removed, got %d", len(k.points))
+	}
+	if a := pick(t, k, "key"); a == addr.String() {
+		t.Fatal("expected the key to move to another server")
+	}
+
+	var each []string
+	k.Each(func(a net.Addr) error {
+		each = append(each, a.String())
+		return nil
+	})
+	if len(each) != len(testServers)-1 {
+		t.Fatalf("expected the ejected server to be skipped, got %v", each)
+	}
+
+	// failures reported late don't eject the next server of the key
+	k.result(addr, fail)
+	k.result(addr, fail)
+	if len(k.ejected) != 1 {
+		t.Fatalf("expected only %s to be ejected, got %v", addr, k.ejected)
+	}
+
+	// not retried before the timeout
+	now = now.Add(30 * time.Second)
+	if a := pick(t, k, "key"); a == addr.String() {
+		t.Fatal("expected the server to be ejected until the timeout")
+	}
+
+	now = now.Add(30 * time.Second)
+	if a := pick(t, k, "key"); a != addr.String() {
+		t.Fatalf("expected the key to be back on %s, got %s", addr, a)
+	}
+	if len(k.ejected) != 0 || len(k.failures) != 0 {
+		t.Fatalf("expected the server to be readmitted, got %v %v", k.ejected, k.failures)
+	}
+	if len(k.points) != len(testServers)*pointsPerServer {
+		t.Fatalf("expected %d points, got %d", len(testServers)*pointsPerServer, len(k.points))
+	}
+}
+
+func TestKetamaNoEjection(t *testing.T) {
+	k, err := newKetama(testServers, 0, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr, _ := k.PickServer("key")
+	for i := 0; i < 10; i++ {
+		k.result(addr, errors.New("connection refused"))
+	}
+	if len(k.ejected) != 0 {
+		t.Fatal("expected servers not to be ejected by default")
+	}
+}
+
+func TestFailed(t *testing.T) {
+	testData := []struct {
+		err    error
+		failed bool
+	}{
+		{nil, false},
+		{mc.ErrCacheMiss, false},
+		{mc.ErrCASConflict, false},
+		{mc.ErrNotStored, false},
+		{mc.ErrMalformedKey, false},
+		{mc.ErrServerError, true},
+		{&mc.ConnectTimeoutError{}, true},
+		{errors.New("connection refused"), true},
+	}
+
+	for _, d := range testData {
+		if f := failed(d.err); f != d.failed {
+			t.Errorf("failed(%v) = %v, expected %v", d.err, f, d.failed)
+		}
+	}
+}
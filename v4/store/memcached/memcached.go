@@ -3,6 +3,7 @@ package memcached
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -15,10 +16,15 @@ import (
 	"go-micro.dev/v4/util/cmd"
 )
 
+// ErrListAuth is returned by List with SASL, which needs the text protocol.
+var ErrListAuth = errors.New("memcached: list isn't supported with sasl auth")
+
 type mkv struct {
 	options store.Options
-	Server  *mc.ServerList
-	Client  *mc.Client
+	ring    *ketama
+	// clients of every server, by address
+	clients map[string]client
+	auth    bool
 }
 
 func init() {
@@ -45,7 +51,11 @@ func (m *mkv) Read(key string, opts ...store.ReadOption) ([]*store.Record, error
 	// TODO: implement read options
 	records := make([]*store.Record, 0, 1)
 
-	keyval, err := m.Client.Get(key)
+	var keyval *mc.Item
+	err := m.do(key, func(c client) (err error) {
+		keyval, err = c.Get(key)
+		return err
+	})
 	if err != nil && err == mc.ErrCacheMiss {
 		return nil, store.ErrNotFound
 	} else if err != nil {
@@ -66,27 +76,48 @@ func (m *mkv) Read(key string, opts ...store.ReadOption) ([]*store.Record, error
 }
 
 func (m *mkv) Delete(key string, opts ...store.DeleteOption) error {
-	return m.Client.Delete(key)
+	return m.do(key, func(c client) error {
+		return c.Delete(key)
+	})
 }
 
 func (m *mkv) Write(record *store.Record, opts ...store.WriteOption) error {
-	return m.Client.Set(&mc.Item{
-		Key:        record.Key,
-		Value:      record.Value,
-		Expiration: int32(record.Expiry.Seconds()),
+	return m.do(record.Key, func(c client) error {
+		return c.Set(&mc.Item{
+			Key:        record.Key,
+			Value:      record.Value,
+			Expiration: int32(record.Expiry.Seconds()),
+		})
 	})
 }
 
+// do sends the request to the server of the key and records the outcome
+// against that server, even if the ring changed meanwhile.
+func (m *mkv) do(key string, fn func(client) error) error {
+	addr, err := m.ring.PickServer(key)
+	if err != nil {
+		return err
+	}
+
+	err = fn(m.clients[addr.String()])
+	m.ring.result(addr, err)
+	return err
+}
+
 func (m *mkv) List(opts ...store.ListOption) ([]string, error) {
 	// stats
 	// cachedump
 	// get keys
 
+	if m.auth {
+		return nil, ErrListAuth
+	}
+
 	var keys []string
 
 	// store := make(map[string]string)
-	if err := m.Server.Each(func(c net.Addr) error {
-		cc, err := net.Dial("tcp", c.String())
+	if err := m.ring.Each(func(c net.Addr) error {
+		cc, err := net.Dial(c.Network(), c.String())
 		if err != nil {
 			return err
 		}
@@ -185,11 +216,27 @@ func (m *mkv) configure() error {
 		nodes = []string{"127.0.0.1:11211"}
 	}
 
-	ss := new(mc.ServerList)
-	ss.SetServers(nodes...)
+	// keys are spread across the servers by consistent hashing
+	ejectAfter, ejectTimeout := getEject(m.options)
+	ring, err := newKetama(nodes, ejectAfter, ejectTimeout)
+	if err != nil {
+		return err
+	}
+
+	a, auth := getAuth(m.options)
+
+	clients := make(map[string]client, len(ring.addrs))
+	for _, addr := range ring.addrs {
+		if auth {
+			clients[addr.String()] = newBinaryClient(serverAddr{addr}, a.username, a.password)
+		} else {
+			clients[addr.String()] = mc.NewFromSelector(serverAddr{addr})
+		}
+	}
 
-	m.Server = ss
-	m.Client = mc.New(nodes...)
+	m.ring = ring
+	m.clients = clients
+	m.auth = auth
 
 	return nil
 }
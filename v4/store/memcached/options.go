@@ -0,0 +1,70 @@
+package memcached
+
+import (
+	"context"
+	"time"
+
+	"go-micro.dev/v4/store"
+)
+
+type authKey struct{}
+type ejectAfterKey struct{}
+type ejectTimeoutKey struct{}
+
+// DefaultEjectTimeout is how long a failing server is ejected for.
+var DefaultEjectTimeout = 30 * time.Second
+
+type auth struct {
+	username, password string
+}
+
+// Auth authenticates with SASL PLAIN, as managed memcached offerings
+// require. Servers with SASL enabled only speak the binary protocol, which
+// the store then uses. List isn't supported by it.
+func Auth(username, password string) store.Option {
+	return setStoreOption(authKey{}, auth{username: username, password: password})
+}
+
+// EjectAfter ejects a server from the hash ring after n failed requests in
+// a row, so its keys move to the other servers until it's retried. Servers
+// are never ejected by default.
+func EjectAfter(n int) store.Option {
+	return setStoreOption(ejectAfterKey{}, n)
+}
+
+// EjectTimeout sets how long a failing server is ejected for, before it's
+// put back on the ring. Defaults to DefaultEjectTimeout.
+func EjectTimeout(d time.Duration) store.Option {
+	return setStoreOption(ejectTimeoutKey{}, d)
+}
+
+func setStoreOption(k, v interface{}) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+func getAuth(o store.Options) (auth, bool) {
+	if o.Context == nil {
+		return auth{}, false
+	}
+	a, ok := o.Context.Value(authKey{}).(auth)
+	return a, ok
+}
+
+func getEject(o store.Options) (int, time.Duration) {
+	n, d := 0, DefaultEjectTimeout
+	if o.Context == nil {
+		return n, d
+	}
+	if v, ok := o.Context.Value(ejectAfterKey{}).(int); ok {
+		n = v
+	}
+	if v, ok := o.Context.Value(ejectTimeoutKey{}).(time.Duration); ok {
+		d = v
+	}
+	return n, d
+}
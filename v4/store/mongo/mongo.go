@@ -0,0 +1,339 @@
+// Package mongo implements the store on MongoDB.
+package mongo
+
+import (
+	"context"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "go-micro.dev/v4/logger"
+	"go-micro.dev/v4/store"
+	"go-micro.dev/v4/util/cmd"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+var (
+	// DefaultURI is the connection string used if no node is provided.
+	DefaultURI = "mongodb://localhost:27017"
+	// DefaultDatabase is the database used if none is provided.
+	DefaultDatabase = "micro"
+	// DefaultTable is the collection used if no table is provided.
+	DefaultTable = "micro"
+)
+
+// expiryField holds the expiry of a record. A TTL index on it has MongoDB
+// delete expired records, until then they're skipped on reads.
+const expiryField = "expireAt"
+
+// document is a record as stored in MongoDB.
+type document struct {
+	Key      string                 `bson:"_id"`
+	Value    []byte                 `bson:"value"`
+	Metadata map[string]interface{} `bson:"metadata,omitempty"`
+	ExpireAt *time.Time             `bson:"expireAt,omitempty"`
+}
+
+type mongoStore struct {
+	ctx     context.Context
+	options store.Options
+	client  *mongo.Client
+
+	sync.RWMutex
+	// collections whose TTL index was created
+	indexed map[string]bool
+}
+
+func init() {
+	cmd.DefaultStores["mongo"] = NewStore
+}
+
+// NewStore returns a new store backed by MongoDB. The node is the
+// connection string, databases are databases and tables collections.
+func NewStore(opts ...store.Option) store.Store {
+	options := store.Options{
+		Database: DefaultDatabase,
+		Table:    DefaultTable,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	s := &mongoStore{
+		ctx:     context.Background(),
+		options: options,
+		indexed: make(map[string]bool),
+	}
+
+	if err := s.configure(); err != nil {
+		log.Fatal(err)
+	}
+
+	return s
+}
+
+func (s *mongoStore) configure() error {
+	client, err := mongo.Connect(s.ctx, clientOptions(s.options)...)
+	if err != nil {
+		return errors.Wrap(err, "connecting to mongo")
+	}
+
+	s.Lock()
+	if s.client != nil {
+		s.client.Disconnect(s.ctx)
+	}
+	s.client = client
+	s.indexed = make(map[string]bool)
+	s.Unlock()
+
+	return nil
+}
+
+func (s *mongoStore) Init(opts ...store.Option) error {
+	for _, o := range opts {
+		o(&s.options)
+	}
+	return s.configure()
+}
+
+func (s *mongoStore) Options() store.Options {
+	return s.options
+}
+
+func (s *mongoStore) Close() error {
+	return s.client.Disconnect(s.ctx)
+}
+
+func (s *mongoStore) String() string {
+	return "mongo"
+}
+
+func (s *mongoStore) collection(database, table string, opts ...*mopts.CollectionOptions) *mongo.Collection {
+	if len(database) == 0 {
+		database = s.options.Database
+	}
+	if len(table) == 0 {
+		table = s.options.Table
+	}
+	return s.client.Database(database).Collection(table, opts...)
+}
+
+// readOptions are the options of the collections read from, which read
+// from secondaries with SecondaryReads.
+func (s *mongoStore) readOptions() *mopts.CollectionOptions {
+	co := mopts.Collection()
+	if getSecondaryReads(s.options) {
+		co.SetReadPreference(readpref.SecondaryPreferred())
+	}
+	return co
+}
+
+// ensureIndex creates the TTL index of the collection on the first write to
+// it.
+func (s *mongoStore) ensureIndex(ctx context.Context, col *mongo.Collection) error {
+	name := col.Database().Name() + "." + col.Name()
+
+	s.RLock()
+	ok := s.indexed[name]
+	s.RUnlock()
+	if ok {
+		return nil
+	}
+
+	_, err := col.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: expiryField, Value: 1}},
+		Options: mopts.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return errors.Wrap(err, "creating ttl index")
+	}
+
+	s.Lock()
+	s.indexed[name] = true
+	s.Unlock()
+	return nil
+}
+
+// filter selects the records which haven't expired yet, whose key has the
+// prefix and suffix. Prefixes are anchored regular expressions, which use
+// the index of the key.
+func filter(prefix, suffix string, now time.Time) bson.D {
+	f := bson.D{{Key: expiryField, Value: bson.D{{Key: "$not", Value: bson.D{{Key: "$lte", Value: now}}}}}}
+
+	var keys bson.A
+	if len(prefix) > 0 {
+		keys = append(keys, bson.D{{Key: "_id", Value: bson.D{{Key: "$regex", Value: "^" + regexp.QuoteMeta(prefix)}}}})
+	}
+	if len(suffix) > 0 {
+		keys = append(keys, bson.D{{Key: "_id", Value: bson.D{{Key: "$regex", Value: regexp.QuoteMeta(suffix) + "$"}}}})
+	}
+
+	if len(keys) > 0 {
+		f = append(f, bson.E{Key: "$and", Value: keys})
+	}
+	return f
+}
+
+// findOptions sorts by key and applies the offset and limit.
+func findOptions(offset, limit uint) *mopts.FindOptions {
+	opts := mopts.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	if offset > 0 {
+		opts.SetSkip(int64(offset))
+	}
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	return opts
+}
+
+func (d *document) record(now time.Time) *store.Record {
+	r := &store.Record{
+		Key:      d.Key,
+		Value:    d.Value,
+		Metadata: d.Metadata,
+	}
+	if r.Metadata == nil {
+		r.Metadata = make(map[string]interface{})
+	}
+	if d.ExpireAt != nil {
+		r.Expiry = d.ExpireAt.Sub(now)
+	}
+	return r
+}
+
+func (s *mongoStore) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	return s.read(s.ctx, s.readOptions(), key, opts...)
+}
+
+func (s *mongoStore) read(ctx context.Context, co *mopts.CollectionOptions, key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	var options store.ReadOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	col := s.collection(options.Database, options.Table, co)
+	now := time.Now()
+
+	if !options.Prefix && !options.Suffix {
+		f := append(filter("", "", now), bson.E{Key: "_id", Value: key})
+
+		var d document
+		err := col.FindOne(ctx, f).Decode(&d)
+		if err == mongo.ErrNoDocuments {
+			return nil, store.ErrNotFound
+		} else if err != nil {
+			return nil, err
+		}
+		return []*store.Record{d.record(now)}, nil
+	}
+
+	var prefix, suffix string
+	if options.Prefix {
+		prefix = key
+	}
+	if options.Suffix {
+		suffix = key
+	}
+
+	cur, err := col.Find(ctx, filter(prefix, suffix, now), findOptions(options.Offset, options.Limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var records []*store.Record
+	for cur.Next(ctx) {
+		var d document
+		if err := cur.Decode(&d); err != nil {
+			return nil, err
+		}
+		records = append(records, d.record(now))
+	}
+
+	return records, cur.Err()
+}
+
+func (s *mongoStore) Write(r *store.Record, opts ...store.WriteOption) error {
+	return s.write(s.ctx, nil, r, opts...)
+}
+
+func (s *mongoStore) write(ctx context.Context, co *mopts.CollectionOptions, r *store.Record, opts ...store.WriteOption) error {
+	var options store.WriteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	col := s.collection(options.Database, options.Table, co)
+	if err := s.ensureIndex(ctx, col); err != nil {
+		return err
+	}
+
+	d := &document{
+		Key:      r.Key,
+		Value:    r.Value,
+		Metadata: r.Metadata,
+	}
+
+	expiry := r.Expiry
+	if options.TTL > 0 {
+		expiry = options.TTL
+	} else if !options.Expiry.IsZero() {
+		expiry = time.Until(options.Expiry)
+	}
+	if expiry > 0 {
+		at := time.Now().Add(expiry)
+		d.ExpireAt = &at
+	}
+
+	_, err := col.ReplaceOne(ctx, bson.D{{Key: "_id", Value: r.Key}}, d, mopts.Replace().SetUpsert(true))
+	return err
+}
+
+func (s *mongoStore) Delete(key string, opts ...store.DeleteOption) error {
+	return s.delete(s.ctx, nil, key, opts...)
+}
+
+func (s *mongoStore) delete(ctx context.Context, co *mopts.CollectionOptions, key string, opts ...store.DeleteOption) error {
+	var options store.DeleteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	_, err := s.collection(options.Database, options.Table, co).DeleteOne(ctx, bson.D{{Key: "_id", Value: key}})
+	return err
+}
+
+func (s *mongoStore) List(opts ...store.ListOption) ([]string, error) {
+	return s.list(s.ctx, s.readOptions(), opts...)
+}
+
+func (s *mongoStore) list(ctx context.Context, co *mopts.CollectionOptions, opts ...store.ListOption) ([]string, error) {
+	var options store.ListOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	col := s.collection(options.Database, options.Table, co)
+	find := findOptions(options.Offset, options.Limit).SetProjection(bson.D{{Key: "_id", Value: 1}})
+
+	cur, err := col.Find(ctx, filter(options.Prefix, options.Suffix, time.Now()), find)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var keys []string
+	for cur.Next(ctx) {
+		var d document
+		if err := cur.Decode(&d); err != nil {
+			return nil, err
+		}
+		keys = append(keys, d.Key)
+	}
+
+	return keys, cur.Err()
+}
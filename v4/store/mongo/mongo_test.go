@@ -0,0 +1,311 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-micro/plugins/v4/util/records"
+	"go-micro.dev/v4/store"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestFilter(t *testing.T) {
+	now := time.Now()
+
+	f := filter("user.", "", now)
+	if len(f) != 2 {
+		t.Fatalf("Expected expiry and key filters, got %v", f)
+	}
+	keys := f[1].Value.(bson.A)
+	re := keys[0].(bson.D)[0].Value.(bson.D)[0].Value
+	if re != `^user\.` {
+		t.Errorf("Expected quoted anchored prefix, got %v", re)
+	}
+
+	if f := filter("", "", now); len(f) != 1 {
+		t.Errorf("Expected only the expiry filter, got %v", f)
+	}
+}
+
+func TestPosition(t *testing.T) {
+	t1 := primitive.Timestamp{T: 1700000000, I: 3}
+	t2 := primitive.Timestamp{T: 1700000001, I: 1}
+
+	if timestamp(position(t1)) != t1 {
+		t.Fatalf("Expected %v, got %v", t1, timestamp(position(t1)))
+	}
+	if position(t1) >= position(t2) {
+		t.Error("Expected positions in operation time order")
+	}
+}
+
+// newMockStore returns a store on the mock deployment of the test, which
+// answers commands with the responses added to it.
+func newMockStore(mt *mtest.T) *mongoStore {
+	return &mongoStore{
+		ctx:     context.Background(),
+		options: store.Options{Database: "test", Table: "users"},
+		client:  mt.Client,
+		indexed: make(map[string]bool),
+	}
+}
+
+func TestWrite(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("upsert", func(mt *mtest.T) {
+		s := newMockStore(mt)
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
+		err := s.Write(&store.Record{Key: "user/1", Value: []byte("alice")}, store.WriteTTL(time.Minute))
+		if err != nil {
+			mt.Fatal(err)
+		}
+
+		// the ttl index is created on the first write
+		index := mt.GetStartedEvent()
+		if index.CommandName != "createIndexes" {
+			mt.Fatalf("Expected the ttl index to be created, got %s", index.CommandName)
+		}
+		spec := index.Command.Lookup("indexes").Array().Index(0).Value().Document()
+		if spec.Lookup("key", expiryField).Int32() != 1 || spec.Lookup("expireAfterSeconds").Int32() != 0 {
+			mt.Errorf("Expected a ttl index on %s, got %s", expiryField, spec)
+		}
+
+		update := mt.GetStartedEvent()
+		if update.CommandName != "update" || update.Command.Lookup("update").StringValue() != "users" {
+			mt.Fatalf("Expected an update of users, got %s", update.Command)
+		}
+		u := update.Command.Lookup("updates").Array().Index(0).Value().Document()
+		if !u.Lookup("upsert").Boolean() || u.Lookup("q", "_id").StringValue() != "user/1" {
+			mt.Errorf("Expected an upsert of user/1, got %s", u)
+		}
+		at := u.Lookup("u", expiryField).Time()
+		if d := time.Until(at); d <= 0 || d > time.Minute {
+			mt.Errorf("Expected the record to expire within the ttl, got %v", at)
+		}
+
+		// but not on the next ones
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		if err := s.Write(&store.Record{Key: "user/2", Value: []byte("bob")}); err != nil {
+			mt.Fatal(err)
+		}
+		update = mt.GetStartedEvent()
+		if update.CommandName != "update" {
+			mt.Fatalf("Expected only the update, got %s", update.CommandName)
+		}
+		u = update.Command.Lookup("updates").Array().Index(0).Value().Document()
+		if _, err := u.LookupErr("u", expiryField); err == nil {
+			mt.Errorf("Expected no expiry, got %s", u)
+		}
+	})
+}
+
+func TestRead(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("key", func(mt *mtest.T) {
+		s := newMockStore(mt)
+
+		at := time.Now().Add(time.Hour)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.users", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: "user/1"},
+			{Key: "value", Value: []byte("alice")},
+			{Key: "metadata", Value: bson.D{{Key: "role", Value: "admin"}}},
+			{Key: expiryField, Value: at},
+		}))
+		results, err := s.Read("user/1")
+		if err != nil {
+			mt.Fatal(err)
+		}
+		r := results[0]
+		if string(r.Value) != "alice" || r.Metadata["role"] != "admin" {
+			mt.Errorf("Unexpected record %s %v", r.Value, r.Metadata)
+		}
+		if r.Expiry <= 0 || r.Expiry > time.Hour {
+			mt.Errorf("Expected the record to expire within an hour, got %v", r.Expiry)
+		}
+
+		find := mt.GetStartedEvent().Command
+		if find.Lookup("filter", "_id").StringValue() != "user/1" {
+			mt.Errorf("Expected the key to be found by id, got %s", find)
+		}
+		if _, err := find.LookupErr("filter", expiryField, "$not", "$lte"); err != nil {
+			mt.Errorf("Expected expired records to be skipped, got %s", find)
+		}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.users", mtest.FirstBatch))
+		if _, err := s.Read("user/2"); err != store.ErrNotFound {
+			mt.Errorf("Expected store.ErrNotFound, got %v", err)
+		}
+	})
+
+	mt.Run("prefix", func(mt *mtest.T) {
+		s := newMockStore(mt)
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.orders", mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: "user/3"}, {Key: "value", Value: []byte("3")}},
+			bson.D{{Key: "_id", Value: "user/4"}, {Key: "value", Value: []byte("4")}},
+		))
+		results, err := s.Read("user/", store.ReadPrefix(), store.ReadFrom("", "orders"), store.ReadOffset(2), store.ReadLimit(2))
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if len(results) != 2 || results[0].Key != "user/3" || results[0].Metadata == nil {
+			mt.Errorf("Unexpected records %v", results)
+		}
+
+		// the offset and limit are applied by the query, in key order
+		find := mt.GetStartedEvent().Command
+		if find.Lookup("find").StringValue() != "orders" {
+			mt.Errorf("Expected the table to be the collection, got %s", find)
+		}
+		if find.Lookup("sort", "_id").Int32() != 1 || find.Lookup("skip").Int64() != 2 || find.Lookup("limit").Int64() != 2 {
+			mt.Errorf("Expected a sorted page of 2 records, got %s", find)
+		}
+	})
+}
+
+func TestList(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("projection", func(mt *mtest.T) {
+		s := newMockStore(mt)
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.users", mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: "order/1"}},
+			bson.D{{Key: "_id", Value: "user/1"}},
+		))
+		keys, err := s.List(store.ListSuffix("/1"))
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if len(keys) != 2 || keys[0] != "order/1" || keys[1] != "user/1" {
+			mt.Errorf("Expected order/1 and user/1, got %v", keys)
+		}
+
+		// only the keys are fetched
+		find := mt.GetStartedEvent().Command
+		if p := find.Lookup("projection"); p.Document().Lookup("_id").Int32() != 1 {
+			mt.Errorf("Expected a projection of the key, got %s", p)
+		}
+	})
+}
+
+func TestWatch(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("changes", func(mt *mtest.T) {
+		s := newMockStore(mt)
+
+		token := func(n int32) bson.D {
+			return bson.D{{Key: "_data", Value: string(rune('a' + n))}}
+		}
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "test.users", mtest.FirstBatch,
+			bson.D{
+				{Key: "_id", Value: token(1)},
+				{Key: "operationType", Value: "insert"},
+				{Key: "documentKey", Value: bson.D{{Key: "_id", Value: "user/1"}}},
+				{Key: "fullDocument", Value: bson.D{{Key: "_id", Value: "user/1"}, {Key: "value", Value: []byte("alice")}}},
+			},
+			bson.D{
+				{Key: "_id", Value: token(2)},
+				{Key: "operationType", Value: "drop"},
+			},
+			bson.D{
+				{Key: "_id", Value: token(3)},
+				{Key: "operationType", Value: "delete"},
+				{Key: "documentKey", Value: bson.D{{Key: "_id", Value: "user/1"}}},
+			},
+		))
+
+		resume, _ := bson.Marshal(token(0))
+		w, err := Watch(s, records.WatchPrefix("user/"), records.WatchResumeAfter(resume))
+		if err != nil {
+			mt.Fatal(err)
+		}
+
+		agg := mt.GetStartedEvent().Command
+		stages := agg.Lookup("pipeline").Array()
+		cs := stages.Index(0).Value().Document().Lookup("$changeStream").Document()
+		if cs.Lookup("fullDocument").StringValue() != "updateLookup" {
+			mt.Errorf("Expected the documents of updates to be looked up, got %s", cs)
+		}
+		if cs.Lookup("resumeAfter", "_data").StringValue() != "a" {
+			mt.Errorf("Expected the stream to resume after the token, got %s", cs)
+		}
+		re := stages.Index(1).Value().Document().Lookup("$match", "documentKey._id", "$regex").StringValue()
+		if re != "^user/" {
+			mt.Errorf("Expected the changes to be matched by prefix, got %s", re)
+		}
+
+		e, err := w.Next()
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if e.Type != records.Create || e.Record.Key != "user/1" || string(e.Record.Value) != "alice" {
+			mt.Errorf("Expected the create of user/1, got %s %v", e.Type, e.Record)
+		}
+		if bson.Raw(e.Token).Lookup("_data").StringValue() != "b" {
+			mt.Errorf("Expected the resume token of the event, got %s", bson.Raw(e.Token))
+		}
+
+		// drops of the collection are skipped
+		e, err = w.Next()
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if e.Type != records.Delete || e.Record.Key != "user/1" {
+			mt.Errorf("Expected the delete of user/1, got %s %v", e.Type, e.Record)
+		}
+		if bson.Raw(e.Token).Lookup("_data").StringValue() != "d" {
+			mt.Errorf("Expected the resume token of the delete, got %s", bson.Raw(e.Token))
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		w.Stop()
+		if _, err := w.Next(); err != records.ErrWatcherStopped {
+			mt.Errorf("Expected records.ErrWatcherStopped, got %v", err)
+		}
+	})
+}
+
+func TestChangeEvent(t *testing.T) {
+	now := time.Now()
+	at := now.Add(time.Minute)
+
+	for op, want := range map[string]records.EventType{
+		"insert":  records.Create,
+		"update":  records.Update,
+		"replace": records.Update,
+		"delete":  records.Delete,
+	} {
+		c := &change{OperationType: op, FullDocument: &document{Key: "1", Value: []byte("1"), ExpireAt: &at}}
+		c.DocumentKey.Key = "1"
+
+		e, ok := c.event(now)
+		if !ok || e.Type != want {
+			t.Errorf("Expected %s to be a %s, got %v", op, want, e)
+			continue
+		}
+		if want == records.Delete && e.Record.Value != nil {
+			t.Errorf("Expected deletes to only have the key, got %v", e.Record)
+		}
+		if want != records.Delete && e.Record.Expiry != time.Minute {
+			t.Errorf("Expected the expiry of the document, got %v", e.Record.Expiry)
+		}
+	}
+
+	for _, op := range []string{"drop", "rename", "invalidate"} {
+		if _, ok := (&change{OperationType: op}).event(now); ok {
+			t.Errorf("Expected %s to be skipped", op)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"go-micro.dev/v4/store"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type maxPoolSizeKey struct{}
+type minPoolSizeKey struct{}
+type maxConnIdleTimeKey struct{}
+type clientOptionsKey struct{}
+type secondaryReadsKey struct{}
+
+// MaxPoolSize sets the maximum number of connections per server.
+// Defaults to 100.
+func MaxPoolSize(n uint64) store.Option {
+	return setStoreOption(maxPoolSizeKey{}, n)
+}
+
+// MinPoolSize sets the number of connections per server kept open while
+// idle.
+func MinPoolSize(n uint64) store.Option {
+	return setStoreOption(minPoolSizeKey{}, n)
+}
+
+// MaxConnIdleTime closes connections idle for longer than d.
+func MaxConnIdleTime(d time.Duration) store.Option {
+	return setStoreOption(maxConnIdleTimeKey{}, d)
+}
+
+// ClientOptions sets the options of the MongoDB client, e.g. the
+// credentials or TLS config. They're applied after the node and the pool
+// options.
+func ClientOptions(opts ...*options.ClientOptions) store.Option {
+	return setStoreOption(clientOptionsKey{}, opts)
+}
+
+// SecondaryReads reads and lists from secondaries, when one is available,
+// so the primary only takes the writes. Secondaries may lag behind, read
+// through Session to read the writes of the session.
+func SecondaryReads() store.Option {
+	return setStoreOption(secondaryReadsKey{}, true)
+}
+
+func setStoreOption(k, v interface{}) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+// clientOptions returns the options of the client to the node.
+func clientOptions(o store.Options) []*options.ClientOptions {
+	uri := DefaultURI
+	if len(o.Nodes) > 0 {
+		uri = o.Nodes[0]
+	}
+	opts := options.Client().ApplyURI(uri)
+
+	if o.Context == nil {
+		return []*options.ClientOptions{opts}
+	}
+
+	if n, ok := o.Context.Value(maxPoolSizeKey{}).(uint64); ok {
+		opts.SetMaxPoolSize(n)
+	}
+	if n, ok := o.Context.Value(minPoolSizeKey{}).(uint64); ok {
+		opts.SetMinPoolSize(n)
+	}
+	if d, ok := o.Context.Value(maxConnIdleTimeKey{}).(time.Duration); ok {
+		opts.SetMaxConnIdleTime(d)
+	}
+
+	all := []*options.ClientOptions{opts}
+	if extra, ok := o.Context.Value(clientOptionsKey{}).([]*options.ClientOptions); ok {
+		all = append(all, extra...)
+	}
+	return all
+}
+
+func getSecondaryReads(o store.Options) bool {
+	if o.Context != nil {
+		if b, ok := o.Context.Value(secondaryReadsKey{}).(bool); ok {
+			return b
+		}
+	}
+	return false
+}
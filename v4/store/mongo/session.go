@@ -0,0 +1,159 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/go-micro/plugins/v4/util/session"
+	"github.com/pkg/errors"
+	"go-micro.dev/v4/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// sessionStore is a view of the store running every operation in a causally
+// consistent mongo session, continuing from the operation time of the
+// session of the context.
+type sessionStore struct {
+	s       *mongoStore
+	ctx     context.Context
+	session *session.Session
+}
+
+// Session returns a view of the store which reads the writes of the session
+// of the context, see the session package. Every operation runs in a causally
+// consistent mongo session with majority reads and writes, so reads from
+// secondaries with SecondaryReads wait for the writes of the session.
+func Session(ctx context.Context, s store.Store) (store.Store, error) {
+	ms, ok := s.(*mongoStore)
+	if !ok {
+		return nil, errors.Errorf("%s is not a mongo store", s.String())
+	}
+
+	ctx, sess := session.Context(ctx)
+	return &sessionStore{s: ms, ctx: ctx, session: sess}, nil
+}
+
+// position packs an operation time into a session position.
+func position(t primitive.Timestamp) uint64 {
+	return uint64(t.T)<<32 | uint64(t.I)
+}
+
+// timestamp unpacks a session position into an operation time.
+func timestamp(pos uint64) primitive.Timestamp {
+	return primitive.Timestamp{T: uint32(pos >> 32), I: uint32(pos)}
+}
+
+// sessionKey returns the key of the database in sessions.
+func (s *sessionStore) sessionKey(database string) string {
+	if len(database) == 0 {
+		database = s.s.options.Database
+	}
+	return "mongo/" + database
+}
+
+// run runs fn in a mongo session advanced to the position of the database,
+// and advances the position to the operation time of the mongo session.
+func (s *sessionStore) run(database string, fn func(ctx context.Context) error) error {
+	ms, err := s.s.client.StartSession(mopts.Session().SetCausalConsistency(true))
+	if err != nil {
+		return err
+	}
+	defer ms.EndSession(s.ctx)
+
+	key := s.sessionKey(database)
+	if pos, ok := s.session.Position(key); ok {
+		t := timestamp(pos)
+		if err := ms.AdvanceOperationTime(&t); err != nil {
+			return err
+		}
+	}
+
+	err = mongo.WithSession(s.ctx, ms, func(ctx mongo.SessionContext) error {
+		return fn(ctx)
+	})
+
+	if t := ms.OperationTime(); t != nil {
+		s.session.Advance(key, position(*t))
+	}
+	return err
+}
+
+func (s *sessionStore) readOptions() *mopts.CollectionOptions {
+	return s.s.readOptions().SetReadConcern(readconcern.Majority())
+}
+
+func (s *sessionStore) writeOptions() *mopts.CollectionOptions {
+	return mopts.Collection().SetWriteConcern(writeconcern.New(writeconcern.WMajority()))
+}
+
+func (s *sessionStore) Init(opts ...store.Option) error {
+	return s.s.Init(opts...)
+}
+
+func (s *sessionStore) Options() store.Options {
+	return s.s.Options()
+}
+
+func (s *sessionStore) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	var options store.ReadOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	var records []*store.Record
+	err := s.run(options.Database, func(ctx context.Context) error {
+		var err error
+		records, err = s.s.read(ctx, s.readOptions(), key, opts...)
+		return err
+	})
+	return records, err
+}
+
+func (s *sessionStore) Write(r *store.Record, opts ...store.WriteOption) error {
+	var options store.WriteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return s.run(options.Database, func(ctx context.Context) error {
+		return s.s.write(ctx, s.writeOptions(), r, opts...)
+	})
+}
+
+func (s *sessionStore) Delete(key string, opts ...store.DeleteOption) error {
+	var options store.DeleteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return s.run(options.Database, func(ctx context.Context) error {
+		return s.s.delete(ctx, s.writeOptions(), key, opts...)
+	})
+}
+
+func (s *sessionStore) List(opts ...store.ListOption) ([]string, error) {
+	var options store.ListOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	var keys []string
+	err := s.run(options.Database, func(ctx context.Context) error {
+		var err error
+		keys, err = s.s.list(ctx, s.readOptions(), opts...)
+		return err
+	})
+	return keys, err
+}
+
+// Close is a no-op, the client is owned by the underlying store.
+func (s *sessionStore) Close() error {
+	return nil
+}
+
+func (s *sessionStore) String() string {
+	return s.s.String()
+}
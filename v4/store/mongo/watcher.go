@@ -0,0 +1,120 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/go-micro/plugins/v4/util/records"
+	"go-micro.dev/v4/store"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Watch opens a change stream on the collection and returns the changes to
+// its records from now on. Change streams need a replica set or sharded
+// cluster. The prefix and resume token of the options are supported, the
+// token of an event is its resume token.
+func Watch(s store.Store, opts ...records.WatchOption) (records.Watcher, error) {
+	ms, ok := s.(*mongoStore)
+	if !ok {
+		return nil, errors.New(s.String() + " is not a mongo store")
+	}
+
+	options := records.NewWatchOptions(opts...)
+
+	streamOpts := mopts.ChangeStream().SetFullDocument(mopts.UpdateLookup)
+	if options.ResumeAfter != nil {
+		streamOpts.SetResumeAfter(bson.Raw(options.ResumeAfter))
+	}
+
+	ctx, cancel := context.WithCancel(ms.ctx)
+	cs, err := ms.collection(options.Database, options.Table).Watch(ctx, pipeline(options.Prefix), streamOpts)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &watcher{ctx: ctx, cs: cs, cancel: cancel}, nil
+}
+
+// pipeline matches the changes to the keys with the prefix.
+func pipeline(prefix string) mongo.Pipeline {
+	p := mongo.Pipeline{}
+	if len(prefix) > 0 {
+		p = append(p, bson.D{{Key: "$match", Value: bson.D{
+			{Key: "documentKey._id", Value: bson.D{{Key: "$regex", Value: "^" + regexp.QuoteMeta(prefix)}}},
+		}}})
+	}
+	return p
+}
+
+type watcher struct {
+	ctx    context.Context
+	cs     *mongo.ChangeStream
+	cancel context.CancelFunc
+}
+
+// change is a change stream event.
+type change struct {
+	OperationType string    `bson:"operationType"`
+	FullDocument  *document `bson:"fullDocument"`
+	DocumentKey   struct {
+		Key string `bson:"_id"`
+	} `bson:"documentKey"`
+}
+
+// event returns the event of the change, false for changes to the
+// collection instead of a record.
+func (c *change) event(now time.Time) (*records.Event, bool) {
+	e := &records.Event{
+		Record: &store.Record{Key: c.DocumentKey.Key, Metadata: make(map[string]interface{})},
+	}
+	switch c.OperationType {
+	case "insert":
+		e.Type = records.Create
+	case "update", "replace":
+		e.Type = records.Update
+	case "delete":
+		e.Type = records.Delete
+	default:
+		// drops and invalidations of the collection
+		return nil, false
+	}
+
+	if c.FullDocument != nil && e.Type != records.Delete {
+		e.Record = c.FullDocument.record(now)
+	}
+	return e, true
+}
+
+func (w *watcher) Next() (*records.Event, error) {
+	for w.cs.Next(w.ctx) {
+		var c change
+		if err := w.cs.Decode(&c); err != nil {
+			return nil, err
+		}
+
+		e, ok := c.event(time.Now())
+		if !ok {
+			continue
+		}
+		e.Token = w.cs.ResumeToken()
+		return e, nil
+	}
+
+	if w.ctx.Err() != nil {
+		return nil, records.ErrWatcherStopped
+	}
+	if err := w.cs.Err(); err != nil {
+		return nil, err
+	}
+	return nil, records.ErrWatcherStopped
+}
+
+func (w *watcher) Stop() {
+	w.cancel()
+	w.cs.Close(context.Background())
+}
@@ -19,6 +19,10 @@ var (
 	DefaultTable = "micro"
 )
 
+// notExpired matches the rows which haven't expired at the time passed as
+// argument.
+const notExpired = "(expiry IS NULL OR expiry > ?)"
+
 type sqlStore struct {
 	db *sql.DB
 
@@ -28,6 +32,9 @@ type sqlStore struct {
 	options store.Options
 
 	readPrepare, writePrepare, deletePrepare *sql.Stmt
+
+	// stop the expiry sweeper
+	sweepExit, sweepDone chan bool
 }
 
 func init() {
@@ -47,12 +54,38 @@ func (s *sqlStore) Options() store.Options {
 }
 
 func (s *sqlStore) Close() error {
+	s.stopSweeper()
 	return s.db.Close()
 }
 
+// args prepends the tenant to the query arguments if tenancy is enabled.
+func (s *sqlStore) args(tenant string, args ...interface{}) ([]interface{}, error) {
+	if len(getTenantColumn(s.options)) == 0 {
+		return args, nil
+	}
+	if len(tenant) == 0 {
+		return nil, ErrNoTenant
+	}
+	return append([]interface{}{tenant}, args...), nil
+}
+
 // List all the known records.
 func (s *sqlStore) List(opts ...store.ListOption) ([]string, error) {
-	rows, err := s.db.Query(fmt.Sprintf("SELECT `key`, value, expiry FROM %s.%s;", s.database, s.table))
+	return s.listTenant("", opts...)
+}
+
+func (s *sqlStore) listTenant(tenant string, opts ...store.ListOption) ([]string, error) {
+	args, err := s.args(tenant, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("SELECT `key` FROM %s.%s WHERE %s;", s.database, s.table, notExpired)
+	if column := getTenantColumn(s.options); len(column) > 0 {
+		query = fmt.Sprintf("SELECT `key` FROM %s.%s WHERE `%s` = ? AND %s;", s.database, s.table, column, notExpired)
+	}
+
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -62,20 +95,13 @@ func (s *sqlStore) List(opts ...store.ListOption) ([]string, error) {
 	defer rows.Close()
 
 	var records []string
-	var cachedTime time.Time
 
 	for rows.Next() {
-		record := &store.Record{}
-		if err := rows.Scan(&record.Key, &record.Value, &cachedTime); err != nil {
+		var key string
+		if err := rows.Scan(&key); err != nil {
 			return nil, err
 		}
-
-		if cachedTime.Before(time.Now()) {
-			// record has expired
-			go s.Delete(record.Key)
-		} else {
-			records = append(records, record.Key)
-		}
+		records = append(records, key)
 	}
 	rowErr := rows.Close()
 	if rowErr != nil {
@@ -90,6 +116,10 @@ func (s *sqlStore) List(opts ...store.ListOption) ([]string, error) {
 
 // Read all records with keys.
 func (s *sqlStore) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	return s.readTenant("", key, opts...)
+}
+
+func (s *sqlStore) readTenant(tenant, key string, opts ...store.ReadOption) ([]*store.Record, error) {
 	var options store.ReadOptions
 	for _, o := range opts {
 		o(&options)
@@ -97,10 +127,15 @@ func (s *sqlStore) Read(key string, opts ...store.ReadOption) ([]*store.Record,
 
 	// TODO: make use of options.Prefix using WHERE key LIKE = ?
 
+	args, err := s.args(tenant, key, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
 	var records []*store.Record
-	row := s.readPrepare.QueryRow(key)
+	row := s.readPrepare.QueryRow(args...)
 	record := &store.Record{}
-	var cachedTime time.Time
+	var cachedTime sql.NullTime
 
 	if err := row.Scan(&record.Key, &record.Value, &cachedTime); err != nil {
 		if err == sql.ErrNoRows {
@@ -108,12 +143,9 @@ func (s *sqlStore) Read(key string, opts ...store.ReadOption) ([]*store.Record,
 		}
 		return records, err
 	}
-	if cachedTime.Before(time.Now()) {
-		// record has expired
-		go s.Delete(key)
-		return records, store.ErrNotFound
+	if cachedTime.Valid {
+		record.Expiry = time.Until(cachedTime.Time)
 	}
-	record.Expiry = time.Until(cachedTime)
 	records = append(records, record)
 
 	return records, nil
@@ -121,9 +153,34 @@ func (s *sqlStore) Read(key string, opts ...store.ReadOption) ([]*store.Record,
 
 // Write records.
 func (s *sqlStore) Write(r *store.Record, opts ...store.WriteOption) error {
-	timeCached := time.Now().Add(r.Expiry)
-	_, err := s.writePrepare.Exec(r.Key, r.Value, timeCached, r.Value, timeCached)
+	return s.writeTenant("", r, opts...)
+}
+
+func (s *sqlStore) writeTenant(tenant string, r *store.Record, opts ...store.WriteOption) error {
+	var options store.WriteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	ttl := r.Expiry
+	if !options.Expiry.IsZero() {
+		ttl = time.Until(options.Expiry)
+	} else if options.TTL != 0 {
+		ttl = options.TTL
+	}
+
+	// records without a ttl never expire
+	var timeCached interface{}
+	if ttl != 0 {
+		timeCached = s.expiry(ttl)
+	}
+
+	args, err := s.args(tenant, r.Key, r.Value, timeCached, r.Value, timeCached)
 	if err != nil {
+		return err
+	}
+
+	if _, err := s.writePrepare.Exec(args...); err != nil {
 		return errors.Wrap(err, "Couldn't insert record "+r.Key)
 	}
 
@@ -132,7 +189,16 @@ func (s *sqlStore) Write(r *store.Record, opts ...store.WriteOption) error {
 
 // Delete records with keys.
 func (s *sqlStore) Delete(key string, opts ...store.DeleteOption) error {
-	result, err := s.deletePrepare.Exec(key)
+	return s.deleteTenant("", key, opts...)
+}
+
+func (s *sqlStore) deleteTenant(tenant, key string, opts ...store.DeleteOption) error {
+	args, err := s.args(tenant, key)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.deletePrepare.Exec(args...)
 	if err != nil {
 		return err
 	}
@@ -156,21 +222,90 @@ func (s *sqlStore) initDB() error {
 		return errors.Wrap(err, "Couldn't use database")
 	}
 
+	if column := getTenantColumn(s.options); len(column) > 0 {
+		return s.initTenantDB(column)
+	}
+
 	// Create a table for the namespace's prefix
-	createSQL := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (`key` varchar(255) primary key, value blob null, expiry timestamp not null);", s.table)
+	createSQL := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (`key` varchar(255) primary key, value blob null, expiry timestamp null default null);", s.table)
 	_, err = s.db.Exec(createSQL)
 	if err != nil {
 		return errors.Wrap(err, "Couldn't create table")
 	}
+	if err := s.migrateExpiry(); err != nil {
+		return err
+	}
 
 	// prepare
-	s.readPrepare, _ = s.db.Prepare(fmt.Sprintf("SELECT `key`, value, expiry FROM %s.%s WHERE `key` = ?;", s.database, s.table))
+	s.readPrepare, _ = s.db.Prepare(fmt.Sprintf("SELECT `key`, value, expiry FROM %s.%s WHERE `key` = ? AND %s;", s.database, s.table, notExpired))
 	s.writePrepare, _ = s.db.Prepare(fmt.Sprintf("INSERT INTO %s.%s (`key`, value, expiry) VALUES(?, ?, ?) ON DUPLICATE KEY UPDATE `value`= ?, `expiry` = ?", s.database, s.table))
 	s.deletePrepare, _ = s.db.Prepare(fmt.Sprintf("DELETE FROM %s.%s WHERE `key` = ?;", s.database, s.table))
 
 	return nil
 }
 
+// migrateExpiry makes the expiry column of tables created by older versions
+// nullable, those stored records without a ttl as expired right away.
+func (s *sqlStore) migrateExpiry() error {
+	var nullable string
+	row := s.db.QueryRow("SELECT IS_NULLABLE FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND COLUMN_NAME = 'expiry';", s.database, s.table)
+	if err := row.Scan(&nullable); err != nil || nullable == "YES" {
+		return nil
+	}
+
+	_, err := s.db.Exec(fmt.Sprintf("ALTER TABLE %s MODIFY expiry timestamp null default null;", s.table))
+	return errors.Wrap(err, "Couldn't migrate expiry column")
+}
+
+// initTenantDB creates the table with the tenant column as part of the
+// primary key and prepares statements scoped to it.
+func (s *sqlStore) initTenantDB(column string) error {
+	createSQL := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (`%s` varchar(255) not null, `key` varchar(255) not null, value blob null, expiry timestamp null default null, primary key (`%s`, `key`));", s.table, column, column)
+	if _, err := s.db.Exec(createSQL); err != nil {
+		return errors.Wrap(err, "Couldn't create table")
+	}
+	if err := s.migrateExpiry(); err != nil {
+		return err
+	}
+	if err := s.migrateTenant(column); err != nil {
+		return err
+	}
+
+	// prepare
+	var err error
+	s.readPrepare, err = s.db.Prepare(fmt.Sprintf("SELECT `key`, value, expiry FROM %s.%s WHERE `%s` = ? AND `key` = ? AND %s;", s.database, s.table, column, notExpired))
+	if err != nil {
+		return errors.Wrap(err, "Couldn't prepare read")
+	}
+	s.writePrepare, err = s.db.Prepare(fmt.Sprintf("INSERT INTO %s.%s (`%s`, `key`, value, expiry) VALUES(?, ?, ?, ?) ON DUPLICATE KEY UPDATE `value`= ?, `expiry` = ?", s.database, s.table, column))
+	if err != nil {
+		return errors.Wrap(err, "Couldn't prepare write")
+	}
+	s.deletePrepare, err = s.db.Prepare(fmt.Sprintf("DELETE FROM %s.%s WHERE `%s` = ? AND `key` = ?;", s.database, s.table, column))
+	if err != nil {
+		return errors.Wrap(err, "Couldn't prepare delete")
+	}
+
+	return nil
+}
+
+// migrateTenant adds the tenant column to tables created without tenancy,
+// as part of the primary key. Existing records get the empty tenant, which
+// no scope uses, until assigned to a tenant with an UPDATE.
+func (s *sqlStore) migrateTenant(column string) error {
+	var n int
+	row := s.db.QueryRow("SELECT COUNT(*) FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND COLUMN_NAME = ?;", s.database, s.table, column)
+	if err := row.Scan(&n); err != nil {
+		return errors.Wrap(err, "Couldn't check tenant column")
+	}
+	if n > 0 {
+		return nil
+	}
+
+	_, err := s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN `%s` varchar(255) not null default '' FIRST, DROP PRIMARY KEY, ADD PRIMARY KEY (`%s`, `key`);", s.table, column, column))
+	return errors.Wrap(err, "Couldn't add tenant column")
+}
+
 func (s *sqlStore) configure() error {
 	nodes := s.options.Nodes
 	if len(nodes) == 0 {
@@ -204,6 +339,7 @@ func (s *sqlStore) configure() error {
 		return err
 	}
 
+	s.stopSweeper()
 	if s.db != nil {
 		s.db.Close()
 	}
@@ -214,7 +350,16 @@ func (s *sqlStore) configure() error {
 	s.table = table
 
 	// initialize the database
-	return s.initDB()
+	if err := s.initDB(); err != nil {
+		return err
+	}
+
+	if err := s.initExpiryEvent(); err != nil {
+		return err
+	}
+
+	s.startSweeper()
+	return nil
 }
 
 func (s *sqlStore) String() string {
@@ -1,12 +1,14 @@
 package mysql
 
 import (
+	"context"
 	"encoding/json"
 	"os"
 	"testing"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
+	"go-micro.dev/v4/metadata"
 	"go-micro.dev/v4/store"
 )
 
@@ -64,3 +66,64 @@ func TestList(t *testing.T) {
 		t.Log(string(beauty))
 	}
 }
+
+func TestTenantScope(t *testing.T) {
+	s := &sqlStore{}
+	if _, err := Scope(context.Background(), s); err != ErrNoTenancy {
+		t.Fatalf("Expected %v, got %v", ErrNoTenancy, err)
+	}
+
+	TenantColumn("tenant")(&s.options)
+	if _, err := Scope(context.Background(), s); err != ErrNoTenant {
+		t.Fatalf("Expected %v, got %v", ErrNoTenant, err)
+	}
+	if err := s.Delete("test"); err != ErrNoTenant {
+		t.Fatalf("Expected unscoped queries to fail with %v, got %v", ErrNoTenant, err)
+	}
+
+	ctx := metadata.NewContext(context.Background(), map[string]string{DefaultTenantKey: "acme"})
+	ts, err := Scope(ctx, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tenant := ts.(*tenantStore).tenant; tenant != "acme" {
+		t.Fatalf("Expected tenant acme, got %s", tenant)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	if err := sqlStoreT.Write(&store.Record{Key: "forever", Value: []byte("foo")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sqlStoreT.Write(&store.Record{Key: "expiring", Value: []byte("foo")}, store.WriteTTL(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := sqlStoreT.Read("forever")
+	if err != nil {
+		t.Fatalf("Expected record without ttl to be kept, got %v", err)
+	}
+	if records[0].Expiry != 0 {
+		t.Fatalf("Expected no expiry, got %v", records[0].Expiry)
+	}
+
+	time.Sleep(2 * time.Second)
+
+	if _, err := sqlStoreT.Read("expiring"); err != store.ErrNotFound {
+		t.Fatalf("Expected %v, got %v", store.ErrNotFound, err)
+	}
+	keys, err := sqlStoreT.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range keys {
+		if k == "expiring" {
+			t.Fatal("Expected expired record not to be listed")
+		}
+	}
+
+	if _, err := Sweep(sqlStoreT); err != nil {
+		t.Fatal(err)
+	}
+	sqlStoreT.Delete("forever")
+}
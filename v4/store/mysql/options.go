@@ -0,0 +1,155 @@
+package mysql
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"go-micro.dev/v4/store"
+)
+
+// DefaultTenantKey is the metadata key the tenant is read from.
+var DefaultTenantKey = "Micro-Tenant"
+
+var columnRe = regexp.MustCompile("[^a-zA-Z0-9_]+")
+
+type tenantColumnKey struct{}
+type tenantKeyKey struct{}
+type sweepIntervalKey struct{}
+type sweepBatchSizeKey struct{}
+type sweepRateKey struct{}
+type ttlJitterKey struct{}
+type expiryEventKey struct{}
+
+var (
+	// DefaultSweepInterval is how often the sweeper deletes expired rows.
+	DefaultSweepInterval = time.Minute
+	// DefaultSweepBatchSize is the number of expired rows deleted per batch.
+	DefaultSweepBatchSize = 1000
+	// DefaultSweepRate is the number of batches deleted per second.
+	DefaultSweepRate = 10
+)
+
+// TenantColumn enables row level tenancy. Every table gets a tenant column
+// with the given name which is part of the primary key, and all queries are
+// scoped to it. The store must then be accessed through Scope, calls on the
+// unscoped store return ErrNoTenant. The column is added to existing tables,
+// their records get the empty tenant and must be assigned to a tenant with
+// an UPDATE to be found again.
+func TenantColumn(name string) store.Option {
+	return setStoreOption(tenantColumnKey{}, name)
+}
+
+// TenantKey sets the metadata key Scope reads the tenant from.
+// Defaults to DefaultTenantKey.
+func TenantKey(key string) store.Option {
+	return setStoreOption(tenantKeyKey{}, key)
+}
+
+// SweepInterval sets how often the background sweeper deletes the expired
+// rows. Defaults to DefaultSweepInterval, unless ExpiryEvent is set. Zero
+// turns the sweeper off, expired rows are then kept until Sweep is called.
+func SweepInterval(d time.Duration) store.Option {
+	return setStoreOption(sweepIntervalKey{}, d)
+}
+
+// SweepBatchSize sets the number of expired rows deleted per statement.
+// Defaults to DefaultSweepBatchSize.
+func SweepBatchSize(n int) store.Option {
+	return setStoreOption(sweepBatchSizeKey{}, n)
+}
+
+// SweepRate limits the sweeper to n batches per second, so a large backlog
+// of expired rows doesn't load the database. Defaults to DefaultSweepRate.
+func SweepRate(n int) store.Option {
+	return setStoreOption(sweepRateKey{}, n)
+}
+
+// TTLJitter adds a random duration of up to d to the expiry of every record
+// written with one, so records written together don't expire together.
+func TTLJitter(d time.Duration) store.Option {
+	return setStoreOption(ttlJitterKey{}, d)
+}
+
+// ExpiryEvent creates a MySQL event deleting the expired rows every interval,
+// so the database reaps them instead of every store. The event scheduler must
+// be on, and the loc of the connection must match the time zone of the
+// server, as the event compares expiries with its current time.
+func ExpiryEvent(d time.Duration) store.Option {
+	return setStoreOption(expiryEventKey{}, d)
+}
+
+func setStoreOption(k, v interface{}) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+func getTenantColumn(o store.Options) string {
+	if o.Context != nil {
+		if c, ok := o.Context.Value(tenantColumnKey{}).(string); ok {
+			return columnRe.ReplaceAllString(c, "_")
+		}
+	}
+	return ""
+}
+
+func getTenantKey(o store.Options) string {
+	if o.Context != nil {
+		if k, ok := o.Context.Value(tenantKeyKey{}).(string); ok && len(k) > 0 {
+			return k
+		}
+	}
+	return DefaultTenantKey
+}
+
+func getSweepInterval(o store.Options) time.Duration {
+	if o.Context != nil {
+		if d, ok := o.Context.Value(sweepIntervalKey{}).(time.Duration); ok {
+			return d
+		}
+	}
+	if getExpiryEvent(o) > 0 {
+		return 0
+	}
+	return DefaultSweepInterval
+}
+
+func getSweepBatchSize(o store.Options) int {
+	if o.Context != nil {
+		if n, ok := o.Context.Value(sweepBatchSizeKey{}).(int); ok && n > 0 {
+			return n
+		}
+	}
+	return DefaultSweepBatchSize
+}
+
+func getSweepRate(o store.Options) int {
+	if o.Context != nil {
+		if n, ok := o.Context.Value(sweepRateKey{}).(int); ok && n > 0 {
+			return n
+		}
+	}
+	return DefaultSweepRate
+}
+
+func getTTLJitter(o store.Options) time.Duration {
+	if o.Context != nil {
+		if d, ok := o.Context.Value(ttlJitterKey{}).(time.Duration); ok {
+			return d
+		}
+	}
+	return 0
+}
+
+func getExpiryEvent(o store.Options) time.Duration {
+	if o.Context != nil {
+		if d, ok := o.Context.Value(expiryEventKey{}).(time.Duration); ok {
+			return d
+		}
+	}
+	return 0
+}
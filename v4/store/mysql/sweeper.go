@@ -0,0 +1,129 @@
+package mysql
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/pkg/errors"
+	log "go-micro.dev/v4/logger"
+	"go-micro.dev/v4/store"
+)
+
+// Sweep deletes the expired rows of the store in batches and returns the
+// number of rows deleted. It's what the sweeper started by SweepInterval
+// runs, for callers scheduling it themselves.
+func Sweep(s store.Store) (int64, error) {
+	ss, ok := s.(*sqlStore)
+	if !ok {
+		return 0, errors.Errorf("%s is not a mysql store", s.String())
+	}
+	return ss.sweep(nil)
+}
+
+func (s *sqlStore) startSweeper() {
+	interval := getSweepInterval(s.options)
+	if interval <= 0 {
+		return
+	}
+
+	s.sweepExit = make(chan bool)
+	s.sweepDone = make(chan bool)
+
+	go func(exit, done chan bool) {
+		defer close(done)
+
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-exit:
+				return
+			case <-t.C:
+				n, err := s.sweep(exit)
+				if err != nil {
+					log.Errorf("store/mysql: sweeping expired rows: %v", err)
+				} else if n > 0 {
+					log.Debugf("store/mysql: swept %d expired rows", n)
+				}
+			}
+		}
+	}(s.sweepExit, s.sweepDone)
+}
+
+func (s *sqlStore) stopSweeper() {
+	if s.sweepExit == nil {
+		return
+	}
+	close(s.sweepExit)
+	<-s.sweepDone
+	s.sweepExit, s.sweepDone = nil, nil
+}
+
+// sweep deletes expired rows a batch at a time until a batch comes back
+// short, pausing between batches to keep to the sweep rate.
+func (s *sqlStore) sweep(exit chan bool) (int64, error) {
+	batch := getSweepBatchSize(s.options)
+	pause := time.Second / time.Duration(getSweepRate(s.options))
+	query := fmt.Sprintf("DELETE FROM %s.%s WHERE expiry < ? LIMIT %d;", s.database, s.table, batch)
+
+	var total int64
+	for {
+		result, err := s.db.Exec(query, time.Now())
+		if err != nil {
+			return total, err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += n
+
+		if n < int64(batch) {
+			return total, nil
+		}
+
+		select {
+		case <-exit:
+			return total, nil
+		case <-time.After(pause):
+		}
+	}
+}
+
+// initExpiryEvent creates or reschedules the event deleting the expired rows
+// if ExpiryEvent is set.
+func (s *sqlStore) initExpiryEvent() error {
+	interval := getExpiryEvent(s.options)
+	if interval <= 0 {
+		return nil
+	}
+
+	seconds := int64(interval / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	event := fmt.Sprintf("%s.%s_expiry", s.database, s.table)
+	create := fmt.Sprintf("CREATE EVENT IF NOT EXISTS %s ON SCHEDULE EVERY %d SECOND DO DELETE FROM %s.%s WHERE expiry < CURRENT_TIMESTAMP;", event, seconds, s.database, s.table)
+	if _, err := s.db.Exec(create); err != nil {
+		return errors.Wrap(err, "Couldn't create expiry event")
+	}
+
+	// the event may exist with another schedule
+	if _, err := s.db.Exec(fmt.Sprintf("ALTER EVENT %s ON SCHEDULE EVERY %d SECOND;", event, seconds)); err != nil {
+		return errors.Wrap(err, "Couldn't schedule expiry event")
+	}
+
+	return nil
+}
+
+// expiry returns the time a record written now with the ttl expires at,
+// with the configured jitter added.
+func (s *sqlStore) expiry(ttl time.Duration) time.Time {
+	if jitter := getTTLJitter(s.options); ttl > 0 && jitter > 0 {
+		ttl += time.Duration(rand.Int63n(int64(jitter)))
+	}
+	return time.Now().Add(ttl)
+}
@@ -0,0 +1,76 @@
+package mysql
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go-micro.dev/v4/metadata"
+	"go-micro.dev/v4/store"
+)
+
+var (
+	// ErrNoTenant is returned when tenancy is enabled but no tenant is set.
+	ErrNoTenant = errors.New("no tenant set")
+	// ErrNoTenancy is returned by Scope if the store has no tenant column.
+	ErrNoTenancy = errors.New("tenancy is not enabled")
+)
+
+// tenantStore is a view of the store bound to a single tenant.
+type tenantStore struct {
+	s      *sqlStore
+	tenant string
+}
+
+// Scope returns a view of the store which only reads and writes the rows of
+// the tenant found in the context metadata. The store must have been created
+// with the TenantColumn option.
+func Scope(ctx context.Context, s store.Store) (store.Store, error) {
+	ss, ok := s.(*sqlStore)
+	if !ok {
+		return nil, errors.Errorf("%s is not a mysql store", s.String())
+	}
+
+	if len(getTenantColumn(ss.options)) == 0 {
+		return nil, ErrNoTenancy
+	}
+
+	tenant, ok := metadata.Get(ctx, getTenantKey(ss.options))
+	if !ok || len(tenant) == 0 {
+		return nil, ErrNoTenant
+	}
+
+	return &tenantStore{s: ss, tenant: tenant}, nil
+}
+
+func (t *tenantStore) Init(opts ...store.Option) error {
+	return t.s.Init(opts...)
+}
+
+func (t *tenantStore) Options() store.Options {
+	return t.s.Options()
+}
+
+func (t *tenantStore) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	return t.s.readTenant(t.tenant, key, opts...)
+}
+
+func (t *tenantStore) Write(r *store.Record, opts ...store.WriteOption) error {
+	return t.s.writeTenant(t.tenant, r, opts...)
+}
+
+func (t *tenantStore) Delete(key string, opts ...store.DeleteOption) error {
+	return t.s.deleteTenant(t.tenant, key, opts...)
+}
+
+func (t *tenantStore) List(opts ...store.ListOption) ([]string, error) {
+	return t.s.listTenant(t.tenant, opts...)
+}
+
+// Close is a no-op, the connection is owned by the underlying store.
+func (t *tenantStore) Close() error {
+	return nil
+}
+
+func (t *tenantStore) String() string {
+	return t.s.String()
+}
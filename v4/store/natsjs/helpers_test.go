@@ -0,0 +1,40 @@
+package natsjs
+
+import (
+	"testing"
+	"time"
+
+	nserver "github.com/nats-io/nats-server/v2/server"
+	"go-micro.dev/v4/store"
+)
+
+// testSetup starts a NATS server with JetStream for the test and returns a
+// store connected to it. Both are stopped once the test is done.
+func testSetup(t *testing.T, opts ...store.Option) store.Store {
+	t.Helper()
+
+	srv, err := nserver.NewServer(&nserver.Options{
+		Host:      "127.0.0.1",
+		Port:      nserver.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Start()
+	if !srv.ReadyForConnections(10 * time.Second) {
+		t.Fatal("NATS server not ready")
+	}
+	t.Cleanup(srv.Shutdown)
+
+	s := NewStore(append(opts, store.Nodes(srv.ClientURL()))...)
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
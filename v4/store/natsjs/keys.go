@@ -0,0 +1,78 @@
+package natsjs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Keys of KV buckets are restricted to letters, digits and -/_=. with dots
+// separating tokens. Other characters, dots included, are escaped as =XX,
+// which keeps prefixes and suffixes of keys intact, and tables are prefixed
+// to keys with a dot, so a table is watched as table.>.
+
+func validKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '/'
+}
+
+func encodeKey(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if validKeyChar(c) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "=%02X", c)
+	}
+	return b.String()
+}
+
+func decodeKey(s string) (string, error) {
+	if !strings.Contains(s, "=") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '=' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+2 >= len(s) {
+			return "", fmt.Errorf("invalid escape in key %s", s)
+		}
+		c, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", fmt.Errorf("invalid escape in key %s", s)
+		}
+		b.WriteByte(byte(c))
+		i += 2
+	}
+	return b.String(), nil
+}
+
+// getKey returns the bucket key of the key in the table.
+func getKey(key, table string) string {
+	if table != "" {
+		return encodeKey(table) + "." + encodeKey(key)
+	}
+	return encodeKey(key)
+}
+
+// splitKey returns the key of a bucket key if it's in the table.
+func splitKey(k, table string) (string, bool) {
+	t := ""
+	if i := strings.IndexByte(k, '.'); i >= 0 {
+		t, k = k[:i], k[i+1:]
+	}
+	if t != encodeKey(table) {
+		return "", false
+	}
+
+	key, err := decodeKey(k)
+	if err != nil {
+		return "", false
+	}
+	return key, true
+}
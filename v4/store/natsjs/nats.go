@@ -0,0 +1,482 @@
+// Package natsjs implements the store on NATS JetStream Key-Value buckets.
+package natsjs
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-micro/plugins/v4/util/records"
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+	"go-micro.dev/v4/store"
+	"go-micro.dev/v4/util/cmd"
+)
+
+var (
+	ErrBucketNotFound = errors.New("Bucket (database) not found")
+)
+
+type natsStore struct {
+	sync.Once
+	sync.RWMutex
+
+	ttl         time.Duration
+	history     uint8
+	storageType nats.StorageType
+	description string
+
+	opts      store.Options
+	nopts     nats.Options
+	jsopts    []nats.JSOpt
+	kvConfigs []*nats.KeyValueConfig
+
+	conn    *nats.Conn
+	js      nats.JetStreamContext
+	buckets map[string]*bucket
+}
+
+// bucket is a KV bucket and the max age of its records.
+type bucket struct {
+	kv  nats.KeyValue
+	ttl time.Duration
+}
+
+// value is a record as stored in a bucket, KV entries have no headers to
+// hold the metadata.
+type value struct {
+	Value    []byte                 `json:"value"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+func init() {
+	cmd.DefaultStores["natsjs-kv"] = NewStore
+}
+
+// NewStore will create a new NATS JetStream Key-Value store.
+func NewStore(opts ...store.Option) store.Store {
+	options := store.Options{
+		Nodes:    []string{},
+		Database: "default",
+		Table:    "",
+		Context:  context.Background(),
+	}
+
+	n := &natsStore{
+		description: "Store managed by go-micro",
+		history:     1,
+		opts:        options,
+		jsopts:      []nats.JSOpt{},
+		kvConfigs:   []*nats.KeyValueConfig{},
+		buckets:     map[string]*bucket{},
+		storageType: nats.FileStorage,
+	}
+
+	n.setOption(opts...)
+
+	return n
+}
+
+// Init initializes the store. It must perform any required setup on the
+// backing storage implementation and check that it is ready for use,
+// returning any errors.
+func (n *natsStore) Init(opts ...store.Option) error {
+	n.setOption(opts...)
+
+	// Connect to NATS servers
+	conn, err := n.nopts.Connect()
+	if err != nil {
+		return errors.Wrap(err, "Failed to connect to NATS Server")
+	}
+
+	// Create JetStream context
+	js, err := conn.JetStream(n.jsopts...)
+	if err != nil {
+		conn.Close()
+		return errors.Wrap(err, "Failed to create JetStream context")
+	}
+
+	n.Lock()
+	n.conn = conn
+	n.js = js
+	n.buckets = map[string]*bucket{}
+	n.Unlock()
+
+	// Create default config if no configs present
+	if len(n.kvConfigs) == 0 {
+		n.kvConfigs = append(n.kvConfigs, n.config(n.opts.Database))
+	}
+
+	// Create key value buckets
+	for _, cfg := range n.kvConfigs {
+		if _, err := n.createBucket(cfg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (n *natsStore) setOption(opts ...store.Option) {
+	for _, o := range opts {
+		o(&n.opts)
+	}
+
+	n.Once.Do(func() {
+		n.nopts = nats.GetDefaultOptions()
+	})
+
+	// Extract options from context
+	if nopts, ok := n.opts.Context.Value(natsOptionsKey{}).(nats.Options); ok {
+		n.nopts = nopts
+	}
+
+	if jsopts, ok := n.opts.Context.Value(jsOptionsKey{}).([]nats.JSOpt); ok {
+		n.jsopts = append(n.jsopts, jsopts...)
+	}
+
+	if cfg, ok := n.opts.Context.Value(kvOptionsKey{}).([]*nats.KeyValueConfig); ok {
+		n.kvConfigs = append(n.kvConfigs, cfg...)
+	}
+
+	if ttl, ok := n.opts.Context.Value(ttlOptionsKey{}).(time.Duration); ok {
+		n.ttl = ttl
+	}
+
+	if history, ok := n.opts.Context.Value(historyOptionsKey{}).(uint8); ok {
+		n.history = history
+	}
+
+	if sType, ok := n.opts.Context.Value(memoryOptionsKey{}).(nats.StorageType); ok {
+		n.storageType = sType
+	}
+
+	if text, ok := n.opts.Context.Value(descriptionOptionsKey{}).(string); ok {
+		n.description = text
+	}
+
+	// Assign store option server addresses to nats options
+	if len(n.opts.Nodes) > 0 {
+		n.nopts.Url = ""
+		n.nopts.Servers = n.opts.Nodes
+	}
+
+	if len(n.nopts.Servers) == 0 && n.nopts.Url == "" {
+		n.nopts.Url = nats.DefaultURL
+	}
+}
+
+func (n *natsStore) config(name string) *nats.KeyValueConfig {
+	return &nats.KeyValueConfig{
+		Bucket:      name,
+		Description: n.description,
+		History:     n.history,
+		TTL:         n.ttl,
+		Storage:     n.storageType,
+	}
+}
+
+// createBucket creates the bucket of the config, or binds to it if it
+// exists.
+func (n *natsStore) createBucket(cfg *nats.KeyValueConfig) (*bucket, error) {
+	kv, err := n.js.CreateKeyValue(cfg)
+	if err == nats.ErrStreamNameAlreadyInUse {
+		kv, err = n.js.KeyValue(cfg.Bucket)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to create bucket (%s)", cfg.Bucket)
+	}
+	return n.addBucket(kv)
+}
+
+func (n *natsStore) addBucket(kv nats.KeyValue) (*bucket, error) {
+	// an existing bucket may be configured differently
+	status, err := kv.Status()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get status of bucket (%s)", kv.Bucket())
+	}
+
+	b := &bucket{kv: kv, ttl: status.TTL()}
+	n.Lock()
+	n.buckets[kv.Bucket()] = b
+	n.Unlock()
+	return b, nil
+}
+
+// getBucket returns the bucket of the database, creating it if create is
+// set.
+func (n *natsStore) getBucket(database string, create bool) (*bucket, error) {
+	n.RLock()
+	conn := n.conn
+	n.RUnlock()
+	if conn == nil {
+		if err := n.Init(); err != nil {
+			return nil, err
+		}
+	}
+
+	if database == "" {
+		database = n.opts.Database
+	}
+
+	n.RLock()
+	b, ok := n.buckets[database]
+	n.RUnlock()
+	if ok {
+		return b, nil
+	}
+
+	kv, err := n.js.KeyValue(database)
+	if err == nats.ErrBucketNotFound {
+		if !create {
+			return nil, ErrBucketNotFound
+		}
+		return n.createBucket(n.config(database))
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "Failed to bind to bucket (%s)", database)
+	}
+	return n.addBucket(kv)
+}
+
+// Options allows you to view the current options.
+func (n *natsStore) Options() store.Options {
+	return n.opts
+}
+
+// record decodes a bucket entry of the key.
+func (b *bucket) record(key string, e nats.KeyValueEntry, now time.Time) (*store.Record, error) {
+	var v value
+	if err := json.Unmarshal(e.Value(), &v); err != nil {
+		return nil, errors.Wrapf(err, "Failed to decode record (%s)", key)
+	}
+
+	r := &store.Record{
+		Key:      key,
+		Value:    v.Value,
+		Metadata: v.Metadata,
+	}
+	if r.Metadata == nil {
+		r.Metadata = map[string]interface{}{}
+	}
+	if b.ttl > 0 {
+		r.Expiry = e.Created().Add(b.ttl).Sub(now)
+	}
+	return r, nil
+}
+
+// keys returns the keys of the table matching the prefix and suffix, sorted.
+func (b *bucket) keys(table, prefix, suffix string) ([]string, error) {
+	names, err := b.kv.Keys()
+	if err == nats.ErrNoKeysFound {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "Failed to list keys in bucket")
+	}
+
+	keys := []string{}
+	for _, name := range names {
+		key, ok := splitKey(name, table)
+		if !ok || !strings.HasPrefix(key, prefix) || !strings.HasSuffix(key, suffix) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// readOptions applies the read options, returning whether ReadHistory was
+// one of them.
+func readOptions(opts ...store.ReadOption) (store.ReadOptions, bool) {
+	var options store.ReadOptions
+	var history bool
+	for _, o := range opts {
+		var probe store.ReadOptions
+		if o(&probe); probe.Database == historyMarker && probe.Table == historyMarker {
+			history = true
+			continue
+		}
+		o(&options)
+	}
+	return options, history
+}
+
+// Read takes a single key name and optional ReadOptions. It returns matching []*Record or an error.
+func (n *natsStore) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	opt, history := readOptions(opts...)
+	if opt.Table == "" {
+		opt.Table = n.opts.Table
+	}
+
+	b, err := n.getBucket(opt.Database, false)
+	if err == ErrBucketNotFound && !opt.Prefix && !opt.Suffix {
+		return nil, store.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	keys := []string{key}
+	if opt.Prefix || opt.Suffix {
+		var prefix, suffix string
+		if opt.Prefix {
+			prefix = key
+		}
+		if opt.Suffix {
+			suffix = key
+		}
+		if keys, err = b.keys(opt.Table, prefix, suffix); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	results := []*store.Record{}
+	for _, k := range keys {
+		var entries []nats.KeyValueEntry
+		if history {
+			entries, err = b.kv.History(getKey(k, opt.Table))
+		} else {
+			var e nats.KeyValueEntry
+			e, err = b.kv.Get(getKey(k, opt.Table))
+			entries = []nats.KeyValueEntry{e}
+		}
+		if err == nats.ErrKeyNotFound {
+			// deleted since listing the keys
+			continue
+		} else if err != nil {
+			return nil, errors.Wrap(err, "Failed to get key from bucket")
+		}
+
+		for _, e := range entries {
+			if e.Operation() != nats.KeyValuePut {
+				continue
+			}
+			r, err := b.record(k, e, now)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, r)
+		}
+	}
+
+	if len(results) == 0 && !opt.Prefix && !opt.Suffix {
+		return nil, store.ErrNotFound
+	}
+
+	start, end := records.Bounds(len(results), opt.Offset, opt.Limit)
+	return results[start:end], nil
+}
+
+// Write writes a record to the store, and returns an error if the record was not written.
+func (n *natsStore) Write(r *store.Record, opts ...store.WriteOption) error {
+	opt := store.WriteOptions{}
+
+	for _, o := range opts {
+		o(&opt)
+	}
+
+	if opt.Table == "" {
+		opt.Table = n.opts.Table
+	}
+
+	b, err := n.getBucket(opt.Database, true)
+	if err != nil {
+		return err
+	}
+
+	v, err := json.Marshal(&value{Value: r.Value, Metadata: r.Metadata})
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode record")
+	}
+
+	if _, err := b.kv.Put(getKey(r.Key, opt.Table), v); err != nil {
+		return errors.Wrap(err, "Failed to store data in bucket")
+	}
+
+	return nil
+}
+
+// Delete removes the record with the corresponding key from the store.
+func (n *natsStore) Delete(key string, opts ...store.DeleteOption) error {
+	opt := store.DeleteOptions{}
+
+	for _, o := range opts {
+		o(&opt)
+	}
+
+	if opt.Table == "DELETE_BUCKET" {
+		if _, err := n.getBucket(key, false); err != nil {
+			return err
+		}
+
+		n.Lock()
+		delete(n.buckets, key)
+		n.Unlock()
+		if err := n.js.DeleteKeyValue(key); err != nil {
+			return errors.Wrap(err, "Failed to delete bucket")
+		}
+		return nil
+	}
+
+	if opt.Table == "" {
+		opt.Table = n.opts.Table
+	}
+
+	b, err := n.getBucket(opt.Database, false)
+	if err == ErrBucketNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if err := b.kv.Delete(getKey(key, opt.Table)); err != nil {
+		return errors.Wrap(err, "Failed to delete data")
+	}
+	return nil
+}
+
+// List returns any keys that match, or an empty list with no error if none matched.
+func (n *natsStore) List(opts ...store.ListOption) ([]string, error) {
+	opt := store.ListOptions{}
+
+	for _, o := range opts {
+		o(&opt)
+	}
+
+	if opt.Table == "" {
+		opt.Table = n.opts.Table
+	}
+
+	b, err := n.getBucket(opt.Database, false)
+	if err == ErrBucketNotFound {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	keys, err := b.keys(opt.Table, opt.Prefix, opt.Suffix)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := records.Bounds(len(keys), opt.Offset, opt.Limit)
+	return keys[start:end], nil
+}
+
+// Close the store.
+func (n *natsStore) Close() error {
+	n.RLock()
+	defer n.RUnlock()
+	if n.conn != nil {
+		n.conn.Close()
+	}
+	return nil
+}
+
+// String returns the name of the implementation.
+func (n *natsStore) String() string {
+	return "NATS JetStream KeyValueStore"
+}
@@ -0,0 +1,205 @@
+package natsjs
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-micro/plugins/v4/util/records"
+	"go-micro.dev/v4/store"
+)
+
+func TestKeys(t *testing.T) {
+	for _, key := range []string{"foo", "foo.bar", "user:1/name", "a=b", "ümlaut", "trailing."} {
+		enc := getKey(key, "table")
+		for i := len("table."); i < len(enc); i++ {
+			if c := enc[i]; !validKeyChar(c) && c != '=' {
+				t.Fatalf("invalid character %q in key %s", c, enc)
+			}
+		}
+
+		dec, ok := splitKey(enc, "table")
+		if !ok || dec != key {
+			t.Fatalf("expected %s, got %s", key, dec)
+		}
+		if _, ok := splitKey(enc, ""); ok {
+			t.Fatalf("key %s of table found outside of it", enc)
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	s := testSetup(t)
+
+	if _, err := s.Read("missing"); err != store.ErrNotFound {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	initial := []*store.Record{
+		{Key: "user:1", Value: []byte("one"), Metadata: map[string]interface{}{"role": "admin"}},
+		{Key: "user:2", Value: []byte("two")},
+		{Key: "user:3", Value: []byte("three")},
+		{Key: "group:1", Value: []byte("group")},
+	}
+	for _, r := range initial {
+		if err := s.Write(r, store.WriteTo("", "users")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Write(&store.Record{Key: "user:1", Value: []byte("other")}); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := s.Read("user:1", store.ReadFrom("", "users"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(r[0].Value) != "one" || r[0].Metadata["role"] != "admin" {
+		t.Fatalf("unexpected record %+v", r[0])
+	}
+
+	r, err = s.Read("user:", store.ReadFrom("", "users"), store.ReadPrefix(), store.ReadOffset(1), store.ReadLimit(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 2 || r[0].Key != "user:2" || r[1].Key != "user:3" {
+		t.Fatalf("unexpected records %v", r)
+	}
+
+	keys, err := s.List(store.ListFrom("", "users"), store.ListSuffix(":1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(keys, []string{"group:1", "user:1"}) {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+
+	keys, err = s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(keys, []string{"user:1"}) {
+		t.Fatalf("unexpected keys outside of table %v", keys)
+	}
+
+	if err := s.Delete("user:1", store.DeleteFrom("", "users")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Read("user:1", store.ReadFrom("", "users")); err != store.ErrNotFound {
+		t.Fatalf("expected not found after delete, got %v", err)
+	}
+
+	if err := s.Write(&store.Record{Key: "foo", Value: []byte("bar")}, store.WriteTo("other", "")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("other", DeleteBucket()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Read("foo", store.ReadFrom("other", "")); err != store.ErrNotFound {
+		t.Fatalf("expected not found after deleting the bucket, got %v", err)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	s := testSetup(t, DefaultHistory(5))
+
+	for _, v := range []string{"v1", "v2", "v3"} {
+		if err := s.Write(&store.Record{Key: "config", Value: []byte(v)}, store.WriteTo("", "app")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := s.Read("config", ReadHistory(), store.ReadFrom("", "app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 3 || string(r[0].Value) != "v1" || string(r[2].Value) != "v3" {
+		t.Fatalf("unexpected history %v", r)
+	}
+
+	r, err = s.Read("config", store.ReadFrom("", "app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 1 || string(r[0].Value) != "v3" {
+		t.Fatalf("unexpected latest %v", r)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	s := testSetup(t, DefaultTTL(2*time.Second))
+
+	if err := s.Write(&store.Record{Key: "foo", Value: []byte("bar")}); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := s.Read("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r[0].Expiry <= 0 || r[0].Expiry > 2*time.Second {
+		t.Fatalf("unexpected expiry %v", r[0].Expiry)
+	}
+
+	time.Sleep(3 * time.Second)
+	if _, err := s.Read("foo"); err != store.ErrNotFound {
+		t.Fatalf("expected not found after ttl, got %v", err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	s := testSetup(t)
+
+	if err := s.Write(&store.Record{Key: "config/a", Value: []byte("1")}); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := s.(WatchStore).Watch(records.WatchPrefix("config/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	initial, err := s.(WatchStore).Watch(records.WatchKey("config/a"), records.WatchInitial())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer initial.Stop()
+
+	s.Write(&store.Record{Key: "other", Value: []byte("x")})
+	s.Write(&store.Record{Key: "config/b", Value: []byte("2")})
+	s.Delete("config/b")
+
+	e, err := w.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Type != records.Put || e.Record.Key != "config/b" || string(e.Record.Value) != "2" {
+		t.Fatalf("unexpected event %s %+v", e.Type, e.Record)
+	}
+	put := e.Revision
+
+	e, err = w.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Type != records.Delete || e.Record.Key != "config/b" {
+		t.Fatalf("unexpected event %s %+v", e.Type, e.Record)
+	}
+	if e.Revision <= put {
+		t.Fatalf("expected the delete after revision %d, got %d", put, e.Revision)
+	}
+
+	e, err = initial.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Type != records.Put || string(e.Record.Value) != "1" {
+		t.Fatalf("unexpected initial event %s %+v", e.Type, e.Record)
+	}
+
+	w.Stop()
+	if _, err := w.Next(); err != records.ErrWatcherStopped {
+		t.Fatalf("expected stopped watcher, got %v", err)
+	}
+}
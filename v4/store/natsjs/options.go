@@ -0,0 +1,83 @@
+package natsjs
+
+import (
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go-micro.dev/v4/store"
+)
+
+// store.Option.
+type natsOptionsKey struct{}
+type jsOptionsKey struct{}
+type kvOptionsKey struct{}
+type ttlOptionsKey struct{}
+type historyOptionsKey struct{}
+type memoryOptionsKey struct{}
+type descriptionOptionsKey struct{}
+
+// NatsOptions accepts nats.Options.
+func NatsOptions(opts nats.Options) store.Option {
+	return setStoreOption(natsOptionsKey{}, opts)
+}
+
+// JetStreamOptions accepts multiple nats.JSOpt.
+func JetStreamOptions(opts ...nats.JSOpt) store.Option {
+	return setStoreOption(jsOptionsKey{}, opts)
+}
+
+// KeyValueOptions accepts multiple nats.KeyValueConfig.
+// This will create buckets with the provided configs at initialization.
+func KeyValueOptions(cfg ...*nats.KeyValueConfig) store.Option {
+	return setStoreOption(kvOptionsKey{}, cfg)
+}
+
+// DefaultTTL sets the max age of records in new buckets. By default records
+// don't expire.
+//
+// TTL on individual writes is not supported, only bucket wide TTL.
+func DefaultTTL(ttl time.Duration) store.Option {
+	return setStoreOption(ttlOptionsKey{}, ttl)
+}
+
+// DefaultHistory sets the number of revisions new buckets keep of a key,
+// 1 by default and at most 64. Read them with ReadHistory.
+func DefaultHistory(n uint8) store.Option {
+	return setStoreOption(historyOptionsKey{}, n)
+}
+
+// DefaultMemory sets the default storage type to memory only.
+//
+// The default is file storage, persisting storage between service restarts.
+func DefaultMemory() store.Option {
+	return setStoreOption(memoryOptionsKey{}, nats.MemoryStorage)
+}
+
+// DefaultDescription sets the default description to use when creating new
+// buckets. The default is "Store managed by go-micro".
+func DefaultDescription(text string) store.Option {
+	return setStoreOption(descriptionOptionsKey{}, text)
+}
+
+// historyMarker marks reads of the history, as store.ReadOptions can't hold
+// plugin options.
+const historyMarker = "READ_HISTORY"
+
+// ReadHistory reads all revisions a bucket keeps of the keys, oldest first,
+// instead of the latest one. Deleted revisions are skipped.
+func ReadHistory() store.ReadOption {
+	return func(r *store.ReadOptions) {
+		r.Database = historyMarker
+		r.Table = historyMarker
+	}
+}
+
+// DeleteBucket will use the key passed to Delete as a bucket (database) name,
+// and delete the bucket.
+// This option should not be combined with the store.DeleteFrom option, as
+// that will overwrite the delete action.
+func DeleteBucket() store.DeleteOption {
+	return func(d *store.DeleteOptions) {
+		d.Table = "DELETE_BUCKET"
+	}
+}
@@ -0,0 +1,100 @@
+package natsjs
+
+import (
+	"strings"
+	"time"
+
+	"github.com/go-micro/plugins/v4/util/records"
+	"github.com/nats-io/nats.go"
+	"go-micro.dev/v4/store"
+)
+
+// WatchStore is the store extended with watches of its records, returned by
+// NewStore:
+//
+//	w, err := s.(natsjs.WatchStore).Watch(records.WatchPrefix("config/"))
+type WatchStore interface {
+	store.Store
+	// Watch returns the changes to records from now on. The key, prefix and
+	// initial options are supported, events are puts and deletes with the
+	// revision of the key.
+	Watch(opts ...records.WatchOption) (records.Watcher, error)
+}
+
+func (n *natsStore) Watch(opts ...records.WatchOption) (records.Watcher, error) {
+	options := records.NewWatchOptions(opts...)
+	if options.Table == "" {
+		options.Table = n.opts.Table
+	}
+
+	b, err := n.getBucket(options.Database, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// tables are tokens of the subject, prefixes are filtered on the keys
+	keys := ">"
+	if len(options.Key) > 0 {
+		keys = getKey(options.Key, options.Table)
+	} else if len(options.Table) > 0 {
+		keys = encodeKey(options.Table) + ".>"
+	}
+
+	kw, err := b.kv.Watch(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &watcher{
+		bucket:  b,
+		kw:      kw,
+		options: options,
+		initial: true,
+	}, nil
+}
+
+type watcher struct {
+	bucket  *bucket
+	kw      nats.KeyWatcher
+	options records.WatchOptions
+	// initial is set until the current records were received
+	initial bool
+}
+
+func (w *watcher) Next() (*records.Event, error) {
+	for e := range w.kw.Updates() {
+		if e == nil {
+			// the current records were received
+			w.initial = false
+			continue
+		}
+		if w.initial && !w.options.Initial {
+			continue
+		}
+
+		key, ok := splitKey(e.Key(), w.options.Table)
+		if !ok || !strings.HasPrefix(key, w.options.Prefix) {
+			continue
+		}
+
+		if e.Operation() != nats.KeyValuePut {
+			return &records.Event{
+				Type:     records.Delete,
+				Record:   &store.Record{Key: key, Metadata: map[string]interface{}{}},
+				Revision: e.Revision(),
+			}, nil
+		}
+
+		r, err := w.bucket.record(key, e, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		return &records.Event{Type: records.Put, Record: r, Revision: e.Revision()}, nil
+	}
+
+	return nil, records.ErrWatcherStopped
+}
+
+func (w *watcher) Stop() {
+	w.kw.Stop()
+}
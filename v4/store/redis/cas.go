@@ -0,0 +1,163 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+	"go-micro.dev/v4/store"
+)
+
+var (
+	// ErrExists is returned by WriteIfNotExists if the record exists.
+	ErrExists = errors.New("record exists")
+	// ErrConflict is returned by CompareAndSwap if the record changed, and by
+	// Update if it kept changing for all of its retries.
+	ErrConflict = errors.New("record changed")
+	// ErrNotString is returned by the conditional writes for tables not in
+	// ModeString.
+	ErrNotString = errors.New("table is not stored as strings")
+
+	// UpdateRetries is the number of times Update retries a write after the
+	// record changed while it was being updated.
+	UpdateRetries = 10
+)
+
+// casScript sets the key to the new value if its value is the old one.
+// Returns -1 if the key doesn't exist and 0 if its value differs.
+var casScript = redis.NewScript(`
+local v = redis.call("GET", KEYS[1])
+if not v then
+	return -1
+end
+if v ~= ARGV[1] then
+	return 0
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+else
+	redis.call("SET", KEYS[1], ARGV[2], "KEEPTTL")
+end
+return 1
+`)
+
+func stringStore(s store.Store, table string) (*rkv, error) {
+	r, ok := s.(*rkv)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a redis store", s.String())
+	}
+	if tableMode(r.options, table) != ModeString {
+		return nil, ErrNotString
+	}
+	return r, nil
+}
+
+// WriteIfNotExists writes the record only if there's no record of its key,
+// otherwise it returns ErrExists. The table of the record must be in
+// ModeString.
+func WriteIfNotExists(s store.Store, record *store.Record, opts ...store.WriteOption) error {
+	options := store.WriteOptions{Table: s.Options().Table}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	r, err := stringStore(s, options.Table)
+	if err != nil {
+		return err
+	}
+
+	ok, err := r.Client.SetNX(r.ctx, r.key(options.Table, record.Key), record.Value, record.Expiry).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrExists
+	}
+	return nil
+}
+
+// CompareAndSwap writes the record only if the value of its key is still
+// old, otherwise it returns ErrConflict, or store.ErrNotFound if there's no
+// record. The expiry of the record is kept unless it has one of its own. The
+// table of the record must be in ModeString.
+func CompareAndSwap(s store.Store, old []byte, record *store.Record, opts ...store.WriteOption) error {
+	options := store.WriteOptions{Table: s.Options().Table}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	r, err := stringStore(s, options.Table)
+	if err != nil {
+		return err
+	}
+
+	rkey := r.key(options.Table, record.Key)
+	n, err := casScript.Run(r.ctx, r.Client, []string{rkey}, old, record.Value, record.Expiry.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+
+	switch n {
+	case -1:
+		return store.ErrNotFound
+	case 0:
+		return ErrConflict
+	}
+	return nil
+}
+
+// Update reads the record of the key, passes it to fn and writes it back
+// once fn returns, retrying from the start if the record changed in the
+// meantime. The record passed to fn has no value if it doesn't exist yet,
+// so counters and locks can be built without racing other writers. An error
+// of fn aborts the update. The table of the record must be in ModeString.
+func Update(s store.Store, key string, fn func(*store.Record) error, opts ...store.WriteOption) error {
+	options := store.WriteOptions{Table: s.Options().Table}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	r, err := stringStore(s, options.Table)
+	if err != nil {
+		return err
+	}
+
+	rkey := r.key(options.Table, key)
+	txf := func(tx *redis.Tx) error {
+		record := &store.Record{Key: key}
+
+		val, err := tx.Get(r.ctx, rkey).Bytes()
+		switch {
+		case err == redis.Nil:
+		case err != nil:
+			return err
+		default:
+			record.Value = val
+			d, err := tx.PTTL(r.ctx, rkey).Result()
+			if err != nil {
+				return err
+			}
+			if d > 0 {
+				record.Expiry = d
+			}
+		}
+
+		if err := fn(record); err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(r.ctx, func(p redis.Pipeliner) error {
+			p.Set(r.ctx, rkey, record.Value, record.Expiry)
+			return nil
+		})
+		return err
+	}
+
+	for i := 0; i < UpdateRetries; i++ {
+		err := r.Client.Watch(r.ctx, txf, rkey)
+		if err != redis.TxFailedErr {
+			return err
+		}
+	}
+	return ErrConflict
+}
@@ -0,0 +1,82 @@
+package redis
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/store"
+)
+
+func Test_stringStore(t *testing.T) {
+	var o store.Options
+	WithTableMode("docs", ModeJSON)(&o)
+	r := &rkv{options: o}
+
+	if _, err := stringStore(r, "other"); err != nil {
+		t.Errorf("stringStore(other) error = %v", err)
+	}
+	if _, err := stringStore(r, "docs"); err != ErrNotString {
+		t.Errorf("stringStore(docs) error = %v, want %v", err, ErrNotString)
+	}
+	if err := WriteIfNotExists(r, &store.Record{Key: "a"}, store.WriteTo("", "docs")); err != ErrNotString {
+		t.Errorf("WriteIfNotExists(docs) error = %v, want %v", err, ErrNotString)
+	}
+}
+
+func Test_CAS(t *testing.T) {
+	r := NewStore(store.Nodes("redis://127.0.0.1:6379")).(*rkv)
+	if err := r.Client.Ping(r.ctx).Err(); err != nil {
+		t.Skip(err)
+	}
+	defer r.Delete("casTest")
+
+	r.Delete("casTest")
+	rec := &store.Record{Key: "casTest", Value: []byte("1"), Expiry: time.Minute}
+	if err := WriteIfNotExists(r, rec); err != nil {
+		t.Fatalf("WriteIfNotExists() error = %v", err)
+	}
+	if err := WriteIfNotExists(r, rec); err != ErrExists {
+		t.Fatalf("WriteIfNotExists() error = %v, want %v", err, ErrExists)
+	}
+
+	rec = &store.Record{Key: "casTest", Value: []byte("2")}
+	if err := CompareAndSwap(r, []byte("0"), rec); err != ErrConflict {
+		t.Fatalf("CompareAndSwap() error = %v, want %v", err, ErrConflict)
+	}
+	if err := CompareAndSwap(r, []byte("1"), rec); err != nil {
+		t.Fatalf("CompareAndSwap() error = %v", err)
+	}
+	if err := CompareAndSwap(r, []byte("1"), &store.Record{Key: "casMissing"}); err != store.ErrNotFound {
+		t.Fatalf("CompareAndSwap() error = %v, want %v", err, store.ErrNotFound)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := Update(r, "casTest", func(rec *store.Record) error {
+				n, _ := strconv.Atoi(string(rec.Value))
+				rec.Value = []byte(strconv.Itoa(n + 1))
+				return nil
+			})
+			if err != nil {
+				t.Errorf("Update() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	recs, err := r.Read("casTest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(recs[0].Value) != "7" {
+		t.Errorf("Read() value = %s, want 7", recs[0].Value)
+	}
+	if recs[0].Expiry <= 0 {
+		t.Errorf("Read() expiry = %v, want the expiry to be kept", recs[0].Expiry)
+	}
+}
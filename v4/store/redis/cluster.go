@@ -0,0 +1,56 @@
+package redis
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// globEscaper escapes the pattern characters of KEYS.
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+// key returns the redis key of the record of the table.
+func (r *rkv) key(table, key string) string {
+	if len(table) > 0 && hashTags(r.options) {
+		return "{" + table + "}" + key
+	}
+	return table + key
+}
+
+// hashKey returns the hash of the records of the table in ModeHash.
+func (r *rkv) hashKey(table string) string {
+	if len(table) == 0 {
+		table = DefaultHashKey
+	}
+	if hashTags(r.options) {
+		return "{" + table + "}"
+	}
+	return table
+}
+
+// keys returns the keys matching the pattern. A cluster client sends KEYS to
+// a single node, so the keys of every master are collected instead.
+func (r *rkv) keys(pattern string) ([]string, error) {
+	c, ok := r.Client.(*redis.ClusterClient)
+	if !ok {
+		return r.Client.Keys(r.ctx, pattern).Result()
+	}
+
+	var mtx sync.Mutex
+	var keys []string
+
+	err := c.ForEachMaster(r.ctx, func(ctx context.Context, n *redis.Client) error {
+		k, err := n.Keys(ctx, pattern).Result()
+		if err != nil {
+			return err
+		}
+		mtx.Lock()
+		keys = append(keys, k...)
+		mtx.Unlock()
+		return nil
+	})
+
+	return keys, err
+}
@@ -0,0 +1,92 @@
+package redis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"go-micro.dev/v4/store"
+)
+
+func Test_rkv_configure_topology(t *testing.T) {
+	r := &rkv{options: store.Options{Nodes: []string{"127.0.0.1:7000"}}}
+	for _, o := range []store.Option{WithCluster(), WithAuth("user", "secret")} {
+		o(&r.options)
+	}
+	if err := r.configure(); err != nil {
+		t.Fatal(err)
+	}
+	cluster, ok := r.Client.(*redis.ClusterClient)
+	if !ok {
+		t.Fatalf("configure() with WithCluster expect a *redis.ClusterClient, got %T", r.Client)
+	}
+	if !reflect.DeepEqual(cluster.Options().Addrs, []string{"127.0.0.1:7000"}) {
+		t.Errorf("configure() Addrs = %v", cluster.Options().Addrs)
+	}
+	if cluster.Options().Username != "user" || cluster.Options().Password != "secret" {
+		t.Errorf("configure() username, password = %v, %v", cluster.Options().Username, cluster.Options().Password)
+	}
+
+	r = &rkv{}
+	for _, o := range []store.Option{
+		WithSentinel("mymaster", "127.0.0.1:26379", "127.0.0.1:26380"),
+		WithSentinelPassword("sentinel"),
+		WithAuth("", "secret"),
+	} {
+		o(&r.options)
+	}
+	if err := r.configure(); err != nil {
+		t.Fatal(err)
+	}
+	client, ok := r.Client.(*redis.Client)
+	if !ok {
+		t.Fatalf("configure() with WithSentinel expect a *redis.Client, got %T", r.Client)
+	}
+	if client.Options().Addr != "FailoverClient" {
+		t.Errorf("configure() Addr = %v, want FailoverClient", client.Options().Addr)
+	}
+	if client.Options().Password != "secret" {
+		t.Errorf("configure() password = %v, want secret", client.Options().Password)
+	}
+
+	r = &rkv{options: store.Options{Nodes: []string{"redis://:password@redis:6379"}}}
+	WithAuth("user", "secret")(&r.options)
+	if err := r.configure(); err != nil {
+		t.Fatal(err)
+	}
+	client = r.Client.(*redis.Client)
+	if client.Options().Addr != "redis:6379" || client.Options().Password != "secret" {
+		t.Errorf("configure() Addr, password = %v, %v", client.Options().Addr, client.Options().Password)
+	}
+}
+
+func Test_rkv_key(t *testing.T) {
+	plain := &rkv{}
+	tagged := &rkv{}
+	WithHashTags()(&tagged.options)
+
+	tests := []struct {
+		r       *rkv
+		table   string
+		key     string
+		want    string
+		wantMap string
+	}{
+		{r: plain, table: "users", key: "42", want: "users42", wantMap: "users"},
+		{r: plain, table: "", key: "42", want: "42", wantMap: DefaultHashKey},
+		{r: tagged, table: "users", key: "42", want: "{users}42", wantMap: "{users}"},
+		{r: tagged, table: "", key: "42", want: "42", wantMap: "{" + DefaultHashKey + "}"},
+	}
+	for _, tt := range tests {
+		if got := tt.r.key(tt.table, tt.key); got != tt.want {
+			t.Errorf("key(%q, %q) = %v, want %v", tt.table, tt.key, got, tt.want)
+		}
+		if got := tt.r.hashKey(tt.table); got != tt.wantMap {
+			t.Errorf("hashKey(%q) = %v, want %v", tt.table, got, tt.wantMap)
+		}
+	}
+
+	if got := globEscaper.Replace("{a*}[b]?"); got != `{a\*}\[b\]\?` {
+		t.Errorf("globEscaper.Replace() = %v", got)
+	}
+}
@@ -3,6 +3,7 @@ module github.com/go-micro/plugins/v4/store/redis
 go 1.17
 
 require (
+	github.com/go-micro/plugins/v4/util/session v1.0.0
 	github.com/go-redis/redis/v8 v8.10.0
 	go-micro.dev/v4 v4.9.0
 )
@@ -50,3 +51,5 @@ require (
 	gopkg.in/warnings.v0 v0.1.2 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
+
+replace github.com/go-micro/plugins/v4/util/session => ../../util/session
@@ -0,0 +1,243 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go-micro.dev/v4/store"
+)
+
+// Mode is how the records of a table are stored.
+type Mode int
+
+const (
+	// ModeString stores every record as a string key prefixed with the table.
+	ModeString Mode = iota
+	// ModeHash stores the records of a table as the fields of one hash named
+	// after the table. Expiries are kept in a second hash, ExpirySuffix
+	// appended to the table, as fields can't expire.
+	ModeHash
+	// ModeJSON stores every record as a RedisJSON document, so fields of it
+	// can be read and written with ReadField and WriteField. Values must be
+	// JSON.
+	ModeJSON
+)
+
+var (
+	// DefaultHashKey is the hash of the records of the empty table in
+	// ModeHash.
+	DefaultHashKey = "micro"
+	// ExpirySuffix is appended to the hash of a table to name the hash of
+	// the expiries.
+	ExpirySuffix = ":expiry"
+
+	// ErrNotJSON is returned by ReadField and WriteField for tables not in
+	// ModeJSON.
+	ErrNotJSON = errors.New("table is not stored as json")
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeHash:
+		return "hash"
+	case ModeJSON:
+		return "json"
+	default:
+		return "string"
+	}
+}
+
+// get reads the value of a string or json key.
+func (r *rkv) get(mode Mode, rkey string) ([]byte, error) {
+	if mode == ModeJSON {
+		val, err := r.Client.Do(r.ctx, "JSON.GET", rkey).Text()
+		if err != nil {
+			return nil, err
+		}
+		return []byte(val), nil
+	}
+	return r.Client.Get(r.ctx, rkey).Bytes()
+}
+
+func (r *rkv) writeJSON(rkey string, record *store.Record) error {
+	_, err := r.Client.TxPipelined(r.ctx, func(p redis.Pipeliner) error {
+		p.Do(r.ctx, "JSON.SET", rkey, "$", string(record.Value))
+		if record.Expiry > 0 {
+			p.Expire(r.ctx, rkey, record.Expiry)
+		}
+		return nil
+	})
+	return err
+}
+
+func (r *rkv) readHash(key string, options store.ReadOptions) ([]*store.Record, error) {
+	hkey := r.hashKey(options.Table)
+
+	keys := []string{key}
+	if options.Prefix {
+		fields, err := r.Client.HKeys(r.ctx, hkey).Result()
+		if err != nil {
+			return nil, err
+		}
+		keys = keys[:0]
+		for _, f := range fields {
+			if strings.HasPrefix(f, key) {
+				keys = append(keys, f)
+			}
+		}
+		if len(keys) == 0 {
+			return nil, store.ErrNotFound
+		}
+	}
+
+	vals, err := r.Client.HMGet(r.ctx, hkey, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	expiries, err := r.Client.HMGet(r.ctx, hkey+ExpirySuffix, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	records := make([]*store.Record, 0, len(keys))
+	var expired []string
+
+	for i, k := range keys {
+		val, ok := vals[i].(string)
+		if !ok {
+			continue
+		}
+
+		record := &store.Record{Key: k, Value: []byte(val)}
+		if s, ok := expiries[i].(string); ok {
+			ms, _ := strconv.ParseInt(s, 10, 64)
+			at := time.Unix(0, ms*int64(time.Millisecond))
+			if !at.After(now) {
+				expired = append(expired, k)
+				continue
+			}
+			record.Expiry = at.Sub(now)
+		}
+		records = append(records, record)
+	}
+
+	if len(expired) > 0 {
+		r.deleteHash(hkey, expired...)
+	}
+
+	if len(records) == 0 {
+		return nil, store.ErrNotFound
+	}
+
+	return records, nil
+}
+
+func (r *rkv) writeHash(table string, record *store.Record) error {
+	hkey := r.hashKey(table)
+
+	_, err := r.Client.TxPipelined(r.ctx, func(p redis.Pipeliner) error {
+		p.HSet(r.ctx, hkey, record.Key, record.Value)
+		if record.Expiry > 0 {
+			at := time.Now().Add(record.Expiry).UnixNano() / int64(time.Millisecond)
+			p.HSet(r.ctx, hkey+ExpirySuffix, record.Key, at)
+		} else {
+			p.HDel(r.ctx, hkey+ExpirySuffix, record.Key)
+		}
+		return nil
+	})
+	return err
+}
+
+func (r *rkv) deleteHash(hkey string, keys ...string) error {
+	_, err := r.Client.TxPipelined(r.ctx, func(p redis.Pipeliner) error {
+		p.HDel(r.ctx, hkey, keys...)
+		p.HDel(r.ctx, hkey+ExpirySuffix, keys...)
+		return nil
+	})
+	return err
+}
+
+func (r *rkv) listHash(table string) ([]string, error) {
+	hkey := r.hashKey(table)
+
+	keys, err := r.Client.HKeys(r.ctx, hkey).Result()
+	if err != nil {
+		return nil, err
+	}
+	expiries, err := r.Client.HGetAll(r.ctx, hkey+ExpirySuffix).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	live := keys[:0]
+	for _, k := range keys {
+		if s, ok := expiries[k]; ok {
+			if ms, _ := strconv.ParseInt(s, 10, 64); ms <= now {
+				continue
+			}
+		}
+		live = append(live, k)
+	}
+
+	return live, nil
+}
+
+func jsonStore(s store.Store, table string) (*rkv, error) {
+	r, ok := s.(*rkv)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a redis store", s.String())
+	}
+	if tableMode(r.options, table) != ModeJSON {
+		return nil, ErrNotJSON
+	}
+	return r, nil
+}
+
+// ReadField reads the value at the JSONPath of the record, without reading
+// the whole document. The table of the record must be in ModeJSON.
+func ReadField(s store.Store, key, path string, opts ...store.ReadOption) ([]byte, error) {
+	options := store.ReadOptions{Table: s.Options().Table}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	r, err := jsonStore(s, options.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	val, err := r.Client.Do(r.ctx, "JSON.GET", r.key(options.Table, key), path).Text()
+	if err == redis.Nil {
+		return nil, store.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return []byte(val), nil
+}
+
+// WriteField sets the JSONPath of the record to the JSON value, leaving the
+// rest of the document as it is. The table of the record must be in
+// ModeJSON.
+func WriteField(s store.Store, key, path string, value []byte, opts ...store.WriteOption) error {
+	options := store.WriteOptions{Table: s.Options().Table}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	r, err := jsonStore(s, options.Table)
+	if err != nil {
+		return err
+	}
+
+	err = r.Client.Do(r.ctx, "JSON.SET", r.key(options.Table, key), path, string(value), "XX").Err()
+	if err == redis.Nil {
+		return store.ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,46 @@
+package redis
+
+import (
+	"testing"
+
+	"go-micro.dev/v4/store"
+)
+
+func Test_tableMode(t *testing.T) {
+	var o store.Options
+	for _, opt := range []store.Option{
+		WithMode(ModeHash),
+		WithTableMode("users", ModeJSON),
+		WithTableMode("sessions", ModeString),
+	} {
+		opt(&o)
+	}
+
+	tests := map[string]Mode{
+		"users":    ModeJSON,
+		"sessions": ModeString,
+		"other":    ModeHash,
+	}
+	for table, want := range tests {
+		if got := tableMode(o, table); got != want {
+			t.Errorf("tableMode(%q) = %v, want %v", table, got, want)
+		}
+	}
+
+	if got := tableMode(store.Options{}, "users"); got != ModeString {
+		t.Errorf("tableMode() without options = %v, want %v", got, ModeString)
+	}
+}
+
+func Test_jsonStore(t *testing.T) {
+	var o store.Options
+	WithTableMode("docs", ModeJSON)(&o)
+	r := &rkv{options: o}
+
+	if _, err := jsonStore(r, "docs"); err != nil {
+		t.Errorf("jsonStore(docs) error = %v", err)
+	}
+	if _, err := jsonStore(r, "other"); err != ErrNotJSON {
+		t.Errorf("jsonStore(other) error = %v, want %v", err, ErrNotJSON)
+	}
+}
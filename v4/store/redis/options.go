@@ -8,6 +8,24 @@ import (
 )
 
 type redisOptionsContextKey struct{}
+type modeContextKey struct{}
+type tableModesContextKey struct{}
+type clusterContextKey struct{}
+type sentinelContextKey struct{}
+type sentinelPasswordContextKey struct{}
+type authContextKey struct{}
+type hashTagsContextKey struct{}
+type replicasContextKey struct{}
+
+type sentinel struct {
+	master string
+	addrs  []string
+}
+
+type auth struct {
+	username string
+	password string
+}
 
 // WithRedisOptions sets advanced options for redis.
 func WithRedisOptions(options redis.UniversalOptions) store.Option {
@@ -20,24 +38,175 @@ func WithRedisOptions(options redis.UniversalOptions) store.Option {
 	}
 }
 
+// WithMode sets how records are stored in tables without a mode of their own.
+// Defaults to ModeString.
+func WithMode(m Mode) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+
+		o.Context = context.WithValue(o.Context, modeContextKey{}, m)
+	}
+}
+
+// WithTableMode sets how records of the table are stored.
+func WithTableMode(table string, m Mode) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+
+		modes := make(map[string]Mode)
+		if m, ok := o.Context.Value(tableModesContextKey{}).(map[string]Mode); ok {
+			for k, v := range m {
+				modes[k] = v
+			}
+		}
+		modes[table] = m
+
+		o.Context = context.WithValue(o.Context, tableModesContextKey{}, modes)
+	}
+}
+
+// WithCluster connects to the Redis Cluster of the seed addresses, even if
+// only one is given. Defaults to the nodes of the store.
+func WithCluster(addrs ...string) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+
+		o.Context = context.WithValue(o.Context, clusterContextKey{}, addrs)
+	}
+}
+
+// WithSentinel connects to the master of the name, as reported by the
+// sentinels of the addresses. Defaults to the nodes of the store as
+// sentinels.
+func WithSentinel(master string, addrs ...string) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+
+		o.Context = context.WithValue(o.Context, sentinelContextKey{}, sentinel{master: master, addrs: addrs})
+	}
+}
+
+// WithSentinelPassword sets the password of the sentinels, which can differ
+// from the one of the master.
+func WithSentinelPassword(password string) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+
+		o.Context = context.WithValue(o.Context, sentinelPasswordContextKey{}, password)
+	}
+}
+
+// WithAuth sets the username and password of the redis nodes, taking
+// precedence over the ones of a node url.
+func WithAuth(username, password string) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+
+		o.Context = context.WithValue(o.Context, authContextKey{}, auth{username: username, password: password})
+	}
+}
+
+// WithHashTags wraps the table of every key in a hash tag, e.g. "{users}42",
+// so all records of a table end up in the same cluster slot. It keeps the
+// hashes and expiries of ModeHash tables in one slot too, so they are
+// written in one transaction. Changes the keys of existing records.
+func WithHashTags() store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+
+		o.Context = context.WithValue(o.Context, hashTagsContextKey{}, true)
+	}
+}
+
+// WithReplicas reads and lists from the replicas of the addresses, in turn,
+// so the primary only takes the writes. Replicas may lag behind, read
+// through Session to read the writes of the session.
+func WithReplicas(addrs ...string) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+
+		o.Context = context.WithValue(o.Context, replicasContextKey{}, addrs)
+	}
+}
+
+func hashTags(o store.Options) bool {
+	if o.Context == nil {
+		return false
+	}
+	b, _ := o.Context.Value(hashTagsContextKey{}).(bool)
+	return b
+}
+
+func tableMode(o store.Options, table string) Mode {
+	if o.Context == nil {
+		return ModeString
+	}
+	if modes, ok := o.Context.Value(tableModesContextKey{}).(map[string]Mode); ok {
+		if m, ok := modes[table]; ok {
+			return m
+		}
+	}
+	if m, ok := o.Context.Value(modeContextKey{}).(Mode); ok {
+		return m
+	}
+	return ModeString
+}
+
 func newUniversalClient(o store.Options) redis.UniversalClient {
 	if o.Context == nil {
 		o.Context = context.Background()
 	}
 
 	opts, ok := o.Context.Value(redisOptionsContextKey{}).(redis.UniversalOptions)
+	a, hasAuth := o.Context.Value(authContextKey{}).(auth)
+	if hasAuth {
+		opts.Username = a.username
+		opts.Password = a.password
+	}
+	if pw, ok := o.Context.Value(sentinelPasswordContextKey{}).(string); ok {
+		opts.SentinelPassword = pw
+	}
+
+	if addrs, ok := o.Context.Value(clusterContextKey{}).([]string); ok {
+		opts.Addrs = addrs
+		if len(opts.Addrs) == 0 {
+			opts.Addrs = o.Nodes
+		}
+		return redis.NewClusterClient(opts.Cluster())
+	}
+
+	if s, ok := o.Context.Value(sentinelContextKey{}).(sentinel); ok {
+		opts.MasterName = s.master
+		opts.Addrs = s.addrs
+		if len(opts.Addrs) == 0 {
+			opts.Addrs = o.Nodes
+		}
+		return redis.NewFailoverClient(opts.Failover())
+	}
+
 	if !ok && len(o.Nodes) <= 1 {
 		addr := "redis://127.0.0.1:6379"
 		if len(o.Nodes) > 0 {
 			addr = o.Nodes[0]
 		}
 
-		redisOptions, err := redis.ParseURL(addr)
-		if err != nil {
-			redisOptions = &redis.Options{Addr: addr}
-		}
-
-		return redis.NewClient(redisOptions)
+		return newClient(o, addr)
 	}
 
 	if len(opts.Addrs) == 0 && len(o.Nodes) > 0 {
@@ -46,3 +215,30 @@ func newUniversalClient(o store.Options) redis.UniversalClient {
 
 	return redis.NewUniversalClient(&opts)
 }
+
+// newClient returns a client of the node of the address, a url or host:port.
+func newClient(o store.Options, addr string) *redis.Client {
+	redisOptions, err := redis.ParseURL(addr)
+	if err != nil {
+		redisOptions = &redis.Options{Addr: addr}
+	}
+	if a, ok := o.Context.Value(authContextKey{}).(auth); ok {
+		redisOptions.Username = a.username
+		redisOptions.Password = a.password
+	}
+
+	return redis.NewClient(redisOptions)
+}
+
+func newReplicaClients(o store.Options) []redis.UniversalClient {
+	if o.Context == nil {
+		return nil
+	}
+
+	addrs, _ := o.Context.Value(replicasContextKey{}).([]string)
+	clients := make([]redis.UniversalClient, 0, len(addrs))
+	for _, addr := range addrs {
+		clients = append(clients, newClient(o, addr))
+	}
+	return clients
+}
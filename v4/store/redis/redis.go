@@ -3,6 +3,7 @@ package redis
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/go-redis/redis/v8"
 	log "go-micro.dev/v4/logger"
@@ -11,9 +12,11 @@ import (
 )
 
 type rkv struct {
-	ctx     context.Context
-	options store.Options
-	Client  redis.UniversalClient
+	ctx      context.Context
+	options  store.Options
+	Client   redis.UniversalClient
+	replicas []redis.UniversalClient
+	next     *uint32
 }
 
 func init() {
@@ -29,10 +32,15 @@ func (r *rkv) Init(opts ...store.Option) error {
 }
 
 func (r *rkv) Close() error {
+	r.closeReplicas()
 	return r.Client.Close()
 }
 
 func (r *rkv) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	return r.on(r.replica()).read(key, opts...)
+}
+
+func (r *rkv) read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
 	options := store.ReadOptions{}
 	options.Table = r.options.Table
 
@@ -40,14 +48,19 @@ func (r *rkv) Read(key string, opts ...store.ReadOption) ([]*store.Record, error
 		o(&options)
 	}
 
+	mode := tableMode(r.options, options.Table)
+	if mode == ModeHash {
+		return r.readHash(key, options)
+	}
+
 	var keys []string
 
-	rkey := fmt.Sprintf("%s%s", options.Table, key)
+	rkey := r.key(options.Table, key)
 	// Handle Prefix
 	// TODO suffix
 	if options.Prefix {
-		prefixKey := fmt.Sprintf("%s*", rkey)
-		fkeys, err := r.Client.Keys(r.ctx, prefixKey).Result()
+		prefixKey := fmt.Sprintf("%s*", globEscaper.Replace(rkey))
+		fkeys, err := r.keys(prefixKey)
 		if err != nil {
 			return nil, err
 		}
@@ -61,7 +74,7 @@ func (r *rkv) Read(key string, opts ...store.ReadOption) ([]*store.Record, error
 	records := make([]*store.Record, 0, len(keys))
 
 	for _, rkey = range keys {
-		val, err := r.Client.Get(r.ctx, rkey).Bytes()
+		val, err := r.get(mode, rkey)
 
 		if err != nil && err == redis.Nil {
 			return nil, store.ErrNotFound
@@ -79,7 +92,7 @@ func (r *rkv) Read(key string, opts ...store.ReadOption) ([]*store.Record, error
 		}
 
 		records = append(records, &store.Record{
-			Key:    key,
+			Key:    strings.TrimPrefix(rkey, r.key(options.Table, "")),
 			Value:  val,
 			Expiry: d,
 		})
@@ -96,7 +109,11 @@ func (r *rkv) Delete(key string, opts ...store.DeleteOption) error {
 		o(&options)
 	}
 
-	rkey := fmt.Sprintf("%s%s", options.Table, key)
+	if tableMode(r.options, options.Table) == ModeHash {
+		return r.deleteHash(r.hashKey(options.Table), key)
+	}
+
+	rkey := r.key(options.Table, key)
 	return r.Client.Del(r.ctx, rkey).Err()
 }
 
@@ -108,11 +125,22 @@ func (r *rkv) Write(record *store.Record, opts ...store.WriteOption) error {
 		o(&options)
 	}
 
-	rkey := fmt.Sprintf("%s%s", options.Table, record.Key)
+	rkey := r.key(options.Table, record.Key)
+	switch tableMode(r.options, options.Table) {
+	case ModeHash:
+		return r.writeHash(options.Table, record)
+	case ModeJSON:
+		return r.writeJSON(rkey, record)
+	}
+
 	return r.Client.Set(r.ctx, rkey, record.Value, record.Expiry).Err()
 }
 
 func (r *rkv) List(opts ...store.ListOption) ([]string, error) {
+	return r.on(r.replica()).list(opts...)
+}
+
+func (r *rkv) list(opts ...store.ListOption) ([]string, error) {
 	options := store.ListOptions{}
 	options.Table = r.options.Table
 
@@ -120,7 +148,11 @@ func (r *rkv) List(opts ...store.ListOption) ([]string, error) {
 		o(&options)
 	}
 
-	keys, err := r.Client.Keys(r.ctx, "*").Result()
+	if tableMode(r.options, options.Table) == ModeHash {
+		return r.listHash(options.Table)
+	}
+
+	keys, err := r.keys("*")
 	if err != nil {
 		return nil, err
 	}
@@ -145,6 +177,7 @@ func NewStore(opts ...store.Option) store.Store {
 	s := &rkv{
 		ctx:     context.Background(),
 		options: options,
+		next:    new(uint32),
 	}
 
 	if err := s.configure(); err != nil {
@@ -160,5 +193,8 @@ func (r *rkv) configure() error {
 	}
 	r.Client = newUniversalClient(r.options)
 
+	r.closeReplicas()
+	r.replicas = newReplicaClients(r.options)
+
 	return nil
 }
@@ -0,0 +1,65 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// replica returns the next replica to read from, or the primary without
+// replicas.
+func (r *rkv) replica() redis.UniversalClient {
+	if len(r.replicas) == 0 {
+		return r.Client
+	}
+	n := atomic.AddUint32(r.next, 1)
+	return r.replicas[int(n)%len(r.replicas)]
+}
+
+// on returns a view of the store sending its commands to the client.
+func (r *rkv) on(c redis.UniversalClient) *rkv {
+	if c == r.Client {
+		return r
+	}
+	return &rkv{ctx: r.ctx, options: r.options, Client: c}
+}
+
+func (r *rkv) closeReplicas() {
+	for _, c := range r.replicas {
+		c.Close()
+	}
+	r.replicas = nil
+}
+
+// replicationOffset returns the field of the replication section of INFO,
+// master_repl_offset on a primary, slave_repl_offset on a replica.
+func replicationOffset(ctx context.Context, c redis.UniversalClient, field string) (uint64, error) {
+	info, err := c.Info(ctx, "replication").Result()
+	if err != nil {
+		return 0, err
+	}
+	return parseOffset(info, field)
+}
+
+// parseOffset returns the offset of the field of an INFO reply.
+func parseOffset(info, field string) (uint64, error) {
+	sc := bufio.NewScanner(strings.NewReader(info))
+	for sc.Scan() {
+		k, v, ok := cut(strings.TrimSpace(sc.Text()), ":")
+		if ok && k == field {
+			return strconv.ParseUint(v, 10, 64)
+		}
+	}
+	return 0, redis.Nil
+}
+
+func cut(s, sep string) (string, string, bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
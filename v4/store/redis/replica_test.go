@@ -0,0 +1,40 @@
+package redis
+
+import (
+	"testing"
+
+	"go-micro.dev/v4/store"
+)
+
+func Test_parseOffset(t *testing.T) {
+	info := "# Replication\r\nrole:slave\r\nmaster_host:primary\r\nslave_repl_offset:1234\r\nmaster_repl_offset:1240\r\n"
+
+	if got, err := parseOffset(info, "slave_repl_offset"); err != nil || got != 1234 {
+		t.Errorf("parseOffset(slave_repl_offset) = %v, %v, want 1234", got, err)
+	}
+	if got, err := parseOffset(info, "master_repl_offset"); err != nil || got != 1240 {
+		t.Errorf("parseOffset(master_repl_offset) = %v, %v, want 1240", got, err)
+	}
+	if _, err := parseOffset(info, "repl_backlog_size"); err == nil {
+		t.Error("parseOffset(repl_backlog_size) expected an error")
+	}
+}
+
+func Test_rkv_replica(t *testing.T) {
+	r := NewStore(store.Nodes("redis://primary:6379"), WithReplicas("replica1:6379", "replica2:6379")).(*rkv)
+	defer r.Close()
+
+	first, second := r.replica(), r.replica()
+	if first == r.Client || second == r.Client || first == second {
+		t.Errorf("replica() expected the replicas in turn")
+	}
+	if r.replica() != first {
+		t.Errorf("replica() expected the first replica again")
+	}
+
+	p := NewStore(store.Nodes("redis://primary:6379")).(*rkv)
+	defer p.Close()
+	if p.replica() != p.Client {
+		t.Errorf("replica() expected the primary without replicas")
+	}
+}
@@ -0,0 +1,115 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/go-micro/plugins/v4/util/session"
+	"go-micro.dev/v4/store"
+)
+
+// sessionStore is a view of the store reading from a replica only once it
+// has replicated the writes of the session.
+type sessionStore struct {
+	r       *rkv
+	ctx     context.Context
+	session *session.Session
+}
+
+// Session returns a view of the store which reads the writes of the session
+// of the context, see the session package. After every write it records the
+// replication offset of the primary in the session, reads go to a replica
+// only once its offset caught up and to the primary otherwise.
+func Session(ctx context.Context, s store.Store) (store.Store, error) {
+	r, ok := s.(*rkv)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a redis store", s.String())
+	}
+
+	ctx, sess := session.Context(ctx)
+	return &sessionStore{r: r, ctx: ctx, session: sess}, nil
+}
+
+// sessionKey returns the key of the primary in sessions.
+func (s *sessionStore) sessionKey() string {
+	nodes := s.r.options.Nodes
+	if len(nodes) == 0 {
+		nodes = []string{"127.0.0.1:6379"}
+	}
+	return "redis/" + strings.Join(nodes, ",")
+}
+
+// reader returns the store to read from, a replica which replicated the
+// writes of the session or the primary.
+func (s *sessionStore) reader() *rkv {
+	c := s.r.replica()
+	if c == s.r.Client {
+		return s.r
+	}
+
+	pos, ok := s.session.Position(s.sessionKey())
+	if !ok {
+		return s.r.on(c)
+	}
+	off, err := replicationOffset(s.ctx, c, "slave_repl_offset")
+	if err != nil || off < pos {
+		return s.r
+	}
+	return s.r.on(c)
+}
+
+// written records the replication offset of the primary after a write. If
+// it can't be read the session reads from the primary from then on.
+func (s *sessionStore) written() {
+	if len(s.r.replicas) == 0 {
+		return
+	}
+	off, err := replicationOffset(s.ctx, s.r.Client, "master_repl_offset")
+	if err != nil {
+		off = math.MaxUint64
+	}
+	s.session.Advance(s.sessionKey(), off)
+}
+
+func (s *sessionStore) Init(opts ...store.Option) error {
+	return s.r.Init(opts...)
+}
+
+func (s *sessionStore) Options() store.Options {
+	return s.r.Options()
+}
+
+func (s *sessionStore) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	return s.reader().read(key, opts...)
+}
+
+func (s *sessionStore) Write(r *store.Record, opts ...store.WriteOption) error {
+	if err := s.r.Write(r, opts...); err != nil {
+		return err
+	}
+	s.written()
+	return nil
+}
+
+func (s *sessionStore) Delete(key string, opts ...store.DeleteOption) error {
+	if err := s.r.Delete(key, opts...); err != nil {
+		return err
+	}
+	s.written()
+	return nil
+}
+
+func (s *sessionStore) List(opts ...store.ListOption) ([]string, error) {
+	return s.reader().list(opts...)
+}
+
+// Close is a no-op, the clients are owned by the underlying store.
+func (s *sessionStore) Close() error {
+	return nil
+}
+
+func (s *sessionStore) String() string {
+	return s.r.String()
+}
@@ -0,0 +1,67 @@
+package s3
+
+import (
+	"context"
+
+	"go-micro.dev/v4/store"
+)
+
+type regionKey struct{}
+type credentialsKey struct{}
+type pathStyleKey struct{}
+type partSizeKey struct{}
+type sseKey struct{}
+
+type credentials struct {
+	id, secret, token string
+}
+
+type sse struct {
+	algorithm, kmsKey string
+}
+
+// Region sets the region of the buckets. Defaults to the region of the
+// environment, or us-east-1.
+func Region(r string) store.Option {
+	return setStoreOption(regionKey{}, r)
+}
+
+// Credentials authenticates with static credentials. Without it the default
+// credential chain is used, which includes environment variables, shared
+// config and instance roles.
+func Credentials(id, secret, token string) store.Option {
+	return setStoreOption(credentialsKey{}, credentials{id: id, secret: secret, token: token})
+}
+
+// PathStyle addresses buckets by path instead of by host, e.g. for MinIO.
+func PathStyle() store.Option {
+	return setStoreOption(pathStyleKey{}, true)
+}
+
+// PartSize sets the size of the parts in bytes records are uploaded in,
+// records up to that size are uploaded in one request. Defaults to
+// DefaultPartSize, the minimum S3 accepts.
+func PartSize(n int64) store.Option {
+	return setStoreOption(partSizeKey{}, n)
+}
+
+// ServerSideEncryption encrypts the records with keys managed by S3
+// (SSE-S3).
+func ServerSideEncryption() store.Option {
+	return setStoreOption(sseKey{}, sse{algorithm: "AES256"})
+}
+
+// KMSKey encrypts the records with the KMS key (SSE-KMS). An empty key ID
+// uses the AWS managed key of S3.
+func KMSKey(id string) store.Option {
+	return setStoreOption(sseKey{}, sse{algorithm: "aws:kms", kmsKey: id})
+}
+
+func setStoreOption(k, v interface{}) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
@@ -0,0 +1,413 @@
+// Package s3 implements the store on Amazon S3 and compatible object
+// storage.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	awscreds "github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	s3api "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/go-micro/plugins/v4/util/records"
+	"github.com/pkg/errors"
+	log "go-micro.dev/v4/logger"
+	"go-micro.dev/v4/store"
+	"go-micro.dev/v4/util/cmd"
+)
+
+var (
+	// DefaultDatabase is the bucket used if no database is provided.
+	DefaultDatabase = "micro"
+	// DefaultPartSize is the size of the parts records are uploaded in.
+	DefaultPartSize int64 = s3manager.MinUploadPartSize
+)
+
+// object metadata key of the record metadata
+const metadataKey = "micrometadata"
+
+// bucket names are lowercase letters, numbers, dots and hyphens
+var bucketRe = regexp.MustCompile("[^a-z0-9.-]+")
+
+type s3Store struct {
+	ctx      context.Context
+	options  store.Options
+	client   *s3api.S3
+	uploader *s3manager.Uploader
+
+	sync.RWMutex
+	// buckets known to exist
+	buckets map[string]bool
+}
+
+func init() {
+	cmd.DefaultStores["s3"] = NewStore
+}
+
+// NewStore returns a new store backed by S3. The node is the endpoint, only
+// needed for other object storage than S3, e.g. http://localhost:9000 for
+// MinIO. Databases are buckets and tables prefix the object keys as
+// "<table>/<key>".
+func NewStore(opts ...store.Option) store.Store {
+	options := store.Options{
+		Database: DefaultDatabase,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	s := &s3Store{
+		ctx:     context.Background(),
+		options: options,
+		buckets: make(map[string]bool),
+	}
+
+	if err := s.configure(); err != nil {
+		log.Fatal(err)
+	}
+
+	return s
+}
+
+func (s *s3Store) configure() error {
+	sess, err := session.NewSession(newConfig(s.options))
+	if err != nil {
+		return errors.Wrap(err, "creating s3 session")
+	}
+
+	client := s3api.New(sess)
+	uploader := s3manager.NewUploaderWithClient(client, func(u *s3manager.Uploader) {
+		u.PartSize = partSize(s.options)
+	})
+
+	s.Lock()
+	s.client = client
+	s.uploader = uploader
+	s.buckets = make(map[string]bool)
+	s.Unlock()
+
+	return nil
+}
+
+func newConfig(o store.Options) *aws.Config {
+	cfg := aws.NewConfig()
+	if len(o.Nodes) > 0 {
+		cfg = cfg.WithEndpoint(o.Nodes[0])
+	}
+	if o.Context == nil {
+		return cfg
+	}
+
+	if r, ok := o.Context.Value(regionKey{}).(string); ok {
+		cfg = cfg.WithRegion(r)
+	}
+	if c, ok := o.Context.Value(credentialsKey{}).(credentials); ok {
+		cfg = cfg.WithCredentials(awscreds.NewStaticCredentials(c.id, c.secret, c.token))
+	}
+	if ok, _ := o.Context.Value(pathStyleKey{}).(bool); ok {
+		cfg = cfg.WithS3ForcePathStyle(true)
+	}
+	return cfg
+}
+
+func partSize(o store.Options) int64 {
+	if o.Context != nil {
+		if n, ok := o.Context.Value(partSizeKey{}).(int64); ok && n >= s3manager.MinUploadPartSize {
+			return n
+		}
+	}
+	return DefaultPartSize
+}
+
+func encryption(o store.Options) (sse, bool) {
+	if o.Context == nil {
+		return sse{}, false
+	}
+	e, ok := o.Context.Value(sseKey{}).(sse)
+	return e, ok
+}
+
+func (s *s3Store) Init(opts ...store.Option) error {
+	for _, o := range opts {
+		o(&s.options)
+	}
+	return s.configure()
+}
+
+func (s *s3Store) Options() store.Options {
+	return s.options
+}
+
+func (s *s3Store) Close() error {
+	return nil
+}
+
+func (s *s3Store) String() string {
+	return "s3"
+}
+
+func (s *s3Store) names(database, table string) (string, string) {
+	return records.ObjectNames(s.options, database, table, bucketRe)
+}
+
+// createBucket creates the bucket on the first write to it.
+func (s *s3Store) createBucket(bucket string) error {
+	s.RLock()
+	ok := s.buckets[bucket]
+	s.RUnlock()
+	if ok {
+		return nil
+	}
+
+	in := &s3api.CreateBucketInput{Bucket: aws.String(bucket)}
+	if r := aws.StringValue(s.client.Config.Region); len(r) > 0 && r != "us-east-1" {
+		in.CreateBucketConfiguration = &s3api.CreateBucketConfiguration{LocationConstraint: aws.String(r)}
+	}
+
+	_, err := s.client.CreateBucketWithContext(s.ctx, in)
+	if err != nil && !hasCode(err, s3api.ErrCodeBucketAlreadyOwnedByYou, s3api.ErrCodeBucketAlreadyExists) {
+		return err
+	}
+
+	s.Lock()
+	s.buckets[bucket] = true
+	s.Unlock()
+	return nil
+}
+
+func hasCode(err error, codes ...string) bool {
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		return false
+	}
+	for _, c := range codes {
+		if aerr.Code() == c {
+			return true
+		}
+	}
+	return false
+}
+
+func notFound(err error) bool {
+	// HEAD requests have no body, so the code is the status text
+	return hasCode(err, s3api.ErrCodeNoSuchKey, s3api.ErrCodeNoSuchBucket, "NotFound")
+}
+
+func (s *s3Store) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	var options store.ReadOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	bucket, prefix := s.names(options.Database, options.Table)
+
+	if !options.Prefix && !options.Suffix {
+		r, err := s.read(bucket, prefix, key)
+		if err != nil {
+			return nil, err
+		}
+		return []*store.Record{r}, nil
+	}
+
+	listPrefix := ""
+	if options.Prefix {
+		listPrefix = key
+	}
+	keys, err := s.list(bucket, prefix, listPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*store.Record
+	for _, k := range keys {
+		if options.Suffix && !strings.HasSuffix(k, key) {
+			continue
+		}
+		r, err := s.read(bucket, prefix, k)
+		if err == store.ErrNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+
+	start, end := records.Bounds(len(results), options.Offset, options.Limit)
+	return results[start:end], nil
+}
+
+// get returns the object of the record, store.ErrNotFound if it doesn't
+// exist or expired.
+func (s *s3Store) get(bucket, prefix, key string) (*s3api.GetObjectOutput, time.Duration, error) {
+	rsp, err := s.client.GetObjectWithContext(s.ctx, &s3api.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(prefix + key),
+	})
+	if notFound(err) {
+		return nil, 0, store.ErrNotFound
+	} else if err != nil {
+		return nil, 0, err
+	}
+
+	d, ok := records.Expiry(rsp.Metadata, time.Now())
+	if ok && d <= 0 {
+		rsp.Body.Close()
+		// lazily delete the expired record
+		go s.delete(bucket, prefix+key)
+		return nil, 0, store.ErrNotFound
+	}
+
+	return rsp, d, nil
+}
+
+func (s *s3Store) read(bucket, prefix, key string) (*store.Record, error) {
+	rsp, d, err := s.get(bucket, prefix, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+
+	value, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &store.Record{
+		Key:      key,
+		Value:    value,
+		Metadata: make(map[string]interface{}),
+		Expiry:   d,
+	}
+	if v, ok := records.Metadata(rsp.Metadata, metadataKey); ok {
+		if b, err := base64.StdEncoding.DecodeString(v); err == nil {
+			json.Unmarshal(b, &r.Metadata)
+		}
+	}
+
+	return r, nil
+}
+
+func (s *s3Store) Write(r *store.Record, opts ...store.WriteOption) error {
+	var options store.WriteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	metadata := make(map[string]*string)
+	if len(r.Metadata) > 0 {
+		b, err := json.Marshal(r.Metadata)
+		if err != nil {
+			return err
+		}
+		// metadata headers are ascii
+		metadata[metadataKey] = aws.String(base64.StdEncoding.EncodeToString(b))
+	}
+
+	return s.upload(r.Key, bytes.NewReader(r.Value), r.Expiry, metadata, options)
+}
+
+// upload uploads the body in parts if it's larger than the part size.
+func (s *s3Store) upload(key string, body io.Reader, d time.Duration, metadata map[string]*string, options store.WriteOptions) error {
+	bucket, prefix := s.names(options.Database, options.Table)
+	if err := s.createBucket(bucket); err != nil {
+		return err
+	}
+
+	records.SetExpiry(metadata, records.TTL(d, options), time.Now())
+
+	in := &s3manager.UploadInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(prefix + key),
+		Body:     body,
+		Metadata: metadata,
+	}
+	if e, ok := encryption(s.options); ok {
+		in.ServerSideEncryption = aws.String(e.algorithm)
+		if len(e.kmsKey) > 0 {
+			in.SSEKMSKeyId = aws.String(e.kmsKey)
+		}
+	}
+
+	_, err := s.uploader.UploadWithContext(s.ctx, in)
+	return err
+}
+
+func (s *s3Store) Delete(key string, opts ...store.DeleteOption) error {
+	var options store.DeleteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	bucket, prefix := s.names(options.Database, options.Table)
+	return s.delete(bucket, prefix+key)
+}
+
+func (s *s3Store) delete(bucket, key string) error {
+	_, err := s.client.DeleteObjectWithContext(s.ctx, &s3api.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if notFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *s3Store) List(opts ...store.ListOption) ([]string, error) {
+	var options store.ListOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	bucket, prefix := s.names(options.Database, options.Table)
+
+	keys, err := s.list(bucket, prefix, options.Prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(options.Suffix) > 0 {
+		filtered := keys[:0]
+		for _, k := range keys {
+			if strings.HasSuffix(k, options.Suffix) {
+				filtered = append(filtered, k)
+			}
+		}
+		keys = filtered
+	}
+
+	start, end := records.Bounds(len(keys), options.Offset, options.Limit)
+	return keys[start:end], nil
+}
+
+// list returns the keys of the table with the prefix, listing the objects
+// with the prefix server side. Listings don't include the object metadata,
+// so expired records are listed until they're read.
+func (s *s3Store) list(bucket, table, prefix string) ([]string, error) {
+	var keys []string
+
+	err := s.client.ListObjectsV2PagesWithContext(s.ctx, &s3api.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(table + prefix),
+	}, func(page *s3api.ListObjectsV2Output, last bool) bool {
+		for _, o := range page.Contents {
+			keys = append(keys, strings.TrimPrefix(aws.StringValue(o.Key), table))
+		}
+		return true
+	})
+	if notFound(err) {
+		return nil, nil
+	}
+
+	return keys, err
+}
@@ -0,0 +1,620 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-micro/plugins/v4/util/records"
+	"go-micro.dev/v4/store"
+)
+
+func TestPresign(t *testing.T) {
+	s := NewStore(
+		store.Database("artifacts"),
+		store.Table("builds"),
+		Region("eu-west-1"),
+		Credentials("id", "secret", ""),
+	)
+
+	u, err := Presign(s, "app.tar.gz", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := url.Parse(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(p.Path, "/builds/app.tar.gz") || !strings.HasPrefix(p.Host, "artifacts.") {
+		t.Errorf("Expected the object of the record, got %s", u)
+	}
+	q := p.Query()
+	if q.Get("X-Amz-Expires") != "3600" {
+		t.Errorf("Expected the url to expire in an hour, got %s", u)
+	}
+	if len(q.Get("X-Amz-Signature")) == 0 || !strings.HasPrefix(q.Get("X-Amz-Credential"), "id/") {
+		t.Errorf("Expected the url to be signed with the credentials of the store, got %s", u)
+	}
+
+	// other tables and path style endpoints
+	s = NewStore(
+		store.Nodes("http://localhost:9000"),
+		Region("us-east-1"),
+		Credentials("id", "secret", ""),
+		PathStyle(),
+	)
+	u, err = Presign(s, "app.tar.gz", time.Minute, store.ReadFrom("releases", "v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(u, "http://localhost:9000/releases/v1/app.tar.gz?") {
+		t.Errorf("Expected a path style url of the table, got %s", u)
+	}
+
+	if _, err := Presign(store.NewMemoryStore(), "app.tar.gz", time.Hour); err == nil {
+		t.Error("Expected an error for other stores")
+	}
+}
+
+type object struct {
+	data    []byte
+	headers http.Header
+}
+
+type upload struct {
+	object
+	parts map[int][]byte
+}
+
+// s3Server is the part of the path style S3 REST API used by the store. It
+// returns at most pageSize objects per list request.
+type s3Server struct {
+	pageSize int
+
+	sync.Mutex
+	buckets map[string]map[string]*object
+	uploads map[string]*upload
+	// the bodies of the create bucket requests
+	creates []string
+	// requests by operation
+	requests map[string]int
+}
+
+func newS3Server(t *testing.T, pageSize int) (*s3Server, *httptest.Server) {
+	s := &s3Server{
+		pageSize: pageSize,
+		buckets:  make(map[string]map[string]*object),
+		uploads:  make(map[string]*upload),
+		requests: make(map[string]int),
+	}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+func (s *s3Server) count(op string) int {
+	s.Lock()
+	defer s.Unlock()
+	return s.requests[op]
+}
+
+func (s *s3Server) get(bucket, key string) (*object, bool) {
+	s.Lock()
+	defer s.Unlock()
+	o, ok := s.buckets[bucket][key]
+	return o, ok
+}
+
+func s3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>%s</Code><Message>%s</Message></Error>`, code, code)
+}
+
+// objectHeaders returns the metadata and encryption headers of a request,
+// which S3 stores with the object.
+func objectHeaders(r *http.Request) http.Header {
+	h := make(http.Header)
+	for k, v := range r.Header {
+		if l := strings.ToLower(k); strings.HasPrefix(l, "x-amz-meta-") || strings.HasPrefix(l, "x-amz-server-side-encryption") {
+			h[k] = v
+		}
+	}
+	return h
+}
+
+func (s *s3Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	// the path is /<bucket>[/<key>]
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	bucket := parts[0]
+	key := ""
+	if len(parts) == 2 {
+		key = parts[1]
+	}
+	q := r.URL.Query()
+	objects, exists := s.buckets[bucket]
+
+	switch {
+	case key == "" && r.Method == http.MethodPut:
+		s.requests["create"]++
+		if exists {
+			s3Error(w, http.StatusConflict, "BucketAlreadyOwnedByYou")
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		s.creates = append(s.creates, string(body))
+		s.buckets[bucket] = make(map[string]*object)
+	case key == "" && r.Method == http.MethodGet:
+		s.requests["list"]++
+		if !exists {
+			s3Error(w, http.StatusNotFound, "NoSuchBucket")
+			return
+		}
+		s.list(w, bucket, objects, q.Get("prefix"), q.Get("continuation-token"))
+	case !exists:
+		s3Error(w, http.StatusNotFound, "NoSuchBucket")
+	case r.Method == http.MethodPost && q.Has("uploads"):
+		s.requests["initiate"]++
+		id := strconv.Itoa(len(s.uploads) + 1)
+		s.uploads[id] = &upload{object: object{headers: objectHeaders(r)}, parts: make(map[int][]byte)}
+		fmt.Fprintf(w, `<InitiateMultipartUploadResult><Bucket>%s</Bucket><Key>%s</Key><UploadId>%s</UploadId></InitiateMultipartUploadResult>`, bucket, key, id)
+	case r.Method == http.MethodPut && q.Has("uploadId"):
+		s.requests["part"]++
+		u, ok := s.uploads[q.Get("uploadId")]
+		if !ok {
+			s3Error(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		n, _ := strconv.Atoi(q.Get("partNumber"))
+		u.parts[n], _ = io.ReadAll(r.Body)
+		w.Header().Set("ETag", fmt.Sprintf(`"%d"`, n))
+	case r.Method == http.MethodPost && q.Has("uploadId"):
+		s.requests["complete"]++
+		u, ok := s.uploads[q.Get("uploadId")]
+		if !ok {
+			s3Error(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		var numbers []int
+		for n := range u.parts {
+			numbers = append(numbers, n)
+		}
+		sort.Ints(numbers)
+		for _, n := range numbers {
+			u.data = append(u.data, u.parts[n]...)
+		}
+		objects[key] = &u.object
+		delete(s.uploads, q.Get("uploadId"))
+		fmt.Fprintf(w, `<CompleteMultipartUploadResult><Bucket>%s</Bucket><Key>%s</Key><ETag>"%d"</ETag></CompleteMultipartUploadResult>`, bucket, key, len(numbers))
+	case r.Method == http.MethodPut:
+		s.requests["put"]++
+		data, _ := io.ReadAll(r.Body)
+		objects[key] = &object{data: data, headers: objectHeaders(r)}
+	case r.Method == http.MethodGet:
+		s.requests["get"]++
+		o, ok := objects[key]
+		if !ok {
+			s3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		for k, v := range o.headers {
+			w.Header()[k] = v
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(o.data)))
+		w.Write(o.data)
+	case r.Method == http.MethodDelete:
+		// deleting a missing object succeeds
+		s.requests["delete"]++
+		delete(objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func (s *s3Server) list(w http.ResponseWriter, bucket string, objects map[string]*object, prefix, token string) {
+	after := ""
+	if len(token) > 0 {
+		b, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			s3Error(w, http.StatusBadRequest, "InvalidArgument")
+			return
+		}
+		after = string(b)
+	}
+
+	var keys []string
+	for key := range objects {
+		if strings.HasPrefix(key, prefix) && key > after {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	type content struct {
+		Key  string `xml:"Key"`
+		Size int    `xml:"Size"`
+	}
+	rsp := struct {
+		XMLName               xml.Name  `xml:"ListBucketResult"`
+		Name                  string    `xml:"Name"`
+		Prefix                string    `xml:"Prefix"`
+		KeyCount              int       `xml:"KeyCount"`
+		MaxKeys               int       `xml:"MaxKeys"`
+		IsTruncated           bool      `xml:"IsTruncated"`
+		ContinuationToken     string    `xml:"ContinuationToken,omitempty"`
+		NextContinuationToken string    `xml:"NextContinuationToken,omitempty"`
+		Contents              []content `xml:"Contents"`
+	}{
+		Name:              bucket,
+		Prefix:            prefix,
+		MaxKeys:           s.pageSize,
+		ContinuationToken: token,
+	}
+
+	if len(keys) > s.pageSize {
+		keys = keys[:s.pageSize]
+		// the token is opaque to clients
+		rsp.IsTruncated = true
+		rsp.NextContinuationToken = base64.StdEncoding.EncodeToString([]byte(keys[len(keys)-1]))
+	}
+	for _, key := range keys {
+		rsp.Contents = append(rsp.Contents, content{Key: key, Size: len(objects[key].data)})
+	}
+	rsp.KeyCount = len(rsp.Contents)
+
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(rsp)
+}
+
+func newTestStore(t *testing.T, pageSize int, opts ...store.Option) (*s3Server, *s3Store) {
+	srv, ts := newS3Server(t, pageSize)
+	opts = append([]store.Option{
+		store.Nodes(ts.URL),
+		Region("us-east-1"),
+		Credentials("id", "secret", ""),
+		PathStyle(),
+	}, opts...)
+	return srv, NewStore(opts...).(*s3Store)
+}
+
+func TestBucketCreation(t *testing.T) {
+	srv, s := newTestStore(t, 10, store.Database("Orders_DB"))
+
+	// reads of a bucket which doesn't exist yet find nothing
+	if _, err := s.Read("1"); err != store.ErrNotFound {
+		t.Fatalf("Expected store.ErrNotFound, got %v", err)
+	}
+	if keys, err := s.List(); err != nil || len(keys) != 0 {
+		t.Fatalf("Expected no keys, got %v %v", keys, err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := s.Write(&store.Record{Key: strconv.Itoa(i), Value: []byte("order")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := srv.count("create"); n != 1 {
+		t.Errorf("Expected the bucket to be created once, got %d requests", n)
+	}
+	if _, ok := srv.get("orders-db", "1"); !ok {
+		t.Error("Expected the object in the orders-db bucket")
+	}
+	// us-east-1 takes no location constraint
+	if len(srv.creates[0]) > 0 {
+		t.Errorf("Expected no location constraint, got %s", srv.creates[0])
+	}
+
+	// buckets created by others are used as is
+	s = NewStore(store.Nodes(s.options.Nodes...), store.WithContext(s.options.Context), store.Database("Orders_DB")).(*s3Store)
+	if err := s.Write(&store.Record{Key: "3", Value: []byte("order")}); err != nil {
+		t.Fatalf("Expected writing to an existing bucket to succeed, got %v", err)
+	}
+
+	// buckets outside us-east-1 are created in the region of the store
+	s = NewStore(store.Nodes(s.options.Nodes...), store.WithContext(s.options.Context), Region("eu-west-1"), store.Database("logs")).(*s3Store)
+	if err := s.Write(&store.Record{Key: "1", Value: []byte("log")}); err != nil {
+		t.Fatal(err)
+	}
+	if c := srv.creates[len(srv.creates)-1]; !strings.Contains(c, "<LocationConstraint>eu-west-1</LocationConstraint>") {
+		t.Errorf("Expected the eu-west-1 location constraint, got %s", c)
+	}
+}
+
+func TestContinuation(t *testing.T) {
+	srv, s := newTestStore(t, 2, store.Table("users"))
+
+	for i := 0; i < 7; i++ {
+		if err := s.Write(&store.Record{Key: fmt.Sprintf("user/%d", i), Value: []byte("user")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Write(&store.Record{Key: "group/1", Value: []byte("group")}); err != nil {
+		t.Fatal(err)
+	}
+	// other tables share the bucket
+	if err := s.Write(&store.Record{Key: "user/0", Value: []byte("order")}, store.WriteTo("", "orders")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := srv.get("micro", "users/user/0"); !ok {
+		t.Fatal("Expected the table to prefix the object key")
+	}
+
+	keys, err := s.List(store.ListPrefix("user/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 7 {
+		t.Fatalf("Expected the 7 keys of every page, got %v", keys)
+	}
+	for _, k := range keys {
+		if !strings.HasPrefix(k, "user/") {
+			t.Errorf("Expected the table prefix to be trimmed, got %s", k)
+		}
+	}
+	if n := srv.count("list"); n != 4 {
+		t.Errorf("Expected 4 pages of 2 objects, got %d list requests", n)
+	}
+
+	// offsets and limits are applied after the pages are merged
+	keys, err = s.List(store.ListPrefix("user/"), store.ListOffset(3), store.ListLimit(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(keys, ",") != "user/3,user/4,user/5" {
+		t.Errorf("Unexpected page of keys %v", keys)
+	}
+
+	recs, err := s.Read("/1", store.ReadSuffix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 2 {
+		t.Errorf("Expected user/1 and group/1, got %d records", len(recs))
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	srv, s := newTestStore(t, 10)
+
+	err := s.Write(&store.Record{
+		Key:      "user/1",
+		Value:    []byte("alice"),
+		Metadata: map[string]interface{}{"role": "admin", "name": "Älice"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// metadata headers only take ascii
+	o, _ := srv.get("micro", "user/1")
+	v := o.headers.Get("x-amz-meta-" + metadataKey)
+	if len(v) == 0 {
+		t.Fatal("Expected the metadata header")
+	}
+	for _, c := range v {
+		if c > 127 {
+			t.Fatalf("Expected ascii metadata, got %q", v)
+		}
+	}
+
+	recs, err := s.Read("user/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := recs[0]
+	if string(r.Value) != "alice" || r.Metadata["role"] != "admin" || r.Metadata["name"] != "Älice" {
+		t.Errorf("Unexpected record %s %v", r.Value, r.Metadata)
+	}
+	if r.Expiry != 0 {
+		t.Errorf("Expected no expiry, got %v", r.Expiry)
+	}
+}
+
+func TestExpiredObject(t *testing.T) {
+	srv, s := newTestStore(t, 10)
+
+	if err := s.Write(&store.Record{Key: "tmp", Value: []byte("tmp")}, store.WriteTTL(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(&store.Record{Key: "keep", Value: []byte("keep")}); err != nil {
+		t.Fatal(err)
+	}
+
+	recs, err := s.Read("tmp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recs[0].Expiry <= 0 || recs[0].Expiry > time.Hour {
+		t.Errorf("Expected the record to expire within an hour, got %v", recs[0].Expiry)
+	}
+
+	// expire the object
+	o, _ := srv.get("micro", "tmp")
+	srv.Lock()
+	o.headers.Set("x-amz-meta-"+records.ExpiryMetadataKey, strconv.FormatInt(time.Now().Add(-time.Second).Unix(), 10))
+	srv.Unlock()
+
+	// listings have no metadata, so the expired key is listed
+	keys, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Errorf("Expected the expired key to be listed until read, got %v", keys)
+	}
+
+	// but reads skip it
+	recs, err = s.Read("", store.ReadPrefix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 1 || recs[0].Key != "keep" {
+		t.Errorf("Expected the expired record to be skipped, got %d records", len(recs))
+	}
+	if _, err := s.Read("tmp"); err != store.ErrNotFound {
+		t.Fatalf("Expected store.ErrNotFound, got %v", err)
+	}
+
+	// and delete it
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok := srv.get("micro", "tmp"); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the expired object to be deleted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMultipartUpload(t *testing.T) {
+	srv, s := newTestStore(t, 10)
+
+	// small records are uploaded in one request
+	if err := s.Write(&store.Record{Key: "small", Value: []byte("small")}); err != nil {
+		t.Fatal(err)
+	}
+	if srv.count("put") != 1 || srv.count("initiate") != 0 {
+		t.Errorf("Expected a single put, got %v", srv.requests)
+	}
+
+	large := bytes.Repeat([]byte("abc"), int(DefaultPartSize))
+	if err := Upload(s, "large", bytes.NewReader(large), store.WriteTTL(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.count("part"); n != 3 {
+		t.Errorf("Expected 3 parts, got %d", n)
+	}
+	if n := srv.count("complete"); n != 1 {
+		t.Errorf("Expected the upload to be completed once, got %d", n)
+	}
+
+	r, err := Open(s, "large")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, large) {
+		t.Errorf("Expected %d bytes, got %d", len(large), len(b))
+	}
+
+	// the expiry is set when the upload is initiated
+	recs, err := s.Read("large")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recs[0].Expiry <= 0 || recs[0].Expiry > time.Hour {
+		t.Errorf("Expected the upload to expire within an hour, got %v", recs[0].Expiry)
+	}
+
+	if _, err := Open(s, "missing"); err != store.ErrNotFound {
+		t.Errorf("Expected store.ErrNotFound, got %v", err)
+	}
+}
+
+func TestPartSize(t *testing.T) {
+	large := bytes.Repeat([]byte("abc"), int(DefaultPartSize))
+
+	// parts are at least the minimum S3 accepts
+	srv, s := newTestStore(t, 10, PartSize(1024))
+	if err := Upload(s, "large", bytes.NewReader(large)); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.count("part"); n != 3 {
+		t.Errorf("Expected 3 parts of the minimum size, got %d", n)
+	}
+
+	srv, s = newTestStore(t, 10, PartSize(2*DefaultPartSize))
+	if err := Upload(s, "large", bytes.NewReader(large)); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.count("part"); n != 2 {
+		t.Errorf("Expected 2 parts, got %d", n)
+	}
+
+	// records up to the part size are put in one request
+	if err := s.Write(&store.Record{Key: "medium", Value: large[:DefaultPartSize+1]}); err != nil {
+		t.Fatal(err)
+	}
+	if srv.count("put") != 1 || srv.count("initiate") != 1 {
+		t.Errorf("Expected a single put, got %v", srv.requests)
+	}
+}
+
+func TestServerSideEncryption(t *testing.T) {
+	large := bytes.Repeat([]byte("abc"), int(DefaultPartSize))
+
+	for _, tt := range []struct {
+		name      string
+		opts      []store.Option
+		algorithm string
+		key       string
+	}{
+		{"none", nil, "", ""},
+		{"s3", []store.Option{ServerSideEncryption()}, "AES256", ""},
+		{"kms", []store.Option{KMSKey("key-1")}, "aws:kms", "key-1"},
+		{"kms default key", []store.Option{KMSKey("")}, "aws:kms", ""},
+	} {
+		srv, s := newTestStore(t, 10, tt.opts...)
+
+		if err := s.Write(&store.Record{Key: "small", Value: []byte("small")}); err != nil {
+			t.Fatal(err)
+		}
+		if err := Upload(s, "large", bytes.NewReader(large)); err != nil {
+			t.Fatal(err)
+		}
+
+		// both upload paths encrypt the object
+		for _, key := range []string{"small", "large"} {
+			o, _ := srv.get("micro", key)
+			if a := o.headers.Get("x-amz-server-side-encryption"); a != tt.algorithm {
+				t.Errorf("%s: Expected %s to be encrypted with %q, got %q", tt.name, key, tt.algorithm, a)
+			}
+			if k := o.headers.Get("x-amz-server-side-encryption-aws-kms-key-id"); k != tt.key {
+				t.Errorf("%s: Expected %s to be encrypted with the key %q, got %q", tt.name, key, tt.key, k)
+			}
+		}
+	}
+}
+
+func TestDelete(t *testing.T) {
+	srv, s := newTestStore(t, 10)
+
+	if err := s.Write(&store.Record{Key: "1", Value: []byte("1")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := srv.get("micro", "1"); ok {
+		t.Error("Expected the object to be deleted")
+	}
+	if err := s.Delete("1"); err != nil {
+		t.Errorf("Expected deleting a missing record to succeed, got %v", err)
+	}
+	// nor does it fail in a bucket which doesn't exist
+	if err := s.Delete("1", store.DeleteFrom("missing", "")); err != nil {
+		t.Errorf("Expected deleting from a missing bucket to succeed, got %v", err)
+	}
+}
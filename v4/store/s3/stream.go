@@ -0,0 +1,79 @@
+package s3
+
+import (
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	s3api "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pkg/errors"
+	"go-micro.dev/v4/store"
+)
+
+func s3Of(s store.Store) (*s3Store, error) {
+	ss, ok := s.(*s3Store)
+	if !ok {
+		return nil, errors.Errorf("%s is not an s3 store", s.String())
+	}
+	return ss, nil
+}
+
+// Upload writes the record from the reader without holding it in memory,
+// uploading it in parts of the part size. Records written by Upload have no
+// metadata.
+func Upload(s store.Store, key string, r io.Reader, opts ...store.WriteOption) error {
+	ss, err := s3Of(s)
+	if err != nil {
+		return err
+	}
+
+	var options store.WriteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return ss.upload(key, r, 0, make(map[string]*string), options)
+}
+
+// Open returns a reader of the value of the record, to read records larger
+// than memory. The reader must be closed.
+func Open(s store.Store, key string, opts ...store.ReadOption) (io.ReadCloser, error) {
+	ss, err := s3Of(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var options store.ReadOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	bucket, prefix := ss.names(options.Database, options.Table)
+	rsp, _, err := ss.get(bucket, prefix, key)
+	if err != nil {
+		return nil, err
+	}
+	return rsp.Body, nil
+}
+
+// Presign returns a URL to download the value of the record without
+// credentials, valid for the ttl, to hand large records to clients directly.
+// The record isn't checked for existence or expiry.
+func Presign(s store.Store, key string, ttl time.Duration, opts ...store.ReadOption) (string, error) {
+	ss, err := s3Of(s)
+	if err != nil {
+		return "", err
+	}
+
+	var options store.ReadOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	bucket, prefix := ss.names(options.Database, options.Table)
+	req, _ := ss.client.GetObjectRequest(&s3api.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(prefix + key),
+	})
+	return req.Presign(ttl)
+}
@@ -3,6 +3,7 @@ module github.com/go-micro/plugins/v4/transport/grpc
 go 1.17
 
 require (
+	github.com/go-micro/plugins/v4/util/socket v1.0.0
 	github.com/golang/protobuf v1.5.2
 	go-micro.dev/v4 v4.9.0
 	google.golang.org/grpc v1.38.0
@@ -53,3 +54,5 @@ require (
 	gopkg.in/tomb.v1 v1.0.0-20141024135613-dd632973f1e7 // indirect
 	gopkg.in/warnings.v0 v0.1.2 // indirect
 )
+
+replace github.com/go-micro/plugins/v4/util/socket => ../../util/socket
@@ -6,6 +6,7 @@ import (
 	"crypto/tls"
 	"net"
 
+	"github.com/go-micro/plugins/v4/util/socket"
 	"go-micro.dev/v4/transport"
 	maddr "go-micro.dev/v4/util/addr"
 	"go-micro.dev/v4/util/cmd"
@@ -18,13 +19,15 @@ import (
 )
 
 type grpcTransport struct {
-	opts transport.Options
+	opts  transport.Options
+	links *links
 }
 
 type grpcTransportListener struct {
 	listener net.Listener
 	secure   bool
 	tls      *tls.Config
+	opts     []grpc.ServerOption
 }
 
 func init() {
@@ -35,7 +38,9 @@ func getTLSConfig(addr string) (*tls.Config, error) {
 	hosts := []string{addr}
 
 	// check if its a valid host:port
-	if host, _, err := net.SplitHostPort(addr); err == nil {
+	if socket.Local(addr) {
+		hosts = []string{"localhost"}
+	} else if host, _, err := net.SplitHostPort(addr); err == nil {
 		if len(host) == 0 {
 			hosts = maddr.IPs()
 		} else {
@@ -53,7 +58,7 @@ func getTLSConfig(addr string) (*tls.Config, error) {
 }
 
 func (t *grpcTransportListener) Addr() string {
-	return t.listener.Addr().String()
+	return socket.Addr(t.listener.Addr())
 }
 
 func (t *grpcTransportListener) Close() error {
@@ -61,15 +66,14 @@ func (t *grpcTransportListener) Close() error {
 }
 
 func (t *grpcTransportListener) Accept(fn func(transport.Socket)) error {
-	var opts []grpc.ServerOption
+	opts := append([]grpc.ServerOption{}, t.opts...)
 
 	// setup tls if specified
 	if t.secure || t.tls != nil {
 		config := t.tls
 		if config == nil {
 			var err error
-			addr := t.listener.Addr().String()
-			config, err = getTLSConfig(addr)
+			config, err = getTLSConfig(t.Addr())
 			if err != nil {
 				return err
 			}
@@ -83,7 +87,7 @@ func (t *grpcTransportListener) Accept(fn func(transport.Socket)) error {
 	srv := grpc.NewServer(opts...)
 
 	// register service
-	pb.RegisterTransportServer(srv, &microTransport{addr: t.listener.Addr().String(), fn: fn})
+	pb.RegisterTransportServer(srv, &microTransport{addr: t.Addr(), fn: fn})
 
 	// start serving
 	return srv.Serve(t.listener)
@@ -98,8 +102,23 @@ func (t *grpcTransport) Dial(addr string, opts ...transport.DialOption) (transpo
 		opt(&dopts)
 	}
 
+	l := &link{outbound: true, remote: addr}
 	options := []grpc.DialOption{
 		grpc.WithTimeout(dopts.Timeout),
+		grpc.WithStatsHandler(&statsHandler{links: t.links, link: l}),
+	}
+	options = append(options, dialOptions(t.opts.Context)...)
+
+	// grpc doesn't dial named pipes, so local sockets are dialed directly
+	target := addr
+	if socket.Local(addr) {
+		target = "passthrough:///" + addr
+		options = append(options,
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return socket.Dial(ctx, addr)
+			}),
+			grpc.WithAuthority("localhost"),
+		)
 	}
 
 	if t.opts.Secure || t.opts.TLSConfig != nil {
@@ -116,7 +135,7 @@ func (t *grpcTransport) Dial(addr string, opts ...transport.DialOption) (transpo
 	}
 
 	// dial the server
-	conn, err := grpc.Dial(addr, options...)
+	conn, err := grpc.Dial(target, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -124,8 +143,10 @@ func (t *grpcTransport) Dial(addr string, opts ...transport.DialOption) (transpo
 	// create stream
 	stream, err := pb.NewTransportClient(conn).Stream(context.Background())
 	if err != nil {
+		conn.Close()
 		return nil, err
 	}
+	t.links.add(l)
 
 	// return a client
 	return &grpcTransportClient{
@@ -133,6 +154,7 @@ func (t *grpcTransport) Dial(addr string, opts ...transport.DialOption) (transpo
 		stream: stream,
 		local:  "localhost",
 		remote: addr,
+		closed: func() { t.links.remove(l) },
 	}, nil
 }
 
@@ -142,9 +164,15 @@ func (t *grpcTransport) Listen(addr string, opts ...transport.ListenOption) (tra
 		o(&options)
 	}
 
-	ln, err := mnet.Listen(addr, func(addr string) (net.Listener, error) {
-		return net.Listen("tcp", addr)
-	})
+	var ln net.Listener
+	var err error
+	if socket.Local(addr) {
+		ln, err = socket.Listen(addr)
+	} else {
+		ln, err = mnet.Listen(addr, func(addr string) (net.Listener, error) {
+			return net.Listen("tcp", addr)
+		})
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -153,6 +181,9 @@ func (t *grpcTransport) Listen(addr string, opts ...transport.ListenOption) (tra
 		listener: ln,
 		tls:      t.opts.TLSConfig,
 		secure:   t.opts.Secure,
+		opts: append(serverOptions(t.opts.Context),
+			grpc.StatsHandler(&statsHandler{links: t.links}),
+		),
 	}, nil
 }
 
@@ -176,5 +207,8 @@ func NewTransport(opts ...transport.Option) transport.Transport {
 	for _, o := range opts {
 		o(&options)
 	}
-	return &grpcTransport{opts: options}
+	return &grpcTransport{
+		opts:  options,
+		links: &links{m: make(map[*link]bool)},
+	}
 }
@@ -2,6 +2,8 @@ package grpc
 
 import (
 	"net"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"go-micro.dev/v4/transport"
@@ -109,3 +111,55 @@ func TestGRPCTransportCommunication(t *testing.T) {
 
 	close(done)
 }
+
+func TestGRPCTransportUnix(t *testing.T) {
+	dir, err := os.MkdirTemp("", "grpc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, secure := range []bool{false, true} {
+		tr := NewTransport(transport.Secure(secure))
+
+		addr := "unix://" + filepath.Join(dir, "svc.sock")
+		l, err := tr.Listen(addr)
+		if err != nil {
+			t.Fatalf("Unexpected listen err: %v", err)
+		}
+		if l.Addr() != addr {
+			t.Fatalf("Expected address %s, got %s", addr, l.Addr())
+		}
+
+		go l.Accept(func(sock transport.Socket) {
+			defer sock.Close()
+
+			var m transport.Message
+			if err := sock.Recv(&m); err != nil {
+				return
+			}
+			sock.Send(&m)
+		})
+
+		c, err := tr.Dial(l.Addr())
+		if err != nil {
+			t.Fatalf("Unexpected dial err: %v", err)
+		}
+
+		m := transport.Message{Body: []byte(`{"message": "Hello World"}`)}
+		if err := c.Send(&m); err != nil {
+			t.Errorf("Unexpected send err: %v", err)
+		}
+
+		var rm transport.Message
+		if err := c.Recv(&rm); err != nil {
+			t.Errorf("Unexpected recv err: %v", err)
+		}
+		if string(rm.Body) != string(m.Body) {
+			t.Errorf("Expected %v, got %v", m.Body, rm.Body)
+		}
+
+		c.Close()
+		l.Close()
+	}
+}
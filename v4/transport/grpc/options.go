@@ -0,0 +1,100 @@
+package grpc
+
+import (
+	"context"
+
+	"go-micro.dev/v4/transport"
+	"google.golang.org/grpc"
+)
+
+type initialWindowSizeKey struct{}
+type initialConnWindowSizeKey struct{}
+type maxConcurrentStreamsKey struct{}
+type writeBufferSizeKey struct{}
+type readBufferSizeKey struct{}
+
+// InitialWindowSize sets the flow control window of the streams of a link,
+// at least 64KB. A larger window keeps fast links busy, at the cost of
+// memory per stream.
+func InitialWindowSize(n int32) transport.Option {
+	return setTransportOption(initialWindowSizeKey{}, n)
+}
+
+// InitialConnWindowSize sets the flow control window of a link, shared by
+// its streams, at least 64KB.
+func InitialConnWindowSize(n int32) transport.Option {
+	return setTransportOption(initialConnWindowSizeKey{}, n)
+}
+
+// MaxConcurrentStreams limits the streams a client may open on a link to
+// the listener. Further streams wait until one ends.
+func MaxConcurrentStreams(n uint32) transport.Option {
+	return setTransportOption(maxConcurrentStreamsKey{}, n)
+}
+
+// WriteBufferSize sets the bytes buffered before a write to the link, 0
+// writes every message right away.
+func WriteBufferSize(n int) transport.Option {
+	return setTransportOption(writeBufferSizeKey{}, n)
+}
+
+// ReadBufferSize sets the bytes read from the link at once.
+func ReadBufferSize(n int) transport.Option {
+	return setTransportOption(readBufferSizeKey{}, n)
+}
+
+func setTransportOption(k, v interface{}) transport.Option {
+	return func(o *transport.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+// serverOptions returns the grpc options of the listeners.
+func serverOptions(ctx context.Context) []grpc.ServerOption {
+	if ctx == nil {
+		return nil
+	}
+
+	var opts []grpc.ServerOption
+	if n, ok := ctx.Value(initialWindowSizeKey{}).(int32); ok {
+		opts = append(opts, grpc.InitialWindowSize(n))
+	}
+	if n, ok := ctx.Value(initialConnWindowSizeKey{}).(int32); ok {
+		opts = append(opts, grpc.InitialConnWindowSize(n))
+	}
+	if n, ok := ctx.Value(maxConcurrentStreamsKey{}).(uint32); ok {
+		opts = append(opts, grpc.MaxConcurrentStreams(n))
+	}
+	if n, ok := ctx.Value(writeBufferSizeKey{}).(int); ok {
+		opts = append(opts, grpc.WriteBufferSize(n))
+	}
+	if n, ok := ctx.Value(readBufferSizeKey{}).(int); ok {
+		opts = append(opts, grpc.ReadBufferSize(n))
+	}
+	return opts
+}
+
+// dialOptions returns the grpc options of the links dialled.
+func dialOptions(ctx context.Context) []grpc.DialOption {
+	if ctx == nil {
+		return nil
+	}
+
+	var opts []grpc.DialOption
+	if n, ok := ctx.Value(initialWindowSizeKey{}).(int32); ok {
+		opts = append(opts, grpc.WithInitialWindowSize(n))
+	}
+	if n, ok := ctx.Value(initialConnWindowSizeKey{}).(int32); ok {
+		opts = append(opts, grpc.WithInitialConnWindowSize(n))
+	}
+	if n, ok := ctx.Value(writeBufferSizeKey{}).(int); ok {
+		opts = append(opts, grpc.WithWriteBufferSize(n))
+	}
+	if n, ok := ctx.Value(readBufferSizeKey{}).(int); ok {
+		opts = append(opts, grpc.WithReadBufferSize(n))
+	}
+	return opts
+}
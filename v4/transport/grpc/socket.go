@@ -12,6 +12,8 @@ type grpcTransportClient struct {
 
 	local  string
 	remote string
+	// closed removes the link of the client
+	closed func()
 }
 
 type grpcTransportSocket struct {
@@ -55,6 +57,9 @@ func (g *grpcTransportClient) Send(m *transport.Message) error {
 }
 
 func (g *grpcTransportClient) Close() error {
+	if g.closed != nil {
+		g.closed()
+	}
 	return g.conn.Close()
 }
 
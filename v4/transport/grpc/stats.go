@@ -0,0 +1,158 @@
+package grpc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc/stats"
+)
+
+// LinkStats is implemented by the transport, reporting the stats of its
+// open links.
+//
+//	if ls, ok := service.Options().Transport.(grpc.LinkStats); ok {
+//		links := ls.Links()
+//	}
+type LinkStats interface {
+	Links() []Link
+}
+
+// Link is the stats of a link, a connection dialled or accepted by the
+// transport.
+type Link struct {
+	Local, Remote string
+	// Outbound is whether the link was dialled
+	Outbound bool
+	// Streams is the number of open streams
+	Streams int64
+	// StreamsTotal is the number of streams opened
+	StreamsTotal uint64
+	// BytesSent and BytesReceived are the bytes of the messages on the wire
+	BytesSent, BytesReceived uint64
+}
+
+type link struct {
+	streams, streamsTotal, sent, received uint64
+
+	outbound bool
+
+	sync.Mutex
+	local, remote string
+}
+
+func (l *link) setAddrs(info *stats.ConnTagInfo) {
+	l.Lock()
+	defer l.Unlock()
+
+	if info.LocalAddr != nil {
+		l.local = info.LocalAddr.String()
+	}
+	if info.RemoteAddr != nil {
+		l.remote = info.RemoteAddr.String()
+	}
+}
+
+func (l *link) stats() Link {
+	l.Lock()
+	local, remote := l.local, l.remote
+	l.Unlock()
+
+	return Link{
+		Local:         local,
+		Remote:        remote,
+		Outbound:      l.outbound,
+		Streams:       int64(atomic.LoadUint64(&l.streams)),
+		StreamsTotal:  atomic.LoadUint64(&l.streamsTotal),
+		BytesSent:     atomic.LoadUint64(&l.sent),
+		BytesReceived: atomic.LoadUint64(&l.received),
+	}
+}
+
+// links is the open links of the transport.
+type links struct {
+	sync.RWMutex
+	m map[*link]bool
+}
+
+func (ls *links) add(l *link) {
+	ls.Lock()
+	ls.m[l] = true
+	ls.Unlock()
+}
+
+func (ls *links) remove(l *link) {
+	ls.Lock()
+	delete(ls.m, l)
+	ls.Unlock()
+}
+
+func (t *grpcTransport) Links() []Link {
+	t.links.RLock()
+	defer t.links.RUnlock()
+
+	stats := make([]Link, 0, len(t.links.m))
+	for l := range t.links.m {
+		stats = append(stats, l.stats())
+	}
+	return stats
+}
+
+type linkKey struct{}
+
+// statsHandler records the stats of the links. The streams of a server
+// have the context of their link. A client is a link of its own, which
+// lives as long as the client, across the reconnects of its connection.
+type statsHandler struct {
+	links *links
+	// link of a client
+	link *link
+}
+
+func (h *statsHandler) TagConn(ctx context.Context, info *stats.ConnTagInfo) context.Context {
+	l := h.link
+	if l == nil {
+		l = &link{}
+	}
+	l.setAddrs(info)
+	return context.WithValue(ctx, linkKey{}, l)
+}
+
+func (h *statsHandler) HandleConn(ctx context.Context, s stats.ConnStats) {
+	l, ok := ctx.Value(linkKey{}).(*link)
+	if !ok || h.link != nil {
+		return
+	}
+
+	switch s.(type) {
+	case *stats.ConnBegin:
+		h.links.add(l)
+	case *stats.ConnEnd:
+		h.links.remove(l)
+	}
+}
+
+func (h *statsHandler) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (h *statsHandler) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	l, ok := ctx.Value(linkKey{}).(*link)
+	if !ok {
+		if l = h.link; l == nil {
+			return
+		}
+	}
+
+	switch v := s.(type) {
+	case *stats.Begin:
+		atomic.AddUint64(&l.streams, 1)
+		atomic.AddUint64(&l.streamsTotal, 1)
+	case *stats.End:
+		atomic.AddUint64(&l.streams, ^uint64(0))
+	case *stats.InPayload:
+		atomic.AddUint64(&l.received, uint64(v.WireLength))
+	case *stats.OutPayload:
+		atomic.AddUint64(&l.sent, uint64(v.WireLength))
+	}
+}
@@ -0,0 +1,66 @@
+package grpc
+
+import (
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/transport"
+)
+
+func TestLinks(t *testing.T) {
+	tr := NewTransport(InitialWindowSize(1<<20), MaxConcurrentStreams(8), WriteBufferSize(64<<10))
+
+	l, err := tr.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go l.Accept(func(sock transport.Socket) {
+		defer sock.Close()
+		for {
+			var m transport.Message
+			if err := sock.Recv(&m); err != nil {
+				return
+			}
+			if err := sock.Send(&m); err != nil {
+				return
+			}
+		}
+	})
+
+	c, err := tr.Dial(l.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := transport.Message{Body: []byte("hello")}
+	if err := c.Send(&m); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Recv(&m); err != nil {
+		t.Fatal(err)
+	}
+
+	links := tr.(LinkStats).Links()
+	if len(links) != 2 {
+		t.Fatalf("Expected an inbound and an outbound link, got %+v", links)
+	}
+	for _, l := range links {
+		if l.Streams != 1 || l.StreamsTotal != 1 {
+			t.Errorf("Expected a stream on the link, got %+v", l)
+		}
+		if l.BytesSent == 0 || l.BytesReceived == 0 {
+			t.Errorf("Expected bytes on the link, got %+v", l)
+		}
+	}
+
+	c.Close()
+	for i := 0; i < 100; i++ {
+		if len(tr.(LinkStats).Links()) == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("Expected the links to be removed, got %+v", tr.(LinkStats).Links())
+}
@@ -0,0 +1,189 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"go-micro.dev/v4/transport"
+	"go-micro.dev/v4/util/buf"
+)
+
+// httpTransportClient is the client of the go-micro http transport on a
+// connection dialed here, which go-micro only does over TCP. Requests are
+// written as HTTP/1.1 and their responses read in order.
+type httpTransportClient struct {
+	opts     transport.Options
+	conn     net.Conn
+	dialOpts transport.DialOptions
+	once     sync.Once
+
+	sync.RWMutex
+
+	// request must be stored for response processing
+	r      chan *http.Request
+	bl     []*http.Request
+	buff   *bufio.Reader
+	closed bool
+
+	// local/remote ip
+	local  string
+	remote string
+}
+
+func newClient(opts transport.Options, conn net.Conn, dopts transport.DialOptions) *httpTransportClient {
+	return &httpTransportClient{
+		opts:     opts,
+		conn:     conn,
+		buff:     bufio.NewReader(conn),
+		dialOpts: dopts,
+		r:        make(chan *http.Request, 100),
+		local:    conn.LocalAddr().String(),
+		remote:   conn.RemoteAddr().String(),
+	}
+}
+
+func (h *httpTransportClient) Local() string {
+	return h.local
+}
+
+func (h *httpTransportClient) Remote() string {
+	return h.remote
+}
+
+func (h *httpTransportClient) Send(m *transport.Message) error {
+	header := make(http.Header)
+
+	for k, v := range m.Header {
+		header.Set(k, v)
+	}
+
+	b := buf.New(bytes.NewBuffer(m.Body))
+	defer b.Close()
+
+	// the host of a local socket isn't a valid url host
+	req := &http.Request{
+		Method: "POST",
+		URL: &url.URL{
+			Scheme: "http",
+			Host:   "localhost",
+		},
+		Header:        header,
+		Body:          b,
+		ContentLength: int64(b.Len()),
+		Host:          "localhost",
+	}
+
+	if !h.dialOpts.Stream {
+		h.Lock()
+		if h.closed {
+			h.Unlock()
+			return io.EOF
+		}
+		h.bl = append(h.bl, req)
+		select {
+		case h.r <- h.bl[0]:
+			h.bl = h.bl[1:]
+		default:
+		}
+		h.Unlock()
+	}
+
+	// set timeout if its greater than 0
+	if h.opts.Timeout > time.Duration(0) {
+		h.conn.SetDeadline(time.Now().Add(h.opts.Timeout))
+	}
+
+	return req.Write(h.conn)
+}
+
+func (h *httpTransportClient) Recv(m *transport.Message) error {
+	if m == nil {
+		return errors.New("message passed in is nil")
+	}
+
+	var r *http.Request
+	if !h.dialOpts.Stream {
+		rc, ok := <-h.r
+		if !ok {
+			h.Lock()
+			if len(h.bl) == 0 {
+				h.Unlock()
+				return io.EOF
+			}
+			rc = h.bl[0]
+			h.bl = h.bl[1:]
+			h.Unlock()
+		}
+		r = rc
+	}
+
+	// set timeout if its greater than 0
+	if h.opts.Timeout > time.Duration(0) {
+		h.conn.SetDeadline(time.Now().Add(h.opts.Timeout))
+	}
+
+	h.Lock()
+	defer h.Unlock()
+	if h.closed {
+		return io.EOF
+	}
+	rsp, err := http.ReadResponse(h.buff, r)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	b, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return err
+	}
+
+	if rsp.StatusCode != 200 {
+		return errors.New(rsp.Status + ": " + string(b))
+	}
+
+	m.Body = b
+
+	if m.Header == nil {
+		m.Header = make(map[string]string, len(rsp.Header))
+	}
+
+	for k, v := range rsp.Header {
+		if len(v) > 0 {
+			m.Header[k] = v[0]
+		} else {
+			m.Header[k] = ""
+		}
+	}
+
+	return nil
+}
+
+func (h *httpTransportClient) Close() error {
+	if !h.dialOpts.Stream {
+		h.once.Do(func() {
+			h.Lock()
+			h.buff.Reset(nil)
+			h.closed = true
+			h.Unlock()
+			close(h.r)
+		})
+		return h.conn.Close()
+	}
+	err := h.conn.Close()
+	h.once.Do(func() {
+		h.Lock()
+		h.buff.Reset(nil)
+		h.closed = true
+		h.Unlock()
+		close(h.r)
+	})
+	return err
+}
@@ -2,10 +2,17 @@ module github.com/go-micro/plugins/v4/transport/http
 
 go 1.17
 
-require go-micro.dev/v4 v4.9.0
+require (
+	github.com/go-micro/plugins/v4/util/socket v1.0.0
+	go-micro.dev/v4 v4.9.0
+)
 
 require (
+	github.com/Microsoft/go-winio v0.5.0 // indirect
 	github.com/google/uuid v1.2.0 // indirect
 	golang.org/x/net v0.0.0-20210510120150-4163338589ed // indirect
+	golang.org/x/sys v0.0.0-20210502180810-71e4cd670f79 // indirect
 	golang.org/x/text v0.3.6 // indirect
 )
+
+replace github.com/go-micro/plugins/v4/util/socket => ../../util/socket
@@ -2,10 +2,104 @@
 package http
 
 import (
+	"crypto/tls"
+	"net"
+
+	"github.com/go-micro/plugins/v4/util/socket"
 	"go-micro.dev/v4/transport"
+	mls "go-micro.dev/v4/util/tls"
 )
 
+// httpTransport is the http transport of go-micro, which listens and dials
+// on local sockets too.
+type httpTransport struct {
+	transport.Transport
+}
+
+type httpTransportListener struct {
+	transport.Listener
+	addr string
+}
+
+func (h *httpTransportListener) Addr() string {
+	return h.addr
+}
+
+func (h *httpTransport) secure() bool {
+	opts := h.Options()
+	return opts.Secure || opts.TLSConfig != nil
+}
+
+func (h *httpTransport) Dial(addr string, opts ...transport.DialOption) (transport.Client, error) {
+	if !socket.Local(addr) {
+		return h.Transport.Dial(addr, opts...)
+	}
+
+	dopts := transport.DialOptions{
+		Timeout: transport.DefaultDialTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(&dopts)
+	}
+
+	conn, err := socket.DialTimeout(addr, dopts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.secure() {
+		config := h.Options().TLSConfig
+		if config == nil {
+			config = &tls.Config{
+				InsecureSkipVerify: true,
+			}
+		}
+		config = config.Clone()
+		config.NextProtos = []string{"http/1.1"}
+		conn = tls.Client(conn, config)
+	}
+
+	return newClient(h.Options(), conn, dopts), nil
+}
+
+func (h *httpTransport) Listen(addr string, opts ...transport.ListenOption) (transport.Listener, error) {
+	if !socket.Local(addr) {
+		return h.Transport.Listen(addr, opts...)
+	}
+
+	var l net.Listener
+	l, err := socket.Listen(addr)
+	if err != nil {
+		return nil, err
+	}
+	la := socket.Addr(l.Addr())
+
+	if h.secure() {
+		config := h.Options().TLSConfig
+		if config == nil {
+			cert, err := mls.Certificate("localhost")
+			if err != nil {
+				l.Close()
+				return nil, err
+			}
+			config = &tls.Config{Certificates: []tls.Certificate{cert}}
+		}
+		l = tls.NewListener(l, config)
+	}
+
+	hl, err := h.Transport.Listen(addr, append(opts, transport.NetListener(l))...)
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	return &httpTransportListener{Listener: hl, addr: la}, nil
+}
+
 // NewTransport returns a new http transport using net/http and supporting http2.
+// Besides TCP addresses it listens and dials on Unix domain sockets and
+// Windows named pipes, addressed unix:///var/run/svc.sock and npipe:////./pipe/svc.
 func NewTransport(opts ...transport.Option) transport.Transport {
-	return transport.NewHTTPTransport(opts...)
+	return &httpTransport{transport.NewHTTPTransport(opts...)}
 }
@@ -1,6 +1,8 @@
 package http
 
 import (
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 
@@ -136,3 +138,64 @@ func BenchmarkTransport64(b *testing.B) {
 func BenchmarkTransport128(b *testing.B) {
 	call(b, 128)
 }
+
+func TestHTTPTransportUnix(t *testing.T) {
+	dir, err := os.MkdirTemp("", "http")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, secure := range []bool{false, true} {
+		tr := NewTransport(transport.Secure(secure))
+
+		addr := "unix://" + filepath.Join(dir, "svc.sock")
+		l, err := tr.Listen(addr)
+		if err != nil {
+			t.Fatalf("Unexpected listen err: %v", err)
+		}
+		if l.Addr() != addr {
+			t.Fatalf("Expected address %s, got %s", addr, l.Addr())
+		}
+
+		go l.Accept(func(sock transport.Socket) {
+			defer sock.Close()
+
+			for {
+				var m transport.Message
+				if err := sock.Recv(&m); err != nil {
+					return
+				}
+				if err := sock.Send(&m); err != nil {
+					return
+				}
+			}
+		})
+
+		c, err := tr.Dial(l.Addr())
+		if err != nil {
+			t.Fatalf("Unexpected dial err: %v", err)
+		}
+
+		for _, body := range []string{`{"message": "Hello World"}`, `{"message": "Hello again"}`} {
+			m := transport.Message{
+				Header: map[string]string{"Content-Type": "application/json"},
+				Body:   []byte(body),
+			}
+			if err := c.Send(&m); err != nil {
+				t.Fatalf("Unexpected send err: %v", err)
+			}
+
+			var rm transport.Message
+			if err := c.Recv(&rm); err != nil {
+				t.Fatalf("Unexpected recv err: %v", err)
+			}
+			if string(rm.Body) != body || rm.Header["Content-Type"] != "application/json" {
+				t.Fatalf("Expected %s, got %s %v", body, rm.Body, rm.Header)
+			}
+		}
+
+		c.Close()
+		l.Close()
+	}
+}
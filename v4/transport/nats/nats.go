@@ -11,6 +11,7 @@ import (
 
 	"github.com/nats-io/nats.go"
 	"go-micro.dev/v4/codec/json"
+	"go-micro.dev/v4/logger"
 	"go-micro.dev/v4/server"
 	"go-micro.dev/v4/transport"
 	"go-micro.dev/v4/util/cmd"
@@ -20,6 +21,8 @@ type ntport struct {
 	addrs []string
 	opts  transport.Options
 	nopts nats.Options
+	// applied to nopts on connect
+	natsOpts []nats.Option
 }
 
 type ntportClient struct {
@@ -60,6 +63,10 @@ type ntportListener struct {
 
 var (
 	DefaultTimeout = time.Minute
+	// DefaultMaxReconnects is the number of reconnect attempts of the
+	// connections unless Options are set, -1 reconnects forever so links
+	// survive a restart of the cluster.
+	DefaultMaxReconnects = -1
 )
 
 func init() {
@@ -72,9 +79,11 @@ func configure(n *ntport, opts ...transport.Option) {
 	}
 
 	natsOptions := nats.GetDefaultOptions()
+	natsOptions.MaxReconnect = DefaultMaxReconnects
 	if n, ok := n.opts.Context.Value(optionsKey{}).(nats.Options); ok {
 		natsOptions = n
 	}
+	n.natsOpts, _ = n.opts.Context.Value(natsOptionsKey{}).([]nats.Option)
 
 	// transport.Options have higher priority than nats.Options
 	// only if Addrs, Secure or TLSConfig were not set through a transport.Option
@@ -349,18 +358,7 @@ func (n *ntport) Dial(addr string, dialOpts ...transport.DialOption) (transport.
 		o(&dopts)
 	}
 
-	opts := n.nopts
-	opts.Servers = n.addrs
-	opts.Secure = n.opts.Secure
-	opts.TLSConfig = n.opts.TLSConfig
-	opts.Timeout = dopts.Timeout
-
-	// secure might not be set
-	if n.opts.TLSConfig != nil {
-		opts.Secure = true
-	}
-
-	c, err := opts.Connect()
+	c, err := n.connect(dopts.Timeout)
 	if err != nil {
 		return nil, err
 	}
@@ -368,6 +366,7 @@ func (n *ntport) Dial(addr string, dialOpts ...transport.DialOption) (transport.
 	id := nats.NewInbox()
 	sub, err := c.SubscribeSync(id)
 	if err != nil {
+		c.Close()
 		return nil, err
 	}
 
@@ -383,17 +382,7 @@ func (n *ntport) Dial(addr string, dialOpts ...transport.DialOption) (transport.
 }
 
 func (n *ntport) Listen(addr string, listenOpts ...transport.ListenOption) (transport.Listener, error) {
-	opts := n.nopts
-	opts.Servers = n.addrs
-	opts.Secure = n.opts.Secure
-	opts.TLSConfig = n.opts.TLSConfig
-
-	// secure might not be set
-	if n.opts.TLSConfig != nil {
-		opts.Secure = true
-	}
-
-	c, err := opts.Connect()
+	c, err := n.connect(0)
 	if err != nil {
 		return nil, err
 	}
@@ -423,6 +412,75 @@ func (n *ntport) Listen(addr string, listenOpts ...transport.ListenOption) (tran
 	}, nil
 }
 
+// natsOptions returns the options of the connections. The timeout is the
+// connect timeout if set.
+func (n *ntport) natsOptions(timeout time.Duration) (nats.Options, error) {
+	opts := n.nopts
+	opts.Servers = n.addrs
+	opts.Secure = n.opts.Secure
+	// options such as ClientCert add to the config
+	opts.TLSConfig = n.opts.TLSConfig.Clone()
+	if timeout > 0 {
+		opts.Timeout = timeout
+	}
+
+	for _, o := range n.natsOpts {
+		if err := o(&opts); err != nil {
+			return opts, err
+		}
+	}
+
+	// secure might not be set
+	if opts.TLSConfig != nil {
+		opts.Secure = true
+	}
+
+	n.logEvents(&opts)
+	return opts, nil
+}
+
+// logEvents logs disconnects, reconnects and closes of the connections,
+// before calling the handlers set.
+func (n *ntport) logEvents(opts *nats.Options) {
+	l := n.opts.Logger
+	if l == nil {
+		l = logger.DefaultLogger
+	}
+
+	disconnected, reconnected, closed := opts.DisconnectedErrCB, opts.ReconnectedCB, opts.ClosedCB
+	opts.DisconnectedErrCB = func(c *nats.Conn, err error) {
+		// and not closed by the transport
+		if !c.IsClosed() {
+			l.Logf(logger.WarnLevel, "nats transport: disconnected from %s: %v", c.ConnectedUrlRedacted(), err)
+		}
+		if disconnected != nil {
+			disconnected(c, err)
+		}
+	}
+	opts.ReconnectedCB = func(c *nats.Conn) {
+		l.Logf(logger.InfoLevel, "nats transport: reconnected to %s", c.ConnectedUrlRedacted())
+		if reconnected != nil {
+			reconnected(c)
+		}
+	}
+	opts.ClosedCB = func(c *nats.Conn) {
+		if err := c.LastError(); err != nil {
+			l.Logf(logger.ErrorLevel, "nats transport: connection closed: %v", err)
+		}
+		if closed != nil {
+			closed(c)
+		}
+	}
+}
+
+func (n *ntport) connect(timeout time.Duration) (*nats.Conn, error) {
+	opts, err := n.natsOptions(timeout)
+	if err != nil {
+		return nil, err
+	}
+	return opts.Connect()
+}
+
 func (n *ntport) Init(opts ...transport.Option) error {
 	configure(n, opts...)
 	return nil
@@ -2,12 +2,14 @@ package nats
 
 import (
 	"context"
+	"time"
 
 	"github.com/nats-io/nats.go"
 	"go-micro.dev/v4/transport"
 )
 
 type optionsKey struct{}
+type natsOptionsKey struct{}
 
 // Options allow to inject a nats.Options struct for configuring
 // the nats connection.
@@ -19,3 +21,93 @@ func Options(nopts nats.Options) transport.Option {
 		o.Context = context.WithValue(o.Context, optionsKey{}, nopts)
 	}
 }
+
+// NatsOptions applies the nats options to the connections, after Options
+// and the transport options.
+func NatsOptions(opts ...nats.Option) transport.Option {
+	return func(o *transport.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		prev, _ := o.Context.Value(natsOptionsKey{}).([]nats.Option)
+		all := make([]nats.Option, 0, len(prev)+len(opts))
+		all = append(append(all, prev...), opts...)
+		o.Context = context.WithValue(o.Context, natsOptionsKey{}, all)
+	}
+}
+
+// Credentials authenticates with the JWT and nkey seed of a creds file, or
+// a JWT file and a separate seed file.
+func Credentials(file string, seedFiles ...string) transport.Option {
+	return NatsOptions(nats.UserCredentials(file, seedFiles...))
+}
+
+// NKeyFile authenticates with the nkey of the seed file.
+func NKeyFile(seedFile string) transport.Option {
+	return NatsOptions(func(o *nats.Options) error {
+		opt, err := nats.NkeyOptionFromSeed(seedFile)
+		if err != nil {
+			return err
+		}
+		return opt(o)
+	})
+}
+
+// Token authenticates with the token.
+func Token(token string) transport.Option {
+	return NatsOptions(nats.Token(token))
+}
+
+// ClientCert sets the client certificate of TLS connections, e.g. for
+// servers verifying clients. It's added to transport.TLSConfig if set.
+func ClientCert(certFile, keyFile string) transport.Option {
+	return NatsOptions(nats.ClientCert(certFile, keyFile))
+}
+
+// RootCAs verifies the certificates of the servers with the CAs of the
+// files.
+func RootCAs(files ...string) transport.Option {
+	return NatsOptions(nats.RootCAs(files...))
+}
+
+// MaxReconnects is the number of reconnect attempts before a connection is
+// closed for good, -1 never gives up. It defaults to DefaultMaxReconnects
+// unless Options are set.
+func MaxReconnects(n int) transport.Option {
+	return NatsOptions(nats.MaxReconnects(n))
+}
+
+// ReconnectWait is the wait between reconnect attempts to the same server.
+func ReconnectWait(d time.Duration) transport.Option {
+	return NatsOptions(nats.ReconnectWait(d))
+}
+
+// ReconnectJitter adds up to the jitter to the reconnect wait, so clients
+// don't reconnect at once after a cluster restart.
+func ReconnectJitter(jitter, jitterTLS time.Duration) transport.Option {
+	return NatsOptions(nats.ReconnectJitter(jitter, jitterTLS))
+}
+
+// ReconnectBackoff returns the wait before the attempt, once every server
+// was tried, instead of ReconnectWait and ReconnectJitter, e.g. for an
+// exponential backoff.
+func ReconnectBackoff(fn func(attempts int) time.Duration) transport.Option {
+	return NatsOptions(nats.CustomReconnectDelay(fn))
+}
+
+// DisconnectHandler is called when a connection is lost, before it
+// reconnects.
+func DisconnectHandler(fn func(c *nats.Conn, err error)) transport.Option {
+	return NatsOptions(nats.DisconnectErrHandler(fn))
+}
+
+// ReconnectHandler is called when a connection is reestablished.
+func ReconnectHandler(fn func(c *nats.Conn)) transport.Option {
+	return NatsOptions(nats.ReconnectHandler(fn))
+}
+
+// ClosedHandler is called when a connection is closed for good, e.g. after
+// MaxReconnects attempts.
+func ClosedHandler(fn func(c *nats.Conn)) transport.Option {
+	return NatsOptions(nats.ClosedHandler(fn))
+}
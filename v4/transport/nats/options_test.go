@@ -0,0 +1,76 @@
+package nats
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go-micro.dev/v4/transport"
+)
+
+func TestNatsOptions(t *testing.T) {
+	var reconnected bool
+	tlsConfig := &tls.Config{ServerName: "nats"}
+
+	tr := NewTransport(
+		transport.TLSConfig(tlsConfig),
+		Token("secret"),
+		ReconnectWait(time.Second),
+		ReconnectBackoff(func(attempts int) time.Duration { return time.Duration(attempts) * time.Second }),
+		ReconnectHandler(func(*nats.Conn) { reconnected = true }),
+	).(*ntport)
+
+	opts, err := tr.natsOptions(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if opts.Token != "secret" {
+		t.Errorf("Expected the token to be set, got %q", opts.Token)
+	}
+	if opts.MaxReconnect != DefaultMaxReconnects {
+		t.Errorf("Expected %d reconnects, got %d", DefaultMaxReconnects, opts.MaxReconnect)
+	}
+	if opts.ReconnectWait != time.Second || opts.CustomReconnectDelayCB(3) != 3*time.Second {
+		t.Error("Expected the reconnect policy to be set")
+	}
+	if !opts.Secure || opts.TLSConfig == tlsConfig || opts.TLSConfig.ServerName != "nats" {
+		t.Error("Expected a copy of the TLS config")
+	}
+	if opts.Timeout != time.Second {
+		t.Errorf("Expected the dial timeout, got %v", opts.Timeout)
+	}
+
+	opts.ReconnectedCB(&nats.Conn{})
+	if !reconnected {
+		t.Error("Expected the reconnect handler to be called")
+	}
+}
+
+func TestNatsOptionsError(t *testing.T) {
+	tr := NewTransport(NKeyFile("does-not-exist.nk")).(*ntport)
+	if _, err := tr.natsOptions(0); err == nil {
+		t.Error("Expected an error for a missing seed file")
+	}
+	if _, err := tr.Dial("foo"); err == nil {
+		t.Error("Expected the dial to fail")
+	}
+}
+
+func TestMaxReconnectsOptions(t *testing.T) {
+	nopts := nats.GetDefaultOptions()
+	tr := NewTransport(Options(nopts)).(*ntport)
+	if tr.nopts.MaxReconnect != nats.DefaultMaxReconnect {
+		t.Errorf("Expected the reconnects of the options, got %d", tr.nopts.MaxReconnect)
+	}
+
+	tr = NewTransport(Options(nopts), MaxReconnects(5)).(*ntport)
+	opts, err := tr.natsOptions(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.MaxReconnect != 5 {
+		t.Errorf("Expected 5 reconnects, got %d", opts.MaxReconnect)
+	}
+}
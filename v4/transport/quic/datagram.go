@@ -0,0 +1,63 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+
+	"go-micro.dev/v4/transport"
+)
+
+// ErrDatagramsUnsupported is returned for datagrams on sockets of a
+// connection whose ends didn't both enable Datagrams.
+var ErrDatagramsUnsupported = errors.New("datagrams not supported by the connection")
+
+// SendDatagram sends the message as an unreliable datagram on the
+// connection of the socket, for latency sensitive fire and forget messages.
+// Datagrams aren't retransmitted and may be lost or reordered, and the
+// encoded message must fit in a packet, about 1200 bytes.
+func SendDatagram(s transport.Socket, m *transport.Message) error {
+	qs, err := datagramSocket(s)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(m); err != nil {
+		return err
+	}
+	return qs.s.SendDatagram(buf.Bytes())
+}
+
+// ReceiveDatagram blocks until a datagram is received on the connection of
+// the socket.
+func ReceiveDatagram(s transport.Socket, m *transport.Message) error {
+	qs, err := datagramSocket(s)
+	if err != nil {
+		return err
+	}
+
+	b, err := qs.s.ReceiveDatagram(context.TODO())
+	if err != nil {
+		return err
+	}
+	return gob.NewDecoder(bytes.NewReader(b)).Decode(m)
+}
+
+func datagramSocket(s transport.Socket) (*quicSocket, error) {
+	var qs *quicSocket
+	switch v := s.(type) {
+	case *quicSocket:
+		qs = v
+	case *quicClient:
+		qs = v.quicSocket
+	default:
+		return nil, errors.New("not a quic socket")
+	}
+
+	if !qs.s.ConnectionState().SupportsDatagrams {
+		return nil, ErrDatagramsUnsupported
+	}
+	return qs, nil
+}
@@ -0,0 +1,72 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	"go-micro.dev/v4/transport"
+)
+
+var (
+	// DefaultIdleTimeout is the time connections are kept open without
+	// activity, unless IdleTimeout is set.
+	DefaultIdleTimeout = time.Minute * 2
+	// DefaultSessionCacheSize is the number of servers whose TLS session
+	// tickets are cached for 0-RTT, unless SessionCache is set.
+	DefaultSessionCacheSize = 128
+)
+
+type zeroRTTKey struct{}
+type sessionCacheKey struct{}
+type idleTimeoutKey struct{}
+type handshakeTimeoutKey struct{}
+type keepAliveKey struct{}
+type datagramsKey struct{}
+
+// ZeroRTT resumes connections to servers connected to before with their
+// TLS session tickets, sending the first message with the handshake. Data
+// sent with 0-RTT can be replayed by an attacker, so only idempotent
+// requests should be sent on new connections. Both ends must enable it.
+func ZeroRTT() transport.Option {
+	return setTransportOption(zeroRTTKey{}, true)
+}
+
+// SessionCache caches the session tickets of the servers for ZeroRTT,
+// instead of an LRU cache of DefaultSessionCacheSize.
+func SessionCache(c tls.ClientSessionCache) transport.Option {
+	return setTransportOption(sessionCacheKey{}, c)
+}
+
+// IdleTimeout closes connections without activity for the duration. The
+// shorter one of the two ends applies.
+func IdleTimeout(d time.Duration) transport.Option {
+	return setTransportOption(idleTimeoutKey{}, d)
+}
+
+// HandshakeTimeout aborts connection attempts without a response from the
+// peer for the duration, 5 seconds by default.
+func HandshakeTimeout(d time.Duration) transport.Option {
+	return setTransportOption(handshakeTimeoutKey{}, d)
+}
+
+// KeepAlive sets whether connections are kept open with keepalive packets,
+// they are by default.
+func KeepAlive(b bool) transport.Option {
+	return setTransportOption(keepAliveKey{}, b)
+}
+
+// Datagrams enables unreliable datagrams, see SendDatagram. Both ends must
+// enable them.
+func Datagrams() transport.Option {
+	return setTransportOption(datagramsKey{}, true)
+}
+
+func setTransportOption(k, v interface{}) transport.Option {
+	return func(o *transport.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
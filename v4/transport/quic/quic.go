@@ -4,17 +4,18 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/gob"
+	"net"
+	"sync"
 	"time"
 
-	"go-micro.dev/v4/util/cmd"
-
-	quic "github.com/lucas-clemente/quic-go"
+	"github.com/quic-go/quic-go"
 	"go-micro.dev/v4/transport"
+	"go-micro.dev/v4/util/cmd"
 	utls "go-micro.dev/v4/util/tls"
 )
 
 type quicSocket struct {
-	s   quic.Session
+	s   quic.Connection
 	st  quic.Stream
 	enc *gob.Encoder
 	dec *gob.Decoder
@@ -22,6 +23,10 @@ type quicSocket struct {
 
 type quicTransport struct {
 	opts transport.Options
+
+	once sync.Once
+	// client session cache for 0-RTT
+	sessions tls.ClientSessionCache
 }
 
 type quicClient struct {
@@ -31,9 +36,14 @@ type quicClient struct {
 }
 
 type quicListener struct {
-	l    quic.Listener
-	t    *quicTransport
-	opts transport.ListenOptions
+	l interface {
+		Addr() net.Addr
+		Close() error
+	}
+	// accept returns the next connection of l
+	accept func(ctx context.Context) (quic.Connection, error)
+	t      *quicTransport
+	opts   transport.ListenOptions
 }
 
 func init() {
@@ -77,7 +87,7 @@ func (q *quicListener) Close() error {
 
 func (q *quicListener) Accept(fn func(transport.Socket)) error {
 	for {
-		s, err := q.l.Accept(context.TODO())
+		s, err := q.accept(context.TODO())
 		if err != nil {
 			return err
 		}
@@ -109,6 +119,44 @@ func (q *quicTransport) Options() transport.Options {
 	return q.opts
 }
 
+// config returns the QUIC config of the options.
+func (q *quicTransport) config() *quic.Config {
+	c := &quic.Config{
+		MaxIdleTimeout: DefaultIdleTimeout,
+		Allow0RTT:      q.zeroRTT(),
+	}
+	keepAlive := true
+
+	if ctx := q.opts.Context; ctx != nil {
+		if d, ok := ctx.Value(idleTimeoutKey{}).(time.Duration); ok {
+			c.MaxIdleTimeout = d
+		}
+		if d, ok := ctx.Value(handshakeTimeoutKey{}).(time.Duration); ok {
+			c.HandshakeIdleTimeout = d
+		}
+		if b, ok := ctx.Value(keepAliveKey{}).(bool); ok {
+			keepAlive = b
+		}
+		if b, ok := ctx.Value(datagramsKey{}).(bool); ok {
+			c.EnableDatagrams = b
+		}
+	}
+
+	// keepalive packets are sent at half of the idle timeout at most
+	if keepAlive {
+		c.KeepAlivePeriod = c.MaxIdleTimeout / 2
+	}
+	return c
+}
+
+func (q *quicTransport) zeroRTT() bool {
+	if q.opts.Context == nil {
+		return false
+	}
+	b, _ := q.opts.Context.Value(zeroRTTKey{}).(bool)
+	return b
+}
+
 func (q *quicTransport) Dial(addr string, opts ...transport.DialOption) (transport.Client, error) {
 	var options transport.DialOptions
 	for _, o := range opts {
@@ -122,10 +170,14 @@ func (q *quicTransport) Dial(addr string, opts ...transport.DialOption) (transpo
 			NextProtos:         []string{"http/1.1"},
 		}
 	}
-	s, err := quic.DialAddr(addr, config, &quic.Config{
-		MaxIdleTimeout: time.Minute * 2,
-		KeepAlive:      true,
-	})
+
+	var s quic.Connection
+	var err error
+	if q.zeroRTT() {
+		s, err = quic.DialAddrEarly(context.TODO(), addr, q.sessionConfig(config), q.config())
+	} else {
+		s, err = quic.DialAddr(context.TODO(), addr, config, q.config())
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -168,18 +220,54 @@ func (q *quicTransport) Listen(addr string, opts ...transport.ListenOption) (tra
 		}
 	}
 
-	l, err := quic.ListenAddr(addr, config, &quic.Config{KeepAlive: true})
+	if q.zeroRTT() {
+		l, err := quic.ListenAddrEarly(addr, config, q.config())
+		if err != nil {
+			return nil, err
+		}
+		return &quicListener{
+			l: l,
+			accept: func(ctx context.Context) (quic.Connection, error) {
+				return l.Accept(ctx)
+			},
+			t:    q,
+			opts: options,
+		}, nil
+	}
+
+	l, err := quic.ListenAddr(addr, config, q.config())
 	if err != nil {
 		return nil, err
 	}
 
 	return &quicListener{
-		l:    l,
-		t:    q,
-		opts: options,
+		l:      l,
+		accept: l.Accept,
+		t:      q,
+		opts:   options,
 	}, nil
 }
 
+// sessionConfig returns the TLS config with a session cache for 0-RTT. The
+// cache is shared by the connections of the transport.
+func (q *quicTransport) sessionConfig(config *tls.Config) *tls.Config {
+	if config.ClientSessionCache != nil {
+		return config
+	}
+
+	q.once.Do(func() {
+		if c, ok := q.opts.Context.Value(sessionCacheKey{}).(tls.ClientSessionCache); ok {
+			q.sessions = c
+			return
+		}
+		q.sessions = tls.NewLRUClientSessionCache(DefaultSessionCacheSize)
+	})
+
+	config = config.Clone()
+	config.ClientSessionCache = q.sessions
+	return config
+}
+
 func (q *quicTransport) String() string {
 	return "quic"
 }
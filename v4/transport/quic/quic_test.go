@@ -0,0 +1,179 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"go-micro.dev/v4/transport"
+)
+
+// echo listens on a local port and sends the messages received back, as
+// datagrams too when both ends enabled them.
+func echo(t *testing.T, tr transport.Transport) transport.Listener {
+	t.Helper()
+
+	l, err := tr.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected listen err: %v", err)
+	}
+
+	go l.Accept(func(sock transport.Socket) {
+		defer sock.Close()
+
+		go func() {
+			for {
+				var m transport.Message
+				if err := ReceiveDatagram(sock, &m); err != nil {
+					return
+				}
+				SendDatagram(sock, &m)
+			}
+		}()
+
+		for {
+			var m transport.Message
+			if err := sock.Recv(&m); err != nil {
+				return
+			}
+			if err := sock.Send(&m); err != nil {
+				return
+			}
+		}
+	})
+
+	return l
+}
+
+func roundTrip(t *testing.T, c transport.Client, body string) {
+	t.Helper()
+
+	m := transport.Message{
+		Header: map[string]string{"Content-Type": "application/json"},
+		Body:   []byte(body),
+	}
+	if err := c.Send(&m); err != nil {
+		t.Fatalf("Unexpected send err: %v", err)
+	}
+
+	var rm transport.Message
+	if err := c.Recv(&rm); err != nil {
+		t.Fatalf("Unexpected recv err: %v", err)
+	}
+	if string(rm.Body) != body || rm.Header["Content-Type"] != "application/json" {
+		t.Fatalf("Expected %s, got %s %v", body, rm.Body, rm.Header)
+	}
+}
+
+func TestQUICTransport(t *testing.T) {
+	tr := NewTransport()
+	l := echo(t, tr)
+	defer l.Close()
+
+	c, err := tr.Dial(l.Addr())
+	if err != nil {
+		t.Fatalf("Unexpected dial err: %v", err)
+	}
+	defer c.Close()
+
+	roundTrip(t, c, `{"message": "Hello World"}`)
+	roundTrip(t, c, `{"message": "Hello again"}`)
+
+	if err := SendDatagram(c, &transport.Message{}); err != ErrDatagramsUnsupported {
+		t.Fatalf("Expected %v, got %v", ErrDatagramsUnsupported, err)
+	}
+}
+
+// sessionCache signals the session tickets stored.
+type sessionCache struct {
+	tls.ClientSessionCache
+	put chan struct{}
+}
+
+func (s *sessionCache) Put(key string, cs *tls.ClientSessionState) {
+	s.ClientSessionCache.Put(key, cs)
+	if cs != nil {
+		select {
+		case s.put <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func TestZeroRTT(t *testing.T) {
+	l := echo(t, NewTransport(ZeroRTT()))
+	defer l.Close()
+
+	cache := &sessionCache{tls.NewLRUClientSessionCache(1), make(chan struct{}, 1)}
+	tr := NewTransport(ZeroRTT(), SessionCache(cache))
+
+	for i, resumed := range []bool{false, true} {
+		c, err := tr.Dial(l.Addr())
+		if err != nil {
+			t.Fatalf("Unexpected dial err: %v", err)
+		}
+		roundTrip(t, c, `{"message": "Hello World"}`)
+
+		if i == 0 {
+			select {
+			case <-cache.put:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Expected a session ticket")
+			}
+		}
+
+		conn := c.(*quicClient).s.(quic.EarlyConnection)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		select {
+		case <-conn.HandshakeComplete():
+		case <-ctx.Done():
+			t.Fatal("Expected the handshake to complete")
+		}
+		if used := conn.ConnectionState().Used0RTT; used != resumed {
+			t.Fatalf("Expected 0-RTT %v on connection %d, got %v", resumed, i+1, used)
+		}
+
+		c.(*quicClient).quicSocket.Close()
+	}
+}
+
+func TestDatagrams(t *testing.T) {
+	tr := NewTransport(Datagrams())
+	l := echo(t, tr)
+	defer l.Close()
+
+	c, err := tr.Dial(l.Addr())
+	if err != nil {
+		t.Fatalf("Unexpected dial err: %v", err)
+	}
+	defer c.Close()
+
+	// the stream is accepted once used
+	roundTrip(t, c, `{"message": "Hello World"}`)
+
+	m := transport.Message{Header: map[string]string{"Id": "1"}, Body: []byte("ping")}
+	if err := SendDatagram(c, &m); err != nil {
+		t.Fatalf("Unexpected datagram send err: %v", err)
+	}
+
+	ch := make(chan error, 1)
+	var rm transport.Message
+	go func() {
+		ch <- ReceiveDatagram(c, &rm)
+	}()
+
+	select {
+	case err := <-ch:
+		if err != nil {
+			t.Fatalf("Unexpected datagram recv err: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a datagram")
+	}
+	if string(rm.Body) != "ping" || rm.Header["Id"] != "1" {
+		t.Fatalf("Expected ping, got %s %v", rm.Body, rm.Header)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"net"
 	"time"
 
+	"github.com/go-micro/plugins/v4/util/socket"
 	log "go-micro.dev/v4/logger"
 	"go-micro.dev/v4/transport"
 	maddr "go-micro.dev/v4/util/addr"
@@ -115,7 +116,7 @@ func (t *tcpTransportSocket) Close() error {
 }
 
 func (t *tcpTransportListener) Addr() string {
-	return t.listener.Addr().String()
+	return socket.Addr(t.listener.Addr())
 }
 
 func (t *tcpTransportListener) Close() error {
@@ -179,7 +180,18 @@ func (t *tcpTransport) Dial(addr string, opts ...transport.DialOption) (transpor
 	var err error
 
 	// TODO: support dial option here rather than using internal config
-	if t.opts.Secure || t.opts.TLSConfig != nil {
+	if socket.Local(addr) {
+		conn, err = socket.DialTimeout(addr, dopts.Timeout)
+		if err == nil && (t.opts.Secure || t.opts.TLSConfig != nil) {
+			config := t.opts.TLSConfig
+			if config == nil {
+				config = &tls.Config{
+					InsecureSkipVerify: true,
+				}
+			}
+			conn = tls.Client(conn, config)
+		}
+	} else if t.opts.Secure || t.opts.TLSConfig != nil {
 		config := t.opts.TLSConfig
 		if config == nil {
 			config = &tls.Config{
@@ -217,7 +229,21 @@ func (t *tcpTransport) Listen(addr string, opts ...transport.ListenOption) (tran
 	var err error
 
 	// TODO: support use of listen options
-	if t.opts.Secure || t.opts.TLSConfig != nil {
+	if socket.Local(addr) {
+		l, err = socket.Listen(addr)
+		if err == nil && (t.opts.Secure || t.opts.TLSConfig != nil) {
+			config := t.opts.TLSConfig
+			if config == nil {
+				cert, cerr := mls.Certificate("localhost")
+				if cerr != nil {
+					l.Close()
+					return nil, cerr
+				}
+				config = &tls.Config{Certificates: []tls.Certificate{cert}}
+			}
+			l = tls.NewListener(l, config)
+		}
+	} else if t.opts.Secure || t.opts.TLSConfig != nil {
 		config := t.opts.TLSConfig
 
 		fn := func(addr string) (net.Listener, error) {
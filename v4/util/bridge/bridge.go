@@ -0,0 +1,225 @@
+// Package bridge exposes broker topics as a server streaming RPC, so
+// consumers outside the cluster subscribe with a client instead of broker
+// credentials.
+package bridge
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	pb "github.com/go-micro/plugins/v4/util/bridge/proto"
+	"go-micro.dev/v4/broker"
+	"go-micro.dev/v4/errors"
+	"go-micro.dev/v4/logger"
+	"go-micro.dev/v4/server"
+)
+
+// Bridge is the handler of the Bridge.Subscribe endpoint. Every topic is
+// subscribed to once, while it has clients, and its messages are fanned out
+// to them.
+type Bridge struct {
+	broker broker.Broker
+	opts   Options
+
+	sync.Mutex
+	topics map[string]*topic
+}
+
+// topic is a subscription shared by its clients.
+type topic struct {
+	sub broker.Subscriber
+
+	sync.RWMutex
+	clients map[*subscriber]bool
+}
+
+// subscriber is a client streaming a topic.
+type subscriber struct {
+	dropped uint64
+	filter  map[string]string
+	events  chan *pb.Event
+
+	once sync.Once
+	// closed once the buffer overflowed with Disconnect
+	slow chan struct{}
+}
+
+// New returns a bridge of the topics of the broker. The broker must be
+// connected, like the broker of a service once it started.
+func New(b broker.Broker, opts ...Option) *Bridge {
+	return &Bridge{
+		broker: b,
+		opts:   newOptions(opts...),
+		topics: make(map[string]*topic),
+	}
+}
+
+// Register registers the bridge with the server.
+func Register(s server.Server, b *Bridge, opts ...server.HandlerOption) error {
+	return s.Handle(s.NewHandler(b, opts...))
+}
+
+// Subscribe streams the messages of the topic of the request matching its
+// filter, until the client goes away.
+func (b *Bridge) Subscribe(ctx context.Context, stream server.Stream) error {
+	req := new(pb.SubscribeRequest)
+	if err := stream.Recv(req); err != nil {
+		return err
+	}
+
+	if len(req.Topic) == 0 && len(b.opts.Topics) == 1 {
+		req.Topic = b.opts.Topics[0]
+	}
+	if !b.allowed(req.Topic) {
+		return errors.Forbidden("bridge", "topic %q not available", req.Topic)
+	}
+	if b.opts.Authorize != nil {
+		if err := b.opts.Authorize(ctx, req); err != nil {
+			return err
+		}
+	}
+
+	s := &subscriber{
+		filter: req.Filter,
+		events: make(chan *pb.Event, b.opts.Buffer),
+		slow:   make(chan struct{}),
+	}
+	if err := b.add(req.Topic, s); err != nil {
+		return errors.InternalServerError("bridge", "subscribing to %s: %v", req.Topic, err)
+	}
+	defer b.remove(req.Topic, s)
+
+	// messages are sent from another goroutine, so a client which stopped
+	// reading is disconnected while a send blocks
+	done := make(chan struct{})
+	defer close(done)
+
+	errc := make(chan error, 1)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case ev := <-s.events:
+				if err := stream.Send(ev); err != nil {
+					errc <- err
+					return
+				}
+			}
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-s.slow:
+		return errors.New("bridge", "client too slow", 429)
+	case err := <-errc:
+		return err
+	}
+}
+
+func (b *Bridge) allowed(name string) bool {
+	for _, t := range b.opts.Topics {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// add adds the subscriber to the topic, subscribing to it for the first
+// one.
+func (b *Bridge) add(name string, s *subscriber) error {
+	b.Lock()
+	defer b.Unlock()
+
+	t, ok := b.topics[name]
+	if !ok {
+		t = &topic{clients: make(map[*subscriber]bool)}
+		sub, err := b.broker.Subscribe(name, b.fanout(t))
+		if err != nil {
+			return err
+		}
+		t.sub = sub
+		b.topics[name] = t
+	}
+
+	t.Lock()
+	t.clients[s] = true
+	t.Unlock()
+	return nil
+}
+
+// remove removes the subscriber from the topic, unsubscribing for the last
+// one.
+func (b *Bridge) remove(name string, s *subscriber) {
+	b.Lock()
+	defer b.Unlock()
+
+	t, ok := b.topics[name]
+	if !ok {
+		return
+	}
+
+	t.Lock()
+	delete(t.clients, s)
+	n := len(t.clients)
+	t.Unlock()
+
+	if dropped := atomic.LoadUint64(&s.dropped); dropped > 0 {
+		b.opts.Logger.Logf(logger.WarnLevel, "bridge: %d messages of %s dropped for a slow client", dropped, name)
+	}
+
+	if n == 0 {
+		delete(b.topics, name)
+		if err := t.sub.Unsubscribe(); err != nil {
+			b.opts.Logger.Logf(logger.ErrorLevel, "bridge: unsubscribing from %s: %v", name, err)
+		}
+	}
+}
+
+// fanout hands the messages of the topic to its clients without blocking,
+// so a slow client doesn't hold up the others.
+func (b *Bridge) fanout(t *topic) broker.Handler {
+	return func(e broker.Event) error {
+		m := e.Message()
+		ev := &pb.Event{
+			Topic:  e.Topic(),
+			Id:     m.Header["Micro-Id"],
+			Header: m.Header,
+			Body:   m.Body,
+		}
+
+		t.RLock()
+		defer t.RUnlock()
+
+		for s := range t.clients {
+			if !s.match(m.Header) {
+				continue
+			}
+
+			select {
+			case s.events <- ev:
+			default:
+				if b.opts.Overflow == Disconnect {
+					s.once.Do(func() { close(s.slow) })
+				} else {
+					atomic.AddUint64(&s.dropped, 1)
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// match returns whether the header has the values of the filter.
+func (s *subscriber) match(header map[string]string) bool {
+	for k, v := range s.filter {
+		if header[k] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,174 @@
+package bridge
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/go-micro/plugins/v4/util/bridge/proto"
+	"go-micro.dev/v4/broker"
+	"go-micro.dev/v4/errors"
+	"go-micro.dev/v4/server"
+)
+
+// testStream is the stream of a client, whose request is sent first.
+type testStream struct {
+	server.Stream
+	req    *pb.SubscribeRequest
+	events chan *pb.Event
+}
+
+func newStream(req *pb.SubscribeRequest) *testStream {
+	return &testStream{req: req, events: make(chan *pb.Event, 16)}
+}
+
+func (s *testStream) Recv(msg interface{}) error {
+	*msg.(*pb.SubscribeRequest) = *s.req
+	return nil
+}
+
+func (s *testStream) Send(msg interface{}) error {
+	s.events <- msg.(*pb.Event)
+	return nil
+}
+
+func newBroker(t *testing.T) broker.Broker {
+	b := broker.NewMemoryBroker()
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// subscribe streams the request until the context is done, returning the
+// error of the stream.
+func subscribe(ctx context.Context, br *Bridge, s *testStream) <-chan error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- br.Subscribe(ctx, s)
+	}()
+	return errc
+}
+
+func waitClients(t *testing.T, br *Bridge, topic string, n int) {
+	for i := 0; i < 100; i++ {
+		br.Lock()
+		tp, ok := br.topics[topic]
+		var clients int
+		if ok {
+			tp.RLock()
+			clients = len(tp.clients)
+			tp.RUnlock()
+		}
+		br.Unlock()
+		if clients == n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Expected %d clients of %s", n, topic)
+}
+
+func TestSubscribe(t *testing.T) {
+	b := newBroker(t)
+	br := New(b, Topics("orders"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	all := newStream(&pb.SubscribeRequest{})
+	eu := newStream(&pb.SubscribeRequest{Topic: "orders", Filter: map[string]string{"Region": "eu"}})
+	errAll, errEU := subscribe(ctx, br, all), subscribe(ctx, br, eu)
+	waitClients(t, br, "orders", 2)
+
+	for _, region := range []string{"us", "eu"} {
+		msg := &broker.Message{Header: map[string]string{"Region": region, "Micro-Id": region}, Body: []byte(region)}
+		if err := b.Publish("orders", msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, expected := range []string{"us", "eu"} {
+		ev := <-all.events
+		if ev.Id != expected || string(ev.Body) != expected || ev.Topic != "orders" {
+			t.Errorf("Expected the %s message, got %+v", expected, ev)
+		}
+	}
+	if ev := <-eu.events; ev.Id != "eu" {
+		t.Errorf("Expected the eu message, got %+v", ev)
+	}
+	select {
+	case ev := <-eu.events:
+		t.Errorf("Expected the us message to be filtered, got %+v", ev)
+	default:
+	}
+
+	cancel()
+	if err := <-errAll; err != nil {
+		t.Error(err)
+	}
+	<-errEU
+
+	br.Lock()
+	n := len(br.topics)
+	br.Unlock()
+	if n != 0 {
+		t.Errorf("Expected the topic to be unsubscribed, got %d topics", n)
+	}
+}
+
+func TestSubscribeForbidden(t *testing.T) {
+	br := New(newBroker(t), Topics("orders", "payments"))
+
+	for _, topic := range []string{"", "secrets"} {
+		err := br.Subscribe(context.Background(), newStream(&pb.SubscribeRequest{Topic: topic}))
+		if merr := errors.FromError(err); merr.Code != 403 {
+			t.Errorf("Expected topic %q to be forbidden, got %v", topic, err)
+		}
+	}
+
+	br = New(newBroker(t), Topics("orders"), Authorize(func(ctx context.Context, req *pb.SubscribeRequest) error {
+		return errors.Unauthorized("test", "no account")
+	}))
+	err := br.Subscribe(context.Background(), newStream(&pb.SubscribeRequest{}))
+	if merr := errors.FromError(err); merr.Code != 401 {
+		t.Errorf("Expected the client to be unauthorized, got %v", err)
+	}
+}
+
+// blockedStream is a client which doesn't read.
+type blockedStream struct {
+	*testStream
+	unblock chan struct{}
+}
+
+func (s *blockedStream) Send(msg interface{}) error {
+	<-s.unblock
+	return nil
+}
+
+func TestOverflow(t *testing.T) {
+	b := newBroker(t)
+	br := New(b, Topics("orders"), Buffer(1), OnOverflow(Disconnect))
+
+	s := &blockedStream{newStream(&pb.SubscribeRequest{}), make(chan struct{})}
+	defer close(s.unblock)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- br.Subscribe(context.Background(), s)
+	}()
+	waitClients(t, br, "orders", 1)
+
+	go func() {
+		for i := 0; i < 3; i++ {
+			b.Publish("orders", &broker.Message{Body: []byte("order")})
+		}
+	}()
+
+	select {
+	case err := <-errc:
+		if merr := errors.FromError(err); merr.Code != 429 {
+			t.Errorf("Expected a 429 error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the slow client to be disconnected")
+	}
+}
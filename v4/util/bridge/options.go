@@ -0,0 +1,95 @@
+package bridge
+
+import (
+	"context"
+
+	pb "github.com/go-micro/plugins/v4/util/bridge/proto"
+	"go-micro.dev/v4/logger"
+)
+
+// DefaultBuffer is the number of messages buffered for each client.
+var DefaultBuffer = 64
+
+// Overflow is what happens to a client whose buffer is full.
+type Overflow int
+
+const (
+	// Drop drops the messages for the client until it caught up.
+	Drop Overflow = iota
+	// Disconnect ends the stream of the client with a 429 error, so it
+	// resubscribes, e.g. from a checkpoint.
+	Disconnect
+)
+
+// Options configure the bridge.
+type Options struct {
+	// Topics clients may subscribe to. Clients may omit the topic if
+	// there's one
+	Topics []string
+	// Buffer is the number of messages buffered for each client. Defaults
+	// to DefaultBuffer
+	Buffer int
+	// Overflow is what happens to clients whose buffer is full
+	Overflow Overflow
+	// Authorize is called with the request of a client before it's
+	// subscribed, returning an error rejects it
+	Authorize func(ctx context.Context, req *pb.SubscribeRequest) error
+	// Logger logs subscriptions and dropped messages. Defaults to the
+	// default logger
+	Logger logger.Logger
+}
+
+// Option sets an option.
+type Option func(*Options)
+
+// Topics sets the topics clients may subscribe to.
+func Topics(t ...string) Option {
+	return func(o *Options) {
+		o.Topics = append(o.Topics, t...)
+	}
+}
+
+// Buffer sets the number of messages buffered for each client.
+func Buffer(n int) Option {
+	return func(o *Options) {
+		o.Buffer = n
+	}
+}
+
+// OnOverflow sets what happens to clients whose buffer is full.
+func OnOverflow(ov Overflow) Option {
+	return func(o *Options) {
+		o.Overflow = ov
+	}
+}
+
+// Authorize sets the function authorizing the clients, e.g. to check the
+// topic against the account of the context.
+func Authorize(fn func(ctx context.Context, req *pb.SubscribeRequest) error) Option {
+	return func(o *Options) {
+		o.Authorize = fn
+	}
+}
+
+// Logger sets the logger of the bridge.
+func Logger(l logger.Logger) Option {
+	return func(o *Options) {
+		o.Logger = l
+	}
+}
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		Buffer: DefaultBuffer,
+		Logger: logger.DefaultLogger,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if options.Buffer <= 0 {
+		options.Buffer = 1
+	}
+	return options
+}
@@ -0,0 +1,167 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: util/bridge/proto/bridge.proto
+
+package bridge
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type SubscribeRequest struct {
+	// topic to subscribe to, may be empty if the bridge exposes one
+	Topic string `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	// header values the messages must have
+	Filter               map[string]string `protobuf:"bytes,2,rep,name=filter,proto3" json:"filter,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *SubscribeRequest) Reset()         { *m = SubscribeRequest{} }
+func (m *SubscribeRequest) String() string { return proto.CompactTextString(m) }
+func (*SubscribeRequest) ProtoMessage()    {}
+func (*SubscribeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0e57f92757ab0c80, []int{0}
+}
+
+func (m *SubscribeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SubscribeRequest.Unmarshal(m, b)
+}
+func (m *SubscribeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SubscribeRequest.Marshal(b, m, deterministic)
+}
+func (m *SubscribeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SubscribeRequest.Merge(m, src)
+}
+func (m *SubscribeRequest) XXX_Size() int {
+	return xxx_messageInfo_SubscribeRequest.Size(m)
+}
+func (m *SubscribeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SubscribeRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SubscribeRequest proto.InternalMessageInfo
+
+func (m *SubscribeRequest) GetTopic() string {
+	if m != nil {
+		return m.Topic
+	}
+	return ""
+}
+
+func (m *SubscribeRequest) GetFilter() map[string]string {
+	if m != nil {
+		return m.Filter
+	}
+	return nil
+}
+
+type Event struct {
+	Topic                string            `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	Id                   string            `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Header               map[string]string `protobuf:"bytes,3,rep,name=header,proto3" json:"header,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Body                 []byte            `protobuf:"bytes,4,opt,name=body,proto3" json:"body,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *Event) Reset()         { *m = Event{} }
+func (m *Event) String() string { return proto.CompactTextString(m) }
+func (*Event) ProtoMessage()    {}
+func (*Event) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0e57f92757ab0c80, []int{1}
+}
+
+func (m *Event) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Event.Unmarshal(m, b)
+}
+func (m *Event) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Event.Marshal(b, m, deterministic)
+}
+func (m *Event) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Event.Merge(m, src)
+}
+func (m *Event) XXX_Size() int {
+	return xxx_messageInfo_Event.Size(m)
+}
+func (m *Event) XXX_DiscardUnknown() {
+	xxx_messageInfo_Event.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Event proto.InternalMessageInfo
+
+func (m *Event) GetTopic() string {
+	if m != nil {
+		return m.Topic
+	}
+	return ""
+}
+
+func (m *Event) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Event) GetHeader() map[string]string {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *Event) GetBody() []byte {
+	if m != nil {
+		return m.Body
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*SubscribeRequest)(nil), "bridge.SubscribeRequest")
+	proto.RegisterMapType((map[string]string)(nil), "bridge.SubscribeRequest.FilterEntry")
+	proto.RegisterType((*Event)(nil), "bridge.Event")
+	proto.RegisterMapType((map[string]string)(nil), "bridge.Event.HeaderEntry")
+}
+
+func init() {
+	proto.RegisterFile("util/bridge/proto/bridge.proto", fileDescriptor_0e57f92757ab0c80)
+}
+
+var fileDescriptor_0e57f92757ab0c80 = []byte{
+	// 296 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x91, 0xb1, 0x4e, 0xf3, 0x30,
+	0x10, 0xc7, 0x3f, 0xa7, 0x6d, 0xa4, 0x5e, 0x3f, 0x50, 0x65, 0x31, 0x98, 0x0e, 0xa8, 0xaa, 0x18,
+	0xb2, 0x10, 0x43, 0x41, 0xa2, 0x05, 0xa6, 0x48, 0x45, 0xcc, 0x61, 0x63, 0xab, 0x13, 0x93, 0x5a,
+	0xa4, 0x71, 0x70, 0xec, 0x48, 0x79, 0x1c, 0x9e, 0x80, 0x57, 0x44, 0x71, 0x0c, 0x2a, 0xa0, 0x0e,
+	0x6c, 0xf7, 0x4b, 0xee, 0xee, 0xff, 0xd3, 0x19, 0x4e, 0x8c, 0x16, 0x39, 0x65, 0x4a, 0xa4, 0x19,
+	0xa7, 0xa5, 0x92, 0x5a, 0x3a, 0x08, 0x2d, 0x60, 0xbf, 0xa3, 0xd9, 0x1b, 0x82, 0xf1, 0xa3, 0x61,
+	0x55, 0xa2, 0x04, 0xe3, 0x31, 0x7f, 0x35, 0xbc, 0xd2, 0xf8, 0x08, 0x06, 0x5a, 0x96, 0x22, 0x21,
+	0x68, 0x8a, 0x82, 0x61, 0xdc, 0x01, 0xbe, 0x03, 0xff, 0x59, 0xe4, 0x9a, 0x2b, 0xe2, 0x4d, 0x7b,
+	0xc1, 0x68, 0x7e, 0x1a, 0xba, 0x8d, 0x3f, 0xe7, 0xc3, 0x7b, 0xdb, 0xb6, 0x2a, 0xb4, 0x6a, 0x62,
+	0x37, 0x33, 0x59, 0xc2, 0x68, 0xe7, 0x33, 0x1e, 0x43, 0xef, 0x85, 0x37, 0x2e, 0xa0, 0x2d, 0xdb,
+	0xd0, 0x7a, 0x9d, 0x1b, 0x4e, 0xbc, 0x2e, 0xd4, 0xc2, 0x8d, 0xb7, 0x40, 0xb3, 0x77, 0x04, 0x83,
+	0x55, 0xcd, 0x8b, 0x7d, 0x62, 0x87, 0xe0, 0x89, 0xd4, 0x8d, 0x79, 0x22, 0xc5, 0x17, 0xe0, 0x6f,
+	0xf8, 0x3a, 0xe5, 0x8a, 0xf4, 0xac, 0xe8, 0xf1, 0xa7, 0xa8, 0x5d, 0x12, 0x3e, 0xd8, 0x7f, 0xce,
+	0xae, 0x6b, 0xc4, 0x18, 0xfa, 0x4c, 0xa6, 0x0d, 0xe9, 0x4f, 0x51, 0xf0, 0x3f, 0xb6, 0x75, 0x6b,
+	0xbc, 0xd3, 0xfa, 0x17, 0xe3, 0x79, 0x04, 0x7e, 0x64, 0x23, 0xf1, 0x02, 0x86, 0x5f, 0xe7, 0xc1,
+	0x64, 0xdf, 0xc5, 0x26, 0x07, 0xdf, 0x14, 0x67, 0xff, 0xce, 0x51, 0xb4, 0x7c, 0xba, 0xce, 0x84,
+	0xde, 0x18, 0x16, 0x26, 0x72, 0x4b, 0x33, 0x79, 0xb6, 0x15, 0x89, 0x92, 0xb4, 0xcc, 0x4d, 0x26,
+	0x8a, 0x8a, 0xd6, 0x57, 0xf4, 0xd7, 0x13, 0xdf, 0x76, 0xc0, 0x7c, 0x4b, 0x97, 0x1f, 0x03, 0x00,
+	0x90, 0x04, 0x50, 0xc5, 0x05, 0x02, 0x00, 0x00,
+}
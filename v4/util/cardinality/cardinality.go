@@ -0,0 +1,106 @@
+// Package cardinality bounds the distinct values of metric labels, so a
+// label fed by requests, such as the endpoints of a proxy or tenants, can't
+// create an unbounded number of series. Values past the limit, or not in the
+// allowlist, are reported as an overflow value instead.
+package cardinality
+
+import (
+	"path"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// DefaultLimit is the number of distinct values of a label kept.
+	DefaultLimit = 256
+	// DefaultOverflow is the value reported for values not kept.
+	DefaultOverflow = "overflow"
+)
+
+// Limiter maps the values of a label to themselves while there are fewer
+// than the limit of them, and to the overflow value afterwards.
+type Limiter struct {
+	opts Options
+
+	sync.RWMutex
+	seen       map[string]bool
+	kept       int
+	overflowed uint64
+}
+
+// New returns a limiter of a label.
+func New(opts ...Option) *Limiter {
+	return &Limiter{
+		opts: newOptions(opts...),
+		seen: make(map[string]bool),
+	}
+}
+
+// Value returns the value to report for v, v itself if it's allowed and
+// among the first values seen, the overflow value otherwise.
+func (l *Limiter) Value(v string) string {
+	if l == nil {
+		return v
+	}
+
+	l.RLock()
+	kept, ok := l.seen[v]
+	l.RUnlock()
+	if ok {
+		return l.report(v, kept)
+	}
+
+	l.Lock()
+	kept, ok = l.seen[v]
+	if !ok {
+		kept = l.allowed(v) && (l.opts.Limit <= 0 || l.kept < l.opts.Limit)
+		if kept {
+			l.kept++
+		}
+		// remember rejected values as well, a bounded number of them, so
+		// they're not matched against the allowlist again
+		if kept || len(l.seen)-l.kept < DefaultLimit {
+			l.seen[v] = kept
+		}
+	}
+	l.Unlock()
+
+	return l.report(v, kept)
+}
+
+// Overflowed returns the number of values reported as the overflow value.
+func (l *Limiter) Overflowed() uint64 {
+	return atomic.LoadUint64(&l.overflowed)
+}
+
+// Values returns the number of distinct values kept.
+func (l *Limiter) Values() int {
+	l.RLock()
+	defer l.RUnlock()
+	return l.kept
+}
+
+func (l *Limiter) report(v string, kept bool) string {
+	if kept {
+		return v
+	}
+	if atomic.AddUint64(&l.overflowed, 1) == 1 && l.opts.OnFirstOverflow != nil {
+		l.opts.OnFirstOverflow(v)
+	}
+	if l.opts.OnOverflow != nil {
+		l.opts.OnOverflow(v)
+	}
+	return l.opts.Overflow
+}
+
+func (l *Limiter) allowed(v string) bool {
+	if len(l.opts.Allow) == 0 {
+		return true
+	}
+	for _, p := range l.opts.Allow {
+		if ok, _ := path.Match(p, v); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package cardinality
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLimit(t *testing.T) {
+	var first string
+	l := New(Limit(3), OnFirstOverflow(func(v string) { first = v }))
+
+	for i := 0; i < 10; i++ {
+		v := fmt.Sprintf("Proxy.Call%d", i)
+		want := v
+		if i >= 3 {
+			want = DefaultOverflow
+		}
+		if got := l.Value(v); got != want {
+			t.Errorf("Value(%s) = %s, want %s", v, got, want)
+		}
+	}
+
+	// values kept stay kept
+	if got := l.Value("Proxy.Call0"); got != "Proxy.Call0" {
+		t.Errorf("Value(Proxy.Call0) = %s", got)
+	}
+	if l.Values() != 3 || l.Overflowed() != 7 || first != "Proxy.Call3" {
+		t.Errorf("Unexpected values %d, overflowed %d, first %s", l.Values(), l.Overflowed(), first)
+	}
+}
+
+func TestAllow(t *testing.T) {
+	l := New(Allow("Greeter.*", "Health.Check"), Limit(0), Overflow("other"))
+
+	for v, want := range map[string]string{
+		"Greeter.Hello": "Greeter.Hello",
+		"Greeter.Bye":   "Greeter.Bye",
+		"Health.Check":  "Health.Check",
+		"tenant-42":     "other",
+	} {
+		if got := l.Value(v); got != want {
+			t.Errorf("Value(%s) = %s, want %s", v, got, want)
+		}
+	}
+
+	var nilLimiter *Limiter
+	if got := nilLimiter.Value("any"); got != "any" {
+		t.Errorf("nil Value(any) = %s", got)
+	}
+}
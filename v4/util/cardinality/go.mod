@@ -0,0 +1,3 @@
+module github.com/go-micro/plugins/v4/util/cardinality
+
+go 1.17
@@ -0,0 +1,69 @@
+package cardinality
+
+// Options configure a limiter.
+type Options struct {
+	// Limit is the number of distinct values kept, zero or less keeps all
+	// allowed values. Defaults to DefaultLimit
+	Limit int
+	// Allow are the path.Match patterns of the values kept, e.g.
+	// "Greeter.*". All values are allowed without patterns
+	Allow []string
+	// Overflow is the value reported instead. Defaults to DefaultOverflow
+	Overflow string
+	// OnFirstOverflow is called with the first value not kept, e.g. to log
+	// a warning
+	OnFirstOverflow func(string)
+	// OnOverflow is called with every value not kept, e.g. to count them
+	OnOverflow func(string)
+}
+
+// Option sets an option.
+type Option func(*Options)
+
+// Limit sets the number of distinct values kept.
+func Limit(n int) Option {
+	return func(o *Options) {
+		o.Limit = n
+	}
+}
+
+// Allow only keeps the values matching one of the patterns.
+func Allow(patterns ...string) Option {
+	return func(o *Options) {
+		o.Allow = append(o.Allow, patterns...)
+	}
+}
+
+// Overflow sets the value reported for values not kept.
+func Overflow(v string) Option {
+	return func(o *Options) {
+		o.Overflow = v
+	}
+}
+
+// OnFirstOverflow calls fn with the first value not kept.
+func OnFirstOverflow(fn func(string)) Option {
+	return func(o *Options) {
+		o.OnFirstOverflow = fn
+	}
+}
+
+// OnOverflow calls fn with every value not kept.
+func OnOverflow(fn func(string)) Option {
+	return func(o *Options) {
+		o.OnOverflow = fn
+	}
+}
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		Limit:    DefaultLimit,
+		Overflow: DefaultOverflow,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return options
+}
@@ -0,0 +1,173 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Mock is a clock which only moves when told to. Timers, tickers and sleeps
+// fire as Add passes their time, in the order they're due, so tests are
+// deterministic.
+type Mock struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []*mockTimer
+}
+
+// NewMock returns a mock clock set to the time.
+func NewMock(t time.Time) *Mock {
+	m := &Mock{now: t}
+	m.cond = sync.NewCond(&m.mu)
+	return m
+}
+
+// Now returns the time of the clock.
+func (m *Mock) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now
+}
+
+func (m *Mock) Since(t time.Time) time.Duration {
+	return m.Now().Sub(t)
+}
+
+func (m *Mock) Until(t time.Time) time.Duration {
+	return t.Sub(m.Now())
+}
+
+func (m *Mock) After(d time.Duration) <-chan time.Time {
+	return m.NewTimer(d).C()
+}
+
+// Sleep blocks until the clock is moved past the duration.
+func (m *Mock) Sleep(d time.Duration) {
+	<-m.After(d)
+}
+
+func (m *Mock) NewTimer(d time.Duration) Timer {
+	t := &mockTimer{m: m, c: make(chan time.Time, 1)}
+	m.mu.Lock()
+	m.schedule(t, d)
+	m.mu.Unlock()
+	return t
+}
+
+func (m *Mock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	t := &mockTimer{m: m, c: make(chan time.Time, 1), period: d}
+	m.mu.Lock()
+	m.schedule(t, d)
+	m.mu.Unlock()
+	return &mockTicker{t}
+}
+
+// Add moves the clock forward, firing the timers due on the way.
+func (m *Mock) Add(d time.Duration) {
+	m.Set(m.Now().Add(d))
+}
+
+// Set moves the clock to the time, firing the timers due until then. Moving
+// it back fires nothing.
+func (m *Mock) Set(t time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for len(m.timers) > 0 && !m.timers[0].when.After(t) {
+		next := m.timers[0]
+		m.now = next.when
+		m.remove(next)
+		next.fire(m.now)
+		if next.period > 0 {
+			m.schedule(next, next.period)
+		}
+	}
+
+	if t.After(m.now) {
+		m.now = t
+	}
+}
+
+// BlockUntil blocks until n timers, tickers or sleeps are waiting, e.g. for
+// the goroutine under test to wait for its next retry before moving the
+// clock.
+func (m *Mock) BlockUntil(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for len(m.timers) < n {
+		m.cond.Wait()
+	}
+}
+
+// schedule adds the timer to fire in d, keeping the timers sorted by when
+// they're due.
+func (m *Mock) schedule(t *mockTimer, d time.Duration) {
+	t.when = m.now.Add(d)
+	i := sort.Search(len(m.timers), func(i int) bool { return m.timers[i].when.After(t.when) })
+	m.timers = append(m.timers, nil)
+	copy(m.timers[i+1:], m.timers[i:])
+	m.timers[i] = t
+	m.cond.Broadcast()
+}
+
+func (m *Mock) remove(t *mockTimer) bool {
+	for i, mt := range m.timers {
+		if mt == t {
+			m.timers = append(m.timers[:i], m.timers[i+1:]...)
+			m.cond.Broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+type mockTimer struct {
+	m      *Mock
+	c      chan time.Time
+	when   time.Time
+	period time.Duration
+}
+
+// fire sends the time without blocking, like the tickers of the time
+// package drop ticks of slow receivers.
+func (t *mockTimer) fire(now time.Time) {
+	select {
+	case t.c <- now:
+	default:
+	}
+}
+
+func (t *mockTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *mockTimer) Stop() bool {
+	t.m.mu.Lock()
+	defer t.m.mu.Unlock()
+	return t.m.remove(t)
+}
+
+// Reset reschedules the timer, if it was stopped or fired too.
+func (t *mockTimer) Reset(d time.Duration) bool {
+	t.m.mu.Lock()
+	defer t.m.mu.Unlock()
+	active := t.m.remove(t)
+	t.m.schedule(t, d)
+	return active
+}
+
+type mockTicker struct {
+	t *mockTimer
+}
+
+func (t *mockTicker) C() <-chan time.Time {
+	return t.t.c
+}
+
+func (t *mockTicker) Stop() {
+	t.t.Stop()
+}
@@ -0,0 +1,56 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMock(t *testing.T) {
+	start := time.Unix(0, 0)
+	m := NewMock(start)
+
+	timer := m.NewTimer(time.Second)
+	ticker := m.NewTicker(400 * time.Millisecond)
+	defer ticker.Stop()
+
+	m.Add(500 * time.Millisecond)
+	select {
+	case <-timer.C():
+		t.Fatal("Expected the timer not to fire yet")
+	default:
+	}
+	if now := <-ticker.C(); !now.Equal(start.Add(400 * time.Millisecond)) {
+		t.Errorf("Expected the tick at 400ms, got %v", now.Sub(start))
+	}
+
+	m.Add(500 * time.Millisecond)
+	if now := <-timer.C(); !now.Equal(start.Add(time.Second)) {
+		t.Errorf("Expected the timer to fire at 1s, got %v", now.Sub(start))
+	}
+	if timer.Stop() {
+		t.Error("Expected the fired timer to be stopped")
+	}
+
+	if d := m.Since(start); d != time.Second {
+		t.Errorf("Expected 1s to have passed, got %v", d)
+	}
+}
+
+func TestMockSleep(t *testing.T) {
+	m := NewMock(time.Unix(0, 0))
+
+	done := make(chan bool)
+	go func() {
+		m.Sleep(time.Minute)
+		close(done)
+	}()
+
+	m.BlockUntil(1)
+	m.Add(time.Minute)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the sleep to return")
+	}
+}
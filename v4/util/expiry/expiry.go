@@ -0,0 +1,42 @@
+// Package expiry sets and checks the expiry of broker messages published
+// with a TTL. It's the header convention of the brokers which drop stale
+// messages in their subscribers, so a message expires the same way whichever
+// broker carries it.
+package expiry
+
+import (
+	"strconv"
+	"time"
+
+	"go-micro.dev/v4/broker"
+)
+
+// Header is the time in unix milliseconds a message published with a TTL
+// expires at. The clocks of publishers and subscribers must be in sync.
+const Header = "Micro-Expires"
+
+// Set returns a copy of the message expiring ttl after now. The message
+// itself is left alone.
+func Set(msg *broker.Message, ttl time.Duration, now time.Time) *broker.Message {
+	header := make(map[string]string, len(msg.Header)+1)
+	for k, v := range msg.Header {
+		header[k] = v
+	}
+	header[Header] = strconv.FormatInt(now.Add(ttl).UnixNano()/int64(time.Millisecond), 10)
+
+	return &broker.Message{Header: header, Body: msg.Body}
+}
+
+// Expired reports whether the message has an expiry which passed at now.
+// Messages without a valid expiry never expire.
+func Expired(msg *broker.Message, now time.Time) bool {
+	v, ok := msg.Header[Header]
+	if !ok {
+		return false
+	}
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return false
+	}
+	return now.After(time.Unix(0, ms*int64(time.Millisecond)))
+}
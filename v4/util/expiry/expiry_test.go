@@ -0,0 +1,41 @@
+package expiry
+
+import (
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/broker"
+)
+
+func TestExpiry(t *testing.T) {
+	now := time.Now()
+	msg := &broker.Message{Header: map[string]string{"Foo": "bar"}, Body: []byte("hello")}
+
+	m := Set(msg, time.Minute, now)
+	if _, ok := msg.Header[Header]; ok {
+		t.Fatal("Expected the message to be left alone")
+	}
+	if m.Header["Foo"] != "bar" || string(m.Body) != "hello" {
+		t.Fatalf("Expected the headers and body to be kept, got %+v", m)
+	}
+
+	testcases := []struct {
+		name    string
+		msg     *broker.Message
+		now     time.Time
+		expired bool
+	}{
+		{"no expiry", msg, now.Add(time.Hour), false},
+		{"before expiry", m, now.Add(time.Second), false},
+		{"past expiry", m, now.Add(2 * time.Minute), true},
+		{"invalid expiry", &broker.Message{Header: map[string]string{Header: "soon"}}, now, false},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			if have := Expired(test.msg, test.now); have != test.expired {
+				t.Errorf("Expected expired %t, have %t", test.expired, have)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+// Package loadtest drives calls against a service through a go-micro client,
+// with its selector, codecs and wrappers, and reports the latency percentiles
+// and errors of the run, so performance regressions fail Go tests.
+package loadtest
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/errors"
+)
+
+// Target makes one call of a run.
+type Target func(ctx context.Context, c client.Client, opts ...client.CallOption) error
+
+// Call returns a target calling the endpoint of the service with the
+// request, decoding into a new response of rsp every call.
+func Call(service, endpoint string, req interface{}, rsp func() interface{}, opts ...client.RequestOption) Target {
+	return func(ctx context.Context, c client.Client, copts ...client.CallOption) error {
+		r := c.NewRequest(service, endpoint, req, opts...)
+		return c.Call(ctx, r, rsp(), copts...)
+	}
+}
+
+// sample is the outcome of one call.
+type sample struct {
+	latency time.Duration
+	err     error
+	done    time.Time
+}
+
+// Run calls the target until the duration passed or the requests were
+// made, and returns the result of the calls made after the warmup. With a
+// rate the latency of a call counts from when it was due, so time spent
+// waiting for a free worker when the service falls behind is included.
+// Cancelling the context stops the run, with the result so far.
+func Run(ctx context.Context, c client.Client, t Target, opts ...Option) (*Result, error) {
+	options := newOptions(opts...)
+
+	start := time.Now()
+	recordFrom := start.Add(options.Warmup)
+	var end time.Time
+	if options.Duration > 0 {
+		end = recordFrom.Add(options.Duration)
+	}
+
+	due := make(chan time.Time)
+	samples := make([][]sample, options.Concurrency)
+
+	var wg sync.WaitGroup
+	for i := 0; i < options.Concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for at := range due {
+				begin := at
+				if options.Rate == 0 {
+					begin = time.Now()
+				}
+				err := t(ctx, c, options.CallOptions...)
+				now := time.Now()
+				if at.Before(recordFrom) {
+					continue
+				}
+				samples[i] = append(samples[i], sample{latency: now.Sub(begin), err: err, done: now})
+			}
+		}(i)
+	}
+
+	err := schedule(ctx, options, start, recordFrom, end, due)
+	close(due)
+	wg.Wait()
+
+	var all []sample
+	for _, s := range samples {
+		all = append(all, s...)
+	}
+	return newResult(recordFrom, all), err
+}
+
+// schedule sends the times calls are due until the run ends.
+func schedule(ctx context.Context, options Options, start, recordFrom, end time.Time, due chan<- time.Time) error {
+	var interval time.Duration
+	if options.Rate > 0 {
+		interval = time.Second / time.Duration(options.Rate)
+	}
+
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+
+	recorded := 0
+	for i := 0; ; i++ {
+		at := time.Now()
+		if interval > 0 {
+			at = start.Add(time.Duration(i) * interval)
+		}
+		if !end.IsZero() && !at.Before(end) {
+			return nil
+		}
+		if !at.Before(recordFrom) {
+			if options.Requests > 0 && recorded == options.Requests {
+				return nil
+			}
+			recorded++
+		}
+
+		if d := time.Until(at); d > 0 {
+			timer.Reset(d)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case due <- at:
+		}
+	}
+}
+
+// classify returns the error stat of the error, without a count. Errors of
+// a service arrive as the text of a micro error, which is parsed.
+func classify(err error) ErrorStat {
+	switch err {
+	case context.DeadlineExceeded:
+		return ErrorStat{Code: http.StatusRequestTimeout, Status: http.StatusText(http.StatusRequestTimeout), Detail: err.Error()}
+	case context.Canceled:
+		return ErrorStat{Status: "Canceled", Detail: err.Error()}
+	}
+
+	merr := errors.FromError(err)
+	if merr.Code == 0 {
+		return ErrorStat{Id: merr.Id, Status: "Error", Detail: err.Error()}
+	}
+	status := merr.Status
+	if len(status) == 0 {
+		status = http.StatusText(int(merr.Code))
+	}
+	return ErrorStat{Id: merr.Id, Code: merr.Code, Status: status, Detail: merr.Detail}
+}
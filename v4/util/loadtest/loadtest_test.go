@@ -0,0 +1,144 @@
+package loadtest
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/errors"
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/server"
+)
+
+type Request struct {
+	Fail bool `json:"fail"`
+}
+
+type Response struct {
+	Ok bool `json:"ok"`
+}
+
+type Test struct {
+	calls int64
+}
+
+func (t *Test) Call(ctx context.Context, req *Request, rsp *Response) error {
+	atomic.AddInt64(&t.calls, 1)
+	if req.Fail {
+		return errors.BadRequest("test", "failing on request")
+	}
+	time.Sleep(time.Millisecond)
+	rsp.Ok = true
+	return nil
+}
+
+func newServer(t *testing.T, r registry.Registry, h *Test) server.Server {
+	s := server.NewServer(
+		server.Name("test"),
+		server.Address("127.0.0.1:0"),
+		server.Registry(r),
+	)
+	if err := s.Handle(s.NewHandler(h)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func newResponse() interface{} {
+	return new(Response)
+}
+
+func TestRequests(t *testing.T) {
+	r := registry.NewMemoryRegistry()
+	h := &Test{}
+	s := newServer(t, r, h)
+	defer s.Stop()
+
+	c := client.NewClient(client.Registry(r))
+	res, err := Run(context.Background(), c, Call("test", "Test.Call", &Request{}, newResponse),
+		Requests(100), Concurrency(4), Warmup(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(res)
+
+	if res.Requests != 100 || res.Errors != 0 || len(res.Latencies) != 100 {
+		t.Fatalf("Expected 100 successful calls, got %+v", res)
+	}
+	if atomic.LoadInt64(&h.calls) <= 100 {
+		t.Errorf("Expected warmup calls on top of the recorded ones, got %d", h.calls)
+	}
+	if res.Percentile(50) < time.Millisecond || res.Percentile(50) > res.Percentile(99) {
+		t.Errorf("Unexpected percentiles p50 %s p99 %s", res.Percentile(50), res.Percentile(99))
+	}
+	if err := res.Check(MaxErrorRate(0), MaxPercentile(99, 5*time.Second)); err != nil {
+		t.Error(err)
+	}
+	if err := res.Check(MaxPercentile(50, time.Nanosecond)); err == nil {
+		t.Error("Expected the p50 threshold to fail")
+	}
+}
+
+func TestRateAndErrors(t *testing.T) {
+	r := registry.NewMemoryRegistry()
+	s := newServer(t, r, &Test{})
+	defer s.Stop()
+
+	c := client.NewClient(client.Registry(r))
+
+	var n int64
+	target := func(ctx context.Context, c client.Client, opts ...client.CallOption) error {
+		switch atomic.AddInt64(&n, 1) % 4 {
+		case 0:
+			return Call("missing", "Test.Call", &Request{}, newResponse)(ctx, c, opts...)
+		case 1:
+			return Call("test", "Test.Call", &Request{Fail: true}, newResponse)(ctx, c, opts...)
+		}
+		return Call("test", "Test.Call", &Request{}, newResponse)(ctx, c, opts...)
+	}
+
+	res, err := Run(context.Background(), c, target, Rate(200), Duration(500*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(res)
+
+	if res.Requests < 90 || res.Requests > 110 {
+		t.Errorf("Expected about 100 calls at 200/s over 500ms, got %d", res.Requests)
+	}
+	if len(res.ErrorStats) != 2 {
+		t.Fatalf("Expected two kinds of errors, got %+v", res.ErrorStats)
+	}
+
+	codes := map[string]int32{}
+	for _, e := range res.ErrorStats {
+		codes[e.Id] = e.Code
+	}
+	if codes["test"] != 400 || codes["go.micro.client"] != 500 {
+		t.Errorf("Unexpected error breakdown %+v", res.ErrorStats)
+	}
+	if err := res.Check(MaxErrorRate(0.1)); err == nil {
+		t.Error("Expected the error rate threshold to fail")
+	}
+}
+
+func TestCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	target := func(ctx context.Context, c client.Client, opts ...client.CallOption) error {
+		return nil
+	}
+	res, err := Run(ctx, nil, target, Rate(100), Duration(time.Minute))
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Expected the deadline, got %v", err)
+	}
+	if res.Requests == 0 || res.Requests > 10 {
+		t.Errorf("Expected the calls until the deadline, got %d", res.Requests)
+	}
+}
@@ -0,0 +1,97 @@
+package loadtest
+
+import (
+	"time"
+
+	"go-micro.dev/v4/client"
+)
+
+var (
+	// DefaultDuration is how long a run lasts without Duration or Requests.
+	DefaultDuration = 10 * time.Second
+	// DefaultConcurrency is the maximum number of calls in flight.
+	DefaultConcurrency = 16
+)
+
+// Options configure a run.
+type Options struct {
+	// Rate is the number of calls started per second. Zero runs a closed
+	// loop, every worker starting a call once the last one returned
+	Rate int
+	// Duration is how long calls are started for. Defaults to
+	// DefaultDuration unless Requests is set
+	Duration time.Duration
+	// Requests stops the run after this many calls
+	Requests int
+	// Concurrency bounds the calls in flight. Defaults to DefaultConcurrency
+	Concurrency int
+	// Warmup is how long calls are made before they're recorded, so
+	// connection pools and caches fill first
+	Warmup time.Duration
+	// CallOptions are passed to every call, e.g. client.WithRequestTimeout
+	CallOptions []client.CallOption
+}
+
+// Option sets an option.
+type Option func(*Options)
+
+// Rate sets the calls started per second.
+func Rate(rps int) Option {
+	return func(o *Options) {
+		o.Rate = rps
+	}
+}
+
+// Duration sets how long calls are started for.
+func Duration(d time.Duration) Option {
+	return func(o *Options) {
+		o.Duration = d
+	}
+}
+
+// Requests stops the run after n calls.
+func Requests(n int) Option {
+	return func(o *Options) {
+		o.Requests = n
+	}
+}
+
+// Concurrency bounds the calls in flight.
+func Concurrency(n int) Option {
+	return func(o *Options) {
+		o.Concurrency = n
+	}
+}
+
+// Warmup makes calls for d before recording them.
+func Warmup(d time.Duration) Option {
+	return func(o *Options) {
+		o.Warmup = d
+	}
+}
+
+// CallOptions passes the options to every call.
+func CallOptions(opts ...client.CallOption) Option {
+	return func(o *Options) {
+		o.CallOptions = append(o.CallOptions, opts...)
+	}
+}
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		Concurrency: DefaultConcurrency,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if options.Duration == 0 && options.Requests == 0 {
+		options.Duration = DefaultDuration
+	}
+	if options.Concurrency <= 0 {
+		options.Concurrency = 1
+	}
+
+	return options
+}
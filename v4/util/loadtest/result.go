@@ -0,0 +1,174 @@
+package loadtest
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrorStat counts the errors of a run with the same id and code.
+type ErrorStat struct {
+	// Id is the id of the micro error, e.g. go.micro.client for errors of
+	// the client such as a service not found
+	Id     string
+	Code   int32
+	Status string
+	Count  int
+	// Detail is the detail of the first error
+	Detail string
+}
+
+// Result is the outcome of a run.
+type Result struct {
+	// Requests is the number of calls recorded
+	Requests int
+	// Errors is the number of calls which failed
+	Errors int
+	// Duration is the time from the end of the warmup to the last call
+	// returning
+	Duration time.Duration
+	// Rate is the number of calls completed per second
+	Rate float64
+	// Latencies are the latencies of the successful calls, sorted
+	Latencies []time.Duration
+	// ErrorStats break the errors down by id and code, most frequent first
+	ErrorStats []ErrorStat
+}
+
+func newResult(from time.Time, samples []sample) *Result {
+	r := &Result{Requests: len(samples)}
+	stats := map[string]*ErrorStat{}
+
+	var last time.Time
+	for _, s := range samples {
+		if s.done.After(last) {
+			last = s.done
+		}
+		if s.err == nil {
+			r.Latencies = append(r.Latencies, s.latency)
+			continue
+		}
+
+		r.Errors++
+		e := classify(s.err)
+		key := fmt.Sprintf("%s/%d/%s", e.Id, e.Code, e.Status)
+		if st, ok := stats[key]; ok {
+			st.Count++
+			continue
+		}
+		e.Count = 1
+		stats[key] = &e
+	}
+
+	sort.Slice(r.Latencies, func(i, j int) bool { return r.Latencies[i] < r.Latencies[j] })
+
+	for _, st := range stats {
+		r.ErrorStats = append(r.ErrorStats, *st)
+	}
+	sort.Slice(r.ErrorStats, func(i, j int) bool {
+		if r.ErrorStats[i].Count != r.ErrorStats[j].Count {
+			return r.ErrorStats[i].Count > r.ErrorStats[j].Count
+		}
+		return r.ErrorStats[i].Code < r.ErrorStats[j].Code
+	})
+
+	if last.After(from) {
+		r.Duration = last.Sub(from)
+		r.Rate = float64(r.Requests) / r.Duration.Seconds()
+	}
+	return r
+}
+
+// Percentile returns the latency below which p percent of the successful
+// calls completed, e.g. 99 for the p99. Zero without successful calls.
+func (r *Result) Percentile(p float64) time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p/100*float64(len(r.Latencies)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(r.Latencies) {
+		i = len(r.Latencies) - 1
+	}
+	return r.Latencies[i]
+}
+
+// Mean returns the mean latency of the successful calls.
+func (r *Result) Mean() time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, l := range r.Latencies {
+		sum += l
+	}
+	return sum / time.Duration(len(r.Latencies))
+}
+
+// ErrorRate returns the fraction of the calls which failed.
+func (r *Result) ErrorRate() float64 {
+	if r.Requests == 0 {
+		return 0
+	}
+	return float64(r.Errors) / float64(r.Requests)
+}
+
+// String returns a summary of the result, for test logs.
+func (r *Result) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "requests %d, errors %d (%.2f%%), %.1f/s over %s\n",
+		r.Requests, r.Errors, r.ErrorRate()*100, r.Rate, r.Duration.Round(time.Millisecond))
+	fmt.Fprintf(&b, "latency mean %s, p50 %s, p90 %s, p99 %s, p99.9 %s, max %s\n",
+		r.Mean(), r.Percentile(50), r.Percentile(90), r.Percentile(99), r.Percentile(99.9), r.Percentile(100))
+	for _, e := range r.ErrorStats {
+		fmt.Fprintf(&b, "%6d x %d %s %s: %s\n", e.Count, e.Code, e.Status, e.Id, e.Detail)
+	}
+	return b.String()
+}
+
+// Threshold checks a result, returning why it's not met.
+type Threshold func(*Result) error
+
+// Check returns the error of the first threshold not met.
+func (r *Result) Check(thresholds ...Threshold) error {
+	for _, t := range thresholds {
+		if err := t(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MaxPercentile fails when the latency of the percentile exceeds d.
+func MaxPercentile(p float64, d time.Duration) Threshold {
+	return func(r *Result) error {
+		if l := r.Percentile(p); l > d {
+			return fmt.Errorf("p%g latency %s exceeds %s", p, l, d)
+		}
+		return nil
+	}
+}
+
+// MaxErrorRate fails when more than the fraction of the calls failed.
+func MaxErrorRate(f float64) Threshold {
+	return func(r *Result) error {
+		if e := r.ErrorRate(); e > f {
+			return fmt.Errorf("error rate %.4f exceeds %.4f", e, f)
+		}
+		return nil
+	}
+}
+
+// MinRate fails when fewer calls than rps completed per second.
+func MinRate(rps float64) Threshold {
+	return func(r *Result) error {
+		if r.Rate < rps {
+			return fmt.Errorf("rate %.1f/s below %.1f/s", r.Rate, rps)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,87 @@
+package records
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-micro.dev/v4/store"
+)
+
+// ExpiryMetadataKey is the metadata key of the expiry of records stored as
+// objects, in unix seconds, for stores which can't expire them server side.
+const ExpiryMetadataKey = "microexpiry"
+
+// Names returns the database and table of an operation, those of the store
+// options if unset.
+func Names(o store.Options, database, table string) (string, string) {
+	if len(database) == 0 {
+		database = o.Database
+	}
+	if len(table) == 0 {
+		table = o.Table
+	}
+	return database, table
+}
+
+// ObjectNames returns the bucket or container of the database of an
+// operation and the key prefix of its table, for stores keeping the tables
+// of a database in one namespace. The name is lower cased with the
+// characters matching invalid replaced by hyphens.
+func ObjectNames(o store.Options, database, table string, invalid *regexp.Regexp) (string, string) {
+	database, table = Names(o, database, table)
+
+	prefix := ""
+	if len(table) > 0 {
+		prefix = table + "/"
+	}
+	return invalid.ReplaceAllString(strings.ToLower(database), "-"), prefix
+}
+
+// TTL returns how long a written record lives, set by the TTL or Expiry of
+// the write options or else the expiry of the record. Zero if it doesn't
+// expire.
+func TTL(expiry time.Duration, o store.WriteOptions) time.Duration {
+	if o.TTL > 0 {
+		return o.TTL
+	}
+	if !o.Expiry.IsZero() {
+		return time.Until(o.Expiry)
+	}
+	return expiry
+}
+
+// Metadata returns the value of the key in object metadata, ignoring case
+// as HTTP APIs return the keys in canonical header case.
+func Metadata(m map[string]*string, key string) (string, bool) {
+	for k, v := range m {
+		if strings.EqualFold(k, key) && v != nil {
+			return *v, true
+		}
+	}
+	return "", false
+}
+
+// SetExpiry sets the expiry of a record written now which lives for the
+// ttl in object metadata, if it expires.
+func SetExpiry(m map[string]*string, ttl time.Duration, now time.Time) {
+	if ttl > 0 {
+		v := strconv.FormatInt(now.Add(ttl).Unix(), 10)
+		m[ExpiryMetadataKey] = &v
+	}
+}
+
+// Expiry returns the time left until the expiry set in object metadata,
+// false if it has none.
+func Expiry(m map[string]*string, now time.Time) (time.Duration, bool) {
+	v, ok := Metadata(m, ExpiryMetadataKey)
+	if !ok {
+		return 0, false
+	}
+	at, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return time.Unix(at, 0).Sub(now), true
+}
@@ -0,0 +1,18 @@
+// Package records provides the helpers shared by the store plugins: paging
+// of the records and keys they filter client side, and the watch API of the
+// stores which can watch the changes to their records.
+package records
+
+// Bounds returns the slice bounds of the offset and limit of n records, for
+// stores applying them after filtering, e.g. because suffixes or expiries
+// can't be queried. A limit of zero returns every record after the offset.
+func Bounds(n int, offset, limit uint) (int, int) {
+	if offset >= uint(n) {
+		return n, n
+	}
+	end := uint(n)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	return int(offset), int(end)
+}
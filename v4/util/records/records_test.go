@@ -0,0 +1,129 @@
+package records
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/store"
+)
+
+func TestBounds(t *testing.T) {
+	tests := []struct {
+		n             int
+		offset, limit uint
+		start, end    int
+	}{
+		{0, 0, 0, 0, 0},
+		{0, 1, 1, 0, 0},
+		{5, 0, 0, 0, 5},
+		{5, 1, 2, 1, 3},
+		{5, 0, 5, 0, 5},
+		{5, 4, 10, 4, 5},
+		{5, 5, 1, 5, 5},
+		{5, 6, 1, 5, 5},
+	}
+	for _, tt := range tests {
+		start, end := Bounds(tt.n, tt.offset, tt.limit)
+		if start != tt.start || end != tt.end {
+			t.Errorf("Bounds(%d, %d, %d) = %d, %d, want %d, %d", tt.n, tt.offset, tt.limit, start, end, tt.start, tt.end)
+		}
+	}
+}
+
+func TestObjectNames(t *testing.T) {
+	o := store.Options{Database: "My_Service", Table: "users"}
+	invalid := regexp.MustCompile("[^a-z0-9-]+")
+
+	bucket, prefix := ObjectNames(o, "", "", invalid)
+	if bucket != "my-service" || prefix != "users/" {
+		t.Errorf("Expected my-service and users/, got %s and %s", bucket, prefix)
+	}
+
+	if bucket, prefix := ObjectNames(o, "db", "orders", invalid); bucket != "db" || prefix != "orders/" {
+		t.Errorf("Expected db and orders/, got %s and %s", bucket, prefix)
+	}
+
+	if _, prefix := ObjectNames(store.Options{Database: "micro"}, "", "", invalid); prefix != "" {
+		t.Errorf("Expected no prefix without a table, got %s", prefix)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	if d := TTL(time.Minute, store.WriteOptions{}); d != time.Minute {
+		t.Errorf("Expected the expiry of the record, got %v", d)
+	}
+	if d := TTL(time.Minute, store.WriteOptions{TTL: time.Hour}); d != time.Hour {
+		t.Errorf("Expected the ttl of the write, got %v", d)
+	}
+	if d := TTL(time.Minute, store.WriteOptions{Expiry: time.Now().Add(time.Hour)}); d <= time.Minute || d > time.Hour {
+		t.Errorf("Expected the expiry of the write, got %v", d)
+	}
+	if d := TTL(0, store.WriteOptions{}); d != 0 {
+		t.Errorf("Expected no expiry, got %v", d)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	now := time.Now()
+
+	m := make(map[string]*string)
+	SetExpiry(m, 0, now)
+	if len(m) != 0 {
+		t.Errorf("Expected no expiry to be set, got %v", m)
+	}
+
+	// keys come back in canonical header case
+	at := strconv.FormatInt(now.Add(time.Minute).Unix(), 10)
+	d, ok := Expiry(map[string]*string{"Microexpiry": &at}, now)
+	if !ok || d <= 0 || d > time.Minute {
+		t.Errorf("Expected an expiry within a minute, got %v %v", d, ok)
+	}
+
+	SetExpiry(m, time.Hour, now)
+	if d, ok := Expiry(m, now); !ok || d <= 59*time.Minute || d > time.Hour {
+		t.Errorf("Expected an expiry within an hour, got %v %v", d, ok)
+	}
+
+	if _, ok := Expiry(map[string]*string{}, now); ok {
+		t.Error("Expected no expiry")
+	}
+
+	invalid := "soon"
+	if _, ok := Expiry(map[string]*string{ExpiryMetadataKey: &invalid}, now); ok {
+		t.Error("Expected no expiry of an invalid value")
+	}
+}
+
+func TestWatchOptions(t *testing.T) {
+	o := NewWatchOptions(
+		WatchFrom("db", "table"),
+		WatchKey("key"),
+		WatchPrefix("prefix/"),
+		WatchInitial(),
+		WatchResumeAfter([]byte("token")),
+	)
+
+	if o.Database != "db" || o.Table != "table" {
+		t.Errorf("Unexpected database and table %s %s", o.Database, o.Table)
+	}
+	if o.Key != "key" || o.Prefix != "prefix/" {
+		t.Errorf("Unexpected key and prefix %s %s", o.Key, o.Prefix)
+	}
+	if !o.Initial {
+		t.Error("Expected initial to be set")
+	}
+	if !bytes.Equal(o.ResumeAfter, []byte("token")) {
+		t.Errorf("Unexpected resume token %s", o.ResumeAfter)
+	}
+}
+
+func TestEventType(t *testing.T) {
+	for typ, s := range map[EventType]string{Create: "create", Update: "update", Delete: "delete", Put: "put"} {
+		if typ.String() != s {
+			t.Errorf("Expected %s, got %s", s, typ)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package records
+
+import (
+	"errors"
+
+	"go-micro.dev/v4/store"
+)
+
+// ErrWatcherStopped is returned by Next once the watcher is stopped.
+var ErrWatcherStopped = errors.New("watcher stopped")
+
+// EventType is the type of a change to a record.
+type EventType int
+
+const (
+	// Create is a record written for the first time.
+	Create EventType = iota
+	// Update is a record written again.
+	Update
+	// Delete is a record deleted or expired.
+	Delete
+	// Put is a record written, by stores which can't tell creates from
+	// updates.
+	Put
+)
+
+func (t EventType) String() string {
+	switch t {
+	case Create:
+		return "create"
+	case Update:
+		return "update"
+	case Put:
+		return "put"
+	default:
+		return "delete"
+	}
+}
+
+// Event is a change to a record. Records of deletes may only have their
+// key.
+type Event struct {
+	Type   EventType
+	Record *store.Record
+	// Revision orders the changes to a key, for stores reporting it.
+	Revision uint64
+	// Token resumes a watch after the event, for stores supporting it, see
+	// WatchResumeAfter.
+	Token []byte
+}
+
+// Watcher receives the changes to the records of a table.
+type Watcher interface {
+	// Next blocks until the next change.
+	Next() (*Event, error)
+	Stop()
+}
+
+// WatchOptions select the records to watch. Stores document which options
+// they support.
+type WatchOptions struct {
+	Database, Table string
+	// Key only watches the key.
+	Key string
+	// Prefix only watches keys with the prefix.
+	Prefix string
+	// Initial starts with an event of each current record.
+	Initial bool
+	// ResumeAfter starts the watch after the event of the token.
+	ResumeAfter []byte
+}
+
+// WatchOption sets values in WatchOptions.
+type WatchOption func(o *WatchOptions)
+
+// NewWatchOptions returns the options with the values set.
+func NewWatchOptions(opts ...WatchOption) WatchOptions {
+	var options WatchOptions
+	for _, o := range opts {
+		o(&options)
+	}
+	return options
+}
+
+// WatchFrom sets the database and table to watch.
+func WatchFrom(database, table string) WatchOption {
+	return func(o *WatchOptions) {
+		o.Database = database
+		o.Table = table
+	}
+}
+
+// WatchKey only watches the key.
+func WatchKey(k string) WatchOption {
+	return func(o *WatchOptions) {
+		o.Key = k
+	}
+}
+
+// WatchPrefix only watches keys with the prefix.
+func WatchPrefix(p string) WatchOption {
+	return func(o *WatchOptions) {
+		o.Prefix = p
+	}
+}
+
+// WatchInitial starts the watch with an event of each current record, to
+// load state and keep it up to date without missing a change in between.
+func WatchInitial() WatchOption {
+	return func(o *WatchOptions) {
+		o.Initial = true
+	}
+}
+
+// WatchResumeAfter resumes a watch after the event of the token, so no
+// change is missed while a watcher restarts.
+func WatchResumeAfter(token []byte) WatchOption {
+	return func(o *WatchOptions) {
+		o.ResumeAfter = token
+	}
+}
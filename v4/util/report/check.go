@@ -0,0 +1,116 @@
+package report
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"go-micro.dev/v4"
+)
+
+// Check finds a misconfiguration in the report or options of a service,
+// returning a description of it or nil.
+type Check struct {
+	Name  string
+	Check func(r *Report, o micro.Options) error
+}
+
+// DefaultChecks are the checks run by default.
+var DefaultChecks = []Check{
+	{Name: "local-plugin", Check: checkLocalPlugins},
+	{Name: "insecure-address", Check: checkInsecureAddress},
+	{Name: "register-ttl", Check: checkRegisterTTL},
+}
+
+// prod reports whether the profile is a production one.
+func prod(profile string) bool {
+	p := strings.ToLower(profile)
+	return p == "prod" || p == "production"
+}
+
+// local plugins only work within a process or host
+var localPlugins = map[string][]string{
+	"registry": {"memory", "mdns"},
+	"broker":   {"memory", "http"},
+	"store":    {"memory"},
+}
+
+func checkLocalPlugins(r *Report, o micro.Options) error {
+	if !prod(r.Profile) {
+		return nil
+	}
+
+	var local []string
+	for kind, names := range localPlugins {
+		p := r.Plugin(kind)
+		if p == nil {
+			continue
+		}
+		for _, n := range names {
+			if p.Name == n {
+				local = append(local, n+" "+kind)
+			}
+		}
+	}
+	if len(local) == 0 {
+		return nil
+	}
+	sort.Strings(local)
+	return fmt.Errorf("%s in %s profile, instances won't discover or reach each other across hosts", strings.Join(local, ", "), r.Profile)
+}
+
+func checkInsecureAddress(r *Report, o micro.Options) error {
+	if o.Server == nil {
+		return nil
+	}
+	so := o.Server.Options()
+	if so.TLSConfig != nil {
+		return nil
+	}
+	if t := so.Transport; t != nil && (t.Options().Secure || t.Options().TLSConfig != nil) {
+		return nil
+	}
+
+	addr := so.Address
+	if len(so.Advertise) > 0 {
+		addr = so.Advertise
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil
+	}
+
+	ip := net.ParseIP(host)
+	switch {
+	case len(host) == 0 || (ip != nil && ip.IsUnspecified()):
+		// all interfaces, public if the host is
+		if !prod(r.Profile) {
+			return nil
+		}
+		return fmt.Errorf("server listens on all interfaces at %s without TLS in %s profile", addr, r.Profile)
+	case ip == nil:
+		// a host name
+		return nil
+	case ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast():
+		return nil
+	}
+	return fmt.Errorf("server address %s is public but TLS isn't configured", addr)
+}
+
+func checkRegisterTTL(r *Report, o micro.Options) error {
+	if o.Server == nil {
+		return nil
+	}
+	so := o.Server.Options()
+	if so.RegisterTTL == 0 {
+		return nil
+	}
+	if so.RegisterInterval == 0 {
+		return fmt.Errorf("register TTL %v without register interval, the service expires from the registry after it", so.RegisterTTL)
+	}
+	if so.RegisterInterval >= so.RegisterTTL {
+		return fmt.Errorf("register interval %v isn't shorter than the register TTL %v, the service drops out of the registry between registrations", so.RegisterInterval, so.RegisterTTL)
+	}
+	return nil
+}
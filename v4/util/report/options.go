@@ -0,0 +1,64 @@
+package report
+
+import (
+	"os"
+
+	"go-micro.dev/v4/logger"
+)
+
+// Options configure the report.
+type Options struct {
+	// Profile is the deployment profile checks take into account, e.g.
+	// prod. Defaults to $MICRO_PROFILE
+	Profile string
+	// Checks warn about misconfigurations. Defaults to DefaultChecks
+	Checks []Check
+	// Logger logs the report. Defaults to the logger of the service
+	Logger logger.Logger
+	// Strict fails the start of the service on warnings
+	Strict bool
+}
+
+// Option sets an option.
+type Option func(*Options)
+
+// Profile sets the deployment profile.
+func Profile(p string) Option {
+	return func(o *Options) {
+		o.Profile = p
+	}
+}
+
+// Checks adds checks to the default ones.
+func Checks(c ...Check) Option {
+	return func(o *Options) {
+		o.Checks = append(o.Checks, c...)
+	}
+}
+
+// Logger sets the logger of the report.
+func Logger(l logger.Logger) Option {
+	return func(o *Options) {
+		o.Logger = l
+	}
+}
+
+// Strict fails the start of the service if a check warned.
+func Strict() Option {
+	return func(o *Options) {
+		o.Strict = true
+	}
+}
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		Profile: os.Getenv("MICRO_PROFILE"),
+		Checks:  append([]Check{}, DefaultChecks...),
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return options
+}
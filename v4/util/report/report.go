@@ -0,0 +1,252 @@
+// Package report logs a summary of the plugins a service runs with and
+// their effective options at startup, and warns about misconfigurations,
+// such as a memory registry in production.
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go-micro.dev/v4"
+	"go-micro.dev/v4/logger"
+)
+
+// Plugin is a plugin the service runs with and its options.
+type Plugin struct {
+	Kind    string            `json:"kind"`
+	Name    string            `json:"name"`
+	Options map[string]string `json:"options,omitempty"`
+}
+
+// Warning is a misconfiguration found by a check.
+type Warning struct {
+	Check   string `json:"check"`
+	Message string `json:"message"`
+}
+
+// Report is the summary of a service at startup.
+type Report struct {
+	Service  string    `json:"service"`
+	Version  string    `json:"version"`
+	Id       string    `json:"id"`
+	Address  string    `json:"address"`
+	Profile  string    `json:"profile,omitempty"`
+	Time     time.Time `json:"time"`
+	Plugins  []Plugin  `json:"plugins"`
+	Warnings []Warning `json:"warnings,omitempty"`
+}
+
+// Plugin returns the plugin of the kind, e.g. registry.
+func (r *Report) Plugin(kind string) *Plugin {
+	for i := range r.Plugins {
+		if r.Plugins[i].Kind == kind {
+			return &r.Plugins[i]
+		}
+	}
+	return nil
+}
+
+// Reporter reports the startup of a service.
+type Reporter struct {
+	service micro.Service
+	opts    Options
+
+	sync.RWMutex
+	last *Report
+}
+
+// New returns the reporter of the service. Run it after the service was
+// initialised, so the options are merged with the flags and environment:
+//
+//	r := report.New(service)
+//	service.Init(micro.AfterStart(r.Run))
+func New(s micro.Service, opts ...Option) *Reporter {
+	return &Reporter{
+		service: s,
+		opts:    newOptions(opts...),
+	}
+}
+
+// Run builds and logs the report. It has the signature of a micro.AfterStart
+// hook and fails if a check warned and the reporter is strict.
+func (r *Reporter) Run() error {
+	rep := Build(r.service.Options(), r.opts.Profile, r.opts.Checks...)
+
+	r.Lock()
+	r.last = rep
+	r.Unlock()
+
+	log := r.opts.Logger
+	if log == nil {
+		log = r.service.Options().Logger
+	}
+	if log == nil {
+		log = logger.DefaultLogger
+	}
+
+	fields := map[string]interface{}{
+		"service": rep.Service,
+		"version": rep.Version,
+		"address": rep.Address,
+	}
+	if len(rep.Profile) > 0 {
+		fields["profile"] = rep.Profile
+	}
+	for _, p := range rep.Plugins {
+		fields[p.Kind] = p.Name
+	}
+	log.Fields(fields).Log(logger.InfoLevel, "Startup report")
+
+	for _, p := range rep.Plugins {
+		if len(p.Options) > 0 {
+			log.Logf(logger.DebugLevel, "Startup report %s [%s] %s", p.Kind, p.Name, formatOptions(p.Options))
+		}
+	}
+	for _, w := range rep.Warnings {
+		log.Logf(logger.WarnLevel, "Startup report %s: %s", w.Check, w.Message)
+	}
+
+	if r.opts.Strict && len(rep.Warnings) > 0 {
+		return fmt.Errorf("startup report: %d misconfigurations, first: %s", len(rep.Warnings), rep.Warnings[0].Message)
+	}
+	return nil
+}
+
+// Report returns the last report built by Run, or nil.
+func (r *Reporter) Report() *Report {
+	r.RLock()
+	defer r.RUnlock()
+	return r.last
+}
+
+// Handler serves the last report as JSON, e.g. on an admin port.
+func (r *Reporter) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		rep := r.Report()
+		if rep == nil {
+			http.Error(w, errors.New("service not started").Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rep)
+	})
+}
+
+func formatOptions(o map[string]string) string {
+	keys := make([]string, 0, len(o))
+	for k := range o {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+o[k])
+	}
+	return strings.Join(parts, " ")
+}
+
+// Build returns the report of the options of a service, with the warnings
+// of the checks.
+func Build(o micro.Options, profile string, checks ...Check) *Report {
+	rep := &Report{
+		Profile: profile,
+		Time:    time.Now(),
+	}
+
+	if o.Server != nil {
+		so := o.Server.Options()
+		rep.Service = so.Name
+		rep.Version = so.Version
+		rep.Id = so.Id
+		rep.Address = so.Address
+		rep.Plugins = append(rep.Plugins, Plugin{Kind: "server", Name: o.Server.String(), Options: map[string]string{
+			"address":           so.Address,
+			"advertise":         so.Advertise,
+			"register_ttl":      duration(so.RegisterTTL),
+			"register_interval": duration(so.RegisterInterval),
+			"tls":               fmt.Sprint(so.TLSConfig != nil),
+		}})
+	}
+	if o.Client != nil {
+		co := o.Client.Options()
+		rep.Plugins = append(rep.Plugins, Plugin{Kind: "client", Name: o.Client.String(), Options: map[string]string{
+			"content_type":    co.ContentType,
+			"pool_size":       fmt.Sprint(co.PoolSize),
+			"pool_ttl":        duration(co.PoolTTL),
+			"retries":         fmt.Sprint(co.CallOptions.Retries),
+			"request_timeout": duration(co.CallOptions.RequestTimeout),
+		}})
+	}
+	if o.Registry != nil {
+		ro := o.Registry.Options()
+		rep.Plugins = append(rep.Plugins, Plugin{Kind: "registry", Name: o.Registry.String(), Options: map[string]string{
+			"addrs":   addrs(ro.Addrs),
+			"timeout": duration(ro.Timeout),
+			"secure":  fmt.Sprint(ro.Secure || ro.TLSConfig != nil),
+		}})
+	}
+	if o.Broker != nil {
+		bo := o.Broker.Options()
+		rep.Plugins = append(rep.Plugins, Plugin{Kind: "broker", Name: o.Broker.String(), Options: map[string]string{
+			"addrs":  addrs(bo.Addrs),
+			"secure": fmt.Sprint(bo.Secure || bo.TLSConfig != nil),
+		}})
+	}
+	if o.Transport != nil {
+		to := o.Transport.Options()
+		rep.Plugins = append(rep.Plugins, Plugin{Kind: "transport", Name: o.Transport.String(), Options: map[string]string{
+			"addrs":   addrs(to.Addrs),
+			"timeout": duration(to.Timeout),
+			"secure":  fmt.Sprint(to.Secure || to.TLSConfig != nil),
+		}})
+	}
+	if o.Store != nil {
+		so := o.Store.Options()
+		rep.Plugins = append(rep.Plugins, Plugin{Kind: "store", Name: o.Store.String(), Options: map[string]string{
+			"nodes":    addrs(so.Nodes),
+			"database": so.Database,
+			"table":    so.Table,
+		}})
+	}
+	if o.Auth != nil {
+		rep.Plugins = append(rep.Plugins, Plugin{Kind: "auth", Name: o.Auth.String()})
+	}
+
+	for _, c := range checks {
+		if w := c.Check(rep, o); w != nil {
+			rep.Warnings = append(rep.Warnings, Warning{Check: c.Name, Message: w.Error()})
+		}
+	}
+
+	return rep
+}
+
+func duration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// addrs joins the addresses with the passwords of URLs redacted.
+func addrs(a []string) string {
+	redacted := make([]string, len(a))
+	for i, addr := range a {
+		redacted[i] = addr
+		if u, err := url.Parse(addr); err == nil && u.User != nil {
+			if _, ok := u.User.Password(); ok {
+				u.User = url.UserPassword(u.User.Username(), "xxxxx")
+				redacted[i] = u.String()
+			}
+		}
+	}
+	return strings.Join(redacted, ",")
+}
@@ -0,0 +1,125 @@
+// Package session provides read-your-writes sessions across the store
+// plugins which read from replicas. A session records the position of the
+// writes made within a request chain, e.g. the replication offset of redis
+// or the operation time of mongo, and stores read from a replica only once
+// it has caught up with them, from the primary otherwise. The session
+// travels with the request in the Micro-Session metadata.
+package session
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+
+	"go-micro.dev/v4/metadata"
+)
+
+// MetadataKey is the metadata key of the session token.
+const MetadataKey = "Micro-Session"
+
+// Session is the positions of the writes within a request chain, by the
+// key of the store written to. It's safe for concurrent use.
+type Session struct {
+	sync.RWMutex
+	positions map[string]uint64
+}
+
+type sessionKey struct{}
+
+// New returns an empty session.
+func New() *Session {
+	return &Session{positions: make(map[string]uint64)}
+}
+
+// Parse returns the session of the token.
+func Parse(token string) (*Session, error) {
+	v, err := url.ParseQuery(token)
+	if err != nil {
+		return nil, err
+	}
+
+	s := New()
+	for k := range v {
+		pos, err := strconv.ParseUint(v.Get(k), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		s.positions[k] = pos
+	}
+	return s, nil
+}
+
+// Advance records a write to the store of the key at the position. The
+// session keeps the latest position of every store.
+func (s *Session) Advance(key string, pos uint64) {
+	s.Lock()
+	if pos > s.positions[key] {
+		s.positions[key] = pos
+	}
+	s.Unlock()
+}
+
+// Position returns the position of the latest write to the store of the
+// key, or false if the session hasn't written to it.
+func (s *Session) Position(key string) (uint64, bool) {
+	s.RLock()
+	pos, ok := s.positions[key]
+	s.RUnlock()
+	return pos, ok
+}
+
+// Token returns the token of the session, passed on to the services called
+// within the session.
+func (s *Session) Token() string {
+	s.RLock()
+	defer s.RUnlock()
+
+	keys := make([]string, 0, len(s.positions))
+	for k := range s.positions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	v := make(url.Values, len(keys))
+	for _, k := range keys {
+		v.Set(k, strconv.FormatUint(s.positions[k], 10))
+	}
+	return v.Encode()
+}
+
+// NewContext returns a context with the session.
+func NewContext(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+// FromContext returns the session of the context, or the session of the
+// token in its metadata, as services called within a session receive it.
+func FromContext(ctx context.Context) (*Session, bool) {
+	if s, ok := ctx.Value(sessionKey{}).(*Session); ok {
+		return s, true
+	}
+	token, ok := metadata.Get(ctx, MetadataKey)
+	if !ok || len(token) == 0 {
+		return nil, false
+	}
+	s, err := Parse(token)
+	if err != nil {
+		return nil, false
+	}
+	return s, true
+}
+
+// Context returns a context with the session of the context, or with a new
+// one if it has none.
+func Context(ctx context.Context) (context.Context, *Session) {
+	if s, ok := ctx.Value(sessionKey{}).(*Session); ok {
+		return ctx, s
+	}
+	s, ok := FromContext(ctx)
+	if !ok {
+		s = New()
+	}
+	return NewContext(ctx, s), s
+}
@@ -0,0 +1,55 @@
+package session
+
+import (
+	"context"
+	"testing"
+
+	"go-micro.dev/v4/metadata"
+)
+
+func TestSession(t *testing.T) {
+	s := New()
+	s.Advance("redis/primary:6379", 100)
+	s.Advance("redis/primary:6379", 50)
+	s.Advance("mongo/micro", 7)
+
+	if pos, ok := s.Position("redis/primary:6379"); !ok || pos != 100 {
+		t.Fatalf("unexpected position %d %v", pos, ok)
+	}
+	if _, ok := s.Position("cockroach/micro"); ok {
+		t.Fatal("unexpected position of a store not written to")
+	}
+
+	p, err := Parse(s.Token())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Token() != s.Token() {
+		t.Fatalf("token %q parsed to %q", s.Token(), p.Token())
+	}
+}
+
+func TestContext(t *testing.T) {
+	if _, ok := FromContext(context.Background()); ok {
+		t.Fatal("unexpected session")
+	}
+
+	ctx, s := Context(context.Background())
+	s.Advance("mongo/micro", 7)
+	if _, s2 := Context(ctx); s2 != s {
+		t.Fatal("expected the session of the context")
+	}
+
+	// a called service receives the session in the metadata
+	token, ok := metadata.Get(withToken(ctx), MetadataKey)
+	if !ok {
+		t.Fatal("no token in the metadata")
+	}
+	called, ok := FromContext(metadata.NewContext(context.Background(), metadata.Metadata{MetadataKey: token}))
+	if !ok {
+		t.Fatal("no session from the metadata")
+	}
+	if pos, _ := called.Position("mongo/micro"); pos != 7 {
+		t.Fatalf("unexpected position %d", pos)
+	}
+}
@@ -0,0 +1,65 @@
+package session
+
+import (
+	"context"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/metadata"
+	"go-micro.dev/v4/server"
+)
+
+type sessionClient struct {
+	client.Client
+}
+
+// withToken sets the token of the session of the context in its metadata,
+// so the services called read the writes made within the session.
+func withToken(ctx context.Context) context.Context {
+	s, ok := ctx.Value(sessionKey{}).(*Session)
+	if !ok {
+		return ctx
+	}
+	return metadata.Set(ctx, MetadataKey, s.Token())
+}
+
+func (c *sessionClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	return c.Client.Call(withToken(ctx), req, rsp, opts...)
+}
+
+func (c *sessionClient) Stream(ctx context.Context, req client.Request, opts ...client.CallOption) (client.Stream, error) {
+	return c.Client.Stream(withToken(ctx), req, opts...)
+}
+
+func (c *sessionClient) Publish(ctx context.Context, p client.Message, opts ...client.PublishOption) error {
+	return c.Client.Publish(withToken(ctx), p, opts...)
+}
+
+// NewClientWrapper passes the session of the context on to the services
+// called, with the writes made within the request so far.
+func NewClientWrapper() client.Wrapper {
+	return func(c client.Client) client.Client {
+		return &sessionClient{c}
+	}
+}
+
+// NewHandlerWrapper starts a session for every request, continuing the
+// session of the caller if it passed one.
+func NewHandlerWrapper() server.HandlerWrapper {
+	return func(h server.HandlerFunc) server.HandlerFunc {
+		return func(ctx context.Context, req server.Request, rsp interface{}) error {
+			ctx, _ = Context(ctx)
+			return h(ctx, req, rsp)
+		}
+	}
+}
+
+// NewSubscriberWrapper starts a session for every message, continuing the
+// session of the publisher if it passed one.
+func NewSubscriberWrapper() server.SubscriberWrapper {
+	return func(fn server.SubscriberFunc) server.SubscriberFunc {
+		return func(ctx context.Context, msg server.Message) error {
+			ctx, _ = Context(ctx)
+			return fn(ctx, msg)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+module github.com/go-micro/plugins/v4/util/socket
+
+go 1.17
+
+require github.com/Microsoft/go-winio v0.5.0
+
+require golang.org/x/sys v0.0.0-20210502180810-71e4cd670f79 // indirect
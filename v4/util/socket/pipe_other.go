@@ -0,0 +1,17 @@
+//go:build !windows
+// +build !windows
+
+package socket
+
+import (
+	"context"
+	"net"
+)
+
+func listenPipe(path string) (net.Listener, error) {
+	return nil, ErrUnsupported
+}
+
+func dialPipe(ctx context.Context, path string) (net.Conn, error) {
+	return nil, ErrUnsupported
+}
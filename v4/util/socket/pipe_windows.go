@@ -0,0 +1,19 @@
+//go:build windows
+// +build windows
+
+package socket
+
+import (
+	"context"
+	"net"
+
+	winio "github.com/Microsoft/go-winio"
+)
+
+func listenPipe(path string) (net.Listener, error) {
+	return winio.ListenPipe(path, nil)
+}
+
+func dialPipe(ctx context.Context, path string) (net.Conn, error) {
+	return winio.DialPipeContext(ctx, path)
+}
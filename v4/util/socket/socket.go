@@ -0,0 +1,114 @@
+// Package socket listens and dials on local sockets, Unix domain sockets
+// and Windows named pipes, addressed by a scheme.
+//
+//	unix:///var/run/svc.sock
+//	npipe:////./pipe/svc
+//
+// Other addresses are TCP addresses.
+package socket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// SchemeUnix is the scheme of Unix domain socket addresses.
+	SchemeUnix = "unix://"
+	// SchemePipe is the scheme of Windows named pipe addresses.
+	SchemePipe = "npipe://"
+)
+
+// ErrUnsupported is returned for named pipes on platforms other than Windows.
+var ErrUnsupported = errors.New("named pipes are only supported on windows")
+
+// Parse returns the network of the address, "unix", "npipe" or "tcp", and
+// the address without its scheme. Named pipe addresses are returned as
+// Windows paths, \\.\pipe\svc.
+func Parse(addr string) (network, address string) {
+	switch {
+	case strings.HasPrefix(addr, SchemeUnix):
+		return "unix", strings.TrimPrefix(addr, SchemeUnix)
+	case strings.HasPrefix(addr, SchemePipe):
+		p := strings.ReplaceAll(strings.TrimPrefix(addr, SchemePipe), "/", `\`)
+		if !strings.HasPrefix(p, `\\`) {
+			p = `\\` + p
+		}
+		return "npipe", p
+	default:
+		return "tcp", addr
+	}
+}
+
+// Local returns whether the address is a Unix domain socket or named pipe.
+func Local(addr string) bool {
+	network, _ := Parse(addr)
+	return network != "tcp"
+}
+
+// Addr returns the address of a listener or connection with its scheme, as
+// accepted by Listen and Dial.
+func Addr(a net.Addr) string {
+	switch a.Network() {
+	case "unix":
+		return SchemeUnix + a.String()
+	case "pipe":
+		return SchemePipe + strings.ReplaceAll(a.String(), `\`, "/")
+	default:
+		return a.String()
+	}
+}
+
+// Listen listens on the address. A stale Unix domain socket left behind
+// by a process which didn't close its listener is removed first.
+func Listen(addr string) (net.Listener, error) {
+	network, address := Parse(addr)
+	switch network {
+	case "unix":
+		if err := removeStale(address); err != nil {
+			return nil, err
+		}
+		return net.Listen("unix", address)
+	case "npipe":
+		return listenPipe(address)
+	default:
+		return net.Listen("tcp", address)
+	}
+}
+
+// Dial connects to the address.
+func Dial(ctx context.Context, addr string) (net.Conn, error) {
+	network, address := Parse(addr)
+	if network == "npipe" {
+		return dialPipe(ctx, address)
+	}
+
+	var d net.Dialer
+	return d.DialContext(ctx, network, address)
+}
+
+// DialTimeout connects to the address, with a timeout.
+func DialTimeout(addr string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return Dial(ctx, addr)
+}
+
+func removeStale(path string) error {
+	fi, err := os.Stat(path)
+	if err != nil || fi.Mode()&os.ModeSocket == 0 {
+		// listening reports paths which exist but aren't sockets
+		return nil
+	}
+
+	if c, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		c.Close()
+		return fmt.Errorf("listen unix %s: address already in use", path)
+	}
+	return os.Remove(path)
+}
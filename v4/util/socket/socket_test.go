@@ -0,0 +1,91 @@
+package socket
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		addr, network, address string
+	}{
+		{"unix:///var/run/svc.sock", "unix", "/var/run/svc.sock"},
+		{"unix://svc.sock", "unix", "svc.sock"},
+		{"npipe:////./pipe/svc", "npipe", `\\.\pipe\svc`},
+		{"npipe://./pipe/svc", "npipe", `\\.\pipe\svc`},
+		{"127.0.0.1:8080", "tcp", "127.0.0.1:8080"},
+		{":0", "tcp", ":0"},
+	}
+
+	for _, tt := range tests {
+		network, address := Parse(tt.addr)
+		if network != tt.network || address != tt.address {
+			t.Errorf("Parse(%q) = %q, %q, want %q, %q", tt.addr, network, address, tt.network, tt.address)
+		}
+		if Local(tt.addr) != (tt.network != "tcp") {
+			t.Errorf("Local(%q) = %v", tt.addr, Local(tt.addr))
+		}
+	}
+}
+
+func TestUnix(t *testing.T) {
+	// socket paths are limited to about 100 bytes, shorter than some temp dirs
+	dir, err := os.MkdirTemp("", "sock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	addr := SchemeUnix + filepath.Join(dir, "svc.sock")
+
+	l, err := Listen(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Addr(l.Addr()); got != addr {
+		t.Fatalf("Addr() = %q, want %q", got, addr)
+	}
+
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		c.Write([]byte("ok"))
+		c.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	c, err := Dial(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 2)
+	if _, err := c.Read(b); err != nil || string(b) != "ok" {
+		t.Fatalf("read %q: %v", b, err)
+	}
+	c.Close()
+
+	if _, err := Listen(addr); err == nil {
+		t.Fatal("listened twice on the socket")
+	}
+	l.Close()
+
+	// a socket left behind is replaced
+	ul, err := net.Listen("unix", filepath.Join(dir, "stale.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ul.(*net.UnixListener).SetUnlinkOnClose(false)
+	ul.Close()
+
+	l, err = Listen(SchemeUnix + filepath.Join(dir, "stale.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+}
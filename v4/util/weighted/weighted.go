@@ -0,0 +1,61 @@
+// Package weighted picks nodes at random in proportion to their weight. The
+// weighted selector strategies of the registries and the load balancer are
+// built on it.
+package weighted
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/selector"
+)
+
+var (
+	mtx sync.Mutex
+	rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// Next returns a selector.Next picking one of the nodes at random, in
+// proportion to the weight returned for it. The weights are asked for on
+// every pick, so they may change between picks. Nodes with a weight of zero
+// or less aren't picked.
+func Next(nodes []*registry.Node, weight func(*registry.Node) float64) selector.Next {
+	return func() (*registry.Node, error) {
+		weights := make([]float64, len(nodes))
+		var total float64
+		for i, n := range nodes {
+			if w := weight(n); w > 0 {
+				weights[i] = w
+				total += w
+			}
+		}
+		if total == 0 {
+			return nil, selector.ErrNoneAvailable
+		}
+
+		r := float64n(total)
+		last := 0
+		for i, w := range weights {
+			if w == 0 {
+				continue
+			}
+			if r < w {
+				return nodes[i], nil
+			}
+			r -= w
+			last = i
+		}
+
+		// rounding may leave the last bit of the total
+		return nodes[last], nil
+	}
+}
+
+// float64n returns a random number in [0, n).
+func float64n(n float64) float64 {
+	mtx.Lock()
+	defer mtx.Unlock()
+	return rnd.Float64() * n
+}
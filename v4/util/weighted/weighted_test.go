@@ -0,0 +1,62 @@
+package weighted
+
+import (
+	"math"
+	"testing"
+
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/selector"
+)
+
+func TestNext(t *testing.T) {
+	nodes := []*registry.Node{{Id: "a"}, {Id: "b"}, {Id: "c"}, {Id: "d"}}
+	weights := map[string]float64{"a": 1, "b": 3, "c": 0, "d": -1}
+
+	next := Next(nodes, func(n *registry.Node) float64 {
+		return weights[n.Id]
+	})
+
+	picks := make(map[string]int)
+	const n = 20000
+	for i := 0; i < n; i++ {
+		node, err := next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		picks[node.Id]++
+	}
+
+	if picks["c"] != 0 || picks["d"] != 0 {
+		t.Fatalf("Expected nodes without a weight not to be picked, got %v", picks)
+	}
+	if share := float64(picks["b"]) / n; math.Abs(share-0.75) > 0.02 {
+		t.Fatalf("Expected b to be picked 75%% of the time, got %.2f", share)
+	}
+}
+
+func TestNextChanged(t *testing.T) {
+	nodes := []*registry.Node{{Id: "a"}, {Id: "b"}}
+	weights := map[string]float64{"a": 1, "b": 0}
+
+	next := Next(nodes, func(n *registry.Node) float64 {
+		return weights[n.Id]
+	})
+
+	if node, err := next(); err != nil || node.Id != "a" {
+		t.Fatalf("Expected a, got %v %v", node, err)
+	}
+
+	weights["a"], weights["b"] = 0, 1
+	if node, err := next(); err != nil || node.Id != "b" {
+		t.Fatalf("Expected the changed weights to pick b, got %v %v", node, err)
+	}
+}
+
+func TestNextNoneAvailable(t *testing.T) {
+	for _, nodes := range [][]*registry.Node{nil, {{Id: "a"}}} {
+		next := Next(nodes, func(*registry.Node) float64 { return 0 })
+		if _, err := next(); err != selector.ErrNoneAvailable {
+			t.Fatalf("Expected no node to be available, got %v", err)
+		}
+	}
+}
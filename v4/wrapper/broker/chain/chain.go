@@ -0,0 +1,79 @@
+// Package chain provides a broker wrapper which applies a chain of
+// interceptors to every publish and handler invocation of any broker.
+package chain
+
+import (
+	"go-micro.dev/v4/broker"
+)
+
+// PublishFunc publishes a message, it has the signature of broker.Publish.
+type PublishFunc func(topic string, m *broker.Message, opts ...broker.PublishOption) error
+
+// PublishWrapper wraps a PublishFunc and returns the equivalent.
+type PublishWrapper func(PublishFunc) PublishFunc
+
+// HandlerWrapper wraps a subscriber's handler and returns the equivalent.
+type HandlerWrapper func(broker.Handler) broker.Handler
+
+// Interceptor wraps both sides of a broker, e.g. to encrypt messages on
+// publish and decrypt them before they're handled.
+type Interceptor interface {
+	Publish(PublishFunc) PublishFunc
+	Handler(broker.Handler) broker.Handler
+}
+
+type chainBroker struct {
+	broker.Broker
+	opts    Options
+	publish PublishFunc
+}
+
+func (c *chainBroker) Publish(topic string, m *broker.Message, opts ...broker.PublishOption) error {
+	return c.publish(topic, m, opts...)
+}
+
+func (c *chainBroker) Subscribe(topic string, h broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	for i := len(c.opts.HandlerWrappers); i > 0; i-- {
+		h = c.opts.HandlerWrappers[i-1](h)
+	}
+	return c.Broker.Subscribe(topic, h, opts...)
+}
+
+func (c *chainBroker) String() string {
+	return c.Broker.String()
+}
+
+type event struct {
+	broker.Event
+	m *broker.Message
+}
+
+func (e *event) Message() *broker.Message {
+	return e.m
+}
+
+// NewEvent returns the event with its message replaced, for handler wrappers
+// transforming the message.
+func NewEvent(e broker.Event, m *broker.Message) broker.Event {
+	return &event{Event: e, m: m}
+}
+
+// NewBroker wraps the broker so every publish and handler passes through the
+// wrappers. The first wrapper is the outermost.
+func NewBroker(b broker.Broker, opts ...Option) broker.Broker {
+	var options Options
+	for _, o := range opts {
+		o(&options)
+	}
+
+	publish := PublishFunc(b.Publish)
+	for i := len(options.PublishWrappers); i > 0; i-- {
+		publish = options.PublishWrappers[i-1](publish)
+	}
+
+	return &chainBroker{
+		Broker:  b,
+		opts:    options,
+		publish: publish,
+	}
+}
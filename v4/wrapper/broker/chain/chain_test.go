@@ -0,0 +1,97 @@
+package chain
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/broker"
+)
+
+// encoder base64 encodes bodies on publish and decodes them before handling.
+type encoder struct{}
+
+func (encoder) Publish(next PublishFunc) PublishFunc {
+	return func(topic string, m *broker.Message, opts ...broker.PublishOption) error {
+		return next(topic, &broker.Message{
+			Header: m.Header,
+			Body:   []byte(base64.StdEncoding.EncodeToString(m.Body)),
+		}, opts...)
+	}
+}
+
+func (encoder) Handler(next broker.Handler) broker.Handler {
+	return func(e broker.Event) error {
+		b, err := base64.StdEncoding.DecodeString(string(e.Message().Body))
+		if err != nil {
+			return err
+		}
+		return next(NewEvent(e, &broker.Message{Header: e.Message().Header, Body: b}))
+	}
+}
+
+func TestChain(t *testing.T) {
+	var calls []string
+
+	trace := func(name string) PublishWrapper {
+		return func(next PublishFunc) PublishFunc {
+			return func(topic string, m *broker.Message, opts ...broker.PublishOption) error {
+				calls = append(calls, name)
+				return next(topic, m, opts...)
+			}
+		}
+	}
+
+	var raw []byte
+	mb := broker.NewMemoryBroker()
+	b := NewBroker(mb,
+		WrapPublish(trace("first"), trace("second")),
+		WrapHandler(func(next broker.Handler) broker.Handler {
+			return func(e broker.Event) error {
+				raw = e.Message().Body
+				return next(e)
+			}
+		}),
+		Intercept(encoder{}),
+	)
+
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	done := make(chan broker.Event, 1)
+
+	sub, err := b.Subscribe("orders", func(e broker.Event) error {
+		done <- e
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	if err := b.Publish("orders", &broker.Message{Body: []byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case e := <-done:
+		if string(e.Message().Body) != "hello" {
+			t.Fatalf("Expected decoded body, got %s", e.Message().Body)
+		}
+		if e.Topic() != "orders" {
+			t.Fatalf("Expected topic orders, got %s", e.Topic())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the event")
+	}
+
+	if strings.Join(calls, ",") != "first,second" {
+		t.Fatalf("Expected wrappers in order, got %v", calls)
+	}
+	if string(raw) != base64.StdEncoding.EncodeToString([]byte("hello")) {
+		t.Fatalf("Expected outer handler wrapper to see the encoded body, got %s", raw)
+	}
+}
@@ -0,0 +1,36 @@
+package chain
+
+// Options configure the wrappers applied to the broker.
+type Options struct {
+	// PublishWrappers are applied to every publish, the first is outermost
+	PublishWrappers []PublishWrapper
+	// HandlerWrappers are applied to every subscriber, the first is outermost
+	HandlerWrappers []HandlerWrapper
+}
+
+// Option sets an option.
+type Option func(*Options)
+
+// WrapPublish adds wrappers applied to every publish.
+func WrapPublish(w ...PublishWrapper) Option {
+	return func(o *Options) {
+		o.PublishWrappers = append(o.PublishWrappers, w...)
+	}
+}
+
+// WrapHandler adds wrappers applied to the handler of every subscriber.
+func WrapHandler(w ...HandlerWrapper) Option {
+	return func(o *Options) {
+		o.HandlerWrappers = append(o.HandlerWrappers, w...)
+	}
+}
+
+// Intercept adds interceptors wrapping both publishes and handlers.
+func Intercept(i ...Interceptor) Option {
+	return func(o *Options) {
+		for _, in := range i {
+			o.PublishWrappers = append(o.PublishWrappers, in.Publish)
+			o.HandlerWrappers = append(o.HandlerWrappers, in.Handler)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Package deadline provides broker and subscriber wrappers which give every
+// message handler a context derived from the service context. The context is
+// cancelled when the service shuts down or the handler timeout expires.
+package deadline
+
+import (
+	"context"
+	"time"
+
+	"go-micro.dev/v4/broker"
+	"go-micro.dev/v4/server"
+)
+
+// Handlers hands out the handler contexts. Stop cancels the contexts of all
+// in-flight handlers.
+type Handlers struct {
+	opts   Options
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// New returns the handler contexts of a service.
+func New(opts ...Option) *Handlers {
+	options := Options{
+		Context: context.Background(),
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	ctx, cancel := context.WithCancel(options.Context)
+
+	return &Handlers{
+		opts:   options,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Stop cancels the contexts of the running handlers, so they can observe the
+// shutdown. Handlers invoked afterwards receive a cancelled context. It has
+// the signature of a micro.BeforeStop hook.
+func (h *Handlers) Stop() error {
+	h.cancel()
+	return nil
+}
+
+// Context returns a handler context valid for the timeout. The values are
+// looked up in the parent first, e.g. the metadata set by the server.
+func (h *Handlers) Context(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(h.ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(h.ctx)
+	}
+
+	if parent == nil {
+		return ctx, cancel
+	}
+
+	return &valueContext{Context: ctx, values: parent}, cancel
+}
+
+// SubscriberWrapper returns a server subscriber wrapper replacing the
+// background context passed to subscribers by a handler context.
+func (h *Handlers) SubscriberWrapper() server.SubscriberWrapper {
+	return func(next server.SubscriberFunc) server.SubscriberFunc {
+		return func(ctx context.Context, msg server.Message) error {
+			ctx, cancel := h.Context(ctx, h.opts.Timeout)
+			defer cancel()
+			return next(ctx, msg)
+		}
+	}
+}
+
+// Broker wraps the broker so every event passed to a handler carries a
+// handler context, it's returned by FromEvent.
+func (h *Handlers) Broker(b broker.Broker) broker.Broker {
+	return &deadlineBroker{Broker: b, h: h}
+}
+
+// valueContext takes the values of a context and the cancellation of another.
+type valueContext struct {
+	context.Context
+	values context.Context
+}
+
+func (v *valueContext) Value(key interface{}) interface{} {
+	if val := v.values.Value(key); val != nil {
+		return val
+	}
+	return v.Context.Value(key)
+}
+
+type deadlineBroker struct {
+	broker.Broker
+	h *Handlers
+}
+
+func (d *deadlineBroker) Subscribe(topic string, handler broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	var options broker.SubscribeOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	timeout := d.h.opts.Timeout
+	if options.Context != nil {
+		if t, ok := options.Context.Value(timeoutKey{}).(time.Duration); ok {
+			timeout = t
+		}
+	}
+
+	return d.Broker.Subscribe(topic, func(e broker.Event) error {
+		ctx, cancel := d.h.Context(nil, timeout)
+		defer cancel()
+		return handler(&event{Event: e, ctx: ctx})
+	}, opts...)
+}
+
+func (d *deadlineBroker) String() string {
+	return d.Broker.String()
+}
+
+type event struct {
+	broker.Event
+	ctx context.Context
+}
+
+func (e *event) Context() context.Context {
+	return e.ctx
+}
+
+// FromEvent returns the handler context of an event received from a wrapped
+// broker, or context.Background() for any other event.
+func FromEvent(e broker.Event) context.Context {
+	if c, ok := e.(interface{ Context() context.Context }); ok {
+		return c.Context()
+	}
+	return context.Background()
+}
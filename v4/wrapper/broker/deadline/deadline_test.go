@@ -0,0 +1,76 @@
+package deadline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/broker"
+	"go-micro.dev/v4/metadata"
+	"go-micro.dev/v4/server"
+)
+
+func TestSubscriberWrapper(t *testing.T) {
+	h := New(WithTimeout(time.Second))
+
+	handler := h.SubscriberWrapper()(func(ctx context.Context, msg server.Message) error {
+		if v, _ := metadata.Get(ctx, "Foo"); v != "bar" {
+			t.Errorf("Expected the message metadata, got %q", v)
+		}
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("Expected the handler timeout to be set")
+		}
+
+		h.Stop()
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+			t.Error("Expected the context to be cancelled on stop")
+		}
+		return nil
+	})
+
+	ctx := metadata.NewContext(context.Background(), metadata.Metadata{"Foo": "bar"})
+	if err := handler(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBroker(t *testing.T) {
+	h := New()
+	b := h.Broker(broker.NewMemoryBroker())
+
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	done := make(chan error, 1)
+
+	_, err := b.Subscribe("test", func(e broker.Event) error {
+		ctx := FromEvent(e)
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("Expected the subscription timeout to be set")
+		}
+		<-ctx.Done()
+		done <- ctx.Err()
+		return nil
+	}, SubscribeTimeout(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Publish("test", &broker.Message{Body: []byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-done:
+		if err != context.DeadlineExceeded {
+			t.Errorf("Expected the deadline to be exceeded, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the handler")
+	}
+}
@@ -0,0 +1,48 @@
+package deadline
+
+import (
+	"context"
+	"time"
+
+	"go-micro.dev/v4/broker"
+)
+
+// Options configure the contexts of the handlers.
+type Options struct {
+	// Context is the parent of every handler context, e.g. the service
+	// context. Defaults to context.Background()
+	Context context.Context
+	// Timeout is the ack deadline of a message, the handler context is
+	// cancelled when it expires. Zero means no timeout
+	Timeout time.Duration
+}
+
+// Option sets an option.
+type Option func(*Options)
+
+// WithContext sets the parent context of the handler contexts.
+func WithContext(ctx context.Context) Option {
+	return func(o *Options) {
+		o.Context = ctx
+	}
+}
+
+// WithTimeout sets the handler timeout.
+func WithTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.Timeout = d
+	}
+}
+
+type timeoutKey struct{}
+
+// SubscribeTimeout overrides the handler timeout for a subscription of a
+// wrapped broker.
+func SubscribeTimeout(d time.Duration) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, timeoutKey{}, d)
+	}
+}
@@ -0,0 +1,73 @@
+package outbox
+
+import (
+	"time"
+
+	"github.com/go-micro/plugins/v4/store/file"
+	"go-micro.dev/v4/store"
+)
+
+// Options configure the outbox.
+type Options struct {
+	// Store persists the buffered messages. Defaults to a file store of
+	// the database "outbox" in file.DefaultDir, which is in the temporary
+	// directory, use one in a durable directory to keep them across reboots
+	Store store.Store
+	// Prefix of the keys of the buffered messages. Defaults to "outbox/"
+	Prefix string
+	// FlushInterval is the interval the buffered messages are retried in.
+	// Defaults to 5 seconds
+	FlushInterval time.Duration
+	// DedupSize is the number of dedup keys subscribers remember to drop
+	// redelivered messages. Defaults to 1024, zero disables it
+	DedupSize int
+}
+
+// Option sets an option.
+type Option func(*Options)
+
+// Store sets the store persisting the buffered messages.
+func Store(s store.Store) Option {
+	return func(o *Options) {
+		o.Store = s
+	}
+}
+
+// Prefix sets the key prefix of the buffered messages.
+func Prefix(p string) Option {
+	return func(o *Options) {
+		o.Prefix = p
+	}
+}
+
+// FlushInterval sets the interval buffered messages are retried in.
+func FlushInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.FlushInterval = d
+	}
+}
+
+// DedupSize sets the number of dedup keys subscribers remember.
+func DedupSize(n int) Option {
+	return func(o *Options) {
+		o.DedupSize = n
+	}
+}
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		Prefix:        "outbox/",
+		FlushInterval: 5 * time.Second,
+		DedupSize:     1024,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if options.Store == nil {
+		options.Store = file.NewStore(store.Database("outbox"))
+	}
+
+	return options
+}
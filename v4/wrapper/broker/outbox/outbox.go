@@ -0,0 +1,288 @@
+// Package outbox provides a broker wrapper which stores messages it couldn't
+// publish and forwards them in order once the broker is reachable again, for
+// edge devices with flaky connectivity.
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go-micro.dev/v4/broker"
+	"go-micro.dev/v4/logger"
+	"go-micro.dev/v4/store"
+)
+
+// HeaderDedupKey identifies a message across publish attempts, so
+// subscribers can drop the duplicates of messages published more than once.
+const HeaderDedupKey = "Micro-Dedup-Key"
+
+// entry is a buffered message.
+type entry struct {
+	Topic  string            `json:"topic"`
+	Header map[string]string `json:"header"`
+	Body   []byte            `json:"body"`
+}
+
+type outbox struct {
+	broker.Broker
+	opts Options
+
+	// mtx orders publishing and flushing
+	mtx     sync.Mutex
+	seq     int64
+	pending int
+	// publish options of the buffered messages by key, they can't be
+	// stored so they're lost on restart
+	pubOpts map[string][]broker.PublishOption
+
+	// runMtx guards exit, which is set while the flush loop runs
+	runMtx sync.Mutex
+	exit   chan bool
+	wg     sync.WaitGroup
+}
+
+// NewBroker wraps the broker so messages which fail to publish are stored
+// and forwarded once it's reachable again. While messages are buffered new
+// messages are buffered behind them, so the order is kept.
+func NewBroker(b broker.Broker, opts ...Option) broker.Broker {
+	o := &outbox{
+		Broker:  b,
+		opts:    newOptions(opts...),
+		pubOpts: make(map[string][]broker.PublishOption),
+	}
+
+	// resume after the messages buffered before a restart
+	if keys, err := o.keys(); err == nil && len(keys) > 0 {
+		o.pending = len(keys)
+		fmt.Sscanf(strings.TrimPrefix(keys[len(keys)-1], o.opts.Prefix), "%d", &o.seq)
+	}
+
+	return o
+}
+
+// Connect connects the broker and starts forwarding the buffered messages. A
+// broker which isn't reachable isn't an error, connecting is retried on every
+// flush.
+func (o *outbox) Connect() error {
+	if err := o.Broker.Connect(); err != nil {
+		logger.Logf(logger.WarnLevel, "[outbox] broker %s not reachable, buffering messages: %v", o.Broker.String(), err)
+	} else {
+		o.flush()
+	}
+
+	o.runMtx.Lock()
+	if o.exit == nil {
+		o.exit = make(chan bool)
+		o.wg.Add(1)
+		go o.run(o.exit)
+	}
+	o.runMtx.Unlock()
+
+	return nil
+}
+
+// Disconnect stops forwarding the buffered messages until the next Connect.
+func (o *outbox) Disconnect() error {
+	o.runMtx.Lock()
+	if o.exit != nil {
+		close(o.exit)
+		o.exit = nil
+	}
+	o.runMtx.Unlock()
+	o.wg.Wait()
+
+	return o.Broker.Disconnect()
+}
+
+func (o *outbox) run(exit chan bool) {
+	defer o.wg.Done()
+
+	t := time.NewTicker(o.opts.FlushInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			o.mtx.Lock()
+			pending := o.pending
+			o.mtx.Unlock()
+
+			if pending == 0 {
+				continue
+			}
+			if err := o.Broker.Connect(); err != nil {
+				continue
+			}
+			o.flush()
+		case <-exit:
+			return
+		}
+	}
+}
+
+// Publish publishes the message, or buffers it if the broker fails. The
+// publish options of buffered messages are kept in memory only, messages
+// forwarded after a restart are published without them.
+func (o *outbox) Publish(topic string, m *broker.Message, opts ...broker.PublishOption) error {
+	msg := &broker.Message{
+		Header: make(map[string]string, len(m.Header)+1),
+		Body:   m.Body,
+	}
+	for k, v := range m.Header {
+		msg.Header[k] = v
+	}
+	if len(msg.Header[HeaderDedupKey]) == 0 {
+		msg.Header[HeaderDedupKey] = uuid.New().String()
+	}
+
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+
+	// keep the order behind the buffered messages
+	if o.pending == 0 {
+		if err := o.Broker.Publish(topic, msg, opts...); err == nil {
+			return nil
+		}
+	}
+
+	return o.buffer(topic, msg, opts)
+}
+
+func (o *outbox) buffer(topic string, m *broker.Message, opts []broker.PublishOption) error {
+	b, err := json.Marshal(&entry{Topic: topic, Header: m.Header, Body: m.Body})
+	if err != nil {
+		return err
+	}
+
+	// keys sort in the order of the messages
+	seq := time.Now().UnixNano()
+	if seq <= o.seq {
+		seq = o.seq + 1
+	}
+
+	key := fmt.Sprintf("%s%020d", o.opts.Prefix, seq)
+	if err := o.opts.Store.Write(&store.Record{Key: key, Value: b}); err != nil {
+		return err
+	}
+
+	if len(opts) > 0 {
+		o.pubOpts[key] = opts
+	}
+	o.seq = seq
+	o.pending++
+	return nil
+}
+
+func (o *outbox) keys() ([]string, error) {
+	keys, err := o.opts.Store.List(store.ListPrefix(o.opts.Prefix))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// flush publishes the buffered messages in order, until one fails.
+func (o *outbox) flush() {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+
+	keys, err := o.keys()
+	if err != nil {
+		logger.Logf(logger.ErrorLevel, "[outbox] failed to list buffered messages: %v", err)
+		return
+	}
+	o.pending = len(keys)
+
+	for _, key := range keys {
+		recs, err := o.opts.Store.Read(key)
+		if err != nil || len(recs) == 0 {
+			delete(o.pubOpts, key)
+			o.pending--
+			continue
+		}
+
+		var e entry
+		if err := json.Unmarshal(recs[0].Value, &e); err != nil {
+			logger.Logf(logger.ErrorLevel, "[outbox] dropping undecodable message %s: %v", key, err)
+			o.opts.Store.Delete(key)
+			delete(o.pubOpts, key)
+			o.pending--
+			continue
+		}
+
+		if err := o.Broker.Publish(e.Topic, &broker.Message{Header: e.Header, Body: e.Body}, o.pubOpts[key]...); err != nil {
+			return
+		}
+
+		if err := o.opts.Store.Delete(key); err != nil {
+			logger.Logf(logger.ErrorLevel, "[outbox] failed to delete forwarded message %s: %v", key, err)
+			return
+		}
+		delete(o.pubOpts, key)
+		o.pending--
+	}
+}
+
+// Subscribe drops the messages whose dedup key was recently handled.
+func (o *outbox) Subscribe(topic string, h broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	if o.opts.DedupSize > 0 {
+		h = newDedup(o.opts.DedupSize).wrap(h)
+	}
+	return o.Broker.Subscribe(topic, h, opts...)
+}
+
+func (o *outbox) String() string {
+	return o.Broker.String()
+}
+
+// dedup remembers the most recent dedup keys.
+type dedup struct {
+	sync.Mutex
+	size int
+	keys map[string]bool
+	ring []string
+	next int
+}
+
+func newDedup(size int) *dedup {
+	return &dedup{
+		size: size,
+		keys: make(map[string]bool, size),
+		ring: make([]string, size),
+	}
+}
+
+// seen records the key and returns whether it was seen before.
+func (d *dedup) seen(key string) bool {
+	d.Lock()
+	defer d.Unlock()
+
+	if d.keys[key] {
+		return true
+	}
+
+	if old := d.ring[d.next]; len(old) > 0 {
+		delete(d.keys, old)
+	}
+	d.ring[d.next] = key
+	d.keys[key] = true
+	d.next = (d.next + 1) % d.size
+
+	return false
+}
+
+func (d *dedup) wrap(h broker.Handler) broker.Handler {
+	return func(e broker.Event) error {
+		key := e.Message().Header[HeaderDedupKey]
+		if len(key) > 0 && d.seen(key) {
+			return nil
+		}
+		return h(e)
+	}
+}
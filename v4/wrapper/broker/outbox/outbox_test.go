@@ -0,0 +1,207 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/broker"
+	"go-micro.dev/v4/store"
+)
+
+// flaky is a broker which fails to publish while it's offline.
+type flaky struct {
+	broker.Broker
+	sync.Mutex
+	offline bool
+}
+
+func (f *flaky) setOffline(v bool) {
+	f.Lock()
+	f.offline = v
+	f.Unlock()
+}
+
+func (f *flaky) Publish(topic string, m *broker.Message, opts ...broker.PublishOption) error {
+	f.Lock()
+	offline := f.offline
+	f.Unlock()
+
+	if offline {
+		return errors.New("broker unreachable")
+	}
+	return f.Broker.Publish(topic, m, opts...)
+}
+
+func TestStoreAndForward(t *testing.T) {
+	fb := &flaky{Broker: broker.NewMemoryBroker()}
+	s := store.NewMemoryStore()
+	b := NewBroker(fb, Store(s), FlushInterval(10*time.Millisecond))
+
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	received := make(chan string, 10)
+	_, err := b.Subscribe("events", func(e broker.Event) error {
+		received <- string(e.Message().Body)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fb.setOffline(true)
+	for _, body := range []string{"1", "2", "3"} {
+		if err := b.Publish("events", &broker.Message{Body: []byte(body)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if keys, _ := s.List(); len(keys) != 3 {
+		t.Fatalf("Expected 3 buffered messages, got %d", len(keys))
+	}
+
+	fb.setOffline(false)
+	// published behind the buffered messages
+	if err := b.Publish("events", &broker.Message{Body: []byte("4")}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"1", "2", "3", "4"} {
+		select {
+		case got := <-received:
+			if got != want {
+				t.Fatalf("Expected message %s, got %s", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for message %s", want)
+		}
+	}
+
+	if keys, _ := s.List(); len(keys) != 0 {
+		t.Fatalf("Expected the outbox to be empty, got %v", keys)
+	}
+}
+
+func TestDedup(t *testing.T) {
+	b := NewBroker(broker.NewMemoryBroker(), Store(store.NewMemoryStore()))
+
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	var count int
+	_, err := b.Subscribe("events", func(e broker.Event) error {
+		count++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &broker.Message{Header: map[string]string{HeaderDedupKey: "order-1"}}
+	for i := 0; i < 3; i++ {
+		if err := b.Publish("events", m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if count != 1 {
+		t.Fatalf("Expected duplicates to be dropped, handled %d", count)
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	fb := &flaky{Broker: broker.NewMemoryBroker()}
+	b := NewBroker(fb, Store(store.NewMemoryStore()), FlushInterval(10*time.Millisecond))
+
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Disconnect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	received := make(chan string, 1)
+	_, err := b.Subscribe("events", func(e broker.Event) error {
+		received <- string(e.Message().Body)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fb.setOffline(true)
+	if err := b.Publish("events", &broker.Message{Body: []byte("1")}); err != nil {
+		t.Fatal(err)
+	}
+	fb.setOffline(false)
+
+	// forwarded by the flush loop of the second connect
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the buffered message to be forwarded after reconnecting")
+	}
+}
+
+type ctxKey struct{}
+
+func TestPublishOptions(t *testing.T) {
+	fb := &flaky{Broker: broker.NewMemoryBroker()}
+	rb := &recorder{Broker: fb}
+	b := NewBroker(rb, Store(store.NewMemoryStore()))
+
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	fb.setOffline(true)
+	opt := broker.PublishContext(context.WithValue(context.Background(), ctxKey{}, "v"))
+	if err := b.Publish("events", &broker.Message{Body: []byte("1")}, opt); err != nil {
+		t.Fatal(err)
+	}
+
+	fb.setOffline(false)
+	b.(*outbox).flush()
+
+	rb.Lock()
+	defer rb.Unlock()
+	if len(rb.values) != 2 || rb.values[1] != "v" {
+		t.Fatalf("Expected the options to be kept for the buffered message, got %v", rb.values)
+	}
+}
+
+// recorder records the context value of the options of every publish.
+type recorder struct {
+	broker.Broker
+	sync.Mutex
+	values []interface{}
+}
+
+func (r *recorder) Publish(topic string, m *broker.Message, opts ...broker.PublishOption) error {
+	var options broker.PublishOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	r.Lock()
+	var v interface{}
+	if options.Context != nil {
+		v = options.Context.Value(ctxKey{})
+	}
+	r.values = append(r.values, v)
+	r.Unlock()
+
+	return r.Broker.Publish(topic, m, opts...)
+}
@@ -0,0 +1,22 @@
+module github.com/go-micro/plugins/v4/wrapper/broker/retry
+
+go 1.17
+
+require (
+	github.com/go-micro/plugins/v4/util/clock v1.0.0
+	go-micro.dev/v4 v4.9.0
+)
+
+require (
+	github.com/golang/protobuf v1.5.2 // indirect
+	github.com/google/uuid v1.2.0 // indirect
+	github.com/miekg/dns v1.1.43 // indirect
+	github.com/oxtoacart/bpool v0.0.0-20190530202638-03653db5a59c // indirect
+	golang.org/x/net v0.0.0-20210510120150-4163338589ed // indirect
+	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c // indirect
+	golang.org/x/sys v0.0.0-20210502180810-71e4cd670f79 // indirect
+	golang.org/x/text v0.3.6 // indirect
+	google.golang.org/protobuf v1.26.0 // indirect
+)
+
+replace github.com/go-micro/plugins/v4/util/clock => ../../../util/clock
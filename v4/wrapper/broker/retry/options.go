@@ -0,0 +1,94 @@
+package retry
+
+import (
+	"time"
+
+	"github.com/go-micro/plugins/v4/util/clock"
+)
+
+// DefaultTiers are the delays of the retry topics, a failed message is
+// retried after 5 seconds, then a minute, then 10 minutes.
+var DefaultTiers = []time.Duration{5 * time.Second, time.Minute, 10 * time.Minute}
+
+// DefaultMaxWaiting is the number of messages a subscriber schedules for
+// their retry at most.
+var DefaultMaxWaiting = 1024
+
+// Options configure the retry topics.
+type Options struct {
+	// Tiers are the delays of the retry topics, in the order a message
+	// moves through them. Defaults to DefaultTiers
+	Tiers []time.Duration
+	// Separator joins the topic and the suffix of the retry and dead letter
+	// topics. Defaults to "."
+	Separator string
+	// DeadLetter is the suffix of the topic messages are moved to after the
+	// last tier failed. Defaults to "dlq"
+	DeadLetter string
+	// Clock times the retries. Defaults to the system clock
+	Clock clock.Clock
+	// MaxWaiting is the number of messages of the retry topics a
+	// subscriber schedules for their retry at most, the delivery of
+	// further messages blocks until one is due. Defaults to
+	// DefaultMaxWaiting
+	MaxWaiting int
+}
+
+// Option sets an option.
+type Option func(*Options)
+
+// Tiers sets the delays of the retry topics.
+func Tiers(delays ...time.Duration) Option {
+	return func(o *Options) {
+		o.Tiers = delays
+	}
+}
+
+// Separator sets the separator of the retry and dead letter topics.
+func Separator(s string) Option {
+	return func(o *Options) {
+		o.Separator = s
+	}
+}
+
+// DeadLetter sets the suffix of the dead letter topic.
+func DeadLetter(suffix string) Option {
+	return func(o *Options) {
+		o.DeadLetter = suffix
+	}
+}
+
+// MaxWaiting sets the number of messages a subscriber schedules for their
+// retry at most.
+func MaxWaiting(n int) Option {
+	return func(o *Options) {
+		o.MaxWaiting = n
+	}
+}
+
+// Clock sets the clock timing the retries, e.g. a mock clock in tests.
+func Clock(c clock.Clock) Option {
+	return func(o *Options) {
+		o.Clock = c
+	}
+}
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		Tiers:      DefaultTiers,
+		Separator:  ".",
+		DeadLetter: "dlq",
+		Clock:      clock.DefaultClock,
+		MaxWaiting: DefaultMaxWaiting,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if options.MaxWaiting < 1 {
+		options.MaxWaiting = 1
+	}
+
+	return options
+}
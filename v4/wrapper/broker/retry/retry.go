@@ -0,0 +1,262 @@
+// Package retry provides a broker wrapper which retries failed messages
+// through tiered retry topics, e.g. orders.retry-5s, orders.retry-1m and
+// orders.retry-10m, before moving them to a dead letter topic.
+package retry
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"go-micro.dev/v4/broker"
+	"go-micro.dev/v4/logger"
+)
+
+const (
+	// HeaderAttempt is the number of times the message failed.
+	HeaderAttempt = "Micro-Retry-Attempt"
+	// HeaderTopic is the topic the message was first published to.
+	HeaderTopic = "Micro-Retry-Topic"
+	// HeaderError is the error of the last failed attempt.
+	HeaderError = "Micro-Retry-Error"
+	// HeaderNotBefore is the time in unix milliseconds the message is
+	// retried at.
+	HeaderNotBefore = "Micro-Retry-Not-Before"
+)
+
+type retryBroker struct {
+	broker.Broker
+	opts Options
+}
+
+// NewBroker wraps the broker so messages whose handler fails are published
+// to the retry topic of the next tier, and to the dead letter topic once all
+// tiers failed. Subscribing to a topic subscribes to its retry topics too.
+func NewBroker(b broker.Broker, opts ...Option) broker.Broker {
+	return &retryBroker{
+		Broker: b,
+		opts:   newOptions(opts...),
+	}
+}
+
+// RetryTopic returns the retry topic of the tier with the delay, e.g.
+// orders.retry-1m.
+func RetryTopic(topic string, delay time.Duration, opts ...Option) string {
+	return retryTopic(topic, delay, newOptions(opts...))
+}
+
+// DeadLetterTopic returns the topic failed messages end up in, e.g.
+// orders.dlq.
+func DeadLetterTopic(topic string, opts ...Option) string {
+	o := newOptions(opts...)
+	return topic + o.Separator + o.DeadLetter
+}
+
+func retryTopic(topic string, delay time.Duration, o Options) string {
+	return topic + o.Separator + "retry-" + formatDelay(delay)
+}
+
+// formatDelay drops the zero units of the duration, 1m0s is 1m.
+func formatDelay(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
+
+func (r *retryBroker) Subscribe(topic string, handler broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	options := broker.SubscribeOptions{AutoAck: true}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	s := &subscriber{
+		topic:   topic,
+		exit:    make(chan bool),
+		waiting: make(chan struct{}, r.opts.MaxWaiting),
+	}
+
+	sub, err := r.Broker.Subscribe(topic, r.handler(topic, 0, handler, options, s), opts...)
+	if err != nil {
+		return nil, err
+	}
+	s.subs = append(s.subs, sub)
+
+	// messages of the retry topics are acked once handled, after waiting
+	// for their retry time
+	tierOpts := append(append([]broker.SubscribeOption{}, opts...), broker.DisableAutoAck())
+
+	for i, delay := range r.opts.Tiers {
+		sub, err := r.Broker.Subscribe(retryTopic(topic, delay, r.opts), r.handler(topic, i+1, handler, options, s), tierOpts...)
+		if err != nil {
+			s.Unsubscribe()
+			return nil, err
+		}
+		s.subs = append(s.subs, sub)
+	}
+
+	return s, nil
+}
+
+// handler returns the handler of the topic of the tier, tier 0 is the topic
+// itself. Messages of the retry topics which aren't due yet are scheduled,
+// so the delivery of the messages behind them isn't held up, and acked once
+// handled. Failed messages move on to the next tier.
+func (r *retryBroker) handler(topic string, tier int, h broker.Handler, options broker.SubscribeOptions, s *subscriber) broker.Handler {
+	return func(e broker.Event) error {
+		m := e.Message()
+		if m == nil {
+			return h(e)
+		}
+
+		if tier == 0 {
+			return r.handle(topic, tier, h, e, options.AutoAck, options.AutoAck)
+		}
+
+		// handlers see the topic the message was published to
+		e = &event{Event: e, topic: topic}
+
+		d := r.delay(m.Header[HeaderNotBefore])
+		if d <= 0 {
+			return r.handle(topic, tier, h, e, false, options.AutoAck)
+		}
+
+		// bound the scheduled messages, the delivery blocks while the
+		// subscriber waits for MaxWaiting of them
+		select {
+		case s.waiting <- struct{}{}:
+		case <-s.exit:
+			return nil
+		}
+
+		go func() {
+			defer func() { <-s.waiting }()
+
+			t := r.opts.Clock.NewTimer(d)
+			defer t.Stop()
+
+			select {
+			case <-t.C():
+			case <-s.exit:
+				// not acked, the broker redelivers it
+				return
+			}
+
+			if err := r.handle(topic, tier, h, e, false, options.AutoAck); err != nil {
+				logger.Logf(logger.ErrorLevel, "[retry] failed to retry message of %s: %v", topic, err)
+			}
+		}()
+
+		return nil
+	}
+}
+
+// handle handles the message of the tier and moves it on to the next tier if
+// it fails. brokerAcks is whether the broker acks the handled messages,
+// autoAck whether the subscriber asked for that.
+func (r *retryBroker) handle(topic string, tier int, h broker.Handler, e broker.Event, brokerAcks, autoAck bool) error {
+	err := h(e)
+	if err == nil {
+		if autoAck && !brokerAcks {
+			return e.Ack()
+		}
+		return nil
+	}
+
+	if err := r.move(topic, tier, e.Message(), err); err != nil {
+		logger.Logf(logger.ErrorLevel, "[retry] failed to move message of %s: %v", topic, err)
+		return err
+	}
+
+	// the message lives on in the next tier
+	if !brokerAcks {
+		return e.Ack()
+	}
+	return nil
+}
+
+// move publishes the message which failed in the tier to the next tier, or
+// to the dead letter topic after the last.
+func (r *retryBroker) move(topic string, tier int, m *broker.Message, cause error) error {
+	msg := &broker.Message{
+		Header: make(map[string]string, len(m.Header)+4),
+		Body:   m.Body,
+	}
+	for k, v := range m.Header {
+		msg.Header[k] = v
+	}
+
+	attempt, _ := strconv.Atoi(msg.Header[HeaderAttempt])
+	msg.Header[HeaderAttempt] = strconv.Itoa(attempt + 1)
+	msg.Header[HeaderTopic] = topic
+	msg.Header[HeaderError] = cause.Error()
+
+	if tier >= len(r.opts.Tiers) {
+		delete(msg.Header, HeaderNotBefore)
+		return r.Broker.Publish(topic+r.opts.Separator+r.opts.DeadLetter, msg)
+	}
+
+	delay := r.opts.Tiers[tier]
+	msg.Header[HeaderNotBefore] = strconv.FormatInt(r.opts.Clock.Now().Add(delay).UnixNano()/int64(time.Millisecond), 10)
+
+	return r.Broker.Publish(retryTopic(topic, delay, r.opts), msg)
+}
+
+// delay returns how long until the retry time of the message.
+func (r *retryBroker) delay(notBefore string) time.Duration {
+	ms, err := strconv.ParseInt(notBefore, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return r.opts.Clock.Until(time.Unix(0, ms*int64(time.Millisecond)))
+}
+
+func (r *retryBroker) String() string {
+	return r.Broker.String()
+}
+
+type subscriber struct {
+	topic string
+	subs  []broker.Subscriber
+	exit  chan bool
+	// waiting holds a slot for every scheduled message
+	waiting chan struct{}
+}
+
+func (s *subscriber) Options() broker.SubscribeOptions {
+	return s.subs[0].Options()
+}
+
+func (s *subscriber) Topic() string {
+	return s.topic
+}
+
+// Unsubscribe unsubscribes from the topic and its retry topics.
+func (s *subscriber) Unsubscribe() error {
+	select {
+	case <-s.exit:
+	default:
+		close(s.exit)
+	}
+
+	var err error
+	for _, sub := range s.subs {
+		if uerr := sub.Unsubscribe(); uerr != nil {
+			err = uerr
+		}
+	}
+	return err
+}
+
+type event struct {
+	broker.Event
+	topic string
+}
+
+func (e *event) Topic() string {
+	return e.topic
+}
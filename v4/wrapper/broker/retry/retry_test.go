@@ -0,0 +1,242 @@
+package retry
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-micro/plugins/v4/util/clock"
+	"go-micro.dev/v4/broker"
+)
+
+func TestFormatDelay(t *testing.T) {
+	tests := map[time.Duration]string{
+		5 * time.Second:  "5s",
+		10 * time.Second: "10s",
+		time.Minute:      "1m",
+		90 * time.Second: "1m30s",
+		time.Hour:        "1h",
+	}
+	for d, want := range tests {
+		if got := formatDelay(d); got != want {
+			t.Errorf("Expected %s, got %s", want, got)
+		}
+	}
+
+	if topic := RetryTopic("orders", 10*time.Minute); topic != "orders.retry-10m" {
+		t.Errorf("Expected orders.retry-10m, got %s", topic)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	b := NewBroker(broker.NewMemoryBroker(), Tiers(10*time.Millisecond, 20*time.Millisecond))
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	attempts := make(chan string, 3)
+	start := time.Now()
+
+	sub, err := b.Subscribe("orders", func(e broker.Event) error {
+		if e.Topic() != "orders" {
+			t.Errorf("Expected topic orders, got %s", e.Topic())
+		}
+		attempt := e.Message().Header[HeaderAttempt]
+		attempts <- attempt
+		if attempt != "2" {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	if err := b.Publish("orders", &broker.Message{Header: map[string]string{}, Body: []byte("1")}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"", "1", "2"} {
+		select {
+		case have := <-attempts:
+			if have != want {
+				t.Fatalf("Expected attempt %q, got %q", want, have)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for attempt %q", want)
+		}
+	}
+	// retry times are in milliseconds, truncated
+	if d := time.Since(start); d < 28*time.Millisecond {
+		t.Errorf("Expected the tier delays to be waited, took %v", d)
+	}
+}
+
+func TestRetryClock(t *testing.T) {
+	c := clock.NewMock(time.Now())
+	b := NewBroker(broker.NewMemoryBroker(), Clock(c))
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	handled := make(chan time.Time, 3)
+	var n int32
+	start := c.Now()
+
+	if _, err := b.Subscribe("orders", func(e broker.Event) error {
+		handled <- c.Now()
+		if atomic.AddInt32(&n, 1) < 3 {
+			return errors.New("unavailable")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the delivery isn't held up by the retry
+	if err := b.Publish("orders", &broker.Message{Header: map[string]string{}, Body: []byte("1")}); err != nil {
+		t.Fatal(err)
+	}
+
+	<-handled
+	c.BlockUntil(1)
+	c.Add(DefaultTiers[0])
+	if at := <-handled; at.Sub(start) != DefaultTiers[0] {
+		t.Errorf("Expected the retry after %v, got %v", DefaultTiers[0], at.Sub(start))
+	}
+	c.BlockUntil(1)
+	c.Add(DefaultTiers[1])
+	if at := <-handled; at.Sub(start) != DefaultTiers[0]+DefaultTiers[1] {
+		t.Errorf("Expected the retry after %v, got %v", DefaultTiers[0]+DefaultTiers[1], at.Sub(start))
+	}
+}
+
+// acks counts the acks of the messages it delivers.
+type acks struct {
+	broker.Broker
+	acked int32
+}
+
+func (a *acks) Subscribe(topic string, h broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	return a.Broker.Subscribe(topic, func(e broker.Event) error {
+		return h(&ackEvent{Event: e, acked: &a.acked})
+	}, opts...)
+}
+
+type ackEvent struct {
+	broker.Event
+	acked *int32
+}
+
+func (e *ackEvent) Ack() error {
+	atomic.AddInt32(e.acked, 1)
+	return nil
+}
+
+func TestRetryScheduled(t *testing.T) {
+	c := clock.NewMock(time.Now())
+	ab := &acks{Broker: broker.NewMemoryBroker()}
+	b := NewBroker(ab, Clock(c), Tiers(time.Minute), MaxWaiting(2))
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	retried := make(chan string, 3)
+	sub, err := b.Subscribe("orders", func(e broker.Event) error {
+		if len(e.Message().Header[HeaderAttempt]) == 0 {
+			return errors.New("unavailable")
+		}
+		retried <- string(e.Message().Body)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// waiting messages don't block the deliveries behind them
+	for _, body := range []string{"1", "2"} {
+		if err := b.Publish("orders", &broker.Message{Header: map[string]string{}, Body: []byte(body)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.BlockUntil(2)
+	if n := atomic.LoadInt32(&ab.acked); n != 0 {
+		t.Fatalf("Expected the waiting messages not to be acked, got %d acks", n)
+	}
+
+	c.Add(time.Minute)
+	for i := 0; i < 2; i++ {
+		select {
+		case <-retried:
+		case <-time.After(time.Second):
+			t.Fatal("Expected the scheduled messages to be retried")
+		}
+	}
+
+	// acked once handled
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&ab.acked) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the retried messages to be acked, got %d acks", atomic.LoadInt32(&ab.acked))
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// waiting messages aren't acked on unsubscribe, the broker redelivers
+	// them
+	if err := b.Publish("orders", &broker.Message{Header: map[string]string{}, Body: []byte("3")}); err != nil {
+		t.Fatal(err)
+	}
+	c.BlockUntil(1)
+	if err := sub.Unsubscribe(); err != nil {
+		t.Fatal(err)
+	}
+	c.Add(time.Minute)
+	if n := atomic.LoadInt32(&ab.acked); n != 2 {
+		t.Errorf("Expected the waiting message not to be acked, got %d acks", n)
+	}
+}
+
+func TestDeadLetter(t *testing.T) {
+	b := NewBroker(broker.NewMemoryBroker(), Tiers(time.Millisecond))
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	if _, err := b.Subscribe("orders", func(e broker.Event) error {
+		return errors.New("invalid order")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	deadLetters := make(chan *broker.Message, 1)
+	if _, err := b.Subscribe(DeadLetterTopic("orders"), func(e broker.Event) error {
+		deadLetters <- e.Message()
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Publish("orders", &broker.Message{Header: map[string]string{"Id": "1"}, Body: []byte("1")}); err != nil {
+		t.Fatal(err)
+	}
+
+	var dead *broker.Message
+	select {
+	case dead = <-deadLetters:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the message to be dead lettered")
+	}
+	if dead.Header[HeaderAttempt] != "2" || dead.Header[HeaderTopic] != "orders" || dead.Header[HeaderError] != "invalid order" {
+		t.Errorf("Unexpected headers %v", dead.Header)
+	}
+	if dead.Header["Id"] != "1" {
+		t.Error("Expected the headers of the message to be kept")
+	}
+}
@@ -0,0 +1,71 @@
+package topic
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Options configure how topics are named on the broker.
+type Options struct {
+	// Prefix parts added in front of the topic, e.g. env and service
+	Prefix []string
+	// Version appended to the topic, unless it has a wildcard
+	Version string
+	// Separator joins the prefix, topic and version. Defaults to "."
+	Separator string
+	// Sanitize is applied to the full topic name
+	Sanitize func(string) string
+}
+
+// Option sets an option.
+type Option func(*Options)
+
+var (
+	kafkaInvalid = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+	natsInvalid  = regexp.MustCompile(`\s`)
+)
+
+// Prefix adds the given parts in front of every topic, e.g. Prefix("prod", "billing").
+func Prefix(parts ...string) Option {
+	return func(o *Options) {
+		o.Prefix = append(o.Prefix, parts...)
+	}
+}
+
+// Version appends a version to every topic, e.g. Version("v2"). Topics with a
+// wildcard aren't versioned, so subscriptions to them match every version.
+func Version(v string) Option {
+	return func(o *Options) {
+		o.Version = v
+	}
+}
+
+// Separator sets the separator joining the topic parts.
+func Separator(s string) Option {
+	return func(o *Options) {
+		o.Separator = s
+	}
+}
+
+// Sanitize sets the function used to clean up the full topic name.
+func Sanitize(fn func(string) string) Option {
+	return func(o *Options) {
+		o.Sanitize = fn
+	}
+}
+
+// SanitizeKafka replaces all characters kafka does not allow in topics with an underscore.
+func SanitizeKafka(topic string) string {
+	return kafkaInvalid.ReplaceAllString(topic, "_")
+}
+
+// SanitizeNATS replaces whitespace with an underscore, wildcards are kept so
+// they can still be used to subscribe.
+func SanitizeNATS(topic string) string {
+	return natsInvalid.ReplaceAllString(topic, "_")
+}
+
+// SanitizeLower lower cases the topic.
+func SanitizeLower(topic string) string {
+	return strings.ToLower(topic)
+}
@@ -0,0 +1,119 @@
+// Package topic provides a broker wrapper which applies a naming strategy to all topics.
+package topic
+
+import (
+	"strings"
+
+	"go-micro.dev/v4/broker"
+)
+
+// Namer maps a logical topic to the name used on the broker.
+type Namer interface {
+	Name(topic string) string
+}
+
+// NamerFunc is a function implementing Namer.
+type NamerFunc func(topic string) string
+
+// Name calls fn(topic).
+func (fn NamerFunc) Name(topic string) string {
+	return fn(topic)
+}
+
+// wildcards are the wildcard characters of the brokers, * and > of NATS,
+// + and # of MQTT, * and # of RabbitMQ. Topics with one aren't versioned.
+const wildcards = "*>+#"
+
+type namer struct {
+	opts Options
+}
+
+func (n *namer) Name(topic string) string {
+	parts := make([]string, 0, len(n.opts.Prefix)+2)
+	for _, p := range n.opts.Prefix {
+		if len(p) > 0 {
+			parts = append(parts, p)
+		}
+	}
+
+	parts = append(parts, topic)
+
+	// a wildcard subscription keeps matching the versioned topics, e.g.
+	// orders.* matches orders.v2
+	if len(n.opts.Version) > 0 && !strings.ContainsAny(topic, wildcards) {
+		parts = append(parts, n.opts.Version)
+	}
+
+	name := strings.Join(parts, n.opts.Separator)
+
+	if n.opts.Sanitize != nil {
+		name = n.opts.Sanitize(name)
+	}
+
+	return name
+}
+
+// NewNamer returns a Namer which prefixes, versions and sanitizes topics.
+func NewNamer(opts ...Option) Namer {
+	options := Options{
+		Separator: ".",
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &namer{opts: options}
+}
+
+type topicBroker struct {
+	broker.Broker
+	namer Namer
+}
+
+type subscriber struct {
+	broker.Subscriber
+	topic string
+}
+
+type event struct {
+	broker.Event
+	topic string
+}
+
+// Topic returns the logical topic the subscriber was created with.
+func (s *subscriber) Topic() string {
+	return s.topic
+}
+
+// Topic returns the logical topic the event was published to.
+func (e *event) Topic() string {
+	return e.topic
+}
+
+func (t *topicBroker) Publish(topic string, m *broker.Message, opts ...broker.PublishOption) error {
+	return t.Broker.Publish(t.namer.Name(topic), m, opts...)
+}
+
+func (t *topicBroker) Subscribe(topic string, h broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	handler := func(e broker.Event) error {
+		return h(&event{Event: e, topic: topic})
+	}
+
+	sub, err := t.Broker.Subscribe(t.namer.Name(topic), handler, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &subscriber{Subscriber: sub, topic: topic}, nil
+}
+
+// NewBroker wraps the broker so all topics published and subscribed to are
+// named by the given namer. It should be set once per service so naming
+// stays consistent regardless of the underlying broker.
+func NewBroker(b broker.Broker, n Namer) broker.Broker {
+	return &topicBroker{
+		Broker: b,
+		namer:  n,
+	}
+}
@@ -0,0 +1,136 @@
+package topic
+
+import (
+	"strings"
+	"testing"
+
+	"go-micro.dev/v4/broker"
+)
+
+func TestNamer(t *testing.T) {
+	testcases := []struct {
+		title string
+		opts  []Option
+		topic string
+		want  string
+	}{
+		{"No options", nil, "orders", "orders"},
+		{"Prefix", []Option{Prefix("prod", "billing")}, "orders", "prod.billing.orders"},
+		{"Empty prefix", []Option{Prefix("", "billing")}, "orders", "billing.orders"},
+		{"Version", []Option{Prefix("prod"), Version("v2")}, "orders", "prod.orders.v2"},
+		{"Version wildcard", []Option{Prefix("prod"), Version("v2")}, "orders.*", "prod.orders.*"},
+		{"Version full wildcard", []Option{Prefix("prod"), Version("v2")}, "orders.>", "prod.orders.>"},
+		{"Version MQTT wildcard", []Option{Prefix("prod"), Separator("/"), Version("v2")}, "orders/#", "prod/orders/#"},
+		{"Separator", []Option{Prefix("prod"), Separator("-")}, "orders", "prod-orders"},
+		{"Kafka", []Option{Prefix("prod"), Sanitize(SanitizeKafka)}, "go.micro/orders created", "prod.go.micro_orders_created"},
+		{"NATS", []Option{Sanitize(SanitizeNATS)}, "orders created.*", "orders_created.*"},
+		{"Lower", []Option{Sanitize(SanitizeLower)}, "Orders", "orders"},
+	}
+
+	for _, test := range testcases {
+		if have := NewNamer(test.opts...).Name(test.topic); have != test.want {
+			t.Errorf("%s: invalid topic, want %q, have %q", test.title, test.want, have)
+		}
+	}
+}
+
+func TestBroker(t *testing.T) {
+	b := NewBroker(broker.NewMemoryBroker(), NewNamer(Prefix("prod")))
+
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	done := make(chan broker.Event, 1)
+
+	sub, err := b.Subscribe("orders", func(e broker.Event) error {
+		done <- e
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	if have := sub.Topic(); have != "orders" {
+		t.Errorf("invalid subscriber topic, want %q, have %q", "orders", have)
+	}
+
+	if err := b.Publish("orders", &broker.Message{Body: []byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if have := (<-done).Topic(); have != "orders" {
+		t.Errorf("invalid event topic, want %q, have %q", "orders", have)
+	}
+}
+
+// recorder records the topics published and subscribed to on the broker.
+type recorder struct {
+	broker.Broker
+	published  []string
+	subscribed []string
+}
+
+func (r *recorder) Publish(topic string, m *broker.Message, opts ...broker.PublishOption) error {
+	r.published = append(r.published, topic)
+	return nil
+}
+
+func (r *recorder) Subscribe(topic string, h broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	r.subscribed = append(r.subscribed, topic)
+	return nil, nil
+}
+
+// matchNATS reports whether the NATS subject matches the filter.
+func matchNATS(filter, subject string) bool {
+	f, s := strings.Split(filter, "."), strings.Split(subject, ".")
+	for i, token := range f {
+		switch {
+		case token == ">":
+			return len(s) > i
+		case i >= len(s) || (token != "*" && token != s[i]):
+			return false
+		}
+	}
+	return len(f) == len(s)
+}
+
+func TestBrokerWildcard(t *testing.T) {
+	r := &recorder{}
+	b := NewBroker(r, NewNamer(Prefix("prod"), Version("v2")))
+
+	for _, topic := range []string{"orders.*", "orders.>", "*"} {
+		if _, err := b.Subscribe(topic, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.Publish("orders.created", &broker.Message{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the wildcard subscriptions match the versioned topic
+	published := r.published[0]
+	if published != "prod.orders.created.v2" {
+		t.Fatalf("Expected the versioned topic, have %q", published)
+	}
+	want := map[string]bool{"prod.orders.*": false, "prod.orders.>": true, "prod.*": false}
+	for _, sub := range r.subscribed {
+		match, ok := want[sub]
+		if !ok {
+			t.Fatalf("Unexpected subscription %q", sub)
+		}
+		if have := matchNATS(sub, published); have != match {
+			t.Errorf("%s %s: expected match %t, have %t", sub, published, match, have)
+		}
+	}
+
+	// a wildcard of one token matches the versions of a topic
+	if err := b.Publish("orders", &broker.Message{}); err != nil {
+		t.Fatal(err)
+	}
+	if !matchNATS("prod.orders.*", r.published[1]) {
+		t.Errorf("Expected orders.* to match %q", r.published[1])
+	}
+}
@@ -0,0 +1,87 @@
+package compress
+
+import (
+	"go-micro.dev/v4/broker"
+)
+
+// Broker wraps the broker to compress the bodies of published messages above
+// the threshold with the first of the encodings, and to decompress the
+// bodies of received messages. Messages can't be negotiated, so subscribers
+// have to be wrapped before publishers.
+func Broker(b broker.Broker, opts ...Option) broker.Broker {
+	options := newOptions(opts...)
+
+	return &compressBroker{
+		Broker:      b,
+		compressors: newCompressors(options),
+		opts:        options,
+	}
+}
+
+type compressBroker struct {
+	broker.Broker
+	compressors compressors
+	opts        Options
+}
+
+func (c *compressBroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
+	if len(c.opts.Encodings) == 0 || len(msg.Body) <= c.opts.Threshold {
+		return c.Broker.Publish(topic, msg, opts...)
+	}
+
+	encoding := c.opts.Encodings[0]
+	b, err := c.compressors.compress(encoding, msg.Body)
+	if err != nil {
+		return err
+	}
+
+	// don't modify the message of the caller
+	header := make(map[string]string, len(msg.Header)+1)
+	for k, v := range msg.Header {
+		header[k] = v
+	}
+	header[HeaderContentEncoding] = encoding
+
+	return c.Broker.Publish(topic, &broker.Message{Header: header, Body: b}, opts...)
+}
+
+func (c *compressBroker) Subscribe(topic string, handler broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	return c.Broker.Subscribe(topic, func(e broker.Event) error {
+		msg := e.Message()
+		if msg == nil {
+			return handler(e)
+		}
+		encoding, ok := msg.Header[HeaderContentEncoding]
+		if !ok {
+			return handler(e)
+		}
+
+		b, err := c.compressors.decompress(encoding, msg.Body)
+		if err != nil {
+			return err
+		}
+
+		header := make(map[string]string, len(msg.Header))
+		for k, v := range msg.Header {
+			if k != HeaderContentEncoding {
+				header[k] = v
+			}
+		}
+
+		return handler(&event{Event: e, msg: &broker.Message{Header: header, Body: b}})
+	}, opts...)
+}
+
+func (c *compressBroker) String() string {
+	return c.Broker.String()
+}
+
+// event carries the decompressed message of an event.
+type event struct {
+	broker.Event
+	msg *broker.Message
+}
+
+func (e *event) Message() *broker.Message {
+	return e.msg
+}
@@ -0,0 +1,60 @@
+package compress
+
+import (
+	"strings"
+	"testing"
+
+	"go-micro.dev/v4/broker"
+)
+
+func TestBroker(t *testing.T) {
+	m := broker.NewMemoryBroker()
+	if err := m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Disconnect()
+
+	b := Broker(m, Threshold(64))
+	received := make(chan *broker.Message, 2)
+
+	if _, err := m.Subscribe("test", func(e broker.Event) error {
+		received <- e.Message()
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Subscribe("test", func(e broker.Event) error {
+		received <- e.Message()
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	large := strings.Repeat("a", 1024)
+	msg := &broker.Message{Header: map[string]string{"Foo": "bar"}, Body: []byte(large)}
+	if err := b.Publish("test", msg); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := msg.Header[HeaderContentEncoding]; ok || string(msg.Body) != large {
+		t.Fatal("Expected the published message to be left as is")
+	}
+
+	for i := 0; i < 2; i++ {
+		r := <-received
+		if r.Header["Foo"] != "bar" {
+			t.Fatalf("Expected the header to be kept, got %v", r.Header)
+		}
+		switch r.Header[HeaderContentEncoding] {
+		case Zstd:
+			if len(r.Body) >= len(large) {
+				t.Fatalf("Expected compressed body, got %d bytes", len(r.Body))
+			}
+		case "":
+			if string(r.Body) != large {
+				t.Fatalf("Expected decompressed body, got %d bytes", len(r.Body))
+			}
+		default:
+			t.Fatalf("Unexpected encoding %v", r.Header)
+		}
+	}
+}
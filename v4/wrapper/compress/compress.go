@@ -0,0 +1,319 @@
+// Package compress compresses large request and response bodies with zstd or
+// lz4, negotiated between client and server, and large broker message bodies.
+package compress
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/codec"
+	"go-micro.dev/v4/metadata"
+	"go-micro.dev/v4/server"
+)
+
+// The http transport returns the request headers on the response, so
+// responses use headers of their own.
+const (
+	// HeaderContentEncoding is set on compressed requests and broker
+	// messages to their encoding.
+	HeaderContentEncoding = "Content-Encoding"
+	// HeaderAcceptEncoding is set by clients on requests to announce the
+	// encodings they can decompress responses with.
+	HeaderAcceptEncoding = "Accept-Encoding"
+	// HeaderResponseEncoding is set on compressed responses to their
+	// encoding.
+	HeaderResponseEncoding = "Micro-Response-Encoding"
+	// HeaderRequestEncoding is set by servers on responses to announce the
+	// encodings they can decompress requests with.
+	HeaderRequestEncoding = "Micro-Request-Encoding"
+
+	// Zstd is the encoding of zstd compressed bodies.
+	Zstd = "zstd"
+	// LZ4 is the encoding of lz4 compressed bodies, in the lz4 frame format.
+	LZ4 = "lz4"
+)
+
+// compressible are the content types whose body is written in one piece, so
+// it can be compressed as a whole.
+var compressible = []string{
+	"application/json",
+	"application/protobuf",
+	"application/octet-stream",
+}
+
+// peers tracks the encodings services announced on their last response.
+type peers struct {
+	sync.RWMutex
+	accept map[string][]string
+}
+
+func (p *peers) accepted(service string) []string {
+	p.RLock()
+	defer p.RUnlock()
+	return p.accept[service]
+}
+
+func (p *peers) set(service string, encodings []string) {
+	p.Lock()
+	p.accept[service] = encodings
+	p.Unlock()
+}
+
+// Client returns a client option compressing request bodies above the
+// threshold and asking servers to compress their responses. Requests to a
+// service are only compressed once one of its responses announced an
+// encoding of the client, and sent as is again as soon as a response doesn't.
+func Client(opts ...Option) client.Option {
+	options := newOptions(opts...)
+	c := newCompressors(options)
+	p := &peers{accept: make(map[string][]string)}
+
+	return func(o *client.Options) {
+		if o.Codecs == nil {
+			o.Codecs = make(map[string]codec.NewCodec)
+		}
+		for _, ct := range compressible {
+			nc, ok := o.Codecs[ct]
+			if !ok {
+				nc = client.DefaultCodecs[ct]
+			}
+			o.Codecs[ct] = newClientCodec(nc, c, p, options)
+		}
+	}
+}
+
+// Server returns a server option decompressing request bodies, compressing
+// response bodies above the threshold for clients accepting an encoding of
+// the server, and announcing its encodings on every response. The encoding
+// headers are removed from the metadata passed to handlers, so they aren't
+// forwarded to the services called by them.
+func Server(opts ...Option) server.Option {
+	options := newOptions(opts...)
+	c := newCompressors(options)
+
+	return func(o *server.Options) {
+		if o.Codecs == nil {
+			o.Codecs = make(map[string]codec.NewCodec)
+		}
+		for _, ct := range compressible {
+			nc, ok := o.Codecs[ct]
+			if !ok {
+				nc = server.DefaultCodecs[ct]
+			}
+			o.Codecs[ct] = newServerCodec(nc, c, options)
+		}
+		o.HdlrWrappers = append(o.HdlrWrappers, stripHeaders)
+	}
+}
+
+// NewServerCodec wraps the codec to decompress request bodies, to compress
+// response bodies and to announce the encodings on responses.
+func NewServerCodec(c codec.NewCodec, opts ...Option) codec.NewCodec {
+	options := newOptions(opts...)
+	return newServerCodec(c, newCompressors(options), options)
+}
+
+func stripHeaders(fn server.HandlerFunc) server.HandlerFunc {
+	return func(ctx context.Context, req server.Request, rsp interface{}) error {
+		ctx = metadata.Delete(ctx, HeaderContentEncoding)
+		ctx = metadata.Delete(ctx, HeaderAcceptEncoding)
+		return fn(ctx, req, rsp)
+	}
+}
+
+// buffer is an in memory io.ReadWriteCloser.
+type buffer struct {
+	*bytes.Buffer
+}
+
+func (b *buffer) Close() error {
+	return nil
+}
+
+// body encodes and compresses bodies with a codec and reads compressed ones.
+type body struct {
+	newCodec    codec.NewCodec
+	conn        io.ReadWriteCloser
+	compressors compressors
+	opts        Options
+}
+
+// write encodes the body and writes it compressed with the encoding when
+// it's above the threshold, setting the header to the encoding.
+func (b *body) write(c codec.Codec, m *codec.Message, v interface{}, header, encoding string) error {
+	if m.Header == nil {
+		m.Header = make(map[string]string)
+	}
+	// the header could have been copied from the metadata of a request
+	delete(m.Header, header)
+
+	if v == nil || len(encoding) == 0 {
+		return c.Write(m, v)
+	}
+
+	// encode into a buffer first to check the size of the body
+	buf := &buffer{new(bytes.Buffer)}
+	if err := b.newCodec(buf).Write(m, v); err != nil {
+		return err
+	}
+
+	if buf.Len() <= b.opts.Threshold {
+		_, err := b.conn.Write(buf.Bytes())
+		return err
+	}
+
+	data, err := b.compressors.compress(encoding, buf.Bytes())
+	if err != nil {
+		return err
+	}
+	if _, err := b.conn.Write(data); err != nil {
+		return err
+	}
+
+	m.Header[header] = encoding
+	return nil
+}
+
+// read decompresses the body of the encoding and decodes it.
+func (b *body) read(c codec.Codec, v interface{}, encoding string) error {
+	if len(encoding) == 0 || v == nil {
+		return c.ReadBody(v)
+	}
+
+	data, err := io.ReadAll(b.conn)
+	if err != nil {
+		return err
+	}
+	data, err = b.compressors.decompress(encoding, data)
+	if err != nil {
+		return err
+	}
+
+	return b.newCodec(&buffer{bytes.NewBuffer(data)}).ReadBody(v)
+}
+
+type clientCodec struct {
+	codec.Codec
+	body
+	peers    *peers
+	target   string
+	encoding string
+}
+
+func newClientCodec(nc codec.NewCodec, c compressors, p *peers, opts Options) codec.NewCodec {
+	return func(conn io.ReadWriteCloser) codec.Codec {
+		return &clientCodec{
+			Codec: nc(conn),
+			body: body{
+				newCodec:    nc,
+				conn:        conn,
+				compressors: c,
+				opts:        opts,
+			},
+			peers: p,
+		}
+	}
+}
+
+func (c *clientCodec) Write(m *codec.Message, b interface{}) error {
+	c.target = m.Target
+
+	if m.Header == nil {
+		m.Header = make(map[string]string)
+	}
+	m.Header[HeaderAcceptEncoding] = strings.Join(c.opts.Encodings, ", ")
+
+	return c.write(c.Codec, m, b, HeaderContentEncoding, negotiate(c.opts.Encodings, c.peers.accepted(m.Target)))
+}
+
+func (c *clientCodec) ReadHeader(m *codec.Message, t codec.MessageType) error {
+	if err := c.Codec.ReadHeader(m, t); err != nil {
+		return err
+	}
+
+	c.encoding = m.Header[HeaderResponseEncoding]
+	if t == codec.Response && len(c.target) > 0 {
+		c.peers.set(c.target, parse(m.Header[HeaderRequestEncoding]))
+	}
+
+	return nil
+}
+
+func (c *clientCodec) ReadBody(b interface{}) error {
+	return c.read(c.Codec, b, c.encoding)
+}
+
+func (c *clientCodec) String() string {
+	return c.Codec.String()
+}
+
+type serverCodec struct {
+	codec.Codec
+	body
+	encoding string
+	accept   []string
+}
+
+func newServerCodec(nc codec.NewCodec, c compressors, opts Options) codec.NewCodec {
+	return func(conn io.ReadWriteCloser) codec.Codec {
+		return &serverCodec{
+			Codec: nc(conn),
+			body: body{
+				newCodec:    nc,
+				conn:        conn,
+				compressors: c,
+				opts:        opts,
+			},
+		}
+	}
+}
+
+func (s *serverCodec) ReadHeader(m *codec.Message, t codec.MessageType) error {
+	s.encoding = m.Header[HeaderContentEncoding]
+	s.accept = parse(m.Header[HeaderAcceptEncoding])
+	return s.Codec.ReadHeader(m, t)
+}
+
+func (s *serverCodec) ReadBody(b interface{}) error {
+	return s.read(s.Codec, b, s.encoding)
+}
+
+func (s *serverCodec) Write(m *codec.Message, b interface{}) error {
+	if m.Header == nil {
+		m.Header = make(map[string]string)
+	}
+	m.Header[HeaderRequestEncoding] = strings.Join(s.opts.Encodings, ", ")
+
+	return s.write(s.Codec, m, b, HeaderResponseEncoding, negotiate(s.opts.Encodings, s.accept))
+}
+
+func (s *serverCodec) String() string {
+	return s.Codec.String()
+}
+
+// parse returns the encodings of an accept encoding header.
+func parse(header string) []string {
+	var encodings []string
+	for _, e := range strings.Split(header, ",") {
+		if e = strings.TrimSpace(e); len(e) > 0 {
+			encodings = append(encodings, e)
+		}
+	}
+	return encodings
+}
+
+// negotiate returns the first of the encodings the peer accepts, or none.
+func negotiate(encodings, accepted []string) string {
+	for _, e := range encodings {
+		for _, a := range accepted {
+			if e == a {
+				return e
+			}
+		}
+	}
+	return ""
+}
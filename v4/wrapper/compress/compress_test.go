@@ -0,0 +1,203 @@
+package compress
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/codec"
+	"go-micro.dev/v4/codec/json"
+	"go-micro.dev/v4/metadata"
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/server"
+)
+
+type Request struct {
+	Text string `json:"text"`
+}
+
+type Response struct {
+	Text     string `json:"text"`
+	Encoding string `json:"encoding"`
+}
+
+type Test struct{}
+
+func (t *Test) Echo(ctx context.Context, req *Request, rsp *Response) error {
+	rsp.Text = req.Text
+	rsp.Encoding, _ = metadata.Get(ctx, HeaderContentEncoding)
+	return nil
+}
+
+func newServer(t *testing.T, r registry.Registry, opts ...server.Option) server.Server {
+	opts = append([]server.Option{
+		server.Name("test"),
+		server.Address("127.0.0.1:0"),
+		server.Registry(r),
+	}, opts...)
+
+	s := server.NewServer(opts...)
+	if err := s.Handle(s.NewHandler(&Test{})); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func call(t *testing.T, c client.Client, text string) *Response {
+	rsp := new(Response)
+	req := c.NewRequest("test", "Test.Echo", &Request{Text: text})
+	if err := c.Call(context.Background(), req, rsp); err != nil {
+		t.Fatal(err)
+	}
+	return rsp
+}
+
+func TestRoundTrip(t *testing.T) {
+	testData := [][]string{
+		{Zstd, LZ4},
+		{LZ4},
+	}
+
+	for _, encodings := range testData {
+		r := registry.NewMemoryRegistry()
+		s := newServer(t, r, Server())
+
+		c := client.NewClient(client.Registry(r), Client(Threshold(64), Encodings(encodings...)))
+		large := strings.Repeat("a", 4096)
+
+		for i := 0; i < 3; i++ {
+			for _, text := range []string{large, "small"} {
+				rsp := call(t, c, text)
+				if rsp.Text != text {
+					t.Fatalf("Expected the text echoed with %v, got %d bytes", encodings, len(rsp.Text))
+				}
+				if len(rsp.Encoding) > 0 {
+					t.Fatalf("Expected the encoding header to be removed from the metadata, got %q", rsp.Encoding)
+				}
+			}
+		}
+
+		s.Stop()
+	}
+}
+
+func TestFallback(t *testing.T) {
+	r := registry.NewMemoryRegistry()
+	s := newServer(t, r)
+	defer s.Stop()
+
+	c := client.NewClient(client.Registry(r), Client(Threshold(64)))
+	large := strings.Repeat("a", 1024)
+
+	for i := 0; i < 2; i++ {
+		if rsp := call(t, c, large); rsp.Text != large || len(rsp.Encoding) > 0 {
+			t.Fatalf("Expected uncompressed request to server without compression, got %q", rsp.Encoding)
+		}
+	}
+}
+
+func TestCodecs(t *testing.T) {
+	options := newOptions(Threshold(64))
+	c := newCompressors(options)
+	p := &peers{accept: map[string][]string{}}
+	large := strings.Repeat("a", 1024)
+
+	conn := &buffer{new(bytes.Buffer)}
+	cc := newClientCodec(json.NewCodec, c, p, options)(conn)
+
+	// the first request negotiates
+	m := &codec.Message{Target: "test", Type: codec.Request}
+	if err := cc.Write(m, &Request{Text: large}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.Header[HeaderContentEncoding]; ok || m.Header[HeaderAcceptEncoding] != "zstd, lz4" {
+		t.Fatalf("Expected uncompressed first request accepting the encodings, got %v", m.Header)
+	}
+	if err := cc.ReadHeader(&codec.Message{Header: map[string]string{HeaderRequestEncoding: "lz4"}}, codec.Response); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.Reset()
+	m = &codec.Message{Target: "test", Type: codec.Request, Header: map[string]string{HeaderContentEncoding: "gzip"}}
+	if err := cc.Write(m, &Request{Text: large}); err != nil {
+		t.Fatal(err)
+	}
+	if m.Header[HeaderContentEncoding] != LZ4 || conn.Len() >= len(large) {
+		t.Fatalf("Expected request compressed with lz4, got %v and %d bytes", m.Header, conn.Len())
+	}
+
+	sc := newServerCodec(json.NewCodec, c, options)(conn)
+	if err := sc.ReadHeader(m, codec.Request); err != nil {
+		t.Fatal(err)
+	}
+	req := new(Request)
+	if err := sc.ReadBody(req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Text != large {
+		t.Fatalf("Expected the request decompressed, got %d bytes", len(req.Text))
+	}
+
+	conn.Reset()
+	m = &codec.Message{Type: codec.Response}
+	if err := sc.Write(m, &Request{Text: large}); err != nil {
+		t.Fatal(err)
+	}
+	if m.Header[HeaderResponseEncoding] != Zstd || m.Header[HeaderRequestEncoding] != "zstd, lz4" {
+		t.Fatalf("Expected response compressed with zstd, got %v", m.Header)
+	}
+
+	if err := cc.ReadHeader(m, codec.Response); err != nil {
+		t.Fatal(err)
+	}
+	rsp := new(Request)
+	if err := cc.ReadBody(rsp); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Text != large {
+		t.Fatalf("Expected the response decompressed, got %d bytes", len(rsp.Text))
+	}
+}
+
+func TestMaxSize(t *testing.T) {
+	c := newCompressors(newOptions(MaxSize(1024)))
+	large := bytes.Repeat([]byte("a"), 4096)
+
+	for _, encoding := range []string{Zstd, LZ4} {
+		b, err := c.compress(encoding, large)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.decompress(encoding, b); err == nil {
+			t.Fatalf("Expected %s body above the max size to be rejected", encoding)
+		}
+	}
+
+	if _, err := c.decompress("br", large); err == nil {
+		t.Fatal("Expected unsupported encoding to be rejected")
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	testData := []struct {
+		header   string
+		expected string
+	}{
+		{"", ""},
+		{"zstd", Zstd},
+		{"lz4, zstd", Zstd},
+		{" lz4 ", LZ4},
+		{"gzip, br", ""},
+	}
+
+	for _, d := range testData {
+		if e := negotiate([]string{Zstd, LZ4}, parse(d.header)); e != d.expected {
+			t.Fatalf("Expected %q for %q, got %q", d.expected, d.header, e)
+		}
+	}
+}
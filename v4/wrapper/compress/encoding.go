@@ -0,0 +1,90 @@
+package compress
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+// compressor compresses and decompresses whole bodies. It's safe for
+// concurrent use.
+type compressor interface {
+	compress(b []byte) ([]byte, error)
+	decompress(b []byte) ([]byte, error)
+}
+
+// compressors holds a compressor per encoding, decompressing bodies of up to
+// the max size.
+type compressors map[string]compressor
+
+func newCompressors(o Options) compressors {
+	// the options are valid, the errors can be ignored
+	enc, _ := zstd.NewWriter(nil)
+	dec, _ := zstd.NewReader(nil, zstd.WithDecoderMaxMemory(uint64(o.MaxSize)))
+
+	return compressors{
+		Zstd: &zstdCompressor{enc: enc, dec: dec},
+		LZ4:  &lz4Compressor{max: o.MaxSize},
+	}
+}
+
+// compress compresses the body with the encoding.
+func (c compressors) compress(encoding string, b []byte) ([]byte, error) {
+	cp, ok := c[encoding]
+	if !ok {
+		return nil, fmt.Errorf("unsupported encoding %q", encoding)
+	}
+	return cp.compress(b)
+}
+
+// decompress decompresses a body of the encoding.
+func (c compressors) decompress(encoding string, b []byte) ([]byte, error) {
+	cp, ok := c[encoding]
+	if !ok {
+		return nil, fmt.Errorf("unsupported encoding %q", encoding)
+	}
+	return cp.decompress(b)
+}
+
+type zstdCompressor struct {
+	enc *zstd.Encoder
+	dec *zstd.Decoder
+}
+
+func (z *zstdCompressor) compress(b []byte) ([]byte, error) {
+	return z.enc.EncodeAll(b, nil), nil
+}
+
+func (z *zstdCompressor) decompress(b []byte) ([]byte, error) {
+	return z.dec.DecodeAll(b, nil)
+}
+
+type lz4Compressor struct {
+	max int
+}
+
+func (l *lz4Compressor) compress(b []byte) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	w := lz4.NewWriter(buf)
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (l *lz4Compressor) decompress(b []byte) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(lz4.NewReader(bytes.NewReader(b)), int64(l.max)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > l.max {
+		return nil, fmt.Errorf("body exceeds %d bytes decompressed", l.max)
+	}
+	return body, nil
+}
@@ -0,0 +1,61 @@
+package compress
+
+// Options configure the compression of bodies.
+type Options struct {
+	// Threshold is the encoded body size in bytes above which a body is
+	// compressed. Defaults to 1KB.
+	Threshold int
+	// Encodings are the encodings bodies are compressed with, in order of
+	// preference. Defaults to zstd, then lz4.
+	Encodings []string
+	// MaxSize is the size in bytes a body may decompress to, larger bodies
+	// are rejected. Defaults to 64MB.
+	MaxSize int
+}
+
+// Option sets an option.
+type Option func(*Options)
+
+// Threshold sets the body size above which bodies are compressed.
+func Threshold(n int) Option {
+	return func(o *Options) {
+		o.Threshold = n
+	}
+}
+
+// Encodings sets the encodings in order of preference, e.g. LZ4 first for
+// its lower CPU cost. Unknown encodings are ignored.
+func Encodings(e ...string) Option {
+	return func(o *Options) {
+		o.Encodings = e
+	}
+}
+
+// MaxSize sets the size a body may decompress to.
+func MaxSize(n int) Option {
+	return func(o *Options) {
+		o.MaxSize = n
+	}
+}
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		Threshold: 1024,
+		Encodings: []string{Zstd, LZ4},
+		MaxSize:   64 << 20,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	known := options.Encodings[:0:0]
+	for _, e := range options.Encodings {
+		if e == Zstd || e == LZ4 {
+			known = append(known, e)
+		}
+	}
+	options.Encodings = known
+
+	return options
+}
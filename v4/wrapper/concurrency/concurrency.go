@@ -0,0 +1,199 @@
+// Package concurrency tracks the requests in flight, the requests queued
+// and the active streams of the endpoints of a service, so operators can see
+// an endpoint saturate before its latency alarms fire.
+package concurrency
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"go-micro.dev/v4/server"
+
+	"github.com/go-micro/plugins/v4/util/cardinality"
+)
+
+// Stats are the current counts of an endpoint.
+type Stats struct {
+	// Endpoint is the endpoint of a handler, or the topic of a subscriber
+	Endpoint string `json:"endpoint"`
+	// InFlight is the number of requests being handled, without streams
+	InFlight int64 `json:"in_flight"`
+	// Queued is the number of requests which passed the queue wrapper but
+	// didn't reach the handler wrapper yet, e.g. waiting for a rate limiter
+	Queued int64 `json:"queued"`
+	// Streams is the number of active streams
+	Streams int64 `json:"streams"`
+	// Peak is the most requests in flight at once since the tracker started
+	Peak int64 `json:"peak"`
+	// Total is the number of requests and streams completed
+	Total int64 `json:"total"`
+}
+
+type counters struct {
+	inFlight int64
+	queued   int64
+	streams  int64
+	peak     int64
+	total    int64
+}
+
+// Tracker tracks the concurrency of the endpoints of a service.
+type Tracker struct {
+	limiter *cardinality.Limiter
+
+	sync.RWMutex
+	endpoints map[string]*counters
+}
+
+// New returns a tracker.
+func New(opts ...Option) *Tracker {
+	var options Options
+	for _, o := range opts {
+		o(&options)
+	}
+
+	limit := options.EndpointLimit
+	if limit == 0 {
+		limit = cardinality.DefaultLimit
+	}
+
+	return &Tracker{
+		limiter:   cardinality.New(cardinality.Limit(limit)),
+		endpoints: make(map[string]*counters),
+	}
+}
+
+// get returns the counters of the endpoint, those of the overflow value past
+// the endpoint limit.
+func (t *Tracker) get(endpoint string) *counters {
+	endpoint = t.limiter.Value(endpoint)
+
+	t.RLock()
+	c, ok := t.endpoints[endpoint]
+	t.RUnlock()
+	if ok {
+		return c
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	if c, ok = t.endpoints[endpoint]; !ok {
+		c = &counters{}
+		t.endpoints[endpoint] = c
+	}
+	return c
+}
+
+// Snapshot returns the stats of the endpoints seen, sorted by endpoint.
+func (t *Tracker) Snapshot() []Stats {
+	t.RLock()
+	stats := make([]Stats, 0, len(t.endpoints))
+	for e, c := range t.endpoints {
+		stats = append(stats, Stats{
+			Endpoint: e,
+			InFlight: atomic.LoadInt64(&c.inFlight),
+			Queued:   atomic.LoadInt64(&c.queued),
+			Streams:  atomic.LoadInt64(&c.streams),
+			Peak:     atomic.LoadInt64(&c.peak),
+			Total:    atomic.LoadInt64(&c.total),
+		})
+	}
+	t.RUnlock()
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Endpoint < stats[j].Endpoint })
+	return stats
+}
+
+// Handler serves the snapshot as JSON, e.g. on an admin port.
+func (t *Tracker) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"endpoints": t.Snapshot()})
+	})
+}
+
+type queuedKey struct{}
+
+// queued is a request queued, dequeued once by the handler wrapper, or by
+// the queue wrapper if it never reached it.
+type queued struct {
+	c    *counters
+	done int32
+}
+
+func (q *queued) dequeue() {
+	if atomic.CompareAndSwapInt32(&q.done, 0, 1) {
+		atomic.AddInt64(&q.c.queued, -1)
+	}
+}
+
+// QueueWrapper returns the handler wrapper queueing the requests, the first
+// wrapper of the server. Requests are queued until they reach the handler
+// wrapper, the last one, so the wrappers between them which may hold
+// requests back, e.g. rate limiters, are the queue. Without it no requests
+// are queued.
+func (t *Tracker) QueueWrapper() server.HandlerWrapper {
+	return func(fn server.HandlerFunc) server.HandlerFunc {
+		return func(ctx context.Context, req server.Request, rsp interface{}) error {
+			q := &queued{c: t.get(req.Endpoint())}
+			atomic.AddInt64(&q.c.queued, 1)
+			defer q.dequeue()
+
+			return fn(context.WithValue(ctx, queuedKey{}, q), req, rsp)
+		}
+	}
+}
+
+// HandlerWrapper returns the handler wrapper counting the requests in flight
+// and the active streams, the last wrapper of the server.
+func (t *Tracker) HandlerWrapper() server.HandlerWrapper {
+	return func(fn server.HandlerFunc) server.HandlerFunc {
+		return func(ctx context.Context, req server.Request, rsp interface{}) error {
+			if q, ok := ctx.Value(queuedKey{}).(*queued); ok {
+				q.dequeue()
+			}
+
+			c := t.get(req.Endpoint())
+			if req.Stream() {
+				atomic.AddInt64(&c.streams, 1)
+				defer atomic.AddInt64(&c.streams, -1)
+			} else {
+				c.start()
+				defer atomic.AddInt64(&c.inFlight, -1)
+			}
+			defer atomic.AddInt64(&c.total, 1)
+
+			return fn(ctx, req, rsp)
+		}
+	}
+}
+
+// SubscriberWrapper returns the subscriber wrapper counting the messages in
+// flight by topic.
+func (t *Tracker) SubscriberWrapper() server.SubscriberWrapper {
+	return func(fn server.SubscriberFunc) server.SubscriberFunc {
+		return func(ctx context.Context, msg server.Message) error {
+			c := t.get(msg.Topic())
+			c.start()
+			defer atomic.AddInt64(&c.inFlight, -1)
+			defer atomic.AddInt64(&c.total, 1)
+
+			return fn(ctx, msg)
+		}
+	}
+}
+
+// start counts a request in flight, raising the peak.
+func (c *counters) start() {
+	n := atomic.AddInt64(&c.inFlight, 1)
+	for {
+		peak := atomic.LoadInt64(&c.peak)
+		if n <= peak || atomic.CompareAndSwapInt64(&c.peak, peak, n) {
+			return
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package concurrency
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/server"
+
+	"github.com/go-micro/plugins/v4/util/cardinality"
+)
+
+type testRequest struct {
+	server.Request
+	endpoint string
+	stream   bool
+}
+
+func (r *testRequest) Endpoint() string { return r.endpoint }
+func (r *testRequest) Stream() bool     { return r.stream }
+
+func waitFor(t *testing.T, tr *Tracker, fn func(Stats) bool) Stats {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		if s := tr.Snapshot(); len(s) > 0 && fn(s[0]) {
+			return s[0]
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Unexpected stats %+v", tr.Snapshot())
+	return Stats{}
+}
+
+func TestHandler(t *testing.T) {
+	tr := New()
+
+	release := make(chan struct{})
+	admit := make(chan struct{})
+	// a wrapper holding requests back, as a limiter would
+	limiter := func(fn server.HandlerFunc) server.HandlerFunc {
+		return func(ctx context.Context, req server.Request, rsp interface{}) error {
+			<-admit
+			return fn(ctx, req, rsp)
+		}
+	}
+	h := tr.QueueWrapper()(limiter(tr.HandlerWrapper()(func(ctx context.Context, req server.Request, rsp interface{}) error {
+		<-release
+		return nil
+	})))
+
+	req := &testRequest{endpoint: "Test.Call"}
+	for i := 0; i < 3; i++ {
+		go h(context.Background(), req, nil)
+	}
+	waitFor(t, tr, func(s Stats) bool { return s.Queued == 3 })
+
+	admit <- struct{}{}
+	admit <- struct{}{}
+	s := waitFor(t, tr, func(s Stats) bool { return s.InFlight == 2 })
+	if s.Queued != 1 || s.Peak != 2 {
+		t.Errorf("Expected one queued and a peak of 2, got %+v", s)
+	}
+
+	admit <- struct{}{}
+	waitFor(t, tr, func(s Stats) bool { return s.InFlight == 3 })
+
+	close(release)
+	s = waitFor(t, tr, func(s Stats) bool { return s.Total == 3 })
+	if s.InFlight != 0 || s.Queued != 0 || s.Peak != 3 {
+		t.Errorf("Expected nothing in flight after a peak of 3, got %+v", s)
+	}
+}
+
+func TestStreamsAndRejections(t *testing.T) {
+	tr := New()
+
+	release := make(chan struct{})
+	h := tr.QueueWrapper()(tr.HandlerWrapper()(func(ctx context.Context, req server.Request, rsp interface{}) error {
+		<-release
+		return nil
+	}))
+	go h(context.Background(), &testRequest{endpoint: "Test.Stream", stream: true}, nil)
+
+	s := waitFor(t, tr, func(s Stats) bool { return s.Streams == 1 })
+	if s.InFlight != 0 || s.Queued != 0 {
+		t.Errorf("Expected the stream not to count in flight, got %+v", s)
+	}
+	close(release)
+
+	// requests rejected before the handler wrapper leave the queue
+	reject := tr.QueueWrapper()(func(ctx context.Context, req server.Request, rsp interface{}) error {
+		return context.Canceled
+	})
+	reject(context.Background(), &testRequest{endpoint: "Test.Stream"}, nil)
+	waitFor(t, tr, func(s Stats) bool { return s.Streams == 0 && s.Queued == 0 && s.Total == 1 })
+}
+
+func TestHTTPHandler(t *testing.T) {
+	tr := New()
+	tr.HandlerWrapper()(func(context.Context, server.Request, interface{}) error { return nil })(context.Background(), &testRequest{endpoint: "Test.Call"}, nil)
+
+	w := httptest.NewRecorder()
+	tr.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/concurrency", nil))
+
+	var rsp struct {
+		Endpoints []Stats `json:"endpoints"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&rsp); err != nil {
+		t.Fatal(err)
+	}
+	if len(rsp.Endpoints) != 1 || rsp.Endpoints[0].Endpoint != "Test.Call" || rsp.Endpoints[0].Total != 1 {
+		t.Errorf("Unexpected endpoints %+v", rsp.Endpoints)
+	}
+}
+
+func TestEndpointLimit(t *testing.T) {
+	tr := New(EndpointLimit(2))
+
+	release := make(chan struct{})
+	h := tr.HandlerWrapper()(func(ctx context.Context, req server.Request, rsp interface{}) error {
+		<-release
+		return nil
+	})
+
+	done := make(chan struct{})
+	for _, e := range []string{"Test.One", "Test.Two", "Test.Three", "Test.Four"} {
+		go func(e string) {
+			h(context.Background(), &testRequest{endpoint: e}, nil)
+			done <- struct{}{}
+		}(e)
+	}
+
+	for i := 0; ; i++ {
+		var inFlight int64
+		for _, s := range tr.Snapshot() {
+			inFlight += s.InFlight
+		}
+		if inFlight == 4 {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("Unexpected stats %+v", tr.Snapshot())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// the endpoints past the limit are tracked together
+	stats := tr.Snapshot()
+	if len(stats) != 3 {
+		t.Fatalf("Expected 2 endpoints and the overflow, got %+v", stats)
+	}
+	for _, s := range stats {
+		if s.Endpoint == cardinality.DefaultOverflow && s.InFlight != 2 {
+			t.Fatalf("Expected 2 requests in flight in the overflow, got %+v", s)
+		}
+	}
+
+	close(release)
+	for i := 0; i < 4; i++ {
+		<-done
+	}
+}
@@ -0,0 +1,20 @@
+package concurrency
+
+// Options configure a tracker.
+type Options struct {
+	// EndpointLimit is the number of distinct endpoints tracked, further
+	// endpoints are tracked together as cardinality.DefaultOverflow.
+	// Defaults to cardinality.DefaultLimit, less than zero disables it
+	EndpointLimit int
+}
+
+// Option sets an option.
+type Option func(*Options)
+
+// EndpointLimit bounds the distinct endpoints tracked, so the endpoints of a
+// proxy or topics named after tenants can't grow the tracker unbounded.
+func EndpointLimit(n int) Option {
+	return func(o *Options) {
+		o.EndpointLimit = n
+	}
+}
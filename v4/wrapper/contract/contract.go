@@ -0,0 +1,247 @@
+// Package contract validates the responses of services against the
+// contract the client expects, for consumer driven contract testing against
+// live services. It's meant for test and staging environments, as every
+// JSON response is buffered and decoded twice.
+package contract
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/codec"
+	"go-micro.dev/v4/errors"
+	"go-micro.dev/v4/metadata"
+	"go-micro.dev/v4/registry"
+	"google.golang.org/protobuf/proto"
+)
+
+// headerCall passes the id of the call from the call wrapper to the codec,
+// it's never sent
+const headerCall = "Micro-Contract-Call"
+
+// Validator is implemented by responses validating themselves, e.g. by
+// messages generated with protoc-gen-validate.
+type Validator interface {
+	Validate() error
+}
+
+// Violation is a response violating the contract.
+type Violation struct {
+	Service  string
+	Endpoint string
+	Err      error
+	Time     time.Time
+}
+
+func (v Violation) Error() string {
+	return fmt.Sprintf("contract of %s %s violated: %v", v.Service, v.Endpoint, v.Err)
+}
+
+// Checker checks the responses of the client against the contract and
+// records the violations.
+type Checker struct {
+	opts Options
+	seq  uint64
+
+	sync.Mutex
+	// raw json bodies by call id
+	raw        map[string][]byte
+	violations []Violation
+}
+
+// New returns a checker of the contract.
+func New(opts ...Option) *Checker {
+	return &Checker{
+		opts: newOptions(opts...),
+		raw:  make(map[string][]byte),
+	}
+}
+
+// Client returns a client option checking the responses of calls.
+func (c *Checker) Client() client.Option {
+	return func(o *client.Options) {
+		o.CallOptions.CallWrappers = append(o.CallOptions.CallWrappers, c.CallWrapper())
+
+		if o.Codecs == nil {
+			o.Codecs = make(map[string]codec.NewCodec)
+		}
+		cdc, ok := o.Codecs["application/json"]
+		if !ok {
+			cdc = client.DefaultCodecs["application/json"]
+		}
+		o.Codecs["application/json"] = c.newCodec(cdc)
+	}
+}
+
+// CallWrapper returns a call wrapper checking the responses. Without the
+// codecs installed by Client, JSON responses aren't validated against their
+// schema.
+func (c *Checker) CallWrapper() client.CallWrapper {
+	return func(next client.CallFunc) client.CallFunc {
+		return func(ctx context.Context, node *registry.Node, req client.Request, rsp interface{}, opts client.CallOptions) error {
+			id := strconv.FormatUint(atomic.AddUint64(&c.seq, 1), 10)
+			err := next(metadata.Set(ctx, headerCall, id), node, req, rsp, opts)
+
+			c.Lock()
+			raw, hasRaw := c.raw[id]
+			delete(c.raw, id)
+			c.Unlock()
+
+			if err != nil {
+				return err
+			}
+
+			var violations []Violation
+			for _, e := range c.check(ctx, req, raw, hasRaw, rsp) {
+				v := Violation{Service: req.Service(), Endpoint: req.Endpoint(), Err: e, Time: time.Now()}
+				violations = append(violations, v)
+				if c.opts.OnViolation != nil {
+					c.opts.OnViolation(ctx, v)
+				}
+			}
+			if len(violations) == 0 {
+				return nil
+			}
+
+			c.Lock()
+			c.violations = append(c.violations, violations...)
+			c.Unlock()
+
+			// a bad gateway, as a 500 would be retried
+			if c.opts.Fail {
+				return errors.New(req.Service(), violations[0].Error(), http.StatusBadGateway)
+			}
+			return nil
+		}
+	}
+}
+
+func (c *Checker) check(ctx context.Context, req client.Request, raw []byte, hasRaw bool, rsp interface{}) []error {
+	k := key(req.Service(), req.Endpoint())
+
+	var errs []error
+	if hasRaw {
+		if s, ok := c.opts.Schemas[k]; ok {
+			errs = append(errs, s.Validate(raw)...)
+		}
+		if !c.opts.AllowUnknownFields {
+			if err := unknownJSONFields(raw, rsp); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	} else if m, ok := rsp.(proto.Message); ok && !c.opts.AllowUnknownFields {
+		if u := m.ProtoReflect().GetUnknown(); len(u) > 0 {
+			errs = append(errs, fmt.Errorf("%d bytes of unknown fields", len(u)))
+		}
+	}
+
+	if v, ok := rsp.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, fn := range c.opts.Checks[k] {
+		if err := fn(ctx, rsp); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// unknownJSONFields decodes the body again, failing on fields the response
+// type doesn't have. Proto messages are skipped, their JSON decoding fails
+// on unknown fields already.
+func unknownJSONFields(raw []byte, rsp interface{}) error {
+	if _, ok := rsp.(proto.Message); ok {
+		return nil
+	}
+	t := reflect.TypeOf(rsp)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(reflect.New(t.Elem()).Interface()); err != nil {
+		return fmt.Errorf("decoding strictly: %w", err)
+	}
+	return nil
+}
+
+// Violations returns the violations recorded so far.
+func (c *Checker) Violations() []Violation {
+	c.Lock()
+	defer c.Unlock()
+	return append([]Violation(nil), c.violations...)
+}
+
+// Reset clears the recorded violations.
+func (c *Checker) Reset() {
+	c.Lock()
+	c.violations = nil
+	c.Unlock()
+}
+
+// buffer is an in memory io.ReadWriteCloser.
+type buffer struct {
+	*bytes.Buffer
+}
+
+func (b *buffer) Close() error {
+	return nil
+}
+
+func (c *Checker) newCodec(cdc codec.NewCodec) codec.NewCodec {
+	return func(conn io.ReadWriteCloser) codec.Codec {
+		return &clientCodec{Codec: cdc(conn), newCodec: cdc, c: c}
+	}
+}
+
+// clientCodec keeps the raw JSON body of the response for the call wrapper.
+type clientCodec struct {
+	codec.Codec
+	newCodec codec.NewCodec
+	c        *Checker
+	id       string
+}
+
+func (cc *clientCodec) Write(m *codec.Message, b interface{}) error {
+	if id, ok := m.Header[headerCall]; ok {
+		cc.id = id
+		delete(m.Header, headerCall)
+	}
+	return cc.Codec.Write(m, b)
+}
+
+func (cc *clientCodec) ReadBody(b interface{}) error {
+	if b == nil || len(cc.id) == 0 {
+		return cc.Codec.ReadBody(b)
+	}
+
+	var raw json.RawMessage
+	if err := cc.Codec.ReadBody(&raw); err != nil {
+		return err
+	}
+
+	cc.c.Lock()
+	cc.c.raw[cc.id] = raw
+	cc.c.Unlock()
+
+	return cc.newCodec(&buffer{bytes.NewBuffer(raw)}).ReadBody(b)
+}
+
+func (cc *clientCodec) String() string {
+	return cc.Codec.String()
+}
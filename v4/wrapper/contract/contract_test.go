@@ -0,0 +1,147 @@
+package contract
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/server"
+)
+
+type Request struct{}
+
+// Response is what the service sends.
+type Response struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Extra  int    `json:"extra,omitempty"`
+}
+
+// Expected is what the consumer expects.
+type Expected struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+func (e *Expected) Validate() error {
+	if len(e.Name) == 0 {
+		return fmt.Errorf("name is empty")
+	}
+	return nil
+}
+
+type Test struct{}
+
+func (t *Test) Get(ctx context.Context, req *Request, rsp *Response) error {
+	rsp.Name = "foo"
+	rsp.Status = "active"
+	return nil
+}
+
+func (t *Test) Extra(ctx context.Context, req *Request, rsp *Response) error {
+	rsp.Status = "deleted"
+	rsp.Extra = 1
+	return nil
+}
+
+func TestSchema(t *testing.T) {
+	s := MustParseSchema([]byte(`{
+		"type": "object",
+		"required": ["name", "tags"],
+		"additionalProperties": false,
+		"properties": {
+			"name": {"type": "string"},
+			"count": {"type": "integer"},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"status": {"enum": ["active", "inactive"]}
+		}
+	}`))
+
+	if errs := s.Validate([]byte(`{"name": "foo", "count": 2, "tags": ["a"], "status": "active"}`)); len(errs) != 0 {
+		t.Fatalf("Expected no violations, got %v", errs)
+	}
+
+	errs := s.Validate([]byte(`{"name": 1, "count": 1.5, "tags": ["a", 2], "status": "deleted", "other": true}`))
+	want := []string{
+		"$.count: expected integer, got number",
+		"$.name: expected string, got number",
+		"$: unexpected property other",
+		"$.status: deleted is not one of [active inactive]",
+		"$.tags[1]: expected string, got number",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("Expected %d violations, got %v", len(want), errs)
+	}
+	for i, e := range errs {
+		if e.Error() != want[i] {
+			t.Errorf("Expected %q, got %q", want[i], e)
+		}
+	}
+
+	if errs := s.Validate([]byte(`{"name": "foo"}`)); len(errs) != 1 || errs[0].Error() != "$: missing required property tags" {
+		t.Fatalf("Expected a missing tags, got %v", errs)
+	}
+}
+
+func TestChecker(t *testing.T) {
+	r := registry.NewMemoryRegistry()
+
+	s := server.NewServer(
+		server.Name("test"),
+		server.Address("127.0.0.1:0"),
+		server.Registry(r),
+	)
+	if err := s.Handle(s.NewHandler(&Test{})); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	var notified int
+	checker := New(
+		JSONSchema("test", "Test.Get", MustParseSchema([]byte(`{"properties": {"status": {"enum": ["active"]}}}`))),
+		JSONSchema("test", "Test.Extra", MustParseSchema([]byte(`{"properties": {"status": {"enum": ["active"]}}}`))),
+		Check("test", "Test.Get", func(ctx context.Context, rsp interface{}) error {
+			if rsp.(*Expected).Name != "foo" {
+				return fmt.Errorf("unexpected name")
+			}
+			return nil
+		}),
+		OnViolation(func(ctx context.Context, v Violation) { notified++ }),
+	)
+	c := client.NewClient(client.Registry(r), checker.Client())
+
+	if err := c.Call(context.Background(), c.NewRequest("test", "Test.Get", &Request{}), new(Expected)); err != nil {
+		t.Fatal(err)
+	}
+	if v := checker.Violations(); len(v) != 0 {
+		t.Fatalf("Expected no violations, got %v", v)
+	}
+
+	if err := c.Call(context.Background(), c.NewRequest("test", "Test.Extra", &Request{}), new(Expected)); err != nil {
+		t.Fatalf("Expected violations to only be recorded, got %v", err)
+	}
+	v := checker.Violations()
+	if len(v) != 3 || notified != 3 {
+		t.Fatalf("Expected the enum, unknown field and validation violations, got %v", v)
+	}
+	for _, v := range v {
+		if v.Service != "test" || v.Endpoint != "Test.Extra" {
+			t.Fatalf("Unexpected violation %v", v)
+		}
+	}
+
+	checker.Reset()
+	failing := New(Fail(), AllowUnknownFields())
+	c = client.NewClient(client.Registry(r), failing.Client())
+	if err := c.Call(context.Background(), c.NewRequest("test", "Test.Extra", &Request{}), new(Expected)); err == nil {
+		t.Fatal("Expected the call to fail on the empty name")
+	}
+	if v := failing.Violations(); len(v) != 1 {
+		t.Fatalf("Expected only the validation violation, got %v", v)
+	}
+}
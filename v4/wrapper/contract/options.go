@@ -0,0 +1,83 @@
+package contract
+
+import (
+	"context"
+)
+
+// CheckFunc checks a decoded response, returning the violated expectation.
+type CheckFunc func(ctx context.Context, rsp interface{}) error
+
+// Options configure the contract checks.
+type Options struct {
+	// Schemas are the JSON Schemas of the responses by endpoint, see key.
+	// Only JSON responses are validated against them.
+	Schemas map[string]*Schema
+	// Checks are custom checks of the decoded responses by endpoint.
+	Checks map[string][]CheckFunc
+	// AllowUnknownFields doesn't report fields of responses the response
+	// type doesn't have, e.g. fields added by a newer version of the
+	// service.
+	AllowUnknownFields bool
+	// Fail returns the first violation of a response as a 502 error of the
+	// call, instead of only recording it.
+	Fail bool
+	// OnViolation is called for every violation, e.g. to log it or count
+	// it in a metric.
+	OnViolation func(ctx context.Context, v Violation)
+}
+
+// Option sets an option.
+type Option func(*Options)
+
+// JSONSchema sets the JSON Schema of the responses of the endpoint.
+func JSONSchema(service, endpoint string, s *Schema) Option {
+	return func(o *Options) {
+		o.Schemas[key(service, endpoint)] = s
+	}
+}
+
+// Check adds a custom check of the responses of the endpoint.
+func Check(service, endpoint string, fn CheckFunc) Option {
+	return func(o *Options) {
+		k := key(service, endpoint)
+		o.Checks[k] = append(o.Checks[k], fn)
+	}
+}
+
+// AllowUnknownFields doesn't report unknown fields of responses.
+func AllowUnknownFields() Option {
+	return func(o *Options) {
+		o.AllowUnknownFields = true
+	}
+}
+
+// Fail fails calls with a response violating the contract.
+func Fail() Option {
+	return func(o *Options) {
+		o.Fail = true
+	}
+}
+
+// OnViolation sets the func called for every violation.
+func OnViolation(fn func(ctx context.Context, v Violation)) Option {
+	return func(o *Options) {
+		o.OnViolation = fn
+	}
+}
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		Schemas: make(map[string]*Schema),
+		Checks:  make(map[string][]CheckFunc),
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return options
+}
+
+func key(service, endpoint string) string {
+	return service + " " + endpoint
+}
@@ -0,0 +1,135 @@
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// Schema is the subset of JSON Schema responses are validated against:
+// type, properties, required, additionalProperties, items and enum.
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+}
+
+// ParseSchema parses a JSON Schema, keywords other than the supported ones
+// are ignored.
+func ParseSchema(b []byte) (*Schema, error) {
+	s := new(Schema)
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// MustParseSchema is like ParseSchema but panics if the schema can't be
+// parsed.
+func MustParseSchema(b []byte) *Schema {
+	s, err := ParseSchema(b)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Validate validates the JSON document and returns the violations.
+func (s *Schema) Validate(b []byte) []error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return []error{err}
+	}
+	return s.validate("$", v)
+}
+
+func (s *Schema) validate(path string, v interface{}) []error {
+	if s == nil {
+		return nil
+	}
+
+	var errs []error
+	if len(s.Type) > 0 && !hasType(s.Type, v) {
+		return []error{fmt.Errorf("%s: expected %s, got %s", path, s.Type, typeOf(v))}
+	}
+
+	if len(s.Enum) > 0 {
+		var found bool
+		for _, e := range s.Enum {
+			if reflect.DeepEqual(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Errorf("%s: %v is not one of %v", path, v, s.Enum))
+		}
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, k := range s.Required {
+			if _, ok := v[k]; !ok {
+				errs = append(errs, fmt.Errorf("%s: missing required property %s", path, k))
+			}
+		}
+
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			p, ok := s.Properties[k]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					errs = append(errs, fmt.Errorf("%s: unexpected property %s", path, k))
+				}
+				continue
+			}
+			errs = append(errs, p.validate(path+"."+k, v[k])...)
+		}
+	case []interface{}:
+		for i, item := range v {
+			errs = append(errs, s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item)...)
+		}
+	}
+
+	return errs
+}
+
+func hasType(t string, v interface{}) bool {
+	switch t {
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	}
+	return typeOf(v) == t
+}
+
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
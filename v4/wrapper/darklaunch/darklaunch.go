@@ -0,0 +1,151 @@
+// Package darklaunch hides endpoints deployed to production from everyone
+// but the holders of a signed token, so new APIs can be tested in place
+// before they're released. Requests without a valid token are rejected as if
+// the endpoint didn't exist.
+package darklaunch
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"path"
+	"strings"
+	"time"
+
+	merrors "go-micro.dev/v4/errors"
+	"go-micro.dev/v4/metadata"
+	"go-micro.dev/v4/server"
+)
+
+// HeaderToken is the metadata key of the token by default.
+const HeaderToken = "Micro-Dark-Launch"
+
+var (
+	// ErrInvalidToken is returned by Verify for malformed tokens and tokens
+	// not signed by any of the secrets.
+	ErrInvalidToken = errors.New("invalid dark launch token")
+	// ErrExpiredToken is returned by Verify for expired tokens.
+	ErrExpiredToken = errors.New("dark launch token expired")
+)
+
+// Token grants access to dark launched endpoints.
+type Token struct {
+	// Subject is who the token was issued to, e.g. a tester or team.
+	Subject string `json:"sub,omitempty"`
+	// Endpoints restricts the token to the endpoints matching the
+	// patterns, it grants access to all dark launched endpoints if empty.
+	Endpoints []string `json:"endpoints,omitempty"`
+	// Expiry is when the token expires, it never does if zero.
+	Expiry time.Time `json:"exp,omitempty"`
+}
+
+// Allows reports whether the token grants access to the endpoint.
+func (t *Token) Allows(endpoint string) bool {
+	if len(t.Endpoints) == 0 {
+		return true
+	}
+	return match(t.Endpoints, endpoint)
+}
+
+// Sign returns the token signed with the secret, its claims base64 encoded
+// JSON followed by their HMAC-SHA256.
+func Sign(secret []byte, t Token) (string, error) {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+
+	claims := base64.RawURLEncoding.EncodeToString(b)
+	return claims + "." + base64.RawURLEncoding.EncodeToString(mac(secret, claims)), nil
+}
+
+// Verify returns the claims of the token if it's signed by one of the
+// secrets and hasn't expired.
+func Verify(token string, secrets ...[]byte) (*Token, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return nil, ErrInvalidToken
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var valid bool
+	for _, secret := range secrets {
+		if hmac.Equal(sig, mac(secret, parts[0])) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, ErrInvalidToken
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	t := new(Token)
+	if err := json.Unmarshal(b, t); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if !t.Expiry.IsZero() && time.Now().After(t.Expiry) {
+		return nil, ErrExpiredToken
+	}
+
+	return t, nil
+}
+
+func mac(secret []byte, claims string) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(claims))
+	return h.Sum(nil)
+}
+
+func match(patterns []string, endpoint string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, endpoint); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// NewContext returns a context sending the token with requests made with
+// it, and with all the requests made downstream while handling them.
+func NewContext(ctx context.Context, token string) context.Context {
+	return metadata.Set(ctx, HeaderToken, token)
+}
+
+// NewHandlerWrapper returns a handler wrapper rejecting requests to dark
+// launched endpoints without a valid token granting access to them.
+func NewHandlerWrapper(opts ...Option) server.HandlerWrapper {
+	options := newOptions(opts...)
+
+	return func(fn server.HandlerFunc) server.HandlerFunc {
+		return func(ctx context.Context, req server.Request, rsp interface{}) error {
+			if !match(options.Endpoints, req.Endpoint()) {
+				return fn(ctx, req, rsp)
+			}
+
+			if token, ok := metadata.Get(ctx, options.Header); ok && len(token) > 0 {
+				if t, err := Verify(token, options.Secrets...); err == nil && t.Allows(req.Endpoint()) {
+					return fn(ctx, req, rsp)
+				}
+			}
+
+			// rejected like the router rejects unknown methods
+			method := req.Endpoint()
+			if i := strings.LastIndex(method, "."); i >= 0 {
+				method = method[i+1:]
+			}
+			return merrors.New(req.Service(), "rpc: can't find method "+method, options.Code)
+		}
+	}
+}
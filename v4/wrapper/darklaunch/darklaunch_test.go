@@ -0,0 +1,100 @@
+package darklaunch
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/errors"
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/server"
+)
+
+type Request struct{}
+
+type Response struct{}
+
+type Test struct{}
+
+func (t *Test) Ping(ctx context.Context, req *Request, rsp *Response) error {
+	return nil
+}
+
+func (t *Test) Beta(ctx context.Context, req *Request, rsp *Response) error {
+	return nil
+}
+
+func TestVerify(t *testing.T) {
+	secret := []byte("secret")
+
+	token, err := Sign(secret, Token{Subject: "qa", Endpoints: []string{"Test.*"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := Verify(token, []byte("old"), secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "qa" || !claims.Allows("Test.Beta") || claims.Allows("Other.Beta") {
+		t.Fatalf("Unexpected claims %+v", claims)
+	}
+
+	if _, err := Verify(token, []byte("other")); err != ErrInvalidToken {
+		t.Fatalf("Expected %v, got %v", ErrInvalidToken, err)
+	}
+	if _, err := Verify(token[1:], secret); err != ErrInvalidToken {
+		t.Fatalf("Expected %v for a tampered token, got %v", ErrInvalidToken, err)
+	}
+
+	token, _ = Sign(secret, Token{Expiry: time.Now().Add(-time.Second)})
+	if _, err := Verify(token, secret); err != ErrExpiredToken {
+		t.Fatalf("Expected %v, got %v", ErrExpiredToken, err)
+	}
+}
+
+func TestHandlerWrapper(t *testing.T) {
+	secret := []byte("secret")
+	r := registry.NewMemoryRegistry()
+
+	s := server.NewServer(
+		server.Name("test"),
+		server.Address("127.0.0.1:0"),
+		server.Registry(r),
+		server.WrapHandler(NewHandlerWrapper(Endpoints("Test.Beta"), Secret(secret))),
+	)
+	if err := s.Handle(s.NewHandler(&Test{})); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	c := client.NewClient(client.Registry(r))
+	call := func(ctx context.Context, endpoint string) error {
+		return c.Call(ctx, c.NewRequest("test", endpoint, &Request{}), new(Response))
+	}
+
+	if err := call(context.Background(), "Test.Ping"); err != nil {
+		t.Fatalf("Expected released endpoints to be served, got %v", err)
+	}
+
+	err := call(context.Background(), "Test.Beta")
+	if e := errors.FromError(err); e.Code != http.StatusNotFound {
+		t.Fatalf("Expected a 404 without token, got %v", err)
+	}
+
+	other, _ := Sign(secret, Token{Endpoints: []string{"Test.Ping"}})
+	err = call(NewContext(context.Background(), other), "Test.Beta")
+	if e := errors.FromError(err); e.Code != http.StatusNotFound {
+		t.Fatalf("Expected a 404 with a token of other endpoints, got %v", err)
+	}
+
+	token, _ := Sign(secret, Token{Subject: "qa"})
+	if err := call(NewContext(context.Background(), token), "Test.Beta"); err != nil {
+		t.Fatalf("Expected the dark launched endpoint to be served with a token, got %v", err)
+	}
+}
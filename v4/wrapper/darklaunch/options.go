@@ -0,0 +1,64 @@
+package darklaunch
+
+import (
+	"net/http"
+)
+
+// Options configure the dark launch wrapper.
+type Options struct {
+	// Endpoints are the patterns of the dark launched endpoints, e.g.
+	// "Greeter.Hello" or "Orders.*", see path.Match.
+	Endpoints []string
+	// Secrets verify the tokens. More than one can be set while rotating
+	// the secret.
+	Secrets [][]byte
+	// Header is the metadata key of the token. Defaults to HeaderToken.
+	Header string
+	// Code is the error code of rejected requests. Defaults to 404, set it
+	// to 501 for grpc clients to see UNIMPLEMENTED.
+	Code int32
+}
+
+// Option sets an option.
+type Option func(*Options)
+
+// Endpoints adds patterns of dark launched endpoints.
+func Endpoints(patterns ...string) Option {
+	return func(o *Options) {
+		o.Endpoints = append(o.Endpoints, patterns...)
+	}
+}
+
+// Secret adds a secret verifying the tokens.
+func Secret(secret []byte) Option {
+	return func(o *Options) {
+		o.Secrets = append(o.Secrets, secret)
+	}
+}
+
+// Header sets the metadata key of the token.
+func Header(key string) Option {
+	return func(o *Options) {
+		o.Header = key
+	}
+}
+
+// Code sets the error code of rejected requests.
+func Code(code int32) Option {
+	return func(o *Options) {
+		o.Code = code
+	}
+}
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		Header: HeaderToken,
+		Code:   http.StatusNotFound,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return options
+}
@@ -0,0 +1,320 @@
+// Package depgraph records the calls between services as the edges of a
+// dependency graph and periodically publishes snapshots of it, e.g. to
+// draw architecture diagrams or find the blast radius of a service.
+package depgraph
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/logger"
+	"go-micro.dev/v4/metadata"
+	"go-micro.dev/v4/server"
+	"go-micro.dev/v4/store"
+)
+
+const (
+	// ProtocolPublish is the protocol of edges to the topics published to,
+	// whose callee is the topic.
+	ProtocolPublish = "publish"
+	// ProtocolSubscribe is the protocol of edges from the publishers of
+	// messages to their subscribers, the endpoint is the topic.
+	ProtocolSubscribe = "subscribe"
+
+	// unknownCaller is recorded for messages without the publisher
+	unknownCaller = "unknown"
+)
+
+// Edge is the calls from a caller to an endpoint of a callee over the
+// interval of a snapshot.
+type Edge struct {
+	Caller   string    `json:"caller"`
+	Callee   string    `json:"callee"`
+	Endpoint string    `json:"endpoint"`
+	Protocol string    `json:"protocol"`
+	Calls    int64     `json:"calls"`
+	Errors   int64     `json:"errors"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+type edgeKey struct {
+	caller, callee, endpoint, protocol string
+}
+
+// Graph is a snapshot of the edges recorded by an instance.
+type Graph struct {
+	Service string    `json:"service"`
+	Node    string    `json:"node"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Edges   []Edge    `json:"edges"`
+}
+
+// Recorder records the edges of the calls of a service.
+type Recorder struct {
+	opts Options
+
+	sync.Mutex
+	edges map[edgeKey]*Edge
+	start time.Time
+
+	once sync.Once
+	exit chan struct{}
+	done chan struct{}
+}
+
+// New returns a recorder, publishing snapshots every interval if a client,
+// store or OnSnapshot func is set.
+func New(opts ...Option) *Recorder {
+	r := &Recorder{
+		opts:  newOptions(opts...),
+		edges: make(map[edgeKey]*Edge),
+		start: time.Now(),
+		exit:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+
+	if r.opts.Client != nil || r.opts.Store != nil || r.opts.OnSnapshot != nil {
+		go r.run()
+	} else {
+		close(r.done)
+	}
+
+	return r
+}
+
+func (r *Recorder) record(caller, callee, endpoint, protocol string, err error) {
+	key := edgeKey{caller: caller, callee: callee, endpoint: endpoint, protocol: protocol}
+
+	r.Lock()
+	defer r.Unlock()
+
+	e, ok := r.edges[key]
+	if !ok {
+		e = &Edge{Caller: caller, Callee: callee, Endpoint: endpoint, Protocol: protocol}
+		r.edges[key] = e
+	}
+	e.Calls++
+	if err != nil {
+		e.Errors++
+	}
+	e.LastSeen = time.Now()
+}
+
+// Snapshot returns the edges recorded since the last snapshot and starts a
+// new interval.
+func (r *Recorder) Snapshot() Graph {
+	r.Lock()
+	edges := r.edges
+	start := r.start
+	r.edges = make(map[edgeKey]*Edge)
+	r.start = time.Now()
+	end := r.start
+	r.Unlock()
+
+	g := Graph{
+		Service: r.opts.Service,
+		Node:    r.opts.Node,
+		Start:   start,
+		End:     end,
+		Edges:   make([]Edge, 0, len(edges)),
+	}
+	for _, e := range edges {
+		g.Edges = append(g.Edges, *e)
+	}
+	sortEdges(g.Edges)
+
+	return g
+}
+
+func sortEdges(edges []Edge) {
+	sort.Slice(edges, func(i, j int) bool {
+		a, b := edges[i], edges[j]
+		if a.Caller != b.Caller {
+			return a.Caller < b.Caller
+		}
+		if a.Callee != b.Callee {
+			return a.Callee < b.Callee
+		}
+		if a.Endpoint != b.Endpoint {
+			return a.Endpoint < b.Endpoint
+		}
+		return a.Protocol < b.Protocol
+	})
+}
+
+func (r *Recorder) run() {
+	defer close(r.done)
+
+	t := time.NewTicker(r.opts.Interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-r.exit:
+			r.publish(r.Snapshot())
+			return
+		case <-t.C:
+			r.publish(r.Snapshot())
+		}
+	}
+}
+
+// publish sends the snapshot to the configured sinks.
+func (r *Recorder) publish(g Graph) {
+	if r.opts.OnSnapshot != nil {
+		r.opts.OnSnapshot(g)
+	}
+
+	if r.opts.Client != nil && len(r.opts.Topic) > 0 {
+		msg := r.opts.Client.NewMessage(r.opts.Topic, g, client.WithMessageContentType("application/json"))
+		if err := r.opts.Client.Publish(context.Background(), msg); err != nil {
+			logger.Errorf("depgraph: publishing snapshot: %v", err)
+		}
+	}
+
+	if r.opts.Store != nil {
+		b, err := json.Marshal(g)
+		if err != nil {
+			logger.Errorf("depgraph: encoding snapshot: %v", err)
+			return
+		}
+		rec := &store.Record{
+			Key:    g.Service + "/" + g.Node,
+			Value:  b,
+			Expiry: 3 * r.opts.Interval,
+		}
+		if err := r.opts.Store.Write(rec, store.WriteTo("", r.opts.Table)); err != nil {
+			logger.Errorf("depgraph: saving snapshot: %v", err)
+		}
+	}
+}
+
+// Stop publishes the edges of the last interval and stops publishing.
+func (r *Recorder) Stop() {
+	r.once.Do(func() {
+		close(r.exit)
+	})
+	<-r.done
+}
+
+// Client returns a client option recording the calls, streams and
+// publications of the client.
+func (r *Recorder) Client() client.Option {
+	return client.Wrap(r.ClientWrapper())
+}
+
+// ClientWrapper returns a client wrapper recording the calls, streams and
+// publications of the client.
+func (r *Recorder) ClientWrapper() client.Wrapper {
+	return func(c client.Client) client.Client {
+		return &recordingClient{Client: c, r: r}
+	}
+}
+
+type recordingClient struct {
+	client.Client
+	r *Recorder
+}
+
+func (c *recordingClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	err := c.Client.Call(ctx, req, rsp, opts...)
+	c.r.record(c.r.opts.Service, req.Service(), req.Endpoint(), c.Client.String(), err)
+	return err
+}
+
+func (c *recordingClient) Stream(ctx context.Context, req client.Request, opts ...client.CallOption) (client.Stream, error) {
+	s, err := c.Client.Stream(ctx, req, opts...)
+	c.r.record(c.r.opts.Service, req.Service(), req.Endpoint(), c.Client.String(), err)
+	return s, err
+}
+
+func (c *recordingClient) Publish(ctx context.Context, msg client.Message, opts ...client.PublishOption) error {
+	err := c.Client.Publish(ctx, msg, opts...)
+	// don't record the publication of the snapshots
+	if msg.Topic() != c.r.opts.Topic || c.r.opts.Client == nil {
+		c.r.record(c.r.opts.Service, msg.Topic(), msg.Topic(), ProtocolPublish, err)
+	}
+	return err
+}
+
+// SubscriberWrapper returns a subscriber wrapper recording the messages
+// received from their publishers, read from the Micro-From-Service header.
+func (r *Recorder) SubscriberWrapper() server.SubscriberWrapper {
+	return func(fn server.SubscriberFunc) server.SubscriberFunc {
+		return func(ctx context.Context, msg server.Message) error {
+			err := fn(ctx, msg)
+
+			caller := msg.Header()["Micro-From-Service"]
+			if len(caller) == 0 {
+				caller, _ = metadata.Get(ctx, "Micro-From-Service")
+			}
+			if len(caller) == 0 {
+				caller = unknownCaller
+			}
+
+			r.record(caller, r.opts.Service, msg.Topic(), ProtocolSubscribe, err)
+			return err
+		}
+	}
+}
+
+// Load reads the snapshots saved in the table of the store.
+func Load(s store.Store, table string) ([]Graph, error) {
+	keys, err := s.List(store.ListFrom("", table))
+	if err != nil {
+		return nil, err
+	}
+
+	graphs := make([]Graph, 0, len(keys))
+	for _, key := range keys {
+		recs, err := s.Read(key, store.ReadFrom("", table))
+		if err == store.ErrNotFound {
+			// expired since listed
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		var g Graph
+		if err := json.Unmarshal(recs[0].Value, &g); err != nil {
+			return nil, err
+		}
+		graphs = append(graphs, g)
+	}
+
+	return graphs, nil
+}
+
+// Merge sums the edges of the snapshots, e.g. of all instances, by caller,
+// callee, endpoint and protocol.
+func Merge(graphs ...Graph) []Edge {
+	edges := make(map[edgeKey]*Edge)
+	for _, g := range graphs {
+		for _, e := range g.Edges {
+			key := edgeKey{caller: e.Caller, callee: e.Callee, endpoint: e.Endpoint, protocol: e.Protocol}
+			m, ok := edges[key]
+			if !ok {
+				m = &Edge{Caller: e.Caller, Callee: e.Callee, Endpoint: e.Endpoint, Protocol: e.Protocol}
+				edges[key] = m
+			}
+			m.Calls += e.Calls
+			m.Errors += e.Errors
+			if e.LastSeen.After(m.LastSeen) {
+				m.LastSeen = e.LastSeen
+			}
+		}
+	}
+
+	merged := make([]Edge, 0, len(edges))
+	for _, e := range edges {
+		merged = append(merged, *e)
+	}
+	sortEdges(merged)
+
+	return merged
+}
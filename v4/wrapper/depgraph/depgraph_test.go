@@ -0,0 +1,87 @@
+package depgraph
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/store"
+)
+
+type testClient struct {
+	client.Client
+	err error
+}
+
+func (c *testClient) NewRequest(service, endpoint string, req interface{}, opts ...client.RequestOption) client.Request {
+	return client.NewClient().NewRequest(service, endpoint, req, opts...)
+}
+
+func (c *testClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	return c.err
+}
+
+func (c *testClient) String() string {
+	return "test"
+}
+
+func TestRecorder(t *testing.T) {
+	r := New(Service("api"))
+	defer r.Stop()
+
+	ok := r.ClientWrapper()(&testClient{})
+	failing := r.ClientWrapper()(&testClient{err: errors.New("down")})
+
+	for i := 0; i < 3; i++ {
+		ok.Call(context.Background(), ok.NewRequest("users", "Users.Get", nil), nil)
+	}
+	failing.Call(context.Background(), failing.NewRequest("orders", "Orders.List", nil), nil)
+
+	g := r.Snapshot()
+	if g.Service != "api" || len(g.Node) == 0 {
+		t.Fatalf("Unexpected graph %+v", g)
+	}
+	if len(g.Edges) != 2 {
+		t.Fatalf("Expected 2 edges, got %d", len(g.Edges))
+	}
+
+	orders, users := g.Edges[0], g.Edges[1]
+	if users.Callee != "users" || users.Endpoint != "Users.Get" || users.Protocol != "test" || users.Calls != 3 || users.Errors != 0 {
+		t.Errorf("Unexpected edge %+v", users)
+	}
+	if orders.Callee != "orders" || orders.Calls != 1 || orders.Errors != 1 {
+		t.Errorf("Unexpected edge %+v", orders)
+	}
+
+	// snapshots start a new interval
+	if g := r.Snapshot(); len(g.Edges) != 0 {
+		t.Errorf("Expected no edges in the new interval, got %d", len(g.Edges))
+	}
+}
+
+func TestStore(t *testing.T) {
+	s := store.NewMemoryStore()
+
+	for _, node := range []string{"1", "2"} {
+		r := New(Service("api"), Node(node), Store(s, "graph"), Interval(time.Hour))
+		c := r.ClientWrapper()(&testClient{})
+		c.Call(context.Background(), c.NewRequest("users", "Users.Get", nil), nil)
+		// publishes the last interval
+		r.Stop()
+	}
+
+	graphs, err := Load(s, "graph")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(graphs) != 2 {
+		t.Fatalf("Expected 2 snapshots, got %d", len(graphs))
+	}
+
+	edges := Merge(graphs...)
+	if len(edges) != 1 || edges[0].Calls != 2 {
+		t.Fatalf("Expected 1 edge with 2 calls, got %+v", edges)
+	}
+}
@@ -0,0 +1,94 @@
+package depgraph
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/store"
+)
+
+// DefaultInterval is how often snapshots are published.
+var DefaultInterval = time.Minute
+
+// Options configure the recorder.
+type Options struct {
+	// Service is the name of the service recorded as the caller.
+	Service string
+	// Node is the id of the instance, snapshots of instances are published
+	// separately. Defaults to a random id.
+	Node string
+	// Interval is how often snapshots are published.
+	Interval time.Duration
+
+	// Client publishes the snapshots to Topic, if set.
+	Client client.Client
+	Topic  string
+	// Store saves the snapshots, if set, under <service>/<node> in Table.
+	// The records expire after three intervals, so stopped instances drop
+	// out of the graph.
+	Store store.Store
+	Table string
+	// OnSnapshot is called with every snapshot.
+	OnSnapshot func(Graph)
+}
+
+// Option sets an option.
+type Option func(*Options)
+
+// Service sets the name of the service recorded as the caller.
+func Service(name string) Option {
+	return func(o *Options) {
+		o.Service = name
+	}
+}
+
+// Node sets the id of the instance.
+func Node(id string) Option {
+	return func(o *Options) {
+		o.Node = id
+	}
+}
+
+// Interval sets how often snapshots are published.
+func Interval(d time.Duration) Option {
+	return func(o *Options) {
+		o.Interval = d
+	}
+}
+
+// Publish publishes the snapshots to the topic with the client.
+func Publish(c client.Client, topic string) Option {
+	return func(o *Options) {
+		o.Client = c
+		o.Topic = topic
+	}
+}
+
+// Store saves the snapshots in the table of the store.
+func Store(s store.Store, table string) Option {
+	return func(o *Options) {
+		o.Store = s
+		o.Table = table
+	}
+}
+
+// OnSnapshot sets the func called with every snapshot.
+func OnSnapshot(fn func(Graph)) Option {
+	return func(o *Options) {
+		o.OnSnapshot = fn
+	}
+}
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		Node:     uuid.New().String(),
+		Interval: DefaultInterval,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return options
+}
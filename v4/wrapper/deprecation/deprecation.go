@@ -0,0 +1,237 @@
+// Package deprecation marks endpoints as deprecated, signals it to callers
+// with the Deprecation and Sunset headers and tracks which clients still call
+// them, to drive API migrations with data.
+package deprecation
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-micro.dev/v4/codec"
+	"go-micro.dev/v4/errors"
+	"go-micro.dev/v4/logger"
+	"go-micro.dev/v4/metadata"
+	"go-micro.dev/v4/server"
+)
+
+const (
+	// HeaderDeprecation is set on responses of deprecated endpoints, to the
+	// deprecation date as "@<unix time>" or "true", see RFC 9745.
+	HeaderDeprecation = "Deprecation"
+	// HeaderSunset is the HTTP date the endpoint stops working, see RFC 8594.
+	HeaderSunset = "Sunset"
+	// HeaderLink links to the migration docs.
+	HeaderLink = "Link"
+
+	// unknownClient is reported for callers without an id
+	unknownClient = "unknown"
+)
+
+// Usage is how often a client called a deprecated endpoint.
+type Usage struct {
+	Endpoint string
+	Client   string
+	Count    int64
+	Last     time.Time
+}
+
+type usageKey struct {
+	endpoint string
+	client   string
+}
+
+type usage struct {
+	Usage
+	logged time.Time
+}
+
+// Tracker tracks the calls of deprecated endpoints.
+type Tracker struct {
+	opts Options
+
+	sync.Mutex
+	usage map[usageKey]*usage
+}
+
+// New returns a tracker of the deprecated endpoints.
+func New(opts ...Option) *Tracker {
+	return &Tracker{
+		opts:  newOptions(opts...),
+		usage: make(map[usageKey]*usage),
+	}
+}
+
+// Server returns a server option adding the handler wrapper and the codecs
+// setting the deprecation headers on responses.
+func (t *Tracker) Server() server.Option {
+	return func(o *server.Options) {
+		o.HdlrWrappers = append(o.HdlrWrappers, t.HandlerWrapper())
+
+		if o.Codecs == nil {
+			o.Codecs = make(map[string]codec.NewCodec)
+		}
+		for ct, c := range server.DefaultCodecs {
+			if wc, ok := o.Codecs[ct]; ok {
+				c = wc
+			}
+			o.Codecs[ct] = t.newCodec(c)
+		}
+	}
+}
+
+// HandlerWrapper returns a handler wrapper tracking the calls of deprecated
+// endpoints, and rejecting them past their sunset if configured to.
+func (t *Tracker) HandlerWrapper() server.HandlerWrapper {
+	return func(fn server.HandlerFunc) server.HandlerFunc {
+		return func(ctx context.Context, req server.Request, rsp interface{}) error {
+			e, ok := t.opts.Endpoints[req.Endpoint()]
+			if !ok {
+				return fn(ctx, req, rsp)
+			}
+
+			now := time.Now()
+			t.track(ctx, Call{Endpoint: e, Client: t.clientID(ctx), Time: now})
+
+			if t.opts.RejectAfterSunset && !e.Sunset.IsZero() && now.After(e.Sunset) {
+				return errors.New(req.Service(), fmt.Sprintf("%s was sunset on %s", e.Name, e.Sunset.Format(time.RFC3339)), http.StatusGone)
+			}
+
+			return fn(ctx, req, rsp)
+		}
+	}
+}
+
+func (t *Tracker) clientID(ctx context.Context) string {
+	if t.opts.ClientID != nil {
+		if id := t.opts.ClientID(ctx); len(id) > 0 {
+			return id
+		}
+		return unknownClient
+	}
+
+	if id, ok := metadata.Get(ctx, "Micro-From-Service"); ok && len(id) > 0 {
+		return id
+	}
+	if id, ok := metadata.Get(ctx, "Remote"); ok && len(id) > 0 {
+		return id
+	}
+
+	return unknownClient
+}
+
+func (t *Tracker) track(ctx context.Context, c Call) {
+	key := usageKey{endpoint: c.Endpoint.Name, client: c.Client}
+
+	t.Lock()
+	u, ok := t.usage[key]
+	if !ok {
+		u = &usage{Usage: Usage{Endpoint: key.endpoint, Client: key.client}}
+		t.usage[key] = u
+	}
+	u.Count++
+	u.Last = c.Time
+
+	log := t.opts.LogInterval >= 0 && c.Time.Sub(u.logged) >= t.opts.LogInterval
+	if log {
+		u.logged = c.Time
+	}
+	t.Unlock()
+
+	if log {
+		sunset := "no sunset"
+		if !c.Endpoint.Sunset.IsZero() {
+			sunset = "sunset on " + c.Endpoint.Sunset.Format(time.RFC3339)
+		}
+		logger.Warnf("Deprecated endpoint %s called by %s, %s", c.Endpoint.Name, c.Client, sunset)
+	}
+
+	if t.opts.OnCall != nil {
+		t.opts.OnCall(ctx, c)
+	}
+}
+
+// Usage returns how often each client called the deprecated endpoints,
+// sorted by endpoint and client.
+func (t *Tracker) Usage() []Usage {
+	t.Lock()
+	usage := make([]Usage, 0, len(t.usage))
+	for _, u := range t.usage {
+		usage = append(usage, u.Usage)
+	}
+	t.Unlock()
+
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Endpoint != usage[j].Endpoint {
+			return usage[i].Endpoint < usage[j].Endpoint
+		}
+		return usage[i].Client < usage[j].Client
+	})
+
+	return usage
+}
+
+// Headers returns the deprecation headers of the endpoint, nil if it isn't
+// deprecated.
+func (t *Tracker) Headers(endpoint string) map[string]string {
+	e, ok := t.opts.Endpoints[endpoint]
+	if !ok {
+		return nil
+	}
+
+	h := map[string]string{HeaderDeprecation: "true"}
+	if !e.Since.IsZero() {
+		h[HeaderDeprecation] = "@" + strconv.FormatInt(e.Since.Unix(), 10)
+	}
+	if !e.Sunset.IsZero() {
+		h[HeaderSunset] = e.Sunset.UTC().Format(http.TimeFormat)
+	}
+	if len(e.Link) > 0 {
+		h[HeaderLink] = fmt.Sprintf("<%s>; rel=\"deprecation\"", e.Link)
+	}
+
+	return h
+}
+
+func (t *Tracker) newCodec(c codec.NewCodec) codec.NewCodec {
+	return func(conn io.ReadWriteCloser) codec.Codec {
+		return &serverCodec{Codec: c(conn), t: t}
+	}
+}
+
+// serverCodec sets the deprecation headers on the responses of the request
+// it read.
+type serverCodec struct {
+	codec.Codec
+	t        *Tracker
+	endpoint string
+}
+
+func (s *serverCodec) ReadHeader(m *codec.Message, mt codec.MessageType) error {
+	s.endpoint = m.Endpoint
+	if len(s.endpoint) == 0 {
+		s.endpoint = m.Method
+	}
+	return s.Codec.ReadHeader(m, mt)
+}
+
+func (s *serverCodec) Write(m *codec.Message, b interface{}) error {
+	if h := s.t.Headers(s.endpoint); h != nil {
+		if m.Header == nil {
+			m.Header = make(map[string]string)
+		}
+		for k, v := range h {
+			m.Header[k] = v
+		}
+	}
+	return s.Codec.Write(m, b)
+}
+
+func (s *serverCodec) String() string {
+	return s.Codec.String()
+}
@@ -0,0 +1,140 @@
+package deprecation
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/codec"
+	"go-micro.dev/v4/errors"
+	"go-micro.dev/v4/metadata"
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/server"
+)
+
+type Request struct{}
+
+type Response struct{}
+
+type Test struct{}
+
+func (t *Test) Old(ctx context.Context, req *Request, rsp *Response) error {
+	return nil
+}
+
+func (t *Test) Gone(ctx context.Context, req *Request, rsp *Response) error {
+	return nil
+}
+
+func (t *Test) New(ctx context.Context, req *Request, rsp *Response) error {
+	return nil
+}
+
+func TestTracker(t *testing.T) {
+	r := registry.NewMemoryRegistry()
+
+	var calls []Call
+	tr := New(
+		Deprecate("Test.Old", time.Now().Add(time.Hour)),
+		Deprecate("Test.Gone", time.Now().Add(-time.Hour)),
+		RejectAfterSunset(),
+		LogInterval(-1),
+		OnCall(func(ctx context.Context, c Call) {
+			calls = append(calls, c)
+		}),
+	)
+
+	s := server.NewServer(
+		server.Name("test"),
+		server.Address("127.0.0.1:0"),
+		server.Registry(r),
+		tr.Server(),
+	)
+	if err := s.Handle(s.NewHandler(&Test{})); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	c := client.NewClient(client.Registry(r))
+	ctx := metadata.NewContext(context.Background(), metadata.Metadata{"Micro-From-Service": "billing"})
+
+	for _, endpoint := range []string{"Test.Old", "Test.Old", "Test.New"} {
+		if err := c.Call(ctx, c.NewRequest("test", endpoint, &Request{}), new(Response)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := c.Call(context.Background(), c.NewRequest("test", "Test.Gone", &Request{}), new(Response))
+	if me := errors.Parse(err.Error()); me.Code != http.StatusGone {
+		t.Fatalf("Expected a 410 error, got %v", err)
+	}
+
+	if len(calls) != 3 {
+		t.Fatalf("Expected 3 calls of deprecated endpoints, got %d", len(calls))
+	}
+
+	usage := tr.Usage()
+	if len(usage) != 2 {
+		t.Fatalf("Expected usage of 2 clients, got %+v", usage)
+	}
+	if usage[1].Endpoint != "Test.Old" || usage[1].Client != "billing" || usage[1].Count != 2 {
+		t.Fatalf("Unexpected usage %+v", usage[1])
+	}
+	if usage[0].Endpoint != "Test.Gone" || usage[0].Count != 1 {
+		t.Fatalf("Unexpected usage %+v", usage[0])
+	}
+}
+
+type stubCodec struct {
+	codec.Codec
+	header map[string]string
+}
+
+func (s *stubCodec) ReadHeader(m *codec.Message, mt codec.MessageType) error {
+	return nil
+}
+
+func (s *stubCodec) Write(m *codec.Message, b interface{}) error {
+	s.header = m.Header
+	return nil
+}
+
+func TestHeaders(t *testing.T) {
+	since := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tr := New(DeprecateEndpoint(Endpoint{
+		Name:   "Test.Old",
+		Since:  since,
+		Sunset: sunset,
+		Link:   "https://example.com/migrate",
+	}))
+
+	stub := &stubCodec{}
+	c := &serverCodec{Codec: stub, t: tr}
+
+	c.ReadHeader(&codec.Message{Endpoint: "Test.Old"}, codec.Request)
+	c.Write(&codec.Message{}, nil)
+
+	if v := stub.header[HeaderDeprecation]; v != "@1640995200" {
+		t.Fatalf("Unexpected deprecation header %q", v)
+	}
+	if v := stub.header[HeaderSunset]; v != "Wed, 01 Jun 2022 00:00:00 GMT" {
+		t.Fatalf("Unexpected sunset header %q", v)
+	}
+	if v := stub.header[HeaderLink]; v != `<https://example.com/migrate>; rel="deprecation"` {
+		t.Fatalf("Unexpected link header %q", v)
+	}
+
+	c.ReadHeader(&codec.Message{Endpoint: "Test.New"}, codec.Request)
+	c.Write(&codec.Message{}, nil)
+
+	if _, ok := stub.header[HeaderDeprecation]; ok {
+		t.Fatal("Unexpected deprecation header on a current endpoint")
+	}
+}
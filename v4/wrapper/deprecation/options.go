@@ -0,0 +1,99 @@
+package deprecation
+
+import (
+	"context"
+	"time"
+)
+
+// Endpoint is a deprecated endpoint.
+type Endpoint struct {
+	// Name of the endpoint, e.g. "Greeter.Hello"
+	Name string
+	// Since is when the endpoint was deprecated, optional
+	Since time.Time
+	// Sunset is when the endpoint stops working, optional
+	Sunset time.Time
+	// Link points to the migration docs, optional
+	Link string
+}
+
+// Call is a call of a deprecated endpoint.
+type Call struct {
+	Endpoint Endpoint
+	Client   string
+	Time     time.Time
+}
+
+// Options configure the deprecation wrapper.
+type Options struct {
+	// Endpoints are the deprecated endpoints by name.
+	Endpoints map[string]Endpoint
+	// ClientID returns the id of the caller. Defaults to the
+	// Micro-From-Service header, then the remote address.
+	ClientID func(ctx context.Context) string
+	// OnCall is called on every call of a deprecated endpoint, e.g. to
+	// count the calls per client in a metric.
+	OnCall func(ctx context.Context, c Call)
+	// LogInterval is how often a call of a client to an endpoint is logged.
+	// Defaults to an hour, a negative interval disables logging.
+	LogInterval time.Duration
+	// RejectAfterSunset rejects calls to endpoints past their sunset.
+	RejectAfterSunset bool
+}
+
+// Option sets an option.
+type Option func(*Options)
+
+// Deprecate marks the endpoint as deprecated, sunset may be zero.
+func Deprecate(name string, sunset time.Time) Option {
+	return DeprecateEndpoint(Endpoint{Name: name, Sunset: sunset})
+}
+
+// DeprecateEndpoint marks the endpoint as deprecated.
+func DeprecateEndpoint(e Endpoint) Option {
+	return func(o *Options) {
+		o.Endpoints[e.Name] = e
+	}
+}
+
+// ClientID sets the func returning the id of the caller.
+func ClientID(fn func(ctx context.Context) string) Option {
+	return func(o *Options) {
+		o.ClientID = fn
+	}
+}
+
+// OnCall sets the func called on every call of a deprecated endpoint.
+func OnCall(fn func(ctx context.Context, c Call)) Option {
+	return func(o *Options) {
+		o.OnCall = fn
+	}
+}
+
+// LogInterval sets how often a call of a client to an endpoint is logged.
+func LogInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.LogInterval = d
+	}
+}
+
+// RejectAfterSunset rejects calls to endpoints past their sunset with a 410
+// error.
+func RejectAfterSunset() Option {
+	return func(o *Options) {
+		o.RejectAfterSunset = true
+	}
+}
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		Endpoints:   make(map[string]Endpoint),
+		LogInterval: time.Hour,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return options
+}
@@ -0,0 +1,431 @@
+// Package edgecache provides an HTTP response cache for the api gateway.
+package edgecache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-micro.dev/v4/cache"
+	"go-micro.dev/v4/logger"
+)
+
+const (
+	// HeaderCache reports whether the response was served from the cache.
+	HeaderCache = "X-Cache"
+
+	keyPrefix = "edgecache:"
+)
+
+// cacheable are the status codes which are cached.
+var cacheable = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusMovedPermanently:     true,
+	http.StatusNotFound:             true,
+	http.StatusGone:                 true,
+}
+
+// entry is a cached response.
+type entry struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+	Stored time.Time   `json:"stored"`
+}
+
+// Handler caches the responses of the wrapped handler according to the
+// Cache-Control header set by the backend.
+type Handler struct {
+	opts    Options
+	handler http.Handler
+}
+
+// NewHandler returns a handler caching the responses of h.
+func NewHandler(h http.Handler, opts ...Option) *Handler {
+	options := Options{
+		MaxBodySize: 1 << 20,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if options.Cache == nil {
+		options.Cache = cache.NewCache()
+	}
+
+	return &Handler{
+		opts:    options,
+		handler: h,
+	}
+}
+
+// Wrapper returns a middleware caching responses, for use with the gateway's
+// http handler wrappers.
+func Wrapper(opts ...Option) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return NewHandler(h, opts...)
+	}
+}
+
+// Key returns the normalized cache key of the request. The host is lower
+// cased, the path cleaned and the query sorted without the ignored parameters,
+// followed by the values of the VaryHeaders. HEAD and GET requests share a key.
+func (h *Handler) Key(r *http.Request) string {
+	key := h.urlKey(r)
+	if len(h.opts.VaryHeaders) == 0 {
+		return key
+	}
+
+	vary := url.Values{}
+	for _, k := range h.opts.VaryHeaders {
+		vary.Set(k, r.Header.Get(k))
+	}
+	return key + "#" + vary.Encode()
+}
+
+// urlKey returns the key of the request's url. With VaryHeaders it holds
+// the keys of the cached variants of the url.
+func (h *Handler) urlKey(r *http.Request) string {
+	p := r.URL.Path
+	if len(p) == 0 {
+		p = "/"
+	}
+	p = path.Clean(p)
+
+	query := url.Values{}
+	for k, v := range r.URL.Query() {
+		if h.ignored(k) {
+			continue
+		}
+		sort.Strings(v)
+		query[k] = v
+	}
+
+	key := keyPrefix + strings.ToLower(r.Host) + p
+	if q := query.Encode(); len(q) > 0 {
+		key += "?" + q
+	}
+
+	return key
+}
+
+func (h *Handler) ignored(param string) bool {
+	for _, i := range h.opts.IgnoreQuery {
+		if strings.HasSuffix(i, "*") {
+			if strings.HasPrefix(param, strings.TrimSuffix(i, "*")) {
+				return true
+			}
+		} else if i == param {
+			return true
+		}
+	}
+	return false
+}
+
+// Purge removes the cached responses for the request's url, of every
+// variant.
+func (h *Handler) Purge(ctx context.Context, r *http.Request) error {
+	key := h.urlKey(r)
+
+	var variants []string
+	h.load(ctx, key, &variants)
+
+	for _, k := range append(variants, key) {
+		if err := h.opts.Cache.Delete(ctx, k); err != nil && err != cache.ErrKeyNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// PurgeHandler returns a handler purging the url of every PURGE or DELETE
+// request it receives. It should only be exposed internally.
+func (h *Handler) PurgeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PURGE" && r.Method != http.MethodDelete {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		if err := h.Purge(r.Context(), r); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+
+	// requests with credentials are never shared
+	if len(r.Header.Get("Authorization")) > 0 {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+
+	key, urlKey := h.Key(r), h.urlKey(r)
+
+	if e := h.get(r.Context(), urlKey, key); e != nil {
+		h.write(w, r, e)
+		return
+	}
+
+	rec := &recorder{ResponseWriter: w, status: http.StatusOK, limit: h.opts.MaxBodySize}
+	w.Header().Set(HeaderCache, "MISS")
+	h.handler.ServeHTTP(rec, r)
+
+	// a HEAD response has no body to cache
+	if r.Method != http.MethodGet || rec.overflow {
+		return
+	}
+
+	ttl, ok := h.ttl(rec.status, w.Header())
+	if !ok {
+		return
+	}
+
+	header := w.Header().Clone()
+	header.Del(HeaderCache)
+
+	e := &entry{
+		Status: rec.status,
+		Header: header,
+		Body:   rec.body.Bytes(),
+		Stored: time.Now(),
+	}
+
+	if err := h.put(r.Context(), key, e, ttl); err != nil {
+		logger.Logf(logger.ErrorLevel, "[edgecache] failed to cache %s: %v", key, err)
+		return
+	}
+
+	if key != urlKey {
+		if err := h.addVariant(r.Context(), urlKey, key, ttl); err != nil {
+			logger.Logf(logger.ErrorLevel, "[edgecache] failed to index %s: %v", key, err)
+		}
+	}
+}
+
+// get returns the cached response of the key. Variants are only served
+// while listed by the key of their url, purging it purges all of them.
+func (h *Handler) get(ctx context.Context, urlKey, key string) *entry {
+	if key != urlKey {
+		var variants []string
+		h.load(ctx, urlKey, &variants)
+		if !contains(variants, key) {
+			return nil
+		}
+	}
+
+	e := new(entry)
+	if _, ok := h.load(ctx, key, e); !ok {
+		return nil
+	}
+	return e
+}
+
+// addVariant lists the key as a variant of the url, for as long as its
+// longest lived variant. Variants lost by concurrent updates are only
+// fetched again.
+func (h *Handler) addVariant(ctx context.Context, urlKey, key string, ttl time.Duration) error {
+	var variants []string
+	expires, ok := h.load(ctx, urlKey, &variants)
+	if ok && !expires.IsZero() {
+		if d := time.Until(expires); d > ttl {
+			ttl = d
+		}
+	}
+
+	if !contains(variants, key) {
+		variants = append(variants, key)
+	}
+	return h.put(ctx, urlKey, variants, ttl)
+}
+
+// load decodes the cached value of the key into v, reporting whether it
+// was found and when it expires.
+func (h *Handler) load(ctx context.Context, key string, v interface{}) (time.Time, bool) {
+	val, expires, err := h.opts.Cache.Get(ctx, key)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	if !expires.IsZero() && time.Now().After(expires) {
+		return time.Time{}, false
+	}
+
+	var b []byte
+	switch val := val.(type) {
+	case []byte:
+		b = val
+	case string:
+		b = []byte(val)
+	default:
+		return time.Time{}, false
+	}
+
+	if err := json.Unmarshal(b, v); err != nil {
+		return time.Time{}, false
+	}
+
+	return expires, true
+}
+
+func (h *Handler) put(ctx context.Context, key string, v interface{}, ttl time.Duration) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return h.opts.Cache.Put(ctx, key, b, ttl)
+}
+
+func (h *Handler) write(w http.ResponseWriter, r *http.Request, e *entry) {
+	for k, v := range e.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set(HeaderCache, "HIT")
+	w.Header().Set("Age", strconv.Itoa(int(time.Since(e.Stored)/time.Second)))
+	w.WriteHeader(e.Status)
+
+	if r.Method != http.MethodHead {
+		w.Write(e.Body)
+	}
+}
+
+// ttl returns how long the response may be cached by a shared cache.
+func (h *Handler) ttl(status int, header http.Header) (time.Duration, bool) {
+	if !cacheable[status] {
+		return 0, false
+	}
+
+	// responses setting cookies are specific to the client
+	if len(header.Get("Set-Cookie")) > 0 {
+		return 0, false
+	}
+
+	for _, v := range header.Values("Vary") {
+		for _, k := range strings.Split(v, ",") {
+			k = strings.ToLower(strings.TrimSpace(k))
+			if len(k) == 0 {
+				continue
+			}
+			if k == "*" || !h.varies(k) {
+				return 0, false
+			}
+		}
+	}
+
+	maxAge, sMaxAge := -1, -1
+
+	for _, v := range header.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			d = strings.ToLower(strings.TrimSpace(d))
+			name, value := d, ""
+			if i := strings.Index(d, "="); i > 0 {
+				name, value = d[:i], strings.Trim(d[i+1:], `"`)
+			}
+
+			switch name {
+			case "no-store", "no-cache", "private":
+				return 0, false
+			case "max-age":
+				if n, err := strconv.Atoi(value); err == nil {
+					maxAge = n
+				}
+			case "s-maxage":
+				if n, err := strconv.Atoi(value); err == nil {
+					sMaxAge = n
+				}
+			}
+		}
+	}
+
+	var ttl time.Duration
+	switch {
+	case sMaxAge >= 0:
+		ttl = time.Duration(sMaxAge) * time.Second
+	case maxAge >= 0:
+		ttl = time.Duration(maxAge) * time.Second
+	default:
+		ttl = h.opts.DefaultTTL
+	}
+
+	return ttl, ttl > 0
+}
+
+func (h *Handler) varies(header string) bool {
+	for _, k := range h.opts.VaryHeaders {
+		if k == header {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// recorder passes the response through while buffering the body for caching.
+type recorder struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	limit    int
+	overflow bool
+	wrote    bool
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.wrote {
+		return
+	}
+	r.wrote = true
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if !r.wrote {
+		r.WriteHeader(http.StatusOK)
+	}
+
+	if !r.overflow {
+		if r.body.Len()+len(b) > r.limit {
+			r.overflow = true
+			r.body.Reset()
+		} else {
+			r.body.Write(b)
+		}
+	}
+
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher, streamed responses are not cached.
+func (r *recorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		r.overflow = true
+		r.body.Reset()
+		f.Flush()
+	}
+}
@@ -0,0 +1,140 @@
+package edgecache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	var calls int
+
+	backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.URL.Path {
+		case "/public":
+			w.Header().Set("Cache-Control", "public, max-age=60")
+		case "/private":
+			w.Header().Set("Cache-Control", "private, max-age=60")
+		case "/shared":
+			w.Header().Set("Cache-Control", "max-age=0, s-maxage=60")
+		}
+		w.Write([]byte("hello"))
+	})
+
+	h := NewHandler(backend, IgnoreQuery("utm_*"))
+
+	testData := []struct {
+		path  string
+		calls int
+		hit   bool
+	}{
+		{"/public", 1, false},
+		{"/public", 1, true},
+		{"/public?utm_source=foo", 1, true},
+		{"/public?a=1", 2, false},
+		{"/private", 3, false},
+		{"/private", 4, false},
+		{"/shared", 5, false},
+		{"/shared", 5, true},
+		{"/none", 6, false},
+		{"/none", 7, false},
+	}
+
+	for _, d := range testData {
+		rsp := httptest.NewRecorder()
+		h.ServeHTTP(rsp, httptest.NewRequest("GET", d.path, nil))
+
+		if rsp.Body.String() != "hello" {
+			t.Fatalf("%s: expected body hello, got %s", d.path, rsp.Body.String())
+		}
+		if calls != d.calls {
+			t.Fatalf("%s: expected %d backend calls, got %d", d.path, d.calls, calls)
+		}
+		if hit := rsp.Header().Get(HeaderCache) == "HIT"; hit != d.hit {
+			t.Fatalf("%s: expected hit %v, got %v", d.path, d.hit, hit)
+		}
+	}
+
+	// purging drops the cached response
+	rsp := httptest.NewRecorder()
+	h.PurgeHandler().ServeHTTP(rsp, httptest.NewRequest("PURGE", "/public", nil))
+	if rsp.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d", http.StatusNoContent, rsp.Code)
+	}
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/public", nil))
+	if calls != 8 {
+		t.Fatalf("Expected purged response to be fetched again, got %d calls", calls)
+	}
+}
+
+func TestKey(t *testing.T) {
+	h := NewHandler(nil, IgnoreQuery("fbclid"))
+
+	a := httptest.NewRequest("GET", "http://Example.com/foo/../bar?b=2&a=1&fbclid=x", nil)
+	b := httptest.NewRequest("HEAD", "http://example.com/bar?a=1&b=2", nil)
+
+	if h.Key(a) != h.Key(b) {
+		t.Fatalf("Expected equal keys, got %s and %s", h.Key(a), h.Key(b))
+	}
+
+	h = NewHandler(nil, VaryHeaders("Accept-Language"))
+	b.Header.Set("Accept-Language", "de")
+	if h.Key(a) == h.Key(b) {
+		t.Fatalf("Expected the keys to differ by the vary header, got %s", h.Key(a))
+	}
+}
+
+func TestVary(t *testing.T) {
+	var calls int
+
+	backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Vary", "Accept-Language")
+		w.Write([]byte(r.Header.Get("Accept-Language")))
+	})
+
+	// responses varying on headers which aren't part of the key are not cached
+	h := NewHandler(backend, DefaultTTL(time.Minute))
+	for i := 0; i < 2; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}
+	if calls != 2 {
+		t.Fatalf("Expected 2 calls, got %d", calls)
+	}
+
+	calls = 0
+	h = NewHandler(backend, DefaultTTL(time.Minute), VaryHeaders("Accept-Language"))
+
+	for _, lang := range []string{"en", "en", "de"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Language", lang)
+
+		rsp := httptest.NewRecorder()
+		h.ServeHTTP(rsp, req)
+
+		if rsp.Body.String() != lang {
+			t.Fatalf("Expected %s, got %s", lang, rsp.Body.String())
+		}
+	}
+
+	if calls != 2 {
+		t.Fatalf("Expected 2 calls, got %d", calls)
+	}
+
+	// purging the url purges every variant
+	if err := h.Purge(context.Background(), httptest.NewRequest("GET", "/", nil)); err != nil {
+		t.Fatal(err)
+	}
+	for _, lang := range []string{"en", "de"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Language", lang)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if calls != 4 {
+		t.Fatalf("Expected 4 calls after the purge, got %d", calls)
+	}
+}
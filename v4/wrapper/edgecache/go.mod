@@ -0,0 +1,7 @@
+module github.com/go-micro/plugins/v4/wrapper/edgecache
+
+go 1.17
+
+require go-micro.dev/v4 v4.9.0
+
+require github.com/google/uuid v1.2.0 // indirect
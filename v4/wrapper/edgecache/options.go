@@ -0,0 +1,66 @@
+package edgecache
+
+import (
+	"strings"
+	"time"
+
+	"go-micro.dev/v4/cache"
+)
+
+// Options configure the edge cache.
+type Options struct {
+	// Cache stores the responses, e.g. the memory or redis cache. Defaults to memory.
+	Cache cache.Cache
+	// DefaultTTL is used for responses without a Cache-Control max age.
+	// Defaults to 0, only responses with an explicit max age are cached.
+	DefaultTTL time.Duration
+	// MaxBodySize is the largest response body which is cached. Defaults to 1MB.
+	MaxBodySize int
+	// IgnoreQuery are query parameters dropped from the cache key, a trailing
+	// "*" matches by prefix, e.g. "utm_*"
+	IgnoreQuery []string
+	// VaryHeaders are request headers which are part of the cache key.
+	// Responses which vary on any other header are not cached.
+	VaryHeaders []string
+}
+
+// Option sets an option.
+type Option func(*Options)
+
+// Cache sets the cache responses are stored in.
+func Cache(c cache.Cache) Option {
+	return func(o *Options) {
+		o.Cache = c
+	}
+}
+
+// DefaultTTL sets how long responses without a max age are cached.
+func DefaultTTL(d time.Duration) Option {
+	return func(o *Options) {
+		o.DefaultTTL = d
+	}
+}
+
+// MaxBodySize sets the largest response body which is cached.
+func MaxBodySize(n int) Option {
+	return func(o *Options) {
+		o.MaxBodySize = n
+	}
+}
+
+// IgnoreQuery drops the given query parameters from the cache key so that
+// e.g. tracking parameters don't fragment the cache.
+func IgnoreQuery(params ...string) Option {
+	return func(o *Options) {
+		o.IgnoreQuery = append(o.IgnoreQuery, params...)
+	}
+}
+
+// VaryHeaders adds request headers to the cache key, e.g. "Accept-Encoding".
+func VaryHeaders(headers ...string) Option {
+	return func(o *Options) {
+		for _, h := range headers {
+			o.VaryHeaders = append(o.VaryHeaders, strings.ToLower(h))
+		}
+	}
+}
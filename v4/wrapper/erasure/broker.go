@@ -0,0 +1,69 @@
+package erasure
+
+import (
+	"go-micro.dev/v4/broker"
+	"go-micro.dev/v4/logger"
+)
+
+type erasureBroker struct {
+	broker.Broker
+	opts Options
+}
+
+// NewBroker wraps the broker so the bodies of messages tagged with data
+// subjects, with the MetadataKey header, are encrypted with the keys of the
+// subjects. Subscribers acknowledge messages whose keys were destroyed
+// without handling them. The keys are set by Shred.
+func NewBroker(b broker.Broker, opts ...Option) broker.Broker {
+	var options Options
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if options.Keys == nil {
+		logger.Fatal("[erasure] no keys set")
+	}
+
+	return &erasureBroker{Broker: b, opts: options}
+}
+
+func (e *erasureBroker) Publish(topic string, m *broker.Message, opts ...broker.PublishOption) error {
+	subjects := split(m.Header[MetadataKey])
+	if len(subjects) == 0 {
+		return e.Broker.Publish(topic, m, opts...)
+	}
+
+	body, err := seal(e.opts.Keys, subjects, m.Body, nil)
+	if err != nil {
+		return err
+	}
+	return e.Broker.Publish(topic, &broker.Message{Header: m.Header, Body: body}, opts...)
+}
+
+func (e *erasureBroker) Subscribe(topic string, h broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	return e.Broker.Subscribe(topic, func(ev broker.Event) error {
+		m := ev.Message()
+		if m == nil || !sealed(m.Body) {
+			return h(ev)
+		}
+
+		body, err := open(e.opts.Keys, m.Body, nil)
+		if err == ErrShredded {
+			logger.Debugf("[erasure] dropping shredded message on %s", topic)
+			return ev.Ack()
+		} else if err != nil {
+			return err
+		}
+
+		return h(&event{Event: ev, msg: &broker.Message{Header: m.Header, Body: body}})
+	}, opts...)
+}
+
+type event struct {
+	broker.Event
+	msg *broker.Message
+}
+
+func (e *event) Message() *broker.Message {
+	return e.msg
+}
@@ -0,0 +1,83 @@
+// Package erasure provides store and broker wrappers which tag records and
+// messages with the data subjects they're about, e.g. user IDs, and Purge,
+// which erases the data of a subject for GDPR erasure requests, by deleting
+// the records or crypto-shredding them, and reports what it erased.
+package erasure
+
+import (
+	"context"
+	"strings"
+
+	"go-micro.dev/v4/metadata"
+	"go-micro.dev/v4/store"
+)
+
+// MetadataKey is the metadata key of the data subjects. It's the header of
+// the messages and the metadata key of the records, the IDs separated by
+// commas.
+const MetadataKey = "Micro-Data-Subject"
+
+// NewContext returns a context with the data subjects added to its metadata,
+// so they're passed on to the services called and the messages published
+// with it.
+func NewContext(ctx context.Context, subjects ...string) context.Context {
+	subjects = append(FromContext(ctx), subjects...)
+	return metadata.Set(ctx, MetadataKey, join(subjects))
+}
+
+// FromContext returns the data subjects of the context.
+func FromContext(ctx context.Context) []string {
+	v, ok := metadata.Get(ctx, MetadataKey)
+	if !ok {
+		return nil
+	}
+	return split(v)
+}
+
+// Tag tags the record with the data subjects.
+func Tag(r *store.Record, subjects ...string) *store.Record {
+	if r.Metadata == nil {
+		r.Metadata = make(map[string]interface{})
+	}
+	subjects = append(Subjects(r), subjects...)
+	r.Metadata[MetadataKey] = join(subjects)
+	return r
+}
+
+// TagContext tags the record with the data subjects of the context.
+func TagContext(ctx context.Context, r *store.Record) *store.Record {
+	return Tag(r, FromContext(ctx)...)
+}
+
+// Subjects returns the data subjects the record is tagged with.
+func Subjects(r *store.Record) []string {
+	v, ok := r.Metadata[MetadataKey].(string)
+	if !ok {
+		return nil
+	}
+	return split(v)
+}
+
+// join returns the subjects separated by commas, without duplicates.
+func join(subjects []string) string {
+	seen := make(map[string]bool, len(subjects))
+	ids := make([]string, 0, len(subjects))
+	for _, s := range subjects {
+		if len(s) == 0 || seen[s] {
+			continue
+		}
+		seen[s] = true
+		ids = append(ids, s)
+	}
+	return strings.Join(ids, ",")
+}
+
+func split(v string) []string {
+	var subjects []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); len(s) > 0 {
+			subjects = append(subjects, s)
+		}
+	}
+	return subjects
+}
@@ -0,0 +1,162 @@
+package erasure
+
+import (
+	"context"
+	"testing"
+
+	"go-micro.dev/v4/broker"
+	"go-micro.dev/v4/metadata"
+	"go-micro.dev/v4/store"
+)
+
+func TestContext(t *testing.T) {
+	ctx := NewContext(context.Background(), "user-1")
+	ctx = NewContext(ctx, "user-2", "user-1")
+
+	if got := FromContext(ctx); len(got) != 2 || got[0] != "user-1" || got[1] != "user-2" {
+		t.Fatalf("unexpected subjects %v", got)
+	}
+	if v, _ := metadata.Get(ctx, MetadataKey); v != "user-1,user-2" {
+		t.Fatalf("unexpected metadata %q", v)
+	}
+
+	r := TagContext(ctx, &store.Record{Key: "k"})
+	if got := Subjects(r); len(got) != 2 {
+		t.Fatalf("unexpected record subjects %v", got)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	keys := StoreKeys(store.NewMemoryStore())
+	audit := store.NewMemoryStore()
+	plain := NewStore(store.NewMemoryStore(), Name("plain"))
+	shred := NewStore(store.NewMemoryStore(), Name("shred"), Shred(keys))
+
+	for _, s := range []store.Store{plain, shred} {
+		if err := s.Write(Tag(&store.Record{Key: "profile", Value: []byte("alice")}, "user-1"), store.WriteTo("app", "users")); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Write(Tag(&store.Record{Key: "other", Value: []byte("bob")}, "user-2"), store.WriteTo("app", "users")); err != nil {
+			t.Fatal(err)
+		}
+		recs, err := s.Read("profile", store.ReadFrom("app", "users"))
+		if err != nil || string(recs[0].Value) != "alice" {
+			t.Fatalf("unexpected read %v %v", recs, err)
+		}
+	}
+
+	// the value is encrypted in the wrapped store
+	recs, err := shred.(*erasureStore).Store.Read("profile", store.ReadFrom("app", "users"))
+	if err != nil || !sealed(recs[0].Value) {
+		t.Fatalf("value not encrypted: %v %v", recs, err)
+	}
+
+	var hooked string
+	r, err := Purge(context.Background(), "user-1",
+		PurgeStores(plain, shred),
+		PurgeHooks(func(ctx context.Context, subject string) ([]Entry, error) {
+			hooked = subject
+			return []Entry{{Store: "search", Key: subject, Action: ActionDeleted}}, nil
+		}),
+		PurgeAudit(audit),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hooked != "user-1" {
+		t.Fatalf("hook not run")
+	}
+	// two records, the key and the hook
+	if len(r.Entries) != 4 {
+		t.Fatalf("unexpected entries %+v", r.Entries)
+	}
+
+	for _, s := range []store.Store{plain, shred} {
+		if _, err := s.Read("profile", store.ReadFrom("app", "users")); err != store.ErrNotFound {
+			t.Fatalf("record of %s not purged: %v", s.String(), err)
+		}
+		if _, err := s.Read("other", store.ReadFrom("app", "users")); err != nil {
+			t.Fatalf("record of another subject purged: %v", err)
+		}
+	}
+
+	reports, err := Reports(audit, "user-1")
+	if err != nil || len(reports) != 1 || len(reports[0].Entries) != 4 {
+		t.Fatalf("unexpected reports %v %v", reports, err)
+	}
+
+	if _, err := Purge(context.Background(), "user-1", PurgeStores(store.NewMemoryStore())); err == nil {
+		t.Fatal("expected an error for a store which isn't wrapped")
+	}
+}
+
+func TestShredOnly(t *testing.T) {
+	keys := StoreKeys(store.NewMemoryStore())
+	s := NewStore(store.NewMemoryStore(), Shred(keys))
+
+	if err := s.Write(Tag(&store.Record{Key: "order-1", Value: []byte("alice")}, "user-1", "user-2")); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Purge(context.Background(), "user-2", PurgeStores(s), ShredOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Entries) != 2 || r.Entries[0].Action != ActionShredded {
+		t.Fatalf("unexpected entries %+v", r.Entries)
+	}
+
+	// the record is kept but unreadable
+	if _, err := s.Read("order-1"); err != store.ErrNotFound {
+		t.Fatalf("expected shredded record to be unreadable, got %v", err)
+	}
+	if _, err := s.(*erasureStore).Store.Read("order-1"); err != nil {
+		t.Fatalf("expected encrypted record to be kept, got %v", err)
+	}
+}
+
+func TestBroker(t *testing.T) {
+	keys := StoreKeys(store.NewMemoryStore())
+	b := NewBroker(broker.NewMemoryBroker(), Shred(keys))
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	bodies := make(chan string, 2)
+	if _, err := b.Subscribe("orders", func(e broker.Event) error {
+		bodies <- string(e.Message().Body)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := &broker.Message{Header: map[string]string{MetadataKey: "user-1"}, Body: []byte("alice")}
+	if err := b.Publish("orders", msg); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-bodies; got != "alice" {
+		t.Fatalf("unexpected body %q", got)
+	}
+
+	// a message of the subject still queued when it's purged
+	queued, err := seal(keys, []string{"user-1"}, []byte("alice"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Purge(context.Background(), "user-1", PurgeKeys(keys)); err != nil {
+		t.Fatal(err)
+	}
+
+	inner := b.(*erasureBroker).Broker
+	if err := inner.Publish("orders", &broker.Message{Header: msg.Header, Body: queued}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Publish("orders", &broker.Message{Body: []byte("untagged")}); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-bodies; got != "untagged" {
+		t.Fatalf("shredded message handled: %q", got)
+	}
+}
@@ -0,0 +1,185 @@
+package erasure
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+
+	"go-micro.dev/v4/store"
+
+	"github.com/go-micro/plugins/v4/wrapper/store/encrypt"
+)
+
+// magic prefixes the values and message bodies encrypted with the keys of
+// their subjects.
+var magic = []byte("mes1")
+
+var (
+	// ErrShredded is returned for data encrypted with the key of a subject
+	// which was destroyed.
+	ErrShredded = errors.New("data of the subject was shredded")
+
+	errInvalid = errors.New("invalid envelope")
+)
+
+// Keys holds a key per data subject. Data encrypted with the key of a
+// subject is erased by destroying the key, without finding and deleting
+// every copy of it, e.g. in backups.
+type Keys interface {
+	// Key returns the AES-256 key of the subject, creating it if create is
+	// true. It returns ErrShredded if the subject has no key.
+	Key(subject string, create bool) ([]byte, error)
+	// Destroy destroys the key of the subject.
+	Destroy(subject string) error
+}
+
+// DefaultKeysTable is the table StoreKeys keeps the keys in.
+const DefaultKeysTable = "erasure-keys"
+
+type storeKeys struct {
+	sync.Mutex
+	store store.Store
+	table string
+	cache map[string][]byte
+}
+
+// StoreKeys keeps random keys in a table of the store. The store should be
+// separate from the data, e.g. with shorter lived backups, since the data is
+// only unreadable once the key is gone from all copies of the store.
+func StoreKeys(s store.Store) Keys {
+	return &storeKeys{
+		store: s,
+		table: DefaultKeysTable,
+		cache: make(map[string][]byte),
+	}
+}
+
+func (k *storeKeys) Key(subject string, create bool) ([]byte, error) {
+	k.Lock()
+	defer k.Unlock()
+
+	if key, ok := k.cache[subject]; ok {
+		return key, nil
+	}
+
+	recs, err := k.store.Read(subject, store.ReadFrom(k.store.Options().Database, k.table))
+	switch {
+	case err == nil && len(recs) > 0:
+		k.cache[subject] = recs[0].Value
+		return recs[0].Value, nil
+	case err != nil && err != store.ErrNotFound:
+		return nil, err
+	case !create:
+		return nil, ErrShredded
+	}
+
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	if err := k.store.Write(&store.Record{Key: subject, Value: key}, store.WriteTo(k.store.Options().Database, k.table)); err != nil {
+		return nil, err
+	}
+	k.cache[subject] = key
+	return key, nil
+}
+
+func (k *storeKeys) Destroy(subject string) error {
+	k.Lock()
+	defer k.Unlock()
+
+	delete(k.cache, subject)
+	err := k.store.Delete(subject, store.DeleteFrom(k.store.Options().Database, k.table))
+	if err == store.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+// seal encrypts the value with the key of every subject in turn, each in
+// the envelope of the encrypt wrapper. The envelope starts with the
+// subjects, so it's opened without the metadata of the record. The
+// ciphertext is bound to ad.
+//
+//	magic | subject count | subject lengths and subjects | ciphertext
+func seal(keys Keys, subjects []string, value, ad []byte) ([]byte, error) {
+	out := append([]byte{}, magic...)
+	out = appendUvarint(out, uint64(len(subjects)))
+	for _, s := range subjects {
+		out = appendUvarint(out, uint64(len(s)))
+		out = append(out, s...)
+	}
+
+	ciphertext := value
+	for _, s := range subjects {
+		sl, err := sealer(keys, s, true, ad)
+		if err != nil {
+			return nil, err
+		}
+		if ciphertext, err = sl.Seal(ciphertext); err != nil {
+			return nil, err
+		}
+	}
+
+	return append(out, ciphertext...), nil
+}
+
+// sealed returns whether the value is an envelope of seal.
+func sealed(value []byte) bool {
+	return len(value) > len(magic) && string(value[:len(magic)]) == string(magic)
+}
+
+// open decrypts the envelope with the keys of its subjects, returning
+// ErrShredded if one of them was destroyed.
+func open(keys Keys, value, ad []byte) ([]byte, error) {
+	b := value[len(magic):]
+	n, l := binary.Uvarint(b)
+	if l <= 0 {
+		return nil, errInvalid
+	}
+	b = b[l:]
+
+	subjects := make([]string, 0, n)
+	for i := uint64(0); i < n; i++ {
+		sl, l := binary.Uvarint(b)
+		if l <= 0 || uint64(len(b)-l) < sl {
+			return nil, errInvalid
+		}
+		subjects = append(subjects, string(b[l:l+int(sl)]))
+		b = b[l+int(sl):]
+	}
+
+	plaintext := b
+	for i := len(subjects) - 1; i >= 0; i-- {
+		sl, err := sealer(keys, subjects[i], false, ad)
+		if err != nil {
+			return nil, err
+		}
+		if plaintext, err = sl.Open(plaintext); err != nil {
+			return nil, err
+		}
+	}
+
+	return plaintext, nil
+}
+
+// sealer returns the sealer of the key of the subject, binding the
+// ciphertext to ad.
+func sealer(keys Keys, subject string, create bool, ad []byte) (*encrypt.Sealer, error) {
+	key, err := keys.Key(subject, create)
+	if err != nil {
+		return nil, err
+	}
+	c, err := encrypt.AESGCM(subject, key)
+	if err != nil {
+		return nil, err
+	}
+	return encrypt.NewSealer(string(ad), c), nil
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
@@ -0,0 +1,115 @@
+package erasure
+
+import "go-micro.dev/v4/store"
+
+// DefaultIndexTable is the table of the index of the records of each
+// subject.
+const DefaultIndexTable = "erasure-index"
+
+// Options configure the store and broker wrappers.
+type Options struct {
+	// Name identifies the store in the reports of Purge, by default it's
+	// the String of the store
+	Name string
+	// Keys encrypt the values and message bodies tagged with subjects with
+	// the keys of the subjects. They're set by Shred
+	Keys Keys
+	// Index is the store of the index, by default the wrapped store
+	Index store.Store
+	// IndexTable is the table of the index
+	IndexTable string
+}
+
+// Option sets an option.
+type Option func(*Options)
+
+// Name sets the name of the store in the reports.
+func Name(n string) Option {
+	return func(o *Options) {
+		o.Name = n
+	}
+}
+
+// Shred encrypts the values and message bodies tagged with subjects with the
+// keys of the subjects, so Purge erases them by destroying the keys, even in
+// copies which it can't delete. Shredded records aren't returned by reads,
+// shredded messages are acknowledged without being handled.
+func Shred(k Keys) Option {
+	return func(o *Options) {
+		o.Keys = k
+	}
+}
+
+// IndexStore keeps the index of the records of each subject in another
+// store.
+func IndexStore(s store.Store) Option {
+	return func(o *Options) {
+		o.Index = s
+	}
+}
+
+// IndexTable sets the table of the index.
+func IndexTable(t string) Option {
+	return func(o *Options) {
+		o.IndexTable = t
+	}
+}
+
+// PurgeOptions configure Purge.
+type PurgeOptions struct {
+	// Stores are purged of the records of the subject. They must be
+	// wrapped by NewStore
+	Stores []store.Store
+	// Keys have the key of the subject destroyed, in addition to those of
+	// the stores
+	Keys []Keys
+	// Hooks erase the data of the subject held elsewhere
+	Hooks []Hook
+	// Audit stores the reports
+	Audit store.Store
+	// ShredOnly keeps the records encrypted with the keys of the subject
+	// and only destroys the keys
+	ShredOnly bool
+}
+
+// PurgeOption sets a purge option.
+type PurgeOption func(*PurgeOptions)
+
+// PurgeStores purges the stores of the records of the subject.
+func PurgeStores(s ...store.Store) PurgeOption {
+	return func(o *PurgeOptions) {
+		o.Stores = append(o.Stores, s...)
+	}
+}
+
+// PurgeKeys destroys the key of the subject in the keys, e.g. those of a
+// broker wrapper.
+func PurgeKeys(k ...Keys) PurgeOption {
+	return func(o *PurgeOptions) {
+		o.Keys = append(o.Keys, k...)
+	}
+}
+
+// PurgeHooks runs the hooks, to erase data of the subject the wrappers don't
+// know about, e.g. in a search index or a third party service.
+func PurgeHooks(h ...Hook) PurgeOption {
+	return func(o *PurgeOptions) {
+		o.Hooks = append(o.Hooks, h...)
+	}
+}
+
+// PurgeAudit writes the reports to the audit table of the store.
+func PurgeAudit(s store.Store) PurgeOption {
+	return func(o *PurgeOptions) {
+		o.Audit = s
+	}
+}
+
+// ShredOnly only destroys the keys of the subject, records encrypted with
+// them are kept, unreadable. Records which aren't encrypted are still
+// deleted.
+func ShredOnly() PurgeOption {
+	return func(o *PurgeOptions) {
+		o.ShredOnly = true
+	}
+}
@@ -0,0 +1,170 @@
+package erasure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"go-micro.dev/v4/store"
+)
+
+// Actions of the entries of a report.
+const (
+	// ActionDeleted is a deleted record.
+	ActionDeleted = "deleted"
+	// ActionShredded is a record or key made unreadable by destroying the
+	// key of the subject.
+	ActionShredded = "shredded"
+)
+
+// DefaultAuditTable is the table of the reports in the audit store.
+const DefaultAuditTable = "erasure-audit"
+
+// Entry is a piece of data of the subject erased by Purge.
+type Entry struct {
+	// Store is the name of the store, or keys for the destroyed keys
+	Store    string `json:"store"`
+	Database string `json:"database,omitempty"`
+	Table    string `json:"table,omitempty"`
+	Key      string `json:"key,omitempty"`
+	Action   string `json:"action"`
+}
+
+// Report is the audit report of a purge.
+type Report struct {
+	Subject  string    `json:"subject"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Entries  []Entry   `json:"entries"`
+	// Errors are the errors of the stores, keys and hooks which failed, the
+	// purge can be retried
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Hook erases the data of the subject held outside of the wrapped stores,
+// returning the entries of what it erased.
+type Hook func(ctx context.Context, subject string) ([]Entry, error)
+
+// Purge erases the data of the subject: it deletes the records of the
+// subject in the stores, destroys the keys of the subject and runs the
+// hooks. A store, key or hook failing doesn't stop the others, their errors
+// are in the report and the returned error. Purges are idempotent, a failed
+// one is retried by purging again.
+func Purge(ctx context.Context, subject string, opts ...PurgeOption) (*Report, error) {
+	var options PurgeOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if len(subject) == 0 {
+		return nil, errors.New("no subject to purge")
+	}
+
+	r := &Report{Subject: subject, Started: time.Now()}
+	fail := func(err error) {
+		r.Errors = append(r.Errors, err.Error())
+	}
+
+	keys := options.Keys
+	for _, s := range options.Stores {
+		es, ok := s.(*erasureStore)
+		if !ok {
+			fail(errors.New(s.String() + " is not an erasure store"))
+			continue
+		}
+		entries, err := es.purge(subject, options.ShredOnly)
+		r.Entries = append(r.Entries, entries...)
+		if err != nil {
+			fail(errors.New(es.opts.Name + ": " + err.Error()))
+		}
+		if es.opts.Keys != nil {
+			keys = append(keys, es.opts.Keys)
+		}
+	}
+
+	for _, k := range unique(keys) {
+		if err := k.Destroy(subject); err != nil {
+			fail(errors.New("keys: " + err.Error()))
+			continue
+		}
+		r.Entries = append(r.Entries, Entry{Store: "keys", Key: subject, Action: ActionShredded})
+	}
+
+	for _, h := range options.Hooks {
+		entries, err := h(ctx, subject)
+		r.Entries = append(r.Entries, entries...)
+		if err != nil {
+			fail(err)
+		}
+	}
+
+	r.Finished = time.Now()
+
+	if options.Audit != nil {
+		if err := audit(options.Audit, r); err != nil {
+			fail(errors.New("audit: " + err.Error()))
+		}
+	}
+
+	if len(r.Errors) > 0 {
+		return r, errors.New("purge of " + subject + " failed: " + strings.Join(r.Errors, "; "))
+	}
+	return r, nil
+}
+
+// Reports returns the reports of the purges of the subject in the audit
+// store, oldest first.
+func Reports(s store.Store, subject string) ([]*Report, error) {
+	recs, err := readPrefix(s, s.Options().Database, DefaultAuditTable, indexPrefix(subject))
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]*Report, 0, len(recs))
+	for _, rec := range recs {
+		var r Report
+		if err := json.Unmarshal(rec.Value, &r); err != nil {
+			return nil, err
+		}
+		reports = append(reports, &r)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Started.Before(reports[j].Started)
+	})
+	return reports, nil
+}
+
+func audit(s store.Store, r *Report) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	rec := &store.Record{
+		Key:   indexPrefix(r.Subject) + r.Started.UTC().Format(time.RFC3339Nano),
+		Value: b,
+	}
+	return s.Write(rec, store.WriteTo(s.Options().Database, DefaultAuditTable))
+}
+
+// unique drops the keys passed more than once, e.g. the same keys shared by
+// a store and a broker.
+func unique(keys []Keys) []Keys {
+	var out []Keys
+	for _, k := range keys {
+		dup := false
+		for _, o := range out {
+			if reflect.TypeOf(k).Comparable() && reflect.TypeOf(o) == reflect.TypeOf(k) && o == k {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			out = append(out, k)
+		}
+	}
+	return out
+}
@@ -0,0 +1,184 @@
+package erasure
+
+import (
+	"encoding/json"
+	"net/url"
+
+	"go-micro.dev/v4/store"
+)
+
+type erasureStore struct {
+	store.Store
+	opts Options
+}
+
+// indexEntry is the value of an index record, a record of a subject.
+type indexEntry struct {
+	Database string `json:"database"`
+	Table    string `json:"table"`
+	Key      string `json:"key"`
+}
+
+// NewStore wraps the store so records tagged with data subjects, see Tag,
+// are indexed by subject for Purge. With Shred their values are encrypted
+// with the keys of the subjects.
+func NewStore(s store.Store, opts ...Option) store.Store {
+	options := Options{
+		Name:       s.String(),
+		Index:      s,
+		IndexTable: DefaultIndexTable,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &erasureStore{Store: s, opts: options}
+}
+
+// indexPrefix returns the prefix of the index records of the subject. The
+// subject is escaped, so one subject's prefix isn't another's.
+func indexPrefix(subject string) string {
+	return url.PathEscape(subject) + "/"
+}
+
+func (e *erasureStore) indexOptions() (string, string) {
+	return e.opts.Index.Options().Database, e.opts.IndexTable
+}
+
+func (e *erasureStore) Write(r *store.Record, opts ...store.WriteOption) error {
+	subjects := Subjects(r)
+	if len(subjects) == 0 {
+		return e.Store.Write(r, opts...)
+	}
+
+	var options store.WriteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+	entry := indexEntry{Database: options.Database, Table: options.Table, Key: r.Key}
+	if len(entry.Database) == 0 {
+		entry.Database = e.Store.Options().Database
+	}
+	if len(entry.Table) == 0 {
+		entry.Table = e.Store.Options().Table
+	}
+
+	// index first, so a record is never written without its index
+	v, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	db, table := e.indexOptions()
+	for _, s := range subjects {
+		ir := &store.Record{
+			Key:   indexPrefix(s) + entry.Database + "/" + entry.Table + "/" + entry.Key,
+			Value: v,
+		}
+		if err := e.opts.Index.Write(ir, store.WriteTo(db, table)); err != nil {
+			return err
+		}
+	}
+
+	if e.opts.Keys == nil {
+		return e.Store.Write(r, opts...)
+	}
+
+	value, err := seal(e.opts.Keys, subjects, r.Value, []byte(r.Key))
+	if err != nil {
+		return err
+	}
+	rec := *r
+	rec.Value = value
+	return e.Store.Write(&rec, opts...)
+}
+
+func (e *erasureStore) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	recs, err := e.Store.Read(key, opts...)
+	if err != nil || e.opts.Keys == nil {
+		return recs, err
+	}
+
+	out := make([]*store.Record, 0, len(recs))
+	for _, r := range recs {
+		if !sealed(r.Value) {
+			out = append(out, r)
+			continue
+		}
+		value, err := open(e.opts.Keys, r.Value, []byte(r.Key))
+		if err == ErrShredded {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		rec := *r
+		rec.Value = value
+		out = append(out, &rec)
+	}
+
+	if len(out) == 0 && len(recs) > 0 {
+		return nil, store.ErrNotFound
+	}
+	return out, nil
+}
+
+// purge deletes the records of the subject in the index. Records encrypted
+// with its key are only left if shredOnly is true, the key is destroyed by
+// Purge. Records deleted before aren't reported, if the store returns
+// ErrNotFound for them.
+func (e *erasureStore) purge(subject string, shredOnly bool) ([]Entry, error) {
+	db, table := e.indexOptions()
+	irs, err := readPrefix(e.opts.Index, db, table, indexPrefix(subject))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, ir := range irs {
+		var ie indexEntry
+		if err := json.Unmarshal(ir.Value, &ie); err != nil {
+			return entries, err
+		}
+		entry := Entry{Store: e.opts.Name, Database: ie.Database, Table: ie.Table, Key: ie.Key}
+
+		if shredOnly && e.opts.Keys != nil {
+			entry.Action = ActionShredded
+			entries = append(entries, entry)
+			continue
+		}
+
+		err := e.Store.Delete(ie.Key, store.DeleteFrom(ie.Database, ie.Table))
+		if err == nil {
+			entry.Action = ActionDeleted
+			entries = append(entries, entry)
+		} else if err != store.ErrNotFound {
+			return entries, err
+		}
+		if err := e.opts.Index.Delete(ir.Key, store.DeleteFrom(db, table)); err != nil && err != store.ErrNotFound {
+			return entries, err
+		}
+	}
+
+	return entries, nil
+}
+
+// readPrefix reads the records of the table whose keys have the prefix. The
+// keys are listed and read one by one, since not every store supports
+// unlimited prefix reads.
+func readPrefix(s store.Store, db, table, prefix string) ([]*store.Record, error) {
+	keys, err := s.List(store.ListFrom(db, table), store.ListPrefix(prefix))
+	if err != nil {
+		return nil, err
+	}
+
+	recs := make([]*store.Record, 0, len(keys))
+	for _, k := range keys {
+		rs, err := s.Read(k, store.ReadFrom(db, table))
+		if err == store.ErrNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		recs = append(recs, rs...)
+	}
+	return recs, nil
+}
@@ -0,0 +1,237 @@
+// Package gzip compresses large request bodies of the client, once the server
+// announced it supports it.
+package gzip
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"sync"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/codec"
+	"go-micro.dev/v4/server"
+)
+
+const (
+	// HeaderContentEncoding is set on compressed requests.
+	HeaderContentEncoding = "Micro-Content-Encoding"
+	// HeaderAcceptEncoding is set by servers on responses to announce the
+	// encodings they can decompress.
+	HeaderAcceptEncoding = "Micro-Accept-Encoding"
+
+	encoding = "gzip"
+)
+
+// compressible are the content types whose body is written in one piece, so
+// it can be compressed as a whole.
+var compressible = []string{
+	"application/json",
+	"application/protobuf",
+	"application/octet-stream",
+}
+
+// peers tracks which services announced gzip support on their last
+// response.
+type peers struct {
+	sync.RWMutex
+	gzip map[string]bool
+}
+
+func (p *peers) supported(service string) bool {
+	p.RLock()
+	defer p.RUnlock()
+	return p.gzip[service]
+}
+
+func (p *peers) set(service string, ok bool) {
+	p.Lock()
+	p.gzip[service] = ok
+	p.Unlock()
+}
+
+// Client returns a client option compressing request bodies above the
+// threshold. Requests to a service are only compressed after one of its
+// responses announced gzip support, and sent as is again as soon as a
+// response doesn't.
+func Client(opts ...Option) client.Option {
+	options := newOptions(opts...)
+	p := &peers{gzip: make(map[string]bool)}
+
+	return func(o *client.Options) {
+		if o.Codecs == nil {
+			o.Codecs = make(map[string]codec.NewCodec)
+		}
+		for _, ct := range compressible {
+			c, ok := o.Codecs[ct]
+			if !ok {
+				c = client.DefaultCodecs[ct]
+			}
+			o.Codecs[ct] = newClientCodec(c, p, options)
+		}
+	}
+}
+
+// Server returns a server option decompressing gzip request bodies and
+// announcing gzip support on every response.
+func Server() server.Option {
+	return func(o *server.Options) {
+		if o.Codecs == nil {
+			o.Codecs = make(map[string]codec.NewCodec)
+		}
+		for _, ct := range compressible {
+			c, ok := o.Codecs[ct]
+			if !ok {
+				c = server.DefaultCodecs[ct]
+			}
+			o.Codecs[ct] = NewServerCodec(c)
+		}
+	}
+}
+
+// buffer is an in memory io.ReadWriteCloser.
+type buffer struct {
+	*bytes.Buffer
+}
+
+func (b *buffer) Close() error {
+	return nil
+}
+
+type clientCodec struct {
+	codec.Codec
+	newCodec codec.NewCodec
+	conn     io.ReadWriteCloser
+	peers    *peers
+	opts     Options
+	target   string
+}
+
+func newClientCodec(c codec.NewCodec, p *peers, opts Options) codec.NewCodec {
+	return func(conn io.ReadWriteCloser) codec.Codec {
+		return &clientCodec{
+			Codec:    c(conn),
+			newCodec: c,
+			conn:     conn,
+			peers:    p,
+			opts:     opts,
+		}
+	}
+}
+
+func (c *clientCodec) Write(m *codec.Message, b interface{}) error {
+	c.target = m.Target
+
+	if b == nil || !c.peers.supported(m.Target) {
+		return c.Codec.Write(m, b)
+	}
+
+	// encode into a buffer first to check the size of the body
+	buf := &buffer{new(bytes.Buffer)}
+	if err := c.newCodec(buf).Write(m, b); err != nil {
+		return err
+	}
+
+	if buf.Len() <= c.opts.Threshold {
+		_, err := c.conn.Write(buf.Bytes())
+		return err
+	}
+
+	w, err := gzip.NewWriterLevel(c.conn, c.opts.Level)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	if m.Header == nil {
+		m.Header = make(map[string]string)
+	}
+	m.Header[HeaderContentEncoding] = encoding
+	return nil
+}
+
+func (c *clientCodec) ReadHeader(m *codec.Message, t codec.MessageType) error {
+	if err := c.Codec.ReadHeader(m, t); err != nil {
+		return err
+	}
+
+	if t == codec.Response && len(c.target) > 0 {
+		c.peers.set(c.target, accepts(m.Header[HeaderAcceptEncoding]))
+	}
+
+	return nil
+}
+
+func (c *clientCodec) String() string {
+	return c.Codec.String()
+}
+
+type serverCodec struct {
+	codec.Codec
+	newCodec codec.NewCodec
+	conn     io.ReadWriteCloser
+	gzip     bool
+}
+
+// NewServerCodec wraps the codec to decompress gzip request bodies and to
+// announce gzip support on responses.
+func NewServerCodec(c codec.NewCodec) codec.NewCodec {
+	return func(conn io.ReadWriteCloser) codec.Codec {
+		return &serverCodec{
+			Codec:    c(conn),
+			newCodec: c,
+			conn:     conn,
+		}
+	}
+}
+
+func (s *serverCodec) ReadHeader(m *codec.Message, t codec.MessageType) error {
+	s.gzip = m.Header[HeaderContentEncoding] == encoding
+	return s.Codec.ReadHeader(m, t)
+}
+
+func (s *serverCodec) ReadBody(b interface{}) error {
+	if !s.gzip || b == nil {
+		return s.Codec.ReadBody(b)
+	}
+
+	r, err := gzip.NewReader(s.conn)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	return s.newCodec(&buffer{bytes.NewBuffer(body)}).ReadBody(b)
+}
+
+func (s *serverCodec) Write(m *codec.Message, b interface{}) error {
+	if m.Header == nil {
+		m.Header = make(map[string]string)
+	}
+	m.Header[HeaderAcceptEncoding] = encoding
+	return s.Codec.Write(m, b)
+}
+
+func (s *serverCodec) String() string {
+	return s.Codec.String()
+}
+
+func accepts(header string) bool {
+	for _, e := range strings.Split(header, ",") {
+		if strings.TrimSpace(e) == encoding {
+			return true
+		}
+	}
+	return false
+}
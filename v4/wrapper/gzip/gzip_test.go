@@ -0,0 +1,109 @@
+package gzip
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/metadata"
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/server"
+)
+
+type Request struct {
+	Text string `json:"text"`
+}
+
+type Response struct {
+	Length   int    `json:"length"`
+	Encoding string `json:"encoding"`
+}
+
+type Test struct{}
+
+func (t *Test) Echo(ctx context.Context, req *Request, rsp *Response) error {
+	rsp.Length = len(req.Text)
+	rsp.Encoding, _ = metadata.Get(ctx, HeaderContentEncoding)
+	return nil
+}
+
+func newServer(t *testing.T, r registry.Registry, opts ...server.Option) server.Server {
+	opts = append([]server.Option{
+		server.Name("test"),
+		server.Address("127.0.0.1:0"),
+		server.Registry(r),
+	}, opts...)
+
+	s := server.NewServer(opts...)
+	if err := s.Handle(s.NewHandler(&Test{})); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func call(t *testing.T, c client.Client, text string) *Response {
+	rsp := new(Response)
+	req := c.NewRequest("test", "Test.Echo", &Request{Text: text})
+	if err := c.Call(context.Background(), req, rsp); err != nil {
+		t.Fatal(err)
+	}
+	return rsp
+}
+
+func TestCompression(t *testing.T) {
+	r := registry.NewMemoryRegistry()
+	s := newServer(t, r, Server())
+	defer s.Stop()
+
+	c := client.NewClient(client.Registry(r), Client(Threshold(64)))
+	large := strings.Repeat("a", 1024)
+
+	// the first request negotiates
+	if rsp := call(t, c, large); rsp.Length != len(large) || len(rsp.Encoding) > 0 {
+		t.Fatalf("Expected uncompressed first request, got %+v", rsp)
+	}
+
+	if rsp := call(t, c, large); rsp.Length != len(large) || rsp.Encoding != "gzip" {
+		t.Fatalf("Expected compressed request, got %+v", rsp)
+	}
+
+	if rsp := call(t, c, "small"); rsp.Length != 5 || len(rsp.Encoding) > 0 {
+		t.Fatalf("Expected small request to be sent as is, got %+v", rsp)
+	}
+}
+
+func TestFallback(t *testing.T) {
+	r := registry.NewMemoryRegistry()
+	s := newServer(t, r)
+	defer s.Stop()
+
+	c := client.NewClient(client.Registry(r), Client(Threshold(64)))
+	large := strings.Repeat("a", 1024)
+
+	for i := 0; i < 2; i++ {
+		if rsp := call(t, c, large); rsp.Length != len(large) || len(rsp.Encoding) > 0 {
+			t.Fatalf("Expected uncompressed request to server without gzip support, got %+v", rsp)
+		}
+	}
+}
+
+func TestAccepts(t *testing.T) {
+	testData := map[string]bool{
+		"":           false,
+		"gzip":       true,
+		"br, gzip":   true,
+		"deflate":    false,
+		"gzipped":    false,
+		" gzip ,zst": true,
+	}
+
+	for header, ok := range testData {
+		if accepts(header) != ok {
+			t.Fatalf("Expected %q to be %v", header, ok)
+		}
+	}
+}
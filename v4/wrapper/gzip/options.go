@@ -0,0 +1,51 @@
+package gzip
+
+import (
+	"compress/gzip"
+)
+
+// Compression levels, see compress/gzip.
+const (
+	BestSpeed          = gzip.BestSpeed
+	BestCompression    = gzip.BestCompression
+	DefaultCompression = gzip.DefaultCompression
+)
+
+// Options configure the compression of request bodies.
+type Options struct {
+	// Threshold is the encoded body size in bytes above which a request is
+	// compressed. Defaults to 1KB.
+	Threshold int
+	// Level is the gzip compression level. Defaults to DefaultCompression.
+	Level int
+}
+
+// Option sets an option.
+type Option func(*Options)
+
+// Threshold sets the body size above which requests are compressed.
+func Threshold(n int) Option {
+	return func(o *Options) {
+		o.Threshold = n
+	}
+}
+
+// Level sets the gzip compression level.
+func Level(l int) Option {
+	return func(o *Options) {
+		o.Level = l
+	}
+}
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		Threshold: 1024,
+		Level:     DefaultCompression,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return options
+}
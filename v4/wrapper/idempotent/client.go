@@ -0,0 +1,189 @@
+package idempotent
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"go-micro.dev/v4/client"
+)
+
+type idempotentClient struct {
+	client.Client
+	opts   Options
+	static map[string]bool
+
+	sync.Mutex
+	services map[string]*service
+}
+
+// service is the cached idempotent endpoints of a service.
+type service struct {
+	endpoints map[string]bool
+	expires   time.Time
+}
+
+// NewClientWrapper returns a client wrapper which retries and hedges calls
+// to idempotent endpoints, and disables the retries of the client for the
+// others. Endpoints are idempotent if their proto method is, or they're
+// marked in the registry or set with Endpoints.
+func NewClientWrapper(opts ...Option) client.Wrapper {
+	options := newOptions(opts...)
+
+	static := make(map[string]bool, len(options.Endpoints))
+	for _, e := range options.Endpoints {
+		static[e] = true
+	}
+
+	return func(c client.Client) client.Client {
+		return &idempotentClient{
+			Client:   c,
+			opts:     options,
+			static:   static,
+			services: make(map[string]*service),
+		}
+	}
+}
+
+func (c *idempotentClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	if !c.idempotent(req.Service(), req.Endpoint()) {
+		return c.Client.Call(ctx, req, rsp, append(opts, client.WithRetries(0))...)
+	}
+	if c.opts.HedgeDelay <= 0 {
+		return c.Client.Call(ctx, req, rsp, append(opts, client.WithRetries(c.opts.Retries))...)
+	}
+	return c.hedge(ctx, req, rsp, opts)
+}
+
+func (c *idempotentClient) Stream(ctx context.Context, req client.Request, opts ...client.CallOption) (client.Stream, error) {
+	if !c.idempotent(req.Service(), req.Endpoint()) {
+		opts = append(opts, client.WithRetries(0))
+	}
+	return c.Client.Stream(ctx, req, opts...)
+}
+
+// idempotent reports whether the endpoint of the service is idempotent.
+func (c *idempotentClient) idempotent(name, endpoint string) bool {
+	if c.static[endpoint] || protoEndpoints()[endpoint] {
+		return true
+	}
+
+	c.Lock()
+	s, ok := c.services[name]
+	c.Unlock()
+	if ok && time.Now().Before(s.expires) {
+		return s.endpoints[endpoint]
+	}
+
+	r := c.opts.Registry
+	if r == nil {
+		r = c.Client.Options().Registry
+	}
+	if r == nil {
+		return false
+	}
+
+	versions, err := r.GetService(name)
+	if err != nil {
+		// calls aren't repeated unless they're known to be idempotent
+		return false
+	}
+
+	s = &service{
+		endpoints: make(map[string]bool),
+		expires:   time.Now().Add(c.opts.TTL),
+	}
+	for _, v := range versions {
+		for _, e := range v.Endpoints {
+			if Is(v, e.Name) {
+				s.endpoints[e.Name] = true
+			}
+		}
+	}
+
+	c.Lock()
+	c.services[name] = s
+	c.Unlock()
+	return s.endpoints[endpoint]
+}
+
+type result struct {
+	rsp interface{}
+	err error
+}
+
+// hedge makes another call whenever a call didn't respond within the delay,
+// up to MaxHedges, and retries failed calls up to Retries. The first
+// response wins and the other calls are cancelled.
+func (c *idempotentClient) hedge(ctx context.Context, req client.Request, rsp interface{}, opts []client.CallOption) error {
+	callOpts := c.Client.Options().CallOptions
+	for _, o := range opts {
+		o(&callOpts)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan result, 1+c.opts.MaxHedges+c.opts.Retries)
+	call := func() {
+		r := reflect.New(reflect.TypeOf(rsp).Elem()).Interface()
+		err := c.Client.Call(ctx, req, r, append(opts, client.WithRetries(0))...)
+		results <- result{rsp: r, err: err}
+	}
+
+	go call()
+	pending, hedges, retries := 1, 0, 0
+
+	timer := time.NewTimer(c.opts.HedgeDelay)
+	defer timer.Stop()
+
+	var lastErr error
+	for {
+		select {
+		case <-timer.C:
+			if hedges < c.opts.MaxHedges {
+				hedges++
+				pending++
+				go call()
+				timer.Reset(c.opts.HedgeDelay)
+			}
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				setResponse(rsp, r.rsp)
+				return nil
+			}
+			lastErr = r.err
+
+			retry := true
+			if callOpts.Retry != nil {
+				ok, err := callOpts.Retry(ctx, req, retries+1, r.err)
+				if err != nil {
+					return err
+				}
+				retry = ok
+			}
+
+			if retry && retries < c.opts.Retries {
+				retries++
+				pending++
+				go call()
+			} else if pending == 0 {
+				return lastErr
+			}
+		}
+	}
+}
+
+// setResponse copies the response of the winning call into the one of the
+// caller.
+func setResponse(dst, src interface{}) {
+	if m, ok := dst.(proto.Message); ok {
+		m.Reset()
+		proto.Merge(m, src.(proto.Message))
+		return
+	}
+	reflect.ValueOf(dst).Elem().Set(reflect.ValueOf(src).Elem())
+}
@@ -0,0 +1,96 @@
+// Package idempotent marks endpoints as idempotent and retries and hedges
+// calls only to those, so calls with side effects aren't repeated.
+// Endpoints are marked with the registration metadata of the handler, or
+// the idempotency_level option of their proto method.
+package idempotent
+
+import (
+	"sync"
+	"time"
+
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/server"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// MetadataKey is the metadata key of the endpoints marking them idempotent.
+const MetadataKey = "idempotent"
+
+var (
+	// DefaultTTL is how long the endpoints of a service are cached.
+	DefaultTTL = time.Minute
+	// DefaultRetries is the number of retries of idempotent calls.
+	DefaultRetries = 2
+)
+
+// Endpoint marks the endpoint of the handler idempotent, e.g.
+// "Greeter.Hello".
+func Endpoint(name string) server.HandlerOption {
+	return server.EndpointMetadata(name, map[string]string{MetadataKey: "true"})
+}
+
+// ProtoEndpoints marks the endpoints of the handler whose proto method has
+// an idempotency_level of IDEMPOTENT or NO_SIDE_EFFECTS idempotent.
+//
+//	rpc Get(GetRequest) returns (GetResponse) {
+//		option idempotency_level = NO_SIDE_EFFECTS;
+//	}
+func ProtoEndpoints() []server.HandlerOption {
+	var opts []server.HandlerOption
+	for name := range protoEndpoints() {
+		opts = append(opts, Endpoint(name))
+	}
+	return opts
+}
+
+// Is reports whether the endpoint of the registry service is marked
+// idempotent.
+func Is(s *registry.Service, endpoint string) bool {
+	for _, e := range s.Endpoints {
+		if e.Name == endpoint {
+			return e.Metadata[MetadataKey] == "true"
+		}
+	}
+	return false
+}
+
+var (
+	protoOnce sync.Once
+	protoIdem map[string]bool
+)
+
+// protoEndpoints returns the endpoints of the registered proto files which
+// are idempotent. Files are registered by init, so they're read once.
+func protoEndpoints() map[string]bool {
+	protoOnce.Do(func() {
+		protoIdem = make(map[string]bool)
+		protoregistry.GlobalFiles.RangeFiles(func(f protoreflect.FileDescriptor) bool {
+			services := f.Services()
+			for i := 0; i < services.Len(); i++ {
+				methods := services.Get(i).Methods()
+				for j := 0; j < methods.Len(); j++ {
+					m := methods.Get(j)
+					if idempotentMethod(m) {
+						protoIdem[string(services.Get(i).Name())+"."+string(m.Name())] = true
+					}
+				}
+			}
+			return true
+		})
+	})
+	return protoIdem
+}
+
+func idempotentMethod(m protoreflect.MethodDescriptor) bool {
+	opts, ok := m.Options().(*descriptorpb.MethodOptions)
+	if !ok || opts == nil {
+		return false
+	}
+	switch opts.GetIdempotencyLevel() {
+	case descriptorpb.MethodOptions_IDEMPOTENT, descriptorpb.MethodOptions_NO_SIDE_EFFECTS:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package idempotent
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-micro/plugins/v4/wrapper/idempotent/test"
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/errors"
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/server"
+)
+
+type Catalog struct{}
+
+func (c *Catalog) Get(ctx context.Context, req *test.Request, rsp *test.Response) error {
+	return nil
+}
+
+func (c *Catalog) Order(ctx context.Context, req *test.Request, rsp *test.Response) error {
+	return nil
+}
+
+// testClient answers calls with the function, recording the retries of the
+// calls.
+type testClient struct {
+	client.Client
+
+	sync.Mutex
+	calls   int
+	retries []int
+	fn      func(ctx context.Context, call int, rsp *test.Response) error
+}
+
+func (c *testClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	var options client.CallOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	c.Lock()
+	c.calls++
+	call := c.calls
+	c.retries = append(c.retries, options.Retries)
+	c.Unlock()
+
+	if c.fn == nil {
+		return nil
+	}
+	return c.fn(ctx, call, rsp.(*test.Response))
+}
+
+func newClient(r registry.Registry, fn func(ctx context.Context, call int, rsp *test.Response) error, opts ...Option) (client.Client, *testClient) {
+	tc := &testClient{Client: client.NewClient(client.Registry(r)), fn: fn}
+	return NewClientWrapper(opts...)(tc), tc
+}
+
+func TestProtoEndpoints(t *testing.T) {
+	eps := protoEndpoints()
+	for name, expected := range map[string]bool{"Catalog.Get": true, "Catalog.Put": true, "Catalog.Order": false} {
+		if eps[name] != expected {
+			t.Errorf("Expected %s idempotent %v", name, expected)
+		}
+	}
+
+	h := server.NewServer().NewHandler(&Catalog{}, ProtoEndpoints()...)
+	s := &registry.Service{Endpoints: h.Endpoints()}
+	if !Is(s, "Catalog.Get") || Is(s, "Catalog.Order") {
+		t.Errorf("Expected Catalog.Get to be marked, got %+v", s.Endpoints)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	r := registry.NewMemoryRegistry()
+	h := server.NewServer().NewHandler(&Catalog{}, Endpoint("Catalog.Order"))
+	if err := r.Register(&registry.Service{
+		Name:      "catalog",
+		Version:   "latest",
+		Endpoints: h.Endpoints(),
+		Nodes:     []*registry.Node{{Id: "1", Address: "10.0.0.1:8080"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	c, tc := newClient(r, nil, Retries(3), Endpoints("Orders.Get"))
+	for _, call := range []struct {
+		service, endpoint string
+		retries           int
+	}{
+		{"catalog", "Catalog.Get", 3},
+		{"catalog", "Catalog.Order", 3},
+		{"orders", "Orders.Get", 3},
+		{"orders", "Orders.Create", 0},
+	} {
+		tc.retries = nil
+		if err := c.Call(context.Background(), c.NewRequest(call.service, call.endpoint, &test.Request{}), &test.Response{}); err != nil {
+			t.Fatal(err)
+		}
+		if tc.retries[0] != call.retries {
+			t.Errorf("Expected %d retries of %s, got %d", call.retries, call.endpoint, tc.retries[0])
+		}
+	}
+}
+
+func TestHedge(t *testing.T) {
+	fn := func(ctx context.Context, call int, rsp *test.Response) error {
+		if call == 1 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		rsp.Msg = "hedged"
+		return nil
+	}
+	c, tc := newClient(registry.NewMemoryRegistry(), fn, Hedge(10*time.Millisecond, 2))
+
+	rsp := new(test.Response)
+	if err := c.Call(context.Background(), c.NewRequest("catalog", "Catalog.Get", &test.Request{}), rsp); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Msg != "hedged" {
+		t.Errorf("Expected the hedged response, got %q", rsp.Msg)
+	}
+
+	tc.Lock()
+	defer tc.Unlock()
+	if tc.calls != 2 {
+		t.Errorf("Expected 2 calls, got %d", tc.calls)
+	}
+	for _, r := range tc.retries {
+		if r != 0 {
+			t.Errorf("Expected hedged calls not to retry, got %d", r)
+		}
+	}
+}
+
+func TestHedgeRetries(t *testing.T) {
+	errFailed := errors.InternalServerError("catalog", "failed")
+	fn := func(ctx context.Context, call int, rsp *test.Response) error {
+		if call < 3 {
+			return errFailed
+		}
+		rsp.Msg = "retried"
+		return nil
+	}
+	c, tc := newClient(registry.NewMemoryRegistry(), fn, Hedge(time.Minute, 1), Retries(2))
+
+	rsp := new(test.Response)
+	if err := c.Call(context.Background(), c.NewRequest("catalog", "Catalog.Put", &test.Request{}), rsp); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Msg != "retried" || tc.calls != 3 {
+		t.Errorf("Expected the third call to succeed, got %q after %d calls", rsp.Msg, tc.calls)
+	}
+
+	c, _ = newClient(registry.NewMemoryRegistry(), func(context.Context, int, *test.Response) error {
+		return errFailed
+	}, Hedge(time.Minute, 1), Retries(2))
+	if err := c.Call(context.Background(), c.NewRequest("catalog", "Catalog.Put", &test.Request{}), rsp); err != error(errFailed) {
+		t.Errorf("Expected the error of the last call, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package idempotent
+
+import (
+	"time"
+
+	"go-micro.dev/v4/registry"
+)
+
+// Options configure the client wrapper.
+type Options struct {
+	// Registry looks up the endpoints marked idempotent. Defaults to the
+	// registry of the client
+	Registry registry.Registry
+	// TTL is how long the endpoints of a service are cached. Defaults to
+	// DefaultTTL
+	TTL time.Duration
+	// Endpoints are idempotent in addition to the marked ones, e.g. of
+	// services not marking them yet
+	Endpoints []string
+	// Retries is the number of retries of failed calls to idempotent
+	// endpoints. Calls to other endpoints aren't retried
+	Retries int
+	// HedgeDelay is the wait for a response before a call to an idempotent
+	// endpoint is hedged with another one, 0 disables hedging
+	HedgeDelay time.Duration
+	// MaxHedges is the number of hedged calls made at most. Defaults to 1
+	MaxHedges int
+}
+
+// Option sets an option.
+type Option func(*Options)
+
+// Registry sets the registry looking up the endpoints marked idempotent.
+func Registry(r registry.Registry) Option {
+	return func(o *Options) {
+		o.Registry = r
+	}
+}
+
+// TTL sets how long the endpoints of a service are cached.
+func TTL(d time.Duration) Option {
+	return func(o *Options) {
+		o.TTL = d
+	}
+}
+
+// Endpoints adds endpoints which are idempotent, e.g. "Greeter.Hello".
+func Endpoints(names ...string) Option {
+	return func(o *Options) {
+		o.Endpoints = append(o.Endpoints, names...)
+	}
+}
+
+// Retries sets the number of retries of idempotent calls.
+func Retries(n int) Option {
+	return func(o *Options) {
+		o.Retries = n
+	}
+}
+
+// Hedge hedges calls to idempotent endpoints without a response after the
+// delay, with up to max more calls.
+func Hedge(delay time.Duration, max int) Option {
+	return func(o *Options) {
+		o.HedgeDelay = delay
+		o.MaxHedges = max
+	}
+}
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		TTL:       DefaultTTL,
+		Retries:   DefaultRetries,
+		MaxHedges: 1,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return options
+}
@@ -0,0 +1,134 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: wrapper/idempotent/test/test.proto
+
+package test
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type Request struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Request) Reset()         { *m = Request{} }
+func (m *Request) String() string { return proto.CompactTextString(m) }
+func (*Request) ProtoMessage()    {}
+func (*Request) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a6454fa32e064f2f, []int{0}
+}
+
+func (m *Request) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Request.Unmarshal(m, b)
+}
+func (m *Request) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Request.Marshal(b, m, deterministic)
+}
+func (m *Request) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Request.Merge(m, src)
+}
+func (m *Request) XXX_Size() int {
+	return xxx_messageInfo_Request.Size(m)
+}
+func (m *Request) XXX_DiscardUnknown() {
+	xxx_messageInfo_Request.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Request proto.InternalMessageInfo
+
+func (m *Request) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type Response struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Msg                  string   `protobuf:"bytes,2,opt,name=msg,proto3" json:"msg,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Response) Reset()         { *m = Response{} }
+func (m *Response) String() string { return proto.CompactTextString(m) }
+func (*Response) ProtoMessage()    {}
+func (*Response) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a6454fa32e064f2f, []int{1}
+}
+
+func (m *Response) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Response.Unmarshal(m, b)
+}
+func (m *Response) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Response.Marshal(b, m, deterministic)
+}
+func (m *Response) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Response.Merge(m, src)
+}
+func (m *Response) XXX_Size() int {
+	return xxx_messageInfo_Response.Size(m)
+}
+func (m *Response) XXX_DiscardUnknown() {
+	xxx_messageInfo_Response.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Response proto.InternalMessageInfo
+
+func (m *Response) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Response) GetMsg() string {
+	if m != nil {
+		return m.Msg
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*Request)(nil), "test.Request")
+	proto.RegisterType((*Response)(nil), "test.Response")
+}
+
+func init() {
+	proto.RegisterFile("wrapper/idempotent/test/test.proto", fileDescriptor_a6454fa32e064f2f)
+}
+
+var fileDescriptor_a6454fa32e064f2f = []byte{
+	// 212 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x52, 0x2a, 0x2f, 0x4a, 0x2c,
+	0x28, 0x48, 0x2d, 0xd2, 0xcf, 0x4c, 0x49, 0xcd, 0x2d, 0xc8, 0x2f, 0x49, 0xcd, 0x2b, 0xd1, 0x2f,
+	0x49, 0x2d, 0x86, 0x10, 0x7a, 0x05, 0x45, 0xf9, 0x25, 0xf9, 0x42, 0x2c, 0x20, 0xb6, 0x92, 0x24,
+	0x17, 0x7b, 0x50, 0x6a, 0x61, 0x69, 0x6a, 0x71, 0x89, 0x10, 0x1f, 0x17, 0x53, 0x66, 0x8a, 0x04,
+	0xa3, 0x02, 0xa3, 0x06, 0x67, 0x10, 0x53, 0x66, 0x8a, 0x92, 0x0e, 0x17, 0x47, 0x50, 0x6a, 0x71,
+	0x41, 0x7e, 0x5e, 0x71, 0x2a, 0xba, 0x9c, 0x90, 0x00, 0x17, 0x73, 0x6e, 0x71, 0xba, 0x04, 0x13,
+	0x58, 0x00, 0xc4, 0x34, 0xea, 0x64, 0xe4, 0x62, 0x77, 0x4e, 0x2c, 0x49, 0xcc, 0xc9, 0x4f, 0x17,
+	0xd2, 0xe4, 0x62, 0x76, 0x4f, 0x2d, 0x11, 0xe2, 0xd5, 0x03, 0x5b, 0x07, 0x35, 0x5f, 0x8a, 0x0f,
+	0xc6, 0x85, 0x98, 0xa9, 0xc4, 0x3c, 0x81, 0x89, 0x11, 0xa4, 0x34, 0xa0, 0x94, 0x18, 0xa5, 0x4c,
+	0x42, 0x1a, 0x5c, 0xac, 0xfe, 0x45, 0x29, 0xa9, 0x45, 0x84, 0x14, 0x33, 0x38, 0xd9, 0x46, 0x59,
+	0xa7, 0x67, 0x96, 0x64, 0x94, 0x26, 0xe9, 0x25, 0xe7, 0xe7, 0xea, 0xa7, 0xe7, 0xeb, 0xe6, 0x66,
+	0x26, 0x17, 0xe5, 0xeb, 0x17, 0xe4, 0x94, 0xa6, 0x67, 0xe6, 0x15, 0xeb, 0x97, 0x99, 0xe8, 0xe3,
+	0x08, 0x1f, 0x6b, 0x10, 0x91, 0xc4, 0x06, 0x0e, 0x20, 0x63, 0xc0, 0x00, 0x91, 0x08, 0x73, 0x74,
+	0x46, 0x01, 0x00, 0x00,
+}
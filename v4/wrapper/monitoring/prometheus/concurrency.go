@@ -0,0 +1,102 @@
+package prometheus
+
+import (
+	"fmt"
+
+	"github.com/go-micro/plugins/v4/util/cardinality"
+	"github.com/go-micro/plugins/v4/wrapper/concurrency"
+	"github.com/prometheus/client_golang/prometheus"
+	"go-micro.dev/v4/logger"
+)
+
+type concurrencyCollector struct {
+	tracker   *concurrency.Tracker
+	labels    []string
+	endpoints *cardinality.Limiter
+
+	inFlight *prometheus.Desc
+	queued   *prometheus.Desc
+	streams  *prometheus.Desc
+	peak     *prometheus.Desc
+}
+
+// NewConcurrencyCollector returns a collector of the stats of the tracker,
+// gauges of the requests in flight, the requests queued and the active
+// streams by endpoint. It's registered with the registry scraped, e.g.
+// prometheus.MustRegister(NewConcurrencyCollector(t, ServiceName("greeter"))).
+// The endpoint label is limited as that of the wrappers.
+func NewConcurrencyCollector(t *concurrency.Tracker, opts ...Option) prometheus.Collector {
+	options := Options{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	limit := options.EndpointLimit
+	if limit == 0 {
+		limit = cardinality.DefaultLimit
+	}
+
+	labels := []string{
+		fmt.Sprintf("%s%s", DefaultLabelPrefix, "name"),
+		fmt.Sprintf("%s%s", DefaultLabelPrefix, "version"),
+		fmt.Sprintf("%s%s", DefaultLabelPrefix, "id"),
+		fmt.Sprintf("%s%s", DefaultLabelPrefix, "endpoint"),
+	}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(DefaultMetricPrefix+name, help, labels, nil)
+	}
+
+	return &concurrencyCollector{
+		tracker: t,
+		labels:  []string{options.Name, options.Version, options.ID},
+		endpoints: cardinality.New(
+			cardinality.Limit(limit),
+			cardinality.Allow(options.AllowEndpoints...),
+			cardinality.OnFirstOverflow(func(v string) {
+				logger.Warnf("Endpoint %s collected as %s, the endpoint label reached its limit of %d values or isn't allowed", v, cardinality.DefaultOverflow, limit)
+			}),
+		),
+		inFlight: desc("requests_in_flight", "Requests being handled, partitioned by endpoint"),
+		queued:   desc("requests_queued", "Requests waiting to be handled, partitioned by endpoint"),
+		streams:  desc("streams_active", "Active streams, partitioned by endpoint"),
+		peak:     desc("requests_in_flight_peak", "Most requests handled at once, partitioned by endpoint"),
+	}
+}
+
+func (c *concurrencyCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.inFlight
+	ch <- c.queued
+	ch <- c.streams
+	ch <- c.peak
+}
+
+func (c *concurrencyCollector) Collect(ch chan<- prometheus.Metric) {
+	// endpoints collected as the overflow value are added up, the peak is
+	// the highest of them
+	var stats []*concurrency.Stats
+	seen := make(map[string]*concurrency.Stats)
+	for _, s := range c.tracker.Snapshot() {
+		s.Endpoint = c.endpoints.Value(s.Endpoint)
+		sum, ok := seen[s.Endpoint]
+		if !ok {
+			s := s
+			seen[s.Endpoint] = &s
+			stats = append(stats, &s)
+			continue
+		}
+		sum.InFlight += s.InFlight
+		sum.Queued += s.Queued
+		sum.Streams += s.Streams
+		if s.Peak > sum.Peak {
+			sum.Peak = s.Peak
+		}
+	}
+
+	for _, s := range stats {
+		labels := append(c.labels[:len(c.labels):len(c.labels)], s.Endpoint)
+		ch <- prometheus.MustNewConstMetric(c.inFlight, prometheus.GaugeValue, float64(s.InFlight), labels...)
+		ch <- prometheus.MustNewConstMetric(c.queued, prometheus.GaugeValue, float64(s.Queued), labels...)
+		ch <- prometheus.MustNewConstMetric(c.streams, prometheus.GaugeValue, float64(s.Streams), labels...)
+		ch <- prometheus.MustNewConstMetric(c.peak, prometheus.GaugeValue, float64(s.Peak), labels...)
+	}
+}
@@ -0,0 +1,87 @@
+package prometheus
+
+import (
+	"fmt"
+
+	"github.com/go-micro/plugins/v4/client/grpc"
+	"github.com/prometheus/client_golang/prometheus"
+	"go-micro.dev/v4/client"
+)
+
+type grpcCollector struct {
+	client client.Client
+	labels []string
+
+	conns   *prometheus.Desc
+	streams *prometheus.Desc
+	idle    *prometheus.Desc
+	dials   *prometheus.Desc
+	failed  *prometheus.Desc
+	reaped  *prometheus.Desc
+	trimmed *prometheus.Desc
+}
+
+// NewGRPCCollector returns a collector of the pool stats of a grpc client,
+// gauges of the pooled connections by connectivity state, the streams and
+// the idle connections, and counters of the dials and the reaped
+// connections by target. Keep a reference to the grpc client, a wrapped
+// client isn't collected. It's registered with the registry scraped, e.g.
+// prometheus.MustRegister(NewGRPCCollector(c, ServiceName("greeter"))).
+func NewGRPCCollector(c client.Client, opts ...Option) prometheus.Collector {
+	options := Options{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	labels := []string{
+		fmt.Sprintf("%s%s", DefaultLabelPrefix, "name"),
+		fmt.Sprintf("%s%s", DefaultLabelPrefix, "version"),
+		fmt.Sprintf("%s%s", DefaultLabelPrefix, "id"),
+		fmt.Sprintf("%s%s", DefaultLabelPrefix, "target"),
+	}
+	desc := func(name, help string, extra ...string) *prometheus.Desc {
+		return prometheus.NewDesc(DefaultMetricPrefix+name, help, append(labels[:len(labels):len(labels)], extra...), nil)
+	}
+
+	return &grpcCollector{
+		client:  c,
+		labels:  []string{options.Name, options.Version, options.ID},
+		conns:   desc("grpc_client_conns", "Pooled connections, partitioned by target and connectivity state", DefaultLabelPrefix+"state"),
+		streams: desc("grpc_client_streams", "Streams in use, partitioned by target"),
+		idle:    desc("grpc_client_conns_idle", "Pooled connections without streams, partitioned by target"),
+		dials:   desc("grpc_client_dials_total", "Connections dialed, partitioned by target"),
+		failed:  desc("grpc_client_dials_failed_total", "Dials which failed, partitioned by target"),
+		reaped:  desc("grpc_client_conns_reaped_total", "Idle connections closed as they were unused or their peer was gone, partitioned by target"),
+		trimmed: desc("grpc_client_conns_trimmed_total", "Idle connections closed as the heap was above the limit, partitioned by target"),
+	}
+}
+
+func (c *grpcCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.conns
+	ch <- c.streams
+	ch <- c.idle
+	ch <- c.dials
+	ch <- c.failed
+	ch <- c.reaped
+	ch <- c.trimmed
+}
+
+func (c *grpcCollector) Collect(ch chan<- prometheus.Metric) {
+	stats, ok := grpc.Stats(c.client)
+	if !ok {
+		return
+	}
+
+	for _, ts := range stats {
+		labels := append(c.labels[:len(c.labels):len(c.labels)], ts.Target)
+		for state, n := range ts.States {
+			ch <- prometheus.MustNewConstMetric(c.conns, prometheus.GaugeValue, float64(n), append(labels, state)...)
+		}
+		ch <- prometheus.MustNewConstMetric(c.streams, prometheus.GaugeValue, float64(ts.Streams), labels...)
+		ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(ts.Idle), labels...)
+		ch <- prometheus.MustNewConstMetric(c.dials, prometheus.CounterValue, float64(ts.Dials), labels...)
+		ch <- prometheus.MustNewConstMetric(c.failed, prometheus.CounterValue, float64(ts.FailedDials), labels...)
+		ch <- prometheus.MustNewConstMetric(c.reaped, prometheus.CounterValue, float64(ts.Reaped), labels...)
+		ch <- prometheus.MustNewConstMetric(c.trimmed, prometheus.CounterValue, float64(ts.Trimmed), labels...)
+	}
+}
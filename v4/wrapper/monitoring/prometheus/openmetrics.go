@@ -0,0 +1,163 @@
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// created tracks when the series of the wrapper's metrics were created, the
+// client library doesn't expose it.
+var created = &createdTimes{}
+
+type createdTimes struct {
+	// labels are the label names of the metrics by name
+	labels map[string][]string
+	times  sync.Map
+}
+
+func createdKey(name string, values []string) string {
+	return name + "\xff" + strings.Join(values, "\xff")
+}
+
+func (c *createdTimes) touch(name string, values []string) {
+	key := createdKey(name, values)
+	if _, ok := c.times.Load(key); !ok {
+		c.times.LoadOrStore(key, float64(time.Now().UnixNano())/1e9)
+	}
+}
+
+// get returns the created timestamp of the metric in seconds.
+func (c *createdTimes) get(name string, m *dto.Metric) (float64, bool) {
+	names, ok := c.labels[name]
+	if !ok {
+		return 0, false
+	}
+
+	values := make([]string, len(names))
+	for i, n := range names {
+		for _, l := range m.GetLabel() {
+			if l.GetName() == n {
+				values[i] = l.GetValue()
+				break
+			}
+		}
+	}
+
+	v, ok := c.times.Load(createdKey(name, values))
+	if !ok {
+		return 0, false
+	}
+	return v.(float64), true
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+// writeCreated writes the _created sample of the metric.
+func writeCreated(w io.Writer, mf *dto.MetricFamily, m *dto.Metric) error {
+	ts, ok := created.get(mf.GetName(), m)
+	if !ok {
+		return nil
+	}
+
+	name := mf.GetName()
+	if mf.GetType() == dto.MetricType_COUNTER {
+		name = strings.TrimSuffix(name, "_total")
+	}
+
+	labels := make([]string, 0, len(m.GetLabel()))
+	for _, l := range m.GetLabel() {
+		labels = append(labels, fmt.Sprintf(`%s="%s"`, l.GetName(), labelEscaper.Replace(l.GetValue())))
+	}
+
+	_, err := fmt.Fprintf(w, "%s_created{%s} %s\n", name, strings.Join(labels, ","), strconv.FormatFloat(ts, 'f', -1, 64))
+	return err
+}
+
+// writeFamily writes the metric family one metric at a time, so the _created
+// sample of a metric follows its other samples as OpenMetrics requires.
+func writeFamily(w io.Writer, mf *dto.MetricFamily) error {
+	var buf bytes.Buffer
+
+	for i, m := range mf.GetMetric() {
+		buf.Reset()
+
+		single := &dto.MetricFamily{Name: mf.Name, Help: mf.Help, Type: mf.Type, Metric: []*dto.Metric{m}}
+		if _, err := expfmt.MetricFamilyToOpenMetrics(&buf, single); err != nil {
+			return err
+		}
+
+		// the metadata of the family is written only once
+		b := buf.Bytes()
+		for i > 0 && bytes.HasPrefix(b, []byte("# ")) {
+			n := bytes.IndexByte(b, '\n')
+			if n < 0 {
+				break
+			}
+			b = b[n+1:]
+		}
+
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+		if err := writeCreated(w, mf, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteOpenMetrics writes the metrics of the gatherer in the OpenMetrics
+// format, with the created timestamps of the wrapper's metrics.
+func WriteOpenMetrics(w io.Writer, g prometheus.Gatherer) error {
+	mfs, err := g.Gather()
+	if err != nil {
+		return err
+	}
+
+	for _, mf := range mfs {
+		if err := writeFamily(w, mf); err != nil {
+			return err
+		}
+	}
+
+	_, err = expfmt.FinalizeOpenMetrics(w)
+	return err
+}
+
+// Handler returns a handler exposing the metrics of the default gatherer. With
+// the OpenMetrics option the format is negotiated with the scraper.
+func Handler(opts ...Option) http.Handler {
+	options := Options{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	text := promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{})
+	if !options.OpenMetrics {
+		return text
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if expfmt.NegotiateIncludingOpenMetrics(r.Header) != expfmt.FmtOpenMetrics {
+			text.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", string(expfmt.FmtOpenMetrics))
+		if err := WriteOpenMetrics(w, prometheus.DefaultGatherer); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -3,7 +3,9 @@ package prometheus
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/go-micro/plugins/v4/util/cardinality"
 	"github.com/prometheus/client_golang/prometheus"
 	"go-micro.dev/v4/client"
 	"go-micro.dev/v4/logger"
@@ -20,12 +22,35 @@ var (
 	opsCounter           *prometheus.CounterVec
 	timeCounterSummary   *prometheus.SummaryVec
 	timeCounterHistogram *prometheus.HistogramVec
+	overflowCounter      *prometheus.CounterVec
+
+	counterName   string
+	summaryName   string
+	histogramName string
+	overflowName  string
 )
 
 type Options struct {
 	Name    string
 	Version string
 	ID      string
+
+	// Pushgateway is the url of the Pushgateway a Pusher pushes to
+	Pushgateway string
+	// Job is the job label of the pushed metrics
+	Job string
+	// PushInterval is the interval of a started Pusher, zero only pushes
+	// when it's stopped
+	PushInterval time.Duration
+	// OpenMetrics enables the OpenMetrics exposition of the Handler
+	OpenMetrics bool
+	// EndpointLimit is the number of distinct endpoint labels of a wrapper,
+	// further endpoints are reported as cardinality.DefaultOverflow.
+	// Defaults to cardinality.DefaultLimit, less than zero disables it
+	EndpointLimit int
+	// AllowEndpoints are the patterns of the endpoint labels reported,
+	// others are reported as cardinality.DefaultOverflow
+	AllowEndpoints []string
 }
 
 type Option func(*Options)
@@ -48,54 +73,110 @@ func ServiceID(id string) Option {
 	}
 }
 
+// Pushgateway sets the Pushgateway and job metrics are pushed to.
+func Pushgateway(url, job string) Option {
+	return func(opts *Options) {
+		opts.Pushgateway = url
+		opts.Job = job
+	}
+}
+
+// PushInterval sets the interval metrics are pushed in.
+func PushInterval(d time.Duration) Option {
+	return func(opts *Options) {
+		opts.PushInterval = d
+	}
+}
+
+// OpenMetrics exposes the OpenMetrics format, including the created
+// timestamps of counters, summaries and histograms, to scrapers accepting it.
+func OpenMetrics() Option {
+	return func(opts *Options) {
+		opts.OpenMetrics = true
+	}
+}
+
+// EndpointLimit bounds the distinct endpoint labels of the wrapper, so the
+// endpoints of a proxy or of topics named after tenants can't create an
+// unbounded number of series.
+func EndpointLimit(n int) Option {
+	return func(opts *Options) {
+		opts.EndpointLimit = n
+	}
+}
+
+// AllowEndpoints only reports the endpoints matching one of the path.Match
+// patterns, e.g. "Greeter.*".
+func AllowEndpoints(patterns ...string) Option {
+	return func(opts *Options) {
+		opts.AllowEndpoints = append(opts.AllowEndpoints, patterns...)
+	}
+}
+
 func init() {
+	counterName = fmt.Sprintf("%srequest_total", DefaultMetricPrefix)
+	summaryName = fmt.Sprintf("%slatency_microseconds", DefaultMetricPrefix)
+	histogramName = fmt.Sprintf("%srequest_duration_seconds", DefaultMetricPrefix)
+	overflowName = fmt.Sprintf("%slabel_overflow_total", DefaultMetricPrefix)
+
+	labels := []string{
+		fmt.Sprintf("%s%s", DefaultLabelPrefix, "name"),
+		fmt.Sprintf("%s%s", DefaultLabelPrefix, "version"),
+		fmt.Sprintf("%s%s", DefaultLabelPrefix, "id"),
+		fmt.Sprintf("%s%s", DefaultLabelPrefix, "endpoint"),
+	}
+	counterLabels := append(labels[:len(labels):len(labels)], fmt.Sprintf("%s%s", DefaultLabelPrefix, "status"))
+
+	overflowLabels := append(labels[:3:3], fmt.Sprintf("%s%s", DefaultLabelPrefix, "label"))
+
+	created.labels = map[string][]string{
+		counterName:   counterLabels,
+		summaryName:   labels,
+		histogramName: labels,
+		overflowName:  overflowLabels,
+	}
+
 	if opsCounter == nil {
 		opsCounter = prometheus.NewCounterVec(
 			prometheus.CounterOpts{
-				Name: fmt.Sprintf("%srequest_total", DefaultMetricPrefix),
+				Name: counterName,
 				Help: "Requests processed, partitioned by endpoint and status",
 			},
-			[]string{
-				fmt.Sprintf("%s%s", DefaultLabelPrefix, "name"),
-				fmt.Sprintf("%s%s", DefaultLabelPrefix, "version"),
-				fmt.Sprintf("%s%s", DefaultLabelPrefix, "id"),
-				fmt.Sprintf("%s%s", DefaultLabelPrefix, "endpoint"),
-				fmt.Sprintf("%s%s", DefaultLabelPrefix, "status"),
-			},
+			counterLabels,
 		)
 	}
 
 	if timeCounterSummary == nil {
 		timeCounterSummary = prometheus.NewSummaryVec(
 			prometheus.SummaryOpts{
-				Name: fmt.Sprintf("%slatency_microseconds", DefaultMetricPrefix),
+				Name: summaryName,
 				Help: "Request latencies in microseconds, partitioned by endpoint",
 			},
-			[]string{
-				fmt.Sprintf("%s%s", DefaultLabelPrefix, "name"),
-				fmt.Sprintf("%s%s", DefaultLabelPrefix, "version"),
-				fmt.Sprintf("%s%s", DefaultLabelPrefix, "id"),
-				fmt.Sprintf("%s%s", DefaultLabelPrefix, "endpoint"),
-			},
+			labels,
 		)
 	}
 
 	if timeCounterHistogram == nil {
 		timeCounterHistogram = prometheus.NewHistogramVec(
 			prometheus.HistogramOpts{
-				Name: fmt.Sprintf("%srequest_duration_seconds", DefaultMetricPrefix),
+				Name: histogramName,
 				Help: "Request time in seconds, partitioned by endpoint",
 			},
-			[]string{
-				fmt.Sprintf("%s%s", DefaultLabelPrefix, "name"),
-				fmt.Sprintf("%s%s", DefaultLabelPrefix, "version"),
-				fmt.Sprintf("%s%s", DefaultLabelPrefix, "id"),
-				fmt.Sprintf("%s%s", DefaultLabelPrefix, "endpoint"),
+			labels,
+		)
+	}
+
+	if overflowCounter == nil {
+		overflowCounter = prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: overflowName,
+				Help: "Requests reported under the overflow value of a label past its cardinality limit",
 			},
+			overflowLabels,
 		)
 	}
 
-	for _, collector := range []prometheus.Collector{opsCounter, timeCounterSummary, timeCounterHistogram} {
+	for _, collector := range []prometheus.Collector{opsCounter, timeCounterSummary, timeCounterHistogram, overflowCounter} {
 		if err := prometheus.DefaultRegisterer.Register(collector); err != nil {
 			// if already registered, skip fatal
 			if _, ok := err.(prometheus.AlreadyRegisteredError); !ok {
@@ -106,24 +187,76 @@ func init() {
 }
 
 type wrapper struct {
-	options  Options
-	callFunc client.CallFunc
+	options   Options
+	callFunc  client.CallFunc
+	endpoints *cardinality.Limiter
 	client.Client
 }
 
+func newWrapper(options Options) *wrapper {
+	limit := options.EndpointLimit
+	if limit == 0 {
+		limit = cardinality.DefaultLimit
+	}
+
+	labels := []string{options.Name, options.Version, options.ID, "endpoint"}
+	endpoints := cardinality.New(
+		cardinality.Limit(limit),
+		cardinality.Allow(options.AllowEndpoints...),
+		cardinality.OnFirstOverflow(func(v string) {
+			logger.Warnf("Endpoint %s reported as %s, the endpoint label reached its limit of %d values or isn't allowed", v, cardinality.DefaultOverflow, limit)
+		}),
+		cardinality.OnOverflow(func(string) {
+			overflowCounter.WithLabelValues(labels...).Inc()
+			created.touch(overflowName, labels)
+		}),
+	)
+
+	return &wrapper{
+		options:   options,
+		endpoints: endpoints,
+	}
+}
+
+// observe returns a timer observing the latency of a request to the endpoint,
+// the value of the endpoint label.
+func (w *wrapper) observe(endpoint string) *prometheus.Timer {
+	labels := []string{w.options.Name, w.options.Version, w.options.ID, endpoint}
+
+	return prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		us := v * 1000000 // make microseconds
+		timeCounterSummary.WithLabelValues(labels...).Observe(us)
+		timeCounterHistogram.WithLabelValues(labels...).Observe(v)
+		created.touch(summaryName, labels)
+		created.touch(histogramName, labels)
+	}))
+}
+
+// count counts a request to the endpoint by its status.
+func (w *wrapper) count(endpoint string, err error) {
+	status := "success"
+	if err != nil {
+		status = "failure"
+	}
+
+	labels := []string{w.options.Name, w.options.Version, w.options.ID, endpoint, status}
+	opsCounter.WithLabelValues(labels...).Inc()
+	created.touch(counterName, labels)
+}
+
 func NewClientWrapper(opts ...Option) client.Wrapper {
 	options := Options{}
 	for _, opt := range opts {
 		opt(&options)
 	}
 
+	w := newWrapper(options)
+
 	return func(c client.Client) client.Client {
-		handler := &wrapper{
-			options: options,
-			Client:  c,
-		}
+		handler := *w
+		handler.Client = c
 
-		return handler
+		return &handler
 	}
 }
 
@@ -133,92 +266,57 @@ func NewCallWrapper(opts ...Option) client.CallWrapper {
 		opt(&options)
 	}
 
+	// the client wraps every call anew, the endpoint limit is shared by them
+	w := newWrapper(options)
+
 	return func(fn client.CallFunc) client.CallFunc {
-		handler := &wrapper{
-			options:  options,
-			callFunc: fn,
-		}
+		handler := *w
+		handler.callFunc = fn
 
 		return handler.CallFunc
 	}
 }
 
 func (w *wrapper) CallFunc(ctx context.Context, node *registry.Node, req client.Request, rsp interface{}, opts client.CallOptions) error {
-	endpoint := fmt.Sprintf("%s.%s", req.Service(), req.Endpoint())
+	endpoint := w.endpoints.Value(fmt.Sprintf("%s.%s", req.Service(), req.Endpoint()))
 
-	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
-		us := v * 1000000 // make microseconds
-		timeCounterSummary.WithLabelValues(w.options.Name, w.options.Version, w.options.ID, endpoint).Observe(us)
-		timeCounterHistogram.WithLabelValues(w.options.Name, w.options.Version, w.options.ID, endpoint).Observe(v)
-	}))
-	defer timer.ObserveDuration()
+	defer w.observe(endpoint).ObserveDuration()
 
 	err := w.callFunc(ctx, node, req, rsp, opts)
-	if err == nil {
-		opsCounter.WithLabelValues(w.options.Name, w.options.Version, w.options.ID, endpoint, "success").Inc()
-	} else {
-		opsCounter.WithLabelValues(w.options.Name, w.options.Version, w.options.ID, endpoint, "failure").Inc()
-	}
+	w.count(endpoint, err)
 
 	return err
 }
 
 func (w *wrapper) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
-	endpoint := fmt.Sprintf("%s.%s", req.Service(), req.Endpoint())
+	endpoint := w.endpoints.Value(fmt.Sprintf("%s.%s", req.Service(), req.Endpoint()))
 
-	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
-		us := v * 1000000 // make microseconds
-		timeCounterSummary.WithLabelValues(w.options.Name, w.options.Version, w.options.ID, endpoint).Observe(us)
-		timeCounterHistogram.WithLabelValues(w.options.Name, w.options.Version, w.options.ID, endpoint).Observe(v)
-	}))
-	defer timer.ObserveDuration()
+	defer w.observe(endpoint).ObserveDuration()
 
 	err := w.Client.Call(ctx, req, rsp, opts...)
-	if err == nil {
-		opsCounter.WithLabelValues(w.options.Name, w.options.Version, w.options.ID, endpoint, "success").Inc()
-	} else {
-		opsCounter.WithLabelValues(w.options.Name, w.options.Version, w.options.ID, endpoint, "failure").Inc()
-	}
+	w.count(endpoint, err)
 
 	return err
 }
 
 func (w *wrapper) Stream(ctx context.Context, req client.Request, opts ...client.CallOption) (client.Stream, error) {
-	endpoint := fmt.Sprintf("%s.%s", req.Service(), req.Endpoint())
+	endpoint := w.endpoints.Value(fmt.Sprintf("%s.%s", req.Service(), req.Endpoint()))
 
-	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
-		us := v * 1000000 // make microseconds
-		timeCounterSummary.WithLabelValues(w.options.Name, w.options.Version, w.options.ID, endpoint).Observe(us)
-		timeCounterHistogram.WithLabelValues(w.options.Name, w.options.Version, w.options.ID, endpoint).Observe(v)
-	}))
-	defer timer.ObserveDuration()
+	defer w.observe(endpoint).ObserveDuration()
 
 	stream, err := w.Client.Stream(ctx, req, opts...)
-	if err == nil {
-		opsCounter.WithLabelValues(w.options.Name, w.options.Version, w.options.ID, endpoint, "success").Inc()
-	} else {
-		opsCounter.WithLabelValues(w.options.Name, w.options.Version, w.options.ID, endpoint, "failure").Inc()
-	}
+	w.count(endpoint, err)
 
 	return stream, err
 }
 
 func (w *wrapper) Publish(ctx context.Context, p client.Message, opts ...client.PublishOption) error {
-	endpoint := p.Topic()
+	endpoint := w.endpoints.Value(p.Topic())
 
-	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
-		us := v * 1000000 // make microseconds
-		timeCounterSummary.WithLabelValues(w.options.Name, w.options.Version, w.options.ID, endpoint).Observe(us)
-		timeCounterHistogram.WithLabelValues(w.options.Name, w.options.Version, w.options.ID, endpoint).Observe(v)
-	}))
-	defer timer.ObserveDuration()
+	defer w.observe(endpoint).ObserveDuration()
 
 	err := w.Client.Publish(ctx, p, opts...)
-	if err == nil {
-		opsCounter.WithLabelValues(w.options.Name, w.options.Version, w.options.ID, endpoint, "success").Inc()
-	} else {
-		opsCounter.WithLabelValues(w.options.Name, w.options.Version, w.options.ID, endpoint, "failure").Inc()
-	}
+	w.count(endpoint, err)
 
 	return err
 }
@@ -229,30 +327,19 @@ func NewHandlerWrapper(opts ...Option) server.HandlerWrapper {
 		opt(&options)
 	}
 
-	handler := &wrapper{
-		options: options,
-	}
+	handler := newWrapper(options)
 
 	return handler.HandlerFunc
 }
 
 func (w *wrapper) HandlerFunc(fn server.HandlerFunc) server.HandlerFunc {
 	return func(ctx context.Context, req server.Request, rsp interface{}) error {
-		endpoint := req.Endpoint()
+		endpoint := w.endpoints.Value(req.Endpoint())
 
-		timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
-			us := v * 1000000 // make microseconds
-			timeCounterSummary.WithLabelValues(w.options.Name, w.options.Version, w.options.ID, endpoint).Observe(us)
-			timeCounterHistogram.WithLabelValues(w.options.Name, w.options.Version, w.options.ID, endpoint).Observe(v)
-		}))
-		defer timer.ObserveDuration()
+		defer w.observe(endpoint).ObserveDuration()
 
 		err := fn(ctx, req, rsp)
-		if err == nil {
-			opsCounter.WithLabelValues(w.options.Name, w.options.Version, w.options.ID, endpoint, "success").Inc()
-		} else {
-			opsCounter.WithLabelValues(w.options.Name, w.options.Version, w.options.ID, endpoint, "failure").Inc()
-		}
+		w.count(endpoint, err)
 
 		return err
 	}
@@ -264,30 +351,19 @@ func NewSubscriberWrapper(opts ...Option) server.SubscriberWrapper {
 		opt(&options)
 	}
 
-	handler := &wrapper{
-		options: options,
-	}
+	handler := newWrapper(options)
 
 	return handler.SubscriberFunc
 }
 
 func (w *wrapper) SubscriberFunc(fn server.SubscriberFunc) server.SubscriberFunc {
 	return func(ctx context.Context, msg server.Message) error {
-		endpoint := msg.Topic()
+		endpoint := w.endpoints.Value(msg.Topic())
 
-		timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
-			us := v * 1000000 // make microseconds
-			timeCounterSummary.WithLabelValues(w.options.Name, w.options.Version, w.options.ID, endpoint).Observe(us)
-			timeCounterHistogram.WithLabelValues(w.options.Name, w.options.Version, w.options.ID, endpoint).Observe(v)
-		}))
-		defer timer.ObserveDuration()
+		defer w.observe(endpoint).ObserveDuration()
 
 		err := fn(ctx, msg)
-		if err == nil {
-			opsCounter.WithLabelValues(w.options.Name, w.options.Version, w.options.ID, endpoint, "success").Inc()
-		} else {
-			opsCounter.WithLabelValues(w.options.Name, w.options.Version, w.options.ID, endpoint, "failure").Inc()
-		}
+		w.count(endpoint, err)
 
 		return err
 	}
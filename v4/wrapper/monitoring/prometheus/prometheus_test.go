@@ -3,14 +3,22 @@ package prometheus_test
 import (
 	"context"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/go-micro/plugins/v4/client/grpc"
+	"github.com/go-micro/plugins/v4/wrapper/concurrency"
 	promwrapper "github.com/go-micro/plugins/v4/wrapper/monitoring/prometheus"
 	"github.com/prometheus/client_golang/prometheus"
 	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	"go-micro.dev/v4/broker"
 	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/codec/bytes"
 	"go-micro.dev/v4/registry"
 	"go-micro.dev/v4/selector"
 	"go-micro.dev/v4/server"
@@ -173,3 +181,270 @@ func findMetricByName(list []*dto.MetricFamily, tp dto.MetricType, name string)
 
 	return nil
 }
+
+type testMessage struct {
+	server.Message
+	topic string
+}
+
+func (m *testMessage) Topic() string {
+	return m.topic
+}
+
+func TestOpenMetricsHandler(t *testing.T) {
+	wrapper := promwrapper.NewSubscriberWrapper(promwrapper.ServiceName("openmetrics"))
+	handler := wrapper(func(ctx context.Context, msg server.Message) error {
+		return nil
+	})
+	assert.NoError(t, handler(context.TODO(), &testMessage{topic: "events"}))
+	assert.NoError(t, handler(context.TODO(), &testMessage{topic: "orders"}))
+
+	srv := httptest.NewServer(promwrapper.Handler(promwrapper.OpenMetrics()))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=0.0.1")
+
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+
+	b, _ := io.ReadAll(rsp.Body)
+	body := string(b)
+
+	assert.Contains(t, rsp.Header.Get("Content-Type"), "application/openmetrics-text")
+	assert.Contains(t, body, `micro_request_created{micro_endpoint="events",micro_id="",micro_name="openmetrics",micro_status="success",micro_version=""} `)
+	assert.Contains(t, body, `micro_request_duration_seconds_created{micro_endpoint="events"`)
+	assert.True(t, strings.HasSuffix(body, "# EOF\n"))
+	assert.Equal(t, 1, strings.Count(body, "# TYPE micro_request counter\n"))
+
+	// the created sample follows the other samples of its labels
+	lines := strings.Split(body, "\n")
+	var seen int
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "micro_request_created{") {
+			continue
+		}
+		seen++
+		labels := line[len("micro_request_created"):strings.Index(line, "} ")+1]
+		assert.True(t, strings.HasPrefix(lines[i-1], "micro_request_total"+labels+" "), "Expected %q after the samples of its labels, got %q", line, lines[i-1])
+	}
+	assert.GreaterOrEqual(t, seen, 2)
+
+	// plain text for scrapers not accepting OpenMetrics
+	rsp, err = http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+
+	b, _ = io.ReadAll(rsp.Body)
+	assert.NotContains(t, string(b), "_created")
+}
+
+func TestPusher(t *testing.T) {
+	var pushed []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pushed = append(pushed, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	p := promwrapper.NewPusher(
+		promwrapper.ServiceID("id-1"),
+		promwrapper.Pushgateway(srv.URL, "batch"),
+		promwrapper.PushInterval(time.Hour),
+	)
+
+	assert.NoError(t, p.Start())
+	assert.NoError(t, p.Stop())
+	assert.Equal(t, []string{"PUT /metrics/job/batch/instance/id-1"}, pushed)
+}
+
+func TestEndpointLimit(t *testing.T) {
+	wrapper := promwrapper.NewSubscriberWrapper(promwrapper.ServiceName("tenants"), promwrapper.EndpointLimit(2))
+	handler := wrapper(func(ctx context.Context, msg server.Message) error {
+		return nil
+	})
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, handler(context.TODO(), &testMessage{topic: fmt.Sprintf("tenant-%d", i)}))
+	}
+
+	list, _ := prometheus.DefaultGatherer.Gather()
+
+	endpoints := map[string]float64{}
+	for _, m := range findMetricByName(list, dto.MetricType_COUNTER, "micro_request_total").Metric {
+		labels := map[string]string{}
+		for _, l := range m.Label {
+			labels[*l.Name] = *l.Value
+		}
+		if labels["micro_name"] == "tenants" {
+			endpoints[labels["micro_endpoint"]] += *m.Counter.Value
+		}
+	}
+	assert.Equal(t, map[string]float64{"tenant-0": 1, "tenant-1": 1, "overflow": 3}, endpoints)
+
+	overflow := findMetricByName(list, dto.MetricType_COUNTER, "micro_label_overflow_total")
+	if overflow == nil {
+		t.Fatal("no overflow metric")
+	}
+	for _, m := range overflow.Metric {
+		for _, l := range m.Label {
+			if *l.Name == "micro_name" && *l.Value == "tenants" {
+				assert.Equal(t, float64(3), *m.Counter.Value)
+			}
+		}
+	}
+
+	allow := promwrapper.NewSubscriberWrapper(promwrapper.ServiceName("allowed"), promwrapper.AllowEndpoints("orders.*"))
+	handler = allow(func(ctx context.Context, msg server.Message) error {
+		return nil
+	})
+	assert.NoError(t, handler(context.TODO(), &testMessage{topic: "orders.created"}))
+	assert.NoError(t, handler(context.TODO(), &testMessage{topic: "tenant-9"}))
+
+	list, _ = prometheus.DefaultGatherer.Gather()
+	var got []string
+	for _, m := range findMetricByName(list, dto.MetricType_COUNTER, "micro_request_total").Metric {
+		labels := map[string]string{}
+		for _, l := range m.Label {
+			labels[*l.Name] = *l.Value
+		}
+		if labels["micro_name"] == "allowed" {
+			got = append(got, labels["micro_endpoint"])
+		}
+	}
+	assert.ElementsMatch(t, []string{"orders.created", "overflow"}, got)
+}
+
+func TestConcurrencyCollector(t *testing.T) {
+	tracker := concurrency.New()
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(promwrapper.NewConcurrencyCollector(tracker, promwrapper.ServiceName("busy")))
+
+	release := make(chan struct{})
+	handler := tracker.SubscriberWrapper()(func(ctx context.Context, msg server.Message) error {
+		<-release
+		return nil
+	})
+	for i := 0; i < 2; i++ {
+		go handler(context.TODO(), &testMessage{topic: "orders"})
+	}
+
+	inFlight := func() float64 {
+		list, _ := reg.Gather()
+		m := findMetricByName(list, dto.MetricType_GAUGE, "micro_requests_in_flight")
+		if m == nil || len(m.Metric) == 0 {
+			return 0
+		}
+		return *m.Metric[0].Gauge.Value
+	}
+	for i := 0; inFlight() != 2; i++ {
+		if i == 100 {
+			t.Fatalf("Expected 2 messages in flight, got %v", inFlight())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(release)
+	for i := 0; inFlight() != 0; i++ {
+		if i == 100 {
+			t.Fatalf("Expected no messages in flight, got %v", inFlight())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestConcurrencyCollectorEndpointLimit(t *testing.T) {
+	tracker := concurrency.New()
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(promwrapper.NewConcurrencyCollector(tracker, promwrapper.ServiceName("busy"), promwrapper.EndpointLimit(1)))
+
+	release := make(chan struct{})
+	handler := tracker.SubscriberWrapper()(func(ctx context.Context, msg server.Message) error {
+		<-release
+		return nil
+	})
+	defer close(release)
+	for _, topic := range []string{"orders", "tenant-1", "tenant-2"} {
+		go handler(context.TODO(), &testMessage{topic: topic})
+	}
+	// the first endpoint collected is kept
+	for i := 0; len(tracker.Snapshot()) != 3; i++ {
+		if i == 100 {
+			t.Fatalf("Expected 3 topics tracked, got %+v", tracker.Snapshot())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// the endpoints past the limit are collected together
+	inFlight := func() map[string]float64 {
+		list, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]float64)
+		if m := findMetricByName(list, dto.MetricType_GAUGE, "micro_requests_in_flight"); m != nil {
+			for _, m := range m.Metric {
+				for _, l := range m.Label {
+					if *l.Name == "micro_endpoint" {
+						got[*l.Value] = *m.Gauge.Value
+					}
+				}
+			}
+		}
+		return got
+	}
+	for i := 0; ; i++ {
+		got := inFlight()
+		if len(got) == 2 && got["orders"] == 1 && got["overflow"] == 2 {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("Expected orders and the overflow in flight, got %v", got)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestGRPCCollector(t *testing.T) {
+	c := grpc.NewClient(client.Retries(0), client.RequestTimeout(100*time.Millisecond))
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(promwrapper.NewGRPCCollector(c, promwrapper.ServiceName("caller")))
+
+	// nothing listens on the target, the call fails after dialing
+	req := c.NewRequest("greeter", "Greeter.Hello", &bytes.Frame{Data: []byte("{}")})
+	if err := c.Call(context.TODO(), req, &bytes.Frame{}, client.WithAddress("127.0.0.1:1")); err == nil {
+		t.Fatal("Expected the call to fail")
+	}
+
+	list, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := findMetricByName(list, dto.MetricType_COUNTER, "micro_grpc_client_dials_total")
+	if m == nil || len(m.Metric) != 1 {
+		t.Fatalf("Expected the dials of the target, got %v", m)
+	}
+	labels := make(map[string]string)
+	for _, l := range m.Metric[0].Label {
+		labels[l.GetName()] = l.GetValue()
+	}
+	if labels["micro_target"] != "127.0.0.1:1" || labels["micro_name"] != "caller" {
+		t.Errorf("Unexpected labels %v", labels)
+	}
+	if v := m.Metric[0].Counter.GetValue(); v != 1 {
+		t.Errorf("Expected 1 dial, got %v", v)
+	}
+
+	// other clients aren't collected
+	reg = prometheus.NewRegistry()
+	reg.MustRegister(promwrapper.NewGRPCCollector(client.NewClient()))
+	if list, _ := reg.Gather(); len(list) != 0 {
+		t.Errorf("Expected no metrics, got %v", list)
+	}
+}
@@ -0,0 +1,90 @@
+package prometheus
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"go-micro.dev/v4/logger"
+)
+
+// Pusher pushes the metrics of the default gatherer to a Pushgateway, for
+// short lived jobs which live too briefly to be scraped.
+type Pusher struct {
+	options Options
+	pusher  *push.Pusher
+
+	once sync.Once
+	exit chan bool
+	wg   sync.WaitGroup
+}
+
+// NewPusher returns a pusher for the Pushgateway option. The metrics are
+// grouped by the job and the service id, if set.
+func NewPusher(opts ...Option) *Pusher {
+	options := Options{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	job := options.Job
+	if len(job) == 0 {
+		job = options.Name
+	}
+
+	pusher := push.New(options.Pushgateway, job).Gatherer(prometheus.DefaultGatherer)
+	if len(options.ID) > 0 {
+		pusher = pusher.Grouping("instance", options.ID)
+	}
+
+	return &Pusher{
+		options: options,
+		pusher:  pusher,
+		exit:    make(chan bool),
+	}
+}
+
+// Push pushes the metrics, replacing the previously pushed metrics of the group.
+func (p *Pusher) Push() error {
+	return p.pusher.Push()
+}
+
+// Start pushes the metrics every push interval until the pusher is stopped.
+// It has the signature of a micro.AfterStart hook.
+func (p *Pusher) Start() error {
+	if p.options.PushInterval <= 0 {
+		return nil
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		t := time.NewTicker(p.options.PushInterval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-t.C:
+				if err := p.Push(); err != nil {
+					logger.Logf(logger.ErrorLevel, "[prometheus] failed to push metrics: %v", err)
+				}
+			case <-p.exit:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops pushing and pushes the final metrics. It has the signature of a
+// micro.AfterStop hook.
+func (p *Pusher) Stop() error {
+	p.once.Do(func() {
+		close(p.exit)
+	})
+	p.wg.Wait()
+	return p.Push()
+}
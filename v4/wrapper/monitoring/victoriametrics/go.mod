@@ -4,6 +4,7 @@ go 1.17
 
 require (
 	github.com/VictoriaMetrics/metrics v1.17.2
+	github.com/go-micro/plugins/v4/util/cardinality v1.0.0
 	github.com/stretchr/testify v1.7.0
 	go-micro.dev/v4 v4.9.0
 )
@@ -30,3 +31,5 @@ require (
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b // indirect
 )
+
+replace github.com/go-micro/plugins/v4/util/cardinality => ../../../util/cardinality
@@ -7,7 +7,9 @@ import (
 	"time"
 
 	metrics "github.com/VictoriaMetrics/metrics"
+	"github.com/go-micro/plugins/v4/util/cardinality"
 	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/logger"
 	"go-micro.dev/v4/registry"
 	"go-micro.dev/v4/server"
 )
@@ -23,6 +25,14 @@ type Options struct {
 	Name    string
 	Version string
 	ID      string
+
+	// EndpointLimit is the number of distinct endpoint labels of a wrapper,
+	// further endpoints are reported as cardinality.DefaultOverflow.
+	// Defaults to cardinality.DefaultLimit, less than zero disables it
+	EndpointLimit int
+	// AllowEndpoints are the patterns of the endpoint labels reported,
+	// others are reported as cardinality.DefaultOverflow
+	AllowEndpoints []string
 }
 
 type Option func(*Options)
@@ -45,6 +55,23 @@ func ServiceID(id string) Option {
 	}
 }
 
+// EndpointLimit bounds the distinct endpoint labels of the wrapper, so the
+// endpoints of a proxy or of topics named after tenants can't create an
+// unbounded number of series.
+func EndpointLimit(n int) Option {
+	return func(opts *Options) {
+		opts.EndpointLimit = n
+	}
+}
+
+// AllowEndpoints only reports the endpoints matching one of the path.Match
+// patterns, e.g. "Greeter.*".
+func AllowEndpoints(patterns ...string) Option {
+	return func(opts *Options) {
+		opts.AllowEndpoints = append(opts.AllowEndpoints, patterns...)
+	}
+}
+
 func getName(name string, labels []string) string {
 	if len(labels) > 0 {
 		return fmt.Sprintf(`%s%s{%s}`, DefaultMetricPrefix, name, strings.Join(labels, ","))
@@ -52,13 +79,7 @@ func getName(name string, labels []string) string {
 	return fmt.Sprintf(`%s%s`, DefaultMetricPrefix, name)
 }
 
-func getLabels(opts ...Option) []string {
-	options := Options{}
-
-	for _, opt := range opts {
-		opt(&options)
-	}
-
+func getLabels(options Options) []string {
 	labels := make([]string, 0, 3)
 	labels = append(labels, fmt.Sprintf(`%sname="%s"`, DefaultLabelPrefix, options.Name))
 	labels = append(labels, fmt.Sprintf(`%sversion="%s"`, DefaultLabelPrefix, options.Version))
@@ -71,37 +92,67 @@ type wrapper struct {
 	options  Options
 	callFunc client.CallFunc
 	client.Client
-	labels []string
+	labels    []string
+	endpoints *cardinality.Limiter
+}
+
+func newWrapper(opts ...Option) *wrapper {
+	options := Options{}
+
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	limit := options.EndpointLimit
+	if limit == 0 {
+		limit = cardinality.DefaultLimit
+	}
+
+	labels := getLabels(options)
+	overflow := getName("label_overflow_total", append(labels, fmt.Sprintf(`%slabel="endpoint"`, DefaultLabelPrefix)))
+	endpoints := cardinality.New(
+		cardinality.Limit(limit),
+		cardinality.Allow(options.AllowEndpoints...),
+		cardinality.OnFirstOverflow(func(v string) {
+			logger.Warnf("Endpoint %s reported as %s, the endpoint label reached its limit of %d values or isn't allowed", v, cardinality.DefaultOverflow, limit)
+		}),
+		cardinality.OnOverflow(func(string) {
+			metrics.GetOrCreateCounter(overflow).Inc()
+		}),
+	)
+
+	return &wrapper{
+		options:   options,
+		labels:    labels,
+		endpoints: endpoints,
+	}
 }
 
 func NewClientWrapper(opts ...Option) client.Wrapper {
-	labels := getLabels(opts...)
+	w := newWrapper(opts...)
 
 	return func(c client.Client) client.Client {
-		handler := &wrapper{
-			labels: labels,
-			Client: c,
-		}
+		handler := *w
+		handler.Client = c
 
-		return handler
+		return &handler
 	}
 }
 
 func NewCallWrapper(opts ...Option) client.CallWrapper {
-	labels := getLabels(opts...)
+	// the client wraps every call anew, the endpoint limit is shared by them
+	w := newWrapper(opts...)
 
 	return func(fn client.CallFunc) client.CallFunc {
-		handler := &wrapper{
-			labels:   labels,
-			callFunc: fn,
-		}
+		handler := *w
+		handler.callFunc = fn
 
 		return handler.CallFunc
 	}
 }
 
 func (w *wrapper) CallFunc(ctx context.Context, node *registry.Node, req client.Request, rsp interface{}, opts client.CallOptions) error {
-	endpoint := fmt.Sprintf("%s.%s", req.Service(), req.Endpoint())
+	endpoint := w.endpoints.Value(fmt.Sprintf("%s.%s", req.Service(), req.Endpoint()))
 	wlabels := append(w.labels, fmt.Sprintf(`%sendpoint="%s"`, DefaultLabelPrefix, endpoint))
 
 	timeCounterSummary := metrics.GetOrCreateSummary(getName("upstream_latency_seconds", wlabels))
@@ -123,7 +174,7 @@ func (w *wrapper) CallFunc(ctx context.Context, node *registry.Node, req client.
 }
 
 func (w *wrapper) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
-	endpoint := fmt.Sprintf("%s.%s", req.Service(), req.Endpoint())
+	endpoint := w.endpoints.Value(fmt.Sprintf("%s.%s", req.Service(), req.Endpoint()))
 	wlabels := append(w.labels, fmt.Sprintf(`%sendpoint="%s"`, DefaultLabelPrefix, endpoint))
 
 	timeCounterSummary := metrics.GetOrCreateSummary(getName("upstream_latency_seconds", wlabels))
@@ -145,7 +196,7 @@ func (w *wrapper) Call(ctx context.Context, req client.Request, rsp interface{},
 }
 
 func (w *wrapper) Stream(ctx context.Context, req client.Request, opts ...client.CallOption) (client.Stream, error) {
-	endpoint := fmt.Sprintf("%s.%s", req.Service(), req.Endpoint())
+	endpoint := w.endpoints.Value(fmt.Sprintf("%s.%s", req.Service(), req.Endpoint()))
 	wlabels := append(w.labels, fmt.Sprintf(`%sendpoint="%s"`, DefaultLabelPrefix, endpoint))
 
 	timeCounterSummary := metrics.GetOrCreateSummary(getName("upstream_latency_seconds", wlabels))
@@ -167,7 +218,7 @@ func (w *wrapper) Stream(ctx context.Context, req client.Request, opts ...client
 }
 
 func (w *wrapper) Publish(ctx context.Context, p client.Message, opts ...client.PublishOption) error {
-	endpoint := p.Topic()
+	endpoint := w.endpoints.Value(p.Topic())
 	wlabels := append(w.labels, fmt.Sprintf(`%sendpoint="%s"`, DefaultLabelPrefix, endpoint))
 
 	timeCounterSummary := metrics.GetOrCreateSummary(getName("upstream_latency_seconds", wlabels))
@@ -189,18 +240,14 @@ func (w *wrapper) Publish(ctx context.Context, p client.Message, opts ...client.
 }
 
 func NewHandlerWrapper(opts ...Option) server.HandlerWrapper {
-	labels := getLabels(opts...)
-
-	handler := &wrapper{
-		labels: labels,
-	}
+	handler := newWrapper(opts...)
 
 	return handler.HandlerFunc
 }
 
 func (w *wrapper) HandlerFunc(fn server.HandlerFunc) server.HandlerFunc {
 	return func(ctx context.Context, req server.Request, rsp interface{}) error {
-		endpoint := req.Endpoint()
+		endpoint := w.endpoints.Value(req.Endpoint())
 		wlabels := append(w.labels, fmt.Sprintf(`%sendpoint="%s"`, DefaultLabelPrefix, endpoint))
 
 		timeCounterSummary := metrics.GetOrCreateSummary(getName("upstream_latency_seconds", wlabels))
@@ -223,18 +270,14 @@ func (w *wrapper) HandlerFunc(fn server.HandlerFunc) server.HandlerFunc {
 }
 
 func NewSubscriberWrapper(opts ...Option) server.SubscriberWrapper {
-	labels := getLabels(opts...)
-
-	handler := &wrapper{
-		labels: labels,
-	}
+	handler := newWrapper(opts...)
 
 	return handler.SubscriberFunc
 }
 
 func (w *wrapper) SubscriberFunc(fn server.SubscriberFunc) server.SubscriberFunc {
 	return func(ctx context.Context, msg server.Message) error {
-		endpoint := msg.Topic()
+		endpoint := w.endpoints.Value(msg.Topic())
 		wlabels := append(w.labels, fmt.Sprintf(`%sendpoint="%s"`, DefaultLabelPrefix, endpoint))
 
 		timeCounterSummary := metrics.GetOrCreateSummary(getName("upstream_latency_seconds", wlabels))
@@ -144,3 +144,31 @@ func findMetricByName(buf io.Reader, tp string, name string) ([]map[string]inter
 	}
 	return metrics, nil
 }
+
+type testMessage struct {
+	server.Message
+	topic string
+}
+
+func (m *testMessage) Topic() string {
+	return m.topic
+}
+
+func TestEndpointLimit(t *testing.T) {
+	wrapper := NewSubscriberWrapper(ServiceName("tenants"), EndpointLimit(2))
+	handler := wrapper(func(ctx context.Context, msg server.Message) error {
+		return nil
+	})
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, handler(context.TODO(), &testMessage{topic: fmt.Sprintf("tenant-%d", i)}))
+	}
+
+	buf := bytes.NewBuffer(nil)
+	metrics.WritePrometheus(buf, false)
+	out := buf.String()
+
+	assert.Contains(t, out, `micro_request_total{micro_name="tenants",micro_version="",micro_id="",micro_endpoint="tenant-1",micro_status="success"} 1`)
+	assert.Contains(t, out, `micro_request_total{micro_name="tenants",micro_version="",micro_id="",micro_endpoint="overflow",micro_status="success"} 3`)
+	assert.NotContains(t, out, `micro_endpoint="tenant-2"`)
+	assert.Contains(t, out, `micro_label_overflow_total{micro_name="tenants",micro_version="",micro_id="",micro_label="endpoint"} 3`)
+}
@@ -0,0 +1,68 @@
+// Package baggage routes requests by labels carried in W3C Baggage, e.g.
+// canary=true or region=eu. The baggage is propagated in the Baggage header
+// of every request, so the whole call tree below a request follows the same
+// routing decision.
+package baggage
+
+import (
+	"context"
+
+	"go-micro.dev/v4/metadata"
+	otelbaggage "go.opentelemetry.io/otel/baggage"
+)
+
+// Header is the metadata key of the W3C Baggage header.
+const Header = "Baggage"
+
+// FromContext returns the baggage of the context. It merges the baggage of
+// the Baggage metadata with the OpenTelemetry baggage of the context, the
+// latter taking precedence.
+func FromContext(ctx context.Context) otelbaggage.Baggage {
+	b := otelbaggage.FromContext(ctx)
+
+	h, ok := metadata.Get(ctx, Header)
+	if !ok {
+		return b
+	}
+	md, err := otelbaggage.Parse(h)
+	if err != nil {
+		return b
+	}
+
+	for _, m := range b.Members() {
+		// members of Members can't be set as they are
+		m, err := otelbaggage.NewMember(m.Key(), m.Value(), m.Properties()...)
+		if err != nil {
+			continue
+		}
+		md, _ = md.SetMember(m)
+	}
+	return md
+}
+
+// NewContext returns a context carrying the baggage, both in the Baggage
+// metadata sent to downstream services and as OpenTelemetry baggage.
+func NewContext(ctx context.Context, b otelbaggage.Baggage) context.Context {
+	ctx = otelbaggage.ContextWithBaggage(ctx, b)
+	return metadata.Set(ctx, Header, b.String())
+}
+
+// Label returns the value of the baggage member of the context.
+func Label(ctx context.Context, key string) (string, bool) {
+	m := FromContext(ctx).Member(key)
+	return m.Value(), len(m.Key()) > 0
+}
+
+// WithLabel returns a context with the baggage member set, so the request and
+// all requests made while handling it are routed by it.
+func WithLabel(ctx context.Context, key, value string) (context.Context, error) {
+	m, err := otelbaggage.NewMember(key, value)
+	if err != nil {
+		return ctx, err
+	}
+	b, err := FromContext(ctx).SetMember(m)
+	if err != nil {
+		return ctx, err
+	}
+	return NewContext(ctx, b), nil
+}
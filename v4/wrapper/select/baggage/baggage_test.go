@@ -0,0 +1,140 @@
+package baggage
+
+import (
+	"context"
+	"testing"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/metadata"
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/server"
+	otelbaggage "go.opentelemetry.io/otel/baggage"
+)
+
+type Request struct{}
+
+type Response struct {
+	Region string
+	Canary string
+}
+
+type Test struct {
+	region string
+}
+
+func (t *Test) Ping(ctx context.Context, req *Request, rsp *Response) error {
+	rsp.Region = t.region
+	rsp.Canary, _ = Label(ctx, "canary")
+	return nil
+}
+
+func TestLabel(t *testing.T) {
+	ctx, err := WithLabel(context.Background(), "region", "eu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctx, err = WithLabel(ctx, "canary", "true"); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, ok := Label(ctx, "region"); !ok || v != "eu" {
+		t.Fatalf("Expected region eu, got %q", v)
+	}
+	if _, ok := Label(ctx, "zone"); ok {
+		t.Fatal("Expected no zone label")
+	}
+
+	h, ok := metadata.Get(ctx, Header)
+	if !ok {
+		t.Fatal("Expected the baggage header to be set")
+	}
+	b, err := otelbaggage.Parse(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Member("canary").Value() != "true" || b.Member("region").Value() != "eu" {
+		t.Fatalf("Unexpected baggage header %q", h)
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	ctx := metadata.Set(context.Background(), Header, "region=eu,canary=true")
+
+	m, _ := otelbaggage.NewMember("region", "us")
+	b, _ := otelbaggage.New(m)
+	ctx = otelbaggage.ContextWithBaggage(ctx, b)
+
+	b = FromContext(ctx)
+	if b.Member("region").Value() != "us" {
+		t.Fatalf("Expected the context baggage to take precedence, got %q", b.Member("region").Value())
+	}
+	if b.Member("canary").Value() != "true" {
+		t.Fatal("Expected the canary label of the header")
+	}
+}
+
+func TestFilter(t *testing.T) {
+	services := []*registry.Service{{
+		Name: "test",
+		Nodes: []*registry.Node{
+			{Id: "eu", Metadata: map[string]string{"region": "eu"}},
+			{Id: "eu-canary", Metadata: map[string]string{"region": "eu", "canary": "true"}},
+			{Id: "us", Metadata: map[string]string{"region": "us"}},
+		},
+	}}
+
+	got := filter(map[string]string{"region": "eu", "canary": "true"}, false)(services)
+	if len(got) != 1 || len(got[0].Nodes) != 1 || got[0].Nodes[0].Id != "eu-canary" {
+		t.Fatalf("Expected only the eu canary, got %+v", got)
+	}
+	if len(services[0].Nodes) != 3 {
+		t.Fatal("Expected the services to be left as they are")
+	}
+
+	if got := filter(map[string]string{"region": "ap"}, false)(services); len(got[0].Nodes) != 3 {
+		t.Fatal("Expected all nodes without a match")
+	}
+	if got := filter(map[string]string{"region": "ap"}, true)(services); len(got) != 0 {
+		t.Fatal("Expected no nodes without a match when strict")
+	}
+}
+
+func TestRoute(t *testing.T) {
+	r := registry.NewMemoryRegistry()
+
+	for _, region := range []string{"eu", "us"} {
+		s := server.NewServer(
+			server.Name("test"),
+			server.Id(region),
+			server.Address("127.0.0.1:0"),
+			server.Registry(r),
+			server.Metadata(map[string]string{"region": region}),
+			server.WrapHandler(NewHandlerWrapper()),
+		)
+		if err := s.Handle(s.NewHandler(&Test{region: region})); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Start(); err != nil {
+			t.Fatal(err)
+		}
+		defer s.Stop()
+	}
+
+	c := NewClientWrapper(Labels("region"))(client.NewClient(client.Registry(r)))
+
+	ctx, _ := WithLabel(context.Background(), "region", "us")
+	ctx, _ = WithLabel(ctx, "canary", "true")
+
+	for i := 0; i < 10; i++ {
+		rsp := new(Response)
+		if err := c.Call(ctx, c.NewRequest("test", "Test.Ping", &Request{}), rsp); err != nil {
+			t.Fatal(err)
+		}
+		if rsp.Region != "us" {
+			t.Fatalf("Expected the us node, got %q", rsp.Region)
+		}
+		if rsp.Canary != "true" {
+			t.Fatalf("Expected the canary label to be propagated, got %q", rsp.Canary)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package baggage
+
+// Options configure the routing by baggage labels.
+type Options struct {
+	// Labels are the baggage members used for routing. A label selects the
+	// nodes whose metadata has the same value for the key, e.g. the label
+	// region=eu selects nodes registered with the metadata region=eu.
+	Labels []string
+	// Strict fails calls with selector.ErrNoneAvailable if no node matches
+	// the labels, instead of falling back to all nodes.
+	Strict bool
+}
+
+// Option sets an option.
+type Option func(*Options)
+
+// Labels sets the baggage members used for routing.
+func Labels(keys ...string) Option {
+	return func(o *Options) {
+		o.Labels = append(o.Labels, keys...)
+	}
+}
+
+// Strict fails calls if no node matches the labels.
+func Strict() Option {
+	return func(o *Options) {
+		o.Strict = true
+	}
+}
+
+func newOptions(opts ...Option) Options {
+	var options Options
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return options
+}
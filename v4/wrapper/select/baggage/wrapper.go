@@ -0,0 +1,138 @@
+package baggage
+
+import (
+	"context"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/selector"
+	"go-micro.dev/v4/server"
+)
+
+// NewClientWrapper returns a client wrapper which propagates the baggage of
+// the context and selects the nodes matching its routing labels.
+func NewClientWrapper(opts ...Option) client.Wrapper {
+	options := newOptions(opts...)
+
+	return func(c client.Client) client.Client {
+		return &clientWrapper{
+			Client: c,
+			opts:   options,
+		}
+	}
+}
+
+type clientWrapper struct {
+	client.Client
+	opts Options
+}
+
+func (w *clientWrapper) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	ctx, opts = w.route(ctx, opts)
+	return w.Client.Call(ctx, req, rsp, opts...)
+}
+
+func (w *clientWrapper) Stream(ctx context.Context, req client.Request, opts ...client.CallOption) (client.Stream, error) {
+	ctx, opts = w.route(ctx, opts)
+	return w.Client.Stream(ctx, req, opts...)
+}
+
+func (w *clientWrapper) Publish(ctx context.Context, p client.Message, opts ...client.PublishOption) error {
+	ctx = propagate(ctx)
+	return w.Client.Publish(ctx, p, opts...)
+}
+
+// route propagates the baggage and adds the filter of its labels.
+func (w *clientWrapper) route(ctx context.Context, opts []client.CallOption) (context.Context, []client.CallOption) {
+	ctx = propagate(ctx)
+
+	labels := w.labels(ctx)
+	if len(labels) == 0 {
+		return ctx, opts
+	}
+
+	return ctx, append(opts, client.WithSelectOption(selector.WithFilter(filter(labels, w.opts.Strict))))
+}
+
+// labels returns the routing labels of the context.
+func (w *clientWrapper) labels(ctx context.Context) map[string]string {
+	b := FromContext(ctx)
+
+	labels := make(map[string]string)
+	for _, k := range w.opts.Labels {
+		if m := b.Member(k); len(m.Key()) > 0 {
+			labels[k] = m.Value()
+		}
+	}
+	return labels
+}
+
+// propagate sets the Baggage metadata, if the baggage was only set as
+// OpenTelemetry baggage.
+func propagate(ctx context.Context) context.Context {
+	b := FromContext(ctx)
+	if b.Len() == 0 {
+		return ctx
+	}
+	return NewContext(ctx, b)
+}
+
+// filter returns the services with only the nodes matching all labels.
+// Without a matching node it returns the services as they are, unless
+// strict.
+func filter(labels map[string]string, strict bool) selector.Filter {
+	return func(old []*registry.Service) []*registry.Service {
+		var services []*registry.Service
+
+		for _, service := range old {
+			var nodes []*registry.Node
+			for _, node := range service.Nodes {
+				if matches(node, labels) {
+					nodes = append(nodes, node)
+				}
+			}
+			if len(nodes) == 0 {
+				continue
+			}
+
+			s := new(registry.Service)
+			*s = *service
+			s.Nodes = nodes
+			services = append(services, s)
+		}
+
+		if len(services) == 0 && !strict {
+			return old
+		}
+		return services
+	}
+}
+
+func matches(node *registry.Node, labels map[string]string) bool {
+	for k, v := range labels {
+		if node.Metadata[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// NewHandlerWrapper returns a handler wrapper which makes the baggage of
+// requests available as OpenTelemetry baggage.
+func NewHandlerWrapper() server.HandlerWrapper {
+	return func(h server.HandlerFunc) server.HandlerFunc {
+		return func(ctx context.Context, req server.Request, rsp interface{}) error {
+			return h(propagate(ctx), req, rsp)
+		}
+	}
+}
+
+// NewSubscriberWrapper returns a subscriber wrapper which makes the baggage
+// of messages available as OpenTelemetry baggage.
+func NewSubscriberWrapper() server.SubscriberWrapper {
+	return func(next server.SubscriberFunc) server.SubscriberFunc {
+		return func(ctx context.Context, msg server.Message) error {
+			return next(propagate(ctx), msg)
+		}
+	}
+}
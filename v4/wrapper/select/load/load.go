@@ -0,0 +1,259 @@
+// Package load balances requests by the load servers report on their
+// responses, similar to ORCA load reports in gRPC. Servers report their
+// utilization and queue depth in response headers, and the client picks
+// nodes at random weighted by their spare capacity.
+package load
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/codec"
+	"go-micro.dev/v4/metadata"
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/selector"
+	"go-micro.dev/v4/server"
+
+	"github.com/go-micro/plugins/v4/util/weighted"
+)
+
+const (
+	// HeaderUtilization is the utilization of the server between 0 and 1.
+	HeaderUtilization = "Micro-Load-Utilization"
+	// HeaderQueueDepth is the number of requests the server is handling.
+	HeaderQueueDepth = "Micro-Load-Queue-Depth"
+
+	// headerNode passes the selected node from the call wrapper to the codec,
+	// it's never sent
+	headerNode = "Micro-Load-Node"
+
+	// minWeight keeps fully utilized nodes selectable, so their recovery is
+	// noticed
+	minWeight = 0.01
+)
+
+// Report is the load of a server.
+type Report struct {
+	Utilization float64
+	QueueDepth  int
+}
+
+func (r Report) weight() float64 {
+	w := (1 - r.Utilization) / float64(1+r.QueueDepth)
+	if w < minWeight {
+		return minWeight
+	}
+	return w
+}
+
+// Server returns a server option reporting the load of the server on every
+// response.
+func Server(opts ...Option) server.Option {
+	t := &tracker{opts: newOptions(opts...)}
+
+	return func(o *server.Options) {
+		o.HdlrWrappers = append(o.HdlrWrappers, t.wrap)
+
+		if o.Codecs == nil {
+			o.Codecs = make(map[string]codec.NewCodec)
+		}
+		for ct, c := range server.DefaultCodecs {
+			if wc, ok := o.Codecs[ct]; ok {
+				c = wc
+			}
+			o.Codecs[ct] = t.newCodec(c)
+		}
+	}
+}
+
+// tracker tracks the requests in flight.
+type tracker struct {
+	opts     Options
+	inflight int64
+}
+
+func (t *tracker) wrap(fn server.HandlerFunc) server.HandlerFunc {
+	return func(ctx context.Context, req server.Request, rsp interface{}) error {
+		atomic.AddInt64(&t.inflight, 1)
+		defer atomic.AddInt64(&t.inflight, -1)
+		return fn(ctx, req, rsp)
+	}
+}
+
+func (t *tracker) report() Report {
+	r := Report{QueueDepth: int(atomic.LoadInt64(&t.inflight))}
+
+	if t.opts.Utilization != nil {
+		r.Utilization = t.opts.Utilization()
+	} else if t.opts.Capacity > 0 {
+		r.Utilization = float64(r.QueueDepth) / float64(t.opts.Capacity)
+	}
+
+	if r.Utilization > 1 {
+		r.Utilization = 1
+	} else if r.Utilization < 0 {
+		r.Utilization = 0
+	}
+
+	return r
+}
+
+func (t *tracker) newCodec(c codec.NewCodec) codec.NewCodec {
+	return func(conn io.ReadWriteCloser) codec.Codec {
+		return &serverCodec{Codec: c(conn), t: t}
+	}
+}
+
+type serverCodec struct {
+	codec.Codec
+	t *tracker
+}
+
+func (s *serverCodec) Write(m *codec.Message, b interface{}) error {
+	if m.Header == nil {
+		m.Header = make(map[string]string)
+	}
+
+	r := s.t.report()
+	m.Header[HeaderUtilization] = strconv.FormatFloat(r.Utilization, 'f', 3, 64)
+	m.Header[HeaderQueueDepth] = strconv.Itoa(r.QueueDepth)
+
+	return s.Codec.Write(m, b)
+}
+
+func (s *serverCodec) String() string {
+	return s.Codec.String()
+}
+
+// Client returns a client option selecting nodes weighted by their last
+// reported load. Nodes without a recent report get the full weight.
+func Client(opts ...Option) client.Option {
+	return newBalancer(opts...).init
+}
+
+type report struct {
+	Report
+	updated time.Time
+}
+
+// balancer keeps the last report of every node.
+type balancer struct {
+	opts Options
+
+	sync.RWMutex
+	reports map[string]report
+}
+
+// load returns the last report of the node, if it's recent.
+func (b *balancer) load(node string) (Report, bool) {
+	b.RLock()
+	r, ok := b.reports[node]
+	b.RUnlock()
+
+	if !ok || time.Since(r.updated) > b.opts.Expiry {
+		return Report{}, false
+	}
+	return r.Report, true
+}
+
+func newBalancer(opts ...Option) *balancer {
+	return &balancer{
+		opts:    newOptions(opts...),
+		reports: make(map[string]report),
+	}
+}
+
+func (b *balancer) init(o *client.Options) {
+	o.CallOptions.CallWrappers = append(o.CallOptions.CallWrappers, b.wrap)
+	o.CallOptions.SelectOptions = append(o.CallOptions.SelectOptions, selector.WithStrategy(b.strategy))
+
+	if o.Codecs == nil {
+		o.Codecs = make(map[string]codec.NewCodec)
+	}
+	for ct, c := range client.DefaultCodecs {
+		if wc, ok := o.Codecs[ct]; ok {
+			c = wc
+		}
+		o.Codecs[ct] = b.newCodec(c)
+	}
+}
+
+func (b *balancer) set(node string, r Report) {
+	b.Lock()
+	b.reports[node] = report{Report: r, updated: time.Now()}
+	b.Unlock()
+}
+
+func (b *balancer) weight(node string) float64 {
+	r, ok := b.load(node)
+	if !ok {
+		return 1
+	}
+	return r.weight()
+}
+
+func (b *balancer) strategy(services []*registry.Service) selector.Next {
+	var nodes []*registry.Node
+	for _, service := range services {
+		nodes = append(nodes, service.Nodes...)
+	}
+
+	return weighted.Next(nodes, func(n *registry.Node) float64 {
+		return b.weight(n.Id)
+	})
+}
+
+// wrap tells the codec of the call which node it's reading the report of.
+func (b *balancer) wrap(next client.CallFunc) client.CallFunc {
+	return func(ctx context.Context, node *registry.Node, req client.Request, rsp interface{}, opts client.CallOptions) error {
+		return next(metadata.Set(ctx, headerNode, node.Id), node, req, rsp, opts)
+	}
+}
+
+func (b *balancer) newCodec(c codec.NewCodec) codec.NewCodec {
+	return func(conn io.ReadWriteCloser) codec.Codec {
+		return &clientCodec{Codec: c(conn), b: b}
+	}
+}
+
+type clientCodec struct {
+	codec.Codec
+	b    *balancer
+	node string
+}
+
+func (c *clientCodec) Write(m *codec.Message, b interface{}) error {
+	if node, ok := m.Header[headerNode]; ok {
+		c.node = node
+		delete(m.Header, headerNode)
+	}
+	return c.Codec.Write(m, b)
+}
+
+func (c *clientCodec) ReadHeader(m *codec.Message, t codec.MessageType) error {
+	if err := c.Codec.ReadHeader(m, t); err != nil {
+		return err
+	}
+
+	if t != codec.Response || len(c.node) == 0 {
+		return nil
+	}
+
+	u, err := strconv.ParseFloat(m.Header[HeaderUtilization], 64)
+	if err != nil {
+		return nil
+	}
+	q, _ := strconv.Atoi(m.Header[HeaderQueueDepth])
+
+	c.b.set(c.node, Report{Utilization: u, QueueDepth: q})
+	return nil
+}
+
+func (c *clientCodec) String() string {
+	return c.Codec.String()
+}
@@ -0,0 +1,98 @@
+package load
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/server"
+)
+
+type Request struct{}
+
+type Response struct{}
+
+type Test struct{}
+
+func (t *Test) Ping(ctx context.Context, req *Request, rsp *Response) error {
+	return nil
+}
+
+func TestReport(t *testing.T) {
+	r := registry.NewMemoryRegistry()
+
+	s := server.NewServer(
+		server.Name("test"),
+		server.Address("127.0.0.1:0"),
+		server.Registry(r),
+		Server(Utilization(func() float64 { return 0.75 })),
+	)
+	if err := s.Handle(s.NewHandler(&Test{})); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	b := newBalancer()
+	c := client.NewClient(client.Registry(r), b.init)
+
+	req := c.NewRequest("test", "Test.Ping", &Request{})
+	if err := c.Call(context.Background(), req, new(Response)); err != nil {
+		t.Fatal(err)
+	}
+
+	services, err := r.GetService("test")
+	if err != nil || len(services) == 0 {
+		t.Fatal("Expected the test service to be registered")
+	}
+
+	rep, ok := b.load(services[0].Nodes[0].Id)
+	if !ok {
+		t.Fatal("Expected a report of the node")
+	}
+	if rep.Utilization != 0.75 || rep.QueueDepth != 0 {
+		t.Fatalf("Expected utilization 0.75 and no queue, got %+v", rep)
+	}
+}
+
+func TestStrategy(t *testing.T) {
+	b := newBalancer(Expiry(time.Minute))
+	b.set("busy", Report{Utilization: 0.9, QueueDepth: 4})
+	b.set("idle", Report{Utilization: 0.1})
+
+	next := b.strategy([]*registry.Service{{
+		Name:  "test",
+		Nodes: []*registry.Node{{Id: "busy"}, {Id: "idle"}},
+	}})
+
+	picks := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		n, err := next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		picks[n.Id]++
+	}
+
+	if picks["idle"] < 900 {
+		t.Fatalf("Expected the idle node to receive most requests, got %v", picks)
+	}
+	if picks["busy"] == 0 {
+		t.Fatalf("Expected the busy node to remain selectable, got %v", picks)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	b := newBalancer(Expiry(time.Millisecond))
+	b.set("node", Report{Utilization: 1})
+
+	time.Sleep(5 * time.Millisecond)
+
+	if w := b.weight("node"); w != 1 {
+		t.Fatalf("Expected an expired report to be ignored, got weight %v", w)
+	}
+}
@@ -0,0 +1,55 @@
+package load
+
+import (
+	"time"
+)
+
+// Options configure the load reports.
+type Options struct {
+	// Capacity is the number of concurrent requests at which a server
+	// reports full utilization. Defaults to 100.
+	Capacity int
+	// Utilization reports the utilization of the server between 0 and 1,
+	// e.g. its cpu usage. Defaults to the concurrent requests by capacity.
+	Utilization func() float64
+	// Expiry is the age after which the client ignores a report. Defaults
+	// to 30 seconds.
+	Expiry time.Duration
+}
+
+// Option sets an option.
+type Option func(*Options)
+
+// Capacity sets the number of concurrent requests of full utilization.
+func Capacity(n int) Option {
+	return func(o *Options) {
+		o.Capacity = n
+	}
+}
+
+// Utilization sets the func reporting the utilization of the server.
+func Utilization(fn func() float64) Option {
+	return func(o *Options) {
+		o.Utilization = fn
+	}
+}
+
+// Expiry sets the age after which reports are ignored.
+func Expiry(d time.Duration) Option {
+	return func(o *Options) {
+		o.Expiry = d
+	}
+}
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		Capacity: 100,
+		Expiry:   30 * time.Second,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return options
+}
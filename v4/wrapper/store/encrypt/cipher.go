@@ -0,0 +1,129 @@
+package encrypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"io"
+
+	"filippo.io/age"
+)
+
+// ErrDecrypt is returned for values which don't decrypt with their key, e.g.
+// as they were tampered with or moved to another key.
+var ErrDecrypt = errors.New("failed to decrypt value")
+
+// Cipher encrypts and decrypts the values of the records. The additional
+// data is authenticated but not encrypted, the wrapper passes the key of the
+// record, so values can't be swapped between records.
+type Cipher interface {
+	// ID identifies the key of the cipher. It's stored with every value, so
+	// values are decrypted with the key they were encrypted with after a
+	// rotation.
+	ID() string
+	Encrypt(plaintext, additional []byte) ([]byte, error)
+	Decrypt(ciphertext, additional []byte) ([]byte, error)
+}
+
+type aesGCM struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// AESGCM returns a cipher encrypting with AES-GCM. The key must be 16, 24 or
+// 32 bytes, to select AES-128, AES-192 or AES-256.
+func AESGCM(id string, key []byte) (Cipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &aesGCM{id: id, aead: aead}, nil
+}
+
+func (a *aesGCM) ID() string {
+	return a.id
+}
+
+func (a *aesGCM) Encrypt(plaintext, additional []byte) ([]byte, error) {
+	nonce := make([]byte, a.aead.NonceSize(), a.aead.NonceSize()+len(plaintext)+a.aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return a.aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+func (a *aesGCM) Decrypt(ciphertext, additional []byte) ([]byte, error) {
+	n := a.aead.NonceSize()
+	if len(ciphertext) < n {
+		return nil, ErrDecrypt
+	}
+	plaintext, err := a.aead.Open(nil, ciphertext[:n], ciphertext[n:], additional)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+type ageCipher struct {
+	id       string
+	identity *age.X25519Identity
+}
+
+// Age returns a cipher encrypting with age to the recipient of the identity.
+// Age has no additional data, a hash of it is encrypted with the value and
+// checked on decryption instead.
+func Age(id string, identity *age.X25519Identity) Cipher {
+	return &ageCipher{id: id, identity: identity}
+}
+
+func (a *ageCipher) ID() string {
+	return a.id
+}
+
+func (a *ageCipher) Encrypt(plaintext, additional []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, a.identity.Recipient())
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(additional)
+	if _, err := w.Write(sum[:]); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (a *ageCipher) Decrypt(ciphertext, additional []byte) ([]byte, error) {
+	r, err := age.Decrypt(bytes.NewReader(ciphertext), a.identity)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	plaintext, err := io.ReadAll(r)
+	if err != nil || len(plaintext) < sha256.Size {
+		return nil, ErrDecrypt
+	}
+
+	sum := sha256.Sum256(additional)
+	if subtle.ConstantTimeCompare(sum[:], plaintext[:sha256.Size]) != 1 {
+		return nil, ErrDecrypt
+	}
+
+	return plaintext[sha256.Size:], nil
+}
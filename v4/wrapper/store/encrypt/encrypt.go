@@ -0,0 +1,242 @@
+// Package encrypt provides a store wrapper which encrypts the values of the
+// records at rest, and optionally hashes their keys, around any store.
+package encrypt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+
+	"go-micro.dev/v4/logger"
+	"go-micro.dev/v4/store"
+)
+
+// version of the envelope of the values
+const version = 1
+
+var (
+	// ErrHashedKeys is returned for prefix and suffix reads and lists of a
+	// store whose keys are hashed.
+	ErrHashedKeys = errors.New("prefix and suffix queries aren't possible with hashed keys")
+	// ErrUnknownKey is returned for values encrypted with a key none of the
+	// ciphers has.
+	ErrUnknownKey = errors.New("value encrypted with unknown key")
+
+	errNotEncrypted = errors.New("value not encrypted")
+)
+
+type encryptStore struct {
+	store.Store
+	opts Options
+}
+
+// NewStore wraps the store so the values of the records are encrypted with
+// the first of the ciphers. Every value is stored with the ID of its key, so
+// it's decrypted with the cipher of that ID.
+func NewStore(s store.Store, opts ...Option) store.Store {
+	var options Options
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if len(options.Ciphers) == 0 {
+		logger.Fatal("[encrypt] no cipher set")
+	}
+
+	return &encryptStore{Store: s, opts: options}
+}
+
+func (e *encryptStore) hashed() bool {
+	return len(e.opts.KeySecret) > 0
+}
+
+// key returns the key the record is stored under.
+func (e *encryptStore) key(k string) string {
+	if !e.hashed() {
+		return k
+	}
+	mac := hmac.New(sha256.New, e.opts.KeySecret)
+	mac.Write([]byte(k))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// seal returns the envelope of the value. The ciphertext is bound to the key
+// it's stored under. With hashed keys the key is encrypted in front of the
+// value.
+func (e *encryptStore) seal(stored, key string, value []byte) ([]byte, error) {
+	plaintext := value
+	if e.hashed() {
+		plaintext = make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(key)+len(value))
+		n := binary.PutUvarint(plaintext, uint64(len(key)))
+		plaintext = append(append(plaintext[:n], key...), value...)
+	}
+
+	return sealEnvelope(e.opts.Ciphers[0], plaintext, []byte(stored))
+}
+
+// open decrypts the envelope of the record stored under the key, returning
+// the key of the record and the value.
+func (e *encryptStore) open(stored string, envelope []byte) (string, []byte, error) {
+	plaintext, err := openEnvelope(e.opts.Ciphers, envelope, []byte(stored))
+	if err != nil {
+		return "", nil, err
+	}
+	if !e.hashed() {
+		return stored, plaintext, nil
+	}
+
+	n, l := binary.Uvarint(plaintext)
+	if l <= 0 || uint64(len(plaintext)-l) < n {
+		return "", nil, ErrDecrypt
+	}
+	return string(plaintext[l : l+int(n)]), plaintext[l+int(n):], nil
+}
+
+// sealEnvelope returns the envelope of the plaintext: the version, the
+// uvarint length of the key ID, the key ID and the ciphertext.
+func sealEnvelope(c Cipher, plaintext, additional []byte) ([]byte, error) {
+	ciphertext, err := c.Encrypt(plaintext, additional)
+	if err != nil {
+		return nil, err
+	}
+
+	id := c.ID()
+	envelope := make([]byte, 1+binary.MaxVarintLen64, 1+binary.MaxVarintLen64+len(id)+len(ciphertext))
+	envelope[0] = version
+	n := binary.PutUvarint(envelope[1:], uint64(len(id)))
+	envelope = append(append(envelope[:1+n], id...), ciphertext...)
+	return envelope, nil
+}
+
+// keyID returns the ID of the key of the envelope and the ciphertext.
+func keyID(envelope []byte) (string, []byte, error) {
+	if len(envelope) < 2 || envelope[0] != version {
+		return "", nil, errNotEncrypted
+	}
+	n, l := binary.Uvarint(envelope[1:])
+	if l <= 0 || uint64(len(envelope)-1-l) < n {
+		return "", nil, errNotEncrypted
+	}
+	start := 1 + l
+	return string(envelope[start : start+int(n)]), envelope[start+int(n):], nil
+}
+
+// openEnvelope decrypts the envelope with the cipher of its key ID.
+func openEnvelope(ciphers []Cipher, envelope, additional []byte) ([]byte, error) {
+	id, ciphertext, err := keyID(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range ciphers {
+		if c.ID() == id {
+			return c.Decrypt(ciphertext, additional)
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+func (e *encryptStore) record(r *store.Record) (*store.Record, error) {
+	key, value, err := e.open(r.Key, r.Value)
+	if err == errNotEncrypted && e.opts.Plaintext {
+		return r, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &store.Record{
+		Key:      key,
+		Value:    value,
+		Metadata: r.Metadata,
+		Expiry:   r.Expiry,
+	}, nil
+}
+
+func (e *encryptStore) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	var options store.ReadOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if e.hashed() && (options.Prefix || options.Suffix) {
+		return nil, ErrHashedKeys
+	}
+
+	records, err := e.Store.Read(e.key(key), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted := make([]*store.Record, 0, len(records))
+	for _, r := range records {
+		d, err := e.record(r)
+		if err != nil {
+			return nil, err
+		}
+		decrypted = append(decrypted, d)
+	}
+
+	return decrypted, nil
+}
+
+func (e *encryptStore) Write(r *store.Record, opts ...store.WriteOption) error {
+	key := e.key(r.Key)
+
+	value, err := e.seal(key, r.Key, r.Value)
+	if err != nil {
+		return err
+	}
+
+	return e.Store.Write(&store.Record{
+		Key:      key,
+		Value:    value,
+		Metadata: r.Metadata,
+		Expiry:   r.Expiry,
+	}, opts...)
+}
+
+func (e *encryptStore) Delete(key string, opts ...store.DeleteOption) error {
+	return e.Store.Delete(e.key(key), opts...)
+}
+
+// List returns the keys of the records. With hashed keys every record is
+// read to decrypt its key.
+func (e *encryptStore) List(opts ...store.ListOption) ([]string, error) {
+	if !e.hashed() {
+		return e.Store.List(opts...)
+	}
+
+	var options store.ListOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if len(options.Prefix) > 0 || len(options.Suffix) > 0 {
+		return nil, ErrHashedKeys
+	}
+
+	hashes, err := e.Store.List(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(hashes))
+	for _, h := range hashes {
+		records, err := e.Store.Read(h, store.ReadFrom(options.Database, options.Table))
+		if err == store.ErrNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		r, err := e.record(records[0])
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, r.Key)
+	}
+
+	return keys, nil
+}
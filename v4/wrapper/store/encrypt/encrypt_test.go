@@ -0,0 +1,159 @@
+package encrypt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"go-micro.dev/v4/store"
+)
+
+func newAES(t *testing.T, id string) Cipher {
+	c, err := AESGCM(id, bytes.Repeat([]byte(id[:1]), 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestEncrypt(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []Cipher{newAES(t, "aes"), Age("age", identity)} {
+		mem := store.NewMemoryStore()
+		s := NewStore(mem, Ciphers(c))
+
+		if err := s.Write(&store.Record{Key: "user/1", Value: []byte("secret")}); err != nil {
+			t.Fatal(err)
+		}
+
+		raw, err := mem.Read("user/1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(raw[0].Value, []byte("secret")) {
+			t.Errorf("%s: expected the value to be encrypted", c.ID())
+		}
+
+		// the memory store needs a limit for prefix reads
+		r, err := s.Read("user/", store.ReadPrefix(), store.ReadLimit(10))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(r) != 1 || string(r[0].Value) != "secret" {
+			t.Errorf("%s: expected secret, got %v", c.ID(), r)
+		}
+
+		// values are bound to their key
+		mem.Write(&store.Record{Key: "user/2", Value: raw[0].Value})
+		if _, err := s.Read("user/2"); err != ErrDecrypt {
+			t.Errorf("%s: expected %v, got %v", c.ID(), ErrDecrypt, err)
+		}
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	// key IDs of any length, e.g. KMS key ARNs
+	for _, n := range []int{1, 127, 128, 300} {
+		id := strings.Repeat("k", n)
+		other := newAES(t, id+"x")
+		c := newAES(t, id)
+
+		envelope, err := sealEnvelope(c, []byte("secret"), []byte("key"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ciphertext, err := keyID(envelope)
+		if err != nil || got != id {
+			t.Fatalf("expected the key ID of %d bytes, got %d bytes: %v", n, len(got), err)
+		}
+
+		plaintext, err := openEnvelope([]Cipher{other, c}, envelope, []byte("key"))
+		if err != nil || string(plaintext) != "secret" {
+			t.Fatalf("expected secret, got %q: %v", plaintext, err)
+		}
+
+		if _, _, err := keyID(envelope[:len(envelope)-len(ciphertext)-1]); err != errNotEncrypted {
+			t.Fatalf("expected %v for a truncated key ID, got %v", errNotEncrypted, err)
+		}
+	}
+}
+
+func TestRotate(t *testing.T) {
+	mem := store.NewMemoryStore()
+	mem.Write(&store.Record{Key: "plain", Value: []byte("plain")})
+
+	old := NewStore(mem, Ciphers(newAES(t, "k1")), AllowPlaintext())
+	old.Write(&store.Record{Key: "foo", Value: []byte("bar")})
+
+	s := NewStore(mem, Ciphers(newAES(t, "k2"), newAES(t, "k1")), AllowPlaintext())
+
+	if r, err := s.Read("foo"); err != nil || string(r[0].Value) != "bar" {
+		t.Fatalf("Expected the previous key to decrypt, got %v %v", r, err)
+	}
+
+	n, err := Rotate(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 records rotated, got %d", n)
+	}
+
+	// only the new key is needed now
+	s = NewStore(mem, Ciphers(newAES(t, "k2")))
+	for _, k := range []string{"foo", "plain"} {
+		if _, err := s.Read(k); err != nil {
+			t.Errorf("Expected %s to be re-encrypted, got %v", k, err)
+		}
+	}
+
+	if n, _ := Rotate(s); n != 0 {
+		t.Errorf("Expected nothing left to rotate, got %d", n)
+	}
+}
+
+func TestHashKeys(t *testing.T) {
+	mem := store.NewMemoryStore()
+	s := NewStore(mem, Ciphers(newAES(t, "k1")), HashKeys([]byte("keysecret")))
+
+	if err := s.Write(&store.Record{Key: "alice@example.com", Value: []byte("profile")}); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, _ := mem.List()
+	if len(raw) != 1 || raw[0] == "alice@example.com" {
+		t.Errorf("Expected the key to be hashed, got %v", raw)
+	}
+
+	r, err := s.Read("alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r[0].Key != "alice@example.com" || string(r[0].Value) != "profile" {
+		t.Errorf("Expected the record, got %s %s", r[0].Key, r[0].Value)
+	}
+
+	keys, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != "alice@example.com" {
+		t.Errorf("Expected the decrypted key, got %v", keys)
+	}
+
+	if _, err := s.Read("alice", store.ReadPrefix()); err != ErrHashedKeys {
+		t.Errorf("Expected %v, got %v", ErrHashedKeys, err)
+	}
+
+	if err := s.Delete("alice@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Read("alice@example.com"); err != store.ErrNotFound {
+		t.Errorf("Expected %v, got %v", store.ErrNotFound, err)
+	}
+}
@@ -0,0 +1,60 @@
+package encrypt
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// FromEnv returns an AES-GCM cipher with the base64 encoded key of the
+// environment variable, e.g. MICRO_ENCRYPTION_KEY.
+func FromEnv(id, name string) (Cipher, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok || len(v) == 0 {
+		return nil, errors.Errorf("%s not set", name)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decoding %s", name)
+	}
+	defer wipe(key)
+
+	return AESGCM(id, key)
+}
+
+// KMS decrypts data keys encrypted with a key management service, such as
+// AWS KMS, Google Cloud KMS or Vault transit. Only the encrypted data key is
+// stored with the service, it never sees the data.
+type KMS interface {
+	Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error)
+}
+
+// KMSFunc is a function decrypting data keys.
+type KMSFunc func(ctx context.Context, ciphertext []byte) ([]byte, error)
+
+// Decrypt calls the function.
+func (f KMSFunc) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	return f(ctx, ciphertext)
+}
+
+// KMSKey returns an AES-GCM cipher with the data key the KMS decrypts. The
+// data key is decrypted once, when the cipher is created.
+func KMSKey(ctx context.Context, id string, kms KMS, encrypted []byte) (Cipher, error) {
+	key, err := kms.Decrypt(ctx, encrypted)
+	if err != nil {
+		return nil, errors.Wrap(err, "decrypting data key")
+	}
+	defer wipe(key)
+
+	return AESGCM(id, key)
+}
+
+// wipe overwrites the key once the cipher expanded it.
+func wipe(key []byte) {
+	for i := range key {
+		key[i] = 0
+	}
+}
@@ -0,0 +1,78 @@
+package encrypt
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"testing"
+)
+
+func TestFromEnv(t *testing.T) {
+	key := bytes.Repeat([]byte("k"), 32)
+	os.Setenv("TEST_ENCRYPTION_KEY", base64.StdEncoding.EncodeToString(key))
+	defer os.Unsetenv("TEST_ENCRYPTION_KEY")
+
+	c, err := FromEnv("env", "TEST_ENCRYPTION_KEY")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := newAES(t, "k")
+	ciphertext, err := c.Encrypt([]byte("secret"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := expected.Decrypt(ciphertext, nil); err != nil {
+		t.Errorf("expected the key of the environment, got %v", err)
+	}
+
+	if _, err := FromEnv("env", "TEST_ENCRYPTION_KEY_UNSET"); err == nil {
+		t.Error("expected an error for an unset variable")
+	}
+}
+
+func TestKMSKey(t *testing.T) {
+	kms := KMSFunc(func(ctx context.Context, ciphertext []byte) ([]byte, error) {
+		if string(ciphertext) != "wrapped" {
+			t.Errorf("expected the encrypted data key, got %q", ciphertext)
+		}
+		return bytes.Repeat([]byte("k"), 32), nil
+	})
+
+	c, err := KMSKey(context.Background(), "kms", kms, []byte("wrapped"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ID() != "kms" {
+		t.Errorf("expected id kms, got %s", c.ID())
+	}
+}
+
+func TestSealer(t *testing.T) {
+	old, current := newAES(t, "old"), newAES(t, "new")
+
+	sealed, err := NewSealer("snapshot", old).Seal([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, []byte("secret")) {
+		t.Error("expected the data to be encrypted")
+	}
+
+	s := NewSealer("snapshot", current, old)
+	plaintext, err := s.Open(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plaintext) != "secret" {
+		t.Errorf("expected secret, got %s", plaintext)
+	}
+
+	if _, err := NewSealer("queue", current, old).Open(sealed); err != ErrDecrypt {
+		t.Errorf("expected ErrDecrypt for another label, got %v", err)
+	}
+	if _, err := s.Open([]byte(`{"plain":true}`)); err != ErrDecrypt {
+		t.Errorf("expected ErrDecrypt for plaintext, got %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package encrypt
+
+// Options configure the encryption of the records.
+type Options struct {
+	// Ciphers decrypt the records encrypted with their key, the first one
+	// encrypts the records written
+	Ciphers []Cipher
+	// KeySecret is the HMAC secret of the keys of the records. Keys aren't
+	// hashed if it's empty
+	KeySecret []byte
+	// Plaintext returns the records which aren't encrypted as they are,
+	// instead of failing. For records written before encryption was enabled
+	Plaintext bool
+}
+
+// Option sets an option.
+type Option func(*Options)
+
+// Ciphers sets the cipher encrypting the records, and the previous ones
+// still decrypting the records encrypted before a key rotation.
+func Ciphers(c Cipher, previous ...Cipher) Option {
+	return func(o *Options) {
+		o.Ciphers = append([]Cipher{c}, previous...)
+	}
+}
+
+// HashKeys stores the records under the HMAC-SHA256 of their key, so keys
+// don't reveal anything either. The key is encrypted with the value, reads
+// return it. Prefix and suffix reads and lists aren't possible, listing
+// reads every record.
+func HashKeys(secret []byte) Option {
+	return func(o *Options) {
+		o.KeySecret = secret
+	}
+}
+
+// AllowPlaintext returns records which aren't encrypted as they are, to
+// enable encryption on an existing store. Rotate encrypts them.
+func AllowPlaintext() Option {
+	return func(o *Options) {
+		o.Plaintext = true
+	}
+}
@@ -0,0 +1,64 @@
+package encrypt
+
+import (
+	"errors"
+
+	"go-micro.dev/v4/store"
+)
+
+// Rotate re-encrypts the records of the table which aren't encrypted with
+// the first cipher, after it was rotated in, so the previous keys can be
+// retired. Plaintext records are encrypted if they're allowed. Returns the
+// number of records rewritten.
+func Rotate(s store.Store, opts ...store.ListOption) (int, error) {
+	e, ok := s.(*encryptStore)
+	if !ok {
+		return 0, errors.New(s.String() + " is not an encrypted store")
+	}
+
+	var options store.ListOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	keys, err := e.Store.List(opts...)
+	if err != nil {
+		return 0, err
+	}
+
+	primary := e.opts.Ciphers[0].ID()
+	rotated := 0
+
+	for _, k := range keys {
+		records, err := e.Store.Read(k, store.ReadFrom(options.Database, options.Table))
+		if err == store.ErrNotFound {
+			continue
+		} else if err != nil {
+			return rotated, err
+		}
+
+		r := records[0]
+		if id, _, err := keyID(r.Value); err == nil && id == primary {
+			continue
+		}
+
+		d, err := e.record(r)
+		if err != nil {
+			return rotated, err
+		}
+
+		// the remaining expiry is kept
+		if err := e.Write(d, store.WriteTo(options.Database, options.Table)); err != nil {
+			return rotated, err
+		}
+		// plaintext records move to their hashed key
+		if e.key(d.Key) != k {
+			if err := e.Store.Delete(k, store.DeleteFrom(options.Database, options.Table)); err != nil {
+				return rotated, err
+			}
+		}
+		rotated++
+	}
+
+	return rotated, nil
+}
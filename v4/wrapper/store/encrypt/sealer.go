@@ -0,0 +1,33 @@
+package encrypt
+
+// Sealer encrypts files and other artifacts persisted locally, such as
+// registry snapshots, with the envelope of the records, so they rotate the
+// same way.
+type Sealer struct {
+	ciphers []Cipher
+	label   []byte
+}
+
+// NewSealer returns a sealer encrypting with the first of the ciphers. The
+// label is authenticated with the data, so an artifact can't be passed off
+// as another one sealed with the same key.
+func NewSealer(label string, c Cipher, previous ...Cipher) *Sealer {
+	return &Sealer{
+		ciphers: append([]Cipher{c}, previous...),
+		label:   []byte(label),
+	}
+}
+
+// Seal encrypts the data.
+func (s *Sealer) Seal(plaintext []byte) ([]byte, error) {
+	return sealEnvelope(s.ciphers[0], plaintext, s.label)
+}
+
+// Open decrypts data sealed with any of the ciphers and the same label.
+func (s *Sealer) Open(envelope []byte) ([]byte, error) {
+	plaintext, err := openEnvelope(s.ciphers, envelope, s.label)
+	if err == errNotEncrypted {
+		return nil, ErrDecrypt
+	}
+	return plaintext, err
+}
@@ -2,9 +2,11 @@ package opentelemetry
 
 import (
 	"context"
+	"path"
 
 	"go-micro.dev/v4/client"
 	"go-micro.dev/v4/server"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -16,6 +18,16 @@ type Options struct {
 	PublishFilter    PublishFilter
 	SubscriberFilter SubscriberFilter
 	HandlerFilter    HandlerFilter
+
+	CallAttributes       CallAttributes
+	StreamAttributes     StreamAttributes
+	PublishAttributes    PublishAttributes
+	SubscriberAttributes SubscriberAttributes
+	HandlerAttributes    HandlerAttributes
+
+	// SpanNames rename spans whose default name matches their pattern, the
+	// first match wins.
+	SpanNames []SpanName
 }
 
 // CallFilter used to filter client.Call, return true to skip call trace.
@@ -33,6 +45,30 @@ type SubscriberFilter func(context.Context, server.Message) bool
 // HandlerFilter used to filter server.Handle, return true to skip handle trace.
 type HandlerFilter func(context.Context, server.Request) bool
 
+// CallAttributes returns extra attributes of the client.Call span.
+type CallAttributes func(context.Context, client.Request) []attribute.KeyValue
+
+// StreamAttributes returns extra attributes of the client.Stream span.
+type StreamAttributes func(context.Context, client.Request) []attribute.KeyValue
+
+// PublishAttributes returns extra attributes of the client.Publish span.
+type PublishAttributes func(context.Context, client.Message) []attribute.KeyValue
+
+// SubscriberAttributes returns extra attributes of the server.Subscribe span.
+type SubscriberAttributes func(context.Context, server.Message) []attribute.KeyValue
+
+// HandlerAttributes returns extra attributes of the server.Handle span.
+type HandlerAttributes func(context.Context, server.Request) []attribute.KeyValue
+
+// SpanName renames the spans whose default name matches the pattern, see
+// path.Match for the pattern syntax. Default names are "Service.Endpoint" for
+// calls, streams and handlers, "Pub to Topic" and "Sub from Topic" for
+// messages.
+type SpanName struct {
+	Pattern string
+	Name    string
+}
+
 type Option func(*Options)
 
 func WithTraceProvider(tp trace.TracerProvider) Option {
@@ -70,3 +106,60 @@ func WithHandleFilter(filter HandlerFilter) Option {
 		o.HandlerFilter = filter
 	}
 }
+
+// WithCallAttributes adds the attributes returned by fn to client.Call spans.
+// The request payload is available as req.Body().
+func WithCallAttributes(fn CallAttributes) Option {
+	return func(o *Options) {
+		o.CallAttributes = fn
+	}
+}
+
+// WithStreamAttributes adds the attributes returned by fn to client.Stream spans.
+func WithStreamAttributes(fn StreamAttributes) Option {
+	return func(o *Options) {
+		o.StreamAttributes = fn
+	}
+}
+
+// WithPublishAttributes adds the attributes returned by fn to client.Publish spans.
+// The message payload is available as msg.Payload().
+func WithPublishAttributes(fn PublishAttributes) Option {
+	return func(o *Options) {
+		o.PublishAttributes = fn
+	}
+}
+
+// WithSubscribeAttributes adds the attributes returned by fn to subscriber spans.
+// The decoded message is available as msg.Payload().
+func WithSubscribeAttributes(fn SubscriberAttributes) Option {
+	return func(o *Options) {
+		o.SubscriberAttributes = fn
+	}
+}
+
+// WithHandleAttributes adds the attributes returned by fn to handler spans.
+// The decoded request is available as req.Body().
+func WithHandleAttributes(fn HandlerAttributes) Option {
+	return func(o *Options) {
+		o.HandlerAttributes = fn
+	}
+}
+
+// WithSpanName renames the spans whose default name matches the pattern.
+// It can be set multiple times, patterns are tried in order.
+func WithSpanName(pattern, name string) Option {
+	return func(o *Options) {
+		o.SpanNames = append(o.SpanNames, SpanName{Pattern: pattern, Name: name})
+	}
+}
+
+// spanName returns the name of the span with the default name.
+func (o Options) spanName(name string) string {
+	for _, n := range o.SpanNames {
+		if ok, _ := path.Match(n.Pattern, name); ok {
+			return n.Name
+		}
+	}
+	return name
+}
@@ -22,10 +22,13 @@ func NewCallWrapper(opts ...Option) client.CallWrapper {
 			if options.CallFilter != nil && options.CallFilter(ctx, req) {
 				return cf(ctx, node, req, rsp, opts)
 			}
-			name := fmt.Sprintf("%s.%s", req.Service(), req.Endpoint())
+			name := options.spanName(fmt.Sprintf("%s.%s", req.Service(), req.Endpoint()))
 			spanOpts := []trace.SpanStartOption{
 				trace.WithSpanKind(trace.SpanKindClient),
 			}
+			if options.CallAttributes != nil {
+				spanOpts = append(spanOpts, trace.WithAttributes(options.CallAttributes(ctx, req)...))
+			}
 			ctx, span := StartSpanFromContext(ctx, options.TraceProvider, name, spanOpts...)
 			defer span.End()
 			if err := cf(ctx, node, req, rsp, opts); err != nil {
@@ -49,10 +52,13 @@ func NewHandlerWrapper(opts ...Option) server.HandlerWrapper {
 			if options.HandlerFilter != nil && options.HandlerFilter(ctx, req) {
 				return h(ctx, req, rsp)
 			}
-			name := fmt.Sprintf("%s.%s", req.Service(), req.Endpoint())
+			name := options.spanName(fmt.Sprintf("%s.%s", req.Service(), req.Endpoint()))
 			spanOpts := []trace.SpanStartOption{
 				trace.WithSpanKind(trace.SpanKindServer),
 			}
+			if options.HandlerAttributes != nil {
+				spanOpts = append(spanOpts, trace.WithAttributes(options.HandlerAttributes(ctx, req)...))
+			}
 			ctx, span := StartSpanFromContext(ctx, options.TraceProvider, name, spanOpts...)
 			defer span.End()
 			if err := h(ctx, req, rsp); err != nil {
@@ -76,10 +82,13 @@ func NewSubscriberWrapper(opts ...Option) server.SubscriberWrapper {
 			if options.SubscriberFilter != nil && options.SubscriberFilter(ctx, msg) {
 				return next(ctx, msg)
 			}
-			name := "Sub from " + msg.Topic()
+			name := options.spanName("Sub from " + msg.Topic())
 			spanOpts := []trace.SpanStartOption{
 				trace.WithSpanKind(trace.SpanKindServer),
 			}
+			if options.SubscriberAttributes != nil {
+				spanOpts = append(spanOpts, trace.WithAttributes(options.SubscriberAttributes(ctx, msg)...))
+			}
 			ctx, span := StartSpanFromContext(ctx, options.TraceProvider, name, spanOpts...)
 			defer span.End()
 			if err := next(ctx, msg); err != nil {
@@ -101,11 +110,8 @@ func NewClientWrapper(opts ...Option) client.Wrapper {
 	}
 	return func(c client.Client) client.Client {
 		w := &clientWrapper{
-			Client:        c,
-			tp:            options.TraceProvider,
-			callFilter:    options.CallFilter,
-			streamFilter:  options.StreamFilter,
-			publishFilter: options.PublishFilter,
+			Client: c,
+			opts:   options,
 		}
 		return w
 	}
@@ -114,21 +120,21 @@ func NewClientWrapper(opts ...Option) client.Wrapper {
 type clientWrapper struct {
 	client.Client
 
-	tp            trace.TracerProvider
-	callFilter    CallFilter
-	streamFilter  StreamFilter
-	publishFilter PublishFilter
+	opts Options
 }
 
 func (w *clientWrapper) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
-	if w.callFilter != nil && w.callFilter(ctx, req) {
+	if w.opts.CallFilter != nil && w.opts.CallFilter(ctx, req) {
 		return w.Client.Call(ctx, req, rsp, opts...)
 	}
-	name := fmt.Sprintf("%s.%s", req.Service(), req.Endpoint())
+	name := w.opts.spanName(fmt.Sprintf("%s.%s", req.Service(), req.Endpoint()))
 	spanOpts := []trace.SpanStartOption{
 		trace.WithSpanKind(trace.SpanKindClient),
 	}
-	ctx, span := StartSpanFromContext(ctx, w.tp, name, spanOpts...)
+	if w.opts.CallAttributes != nil {
+		spanOpts = append(spanOpts, trace.WithAttributes(w.opts.CallAttributes(ctx, req)...))
+	}
+	ctx, span := StartSpanFromContext(ctx, w.opts.TraceProvider, name, spanOpts...)
 	defer span.End()
 	if err := w.Client.Call(ctx, req, rsp, opts...); err != nil {
 		span.SetStatus(codes.Error, err.Error())
@@ -139,14 +145,17 @@ func (w *clientWrapper) Call(ctx context.Context, req client.Request, rsp interf
 }
 
 func (w *clientWrapper) Stream(ctx context.Context, req client.Request, opts ...client.CallOption) (client.Stream, error) {
-	if w.streamFilter != nil && w.streamFilter(ctx, req) {
+	if w.opts.StreamFilter != nil && w.opts.StreamFilter(ctx, req) {
 		return w.Client.Stream(ctx, req, opts...)
 	}
-	name := fmt.Sprintf("%s.%s", req.Service(), req.Endpoint())
+	name := w.opts.spanName(fmt.Sprintf("%s.%s", req.Service(), req.Endpoint()))
 	spanOpts := []trace.SpanStartOption{
 		trace.WithSpanKind(trace.SpanKindClient),
 	}
-	ctx, span := StartSpanFromContext(ctx, w.tp, name, spanOpts...)
+	if w.opts.StreamAttributes != nil {
+		spanOpts = append(spanOpts, trace.WithAttributes(w.opts.StreamAttributes(ctx, req)...))
+	}
+	ctx, span := StartSpanFromContext(ctx, w.opts.TraceProvider, name, spanOpts...)
 	defer span.End()
 	stream, err := w.Client.Stream(ctx, req, opts...)
 	if err != nil {
@@ -157,14 +166,17 @@ func (w *clientWrapper) Stream(ctx context.Context, req client.Request, opts ...
 }
 
 func (w *clientWrapper) Publish(ctx context.Context, p client.Message, opts ...client.PublishOption) error {
-	if w.publishFilter != nil && w.publishFilter(ctx, p) {
+	if w.opts.PublishFilter != nil && w.opts.PublishFilter(ctx, p) {
 		return w.Client.Publish(ctx, p, opts...)
 	}
-	name := fmt.Sprintf("Pub to %s", p.Topic())
+	name := w.opts.spanName(fmt.Sprintf("Pub to %s", p.Topic()))
 	spanOpts := []trace.SpanStartOption{
 		trace.WithSpanKind(trace.SpanKindClient),
 	}
-	ctx, span := StartSpanFromContext(ctx, w.tp, name, spanOpts...)
+	if w.opts.PublishAttributes != nil {
+		spanOpts = append(spanOpts, trace.WithAttributes(w.opts.PublishAttributes(ctx, p)...))
+	}
+	ctx, span := StartSpanFromContext(ctx, w.opts.TraceProvider, name, spanOpts...)
 	defer span.End()
 	if err := w.Client.Publish(ctx, p, opts...); err != nil {
 		span.SetStatus(codes.Error, err.Error())